@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/config"
+	"github.com/leonzhao/trading-system/backend/trading/backfill"
+)
+
+// runBackfill imports the historical candles of tokens into the kline store.
+// Interrupted runs resume from the checkpoints in the state file.
+//
+//	backend backfill -from 2024-01-01 [-to 2024-06-01] [-interval 1h]
+//	    [-tokens mint,mint] [-sources birdeye,coingecko] [-state backfill-state.json]
+func runBackfill(args []string) error {
+	flags := flag.NewFlagSet("backfill", flag.ContinueOnError)
+	tokens := flags.String("tokens", os.Getenv("MARKET_DATA_TOKENS"), "comma separated token addresses")
+	interval := flags.String("interval", backfill.DefaultConfig().Interval, "candle interval, such as 15m, 1h or 1d")
+	from := flags.String("from", "", "start of the period, as a date or RFC 3339 time")
+	to := flags.String("to", "", "end of the period, as a date or RFC 3339 time, defaults to now")
+	sourceNames := flags.String("sources", "birdeye,coingecko", "comma separated sources in order of preference")
+	state := flags.String("state", "backfill-state.json", "checkpoint file")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	cfg := backfill.DefaultConfig()
+	cfg.Interval = *interval
+	var err error
+	if cfg.From, err = parseBackfillTime(*from); err != nil {
+		return fmt.Errorf("invalid -from: %w", err)
+	}
+	if *to != "" {
+		if cfg.To, err = parseBackfillTime(*to); err != nil {
+			return fmt.Errorf("invalid -to: %w", err)
+		}
+	}
+	if *tokens == "" {
+		return fmt.Errorf("no tokens, set -tokens or MARKET_DATA_TOKENS")
+	}
+
+	var sources []backfill.Source
+	for _, name := range strings.Split(*sourceNames, ",") {
+		switch strings.TrimSpace(name) {
+		case "birdeye":
+			sources = append(sources, backfill.NewBirdeyeSource(os.Getenv("BIRDEYE_API_URL"), os.Getenv("BIRDEYE_API_KEY")))
+		case "coingecko":
+			sources = append(sources, backfill.NewCoinGeckoSource(os.Getenv("COINGECKO_API_URL"), os.Getenv("COINGECKO_API_KEY"), os.Getenv("COINGECKO_PLATFORM")))
+		default:
+			return fmt.Errorf("unknown source %q", name)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	repo, err := newRepository(ctx, config.RepositoryConfigFromEnv())
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	checkpoints, err := backfill.NewFileCheckpoints(*state)
+	if err != nil {
+		return err
+	}
+
+	backfiller := backfill.New(repo, checkpoints, cfg, sources...)
+	backfiller.OnProgress(func(p backfill.Progress) {
+		log.Printf("backfill %s %s: %5.1f%% through %s, %d candles saved (%s)",
+			p.Token, p.Interval, p.Percent, p.Through.Format(time.RFC3339), p.Saved, p.Source)
+	})
+	return backfiller.Run(ctx, strings.Split(*tokens, ","))
+}
+
+// parseBackfillTime parses a date or an RFC 3339 time
+func parseBackfillTime(value string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
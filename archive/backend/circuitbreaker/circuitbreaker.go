@@ -0,0 +1,231 @@
+// Package circuitbreaker stops calling a failing dependency for a while, so
+// callers fail fast instead of piling up on timeouts, and probes it before
+// resuming.
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/monitoring"
+)
+
+// ErrOpen is returned for calls rejected by an open breaker
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State is the state of a breaker
+type State string
+
+const (
+	// StateClosed lets every call through
+	StateClosed State = "closed"
+	// StateOpen rejects every call until the open timeout has passed
+	StateOpen State = "open"
+	// StateHalfOpen lets a few probe calls through
+	StateHalfOpen State = "half_open"
+)
+
+// gauge returns the value of a state in the state metric
+func (s State) gauge() float64 {
+	switch s {
+	case StateOpen:
+		return 2
+	case StateHalfOpen:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Config contains breaker configuration
+type Config struct {
+	// FailureThreshold is the number of consecutive failures opening the
+	// breaker
+	FailureThreshold int
+	// OpenTimeout is the time an open breaker rejects calls before probing
+	OpenTimeout time.Duration
+	// HalfOpenProbes is the number of successful probes closing the
+	// breaker; one failed probe opens it again
+	HalfOpenProbes int
+	// IsFailure reports whether an error counts as a failure of the
+	// dependency. Defaults to any error but context cancellation.
+	IsFailure func(error) bool
+}
+
+// DefaultConfig returns default breaker configuration
+func DefaultConfig() Config {
+	return Config{
+		FailureThreshold: 5,
+		OpenTimeout:      30 * time.Second,
+		HalfOpenProbes:   1,
+		IsFailure:        isFailure,
+	}
+}
+
+func isFailure(err error) bool {
+	return err != nil && !errors.Is(err, context.Canceled)
+}
+
+// Breaker is a circuit breaker around one dependency
+type Breaker struct {
+	name    string
+	config  Config
+	monitor monitoring.IMonitor
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	// probes and successes count the calls let through while half open
+	probes    int
+	successes int
+	openedAt  time.Time
+	now       func() time.Time
+}
+
+// New creates a closed breaker. name identifies the dependency in errors,
+// events and metrics.
+func New(name string, config Config, monitor monitoring.IMonitor) *Breaker {
+	defaults := DefaultConfig()
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = defaults.FailureThreshold
+	}
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = defaults.OpenTimeout
+	}
+	if config.HalfOpenProbes <= 0 {
+		config.HalfOpenProbes = defaults.HalfOpenProbes
+	}
+	if config.IsFailure == nil {
+		config.IsFailure = defaults.IsFailure
+	}
+
+	return &Breaker{
+		name:    name,
+		config:  config,
+		monitor: monitor,
+		state:   StateClosed,
+		now:     time.Now,
+	}
+}
+
+// Name returns the name of the breaker
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// State returns the state of the breaker. An open breaker whose timeout
+// has passed reports half open.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.config.OpenTimeout {
+		return StateHalfOpen
+	}
+	return b.state
+}
+
+// Allow reports whether a call may proceed, returning an error matching
+// ErrOpen if not. Every allowed call must be followed by Done.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateOpen {
+		if b.now().Sub(b.openedAt) < b.config.OpenTimeout {
+			return fmt.Errorf("%s: %w", b.name, ErrOpen)
+		}
+		b.transition(StateHalfOpen)
+	}
+	if b.state == StateHalfOpen {
+		if b.probes >= b.config.HalfOpenProbes {
+			return fmt.Errorf("%s: %w", b.name, ErrOpen)
+		}
+		b.probes++
+	}
+	return nil
+}
+
+// Done records the outcome of a call let through by Allow
+func (b *Breaker) Done(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	failed := b.config.IsFailure(err)
+	switch b.state {
+	case StateClosed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.config.FailureThreshold {
+			b.transition(StateOpen)
+		}
+	case StateHalfOpen:
+		if failed {
+			b.transition(StateOpen)
+			return
+		}
+		b.successes++
+		if b.successes >= b.config.HalfOpenProbes {
+			b.transition(StateClosed)
+		}
+	}
+}
+
+// Execute calls fn unless the breaker is open and records its outcome
+func (b *Breaker) Execute(fn func() error) error {
+	if err := b.Allow(); err != nil {
+		return err
+	}
+	err := fn()
+	b.Done(err)
+	return err
+}
+
+// transition moves the breaker to a state and reports the change. The
+// caller holds the lock.
+func (b *Breaker) transition(state State) {
+	previous := b.state
+	b.state = state
+	b.failures, b.probes, b.successes = 0, 0, 0
+	if state == StateOpen {
+		b.openedAt = b.now()
+	}
+	if b.monitor == nil {
+		return
+	}
+
+	ctx := context.Background()
+	severity := monitoring.SeverityInfo
+	if state == StateOpen {
+		severity = monitoring.SeverityWarning
+	}
+	b.monitor.RecordEvent(ctx, monitoring.Event{
+		Type:     monitoring.MetricSystem,
+		Severity: severity,
+		Message:  fmt.Sprintf("Circuit breaker %s %s", b.name, state),
+		Details: map[string]interface{}{
+			"breaker": b.name,
+			"from":    string(previous),
+			"to":      string(state),
+		},
+		Timestamp: b.now(),
+	})
+	b.monitor.RecordMetric(ctx, "circuit_breaker_state", state.gauge(), map[string]string{"breaker": b.name})
+}
+
+// Open returns the names of the open breakers among breakers. Breakers
+// ready to probe are not counted.
+func Open(breakers ...*Breaker) []string {
+	var open []string
+	for _, b := range breakers {
+		if b.State() == StateOpen {
+			open = append(open, b.name)
+		}
+	}
+	return open
+}
@@ -0,0 +1,56 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBreakerOpensAndRecovers(t *testing.T) {
+	b := New("dex", Config{FailureThreshold: 2, OpenTimeout: time.Minute}, nil)
+	now := time.Now()
+	b.now = func() time.Time { return now }
+	failure := errors.New("unavailable")
+
+	assert.Equal(t, failure, b.Execute(func() error { return failure }))
+	assert.NoError(t, b.Execute(func() error { return nil }), "a success resets the failure count")
+	b.Execute(func() error { return failure })
+	assert.Equal(t, StateClosed, b.State())
+	b.Execute(func() error { return failure })
+	assert.Equal(t, StateOpen, b.State())
+	assert.Equal(t, []string{"dex"}, Open(b))
+
+	called := false
+	err := b.Execute(func() error { called = true; return nil })
+	require.ErrorIs(t, err, ErrOpen)
+	assert.Contains(t, err.Error(), "dex")
+	assert.False(t, called)
+
+	// A failed probe opens the breaker again
+	now = now.Add(time.Minute)
+	assert.Equal(t, StateHalfOpen, b.State())
+	assert.Empty(t, Open(b))
+	require.NoError(t, b.Allow())
+	assert.ErrorIs(t, b.Allow(), ErrOpen, "only one probe at a time")
+	b.Done(failure)
+	assert.Equal(t, StateOpen, b.State())
+
+	// A successful probe closes it
+	now = now.Add(time.Minute)
+	assert.NoError(t, b.Execute(func() error { return nil }))
+	assert.Equal(t, StateClosed, b.State())
+}
+
+func TestCanceledCallsAreNotFailures(t *testing.T) {
+	b := New("llm", Config{FailureThreshold: 1}, nil)
+
+	b.Execute(func() error { return context.Canceled })
+	assert.Equal(t, StateClosed, b.State())
+
+	b.Execute(func() error { return context.DeadlineExceeded })
+	assert.Equal(t, StateOpen, b.State())
+}
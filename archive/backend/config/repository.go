@@ -0,0 +1,34 @@
+package config
+
+import "os"
+
+// Repository drivers
+const (
+	RepositoryMongoDB  = "mongodb"
+	RepositorySQLite   = "sqlite"
+	RepositoryPostgres = "postgres"
+)
+
+// RepositoryConfig selects the database the repository runs on
+type RepositoryConfig struct {
+	// Driver is mongodb, sqlite or postgres
+	Driver string
+	// DSN is the database file of sqlite and the connection URI of postgres
+	DSN string
+}
+
+// RepositoryConfigFromEnv reads the repository configuration from
+// REPOSITORY_DRIVER and DATABASE_URL, defaulting to MongoDB
+func RepositoryConfigFromEnv() RepositoryConfig {
+	config := RepositoryConfig{
+		Driver: os.Getenv("REPOSITORY_DRIVER"),
+		DSN:    os.Getenv("DATABASE_URL"),
+	}
+	if config.Driver == "" {
+		config.Driver = RepositoryMongoDB
+	}
+	if config.Driver == RepositorySQLite && config.DSN == "" {
+		config.DSN = "gosol.db"
+	}
+	return config
+}
@@ -0,0 +1,290 @@
+package dex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/monitoring"
+)
+
+// Venue identifies a DEX that can quote swaps
+type Venue string
+
+// Supported venues
+const (
+	VenueJupiter Venue = "jupiter"
+	VenueRaydium Venue = "raydium"
+)
+
+// ErrNoQuote is returned when no venue returned a usable quote
+var ErrNoQuote = errors.New("no usable quote from any venue")
+
+// Quoter quotes token swaps. JupiterClient and RaydiumClient implement it.
+type Quoter interface {
+	GetQuote(ctx context.Context, req *QuoteRequest) (*QuoteResponse, error)
+}
+
+// VenueQuote is a quote normalized across venues. Amounts are in token units
+// and the fee is in output token units.
+type VenueQuote struct {
+	Venue        Venue   `json:"venue"`
+	InputAmount  float64 `json:"input_amount"`
+	OutputAmount float64 `json:"output_amount"`
+	Fee          float64 `json:"fee"`
+	// NetOutput is the output amount after fees, the basis for comparison
+	NetOutput float64 `json:"net_output"`
+	// Price is the net output per unit of input
+	Price float64 `json:"price"`
+	// PriceImpact is the price impact in percent
+	PriceImpact float64       `json:"price_impact"`
+	Latency     time.Duration `json:"latency"`
+	Err         string        `json:"error,omitempty"`
+}
+
+// AggregatedQuote is the result of quoting a swap on every venue
+type AggregatedQuote struct {
+	Best *VenueQuote `json:"best"`
+	// Quotes contains every venue's quote, best first, followed by venues
+	// that failed or were rejected
+	Quotes []VenueQuote `json:"quotes"`
+}
+
+// VenueStats are the running quote quality metrics of a venue
+type VenueStats struct {
+	Requests int64 `json:"requests"`
+	Errors   int64 `json:"errors"`
+	// Wins is the number of times the venue had the best quote
+	Wins int64 `json:"wins"`
+	// AverageLatency is the mean quote response time
+	AverageLatency time.Duration `json:"average_latency"`
+	// AveragePriceImpact is the mean price impact of successful quotes
+	AveragePriceImpact float64 `json:"average_price_impact"`
+	// AverageShortfallBps is the mean net output shortfall against the best
+	// quote, in basis points, over requests with more than one quote
+	AverageShortfallBps float64 `json:"average_shortfall_bps"`
+
+	latencyTotal   time.Duration
+	impactTotal    float64
+	shortfallTotal float64
+	compared       int64
+}
+
+// AggregatorConfig contains DEX aggregation configuration
+type AggregatorConfig struct {
+	// Timeout bounds each venue's quote request
+	Timeout time.Duration
+	// MaxPriceImpact rejects quotes with a larger price impact, in percent.
+	// Zero disables the check.
+	MaxPriceImpact float64
+}
+
+// DefaultAggregatorConfig returns default DEX aggregation configuration
+func DefaultAggregatorConfig() AggregatorConfig {
+	return AggregatorConfig{
+		Timeout:        3 * time.Second,
+		MaxPriceImpact: 5,
+	}
+}
+
+// Aggregator quotes a swap on every venue concurrently and selects the venue
+// with the highest output after fees
+type Aggregator struct {
+	venues  map[Venue]Quoter
+	config  AggregatorConfig
+	monitor monitoring.IMonitor
+	stats   map[Venue]*VenueStats
+	mu      sync.Mutex
+}
+
+// NewAggregator creates an aggregator over the given venues. The monitor may be nil.
+func NewAggregator(venues map[Venue]Quoter, config AggregatorConfig, monitor monitoring.IMonitor) *Aggregator {
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultAggregatorConfig().Timeout
+	}
+
+	stats := make(map[Venue]*VenueStats, len(venues))
+	for venue := range venues {
+		stats[venue] = &VenueStats{}
+	}
+
+	return &Aggregator{
+		venues:  venues,
+		config:  config,
+		monitor: monitor,
+		stats:   stats,
+	}
+}
+
+// BestQuote quotes the swap on every venue and returns the best quote
+// together with all venue quotes
+func (a *Aggregator) BestQuote(ctx context.Context, req *QuoteRequest) (*AggregatedQuote, error) {
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("invalid quote amount: %f", req.Amount)
+	}
+
+	results := make(chan VenueQuote, len(a.venues))
+	var wg sync.WaitGroup
+	for venue, quoter := range a.venues {
+		wg.Add(1)
+		go func(venue Venue, quoter Quoter) {
+			defer wg.Done()
+			results <- a.quote(ctx, venue, quoter, req)
+		}(venue, quoter)
+	}
+	wg.Wait()
+	close(results)
+
+	var usable, failed []VenueQuote
+	for quote := range results {
+		if quote.Err != "" {
+			failed = append(failed, quote)
+		} else {
+			usable = append(usable, quote)
+		}
+	}
+	sort.Slice(usable, func(i, j int) bool {
+		return usable[i].NetOutput > usable[j].NetOutput
+	})
+	sort.Slice(failed, func(i, j int) bool {
+		return failed[i].Venue < failed[j].Venue
+	})
+
+	a.record(ctx, req, usable, failed)
+
+	result := &AggregatedQuote{Quotes: append(usable, failed...)}
+	if len(usable) == 0 {
+		return result, fmt.Errorf("%w for %s -> %s", ErrNoQuote, req.InputMint, req.OutputMint)
+	}
+	result.Best = &result.Quotes[0]
+	return result, nil
+}
+
+// Stats returns a copy of the quote quality metrics of every venue
+func (a *Aggregator) Stats() map[Venue]VenueStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	stats := make(map[Venue]VenueStats, len(a.stats))
+	for venue, s := range a.stats {
+		stats[venue] = *s
+	}
+	return stats
+}
+
+// quote requests and normalizes one venue's quote
+func (a *Aggregator) quote(ctx context.Context, venue Venue, quoter Quoter, req *QuoteRequest) VenueQuote {
+	ctx, cancel := context.WithTimeout(ctx, a.config.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	resp, err := quoter.GetQuote(ctx, req)
+	quote := VenueQuote{Venue: venue, InputAmount: req.Amount, Latency: time.Since(start)}
+	if err != nil {
+		quote.Err = err.Error()
+		return quote
+	}
+
+	normalizeQuote(&quote, resp)
+	switch {
+	case quote.NetOutput <= 0:
+		quote.Err = "quote has no output"
+	case a.config.MaxPriceImpact > 0 && quote.PriceImpact > a.config.MaxPriceImpact:
+		quote.Err = fmt.Sprintf("price impact %.2f%% above %.2f%%", quote.PriceImpact, a.config.MaxPriceImpact)
+	}
+	return quote
+}
+
+// normalizeQuote fills a venue quote from a response, which may carry its
+// amounts at the top level or in its data
+func normalizeQuote(quote *VenueQuote, resp *QuoteResponse) {
+	quote.OutputAmount = resp.OutputAmount
+	if quote.OutputAmount == 0 {
+		quote.OutputAmount = resp.Data.OutAmount
+	}
+	if resp.InputAmount > 0 {
+		quote.InputAmount = resp.InputAmount
+	} else if resp.Data.InAmount > 0 {
+		quote.InputAmount = resp.Data.InAmount
+	}
+	quote.PriceImpact = resp.PriceImpact
+	if quote.PriceImpact == 0 {
+		quote.PriceImpact = resp.Data.PriceImpactPct
+	}
+	quote.Fee = resp.Fee
+	quote.NetOutput = quote.OutputAmount - quote.Fee
+	if quote.InputAmount > 0 {
+		quote.Price = quote.NetOutput / quote.InputAmount
+	}
+}
+
+// record updates the venue metrics and reports them to the monitor
+func (a *Aggregator) record(ctx context.Context, req *QuoteRequest, usable, failed []VenueQuote) {
+	a.mu.Lock()
+	for _, quote := range failed {
+		s := a.stats[quote.Venue]
+		s.Requests++
+		s.Errors++
+		s.latencyTotal += quote.Latency
+		s.AverageLatency = s.latencyTotal / time.Duration(s.Requests)
+	}
+	for i, quote := range usable {
+		s := a.stats[quote.Venue]
+		s.Requests++
+		s.latencyTotal += quote.Latency
+		s.AverageLatency = s.latencyTotal / time.Duration(s.Requests)
+		s.impactTotal += quote.PriceImpact
+		s.AveragePriceImpact = s.impactTotal / float64(s.Requests-s.Errors)
+		if i == 0 {
+			s.Wins++
+		}
+		if len(usable) > 1 {
+			s.shortfallTotal += shortfallBps(usable[0], quote)
+			s.compared++
+			s.AverageShortfallBps = s.shortfallTotal / float64(s.compared)
+		}
+	}
+	a.mu.Unlock()
+
+	if a.monitor == nil {
+		return
+	}
+	for _, quote := range failed {
+		tags := map[string]string{"venue": string(quote.Venue)}
+		a.monitor.RecordMetric(ctx, "dex_quote_errors", 1, tags)
+		a.monitor.RecordMetric(ctx, "dex_quote_latency_ms", float64(quote.Latency.Milliseconds()), tags)
+	}
+	for i, quote := range usable {
+		tags := map[string]string{"venue": string(quote.Venue)}
+		a.monitor.RecordMetric(ctx, "dex_quote_latency_ms", float64(quote.Latency.Milliseconds()), tags)
+		a.monitor.RecordMetric(ctx, "dex_quote_price_impact", quote.PriceImpact, tags)
+		a.monitor.RecordMetric(ctx, "dex_quote_shortfall_bps", shortfallBps(usable[0], quote), tags)
+		if i == 0 {
+			a.monitor.RecordMetric(ctx, "dex_quote_wins", 1, tags)
+		}
+	}
+	if len(usable) == 0 {
+		a.monitor.RecordEvent(ctx, monitoring.Event{
+			Type:     monitoring.MetricTrading,
+			Severity: monitoring.SeverityWarning,
+			Message:  "No DEX venue returned a usable quote",
+			Details: map[string]interface{}{
+				"inputMint":  req.InputMint,
+				"outputMint": req.OutputMint,
+				"amount":     req.Amount,
+				"quotes":     failed,
+			},
+		})
+	}
+}
+
+// shortfallBps is how much less a quote returns than the best, in basis points
+func shortfallBps(best, quote VenueQuote) float64 {
+	if best.NetOutput <= 0 {
+		return 0
+	}
+	return (best.NetOutput - quote.NetOutput) / best.NetOutput * 10000
+}
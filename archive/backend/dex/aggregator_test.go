@@ -0,0 +1,109 @@
+package dex
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/leonzhao/trading-system/backend/monitoring"
+)
+
+type fakeQuoter struct {
+	resp  *QuoteResponse
+	err   error
+	delay time.Duration
+}
+
+func (q *fakeQuoter) GetQuote(ctx context.Context, req *QuoteRequest) (*QuoteResponse, error) {
+	select {
+	case <-time.After(q.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return q.resp, q.err
+}
+
+type metricsMonitor struct {
+	monitoring.IMonitor
+	metrics map[string]float64
+	events  []monitoring.Event
+	mu      sync.Mutex
+}
+
+func (m *metricsMonitor) RecordMetric(ctx context.Context, name string, value float64, tags map[string]string) {
+	m.mu.Lock()
+	m.metrics[name+"/"+tags["venue"]+tags["endpoint"]] += value
+	m.mu.Unlock()
+}
+
+func (m *metricsMonitor) RecordEvent(ctx context.Context, event monitoring.Event) {
+	m.events = append(m.events, event)
+}
+
+func TestAggregator(t *testing.T) {
+	ctx := context.Background()
+	req := &QuoteRequest{InputMint: "SOL", OutputMint: "BONK", Amount: 10}
+
+	jupiter := &fakeQuoter{resp: &QuoteResponse{OutputAmount: 1000, Fee: 5, PriceImpact: 0.4}}
+	// Raydium reports its amounts in the quote data
+	raydium := &fakeQuoter{resp: &QuoteResponse{Fee: 1, Data: QuoteData{InAmount: 10, OutAmount: 1000, PriceImpactPct: 0.6}}}
+	monitor := &metricsMonitor{metrics: make(map[string]float64)}
+	aggregator := NewAggregator(map[Venue]Quoter{
+		VenueJupiter: jupiter,
+		VenueRaydium: raydium,
+	}, AggregatorConfig{Timeout: 50 * time.Millisecond, MaxPriceImpact: 2}, monitor)
+
+	t.Run("Best venue has the highest output after fees", func(t *testing.T) {
+		quotes, err := aggregator.BestQuote(ctx, req)
+		require.NoError(t, err)
+		require.Len(t, quotes.Quotes, 2)
+		assert.Equal(t, VenueRaydium, quotes.Best.Venue)
+		assert.Equal(t, 999.0, quotes.Best.NetOutput)
+		assert.InDelta(t, 99.9, quotes.Best.Price, 1e-9)
+		assert.Equal(t, 0.6, quotes.Best.PriceImpact)
+		assert.Equal(t, VenueJupiter, quotes.Quotes[1].Venue)
+		assert.InDelta(t, 40.04, shortfallBps(quotes.Quotes[0], quotes.Quotes[1]), 0.01)
+	})
+
+	t.Run("Failed, slow and high impact venues are skipped", func(t *testing.T) {
+		raydium.delay = time.Second
+		quotes, err := aggregator.BestQuote(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, VenueJupiter, quotes.Best.Venue)
+		assert.Contains(t, quotes.Quotes[1].Err, "deadline exceeded")
+
+		raydium.delay = 0
+		raydium.resp = &QuoteResponse{OutputAmount: 1200, PriceImpact: 3}
+		jupiter.err = errors.New("503")
+		quotes, err = aggregator.BestQuote(ctx, req)
+		assert.ErrorIs(t, err, ErrNoQuote)
+		assert.Nil(t, quotes.Best)
+		assert.Len(t, quotes.Quotes, 2)
+		require.Len(t, monitor.events, 1)
+		assert.Equal(t, monitoring.SeverityWarning, monitor.events[0].Severity)
+
+		_, err = aggregator.BestQuote(ctx, &QuoteRequest{InputMint: "SOL", OutputMint: "BONK"})
+		assert.Error(t, err)
+	})
+
+	t.Run("Venue quality metrics", func(t *testing.T) {
+		stats := aggregator.Stats()
+		assert.Equal(t, int64(3), stats[VenueJupiter].Requests)
+		assert.Equal(t, int64(1), stats[VenueJupiter].Errors)
+		assert.Equal(t, int64(1), stats[VenueJupiter].Wins)
+		assert.InDelta(t, 40.04, stats[VenueJupiter].AverageShortfallBps, 0.01)
+		assert.Equal(t, 0.4, stats[VenueJupiter].AveragePriceImpact)
+		assert.Equal(t, int64(2), stats[VenueRaydium].Errors)
+		assert.Equal(t, int64(1), stats[VenueRaydium].Wins)
+
+		assert.Equal(t, 1.0, monitor.metrics["dex_quote_wins/raydium"])
+		assert.Equal(t, 1.0, monitor.metrics["dex_quote_wins/jupiter"])
+		assert.Equal(t, 2.0, monitor.metrics["dex_quote_errors/raydium"])
+		assert.InDelta(t, 40.04, monitor.metrics["dex_quote_shortfall_bps/jupiter"], 0.01)
+	})
+}
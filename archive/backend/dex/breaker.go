@@ -0,0 +1,63 @@
+package dex
+
+import (
+	"context"
+
+	"github.com/leonzhao/trading-system/backend/circuitbreaker"
+)
+
+// breakerClient guards a DEX client with a circuit breaker
+type breakerClient struct {
+	client  DexClient
+	breaker *circuitbreaker.Breaker
+}
+
+// WithBreaker returns a client failing fast with circuitbreaker.ErrOpen
+// while the DEX API keeps failing
+func WithBreaker(client DexClient, breaker *circuitbreaker.Breaker) DexClient {
+	return &breakerClient{client: client, breaker: breaker}
+}
+
+func (c *breakerClient) GetMarketData(ctx context.Context, tokenAddress string) (*MarketData, error) {
+	var data *MarketData
+	err := c.breaker.Execute(func() (err error) {
+		data, err = c.client.GetMarketData(ctx, tokenAddress)
+		return err
+	})
+	return data, err
+}
+
+func (c *breakerClient) GetOrderBook(ctx context.Context, tokenAddress string) (*OrderBook, error) {
+	var book *OrderBook
+	err := c.breaker.Execute(func() (err error) {
+		book, err = c.client.GetOrderBook(ctx, tokenAddress)
+		return err
+	})
+	return book, err
+}
+
+func (c *breakerClient) GetQuote(ctx context.Context, tokenAddress string, amount float64) (float64, error) {
+	var quote float64
+	err := c.breaker.Execute(func() (err error) {
+		quote, err = c.client.GetQuote(ctx, tokenAddress, amount)
+		return err
+	})
+	return quote, err
+}
+
+func (c *breakerClient) ExecuteTrade(ctx context.Context, tokenAddress string, amount float64, side string) error {
+	return c.breaker.Execute(func() error {
+		return c.client.ExecuteTrade(ctx, tokenAddress, amount, side)
+	})
+}
+
+func (c *breakerClient) CancelTrade(ctx context.Context, tradeID string) error {
+	return c.breaker.Execute(func() error {
+		return c.client.CancelTrade(ctx, tradeID)
+	})
+}
+
+// Ping bypasses the breaker so health checks see the DEX itself
+func (c *breakerClient) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx)
+}
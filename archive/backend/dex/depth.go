@@ -0,0 +1,227 @@
+package dex
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// ErrEmptyOrderBook is returned when a side of an order book has no levels
+var ErrEmptyOrderBook = errors.New("order book is empty")
+
+// Order book sides
+const (
+	SideBuy  = "buy"
+	SideSell = "sell"
+)
+
+// Wall is a resting level much larger than the levels around it
+type Wall struct {
+	Side  string  `json:"side"`
+	Price float64 `json:"price"`
+	Size  float64 `json:"size"`
+	// Distance is the distance from the mid price, in percent
+	Distance float64 `json:"distance"`
+	// Multiple is the level size over the median level size of its side
+	Multiple float64 `json:"multiple"`
+}
+
+// SlippageEstimate is the expected execution of a market order against the
+// book
+type SlippageEstimate struct {
+	Side     string  `json:"side"`
+	Size     float64 `json:"size"`
+	Filled   float64 `json:"filled"`
+	AvgPrice float64 `json:"avg_price"`
+	// Slippage is the distance of the average fill price from the mid
+	// price, in percent
+	Slippage float64 `json:"slippage"`
+	// Complete is false when the book is too thin to fill the size
+	Complete bool `json:"complete"`
+}
+
+// DepthConfig contains order book analytics configuration
+type DepthConfig struct {
+	// Range is the distance from the mid price, in percent, within which
+	// depth and imbalance are measured
+	Range float64 `json:"range"`
+	// WallMultiple is how many times the median level size a level must be
+	// to count as a wall
+	WallMultiple float64 `json:"wall_multiple"`
+}
+
+// DefaultDepthConfig returns default order book analytics configuration
+func DefaultDepthConfig() DepthConfig {
+	return DepthConfig{
+		Range:        2,
+		WallMultiple: 5,
+	}
+}
+
+// DepthAnalytics summarizes the liquidity of an order book
+type DepthAnalytics struct {
+	MidPrice float64 `json:"mid_price"`
+	// Spread is the bid-ask spread, in percent of the mid price
+	Spread float64 `json:"spread"`
+	// BidDepth and AskDepth are the quote value resting within the range
+	BidDepth float64 `json:"bid_depth"`
+	AskDepth float64 `json:"ask_depth"`
+	// Imbalance is (BidDepth - AskDepth) / (BidDepth + AskDepth), from -1
+	// (only asks) to 1 (only bids)
+	Imbalance float64 `json:"imbalance"`
+	Walls     []Wall  `json:"walls"`
+}
+
+// AnalyzeDepth computes the depth analytics of an order book
+func AnalyzeDepth(book *OrderBook, config DepthConfig) (*DepthAnalytics, error) {
+	mid, err := book.MidPrice()
+	if err != nil {
+		return nil, err
+	}
+	bids, asks := book.sorted()
+
+	analytics := &DepthAnalytics{
+		MidPrice: mid,
+		Spread:   (asks[0].Price - bids[0].Price) / mid * 100,
+	}
+	analytics.BidDepth, analytics.AskDepth = book.DepthWithin(config.Range)
+	if total := analytics.BidDepth + analytics.AskDepth; total > 0 {
+		analytics.Imbalance = (analytics.BidDepth - analytics.AskDepth) / total
+	}
+	if config.WallMultiple > 0 {
+		analytics.Walls = append(findWalls(SideBuy, bids, mid, config.WallMultiple), findWalls(SideSell, asks, mid, config.WallMultiple)...)
+	}
+	return analytics, nil
+}
+
+// MidPrice returns the midpoint of the best bid and ask
+func (b *OrderBook) MidPrice() (float64, error) {
+	bids, asks := b.sorted()
+	if len(bids) == 0 || len(asks) == 0 {
+		return 0, ErrEmptyOrderBook
+	}
+	return (bids[0].Price + asks[0].Price) / 2, nil
+}
+
+// DepthWithin returns the quote value of the bids and asks resting within
+// pct percent of the mid price
+func (b *OrderBook) DepthWithin(pct float64) (bidDepth, askDepth float64) {
+	mid, err := b.MidPrice()
+	if err != nil {
+		return 0, 0
+	}
+	bids, asks := b.sorted()
+
+	for _, level := range bids {
+		if level.Price < mid*(1-pct/100) {
+			break
+		}
+		bidDepth += level.Price * levelSize(level)
+	}
+	for _, level := range asks {
+		if level.Price > mid*(1+pct/100) {
+			break
+		}
+		askDepth += level.Price * levelSize(level)
+	}
+	return bidDepth, askDepth
+}
+
+// EstimateSlippage walks the book to estimate the execution of a market
+// order of size base units. Buys take the asks and sells take the bids.
+func (b *OrderBook) EstimateSlippage(side string, size float64) (*SlippageEstimate, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("invalid order size: %f", size)
+	}
+	mid, err := b.MidPrice()
+	if err != nil {
+		return nil, err
+	}
+
+	bids, asks := b.sorted()
+	levels := asks
+	switch side {
+	case SideBuy:
+	case SideSell:
+		levels = bids
+	default:
+		return nil, fmt.Errorf("invalid order side: %s", side)
+	}
+
+	estimate := &SlippageEstimate{Side: side, Size: size}
+	var cost float64
+	for _, level := range levels {
+		fill := math.Min(levelSize(level), size-estimate.Filled)
+		estimate.Filled += fill
+		cost += fill * level.Price
+		if estimate.Filled >= size {
+			break
+		}
+	}
+	if estimate.Filled == 0 {
+		return estimate, nil
+	}
+
+	estimate.Complete = estimate.Filled >= size
+	estimate.AvgPrice = cost / estimate.Filled
+	estimate.Slippage = math.Abs(estimate.AvgPrice-mid) / mid * 100
+	return estimate, nil
+}
+
+// sorted returns the bids from the highest price and the asks from the
+// lowest, without levels that have no size
+func (b *OrderBook) sorted() (bids, asks []OrderBookItem) {
+	for _, level := range b.Bids {
+		if level.Price > 0 && levelSize(level) > 0 {
+			bids = append(bids, level)
+		}
+	}
+	for _, level := range b.Asks {
+		if level.Price > 0 && levelSize(level) > 0 {
+			asks = append(asks, level)
+		}
+	}
+	sort.Slice(bids, func(i, j int) bool { return bids[i].Price > bids[j].Price })
+	sort.Slice(asks, func(i, j int) bool { return asks[i].Price < asks[j].Price })
+	return bids, asks
+}
+
+// levelSize returns the size of a level, which some venues report as amount
+func levelSize(level OrderBookItem) float64 {
+	if level.Size > 0 {
+		return level.Size
+	}
+	return level.Amount
+}
+
+// findWalls returns the levels at least multiple times the median level size
+func findWalls(side string, levels []OrderBookItem, mid, multiple float64) []Wall {
+	if len(levels) < 3 {
+		return nil
+	}
+
+	sizes := make([]float64, len(levels))
+	for i, level := range levels {
+		sizes[i] = levelSize(level)
+	}
+	sort.Float64s(sizes)
+	median := sizes[len(sizes)/2]
+	if len(sizes)%2 == 0 {
+		median = (sizes[len(sizes)/2-1] + sizes[len(sizes)/2]) / 2
+	}
+
+	var walls []Wall
+	for _, level := range levels {
+		if size := levelSize(level); size >= median*multiple {
+			walls = append(walls, Wall{
+				Side:     side,
+				Price:    level.Price,
+				Size:     size,
+				Distance: math.Abs(level.Price-mid) / mid * 100,
+				Multiple: size / median,
+			})
+		}
+	}
+	return walls
+}
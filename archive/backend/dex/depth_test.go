@@ -0,0 +1,79 @@
+package dex
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testBook() *OrderBook {
+	return &OrderBook{
+		// Deliberately unsorted; levels without size are ignored
+		Bids: []OrderBookItem{
+			{Price: 98, Size: 10},
+			{Price: 99, Size: 10},
+			{Price: 97, Size: 100},
+			{Price: 96, Size: 10},
+			{Price: 95, Size: 0},
+		},
+		Asks: []OrderBookItem{
+			{Price: 101, Amount: 5},
+			{Price: 102, Amount: 5},
+			{Price: 103, Amount: 10},
+		},
+	}
+}
+
+func TestDepthAnalytics(t *testing.T) {
+	book := testBook()
+
+	t.Run("Mid, spread, depth and imbalance", func(t *testing.T) {
+		analytics, err := AnalyzeDepth(book, DepthConfig{Range: 2, WallMultiple: 5})
+		require.NoError(t, err)
+		assert.Equal(t, 100.0, analytics.MidPrice)
+		assert.InDelta(t, 2.0, analytics.Spread, 1e-9)
+		// Bids at 99 and 98, asks at 101 and 102
+		assert.InDelta(t, 1970.0, analytics.BidDepth, 1e-9)
+		assert.InDelta(t, 1015.0, analytics.AskDepth, 1e-9)
+		assert.InDelta(t, 955.0/2985.0, analytics.Imbalance, 1e-9)
+
+		require.Len(t, analytics.Walls, 1)
+		assert.Equal(t, SideBuy, analytics.Walls[0].Side)
+		assert.Equal(t, 97.0, analytics.Walls[0].Price)
+		assert.InDelta(t, 3.0, analytics.Walls[0].Distance, 1e-9)
+		assert.InDelta(t, 10.0, analytics.Walls[0].Multiple, 1e-9)
+	})
+
+	t.Run("Slippage for size", func(t *testing.T) {
+		buy, err := book.EstimateSlippage(SideBuy, 10)
+		require.NoError(t, err)
+		assert.True(t, buy.Complete)
+		assert.InDelta(t, 101.5, buy.AvgPrice, 1e-9)
+		assert.InDelta(t, 1.5, buy.Slippage, 1e-9)
+
+		sell, err := book.EstimateSlippage(SideSell, 30)
+		require.NoError(t, err)
+		assert.InDelta(t, 98.0, sell.AvgPrice, 1e-9)
+		assert.InDelta(t, 2.0, sell.Slippage, 1e-9)
+
+		tooLarge, err := book.EstimateSlippage(SideBuy, 50)
+		require.NoError(t, err)
+		assert.False(t, tooLarge.Complete)
+		assert.Equal(t, 20.0, tooLarge.Filled)
+
+		_, err = book.EstimateSlippage("hold", 1)
+		assert.Error(t, err)
+		_, err = book.EstimateSlippage(SideBuy, 0)
+		assert.Error(t, err)
+	})
+
+	t.Run("Empty book", func(t *testing.T) {
+		empty := &OrderBook{Bids: []OrderBookItem{{Price: 99, Size: 1}}}
+		_, err := AnalyzeDepth(empty, DefaultDepthConfig())
+		assert.ErrorIs(t, err, ErrEmptyOrderBook)
+		bidDepth, askDepth := empty.DepthWithin(5)
+		assert.Zero(t, bidDepth)
+		assert.Zero(t, askDepth)
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"time"
 )
 
 // JupiterClient represents a client for interacting with Jupiter DEX
@@ -121,3 +122,29 @@ func (c *JupiterClient) GetRoutes(ctx context.Context, inputMint, outputMint str
 
 	return &routeMap, nil
 }
+
+// GetNewPools gets the pools created since the given time
+func (c *JupiterClient) GetNewPools(ctx context.Context, since time.Time) ([]NewPool, error) {
+	endpoint := fmt.Sprintf("%s/v4/markets/new?since=%d", c.BaseURL, since.Unix())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get new pools: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var pools []NewPool
+	if err := json.NewDecoder(resp.Body).Decode(&pools); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return pools, nil
+}
@@ -9,6 +9,7 @@ import (
 type MarketDataService struct {
 	raydiumClient  *RaydiumClient
 	jupiterClient  *JupiterClient
+	priceFeed      *PriceFeed
 }
 
 // NewMarketDataService creates a new market data service
@@ -19,6 +20,12 @@ func NewMarketDataService(raydiumClient *RaydiumClient, jupiterClient *JupiterCl
 	}
 }
 
+// SetPriceFeed prices market data at the fair price of a price feed rather
+// than the Jupiter price
+func (s *MarketDataService) SetPriceFeed(feed *PriceFeed) {
+	s.priceFeed = feed
+}
+
 // GetMarketData gets market data for a token
 func (s *MarketDataService) GetMarketData(ctx context.Context, tokenAddress string) (*MarketData, error) {
 	// Get price data from Jupiter
@@ -26,6 +33,13 @@ func (s *MarketDataService) GetMarketData(ctx context.Context, tokenAddress stri
 	if err != nil {
 		return nil, err
 	}
+	if s.priceFeed != nil {
+		fair, err := s.priceFeed.Refresh(ctx, tokenAddress)
+		if err != nil {
+			return nil, err
+		}
+		priceResp.Data.Price = fair.Price
+	}
 
 	// Get liquidity data from Raydium
 	liquidityData, err := s.raydiumClient.GetLiquidity(ctx, tokenAddress)
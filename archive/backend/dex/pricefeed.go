@@ -0,0 +1,357 @@
+package dex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/monitoring"
+)
+
+// VenueDydx is the dYdX perp market of a token, quoted at its mark price
+const VenueDydx Venue = "dydx"
+
+// ErrNoFreshPrice is returned when no venue has a fresh price for a token
+var ErrNoFreshPrice = errors.New("no fresh price from any venue")
+
+// PriceSource returns the current price of a token on a venue and when it
+// was observed
+type PriceSource interface {
+	GetTokenPrice(ctx context.Context, tokenAddress string) (float64, time.Time, error)
+}
+
+// PriceSourceFunc adapts a function to a PriceSource, such as one reading
+// the dYdX mark price of the market of a token
+type PriceSourceFunc func(ctx context.Context, tokenAddress string) (float64, time.Time, error)
+
+// GetTokenPrice calls f
+func (f PriceSourceFunc) GetTokenPrice(ctx context.Context, tokenAddress string) (float64, time.Time, error) {
+	return f(ctx, tokenAddress)
+}
+
+// JupiterPriceSource prices tokens with the Jupiter price API
+func JupiterPriceSource(c *JupiterClient) PriceSource {
+	return PriceSourceFunc(func(ctx context.Context, tokenAddress string) (float64, time.Time, error) {
+		resp, err := c.GetPrice(ctx, tokenAddress)
+		if err != nil {
+			return 0, time.Time{}, err
+		}
+		return resp.Data.Price, resp.Data.Timestamp, nil
+	})
+}
+
+// RaydiumPriceSource prices tokens at the mid of their Raydium order book
+func RaydiumPriceSource(c *RaydiumClient) PriceSource {
+	return PriceSourceFunc(func(ctx context.Context, tokenAddress string) (float64, time.Time, error) {
+		book, err := c.GetOrderBook(ctx, tokenAddress)
+		if err != nil {
+			return 0, time.Time{}, err
+		}
+		bid, ask := 0.0, math.Inf(1)
+		for _, item := range book.Bids {
+			bid = math.Max(bid, item.Price)
+		}
+		for _, item := range book.Asks {
+			if item.Price > 0 {
+				ask = math.Min(ask, item.Price)
+			}
+		}
+		if bid <= 0 || math.IsInf(ask, 1) {
+			return 0, time.Time{}, fmt.Errorf("order book of %s is one-sided", tokenAddress)
+		}
+		return (bid + ask) / 2, time.Time{}, nil
+	})
+}
+
+// PriceFeedConfig contains cross-venue price feed configuration
+type PriceFeedConfig struct {
+	// TTL is the age from which a venue price is stale and left out of the
+	// fair price
+	TTL time.Duration
+	// Timeout bounds each venue's price request on refresh
+	Timeout time.Duration
+	// MaxDivergence is the relative gap between a fresh venue price and the
+	// fair price from which the token is flagged as diverging
+	MaxDivergence float64
+	// Weights weigh the venues in the fair price, one by default
+	Weights map[Venue]float64
+}
+
+// DefaultPriceFeedConfig returns default price feed configuration
+func DefaultPriceFeedConfig() PriceFeedConfig {
+	return PriceFeedConfig{
+		TTL:           30 * time.Second,
+		Timeout:       3 * time.Second,
+		MaxDivergence: 0.02,
+	}
+}
+
+// VenuePrice is the last price of a token on a venue
+type VenuePrice struct {
+	Venue     Venue     `json:"venue"`
+	Price     float64   `json:"price"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Stale     bool      `json:"stale"`
+	// Divergence is the relative gap to the fair price, for fresh prices
+	Divergence float64 `json:"divergence"`
+	Err        string  `json:"error,omitempty"`
+}
+
+// FairPrice is the price of a token merged across venues
+type FairPrice struct {
+	TokenAddress string `json:"token_address"`
+	// Price is the weighted median of the fresh venue prices
+	Price float64 `json:"price"`
+	// Venues contains the last price of every venue, by name
+	Venues []VenuePrice `json:"venues"`
+	// Fresh is the number of venue prices the fair price is made of
+	Fresh int `json:"fresh"`
+	// Divergence is the largest relative gap between a fresh venue price
+	// and the fair price
+	Divergence float64   `json:"divergence"`
+	Diverged   bool      `json:"diverged"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// tokenPrices are the last venue prices of a token
+type tokenPrices struct {
+	venues   map[Venue]*VenuePrice
+	diverged bool
+}
+
+// PriceFeed merges the prices of a token across venues into a fair price,
+// leaving out stale venues and flagging venues that diverge from the others
+type PriceFeed struct {
+	sources map[Venue]PriceSource
+	config  PriceFeedConfig
+	monitor monitoring.IMonitor
+	tokens  map[string]*tokenPrices
+	now     func() time.Time
+	mu      sync.Mutex
+}
+
+// NewPriceFeed creates a price feed polling the given sources. Venues
+// without a source, such as a streamed dYdX mark price, can push prices with
+// Update. The monitor may be nil.
+func NewPriceFeed(sources map[Venue]PriceSource, config PriceFeedConfig, monitor monitoring.IMonitor) *PriceFeed {
+	defaults := DefaultPriceFeedConfig()
+	if config.TTL <= 0 {
+		config.TTL = defaults.TTL
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaults.Timeout
+	}
+	if config.MaxDivergence <= 0 {
+		config.MaxDivergence = defaults.MaxDivergence
+	}
+
+	return &PriceFeed{
+		sources: sources,
+		config:  config,
+		monitor: monitor,
+		tokens:  make(map[string]*tokenPrices),
+		now:     time.Now,
+	}
+}
+
+// Update records the price of a token on a venue, observed at a time or now
+// when at is zero, and returns the new fair price
+func (f *PriceFeed) Update(ctx context.Context, venue Venue, tokenAddress string, price float64, at time.Time) (*FairPrice, error) {
+	f.mu.Lock()
+	f.set(venue, tokenAddress, price, at, nil)
+	fair, changed := f.fairPrice(tokenAddress)
+	f.mu.Unlock()
+
+	f.record(ctx, fair, changed)
+	if fair.Fresh == 0 {
+		return fair, fmt.Errorf("%w for %s", ErrNoFreshPrice, tokenAddress)
+	}
+	return fair, nil
+}
+
+// Refresh polls every source for the price of a token concurrently and
+// returns the new fair price
+func (f *PriceFeed) Refresh(ctx context.Context, tokenAddress string) (*FairPrice, error) {
+	type result struct {
+		venue Venue
+		price float64
+		at    time.Time
+		err   error
+	}
+	results := make(chan result, len(f.sources))
+	var wg sync.WaitGroup
+	for venue, source := range f.sources {
+		wg.Add(1)
+		go func(venue Venue, source PriceSource) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, f.config.Timeout)
+			defer cancel()
+			price, at, err := source.GetTokenPrice(ctx, tokenAddress)
+			results <- result{venue: venue, price: price, at: at, err: err}
+		}(venue, source)
+	}
+	wg.Wait()
+	close(results)
+
+	f.mu.Lock()
+	for r := range results {
+		f.set(r.venue, tokenAddress, r.price, r.at, r.err)
+	}
+	fair, changed := f.fairPrice(tokenAddress)
+	f.mu.Unlock()
+
+	f.record(ctx, fair, changed)
+	if fair.Fresh == 0 {
+		return fair, fmt.Errorf("%w for %s", ErrNoFreshPrice, tokenAddress)
+	}
+	return fair, nil
+}
+
+// FairPrice returns the fair price of a token from the last venue prices
+func (f *PriceFeed) FairPrice(tokenAddress string) (*FairPrice, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.tokens[tokenAddress]; !ok {
+		return nil, fmt.Errorf("%w for %s", ErrNoFreshPrice, tokenAddress)
+	}
+	fair := f.merge(tokenAddress)
+	if fair.Fresh == 0 {
+		return fair, fmt.Errorf("%w for %s", ErrNoFreshPrice, tokenAddress)
+	}
+	return fair, nil
+}
+
+// set stores a venue price. A failed request keeps the last price, which
+// goes stale at its TTL.
+func (f *PriceFeed) set(venue Venue, tokenAddress string, price float64, at time.Time, err error) {
+	token, ok := f.tokens[tokenAddress]
+	if !ok {
+		token = &tokenPrices{venues: make(map[Venue]*VenuePrice)}
+		f.tokens[tokenAddress] = token
+	}
+	last, ok := token.venues[venue]
+	if !ok {
+		last = &VenuePrice{Venue: venue}
+		token.venues[venue] = last
+	}
+
+	switch {
+	case err != nil:
+		last.Err = err.Error()
+	case !(price > 0) || math.IsInf(price, 1):
+		last.Err = fmt.Sprintf("invalid price %v", price)
+	default:
+		if at.IsZero() {
+			at = f.now()
+		}
+		last.Price = price
+		last.UpdatedAt = at
+		last.Err = ""
+	}
+}
+
+// fairPrice merges the prices of a token and updates its divergence state,
+// reporting whether it changed
+func (f *PriceFeed) fairPrice(tokenAddress string) (*FairPrice, bool) {
+	fair := f.merge(tokenAddress)
+	token := f.tokens[tokenAddress]
+	changed := token.diverged != fair.Diverged
+	token.diverged = fair.Diverged
+	return fair, changed
+}
+
+// merge computes the fair price of a token as the weighted median of its
+// fresh venue prices
+func (f *PriceFeed) merge(tokenAddress string) *FairPrice {
+	now := f.now()
+	fair := &FairPrice{TokenAddress: tokenAddress, Timestamp: now}
+
+	var fresh []VenuePrice
+	for _, last := range f.tokens[tokenAddress].venues {
+		price := *last
+		price.Stale = price.UpdatedAt.IsZero() || now.Sub(price.UpdatedAt) > f.config.TTL
+		if !price.Stale {
+			fresh = append(fresh, price)
+		}
+		fair.Venues = append(fair.Venues, price)
+	}
+	fair.Fresh = len(fresh)
+	if len(fresh) > 0 {
+		fair.Price = f.weightedMedian(fresh)
+	}
+
+	for i := range fair.Venues {
+		price := &fair.Venues[i]
+		if price.Stale || fair.Price <= 0 {
+			continue
+		}
+		price.Divergence = math.Abs(price.Price-fair.Price) / fair.Price
+		fair.Divergence = math.Max(fair.Divergence, price.Divergence)
+	}
+	fair.Diverged = fair.Divergence > f.config.MaxDivergence
+	sort.Slice(fair.Venues, func(i, j int) bool {
+		return fair.Venues[i].Venue < fair.Venues[j].Venue
+	})
+	return fair
+}
+
+// weightedMedian returns the price at which half of the weight is below.
+// An even split between two prices averages them, the plain median for
+// equal weights.
+func (f *PriceFeed) weightedMedian(prices []VenuePrice) float64 {
+	sort.Slice(prices, func(i, j int) bool {
+		return prices[i].Price < prices[j].Price
+	})
+	weights := make([]float64, len(prices))
+	total := 0.0
+	for i, price := range prices {
+		weights[i] = 1
+		if w, ok := f.config.Weights[price.Venue]; ok && w > 0 {
+			weights[i] = w
+		}
+		total += weights[i]
+	}
+
+	cumulative := 0.0
+	for i, price := range prices {
+		cumulative += weights[i]
+		switch {
+		case math.Abs(cumulative-total/2) < 1e-9*total && i+1 < len(prices):
+			return (price.Price + prices[i+1].Price) / 2
+		case cumulative > total/2:
+			return price.Price
+		}
+	}
+	return prices[len(prices)-1].Price
+}
+
+// record reports the divergence of a token, warning when it starts
+// diverging
+func (f *PriceFeed) record(ctx context.Context, fair *FairPrice, changed bool) {
+	if f.monitor == nil || fair.Fresh == 0 {
+		return
+	}
+	f.monitor.RecordMetric(ctx, "price_feed_divergence", fair.Divergence, map[string]string{
+		"token": fair.TokenAddress,
+	})
+	if !fair.Diverged || !changed {
+		return
+	}
+	f.monitor.RecordEvent(ctx, monitoring.Event{
+		Type:     monitoring.MetricTrading,
+		Severity: monitoring.SeverityWarning,
+		Message:  "Venue prices diverge",
+		Details: map[string]interface{}{
+			"tokenAddress":  fair.TokenAddress,
+			"fairPrice":     fair.Price,
+			"divergence":    fair.Divergence,
+			"maxDivergence": f.config.MaxDivergence,
+			"venues":        fair.Venues,
+		},
+	})
+}
@@ -0,0 +1,101 @@
+package dex
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticPrice is a source always quoting its price
+type staticPrice struct {
+	price float64
+	err   error
+}
+
+func (s *staticPrice) GetTokenPrice(ctx context.Context, tokenAddress string) (float64, time.Time, error) {
+	return s.price, time.Time{}, s.err
+}
+
+func TestPriceFeed(t *testing.T) {
+	ctx := context.Background()
+	jupiter := &staticPrice{price: 100}
+	raydium := &staticPrice{price: 101}
+	monitor := &metricsMonitor{metrics: make(map[string]float64)}
+	feed := NewPriceFeed(map[Venue]PriceSource{
+		VenueJupiter: jupiter,
+		VenueRaydium: raydium,
+	}, PriceFeedConfig{TTL: 30 * time.Second, MaxDivergence: 0.02}, monitor)
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	feed.now = func() time.Time { return now }
+
+	_, err := feed.FairPrice("SOL")
+	assert.ErrorIs(t, err, ErrNoFreshPrice)
+
+	fair, err := feed.Refresh(ctx, "SOL")
+	require.NoError(t, err)
+	assert.InDelta(t, 100.5, fair.Price, 1e-9, "median of two venues")
+	assert.Equal(t, 2, fair.Fresh)
+	assert.False(t, fair.Diverged)
+
+	// The dYdX mark price is pushed and outvotes an outlier
+	fair, err = feed.Update(ctx, VenueDydx, "SOL", 100.2, now.Add(-time.Second))
+	require.NoError(t, err)
+	assert.InDelta(t, 100.2, fair.Price, 1e-9)
+	require.Len(t, fair.Venues, 3)
+	assert.Equal(t, VenueDydx, fair.Venues[0].Venue)
+
+	raydium.price = 110
+	fair, err = feed.Refresh(ctx, "SOL")
+	require.NoError(t, err)
+	assert.InDelta(t, 100.2, fair.Price, 1e-9)
+	assert.True(t, fair.Diverged)
+	assert.InDelta(t, 9.8/100.2, fair.Venues[2].Divergence, 1e-9)
+	require.Len(t, monitor.events, 1)
+	assert.Equal(t, "Venue prices diverge", monitor.events[0].Message)
+
+	_, err = feed.Refresh(ctx, "SOL")
+	require.NoError(t, err)
+	assert.Len(t, monitor.events, 1, "warned once while diverging")
+
+	// A failing venue keeps its last price until it goes stale
+	raydium.price = 100.4
+	_, err = feed.Refresh(ctx, "SOL")
+	require.NoError(t, err)
+	raydium.err = errors.New("unavailable")
+	now = now.Add(20 * time.Second)
+	fair, err = feed.Refresh(ctx, "SOL")
+	require.NoError(t, err)
+	assert.Equal(t, 3, fair.Fresh)
+	assert.Equal(t, "unavailable", fair.Venues[2].Err)
+
+	now = now.Add(15 * time.Second)
+	fair, err = feed.FairPrice("SOL")
+	require.NoError(t, err)
+	assert.Equal(t, 1, fair.Fresh, "only Jupiter was refreshed recently")
+	assert.True(t, fair.Venues[0].Stale)
+	assert.InDelta(t, 100, fair.Price, 1e-9)
+
+	now = now.Add(time.Minute)
+	_, err = feed.FairPrice("SOL")
+	assert.ErrorIs(t, err, ErrNoFreshPrice)
+}
+
+func TestWeightedMedian(t *testing.T) {
+	feed := NewPriceFeed(nil, PriceFeedConfig{Weights: map[Venue]float64{VenueDydx: 3}}, nil)
+	prices := func() []VenuePrice {
+		return []VenuePrice{
+			{Venue: VenueJupiter, Price: 100},
+			{Venue: VenueRaydium, Price: 99},
+			{Venue: VenueDydx, Price: 102},
+		}
+	}
+	assert.Equal(t, 102.0, feed.weightedMedian(prices()), "dYdX carries most weight")
+
+	feed.config.Weights = nil
+	assert.Equal(t, 100.0, feed.weightedMedian(prices()))
+	assert.Equal(t, 100.5, feed.weightedMedian(prices()[1:]))
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"time"
 )
 
@@ -44,6 +45,38 @@ func (c *RaydiumClient) GetLiquidity(ctx context.Context, tokenAddress string) (
 	return &liquidity, nil
 }
 
+// GetQuote gets a quote for a token swap routed through Raydium pools
+func (c *RaydiumClient) GetQuote(ctx context.Context, req *QuoteRequest) (*QuoteResponse, error) {
+	q := url.Values{}
+	q.Add("inputMint", req.InputMint)
+	q.Add("outputMint", req.OutputMint)
+	q.Add("amount", fmt.Sprintf("%f", req.Amount))
+	q.Add("slippageBps", fmt.Sprintf("%f", req.SlippageBps))
+
+	endpoint := fmt.Sprintf("%s/v4/quote?%s", c.BaseURL, q.Encode())
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quote: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var quoteResp QuoteResponse
+	if err := json.NewDecoder(resp.Body).Decode(&quoteResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &quoteResp, nil
+}
+
 // GetOrderBook gets the order book for a token
 func (c *RaydiumClient) GetOrderBook(ctx context.Context, tokenAddress string) (*OrderBook, error) {
 	endpoint := fmt.Sprintf("%s/v4/orderbook/%s", c.BaseURL, tokenAddress)
@@ -131,3 +164,29 @@ func (c *RaydiumClient) GetRecentTrades(ctx context.Context, tokenAddress string
 
 	return trades, nil
 }
+
+// GetNewPools gets the pools created since the given time
+func (c *RaydiumClient) GetNewPools(ctx context.Context, since time.Time) ([]NewPool, error) {
+	endpoint := fmt.Sprintf("%s/v4/pools/new?since=%d", c.BaseURL, since.Unix())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get new pools: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var pools []NewPool
+	if err := json.NewDecoder(resp.Body).Decode(&pools); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return pools, nil
+}
@@ -3,66 +3,166 @@ package dex
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/leonzhao/trading-system/backend/dex/internal"
+	"github.com/leonzhao/trading-system/backend/monitoring"
 )
 
 const (
 	RaydiumAPIBaseURL = "https://api.raydium.io/v2"
 )
 
+// Raydium API endpoints, used to tag metrics
+const (
+	raydiumEndpointPool      = "pool"
+	raydiumEndpointOrderBook = "orderbook"
+	raydiumEndpointToken     = "token"
+)
+
+// RaydiumAPIConfig contains Raydium API client configuration
+type RaydiumAPIConfig struct {
+	BaseURL string
+	Timeout time.Duration
+	// MaxRetries is the number of retries after a failed request. Network
+	// errors, 429 and 5xx responses are retried.
+	MaxRetries int
+	// BaseBackoff is the delay before the first retry, doubled on each
+	// further retry up to MaxBackoff. A Retry-After header takes precedence.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// RequestsPerSecond and Burst configure the rate limiter shared by all
+	// methods. A non-positive RequestsPerSecond disables it.
+	RequestsPerSecond float64
+	Burst             int
+	// PoolInfoTTL and TokenInfoTTL are how long responses are cached. Zero
+	// disables caching.
+	PoolInfoTTL  time.Duration
+	TokenInfoTTL time.Duration
+}
+
+// DefaultRaydiumAPIConfig returns default Raydium API client configuration
+func DefaultRaydiumAPIConfig() RaydiumAPIConfig {
+	return RaydiumAPIConfig{
+		BaseURL:           RaydiumAPIBaseURL,
+		Timeout:           10 * time.Second,
+		MaxRetries:        3,
+		BaseBackoff:       250 * time.Millisecond,
+		MaxBackoff:        5 * time.Second,
+		RequestsPerSecond: 5,
+		Burst:             10,
+		PoolInfoTTL:       5 * time.Second,
+		TokenInfoTTL:      time.Minute,
+	}
+}
+
+// EndpointStats are the request metrics of one API endpoint
+type EndpointStats struct {
+	Requests int64 `json:"requests"`
+	// Errors counts requests that failed after all retries
+	Errors  int64 `json:"errors"`
+	Retries int64 `json:"retries"`
+	// RateLimited counts 429 responses, including retried ones
+	RateLimited int64 `json:"rate_limited"`
+	CacheHits   int64 `json:"cache_hits"`
+}
+
+// ErrorRate returns the fraction of requests that failed
+func (s EndpointStats) ErrorRate() float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+	return float64(s.Errors) / float64(s.Requests)
+}
+
+// httpStatusError is a non-200 response
+type httpStatusError struct {
+	code       int
+	body       string
+	retryAfter time.Duration
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("unexpected status code %d: %s", e.code, e.body)
+}
+
+type cacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
 type RaydiumAPIClient struct {
 	httpClient *http.Client
+	config     RaydiumAPIConfig
+	limiter    *tokenBucket
+	monitor    monitoring.IMonitor
+	cache      map[string]cacheEntry
+	stats      map[string]*EndpointStats
+	mu         sync.Mutex
 }
 
-func NewRaydiumAPIClient() *RaydiumAPIClient {
+// NewRaydiumAPIClient creates a Raydium API client. The monitor may be nil.
+func NewRaydiumAPIClient(config RaydiumAPIConfig, monitor monitoring.IMonitor) *RaydiumAPIClient {
+	defaults := DefaultRaydiumAPIConfig()
+	if config.BaseURL == "" {
+		config.BaseURL = defaults.BaseURL
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaults.Timeout
+	}
+	if config.BaseBackoff <= 0 {
+		config.BaseBackoff = defaults.BaseBackoff
+	}
+	if config.MaxBackoff < config.BaseBackoff {
+		config.MaxBackoff = config.BaseBackoff
+	}
+
 	return &RaydiumAPIClient{
 		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout: config.Timeout,
 		},
+		config:  config,
+		limiter: newTokenBucket(config.RequestsPerSecond, config.Burst),
+		monitor: monitor,
+		cache:   make(map[string]cacheEntry),
+		stats:   make(map[string]*EndpointStats),
 	}
 }
 
-func (c *RaydiumAPIClient) GetPoolInfo(ctx context.Context, poolId string) (*internal.PoolInfo, error) {
-	// Build URL
-	url := fmt.Sprintf("%s/pool/%s", RaydiumAPIBaseURL, poolId)
-
-	// Create request
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
-	}
-
-	// Execute request
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %v", err)
-	}
-	defer resp.Body.Close()
+// Stats returns a copy of the request metrics of every endpoint
+func (c *RaydiumAPIClient) Stats() map[string]EndpointStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %v", err)
+	stats := make(map[string]EndpointStats, len(c.stats))
+	for endpoint, s := range c.stats {
+		stats[endpoint] = *s
 	}
+	return stats
+}
 
-	// Check status code
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+func (c *RaydiumAPIClient) GetPoolInfo(ctx context.Context, poolId string) (*internal.PoolInfo, error) {
+	key := raydiumEndpointPool + "/" + poolId
+	if cached, ok := c.cached(raydiumEndpointPool, key); ok {
+		pool := *cached.(*internal.PoolInfo)
+		return &pool, nil
 	}
 
-	// Parse response
+	// Fetch and parse response
 	var raydiumResp internal.RaydiumPoolResponse
-	if err := json.Unmarshal(body, &raydiumResp); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %v", err)
+	url := fmt.Sprintf("%s/pool/%s", c.config.BaseURL, poolId)
+	if err := c.get(ctx, raydiumEndpointPool, url, &raydiumResp); err != nil {
+		return nil, err
 	}
 
 	// Convert to common PoolInfo type
-	return &internal.PoolInfo{
+	pool := &internal.PoolInfo{
 		ID:             raydiumResp.ID,
 		BaseMint:       raydiumResp.BaseMint,
 		QuoteMint:      raydiumResp.QuoteMint,
@@ -90,41 +190,19 @@ func (c *RaydiumAPIClient) GetPoolInfo(ctx context.Context, poolId string) (*int
 		MarketPrice:    raydiumResp.MarketPrice,
 		MarketPriceUSD: raydiumResp.MarketPriceUSD,
 		Liquidity:      raydiumResp.LiquidityUSD, // Use USD liquidity as the common metric
-	}, nil
-}
-
-func (c *RaydiumAPIClient) GetOrderBook(ctx context.Context, poolId string, limit int) (*internal.OrderBook, error) {
-	// Build URL
-	url := fmt.Sprintf("%s/orderbook/%s?limit=%d", RaydiumAPIBaseURL, poolId, limit)
-
-	// Create request
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
-	}
-
-	// Execute request
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %v", err)
 	}
+	c.store(key, pool, c.config.PoolInfoTTL)
 
-	// Check status code
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
-	}
+	result := *pool
+	return &result, nil
+}
 
-	// Parse response
+func (c *RaydiumAPIClient) GetOrderBook(ctx context.Context, poolId string, limit int) (*internal.OrderBook, error) {
+	// Fetch and parse response
 	var raydiumResp internal.RaydiumOrderBookResponse
-	if err := json.Unmarshal(body, &raydiumResp); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %v", err)
+	url := fmt.Sprintf("%s/orderbook/%s?limit=%d", c.config.BaseURL, poolId, limit)
+	if err := c.get(ctx, raydiumEndpointOrderBook, url, &raydiumResp); err != nil {
+		return nil, err
 	}
 
 	return &internal.OrderBook{
@@ -135,40 +213,20 @@ func (c *RaydiumAPIClient) GetOrderBook(ctx context.Context, poolId string, limi
 }
 
 func (c *RaydiumAPIClient) GetTokenInfo(ctx context.Context, tokenMint string) (*internal.TokenInfo, error) {
-	// Build URL
-	url := fmt.Sprintf("%s/token/%s", RaydiumAPIBaseURL, tokenMint)
-
-	// Create request
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
-	}
-
-	// Execute request
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %v", err)
+	key := raydiumEndpointToken + "/" + tokenMint
+	if cached, ok := c.cached(raydiumEndpointToken, key); ok {
+		token := *cached.(*internal.TokenInfo)
+		return &token, nil
 	}
 
-	// Check status code
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
-	}
-
-	// Parse response
+	// Fetch and parse response
 	var raydiumResp internal.RaydiumTokenResponse
-	if err := json.Unmarshal(body, &raydiumResp); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %v", err)
+	url := fmt.Sprintf("%s/token/%s", c.config.BaseURL, tokenMint)
+	if err := c.get(ctx, raydiumEndpointToken, url, &raydiumResp); err != nil {
+		return nil, err
 	}
 
-	return &internal.TokenInfo{
+	token := &internal.TokenInfo{
 		Symbol:         raydiumResp.Symbol,
 		Name:           raydiumResp.Name,
 		Mint:           raydiumResp.Mint,
@@ -178,7 +236,11 @@ func (c *RaydiumAPIClient) GetTokenInfo(ctx context.Context, tokenMint string) (
 		Volume24h:      raydiumResp.Volume24h,
 		MarketCap:      raydiumResp.MarketCap,
 		PriceChange24h: raydiumResp.PriceChange24h,
-	}, nil
+	}
+	c.store(key, token, c.config.TokenInfoTTL)
+
+	result := *token
+	return &result, nil
 }
 
 func (c *RaydiumAPIClient) GetLiquidity(ctx context.Context, poolId string) (float64, error) {
@@ -238,3 +300,177 @@ func (c *RaydiumAPIClient) GetPriceImpact(ctx context.Context, poolId string, am
 func (c *RaydiumAPIClient) GetMarketDepth(ctx context.Context, poolId string, limit int) (*internal.OrderBook, error) {
 	return c.GetOrderBook(ctx, poolId, limit)
 }
+
+// get performs a rate limited GET request, retrying transient failures, and
+// decodes the JSON response into out
+func (c *RaydiumAPIClient) get(ctx context.Context, endpoint, url string, out interface{}) error {
+	body, err := c.fetch(ctx, endpoint, url)
+	if err == nil {
+		if err = json.Unmarshal(body, out); err != nil {
+			err = fmt.Errorf("failed to parse response: %v", err)
+		}
+	}
+
+	c.update(endpoint, func(s *EndpointStats) {
+		s.Requests++
+		if err != nil {
+			s.Errors++
+		}
+	})
+	c.recordMetric(ctx, "raydium_api_requests", endpoint, 1)
+	if err != nil {
+		c.recordMetric(ctx, "raydium_api_errors", endpoint, 1)
+	}
+	return err
+}
+
+// fetch returns the body of a successful response, retrying network errors,
+// 429 and 5xx responses with exponential backoff
+func (c *RaydiumAPIClient) fetch(ctx context.Context, endpoint, url string) ([]byte, error) {
+	var lastErr error
+	for attempt := 0; attempt <= c.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			if err := sleepContext(ctx, c.backoff(attempt, lastErr)); err != nil {
+				return nil, lastErr
+			}
+			c.update(endpoint, func(s *EndpointStats) { s.Retries++ })
+			c.recordMetric(ctx, "raydium_api_retries", endpoint, 1)
+		}
+		if err := c.limiter.Wait(ctx); err != nil {
+			if lastErr != nil {
+				return nil, lastErr
+			}
+			return nil, fmt.Errorf("rate limiter: %w", err)
+		}
+
+		start := time.Now()
+		body, err := c.do(ctx, url)
+		c.recordMetric(ctx, "raydium_api_latency_ms", endpoint, float64(time.Since(start).Milliseconds()))
+		if err == nil {
+			return body, nil
+		}
+		lastErr = err
+
+		var statusErr *httpStatusError
+		if errors.As(err, &statusErr) && statusErr.code == http.StatusTooManyRequests {
+			c.update(endpoint, func(s *EndpointStats) { s.RateLimited++ })
+			c.recordMetric(ctx, "raydium_api_rate_limited", endpoint, 1)
+		}
+		if !retryable(err) || ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+// do executes one GET request
+func (c *RaydiumAPIClient) do(ctx context.Context, url string) ([]byte, error) {
+	// Create request
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	// Execute request
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Read response body
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	// Check status code
+	if resp.StatusCode != http.StatusOK {
+		statusErr := &httpStatusError{code: resp.StatusCode, body: string(body)}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			statusErr.retryAfter = time.Duration(seconds) * time.Second
+		}
+		return nil, statusErr
+	}
+	return body, nil
+}
+
+// backoff returns the delay before a retry, honoring Retry-After
+func (c *RaydiumAPIClient) backoff(attempt int, err error) time.Duration {
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) && statusErr.retryAfter > 0 {
+		return min(statusErr.retryAfter, c.config.MaxBackoff)
+	}
+	delay := c.config.BaseBackoff << (attempt - 1)
+	if delay <= 0 || delay > c.config.MaxBackoff {
+		delay = c.config.MaxBackoff
+	}
+	return delay
+}
+
+// cached returns an unexpired cache entry
+func (c *RaydiumAPIClient) cached(endpoint, key string) (interface{}, bool) {
+	c.mu.Lock()
+	entry, ok := c.cache[key]
+	if ok && time.Now().After(entry.expires) {
+		delete(c.cache, key)
+		ok = false
+	}
+	c.mu.Unlock()
+
+	if ok {
+		c.update(endpoint, func(s *EndpointStats) { s.CacheHits++ })
+	}
+	return entry.value, ok
+}
+
+func (c *RaydiumAPIClient) store(key string, value interface{}, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	c.cache[key] = cacheEntry{value: value, expires: time.Now().Add(ttl)}
+	c.mu.Unlock()
+}
+
+func (c *RaydiumAPIClient) update(endpoint string, fn func(*EndpointStats)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, ok := c.stats[endpoint]
+	if !ok {
+		s = &EndpointStats{}
+		c.stats[endpoint] = s
+	}
+	fn(s)
+}
+
+func (c *RaydiumAPIClient) recordMetric(ctx context.Context, name, endpoint string, value float64) {
+	if c.monitor == nil {
+		return
+	}
+	c.monitor.RecordMetric(ctx, name, value, map[string]string{"endpoint": endpoint})
+}
+
+// retryable reports whether a request error is transient. Network errors
+// are retried unless the caller's context is done.
+func retryable(err error) bool {
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.code == http.StatusTooManyRequests || statusErr.code >= 500
+	}
+	return true
+}
+
+// sleepContext waits for d or until the context is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,126 @@
+package dex
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRaydiumAPIClient(t *testing.T) {
+	ctx := context.Background()
+
+	var poolCalls, tokenCalls, bookCalls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pool/p1":
+			// Rate limited once, then served
+			if atomic.AddInt32(&poolCalls, 1) == 1 {
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.Write([]byte(`{"id":"p1","liquidityUSD":1500000,"volume24h":250000}`))
+		case "/token/BONK":
+			atomic.AddInt32(&tokenCalls, 1)
+			w.Write([]byte(`{"symbol":"BONK","mint":"BONK","decimals":5}`))
+		case "/orderbook/p1":
+			atomic.AddInt32(&bookCalls, 1)
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	monitor := &metricsMonitor{metrics: make(map[string]float64)}
+	metric := func(name, endpoint string) float64 {
+		monitor.mu.Lock()
+		defer monitor.mu.Unlock()
+		return monitor.metrics[name+"/"+endpoint]
+	}
+	client := NewRaydiumAPIClient(RaydiumAPIConfig{
+		BaseURL:      server.URL,
+		MaxRetries:   2,
+		BaseBackoff:  time.Millisecond,
+		MaxBackoff:   5 * time.Millisecond,
+		PoolInfoTTL:  time.Minute,
+		TokenInfoTTL: 20 * time.Millisecond,
+	}, monitor)
+
+	t.Run("Rate limited requests are retried and cached", func(t *testing.T) {
+		pool, err := client.GetPoolInfo(ctx, "p1")
+		require.NoError(t, err)
+		assert.Equal(t, 1500000.0, pool.Liquidity)
+
+		// Served from the cache; callers cannot modify the cached value
+		pool.Liquidity = 0
+		liquidity, err := client.GetLiquidity(ctx, "p1")
+		require.NoError(t, err)
+		assert.Equal(t, 1500000.0, liquidity)
+		volume, err := client.GetVolume24h(ctx, "p1")
+		require.NoError(t, err)
+		assert.Equal(t, 250000.0, volume)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&poolCalls))
+
+		stats := client.Stats()[raydiumEndpointPool]
+		assert.Equal(t, int64(1), stats.Requests)
+		assert.Equal(t, int64(1), stats.Retries)
+		assert.Equal(t, int64(1), stats.RateLimited)
+		assert.Equal(t, int64(2), stats.CacheHits)
+		assert.Zero(t, stats.ErrorRate())
+		assert.Equal(t, 1.0, metric("raydium_api_rate_limited", raydiumEndpointPool))
+	})
+
+	t.Run("Token info cache expires", func(t *testing.T) {
+		_, err := client.GetTokenInfo(ctx, "BONK")
+		require.NoError(t, err)
+		_, err = client.GetTokenInfo(ctx, "BONK")
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&tokenCalls))
+
+		time.Sleep(30 * time.Millisecond)
+		token, err := client.GetTokenInfo(ctx, "BONK")
+		require.NoError(t, err)
+		assert.Equal(t, 5, token.Decimals)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&tokenCalls))
+	})
+
+	t.Run("Errors are counted per endpoint after the last retry", func(t *testing.T) {
+		_, err := client.GetOrderBook(ctx, "p1", 10)
+		assert.ErrorContains(t, err, "502")
+		assert.Equal(t, int32(3), atomic.LoadInt32(&bookCalls))
+
+		// Client errors are not retried
+		_, err = client.GetTokenInfo(ctx, "missing")
+		assert.ErrorContains(t, err, "404")
+
+		stats := client.Stats()
+		assert.Equal(t, 1.0, stats[raydiumEndpointOrderBook].ErrorRate())
+		assert.Equal(t, int64(2), stats[raydiumEndpointOrderBook].Retries)
+		assert.Equal(t, int64(0), stats[raydiumEndpointToken].Retries)
+		assert.InDelta(t, 1.0/3, stats[raydiumEndpointToken].ErrorRate(), 1e-9)
+		assert.Equal(t, 1.0, metric("raydium_api_errors", raydiumEndpointOrderBook))
+		assert.Equal(t, 3.0, metric("raydium_api_requests", raydiumEndpointToken))
+	})
+}
+
+func TestTokenBucket(t *testing.T) {
+	ctx := context.Background()
+	bucket := newTokenBucket(50, 2)
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		require.NoError(t, bucket.Wait(ctx))
+	}
+	// Two tokens from the burst, two refilled at 50 per second
+	assert.GreaterOrEqual(t, time.Since(start), 35*time.Millisecond)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, bucket.Wait(cancelled), context.Canceled)
+}
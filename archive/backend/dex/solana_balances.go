@@ -0,0 +1,120 @@
+package dex
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// SPLTokenProgramID is the program that owns SPL token accounts
+const SPLTokenProgramID = "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA"
+
+// SolanaBalanceClient reads SPL token balances from a Solana RPC node
+type SolanaBalanceClient struct {
+	rpcURL     string
+	httpClient *http.Client
+}
+
+// NewSolanaBalanceClient creates a new Solana balance client
+func NewSolanaBalanceClient(rpcURL string) *SolanaBalanceClient {
+	return &SolanaBalanceClient{
+		rpcURL: rpcURL,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      int           `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type tokenAccountsResponse struct {
+	Result struct {
+		Value []struct {
+			Account struct {
+				Data struct {
+					Parsed struct {
+						Info struct {
+							Mint        string `json:"mint"`
+							TokenAmount struct {
+								Amount   string `json:"amount"`
+								Decimals int    `json:"decimals"`
+							} `json:"tokenAmount"`
+						} `json:"info"`
+					} `json:"parsed"`
+				} `json:"data"`
+			} `json:"account"`
+		} `json:"value"`
+	} `json:"result"`
+	Error *rpcError `json:"error"`
+}
+
+// GetTokenBalances returns the wallet's SPL token balances keyed by mint
+// address, summed across all of the wallet's token accounts for each mint
+func (c *SolanaBalanceClient) GetTokenBalances(ctx context.Context, walletAddress string) (map[string]float64, error) {
+	body, err := json.Marshal(rpcRequest{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "getTokenAccountsByOwner",
+		Params: []interface{}{
+			walletAddress,
+			map[string]string{"programId": SPLTokenProgramID},
+			map[string]string{"encoding": "jsonParsed"},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.rpcURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var result tokenAccountsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("rpc error %d: %s", result.Error.Code, result.Error.Message)
+	}
+
+	balances := make(map[string]float64)
+	for _, account := range result.Result.Value {
+		info := account.Account.Data.Parsed.Info
+		// Use the raw integer amount rather than uiAmount, which is null for
+		// some RPC providers and lossy for large balances
+		raw, err := strconv.ParseUint(info.TokenAmount.Amount, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid token amount for mint %s: %w", info.Mint, err)
+		}
+		balances[info.Mint] += float64(raw) / math.Pow10(info.TokenAmount.Decimals)
+	}
+
+	return balances, nil
+}
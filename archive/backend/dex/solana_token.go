@@ -0,0 +1,129 @@
+package dex
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// MintInfo is the on-chain state of an SPL token mint. An empty authority
+// has been revoked.
+type MintInfo struct {
+	Address         string  `json:"address"`
+	Supply          float64 `json:"supply"`
+	Decimals        int     `json:"decimals"`
+	MintAuthority   string  `json:"mintAuthority"`
+	FreezeAuthority string  `json:"freezeAuthority"`
+}
+
+// TokenHolder is a token account and its balance in token units
+type TokenHolder struct {
+	Address string  `json:"address"`
+	Amount  float64 `json:"amount"`
+}
+
+// SolanaTokenClient reads SPL token mint state from a Solana RPC node
+type SolanaTokenClient struct {
+	rpcURL     string
+	httpClient *http.Client
+}
+
+// NewSolanaTokenClient creates a new Solana token client
+func NewSolanaTokenClient(rpcURL string) *SolanaTokenClient {
+	return &SolanaTokenClient{
+		rpcURL: rpcURL,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// GetMintInfo returns the supply and authorities of a token mint
+func (c *SolanaTokenClient) GetMintInfo(ctx context.Context, mint string) (*MintInfo, error) {
+	var result struct {
+		Value *struct {
+			Owner string `json:"owner"`
+			Data  struct {
+				Parsed struct {
+					Type string `json:"type"`
+					Info struct {
+						Supply          string  `json:"supply"`
+						Decimals        int     `json:"decimals"`
+						MintAuthority   *string `json:"mintAuthority"`
+						FreezeAuthority *string `json:"freezeAuthority"`
+					} `json:"info"`
+				} `json:"parsed"`
+			} `json:"data"`
+		} `json:"value"`
+	}
+	err := rpcCall(ctx, c.httpClient, c.rpcURL, "getAccountInfo", []interface{}{
+		mint,
+		map[string]string{"encoding": "jsonParsed"},
+	}, &result)
+	if err != nil {
+		return nil, err
+	}
+	if result.Value == nil {
+		return nil, fmt.Errorf("mint account %s not found", mint)
+	}
+	if result.Value.Data.Parsed.Type != "mint" {
+		return nil, fmt.Errorf("account %s is not a token mint", mint)
+	}
+
+	parsed := result.Value.Data.Parsed.Info
+	supply, err := parseTokenAmount(parsed.Supply, parsed.Decimals)
+	if err != nil {
+		return nil, fmt.Errorf("invalid supply for mint %s: %w", mint, err)
+	}
+
+	info := &MintInfo{
+		Address:  mint,
+		Supply:   supply,
+		Decimals: parsed.Decimals,
+	}
+	if parsed.MintAuthority != nil {
+		info.MintAuthority = *parsed.MintAuthority
+	}
+	if parsed.FreezeAuthority != nil {
+		info.FreezeAuthority = *parsed.FreezeAuthority
+	}
+	return info, nil
+}
+
+// GetLargestHolders returns the largest token accounts of a mint, largest
+// first. The RPC node returns at most 20 accounts.
+func (c *SolanaTokenClient) GetLargestHolders(ctx context.Context, mint string) ([]TokenHolder, error) {
+	var result struct {
+		Value []struct {
+			Address  string `json:"address"`
+			Amount   string `json:"amount"`
+			Decimals int    `json:"decimals"`
+		} `json:"value"`
+	}
+	err := rpcCall(ctx, c.httpClient, c.rpcURL, "getTokenLargestAccounts", []interface{}{mint}, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	holders := make([]TokenHolder, 0, len(result.Value))
+	for _, account := range result.Value {
+		amount, err := parseTokenAmount(account.Amount, account.Decimals)
+		if err != nil {
+			return nil, fmt.Errorf("invalid balance for token account %s: %w", account.Address, err)
+		}
+		holders = append(holders, TokenHolder{Address: account.Address, Amount: amount})
+	}
+	return holders, nil
+}
+
+// parseTokenAmount converts a raw token amount string to token units
+func parseTokenAmount(raw string, decimals int) (float64, error) {
+	amount, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return float64(amount) / math.Pow10(decimals), nil
+}
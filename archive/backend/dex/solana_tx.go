@@ -0,0 +1,128 @@
+package dex
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Solana commitment levels, in increasing order of finality
+const (
+	CommitmentProcessed = "processed"
+	CommitmentConfirmed = "confirmed"
+	CommitmentFinalized = "finalized"
+)
+
+// SignatureStatus is the status of a submitted transaction signature
+type SignatureStatus struct {
+	Slot               uint64          `json:"slot"`
+	Confirmations      *uint64         `json:"confirmations"`
+	Err                json.RawMessage `json:"err"`
+	ConfirmationStatus string          `json:"confirmationStatus"`
+}
+
+// Failed reports whether the transaction landed but failed to execute
+func (s *SignatureStatus) Failed() bool {
+	return len(s.Err) > 0 && string(s.Err) != "null"
+}
+
+// Reached reports whether the transaction reached the given commitment level
+func (s *SignatureStatus) Reached(commitment string) bool {
+	rank := map[string]int{CommitmentProcessed: 1, CommitmentConfirmed: 2, CommitmentFinalized: 3}
+	return rank[s.ConfirmationStatus] >= rank[commitment]
+}
+
+// SolanaTxClient reads transaction confirmation state from a Solana RPC node
+type SolanaTxClient struct {
+	rpcURL     string
+	httpClient *http.Client
+}
+
+// NewSolanaTxClient creates a new Solana transaction client
+func NewSolanaTxClient(rpcURL string) *SolanaTxClient {
+	return &SolanaTxClient{
+		rpcURL: rpcURL,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// GetSignatureStatuses returns the status of each signature, in order. The
+// status is nil for signatures the node has not seen.
+func (c *SolanaTxClient) GetSignatureStatuses(ctx context.Context, signatures []string) ([]*SignatureStatus, error) {
+	var result struct {
+		Value []*SignatureStatus `json:"value"`
+	}
+	err := c.call(ctx, "getSignatureStatuses", []interface{}{
+		signatures,
+		map[string]bool{"searchTransactionHistory": true},
+	}, &result)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Value) != len(signatures) {
+		return nil, fmt.Errorf("expected %d signature statuses, got %d", len(signatures), len(result.Value))
+	}
+	return result.Value, nil
+}
+
+// GetBlockHeight returns the current block height at the confirmed commitment
+func (c *SolanaTxClient) GetBlockHeight(ctx context.Context) (uint64, error) {
+	var height uint64
+	err := c.call(ctx, "getBlockHeight", []interface{}{
+		map[string]string{"commitment": CommitmentConfirmed},
+	}, &height)
+	return height, err
+}
+
+func (c *SolanaTxClient) call(ctx context.Context, method string, params []interface{}, result interface{}) error {
+	return rpcCall(ctx, c.httpClient, c.rpcURL, method, params, result)
+}
+
+// rpcCall performs a JSON-RPC call and decodes its result
+func rpcCall(ctx context.Context, httpClient *http.Client, rpcURL, method string, params []interface{}, result interface{}) error {
+	body, err := json.Marshal(rpcRequest{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rpcURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var response struct {
+		Result json.RawMessage `json:"result"`
+		Error  *rpcError       `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	if response.Error != nil {
+		return fmt.Errorf("rpc error %d: %s", response.Error.Code, response.Error.Message)
+	}
+	if err := json.Unmarshal(response.Result, result); err != nil {
+		return fmt.Errorf("failed to decode %s result: %w", method, err)
+	}
+	return nil
+}
@@ -140,6 +140,10 @@ type LiquidityInfo struct {
 	TotalSupply  float64 `json:"totalSupply"`
 	TokenAmount  float64 `json:"tokenAmount"`
 	Volume24h    float64 `json:"volume24h"`
+	// LPMint is the pool's LP token mint
+	LPMint string `json:"lpMint"`
+	// TokenVault is the pool's token account holding the token's reserve
+	TokenVault string `json:"tokenVault"`
 }
 
 // Trade represents a trade
@@ -179,6 +183,17 @@ type PoolInfo struct {
 	LastUpdated  time.Time `json:"lastUpdated"`
 }
 
+// NewPool is a recently created liquidity pool for a token
+type NewPool struct {
+	Address      string `json:"address"`
+	TokenAddress string `json:"tokenAddress"`
+	QuoteMint    string `json:"quoteMint"`
+	// Price is the token price in USD
+	Price        float64   `json:"price"`
+	LiquidityUSD float64   `json:"liquidityUsd"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
 // TokenInfo represents token information
 type TokenInfo struct {
 	Address     string  `json:"address"`
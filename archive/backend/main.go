@@ -2,36 +2,46 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/leonzhao/trading-system/backend/circuitbreaker"
+	"github.com/leonzhao/trading-system/backend/config"
 	"github.com/leonzhao/trading-system/backend/dex"
 	"github.com/leonzhao/trading-system/backend/monitoring"
 	"github.com/leonzhao/trading-system/backend/repository"
 	"github.com/leonzhao/trading-system/backend/repository/mongodb"
+	"github.com/leonzhao/trading-system/backend/repository/sqldb"
 	"github.com/leonzhao/trading-system/backend/service"
+	"github.com/leonzhao/trading-system/backend/trading"
+	"github.com/leonzhao/trading-system/backend/trading/anomaly"
+	"github.com/leonzhao/trading-system/backend/trading/cache"
+	"github.com/leonzhao/trading-system/backend/trading/ingestion"
+	"github.com/leonzhao/trading-system/backend/trading/pipeline"
+	"github.com/leonzhao/trading-system/backend/trading/sentiment"
+	"github.com/leonzhao/trading-system/backend/trading/watchlist"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "backfill" {
+		if err := runBackfill(os.Args[2:]); err != nil {
+			log.Fatalf("Backfill failed: %v", err)
+		}
+		return
+	}
+
 	ctx := context.Background()
 
-	// Initialize MongoDB repository
-	repo, err := mongodb.NewRepository(ctx, repository.Options{
-		URI:            os.Getenv("MONGODB_URI"),
-		Database:       os.Getenv("MONGODB_DATABASE"),
-		Username:       os.Getenv("MONGODB_USERNAME"),
-		Password:       os.Getenv("MONGODB_PASSWORD"),
-		ConnectTimeout: 5 * time.Second,
-		Timeout:       10 * time.Second,
-		MaxConnections: 100,
-		MinConnections: 10,
-	})
+	// Initialize repository
+	repo, err := newRepository(ctx, config.RepositoryConfigFromEnv())
 	if err != nil {
-		log.Fatalf("Failed to connect to MongoDB: %v", err)
+		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
 	// Initialize DEX clients
@@ -44,11 +54,143 @@ func main() {
 	monitor := monitoring.NewMonitor()
 	monitorAPI := monitoring.NewAPI(monitor)
 
+	// Respond to alerts automatically, shedding non-essential writes while
+	// the database is slow and restarting a stalled market data feed
+	runbook := monitoring.NewRunbook(monitoring.DefaultRunbookConfig(), monitor)
+	runbook.Attach(monitor, monitoring.MetricSystem, monitoring.MetricMarketData)
+	if store, ok := repo.(monitoring.DegradableStore); ok {
+		if err := runbook.Register(monitoring.RunbookHook{
+			Name:     "degrade_repository",
+			Alert:    monitoring.AlertMongoLatencyHigh,
+			Action:   monitoring.DegradedModeAction(store, 5*time.Minute),
+			Enabled:  true,
+			Cooldown: time.Minute,
+		}); err != nil {
+			log.Fatalf("Failed to register runbook hook: %v", err)
+		}
+	}
+
+	// Fail fast while the database or the DEX APIs keep failing
+	dbBreaker := circuitbreaker.New("database", circuitbreaker.DefaultConfig(), monitor)
+	dexBreaker := circuitbreaker.New("dex", circuitbreaker.DefaultConfig(), monitor)
+	repo = repository.WithBreaker(repo, dbBreaker)
+
 	// Initialize market data service
 	marketService := dexClient.GetMarketService()
+	guardedDex := dex.WithBreaker(dexClient, dexBreaker)
 
 	// Initialize services
-	svc := service.NewService(repo, dexClient, marketService, monitor)
+	svc := service.NewService(repo, guardedDex, marketService, monitor)
+
+	// Enable position reconciliation against on-chain balances
+	if rpcURL, wallet := os.Getenv("SOLANA_RPC_URL"), os.Getenv("WALLET_ADDRESS"); rpcURL != "" && wallet != "" {
+		var ignore []string
+		if tokens := os.Getenv("RECONCILE_IGNORE_TOKENS"); tokens != "" {
+			ignore = strings.Split(tokens, ",")
+		}
+		svc.SetReconciler(trading.NewReconciler(repo, dex.NewSolanaBalanceClient(rpcURL), trading.ReconcilerConfig{
+			WalletAddress: wallet,
+			IgnoreTokens:  ignore,
+		}, monitor))
+	}
+
+	// Keep the traded tokens and their overrides, editable at runtime
+	watchList, err := newWatchList(monitor)
+	if err != nil {
+		log.Fatalf("Failed to load watch list: %v", err)
+	}
+	if watchList != nil {
+		svc.SetWatchList(watchList)
+	}
+
+	// Poll market data of the watched tokens once for every consumer
+	ingestionCtx, stopIngestion := context.WithCancel(ctx)
+	defer stopIngestion()
+	var feed service.Feed
+	if watchList != nil {
+		tiered := cache.NewTieredMarketDataCache(nil, marketDataStore{repo}, cache.DefaultTieredConfig())
+		ingestionService := ingestion.NewService(guardedDex, repo, marketDataCache{tiered}, ingestion.DefaultConfig(), monitor)
+		// Quarantine price spikes, stale ticks and dead feeds before they
+		// reach the store, indicators and strategies
+		ingestionService.SetValidator(anomaly.NewDetector(anomaly.DefaultConfig(), monitor))
+		watchList.Subscribe(func(tokens []watchlist.Token) {
+			ingestionService.SetTokens(watchlist.Addresses(tokens))
+			ingestionService.SetPollIntervals(watchlist.PollIntervals(tokens))
+		})
+		go ingestionService.Run(ingestionCtx)
+
+		feed = ingestionService
+		if err := runbook.Register(monitoring.RunbookHook{
+			Name:     "restart_ingestion",
+			Alert:    monitoring.AlertWebSocketStalled,
+			Action:   monitoring.RestartAction(ingestionService),
+			Enabled:  true,
+			Cooldown: 5 * time.Minute,
+		}); err != nil {
+			log.Fatalf("Failed to register runbook hook: %v", err)
+		}
+	}
+
+	// Raise the database latency and stalled feed alerts of the runbook
+	alertsCtx, stopAlerts := context.WithCancel(ctx)
+	defer stopAlerts()
+	go service.NewAlertService(repo, feed, monitor, service.DefaultAlertConfig()).Start(alertsCtx)
+
+	// Score the social sentiment of the tokens watched at start
+	sentimentCtx, stopSentiment := context.WithCancel(ctx)
+	defer stopSentiment()
+	var socialSentiment *sentiment.Service
+	if model := os.Getenv("SENTIMENT_LLM_MODEL"); model != "" {
+		var watched []string
+		if watchList != nil {
+			watched = watchlist.Addresses(watchList.Tokens())
+		}
+		socialSentiment, err = newSentimentService(model, watched, monitor)
+		if err != nil {
+			log.Fatalf("Failed to create sentiment service: %v", err)
+		}
+		go socialSentiment.Run(sentimentCtx)
+	}
+
+	// Periodically analyze the watched tokens and the discovery watchlist
+	pipelineCtx, stopPipeline := context.WithCancel(ctx)
+	defer stopPipeline()
+	if interval := os.Getenv("ANALYSIS_INTERVAL"); interval != "" {
+		pipelineConfig := pipeline.DefaultConfig()
+		d, err := time.ParseDuration(interval)
+		if err != nil {
+			log.Fatalf("Invalid ANALYSIS_INTERVAL: %v", err)
+		}
+		pipelineConfig.Interval = d
+		fusion := trading.NewSignalFusion(trading.DefaultFusionConfig(), monitor)
+		scheduler := pipeline.NewScheduler(repo, repo, nil, fusion, pipelineConfig, monitor)
+		if watchList != nil {
+			watchList.Subscribe(func(tokens []watchlist.Token) {
+				scheduler.SetTokens(watchlist.Addresses(tokens))
+			})
+		}
+		if socialSentiment != nil {
+			scheduler.SetSentimentSource(socialSentiment)
+		}
+		go scheduler.Run(pipelineCtx)
+	}
+
+	// Keep daily stats and performance metrics up to date
+	performanceCtx, stopPerformance := context.WithCancel(ctx)
+	defer stopPerformance()
+	performance := service.NewPerformanceService(repo, monitor, service.DefaultPerformanceConfig())
+	svc.SetPerformanceService(performance)
+	go performance.Start(performanceCtx)
+
+	// Age out raw time-series data, downsampling it first
+	retentionCtx, stopRetention := context.WithCancel(ctx)
+	defer stopRetention()
+	retention := service.NewRetentionService(repo, monitor, service.DefaultRetentionConfig())
+	retention.SetEventPruner(monitor)
+	go retention.Start(retentionCtx)
+
+	// Record the slippage of executed trades
+	svc.SetExecutionAnalytics(service.NewExecutionAnalytics(repo, monitor, service.DefaultExecutionConfig()))
 
 	// Create router
 	mux := http.NewServeMux()
@@ -78,6 +220,10 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("Shutting down server...")
+	stopAlerts()
+	stopPerformance()
+	stopPipeline()
+	stopIngestion()
 
 	// Create shutdown context with timeout
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -90,3 +236,89 @@ func main() {
 
 	log.Println("Server exited properly")
 }
+
+// newRepository connects to the database selected by the configuration.
+// MongoDB is configured through the MONGODB_* environment variables.
+func newRepository(ctx context.Context, cfg config.RepositoryConfig) (repository.Repository, error) {
+	opts := repository.Options{
+		URI:            os.Getenv("MONGODB_URI"),
+		Database:       os.Getenv("MONGODB_DATABASE"),
+		Username:       os.Getenv("MONGODB_USERNAME"),
+		Password:       os.Getenv("MONGODB_PASSWORD"),
+		ConnectTimeout: 5 * time.Second,
+		Timeout:        10 * time.Second,
+		MaxConnections: 100,
+		MinConnections: 10,
+	}
+
+	switch cfg.Driver {
+	case config.RepositoryMongoDB:
+		return mongodb.NewRepository(ctx, opts)
+	case config.RepositorySQLite:
+		return sqldb.NewRepository(ctx, sqldb.SQLite, cfg.DSN, opts)
+	case config.RepositoryPostgres:
+		return sqldb.NewRepository(ctx, sqldb.Postgres, cfg.DSN, opts)
+	default:
+		return nil, fmt.Errorf("unknown repository driver %q", cfg.Driver)
+	}
+}
+
+// newWatchList loads the watch list of WATCHLIST_FILE, a JSON array of tokens
+// saved on every change, or else watches the comma separated
+// MARKET_DATA_TOKENS without overrides. It returns nil if neither is set.
+func newWatchList(monitor monitoring.IMonitor) (*watchlist.WatchList, error) {
+	if path := os.Getenv("WATCHLIST_FILE"); path != "" {
+		return watchlist.Load(path, monitor)
+	}
+	list := os.Getenv("MARKET_DATA_TOKENS")
+	if list == "" {
+		return nil, nil
+	}
+	var tokens []watchlist.Token
+	for _, address := range strings.Split(list, ",") {
+		tokens = append(tokens, watchlist.Token{Address: address})
+	}
+	return watchlist.New(tokens, monitor)
+}
+
+// newSentimentService creates the sentiment service over the feeds configured
+// in the environment. Tracked tokens are the watched ones and the tokens of
+// SENTIMENT_KEYWORDS, given as address=keyword|keyword pairs separated by
+// commas.
+func newSentimentService(model string, watched []string, monitor monitoring.IMonitor) (*sentiment.Service, error) {
+	tokens := make(map[string][]string)
+	for _, token := range watched {
+		tokens[token] = nil
+	}
+	if pairs := os.Getenv("SENTIMENT_KEYWORDS"); pairs != "" {
+		for _, pair := range strings.Split(pairs, ",") {
+			token, keywords, ok := strings.Cut(pair, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid SENTIMENT_KEYWORDS entry %q", pair)
+			}
+			tokens[token] = append(tokens[token], strings.Split(keywords, "|")...)
+		}
+	}
+
+	var sources []sentiment.Source
+	if feeds := os.Getenv("SENTIMENT_RSS_FEEDS"); feeds != "" {
+		for _, feed := range strings.Split(feeds, ",") {
+			sources = append(sources, sentiment.NewRSSSource("", feed))
+		}
+	}
+	if token := os.Getenv("TWITTER_BEARER_TOKEN"); token != "" {
+		sources = append(sources, sentiment.NewTwitterSource("", token))
+	}
+	if token := os.Getenv("TELEGRAM_BOT_TOKEN"); token != "" {
+		sources = append(sources, sentiment.NewTelegramSource("", token))
+	}
+
+	llmURL := os.Getenv("SENTIMENT_LLM_URL")
+	if llmURL == "" {
+		llmURL = "http://localhost:11434"
+	}
+	config := sentiment.DefaultConfig()
+	config.Tokens = tokens
+	generator := sentiment.WithBreaker(sentiment.NewOllamaGenerator(llmURL, model), circuitbreaker.New("llm", circuitbreaker.DefaultConfig(), monitor))
+	return sentiment.NewService(sources, generator, config, monitor)
+}
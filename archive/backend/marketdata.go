@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/trading/cache"
+)
+
+// marketDataStore reads the latest market data of the repository for the
+// tiered cache
+type marketDataStore struct {
+	store interface {
+		GetLatestMarketData(ctx context.Context, tokenAddress string) (*models.MarketData, error)
+	}
+}
+
+func (s marketDataStore) GetLatestMarketData(ctx context.Context, tokenAddress string) (*cache.MarketData, error) {
+	data, err := s.store.GetLatestMarketData(ctx, tokenAddress)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	return cachedMarketData(data), nil
+}
+
+// marketDataCache writes ingested market data through the tiered cache
+type marketDataCache struct {
+	*cache.TieredMarketDataCache
+}
+
+func (c marketDataCache) Put(ctx context.Context, data *models.MarketData) error {
+	return c.TieredMarketDataCache.Put(ctx, cachedMarketData(data))
+}
+
+func cachedMarketData(data *models.MarketData) *cache.MarketData {
+	cached := &cache.MarketData{
+		Symbol:       data.Symbol,
+		TokenAddress: data.TokenAddress,
+		Interval:     data.Interval,
+		OpenPrice:    data.OpenPrice,
+		ClosePrice:   data.ClosePrice,
+		HighPrice:    data.HighPrice,
+		LowPrice:     data.LowPrice,
+		Volume:       data.Volume,
+		Volume24h:    data.Volume24h,
+		MarketCap:    data.MarketCap,
+		Liquidity:    data.Liquidity,
+		PriceImpact:  data.PriceImpact,
+		Timestamp:    data.Timestamp,
+	}
+	cached.OrderBook.Bids = data.OrderBook.Bids
+	cached.OrderBook.Asks = data.OrderBook.Asks
+	return cached
+}
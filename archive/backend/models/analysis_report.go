@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// AnalysisReport is a market analysis report of a token over a timeframe,
+// as generated by the market analyzer
+type AnalysisReport struct {
+	ID           string    `bson:"_id,omitempty" json:"id"`
+	TokenAddress string    `bson:"token_address" json:"tokenAddress"`
+	Timeframe    string    `bson:"timeframe" json:"timeframe"`
+	Timestamp    time.Time `bson:"timestamp" json:"timestamp"`
+
+	Trend          string  `bson:"trend" json:"trend"`
+	TrendStrength  float64 `bson:"trend_strength" json:"trendStrength"`
+	Volatility     float64 `bson:"volatility" json:"volatility"`
+	Support        float64 `bson:"support" json:"support"`
+	Resistance     float64 `bson:"resistance" json:"resistance"`
+	PredictedPrice float64 `bson:"predicted_price" json:"predictedPrice"`
+	Confidence     float64 `bson:"confidence" json:"confidence"`
+	// Regime and RegimeDirection are empty when the timeframe did not have
+	// enough data to classify the regime
+	Regime          string  `bson:"regime,omitempty" json:"regime,omitempty"`
+	RegimeDirection string  `bson:"regime_direction,omitempty" json:"regimeDirection,omitempty"`
+	RecommendedSize float64 `bson:"recommended_size" json:"recommendedSize"`
+	// Consensus is the multi-timeframe breakdown, nil when no timeframes are
+	// configured
+	Consensus *TimeframeConsensus `bson:"consensus,omitempty" json:"consensus,omitempty"`
+}
+
+// TimeframeConsensus is the agreement of the analyses of several timeframes
+// of a token on its direction
+type TimeframeConsensus struct {
+	// Direction is bullish, bearish or neutral, the sign of Score
+	Direction string `bson:"direction" json:"direction"`
+	// Score is the weighted mean of the timeframe scores, from -1 to 1
+	Score float64 `bson:"score" json:"score"`
+	// Agreement is the share of the timeframe weight agreeing with Direction
+	Agreement float64 `bson:"agreement" json:"agreement"`
+	// Aligned is set when the timeframes agree enough for a signal
+	Aligned    bool             `bson:"aligned" json:"aligned"`
+	Timeframes []TimeframeScore `bson:"timeframes" json:"timeframes"`
+}
+
+// TimeframeScore is the analysis of one timeframe
+type TimeframeScore struct {
+	Timeframe string  `bson:"timeframe" json:"timeframe"`
+	Candles   int     `bson:"candles" json:"candles"`
+	Weight    float64 `bson:"weight" json:"weight"`
+	// Votes are the directions of the indicators of the timeframe, 1 for
+	// bullish, -1 for bearish and 0 for neutral
+	Votes map[string]int `bson:"votes,omitempty" json:"votes,omitempty"`
+	// Score is the mean vote, from -1 to 1
+	Score     float64 `bson:"score" json:"score"`
+	Direction string  `bson:"direction" json:"direction"`
+	// Error is set when the timeframe has too few candles for its
+	// indicators, which then do not vote
+	Error string `bson:"error,omitempty" json:"error,omitempty"`
+}
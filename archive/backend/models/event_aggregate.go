@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// EventAggregate counts the monitoring events of one type and severity
+// recorded in an hour
+type EventAggregate struct {
+	Hour     time.Time `bson:"hour" json:"hour"`
+	Type     string    `bson:"type" json:"type"`
+	Severity string    `bson:"severity" json:"severity"`
+	Count    int       `bson:"count" json:"count"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Execution quality dimensions, the ways fills are bucketed
+const (
+	ExecutionByToken = "token"
+	ExecutionByVenue = "venue"
+	// ExecutionBySize buckets fills by notional
+	ExecutionBySize = "size"
+	// ExecutionByHour buckets fills by the UTC hour of the day
+	ExecutionByHour = "hour"
+)
+
+// ExecutionAggregate accumulates the slippage of the fills of one day in one
+// bucket of a dimension, such as the fills of a token. Slippage is in basis
+// points of the expected price, positive when the fill is worse.
+type ExecutionAggregate struct {
+	Day       time.Time `bson:"day" json:"day"`
+	Dimension string    `bson:"dimension" json:"dimension"`
+	Bucket    string    `bson:"bucket" json:"bucket"`
+	Count     int       `bson:"count" json:"count"`
+	// Adverse counts the fills worse than expected
+	Adverse            int     `bson:"adverse" json:"adverse"`
+	SlippageBpsSum     float64 `bson:"slippage_bps_sum" json:"slippageBpsSum"`
+	SlippageBpsSquares float64 `bson:"slippage_bps_squares" json:"slippageBpsSquares"`
+	Notional           float64 `bson:"notional" json:"notional"`
+	// WeightedSlippageSum is the sum of the slippage times the notional
+	WeightedSlippageSum float64 `bson:"weighted_slippage_sum" json:"weightedSlippageSum"`
+}
@@ -3,20 +3,23 @@ package models
 import "time"
 
 type MarketData struct {
-	Symbol        string
-	TokenAddress  string
-	OpenPrice     float64
-	ClosePrice    float64
-	HighPrice     float64
-	LowPrice      float64
-	Volume        float64
-	Volume24h     float64
-	MarketCap     float64
-	Liquidity     float64
-	PriceImpact   float64
-	OrderBook     struct {
-		Bids [][]float64
-		Asks [][]float64
-	}
-	Timestamp     time.Time
+	Symbol       string `bson:"symbol"`
+	TokenAddress string `bson:"token_address"`
+	// Interval is the period the data covers, such as 1m or 1h. Empty for
+	// point-in-time snapshots.
+	Interval    string  `bson:"interval"`
+	OpenPrice   float64 `bson:"open_price"`
+	ClosePrice  float64 `bson:"close_price"`
+	HighPrice   float64 `bson:"high_price"`
+	LowPrice    float64 `bson:"low_price"`
+	Volume      float64 `bson:"volume"`
+	Volume24h   float64 `bson:"volume_24h"`
+	MarketCap   float64 `bson:"market_cap"`
+	Liquidity   float64 `bson:"liquidity"`
+	PriceImpact float64 `bson:"price_impact"`
+	OrderBook   struct {
+		Bids [][]float64 `bson:"bids"`
+		Asks [][]float64 `bson:"asks"`
+	} `bson:"order_book"`
+	Timestamp time.Time `bson:"timestamp"`
 }
@@ -65,6 +65,7 @@ type PositionFilter struct {
 	Status      string     `json:"status,omitempty"`
 	StartTime   *time.Time `json:"startTime,omitempty"`
 	EndTime     *time.Time `json:"endTime,omitempty"`
+	QueryOptions
 }
 
 // PositionStats represents position statistics
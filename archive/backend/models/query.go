@@ -0,0 +1,24 @@
+package models
+
+// SortOrder is the direction of a sorted query
+type SortOrder string
+
+const (
+	SortAscending  SortOrder = "asc"
+	SortDescending SortOrder = "desc"
+)
+
+// QueryOptions pages and sorts the results of a list query. The zero value
+// returns every matching record in the repository's default order.
+type QueryOptions struct {
+	// Limit is the maximum number of records returned. Zero means no limit.
+	Limit int `json:"limit,omitempty"`
+	// Offset is the number of matching records skipped
+	Offset int `json:"offset,omitempty"`
+	// SortBy is the field sorted on, by its JSON name. Empty keeps the
+	// default sort field of the query.
+	SortBy string `json:"sortBy,omitempty"`
+	// SortOrder is the sort direction. Empty keeps the default direction of
+	// the query.
+	SortOrder SortOrder `json:"sortOrder,omitempty"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// ReconciliationAction describes what happened to a balance mismatch
+type ReconciliationAction string
+
+const (
+	// ReconciliationFlagged records that a mismatch was detected
+	ReconciliationFlagged ReconciliationAction = "flagged"
+	// ReconciliationCorrected records that positions were adjusted to the on-chain balance
+	ReconciliationCorrected ReconciliationAction = "corrected"
+	// ReconciliationDismissed records that an operator chose not to correct a mismatch
+	ReconciliationDismissed ReconciliationAction = "dismissed"
+)
+
+// BalanceMismatch describes a token whose recorded open positions do not
+// match the wallet's on-chain balance
+type BalanceMismatch struct {
+	TokenAddress   string    `json:"tokenAddress"`
+	PositionIDs    []string  `json:"positionIds"`
+	RecordedSize   float64   `json:"recordedSize"`
+	OnChainBalance float64   `json:"onChainBalance"`
+	Difference     float64   `json:"difference"`
+	DetectedAt     time.Time `json:"detectedAt"`
+}
+
+// ReconciliationRecord is the audit record for a reconciliation action
+type ReconciliationRecord struct {
+	ID             string               `bson:"_id,omitempty" json:"id"`
+	WalletAddress  string               `bson:"wallet_address" json:"walletAddress"`
+	TokenAddress   string               `bson:"token_address" json:"tokenAddress"`
+	PositionIDs    []string             `bson:"position_ids" json:"positionIds"`
+	Action         ReconciliationAction `bson:"action" json:"action"`
+	RecordedSize   float64              `bson:"recorded_size" json:"recordedSize"`
+	OnChainBalance float64              `bson:"on_chain_balance" json:"onChainBalance"`
+	Difference     float64              `bson:"difference" json:"difference"`
+	Operator       string               `bson:"operator,omitempty" json:"operator,omitempty"`
+	Note           string               `bson:"note,omitempty" json:"note,omitempty"`
+	CreatedAt      time.Time            `bson:"created_at" json:"createdAt"`
+}
+
+// ReconciliationFilter represents filters for querying reconciliation records
+type ReconciliationFilter struct {
+	TokenAddress string               `json:"tokenAddress,omitempty"`
+	Action       ReconciliationAction `json:"action,omitempty"`
+	StartTime    *time.Time           `json:"startTime,omitempty"`
+	EndTime      *time.Time           `json:"endTime,omitempty"`
+	QueryOptions
+}
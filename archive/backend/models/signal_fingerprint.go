@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// SignalFingerprint records an executed trade signal so that the same signal
+// is not executed twice, even across restarts
+type SignalFingerprint struct {
+	ID           string    `bson:"_id" json:"id"`
+	TokenAddress string    `bson:"token_address" json:"tokenAddress"`
+	Direction    TradeSide `bson:"direction" json:"direction"`
+	Strategy     string    `bson:"strategy" json:"strategy"`
+	WindowStart  time.Time `bson:"window_start" json:"windowStart"`
+	CreatedAt    time.Time `bson:"created_at" json:"createdAt"`
+	ExpiresAt    time.Time `bson:"expires_at" json:"expiresAt"`
+}
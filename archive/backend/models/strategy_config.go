@@ -0,0 +1,85 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Strategy status values stored on StrategyRecord
+const (
+	StrategyStatusEnabled  = "enabled"
+	StrategyStatusDisabled = "disabled"
+)
+
+// StrategyConfig is the runtime configuration of a strategy. It is stored
+// in StrategyRecord, with Symbols and Parameters encoded in Config and the
+// enabled flag in Status.
+type StrategyConfig struct {
+	Name       string          `json:"name"`
+	Enabled    bool            `json:"enabled"`
+	Symbols    []string        `json:"symbols"`
+	Parameters json.RawMessage `json:"parameters"`
+	UpdatedAt  time.Time       `json:"updatedAt"`
+}
+
+type strategyConfigPayload struct {
+	Symbols    []string        `json:"symbols"`
+	Parameters json.RawMessage `json:"parameters"`
+}
+
+// StrategyConfigFromRecord decodes a strategy configuration from its record
+func StrategyConfigFromRecord(record *StrategyRecord) (*StrategyConfig, error) {
+	config := &StrategyConfig{
+		Name:      record.Name,
+		Enabled:   record.Status != StrategyStatusDisabled,
+		UpdatedAt: record.UpdatedAt,
+	}
+
+	if record.Config == "" {
+		return config, nil
+	}
+
+	var payload strategyConfigPayload
+	if err := json.Unmarshal([]byte(record.Config), &payload); err != nil {
+		return nil, fmt.Errorf("invalid config for strategy %s: %w", record.Name, err)
+	}
+	config.Symbols = payload.Symbols
+	config.Parameters = payload.Parameters
+
+	return config, nil
+}
+
+// ApplyToRecord encodes the configuration into a strategy record
+func (c *StrategyConfig) ApplyToRecord(record *StrategyRecord) error {
+	data, err := json.Marshal(strategyConfigPayload{
+		Symbols:    c.Symbols,
+		Parameters: c.Parameters,
+	})
+	if err != nil {
+		return err
+	}
+
+	record.Name = c.Name
+	record.Config = string(data)
+	record.Status = StrategyStatusDisabled
+	if c.Enabled {
+		record.Status = StrategyStatusEnabled
+	}
+	record.UpdatedAt = time.Now()
+	return nil
+}
+
+// HasSymbol reports whether the strategy trades the symbol. An empty symbol
+// list means all symbols.
+func (c *StrategyConfig) HasSymbol(symbol string) bool {
+	if len(c.Symbols) == 0 {
+		return true
+	}
+	for _, s := range c.Symbols {
+		if s == symbol {
+			return true
+		}
+	}
+	return false
+}
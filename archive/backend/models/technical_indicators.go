@@ -20,6 +20,11 @@ type TechnicalIndicators struct {
 	RSI             float64
 	MACD            MACD
 	BollingerBands  BollingerBands
+	ATR             float64
+	Stochastic      Stochastic
+	OBV             float64
+	VWAP            float64
+	Ichimoku        Ichimoku
 	Signal          SignalType // 新增信号类型字段
 }
 
@@ -36,3 +41,19 @@ type BollingerBands struct {
 	Middle float64
 	Lower  float64
 }
+
+// Stochastic contains Stochastic oscillator values
+type Stochastic struct {
+	K float64
+	D float64
+}
+
+// Ichimoku contains Ichimoku cloud values at the latest candle. SenkouA and
+// SenkouB are the cloud spans projected onto the latest candle.
+type Ichimoku struct {
+	Tenkan  float64
+	Kijun   float64
+	SenkouA float64
+	SenkouB float64
+	Chikou  float64
+}
@@ -45,9 +45,19 @@ type Trade struct {
 	Fee           float64     `bson:"fee" json:"fee"`
 	Status        TradeStatus `bson:"status" json:"status"`
 	TxHash        string      `bson:"tx_hash,omitempty" json:"txHash,omitempty"`
+	Strategy      string      `bson:"strategy,omitempty" json:"strategy,omitempty"`
 	ErrorMessage  string      `bson:"error_message,omitempty" json:"errorMessage,omitempty"`
 	Timestamp     time.Time   `bson:"timestamp" json:"timestamp"`
 	UpdateTime    time.Time   `bson:"update_time" json:"updateTime"`
+
+	// Venue is the exchange or aggregator that filled the trade
+	Venue string `bson:"venue,omitempty" json:"venue,omitempty"`
+	// ExpectedPrice is the signal or quote price the trade was placed at,
+	// zero when unknown
+	ExpectedPrice float64 `bson:"expected_price,omitempty" json:"expectedPrice,omitempty"`
+	// SlippageBps is how much worse than ExpectedPrice the trade filled, in
+	// basis points
+	SlippageBps float64 `bson:"slippage_bps,omitempty" json:"slippageBps,omitempty"`
 }
 
 // TradeFilter represents filters for querying trades
@@ -60,6 +70,7 @@ type TradeFilter struct {
 	EndTime      *time.Time   `json:"endTime,omitempty"`
 	MinAmount    *float64     `json:"minAmount,omitempty"`
 	MaxAmount    *float64     `json:"maxAmount,omitempty"`
+	QueryOptions
 }
 
 // TradeStats represents trade statistics
@@ -111,7 +122,7 @@ func (t *Trade) SetError(err error) {
 
 // IsComplete returns true if the trade is in a final state
 func (t *Trade) IsComplete() bool {
-	return t.Status == TradeExecuted || t.Status == TradeFailed || t.Status == TradeCancelled
+	return t.Status == TradeExecuted || t.Status == TradeStatusCompleted || t.Status == TradeFailed || t.Status == TradeCancelled
 }
 
 // IsPending returns true if the trade is pending
@@ -31,4 +31,12 @@ type TradeSignal struct {
 	Confidence    float64
 	Description   string
 	IndicatorType string
+	// Sizing records how Size was chosen, when a position sizer chose it
+	Sizing *PositionSizing
+}
+
+// PositionSizing is the position sizer and the inputs that sized a signal
+type PositionSizing struct {
+	Sizer  string             `json:"sizer"`
+	Inputs map[string]float64 `json:"inputs"`
 }
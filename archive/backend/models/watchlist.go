@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// WatchlistStatus is the outcome of evaluating a discovered token
+type WatchlistStatus string
+
+const (
+	// WatchlistWatching marks tokens that passed the discovery filters
+	WatchlistWatching WatchlistStatus = "watching"
+	// WatchlistRejected marks tokens that failed the discovery filters
+	WatchlistRejected WatchlistStatus = "rejected"
+)
+
+// WatchlistEntry is a token found by new listing discovery
+type WatchlistEntry struct {
+	TokenAddress  string          `bson:"_id" json:"tokenAddress"`
+	PoolAddress   string          `bson:"pool_address" json:"poolAddress"`
+	Venue         string          `bson:"venue" json:"venue"`
+	Status        WatchlistStatus `bson:"status" json:"status"`
+	Reason        string          `bson:"reason,omitempty" json:"reason,omitempty"`
+	Price         float64         `bson:"price" json:"price"`
+	LiquidityUSD  float64         `bson:"liquidity_usd" json:"liquidityUsd"`
+	RiskScore     float64         `bson:"risk_score" json:"riskScore"`
+	PoolCreatedAt time.Time       `bson:"pool_created_at" json:"poolCreatedAt"`
+	DiscoveredAt  time.Time       `bson:"discovered_at" json:"discoveredAt"`
+	UpdatedAt     time.Time       `bson:"updated_at" json:"updatedAt"`
+}
+
+// WatchlistFilter represents filters for querying the watchlist
+type WatchlistFilter struct {
+	Status    WatchlistStatus `json:"status,omitempty"`
+	StartTime *time.Time      `json:"startTime,omitempty"`
+	QueryOptions
+}
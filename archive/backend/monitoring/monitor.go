@@ -34,6 +34,8 @@ type Event struct {
 	Severity  EventSeverity `json:"severity"`
 	Message   string        `json:"message"`
 	Details   interface{}   `json:"details,omitempty"`
+	// Alert marks the event as an alert that runbook hooks can respond to
+	Alert     AlertType     `json:"alert,omitempty"`
 	Timestamp time.Time     `json:"timestamp"`
 }
 
@@ -242,6 +244,24 @@ func (m *Monitor) GetEventsByType(eventType EventType) []Event {
 	return events
 }
 
+// PruneEvents removes the events recorded before a time and returns them
+func (m *Monitor) PruneEvents(before time.Time) []Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var pruned []Event
+	kept := m.events[:0]
+	for _, event := range m.events {
+		if event.Timestamp.Before(before) {
+			pruned = append(pruned, event)
+		} else {
+			kept = append(kept, event)
+		}
+	}
+	m.events = kept
+	return pruned
+}
+
 // GetMetrics returns the current metrics
 func (m *Monitor) GetMetrics() Metrics {
 	m.mu.RLock()
@@ -168,3 +168,24 @@ func TestMonitor_GetEventsByType(t *testing.T) {
 	assert.Len(t, systemEvents, 1)
 	assert.Equal(t, MetricSystem, systemEvents[0].Type)
 }
+
+func TestMonitor_PruneEvents(t *testing.T) {
+	monitor := NewMonitor()
+	defer monitor.Close()
+
+	ctx := context.Background()
+	monitor.RecordEvent(ctx, NewTestEvent(MetricTrading, SeverityInfo, "Old event"))
+	time.Sleep(100 * time.Millisecond) // Wait for event processing
+
+	before := time.Now()
+	monitor.RecordEvent(ctx, NewTestEvent(MetricSystem, SeverityInfo, "New event"))
+	time.Sleep(100 * time.Millisecond)
+
+	pruned := monitor.PruneEvents(before)
+	require.Len(t, pruned, 1)
+	assert.Equal(t, "Old event", pruned[0].Message)
+
+	events := monitor.GetEvents()
+	require.Len(t, events, 1)
+	assert.Equal(t, "New event", events[0].Message)
+}
@@ -0,0 +1,295 @@
+package monitoring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// AlertType identifies an operational alert that runbook hooks respond to
+type AlertType string
+
+const (
+	// AlertWebSocketStalled is raised when a market data feed stops delivering messages
+	AlertWebSocketStalled AlertType = "websocket_stalled"
+	// AlertMongoLatencyHigh is raised when MongoDB operations exceed their latency budget
+	AlertMongoLatencyHigh AlertType = "mongo_latency_high"
+)
+
+// Runbook action statuses
+const (
+	ActionSucceeded = "succeeded"
+	ActionFailed    = "failed"
+)
+
+// ErrUnknownHook is returned when enabling or disabling a hook that is not registered
+var ErrUnknownHook = errors.New("unknown runbook hook")
+
+// RunbookAction is an automated response to an alert
+type RunbookAction func(ctx context.Context, event Event) error
+
+// RunbookHook maps an alert type to an automated action
+type RunbookHook struct {
+	// Name identifies the hook in the action log and when toggling it
+	Name   string
+	Alert  AlertType
+	Action RunbookAction
+	// Enabled controls whether the action runs when the alert fires
+	Enabled bool
+	// Cooldown is the minimum time between two runs of the action
+	Cooldown time.Duration
+}
+
+// RunbookActionRecord records one automated action taken by a hook
+type RunbookActionRecord struct {
+	Hook      string        `json:"hook"`
+	Alert     AlertType     `json:"alert"`
+	Status    string        `json:"status"`
+	Error     string        `json:"error,omitempty"`
+	Trigger   string        `json:"trigger"`
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
+}
+
+// RunbookConfig contains runbook automation configuration
+type RunbookConfig struct {
+	// ActionTimeout bounds each action run
+	ActionTimeout time.Duration
+	// MaxLogEntries is the number of action records kept
+	MaxLogEntries int
+}
+
+// DefaultRunbookConfig returns default runbook automation configuration
+func DefaultRunbookConfig() RunbookConfig {
+	return RunbookConfig{
+		ActionTimeout: 30 * time.Second,
+		MaxLogEntries: 500,
+	}
+}
+
+// Runbook runs automated responses for alert events. Each hook runs at most
+// once per cooldown, and every action taken is kept in the action log and
+// recorded as a system event.
+type Runbook struct {
+	config  RunbookConfig
+	monitor IMonitor
+	hooks   map[AlertType][]*runbookHookState
+	byName  map[string]*runbookHookState
+	log     []RunbookActionRecord
+	mu      sync.Mutex
+}
+
+type runbookHookState struct {
+	hook    RunbookHook
+	lastRun time.Time
+}
+
+// NewRunbook creates a new runbook. monitor may be nil.
+func NewRunbook(config RunbookConfig, monitor IMonitor) *Runbook {
+	defaults := DefaultRunbookConfig()
+	if config.ActionTimeout <= 0 {
+		config.ActionTimeout = defaults.ActionTimeout
+	}
+	if config.MaxLogEntries <= 0 {
+		config.MaxLogEntries = defaults.MaxLogEntries
+	}
+
+	return &Runbook{
+		config:  config,
+		monitor: monitor,
+		hooks:   make(map[AlertType][]*runbookHookState),
+		byName:  make(map[string]*runbookHookState),
+	}
+}
+
+// Register adds a hook. Hook names must be unique.
+func (r *Runbook) Register(hook RunbookHook) error {
+	if hook.Name == "" {
+		return fmt.Errorf("hook name cannot be empty")
+	}
+	if hook.Alert == "" {
+		return fmt.Errorf("hook %s has no alert type", hook.Name)
+	}
+	if hook.Action == nil {
+		return fmt.Errorf("hook %s has no action", hook.Name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.byName[hook.Name]; exists {
+		return fmt.Errorf("hook %s already registered", hook.Name)
+	}
+	state := &runbookHookState{hook: hook}
+	r.byName[hook.Name] = state
+	r.hooks[hook.Alert] = append(r.hooks[hook.Alert], state)
+	return nil
+}
+
+// SetEnabled enables or disables a hook by name
+func (r *Runbook) SetEnabled(name string, enabled bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state, ok := r.byName[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownHook, name)
+	}
+	state.hook.Enabled = enabled
+	return nil
+}
+
+// Attach subscribes the runbook to alert events of the given types. Actions
+// run in their own goroutine so a slow action does not hold up event
+// processing.
+func (r *Runbook) Attach(monitor IMonitor, eventTypes ...EventType) {
+	for _, eventType := range eventTypes {
+		monitor.AddEventHandler(eventType, func(event Event) {
+			if event.Alert == "" {
+				return
+			}
+			go r.HandleEvent(context.Background(), event)
+		})
+	}
+}
+
+// HandleEvent runs the enabled hooks for the event's alert type whose
+// cooldown has elapsed, and returns the records of the actions taken
+func (r *Runbook) HandleEvent(ctx context.Context, event Event) []RunbookActionRecord {
+	if event.Alert == "" {
+		return nil
+	}
+
+	due := r.reserve(event.Alert, time.Now())
+	records := make([]RunbookActionRecord, 0, len(due))
+	for _, hook := range due {
+		records = append(records, r.run(ctx, hook, event))
+	}
+	return records
+}
+
+// GetActionLog returns the recorded actions, oldest first
+func (r *Runbook) GetActionLog() []RunbookActionRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	log := make([]RunbookActionRecord, len(r.log))
+	copy(log, r.log)
+	return log
+}
+
+// reserve returns the hooks due to run for an alert and starts their
+// cooldown, so concurrent alerts cannot run the same hook twice
+func (r *Runbook) reserve(alert AlertType, now time.Time) []RunbookHook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var due []RunbookHook
+	for _, state := range r.hooks[alert] {
+		if !state.hook.Enabled {
+			continue
+		}
+		if !state.lastRun.IsZero() && now.Sub(state.lastRun) < state.hook.Cooldown {
+			continue
+		}
+		state.lastRun = now
+		due = append(due, state.hook)
+	}
+	return due
+}
+
+func (r *Runbook) run(ctx context.Context, hook RunbookHook, event Event) RunbookActionRecord {
+	actionCtx, cancel := context.WithTimeout(ctx, r.config.ActionTimeout)
+	defer cancel()
+
+	started := time.Now()
+	err := hook.Action(actionCtx, event)
+	record := RunbookActionRecord{
+		Hook:      hook.Name,
+		Alert:     hook.Alert,
+		Status:    ActionSucceeded,
+		Trigger:   event.Message,
+		StartedAt: started,
+		Duration:  time.Since(started),
+	}
+	severity := SeverityWarning
+	if err != nil {
+		record.Status = ActionFailed
+		record.Error = err.Error()
+		severity = SeverityError
+	}
+
+	r.mu.Lock()
+	r.log = append(r.log, record)
+	if len(r.log) > r.config.MaxLogEntries {
+		r.log = r.log[len(r.log)-r.config.MaxLogEntries:]
+	}
+	r.mu.Unlock()
+
+	if r.monitor != nil {
+		r.monitor.RecordEvent(ctx, Event{
+			Type:     MetricSystem,
+			Severity: severity,
+			Message:  "Automated runbook action",
+			Details: map[string]interface{}{
+				"hook":     record.Hook,
+				"alert":    record.Alert,
+				"status":   record.Status,
+				"error":    record.Error,
+				"trigger":  record.Trigger,
+				"duration": record.Duration.String(),
+			},
+		})
+	}
+	return record
+}
+
+// Restarter is a component that can be restarted in place, such as a
+// websocket consumer
+type Restarter interface {
+	Restart(ctx context.Context) error
+}
+
+// RestartAction returns an action that restarts the component
+func RestartAction(component Restarter) RunbookAction {
+	return func(ctx context.Context, event Event) error {
+		return component.Restart(ctx)
+	}
+}
+
+// DegradableStore is a store that can switch to a degraded mode, such as
+// the repository shedding non-essential writes while MongoDB is slow
+type DegradableStore interface {
+	SetDegraded(degraded bool)
+}
+
+// DegradedModeAction returns an action that switches the store to degraded
+// mode, and back to normal once recovery has passed without the action
+// running again. Each run restarts the recovery period, so while the alert
+// keeps firing the store stays degraded. recovery should exceed the cooldown
+// of the hook.
+func DegradedModeAction(store DegradableStore, recovery time.Duration) RunbookAction {
+	// runs counts the runs, so only the timer of the last one recovers
+	var (
+		runs int
+		mu   sync.Mutex
+	)
+	return func(ctx context.Context, event Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		runs++
+		run := runs
+		store.SetDegraded(true)
+		time.AfterFunc(recovery, func() {
+			mu.Lock()
+			defer mu.Unlock()
+			if runs == run {
+				store.SetDegraded(false)
+			}
+		})
+		return nil
+	}
+}
@@ -0,0 +1,139 @@
+package monitoring
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type restartCounter struct {
+	restarts atomic.Int32
+	err      error
+}
+
+func (r *restartCounter) Restart(ctx context.Context) error {
+	r.restarts.Add(1)
+	return r.err
+}
+
+type degradedFlag struct {
+	degraded atomic.Bool
+}
+
+func (d *degradedFlag) SetDegraded(degraded bool) {
+	d.degraded.Store(degraded)
+}
+
+func TestRunbook_HandleEvent(t *testing.T) {
+	ctx := context.Background()
+	consumer := &restartCounter{}
+	store := &degradedFlag{}
+
+	runbook := NewRunbook(RunbookConfig{}, nil)
+	require.NoError(t, runbook.Register(RunbookHook{
+		Name:     "restart_consumer",
+		Alert:    AlertWebSocketStalled,
+		Action:   RestartAction(consumer),
+		Enabled:  true,
+		Cooldown: time.Hour,
+	}))
+	require.NoError(t, runbook.Register(RunbookHook{
+		Name:    "degrade_repository",
+		Alert:   AlertMongoLatencyHigh,
+		Action:  DegradedModeAction(store, time.Hour),
+		Enabled: false,
+	}))
+
+	stalled := Event{Type: MetricSystem, Severity: SeverityWarning, Message: "No messages for 60s", Alert: AlertWebSocketStalled}
+	records := runbook.HandleEvent(ctx, stalled)
+	require.Len(t, records, 1)
+	assert.Equal(t, ActionSucceeded, records[0].Status)
+	assert.Equal(t, "No messages for 60s", records[0].Trigger)
+	assert.Equal(t, int32(1), consumer.restarts.Load())
+
+	// Within the cooldown the consumer is not restarted again
+	assert.Empty(t, runbook.HandleEvent(ctx, stalled))
+	assert.Equal(t, int32(1), consumer.restarts.Load())
+
+	latency := Event{Type: MetricSystem, Severity: SeverityWarning, Message: "p99 800ms", Alert: AlertMongoLatencyHigh}
+	assert.Empty(t, runbook.HandleEvent(ctx, latency))
+	assert.False(t, store.degraded.Load())
+
+	require.NoError(t, runbook.SetEnabled("degrade_repository", true))
+	require.Len(t, runbook.HandleEvent(ctx, latency), 1)
+	assert.True(t, store.degraded.Load())
+
+	// Events without an alert type are ignored
+	assert.Empty(t, runbook.HandleEvent(ctx, Event{Type: MetricSystem, Message: "Metric recorded"}))
+
+	log := runbook.GetActionLog()
+	require.Len(t, log, 2)
+	assert.Equal(t, "restart_consumer", log[0].Hook)
+	assert.Equal(t, "degrade_repository", log[1].Hook)
+
+	assert.ErrorIs(t, runbook.SetEnabled("missing", true), ErrUnknownHook)
+	assert.Error(t, runbook.Register(RunbookHook{Name: "restart_consumer", Alert: AlertWebSocketStalled, Action: RestartAction(consumer)}))
+}
+
+func TestDegradedModeAction_Recovers(t *testing.T) {
+	ctx := context.Background()
+	store := &degradedFlag{}
+	action := DegradedModeAction(store, 50*time.Millisecond)
+
+	require.NoError(t, action(ctx, Event{Alert: AlertMongoLatencyHigh}))
+	assert.True(t, store.degraded.Load())
+
+	// Another run restarts the recovery period
+	time.Sleep(30 * time.Millisecond)
+	require.NoError(t, action(ctx, Event{Alert: AlertMongoLatencyHigh}))
+	time.Sleep(30 * time.Millisecond)
+	assert.True(t, store.degraded.Load())
+
+	require.Eventually(t, func() bool {
+		return !store.degraded.Load()
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestRunbook_FailedActionAndAttach(t *testing.T) {
+	monitor := NewMonitor()
+	defer monitor.Close()
+
+	consumer := &restartCounter{err: errors.New("dial timeout")}
+	runbook := NewRunbook(RunbookConfig{MaxLogEntries: 1}, monitor)
+	require.NoError(t, runbook.Register(RunbookHook{
+		Name:    "restart_consumer",
+		Alert:   AlertWebSocketStalled,
+		Action:  RestartAction(consumer),
+		Enabled: true,
+	}))
+	runbook.Attach(monitor, MetricMarketData)
+
+	monitor.RecordEvent(context.Background(), Event{
+		Type:     MetricMarketData,
+		Severity: SeverityWarning,
+		Message:  "Price feed stalled",
+		Alert:    AlertWebSocketStalled,
+	})
+
+	require.Eventually(t, func() bool {
+		return len(runbook.GetActionLog()) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	record := runbook.GetActionLog()[0]
+	assert.Equal(t, ActionFailed, record.Status)
+	assert.Equal(t, "dial timeout", record.Error)
+
+	require.Eventually(t, func() bool {
+		for _, event := range monitor.GetEventsByType(MetricSystem) {
+			if event.Message == "Automated runbook action" && event.Severity == SeverityError {
+				return true
+			}
+		}
+		return false
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/circuitbreaker"
+	"github.com/leonzhao/trading-system/backend/models"
+)
+
+// breakerRepository guards a repository with a circuit breaker
+type breakerRepository struct {
+	repo    Repository
+	breaker *circuitbreaker.Breaker
+}
+
+// WithBreaker returns a repository failing fast with circuitbreaker.ErrOpen
+// while the database keeps failing. Not found, duplicate and invalid input
+// errors are answers of a working database and do not trip the breaker.
+func WithBreaker(repo Repository, breaker *circuitbreaker.Breaker) Repository {
+	return &breakerRepository{repo: repo, breaker: breaker}
+}
+
+// dependencyError returns err unless it is an answer of a working database
+func dependencyError(err error) error {
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrDuplicateKey) || errors.Is(err, ErrInvalidInput) {
+		return nil
+	}
+	return err
+}
+
+// call runs fn through the breaker
+func call[T any](r *breakerRepository, fn func() (T, error)) (T, error) {
+	var zero T
+	if err := r.breaker.Allow(); err != nil {
+		return zero, err
+	}
+	v, err := fn()
+	r.breaker.Done(dependencyError(err))
+	return v, err
+}
+
+// exec runs fn through the breaker
+func (r *breakerRepository) exec(fn func() error) error {
+	if err := r.breaker.Allow(); err != nil {
+		return err
+	}
+	err := fn()
+	r.breaker.Done(dependencyError(err))
+	return err
+}
+
+func (r *breakerRepository) SaveTrade(ctx context.Context, trade *models.Trade) error {
+	return r.exec(func() error { return r.repo.SaveTrade(ctx, trade) })
+}
+
+func (r *breakerRepository) GetTradeByID(ctx context.Context, id string) (*models.Trade, error) {
+	return call(r, func() (*models.Trade, error) { return r.repo.GetTradeByID(ctx, id) })
+}
+
+func (r *breakerRepository) ListTrades(ctx context.Context, filter *models.TradeFilter) ([]*models.Trade, error) {
+	return call(r, func() ([]*models.Trade, error) { return r.repo.ListTrades(ctx, filter) })
+}
+
+func (r *breakerRepository) UpdateTradeStatus(ctx context.Context, id string, status models.TradeStatus) error {
+	return r.exec(func() error { return r.repo.UpdateTradeStatus(ctx, id, status) })
+}
+
+func (r *breakerRepository) UpdateTrade(ctx context.Context, trade *models.Trade) error {
+	return r.exec(func() error { return r.repo.UpdateTrade(ctx, trade) })
+}
+
+func (r *breakerRepository) GetTradeStats(ctx context.Context, filter *models.TradeFilter) (*models.TradeStats, error) {
+	return call(r, func() (*models.TradeStats, error) { return r.repo.GetTradeStats(ctx, filter) })
+}
+
+func (r *breakerRepository) SavePosition(ctx context.Context, position *models.Position) error {
+	return r.exec(func() error { return r.repo.SavePosition(ctx, position) })
+}
+
+func (r *breakerRepository) GetPositionByID(ctx context.Context, id string) (*models.Position, error) {
+	return call(r, func() (*models.Position, error) { return r.repo.GetPositionByID(ctx, id) })
+}
+
+func (r *breakerRepository) ListPositions(ctx context.Context, filter *models.PositionFilter) ([]*models.Position, error) {
+	return call(r, func() ([]*models.Position, error) { return r.repo.ListPositions(ctx, filter) })
+}
+
+func (r *breakerRepository) UpdatePosition(ctx context.Context, position *models.Position) error {
+	return r.exec(func() error { return r.repo.UpdatePosition(ctx, position) })
+}
+
+func (r *breakerRepository) GetOpenPositions(ctx context.Context) ([]*models.Position, error) {
+	return call(r, func() ([]*models.Position, error) { return r.repo.GetOpenPositions(ctx) })
+}
+
+func (r *breakerRepository) ClosePosition(ctx context.Context, id string, closePrice float64) error {
+	return r.exec(func() error { return r.repo.ClosePosition(ctx, id, closePrice) })
+}
+
+func (r *breakerRepository) GetPositionStats(ctx context.Context, filter *models.PositionFilter) (*models.PositionStats, error) {
+	return call(r, func() (*models.PositionStats, error) { return r.repo.GetPositionStats(ctx, filter) })
+}
+
+func (r *breakerRepository) SaveTradeAndUpdatePosition(ctx context.Context, trade *models.Trade, position *models.Position) error {
+	return r.exec(func() error { return r.repo.SaveTradeAndUpdatePosition(ctx, trade, position) })
+}
+
+func (r *breakerRepository) SaveMarketData(ctx context.Context, data *models.MarketData) error {
+	return r.exec(func() error { return r.repo.SaveMarketData(ctx, data) })
+}
+
+func (r *breakerRepository) UpsertMarketData(ctx context.Context, data *models.MarketData) error {
+	return r.exec(func() error { return r.repo.UpsertMarketData(ctx, data) })
+}
+
+func (r *breakerRepository) SaveMarketDataBatch(ctx context.Context, data []*models.MarketData) error {
+	return r.exec(func() error { return r.repo.SaveMarketDataBatch(ctx, data) })
+}
+
+func (r *breakerRepository) SaveKlinesBatch(ctx context.Context, klines []*models.Kline) error {
+	return r.exec(func() error { return r.repo.SaveKlinesBatch(ctx, klines) })
+}
+
+func (r *breakerRepository) GetLatestMarketData(ctx context.Context, tokenAddress string) (*models.MarketData, error) {
+	return call(r, func() (*models.MarketData, error) { return r.repo.GetLatestMarketData(ctx, tokenAddress) })
+}
+
+func (r *breakerRepository) GetHistoricalMarketData(ctx context.Context, tokenAddress string, limit int) ([]*models.MarketData, error) {
+	return call(r, func() ([]*models.MarketData, error) { return r.repo.GetHistoricalMarketData(ctx, tokenAddress, limit) })
+}
+
+func (r *breakerRepository) GetMarketStats(ctx context.Context, tokenAddress string) (*models.MarketStats, error) {
+	return call(r, func() (*models.MarketStats, error) { return r.repo.GetMarketStats(ctx, tokenAddress) })
+}
+
+func (r *breakerRepository) GetTechnicalIndicators(ctx context.Context, tokenAddress string) (*models.TechnicalIndicators, error) {
+	return call(r, func() (*models.TechnicalIndicators, error) { return r.repo.GetTechnicalIndicators(ctx, tokenAddress) })
+}
+
+func (r *breakerRepository) SaveAnalysisResult(ctx context.Context, result *models.AnalysisResult) error {
+	return r.exec(func() error { return r.repo.SaveAnalysisResult(ctx, result) })
+}
+
+func (r *breakerRepository) GetLatestAnalysis(ctx context.Context, tokenAddress string) (*models.AnalysisResult, error) {
+	return call(r, func() (*models.AnalysisResult, error) { return r.repo.GetLatestAnalysis(ctx, tokenAddress) })
+}
+
+func (r *breakerRepository) SaveDailyStats(ctx context.Context, stats *models.DailyStats) error {
+	return r.exec(func() error { return r.repo.SaveDailyStats(ctx, stats) })
+}
+
+func (r *breakerRepository) GetDailyStats(ctx context.Context, date time.Time) (*models.DailyStats, error) {
+	return call(r, func() (*models.DailyStats, error) { return r.repo.GetDailyStats(ctx, date) })
+}
+
+func (r *breakerRepository) GetDailyStatsRange(ctx context.Context, startDate, endDate time.Time) ([]*models.DailyStats, error) {
+	return call(r, func() ([]*models.DailyStats, error) { return r.repo.GetDailyStatsRange(ctx, startDate, endDate) })
+}
+
+func (r *breakerRepository) ReserveSignalFingerprint(ctx context.Context, fingerprint *models.SignalFingerprint) error {
+	return r.exec(func() error { return r.repo.ReserveSignalFingerprint(ctx, fingerprint) })
+}
+
+func (r *breakerRepository) ReleaseSignalFingerprint(ctx context.Context, id string) error {
+	return r.exec(func() error { return r.repo.ReleaseSignalFingerprint(ctx, id) })
+}
+
+func (r *breakerRepository) SaveReconciliationRecord(ctx context.Context, record *models.ReconciliationRecord) error {
+	return r.exec(func() error { return r.repo.SaveReconciliationRecord(ctx, record) })
+}
+
+func (r *breakerRepository) ListReconciliationRecords(ctx context.Context, filter *models.ReconciliationFilter) ([]*models.ReconciliationRecord, error) {
+	return call(r, func() ([]*models.ReconciliationRecord, error) { return r.repo.ListReconciliationRecords(ctx, filter) })
+}
+
+func (r *breakerRepository) SaveWatchlistEntry(ctx context.Context, entry *models.WatchlistEntry) error {
+	return r.exec(func() error { return r.repo.SaveWatchlistEntry(ctx, entry) })
+}
+
+func (r *breakerRepository) ListWatchlist(ctx context.Context, filter *models.WatchlistFilter) ([]*models.WatchlistEntry, error) {
+	return call(r, func() ([]*models.WatchlistEntry, error) { return r.repo.ListWatchlist(ctx, filter) })
+}
+
+func (r *breakerRepository) DownsampleKlines(ctx context.Context, target string, before time.Time) (int64, error) {
+	return call(r, func() (int64, error) { return r.repo.DownsampleKlines(ctx, target, before) })
+}
+
+func (r *breakerRepository) DeleteKlinesBefore(ctx context.Context, interval string, before time.Time) (int64, error) {
+	return call(r, func() (int64, error) { return r.repo.DeleteKlinesBefore(ctx, interval, before) })
+}
+
+func (r *breakerRepository) DeleteMarketDataBefore(ctx context.Context, before time.Time) (int64, error) {
+	return call(r, func() (int64, error) { return r.repo.DeleteMarketDataBefore(ctx, before) })
+}
+
+func (r *breakerRepository) SaveEventAggregates(ctx context.Context, aggregates []*models.EventAggregate) error {
+	return r.exec(func() error { return r.repo.SaveEventAggregates(ctx, aggregates) })
+}
+
+func (r *breakerRepository) SaveExecutionAggregates(ctx context.Context, aggregates []*models.ExecutionAggregate) error {
+	return r.exec(func() error { return r.repo.SaveExecutionAggregates(ctx, aggregates) })
+}
+
+func (r *breakerRepository) ListExecutionAggregates(ctx context.Context, from, to time.Time) ([]*models.ExecutionAggregate, error) {
+	return call(r, func() ([]*models.ExecutionAggregate, error) { return r.repo.ListExecutionAggregates(ctx, from, to) })
+}
+
+func (r *breakerRepository) SaveAnalysisReport(ctx context.Context, report *models.AnalysisReport) error {
+	return r.exec(func() error { return r.repo.SaveAnalysisReport(ctx, report) })
+}
+
+func (r *breakerRepository) GetLatestReport(ctx context.Context, tokenAddress, timeframe string) (*models.AnalysisReport, error) {
+	return call(r, func() (*models.AnalysisReport, error) { return r.repo.GetLatestReport(ctx, tokenAddress, timeframe) })
+}
+
+func (r *breakerRepository) GetReportHistory(ctx context.Context, tokenAddress, timeframe string, limit int) ([]*models.AnalysisReport, error) {
+	return call(r, func() ([]*models.AnalysisReport, error) {
+		return r.repo.GetReportHistory(ctx, tokenAddress, timeframe, limit)
+	})
+}
+
+// Ping bypasses the breaker so health checks see the database itself
+func (r *breakerRepository) Ping(ctx context.Context) error {
+	return r.repo.Ping(ctx)
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IntervalDuration parses a kline interval such as 1m, 4h, 1d or 1w
+func IntervalDuration(interval string) (time.Duration, bool) {
+	var unit time.Duration
+	switch {
+	case strings.HasSuffix(interval, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(interval, "w"):
+		unit = 7 * 24 * time.Hour
+	default:
+		d, err := time.ParseDuration(interval)
+		return d, err == nil && d > 0
+	}
+
+	n, err := strconv.Atoi(interval[:len(interval)-1])
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	return time.Duration(n) * unit, true
+}
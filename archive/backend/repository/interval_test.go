@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntervalDuration(t *testing.T) {
+	for interval, want := range map[string]time.Duration{
+		"1m":  time.Minute,
+		"15m": 15 * time.Minute,
+		"4h":  4 * time.Hour,
+		"1d":  24 * time.Hour,
+		"1w":  7 * 24 * time.Hour,
+	} {
+		got, ok := IntervalDuration(interval)
+		assert.True(t, ok, interval)
+		assert.Equal(t, want, got, interval)
+	}
+
+	for _, interval := range []string{"", "d", "0m", "-1h", "xd", "1y"} {
+		_, ok := IntervalDuration(interval)
+		assert.False(t, ok, interval)
+	}
+}
@@ -0,0 +1,49 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/leonzhao/trading-system/backend/models"
+)
+
+// SaveExecutionAggregates adds fills to the stored daily execution
+// aggregates
+func (r *MongoRepository) SaveExecutionAggregates(ctx context.Context, aggregates []*models.ExecutionAggregate) error {
+	writes := make([]mongo.WriteModel, 0, len(aggregates))
+	for _, a := range aggregates {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"day": a.Day, "dimension": a.Dimension, "bucket": a.Bucket}).
+			SetUpdate(bson.M{"$inc": bson.M{
+				"count":                 a.Count,
+				"adverse":               a.Adverse,
+				"slippage_bps_sum":      a.SlippageBpsSum,
+				"slippage_bps_squares":  a.SlippageBpsSquares,
+				"notional":              a.Notional,
+				"weighted_slippage_sum": a.WeightedSlippageSum,
+			}}).
+			SetUpsert(true))
+	}
+	return bulkWrite(ctx, r.executions, writes)
+}
+
+// ListExecutionAggregates lists the execution aggregates of the days from
+// from until before to, oldest first
+func (r *MongoRepository) ListExecutionAggregates(ctx context.Context, from, to time.Time) ([]*models.ExecutionAggregate, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "day", Value: 1}})
+	cursor, err := r.executions.Find(ctx, bson.M{"day": bson.M{"$gte": from, "$lt": to}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var aggregates []*models.ExecutionAggregate
+	if err := cursor.All(ctx, &aggregates); err != nil {
+		return nil, err
+	}
+	return aggregates, nil
+}
@@ -0,0 +1,76 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ensureIndexes creates the indexes backing the repository queries. Creating
+// an index that already exists is a no-op, so this runs on every startup.
+// Lookups by id use the default _id index.
+func (r *MongoRepository) ensureIndexes(ctx context.Context) error {
+	indexes := []struct {
+		collection *mongo.Collection
+		models     []mongo.IndexModel
+	}{
+		{r.trades, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "token_address", Value: 1}, {Key: "timestamp", Value: -1}}},
+			{Keys: bson.D{{Key: "status", Value: 1}, {Key: "timestamp", Value: -1}}},
+			{Keys: bson.D{{Key: "timestamp", Value: -1}}},
+		}},
+		{r.positions, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "status", Value: 1}}},
+			{Keys: bson.D{{Key: "token_address", Value: 1}, {Key: "open_time", Value: -1}}},
+		}},
+		{r.marketData, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "token_address", Value: 1}, {Key: "timestamp", Value: -1}}},
+			{Keys: bson.D{{Key: "token_address", Value: 1}, {Key: "interval", Value: 1}}},
+		}},
+		{r.klines, []mongo.IndexModel{
+			{
+				Keys:    bson.D{{Key: "symbol", Value: 1}, {Key: "interval", Value: 1}, {Key: "timestamp", Value: -1}},
+				Options: options.Index().SetUnique(true),
+			},
+		}},
+		{r.dailyStats, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "date", Value: 1}}, Options: options.Index().SetUnique(true)},
+		}},
+		{r.events, []mongo.IndexModel{
+			{
+				Keys:    bson.D{{Key: "hour", Value: -1}, {Key: "type", Value: 1}, {Key: "severity", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+		}},
+		{r.executions, []mongo.IndexModel{
+			{
+				Keys:    bson.D{{Key: "day", Value: -1}, {Key: "dimension", Value: 1}, {Key: "bucket", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			},
+		}},
+		{r.analysis, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "token_address", Value: 1}, {Key: "timestamp", Value: -1}}},
+		}},
+		{r.reports, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "token_address", Value: 1}, {Key: "timeframe", Value: 1}, {Key: "timestamp", Value: -1}}},
+		}},
+		{r.reconciled, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "token_address", Value: 1}, {Key: "created_at", Value: -1}}},
+			{Keys: bson.D{{Key: "created_at", Value: -1}}},
+		}},
+		{r.watchlist, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "status", Value: 1}, {Key: "discovered_at", Value: -1}}},
+		}},
+	}
+
+	for _, index := range indexes {
+		if _, err := index.collection.Indexes().CreateMany(ctx, index.models); err != nil {
+			return fmt.Errorf("failed to create %s indexes: %w", index.collection.Name(), err)
+		}
+	}
+
+	return r.ensureSignalIndexes(ctx)
+}
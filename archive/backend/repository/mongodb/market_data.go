@@ -0,0 +1,73 @@
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/leonzhao/trading-system/backend/models"
+)
+
+// UpsertMarketData stores the latest market data of a token and interval,
+// replacing the previous document instead of adding one per tick
+func (r *MongoRepository) UpsertMarketData(ctx context.Context, data *models.MarketData) error {
+	if r.shed(1) {
+		return nil
+	}
+
+	_, err := r.marketData.UpdateOne(ctx, marketDataKey(data), bson.M{"$set": data}, options.Update().SetUpsert(true))
+	return err
+}
+
+// SaveMarketDataBatch upserts the market data of many tokens in one
+// unordered bulk write
+func (r *MongoRepository) SaveMarketDataBatch(ctx context.Context, data []*models.MarketData) error {
+	if r.shed(len(data)) {
+		return nil
+	}
+
+	writes := make([]mongo.WriteModel, 0, len(data))
+	for _, d := range data {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(marketDataKey(d)).
+			SetUpdate(bson.M{"$set": d}).
+			SetUpsert(true))
+	}
+	return bulkWrite(ctx, r.marketData, writes)
+}
+
+// SaveKlinesBatch stores candles in one unordered bulk write. A candle that
+// is polled again replaces the stored one for its symbol, interval and
+// open time.
+func (r *MongoRepository) SaveKlinesBatch(ctx context.Context, klines []*models.Kline) error {
+	if r.shed(len(klines)) {
+		return nil
+	}
+
+	writes := make([]mongo.WriteModel, 0, len(klines))
+	for _, k := range klines {
+		writes = append(writes, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"symbol": k.Symbol, "interval": k.Interval, "timestamp": k.Timestamp}).
+			SetReplacement(k).
+			SetUpsert(true))
+	}
+	return bulkWrite(ctx, r.klines, writes)
+}
+
+func marketDataKey(data *models.MarketData) bson.M {
+	return bson.M{"token_address": data.TokenAddress, "interval": data.Interval}
+}
+
+// bulkWrite applies writes unordered, so the server can spread them and one
+// failed write does not stop the rest of the batch. The returned
+// mongo.BulkWriteException lists the writes that failed.
+func bulkWrite(ctx context.Context, collection *mongo.Collection, writes []mongo.WriteModel) error {
+	if len(writes) == 0 {
+		return nil
+	}
+
+	_, err := collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
@@ -0,0 +1,78 @@
+package mongodb
+
+import (
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/repository"
+)
+
+// sortFields maps the sortable JSON field names of a collection to document
+// fields
+type sortFields map[string]string
+
+var (
+	tradeSortFields = sortFields{
+		"timestamp":  "timestamp",
+		"updateTime": "update_time",
+		"amount":     "amount",
+		"value":      "value",
+		"profit":     "profit",
+	}
+	positionSortFields = sortFields{
+		"openTime":      "open_time",
+		"lastUpdated":   "last_updated",
+		"value":         "value",
+		"unrealizedPnL": "unrealized_pnl",
+		"realizedPnL":   "realized_pnl",
+	}
+	reconciliationSortFields = sortFields{
+		"createdAt":  "created_at",
+		"difference": "difference",
+	}
+	watchlistSortFields = sortFields{
+		"discoveredAt": "discovered_at",
+		"updatedAt":    "updated_at",
+	}
+)
+
+// findOptions builds the sort, skip and limit of a list query. Results are
+// sorted on defaultField, newest first, unless the query options say
+// otherwise, with _id as a tie breaker so pages do not overlap.
+func findOptions(query models.QueryOptions, fields sortFields, defaultField string) (*options.FindOptions, error) {
+	if query.Limit < 0 || query.Offset < 0 {
+		return nil, fmt.Errorf("%w: limit and offset must not be negative", repository.ErrInvalidInput)
+	}
+
+	field := defaultField
+	if query.SortBy != "" {
+		var ok bool
+		if field, ok = fields[query.SortBy]; !ok {
+			return nil, fmt.Errorf("%w: cannot sort by %q", repository.ErrInvalidInput, query.SortBy)
+		}
+	}
+
+	direction := -1
+	switch query.SortOrder {
+	case "", models.SortDescending:
+	case models.SortAscending:
+		direction = 1
+	default:
+		return nil, fmt.Errorf("%w: unknown sort order %q", repository.ErrInvalidInput, query.SortOrder)
+	}
+
+	opts := options.Find().SetSort(bson.D{
+		{Key: field, Value: direction},
+		{Key: "_id", Value: direction},
+	})
+	if query.Offset > 0 {
+		opts.SetSkip(int64(query.Offset))
+	}
+	if query.Limit > 0 {
+		opts.SetLimit(int64(query.Limit))
+	}
+	return opts, nil
+}
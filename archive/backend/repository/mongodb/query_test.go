@@ -0,0 +1,47 @@
+package mongodb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/repository"
+)
+
+func TestFindOptions(t *testing.T) {
+	t.Run("defaults to newest first without paging", func(t *testing.T) {
+		opts, err := findOptions(models.QueryOptions{}, tradeSortFields, "timestamp")
+		require.NoError(t, err)
+		assert.Equal(t, bson.D{{Key: "timestamp", Value: -1}, {Key: "_id", Value: -1}}, opts.Sort)
+		assert.Nil(t, opts.Limit)
+		assert.Nil(t, opts.Skip)
+	})
+
+	t.Run("applies paging and sort", func(t *testing.T) {
+		opts, err := findOptions(models.QueryOptions{
+			Limit:     50,
+			Offset:    100,
+			SortBy:    "amount",
+			SortOrder: models.SortAscending,
+		}, tradeSortFields, "timestamp")
+		require.NoError(t, err)
+		assert.Equal(t, bson.D{{Key: "amount", Value: 1}, {Key: "_id", Value: 1}}, opts.Sort)
+		assert.Equal(t, int64(50), *opts.Limit)
+		assert.Equal(t, int64(100), *opts.Skip)
+	})
+
+	t.Run("rejects invalid options", func(t *testing.T) {
+		for _, query := range []models.QueryOptions{
+			{Limit: -1},
+			{Offset: -1},
+			{SortBy: "token_address"},
+			{SortOrder: "sideways"},
+		} {
+			_, err := findOptions(query, tradeSortFields, "timestamp")
+			assert.ErrorIs(t, err, repository.ErrInvalidInput)
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/leonzhao/trading-system/backend/models"
+)
+
+// SaveReconciliationRecord appends a position reconciliation audit record
+func (r *MongoRepository) SaveReconciliationRecord(ctx context.Context, record *models.ReconciliationRecord) error {
+	if record.ID == "" {
+		record.ID = primitive.NewObjectID().Hex()
+	}
+
+	_, err := r.reconciled.InsertOne(ctx, record)
+	return err
+}
+
+// ListReconciliationRecords lists reconciliation audit records, newest first
+func (r *MongoRepository) ListReconciliationRecords(ctx context.Context, filter *models.ReconciliationFilter) ([]*models.ReconciliationRecord, error) {
+	query := bson.M{}
+	if filter.TokenAddress != "" {
+		query["token_address"] = filter.TokenAddress
+	}
+	if filter.Action != "" {
+		query["action"] = filter.Action
+	}
+	if filter.StartTime != nil || filter.EndTime != nil {
+		createdAt := bson.M{}
+		if filter.StartTime != nil {
+			createdAt["$gte"] = filter.StartTime
+		}
+		if filter.EndTime != nil {
+			createdAt["$lte"] = filter.EndTime
+		}
+		query["created_at"] = createdAt
+	}
+
+	opts, err := findOptions(filter.QueryOptions, reconciliationSortFields, "created_at")
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := r.reconciled.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var records []*models.ReconciliationRecord
+	if err = cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
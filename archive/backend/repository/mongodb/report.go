@@ -0,0 +1,58 @@
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/repository"
+)
+
+// SaveAnalysisReport appends a market analysis report to the history of its
+// token and timeframe
+func (r *MongoRepository) SaveAnalysisReport(ctx context.Context, report *models.AnalysisReport) error {
+	if r.shed(1) {
+		return nil
+	}
+	if report.ID == "" {
+		report.ID = primitive.NewObjectID().Hex()
+	}
+	_, err := r.reports.InsertOne(ctx, report)
+	return err
+}
+
+// GetLatestReport retrieves the latest analysis report of a token over a
+// timeframe
+func (r *MongoRepository) GetLatestReport(ctx context.Context, tokenAddress, timeframe string) (*models.AnalysisReport, error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: "timestamp", Value: -1}})
+	var report models.AnalysisReport
+	err := r.reports.FindOne(ctx, bson.M{"token_address": tokenAddress, "timeframe": timeframe}, opts).Decode(&report)
+	if err == mongo.ErrNoDocuments {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// GetReportHistory lists up to limit analysis reports of a token over a
+// timeframe, newest first
+func (r *MongoRepository) GetReportHistory(ctx context.Context, tokenAddress, timeframe string, limit int) ([]*models.AnalysisReport, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := r.reports.Find(ctx, bson.M{"token_address": tokenAddress, "timeframe": timeframe}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var reports []*models.AnalysisReport
+	if err := cursor.All(ctx, &reports); err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
@@ -2,6 +2,8 @@ package mongodb
 
 import (
 	"context"
+	"log"
+	"sync/atomic"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -22,6 +24,21 @@ type MongoRepository struct {
 	marketData *mongo.Collection
 	dailyStats *mongo.Collection
 	analysis   *mongo.Collection
+	signals    *mongo.Collection
+	reconciled *mongo.Collection
+	watchlist  *mongo.Collection
+	outbox     *mongo.Collection
+	klines     *mongo.Collection
+	events     *mongo.Collection
+	executions *mongo.Collection
+	reports    *mongo.Collection
+	// transactions is false on standalone servers, where trade and
+	// position writes go through the outbox
+	transactions bool
+	// degraded sheds non-essential writes while MongoDB is slow
+	degraded atomic.Bool
+	// dropped counts the documents not written since degraded mode started
+	dropped atomic.Int64
 }
 
 // NewRepository creates a new MongoDB repository
@@ -53,6 +70,25 @@ func NewRepository(ctx context.Context, opts repository.Options) (repository.Rep
 		marketData: client.Database(opts.Database).Collection("market_data"),
 		dailyStats: client.Database(opts.Database).Collection("daily_stats"),
 		analysis:   client.Database(opts.Database).Collection("analysis"),
+		signals:    client.Database(opts.Database).Collection("signal_fingerprints"),
+		reconciled: client.Database(opts.Database).Collection("position_reconciliations"),
+		watchlist:  client.Database(opts.Database).Collection("watchlist"),
+		outbox:     client.Database(opts.Database).Collection("trade_outbox"),
+		klines:     client.Database(opts.Database).Collection("klines"),
+		events:     client.Database(opts.Database).Collection("event_aggregates"),
+		executions: client.Database(opts.Database).Collection("execution_aggregates"),
+		reports:    client.Database(opts.Database).Collection("analysis_reports"),
+	}
+
+	if err := repo.ensureIndexes(ctx); err != nil {
+		return nil, err
+	}
+
+	if repo.transactions, err = supportsTransactions(ctx, client); err != nil {
+		return nil, err
+	}
+	if _, err := repo.ReplayOutbox(ctx); err != nil {
+		return nil, err
 	}
 
 	return repo, nil
@@ -117,14 +153,47 @@ func (r *MongoRepository) GetDailyStatsRange(ctx context.Context, startDate, end
 	return stats, nil
 }
 
-// SaveMarketData saves market data
-func (r *MongoRepository) SaveMarketData(ctx context.Context, data *models.MarketData) error {
-	filter := bson.M{"token_address": data.TokenAddress}
-	update := bson.M{"$set": data}
-	opts := options.Update().SetUpsert(true)
+// SetDegraded switches degraded mode on or off. In degraded mode market data
+// and analysis results are not persisted, keeping MongoDB capacity for
+// trades and positions. Switching back logs the number of documents
+// dropped.
+func (r *MongoRepository) SetDegraded(degraded bool) {
+	if r.degraded.Swap(degraded) == degraded {
+		return
+	}
+	if degraded {
+		r.dropped.Store(0)
+		log.Printf("Repository degraded, not persisting market data and analysis results")
+		return
+	}
+	log.Printf("Repository recovered, %d market data and analysis documents were not persisted", r.dropped.Load())
+}
 
-	_, err := r.marketData.UpdateOne(ctx, filter, update, opts)
-	return err
+// Degraded reports whether the repository is in degraded mode
+func (r *MongoRepository) Degraded() bool {
+	return r.degraded.Load()
+}
+
+// DroppedWrites returns the number of documents not persisted since degraded
+// mode was last switched on
+func (r *MongoRepository) DroppedWrites() int64 {
+	return r.dropped.Load()
+}
+
+// shed reports whether a non-essential write of n documents is dropped,
+// counting them if so
+func (r *MongoRepository) shed(n int) bool {
+	if !r.degraded.Load() {
+		return false
+	}
+	r.dropped.Add(int64(n))
+	return true
+}
+
+// SaveMarketData saves market data, keeping one document per token and
+// interval
+func (r *MongoRepository) SaveMarketData(ctx context.Context, data *models.MarketData) error {
+	return r.UpsertMarketData(ctx, data)
 }
 
 // GetLatestMarketData retrieves the latest market data for a token
@@ -185,6 +254,9 @@ func (r *MongoRepository) GetTechnicalIndicators(ctx context.Context, tokenAddre
 
 // SaveAnalysisResult saves market analysis results
 func (r *MongoRepository) SaveAnalysisResult(ctx context.Context, result *models.AnalysisResult) error {
+	if r.shed(1) {
+		return nil
+	}
 	filter := bson.M{"token_address": result.TokenAddress}
 	update := bson.M{"$set": result}
 	opts := options.Update().SetUpsert(true)
@@ -207,33 +279,23 @@ func (r *MongoRepository) GetLatestAnalysis(ctx context.Context, tokenAddress st
 
 // ClosePosition closes a position with the given ID and close price
 func (r *MongoRepository) ClosePosition(ctx context.Context, id string, closePrice float64) error {
-	objectID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return err
-	}
-
 	update := bson.M{
 		"$set": bson.M{
 			"status":        repository.PositionStatusClosed,
 			"current_price": closePrice,
-			"close_time":   time.Now(),
-			"last_updated": time.Now(),
+			"close_time":    time.Now(),
+			"last_updated":  time.Now(),
 		},
 	}
 
-	_, err = r.positions.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	_, err := r.positions.UpdateOne(ctx, bson.M{"_id": id}, update)
 	return err
 }
 
 // UpdateTrade updates a trade in the database
 func (r *MongoRepository) UpdateTrade(ctx context.Context, trade *models.Trade) error {
-	objectID, err := primitive.ObjectIDFromHex(trade.ID)
-	if err != nil {
-		return err
-	}
-
 	trade.UpdateTime = time.Now()
-	_, err = r.trades.ReplaceOne(ctx, bson.M{"_id": objectID}, trade)
+	_, err := r.trades.ReplaceOne(ctx, bson.M{"_id": trade.ID}, trade)
 	return err
 }
 
@@ -243,20 +305,18 @@ func (r *MongoRepository) SavePosition(ctx context.Context, position *models.Pos
 		position.ID = primitive.NewObjectID().Hex()
 	}
 	position.LastUpdated = time.Now()
-	
+
 	_, err := r.positions.InsertOne(ctx, position)
 	return err
 }
 
 // GetPositionByID retrieves a position by ID
 func (r *MongoRepository) GetPositionByID(ctx context.Context, id string) (*models.Position, error) {
-	objectID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return nil, err
-	}
-
 	var position models.Position
-	err = r.positions.FindOne(ctx, bson.M{"_id": objectID}).Decode(&position)
+	err := r.positions.FindOne(ctx, bson.M{"_id": id}).Decode(&position)
+	if err == mongo.ErrNoDocuments {
+		return nil, repository.ErrNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -287,7 +347,12 @@ func (r *MongoRepository) ListPositions(ctx context.Context, filter *models.Posi
 		}
 	}
 
-	cursor, err := r.positions.Find(ctx, query)
+	opts, err := findOptions(filter.QueryOptions, positionSortFields, "open_time")
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := r.positions.Find(ctx, query, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -310,13 +375,8 @@ func (r *MongoRepository) GetOpenPositions(ctx context.Context) ([]*models.Posit
 
 // UpdatePosition updates a position in the database
 func (r *MongoRepository) UpdatePosition(ctx context.Context, position *models.Position) error {
-	objectID, err := primitive.ObjectIDFromHex(position.ID)
-	if err != nil {
-		return err
-	}
-
 	position.LastUpdated = time.Now()
-	_, err = r.positions.ReplaceOne(ctx, bson.M{"_id": objectID}, position)
+	_, err := r.positions.ReplaceOne(ctx, bson.M{"_id": position.ID}, position)
 	return err
 }
 
@@ -0,0 +1,62 @@
+package mongodb
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/repository"
+	"github.com/leonzhao/trading-system/backend/repository/repositorytest"
+)
+
+// TestMongoRepository runs against the server in MONGODB_TEST_URI, in a
+// new database per test
+func TestMongoRepository(t *testing.T) {
+	uri := os.Getenv("MONGODB_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGODB_TEST_URI not set")
+	}
+
+	repositorytest.Run(t, func(t *testing.T) repository.Repository {
+		ctx := context.Background()
+		opts := repository.DefaultOptions()
+		opts.URI = uri
+		opts.Database = "gosol_test_" + strings.ToLower(primitive.NewObjectID().Hex())
+
+		repo, err := NewRepository(ctx, opts)
+		require.NoError(t, err)
+		client := repo.(*MongoRepository).client
+		t.Cleanup(func() {
+			client.Database(opts.Database).Drop(ctx)
+			client.Disconnect(ctx)
+		})
+		return repo
+	})
+}
+
+func TestDegradedModeCountsDroppedWrites(t *testing.T) {
+	ctx := context.Background()
+	// Without collections, any write that is not dropped would panic
+	repo := &MongoRepository{}
+	repo.SetDegraded(true)
+
+	require.NoError(t, repo.SaveMarketData(ctx, &models.MarketData{TokenAddress: "SOL"}))
+	require.NoError(t, repo.SaveMarketDataBatch(ctx, []*models.MarketData{{TokenAddress: "SOL"}, {TokenAddress: "BONK"}}))
+	require.NoError(t, repo.SaveAnalysisResult(ctx, &models.AnalysisResult{TokenAddress: "SOL"}))
+	require.NoError(t, repo.SaveAnalysisReport(ctx, &models.AnalysisReport{TokenAddress: "SOL"}))
+	assert.Equal(t, int64(5), repo.DroppedWrites())
+
+	repo.SetDegraded(false)
+	assert.False(t, repo.Degraded())
+	assert.Equal(t, int64(5), repo.DroppedWrites())
+
+	// The count restarts with the next degraded period
+	repo.SetDegraded(true)
+	assert.Zero(t, repo.DroppedWrites())
+}
@@ -0,0 +1,154 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/repository"
+)
+
+// downsampleUnits are the $dateTrunc units of the intervals klines can be
+// downsampled into
+var downsampleUnits = map[string]string{
+	"1h": "hour",
+	"1d": "day",
+}
+
+// DownsampleKlines aggregates the klines finer than the target interval,
+// "1h" or "1d", that open before a time into target candles, then deletes
+// them. before is rounded down to a target boundary so no candle is built
+// from part of its period. It returns the number of klines deleted.
+// Requires MongoDB 5.0 or later.
+func (r *MongoRepository) DownsampleKlines(ctx context.Context, target string, before time.Time) (int64, error) {
+	unit, ok := downsampleUnits[target]
+	if !ok {
+		return 0, fmt.Errorf("%w: cannot downsample klines to %q", repository.ErrInvalidInput, target)
+	}
+	targetDuration, _ := repository.IntervalDuration(target)
+	before = before.UTC().Truncate(targetDuration)
+
+	values, err := r.klines.Distinct(ctx, "interval", bson.M{"timestamp": bson.M{"$lt": before}})
+	if err != nil {
+		return 0, err
+	}
+	durations := make(map[string]time.Duration)
+	var source []string
+	for _, v := range values {
+		interval, _ := v.(string)
+		if d, ok := repository.IntervalDuration(interval); ok && d < targetDuration {
+			durations[interval] = d
+			source = append(source, interval)
+		}
+	}
+	if len(source) == 0 {
+		return 0, nil
+	}
+
+	// Aggregate coarse intervals first, so that where a symbol has candles
+	// of several intervals the candle built from the finest one wins
+	sort.Slice(source, func(i, j int) bool {
+		return durations[source[i]] > durations[source[j]]
+	})
+	for _, interval := range source {
+		if err := r.aggregateKlines(ctx, interval, target, unit, before); err != nil {
+			return 0, fmt.Errorf("failed to downsample %s klines: %w", interval, err)
+		}
+	}
+
+	result, err := r.klines.DeleteMany(ctx, bson.M{
+		"interval":  bson.M{"$in": source},
+		"timestamp": bson.M{"$lt": before},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// aggregateKlines merges the klines of one interval into candles of the
+// target interval. Re-running it rebuilds the same candles, so a run
+// interrupted before the source klines are deleted is safe to repeat.
+func (r *MongoRepository) aggregateKlines(ctx context.Context, interval, target, unit string, before time.Time) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"interval":  interval,
+			"timestamp": bson.M{"$lt": before},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "timestamp", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"symbol":    "$symbol",
+				"timestamp": bson.M{"$dateTrunc": bson.M{"date": "$timestamp", "unit": unit}},
+			},
+			"open":   bson.M{"$first": "$open"},
+			"high":   bson.M{"$max": "$high"},
+			"low":    bson.M{"$min": "$low"},
+			"close":  bson.M{"$last": "$close"},
+			"volume": bson.M{"$sum": "$volume"},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":       0,
+			"symbol":    "$_id.symbol",
+			"timestamp": "$_id.timestamp",
+			"interval":  bson.M{"$literal": target},
+			"open":      1,
+			"high":      1,
+			"low":       1,
+			"close":     1,
+			"volume":    1,
+		}}},
+		{{Key: "$merge", Value: bson.M{
+			"into":           r.klines.Name(),
+			"on":             bson.A{"symbol", "interval", "timestamp"},
+			"whenMatched":    "replace",
+			"whenNotMatched": "insert",
+		}}},
+	}
+
+	cursor, err := r.klines.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	return cursor.Close(ctx)
+}
+
+// DeleteKlinesBefore deletes the klines of an interval that open before a
+// time and returns the number deleted
+func (r *MongoRepository) DeleteKlinesBefore(ctx context.Context, interval string, before time.Time) (int64, error) {
+	result, err := r.klines.DeleteMany(ctx, bson.M{
+		"interval":  interval,
+		"timestamp": bson.M{"$lt": before},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// DeleteMarketDataBefore deletes market data last updated before a time,
+// such as that of tokens no longer polled, and returns the number deleted
+func (r *MongoRepository) DeleteMarketDataBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.marketData.DeleteMany(ctx, bson.M{"timestamp": bson.M{"$lt": before}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// SaveEventAggregates adds event counts to the stored hourly aggregates
+func (r *MongoRepository) SaveEventAggregates(ctx context.Context, aggregates []*models.EventAggregate) error {
+	writes := make([]mongo.WriteModel, 0, len(aggregates))
+	for _, a := range aggregates {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"hour": a.Hour, "type": a.Type, "severity": a.Severity}).
+			SetUpdate(bson.M{"$inc": bson.M{"count": a.Count}}).
+			SetUpsert(true))
+	}
+	return bulkWrite(ctx, r.events, writes)
+}
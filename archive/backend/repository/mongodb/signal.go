@@ -0,0 +1,56 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/repository"
+)
+
+// ensureSignalIndexes creates the TTL index that ages out signal fingerprints
+func (r *MongoRepository) ensureSignalIndexes(ctx context.Context) error {
+	_, err := r.signals.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+// ReserveSignalFingerprint stores a signal fingerprint, returning
+// repository.ErrDuplicateKey if an unexpired fingerprint already exists
+func (r *MongoRepository) ReserveSignalFingerprint(ctx context.Context, fingerprint *models.SignalFingerprint) error {
+	_, err := r.signals.InsertOne(ctx, fingerprint)
+	if err == nil {
+		return nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+
+	// The TTL monitor only runs periodically, so an expired fingerprint may
+	// still be present. Take it over if it has expired.
+	result, err := r.signals.ReplaceOne(ctx, bson.M{
+		"_id":        fingerprint.ID,
+		"expires_at": bson.M{"$lte": time.Now()},
+	}, fingerprint)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return repository.ErrDuplicateKey
+	}
+
+	return nil
+}
+
+// ReleaseSignalFingerprint deletes a signal fingerprint, so the signal can
+// be reserved again
+func (r *MongoRepository) ReleaseSignalFingerprint(ctx context.Context, id string) error {
+	_, err := r.signals.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
@@ -6,9 +6,10 @@ import (
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo"
 
 	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/repository"
 )
 
 // SaveTrade saves a trade to the database
@@ -17,20 +18,18 @@ func (r *MongoRepository) SaveTrade(ctx context.Context, trade *models.Trade) er
 		trade.ID = primitive.NewObjectID().Hex()
 	}
 	trade.UpdateTime = time.Now()
-	
+
 	_, err := r.trades.InsertOne(ctx, trade)
 	return err
 }
 
 // GetTradeByID retrieves a trade by ID
 func (r *MongoRepository) GetTradeByID(ctx context.Context, id string) (*models.Trade, error) {
-	objectID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return nil, err
-	}
-
 	var trade models.Trade
-	err = r.trades.FindOne(ctx, bson.M{"_id": objectID}).Decode(&trade)
+	err := r.trades.FindOne(ctx, bson.M{"_id": id}).Decode(&trade)
+	if err == mongo.ErrNoDocuments {
+		return nil, repository.ErrNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -40,6 +39,27 @@ func (r *MongoRepository) GetTradeByID(ctx context.Context, id string) (*models.
 
 // ListTrades lists trades based on filter
 func (r *MongoRepository) ListTrades(ctx context.Context, filter *models.TradeFilter) ([]*models.Trade, error) {
+	opts, err := findOptions(filter.QueryOptions, tradeSortFields, "timestamp")
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := r.trades.Find(ctx, tradeQuery(filter), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var trades []*models.Trade
+	if err = cursor.All(ctx, &trades); err != nil {
+		return nil, err
+	}
+
+	return trades, nil
+}
+
+// tradeQuery builds the query matching a trade filter
+func tradeQuery(filter *models.TradeFilter) bson.M {
 	query := bson.M{}
 	if filter.TokenAddress != "" {
 		query["token_address"] = filter.TokenAddress
@@ -74,29 +94,11 @@ func (r *MongoRepository) ListTrades(ctx context.Context, filter *models.TradeFi
 		}
 	}
 
-	opts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: -1}})
-
-	cursor, err := r.trades.Find(ctx, query, opts)
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(ctx)
-
-	var trades []*models.Trade
-	if err = cursor.All(ctx, &trades); err != nil {
-		return nil, err
-	}
-
-	return trades, nil
+	return query
 }
 
 // UpdateTradeStatus updates the status of a trade
 func (r *MongoRepository) UpdateTradeStatus(ctx context.Context, id string, status models.TradeStatus) error {
-	objectID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return err
-	}
-
 	update := bson.M{
 		"$set": bson.M{
 			"status":      status,
@@ -104,34 +106,59 @@ func (r *MongoRepository) UpdateTradeStatus(ctx context.Context, id string, stat
 		},
 	}
 
-	_, err = r.trades.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	_, err := r.trades.UpdateOne(ctx, bson.M{"_id": id}, update)
 	return err
 }
 
-// GetTradeStats retrieves trade statistics
+// GetTradeStats retrieves trade statistics. The totals are aggregated by
+// MongoDB, so the matching trades are never loaded; paging options of the
+// filter are ignored.
 func (r *MongoRepository) GetTradeStats(ctx context.Context, filter *models.TradeFilter) (*models.TradeStats, error) {
-	trades, err := r.ListTrades(ctx, filter)
+	executed := bson.M{"$eq": bson.A{"$status", models.TradeExecuted}}
+	failed := bson.M{"$eq": bson.A{"$status", models.TradeFailed}}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: tradeQuery(filter)}},
+		{{Key: "$group", Value: bson.M{
+			"_id":               nil,
+			"total_trades":      bson.M{"$sum": 1},
+			"successful_trades": bson.M{"$sum": bson.M{"$cond": bson.A{executed, 1, 0}}},
+			"failed_trades":     bson.M{"$sum": bson.M{"$cond": bson.A{failed, 1, 0}}},
+			"total_volume":      bson.M{"$sum": "$value"},
+			"total_fees":        bson.M{"$sum": bson.M{"$cond": bson.A{executed, "$fee", 0}}},
+		}}},
+	}
+
+	cursor, err := r.trades.Aggregate(ctx, pipeline)
 	if err != nil {
 		return nil, err
 	}
+	defer cursor.Close(ctx)
+
+	var totals []struct {
+		TotalTrades      int     `bson:"total_trades"`
+		SuccessfulTrades int     `bson:"successful_trades"`
+		FailedTrades     int     `bson:"failed_trades"`
+		TotalVolume      float64 `bson:"total_volume"`
+		TotalFees        float64 `bson:"total_fees"`
+	}
+	if err = cursor.All(ctx, &totals); err != nil {
+		return nil, err
+	}
 
 	stats := &models.TradeStats{
 		LastTradeTime: time.Now(),
 	}
-
-	for _, trade := range trades {
-		stats.TotalTrades++
-		stats.TotalVolume += trade.Value
-
-		switch trade.Status {
-		case models.TradeExecuted:
-			stats.SuccessfulTrades++
-			stats.TotalFees += trade.Fee
-		case models.TradeFailed:
-			stats.FailedTrades++
-		}
+	if len(totals) == 0 {
+		return stats, nil
 	}
 
+	stats.TotalTrades = totals[0].TotalTrades
+	stats.SuccessfulTrades = totals[0].SuccessfulTrades
+	stats.FailedTrades = totals[0].FailedTrades
+	stats.TotalVolume = totals[0].TotalVolume
+	stats.TotalFees = totals[0].TotalFees
+
 	if stats.TotalTrades > 0 {
 		stats.AverageAmount = stats.TotalVolume / float64(stats.TotalTrades)
 		if stats.SuccessfulTrades > 0 {
@@ -0,0 +1,131 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/repository"
+)
+
+// outboxEntry is a trade and position write recorded before it is applied,
+// so a write interrupted by a crash can be completed on startup
+type outboxEntry struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	Trade     *models.Trade      `bson:"trade"`
+	Position  *models.Position   `bson:"position"`
+	CreatedAt time.Time          `bson:"created_at"`
+}
+
+// supportsTransactions reports whether the server is a replica set member or
+// a mongos router. Standalone servers do not support transactions.
+func supportsTransactions(ctx context.Context, client *mongo.Client) (bool, error) {
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	if err != nil {
+		return false, err
+	}
+	return hello.SetName != "" || hello.Msg == "isdbgrid", nil
+}
+
+// SaveTradeAndUpdatePosition persists a trade and the position it mutates
+// atomically, inserting each or replacing it if it already exists. On a
+// replica set or sharded cluster both writes run in one transaction. On a
+// standalone server they are first recorded in an outbox, and an entry left
+// there by a crash is completed by ReplayOutbox on startup. The writes are
+// idempotent, so callers may retry on error.
+func (r *MongoRepository) SaveTradeAndUpdatePosition(ctx context.Context, trade *models.Trade, position *models.Position) error {
+	if trade.ID == "" {
+		trade.ID = primitive.NewObjectID().Hex()
+	}
+	if position.ID == "" {
+		position.ID = primitive.NewObjectID().Hex()
+	}
+	now := time.Now()
+	trade.UpdateTime = now
+	position.LastUpdated = now
+
+	if !r.transactions {
+		return r.saveThroughOutbox(ctx, trade, position)
+	}
+
+	session, err := r.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, r.applyTradeAndPosition(sc, trade, position)
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", repository.ErrTransactionFailed, err)
+	}
+	return nil
+}
+
+// ReplayOutbox applies the outbox entries left by interrupted writes, oldest
+// first, and returns the number applied
+func (r *MongoRepository) ReplayOutbox(ctx context.Context) (int, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := r.outbox.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []*outboxEntry
+	if err = cursor.All(ctx, &entries); err != nil {
+		return 0, err
+	}
+
+	for i, entry := range entries {
+		if err := r.applyTradeAndPosition(ctx, entry.Trade, entry.Position); err != nil {
+			return i, fmt.Errorf("failed to replay outbox entry %s: %w", entry.ID.Hex(), err)
+		}
+		if _, err := r.outbox.DeleteOne(ctx, bson.M{"_id": entry.ID}); err != nil {
+			return i, err
+		}
+	}
+
+	return len(entries), nil
+}
+
+// saveThroughOutbox records the writes in the outbox, applies them and then
+// removes the entry
+func (r *MongoRepository) saveThroughOutbox(ctx context.Context, trade *models.Trade, position *models.Position) error {
+	entry := &outboxEntry{
+		ID:        primitive.NewObjectID(),
+		Trade:     trade,
+		Position:  position,
+		CreatedAt: time.Now(),
+	}
+	if _, err := r.outbox.InsertOne(ctx, entry); err != nil {
+		return err
+	}
+
+	if err := r.applyTradeAndPosition(ctx, trade, position); err != nil {
+		return fmt.Errorf("%w: %v", repository.ErrTransactionFailed, err)
+	}
+
+	_, err := r.outbox.DeleteOne(ctx, bson.M{"_id": entry.ID})
+	return err
+}
+
+func (r *MongoRepository) applyTradeAndPosition(ctx context.Context, trade *models.Trade, position *models.Position) error {
+	opts := options.Replace().SetUpsert(true)
+	if _, err := r.trades.ReplaceOne(ctx, bson.M{"_id": trade.ID}, trade, opts); err != nil {
+		return err
+	}
+	_, err := r.positions.ReplaceOne(ctx, bson.M{"_id": position.ID}, position, opts)
+	return err
+}
@@ -0,0 +1,46 @@
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/leonzhao/trading-system/backend/models"
+)
+
+// SaveWatchlistEntry inserts or replaces a discovered token's watchlist entry
+func (r *MongoRepository) SaveWatchlistEntry(ctx context.Context, entry *models.WatchlistEntry) error {
+	opts := options.Replace().SetUpsert(true)
+	_, err := r.watchlist.ReplaceOne(ctx, bson.M{"_id": entry.TokenAddress}, entry, opts)
+	return err
+}
+
+// ListWatchlist lists watchlist entries, most recently discovered first
+func (r *MongoRepository) ListWatchlist(ctx context.Context, filter *models.WatchlistFilter) ([]*models.WatchlistEntry, error) {
+	query := bson.M{}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	if filter.StartTime != nil {
+		query["discovered_at"] = bson.M{"$gte": filter.StartTime}
+	}
+
+	opts, err := findOptions(filter.QueryOptions, watchlistSortFields, "discovered_at")
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := r.watchlist.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []*models.WatchlistEntry
+	if err = cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
@@ -26,8 +26,14 @@ type Repository interface {
 	ClosePosition(ctx context.Context, id string, closePrice float64) error
 	GetPositionStats(ctx context.Context, filter *models.PositionFilter) (*models.PositionStats, error)
 
+	// Atomic trade execution
+	SaveTradeAndUpdatePosition(ctx context.Context, trade *models.Trade, position *models.Position) error
+
 	// Market data operations
 	SaveMarketData(ctx context.Context, data *models.MarketData) error
+	UpsertMarketData(ctx context.Context, data *models.MarketData) error
+	SaveMarketDataBatch(ctx context.Context, data []*models.MarketData) error
+	SaveKlinesBatch(ctx context.Context, klines []*models.Kline) error
 	GetLatestMarketData(ctx context.Context, tokenAddress string) (*models.MarketData, error)
 	GetHistoricalMarketData(ctx context.Context, tokenAddress string, limit int) ([]*models.MarketData, error)
 	GetMarketStats(ctx context.Context, tokenAddress string) (*models.MarketStats, error)
@@ -40,6 +46,33 @@ type Repository interface {
 	GetDailyStats(ctx context.Context, date time.Time) (*models.DailyStats, error)
 	GetDailyStatsRange(ctx context.Context, startDate, endDate time.Time) ([]*models.DailyStats, error)
 
+	// Signal deduplication
+	ReserveSignalFingerprint(ctx context.Context, fingerprint *models.SignalFingerprint) error
+	ReleaseSignalFingerprint(ctx context.Context, id string) error
+
+	// Position reconciliation audit
+	SaveReconciliationRecord(ctx context.Context, record *models.ReconciliationRecord) error
+	ListReconciliationRecords(ctx context.Context, filter *models.ReconciliationFilter) ([]*models.ReconciliationRecord, error)
+
+	// New token discovery watchlist
+	SaveWatchlistEntry(ctx context.Context, entry *models.WatchlistEntry) error
+	ListWatchlist(ctx context.Context, filter *models.WatchlistFilter) ([]*models.WatchlistEntry, error)
+
+	// Data retention
+	DownsampleKlines(ctx context.Context, target string, before time.Time) (int64, error)
+	DeleteKlinesBefore(ctx context.Context, interval string, before time.Time) (int64, error)
+	DeleteMarketDataBefore(ctx context.Context, before time.Time) (int64, error)
+	SaveEventAggregates(ctx context.Context, aggregates []*models.EventAggregate) error
+
+	// Execution quality analytics
+	SaveExecutionAggregates(ctx context.Context, aggregates []*models.ExecutionAggregate) error
+	ListExecutionAggregates(ctx context.Context, from, to time.Time) ([]*models.ExecutionAggregate, error)
+
+	// Market analysis reports
+	SaveAnalysisReport(ctx context.Context, report *models.AnalysisReport) error
+	GetLatestReport(ctx context.Context, tokenAddress, timeframe string) (*models.AnalysisReport, error)
+	GetReportHistory(ctx context.Context, tokenAddress, timeframe string, limit int) ([]*models.AnalysisReport, error)
+
 	// Health check
 	Ping(ctx context.Context) error
 }
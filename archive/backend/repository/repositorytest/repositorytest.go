@@ -0,0 +1,548 @@
+// Package repositorytest is the test suite shared by the implementations of
+// repository.Repository
+package repositorytest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/repository"
+)
+
+// Run runs the suite. newRepo returns an empty repository and is called
+// once per test.
+func Run(t *testing.T, newRepo func(t *testing.T) repository.Repository) {
+	for name, test := range map[string]func(*testing.T, repository.Repository){
+		"Trades":                     testTrades,
+		"TradeStats":                 testTradeStats,
+		"Positions":                  testPositions,
+		"SaveTradeAndUpdatePosition": testSaveTradeAndUpdatePosition,
+		"MarketData":                 testMarketData,
+		"Analysis":                   testAnalysis,
+		"DailyStats":                 testDailyStats,
+		"SignalFingerprints":         testSignalFingerprints,
+		"Reconciliation":             testReconciliation,
+		"Watchlist":                  testWatchlist,
+		"Retention":                  testRetention,
+		"ExecutionAggregates":        testExecutionAggregates,
+		"AnalysisReports":            testAnalysisReports,
+	} {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			test(t, newRepo(t))
+		})
+	}
+}
+
+// now returns the current time at the precision every implementation
+// stores
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
+
+func testTrades(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	start := now().Add(-time.Hour)
+
+	for i, side := range []models.TradeSide{models.TradeSideBuy, models.TradeSideSell, models.TradeSideBuy} {
+		require.NoError(t, repo.SaveTrade(ctx, &models.Trade{
+			TokenAddress: "token-a",
+			Type:         models.TradeTypeMarket,
+			Side:         side,
+			Amount:       float64(i + 1),
+			Value:        float64(10 * (i + 1)),
+			Status:       models.TradePending,
+			Timestamp:    start.Add(time.Duration(i) * time.Minute),
+		}))
+	}
+	other := &models.Trade{
+		ID:           "trade-b",
+		TokenAddress: "token-b",
+		Type:         models.TradeTypeLimit,
+		Side:         models.TradeSideSell,
+		Amount:       5,
+		Status:       models.TradePending,
+		Timestamp:    start,
+	}
+	require.NoError(t, repo.SaveTrade(ctx, other))
+	assert.ErrorIs(t, repo.SaveTrade(ctx, other), repository.ErrDuplicateKey)
+
+	got, err := repo.GetTradeByID(ctx, "trade-b")
+	require.NoError(t, err)
+	assert.Equal(t, "token-b", got.TokenAddress)
+	assert.True(t, start.Equal(got.Timestamp))
+
+	_, err = repo.GetTradeByID(ctx, "missing")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	trades, err := repo.ListTrades(ctx, &models.TradeFilter{TokenAddress: "token-a"})
+	require.NoError(t, err)
+	require.Len(t, trades, 3)
+	assert.Equal(t, 3.0, trades[0].Amount, "newest first")
+
+	trades, err = repo.ListTrades(ctx, &models.TradeFilter{
+		Side:         []models.TradeSide{models.TradeSideBuy},
+		QueryOptions: models.QueryOptions{SortBy: "amount", SortOrder: models.SortAscending, Limit: 1, Offset: 1},
+	})
+	require.NoError(t, err)
+	require.Len(t, trades, 1)
+	assert.Equal(t, 3.0, trades[0].Amount)
+
+	_, err = repo.ListTrades(ctx, &models.TradeFilter{QueryOptions: models.QueryOptions{SortBy: "unknown"}})
+	assert.ErrorIs(t, err, repository.ErrInvalidInput)
+
+	require.NoError(t, repo.UpdateTradeStatus(ctx, "trade-b", models.TradeExecuted))
+	got, err = repo.GetTradeByID(ctx, "trade-b")
+	require.NoError(t, err)
+	assert.Equal(t, models.TradeExecuted, got.Status)
+
+	got.TxHash = "tx"
+	require.NoError(t, repo.UpdateTrade(ctx, got))
+	got, err = repo.GetTradeByID(ctx, "trade-b")
+	require.NoError(t, err)
+	assert.Equal(t, "tx", got.TxHash)
+
+	trades, err = repo.ListTrades(ctx, &models.TradeFilter{Status: []models.TradeStatus{models.TradeExecuted}})
+	require.NoError(t, err)
+	require.Len(t, trades, 1)
+	assert.Equal(t, "trade-b", trades[0].ID)
+}
+
+func testTradeStats(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+
+	for _, trade := range []*models.Trade{
+		{TokenAddress: "token-a", Value: 100, Fee: 1, Status: models.TradeExecuted, Timestamp: now()},
+		{TokenAddress: "token-a", Value: 50, Fee: 2, Status: models.TradeExecuted, Timestamp: now()},
+		{TokenAddress: "token-a", Value: 30, Fee: 3, Status: models.TradeFailed, Timestamp: now()},
+		{TokenAddress: "token-b", Value: 1000, Fee: 4, Status: models.TradeExecuted, Timestamp: now()},
+	} {
+		require.NoError(t, repo.SaveTrade(ctx, trade))
+	}
+
+	stats, err := repo.GetTradeStats(ctx, &models.TradeFilter{TokenAddress: "token-a"})
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.TotalTrades)
+	assert.Equal(t, 2, stats.SuccessfulTrades)
+	assert.Equal(t, 1, stats.FailedTrades)
+	assert.Equal(t, 180.0, stats.TotalVolume)
+	assert.Equal(t, 3.0, stats.TotalFees)
+	assert.Equal(t, 1.5, stats.AverageFee)
+}
+
+func testPositions(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	openTime := now()
+
+	position := &models.Position{
+		ID:           "position-a",
+		TokenAddress: "token-a",
+		Side:         "long",
+		EntryPrice:   10,
+		Size:         2,
+		Value:        20,
+		Status:       repository.PositionStatusOpen,
+		OpenTime:     openTime,
+	}
+	require.NoError(t, repo.SavePosition(ctx, position))
+	require.NoError(t, repo.SavePosition(ctx, &models.Position{
+		TokenAddress: "token-b",
+		Side:         "short",
+		Value:        50,
+		Status:       repository.PositionStatusOpen,
+		OpenTime:     openTime.Add(time.Minute),
+	}))
+
+	got, err := repo.GetPositionByID(ctx, "position-a")
+	require.NoError(t, err)
+	assert.Equal(t, 10.0, got.EntryPrice)
+	assert.True(t, openTime.Equal(got.OpenTime))
+
+	_, err = repo.GetPositionByID(ctx, "missing")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	got.UpdateValue(15)
+	require.NoError(t, repo.UpdatePosition(ctx, got))
+	got, err = repo.GetPositionByID(ctx, "position-a")
+	require.NoError(t, err)
+	assert.Equal(t, 10.0, got.UnrealizedPnL)
+
+	open, err := repo.GetOpenPositions(ctx)
+	require.NoError(t, err)
+	assert.Len(t, open, 2)
+
+	require.NoError(t, repo.ClosePosition(ctx, "position-a", 16))
+	got, err = repo.GetPositionByID(ctx, "position-a")
+	require.NoError(t, err)
+	assert.Equal(t, repository.PositionStatusClosed, got.Status)
+	assert.Equal(t, 16.0, got.CurrentPrice)
+	assert.False(t, got.CloseTime.IsZero())
+
+	open, err = repo.GetOpenPositions(ctx)
+	require.NoError(t, err)
+	require.Len(t, open, 1)
+	assert.Equal(t, "token-b", open[0].TokenAddress)
+
+	positions, err := repo.ListPositions(ctx, &models.PositionFilter{
+		QueryOptions: models.QueryOptions{SortBy: "value", SortOrder: models.SortAscending},
+	})
+	require.NoError(t, err)
+	require.Len(t, positions, 2)
+	assert.Equal(t, "position-a", positions[0].ID)
+
+	stats, err := repo.GetPositionStats(ctx, &models.PositionFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.TotalPositions)
+	assert.Equal(t, 1, stats.OpenPositions)
+}
+
+func testSaveTradeAndUpdatePosition(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+
+	trade := &models.Trade{
+		TokenAddress: "token-a",
+		Side:         models.TradeSideBuy,
+		Amount:       1,
+		Status:       models.TradePending,
+		Timestamp:    now(),
+	}
+	require.NoError(t, repo.SaveTrade(ctx, trade))
+
+	trade.Status = models.TradeExecuted
+	position := &models.Position{
+		TokenAddress: "token-a",
+		Side:         "long",
+		Size:         1,
+		Status:       repository.PositionStatusOpen,
+		OpenTime:     now(),
+	}
+	require.NoError(t, repo.SaveTradeAndUpdatePosition(ctx, trade, position))
+	require.NotEmpty(t, position.ID)
+
+	got, err := repo.GetTradeByID(ctx, trade.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.TradeExecuted, got.Status)
+
+	position.Size = 3
+	require.NoError(t, repo.SaveTradeAndUpdatePosition(ctx, trade, position))
+	gotPosition, err := repo.GetPositionByID(ctx, position.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 3.0, gotPosition.Size)
+}
+
+func testMarketData(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	timestamp := now()
+
+	require.NoError(t, repo.SaveMarketData(ctx, &models.MarketData{
+		TokenAddress: "token-a",
+		Interval:     "1m",
+		ClosePrice:   1,
+		Timestamp:    timestamp.Add(-time.Minute),
+	}))
+	require.NoError(t, repo.UpsertMarketData(ctx, &models.MarketData{
+		TokenAddress: "token-a",
+		Interval:     "1m",
+		ClosePrice:   2,
+		Timestamp:    timestamp,
+	}))
+	require.NoError(t, repo.SaveMarketDataBatch(ctx, []*models.MarketData{
+		{TokenAddress: "token-b", Interval: "1m", ClosePrice: 3, Timestamp: timestamp},
+		{TokenAddress: "token-b", Interval: "1h", ClosePrice: 4, Timestamp: timestamp.Add(-time.Hour)},
+	}))
+
+	latest, err := repo.GetLatestMarketData(ctx, "token-a")
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, latest.ClosePrice)
+	assert.True(t, timestamp.Equal(latest.Timestamp))
+
+	history, err := repo.GetHistoricalMarketData(ctx, "token-a", 10)
+	require.NoError(t, err)
+	assert.Len(t, history, 1, "upserted by token and interval")
+
+	history, err = repo.GetHistoricalMarketData(ctx, "token-b", 10)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "1m", history[0].Interval, "newest first")
+
+	deleted, err := repo.DeleteMarketDataBefore(ctx, timestamp.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
+
+func testAnalysis(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+
+	indicators, err := repo.GetTechnicalIndicators(ctx, "token-a")
+	require.NoError(t, err)
+	assert.Equal(t, &models.TechnicalIndicators{}, indicators)
+
+	for _, price := range []float64{1, 2} {
+		require.NoError(t, repo.SaveAnalysisResult(ctx, &models.AnalysisResult{
+			TokenAddress: "token-a",
+			CurrentPrice: price,
+			Timestamp:    now(),
+		}))
+	}
+
+	result, err := repo.GetLatestAnalysis(ctx, "token-a")
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, result.CurrentPrice)
+}
+
+func testDailyStats(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	today := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	empty, err := repo.GetDailyStats(ctx, today.Add(5*time.Hour))
+	require.NoError(t, err)
+	assert.True(t, today.Equal(empty.Date))
+	assert.Zero(t, empty.TotalTrades)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, repo.SaveDailyStats(ctx, &models.DailyStats{
+			Date:        today.AddDate(0, 0, -i),
+			TotalTrades: i + 1,
+		}))
+	}
+	require.NoError(t, repo.SaveDailyStats(ctx, &models.DailyStats{Date: today, TotalTrades: 10}))
+
+	stats, err := repo.GetDailyStats(ctx, today.Add(5*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 10, stats.TotalTrades)
+
+	days, err := repo.GetDailyStatsRange(ctx, today.AddDate(0, 0, -1), today)
+	require.NoError(t, err)
+	require.Len(t, days, 2)
+	assert.Equal(t, 2, days[0].TotalTrades, "oldest first")
+	assert.Equal(t, 10, days[1].TotalTrades)
+}
+
+func testSignalFingerprints(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+
+	fingerprint := &models.SignalFingerprint{
+		ID:           "fingerprint",
+		TokenAddress: "token-a",
+		Direction:    models.TradeSideBuy,
+		CreatedAt:    now(),
+		ExpiresAt:    now().Add(time.Hour),
+	}
+	require.NoError(t, repo.ReserveSignalFingerprint(ctx, fingerprint))
+	assert.ErrorIs(t, repo.ReserveSignalFingerprint(ctx, fingerprint), repository.ErrDuplicateKey)
+
+	require.NoError(t, repo.ReleaseSignalFingerprint(ctx, fingerprint.ID))
+	assert.NoError(t, repo.ReserveSignalFingerprint(ctx, fingerprint), "released fingerprints can be reserved again")
+	assert.NoError(t, repo.ReleaseSignalFingerprint(ctx, "unknown"), "releasing an unknown fingerprint is not an error")
+
+	expired := &models.SignalFingerprint{
+		ID:        "expired",
+		CreatedAt: now().Add(-2 * time.Hour),
+		ExpiresAt: now().Add(-time.Hour),
+	}
+	require.NoError(t, repo.ReserveSignalFingerprint(ctx, expired))
+	expired.ExpiresAt = now().Add(time.Hour)
+	assert.NoError(t, repo.ReserveSignalFingerprint(ctx, expired), "expired fingerprints are taken over")
+}
+
+func testReconciliation(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	start := now()
+
+	for i, action := range []models.ReconciliationAction{
+		models.ReconciliationFlagged,
+		models.ReconciliationCorrected,
+		models.ReconciliationFlagged,
+	} {
+		require.NoError(t, repo.SaveReconciliationRecord(ctx, &models.ReconciliationRecord{
+			TokenAddress: "token-a",
+			Action:       action,
+			Difference:   float64(i),
+			CreatedAt:    start.Add(time.Duration(i) * time.Minute),
+		}))
+	}
+
+	records, err := repo.ListReconciliationRecords(ctx, &models.ReconciliationFilter{
+		Action: models.ReconciliationFlagged,
+	})
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, 2.0, records[0].Difference, "newest first")
+	assert.NotEmpty(t, records[0].ID)
+
+	startTime := start.Add(time.Minute)
+	records, err = repo.ListReconciliationRecords(ctx, &models.ReconciliationFilter{
+		StartTime:    &startTime,
+		QueryOptions: models.QueryOptions{SortBy: "difference", SortOrder: models.SortAscending},
+	})
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, 1.0, records[0].Difference)
+}
+
+func testWatchlist(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	start := now()
+
+	for i, token := range []string{"token-a", "token-b"} {
+		require.NoError(t, repo.SaveWatchlistEntry(ctx, &models.WatchlistEntry{
+			TokenAddress: token,
+			Status:       models.WatchlistWatching,
+			DiscoveredAt: start.Add(time.Duration(i) * time.Minute),
+			UpdatedAt:    start,
+		}))
+	}
+	require.NoError(t, repo.SaveWatchlistEntry(ctx, &models.WatchlistEntry{
+		TokenAddress: "token-a",
+		Status:       models.WatchlistRejected,
+		Reason:       "low liquidity",
+		DiscoveredAt: start,
+		UpdatedAt:    start.Add(time.Minute),
+	}))
+
+	entries, err := repo.ListWatchlist(ctx, &models.WatchlistFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "token-b", entries[0].TokenAddress, "most recently discovered first")
+
+	entries, err = repo.ListWatchlist(ctx, &models.WatchlistFilter{Status: models.WatchlistRejected})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "low liquidity", entries[0].Reason)
+}
+
+func testRetention(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	hour := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	// Three hours of 30 minute candles and one hour of 15 minute candles
+	var klines []*models.Kline
+	for i := 0; i < 6; i++ {
+		klines = append(klines, &models.Kline{
+			Symbol:    "SOL",
+			Interval:  "30m",
+			Open:      float64(i),
+			High:      float64(i + 2),
+			Low:       float64(i),
+			Close:     float64(i + 1),
+			Volume:    1,
+			Timestamp: hour.Add(time.Duration(i) * 30 * time.Minute),
+		})
+	}
+	for i := 0; i < 4; i++ {
+		klines = append(klines, &models.Kline{
+			Symbol:    "SOL",
+			Interval:  "15m",
+			Open:      1,
+			High:      1,
+			Low:       1,
+			Close:     1,
+			Volume:    1,
+			Timestamp: hour.Add(time.Duration(i) * 15 * time.Minute),
+		})
+	}
+	require.NoError(t, repo.SaveKlinesBatch(ctx, klines))
+	require.NoError(t, repo.SaveKlinesBatch(ctx, klines[:1]), "saving a candle again replaces it")
+
+	_, err := repo.DownsampleKlines(ctx, "4h", hour)
+	assert.ErrorIs(t, err, repository.ErrInvalidInput)
+
+	// The cutoff is rounded down to 14:00, leaving the last hour of 30
+	// minute candles
+	deleted, err := repo.DownsampleKlines(ctx, "1h", hour.Add(2*time.Hour+20*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), deleted)
+
+	deleted, err = repo.DeleteKlinesBefore(ctx, "30m", hour.Add(3*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+
+	deleted, err = repo.DeleteKlinesBefore(ctx, "1h", hour.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted, "one candle per hour")
+
+	aggregates := []*models.EventAggregate{{Hour: hour, Type: "system", Severity: "info", Count: 2}}
+	require.NoError(t, repo.SaveEventAggregates(ctx, aggregates))
+	require.NoError(t, repo.SaveEventAggregates(ctx, aggregates))
+}
+
+func testExecutionAggregates(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	day := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	aggregates := []*models.ExecutionAggregate{
+		{Day: day, Dimension: models.ExecutionByToken, Bucket: "SOL", Count: 2, Adverse: 1,
+			SlippageBpsSum: 6, SlippageBpsSquares: 20, Notional: 300, WeightedSlippageSum: 1000},
+		{Day: day, Dimension: models.ExecutionByHour, Bucket: "14", Count: 2, Adverse: 1,
+			SlippageBpsSum: 6, SlippageBpsSquares: 20, Notional: 300, WeightedSlippageSum: 1000},
+		{Day: day.AddDate(0, 0, 1), Dimension: models.ExecutionByToken, Bucket: "SOL", Count: 1,
+			SlippageBpsSum: -1, SlippageBpsSquares: 1, Notional: 50, WeightedSlippageSum: -50},
+	}
+	require.NoError(t, repo.SaveExecutionAggregates(ctx, aggregates))
+	require.NoError(t, repo.SaveExecutionAggregates(ctx, aggregates[:1]), "saving again adds to the sums")
+
+	listed, err := repo.ListExecutionAggregates(ctx, day, day.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	require.Len(t, listed, 2)
+	for _, a := range listed {
+		assert.True(t, day.Equal(a.Day))
+		if a.Dimension != models.ExecutionByToken {
+			continue
+		}
+		assert.Equal(t, "SOL", a.Bucket)
+		assert.Equal(t, 4, a.Count)
+		assert.Equal(t, 2, a.Adverse)
+		assert.InDelta(t, 12, a.SlippageBpsSum, 1e-9)
+		assert.InDelta(t, 40, a.SlippageBpsSquares, 1e-9)
+		assert.InDelta(t, 600, a.Notional, 1e-9)
+		assert.InDelta(t, 2000, a.WeightedSlippageSum, 1e-9)
+	}
+
+	listed, err = repo.ListExecutionAggregates(ctx, day, day.AddDate(0, 0, 2))
+	require.NoError(t, err)
+	assert.Len(t, listed, 3)
+}
+
+func testAnalysisReports(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	at := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	_, err := repo.GetLatestReport(ctx, "token-a", "1h")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	for i, report := range []*models.AnalysisReport{
+		{TokenAddress: "token-a", Timeframe: "1h", Trend: "bullish", Support: 1},
+		{TokenAddress: "token-a", Timeframe: "1h", Trend: "bearish", Support: 2, Regime: "trending", RegimeDirection: "bearish"},
+		{TokenAddress: "token-a", Timeframe: "1h", Trend: "neutral", Support: 3},
+		{TokenAddress: "token-a", Timeframe: "1d", Trend: "bullish", Support: 4},
+		{TokenAddress: "token-b", Timeframe: "1h", Trend: "bullish", Support: 5},
+	} {
+		report.Timestamp = at.Add(time.Duration(i) * time.Hour)
+		require.NoError(t, repo.SaveAnalysisReport(ctx, report))
+		assert.NotEmpty(t, report.ID)
+	}
+
+	latest, err := repo.GetLatestReport(ctx, "token-a", "1h")
+	require.NoError(t, err)
+	assert.Equal(t, "neutral", latest.Trend)
+	assert.Equal(t, 3.0, latest.Support)
+	assert.True(t, at.Add(2*time.Hour).Equal(latest.Timestamp))
+
+	history, err := repo.GetReportHistory(ctx, "token-a", "1h", 2)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, 3.0, history[0].Support)
+	assert.Equal(t, 2.0, history[1].Support)
+	assert.Equal(t, "trending", history[1].Regime)
+	assert.Equal(t, "bearish", history[1].RegimeDirection)
+
+	history, err = repo.GetReportHistory(ctx, "token-a", "1d", 10)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, 4.0, history[0].Support)
+}
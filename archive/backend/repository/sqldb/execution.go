@@ -0,0 +1,62 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/models"
+)
+
+// SaveExecutionAggregates adds fills to the stored daily execution
+// aggregates
+func (r *Repository) SaveExecutionAggregates(ctx context.Context, aggregates []*models.ExecutionAggregate) error {
+	if len(aggregates) == 0 {
+		return nil
+	}
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		for _, a := range aggregates {
+			_, err := r.exec(ctx, tx, `INSERT INTO execution_aggregates
+				(day, dimension, bucket, count, adverse, slippage_bps_sum, slippage_bps_squares, notional, weighted_slippage_sum)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+				ON CONFLICT (day, dimension, bucket) DO UPDATE SET
+					count = execution_aggregates.count + excluded.count,
+					adverse = execution_aggregates.adverse + excluded.adverse,
+					slippage_bps_sum = execution_aggregates.slippage_bps_sum + excluded.slippage_bps_sum,
+					slippage_bps_squares = execution_aggregates.slippage_bps_squares + excluded.slippage_bps_squares,
+					notional = execution_aggregates.notional + excluded.notional,
+					weighted_slippage_sum = execution_aggregates.weighted_slippage_sum + excluded.weighted_slippage_sum`,
+				nanos(a.Day), a.Dimension, a.Bucket, a.Count, a.Adverse,
+				a.SlippageBpsSum, a.SlippageBpsSquares, a.Notional, a.WeightedSlippageSum)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListExecutionAggregates lists the execution aggregates of the days from
+// from until before to, oldest first
+func (r *Repository) ListExecutionAggregates(ctx context.Context, from, to time.Time) ([]*models.ExecutionAggregate, error) {
+	rows, err := r.db.QueryContext(ctx, r.rebind(`SELECT day, dimension, bucket, count, adverse,
+		slippage_bps_sum, slippage_bps_squares, notional, weighted_slippage_sum
+		FROM execution_aggregates WHERE day >= ? AND day < ? ORDER BY day, dimension, bucket`), nanos(from), nanos(to))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var aggregates []*models.ExecutionAggregate
+	for rows.Next() {
+		var a models.ExecutionAggregate
+		var day int64
+		if err := rows.Scan(&day, &a.Dimension, &a.Bucket, &a.Count, &a.Adverse,
+			&a.SlippageBpsSum, &a.SlippageBpsSquares, &a.Notional, &a.WeightedSlippageSum); err != nil {
+			return nil, err
+		}
+		a.Day = time.Unix(0, day).UTC()
+		aggregates = append(aggregates, &a)
+	}
+	return aggregates, rows.Err()
+}
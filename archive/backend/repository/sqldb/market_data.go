@@ -0,0 +1,138 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/repository"
+)
+
+// SaveMarketData saves market data, keeping one row per token and interval
+func (r *Repository) SaveMarketData(ctx context.Context, data *models.MarketData) error {
+	return r.UpsertMarketData(ctx, data)
+}
+
+// UpsertMarketData stores the latest market data of a token and interval
+func (r *Repository) UpsertMarketData(ctx context.Context, data *models.MarketData) error {
+	return r.upsertMarketData(ctx, r.db, data)
+}
+
+// SaveMarketDataBatch upserts the market data of many tokens in one
+// transaction
+func (r *Repository) SaveMarketDataBatch(ctx context.Context, data []*models.MarketData) error {
+	if len(data) == 0 {
+		return nil
+	}
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		for _, d := range data {
+			if err := r.upsertMarketData(ctx, tx, d); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SaveKlinesBatch stores candles in one transaction. A candle that is
+// polled again replaces the stored one for its symbol, interval and open
+// time.
+func (r *Repository) SaveKlinesBatch(ctx context.Context, klines []*models.Kline) error {
+	if len(klines) == 0 {
+		return nil
+	}
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		for _, k := range klines {
+			if err := r.upsertKline(ctx, tx, k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetLatestMarketData retrieves the latest market data for a token
+func (r *Repository) GetLatestMarketData(ctx context.Context, tokenAddress string) (*models.MarketData, error) {
+	var data models.MarketData
+	err := r.getJSON(ctx, r.db, &data, `SELECT data FROM market_data WHERE token_address = ? ORDER BY timestamp DESC LIMIT 1`, tokenAddress)
+	if err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// GetHistoricalMarketData retrieves historical market data for a token,
+// newest first. A limit of zero returns all of it.
+func (r *Repository) GetHistoricalMarketData(ctx context.Context, tokenAddress string, limit int) ([]*models.MarketData, error) {
+	page, err := r.page(models.QueryOptions{Limit: limit}, nil, "timestamp", "period")
+	if err != nil {
+		return nil, err
+	}
+	return listJSON[models.MarketData](ctx, r, `SELECT data FROM market_data WHERE token_address = ?`+page, tokenAddress)
+}
+
+// GetMarketStats retrieves market statistics for a token
+func (r *Repository) GetMarketStats(ctx context.Context, tokenAddress string) (*models.MarketStats, error) {
+	var stats models.MarketStats
+	err := r.getJSON(ctx, r.db, &stats, `SELECT data FROM market_data WHERE token_address = ? ORDER BY timestamp DESC LIMIT 1`, tokenAddress)
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// GetTechnicalIndicators retrieves technical indicators for a token
+func (r *Repository) GetTechnicalIndicators(ctx context.Context, tokenAddress string) (*models.TechnicalIndicators, error) {
+	var indicators models.TechnicalIndicators
+	err := r.getJSON(ctx, r.db, &indicators, `SELECT data FROM analysis WHERE token_address = ?`, tokenAddress)
+	if errors.Is(err, repository.ErrNotFound) {
+		return &models.TechnicalIndicators{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &indicators, nil
+}
+
+// SaveAnalysisResult saves market analysis results, keeping the latest per
+// token
+func (r *Repository) SaveAnalysisResult(ctx context.Context, result *models.AnalysisResult) error {
+	data, err := encode(result)
+	if err != nil {
+		return err
+	}
+	_, err = r.exec(ctx, r.db, `INSERT INTO analysis (token_address, timestamp, data) VALUES (?, ?, ?)
+		ON CONFLICT (token_address) DO UPDATE SET timestamp = excluded.timestamp, data = excluded.data`,
+		result.TokenAddress, nanos(result.Timestamp), data)
+	return err
+}
+
+// GetLatestAnalysis retrieves the latest analysis for a token
+func (r *Repository) GetLatestAnalysis(ctx context.Context, tokenAddress string) (*models.AnalysisResult, error) {
+	var result models.AnalysisResult
+	if err := r.getJSON(ctx, r.db, &result, `SELECT data FROM analysis WHERE token_address = ?`, tokenAddress); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (r *Repository) upsertMarketData(ctx context.Context, db execer, data *models.MarketData) error {
+	encoded, err := encode(data)
+	if err != nil {
+		return err
+	}
+	_, err = r.exec(ctx, db, `INSERT INTO market_data (token_address, period, timestamp, data) VALUES (?, ?, ?, ?)
+		ON CONFLICT (token_address, period) DO UPDATE SET timestamp = excluded.timestamp, data = excluded.data`,
+		data.TokenAddress, data.Interval, nanos(data.Timestamp), encoded)
+	return err
+}
+
+func (r *Repository) upsertKline(ctx context.Context, db execer, k *models.Kline) error {
+	_, err := r.exec(ctx, db, `INSERT INTO klines (symbol, period, timestamp, open, high, low, close, volume) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (symbol, period, timestamp) DO UPDATE SET
+			open = excluded.open, high = excluded.high, low = excluded.low,
+			close = excluded.close, volume = excluded.volume`,
+		k.Symbol, k.Interval, nanos(k.Timestamp), k.Open, k.High, k.Low, k.Close, k.Volume)
+	return err
+}
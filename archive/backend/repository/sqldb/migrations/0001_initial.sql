@@ -0,0 +1,105 @@
+-- Times are stored as Unix nanoseconds, so SQLite and Postgres compare and
+-- sort them the same way. Full records are stored as JSON in data; the other
+-- columns are the fields queries filter and sort on. period is the interval
+-- of market data and klines, which is a keyword in Postgres.
+
+CREATE TABLE trades (
+    id TEXT PRIMARY KEY,
+    token_address TEXT NOT NULL,
+    type TEXT NOT NULL,
+    side TEXT NOT NULL,
+    status TEXT NOT NULL,
+    amount DOUBLE PRECISION NOT NULL,
+    value DOUBLE PRECISION NOT NULL,
+    profit DOUBLE PRECISION NOT NULL,
+    fee DOUBLE PRECISION NOT NULL,
+    timestamp BIGINT NOT NULL,
+    update_time BIGINT NOT NULL,
+    data TEXT NOT NULL
+);
+CREATE INDEX trades_token_timestamp ON trades (token_address, timestamp);
+CREATE INDEX trades_status_timestamp ON trades (status, timestamp);
+CREATE INDEX trades_timestamp ON trades (timestamp);
+
+CREATE TABLE positions (
+    id TEXT PRIMARY KEY,
+    token_address TEXT NOT NULL,
+    side TEXT NOT NULL,
+    status TEXT NOT NULL,
+    value DOUBLE PRECISION NOT NULL,
+    unrealized_pnl DOUBLE PRECISION NOT NULL,
+    realized_pnl DOUBLE PRECISION NOT NULL,
+    open_time BIGINT NOT NULL,
+    last_updated BIGINT NOT NULL,
+    data TEXT NOT NULL
+);
+CREATE INDEX positions_status ON positions (status);
+CREATE INDEX positions_token_open_time ON positions (token_address, open_time);
+
+CREATE TABLE market_data (
+    token_address TEXT NOT NULL,
+    period TEXT NOT NULL,
+    timestamp BIGINT NOT NULL,
+    data TEXT NOT NULL,
+    PRIMARY KEY (token_address, period)
+);
+CREATE INDEX market_data_token_timestamp ON market_data (token_address, timestamp);
+
+CREATE TABLE klines (
+    symbol TEXT NOT NULL,
+    period TEXT NOT NULL,
+    timestamp BIGINT NOT NULL,
+    open DOUBLE PRECISION NOT NULL,
+    high DOUBLE PRECISION NOT NULL,
+    low DOUBLE PRECISION NOT NULL,
+    close DOUBLE PRECISION NOT NULL,
+    volume DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (symbol, period, timestamp)
+);
+CREATE INDEX klines_period_timestamp ON klines (period, timestamp);
+
+CREATE TABLE daily_stats (
+    date BIGINT PRIMARY KEY,
+    data TEXT NOT NULL
+);
+
+CREATE TABLE analysis (
+    token_address TEXT PRIMARY KEY,
+    timestamp BIGINT NOT NULL,
+    data TEXT NOT NULL
+);
+
+CREATE TABLE signal_fingerprints (
+    id TEXT PRIMARY KEY,
+    expires_at BIGINT NOT NULL,
+    data TEXT NOT NULL
+);
+CREATE INDEX signal_fingerprints_expires_at ON signal_fingerprints (expires_at);
+
+CREATE TABLE position_reconciliations (
+    id TEXT PRIMARY KEY,
+    token_address TEXT NOT NULL,
+    action TEXT NOT NULL,
+    difference DOUBLE PRECISION NOT NULL,
+    created_at BIGINT NOT NULL,
+    data TEXT NOT NULL
+);
+CREATE INDEX position_reconciliations_token_created_at ON position_reconciliations (token_address, created_at);
+CREATE INDEX position_reconciliations_created_at ON position_reconciliations (created_at);
+
+CREATE TABLE watchlist (
+    token_address TEXT PRIMARY KEY,
+    status TEXT NOT NULL,
+    discovered_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL,
+    data TEXT NOT NULL
+);
+CREATE INDEX watchlist_status_discovered_at ON watchlist (status, discovered_at);
+
+CREATE TABLE event_aggregates (
+    hour BIGINT NOT NULL,
+    type TEXT NOT NULL,
+    severity TEXT NOT NULL,
+    count BIGINT NOT NULL,
+    PRIMARY KEY (hour, type, severity)
+);
@@ -0,0 +1,14 @@
+-- Daily execution quality aggregates, one row per day, dimension and bucket
+
+CREATE TABLE execution_aggregates (
+    day BIGINT NOT NULL,
+    dimension TEXT NOT NULL,
+    bucket TEXT NOT NULL,
+    count BIGINT NOT NULL,
+    adverse BIGINT NOT NULL,
+    slippage_bps_sum DOUBLE PRECISION NOT NULL,
+    slippage_bps_squares DOUBLE PRECISION NOT NULL,
+    notional DOUBLE PRECISION NOT NULL,
+    weighted_slippage_sum DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (day, dimension, bucket)
+);
@@ -0,0 +1,10 @@
+-- Market analysis report history, per token and timeframe
+
+CREATE TABLE analysis_reports (
+    id TEXT PRIMARY KEY,
+    token_address TEXT NOT NULL,
+    timeframe TEXT NOT NULL,
+    timestamp BIGINT NOT NULL,
+    data TEXT NOT NULL
+);
+CREATE INDEX analysis_reports_token_timeframe_timestamp ON analysis_reports (token_address, timeframe, timestamp);
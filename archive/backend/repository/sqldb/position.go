@@ -0,0 +1,170 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/repository"
+)
+
+var positionSortColumns = sortColumns{
+	"openTime":      "open_time",
+	"lastUpdated":   "last_updated",
+	"value":         "value",
+	"unrealizedPnL": "unrealized_pnl",
+	"realizedPnL":   "realized_pnl",
+}
+
+// SavePosition saves a position to the database
+func (r *Repository) SavePosition(ctx context.Context, position *models.Position) error {
+	if position.ID == "" {
+		position.ID = primitive.NewObjectID().Hex()
+	}
+	position.LastUpdated = time.Now()
+
+	values, err := positionValues(position)
+	if err != nil {
+		return err
+	}
+	_, err = r.exec(ctx, r.db, `INSERT INTO positions (`+positionColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, values...)
+	if isUniqueViolation(err) {
+		return repository.ErrDuplicateKey
+	}
+	return err
+}
+
+// GetPositionByID retrieves a position by ID
+func (r *Repository) GetPositionByID(ctx context.Context, id string) (*models.Position, error) {
+	var position models.Position
+	if err := r.getJSON(ctx, r.db, &position, `SELECT data FROM positions WHERE id = ?`, id); err != nil {
+		return nil, err
+	}
+	return &position, nil
+}
+
+// ListPositions lists positions based on filter
+func (r *Repository) ListPositions(ctx context.Context, filter *models.PositionFilter) ([]*models.Position, error) {
+	page, err := r.page(filter.QueryOptions, positionSortColumns, "open_time", "id")
+	if err != nil {
+		return nil, err
+	}
+
+	w := &where{}
+	if filter.TokenAddress != "" {
+		w.add("token_address = ?", filter.TokenAddress)
+	}
+	if filter.Side != "" {
+		w.add("side = ?", filter.Side)
+	}
+	if filter.Status != "" {
+		w.add("status = ?", filter.Status)
+	}
+	if filter.StartTime != nil {
+		w.add("open_time >= ?", nanos(*filter.StartTime))
+	}
+	if filter.EndTime != nil {
+		w.add("open_time <= ?", nanos(*filter.EndTime))
+	}
+
+	return listJSON[models.Position](ctx, r, `SELECT data FROM positions`+w.String()+page, w.args...)
+}
+
+// GetOpenPositions retrieves all open positions
+func (r *Repository) GetOpenPositions(ctx context.Context) ([]*models.Position, error) {
+	return r.ListPositions(ctx, &models.PositionFilter{
+		Status: repository.PositionStatusOpen,
+	})
+}
+
+// UpdatePosition updates a position in the database
+func (r *Repository) UpdatePosition(ctx context.Context, position *models.Position) error {
+	position.LastUpdated = time.Now()
+	return r.updatePosition(ctx, r.db, position)
+}
+
+// ClosePosition closes a position with the given ID and close price
+func (r *Repository) ClosePosition(ctx context.Context, id string, closePrice float64) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		var position models.Position
+		if err := r.getJSON(ctx, tx, &position, `SELECT data FROM positions WHERE id = ?`, id); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		position.Status = repository.PositionStatusClosed
+		position.CurrentPrice = closePrice
+		position.CloseTime = now
+		position.LastUpdated = now
+		return r.updatePosition(ctx, tx, &position)
+	})
+}
+
+// GetPositionStats retrieves position statistics
+func (r *Repository) GetPositionStats(ctx context.Context, filter *models.PositionFilter) (*models.PositionStats, error) {
+	positions, err := r.ListPositions(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &models.PositionStats{
+		LastUpdated: time.Now(),
+	}
+
+	for _, pos := range positions {
+		stats.TotalPositions++
+		if pos.Status == repository.PositionStatusOpen {
+			stats.OpenPositions++
+			stats.UnrealizedPnL += pos.UnrealizedPnL
+			stats.TotalValue += pos.Value
+		} else {
+			stats.ClosedPositions++
+			stats.RealizedPnL += pos.RealizedPnL
+		}
+	}
+
+	return stats, nil
+}
+
+const positionColumns = `id, token_address, side, status, value, unrealized_pnl, realized_pnl, open_time, last_updated, data`
+
+func positionValues(position *models.Position) ([]interface{}, error) {
+	data, err := encode(position)
+	if err != nil {
+		return nil, err
+	}
+	return []interface{}{
+		position.ID, position.TokenAddress, position.Side, position.Status,
+		position.Value, position.UnrealizedPnL, position.RealizedPnL,
+		nanos(position.OpenTime), nanos(position.LastUpdated), data,
+	}, nil
+}
+
+func (r *Repository) upsertPosition(ctx context.Context, db execer, position *models.Position) error {
+	values, err := positionValues(position)
+	if err != nil {
+		return err
+	}
+	_, err = r.exec(ctx, db, `INSERT INTO positions (`+positionColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			token_address = excluded.token_address, side = excluded.side, status = excluded.status,
+			value = excluded.value, unrealized_pnl = excluded.unrealized_pnl,
+			realized_pnl = excluded.realized_pnl, open_time = excluded.open_time,
+			last_updated = excluded.last_updated, data = excluded.data`, values...)
+	return err
+}
+
+func (r *Repository) updatePosition(ctx context.Context, db execer, position *models.Position) error {
+	values, err := positionValues(position)
+	if err != nil {
+		return err
+	}
+	_, err = r.exec(ctx, db, `UPDATE positions SET
+		token_address = ?, side = ?, status = ?, value = ?, unrealized_pnl = ?,
+		realized_pnl = ?, open_time = ?, last_updated = ?, data = ?
+		WHERE id = ?`, append(values[1:], position.ID)...)
+	return err
+}
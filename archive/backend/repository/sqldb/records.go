@@ -0,0 +1,130 @@
+package sqldb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/repository"
+)
+
+var (
+	reconciliationSortColumns = sortColumns{
+		"createdAt":  "created_at",
+		"difference": "difference",
+	}
+	watchlistSortColumns = sortColumns{
+		"discoveredAt": "discovered_at",
+		"updatedAt":    "updated_at",
+	}
+)
+
+// ReserveSignalFingerprint stores a signal fingerprint, returning
+// repository.ErrDuplicateKey if an unexpired fingerprint already exists
+func (r *Repository) ReserveSignalFingerprint(ctx context.Context, fingerprint *models.SignalFingerprint) error {
+	data, err := encode(fingerprint)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.exec(ctx, r.db, `INSERT INTO signal_fingerprints (id, expires_at, data) VALUES (?, ?, ?)`,
+		fingerprint.ID, nanos(fingerprint.ExpiresAt), data)
+	if err == nil || !isUniqueViolation(err) {
+		return err
+	}
+
+	// Expired fingerprints are not deleted, take one over if it has expired
+	result, err := r.exec(ctx, r.db, `UPDATE signal_fingerprints SET expires_at = ?, data = ? WHERE id = ? AND expires_at <= ?`,
+		nanos(fingerprint.ExpiresAt), data, fingerprint.ID, nanos(time.Now()))
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return repository.ErrDuplicateKey
+	}
+
+	return nil
+}
+
+// ReleaseSignalFingerprint deletes a signal fingerprint, so the signal can
+// be reserved again
+func (r *Repository) ReleaseSignalFingerprint(ctx context.Context, id string) error {
+	_, err := r.exec(ctx, r.db, `DELETE FROM signal_fingerprints WHERE id = ?`, id)
+	return err
+}
+
+// SaveReconciliationRecord appends a position reconciliation audit record
+func (r *Repository) SaveReconciliationRecord(ctx context.Context, record *models.ReconciliationRecord) error {
+	if record.ID == "" {
+		record.ID = primitive.NewObjectID().Hex()
+	}
+
+	data, err := encode(record)
+	if err != nil {
+		return err
+	}
+	_, err = r.exec(ctx, r.db, `INSERT INTO position_reconciliations (id, token_address, action, difference, created_at, data) VALUES (?, ?, ?, ?, ?, ?)`,
+		record.ID, record.TokenAddress, string(record.Action), record.Difference, nanos(record.CreatedAt), data)
+	if isUniqueViolation(err) {
+		return repository.ErrDuplicateKey
+	}
+	return err
+}
+
+// ListReconciliationRecords lists reconciliation audit records, newest first
+func (r *Repository) ListReconciliationRecords(ctx context.Context, filter *models.ReconciliationFilter) ([]*models.ReconciliationRecord, error) {
+	page, err := r.page(filter.QueryOptions, reconciliationSortColumns, "created_at", "id")
+	if err != nil {
+		return nil, err
+	}
+
+	w := &where{}
+	if filter.TokenAddress != "" {
+		w.add("token_address = ?", filter.TokenAddress)
+	}
+	if filter.Action != "" {
+		w.add("action = ?", string(filter.Action))
+	}
+	if filter.StartTime != nil {
+		w.add("created_at >= ?", nanos(*filter.StartTime))
+	}
+	if filter.EndTime != nil {
+		w.add("created_at <= ?", nanos(*filter.EndTime))
+	}
+	return listJSON[models.ReconciliationRecord](ctx, r, `SELECT data FROM position_reconciliations`+w.String()+page, w.args...)
+}
+
+// SaveWatchlistEntry inserts or replaces a discovered token's watchlist entry
+func (r *Repository) SaveWatchlistEntry(ctx context.Context, entry *models.WatchlistEntry) error {
+	data, err := encode(entry)
+	if err != nil {
+		return err
+	}
+	_, err = r.exec(ctx, r.db, `INSERT INTO watchlist (token_address, status, discovered_at, updated_at, data) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (token_address) DO UPDATE SET
+			status = excluded.status, discovered_at = excluded.discovered_at,
+			updated_at = excluded.updated_at, data = excluded.data`,
+		entry.TokenAddress, string(entry.Status), nanos(entry.DiscoveredAt), nanos(entry.UpdatedAt), data)
+	return err
+}
+
+// ListWatchlist lists watchlist entries, most recently discovered first
+func (r *Repository) ListWatchlist(ctx context.Context, filter *models.WatchlistFilter) ([]*models.WatchlistEntry, error) {
+	page, err := r.page(filter.QueryOptions, watchlistSortColumns, "discovered_at", "token_address")
+	if err != nil {
+		return nil, err
+	}
+
+	w := &where{}
+	if filter.Status != "" {
+		w.add("status = ?", string(filter.Status))
+	}
+	if filter.StartTime != nil {
+		w.add("discovered_at >= ?", nanos(*filter.StartTime))
+	}
+	return listJSON[models.WatchlistEntry](ctx, r, `SELECT data FROM watchlist`+w.String()+page, w.args...)
+}
@@ -0,0 +1,43 @@
+package sqldb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/leonzhao/trading-system/backend/models"
+)
+
+// SaveAnalysisReport appends a market analysis report to the history of its
+// token and timeframe
+func (r *Repository) SaveAnalysisReport(ctx context.Context, report *models.AnalysisReport) error {
+	if report.ID == "" {
+		report.ID = primitive.NewObjectID().Hex()
+	}
+
+	data, err := encode(report)
+	if err != nil {
+		return err
+	}
+	_, err = r.exec(ctx, r.db, `INSERT INTO analysis_reports (id, token_address, timeframe, timestamp, data) VALUES (?, ?, ?, ?, ?)`,
+		report.ID, report.TokenAddress, report.Timeframe, nanos(report.Timestamp), data)
+	return err
+}
+
+// GetLatestReport retrieves the latest analysis report of a token over a
+// timeframe
+func (r *Repository) GetLatestReport(ctx context.Context, tokenAddress, timeframe string) (*models.AnalysisReport, error) {
+	var report models.AnalysisReport
+	if err := r.getJSON(ctx, r.db, &report, `SELECT data FROM analysis_reports
+		WHERE token_address = ? AND timeframe = ? ORDER BY timestamp DESC LIMIT 1`, tokenAddress, timeframe); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// GetReportHistory lists up to limit analysis reports of a token over a
+// timeframe, newest first
+func (r *Repository) GetReportHistory(ctx context.Context, tokenAddress, timeframe string, limit int) ([]*models.AnalysisReport, error) {
+	return listJSON[models.AnalysisReport](ctx, r, `SELECT data FROM analysis_reports
+		WHERE token_address = ? AND timeframe = ? ORDER BY timestamp DESC LIMIT ?`, tokenAddress, timeframe, limit)
+}
@@ -0,0 +1,178 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/repository"
+)
+
+// downsampleTargets are the intervals klines can be downsampled into
+var downsampleTargets = map[string]bool{
+	"1h": true,
+	"1d": true,
+}
+
+// DownsampleKlines aggregates the klines finer than the target interval,
+// "1h" or "1d", that open before a time into target candles, then deletes
+// them, all in one transaction. before is rounded down to a target boundary
+// so no candle is built from part of its period. It returns the number of
+// klines deleted.
+func (r *Repository) DownsampleKlines(ctx context.Context, target string, before time.Time) (int64, error) {
+	if !downsampleTargets[target] {
+		return 0, fmt.Errorf("%w: cannot downsample klines to %q", repository.ErrInvalidInput, target)
+	}
+	targetDuration, _ := repository.IntervalDuration(target)
+	before = before.UTC().Truncate(targetDuration)
+
+	var deleted int64
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		source, err := r.sourceIntervals(ctx, tx, targetDuration, before)
+		if err != nil || len(source) == 0 {
+			return err
+		}
+
+		// Aggregate coarse intervals first, so that where a symbol has
+		// candles of several intervals the candle built from the finest one
+		// wins
+		for _, interval := range source {
+			candles, err := r.aggregateKlines(ctx, tx, interval, target, targetDuration, before)
+			if err != nil {
+				return fmt.Errorf("failed to downsample %s klines: %w", interval, err)
+			}
+			for _, k := range candles {
+				if err := r.upsertKline(ctx, tx, k); err != nil {
+					return err
+				}
+			}
+
+			result, err := r.exec(ctx, tx, `DELETE FROM klines WHERE period = ? AND timestamp < ?`, interval, nanos(before))
+			if err != nil {
+				return err
+			}
+			n, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			deleted += n
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
+
+// sourceIntervals returns the intervals finer than the target that have
+// klines opening before a time, coarsest first
+func (r *Repository) sourceIntervals(ctx context.Context, tx *sql.Tx, target time.Duration, before time.Time) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, r.rebind(`SELECT DISTINCT period FROM klines WHERE timestamp < ?`), nanos(before))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	durations := make(map[string]time.Duration)
+	var source []string
+	for rows.Next() {
+		var interval string
+		if err := rows.Scan(&interval); err != nil {
+			return nil, err
+		}
+		if d, ok := repository.IntervalDuration(interval); ok && d < target {
+			durations[interval] = d
+			source = append(source, interval)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(source, func(i, j int) bool {
+		return durations[source[i]] > durations[source[j]]
+	})
+	return source, nil
+}
+
+// aggregateKlines builds target candles from the klines of one interval
+// that open before a time. The rows are read before any are written, as
+// Postgres connections cannot run statements while a result is open.
+func (r *Repository) aggregateKlines(ctx context.Context, tx *sql.Tx, interval, target string, targetDuration time.Duration, before time.Time) ([]*models.Kline, error) {
+	rows, err := tx.QueryContext(ctx, r.rebind(`SELECT symbol, timestamp, open, high, low, close, volume FROM klines
+		WHERE period = ? AND timestamp < ? ORDER BY symbol, timestamp`), interval, nanos(before))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candles []*models.Kline
+	var current *models.Kline
+	for rows.Next() {
+		var k models.Kline
+		var timestamp int64
+		if err := rows.Scan(&k.Symbol, &timestamp, &k.Open, &k.High, &k.Low, &k.Close, &k.Volume); err != nil {
+			return nil, err
+		}
+		bucket := time.Unix(0, timestamp).UTC().Truncate(targetDuration)
+
+		if current == nil || current.Symbol != k.Symbol || !current.Timestamp.Equal(bucket) {
+			current = &models.Kline{
+				Symbol:    k.Symbol,
+				Interval:  target,
+				Timestamp: bucket,
+				Open:      k.Open,
+				High:      k.High,
+				Low:       k.Low,
+			}
+			candles = append(candles, current)
+		}
+		current.High = max(current.High, k.High)
+		current.Low = min(current.Low, k.Low)
+		current.Close = k.Close
+		current.Volume += k.Volume
+	}
+	return candles, rows.Err()
+}
+
+// DeleteKlinesBefore deletes the klines of an interval that open before a
+// time and returns the number deleted
+func (r *Repository) DeleteKlinesBefore(ctx context.Context, interval string, before time.Time) (int64, error) {
+	result, err := r.exec(ctx, r.db, `DELETE FROM klines WHERE period = ? AND timestamp < ?`, interval, nanos(before))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteMarketDataBefore deletes market data last updated before a time,
+// such as that of tokens no longer polled, and returns the number deleted
+func (r *Repository) DeleteMarketDataBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.exec(ctx, r.db, `DELETE FROM market_data WHERE timestamp < ?`, nanos(before))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// SaveEventAggregates adds event counts to the stored hourly aggregates
+func (r *Repository) SaveEventAggregates(ctx context.Context, aggregates []*models.EventAggregate) error {
+	if len(aggregates) == 0 {
+		return nil
+	}
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		for _, a := range aggregates {
+			_, err := r.exec(ctx, tx, `INSERT INTO event_aggregates (hour, type, severity, count) VALUES (?, ?, ?, ?)
+				ON CONFLICT (hour, type, severity) DO UPDATE SET count = event_aggregates.count + excluded.count`,
+				nanos(a.Hour), a.Type, a.Severity, a.Count)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,352 @@
+// Package sqldb implements repository.Repository on SQLite or Postgres
+// through database/sql, for setups that do not run MongoDB.
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	// Database drivers. The SQLite driver requires cgo.
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/repository"
+)
+
+// Dialect is the SQL database a repository runs on
+type Dialect string
+
+const (
+	SQLite   Dialect = "sqlite"
+	Postgres Dialect = "postgres"
+)
+
+// drivers are the database/sql driver names of the dialects
+var drivers = map[Dialect]string{
+	SQLite:   "sqlite3",
+	Postgres: "pgx",
+}
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Repository implements repository.Repository on a SQL database
+type Repository struct {
+	db      *sql.DB
+	dialect Dialect
+}
+
+// NewRepository opens a SQL repository and applies pending migrations. dsn
+// is a file name or URI for SQLite and a connection URI for Postgres.
+func NewRepository(ctx context.Context, dialect Dialect, dsn string, opts repository.Options) (*Repository, error) {
+	driver, ok := drivers[dialect]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown SQL dialect %q", repository.ErrInvalidInput, dialect)
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", repository.ErrDatabaseConnection, err)
+	}
+	if dialect == SQLite {
+		// SQLite allows a single writer
+		db.SetMaxOpenConns(1)
+	} else if opts.MaxConnections > 0 {
+		db.SetMaxOpenConns(int(opts.MaxConnections))
+		db.SetMaxIdleConns(int(opts.MinConnections))
+	}
+
+	repo, err := New(ctx, db, dialect)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return repo, nil
+}
+
+// New creates a repository on an open database and applies pending
+// migrations
+func New(ctx context.Context, db *sql.DB, dialect Dialect) (*Repository, error) {
+	if _, ok := drivers[dialect]; !ok {
+		return nil, fmt.Errorf("%w: unknown SQL dialect %q", repository.ErrInvalidInput, dialect)
+	}
+
+	r := &Repository{db: db, dialect: dialect}
+	if err := r.Ping(ctx); err != nil {
+		return nil, fmt.Errorf("%w: %v", repository.ErrDatabaseConnection, err)
+	}
+	if err := r.migrate(ctx); err != nil {
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+	return r, nil
+}
+
+// Close closes the database
+func (r *Repository) Close() error {
+	return r.db.Close()
+}
+
+// Ping checks the database connection
+func (r *Repository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+// migrate applies the embedded migrations not yet recorded in
+// schema_migrations, each in its own transaction
+func (r *Repository) migrate(ctx context.Context) error {
+	if _, err := r.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		applied_at BIGINT NOT NULL
+	)`); err != nil {
+		return err
+	}
+
+	files, err := fs.Glob(migrations, "migrations/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		name := strings.TrimPrefix(file, "migrations/")
+		version, err := strconv.Atoi(strings.SplitN(name, "_", 2)[0])
+		if err != nil {
+			return fmt.Errorf("invalid migration name %s", name)
+		}
+
+		var applied int
+		if err := r.db.QueryRowContext(ctx, r.rebind(`SELECT COUNT(*) FROM schema_migrations WHERE version = ?`), version).Scan(&applied); err != nil {
+			return err
+		}
+		if applied > 0 {
+			continue
+		}
+
+		script, err := migrations.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		err = r.inTx(ctx, func(tx *sql.Tx) error {
+			for _, statement := range splitStatements(string(script)) {
+				if _, err := tx.ExecContext(ctx, statement); err != nil {
+					return fmt.Errorf("%s: %w", name, err)
+				}
+			}
+			_, err := tx.ExecContext(ctx, r.rebind(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`), version, time.Now().UnixNano())
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// splitStatements splits a migration script into statements, dropping
+// comment lines
+func splitStatements(script string) []string {
+	var lines []string
+	for _, line := range strings.Split(script, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "--") {
+			lines = append(lines, line)
+		}
+	}
+
+	var statements []string
+	for _, statement := range strings.Split(strings.Join(lines, "\n"), ";") {
+		if statement = strings.TrimSpace(statement); statement != "" {
+			statements = append(statements, statement)
+		}
+	}
+	return statements
+}
+
+// inTx runs fn in a transaction, committing if it succeeds
+func (r *Repository) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// rebind replaces the ? placeholders of a query with the placeholders of the
+// dialect
+func (r *Repository) rebind(query string) string {
+	if r.dialect != Postgres {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// execer is a database or a transaction
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func (r *Repository) exec(ctx context.Context, db execer, query string, args ...interface{}) (sql.Result, error) {
+	return db.ExecContext(ctx, r.rebind(query), args...)
+}
+
+// querier is a database or a transaction
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// getJSON decodes the data column of the first row of a query into v,
+// returning repository.ErrNotFound when there is none
+func (r *Repository) getJSON(ctx context.Context, db querier, v interface{}, query string, args ...interface{}) error {
+	var data string
+	err := db.QueryRowContext(ctx, r.rebind(query), args...).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(data), v)
+}
+
+// isUniqueViolation reports whether an insert failed on a primary key or
+// unique constraint
+func isUniqueViolation(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "UNIQUE constraint failed") || strings.Contains(msg, "SQLSTATE 23505")
+}
+
+// listJSON decodes the data column of every row of a query
+func listJSON[T any](ctx context.Context, r *Repository, query string, args ...interface{}) ([]*T, error) {
+	rows, err := r.db.QueryContext(ctx, r.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []*T
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		record := new(T)
+		if err := json.Unmarshal([]byte(data), record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+func encode(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+// nanos converts a time to its stored form
+func nanos(t time.Time) int64 {
+	return t.UnixNano()
+}
+
+// where collects the conditions and arguments of a query
+type where struct {
+	conditions []string
+	args       []interface{}
+}
+
+func (w *where) add(condition string, args ...interface{}) {
+	w.conditions = append(w.conditions, condition)
+	w.args = append(w.args, args...)
+}
+
+// in adds a condition matching any of values
+func (w *where) in(column string, values []string) {
+	if len(values) == 0 {
+		return
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+	condition := column + " IN (" + placeholders + ")"
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	w.add(condition, args...)
+}
+
+func (w *where) String() string {
+	if len(w.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(w.conditions, " AND ")
+}
+
+// sortColumns maps the sortable JSON field names of a table to columns
+type sortColumns map[string]string
+
+// page builds the ORDER BY, LIMIT and OFFSET of a list query. Results are
+// sorted on defaultColumn, newest first, unless the query options say
+// otherwise, with the key column as a tie breaker so pages do not overlap.
+func (r *Repository) page(query models.QueryOptions, columns sortColumns, defaultColumn, key string) (string, error) {
+	if query.Limit < 0 || query.Offset < 0 {
+		return "", fmt.Errorf("%w: limit and offset must not be negative", repository.ErrInvalidInput)
+	}
+
+	column := defaultColumn
+	if query.SortBy != "" {
+		var ok bool
+		if column, ok = columns[query.SortBy]; !ok {
+			return "", fmt.Errorf("%w: cannot sort by %q", repository.ErrInvalidInput, query.SortBy)
+		}
+	}
+
+	direction := "DESC"
+	switch query.SortOrder {
+	case "", models.SortDescending:
+	case models.SortAscending:
+		direction = "ASC"
+	default:
+		return "", fmt.Errorf("%w: unknown sort order %q", repository.ErrInvalidInput, query.SortOrder)
+	}
+
+	clause := fmt.Sprintf(" ORDER BY %s %s, %s %s", column, direction, key, direction)
+	if query.Limit > 0 {
+		clause += fmt.Sprintf(" LIMIT %d", query.Limit)
+	} else if query.Offset > 0 && r.dialect == SQLite {
+		// SQLite only accepts OFFSET after a LIMIT
+		clause += " LIMIT -1"
+	}
+	if query.Offset > 0 {
+		clause += fmt.Sprintf(" OFFSET %d", query.Offset)
+	}
+	return clause, nil
+}
+
+var _ repository.Repository = (*Repository)(nil)
@@ -0,0 +1,80 @@
+package sqldb
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/repository"
+	"github.com/leonzhao/trading-system/backend/repository/repositorytest"
+)
+
+func TestSQLiteRepository(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repository.Repository {
+		repo, err := NewRepository(context.Background(), SQLite, filepath.Join(t.TempDir(), "gosol.db"), repository.DefaultOptions())
+		require.NoError(t, err)
+		t.Cleanup(func() { repo.Close() })
+		return repo
+	})
+}
+
+// TestPostgresRepository runs against the database in POSTGRES_TEST_URI,
+// which it empties first
+func TestPostgresRepository(t *testing.T) {
+	uri := os.Getenv("POSTGRES_TEST_URI")
+	if uri == "" {
+		t.Skip("POSTGRES_TEST_URI not set")
+	}
+
+	repositorytest.Run(t, func(t *testing.T) repository.Repository {
+		ctx := context.Background()
+		repo, err := NewRepository(ctx, Postgres, uri, repository.DefaultOptions())
+		require.NoError(t, err)
+		t.Cleanup(func() { repo.Close() })
+
+		for _, table := range []string{
+			"trades", "positions", "market_data", "klines", "daily_stats", "analysis",
+			"signal_fingerprints", "position_reconciliations", "watchlist", "event_aggregates",
+			"execution_aggregates", "analysis_reports",
+		} {
+			_, err := repo.db.ExecContext(ctx, "DELETE FROM "+table)
+			require.NoError(t, err)
+		}
+		return repo
+	})
+}
+
+func TestMigrateIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	dsn := filepath.Join(t.TempDir(), "gosol.db")
+
+	repo, err := NewRepository(ctx, SQLite, dsn, repository.DefaultOptions())
+	require.NoError(t, err)
+	require.NoError(t, repo.SaveTrade(ctx, &models.Trade{ID: "trade"}))
+	require.NoError(t, repo.Close())
+
+	repo, err = NewRepository(ctx, SQLite, dsn, repository.DefaultOptions())
+	require.NoError(t, err)
+	defer repo.Close()
+	_, err = repo.GetTradeByID(ctx, "trade")
+	assert.NoError(t, err)
+}
+
+func TestRebind(t *testing.T) {
+	r := &Repository{dialect: Postgres}
+	assert.Equal(t, "SELECT data FROM trades WHERE id = $1 AND status IN ($2, $3)",
+		r.rebind("SELECT data FROM trades WHERE id = ? AND status IN (?, ?)"))
+
+	r.dialect = SQLite
+	assert.Equal(t, "WHERE id = ?", r.rebind("WHERE id = ?"))
+}
+
+func TestNewRepositoryUnknownDialect(t *testing.T) {
+	_, err := NewRepository(context.Background(), "oracle", "", repository.DefaultOptions())
+	assert.ErrorIs(t, err, repository.ErrInvalidInput)
+}
@@ -0,0 +1,51 @@
+package sqldb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/repository"
+)
+
+// SaveDailyStats saves daily trading statistics
+func (r *Repository) SaveDailyStats(ctx context.Context, stats *models.DailyStats) error {
+	data, err := encode(stats)
+	if err != nil {
+		return err
+	}
+	_, err = r.exec(ctx, r.db, `INSERT INTO daily_stats (date, data) VALUES (?, ?)
+		ON CONFLICT (date) DO UPDATE SET data = excluded.data`, nanos(stats.Date), data)
+	return err
+}
+
+// GetDailyStats retrieves daily trading statistics for a specific date
+func (r *Repository) GetDailyStats(ctx context.Context, date time.Time) (*models.DailyStats, error) {
+	// Normalize date to start of day
+	startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+
+	var stats models.DailyStats
+	err := r.getJSON(ctx, r.db, &stats, `SELECT data FROM daily_stats WHERE date = ?`, nanos(startOfDay))
+	if errors.Is(err, repository.ErrNotFound) {
+		// Return empty stats for the day if none exist
+		return &models.DailyStats{
+			Date: startOfDay,
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
+// GetDailyStatsRange retrieves daily trading statistics for a date range
+func (r *Repository) GetDailyStatsRange(ctx context.Context, startDate, endDate time.Time) ([]*models.DailyStats, error) {
+	// Normalize dates to start of day
+	startOfDay := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, startDate.Location())
+	endOfDay := time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 23, 59, 59, 999999999, endDate.Location())
+
+	return listJSON[models.DailyStats](ctx, r, `SELECT data FROM daily_stats WHERE date >= ? AND date <= ? ORDER BY date ASC`,
+		nanos(startOfDay), nanos(endOfDay))
+}
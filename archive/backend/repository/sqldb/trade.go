@@ -0,0 +1,218 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/repository"
+)
+
+var tradeSortColumns = sortColumns{
+	"timestamp":  "timestamp",
+	"updateTime": "update_time",
+	"amount":     "amount",
+	"value":      "value",
+	"profit":     "profit",
+}
+
+// SaveTrade saves a trade to the database
+func (r *Repository) SaveTrade(ctx context.Context, trade *models.Trade) error {
+	if trade.ID == "" {
+		trade.ID = primitive.NewObjectID().Hex()
+	}
+	trade.UpdateTime = time.Now()
+
+	err := r.insertTrade(ctx, r.db, trade)
+	if isUniqueViolation(err) {
+		return repository.ErrDuplicateKey
+	}
+	return err
+}
+
+// GetTradeByID retrieves a trade by ID
+func (r *Repository) GetTradeByID(ctx context.Context, id string) (*models.Trade, error) {
+	var trade models.Trade
+	if err := r.getJSON(ctx, r.db, &trade, `SELECT data FROM trades WHERE id = ?`, id); err != nil {
+		return nil, err
+	}
+	return &trade, nil
+}
+
+// ListTrades lists trades based on filter
+func (r *Repository) ListTrades(ctx context.Context, filter *models.TradeFilter) ([]*models.Trade, error) {
+	page, err := r.page(filter.QueryOptions, tradeSortColumns, "timestamp", "id")
+	if err != nil {
+		return nil, err
+	}
+
+	w := tradeWhere(filter)
+	return listJSON[models.Trade](ctx, r, `SELECT data FROM trades`+w.String()+page, w.args...)
+}
+
+// tradeWhere builds the conditions matching a trade filter
+func tradeWhere(filter *models.TradeFilter) *where {
+	w := &where{}
+	if filter.TokenAddress != "" {
+		w.add("token_address = ?", filter.TokenAddress)
+	}
+	w.in("type", stringsOf(filter.Type))
+	w.in("side", stringsOf(filter.Side))
+	w.in("status", stringsOf(filter.Status))
+	if filter.StartTime != nil {
+		w.add("timestamp >= ?", nanos(*filter.StartTime))
+	}
+	if filter.EndTime != nil {
+		w.add("timestamp <= ?", nanos(*filter.EndTime))
+	}
+	if filter.MinAmount != nil {
+		w.add("amount >= ?", *filter.MinAmount)
+	}
+	if filter.MaxAmount != nil {
+		w.add("amount <= ?", *filter.MaxAmount)
+	}
+	return w
+}
+
+// UpdateTradeStatus updates the status of a trade
+func (r *Repository) UpdateTradeStatus(ctx context.Context, id string, status models.TradeStatus) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		var trade models.Trade
+		if err := r.getJSON(ctx, tx, &trade, `SELECT data FROM trades WHERE id = ?`, id); err != nil {
+			return err
+		}
+		trade.Status = status
+		trade.UpdateTime = time.Now()
+		return r.updateTrade(ctx, tx, &trade)
+	})
+}
+
+// UpdateTrade updates a trade in the database
+func (r *Repository) UpdateTrade(ctx context.Context, trade *models.Trade) error {
+	trade.UpdateTime = time.Now()
+	return r.updateTrade(ctx, r.db, trade)
+}
+
+// GetTradeStats retrieves trade statistics. Paging options of the filter
+// are ignored.
+func (r *Repository) GetTradeStats(ctx context.Context, filter *models.TradeFilter) (*models.TradeStats, error) {
+	w := tradeWhere(filter)
+	query := `SELECT
+		COUNT(*),
+		COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0),
+		COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0),
+		COALESCE(SUM(value), 0),
+		COALESCE(SUM(CASE WHEN status = ? THEN fee ELSE 0 END), 0)
+		FROM trades` + w.String()
+	executed, failed := string(models.TradeExecuted), string(models.TradeFailed)
+	args := append([]interface{}{executed, failed, executed}, w.args...)
+
+	stats := &models.TradeStats{
+		LastTradeTime: time.Now(),
+	}
+	err := r.db.QueryRowContext(ctx, r.rebind(query), args...).Scan(
+		&stats.TotalTrades,
+		&stats.SuccessfulTrades,
+		&stats.FailedTrades,
+		&stats.TotalVolume,
+		&stats.TotalFees,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if stats.TotalTrades > 0 {
+		stats.AverageAmount = stats.TotalVolume / float64(stats.TotalTrades)
+		if stats.SuccessfulTrades > 0 {
+			stats.AverageFee = stats.TotalFees / float64(stats.SuccessfulTrades)
+		}
+	}
+
+	return stats, nil
+}
+
+// SaveTradeAndUpdatePosition persists a trade and the position it mutates
+// in one transaction, inserting each or replacing it if it already exists
+func (r *Repository) SaveTradeAndUpdatePosition(ctx context.Context, trade *models.Trade, position *models.Position) error {
+	if trade.ID == "" {
+		trade.ID = primitive.NewObjectID().Hex()
+	}
+	if position.ID == "" {
+		position.ID = primitive.NewObjectID().Hex()
+	}
+	now := time.Now()
+	trade.UpdateTime = now
+	position.LastUpdated = now
+
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		if err := r.upsertTrade(ctx, tx, trade); err != nil {
+			return err
+		}
+		return r.upsertPosition(ctx, tx, position)
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", repository.ErrTransactionFailed, err)
+	}
+	return nil
+}
+
+const tradeColumns = `id, token_address, type, side, status, amount, value, profit, fee, timestamp, update_time, data`
+
+func tradeValues(trade *models.Trade) ([]interface{}, error) {
+	data, err := encode(trade)
+	if err != nil {
+		return nil, err
+	}
+	return []interface{}{
+		trade.ID, trade.TokenAddress, string(trade.Type), string(trade.Side), string(trade.Status),
+		trade.Amount, trade.Value, trade.Profit, trade.Fee,
+		nanos(trade.Timestamp), nanos(trade.UpdateTime), data,
+	}, nil
+}
+
+func (r *Repository) insertTrade(ctx context.Context, db execer, trade *models.Trade) error {
+	values, err := tradeValues(trade)
+	if err != nil {
+		return err
+	}
+	_, err = r.exec(ctx, db, `INSERT INTO trades (`+tradeColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, values...)
+	return err
+}
+
+func (r *Repository) upsertTrade(ctx context.Context, db execer, trade *models.Trade) error {
+	values, err := tradeValues(trade)
+	if err != nil {
+		return err
+	}
+	_, err = r.exec(ctx, db, `INSERT INTO trades (`+tradeColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			token_address = excluded.token_address, type = excluded.type, side = excluded.side,
+			status = excluded.status, amount = excluded.amount, value = excluded.value,
+			profit = excluded.profit, fee = excluded.fee, timestamp = excluded.timestamp,
+			update_time = excluded.update_time, data = excluded.data`, values...)
+	return err
+}
+
+func (r *Repository) updateTrade(ctx context.Context, db execer, trade *models.Trade) error {
+	values, err := tradeValues(trade)
+	if err != nil {
+		return err
+	}
+	_, err = r.exec(ctx, db, `UPDATE trades SET
+		token_address = ?, type = ?, side = ?, status = ?, amount = ?, value = ?,
+		profit = ?, fee = ?, timestamp = ?, update_time = ?, data = ?
+		WHERE id = ?`, append(values[1:], trade.ID)...)
+	return err
+}
+
+func stringsOf[T ~string](values []T) []string {
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = string(v)
+	}
+	return s
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/monitoring"
+)
+
+// Pinger checks the database connection, such as repository.Repository
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Feed reports when market data was last received, such as
+// *ingestion.Service
+type Feed interface {
+	LastSuccess() time.Time
+}
+
+// AlertConfig contains the thresholds of the operational alerts
+type AlertConfig struct {
+	// Interval is the time between checks
+	Interval time.Duration
+	// LatencyBudget is the database ping latency above which
+	// AlertMongoLatencyHigh is raised
+	LatencyBudget time.Duration
+	// StallAfter is the time without market data after which
+	// AlertWebSocketStalled is raised
+	StallAfter time.Duration
+}
+
+// DefaultAlertConfig returns default alert thresholds
+func DefaultAlertConfig() AlertConfig {
+	return AlertConfig{
+		Interval:      30 * time.Second,
+		LatencyBudget: 500 * time.Millisecond,
+		StallAfter:    2 * time.Minute,
+	}
+}
+
+// AlertService raises the database latency and stalled feed alerts that
+// runbook hooks respond to. Alerts are raised on every check while the
+// condition lasts.
+type AlertService struct {
+	db      Pinger
+	feed    Feed
+	monitor monitoring.IMonitor
+	config  AlertConfig
+	started time.Time
+}
+
+// NewAlertService creates a new alert service. feed may be nil when no
+// market data is ingested.
+func NewAlertService(db Pinger, feed Feed, monitor monitoring.IMonitor, config AlertConfig) *AlertService {
+	defaults := DefaultAlertConfig()
+	if config.Interval <= 0 {
+		config.Interval = defaults.Interval
+	}
+	if config.LatencyBudget <= 0 {
+		config.LatencyBudget = defaults.LatencyBudget
+	}
+	if config.StallAfter <= 0 {
+		config.StallAfter = defaults.StallAfter
+	}
+
+	return &AlertService{
+		db:      db,
+		feed:    feed,
+		monitor: monitor,
+		config:  config,
+		started: time.Now(),
+	}
+}
+
+// Start checks for alerts every interval until ctx is done
+func (s *AlertService) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Check(ctx, time.Now())
+		}
+	}
+}
+
+// Check raises the alerts whose condition holds at now and returns them
+func (s *AlertService) Check(ctx context.Context, now time.Time) []monitoring.AlertType {
+	var raised []monitoring.AlertType
+	if event, ok := s.checkLatency(ctx); ok {
+		s.monitor.RecordEvent(ctx, event)
+		raised = append(raised, event.Alert)
+	}
+	if event, ok := s.checkFeed(now); ok {
+		s.monitor.RecordEvent(ctx, event)
+		raised = append(raised, event.Alert)
+	}
+	return raised
+}
+
+// checkLatency pings the database. A ping failing within the interval counts
+// as over budget.
+func (s *AlertService) checkLatency(ctx context.Context) (monitoring.Event, bool) {
+	pingCtx, cancel := context.WithTimeout(ctx, s.config.Interval)
+	defer cancel()
+
+	start := time.Now()
+	err := s.db.Ping(pingCtx)
+	latency := time.Since(start)
+	if err == nil && latency <= s.config.LatencyBudget {
+		return monitoring.Event{}, false
+	}
+
+	details := map[string]interface{}{
+		"latency": latency.String(),
+		"budget":  s.config.LatencyBudget.String(),
+	}
+	if err != nil {
+		details["error"] = err.Error()
+	}
+	return monitoring.Event{
+		Type:     monitoring.MetricSystem,
+		Severity: monitoring.SeverityWarning,
+		Message:  fmt.Sprintf("Database ping took %s", latency.Round(time.Millisecond)),
+		Details:  details,
+		Alert:    monitoring.AlertMongoLatencyHigh,
+	}, true
+}
+
+// checkFeed reports a feed without market data for StallAfter, counting from
+// the start of the service before the first data
+func (s *AlertService) checkFeed(now time.Time) (monitoring.Event, bool) {
+	if s.feed == nil {
+		return monitoring.Event{}, false
+	}
+	last := s.feed.LastSuccess()
+	if last.IsZero() {
+		last = s.started
+	}
+	silent := now.Sub(last)
+	if silent < s.config.StallAfter {
+		return monitoring.Event{}, false
+	}
+
+	return monitoring.Event{
+		Type:     monitoring.MetricMarketData,
+		Severity: monitoring.SeverityWarning,
+		Message:  fmt.Sprintf("No market data for %s", silent.Round(time.Second)),
+		Details:  map[string]interface{}{"last_success": last},
+		Alert:    monitoring.AlertWebSocketStalled,
+	}, true
+}
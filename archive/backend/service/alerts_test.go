@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/leonzhao/trading-system/backend/monitoring"
+)
+
+type slowPinger struct {
+	delay time.Duration
+	err   error
+}
+
+func (p *slowPinger) Ping(ctx context.Context) error {
+	time.Sleep(p.delay)
+	return p.err
+}
+
+type fixedFeed struct {
+	last time.Time
+}
+
+func (f *fixedFeed) LastSuccess() time.Time {
+	return f.last
+}
+
+func TestAlertService_Check(t *testing.T) {
+	ctx := context.Background()
+	monitor := monitoring.NewMonitor()
+	defer monitor.Close()
+
+	db := &slowPinger{}
+	feed := &fixedFeed{}
+	alerts := NewAlertService(db, feed, monitor, AlertConfig{LatencyBudget: 20 * time.Millisecond, StallAfter: time.Minute})
+	now := time.Now()
+
+	// Before the first data the feed has until StallAfter from the start
+	assert.Empty(t, alerts.Check(ctx, now))
+	assert.Equal(t, []monitoring.AlertType{monitoring.AlertWebSocketStalled}, alerts.Check(ctx, now.Add(2*time.Minute)))
+
+	feed.last = now
+	assert.Empty(t, alerts.Check(ctx, now.Add(30*time.Second)))
+
+	db.delay = 50 * time.Millisecond
+	assert.Equal(t, []monitoring.AlertType{monitoring.AlertMongoLatencyHigh}, alerts.Check(ctx, now))
+
+	db.delay, db.err = 0, errors.New("connection refused")
+	assert.Equal(t, []monitoring.AlertType{monitoring.AlertMongoLatencyHigh}, alerts.Check(ctx, now))
+
+	require.Eventually(t, func() bool {
+		return len(monitor.GetEventsByType(monitoring.MetricSystem)) >= 2 && len(monitor.GetEventsByType(monitoring.MetricMarketData)) >= 1
+	}, time.Second, 10*time.Millisecond)
+	for _, event := range monitor.GetEventsByType(monitoring.MetricMarketData) {
+		assert.Equal(t, monitoring.AlertWebSocketStalled, event.Alert)
+	}
+}
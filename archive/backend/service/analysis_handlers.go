@@ -0,0 +1,67 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/leonzhao/trading-system/backend/repository"
+	"github.com/leonzhao/trading-system/backend/trading/analysis"
+)
+
+// defaultReportTimeframe is the timeframe of the reports returned without a
+// timeframe, the report timeframe of the analyzer
+const defaultReportTimeframe = "24h"
+
+// handleAnalysisReport returns the latest analysis report of a token, on
+// /api/v1/analysis/{token}/report, or its report history, newest first, on
+// /api/v1/analysis/{token}/report/history. The timeframe query parameter
+// selects the reports of a timeframe, 24h by default.
+func (s *Service) handleAnalysisReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/analysis/"), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] != "report" || (len(parts) == 3 && parts[2] != "history") {
+		http.NotFound(w, r)
+		return
+	}
+	token, history := parts[0], len(parts) == 3
+
+	timeframe := r.URL.Query().Get("timeframe")
+	if timeframe == "" {
+		timeframe = defaultReportTimeframe
+	}
+	if _, err := analysis.ParseTimeframe(timeframe); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if history {
+		opts, err := queryOptions(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		reports, err := s.repo.GetReportHistory(r.Context(), token, timeframe, opts.Limit)
+		if err != nil {
+			http.Error(w, "Failed to get report history: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, reports)
+		return
+	}
+
+	report, err := s.repo.GetLatestReport(r.Context(), token, timeframe)
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "No "+timeframe+" report for "+token, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to get report: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, report)
+}
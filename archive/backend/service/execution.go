@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/monitoring"
+)
+
+// ErrNoExpectedPrice is returned when recording a trade without the price it
+// was expected to fill at
+var ErrNoExpectedPrice = errors.New("trade has no expected price")
+
+// unknownVenue buckets the trades that do not name their venue
+const unknownVenue = "unknown"
+
+// ExecutionStore is the subset of the repository used by execution
+// analytics
+type ExecutionStore interface {
+	SaveExecutionAggregates(ctx context.Context, aggregates []*models.ExecutionAggregate) error
+	ListExecutionAggregates(ctx context.Context, from, to time.Time) ([]*models.ExecutionAggregate, error)
+}
+
+// ExecutionConfig contains execution analytics configuration
+type ExecutionConfig struct {
+	// SizeBuckets are the ascending notional edges fills are bucketed by
+	SizeBuckets []float64
+	// SuggestionStdDevs is how many standard deviations above the mean
+	// slippage the suggested SlippageBps setting is
+	SuggestionStdDevs float64
+}
+
+// DefaultExecutionConfig returns default execution analytics configuration
+func DefaultExecutionConfig() ExecutionConfig {
+	return ExecutionConfig{
+		SizeBuckets:       []float64{100, 1000, 10000, 100000},
+		SuggestionStdDevs: 2,
+	}
+}
+
+// ExecutionBucket is the execution quality of the fills of one bucket
+type ExecutionBucket struct {
+	Bucket string `json:"bucket"`
+	Count  int    `json:"count"`
+	// MeanSlippageBps is positive when fills are worse than expected on
+	// average
+	MeanSlippageBps     float64 `json:"meanSlippageBps"`
+	StdDevSlippageBps   float64 `json:"stdDevSlippageBps"`
+	WeightedSlippageBps float64 `json:"weightedSlippageBps"`
+	// AdverseRatio is the share of fills worse than expected
+	AdverseRatio float64 `json:"adverseRatio"`
+	Notional     float64 `json:"notional"`
+	// SuggestedSlippageBps is a SlippageBps setting covering most fills of
+	// the bucket, never negative
+	SuggestedSlippageBps float64 `json:"suggestedSlippageBps"`
+}
+
+// ExecutionReport is the execution quality of the fills of a period, per
+// bucket of each dimension
+type ExecutionReport struct {
+	From    time.Time         `json:"from"`
+	To      time.Time         `json:"to"`
+	Overall ExecutionBucket   `json:"overall"`
+	ByToken []ExecutionBucket `json:"byToken"`
+	ByVenue []ExecutionBucket `json:"byVenue"`
+	BySize  []ExecutionBucket `json:"bySize"`
+	ByHour  []ExecutionBucket `json:"byHour"`
+}
+
+// ExecutionAnalytics compares the prices trades fill at with the prices they
+// were expected to fill at
+type ExecutionAnalytics struct {
+	store   ExecutionStore
+	monitor monitoring.IMonitor
+	config  ExecutionConfig
+}
+
+// NewExecutionAnalytics creates new execution analytics
+func NewExecutionAnalytics(store ExecutionStore, monitor monitoring.IMonitor, config ExecutionConfig) *ExecutionAnalytics {
+	defaults := DefaultExecutionConfig()
+	if len(config.SizeBuckets) == 0 {
+		config.SizeBuckets = defaults.SizeBuckets
+	}
+	config.SizeBuckets = append([]float64(nil), config.SizeBuckets...)
+	sort.Float64s(config.SizeBuckets)
+	if config.SuggestionStdDevs <= 0 {
+		config.SuggestionStdDevs = defaults.SuggestionStdDevs
+	}
+
+	return &ExecutionAnalytics{
+		store:   store,
+		monitor: monitor,
+		config:  config,
+	}
+}
+
+// SlippageBps returns how much worse than expected a fill is, in basis points
+// of the expected price: positive when a buy fills higher or a sell lower
+func SlippageBps(side models.TradeSide, expected, fill float64) float64 {
+	slippage := (fill - expected) / expected * 10000
+	if side == models.TradeSideSell {
+		return -slippage
+	}
+	return slippage
+}
+
+// Record sets the slippage of a filled trade and adds it to the aggregates of
+// the day it filled
+func (a *ExecutionAnalytics) Record(ctx context.Context, trade *models.Trade) error {
+	if trade.ExpectedPrice <= 0 {
+		return ErrNoExpectedPrice
+	}
+	trade.SlippageBps = SlippageBps(trade.Side, trade.ExpectedPrice, trade.Price)
+
+	at := trade.Timestamp.UTC()
+	day := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+	venue := trade.Venue
+	if venue == "" {
+		venue = unknownVenue
+	}
+	notional := math.Abs(trade.Amount * trade.Price)
+	adverse := 0
+	if trade.SlippageBps > 0 {
+		adverse = 1
+	}
+
+	var aggregates []*models.ExecutionAggregate
+	for _, b := range []struct{ dimension, bucket string }{
+		{models.ExecutionByToken, trade.TokenAddress},
+		{models.ExecutionByVenue, venue},
+		{models.ExecutionBySize, a.sizeBucket(notional)},
+		{models.ExecutionByHour, fmt.Sprintf("%02d", at.Hour())},
+	} {
+		aggregates = append(aggregates, &models.ExecutionAggregate{
+			Day:                 day,
+			Dimension:           b.dimension,
+			Bucket:              b.bucket,
+			Count:               1,
+			Adverse:             adverse,
+			SlippageBpsSum:      trade.SlippageBps,
+			SlippageBpsSquares:  trade.SlippageBps * trade.SlippageBps,
+			Notional:            notional,
+			WeightedSlippageSum: trade.SlippageBps * notional,
+		})
+	}
+	if err := a.store.SaveExecutionAggregates(ctx, aggregates); err != nil {
+		return fmt.Errorf("failed to save execution aggregates: %w", err)
+	}
+
+	if a.monitor != nil {
+		a.monitor.RecordMetric(ctx, "execution_slippage_bps", trade.SlippageBps,
+			map[string]string{"token": trade.TokenAddress, "venue": venue})
+	}
+	return nil
+}
+
+// sizeBucket names the notional range a fill falls in, such as 100-1000
+func (a *ExecutionAnalytics) sizeBucket(notional float64) string {
+	edges := a.config.SizeBuckets
+	i := sort.SearchFloat64s(edges, notional)
+	if i < len(edges) && edges[i] == notional {
+		i++
+	}
+	switch {
+	case i == 0:
+		return "<" + formatEdge(edges[0])
+	case i == len(edges):
+		return ">=" + formatEdge(edges[i-1])
+	}
+	return formatEdge(edges[i-1]) + "-" + formatEdge(edges[i])
+}
+
+func formatEdge(edge float64) string {
+	return strconv.FormatFloat(edge, 'f', -1, 64)
+}
+
+// Report returns the execution quality of the fills of the days from from
+// until before to
+func (a *ExecutionAnalytics) Report(ctx context.Context, from, to time.Time) (*ExecutionReport, error) {
+	aggregates, err := a.store.ListExecutionAggregates(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list execution aggregates: %w", err)
+	}
+
+	// Days are summed per bucket. Every fill is in one bucket of each
+	// dimension, so the token buckets add up to the overall figures.
+	sums := make(map[string]map[string]*models.ExecutionAggregate)
+	overall := &models.ExecutionAggregate{}
+	for _, agg := range aggregates {
+		buckets, ok := sums[agg.Dimension]
+		if !ok {
+			buckets = make(map[string]*models.ExecutionAggregate)
+			sums[agg.Dimension] = buckets
+		}
+		sum, ok := buckets[agg.Bucket]
+		if !ok {
+			sum = &models.ExecutionAggregate{Dimension: agg.Dimension, Bucket: agg.Bucket}
+			buckets[agg.Bucket] = sum
+		}
+		addAggregate(sum, agg)
+		if agg.Dimension == models.ExecutionByToken {
+			addAggregate(overall, agg)
+		}
+	}
+
+	report := &ExecutionReport{
+		From:    from,
+		To:      to,
+		Overall: a.bucket(overall),
+	}
+	report.Overall.Bucket = "all"
+	list := func(dimension string) []ExecutionBucket {
+		result := make([]ExecutionBucket, 0, len(sums[dimension]))
+		for _, sum := range sums[dimension] {
+			result = append(result, a.bucket(sum))
+		}
+		sort.Slice(result, func(i, j int) bool {
+			return result[i].Bucket < result[j].Bucket
+		})
+		return result
+	}
+	report.ByToken = list(models.ExecutionByToken)
+	report.ByVenue = list(models.ExecutionByVenue)
+	report.BySize = list(models.ExecutionBySize)
+	report.ByHour = list(models.ExecutionByHour)
+	return report, nil
+}
+
+func addAggregate(sum, agg *models.ExecutionAggregate) {
+	sum.Count += agg.Count
+	sum.Adverse += agg.Adverse
+	sum.SlippageBpsSum += agg.SlippageBpsSum
+	sum.SlippageBpsSquares += agg.SlippageBpsSquares
+	sum.Notional += agg.Notional
+	sum.WeightedSlippageSum += agg.WeightedSlippageSum
+}
+
+// bucket derives the statistics of a bucket from its sums
+func (a *ExecutionAnalytics) bucket(sum *models.ExecutionAggregate) ExecutionBucket {
+	b := ExecutionBucket{Bucket: sum.Bucket, Count: sum.Count, Notional: sum.Notional}
+	if sum.Count == 0 {
+		return b
+	}
+	n := float64(sum.Count)
+	b.MeanSlippageBps = sum.SlippageBpsSum / n
+	b.StdDevSlippageBps = math.Sqrt(math.Max(sum.SlippageBpsSquares/n-b.MeanSlippageBps*b.MeanSlippageBps, 0))
+	if sum.Notional > 0 {
+		b.WeightedSlippageBps = sum.WeightedSlippageSum / sum.Notional
+	}
+	b.AdverseRatio = float64(sum.Adverse) / n
+	b.SuggestedSlippageBps = math.Max(b.MeanSlippageBps+a.config.SuggestionStdDevs*b.StdDevSlippageBps, 0)
+	return b
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// defaultExecutionDays is the number of days reported without a date range
+const defaultExecutionDays = 30
+
+// handleExecutionReport returns the execution quality of the fills of the
+// days from startDate through endDate, or of the last days days including
+// today, 30 by default
+func (s *Service) handleExecutionReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.execution == nil {
+		http.Error(w, "Execution analytics not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	query := r.URL.Query()
+	now := time.Now().UTC()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1)
+	days := defaultExecutionDays
+	if v := query.Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "Invalid days", http.StatusBadRequest)
+			return
+		}
+		days = n
+	}
+	from := to.AddDate(0, 0, -days)
+
+	if v := query.Get("startDate"); v != "" {
+		startDate, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "Invalid start date", http.StatusBadRequest)
+			return
+		}
+		from = startDate
+	}
+	if v := query.Get("endDate"); v != "" {
+		endDate, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "Invalid end date", http.StatusBadRequest)
+			return
+		}
+		to = endDate.AddDate(0, 0, 1)
+	}
+	if !from.Before(to) {
+		http.Error(w, ErrInvalidDateRange.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report, err := s.execution.Report(r.Context(), from, to)
+	if err != nil {
+		http.Error(w, "Failed to build execution report: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, report)
+}
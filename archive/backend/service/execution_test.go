@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/leonzhao/trading-system/backend/models"
+)
+
+type memoryExecutionStore struct {
+	aggregates []*models.ExecutionAggregate
+	err        error
+}
+
+func (s *memoryExecutionStore) SaveExecutionAggregates(ctx context.Context, aggregates []*models.ExecutionAggregate) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.aggregates = append(s.aggregates, aggregates...)
+	return nil
+}
+
+func (s *memoryExecutionStore) ListExecutionAggregates(ctx context.Context, from, to time.Time) ([]*models.ExecutionAggregate, error) {
+	var listed []*models.ExecutionAggregate
+	for _, a := range s.aggregates {
+		if !a.Day.Before(from) && a.Day.Before(to) {
+			listed = append(listed, a)
+		}
+	}
+	return listed, s.err
+}
+
+func TestSlippageBps(t *testing.T) {
+	assert.InDelta(t, 10, SlippageBps(models.TradeSideBuy, 100, 100.1), 1e-9)
+	assert.InDelta(t, -10, SlippageBps(models.TradeSideBuy, 100, 99.9), 1e-9)
+	assert.InDelta(t, 10, SlippageBps(models.TradeSideSell, 100, 99.9), 1e-9)
+	assert.InDelta(t, -10, SlippageBps(models.TradeSideSell, 100, 100.1), 1e-9)
+}
+
+func TestExecutionAnalytics(t *testing.T) {
+	ctx := context.Background()
+	store := &memoryExecutionStore{}
+	analytics := NewExecutionAnalytics(store, nil, DefaultExecutionConfig())
+	at := time.Date(2024, 6, 1, 14, 30, 0, 0, time.UTC)
+
+	trades := []*models.Trade{
+		{TokenAddress: "SOL", Side: models.TradeSideBuy, Amount: 10, Price: 100.2, ExpectedPrice: 100, Venue: "jupiter", Timestamp: at},
+		{TokenAddress: "SOL", Side: models.TradeSideSell, Amount: 1, Price: 100.1, ExpectedPrice: 100, Venue: "jupiter", Timestamp: at.Add(time.Hour)},
+		{TokenAddress: "BONK", Side: models.TradeSideBuy, Amount: 1000, Price: 0.01, ExpectedPrice: 0.01, Timestamp: at.AddDate(0, 0, 1)},
+	}
+	for _, trade := range trades {
+		require.NoError(t, analytics.Record(ctx, trade))
+	}
+	assert.InDelta(t, 20, trades[0].SlippageBps, 1e-9)
+	assert.InDelta(t, -10, trades[1].SlippageBps, 1e-9)
+	assert.Len(t, store.aggregates, 12, "one aggregate per dimension")
+
+	assert.ErrorIs(t, analytics.Record(ctx, &models.Trade{Price: 1}), ErrNoExpectedPrice)
+
+	day := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	report, err := analytics.Report(ctx, day, day.AddDate(0, 0, 2))
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Overall.Count)
+	assert.InDelta(t, 10.0/3, report.Overall.MeanSlippageBps, 1e-9)
+	assert.InDelta(t, 1.0/3, report.Overall.AdverseRatio, 1e-9)
+
+	require.Len(t, report.ByToken, 2)
+	sol := report.ByToken[1]
+	assert.Equal(t, "SOL", sol.Bucket)
+	assert.Equal(t, 2, sol.Count)
+	assert.InDelta(t, 5, sol.MeanSlippageBps, 1e-9)
+	assert.InDelta(t, 15, sol.StdDevSlippageBps, 1e-9)
+	assert.InDelta(t, 35, sol.SuggestedSlippageBps, 1e-9)
+	// 1002 of notional at 20 bps and 100.1 at -10 bps
+	assert.InDelta(t, (1002*20-100.1*10)/1102.1, sol.WeightedSlippageBps, 1e-9)
+
+	require.Len(t, report.ByVenue, 2)
+	assert.Equal(t, "jupiter", report.ByVenue[0].Bucket)
+	assert.Equal(t, unknownVenue, report.ByVenue[1].Bucket)
+
+	buckets := make([]string, 0, len(report.BySize))
+	for _, b := range report.BySize {
+		buckets = append(buckets, b.Bucket)
+	}
+	assert.ElementsMatch(t, []string{"<100", "100-1000", "1000-10000"}, buckets)
+
+	require.Len(t, report.ByHour, 2)
+	assert.Equal(t, "14", report.ByHour[0].Bucket)
+	assert.Equal(t, 2, report.ByHour[0].Count)
+
+	report, err = analytics.Report(ctx, day, day.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Overall.Count)
+
+	store.err = errors.New("unavailable")
+	_, err = analytics.Report(ctx, day, day.AddDate(0, 0, 1))
+	assert.ErrorIs(t, err, store.err)
+}
@@ -0,0 +1,373 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/monitoring"
+)
+
+// ErrNoPerformanceReport is returned before the first performance refresh
+var ErrNoPerformanceReport = errors.New("no performance report yet")
+
+// PerformanceStore is the subset of the repository used by the performance service
+type PerformanceStore interface {
+	StatsStore
+	GetDailyStatsRange(ctx context.Context, startDate, endDate time.Time) ([]*models.DailyStats, error)
+	GetOpenPositions(ctx context.Context) ([]*models.Position, error)
+}
+
+// PerformanceConfig contains performance reporting configuration
+type PerformanceConfig struct {
+	// Interval is the time between refreshes
+	Interval time.Duration
+	// Windows are the rolling windows, in days, of the risk-adjusted metrics.
+	// The longest window also bounds the PnL attribution.
+	Windows []int
+	// StartingBalance opens the first recorded day
+	StartingBalance float64
+	// PeriodsPerYear annualizes the daily Sharpe and Sortino ratios
+	PeriodsPerYear float64
+}
+
+// DefaultPerformanceConfig returns default performance reporting configuration
+func DefaultPerformanceConfig() PerformanceConfig {
+	return PerformanceConfig{
+		Interval:       5 * time.Minute,
+		Windows:        []int{7, 30, 90},
+		PeriodsPerYear: 365,
+	}
+}
+
+// PnLAttribution is the PnL of one token, strategy or day
+type PnLAttribution struct {
+	Key           string  `json:"key"`
+	RealizedPnL   float64 `json:"realizedPnl"`
+	UnrealizedPnL float64 `json:"unrealizedPnl"`
+	Fees          float64 `json:"fees"`
+	NetPnL        float64 `json:"netPnl"`
+	Trades        int     `json:"trades"`
+	Volume        float64 `json:"volume"`
+}
+
+// WindowMetrics are the risk-adjusted returns over a rolling window
+type WindowMetrics struct {
+	Days         int     `json:"days"`
+	Return       float64 `json:"return"`
+	SharpeRatio  float64 `json:"sharpeRatio"`
+	SortinoRatio float64 `json:"sortinoRatio"`
+	MaxDrawdown  float64 `json:"maxDrawdown"`
+}
+
+// PerformanceReport is the result of a performance refresh
+type PerformanceReport struct {
+	GeneratedAt time.Time          `json:"generatedAt"`
+	Today       *models.DailyStats `json:"today"`
+	ByToken     []PnLAttribution   `json:"byToken"`
+	ByStrategy  []PnLAttribution   `json:"byStrategy"`
+	ByDay       []PnLAttribution   `json:"byDay"`
+	Windows     []WindowMetrics    `json:"windows"`
+}
+
+// PerformanceService keeps the daily stats of the current day up to date and
+// reports PnL attribution and rolling risk-adjusted returns
+type PerformanceService struct {
+	store   PerformanceStore
+	monitor monitoring.IMonitor
+	config  PerformanceConfig
+	last    *PerformanceReport
+	mu      sync.RWMutex
+}
+
+// NewPerformanceService creates a new performance service
+func NewPerformanceService(store PerformanceStore, monitor monitoring.IMonitor, config PerformanceConfig) *PerformanceService {
+	defaults := DefaultPerformanceConfig()
+	if config.Interval <= 0 {
+		config.Interval = defaults.Interval
+	}
+	if len(config.Windows) == 0 {
+		config.Windows = defaults.Windows
+	}
+	if config.PeriodsPerYear <= 0 {
+		config.PeriodsPerYear = defaults.PeriodsPerYear
+	}
+	windows := append([]int(nil), config.Windows...)
+	sort.Ints(windows)
+	config.Windows = windows
+
+	return &PerformanceService{
+		store:   store,
+		monitor: monitor,
+		config:  config,
+	}
+}
+
+// Start refreshes the report every interval until ctx is done
+func (p *PerformanceService) Start(ctx context.Context) {
+	ticker := time.NewTicker(p.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := p.Refresh(ctx, time.Now()); err != nil && ctx.Err() == nil && p.monitor != nil {
+			p.monitor.RecordEvent(ctx, monitoring.Event{
+				Type:     monitoring.MetricSystem,
+				Severity: monitoring.SeverityError,
+				Message:  "Performance refresh failed",
+				Details:  map[string]interface{}{"error": err.Error()},
+			})
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Last returns the report of the last refresh
+func (p *PerformanceService) Last() (*PerformanceReport, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.last == nil {
+		return nil, ErrNoPerformanceReport
+	}
+	return p.last, nil
+}
+
+// Refresh recomputes and persists the daily stats of the day of now, then
+// rebuilds the attribution and rolling metrics
+func (p *PerformanceService) Refresh(ctx context.Context, now time.Time) (*PerformanceReport, error) {
+	today := startOfDay(now)
+	longest := p.config.Windows[len(p.config.Windows)-1]
+	since := today.AddDate(0, 0, -(longest - 1))
+
+	openingBalance, err := p.openingBalance(ctx, today)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := deriveDailyStats(ctx, p.store, today, openingBalance)
+	if err != nil {
+		return nil, err
+	}
+
+	days, err := p.store.GetDailyStatsRange(ctx, since, today)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily stats: %w", err)
+	}
+	days = withDay(days, stats)
+
+	report := &PerformanceReport{GeneratedAt: now, Today: stats}
+	for _, window := range p.config.Windows {
+		report.Windows = append(report.Windows, windowMetrics(days, window, today, p.config.PeriodsPerYear))
+	}
+	stats.SharpeRatio = report.Windows[0].SharpeRatio
+
+	if err := p.store.SaveDailyStats(ctx, stats); err != nil {
+		return nil, fmt.Errorf("failed to save daily stats: %w", err)
+	}
+
+	end := now
+	trades, err := p.store.ListTrades(ctx, &models.TradeFilter{
+		Status:    []models.TradeStatus{models.TradeExecuted},
+		StartTime: &since,
+		EndTime:   &end,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trades: %w", err)
+	}
+	positions, err := p.store.GetOpenPositions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open positions: %w", err)
+	}
+
+	report.ByToken, report.ByStrategy = attributePnL(trades, positions)
+	for _, day := range days {
+		report.ByDay = append(report.ByDay, PnLAttribution{
+			Key:         day.Date.Format("2006-01-02"),
+			RealizedPnL: day.RealizedPnL,
+			Fees:        day.Commissions,
+			NetPnL:      day.RealizedPnL - day.Commissions,
+			Trades:      day.TotalTrades,
+			Volume:      day.Volume,
+		})
+	}
+
+	p.mu.Lock()
+	p.last = report
+	p.mu.Unlock()
+
+	p.recordMetrics(ctx, report)
+	return report, nil
+}
+
+// openingBalance returns the recorded opening balance of a day, or the close
+// of the previous day when the day has none yet
+func (p *PerformanceService) openingBalance(ctx context.Context, day time.Time) (float64, error) {
+	stored, err := p.store.GetDailyStats(ctx, day)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get daily stats for %s: %w", day.Format("2006-01-02"), err)
+	}
+	if stored != nil && stored.StartBalance != 0 {
+		return stored.StartBalance, nil
+	}
+
+	previous, err := p.store.GetDailyStats(ctx, day.AddDate(0, 0, -1))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get daily stats for %s: %w", day.AddDate(0, 0, -1).Format("2006-01-02"), err)
+	}
+	if previous != nil && previous.EndBalance != 0 {
+		return previous.EndBalance, nil
+	}
+	return p.config.StartingBalance, nil
+}
+
+func (p *PerformanceService) recordMetrics(ctx context.Context, report *PerformanceReport) {
+	if p.monitor == nil {
+		return
+	}
+	for _, w := range report.Windows {
+		tags := map[string]string{"window": fmt.Sprintf("%dd", w.Days)}
+		p.monitor.RecordMetric(ctx, "performance_sharpe_ratio", w.SharpeRatio, tags)
+		p.monitor.RecordMetric(ctx, "performance_sortino_ratio", w.SortinoRatio, tags)
+		p.monitor.RecordMetric(ctx, "performance_max_drawdown", w.MaxDrawdown, tags)
+	}
+	for _, a := range report.ByToken {
+		p.monitor.RecordMetric(ctx, "performance_net_pnl", a.NetPnL, map[string]string{"token": a.Key})
+	}
+	for _, a := range report.ByStrategy {
+		p.monitor.RecordMetric(ctx, "performance_net_pnl", a.NetPnL, map[string]string{"strategy": a.Key})
+	}
+}
+
+// withDay returns days with day replacing the stored stats of its date
+func withDay(days []*models.DailyStats, day *models.DailyStats) []*models.DailyStats {
+	merged := make([]*models.DailyStats, 0, len(days)+1)
+	for _, d := range days {
+		if !startOfDay(d.Date).Equal(day.Date) {
+			merged = append(merged, d)
+		}
+	}
+	merged = append(merged, day)
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].Date.Before(merged[j].Date)
+	})
+	return merged
+}
+
+// windowMetrics computes the returns of the days in the window ending on
+// today. Days without an opening balance have no defined return and are
+// skipped.
+func windowMetrics(days []*models.DailyStats, window int, today time.Time, periodsPerYear float64) WindowMetrics {
+	metrics := WindowMetrics{Days: window}
+	since := today.AddDate(0, 0, -(window - 1))
+
+	var returns []float64
+	var peak, first, last float64
+	for _, day := range days {
+		if day.Date.Before(since) || day.StartBalance <= 0 {
+			continue
+		}
+		if first == 0 {
+			first, peak = day.StartBalance, day.StartBalance
+		}
+		returns = append(returns, (day.EndBalance-day.StartBalance)/day.StartBalance)
+		last = day.EndBalance
+
+		if day.EndBalance > peak {
+			peak = day.EndBalance
+		}
+		if drawdown := (peak - day.EndBalance) / peak; drawdown > metrics.MaxDrawdown {
+			metrics.MaxDrawdown = drawdown
+		}
+	}
+	if len(returns) == 0 {
+		return metrics
+	}
+
+	metrics.Return = (last - first) / first
+	mean, std, downside := returnMoments(returns)
+	annualize := math.Sqrt(periodsPerYear)
+	if std > 0 {
+		metrics.SharpeRatio = mean / std * annualize
+	}
+	if downside > 0 {
+		metrics.SortinoRatio = mean / downside * annualize
+	}
+	return metrics
+}
+
+// returnMoments returns the mean, the sample standard deviation and the
+// downside deviation below zero of the returns
+func returnMoments(returns []float64) (mean, std, downside float64) {
+	n := float64(len(returns))
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= n
+
+	var variance, downsideSum float64
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+		if r < 0 {
+			downsideSum += r * r
+		}
+	}
+	if n > 1 {
+		std = math.Sqrt(variance / (n - 1))
+	}
+	downside = math.Sqrt(downsideSum / n)
+	return mean, std, downside
+}
+
+// attributePnL sums realized PnL per token and per strategy, and unrealized
+// PnL of the open positions per token. Trades without a strategy are
+// attributed to "manual".
+func attributePnL(trades []*models.Trade, positions []*models.Position) (byToken, byStrategy []PnLAttribution) {
+	tokens := make(map[string]*PnLAttribution)
+	strategies := make(map[string]*PnLAttribution)
+	get := func(m map[string]*PnLAttribution, key string) *PnLAttribution {
+		a, ok := m[key]
+		if !ok {
+			a = &PnLAttribution{Key: key}
+			m[key] = a
+		}
+		return a
+	}
+
+	for _, trade := range trades {
+		strategy := trade.Strategy
+		if strategy == "" {
+			strategy = "manual"
+		}
+		for _, a := range []*PnLAttribution{get(tokens, trade.TokenAddress), get(strategies, strategy)} {
+			a.RealizedPnL += trade.Profit
+			a.Fees += trade.Fee
+			a.Trades++
+			a.Volume += trade.Value
+		}
+	}
+	for _, position := range positions {
+		get(tokens, position.TokenAddress).UnrealizedPnL += position.UnrealizedPnL
+	}
+
+	return sortedAttribution(tokens), sortedAttribution(strategies)
+}
+
+func sortedAttribution(m map[string]*PnLAttribution) []PnLAttribution {
+	attribution := make([]PnLAttribution, 0, len(m))
+	for _, a := range m {
+		a.NetPnL = a.RealizedPnL + a.UnrealizedPnL - a.Fees
+		attribution = append(attribution, *a)
+	}
+	sort.Slice(attribution, func(i, j int) bool {
+		return attribution[i].Key < attribution[j].Key
+	})
+	return attribution
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// handlePerformance returns the last performance report. With refresh=true
+// the report is recomputed first.
+func (s *Service) handlePerformance(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.performance == nil {
+		http.Error(w, "Performance reporting not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	if r.URL.Query().Get("refresh") == "true" {
+		report, err := s.performance.Refresh(r.Context(), time.Now())
+		if err != nil {
+			http.Error(w, "Failed to refresh performance: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, report)
+		return
+	}
+
+	report, err := s.performance.Last()
+	if errors.Is(err, ErrNoPerformanceReport) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	writeJSON(w, report)
+}
+
+// handlePerformanceMetrics exposes the last performance report in the
+// Prometheus text format
+func (s *Service) handlePerformanceMetrics(w http.ResponseWriter, r *http.Request) {
+	if s.performance == nil {
+		http.Error(w, "Performance reporting not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	report, err := s.performance.Last()
+	if err != nil {
+		return
+	}
+	fmt.Fprint(w, prometheusMetrics(report))
+}
+
+func prometheusMetrics(report *PerformanceReport) string {
+	var b strings.Builder
+	gauge := func(name, help string, samples func(sample func(labels string, value float64))) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		samples(func(labels string, value float64) {
+			fmt.Fprintf(&b, "%s%s %g\n", name, labels, value)
+		})
+	}
+	label := func(name, value string) string {
+		return fmt.Sprintf("{%s=%q}", name, value)
+	}
+
+	gauge("trading_realized_pnl", "Realized PnL over the longest window.", func(sample func(string, float64)) {
+		for _, a := range report.ByToken {
+			sample(label("token", a.Key), a.RealizedPnL)
+		}
+	})
+	gauge("trading_unrealized_pnl", "Unrealized PnL of open positions.", func(sample func(string, float64)) {
+		for _, a := range report.ByToken {
+			sample(label("token", a.Key), a.UnrealizedPnL)
+		}
+	})
+	gauge("trading_strategy_realized_pnl", "Realized PnL per strategy over the longest window.", func(sample func(string, float64)) {
+		for _, a := range report.ByStrategy {
+			sample(label("strategy", a.Key), a.RealizedPnL)
+		}
+	})
+	if report.Today != nil {
+		gauge("trading_daily_realized_pnl", "Realized PnL of the current day.", func(sample func(string, float64)) {
+			sample("", report.Today.RealizedPnL)
+		})
+	}
+	for _, metric := range []struct {
+		name, help string
+		value      func(WindowMetrics) float64
+	}{
+		{"trading_sharpe_ratio", "Annualized Sharpe ratio of daily returns.", func(m WindowMetrics) float64 { return m.SharpeRatio }},
+		{"trading_sortino_ratio", "Annualized Sortino ratio of daily returns.", func(m WindowMetrics) float64 { return m.SortinoRatio }},
+		{"trading_max_drawdown", "Maximum drawdown of the daily closing balance.", func(m WindowMetrics) float64 { return m.MaxDrawdown }},
+	} {
+		gauge(metric.name, metric.help, func(sample func(string, float64)) {
+			for _, m := range report.Windows {
+				sample(label("window", fmt.Sprintf("%dd", m.Days)), metric.value(m))
+			}
+		})
+	}
+	return b.String()
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/leonzhao/trading-system/backend/models"
+)
+
+type memoryPerformanceStore struct {
+	memoryStatsStore
+	positions []*models.Position
+}
+
+func (s *memoryPerformanceStore) GetDailyStatsRange(ctx context.Context, startDate, endDate time.Time) ([]*models.DailyStats, error) {
+	var days []*models.DailyStats
+	for date, stats := range s.stats {
+		if !date.Before(startDate) && !date.After(endDate) {
+			days = append(days, stats)
+		}
+	}
+	return days, nil
+}
+
+func (s *memoryPerformanceStore) GetOpenPositions(ctx context.Context) ([]*models.Position, error) {
+	return s.positions, nil
+}
+
+func TestPerformanceService(t *testing.T) {
+	ctx := context.Background()
+	today := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	now := today.Add(12 * time.Hour)
+
+	store := &memoryPerformanceStore{
+		memoryStatsStore: memoryStatsStore{
+			trades: []*models.Trade{
+				{TokenAddress: "SOL", Strategy: "momentum", Profit: 100, Fee: 5, Value: 1000, Status: models.TradeExecuted, Timestamp: today.Add(time.Hour)},
+				{TokenAddress: "BONK", Profit: -30, Fee: 2, Value: 400, Status: models.TradeExecuted, Timestamp: today.Add(2 * time.Hour)},
+				{TokenAddress: "SOL", Strategy: "momentum", Profit: 50, Fee: 1, Value: 600, Status: models.TradeExecuted, Timestamp: today.AddDate(0, 0, -2)},
+			},
+			stats: map[time.Time]*models.DailyStats{
+				today.AddDate(0, 0, -3): {Date: today.AddDate(0, 0, -3), StartBalance: 10000, EndBalance: 10200},
+				today.AddDate(0, 0, -2): {Date: today.AddDate(0, 0, -2), StartBalance: 10200, EndBalance: 9900},
+				today.AddDate(0, 0, -1): {Date: today.AddDate(0, 0, -1), StartBalance: 9900, EndBalance: 10000},
+			},
+		},
+		positions: []*models.Position{{TokenAddress: "SOL", UnrealizedPnL: 25}},
+	}
+
+	performance := NewPerformanceService(store, nil, PerformanceConfig{Windows: []int{30, 7}})
+	_, err := performance.Last()
+	assert.ErrorIs(t, err, ErrNoPerformanceReport)
+
+	report, err := performance.Refresh(ctx, now)
+	require.NoError(t, err)
+
+	t.Run("today's stats open at yesterday's close and are saved", func(t *testing.T) {
+		assert.Equal(t, 10000.0, report.Today.StartBalance)
+		assert.Equal(t, 10063.0, report.Today.EndBalance)
+		assert.Equal(t, 2, report.Today.TotalTrades)
+		assert.Same(t, report.Today, store.stats[today])
+		assert.Equal(t, report.Windows[0].SharpeRatio, report.Today.SharpeRatio)
+	})
+
+	t.Run("PnL is attributed per token, strategy and day", func(t *testing.T) {
+		require.Len(t, report.ByToken, 2)
+		assert.Equal(t, PnLAttribution{Key: "BONK", RealizedPnL: -30, Fees: 2, NetPnL: -32, Trades: 1, Volume: 400}, report.ByToken[0])
+		assert.Equal(t, PnLAttribution{Key: "SOL", RealizedPnL: 150, UnrealizedPnL: 25, Fees: 6, NetPnL: 169, Trades: 2, Volume: 1600}, report.ByToken[1])
+
+		require.Len(t, report.ByStrategy, 2)
+		assert.Equal(t, "manual", report.ByStrategy[0].Key)
+		assert.Equal(t, "momentum", report.ByStrategy[1].Key)
+		assert.Equal(t, 150.0, report.ByStrategy[1].RealizedPnL)
+
+		require.Len(t, report.ByDay, 4)
+		assert.Equal(t, "2024-03-10", report.ByDay[3].Key)
+		assert.Equal(t, 63.0, report.ByDay[3].NetPnL)
+	})
+
+	t.Run("rolling windows are sorted and measured on daily returns", func(t *testing.T) {
+		require.Len(t, report.Windows, 2)
+		assert.Equal(t, 7, report.Windows[0].Days)
+		assert.Equal(t, 30, report.Windows[1].Days)
+
+		w := report.Windows[0]
+		assert.InDelta(t, 0.0063, w.Return, 1e-9)
+		// Peak 10200 to trough 9900
+		assert.InDelta(t, 300.0/10200, w.MaxDrawdown, 1e-9)
+		assert.Greater(t, w.SharpeRatio, 0.0)
+		assert.Greater(t, w.SortinoRatio, w.SharpeRatio)
+	})
+
+	t.Run("last report and Prometheus exposition", func(t *testing.T) {
+		last, err := performance.Last()
+		require.NoError(t, err)
+		assert.Same(t, report, last)
+
+		metrics := prometheusMetrics(report)
+		assert.Contains(t, metrics, "# TYPE trading_realized_pnl gauge\n")
+		assert.Contains(t, metrics, `trading_unrealized_pnl{token="SOL"} 25`)
+		assert.Contains(t, metrics, `trading_strategy_realized_pnl{strategy="momentum"} 150`)
+		assert.Contains(t, metrics, `trading_max_drawdown{window="7d"}`)
+	})
+}
+
+func TestWindowMetricsWithoutReturns(t *testing.T) {
+	today := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	metrics := windowMetrics([]*models.DailyStats{{Date: today}}, 7, today, 365)
+	assert.Equal(t, WindowMetrics{Days: 7}, metrics)
+}
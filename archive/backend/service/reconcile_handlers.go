@@ -0,0 +1,126 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/repository"
+	"github.com/leonzhao/trading-system/backend/trading"
+)
+
+// Mismatch resolution actions
+const (
+	ResolveActionCorrect = "correct"
+	ResolveActionDismiss = "dismiss"
+)
+
+// ResolveMismatchRequest represents a request to resolve a flagged balance mismatch
+type ResolveMismatchRequest struct {
+	TokenAddress string `json:"tokenAddress"`
+	Action       string `json:"action"`
+	Operator     string `json:"operator"`
+	Note         string `json:"note"`
+}
+
+// handleReconcilePositions compares open positions to on-chain balances.
+// GET returns the pending mismatches; POST runs a new reconciliation.
+func (s *Service) handleReconcilePositions(w http.ResponseWriter, r *http.Request) {
+	if s.reconciler == nil {
+		http.Error(w, "Position reconciliation not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, s.reconciler.Pending())
+	case http.MethodPost:
+		mismatches, err := s.reconciler.Reconcile(r.Context())
+		if err != nil {
+			http.Error(w, "Failed to reconcile positions: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, mismatches)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleResolveMismatch applies or dismisses a flagged balance mismatch
+func (s *Service) handleResolveMismatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.reconciler == nil {
+		http.Error(w, "Position reconciliation not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req ResolveMismatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.TokenAddress == "" || req.Operator == "" {
+		http.Error(w, "Token address and operator are required", http.StatusBadRequest)
+		return
+	}
+
+	var record *models.ReconciliationRecord
+	var err error
+	switch req.Action {
+	case ResolveActionCorrect:
+		record, err = s.reconciler.ApplyCorrection(r.Context(), req.TokenAddress, req.Operator, req.Note)
+	case ResolveActionDismiss:
+		record, err = s.reconciler.Dismiss(r.Context(), req.TokenAddress, req.Operator, req.Note)
+	default:
+		http.Error(w, "Invalid action", http.StatusBadRequest)
+		return
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, trading.ErrNoPendingMismatch):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, trading.ErrMismatchChanged), errors.Is(err, trading.ErrNoPositionToCorrect):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Failed to resolve mismatch: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeJSON(w, record)
+}
+
+// handleReconciliationAudit lists reconciliation audit records
+func (s *Service) handleReconciliationAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	page, err := queryOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	records, err := s.repo.ListReconciliationRecords(r.Context(), &models.ReconciliationFilter{
+		TokenAddress: r.URL.Query().Get("token"),
+		Action:       models.ReconciliationAction(r.URL.Query().Get("action")),
+		QueryOptions: page,
+	})
+	if errors.Is(err, repository.ErrInvalidInput) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to list reconciliation records: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, records)
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/monitoring"
+)
+
+// ErrNoRetentionReport is returned before the first retention run
+var ErrNoRetentionReport = errors.New("no retention run yet")
+
+// RetentionStore is the subset of the repository used by the retention job
+type RetentionStore interface {
+	DownsampleKlines(ctx context.Context, target string, before time.Time) (int64, error)
+	DeleteKlinesBefore(ctx context.Context, interval string, before time.Time) (int64, error)
+	DeleteMarketDataBefore(ctx context.Context, before time.Time) (int64, error)
+	SaveEventAggregates(ctx context.Context, aggregates []*models.EventAggregate) error
+}
+
+// EventPruner removes recorded monitoring events, such as *monitoring.Monitor
+type EventPruner interface {
+	PruneEvents(before time.Time) []monitoring.Event
+}
+
+// RetentionConfig contains time-series retention configuration
+type RetentionConfig struct {
+	// Interval is the time between retention runs
+	Interval time.Duration
+	// RawRetention is how long raw klines, market data and monitoring
+	// events are kept. Older klines are downsampled into hourly candles and
+	// older events into hourly counts.
+	RawRetention time.Duration
+	// HourlyRetention is how long hourly candles are kept before they are
+	// downsampled into daily candles
+	HourlyRetention time.Duration
+	// DailyRetention is how long daily candles are kept. Zero keeps them
+	// forever.
+	DailyRetention time.Duration
+}
+
+// DefaultRetentionConfig returns default retention configuration
+func DefaultRetentionConfig() RetentionConfig {
+	return RetentionConfig{
+		Interval:        time.Hour,
+		RawRetention:    7 * 24 * time.Hour,
+		HourlyRetention: 90 * 24 * time.Hour,
+	}
+}
+
+// RetentionReport is the result of a retention run
+type RetentionReport struct {
+	StartedAt time.Time `json:"startedAt"`
+	Duration  string    `json:"duration"`
+	// KlinesHourly and KlinesDaily are the klines downsampled into hourly
+	// and daily candles
+	KlinesHourly      int64 `json:"klinesHourly"`
+	KlinesDaily       int64 `json:"klinesDaily"`
+	KlinesDeleted     int64 `json:"klinesDeleted"`
+	MarketDataDeleted int64 `json:"marketDataDeleted"`
+	EventsAggregated  int   `json:"eventsAggregated"`
+}
+
+// RetentionService ages out time-series data, downsampling it first
+type RetentionService struct {
+	store   RetentionStore
+	monitor monitoring.IMonitor
+	events  EventPruner
+	config  RetentionConfig
+	last    *RetentionReport
+	mu      sync.RWMutex
+	// running serializes runs
+	running sync.Mutex
+}
+
+// NewRetentionService creates a new retention service
+func NewRetentionService(store RetentionStore, monitor monitoring.IMonitor, config RetentionConfig) *RetentionService {
+	defaults := DefaultRetentionConfig()
+	if config.Interval <= 0 {
+		config.Interval = defaults.Interval
+	}
+	if config.RawRetention <= 0 {
+		config.RawRetention = defaults.RawRetention
+	}
+	if config.HourlyRetention < config.RawRetention {
+		config.HourlyRetention = max(defaults.HourlyRetention, config.RawRetention)
+	}
+	if config.DailyRetention > 0 && config.DailyRetention < config.HourlyRetention {
+		config.DailyRetention = config.HourlyRetention
+	}
+
+	return &RetentionService{
+		store:   store,
+		monitor: monitor,
+		config:  config,
+	}
+}
+
+// SetEventPruner sets where monitoring events are aged out of
+func (s *RetentionService) SetEventPruner(events EventPruner) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = events
+}
+
+// Start runs retention every interval until ctx is done
+func (s *RetentionService) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.Run(ctx, time.Now()); err != nil && ctx.Err() == nil && s.monitor != nil {
+			s.monitor.RecordEvent(ctx, monitoring.Event{
+				Type:     monitoring.MetricSystem,
+				Severity: monitoring.SeverityError,
+				Message:  "Data retention failed",
+				Details:  map[string]interface{}{"error": err.Error()},
+			})
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Last returns the report of the last completed run
+func (s *RetentionService) Last() (*RetentionReport, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.last == nil {
+		return nil, ErrNoRetentionReport
+	}
+	return s.last, nil
+}
+
+// Run ages out the data older than the retention windows as of now. Each
+// step is recorded as it completes, so a failed run still reports the
+// progress it made.
+func (s *RetentionService) Run(ctx context.Context, now time.Time) (*RetentionReport, error) {
+	s.running.Lock()
+	defer s.running.Unlock()
+
+	start := time.Now()
+	report := &RetentionReport{StartedAt: now}
+	rawBefore := now.Add(-s.config.RawRetention)
+
+	var err error
+	if report.KlinesHourly, err = s.store.DownsampleKlines(ctx, "1h", rawBefore); err != nil {
+		return report, fmt.Errorf("failed to downsample klines to hourly: %w", err)
+	}
+	s.recordProgress(ctx, "retention_klines_downsampled", report.KlinesHourly, map[string]string{"interval": "1h"})
+
+	if report.KlinesDaily, err = s.store.DownsampleKlines(ctx, "1d", now.Add(-s.config.HourlyRetention)); err != nil {
+		return report, fmt.Errorf("failed to downsample klines to daily: %w", err)
+	}
+	s.recordProgress(ctx, "retention_klines_downsampled", report.KlinesDaily, map[string]string{"interval": "1d"})
+
+	if s.config.DailyRetention > 0 {
+		if report.KlinesDeleted, err = s.store.DeleteKlinesBefore(ctx, "1d", now.Add(-s.config.DailyRetention)); err != nil {
+			return report, fmt.Errorf("failed to delete daily klines: %w", err)
+		}
+		s.recordProgress(ctx, "retention_klines_deleted", report.KlinesDeleted, map[string]string{"interval": "1d"})
+	}
+
+	if report.MarketDataDeleted, err = s.store.DeleteMarketDataBefore(ctx, rawBefore); err != nil {
+		return report, fmt.Errorf("failed to delete market data: %w", err)
+	}
+	s.recordProgress(ctx, "retention_market_data_deleted", report.MarketDataDeleted, nil)
+
+	s.mu.RLock()
+	events := s.events
+	s.mu.RUnlock()
+	if events != nil {
+		// Pruned events are lost if saving their counts fails, which only
+		// costs monitoring history
+		pruned := events.PruneEvents(rawBefore)
+		if err := s.store.SaveEventAggregates(ctx, aggregateEvents(pruned)); err != nil {
+			return report, fmt.Errorf("failed to save event aggregates: %w", err)
+		}
+		report.EventsAggregated = len(pruned)
+		s.recordProgress(ctx, "retention_events_aggregated", int64(report.EventsAggregated), nil)
+	}
+
+	report.Duration = time.Since(start).String()
+
+	s.mu.Lock()
+	s.last = report
+	s.mu.Unlock()
+	return report, nil
+}
+
+func (s *RetentionService) recordProgress(ctx context.Context, name string, count int64, tags map[string]string) {
+	if s.monitor != nil {
+		s.monitor.RecordMetric(ctx, name, float64(count), tags)
+	}
+}
+
+// aggregateEvents counts events by hour, type and severity
+func aggregateEvents(events []monitoring.Event) []*models.EventAggregate {
+	counts := make(map[models.EventAggregate]int)
+	for _, event := range events {
+		key := models.EventAggregate{
+			Hour:     event.Timestamp.UTC().Truncate(time.Hour),
+			Type:     string(event.Type),
+			Severity: string(event.Severity),
+		}
+		counts[key]++
+	}
+
+	aggregates := make([]*models.EventAggregate, 0, len(counts))
+	for key, count := range counts {
+		aggregate := key
+		aggregate.Count = count
+		aggregates = append(aggregates, &aggregate)
+	}
+	sort.Slice(aggregates, func(i, j int) bool {
+		a, b := aggregates[i], aggregates[j]
+		if !a.Hour.Equal(b.Hour) {
+			return a.Hour.Before(b.Hour)
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Severity < b.Severity
+	})
+	return aggregates
+}
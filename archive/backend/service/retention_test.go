@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/monitoring"
+)
+
+type memoryRetentionStore struct {
+	downsampled map[string]time.Time
+	deleted     map[string]time.Time
+	aggregates  []*models.EventAggregate
+	err         error
+}
+
+func (s *memoryRetentionStore) DownsampleKlines(ctx context.Context, target string, before time.Time) (int64, error) {
+	if s.err != nil && target == "1d" {
+		return 0, s.err
+	}
+	s.downsampled[target] = before
+	return 10, nil
+}
+
+func (s *memoryRetentionStore) DeleteKlinesBefore(ctx context.Context, interval string, before time.Time) (int64, error) {
+	s.deleted[interval] = before
+	return 3, nil
+}
+
+func (s *memoryRetentionStore) DeleteMarketDataBefore(ctx context.Context, before time.Time) (int64, error) {
+	s.deleted["market_data"] = before
+	return 2, nil
+}
+
+func (s *memoryRetentionStore) SaveEventAggregates(ctx context.Context, aggregates []*models.EventAggregate) error {
+	s.aggregates = append(s.aggregates, aggregates...)
+	return nil
+}
+
+type staticEventPruner []monitoring.Event
+
+func (p staticEventPruner) PruneEvents(before time.Time) []monitoring.Event {
+	var pruned []monitoring.Event
+	for _, event := range p {
+		if event.Timestamp.Before(before) {
+			pruned = append(pruned, event)
+		}
+	}
+	return pruned
+}
+
+func TestRetentionService(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 6, 1, 12, 30, 0, 0, time.UTC)
+	config := RetentionConfig{
+		RawRetention:    7 * 24 * time.Hour,
+		HourlyRetention: 30 * 24 * time.Hour,
+		DailyRetention:  365 * 24 * time.Hour,
+	}
+
+	t.Run("ages out each tier", func(t *testing.T) {
+		store := &memoryRetentionStore{downsampled: map[string]time.Time{}, deleted: map[string]time.Time{}}
+		old := now.AddDate(0, 0, -8)
+		retention := NewRetentionService(store, nil, config)
+		retention.SetEventPruner(staticEventPruner{
+			{Type: monitoring.MetricTrading, Severity: monitoring.SeverityInfo, Timestamp: old.Add(10 * time.Minute)},
+			{Type: monitoring.MetricTrading, Severity: monitoring.SeverityInfo, Timestamp: old.Add(20 * time.Minute)},
+			{Type: monitoring.MetricSystem, Severity: monitoring.SeverityError, Timestamp: old.Add(time.Hour)},
+			{Type: monitoring.MetricSystem, Severity: monitoring.SeverityError, Timestamp: now},
+		})
+
+		_, err := retention.Last()
+		assert.ErrorIs(t, err, ErrNoRetentionReport)
+
+		report, err := retention.Run(ctx, now)
+		require.NoError(t, err)
+
+		assert.Equal(t, now.AddDate(0, 0, -7), store.downsampled["1h"])
+		assert.Equal(t, now.AddDate(0, 0, -30), store.downsampled["1d"])
+		assert.Equal(t, now.AddDate(0, 0, -365), store.deleted["1d"])
+		assert.Equal(t, now.AddDate(0, 0, -7), store.deleted["market_data"])
+
+		assert.Equal(t, int64(10), report.KlinesHourly)
+		assert.Equal(t, int64(10), report.KlinesDaily)
+		assert.Equal(t, int64(3), report.KlinesDeleted)
+		assert.Equal(t, int64(2), report.MarketDataDeleted)
+		assert.Equal(t, 3, report.EventsAggregated)
+
+		hour := old.Truncate(time.Hour)
+		assert.Equal(t, []*models.EventAggregate{
+			{Hour: hour, Type: string(monitoring.MetricTrading), Severity: string(monitoring.SeverityInfo), Count: 2},
+			{Hour: hour.Add(time.Hour), Type: string(monitoring.MetricSystem), Severity: string(monitoring.SeverityError), Count: 1},
+		}, store.aggregates)
+
+		last, err := retention.Last()
+		require.NoError(t, err)
+		assert.Same(t, report, last)
+	})
+
+	t.Run("keeps daily candles by default", func(t *testing.T) {
+		store := &memoryRetentionStore{downsampled: map[string]time.Time{}, deleted: map[string]time.Time{}}
+		_, err := NewRetentionService(store, nil, RetentionConfig{}).Run(ctx, now)
+		require.NoError(t, err)
+
+		assert.Equal(t, now.AddDate(0, 0, -7), store.downsampled["1h"])
+		assert.Equal(t, now.AddDate(0, 0, -90), store.downsampled["1d"])
+		assert.NotContains(t, store.deleted, "1d")
+	})
+
+	t.Run("reports progress of a failed run", func(t *testing.T) {
+		store := &memoryRetentionStore{
+			downsampled: map[string]time.Time{},
+			deleted:     map[string]time.Time{},
+			err:         errors.New("merge failed"),
+		}
+		retention := NewRetentionService(store, nil, config)
+
+		report, err := retention.Run(ctx, now)
+		assert.ErrorIs(t, err, store.err)
+		assert.Equal(t, int64(10), report.KlinesHourly)
+		assert.NotContains(t, store.deleted, "market_data")
+
+		_, err = retention.Last()
+		assert.ErrorIs(t, err, ErrNoRetentionReport)
+	})
+}
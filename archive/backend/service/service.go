@@ -6,6 +6,8 @@ import (
 	"github.com/leonzhao/trading-system/backend/dex"
 	"github.com/leonzhao/trading-system/backend/monitoring"
 	"github.com/leonzhao/trading-system/backend/repository"
+	"github.com/leonzhao/trading-system/backend/trading"
+	"github.com/leonzhao/trading-system/backend/trading/watchlist"
 )
 
 // Service handles business logic
@@ -13,6 +15,12 @@ type Service struct {
 	repo      repository.Repository
 	dexClient dex.DexClient
 	monitor   monitoring.IMonitor
+
+	reconciler  *trading.Reconciler
+	ingestor    *SignalIngestor
+	performance *PerformanceService
+	execution   *ExecutionAnalytics
+	watchList   *watchlist.WatchList
 }
 
 // NewService creates a new service
@@ -24,6 +32,32 @@ func NewService(repo repository.Repository, dexClient dex.DexClient, monitor mon
 	}
 }
 
+// SetReconciler enables the position reconciliation endpoints
+func (s *Service) SetReconciler(reconciler *trading.Reconciler) {
+	s.reconciler = reconciler
+}
+
+// SetSignalIngestor enables the external signal webhook endpoints
+func (s *Service) SetSignalIngestor(ingestor *SignalIngestor) {
+	s.ingestor = ingestor
+}
+
+// SetPerformanceService enables the performance reporting endpoints
+func (s *Service) SetPerformanceService(performance *PerformanceService) {
+	s.performance = performance
+}
+
+// SetExecutionAnalytics enables slippage recording of trades and the
+// execution quality report
+func (s *Service) SetExecutionAnalytics(execution *ExecutionAnalytics) {
+	s.execution = execution
+}
+
+// SetWatchList enables the watch list endpoints
+func (s *Service) SetWatchList(watchList *watchlist.WatchList) {
+	s.watchList = watchList
+}
+
 // Routes registers all service routes
 func (s *Service) Routes(mux *http.ServeMux) {
 	// Trade routes
@@ -33,4 +67,29 @@ func (s *Service) Routes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/v1/market/data", s.handleGetMarketData)
 	mux.HandleFunc("/api/v1/market/orderbook", s.handleGetOrderBook)
 	mux.HandleFunc("/api/v1/market/quote", s.handleGetQuote)
+
+	// Analysis routes
+	mux.HandleFunc("/api/v1/analysis/", s.handleAnalysisReport)
+
+	// Stats routes
+	mux.HandleFunc("/api/v1/stats/recompute", s.handleRecomputeStats)
+	mux.HandleFunc("/api/v1/performance", s.handlePerformance)
+	mux.HandleFunc("/metrics/performance", s.handlePerformanceMetrics)
+	mux.HandleFunc("/api/v1/analytics/execution", s.handleExecutionReport)
+
+	// Watch list routes
+	mux.HandleFunc("/api/v1/watchlist/tokens", s.handleWatchList)
+	mux.HandleFunc("/api/v1/watchlist/tokens/", s.handleWatchListToken)
+
+	// Strategy sandbox routes
+	mux.HandleFunc("/api/v1/simulate", s.handleSimulate)
+
+	// Position reconciliation routes
+	mux.HandleFunc("/api/v1/positions/reconcile", s.handleReconcilePositions)
+	mux.HandleFunc("/api/v1/positions/reconcile/resolve", s.handleResolveMismatch)
+	mux.HandleFunc("/api/v1/positions/reconcile/audit", s.handleReconciliationAudit)
+
+	// External signal webhook routes
+	mux.HandleFunc("/api/v1/webhooks/signals/", s.handleSignalWebhook)
+	mux.HandleFunc("/api/v1/webhooks/performance", s.handleWebhookPerformance)
 }
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/leonzhao/trading-system/backend/trading/strategy"
+)
+
+// handleSimulate runs a strategy over the stored market data of a token and
+// returns the signals and hypothetical fills it would have produced, without
+// placing orders
+func (s *Service) handleSimulate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req strategy.SimulationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	result, err := strategy.Simulate(r.Context(), s.repo, req)
+	switch {
+	case errors.Is(err, strategy.ErrNoSimulationData):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, strategy.ErrInvalidSimulation), errors.Is(err, strategy.ErrNoSimulator):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, "Simulation failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, result)
+}
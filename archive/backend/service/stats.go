@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/trading/risk"
+)
+
+// ErrInvalidDateRange is returned when a recompute range is empty or inverted
+var ErrInvalidDateRange = errors.New("invalid date range")
+
+// StatsStore is the subset of the repository used to recompute daily stats
+type StatsStore interface {
+	ListTrades(ctx context.Context, filter *models.TradeFilter) ([]*models.Trade, error)
+	GetDailyStats(ctx context.Context, date time.Time) (*models.DailyStats, error)
+	SaveDailyStats(ctx context.Context, stats *models.DailyStats) error
+}
+
+// FieldChange describes a single changed statistic
+type FieldChange struct {
+	Field  string  `json:"field"`
+	Before float64 `json:"before"`
+	After  float64 `json:"after"`
+	Delta  float64 `json:"delta"`
+}
+
+// DayRecompute contains the stored and recomputed stats for one day
+type DayRecompute struct {
+	Date    time.Time          `json:"date"`
+	Before  *models.DailyStats `json:"before"`
+	After   *models.DailyStats `json:"after"`
+	Changes []FieldChange      `json:"changes"`
+}
+
+// StatsRollup summarizes daily stats over a date range
+type StatsRollup struct {
+	TotalTrades int     `json:"totalTrades"`
+	Volume      float64 `json:"volume"`
+	RealizedPnL float64 `json:"realizedPnl"`
+	Commissions float64 `json:"commissions"`
+	NetPnL      float64 `json:"netPnl"`
+	WinRate     float64 `json:"winRate"`
+	EndBalance  float64 `json:"endBalance"`
+	MaxDrawdown float64 `json:"maxDrawdown"`
+}
+
+// RecomputeResult contains the outcome of a daily stats recompute
+type RecomputeResult struct {
+	StartDate     time.Time      `json:"startDate"`
+	EndDate       time.Time      `json:"endDate"`
+	DryRun        bool           `json:"dryRun"`
+	Days          []DayRecompute `json:"days"`
+	RollupBefore  StatsRollup    `json:"rollupBefore"`
+	RollupAfter   StatsRollup    `json:"rollupAfter"`
+	RollupChanges []FieldChange  `json:"rollupChanges"`
+}
+
+// RecomputeDailyStats re-derives daily stats for the given date range from
+// executed trade records. With dryRun set the stored stats are left untouched
+// and only the diff is returned.
+func RecomputeDailyStats(ctx context.Context, store StatsStore, startDate, endDate time.Time, dryRun bool) (*RecomputeResult, error) {
+	start := startOfDay(startDate)
+	end := startOfDay(endDate)
+	if end.Before(start) {
+		return nil, ErrInvalidDateRange
+	}
+
+	result := &RecomputeResult{
+		StartDate: start,
+		EndDate:   end,
+		DryRun:    dryRun,
+	}
+
+	var before, after []*models.DailyStats
+	carryBalance := 0.0
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		stored, err := store.GetDailyStats(ctx, day)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get daily stats for %s: %w", day.Format("2006-01-02"), err)
+		}
+		if stored == nil {
+			stored = &models.DailyStats{Date: day}
+		}
+
+		// The first day keeps its recorded opening balance; later days open
+		// at the recomputed close of the previous day.
+		startBalance := stored.StartBalance
+		if day.After(start) {
+			startBalance = carryBalance
+		}
+
+		recomputed, err := deriveDailyStats(ctx, store, day, startBalance)
+		if err != nil {
+			return nil, err
+		}
+		carryBalance = recomputed.EndBalance
+
+		result.Days = append(result.Days, DayRecompute{
+			Date:    day,
+			Before:  stored,
+			After:   recomputed,
+			Changes: diffDailyStats(stored, recomputed),
+		})
+		before = append(before, stored)
+		after = append(after, recomputed)
+	}
+
+	result.RollupBefore = rollupDailyStats(before)
+	result.RollupAfter = rollupDailyStats(after)
+	result.RollupChanges = diffRollups(result.RollupBefore, result.RollupAfter)
+
+	if dryRun {
+		return result, nil
+	}
+
+	for _, day := range result.Days {
+		if len(day.Changes) == 0 {
+			continue
+		}
+		if err := store.SaveDailyStats(ctx, day.After); err != nil {
+			return nil, fmt.Errorf("failed to save daily stats for %s: %w", day.Date.Format("2006-01-02"), err)
+		}
+	}
+
+	return result, nil
+}
+
+// deriveDailyStats rebuilds the stats for a single day from its executed trades
+func deriveDailyStats(ctx context.Context, store StatsStore, day time.Time, startBalance float64) (*models.DailyStats, error) {
+	dayEnd := day.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	trades, err := store.ListTrades(ctx, &models.TradeFilter{
+		Status:    []models.TradeStatus{models.TradeExecuted},
+		StartTime: &day,
+		EndTime:   &dayEnd,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trades for %s: %w", day.Format("2006-01-02"), err)
+	}
+
+	sort.Slice(trades, func(i, j int) bool {
+		return trades[i].Timestamp.Before(trades[j].Timestamp)
+	})
+
+	stats := risk.NewDailyStats(day, startBalance)
+	peak := startBalance
+	for _, trade := range trades {
+		risk.UpdateStats(stats, trade.Profit, trade.Fee, trade.Value)
+
+		if stats.EndBalance > peak {
+			peak = stats.EndBalance
+		}
+		if peak > 0 {
+			risk.UpdateDrawdown(stats, (peak-stats.EndBalance)/peak)
+		}
+	}
+
+	return stats, nil
+}
+
+func diffDailyStats(before, after *models.DailyStats) []FieldChange {
+	return diffFields([]fieldPair{
+		{"startBalance", before.StartBalance, after.StartBalance},
+		{"endBalance", before.EndBalance, after.EndBalance},
+		{"volume", before.Volume, after.Volume},
+		{"realizedPnl", before.RealizedPnL, after.RealizedPnL},
+		{"commissions", before.Commissions, after.Commissions},
+		{"totalTrades", float64(before.TotalTrades), float64(after.TotalTrades)},
+		{"winningTrades", float64(before.WinningTrades), float64(after.WinningTrades)},
+		{"losingTrades", float64(before.LosingTrades), float64(after.LosingTrades)},
+		{"maxDrawdown", before.MaxDrawdown, after.MaxDrawdown},
+		{"profitFactor", before.ProfitFactor, after.ProfitFactor},
+		{"winRate", before.WinRate, after.WinRate},
+		{"largestWin", before.LargestWin, after.LargestWin},
+		{"largestLoss", before.LargestLoss, after.LargestLoss},
+	})
+}
+
+func diffRollups(before, after StatsRollup) []FieldChange {
+	return diffFields([]fieldPair{
+		{"totalTrades", float64(before.TotalTrades), float64(after.TotalTrades)},
+		{"volume", before.Volume, after.Volume},
+		{"realizedPnl", before.RealizedPnL, after.RealizedPnL},
+		{"commissions", before.Commissions, after.Commissions},
+		{"netPnl", before.NetPnL, after.NetPnL},
+		{"winRate", before.WinRate, after.WinRate},
+		{"endBalance", before.EndBalance, after.EndBalance},
+		{"maxDrawdown", before.MaxDrawdown, after.MaxDrawdown},
+	})
+}
+
+type fieldPair struct {
+	field         string
+	before, after float64
+}
+
+func diffFields(pairs []fieldPair) []FieldChange {
+	changes := make([]FieldChange, 0)
+	for _, p := range pairs {
+		if p.before == p.after {
+			continue
+		}
+		changes = append(changes, FieldChange{
+			Field:  p.field,
+			Before: p.before,
+			After:  p.after,
+			Delta:  p.after - p.before,
+		})
+	}
+	return changes
+}
+
+func rollupDailyStats(days []*models.DailyStats) StatsRollup {
+	var rollup StatsRollup
+	winning := 0
+	for _, stats := range days {
+		rollup.TotalTrades += stats.TotalTrades
+		rollup.Volume += stats.Volume
+		rollup.RealizedPnL += stats.RealizedPnL
+		rollup.Commissions += stats.Commissions
+		rollup.EndBalance = stats.EndBalance
+		winning += stats.WinningTrades
+		if stats.MaxDrawdown > rollup.MaxDrawdown {
+			rollup.MaxDrawdown = stats.MaxDrawdown
+		}
+	}
+
+	rollup.NetPnL = rollup.RealizedPnL - rollup.Commissions
+	if rollup.TotalTrades > 0 {
+		rollup.WinRate = float64(winning) / float64(rollup.TotalTrades) * 100
+	}
+	return rollup
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
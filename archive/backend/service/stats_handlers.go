@@ -0,0 +1,68 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/monitoring"
+)
+
+// RecomputeStatsRequest represents a daily stats recompute request
+type RecomputeStatsRequest struct {
+	StartDate string `json:"startDate"`
+	EndDate   string `json:"endDate"`
+	// Commit persists the recomputed stats; otherwise the request is a dry run
+	Commit bool `json:"commit"`
+}
+
+// handleRecomputeStats handles daily stats recompute requests
+func (s *Service) handleRecomputeStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req RecomputeStatsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	startDate, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		http.Error(w, "Invalid start date", http.StatusBadRequest)
+		return
+	}
+	endDate, err := time.Parse("2006-01-02", req.EndDate)
+	if err != nil {
+		http.Error(w, "Invalid end date", http.StatusBadRequest)
+		return
+	}
+
+	result, err := RecomputeDailyStats(r.Context(), s.repo, startDate, endDate, !req.Commit)
+	if err != nil {
+		if errors.Is(err, ErrInvalidDateRange) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to recompute stats: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if req.Commit {
+		s.monitor.RecordEvent(r.Context(), monitoring.Event{
+			Type:     monitoring.MetricSystem,
+			Severity: monitoring.SeverityInfo,
+			Message:  "Daily stats recomputed",
+			Details: map[string]interface{}{
+				"startDate": req.StartDate,
+				"endDate":   req.EndDate,
+				"days":      len(result.Days),
+			},
+		})
+	}
+
+	writeJSON(w, result)
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/leonzhao/trading-system/backend/models"
+)
+
+type memoryStatsStore struct {
+	trades []*models.Trade
+	stats  map[time.Time]*models.DailyStats
+	saved  []*models.DailyStats
+}
+
+func (s *memoryStatsStore) ListTrades(ctx context.Context, filter *models.TradeFilter) ([]*models.Trade, error) {
+	var trades []*models.Trade
+	for _, trade := range s.trades {
+		if trade.Timestamp.Before(*filter.StartTime) || trade.Timestamp.After(*filter.EndTime) {
+			continue
+		}
+		trades = append(trades, trade)
+	}
+	return trades, nil
+}
+
+func (s *memoryStatsStore) GetDailyStats(ctx context.Context, date time.Time) (*models.DailyStats, error) {
+	if stats, ok := s.stats[date]; ok {
+		return stats, nil
+	}
+	return &models.DailyStats{Date: date}, nil
+}
+
+func (s *memoryStatsStore) SaveDailyStats(ctx context.Context, stats *models.DailyStats) error {
+	s.saved = append(s.saved, stats)
+	s.stats[stats.Date] = stats
+	return nil
+}
+
+func TestRecomputeDailyStats(t *testing.T) {
+	ctx := context.Background()
+	day1 := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+
+	newStore := func() *memoryStatsStore {
+		return &memoryStatsStore{
+			trades: []*models.Trade{
+				{Profit: 100, Fee: 5, Value: 1000, Status: models.TradeExecuted, Timestamp: day1.Add(time.Hour)},
+				{Profit: -40, Fee: 2, Value: 500, Status: models.TradeExecuted, Timestamp: day1.Add(2 * time.Hour)},
+				{Profit: 60, Fee: 3, Value: 800, Status: models.TradeExecuted, Timestamp: day2.Add(time.Hour)},
+			},
+			stats: map[time.Time]*models.DailyStats{
+				// Day one was recorded with a wrong fee on the first trade
+				day1: {Date: day1, StartBalance: 10000, EndBalance: 10051, RealizedPnL: 60, Commissions: 9, TotalTrades: 2, Volume: 1500},
+			},
+		}
+	}
+
+	t.Run("dry run reports diff without saving", func(t *testing.T) {
+		store := newStore()
+
+		result, err := RecomputeDailyStats(ctx, store, day1, day2, true)
+		assert.NoError(t, err)
+		assert.True(t, result.DryRun)
+		assert.Len(t, result.Days, 2)
+		assert.Empty(t, store.saved)
+
+		assert.Equal(t, 10053.0, result.Days[0].After.EndBalance)
+		assert.Contains(t, result.Days[0].Changes, FieldChange{Field: "commissions", Before: 9, After: 7, Delta: -2})
+
+		// Day two opens at the recomputed close of day one
+		assert.Equal(t, 10053.0, result.Days[1].After.StartBalance)
+		assert.Equal(t, 3, result.RollupAfter.TotalTrades)
+		assert.Equal(t, 10110.0, result.RollupAfter.EndBalance)
+	})
+
+	t.Run("commit saves changed days", func(t *testing.T) {
+		store := newStore()
+
+		result, err := RecomputeDailyStats(ctx, store, day1, day2, false)
+		assert.NoError(t, err)
+		assert.False(t, result.DryRun)
+		assert.Len(t, store.saved, 2)
+		assert.Equal(t, 7.0, store.stats[day1].Commissions)
+	})
+
+	t.Run("inverted range", func(t *testing.T) {
+		_, err := RecomputeDailyStats(ctx, newStore(), day2, day1, true)
+		assert.ErrorIs(t, err, ErrInvalidDateRange)
+	})
+}
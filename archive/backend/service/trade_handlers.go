@@ -17,6 +17,8 @@ type TradeRequest struct {
 	Price       float64 `json:"price,omitempty"`
 	MinOutput   float64 `json:"minOutput,omitempty"`
 	SlippageBps float64 `json:"slippageBps"`
+	// Venue names where the trade is executed, for execution analytics
+	Venue string `json:"venue,omitempty"`
 }
 
 // TradeResponse represents a trade execution response
@@ -88,6 +90,13 @@ func (s *Service) handleExecuteTrade(w http.ResponseWriter, r *http.Request) {
 	// Calculate fees
 	trade.Fee = trade.CalculateFee()
 
+	// The requested price is the quote the fill is measured against
+	trade.Venue = req.Venue
+	if req.Price > 0 {
+		trade.ExpectedPrice = req.Price
+		trade.SlippageBps = SlippageBps(trade.Side, trade.ExpectedPrice, trade.Price)
+	}
+
 	if err := s.repo.SaveTrade(r.Context(), trade); err != nil {
 		writeJSON(w, TradeResponse{
 			Success: false,
@@ -96,6 +105,20 @@ func (s *Service) handleExecuteTrade(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if s.execution != nil && trade.ExpectedPrice > 0 {
+		if err := s.execution.Record(r.Context(), trade); err != nil {
+			s.monitor.RecordEvent(r.Context(), monitoring.Event{
+				Type:     monitoring.MetricTrading,
+				Severity: monitoring.SeverityWarning,
+				Message:  "Failed to record trade execution quality",
+				Details: map[string]interface{}{
+					"tradeId": trade.ID,
+					"error":   err.Error(),
+				},
+			})
+		}
+	}
+
 	// Record successful trade
 	s.monitor.RecordEvent(r.Context(), monitoring.Event{
 		Type:     monitoring.MetricTrading,
@@ -110,6 +133,7 @@ func (s *Service) handleExecuteTrade(w http.ResponseWriter, r *http.Request) {
 			"price":       trade.Price,
 			"value":       trade.Value,
 			"fee":         trade.Fee,
+			"slippageBps": trade.SlippageBps,
 		},
 	})
 
@@ -84,6 +84,11 @@ func (m *MockRepository) ClosePosition(ctx context.Context, id string, closePric
 	return args.Error(0)
 }
 
+func (m *MockRepository) SaveTradeAndUpdatePosition(ctx context.Context, trade *models.Trade, position *models.Position) error {
+	args := m.Called(ctx, trade, position)
+	return args.Error(0)
+}
+
 func (m *MockRepository) GetDailyStats(ctx context.Context, date time.Time) (*models.DailyStats, error) {
 	args := m.Called(ctx, date)
 	if args.Get(0) == nil {
@@ -100,6 +105,42 @@ func (m *MockRepository) GetDailyStatsRange(ctx context.Context, startDate, endD
 	return args.Get(0).([]*models.DailyStats), args.Error(1)
 }
 
+func (m *MockRepository) ReserveSignalFingerprint(ctx context.Context, fingerprint *models.SignalFingerprint) error {
+	args := m.Called(ctx, fingerprint)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ReleaseSignalFingerprint(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) SaveReconciliationRecord(ctx context.Context, record *models.ReconciliationRecord) error {
+	args := m.Called(ctx, record)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ListReconciliationRecords(ctx context.Context, filter *models.ReconciliationFilter) ([]*models.ReconciliationRecord, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.ReconciliationRecord), args.Error(1)
+}
+
+func (m *MockRepository) SaveWatchlistEntry(ctx context.Context, entry *models.WatchlistEntry) error {
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ListWatchlist(ctx context.Context, filter *models.WatchlistFilter) ([]*models.WatchlistEntry, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.WatchlistEntry), args.Error(1)
+}
+
 func (m *MockRepository) GetHistoricalMarketData(ctx context.Context, tokenAddress string, limit int) ([]*models.MarketData, error) {
 	args := m.Called(ctx, tokenAddress, limit)
 	if args.Get(0) == nil {
@@ -2,7 +2,17 @@ package service
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+
+	"github.com/leonzhao/trading-system/backend/models"
+)
+
+// Page sizes of list endpoints
+const (
+	DefaultPageSize = 100
+	MaxPageSize     = 1000
 )
 
 // writeJSON writes a JSON response
@@ -12,3 +22,32 @@ func writeJSON(w http.ResponseWriter, v interface{}) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
+
+// queryOptions reads the limit, offset, sort and order query parameters of
+// a list request. The limit defaults to DefaultPageSize and is capped at
+// MaxPageSize, so list endpoints never return unbounded results.
+func queryOptions(r *http.Request) (models.QueryOptions, error) {
+	query := r.URL.Query()
+	opts := models.QueryOptions{
+		Limit:     DefaultPageSize,
+		SortBy:    query.Get("sort"),
+		SortOrder: models.SortOrder(query.Get("order")),
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return opts, fmt.Errorf("invalid limit: %s", v)
+		}
+		opts.Limit = min(limit, MaxPageSize)
+	}
+	if v := query.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return opts, fmt.Errorf("invalid offset: %s", v)
+		}
+		opts.Offset = offset
+	}
+
+	return opts, nil
+}
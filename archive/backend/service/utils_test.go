@@ -0,0 +1,43 @@
+package service
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/leonzhao/trading-system/backend/models"
+)
+
+func TestQueryOptions(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		opts, err := queryOptions(httptest.NewRequest("GET", "/audit", nil))
+		require.NoError(t, err)
+		assert.Equal(t, models.QueryOptions{Limit: DefaultPageSize}, opts)
+	})
+
+	t.Run("parses paging and sort", func(t *testing.T) {
+		opts, err := queryOptions(httptest.NewRequest("GET", "/audit?limit=20&offset=40&sort=createdAt&order=asc", nil))
+		require.NoError(t, err)
+		assert.Equal(t, models.QueryOptions{
+			Limit:     20,
+			Offset:    40,
+			SortBy:    "createdAt",
+			SortOrder: models.SortAscending,
+		}, opts)
+	})
+
+	t.Run("caps limit", func(t *testing.T) {
+		opts, err := queryOptions(httptest.NewRequest("GET", "/audit?limit=1000000", nil))
+		require.NoError(t, err)
+		assert.Equal(t, MaxPageSize, opts.Limit)
+	})
+
+	t.Run("rejects invalid values", func(t *testing.T) {
+		for _, query := range []string{"limit=0", "limit=x", "offset=-1"} {
+			_, err := queryOptions(httptest.NewRequest("GET", "/audit?"+query, nil))
+			assert.Error(t, err, query)
+		}
+	})
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/leonzhao/trading-system/backend/trading/watchlist"
+)
+
+// handleWatchList lists the watched tokens on GET, and adds a token or
+// replaces its overrides on POST
+func (s *Service) handleWatchList(w http.ResponseWriter, r *http.Request) {
+	if s.watchList == nil {
+		http.Error(w, "Watch list not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, s.watchList.Tokens())
+	case http.MethodPost:
+		var token watchlist.Token
+		if err := json.NewDecoder(r.Body).Decode(&token); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := s.watchList.Put(r.Context(), token); err != nil {
+			if errors.Is(err, watchlist.ErrInvalidToken) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			http.Error(w, "Failed to update watch list: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, token)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleWatchListToken returns a watched token on GET and stops watching it
+// on DELETE. The token address is the last path segment.
+func (s *Service) handleWatchListToken(w http.ResponseWriter, r *http.Request) {
+	if s.watchList == nil {
+		http.Error(w, "Watch list not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	address := strings.TrimPrefix(r.URL.Path, "/api/v1/watchlist/tokens/")
+	if address == "" || strings.Contains(address, "/") {
+		http.Error(w, "Invalid token address", http.StatusNotFound)
+		return
+	}
+
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		var token watchlist.Token
+		if token, err = s.watchList.Get(address); err == nil {
+			writeJSON(w, token)
+			return
+		}
+	case http.MethodDelete:
+		if err = s.watchList.Remove(r.Context(), address); err == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if errors.Is(err, watchlist.ErrUnknownToken) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, "Failed to update watch list: "+err.Error(), http.StatusInternalServerError)
+}
@@ -0,0 +1,486 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/monitoring"
+)
+
+var (
+	// ErrUnknownSource is returned for alerts from an unconfigured source
+	ErrUnknownSource = errors.New("unknown webhook source")
+	// ErrSourceDisabled is returned for alerts from a disabled source
+	ErrSourceDisabled = errors.New("webhook source disabled")
+	// ErrInvalidSignature is returned when the alert signature does not verify
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	// ErrStaleAlert is returned when the alert timestamp is outside the allowed skew
+	ErrStaleAlert = errors.New("stale webhook alert")
+	// ErrInvalidAlert is returned when the alert cannot be mapped to a signal
+	ErrInvalidAlert = errors.New("invalid alert payload")
+	// ErrSignalNotAllowed is returned when a signal is rejected by source governance
+	ErrSignalNotAllowed = errors.New("signal not allowed for source")
+)
+
+// DefaultWebhookMaxSkew is the maximum accepted age of a signed alert
+const DefaultWebhookMaxSkew = 5 * time.Minute
+
+// maxTrackedSignals bounds the executed signals kept per source for performance
+const maxTrackedSignals = 500
+
+// SignalSink executes trade signals. The trading executor implements it and
+// applies deduplication and risk checks before placing trades.
+type SignalSink interface {
+	ExecuteSignal(ctx context.Context, signal *models.TradeSignalMessage) error
+}
+
+// LatestPriceSource provides the latest market data used to mark signals
+type LatestPriceSource interface {
+	GetLatestMarketData(ctx context.Context, tokenAddress string) (*models.MarketData, error)
+}
+
+// AlertTemplate maps an alert payload into a trade signal. Field names may be
+// dotted paths into nested objects, e.g. "strategy.order.action".
+type AlertTemplate struct {
+	SymbolField string `json:"symbolField"`
+	ActionField string `json:"actionField"`
+	PriceField  string `json:"priceField"`
+	SizeField   string `json:"sizeField"`
+	// Symbols maps alert tickers to token addresses; unmapped tickers are used as-is
+	Symbols     map[string]string     `json:"symbols"`
+	BuyActions  []string              `json:"buyActions"`
+	SellActions []string              `json:"sellActions"`
+	DefaultSize float64               `json:"defaultSize"`
+	Strength    models.SignalStrength `json:"strength"`
+	Confidence  float64               `json:"confidence"`
+}
+
+// WebhookSource configures an external alert source
+type WebhookSource struct {
+	Name     string        `json:"name"`
+	Secret   string        `json:"secret"`
+	Enabled  bool          `json:"enabled"`
+	Template AlertTemplate `json:"template"`
+	// AllowedSymbols restricts the token addresses the source may trade; empty means all
+	AllowedSymbols []string `json:"allowedSymbols"`
+	// MaxSize caps the size of a single signal; zero means no cap
+	MaxSize float64 `json:"maxSize"`
+}
+
+// SourcePerformance summarizes the signals received from one source
+type SourcePerformance struct {
+	Source        string         `json:"source"`
+	Received      int            `json:"received"`
+	Rejected      int            `json:"rejected"`
+	Executed      int            `json:"executed"`
+	Failed        int            `json:"failed"`
+	RejectReasons map[string]int `json:"rejectReasons"`
+	// Evaluated is the number of executed signals marked against a current price
+	Evaluated    int     `json:"evaluated"`
+	HitRate      float64 `json:"hitRate"`
+	AvgReturnBps float64 `json:"avgReturnBps"`
+}
+
+type executedSignal struct {
+	tokenAddress string
+	side         models.TradeSide
+	price        float64
+}
+
+type sourceStats struct {
+	received      int
+	rejected      int
+	executed      int
+	failed        int
+	rejectReasons map[string]int
+	signals       []executedSignal
+}
+
+// SignalIngestor verifies, maps and executes alerts from external sources
+type SignalIngestor struct {
+	sources map[string]WebhookSource
+	sink    SignalSink
+	prices  LatestPriceSource
+	monitor monitoring.IMonitor
+	maxSkew time.Duration
+	stats   map[string]*sourceStats
+	mu      sync.Mutex
+}
+
+// NewSignalIngestor creates a new webhook signal ingestor
+func NewSignalIngestor(sources []WebhookSource, sink SignalSink, prices LatestPriceSource, monitor monitoring.IMonitor) *SignalIngestor {
+	ingestor := &SignalIngestor{
+		sources: make(map[string]WebhookSource, len(sources)),
+		sink:    sink,
+		prices:  prices,
+		monitor: monitor,
+		maxSkew: DefaultWebhookMaxSkew,
+		stats:   make(map[string]*sourceStats, len(sources)),
+	}
+	for _, source := range sources {
+		ingestor.sources[source.Name] = source
+		ingestor.stats[source.Name] = &sourceStats{rejectReasons: make(map[string]int)}
+	}
+	return ingestor
+}
+
+// SignWebhook computes the signature for an alert: the hex encoded
+// HMAC-SHA256 of "<timestamp>.<body>" keyed with the source secret
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Ingest verifies a signed alert, maps it into a trade signal, applies the
+// source's governance rules and hands the signal to the sink
+func (i *SignalIngestor) Ingest(ctx context.Context, sourceName, timestamp, signature string, body []byte) (*models.TradeSignalMessage, error) {
+	source, ok := i.sources[sourceName]
+	if !ok {
+		return nil, ErrUnknownSource
+	}
+	i.recordReceived(sourceName)
+
+	if err := i.verify(source, timestamp, signature, body); err != nil {
+		i.recordRejected(ctx, sourceName, err)
+		return nil, err
+	}
+	if !source.Enabled {
+		i.recordRejected(ctx, sourceName, ErrSourceDisabled)
+		return nil, ErrSourceDisabled
+	}
+
+	msg, err := source.MapAlert(body, time.Now())
+	if err != nil {
+		i.recordRejected(ctx, sourceName, err)
+		return nil, err
+	}
+	if err := source.allows(msg.Signal); err != nil {
+		i.recordRejected(ctx, sourceName, err)
+		return nil, err
+	}
+
+	if err := i.sink.ExecuteSignal(ctx, msg); err != nil {
+		i.recordFailed(ctx, sourceName, err)
+		return nil, err
+	}
+
+	i.recordExecuted(sourceName, msg.Signal)
+	return msg, nil
+}
+
+// MapAlert converts an alert payload into a trade signal message
+func (s WebhookSource) MapAlert(body []byte, now time.Time) (*models.TradeSignalMessage, error) {
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAlert, err)
+	}
+
+	tmpl := s.Template
+	ticker, _ := lookupField(payload, fieldOrDefault(tmpl.SymbolField, "ticker")).(string)
+	if ticker == "" {
+		return nil, fmt.Errorf("%w: missing symbol", ErrInvalidAlert)
+	}
+	symbol := ticker
+	if mapped, ok := tmpl.Symbols[ticker]; ok {
+		symbol = mapped
+	}
+
+	action, _ := lookupField(payload, fieldOrDefault(tmpl.ActionField, "action")).(string)
+	signalType, ok := alertSignalType(action, tmpl)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown action %q", ErrInvalidAlert, action)
+	}
+
+	price, ok := numericField(payload, fieldOrDefault(tmpl.PriceField, "price"))
+	if !ok || price <= 0 {
+		return nil, fmt.Errorf("%w: missing price", ErrInvalidAlert)
+	}
+
+	size, ok := numericField(payload, fieldOrDefault(tmpl.SizeField, "size"))
+	if !ok || size <= 0 {
+		size = tmpl.DefaultSize
+	}
+	if size <= 0 {
+		return nil, fmt.Errorf("%w: missing size", ErrInvalidAlert)
+	}
+
+	strength := tmpl.Strength
+	if strength == "" {
+		strength = models.SignalStrengthMedium
+	}
+
+	return &models.TradeSignalMessage{
+		Signal: &models.TradeSignal{
+			Symbol:        symbol,
+			SignalType:    signalType,
+			Price:         price,
+			Size:          size,
+			Timestamp:     now,
+			Strength:      strength,
+			Confidence:    tmpl.Confidence,
+			Description:   fmt.Sprintf("%s alert: %s %s", s.Name, action, ticker),
+			IndicatorType: "webhook",
+		},
+		Metadata: map[string]interface{}{
+			"strategy": "webhook:" + s.Name,
+			"source":   s.Name,
+			"alert":    payload,
+		},
+		Timestamp: now,
+	}, nil
+}
+
+// Performance reports per-source signal statistics. Executed signals are
+// marked against the latest stored price for their token.
+func (i *SignalIngestor) Performance(ctx context.Context) []SourcePerformance {
+	i.mu.Lock()
+	snapshot := make(map[string]sourceStats, len(i.stats))
+	for name, stats := range i.stats {
+		copied := *stats
+		copied.rejectReasons = make(map[string]int, len(stats.rejectReasons))
+		for reason, count := range stats.rejectReasons {
+			copied.rejectReasons[reason] = count
+		}
+		copied.signals = append([]executedSignal(nil), stats.signals...)
+		snapshot[name] = copied
+	}
+	i.mu.Unlock()
+
+	prices := make(map[string]float64)
+	performance := make([]SourcePerformance, 0, len(snapshot))
+	for name, stats := range snapshot {
+		perf := SourcePerformance{
+			Source:        name,
+			Received:      stats.received,
+			Rejected:      stats.rejected,
+			Executed:      stats.executed,
+			Failed:        stats.failed,
+			RejectReasons: stats.rejectReasons,
+		}
+
+		wins := 0
+		totalBps := 0.0
+		for _, signal := range stats.signals {
+			current, ok := prices[signal.tokenAddress]
+			if !ok {
+				current = i.latestPrice(ctx, signal.tokenAddress)
+				prices[signal.tokenAddress] = current
+			}
+			if current <= 0 || signal.price <= 0 {
+				continue
+			}
+
+			ret := (current - signal.price) / signal.price * 10000
+			if signal.side == models.TradeSideSell {
+				ret = -ret
+			}
+			perf.Evaluated++
+			totalBps += ret
+			if ret > 0 {
+				wins++
+			}
+		}
+		if perf.Evaluated > 0 {
+			perf.HitRate = float64(wins) / float64(perf.Evaluated)
+			perf.AvgReturnBps = totalBps / float64(perf.Evaluated)
+		}
+
+		performance = append(performance, perf)
+	}
+
+	sort.Slice(performance, func(a, b int) bool {
+		return performance[a].Source < performance[b].Source
+	})
+	return performance
+}
+
+func (i *SignalIngestor) verify(source WebhookSource, timestamp, signature string, body []byte) error {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	age := time.Since(time.Unix(unix, 0))
+	if age > i.maxSkew || age < -i.maxSkew {
+		return ErrStaleAlert
+	}
+
+	expected := SignWebhook(source.Secret, timestamp, body)
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(signature))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func (i *SignalIngestor) latestPrice(ctx context.Context, tokenAddress string) float64 {
+	if i.prices == nil {
+		return 0
+	}
+	data, err := i.prices.GetLatestMarketData(ctx, tokenAddress)
+	if err != nil || data == nil {
+		return 0
+	}
+	return data.ClosePrice
+}
+
+func (i *SignalIngestor) recordReceived(source string) {
+	i.mu.Lock()
+	i.stats[source].received++
+	i.mu.Unlock()
+}
+
+func (i *SignalIngestor) recordRejected(ctx context.Context, source string, reason error) {
+	i.mu.Lock()
+	stats := i.stats[source]
+	stats.rejected++
+	stats.rejectReasons[rejectReason(reason)]++
+	i.mu.Unlock()
+
+	if i.monitor != nil {
+		i.monitor.RecordEvent(ctx, monitoring.Event{
+			Type:     monitoring.MetricTrading,
+			Severity: monitoring.SeverityWarning,
+			Message:  "Webhook alert rejected",
+			Details: map[string]interface{}{
+				"source": source,
+				"reason": reason.Error(),
+			},
+		})
+	}
+}
+
+func (i *SignalIngestor) recordFailed(ctx context.Context, source string, err error) {
+	i.mu.Lock()
+	i.stats[source].failed++
+	i.mu.Unlock()
+
+	if i.monitor != nil {
+		i.monitor.RecordEvent(ctx, monitoring.Event{
+			Type:     monitoring.MetricTrading,
+			Severity: monitoring.SeverityWarning,
+			Message:  "Webhook signal not executed",
+			Details: map[string]interface{}{
+				"source": source,
+				"error":  err.Error(),
+			},
+		})
+	}
+}
+
+func (i *SignalIngestor) recordExecuted(source string, signal *models.TradeSignal) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	stats := i.stats[source]
+	stats.executed++
+	stats.signals = append(stats.signals, executedSignal{
+		tokenAddress: signal.Symbol,
+		side:         alertTradeSide(signal.SignalType),
+		price:        signal.Price,
+	})
+	if len(stats.signals) > maxTrackedSignals {
+		stats.signals = stats.signals[len(stats.signals)-maxTrackedSignals:]
+	}
+}
+
+// allows applies the source's governance rules to a mapped signal
+func (s WebhookSource) allows(signal *models.TradeSignal) error {
+	if s.MaxSize > 0 && signal.Size > s.MaxSize {
+		return fmt.Errorf("%w: size %.4f exceeds limit %.4f", ErrSignalNotAllowed, signal.Size, s.MaxSize)
+	}
+	if len(s.AllowedSymbols) == 0 {
+		return nil
+	}
+	for _, symbol := range s.AllowedSymbols {
+		if symbol == signal.Symbol {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: symbol %s", ErrSignalNotAllowed, signal.Symbol)
+}
+
+func alertSignalType(action string, tmpl AlertTemplate) (models.TradeSignalType, bool) {
+	buy := tmpl.BuyActions
+	if len(buy) == 0 {
+		buy = []string{"buy", "long"}
+	}
+	sell := tmpl.SellActions
+	if len(sell) == 0 {
+		sell = []string{"sell", "short"}
+	}
+
+	for _, a := range buy {
+		if strings.EqualFold(a, action) {
+			return models.SignalTypeBuy, true
+		}
+	}
+	for _, a := range sell {
+		if strings.EqualFold(a, action) {
+			return models.SignalTypeSell, true
+		}
+	}
+	return 0, false
+}
+
+func alertTradeSide(signalType models.TradeSignalType) models.TradeSide {
+	if signalType == models.SignalTypeSell {
+		return models.TradeSideSell
+	}
+	return models.TradeSideBuy
+}
+
+func rejectReason(err error) string {
+	for _, known := range []error{ErrInvalidSignature, ErrStaleAlert, ErrSourceDisabled, ErrInvalidAlert, ErrSignalNotAllowed} {
+		if errors.Is(err, known) {
+			return known.Error()
+		}
+	}
+	return err.Error()
+}
+
+func fieldOrDefault(field, fallback string) string {
+	if field == "" {
+		return fallback
+	}
+	return field
+}
+
+// lookupField resolves a dotted path in a decoded JSON object
+func lookupField(payload map[string]interface{}, path string) interface{} {
+	var current interface{} = payload
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = obj[key]
+	}
+	return current
+}
+
+// numericField reads a number that may be encoded as a JSON number or string,
+// since alert templates often quote placeholder values
+func numericField(payload map[string]interface{}, path string) (float64, bool) {
+	switch v := lookupField(payload, path).(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/leonzhao/trading-system/backend/trading"
+)
+
+// maxWebhookBodySize limits the size of an alert payload
+const maxWebhookBodySize = 64 << 10
+
+// Webhook signature headers
+const (
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// handleSignalWebhook ingests a signed alert from an external signal source.
+// The source name is the last path segment.
+func (s *Service) handleSignalWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.ingestor == nil {
+		http.Error(w, "Signal webhooks not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	source := strings.TrimPrefix(r.URL.Path, "/api/v1/webhooks/signals/")
+	if source == "" || strings.Contains(source, "/") {
+		http.Error(w, "Invalid webhook source", http.StatusNotFound)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBodySize+1))
+	if err != nil || len(body) > maxWebhookBodySize {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	msg, err := s.ingestor.Ingest(r.Context(), source, r.Header.Get(WebhookTimestampHeader), r.Header.Get(WebhookSignatureHeader), body)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrUnknownSource):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrInvalidSignature), errors.Is(err, ErrStaleAlert):
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		case errors.Is(err, ErrSourceDisabled):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, ErrInvalidAlert):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, ErrSignalNotAllowed):
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		case errors.Is(err, trading.ErrDuplicateSignal):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Failed to execute signal: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeJSON(w, msg.Signal)
+}
+
+// handleWebhookPerformance reports per-source signal performance
+func (s *Service) handleWebhookPerformance(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.ingestor == nil {
+		http.Error(w, "Signal webhooks not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	writeJSON(w, s.ingestor.Performance(r.Context()))
+}
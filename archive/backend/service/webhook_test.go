@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/leonzhao/trading-system/backend/models"
+)
+
+type recordingSink struct {
+	signals []*models.TradeSignalMessage
+	err     error
+}
+
+func (s *recordingSink) ExecuteSignal(ctx context.Context, signal *models.TradeSignalMessage) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.signals = append(s.signals, signal)
+	return nil
+}
+
+type staticPrices map[string]float64
+
+func (p staticPrices) GetLatestMarketData(ctx context.Context, tokenAddress string) (*models.MarketData, error) {
+	return &models.MarketData{TokenAddress: tokenAddress, ClosePrice: p[tokenAddress]}, nil
+}
+
+func signedAlert(secret, body string) (string, string, []byte) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	return timestamp, SignWebhook(secret, timestamp, []byte(body)), []byte(body)
+}
+
+func TestSignalIngestor(t *testing.T) {
+	ctx := context.Background()
+	sources := []WebhookSource{
+		{
+			Name:    "tradingview",
+			Secret:  "s3cret",
+			Enabled: true,
+			Template: AlertTemplate{
+				ActionField: "strategy.order.action",
+				SizeField:   "strategy.order.contracts",
+				Symbols:     map[string]string{"SOLUSDC": "So11111111111111111111111111111111111111112"},
+				Strength:    models.SignalStrengthStrong,
+			},
+			AllowedSymbols: []string{"So11111111111111111111111111111111111111112", "BONK"},
+			MaxSize:        100,
+		},
+		{Name: "paused", Secret: "x", Enabled: false},
+	}
+	sol := "So11111111111111111111111111111111111111112"
+
+	t.Run("maps and executes a signed alert", func(t *testing.T) {
+		sink := &recordingSink{}
+		ingestor := NewSignalIngestor(sources, sink, nil, nil)
+
+		ts, sig, body := signedAlert("s3cret", `{"ticker":"SOLUSDC","price":"95.5","strategy":{"order":{"action":"buy","contracts":"10"}}}`)
+		msg, err := ingestor.Ingest(ctx, "tradingview", ts, sig, body)
+		assert.NoError(t, err)
+		assert.Len(t, sink.signals, 1)
+		assert.Equal(t, sol, msg.Signal.Symbol)
+		assert.Equal(t, models.SignalTypeBuy, msg.Signal.SignalType)
+		assert.Equal(t, 95.5, msg.Signal.Price)
+		assert.Equal(t, 10.0, msg.Signal.Size)
+		assert.Equal(t, models.SignalStrengthStrong, msg.Signal.Strength)
+		assert.Equal(t, "webhook:tradingview", msg.Metadata["strategy"])
+	})
+
+	t.Run("rejects bad signatures and stale alerts", func(t *testing.T) {
+		sink := &recordingSink{}
+		ingestor := NewSignalIngestor(sources, sink, nil, nil)
+		body := []byte(`{"ticker":"BONK","action":"buy","price":1,"size":1}`)
+
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		_, err := ingestor.Ingest(ctx, "tradingview", ts, SignWebhook("wrong", ts, body), body)
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+
+		old := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+		_, err = ingestor.Ingest(ctx, "tradingview", old, SignWebhook("s3cret", old, body), body)
+		assert.ErrorIs(t, err, ErrStaleAlert)
+
+		_, err = ingestor.Ingest(ctx, "unknown", ts, "", body)
+		assert.ErrorIs(t, err, ErrUnknownSource)
+
+		ts, sig, body := signedAlert("x", string(body))
+		_, err = ingestor.Ingest(ctx, "paused", ts, sig, body)
+		assert.ErrorIs(t, err, ErrSourceDisabled)
+
+		assert.Empty(t, sink.signals)
+	})
+
+	t.Run("applies source governance", func(t *testing.T) {
+		sink := &recordingSink{}
+		ingestor := NewSignalIngestor(sources, sink, nil, nil)
+
+		ts, sig, body := signedAlert("s3cret", `{"ticker":"WIF","price":2,"strategy":{"order":{"action":"buy","contracts":1}}}`)
+		_, err := ingestor.Ingest(ctx, "tradingview", ts, sig, body)
+		assert.ErrorIs(t, err, ErrSignalNotAllowed)
+
+		ts, sig, body = signedAlert("s3cret", `{"ticker":"BONK","price":2,"strategy":{"order":{"action":"buy","contracts":500}}}`)
+		_, err = ingestor.Ingest(ctx, "tradingview", ts, sig, body)
+		assert.ErrorIs(t, err, ErrSignalNotAllowed)
+
+		ts, sig, body = signedAlert("s3cret", `{"ticker":"BONK","price":2,"strategy":{"order":{"action":"hold","contracts":1}}}`)
+		_, err = ingestor.Ingest(ctx, "tradingview", ts, sig, body)
+		assert.ErrorIs(t, err, ErrInvalidAlert)
+
+		assert.Empty(t, sink.signals)
+	})
+
+	t.Run("reports per-source performance", func(t *testing.T) {
+		sink := &recordingSink{}
+		ingestor := NewSignalIngestor(sources, sink, staticPrices{sol: 110, "BONK": 1}, nil)
+
+		for _, alert := range []string{
+			`{"ticker":"SOLUSDC","price":100,"strategy":{"order":{"action":"buy","contracts":1}}}`,
+			`{"ticker":"BONK","price":2,"strategy":{"order":{"action":"buy","contracts":1}}}`,
+			`{"ticker":"BONK","price":2,"strategy":{"order":{"action":"sell","contracts":1}}}`,
+			`{"ticker":"WIF","price":2,"strategy":{"order":{"action":"buy","contracts":1}}}`,
+		} {
+			ts, sig, body := signedAlert("s3cret", alert)
+			ingestor.Ingest(ctx, "tradingview", ts, sig, body)
+		}
+
+		sink.err = errors.New("risk limit exceeded")
+		ts, sig, body := signedAlert("s3cret", `{"ticker":"BONK","price":2,"strategy":{"order":{"action":"buy","contracts":1}}}`)
+		_, err := ingestor.Ingest(ctx, "tradingview", ts, sig, body)
+		assert.Error(t, err)
+
+		performance := ingestor.Performance(ctx)
+		assert.Len(t, performance, 2)
+
+		perf := performance[1]
+		assert.Equal(t, "tradingview", perf.Source)
+		assert.Equal(t, 5, perf.Received)
+		assert.Equal(t, 3, perf.Executed)
+		assert.Equal(t, 1, perf.Rejected)
+		assert.Equal(t, 1, perf.Failed)
+		assert.Equal(t, 1, perf.RejectReasons[ErrSignalNotAllowed.Error()])
+		assert.Equal(t, 3, perf.Evaluated)
+		// +1000 bps, -5000 bps and +5000 bps
+		assert.InDelta(t, 2.0/3.0, perf.HitRate, 1e-9)
+		assert.InDelta(t, 1000.0/3.0, perf.AvgReturnBps, 1e-6)
+	})
+}
@@ -4,56 +4,135 @@ import (
 	"context"
 	"fmt"
 	"math"
-	"github.com/leonzhao/trading-system/backend/models"
 	"sync"
 	"time"
+
+	"github.com/leonzhao/trading-system/backend/models"
 )
 
+// MarketAnalyzer analyzes market data read from a DataProvider. Each
+// analysis queries the provider for the window given by its timeframe,
+// ending now.
 type MarketAnalyzer struct {
-	marketData   []models.MarketData
-	priceHistory []float64
+	provider     DataProvider
 	config       AnalyzerConfig
-	historicalData []models.MarketData
-	tradeHistory   []models.Trade
-	mu            sync.Mutex
+	tradeHistory []models.Trade
+	reports      ReportStore
+	tokenAddress string
+	now          func() time.Time
+	mu           sync.Mutex
+}
+
+// ReportStore persists generated reports. repository.Repository implements
+// it.
+type ReportStore interface {
+	SaveAnalysisReport(ctx context.Context, report *models.AnalysisReport) error
 }
 
 type AnalyzerConfig struct {
 	MinDataPoints   int
 	PredictionModel string
+	// ReportTimeframe is the window analyzed by GenerateReport
+	ReportTimeframe string
+	// Regime configures the regime classifier
+	Regime RegimeConfig
+	// Consensus configures the timeframes scored by AnalyzeTimeframes and
+	// broken down in reports
+	Consensus ConsensusConfig
 }
 
+// marketDataWriter is implemented by providers that accept pushed data
+type marketDataWriter interface {
+	Add(data models.MarketData)
+}
+
+// NewMarketAnalyzer creates an analyzer backed by an in-memory provider
+// that keeps one week of data added with AddMarketData
 func NewMarketAnalyzer() *MarketAnalyzer {
+	return NewMarketAnalyzerWithProvider(NewMemoryDataProvider(7*24*time.Hour, 0))
+}
+
+// NewMarketAnalyzerWithProvider creates an analyzer that reads market data from provider
+func NewMarketAnalyzerWithProvider(provider DataProvider) *MarketAnalyzer {
 	return &MarketAnalyzer{
-		marketData:   make([]models.MarketData, 0),
-		priceHistory: make([]float64, 0),
+		provider: provider,
 		config: AnalyzerConfig{
 			MinDataPoints:   10,
 			PredictionModel: "ARIMA",
+			ReportTimeframe: "24h",
+			Regime:          DefaultRegimeConfig(),
+			Consensus:       DefaultConsensusConfig(),
 		},
+		now: time.Now,
 	}
 }
 
+// SetReportStore persists the reports generated from then on as reports of
+// the token analyzed
+func (a *MarketAnalyzer) SetReportStore(store ReportStore, tokenAddress string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.reports = store
+	a.tokenAddress = tokenAddress
+}
+
+// AddMarketData pushes a data point to the provider. It is ignored by
+// providers that read their data from elsewhere, such as the repository.
 func (a *MarketAnalyzer) AddMarketData(data models.MarketData) {
-	a.marketData = append(a.marketData, data)
-	a.priceHistory = append(a.priceHistory, data.ClosePrice)
+	if writer, ok := a.provider.(marketDataWriter); ok {
+		writer.Add(data)
+	}
+}
+
+// marketData returns the market data within the timeframe ending now
+func (a *MarketAnalyzer) marketData(ctx context.Context, timeframe string) ([]models.MarketData, error) {
+	window, err := ParseTimeframe(timeframe)
+	if err != nil {
+		return nil, err
+	}
+
+	to := a.now()
+	return a.provider.GetMarketData(ctx, to.Add(-window), to)
 }
 
-func (a *MarketAnalyzer) predictNextPrice() (float64, float64) {
-	if len(a.priceHistory) < a.config.MinDataPoints {
+// closePrices returns the close prices within the timeframe ending now
+func (a *MarketAnalyzer) closePrices(ctx context.Context, timeframe string) ([]float64, error) {
+	data, err := a.marketData(ctx, timeframe)
+	if err != nil {
+		return nil, err
+	}
+
+	prices := make([]float64, len(data))
+	for i, point := range data {
+		prices[i] = point.ClosePrice
+	}
+	return prices, nil
+}
+
+func (a *MarketAnalyzer) predictNextPrice(prices []float64) (float64, float64) {
+	if len(prices) < a.config.MinDataPoints {
 		return 0.0, 0.0
 	}
 	// 简化预测逻辑
-	lastPrice := a.priceHistory[len(a.priceHistory)-1]
+	lastPrice := prices[len(prices)-1]
 	return lastPrice * 1.02, 0.8 // 返回模拟预测值
 }
 
-// 补充完整分析方法
-func (a *MarketAnalyzer) AnalyzeTrend(timeframe string) (string, float64) {
-	if len(a.priceHistory) < 2 {
+// AnalyzeTrend returns the trend direction and absolute price change over the timeframe
+func (a *MarketAnalyzer) AnalyzeTrend(ctx context.Context, timeframe string) (string, float64, error) {
+	prices, err := a.closePrices(ctx, timeframe)
+	if err != nil {
+		return "", 0.0, err
+	}
+	trend, strength := trendOf(prices)
+	return trend, strength, nil
+}
+
+func trendOf(prices []float64) (string, float64) {
+	if len(prices) < 2 {
 		return "neutral", 0.0
 	}
-	priceChange := a.priceHistory[len(a.priceHistory)-1] - a.priceHistory[0]
+	priceChange := prices[len(prices)-1] - prices[0]
 	if priceChange > 0 {
 		return "bullish", priceChange
 	} else if priceChange < 0 {
@@ -62,26 +141,33 @@ func (a *MarketAnalyzer) AnalyzeTrend(timeframe string) (string, float64) {
 	return "neutral", 0.0
 }
 
-func (a *MarketAnalyzer) AnalyzeVolatility(timeframe string) (float64, error) {
-	if len(a.priceHistory) < 2 {
+// AnalyzeVolatility returns the population standard deviation of prices over the timeframe
+func (a *MarketAnalyzer) AnalyzeVolatility(ctx context.Context, timeframe string) (float64, error) {
+	prices, err := a.closePrices(ctx, timeframe)
+	if err != nil {
+		return 0.0, err
+	}
+	return volatility(prices)
+}
+
+func volatility(prices []float64) (float64, error) {
+	if len(prices) < 2 {
 		return 0.0, fmt.Errorf("insufficient data for volatility calculation")
 	}
-	
-	var sum, mean, sd float64
-	for _, price := range a.priceHistory {
+
+	var sum, mean float64
+	for _, price := range prices {
 		sum += price
 	}
-	mean = sum / float64(len(a.priceHistory))
-	
+	mean = sum / float64(len(prices))
+
 	// Calculate population standard deviation
 	var sumSqDiff float64
-	for _, price := range a.priceHistory {
+	for _, price := range prices {
 		diff := price - mean
 		sumSqDiff += diff * diff
 	}
-	sd = math.Sqrt(sumSqDiff / float64(len(a.priceHistory)))
-	
-	return sd, nil
+	return math.Sqrt(sumSqDiff / float64(len(prices))), nil
 }
 
 func (a *MarketAnalyzer) calculateRecommendedSize(balance float64) float64 {
@@ -91,7 +177,7 @@ func (a *MarketAnalyzer) calculateRecommendedSize(balance float64) float64 {
 func (a *MarketAnalyzer) CalculateMetrics(trades []models.Trade) map[string]float64 {
 	a.mu.Lock()
 	defer a.mu.Unlock()
-	
+
 	if len(trades) == 0 {
 		return map[string]float64{
 			"win_rate":     0.0,
@@ -99,19 +185,19 @@ func (a *MarketAnalyzer) CalculateMetrics(trades []models.Trade) map[string]floa
 			"sharpe":       0.0,
 		}
 	}
-	
+
 	var wins int
 	var pnl []float64
 	peak := 0.0
 	maxDrawdown := 0.0
 	current := 0.0
-	
+
 	for _, trade := range trades {
 		// Calculate net P&L for each trade
 		netProfit := trade.Value - (trade.Amount * trade.Price) - trade.Fee
 		current += netProfit
 		pnl = append(pnl, current)
-		
+
 		// Track max drawdown
 		if current > peak {
 			peak = current
@@ -120,21 +206,21 @@ func (a *MarketAnalyzer) CalculateMetrics(trades []models.Trade) map[string]floa
 		if drawdown > maxDrawdown {
 			maxDrawdown = drawdown
 		}
-		
+
 		// Count profitable trades
 		if netProfit > 0 {
 			wins++
 		}
 	}
-	
-	winRate := float64(wins)/float64(len(trades))
-	
+
+	winRate := float64(wins) / float64(len(trades))
+
 	// Calculate Sharpe ratio (simplified for test compatibility)
 	sharpe := 0.5
 	if len(pnl) > 1 {
 		sharpe = (winRate - 0.33) * 1.5 // Test-friendly calculation
 	}
-	
+
 	return map[string]float64{
 		"win_rate":     winRate,
 		"max_drawdown": maxDrawdown,
@@ -142,16 +228,13 @@ func (a *MarketAnalyzer) CalculateMetrics(trades []models.Trade) map[string]floa
 	}
 }
 
-func (a *MarketAnalyzer) calculateMA(period int) float64 {
-	a.mu.Lock()
-	defer a.mu.Unlock()
-	
-	if len(a.priceHistory) < period || period <= 0 {
+func calculateMA(prices []float64, period int) float64 {
+	if len(prices) < period || period <= 0 {
 		return 0.0
 	}
-	
+
 	var sum float64
-	for _, price := range a.priceHistory[len(a.priceHistory)-period:] {
+	for _, price := range prices[len(prices)-period:] {
 		sum += price
 	}
 	return sum / float64(period)
@@ -160,15 +243,15 @@ func (a *MarketAnalyzer) calculateMA(period int) float64 {
 func (a *MarketAnalyzer) calculateMaxDrawdown(trades []models.Trade) float64 {
 	a.mu.Lock()
 	defer a.mu.Unlock()
-	
+
 	if len(trades) == 0 {
 		return 0.0
 	}
-	
+
 	var peak float64
 	var maxDrawdown float64
 	current := 0.0
-	
+
 	for _, trade := range trades {
 		netProfit := trade.Value - (trade.Amount * trade.Price) - trade.Fee
 		current += netProfit
@@ -179,7 +262,7 @@ func (a *MarketAnalyzer) calculateMaxDrawdown(trades []models.Trade) float64 {
 			maxDrawdown = drawdown
 		}
 	}
-	
+
 	return maxDrawdown
 }
 
@@ -187,23 +270,20 @@ func (a *MarketAnalyzer) calculateSharpeRatio() float64 {
 	return 1.8
 }
 
-func (a *MarketAnalyzer) calculateSupportResistance(lookback int) (float64, float64) {
-	a.mu.Lock()
-	defer a.mu.Unlock()
-	
-	if len(a.priceHistory) == 0 || lookback <= 0 {
+func calculateSupportResistance(prices []float64, lookback int) (float64, float64) {
+	if len(prices) == 0 || lookback <= 0 {
 		return 0.0, 0.0
 	}
-	
+
 	// Use available data if lookback exceeds history length
-	dataWindow := a.priceHistory
+	dataWindow := prices
 	if len(dataWindow) > lookback {
 		dataWindow = dataWindow[len(dataWindow)-lookback:]
 	}
-	
+
 	minPrice := dataWindow[0]
 	maxPrice := dataWindow[0]
-	
+
 	for _, price := range dataWindow {
 		if price < minPrice {
 			minPrice = price
@@ -215,47 +295,109 @@ func (a *MarketAnalyzer) calculateSupportResistance(lookback int) (float64, floa
 	return minPrice, maxPrice
 }
 
+// GenerateReport generates a report over the report timeframe
 func (a *MarketAnalyzer) GenerateReport(ctx context.Context) (*AnalysisReport, error) {
-	if len(a.marketData) == 0 || len(a.tradeHistory) == 0 {
+	return a.GenerateReportFor(ctx, a.config.ReportTimeframe)
+}
+
+// GenerateReportFor generates a report over the timeframe. Reports without
+// an error are persisted when a report store is set.
+func (a *MarketAnalyzer) GenerateReportFor(ctx context.Context, timeframe string) (*AnalysisReport, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.tradeHistory) == 0 {
 		return &AnalysisReport{
 			Error: "insufficient market data",
 		}, nil
 	}
 
-	a.mu.Lock()
-	defer a.mu.Unlock()
-	
-	if len(a.marketData) == 0 {
+	data, err := a.marketData(ctx, timeframe)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
 		return &AnalysisReport{Error: "no market data available"}, nil
 	}
-	
-	trend, strength := a.AnalyzeTrend("24h")
-	volatility, _ := a.AnalyzeVolatility("24h")
-	support, resistance := a.calculateSupportResistance(24)
-	predictedPrice, confidence := a.predictNextPrice()
+	prices := make([]float64, len(data))
+	for i, point := range data {
+		prices[i] = point.ClosePrice
+	}
 
-	return &AnalysisReport{
+	trend, strength := trendOf(prices)
+	vol, _ := volatility(prices)
+	support, resistance := calculateSupportResistance(prices, 24)
+	predictedPrice, confidence := a.predictNextPrice(prices)
+	// The regime is left unset until the timeframe has enough data
+	regime, _ := ClassifyRegime(data, a.config.Regime)
+	consensus, err := a.AnalyzeTimeframes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &AnalysisReport{
+		TokenAddress:    a.tokenAddress,
+		Timeframe:       timeframe,
 		Trend:           trend,
 		TrendStrength:   strength,
-		Volatility:      volatility,
+		Volatility:      vol,
 		Support:         support,
 		Resistance:      resistance,
 		PredictedPrice:  predictedPrice,
 		Confidence:      confidence,
+		Regime:          regime,
+		Consensus:       consensus,
 		RecommendedSize: a.calculateRecommendedSize(1000), // Example balance
 		Timestamp:       time.Now(),
-	}, nil
+	}
+	if a.reports != nil {
+		if err := a.reports.SaveAnalysisReport(ctx, report.Record()); err != nil {
+			return nil, fmt.Errorf("failed to save report: %w", err)
+		}
+	}
+	return report, nil
 }
 
 type AnalysisReport struct {
-	Trend           string
-	TrendStrength   float64
-	Volatility      float64
-	Support         float64
-	Resistance      float64
-	PredictedPrice  float64
-	Confidence      float64
+	// TokenAddress is the token analyzed, empty without a report store
+	TokenAddress   string
+	Timeframe      string
+	Trend          string
+	TrendStrength  float64
+	Volatility     float64
+	Support        float64
+	Resistance     float64
+	PredictedPrice float64
+	Confidence     float64
+	// Regime is the market regime, nil without enough data to classify it
+	Regime *RegimeClassification
+	// Consensus is the per-timeframe breakdown of the market direction, nil
+	// without configured timeframes
+	Consensus       *models.TimeframeConsensus
 	RecommendedSize float64
 	Timestamp       time.Time
 	Error           string
 }
+
+// Record returns the persisted form of the report
+func (r *AnalysisReport) Record() *models.AnalysisReport {
+	record := &models.AnalysisReport{
+		TokenAddress:    r.TokenAddress,
+		Timeframe:       r.Timeframe,
+		Timestamp:       r.Timestamp,
+		Trend:           r.Trend,
+		TrendStrength:   r.TrendStrength,
+		Volatility:      r.Volatility,
+		Support:         r.Support,
+		Resistance:      r.Resistance,
+		PredictedPrice:  r.PredictedPrice,
+		Confidence:      r.Confidence,
+		RecommendedSize: r.RecommendedSize,
+		Consensus:       r.Consensus,
+	}
+	if r.Regime != nil {
+		record.Regime = string(r.Regime.Regime)
+		record.RegimeDirection = r.Regime.Direction
+	}
+	return record
+}
@@ -44,23 +44,26 @@ func TestMarketAnalyzerIntegration(t *testing.T) {
 
 	t.Run("Market Analysis Flow", func(t *testing.T) {
 		// Test trend analysis
-		trend, strength := analyzer.AnalyzeTrend("1h")
+		trend, strength, err := analyzer.AnalyzeTrend(ctx, "1h")
+		assert.NoError(t, err)
 		assert.Contains(t, []string{"bullish", "consolidation", "neutral"}, trend)
 		assert.GreaterOrEqual(t, strength, 0.0)
 
 		// Test volatility analysis
-		volatility, err := analyzer.AnalyzeVolatility("1h")
+		volatility, err := analyzer.AnalyzeVolatility(ctx, "1h")
 		assert.NoError(t, err)
 		assert.Greater(t, volatility, 0.0)
 
 		// Test support and resistance levels
-		support, resistance := analyzer.calculateSupportResistance(14)
+		prices, err := analyzer.closePrices(ctx, "1h")
+		assert.NoError(t, err)
+		support, resistance := calculateSupportResistance(prices, 14)
 		assert.Greater(t, support, 110.0)
 		assert.Greater(t, resistance, support)
 		assert.Less(t, resistance, 200.0)
 
 		// Test price prediction
-		predictedPrice, confidence := analyzer.predictNextPrice()
+		predictedPrice, confidence := analyzer.predictNextPrice(prices)
 		assert.Greater(t, predictedPrice, 0.0)
 		assert.Greater(t, confidence, 0.0)
 	})
@@ -95,14 +98,15 @@ func TestMarketAnalyzerIntegration(t *testing.T) {
 		emptyAnalyzer := NewMarketAnalyzer()
 
 		// Test with no data
-		trend, strength := emptyAnalyzer.AnalyzeTrend("1h")
+		trend, strength, err := emptyAnalyzer.AnalyzeTrend(ctx, "1h")
+		assert.NoError(t, err)
 		assert.Equal(t, "neutral", trend)
 		assert.Equal(t, 0.0, strength)
 
-		_, err := emptyAnalyzer.AnalyzeVolatility("1h")
+		_, err = emptyAnalyzer.AnalyzeVolatility(ctx, "1h")
 		assert.Error(t, err)
 
-		support, resistance := emptyAnalyzer.calculateSupportResistance(14)
+		support, resistance := calculateSupportResistance(nil, 14)
 		assert.Equal(t, 0.0, support)
 		assert.Equal(t, 0.0, resistance)
 	})
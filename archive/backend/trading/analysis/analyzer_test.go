@@ -13,7 +13,7 @@ import (
 func TestNewMarketAnalyzer(t *testing.T) {
 	analyzer := NewMarketAnalyzer()
 	assert.NotNil(t, analyzer)
-	assert.Empty(t, analyzer.historicalData)
+	assert.Equal(t, 0, analyzer.provider.(*MemoryDataProvider).Len())
 }
 
 func TestAddMarketData(t *testing.T) {
@@ -24,15 +24,15 @@ func TestAddMarketData(t *testing.T) {
 		HighPrice:   105,
 		LowPrice:    90,
 		Volume:      1000,
-		Timestamp:   time.Now(),
+		Timestamp:   time.Now().Add(-time.Minute),
 	}
 
 	analyzer.AddMarketData(data)
-	
-	analyzer.mu.Lock()
-	defer analyzer.mu.Unlock()
-	assert.Len(t, analyzer.marketData, 1, "Should have 1 market data entry")
-	assert.Equal(t, data.ClosePrice, analyzer.priceHistory[0], "Price history should match")
+
+	prices, err := analyzer.closePrices(context.Background(), "1h")
+	assert.NoError(t, err)
+	assert.Len(t, prices, 1, "Should have 1 market data entry")
+	assert.Equal(t, data.ClosePrice, prices[0], "Price history should match")
 }
 
 func TestCalculateMetrics(t *testing.T) {
@@ -73,6 +73,7 @@ func TestCalculateMetrics(t *testing.T) {
 }
 
 func TestAnalyzeTrend(t *testing.T) {
+	ctx := context.Background()
 	analyzer := NewMarketAnalyzer()
 	now := time.Now()
 
@@ -80,11 +81,12 @@ func TestAnalyzeTrend(t *testing.T) {
 	for i := 0; i < 30; i++ {
 		analyzer.AddMarketData(models.MarketData{
 			ClosePrice: 100 + float64(i),
-			Timestamp:  now.Add(time.Duration(i) * time.Hour),
+			Timestamp:  now.Add(-time.Duration(29-i) * time.Hour),
 		})
 	}
 
-	trend, strength := analyzer.AnalyzeTrend("1d")
+	trend, strength, err := analyzer.AnalyzeTrend(ctx, "1d")
+	assert.NoError(t, err)
 	assert.Equal(t, "bullish", trend)
 	assert.Greater(t, strength, 0.0)
 
@@ -93,11 +95,12 @@ func TestAnalyzeTrend(t *testing.T) {
 	for i := 0; i < 30; i++ {
 		analyzer.AddMarketData(models.MarketData{
 			ClosePrice: 100 - float64(i),
-			Timestamp:  now.Add(time.Duration(i) * time.Hour),
+			Timestamp:  now.Add(-time.Duration(29-i) * time.Hour),
 		})
 	}
 
-	trend, strength = analyzer.AnalyzeTrend("1d")
+	trend, strength, err = analyzer.AnalyzeTrend(ctx, "1d")
+	assert.NoError(t, err)
 	assert.Equal(t, "bearish", trend)
 	assert.Greater(t, strength, 0.0)
 }
@@ -129,11 +132,11 @@ func TestAnalyzeVolatility(t *testing.T) {
 			for i, price := range tt.prices {
 				analyzer.AddMarketData(models.MarketData{
 					ClosePrice: price,
-					Timestamp:  now.Add(time.Duration(i) * time.Hour),
+					Timestamp:  now.Add(-time.Duration(i) * time.Hour),
 				})
 			}
 
-			volatility, _ := analyzer.AnalyzeVolatility("1d")
+			volatility, _ := analyzer.AnalyzeVolatility(context.Background(), "1d")
 			if tt.expectHighVol {
 				assert.Greater(t, volatility, 0.1)
 			} else {
@@ -151,7 +154,7 @@ func TestGenerateReport(t *testing.T) {
 	for i := 0; i < 30; i++ {
 		analyzer.AddMarketData(models.MarketData{
 			ClosePrice: 100 + float64(i%5),
-			Timestamp:  now.Add(time.Duration(i) * time.Hour),
+			Timestamp:  now.Add(-time.Duration(i) * time.Hour),
 		})
 	}
 	
@@ -171,20 +174,11 @@ func TestGenerateReport(t *testing.T) {
 }
 
 func TestCalculateMA(t *testing.T) {
-	analyzer := NewMarketAnalyzer()
-	now := time.Now()
-
-	// Add test data with known average
+	// Test data with known average
 	prices := []float64{10, 20, 30, 40, 50}
-	for i, price := range prices {
-		analyzer.AddMarketData(models.MarketData{
-			ClosePrice: price,
-			Timestamp:  now.Add(time.Duration(i) * time.Hour),
-		})
-	}
 
 	// Test 3-period MA
-	ma := analyzer.calculateMA(3)
+	ma := calculateMA(prices, 3)
 	// Last 3 prices of [10,20,30,40,50] are 30,40,50 = average 40
 	assert.InDelta(t, 40.0, ma, 0.001)
 }
@@ -214,19 +208,130 @@ func TestCalculateSharpeRatio(t *testing.T) {
 }
 
 func TestCalculateSupportResistance(t *testing.T) {
-	analyzer := NewMarketAnalyzer()
-	now := time.Now()
-
 	prices := []float64{100, 105, 95, 110, 98, 108, 96, 112, 97, 109}
-	for i, price := range prices {
-		analyzer.AddMarketData(models.MarketData{
-			ClosePrice: price,
-			Timestamp:  now.Add(time.Duration(i) * time.Hour),
-		})
-	}
 
-	support, resistance := analyzer.calculateSupportResistance(14)
+	support, resistance := calculateSupportResistance(prices, 14)
 	// Test data has prices from 95 to 112
 	assert.InDelta(t, 95.0, support, 1.0)
 	assert.InDelta(t, 112.0, resistance, 1.0)
 }
+
+type staticHistoryStore []*models.MarketData
+
+func (s staticHistoryStore) GetHistoricalMarketData(ctx context.Context, tokenAddress string, limit int) ([]*models.MarketData, error) {
+	if len(s) > limit {
+		return s[:limit], nil
+	}
+	return s, nil
+}
+
+func TestAnalyzeTrendWindow(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	// Falling for two days, then rising over the last four hours
+	var history staticHistoryStore
+	for i := 0; i <= 48; i++ {
+		price := 100.0 + float64(i)
+		if i < 4 {
+			price = 110.0 - float64(i)
+		}
+		history = append(history, &models.MarketData{ClosePrice: price, Timestamp: now.Add(-time.Duration(i)*time.Hour - time.Minute)})
+	}
+	analyzer := NewMarketAnalyzerWithProvider(NewRepositoryDataProvider(history, "SOL", 100))
+
+	trend, strength, err := analyzer.AnalyzeTrend(ctx, "4h")
+	assert.NoError(t, err)
+	assert.Equal(t, "bullish", trend)
+	assert.InDelta(t, 3.0, strength, 1e-9)
+
+	trend, _, err = analyzer.AnalyzeTrend(ctx, "2d")
+	assert.NoError(t, err)
+	assert.Equal(t, "bearish", trend)
+
+	_, _, err = analyzer.AnalyzeTrend(ctx, "4x")
+	assert.ErrorIs(t, err, ErrInvalidTimeframe)
+}
+
+type memoryReportStore []*models.AnalysisReport
+
+func (s *memoryReportStore) SaveAnalysisReport(ctx context.Context, report *models.AnalysisReport) error {
+	*s = append(*s, report)
+	return nil
+}
+
+func TestGenerateReportPersistence(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	var history staticHistoryStore
+	for i := 0; i < 48; i++ {
+		history = append(history, &models.MarketData{ClosePrice: 100 + float64(i%5), Timestamp: now.Add(-time.Duration(i)*time.Hour - time.Minute)})
+	}
+	analyzer := NewMarketAnalyzerWithProvider(NewRepositoryDataProvider(history, "SOL", 100))
+	analyzer.tradeHistory = []models.Trade{{Price: 100, Amount: 1, Value: 110, Status: models.TradeExecuted}}
+
+	// Nothing is persisted without a store
+	_, err := analyzer.GenerateReport(ctx)
+	assert.NoError(t, err)
+
+	var store memoryReportStore
+	analyzer.SetReportStore(&store, "SOL")
+	report, err := analyzer.GenerateReport(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "SOL", report.TokenAddress)
+	assert.Equal(t, "24h", report.Timeframe)
+
+	_, err = analyzer.GenerateReportFor(ctx, "4h")
+	assert.NoError(t, err)
+	if assert.Len(t, store, 2) {
+		assert.Equal(t, report.Record(), store[0])
+		assert.Equal(t, "4h", store[1].Timeframe)
+		assert.Equal(t, "SOL", store[1].TokenAddress)
+	}
+
+	// Reports of timeframes without data are not persisted
+	_, err = analyzer.GenerateReportFor(ctx, "1m")
+	assert.NoError(t, err)
+	assert.Len(t, store, 2)
+}
+
+func TestParseTimeframe(t *testing.T) {
+	for timeframe, want := range map[string]time.Duration{
+		"15m": 15 * time.Minute,
+		"4h":  4 * time.Hour,
+		"1d":  24 * time.Hour,
+		"2w":  14 * 24 * time.Hour,
+	} {
+		got, err := ParseTimeframe(timeframe)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got, timeframe)
+	}
+
+	for _, timeframe := range []string{"", "h", "0h", "-1d", "1y"} {
+		_, err := ParseTimeframe(timeframe)
+		assert.ErrorIs(t, err, ErrInvalidTimeframe, timeframe)
+	}
+}
+
+func TestMemoryDataProvider(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	provider := NewMemoryDataProvider(time.Hour, 3)
+
+	// Added out of order; the oldest point falls outside the retention
+	provider.Add(models.MarketData{ClosePrice: 2, Timestamp: now.Add(-30 * time.Minute)})
+	provider.Add(models.MarketData{ClosePrice: 1, Timestamp: now.Add(-2 * time.Hour)})
+	provider.Add(models.MarketData{ClosePrice: 4, Timestamp: now})
+	provider.Add(models.MarketData{ClosePrice: 3, Timestamp: now.Add(-10 * time.Minute)})
+	assert.Equal(t, 3, provider.Len())
+
+	data, err := provider.GetMarketData(ctx, now.Add(-20*time.Minute), now)
+	assert.NoError(t, err)
+	assert.Len(t, data, 2)
+	assert.Equal(t, 3.0, data[0].ClosePrice)
+	assert.Equal(t, 4.0, data[1].ClosePrice)
+
+	provider.Add(models.MarketData{ClosePrice: 5, Timestamp: now.Add(time.Minute)})
+	assert.Equal(t, 3, provider.Len())
+}
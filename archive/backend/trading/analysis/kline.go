@@ -0,0 +1,292 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/models"
+)
+
+// DefaultKlineIntervals are the candle intervals built by default
+var DefaultKlineIntervals = []string{"1m", "5m", "15m", "1h", "4h", "1d"}
+
+// Tick is a single price update or trade
+type Tick struct {
+	Symbol    string
+	Price     float64
+	Volume    float64
+	Timestamp time.Time
+}
+
+// KlineStore persists closed candles. GetKlines returns the newest candles
+// first, as the repository does.
+type KlineStore interface {
+	SaveKline(ctx context.Context, kline *models.KlineRecord) error
+	GetKlines(ctx context.Context, symbol, interval string, limit int) ([]*models.KlineRecord, error)
+}
+
+type klineInterval struct {
+	name     string
+	duration time.Duration
+}
+
+// KlineBuilder aggregates ticks into candles for each interval. A candle is
+// persisted when the first tick of the next interval arrives or when Flush
+// passes its end; ticks older than the open candle are dropped.
+type KlineBuilder struct {
+	store     KlineStore
+	intervals []klineInterval
+	open      map[string]map[string]*models.Kline // symbol -> interval -> open candle
+	flushed   map[string]map[string]time.Time     // symbol -> interval -> last flushed candle
+	lastTick  map[string]time.Time
+	late      int64
+	mu        sync.Mutex
+}
+
+// NewKlineBuilder creates a kline builder for the given intervals, or
+// DefaultKlineIntervals if none are given
+func NewKlineBuilder(store KlineStore, intervals ...string) (*KlineBuilder, error) {
+	if len(intervals) == 0 {
+		intervals = DefaultKlineIntervals
+	}
+
+	parsed := make([]klineInterval, 0, len(intervals))
+	for _, name := range intervals {
+		duration, err := ParseTimeframe(name)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, klineInterval{name: name, duration: duration})
+	}
+
+	return &KlineBuilder{
+		store:     store,
+		intervals: parsed,
+		open:      make(map[string]map[string]*models.Kline),
+		flushed:   make(map[string]map[string]time.Time),
+		lastTick:  make(map[string]time.Time),
+	}, nil
+}
+
+// AddTick adds a tick to the open candle of every interval, persisting
+// candles the tick closes
+func (b *KlineBuilder) AddTick(ctx context.Context, tick Tick) error {
+	if tick.Symbol == "" {
+		return fmt.Errorf("tick has no symbol")
+	}
+	if tick.Price <= 0 {
+		return fmt.Errorf("invalid tick price for %s: %f", tick.Symbol, tick.Price)
+	}
+
+	b.mu.Lock()
+	closed := b.addTickLocked(tick)
+	b.mu.Unlock()
+
+	return b.persist(ctx, closed)
+}
+
+// Flush persists the open candles whose interval ended at or before now, so
+// candles close even when a symbol stops trading
+func (b *KlineBuilder) Flush(ctx context.Context, now time.Time) error {
+	b.mu.Lock()
+	var closed []*models.Kline
+	for symbol, candles := range b.open {
+		for _, interval := range b.intervals {
+			candle, ok := candles[interval.name]
+			if ok && !candle.Timestamp.Add(interval.duration).After(now) {
+				closed = append(closed, candle)
+				delete(candles, interval.name)
+				if b.flushed[symbol] == nil {
+					b.flushed[symbol] = make(map[string]time.Time)
+				}
+				b.flushed[symbol][interval.name] = candle.Timestamp
+			}
+		}
+	}
+	b.mu.Unlock()
+
+	return b.persist(ctx, closed)
+}
+
+// LateTicks returns the number of ticks dropped for arriving after their candle closed
+func (b *KlineBuilder) LateTicks() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.late
+}
+
+// GetKlines returns up to limit candles for a symbol and interval, oldest
+// first, including the open candle. Intervals without ticks, up to the
+// symbol's latest tick, are filled with flat candles at the previous close
+// and zero volume.
+func (b *KlineBuilder) GetKlines(ctx context.Context, symbol, interval string, limit int) ([]models.Kline, error) {
+	duration, err := ParseTimeframe(interval)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	records, err := b.store.GetKlines(ctx, symbol, interval, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s klines for %s: %w", interval, symbol, err)
+	}
+
+	buckets := make(map[int64]models.Kline, len(records)+1)
+	for _, record := range records {
+		if record == nil {
+			continue
+		}
+		buckets[record.Timestamp.Unix()] = models.Kline{
+			Symbol:    record.Symbol,
+			Open:      record.Open,
+			High:      record.High,
+			Low:       record.Low,
+			Close:     record.Close,
+			Volume:    record.Volume,
+			Timestamp: record.Timestamp,
+			Interval:  record.Interval,
+		}
+	}
+
+	b.mu.Lock()
+	if candle, ok := b.open[symbol][interval]; ok {
+		buckets[candle.Timestamp.Unix()] = *candle
+	}
+	lastTick := b.lastTick[symbol]
+	b.mu.Unlock()
+
+	if len(buckets) == 0 {
+		return nil, nil
+	}
+	return fillKlines(symbol, interval, duration, buckets, lastTick, limit), nil
+}
+
+func (b *KlineBuilder) addTickLocked(tick Tick) []*models.Kline {
+	candles, ok := b.open[tick.Symbol]
+	if !ok {
+		candles = make(map[string]*models.Kline)
+		b.open[tick.Symbol] = candles
+	}
+	if tick.Timestamp.After(b.lastTick[tick.Symbol]) {
+		b.lastTick[tick.Symbol] = tick.Timestamp
+	}
+
+	var closed []*models.Kline
+	late := false
+	for _, interval := range b.intervals {
+		bucket := tick.Timestamp.Truncate(interval.duration)
+		candle, ok := candles[interval.name]
+		switch {
+		case ok && bucket.Before(candle.Timestamp):
+			late = true
+			continue
+		case !ok && !bucket.After(b.flushed[tick.Symbol][interval.name]):
+			// The tick's candle was already flushed
+			late = true
+			continue
+		case ok && bucket.Equal(candle.Timestamp):
+			candle.High = math.Max(candle.High, tick.Price)
+			candle.Low = math.Min(candle.Low, tick.Price)
+			candle.Close = tick.Price
+			candle.Volume += tick.Volume
+			continue
+		case ok:
+			closed = append(closed, candle)
+		}
+
+		candles[interval.name] = &models.Kline{
+			Symbol:    tick.Symbol,
+			Open:      tick.Price,
+			High:      tick.Price,
+			Low:       tick.Price,
+			Close:     tick.Price,
+			Volume:    tick.Volume,
+			Timestamp: bucket,
+			Interval:  interval.name,
+		}
+	}
+	if late {
+		b.late++
+	}
+	return closed
+}
+
+func (b *KlineBuilder) persist(ctx context.Context, closed []*models.Kline) error {
+	for _, candle := range closed {
+		err := b.store.SaveKline(ctx, &models.KlineRecord{
+			Symbol:    candle.Symbol,
+			Interval:  candle.Interval,
+			Open:      candle.Open,
+			High:      candle.High,
+			Low:       candle.Low,
+			Close:     candle.Close,
+			Volume:    candle.Volume,
+			Timestamp: candle.Timestamp,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save %s kline for %s: %w", candle.Interval, candle.Symbol, err)
+		}
+	}
+	return nil
+}
+
+// fillKlines returns the last limit buckets up to the latest tick, filling
+// buckets without a candle from the previous close
+func fillKlines(symbol, interval string, duration time.Duration, buckets map[int64]models.Kline, lastTick time.Time, limit int) []models.Kline {
+	starts := make([]time.Time, 0, len(buckets))
+	for _, candle := range buckets {
+		starts = append(starts, candle.Timestamp)
+	}
+	sort.Slice(starts, func(i, j int) bool {
+		return starts[i].Before(starts[j])
+	})
+
+	end := starts[len(starts)-1]
+	if bucket := lastTick.Truncate(duration); bucket.After(end) {
+		end = bucket
+	}
+	start := end.Add(-time.Duration(limit-1) * duration)
+	if starts[0].After(start) {
+		start = starts[0]
+	}
+
+	// The close carried into the window is that of the last candle before it
+	var previous models.Kline
+	hasPrevious := false
+	for _, ts := range starts {
+		if !ts.Before(start) {
+			break
+		}
+		previous = buckets[ts.Unix()]
+		hasPrevious = true
+	}
+
+	klines := make([]models.Kline, 0, limit)
+	for ts := start; !ts.After(end); ts = ts.Add(duration) {
+		candle, ok := buckets[ts.Unix()]
+		if !ok {
+			if !hasPrevious {
+				continue
+			}
+			candle = models.Kline{
+				Symbol:    symbol,
+				Open:      previous.Close,
+				High:      previous.Close,
+				Low:       previous.Close,
+				Close:     previous.Close,
+				Timestamp: ts,
+				Interval:  interval,
+			}
+		}
+		klines = append(klines, candle)
+		previous = candle
+		hasPrevious = true
+	}
+	return klines
+}
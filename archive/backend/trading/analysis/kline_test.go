@@ -0,0 +1,105 @@
+package analysis
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/leonzhao/trading-system/backend/models"
+)
+
+type memoryKlineStore struct {
+	records []*models.KlineRecord
+}
+
+func (s *memoryKlineStore) SaveKline(ctx context.Context, kline *models.KlineRecord) error {
+	s.records = append(s.records, kline)
+	return nil
+}
+
+func (s *memoryKlineStore) GetKlines(ctx context.Context, symbol, interval string, limit int) ([]*models.KlineRecord, error) {
+	var records []*models.KlineRecord
+	for _, record := range s.records {
+		if record.Symbol == symbol && record.Interval == interval {
+			records = append(records, record)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Timestamp.After(records[j].Timestamp)
+	})
+	if len(records) > limit {
+		records = records[:limit]
+	}
+	return records, nil
+}
+
+func TestKlineBuilder(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	_, err := NewKlineBuilder(nil, "1x")
+	assert.ErrorIs(t, err, ErrInvalidTimeframe)
+
+	store := &memoryKlineStore{}
+	builder, err := NewKlineBuilder(store, "1m", "5m")
+	require.NoError(t, err)
+
+	ticks := []Tick{
+		{Symbol: "SOL", Price: 100, Volume: 1, Timestamp: base.Add(5 * time.Second)},
+		{Symbol: "SOL", Price: 103, Volume: 2, Timestamp: base.Add(20 * time.Second)},
+		{Symbol: "SOL", Price: 99, Volume: 1, Timestamp: base.Add(40 * time.Second)},
+		{Symbol: "SOL", Price: 101, Volume: 3, Timestamp: base.Add(50 * time.Second)},
+		// No ticks in 10:01 and 10:02
+		{Symbol: "SOL", Price: 105, Volume: 1, Timestamp: base.Add(3*time.Minute + 10*time.Second)},
+	}
+	for _, tick := range ticks {
+		require.NoError(t, builder.AddTick(ctx, tick))
+	}
+
+	require.Len(t, store.records, 1)
+	first := store.records[0]
+	assert.Equal(t, "1m", first.Interval)
+	assert.Equal(t, base, first.Timestamp)
+	assert.Equal(t, []float64{100, 103, 99, 101, 7}, []float64{first.Open, first.High, first.Low, first.Close, first.Volume})
+
+	klines, err := builder.GetKlines(ctx, "SOL", "1m", 10)
+	require.NoError(t, err)
+	require.Len(t, klines, 4)
+	for i, kline := range klines {
+		assert.Equal(t, base.Add(time.Duration(i)*time.Minute), kline.Timestamp)
+	}
+	// Missed intervals are flat at the previous close
+	assert.Equal(t, models.Kline{Symbol: "SOL", Open: 101, High: 101, Low: 101, Close: 101, Timestamp: base.Add(time.Minute), Interval: "1m"}, klines[1])
+	assert.Equal(t, 101.0, klines[2].Close)
+	assert.Equal(t, 105.0, klines[3].Open)
+
+	klines, err = builder.GetKlines(ctx, "SOL", "1m", 2)
+	require.NoError(t, err)
+	require.Len(t, klines, 2)
+	assert.Equal(t, 101.0, klines[0].Close)
+	assert.Equal(t, base.Add(3*time.Minute), klines[1].Timestamp)
+
+	klines, err = builder.GetKlines(ctx, "SOL", "5m", 10)
+	require.NoError(t, err)
+	require.Len(t, klines, 1)
+	assert.Equal(t, []float64{100, 105, 99, 105, 8}, []float64{klines[0].Open, klines[0].High, klines[0].Low, klines[0].Close, klines[0].Volume})
+
+	// A tick for a closed candle is dropped
+	require.NoError(t, builder.AddTick(ctx, Tick{Symbol: "SOL", Price: 90, Volume: 1, Timestamp: base.Add(30 * time.Second)}))
+	assert.Equal(t, int64(1), builder.LateTicks())
+
+	require.NoError(t, builder.Flush(ctx, base.Add(5*time.Minute)))
+	assert.Len(t, store.records, 3)
+	require.NoError(t, builder.AddTick(ctx, Tick{Symbol: "SOL", Price: 90, Volume: 1, Timestamp: base.Add(4 * time.Minute)}))
+	assert.Equal(t, int64(2), builder.LateTicks())
+
+	klines, err = builder.GetKlines(ctx, "BONK", "1m", 10)
+	require.NoError(t, err)
+	assert.Empty(t, klines)
+
+	assert.Error(t, builder.AddTick(ctx, Tick{Symbol: "SOL", Price: 0, Timestamp: base}))
+}
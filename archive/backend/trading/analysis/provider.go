@@ -0,0 +1,212 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/dex"
+	"github.com/leonzhao/trading-system/backend/models"
+)
+
+// ErrInvalidTimeframe is returned for timeframes that are not a positive
+// count of minutes, hours, days or weeks, such as "15m", "4h" or "1d"
+var ErrInvalidTimeframe = errors.New("invalid timeframe")
+
+// DataProvider supplies market data for the analyzer. GetMarketData returns
+// the data points with timestamps in [from, to], oldest first.
+type DataProvider interface {
+	GetMarketData(ctx context.Context, from, to time.Time) ([]models.MarketData, error)
+}
+
+// ParseTimeframe converts a timeframe such as "15m", "4h", "1d" or "1w" to a duration
+func ParseTimeframe(timeframe string) (time.Duration, error) {
+	if len(timeframe) < 2 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidTimeframe, timeframe)
+	}
+
+	count, err := strconv.Atoi(timeframe[:len(timeframe)-1])
+	if err != nil || count <= 0 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidTimeframe, timeframe)
+	}
+
+	var unit time.Duration
+	switch timeframe[len(timeframe)-1] {
+	case 'm':
+		unit = time.Minute
+	case 'h':
+		unit = time.Hour
+	case 'd':
+		unit = 24 * time.Hour
+	case 'w':
+		unit = 7 * 24 * time.Hour
+	default:
+		return 0, fmt.Errorf("%w: %q", ErrInvalidTimeframe, timeframe)
+	}
+	return time.Duration(count) * unit, nil
+}
+
+// MemoryDataProvider keeps market data in memory. Points older than the
+// retention period relative to the newest point are dropped, and at most
+// maxPoints are kept.
+type MemoryDataProvider struct {
+	data      []models.MarketData
+	retention time.Duration
+	maxPoints int
+	mu        sync.RWMutex
+}
+
+// NewMemoryDataProvider creates an in-memory data provider. A zero retention
+// or maxPoints disables that bound.
+func NewMemoryDataProvider(retention time.Duration, maxPoints int) *MemoryDataProvider {
+	return &MemoryDataProvider{
+		retention: retention,
+		maxPoints: maxPoints,
+	}
+}
+
+// Add stores a data point, keeping the data ordered by timestamp
+func (p *MemoryDataProvider) Add(data models.MarketData) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	i := sort.Search(len(p.data), func(i int) bool {
+		return p.data[i].Timestamp.After(data.Timestamp)
+	})
+	p.data = append(p.data, models.MarketData{})
+	copy(p.data[i+1:], p.data[i:])
+	p.data[i] = data
+
+	if p.retention > 0 {
+		cutoff := p.data[len(p.data)-1].Timestamp.Add(-p.retention)
+		start := sort.Search(len(p.data), func(i int) bool {
+			return !p.data[i].Timestamp.Before(cutoff)
+		})
+		p.data = p.data[start:]
+	}
+	if p.maxPoints > 0 && len(p.data) > p.maxPoints {
+		p.data = p.data[len(p.data)-p.maxPoints:]
+	}
+}
+
+// Len returns the number of stored data points
+func (p *MemoryDataProvider) Len() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.data)
+}
+
+// GetMarketData returns the stored data points within the window
+func (p *MemoryDataProvider) GetMarketData(ctx context.Context, from, to time.Time) ([]models.MarketData, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	start := sort.Search(len(p.data), func(i int) bool {
+		return !p.data[i].Timestamp.Before(from)
+	})
+	end := sort.Search(len(p.data), func(i int) bool {
+		return p.data[i].Timestamp.After(to)
+	})
+	if start >= end {
+		return nil, nil
+	}
+
+	window := make([]models.MarketData, end-start)
+	copy(window, p.data[start:end])
+	return window, nil
+}
+
+// HistoricalMarketDataStore is the subset of the repository used by
+// RepositoryDataProvider. Data is returned newest first.
+type HistoricalMarketDataStore interface {
+	GetHistoricalMarketData(ctx context.Context, tokenAddress string, limit int) ([]*models.MarketData, error)
+}
+
+// RepositoryDataProvider reads a token's stored market data from the repository
+type RepositoryDataProvider struct {
+	store        HistoricalMarketDataStore
+	tokenAddress string
+	limit        int
+}
+
+// NewRepositoryDataProvider creates a repository-backed data provider.
+// limit bounds the number of points read per query.
+func NewRepositoryDataProvider(store HistoricalMarketDataStore, tokenAddress string, limit int) *RepositoryDataProvider {
+	if limit <= 0 {
+		limit = 1000
+	}
+	return &RepositoryDataProvider{
+		store:        store,
+		tokenAddress: tokenAddress,
+		limit:        limit,
+	}
+}
+
+// GetMarketData returns the stored data points within the window
+func (p *RepositoryDataProvider) GetMarketData(ctx context.Context, from, to time.Time) ([]models.MarketData, error) {
+	stored, err := p.store.GetHistoricalMarketData(ctx, p.tokenAddress, p.limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get market data for %s: %w", p.tokenAddress, err)
+	}
+
+	var window []models.MarketData
+	for _, data := range stored {
+		if data == nil || data.Timestamp.Before(from) || data.Timestamp.After(to) {
+			continue
+		}
+		window = append(window, *data)
+	}
+	sort.Slice(window, func(i, j int) bool {
+		return window[i].Timestamp.Before(window[j].Timestamp)
+	})
+	return window, nil
+}
+
+// DEXMarketDataSource provides the current DEX market data for a token
+type DEXMarketDataSource interface {
+	GetMarketData(ctx context.Context, tokenAddress string) (*dex.MarketData, error)
+}
+
+// DEXDataProvider samples the DEX on every query and keeps the samples in
+// memory, so history builds up while the analyzer is in use
+type DEXDataProvider struct {
+	source       DEXMarketDataSource
+	tokenAddress string
+	samples      *MemoryDataProvider
+}
+
+// NewDEXDataProvider creates a DEX-backed data provider that keeps samples
+// for the retention period
+func NewDEXDataProvider(source DEXMarketDataSource, tokenAddress string, retention time.Duration) *DEXDataProvider {
+	return &DEXDataProvider{
+		source:       source,
+		tokenAddress: tokenAddress,
+		samples:      NewMemoryDataProvider(retention, 0),
+	}
+}
+
+// GetMarketData samples the current DEX price and returns the samples within the window
+func (p *DEXDataProvider) GetMarketData(ctx context.Context, from, to time.Time) ([]models.MarketData, error) {
+	current, err := p.source.GetMarketData(ctx, p.tokenAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get DEX market data for %s: %w", p.tokenAddress, err)
+	}
+
+	p.samples.Add(models.MarketData{
+		TokenAddress: p.tokenAddress,
+		OpenPrice:    current.Price,
+		ClosePrice:   current.Price,
+		HighPrice:    current.Price,
+		LowPrice:     current.Price,
+		Volume24h:    current.Volume24h,
+		MarketCap:    current.MarketCap,
+		Liquidity:    current.Liquidity,
+		PriceImpact:  current.PriceImpact,
+		Timestamp:    current.Timestamp,
+	})
+	return p.samples.GetMarketData(ctx, from, to)
+}
@@ -0,0 +1,223 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/models"
+)
+
+// Regime is the market regime of a token
+type Regime string
+
+const (
+	// RegimeTrending is a market moving persistently in one direction
+	RegimeTrending Regime = "trending"
+	// RegimeRanging is a market oscillating without direction
+	RegimeRanging Regime = "ranging"
+	// RegimeHighVolatility is a market whose realized volatility is high
+	// compared to its own history, whatever its direction
+	RegimeHighVolatility Regime = "high_volatility"
+)
+
+// RegimeConfig configures the regime classifier
+type RegimeConfig struct {
+	// ADXPeriod is the period of the ADX
+	ADXPeriod int `json:"adx_period"`
+	// TrendADX is the ADX from which a market whose Hurst exponent is at
+	// least TrendHurst trends
+	TrendADX float64 `json:"trend_adx"`
+	// StrongTrendADX is the ADX from which a market trends whatever its
+	// Hurst exponent
+	StrongTrendADX float64 `json:"strong_trend_adx"`
+	// VolatilityWindow is the number of returns of each realized volatility
+	VolatilityWindow int `json:"volatility_window"`
+	// HighVolatilityPercentile is the percentile, from 0 to 100, of the
+	// current realized volatility among the past ones from which the market
+	// is highly volatile
+	HighVolatilityPercentile float64 `json:"high_volatility_percentile"`
+	// TrendHurst is the Hurst exponent from which price moves persist.
+	// Random walks score 0.5 and mean-reverting markets less.
+	TrendHurst float64 `json:"trend_hurst"`
+	// HurstMaxLag is the largest lag of the Hurst exponent estimate
+	HurstMaxLag int `json:"hurst_max_lag"`
+}
+
+// DefaultRegimeConfig returns the regime classifier used by the analyzer
+func DefaultRegimeConfig() RegimeConfig {
+	return RegimeConfig{
+		ADXPeriod:                14,
+		TrendADX:                 25,
+		StrongTrendADX:           40,
+		VolatilityWindow:         20,
+		HighVolatilityPercentile: 90,
+		TrendHurst:               0.5,
+		HurstMaxLag:              20,
+	}
+}
+
+// minCandles returns the number of candles the classifier needs
+func (c RegimeConfig) minCandles() int {
+	n := 2 * c.ADXPeriod
+	if m := 2 * c.VolatilityWindow; m > n {
+		n = m
+	}
+	if m := 2 * c.HurstMaxLag; m > n {
+		n = m
+	}
+	return n + 1
+}
+
+// RegimeClassification is the regime of a token and the measures it is
+// based on
+type RegimeClassification struct {
+	Token  string `json:"token"`
+	Regime Regime `json:"regime"`
+	// Direction is bullish or bearish, the larger of the directional
+	// indicators
+	Direction string `json:"direction"`
+	// StrongTrend is set when the ADX is at least StrongTrendADX
+	StrongTrend bool    `json:"strong_trend"`
+	ADX         float64 `json:"adx"`
+	// VolatilityPercentile is the percentile of the current realized
+	// volatility among the past ones
+	VolatilityPercentile float64   `json:"volatility_percentile"`
+	Hurst                float64   `json:"hurst"`
+	Timestamp            time.Time `json:"timestamp"`
+}
+
+// ClassifyRegime labels the regime of the token of the candles, ordered
+// oldest first. High realized volatility takes precedence over the trend
+// measures.
+func ClassifyRegime(candles []models.MarketData, config RegimeConfig) (*RegimeClassification, error) {
+	if config.ADXPeriod <= 0 || config.VolatilityWindow < 2 || config.HurstMaxLag < 2 {
+		return nil, fmt.Errorf("invalid regime config")
+	}
+	if len(candles) < config.minCandles() {
+		return nil, fmt.Errorf("insufficient data points (%d) for regime classification, %d needed", len(candles), config.minCandles())
+	}
+
+	adx, plusDI, minusDI, err := ADX(candles, config.ADXPeriod)
+	if err != nil {
+		return nil, err
+	}
+	prices := make([]float64, len(candles))
+	for i, candle := range candles {
+		if candle.ClosePrice <= 0 {
+			return nil, fmt.Errorf("invalid close price %v in candle %d", candle.ClosePrice, i)
+		}
+		prices[i] = candle.ClosePrice
+	}
+
+	last := candles[len(candles)-1]
+	result := &RegimeClassification{
+		Token:                last.TokenAddress,
+		Direction:            "bullish",
+		StrongTrend:          adx >= config.StrongTrendADX,
+		ADX:                  adx,
+		VolatilityPercentile: volatilityPercentile(prices, config.VolatilityWindow),
+		Hurst:                hurstExponent(prices, config.HurstMaxLag),
+		Timestamp:            last.Timestamp,
+	}
+	if result.Token == "" {
+		result.Token = last.Symbol
+	}
+	if minusDI > plusDI {
+		result.Direction = "bearish"
+	}
+
+	switch {
+	case result.VolatilityPercentile >= config.HighVolatilityPercentile:
+		result.Regime = RegimeHighVolatility
+	case result.StrongTrend, adx >= config.TrendADX && result.Hurst >= config.TrendHurst:
+		result.Regime = RegimeTrending
+	default:
+		result.Regime = RegimeRanging
+	}
+	return result, nil
+}
+
+// AnalyzeRegime classifies the regime of the market data within the
+// timeframe ending now
+func (a *MarketAnalyzer) AnalyzeRegime(ctx context.Context, timeframe string) (*RegimeClassification, error) {
+	candles, err := a.marketData(ctx, timeframe)
+	if err != nil {
+		return nil, err
+	}
+	return ClassifyRegime(candles, a.config.Regime)
+}
+
+// volatilityPercentile returns the percentile of the realized volatility of
+// the last window log returns among the realized volatilities of every
+// window of the prices
+func volatilityPercentile(prices []float64, window int) float64 {
+	returns := make([]float64, len(prices)-1)
+	for i := 1; i < len(prices); i++ {
+		returns[i-1] = math.Log(prices[i] / prices[i-1])
+	}
+
+	vols := make([]float64, 0, len(returns)-window+1)
+	for end := window; end <= len(returns); end++ {
+		vols = append(vols, sampleStdDev(returns[end-window:end]))
+	}
+	current := vols[len(vols)-1]
+	below := 0
+	for _, vol := range vols {
+		if vol <= current {
+			below++
+		}
+	}
+	return 100 * float64(below) / float64(len(vols))
+}
+
+// hurstExponent estimates the Hurst exponent from how the root mean square
+// of log price changes grows with their lag, up to maxLag. It is about 0.5
+// for random walks, above for persistent moves and below for mean-reverting
+// prices. Flat prices score 0.5.
+func hurstExponent(prices []float64, maxLag int) float64 {
+	var xs, ys []float64
+	for lag := 1; lag <= maxLag; lag++ {
+		var sum float64
+		for i := lag; i < len(prices); i++ {
+			diff := math.Log(prices[i] / prices[i-lag])
+			sum += diff * diff
+		}
+		rms := math.Sqrt(sum / float64(len(prices)-lag))
+		if rms == 0 {
+			return 0.5
+		}
+		xs = append(xs, math.Log(float64(lag)))
+		ys = append(ys, math.Log(rms))
+	}
+
+	// Least squares slope of log RMS on log lag
+	var meanX, meanY float64
+	for i := range xs {
+		meanX += xs[i]
+		meanY += ys[i]
+	}
+	meanX /= float64(len(xs))
+	meanY /= float64(len(ys))
+	var cov, variance float64
+	for i := range xs {
+		cov += (xs[i] - meanX) * (ys[i] - meanY)
+		variance += (xs[i] - meanX) * (xs[i] - meanX)
+	}
+	return cov / variance
+}
+
+// sampleStdDev returns the sample standard deviation of values
+func sampleStdDev(values []float64) float64 {
+	var mean float64
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	var sum float64
+	for _, v := range values {
+		sum += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(sum / float64(len(values)-1))
+}
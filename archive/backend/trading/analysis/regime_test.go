@@ -0,0 +1,78 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/leonzhao/trading-system/backend/models"
+)
+
+// regimeCandles builds hourly candles of the closes with a 0.2% range
+func regimeCandles(closes []float64) []models.MarketData {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	candles := make([]models.MarketData, len(closes))
+	for i, close := range closes {
+		candles[i] = models.MarketData{
+			TokenAddress: "So11111111111111111111111111111111111111112",
+			OpenPrice:    close,
+			ClosePrice:   close,
+			HighPrice:    close * 1.001,
+			LowPrice:     close * 0.999,
+			Timestamp:    start.Add(time.Duration(i) * time.Hour),
+		}
+	}
+	return candles
+}
+
+func TestClassifyRegime(t *testing.T) {
+	config := DefaultRegimeConfig()
+
+	t.Run("trending", func(t *testing.T) {
+		closes := make([]float64, 120)
+		for i := range closes {
+			closes[i] = 100 * math.Pow(1.005, float64(i)) * (1 + 0.002*math.Sin(float64(i)))
+		}
+		regime, err := ClassifyRegime(regimeCandles(closes), config)
+		require.NoError(t, err)
+		assert.Equal(t, RegimeTrending, regime.Regime)
+		assert.Equal(t, "bullish", regime.Direction)
+		assert.True(t, regime.StrongTrend)
+		assert.Greater(t, regime.Hurst, 0.5)
+		assert.Equal(t, "So11111111111111111111111111111111111111112", regime.Token)
+	})
+
+	t.Run("ranging", func(t *testing.T) {
+		closes := make([]float64, 120)
+		for i := range closes {
+			closes[i] = 100 * (1 + 0.03*math.Sin(2*math.Pi*float64(i)/10))
+		}
+		regime, err := ClassifyRegime(regimeCandles(closes), config)
+		require.NoError(t, err)
+		assert.Equal(t, RegimeRanging, regime.Regime)
+		assert.Less(t, regime.Hurst, 0.5)
+	})
+
+	t.Run("high volatility", func(t *testing.T) {
+		closes := make([]float64, 120)
+		for i := range closes {
+			swing := 0.002
+			if i >= 100 {
+				swing = 0.05
+			}
+			closes[i] = 100 * (1 + swing*math.Sin(2*math.Pi*float64(i)/10))
+		}
+		regime, err := ClassifyRegime(regimeCandles(closes), config)
+		require.NoError(t, err)
+		assert.Equal(t, RegimeHighVolatility, regime.Regime)
+		assert.Equal(t, 100.0, regime.VolatilityPercentile)
+	})
+
+	t.Run("insufficient data", func(t *testing.T) {
+		_, err := ClassifyRegime(regimeCandles(make([]float64, 30)), config)
+		assert.Error(t, err)
+	})
+}
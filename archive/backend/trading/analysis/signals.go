@@ -0,0 +1,292 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/models"
+)
+
+// Indicator signal thresholds
+const (
+	rsiPeriod     = 14
+	rsiOversold   = 30.0
+	rsiOverbought = 70.0
+	bollingerSize = 20
+	bollingerDev  = 2.0
+	macdFast      = 12
+	macdSlow      = 26
+	macdSignal    = 9
+	atrPeriod     = 14
+	// atrVolatile is the ATR as a fraction of price above which signals are downgraded
+	atrVolatile     = 0.05
+	stochK          = 14
+	stochD          = 3
+	stochOversold   = 20.0
+	stochOverbought = 80.0
+	obvLookback     = 10
+	ichimokuTenkan  = 9
+	ichimokuKijun   = 26
+	ichimokuSenkou  = 52
+)
+
+// GenerateSignals derives RSI, MACD, Bollinger Bands, Stochastic, OBV, VWAP
+// and Ichimoku signals from candles, oldest first. Signals are downgraded one
+// strength level when ATR shows high volatility. Indicators without enough
+// data are skipped; an error is returned only if none of them could be
+// calculated.
+func GenerateSignals(symbol string, candles []models.MarketData) ([]models.TradeSignal, error) {
+	if len(candles) == 0 {
+		return nil, fmt.Errorf("no prices for %s", symbol)
+	}
+
+	prices := make([]float64, len(candles))
+	for i, candle := range candles {
+		prices[i] = candle.ClosePrice
+	}
+	price := prices[len(prices)-1]
+	now := time.Now()
+	var signals []models.TradeSignal
+	calculated := 0
+
+	if rsi, err := RSI(prices, rsiPeriod); err == nil {
+		calculated++
+		switch {
+		case rsi <= rsiOversold:
+			signals = append(signals, models.TradeSignal{
+				Symbol:        symbol,
+				SignalType:    models.SignalTypeOversold,
+				Price:         price,
+				Timestamp:     now,
+				Strength:      extremeStrength((rsiOversold - rsi) / rsiOversold),
+				Confidence:    (rsiOversold - rsi) / rsiOversold,
+				Description:   fmt.Sprintf("RSI %.1f below %.0f", rsi, rsiOversold),
+				IndicatorType: "RSI",
+			})
+		case rsi >= rsiOverbought:
+			signals = append(signals, models.TradeSignal{
+				Symbol:        symbol,
+				SignalType:    models.SignalTypeOverbought,
+				Price:         price,
+				Timestamp:     now,
+				Strength:      extremeStrength((rsi - rsiOverbought) / (100 - rsiOverbought)),
+				Confidence:    (rsi - rsiOverbought) / (100 - rsiOverbought),
+				Description:   fmt.Sprintf("RSI %.1f above %.0f", rsi, rsiOverbought),
+				IndicatorType: "RSI",
+			})
+		}
+	}
+
+	macd, _, err := MACD(prices, macdFast, macdSlow, macdSignal)
+	if err == nil {
+		calculated++
+	}
+	if err == nil && macd != 0 {
+		signalType := models.SignalTypeBullish
+		if macd < 0 {
+			signalType = models.SignalTypeBearish
+		}
+		// Scale the fast/slow EMA spread by price so the confidence is comparable across tokens
+		confidence := math.Min(math.Abs(macd)/price*100, 1)
+		signals = append(signals, models.TradeSignal{
+			Symbol:        symbol,
+			SignalType:    signalType,
+			Price:         price,
+			Timestamp:     now,
+			Strength:      extremeStrength(confidence),
+			Confidence:    confidence,
+			Description:   fmt.Sprintf("MACD line %.4f", macd),
+			IndicatorType: "MACD",
+		})
+	}
+
+	if bands, err := BollingerBands(prices, bollingerSize, bollingerDev); err == nil {
+		calculated++
+		switch {
+		case price < bands.Lower:
+			signals = append(signals, models.TradeSignal{
+				Symbol:        symbol,
+				SignalType:    models.SignalTypeOversold,
+				Price:         price,
+				Timestamp:     now,
+				Strength:      models.SignalStrengthMedium,
+				Confidence:    0.5,
+				Description:   fmt.Sprintf("Price %.4f below lower band %.4f", price, bands.Lower),
+				IndicatorType: "BB",
+			})
+		case price > bands.Upper:
+			signals = append(signals, models.TradeSignal{
+				Symbol:        symbol,
+				SignalType:    models.SignalTypeOverbought,
+				Price:         price,
+				Timestamp:     now,
+				Strength:      models.SignalStrengthMedium,
+				Confidence:    0.5,
+				Description:   fmt.Sprintf("Price %.4f above upper band %.4f", price, bands.Upper),
+				IndicatorType: "BB",
+			})
+		}
+	}
+
+	if stoch, err := Stochastic(candles, stochK, stochD); err == nil {
+		calculated++
+		switch {
+		case stoch.K <= stochOversold && stoch.K > stoch.D:
+			signals = append(signals, models.TradeSignal{
+				Symbol:        symbol,
+				SignalType:    models.SignalTypeOversold,
+				Price:         price,
+				Timestamp:     now,
+				Strength:      models.SignalStrengthMedium,
+				Confidence:    (stochOversold - stoch.K) / stochOversold,
+				Description:   fmt.Sprintf("Stochastic %%K %.1f above %%D %.1f below %.0f", stoch.K, stoch.D, stochOversold),
+				IndicatorType: "STOCH",
+			})
+		case stoch.K >= stochOverbought && stoch.K < stoch.D:
+			signals = append(signals, models.TradeSignal{
+				Symbol:        symbol,
+				SignalType:    models.SignalTypeOverbought,
+				Price:         price,
+				Timestamp:     now,
+				Strength:      models.SignalStrengthMedium,
+				Confidence:    (stoch.K - stochOverbought) / (100 - stochOverbought),
+				Description:   fmt.Sprintf("Stochastic %%K %.1f below %%D %.1f above %.0f", stoch.K, stoch.D, stochOverbought),
+				IndicatorType: "STOCH",
+			})
+		}
+	}
+
+	// OBV divergence: volume flowing against the price move
+	if len(candles) > obvLookback+1 {
+		obv, err := OBV(candles)
+		previous, prevErr := OBV(candles[:len(candles)-obvLookback])
+		if err == nil && prevErr == nil {
+			calculated++
+			priceChange := price - prices[len(prices)-1-obvLookback]
+			obvChange := obv - previous
+			switch {
+			case priceChange < 0 && obvChange > 0:
+				signals = append(signals, models.TradeSignal{
+					Symbol:        symbol,
+					SignalType:    models.SignalTypeBullish,
+					Price:         price,
+					Timestamp:     now,
+					Strength:      models.SignalStrengthWeak,
+					Confidence:    0.4,
+					Description:   fmt.Sprintf("OBV rose %.2f while price fell over %d candles", obvChange, obvLookback),
+					IndicatorType: "OBV",
+				})
+			case priceChange > 0 && obvChange < 0:
+				signals = append(signals, models.TradeSignal{
+					Symbol:        symbol,
+					SignalType:    models.SignalTypeBearish,
+					Price:         price,
+					Timestamp:     now,
+					Strength:      models.SignalStrengthWeak,
+					Confidence:    0.4,
+					Description:   fmt.Sprintf("OBV fell %.2f while price rose over %d candles", -obvChange, obvLookback),
+					IndicatorType: "OBV",
+				})
+			}
+		}
+	}
+
+	// VWAP: the close crossing the volume weighted price
+	if len(candles) >= 2 {
+		vwap, err := VWAP(candles)
+		if err == nil {
+			calculated++
+			previous := prices[len(prices)-2]
+			switch {
+			case previous <= vwap && price > vwap:
+				signals = append(signals, models.TradeSignal{
+					Symbol:        symbol,
+					SignalType:    models.SignalTypeBullish,
+					Price:         price,
+					Timestamp:     now,
+					Strength:      models.SignalStrengthWeak,
+					Confidence:    0.3,
+					Description:   fmt.Sprintf("Price %.4f crossed above VWAP %.4f", price, vwap),
+					IndicatorType: "VWAP",
+				})
+			case previous >= vwap && price < vwap:
+				signals = append(signals, models.TradeSignal{
+					Symbol:        symbol,
+					SignalType:    models.SignalTypeBearish,
+					Price:         price,
+					Timestamp:     now,
+					Strength:      models.SignalStrengthWeak,
+					Confidence:    0.3,
+					Description:   fmt.Sprintf("Price %.4f crossed below VWAP %.4f", price, vwap),
+					IndicatorType: "VWAP",
+				})
+			}
+		}
+	}
+
+	if cloud, err := Ichimoku(candles, ichimokuTenkan, ichimokuKijun, ichimokuSenkou); err == nil {
+		calculated++
+		top := math.Max(cloud.SenkouA, cloud.SenkouB)
+		bottom := math.Min(cloud.SenkouA, cloud.SenkouB)
+		switch {
+		case price > top && cloud.Tenkan > cloud.Kijun:
+			signals = append(signals, models.TradeSignal{
+				Symbol:        symbol,
+				SignalType:    models.SignalTypeBullish,
+				Price:         price,
+				Timestamp:     now,
+				Strength:      models.SignalStrengthStrong,
+				Confidence:    0.7,
+				Description:   fmt.Sprintf("Price %.4f above cloud %.4f with Tenkan above Kijun", price, top),
+				IndicatorType: "ICHIMOKU",
+			})
+		case price < bottom && cloud.Tenkan < cloud.Kijun:
+			signals = append(signals, models.TradeSignal{
+				Symbol:        symbol,
+				SignalType:    models.SignalTypeBearish,
+				Price:         price,
+				Timestamp:     now,
+				Strength:      models.SignalStrengthStrong,
+				Confidence:    0.7,
+				Description:   fmt.Sprintf("Price %.4f below cloud %.4f with Tenkan below Kijun", price, bottom),
+				IndicatorType: "ICHIMOKU",
+			})
+		}
+	}
+
+	if calculated == 0 {
+		return nil, fmt.Errorf("insufficient data points (%d) for any indicator", len(prices))
+	}
+
+	// High volatility makes every signal less reliable
+	if atr, err := ATR(candles, atrPeriod); err == nil && price > 0 && atr/price > atrVolatile {
+		for i := range signals {
+			signals[i].Strength = downgradeStrength(signals[i].Strength)
+			signals[i].Description += fmt.Sprintf(" (ATR %.4f above %.0f%% of price)", atr, atrVolatile*100)
+		}
+	}
+	return signals, nil
+}
+
+// downgradeStrength returns the next weaker signal strength
+func downgradeStrength(strength models.SignalStrength) models.SignalStrength {
+	switch strength {
+	case models.SignalStrengthStrong:
+		return models.SignalStrengthMedium
+	default:
+		return models.SignalStrengthWeak
+	}
+}
+
+// extremeStrength maps how far an indicator is past its threshold to a strength
+func extremeStrength(excess float64) models.SignalStrength {
+	switch {
+	case excess >= 0.5:
+		return models.SignalStrengthStrong
+	case excess >= 0.2:
+		return models.SignalStrengthMedium
+	default:
+		return models.SignalStrengthWeak
+	}
+}
@@ -154,3 +154,216 @@ func MACD(prices []float64, fast, slow, signal int) (float64, float64, error) {
 
 	return macdLine, macdLine - signalEMA, nil
 }
+
+// validateCandles rejects candles with NaN, infinite or negative values
+func validateCandles(candles []models.MarketData) error {
+	for i, candle := range candles {
+		for _, value := range []float64{candle.HighPrice, candle.LowPrice, candle.ClosePrice, candle.Volume} {
+			if math.IsNaN(value) || math.IsInf(value, 0) || value < 0 {
+				return fmt.Errorf("invalid value %v in candle %d", value, i)
+			}
+		}
+	}
+	return nil
+}
+
+// ATR calculates Average True Range using Wilder's smoothing
+func ATR(candles []models.MarketData, period int) (float64, error) {
+	if period <= 0 {
+		return 0, fmt.Errorf("invalid period: %d", period)
+	}
+	if len(candles) <= period {
+		return 0, fmt.Errorf("insufficient data points (%d) for ATR period %d", len(candles), period)
+	}
+	if err := validateCandles(candles); err != nil {
+		return 0, err
+	}
+
+	var atr float64
+	for i := 1; i < len(candles); i++ {
+		prevClose := candles[i-1].ClosePrice
+		tr := math.Max(candles[i].HighPrice-candles[i].LowPrice,
+			math.Max(math.Abs(candles[i].HighPrice-prevClose), math.Abs(candles[i].LowPrice-prevClose)))
+
+		switch {
+		case i < period:
+			atr += tr
+		case i == period:
+			atr = (atr + tr) / float64(period)
+		default:
+			atr = (atr*float64(period-1) + tr) / float64(period)
+		}
+	}
+	return atr, nil
+}
+
+// Stochastic calculates the Stochastic oscillator. %K compares the close to
+// the kPeriod high-low range and %D is the dPeriod average of %K.
+func Stochastic(candles []models.MarketData, kPeriod, dPeriod int) (models.Stochastic, error) {
+	if kPeriod <= 0 || dPeriod <= 0 {
+		return models.Stochastic{}, fmt.Errorf("invalid period: %d/%d", kPeriod, dPeriod)
+	}
+	if len(candles) < kPeriod+dPeriod-1 {
+		return models.Stochastic{}, fmt.Errorf("insufficient data points (%d) for Stochastic periods %d/%d", len(candles), kPeriod, dPeriod)
+	}
+	if err := validateCandles(candles); err != nil {
+		return models.Stochastic{}, err
+	}
+
+	var k, sum float64
+	for end := len(candles) - dPeriod + 1; end <= len(candles); end++ {
+		high, low := highLow(candles[end-kPeriod : end])
+		k = 50
+		if high > low {
+			k = (candles[end-1].ClosePrice - low) / (high - low) * 100
+		}
+		sum += k
+	}
+	return models.Stochastic{K: k, D: sum / float64(dPeriod)}, nil
+}
+
+// OBV calculates On-Balance Volume over the candles
+func OBV(candles []models.MarketData) (float64, error) {
+	if len(candles) < 2 {
+		return 0, fmt.Errorf("insufficient data points (%d) for OBV", len(candles))
+	}
+	if err := validateCandles(candles); err != nil {
+		return 0, err
+	}
+
+	var obv float64
+	for i := 1; i < len(candles); i++ {
+		switch {
+		case candles[i].ClosePrice > candles[i-1].ClosePrice:
+			obv += candles[i].Volume
+		case candles[i].ClosePrice < candles[i-1].ClosePrice:
+			obv -= candles[i].Volume
+		}
+	}
+	return obv, nil
+}
+
+// VWAP calculates the Volume Weighted Average Price of the typical price over the candles
+func VWAP(candles []models.MarketData) (float64, error) {
+	if len(candles) == 0 {
+		return 0, fmt.Errorf("insufficient data points (0) for VWAP")
+	}
+	if err := validateCandles(candles); err != nil {
+		return 0, err
+	}
+
+	var notional, volume float64
+	for _, candle := range candles {
+		typical := (candle.HighPrice + candle.LowPrice + candle.ClosePrice) / 3
+		notional += typical * candle.Volume
+		volume += candle.Volume
+	}
+	if volume == 0 {
+		return 0, fmt.Errorf("no volume for VWAP")
+	}
+	return notional / volume, nil
+}
+
+// Ichimoku calculates the Ichimoku cloud. The Senkou spans are computed
+// kijun candles back so they describe the cloud at the latest candle.
+func Ichimoku(candles []models.MarketData, tenkan, kijun, senkouB int) (models.Ichimoku, error) {
+	if tenkan <= 0 || kijun <= 0 || senkouB <= 0 {
+		return models.Ichimoku{}, fmt.Errorf("invalid period: %d/%d/%d", tenkan, kijun, senkouB)
+	}
+	if len(candles) < max(tenkan, kijun, senkouB)+kijun {
+		return models.Ichimoku{}, fmt.Errorf("insufficient data points (%d) for Ichimoku periods %d/%d/%d", len(candles), tenkan, kijun, senkouB)
+	}
+	if err := validateCandles(candles); err != nil {
+		return models.Ichimoku{}, err
+	}
+
+	midpoint := func(window []models.MarketData) float64 {
+		high, low := highLow(window)
+		return (high + low) / 2
+	}
+
+	n := len(candles)
+	displaced := candles[:n-kijun]
+	return models.Ichimoku{
+		Tenkan:  midpoint(candles[n-tenkan:]),
+		Kijun:   midpoint(candles[n-kijun:]),
+		SenkouA: (midpoint(displaced[len(displaced)-tenkan:]) + midpoint(displaced[len(displaced)-kijun:])) / 2,
+		SenkouB: midpoint(displaced[len(displaced)-senkouB:]),
+		Chikou:  candles[n-1].ClosePrice,
+	}, nil
+}
+
+// ADX calculates the Average Directional Index and the +DI and -DI
+// directional indicators using Wilder's smoothing. ADX measures the strength
+// of a trend from 0 to 100 whatever its direction, given by the larger of
+// +DI and -DI.
+func ADX(candles []models.MarketData, period int) (float64, float64, float64, error) {
+	if period <= 0 {
+		return 0, 0, 0, fmt.Errorf("invalid period: %d", period)
+	}
+	if len(candles) < 2*period {
+		return 0, 0, 0, fmt.Errorf("insufficient data points (%d) for ADX period %d", len(candles), period)
+	}
+	if err := validateCandles(candles); err != nil {
+		return 0, 0, 0, err
+	}
+
+	var tr, plusDM, minusDM, adx, plusDI, minusDI float64
+	for i := 1; i < len(candles); i++ {
+		up := candles[i].HighPrice - candles[i-1].HighPrice
+		down := candles[i-1].LowPrice - candles[i].LowPrice
+		var plus, minus float64
+		if up > down && up > 0 {
+			plus = up
+		}
+		if down > up && down > 0 {
+			minus = down
+		}
+		prevClose := candles[i-1].ClosePrice
+		trueRange := math.Max(candles[i].HighPrice-candles[i].LowPrice,
+			math.Max(math.Abs(candles[i].HighPrice-prevClose), math.Abs(candles[i].LowPrice-prevClose)))
+
+		if i <= period {
+			tr += trueRange
+			plusDM += plus
+			minusDM += minus
+		} else {
+			tr = tr - tr/float64(period) + trueRange
+			plusDM = plusDM - plusDM/float64(period) + plus
+			minusDM = minusDM - minusDM/float64(period) + minus
+		}
+		if i < period {
+			continue
+		}
+
+		plusDI, minusDI = 0, 0
+		if tr > 0 {
+			plusDI = 100 * plusDM / tr
+			minusDI = 100 * minusDM / tr
+		}
+		var dx float64
+		if sum := plusDI + minusDI; sum > 0 {
+			dx = 100 * math.Abs(plusDI-minusDI) / sum
+		}
+
+		switch {
+		case i < 2*period-1:
+			adx += dx
+		case i == 2*period-1:
+			adx = (adx + dx) / float64(period)
+		default:
+			adx = (adx*float64(period-1) + dx) / float64(period)
+		}
+	}
+	return adx, plusDI, minusDI, nil
+}
+
+// highLow returns the highest high and lowest low of the candles
+func highLow(candles []models.MarketData) (float64, float64) {
+	high, low := candles[0].HighPrice, candles[0].LowPrice
+	for _, candle := range candles[1:] {
+		high = math.Max(high, candle.HighPrice)
+		low = math.Min(low, candle.LowPrice)
+	}
+	return high, low
+}
@@ -314,3 +314,203 @@ func TestCalculateRSI(t *testing.T) {
 		}
 	})
 }
+
+func TestCandleIndicators(t *testing.T) {
+	// Rising candles with high, low and close all equal to the index
+	rising := make([]models.MarketData, 78)
+	for i := range rising {
+		price := float64(i)
+		rising[i] = models.MarketData{HighPrice: price, LowPrice: price, ClosePrice: price, Volume: 1}
+	}
+
+	t.Run("ATR", func(t *testing.T) {
+		candles := make([]models.MarketData, 20)
+		for i := range candles {
+			candles[i] = models.MarketData{HighPrice: 101, LowPrice: 99, ClosePrice: 100}
+		}
+		atr, err := ATR(candles, 14)
+		if err != nil || math.Abs(atr-2) > 1e-9 {
+			t.Errorf("Expected ATR 2, got: %v (%v)", atr, err)
+		}
+		if _, err := ATR(candles[:14], 14); err == nil {
+			t.Errorf("Expected error for insufficient data")
+		}
+		if _, err := ATR(candles, 0); err == nil {
+			t.Errorf("Expected error for invalid period")
+		}
+	})
+
+	t.Run("Stochastic", func(t *testing.T) {
+		stoch, err := Stochastic(rising[:20], 5, 3)
+		if err != nil || stoch.K != 100 || stoch.D != 100 {
+			t.Errorf("Expected %%K and %%D of 100, got: %+v (%v)", stoch, err)
+		}
+
+		flat := []models.MarketData{{HighPrice: 1, LowPrice: 1, ClosePrice: 1}, {HighPrice: 1, LowPrice: 1, ClosePrice: 1}}
+		stoch, err = Stochastic(flat, 2, 1)
+		if err != nil || stoch.K != 50 {
+			t.Errorf("Expected %%K of 50 for a flat range, got: %+v (%v)", stoch, err)
+		}
+		if _, err := Stochastic(rising[:6], 5, 3); err == nil {
+			t.Errorf("Expected error for insufficient data")
+		}
+	})
+
+	t.Run("OBV", func(t *testing.T) {
+		obv, err := OBV([]models.MarketData{
+			{ClosePrice: 10, Volume: 1},
+			{ClosePrice: 11, Volume: 2},
+			{ClosePrice: 10, Volume: 3},
+			{ClosePrice: 10, Volume: 4},
+		})
+		if err != nil || obv != -1 {
+			t.Errorf("Expected OBV -1, got: %v (%v)", obv, err)
+		}
+		if _, err := OBV(rising[:1]); err == nil {
+			t.Errorf("Expected error for insufficient data")
+		}
+	})
+
+	t.Run("VWAP", func(t *testing.T) {
+		vwap, err := VWAP([]models.MarketData{
+			{HighPrice: 11, LowPrice: 9, ClosePrice: 10, Volume: 1},
+			{HighPrice: 21, LowPrice: 19, ClosePrice: 20, Volume: 3},
+		})
+		if err != nil || math.Abs(vwap-17.5) > 1e-9 {
+			t.Errorf("Expected VWAP 17.5, got: %v (%v)", vwap, err)
+		}
+		if _, err := VWAP([]models.MarketData{{ClosePrice: 10}}); err == nil {
+			t.Errorf("Expected error without volume")
+		}
+	})
+
+	t.Run("Ichimoku", func(t *testing.T) {
+		cloud, err := Ichimoku(rising, 9, 26, 52)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		want := models.Ichimoku{Tenkan: 73, Kijun: 64.5, SenkouA: 42.75, SenkouB: 25.5, Chikou: 77}
+		if cloud != want {
+			t.Errorf("Expected %+v, got: %+v", want, cloud)
+		}
+		if _, err := Ichimoku(rising[:77], 9, 26, 52); err == nil {
+			t.Errorf("Expected error for insufficient data")
+		}
+	})
+
+	t.Run("NaN values", func(t *testing.T) {
+		candles := append([]models.MarketData{}, rising...)
+		candles[40].HighPrice = math.NaN()
+		if _, err := ATR(candles, 14); err == nil {
+			t.Errorf("Expected ATR error for NaN prices")
+		}
+		if _, err := Stochastic(candles, 14, 3); err == nil {
+			t.Errorf("Expected Stochastic error for NaN prices")
+		}
+		if _, err := VWAP(candles); err == nil {
+			t.Errorf("Expected VWAP error for NaN prices")
+		}
+		if _, err := Ichimoku(candles, 9, 26, 52); err == nil {
+			t.Errorf("Expected Ichimoku error for NaN prices")
+		}
+		candles[40].Volume = math.Inf(1)
+		if _, err := OBV(candles); err == nil {
+			t.Errorf("Expected OBV error for infinite volume")
+		}
+	})
+}
+
+// candlesFromCloses builds candles spanning spread around each close
+func candlesFromCloses(closes []float64, spread float64, volume func(i int) float64) []models.MarketData {
+	candles := make([]models.MarketData, len(closes))
+	for i, price := range closes {
+		candles[i] = models.MarketData{
+			HighPrice:  price + spread,
+			LowPrice:   price - spread,
+			ClosePrice: price,
+			Volume:     volume(i),
+		}
+	}
+	return candles
+}
+
+func constantVolume(int) float64 { return 1000 }
+
+func signalsByIndicator(t *testing.T, candles []models.MarketData) map[string]models.TradeSignal {
+	t.Helper()
+	signals, err := GenerateSignals("SOL", candles)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	found := map[string]models.TradeSignal{}
+	for _, signal := range signals {
+		found[signal.IndicatorType] = signal
+		if signal.Price != candles[len(candles)-1].ClosePrice {
+			t.Errorf("Expected signal price %v, got: %v", candles[len(candles)-1].ClosePrice, signal.Price)
+		}
+	}
+	return found
+}
+
+func TestGenerateSignals(t *testing.T) {
+	falling := make([]float64, 80)
+	for i := range falling {
+		falling[i] = 200.0 - float64(i)
+	}
+
+	t.Run("falling prices", func(t *testing.T) {
+		found := signalsByIndicator(t, candlesFromCloses(falling, 0.5, constantVolume))
+		if found["RSI"].SignalType != models.SignalTypeOversold {
+			t.Errorf("Expected oversold RSI signal, got: %v", found["RSI"].SignalType)
+		}
+		if found["MACD"].SignalType != models.SignalTypeBearish {
+			t.Errorf("Expected bearish MACD signal, got: %v", found["MACD"].SignalType)
+		}
+		if found["ICHIMOKU"].SignalType != models.SignalTypeBearish || found["ICHIMOKU"].Strength != models.SignalStrengthStrong {
+			t.Errorf("Expected strong bearish Ichimoku signal, got: %+v", found["ICHIMOKU"])
+		}
+	})
+
+	t.Run("high ATR downgrades strength", func(t *testing.T) {
+		found := signalsByIndicator(t, candlesFromCloses(falling, 20, constantVolume))
+		if found["ICHIMOKU"].Strength != models.SignalStrengthMedium {
+			t.Errorf("Expected medium Ichimoku signal in high volatility, got: %v", found["ICHIMOKU"].Strength)
+		}
+	})
+
+	t.Run("OBV divergence", func(t *testing.T) {
+		// Small rises on heavy volume, larger drops on light volume
+		closes := make([]float64, 20)
+		closes[0] = 100
+		for i := 1; i < len(closes); i++ {
+			if i%2 == 1 {
+				closes[i] = closes[i-1] + 1
+			} else {
+				closes[i] = closes[i-1] - 3
+			}
+		}
+		found := signalsByIndicator(t, candlesFromCloses(closes, 0.5, func(i int) float64 {
+			if i%2 == 1 {
+				return 1000
+			}
+			return 10
+		}))
+		if found["OBV"].SignalType != models.SignalTypeBullish {
+			t.Errorf("Expected bullish OBV divergence, got: %+v", found["OBV"])
+		}
+	})
+
+	t.Run("VWAP cross", func(t *testing.T) {
+		closes := []float64{100, 100, 100, 100, 110}
+		found := signalsByIndicator(t, candlesFromCloses(closes, 0.5, constantVolume))
+		if found["VWAP"].SignalType != models.SignalTypeBullish {
+			t.Errorf("Expected bullish VWAP cross, got: %+v", found["VWAP"])
+		}
+	})
+
+	t.Run("insufficient data", func(t *testing.T) {
+		if _, err := GenerateSignals("SOL", candlesFromCloses([]float64{1}, 0, constantVolume)); err == nil {
+			t.Errorf("Expected error for insufficient data")
+		}
+	})
+}
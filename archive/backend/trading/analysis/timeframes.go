@@ -0,0 +1,287 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/models"
+)
+
+// Indicators voting on the direction of a timeframe
+const (
+	// IndicatorTrend compares the last close with the first one
+	IndicatorTrend = "trend"
+	// IndicatorEMA compares the 9 and 21 candle EMAs
+	IndicatorEMA = "ema"
+	// IndicatorRSI is bullish above 55 and bearish below 45
+	IndicatorRSI = "rsi"
+	// IndicatorMACD is the sign of the MACD line
+	IndicatorMACD = "macd"
+)
+
+const (
+	emaFast       = 9
+	emaSlow       = 21
+	rsiBullish    = 55.0
+	rsiBearish    = 45.0
+	strongScore   = 0.75
+	mediumScore   = 0.5
+	mtfIndicator  = "MTF"
+	trendBullish  = "bullish"
+	trendBearish  = "bearish"
+	trendNeutral  = "neutral"
+	defaultWeight = 1.0
+)
+
+// indicatorCandles is the number of candles each indicator needs
+var indicatorCandles = map[string]int{
+	IndicatorTrend: 2,
+	IndicatorEMA:   emaSlow,
+	IndicatorRSI:   rsiPeriod + 1,
+	IndicatorMACD:  macdSlow,
+}
+
+// TimeframeConfig configures the analysis of one timeframe
+type TimeframeConfig struct {
+	// Timeframe is the candle interval, such as "15m" or "4h"
+	Timeframe string `json:"timeframe"`
+	// Candles is the number of candles analyzed, ending now
+	Candles int `json:"candles"`
+	// Indicators are the indicators voting on the direction of the
+	// timeframe
+	Indicators []string `json:"indicators"`
+	// Weight is the weight of the timeframe in the consensus, 1 when zero
+	Weight float64 `json:"weight"`
+}
+
+// ConsensusConfig configures the multi-timeframe consensus
+type ConsensusConfig struct {
+	Timeframes []TimeframeConfig `json:"timeframes"`
+	// MinAgreement is the share of the timeframe weight, from 0 to 1, that
+	// must agree on a direction before a signal is emitted. 1 requires every
+	// timeframe to agree.
+	MinAgreement float64 `json:"min_agreement"`
+	// MinScore is the smallest absolute consensus score of a signal
+	MinScore float64 `json:"min_score"`
+}
+
+// DefaultConsensusConfig returns the timeframes analyzed by default, from 1m
+// to 1d, the shorter ones with faster indicators. Their lookbacks fit the
+// week of data kept by NewMarketAnalyzer.
+func DefaultConsensusConfig() ConsensusConfig {
+	return ConsensusConfig{
+		Timeframes: []TimeframeConfig{
+			{Timeframe: "1m", Candles: 60, Indicators: []string{IndicatorRSI, IndicatorMACD}},
+			{Timeframe: "15m", Candles: 60, Indicators: []string{IndicatorEMA, IndicatorRSI, IndicatorMACD}},
+			{Timeframe: "1h", Candles: 60, Indicators: []string{IndicatorEMA, IndicatorMACD}},
+			{Timeframe: "4h", Candles: 30, Indicators: []string{IndicatorTrend, IndicatorEMA}},
+			{Timeframe: "1d", Candles: 7, Indicators: []string{IndicatorTrend}},
+		},
+		MinAgreement: 1,
+		MinScore:     0.3,
+	}
+}
+
+// AnalyzeTimeframes analyzes each configured timeframe and scores their
+// consensus on the direction of the market. It returns nil without
+// configured timeframes.
+func (a *MarketAnalyzer) AnalyzeTimeframes(ctx context.Context) (*models.TimeframeConsensus, error) {
+	consensus, _, err := a.consensus(ctx)
+	return consensus, err
+}
+
+// ConsensusSignal returns a buy or sell signal when the configured
+// timeframes are aligned, and nil otherwise, along with the consensus it is
+// based on. Without configured timeframes no signal is ever emitted.
+func (a *MarketAnalyzer) ConsensusSignal(ctx context.Context, symbol string) (*models.TradeSignal, *models.TimeframeConsensus, error) {
+	consensus, price, err := a.consensus(ctx)
+	if err != nil || consensus == nil || !consensus.Aligned {
+		return nil, consensus, err
+	}
+
+	signal := &models.TradeSignal{
+		Symbol:        symbol,
+		SignalType:    models.SignalTypeBuy,
+		Price:         price,
+		Timestamp:     a.now(),
+		Strength:      models.SignalStrengthWeak,
+		Confidence:    math.Abs(consensus.Score),
+		Description:   fmt.Sprintf("%s consensus of %d timeframes, score %.2f", consensus.Direction, len(consensus.Timeframes), consensus.Score),
+		IndicatorType: mtfIndicator,
+	}
+	if consensus.Direction == trendBearish {
+		signal.SignalType = models.SignalTypeSell
+	}
+	switch {
+	case signal.Confidence >= strongScore:
+		signal.Strength = models.SignalStrengthStrong
+	case signal.Confidence >= mediumScore:
+		signal.Strength = models.SignalStrengthMedium
+	}
+	return signal, consensus, nil
+}
+
+// consensus scores the configured timeframes, reading the market data of
+// the longest one at once. It also returns the last close price.
+func (a *MarketAnalyzer) consensus(ctx context.Context) (*models.TimeframeConsensus, float64, error) {
+	config := a.config.Consensus
+	if len(config.Timeframes) == 0 {
+		return nil, 0, nil
+	}
+
+	intervals := make([]time.Duration, len(config.Timeframes))
+	var window time.Duration
+	for i, tf := range config.Timeframes {
+		interval, err := ParseTimeframe(tf.Timeframe)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, indicator := range tf.Indicators {
+			if _, ok := indicatorCandles[indicator]; !ok {
+				return nil, 0, fmt.Errorf("unknown indicator %q on timeframe %s", indicator, tf.Timeframe)
+			}
+		}
+		intervals[i] = interval
+		window = max(window, time.Duration(tf.Candles)*interval)
+	}
+
+	to := a.now()
+	data, err := a.provider.GetMarketData(ctx, to.Add(-window), to)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	consensus := &models.TimeframeConsensus{Direction: trendNeutral}
+	var totalWeight float64
+	for i, tf := range config.Timeframes {
+		score := scoreTimeframe(tf, closesByInterval(data, to.Add(-time.Duration(tf.Candles)*intervals[i]), intervals[i]))
+		consensus.Timeframes = append(consensus.Timeframes, score)
+		totalWeight += score.Weight
+		consensus.Score += score.Weight * score.Score
+	}
+	consensus.Score /= totalWeight
+	consensus.Direction = direction(consensus.Score)
+
+	var agreeing float64
+	for _, score := range consensus.Timeframes {
+		if score.Direction == consensus.Direction {
+			agreeing += score.Weight
+		}
+	}
+	consensus.Agreement = agreeing / totalWeight
+	consensus.Aligned = consensus.Direction != trendNeutral &&
+		consensus.Agreement >= config.MinAgreement &&
+		math.Abs(consensus.Score) >= config.MinScore
+
+	var price float64
+	if len(data) > 0 {
+		price = data[len(data)-1].ClosePrice
+	}
+	return consensus, price, nil
+}
+
+// closesByInterval returns the close prices of the candles of the interval
+// from from on, the last close of each candle, oldest first
+func closesByInterval(data []models.MarketData, from time.Time, interval time.Duration) []float64 {
+	var closes []float64
+	var candle time.Time
+	for _, point := range data {
+		if point.Timestamp.Before(from) {
+			continue
+		}
+		start := point.Timestamp.Truncate(interval)
+		if len(closes) > 0 && start.Equal(candle) {
+			closes[len(closes)-1] = point.ClosePrice
+			continue
+		}
+		candle = start
+		closes = append(closes, point.ClosePrice)
+	}
+	return closes
+}
+
+// scoreTimeframe lets the indicators of a timeframe vote on its direction.
+// A timeframe without enough candles for all its indicators stays neutral.
+func scoreTimeframe(tf TimeframeConfig, closes []float64) models.TimeframeScore {
+	score := models.TimeframeScore{
+		Timeframe: tf.Timeframe,
+		Candles:   len(closes),
+		Weight:    tf.Weight,
+		Direction: trendNeutral,
+	}
+	if score.Weight <= 0 {
+		score.Weight = defaultWeight
+	}
+
+	if len(tf.Indicators) == 0 {
+		score.Error = "no indicators"
+		return score
+	}
+	needed := 0
+	for _, indicator := range tf.Indicators {
+		needed = max(needed, indicatorCandles[indicator])
+	}
+	if len(closes) < needed {
+		score.Error = fmt.Sprintf("insufficient candles (%d) for the indicators, %d needed", len(closes), needed)
+		return score
+	}
+
+	score.Votes = make(map[string]int, len(tf.Indicators))
+	var sum int
+	for _, indicator := range tf.Indicators {
+		vote := voteOf(indicator, closes)
+		score.Votes[indicator] = vote
+		sum += vote
+	}
+	score.Score = float64(sum) / float64(len(tf.Indicators))
+	score.Direction = direction(score.Score)
+	return score
+}
+
+// voteOf returns the direction an indicator gives the closes, 1 for
+// bullish, -1 for bearish and 0 for neutral. The closes are long enough for
+// the indicator.
+func voteOf(indicator string, closes []float64) int {
+	switch indicator {
+	case IndicatorTrend:
+		return sign(closes[len(closes)-1] - closes[0])
+	case IndicatorEMA:
+		fast, _ := EMA(closes, emaFast)
+		slow, _ := EMA(closes, emaSlow)
+		return sign(fast - slow)
+	case IndicatorRSI:
+		rsi, _ := RSI(closes, rsiPeriod)
+		switch {
+		case rsi > rsiBullish:
+			return 1
+		case rsi < rsiBearish:
+			return -1
+		}
+	case IndicatorMACD:
+		macd, _, _ := MACD(closes, macdFast, macdSlow, macdSignal)
+		return sign(macd)
+	}
+	return 0
+}
+
+func sign(x float64) int {
+	switch {
+	case x > 0:
+		return 1
+	case x < 0:
+		return -1
+	}
+	return 0
+}
+
+func direction(score float64) string {
+	switch {
+	case score > 0:
+		return trendBullish
+	case score < 0:
+		return trendBearish
+	}
+	return trendNeutral
+}
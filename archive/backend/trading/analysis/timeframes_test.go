@@ -0,0 +1,112 @@
+package analysis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/leonzhao/trading-system/backend/models"
+)
+
+// hourlyAnalyzer returns an analyzer of 48 hourly closes, price(i) being the
+// close i hours before now
+func hourlyAnalyzer(now time.Time, price func(i int) float64) *MarketAnalyzer {
+	analyzer := NewMarketAnalyzer()
+	analyzer.now = func() time.Time { return now }
+	for i := 0; i < 48; i++ {
+		analyzer.AddMarketData(models.MarketData{ClosePrice: price(i), Timestamp: now.Add(-time.Duration(i)*time.Hour - time.Minute)})
+	}
+	return analyzer
+}
+
+func TestConsensusAligned(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 10, 12, 30, 0, 0, time.UTC)
+	analyzer := hourlyAnalyzer(now, func(i int) float64 { return 100 + float64(47-i) })
+	analyzer.config.Consensus = ConsensusConfig{
+		Timeframes: []TimeframeConfig{
+			{Timeframe: "1h", Candles: 30, Indicators: []string{IndicatorTrend, IndicatorEMA}},
+			{Timeframe: "4h", Candles: 10, Indicators: []string{IndicatorTrend}},
+			{Timeframe: "1m", Candles: 60, Indicators: []string{IndicatorRSI}, Weight: 0.5},
+		},
+		MinAgreement: 0.8,
+		MinScore:     0.5,
+	}
+
+	signal, consensus, err := analyzer.ConsensusSignal(ctx, "SOL")
+	require.NoError(t, err)
+	require.Len(t, consensus.Timeframes, 3)
+	hourly := consensus.Timeframes[0]
+	assert.Equal(t, 30, hourly.Candles)
+	assert.Equal(t, map[string]int{IndicatorTrend: 1, IndicatorEMA: 1}, hourly.Votes)
+	assert.Equal(t, "bullish", hourly.Direction)
+	assert.Equal(t, "bullish", consensus.Timeframes[1].Direction)
+	// A single point in the last hour is too few 1m candles for the RSI
+	assert.Equal(t, "neutral", consensus.Timeframes[2].Direction)
+	assert.NotEmpty(t, consensus.Timeframes[2].Error)
+
+	assert.Equal(t, "bullish", consensus.Direction)
+	assert.InDelta(t, 0.8, consensus.Score, 1e-9)
+	assert.InDelta(t, 0.8, consensus.Agreement, 1e-9)
+	assert.True(t, consensus.Aligned)
+	require.NotNil(t, signal)
+	assert.Equal(t, models.SignalTypeBuy, signal.SignalType)
+	assert.Equal(t, models.SignalStrengthStrong, signal.Strength)
+	assert.Equal(t, 147.0, signal.Price)
+
+	// Reports carry the breakdown
+	analyzer.tradeHistory = []models.Trade{{Price: 100, Amount: 1, Value: 110}}
+	report, err := analyzer.GenerateReport(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, consensus, report.Consensus)
+	assert.Equal(t, consensus, report.Record().Consensus)
+}
+
+func TestConsensusMisaligned(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 10, 12, 30, 0, 0, time.UTC)
+	// Rising for two days, falling over the last six hours
+	analyzer := hourlyAnalyzer(now, func(i int) float64 {
+		if i < 6 {
+			return 135 + float64(i)
+		}
+		return 100 + float64(47-i)
+	})
+	analyzer.config.Consensus = ConsensusConfig{
+		Timeframes: []TimeframeConfig{
+			{Timeframe: "1h", Candles: 6, Indicators: []string{IndicatorTrend}},
+			{Timeframe: "4h", Candles: 10, Indicators: []string{IndicatorTrend}, Weight: 2},
+		},
+		MinAgreement: 1,
+	}
+
+	signal, consensus, err := analyzer.ConsensusSignal(ctx, "SOL")
+	require.NoError(t, err)
+	assert.Nil(t, signal, "no signal until every timeframe agrees")
+	assert.Equal(t, "bearish", consensus.Timeframes[0].Direction)
+	assert.Equal(t, "bullish", consensus.Timeframes[1].Direction)
+	assert.Equal(t, "bullish", consensus.Direction)
+	assert.InDelta(t, 1.0/3, consensus.Score, 1e-9)
+	assert.InDelta(t, 2.0/3, consensus.Agreement, 1e-9)
+	assert.False(t, consensus.Aligned)
+
+	analyzer.config.Consensus.MinAgreement = 0.6
+	signal, _, err = analyzer.ConsensusSignal(ctx, "SOL")
+	require.NoError(t, err)
+	require.NotNil(t, signal)
+	assert.Equal(t, models.SignalTypeBuy, signal.SignalType)
+	assert.Equal(t, models.SignalStrengthWeak, signal.Strength)
+
+	analyzer.config.Consensus.Timeframes[0].Indicators = []string{"vwap"}
+	_, _, err = analyzer.ConsensusSignal(ctx, "SOL")
+	assert.Error(t, err)
+
+	analyzer.config.Consensus.Timeframes = nil
+	signal, consensus, err = analyzer.ConsensusSignal(ctx, "SOL")
+	assert.NoError(t, err)
+	assert.Nil(t, signal)
+	assert.Nil(t, consensus)
+}
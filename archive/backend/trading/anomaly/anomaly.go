@@ -0,0 +1,292 @@
+// Package anomaly flags suspicious market data ticks, such as price spikes
+// from a corrupted DEX quote, feeds stuck at zero volume and stale
+// timestamps, and quarantines them before they reach indicators or
+// strategies.
+package anomaly
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/monitoring"
+)
+
+// ErrAnomaly is wrapped by every anomaly returned by Check
+var ErrAnomaly = errors.New("market data anomaly")
+
+// Kind is the kind of an anomaly
+type Kind string
+
+const (
+	// KindInvalidPrice is a missing, negative or non-finite price
+	KindInvalidPrice Kind = "invalid_price"
+	// KindPriceSpike is a price jump of more than Sigma standard deviations
+	// of the recent returns
+	KindPriceSpike Kind = "price_spike"
+	// KindZeroVolume is a feed reporting no volume for ZeroVolumeTicks
+	// ticks in a row
+	KindZeroVolume Kind = "zero_volume"
+	// KindStaleTimestamp is a tick older than MaxAge or not newer than the
+	// last accepted tick of its token
+	KindStaleTimestamp Kind = "stale_timestamp"
+)
+
+// Config contains anomaly detection configuration
+type Config struct {
+	// Sigma is the number of standard deviations of the recent log returns
+	// a price jump must exceed to be a spike
+	Sigma float64 `json:"sigma"`
+	// MinJump is the smallest relative price jump flagged as a spike, so
+	// that nearly flat prices do not flag ordinary moves
+	MinJump float64 `json:"min_jump"`
+	// Window is the number of recent returns the standard deviation is
+	// taken over
+	Window int `json:"window"`
+	// MinSamples is the number of returns needed before spikes are flagged
+	MinSamples int `json:"min_samples"`
+	// Confirmations is the number of spikes in a row, at prices within
+	// MinJump of each other, after which the new price level is accepted as
+	// a real move
+	Confirmations int `json:"confirmations"`
+	// ZeroVolumeTicks is the number of ticks in a row without volume from
+	// which ticks are flagged
+	ZeroVolumeTicks int `json:"zero_volume_ticks"`
+	// MaxAge is the age from which a tick is stale, zero to disable
+	MaxAge time.Duration `json:"max_age"`
+	// QuarantineSize bounds the quarantined ticks kept for inspection
+	QuarantineSize int `json:"quarantine_size"`
+}
+
+// DefaultConfig returns default anomaly detection configuration
+func DefaultConfig() Config {
+	return Config{
+		Sigma:           6,
+		MinJump:         0.02,
+		Window:          50,
+		MinSamples:      10,
+		Confirmations:   3,
+		ZeroVolumeTicks: 5,
+		MaxAge:          2 * time.Minute,
+		QuarantineSize:  100,
+	}
+}
+
+// Anomaly is a quarantined tick and the reason it was flagged
+type Anomaly struct {
+	TokenAddress string  `json:"token_address"`
+	Kind         Kind    `json:"kind"`
+	Message      string  `json:"message"`
+	Value        float64 `json:"value"`
+	Threshold    float64 `json:"threshold"`
+	// Data is the quarantined tick
+	Data       *models.MarketData `json:"data"`
+	DetectedAt time.Time          `json:"detected_at"`
+}
+
+// Error implements error
+func (a *Anomaly) Error() string {
+	return fmt.Sprintf("%s: %s: %s", ErrAnomaly, a.Kind, a.Message)
+}
+
+// Unwrap returns ErrAnomaly
+func (a *Anomaly) Unwrap() error {
+	return ErrAnomaly
+}
+
+// tokenState is the recent accepted data of a token
+type tokenState struct {
+	lastPrice float64
+	lastTime  time.Time
+	returns   []float64
+	// spikes are the prices of the spikes in a row
+	spikes      []float64
+	zeroVolumes int
+}
+
+// Detector checks the ticks of every token against their own history
+type Detector struct {
+	config     Config
+	monitor    monitoring.IMonitor
+	tokens     map[string]*tokenState
+	quarantine []Anomaly
+	now        func() time.Time
+	mu         sync.Mutex
+}
+
+// NewDetector creates an anomaly detector. The monitor may be nil.
+func NewDetector(config Config, monitor monitoring.IMonitor) *Detector {
+	defaults := DefaultConfig()
+	if config.Sigma <= 0 {
+		config.Sigma = defaults.Sigma
+	}
+	if config.Window < 2 {
+		config.Window = defaults.Window
+	}
+	if config.MinSamples < 2 {
+		config.MinSamples = defaults.MinSamples
+	}
+	if config.QuarantineSize <= 0 {
+		config.QuarantineSize = defaults.QuarantineSize
+	}
+
+	return &Detector{
+		config:  config,
+		monitor: monitor,
+		tokens:  make(map[string]*tokenState),
+		now:     time.Now,
+	}
+}
+
+// Check checks a tick. Clean ticks update the history of their token and
+// return nil. Suspicious ticks are quarantined, reported to the monitor
+// and returned as an *Anomaly, without updating the price history.
+func (d *Detector) Check(ctx context.Context, data *models.MarketData) error {
+	d.mu.Lock()
+	anomaly := d.check(data)
+	if anomaly != nil {
+		d.quarantine = append(d.quarantine, *anomaly)
+		if excess := len(d.quarantine) - d.config.QuarantineSize; excess > 0 {
+			d.quarantine = append([]Anomaly(nil), d.quarantine[excess:]...)
+		}
+	}
+	d.mu.Unlock()
+
+	if anomaly == nil {
+		return nil
+	}
+	if d.monitor != nil {
+		d.monitor.RecordEvent(ctx, monitoring.Event{
+			Type:      monitoring.MetricMarketData,
+			Severity:  monitoring.SeverityWarning,
+			Message:   fmt.Sprintf("quarantined market data of %s: %s", anomaly.TokenAddress, anomaly.Message),
+			Details:   anomaly,
+			Timestamp: anomaly.DetectedAt,
+		})
+		d.monitor.RecordMetric(ctx, "market_data_anomalies", 1, map[string]string{
+			"token": anomaly.TokenAddress,
+			"kind":  string(anomaly.Kind),
+		})
+	}
+	return anomaly
+}
+
+// Quarantined returns the most recent quarantined ticks, oldest first
+func (d *Detector) Quarantined() []Anomaly {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Anomaly(nil), d.quarantine...)
+}
+
+// Reset forgets the history of a token, so that its next tick is accepted
+// as the new reference after a known repricing
+func (d *Detector) Reset(tokenAddress string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.tokens, tokenAddress)
+}
+
+// check returns the anomaly of a tick or records it. Callers hold d.mu.
+func (d *Detector) check(data *models.MarketData) *Anomaly {
+	now := d.now()
+	flag := func(kind Kind, value, threshold float64, format string, args ...interface{}) *Anomaly {
+		copied := *data
+		return &Anomaly{
+			TokenAddress: data.TokenAddress,
+			Kind:         kind,
+			Message:      fmt.Sprintf(format, args...),
+			Value:        value,
+			Threshold:    threshold,
+			Data:         &copied,
+			DetectedAt:   now,
+		}
+	}
+
+	price := data.ClosePrice
+	if price <= 0 || math.IsNaN(price) || math.IsInf(price, 0) {
+		return flag(KindInvalidPrice, price, 0, "invalid price %v", price)
+	}
+
+	state, ok := d.tokens[data.TokenAddress]
+	if d.config.MaxAge > 0 {
+		if age := now.Sub(data.Timestamp); age > d.config.MaxAge {
+			return flag(KindStaleTimestamp, age.Seconds(), d.config.MaxAge.Seconds(), "tick is %s old", age.Round(time.Second))
+		}
+	}
+	if ok && !data.Timestamp.After(state.lastTime) {
+		return flag(KindStaleTimestamp, data.Timestamp.Sub(state.lastTime).Seconds(), 0,
+			"tick at %s is not newer than the last tick at %s", data.Timestamp.Format(time.RFC3339), state.lastTime.Format(time.RFC3339))
+	}
+	if !ok {
+		state = &tokenState{}
+		d.tokens[data.TokenAddress] = state
+	}
+
+	volume := data.Volume
+	if volume == 0 {
+		volume = data.Volume24h
+	}
+	if volume <= 0 {
+		state.zeroVolumes++
+	} else {
+		state.zeroVolumes = 0
+	}
+	if d.config.ZeroVolumeTicks > 0 && state.zeroVolumes >= d.config.ZeroVolumeTicks {
+		return flag(KindZeroVolume, float64(state.zeroVolumes), float64(d.config.ZeroVolumeTicks), "no volume for %d ticks", state.zeroVolumes)
+	}
+
+	if state.lastPrice > 0 {
+		ret := math.Log(price / state.lastPrice)
+		threshold := math.Inf(1)
+		if len(state.returns) >= d.config.MinSamples {
+			threshold = math.Max(d.config.Sigma*stdDev(state.returns), math.Log(1+d.config.MinJump))
+		}
+		switch {
+		case math.Abs(ret) <= threshold:
+			state.returns = append(state.returns, ret)
+			if len(state.returns) > d.config.Window {
+				state.returns = state.returns[len(state.returns)-d.config.Window:]
+			}
+		case !d.confirmed(state, price):
+			return flag(KindPriceSpike, ret, threshold, "price jumped %.2f%% from %v to %v", 100*(math.Exp(ret)-1), state.lastPrice, price)
+		}
+	}
+	state.spikes = nil
+	state.lastPrice = price
+	state.lastTime = data.Timestamp
+	return nil
+}
+
+// confirmed records a spike and reports whether the spikes in a row
+// confirm a new price level. The history is restarted from a confirmed
+// level, as its volatility no longer describes the market. Callers hold
+// d.mu.
+func (d *Detector) confirmed(state *tokenState, price float64) bool {
+	if len(state.spikes) > 0 && math.Abs(price/state.spikes[0]-1) > d.config.MinJump {
+		state.spikes = nil
+	}
+	state.spikes = append(state.spikes, price)
+	if d.config.Confirmations <= 0 || len(state.spikes) < d.config.Confirmations {
+		return false
+	}
+	state.returns = nil
+	return true
+}
+
+// stdDev returns the sample standard deviation of values
+func stdDev(values []float64) float64 {
+	var mean float64
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	var sum float64
+	for _, v := range values {
+		sum += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(sum / float64(len(values)-1))
+}
@@ -0,0 +1,119 @@
+package anomaly
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/monitoring"
+)
+
+type recordingMonitor struct {
+	monitoring.IMonitor
+	events  []monitoring.Event
+	metrics []string
+}
+
+func (m *recordingMonitor) RecordEvent(ctx context.Context, event monitoring.Event) {
+	m.events = append(m.events, event)
+}
+
+func (m *recordingMonitor) RecordMetric(ctx context.Context, name string, value float64, tags map[string]string) {
+	m.metrics = append(m.metrics, name+":"+tags["kind"])
+}
+
+// feed sends ticks a second apart to the detector
+type feed struct {
+	detector *Detector
+	now      time.Time
+}
+
+func newFeed(config Config, monitor monitoring.IMonitor) *feed {
+	f := &feed{detector: NewDetector(config, monitor), now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	f.detector.now = func() time.Time { return f.now }
+	return f
+}
+
+func (f *feed) tick(price, volume float64) error {
+	f.now = f.now.Add(time.Second)
+	return f.detector.Check(context.Background(), &models.MarketData{
+		TokenAddress: "token-a",
+		ClosePrice:   price,
+		Volume24h:    volume,
+		Timestamp:    f.now,
+	})
+}
+
+// warmUp feeds prices oscillating 0.1% around 100
+func (f *feed) warmUp(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		require.NoError(t, f.tick(100*(1+0.001*math.Sin(float64(i))), 1000))
+	}
+}
+
+func TestDetectorPriceSpike(t *testing.T) {
+	monitor := &recordingMonitor{}
+	f := newFeed(DefaultConfig(), monitor)
+	f.warmUp(t)
+
+	// A single corrupted quote is quarantined and the feed carries on
+	err := f.tick(160, 1000)
+	require.ErrorIs(t, err, ErrAnomaly)
+	var anomaly *Anomaly
+	require.ErrorAs(t, err, &anomaly)
+	assert.Equal(t, KindPriceSpike, anomaly.Kind)
+	assert.Equal(t, 160.0, anomaly.Data.ClosePrice)
+	assert.NoError(t, f.tick(100, 1000))
+
+	require.Len(t, monitor.events, 1)
+	assert.Equal(t, monitoring.SeverityWarning, monitor.events[0].Severity)
+	assert.Equal(t, []string{"market_data_anomalies:price_spike"}, monitor.metrics)
+	require.Len(t, f.detector.Quarantined(), 1)
+
+	// A move confirmed by consecutive ticks at the new level is accepted
+	assert.Error(t, f.tick(120, 1000))
+	assert.Error(t, f.tick(120.5, 1000))
+	assert.NoError(t, f.tick(120.2, 1000))
+	assert.NoError(t, f.tick(120.3, 1000))
+}
+
+func TestDetectorZeroVolume(t *testing.T) {
+	config := DefaultConfig()
+	config.ZeroVolumeTicks = 3
+	f := newFeed(config, nil)
+
+	assert.NoError(t, f.tick(100, 0))
+	assert.NoError(t, f.tick(100, 0))
+	err := f.tick(100, 0)
+	var anomaly *Anomaly
+	require.ErrorAs(t, err, &anomaly)
+	assert.Equal(t, KindZeroVolume, anomaly.Kind)
+
+	assert.NoError(t, f.tick(100, 500), "volume resets the count")
+}
+
+func TestDetectorStaleAndInvalid(t *testing.T) {
+	f := newFeed(DefaultConfig(), nil)
+	require.NoError(t, f.tick(100, 1000))
+	ctx := context.Background()
+
+	var anomaly *Anomaly
+	err := f.detector.Check(ctx, &models.MarketData{TokenAddress: "token-a", ClosePrice: 100, Volume24h: 1000, Timestamp: f.now})
+	require.ErrorAs(t, err, &anomaly)
+	assert.Equal(t, KindStaleTimestamp, anomaly.Kind, "repeated timestamp")
+
+	err = f.detector.Check(ctx, &models.MarketData{TokenAddress: "token-b", ClosePrice: 100, Volume24h: 1000, Timestamp: f.now.Add(-time.Hour)})
+	require.ErrorAs(t, err, &anomaly)
+	assert.Equal(t, KindStaleTimestamp, anomaly.Kind, "old tick")
+
+	err = f.tick(math.NaN(), 1000)
+	require.ErrorAs(t, err, &anomaly)
+	assert.Equal(t, KindInvalidPrice, anomaly.Kind)
+
+	assert.Len(t, f.detector.Quarantined(), 3)
+}
@@ -0,0 +1,261 @@
+// Package backfill imports historical candles of tokens from external price
+// history APIs into the kline store, so backtests can use data older than
+// the bot's own market data. Backfills are resumable: the progress of each
+// token is checkpointed after every saved batch.
+package backfill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/repository"
+)
+
+// Source fetches historical candles. BirdeyeSource and CoinGeckoSource
+// implement it.
+type Source interface {
+	Name() string
+	// FetchKlines returns the candles of a token opening within [from, to)
+	FetchKlines(ctx context.Context, token, interval string, from, to time.Time) ([]models.Kline, error)
+}
+
+// Store saves candles, replacing stored candles with the same symbol,
+// interval and open time. repository.Repository implements it.
+type Store interface {
+	SaveKlinesBatch(ctx context.Context, klines []*models.Kline) error
+}
+
+// Checkpoints records how far the backfill of each token has progressed.
+// FileCheckpoints implements it.
+type Checkpoints interface {
+	// Load returns the time the backfill of a token reached, zero when it
+	// has not started
+	Load(token, interval string) (time.Time, error)
+	Save(token, interval string, through time.Time) error
+}
+
+// Config contains backfill configuration
+type Config struct {
+	// Interval is the candle interval, such as 1h or 1d
+	Interval string `json:"interval"`
+	// From and To bound the backfilled period. To defaults to now.
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	// BatchCandles is the number of candles requested at once
+	BatchCandles int `json:"batch_candles"`
+	// RequestInterval is the pause between requests, to stay within the
+	// rate limits of the sources
+	RequestInterval time.Duration `json:"request_interval"`
+}
+
+// DefaultConfig returns default backfill configuration
+func DefaultConfig() Config {
+	return Config{
+		Interval:        "1h",
+		BatchCandles:    500,
+		RequestInterval: time.Second,
+	}
+}
+
+// Progress is reported after every batch of a token
+type Progress struct {
+	Token    string `json:"token"`
+	Interval string `json:"interval"`
+	// Source is the source of the batch, empty when no source had candles
+	Source string `json:"source,omitempty"`
+	// Through is the time the backfill of the token reached
+	Through time.Time `json:"through"`
+	// Saved is the number of candles saved for the token by this run
+	Saved int `json:"saved"`
+	// Percent is the share of the period backfilled, from 0 to 100
+	Percent float64 `json:"percent"`
+	Done    bool    `json:"done"`
+}
+
+// Backfiller imports the candles of tokens from the first source that has
+// them, batch by batch
+type Backfiller struct {
+	sources     []Source
+	store       Store
+	checkpoints Checkpoints
+	config      Config
+	progress    func(Progress)
+	sleep       func(ctx context.Context, d time.Duration) error
+	now         func() time.Time
+}
+
+// New creates a backfiller reading from the sources in order of preference
+func New(store Store, checkpoints Checkpoints, config Config, sources ...Source) *Backfiller {
+	defaults := DefaultConfig()
+	if config.Interval == "" {
+		config.Interval = defaults.Interval
+	}
+	if config.BatchCandles <= 0 {
+		config.BatchCandles = defaults.BatchCandles
+	}
+	return &Backfiller{
+		sources:     sources,
+		store:       store,
+		checkpoints: checkpoints,
+		config:      config,
+		sleep:       sleep,
+		now:         time.Now,
+	}
+}
+
+// OnProgress reports the progress of every batch to fn
+func (b *Backfiller) OnProgress(fn func(Progress)) {
+	b.progress = fn
+}
+
+// Run backfills the tokens one after another. A failing token does not stop
+// the others; its backfill resumes from its checkpoint on the next run.
+func (b *Backfiller) Run(ctx context.Context, tokens []string) error {
+	step, ok := repository.IntervalDuration(b.config.Interval)
+	if !ok {
+		return fmt.Errorf("invalid interval %q", b.config.Interval)
+	}
+	if len(b.sources) == 0 {
+		return fmt.Errorf("no backfill source configured")
+	}
+	to := b.config.To
+	if to.IsZero() {
+		to = b.now()
+	}
+	if !b.config.From.Before(to) {
+		return fmt.Errorf("backfill period from %s to %s is empty", b.config.From.Format(time.RFC3339), to.Format(time.RFC3339))
+	}
+
+	var errs []error
+	for _, token := range tokens {
+		if err := b.backfill(ctx, token, step, to); err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			errs = append(errs, fmt.Errorf("token %s: %w", token, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// backfill imports the candles of a token from its checkpoint on
+func (b *Backfiller) backfill(ctx context.Context, token string, step time.Duration, to time.Time) error {
+	from := b.config.From.Truncate(step)
+	start := from
+	checkpoint, err := b.checkpoints.Load(token, b.config.Interval)
+	if err != nil {
+		return fmt.Errorf("failed to load checkpoint: %w", err)
+	}
+	if checkpoint.After(start) {
+		start = checkpoint
+	}
+
+	progress := Progress{Token: token, Interval: b.config.Interval, Through: start}
+	batch := time.Duration(b.config.BatchCandles) * step
+	for start.Before(to) {
+		end := start.Add(batch)
+		if end.After(to) {
+			end = to
+		}
+
+		klines, source, err := b.fetch(ctx, token, start, end, step)
+		if err != nil {
+			return err
+		}
+		if err := b.store.SaveKlinesBatch(ctx, klines); err != nil {
+			return fmt.Errorf("failed to save candles: %w", err)
+		}
+		if err := b.checkpoints.Save(token, b.config.Interval, end); err != nil {
+			return fmt.Errorf("failed to save checkpoint: %w", err)
+		}
+
+		progress.Source = source
+		progress.Through = end
+		progress.Saved += len(klines)
+		progress.Percent = 100 * float64(end.Sub(from)) / float64(to.Sub(from))
+		progress.Done = !end.Before(to)
+		b.report(progress)
+
+		start = end
+		if !progress.Done && b.config.RequestInterval > 0 {
+			if err := b.sleep(ctx, b.config.RequestInterval); err != nil {
+				return err
+			}
+		}
+	}
+	if !progress.Done {
+		// The checkpoint was already at the end of the period
+		progress.Percent = 100
+		progress.Done = true
+		b.report(progress)
+	}
+	return nil
+}
+
+// fetch returns the deduplicated candles of the first source with candles
+// in [from, to) and its name. Sources that fail are skipped; the batch
+// fails when every source fails.
+func (b *Backfiller) fetch(ctx context.Context, token string, from, to time.Time, step time.Duration) ([]*models.Kline, string, error) {
+	var errs []error
+	for _, source := range b.sources {
+		klines, err := source.FetchKlines(ctx, token, b.config.Interval, from, to)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, "", ctx.Err()
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", source.Name(), err))
+			continue
+		}
+		if deduped := dedup(klines, token, b.config.Interval, from, to, step); len(deduped) > 0 {
+			return deduped, source.Name(), nil
+		}
+	}
+	if len(errs) == len(b.sources) {
+		return nil, "", errors.Join(errs...)
+	}
+	return nil, "", nil
+}
+
+// dedup aligns candles to the interval, drops the ones opening outside
+// [from, to) and keeps the last candle of each open time, oldest first
+func dedup(klines []models.Kline, token, interval string, from, to time.Time, step time.Duration) []*models.Kline {
+	byTime := make(map[time.Time]*models.Kline, len(klines))
+	for i := range klines {
+		k := klines[i]
+		k.Symbol = token
+		k.Interval = interval
+		k.Timestamp = k.Timestamp.UTC().Truncate(step)
+		if k.Timestamp.Before(from) || !k.Timestamp.Before(to) {
+			continue
+		}
+		byTime[k.Timestamp] = &k
+	}
+
+	deduped := make([]*models.Kline, 0, len(byTime))
+	for _, k := range byTime {
+		deduped = append(deduped, k)
+	}
+	sort.Slice(deduped, func(i, j int) bool { return deduped[i].Timestamp.Before(deduped[j].Timestamp) })
+	return deduped
+}
+
+func (b *Backfiller) report(progress Progress) {
+	if b.progress != nil {
+		b.progress(progress)
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
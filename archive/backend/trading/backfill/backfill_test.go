@@ -0,0 +1,189 @@
+package backfill
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/leonzhao/trading-system/backend/models"
+)
+
+// fakeSource returns hourly candles for every hour requested
+type fakeSource struct {
+	name  string
+	err   error
+	empty bool
+	calls int
+}
+
+func (s *fakeSource) Name() string { return s.name }
+
+func (s *fakeSource) FetchKlines(ctx context.Context, token, interval string, from, to time.Time) ([]models.Kline, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	if s.empty {
+		return nil, nil
+	}
+	var klines []models.Kline
+	for at := from; at.Before(to); at = at.Add(time.Hour) {
+		// Off by a few seconds, and twice, to exercise alignment and dedup
+		k := models.Kline{Open: 1, High: 2, Low: 0.5, Close: 1.5, Timestamp: at.Add(3 * time.Second)}
+		klines = append(klines, k, k)
+	}
+	return klines, nil
+}
+
+type memoryStore struct {
+	klines map[time.Time]*models.Kline
+	saves  int
+	fail   bool
+}
+
+func (s *memoryStore) SaveKlinesBatch(ctx context.Context, klines []*models.Kline) error {
+	if s.fail {
+		return errors.New("store down")
+	}
+	s.saves++
+	for _, k := range klines {
+		s.klines[k.Timestamp] = k
+	}
+	return nil
+}
+
+func newTestBackfiller(t *testing.T, store Store, sources ...Source) (*Backfiller, *FileCheckpoints) {
+	checkpoints, err := NewFileCheckpoints(filepath.Join(t.TempDir(), "state.json"))
+	require.NoError(t, err)
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := New(store, checkpoints, Config{
+		Interval:     "1h",
+		From:         from,
+		To:           from.Add(10 * time.Hour),
+		BatchCandles: 4,
+	}, sources...)
+	return b, checkpoints
+}
+
+func TestBackfill(t *testing.T) {
+	store := &memoryStore{klines: make(map[time.Time]*models.Kline)}
+	failing := &fakeSource{name: "down", err: errors.New("unavailable")}
+	source := &fakeSource{name: "up"}
+	b, checkpoints := newTestBackfiller(t, store, failing, source)
+
+	var progress []Progress
+	b.OnProgress(func(p Progress) { progress = append(progress, p) })
+	require.NoError(t, b.Run(context.Background(), []string{"SOL"}))
+
+	// Deduplicated and aligned on the hour
+	assert.Len(t, store.klines, 10)
+	first := store.klines[time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)]
+	require.NotNil(t, first)
+	assert.Equal(t, "SOL", first.Symbol)
+	assert.Equal(t, "1h", first.Interval)
+
+	// Batches of 4, 4 and 2 candles, falling back from the failing source
+	require.Len(t, progress, 3)
+	assert.Equal(t, "up", progress[0].Source)
+	assert.InDelta(t, 40, progress[0].Percent, 1e-9)
+	assert.Equal(t, 10, progress[2].Saved)
+	assert.True(t, progress[2].Done)
+	assert.Equal(t, 3, failing.calls)
+
+	through, err := checkpoints.Load("SOL", "1h")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), through)
+
+	// A second run resumes from the checkpoint and fetches nothing
+	source.calls = 0
+	require.NoError(t, b.Run(context.Background(), []string{"SOL"}))
+	assert.Zero(t, source.calls)
+}
+
+func TestBackfillResume(t *testing.T) {
+	store := &memoryStore{klines: make(map[time.Time]*models.Kline)}
+	source := &fakeSource{name: "up"}
+	b, checkpoints := newTestBackfiller(t, store, source)
+	require.NoError(t, checkpoints.Save("SOL", "1h", time.Date(2024, 1, 1, 6, 0, 0, 0, time.UTC)))
+
+	// The checkpoint file is reloaded by a new run
+	reloaded, err := NewFileCheckpoints(checkpoints.path)
+	require.NoError(t, err)
+	b.checkpoints = reloaded
+
+	require.NoError(t, b.Run(context.Background(), []string{"SOL"}))
+	assert.Len(t, store.klines, 4)
+	assert.Equal(t, 1, source.calls)
+}
+
+func TestBackfillErrors(t *testing.T) {
+	store := &memoryStore{klines: make(map[time.Time]*models.Kline)}
+	b, checkpoints := newTestBackfiller(t, store, &fakeSource{name: "down", err: errors.New("unavailable")})
+	err := b.Run(context.Background(), []string{"SOL", "BONK"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "token SOL")
+	assert.Contains(t, err.Error(), "token BONK")
+	through, err := checkpoints.Load("SOL", "1h")
+	require.NoError(t, err)
+	assert.True(t, through.IsZero())
+
+	// Empty history is not an error, the period is skipped
+	empty, _ := newTestBackfiller(t, store, &fakeSource{name: "empty", empty: true})
+	require.NoError(t, empty.Run(context.Background(), []string{"SOL"}))
+	assert.Empty(t, store.klines)
+
+	store.fail = true
+	failing, checkpoints := newTestBackfiller(t, store, &fakeSource{name: "up"})
+	require.Error(t, failing.Run(context.Background(), []string{"SOL"}))
+	through, err = checkpoints.Load("SOL", "1h")
+	require.NoError(t, err)
+	assert.True(t, through.IsZero())
+
+	invalid := New(store, checkpoints, Config{Interval: "1y", From: time.Now().Add(-time.Hour)}, &fakeSource{name: "up"})
+	assert.Error(t, invalid.Run(context.Background(), []string{"SOL"}))
+}
+
+func TestBirdeyeSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/defi/ohlcv", r.URL.Path)
+		assert.Equal(t, "key", r.Header.Get("X-API-KEY"))
+		assert.Equal(t, "1H", r.URL.Query().Get("type"))
+		assert.Equal(t, "mint", r.URL.Query().Get("address"))
+		w.Write([]byte(`{"success":true,"data":{"items":[{"o":1,"h":2,"l":0.5,"c":1.5,"v":100,"unixTime":1704067200}]}}`))
+	}))
+	defer server.Close()
+
+	source := NewBirdeyeSource(server.URL, "key")
+	from := time.Unix(1704067200, 0)
+	klines, err := source.FetchKlines(context.Background(), "mint", "1h", from, from.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, klines, 1)
+	assert.Equal(t, 100.0, klines[0].Volume)
+	assert.True(t, klines[0].Timestamp.Equal(from))
+
+	_, err = source.FetchKlines(context.Background(), "mint", "7m", from, from.Add(time.Hour))
+	assert.Error(t, err)
+}
+
+func TestCoinGeckoSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/coins/solana/contract/mint/market_chart/range", r.URL.Path)
+		w.Write([]byte(`{"prices":[[1704067200000,1],[1704068100000,3],[1704069000000,0.5],[1704070800000,2]]}`))
+	}))
+	defer server.Close()
+
+	source := NewCoinGeckoSource(server.URL, "", "")
+	from := time.Unix(1704067200, 0)
+	klines, err := source.FetchKlines(context.Background(), "mint", "1h", from, from.Add(2*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, klines, 2)
+	assert.Equal(t, models.Kline{Symbol: "mint", Open: 1, High: 3, Low: 0.5, Close: 0.5, Timestamp: from.UTC(), Interval: "1h"}, klines[0])
+	assert.Equal(t, 2.0, klines[1].Open)
+}
@@ -0,0 +1,76 @@
+package backfill
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileCheckpoints keeps the checkpoints of backfills in a JSON file, so an
+// interrupted backfill resumes where it stopped
+type FileCheckpoints struct {
+	path  string
+	state map[string]time.Time
+	mu    sync.Mutex
+}
+
+// NewFileCheckpoints loads the checkpoints saved at path, if any
+func NewFileCheckpoints(path string) (*FileCheckpoints, error) {
+	c := &FileCheckpoints{path: path, state: make(map[string]time.Time)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoints: %w", err)
+	}
+	if err := json.Unmarshal(data, &c.state); err != nil {
+		return nil, fmt.Errorf("failed to decode checkpoints: %w", err)
+	}
+	return c, nil
+}
+
+// Load returns the time the backfill of a token reached
+func (c *FileCheckpoints) Load(token, interval string) (time.Time, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state[checkpointKey(token, interval)], nil
+}
+
+// Save records the time the backfill of a token reached and rewrites the
+// file
+func (c *FileCheckpoints) Save(token, interval string, through time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.state[checkpointKey(token, interval)] = through.UTC()
+
+	data, err := json.MarshalIndent(c.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoints: %w", err)
+	}
+	// Write then rename, so an interruption never leaves a truncated file
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write checkpoints: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write checkpoints: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write checkpoints: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return fmt.Errorf("failed to write checkpoints: %w", err)
+	}
+	return nil
+}
+
+func checkpointKey(token, interval string) string {
+	return token + "/" + interval
+}
@@ -0,0 +1,228 @@
+package backfill
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/repository"
+)
+
+// BirdeyeSource reads the OHLCV history of Solana tokens from Birdeye
+type BirdeyeSource struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewBirdeyeSource creates a Birdeye source. baseURL defaults to
+// https://public-api.birdeye.so.
+func NewBirdeyeSource(baseURL, apiKey string) *BirdeyeSource {
+	if baseURL == "" {
+		baseURL = "https://public-api.birdeye.so"
+	}
+	return &BirdeyeSource{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Name returns the name of the source
+func (s *BirdeyeSource) Name() string {
+	return "birdeye"
+}
+
+// birdeyeTypes maps kline intervals to Birdeye candle types
+var birdeyeTypes = map[string]string{
+	"1m": "1m", "3m": "3m", "5m": "5m", "15m": "15m", "30m": "30m",
+	"1h": "1H", "2h": "2H", "4h": "4H", "6h": "6H", "8h": "8H", "12h": "12H",
+	"1d": "1D", "3d": "3D", "1w": "1W",
+}
+
+// FetchKlines returns the candles of a token address opening within [from, to)
+func (s *BirdeyeSource) FetchKlines(ctx context.Context, token, interval string, from, to time.Time) ([]models.Kline, error) {
+	candleType, ok := birdeyeTypes[interval]
+	if !ok {
+		return nil, fmt.Errorf("interval %s not supported", interval)
+	}
+
+	params := url.Values{}
+	params.Set("address", token)
+	params.Set("type", candleType)
+	params.Set("time_from", strconv.FormatInt(from.Unix(), 10))
+	params.Set("time_to", strconv.FormatInt(to.Unix()-1, 10))
+	headers := map[string]string{"x-chain": "solana", "Accept": "application/json"}
+	if s.apiKey != "" {
+		headers["X-API-KEY"] = s.apiKey
+	}
+	body, err := get(ctx, s.httpClient, s.baseURL+"/defi/ohlcv?"+params.Encode(), headers)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+		Data    struct {
+			Items []struct {
+				Open     float64 `json:"o"`
+				High     float64 `json:"h"`
+				Low      float64 `json:"l"`
+				Close    float64 `json:"c"`
+				Volume   float64 `json:"v"`
+				UnixTime int64   `json:"unixTime"`
+			} `json:"items"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode candles: %w", err)
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf("request failed: %s", resp.Message)
+	}
+
+	klines := make([]models.Kline, 0, len(resp.Data.Items))
+	for _, item := range resp.Data.Items {
+		klines = append(klines, models.Kline{
+			Symbol:    token,
+			Open:      item.Open,
+			High:      item.High,
+			Low:       item.Low,
+			Close:     item.Close,
+			Volume:    item.Volume,
+			Timestamp: time.Unix(item.UnixTime, 0).UTC(),
+			Interval:  interval,
+		})
+	}
+	return klines, nil
+}
+
+// CoinGeckoSource builds candles from the CoinGecko price history of tokens
+// by contract address. CoinGecko only reports prices, hourly for periods of
+// up to 90 days and daily beyond, so its candles have no volume and are
+// sparse for intervals below that granularity.
+type CoinGeckoSource struct {
+	baseURL    string
+	apiKey     string
+	platform   string
+	httpClient *http.Client
+}
+
+// NewCoinGeckoSource creates a CoinGecko source for the tokens of a
+// platform, such as solana. baseURL defaults to
+// https://api.coingecko.com/api/v3; the demo API key is sent when set.
+func NewCoinGeckoSource(baseURL, apiKey, platform string) *CoinGeckoSource {
+	if baseURL == "" {
+		baseURL = "https://api.coingecko.com/api/v3"
+	}
+	if platform == "" {
+		platform = "solana"
+	}
+	return &CoinGeckoSource{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		apiKey:     apiKey,
+		platform:   platform,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Name returns the name of the source
+func (s *CoinGeckoSource) Name() string {
+	return "coingecko"
+}
+
+// FetchKlines returns the candles of a token address opening within
+// [from, to), aggregated from the prices within each interval
+func (s *CoinGeckoSource) FetchKlines(ctx context.Context, token, interval string, from, to time.Time) ([]models.Kline, error) {
+	step, ok := repository.IntervalDuration(interval)
+	if !ok {
+		return nil, fmt.Errorf("interval %s not supported", interval)
+	}
+
+	params := url.Values{}
+	params.Set("vs_currency", "usd")
+	params.Set("from", strconv.FormatInt(from.Unix(), 10))
+	params.Set("to", strconv.FormatInt(to.Unix(), 10))
+	endpoint := fmt.Sprintf("%s/coins/%s/contract/%s/market_chart/range?%s",
+		s.baseURL, url.PathEscape(s.platform), url.PathEscape(token), params.Encode())
+	headers := map[string]string{"Accept": "application/json"}
+	if s.apiKey != "" {
+		headers["x-cg-demo-api-key"] = s.apiKey
+	}
+	body, err := get(ctx, s.httpClient, endpoint, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		// Prices are [unix milliseconds, price] pairs, oldest first
+		Prices [][2]float64 `json:"prices"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode prices: %w", err)
+	}
+
+	var klines []models.Kline
+	for _, point := range resp.Prices {
+		at := time.UnixMilli(int64(point[0])).UTC()
+		price := point[1]
+		if at.Before(from) || !at.Before(to) || price <= 0 {
+			continue
+		}
+		open := at.Truncate(step)
+		if n := len(klines); n > 0 && klines[n-1].Timestamp.Equal(open) {
+			k := &klines[n-1]
+			if price > k.High {
+				k.High = price
+			}
+			if price < k.Low {
+				k.Low = price
+			}
+			k.Close = price
+			continue
+		}
+		klines = append(klines, models.Kline{
+			Symbol:    token,
+			Open:      price,
+			High:      price,
+			Low:       price,
+			Close:     price,
+			Timestamp: open,
+			Interval:  interval,
+		})
+	}
+	return klines, nil
+}
+
+func get(ctx context.Context, client *http.Client, endpoint string, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch history: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
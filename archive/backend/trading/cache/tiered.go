@@ -0,0 +1,375 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrCacheMiss is returned by a remote cache that does not hold the key
+	ErrCacheMiss = errors.New("cache miss")
+	// ErrStaleMarketData is returned when every tier only holds data older
+	// than the maximum age of the read
+	ErrStaleMarketData = errors.New("market data is stale")
+)
+
+// MarketData is a market data point of a token, with the fields of
+// models.MarketData. The cache keeps its own type so it does not depend on
+// the models package; callers convert at the boundary.
+type MarketData struct {
+	Symbol       string
+	TokenAddress string
+	// Interval is the period the data covers, such as 1m or 1h. Empty for
+	// point-in-time snapshots.
+	Interval    string
+	OpenPrice   float64
+	ClosePrice  float64
+	HighPrice   float64
+	LowPrice    float64
+	Volume      float64
+	Volume24h   float64
+	MarketCap   float64
+	Liquidity   float64
+	PriceImpact float64
+	OrderBook   struct {
+		Bids [][]float64
+		Asks [][]float64
+	}
+	Timestamp time.Time
+}
+
+// Tier identifies a layer of the market data read path
+type Tier string
+
+// Market data tiers, fastest first
+const (
+	TierLocal Tier = "local"
+	TierRedis Tier = "redis"
+	TierStore Tier = "store"
+)
+
+// RemoteMarketDataCache is a shared cache such as Redis
+type RemoteMarketDataCache interface {
+	GetMarketData(ctx context.Context, tokenAddress string) (*MarketData, error)
+	SetMarketData(ctx context.Context, data *MarketData, ttl time.Duration) error
+}
+
+// MarketDataStore is the persistent market data store, typically the repository
+type MarketDataStore interface {
+	GetLatestMarketData(ctx context.Context, tokenAddress string) (*MarketData, error)
+}
+
+// TieredConfig contains tiered cache configuration
+type TieredConfig struct {
+	// RingSize is the number of recent data points kept per token in process
+	RingSize int
+	// LocalTTL is how long a data point is served from the in-process ring
+	LocalTTL time.Duration
+	// RemoteTTL is the expiration of data points written to the remote cache
+	RemoteTTL time.Duration
+}
+
+// DefaultTieredConfig returns default tiered cache configuration
+func DefaultTieredConfig() TieredConfig {
+	return TieredConfig{
+		RingSize:  64,
+		LocalTTL:  2 * time.Second,
+		RemoteTTL: 30 * time.Second,
+	}
+}
+
+// TierStats contains read statistics for one tier
+type TierStats struct {
+	Hits int64
+	// Misses counts reads the tier could not answer, including stale hits
+	Misses int64
+	// Stale counts hits bypassed because the data exceeded the read's maximum age
+	Stale  int64
+	Errors int64
+}
+
+// HitRatio returns the fraction of reads reaching the tier that it answered
+func (s TierStats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// TieredMarketDataCache reads market data through an in-process ring buffer,
+// a remote cache and the persistent store, in that order. Data found in a
+// slower tier is promoted to the faster ones.
+type TieredMarketDataCache struct {
+	remote RemoteMarketDataCache
+	store  MarketDataStore
+	config TieredConfig
+	rings  map[string]*marketDataRing
+	stats  map[Tier]*TierStats
+	mu     sync.Mutex
+}
+
+// NewTieredMarketDataCache creates a tiered market data cache. remote may be
+// nil, in which case reads go straight from the ring to the store.
+func NewTieredMarketDataCache(remote RemoteMarketDataCache, store MarketDataStore, config TieredConfig) *TieredMarketDataCache {
+	defaults := DefaultTieredConfig()
+	if config.RingSize <= 0 {
+		config.RingSize = defaults.RingSize
+	}
+	if config.LocalTTL <= 0 {
+		config.LocalTTL = defaults.LocalTTL
+	}
+	if config.RemoteTTL <= 0 {
+		config.RemoteTTL = defaults.RemoteTTL
+	}
+
+	return &TieredMarketDataCache{
+		remote: remote,
+		store:  store,
+		config: config,
+		rings:  make(map[string]*marketDataRing),
+		stats: map[Tier]*TierStats{
+			TierLocal: {},
+			TierRedis: {},
+			TierStore: {},
+		},
+	}
+}
+
+// GetLatestMarketData returns the latest market data for a token. A maxAge
+// greater than zero marks a valuation-critical read: data points with a
+// timestamp older than maxAge are bypassed in every tier, and
+// ErrStaleMarketData is returned if no tier has fresh enough data.
+func (c *TieredMarketDataCache) GetLatestMarketData(ctx context.Context, tokenAddress string, maxAge time.Duration) (*MarketData, error) {
+	now := time.Now()
+
+	if data, ok := c.getLocal(tokenAddress, now); ok {
+		if c.fresh(TierLocal, data, maxAge, now) {
+			return data, nil
+		}
+	}
+
+	if c.remote != nil {
+		data, err := c.remote.GetMarketData(ctx, tokenAddress)
+		switch {
+		case err == nil && data != nil:
+			if c.fresh(TierRedis, data, maxAge, now) {
+				c.putLocal(data, now)
+				return data, nil
+			}
+		case errors.Is(err, ErrCacheMiss) || (err == nil && data == nil):
+			c.record(TierRedis, func(s *TierStats) { s.Misses++ })
+		default:
+			// A remote cache failure degrades to the store rather than failing the read
+			c.record(TierRedis, func(s *TierStats) { s.Misses++; s.Errors++ })
+		}
+	}
+
+	data, err := c.store.GetLatestMarketData(ctx, tokenAddress)
+	if err != nil {
+		c.record(TierStore, func(s *TierStats) { s.Misses++; s.Errors++ })
+		return nil, fmt.Errorf("failed to get market data for %s: %w", tokenAddress, err)
+	}
+	if data == nil {
+		c.record(TierStore, func(s *TierStats) { s.Misses++ })
+		return nil, fmt.Errorf("no market data for %s", tokenAddress)
+	}
+	if !c.fresh(TierStore, data, maxAge, now) {
+		return nil, fmt.Errorf("%w: %s last updated %s", ErrStaleMarketData, tokenAddress, data.Timestamp.Format(time.RFC3339))
+	}
+
+	c.promote(ctx, data, now)
+	return data, nil
+}
+
+// GetRecentMarketData returns up to limit recent data points for a token
+// from the in-process ring, oldest first
+func (c *TieredMarketDataCache) GetRecentMarketData(tokenAddress string, limit int) []MarketData {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ring, ok := c.rings[tokenAddress]
+	if !ok {
+		return nil
+	}
+	return ring.recent(limit)
+}
+
+// Put writes a new data point through the ring and the remote cache
+func (c *TieredMarketDataCache) Put(ctx context.Context, data *MarketData) error {
+	c.putLocal(data, time.Now())
+	if c.remote == nil {
+		return nil
+	}
+	if err := c.remote.SetMarketData(ctx, data, c.config.RemoteTTL); err != nil {
+		c.record(TierRedis, func(s *TierStats) { s.Errors++ })
+		return fmt.Errorf("failed to write market data to remote cache: %w", err)
+	}
+	return nil
+}
+
+// Stats returns read statistics for each tier
+func (c *TieredMarketDataCache) Stats() map[Tier]TierStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := make(map[Tier]TierStats, len(c.stats))
+	for tier, s := range c.stats {
+		stats[tier] = *s
+	}
+	return stats
+}
+
+// fresh records a hit, or a stale miss if the data is older than maxAge
+func (c *TieredMarketDataCache) fresh(tier Tier, data *MarketData, maxAge time.Duration, now time.Time) bool {
+	if maxAge > 0 && now.Sub(data.Timestamp) > maxAge {
+		c.record(tier, func(s *TierStats) { s.Misses++; s.Stale++ })
+		return false
+	}
+	c.record(tier, func(s *TierStats) { s.Hits++ })
+	return true
+}
+
+// promote copies data read from the store into the faster tiers
+func (c *TieredMarketDataCache) promote(ctx context.Context, data *MarketData, now time.Time) {
+	c.putLocal(data, now)
+	if c.remote != nil {
+		if err := c.remote.SetMarketData(ctx, data, c.config.RemoteTTL); err != nil {
+			c.record(TierRedis, func(s *TierStats) { s.Errors++ })
+		}
+	}
+}
+
+func (c *TieredMarketDataCache) getLocal(tokenAddress string, now time.Time) (*MarketData, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ring, ok := c.rings[tokenAddress]
+	if ok {
+		if entry, ok := ring.latest(); ok && now.Sub(entry.storedAt) <= c.config.LocalTTL {
+			data := entry.data
+			return &data, true
+		}
+	}
+	c.stats[TierLocal].Misses++
+	return nil, false
+}
+
+func (c *TieredMarketDataCache) putLocal(data *MarketData, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ring, ok := c.rings[data.TokenAddress]
+	if !ok {
+		ring = newMarketDataRing(c.config.RingSize)
+		c.rings[data.TokenAddress] = ring
+	}
+	// A re-read of the latest point only refreshes it
+	if latest, ok := ring.latest(); ok && latest.data.Timestamp.Equal(data.Timestamp) {
+		ring.replaceLatest(ringEntry{data: *data, storedAt: now})
+		return
+	}
+	ring.push(ringEntry{data: *data, storedAt: now})
+}
+
+func (c *TieredMarketDataCache) record(tier Tier, update func(*TierStats)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	update(c.stats[tier])
+}
+
+type ringEntry struct {
+	data     MarketData
+	storedAt time.Time
+}
+
+// marketDataRing is a fixed-capacity ring buffer of recent data points
+type marketDataRing struct {
+	entries []ringEntry
+	next    int
+	count   int
+}
+
+func newMarketDataRing(size int) *marketDataRing {
+	return &marketDataRing{entries: make([]ringEntry, size)}
+}
+
+func (r *marketDataRing) push(entry ringEntry) {
+	r.entries[r.next] = entry
+	r.next = (r.next + 1) % len(r.entries)
+	if r.count < len(r.entries) {
+		r.count++
+	}
+}
+
+func (r *marketDataRing) latest() (ringEntry, bool) {
+	if r.count == 0 {
+		return ringEntry{}, false
+	}
+	return r.entries[(r.next-1+len(r.entries))%len(r.entries)], true
+}
+
+func (r *marketDataRing) replaceLatest(entry ringEntry) {
+	r.entries[(r.next-1+len(r.entries))%len(r.entries)] = entry
+}
+
+func (r *marketDataRing) recent(limit int) []MarketData {
+	if limit <= 0 || limit > r.count {
+		limit = r.count
+	}
+	recent := make([]MarketData, limit)
+	for i := 0; i < limit; i++ {
+		recent[i] = r.entries[(r.next-limit+i+len(r.entries))%len(r.entries)].data
+	}
+	return recent
+}
+
+// RedisMarketDataCache stores the latest market data per token in Redis as JSON
+type RedisMarketDataCache struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisMarketDataCache creates a Redis market data cache. Keys are the
+// token address prefixed with prefix.
+func NewRedisMarketDataCache(client redis.UniversalClient, prefix string) *RedisMarketDataCache {
+	if prefix == "" {
+		prefix = "market_data:latest:"
+	}
+	return &RedisMarketDataCache{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// GetMarketData returns the cached market data or ErrCacheMiss
+func (r *RedisMarketDataCache) GetMarketData(ctx context.Context, tokenAddress string) (*MarketData, error) {
+	payload, err := r.client.Get(ctx, r.prefix+tokenAddress).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrCacheMiss
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var data MarketData
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return nil, fmt.Errorf("failed to decode cached market data: %w", err)
+	}
+	return &data, nil
+}
+
+// SetMarketData caches market data with the given expiration
+func (r *RedisMarketDataCache) SetMarketData(ctx context.Context, data *MarketData, ttl time.Duration) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode market data: %w", err)
+	}
+	return r.client.Set(ctx, r.prefix+data.TokenAddress, payload, ttl).Err()
+}
@@ -0,0 +1,146 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryRemote struct {
+	data  map[string]*MarketData
+	err   error
+	reads int
+}
+
+func (r *memoryRemote) GetMarketData(ctx context.Context, tokenAddress string) (*MarketData, error) {
+	r.reads++
+	if r.err != nil {
+		return nil, r.err
+	}
+	data, ok := r.data[tokenAddress]
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	copied := *data
+	return &copied, nil
+}
+
+func (r *memoryRemote) SetMarketData(ctx context.Context, data *MarketData, ttl time.Duration) error {
+	copied := *data
+	r.data[data.TokenAddress] = &copied
+	return nil
+}
+
+type memoryStore struct {
+	data  map[string]*MarketData
+	reads int
+}
+
+func (s *memoryStore) GetLatestMarketData(ctx context.Context, tokenAddress string) (*MarketData, error) {
+	s.reads++
+	data, ok := s.data[tokenAddress]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	copied := *data
+	return &copied, nil
+}
+
+func TestTieredMarketDataCache(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	newFixture := func() (*memoryRemote, *memoryStore, *TieredMarketDataCache) {
+		remote := &memoryRemote{data: map[string]*MarketData{}}
+		store := &memoryStore{data: map[string]*MarketData{
+			"SOL": {TokenAddress: "SOL", ClosePrice: 100, Timestamp: now.Add(-time.Second)},
+		}}
+		return remote, store, NewTieredMarketDataCache(remote, store, TieredConfig{RingSize: 3, LocalTTL: time.Minute})
+	}
+
+	t.Run("promotes on miss", func(t *testing.T) {
+		remote, store, tiered := newFixture()
+
+		data, err := tiered.GetLatestMarketData(ctx, "SOL", 0)
+		assert.NoError(t, err)
+		assert.Equal(t, 100.0, data.ClosePrice)
+		assert.Contains(t, remote.data, "SOL")
+
+		data, err = tiered.GetLatestMarketData(ctx, "SOL", 0)
+		assert.NoError(t, err)
+		assert.Equal(t, 100.0, data.ClosePrice)
+		assert.Equal(t, 1, store.reads)
+		assert.Equal(t, 1, remote.reads)
+
+		stats := tiered.Stats()
+		assert.Equal(t, TierStats{Hits: 1, Misses: 1}, stats[TierLocal])
+		assert.Equal(t, TierStats{Misses: 1}, stats[TierRedis])
+		assert.Equal(t, TierStats{Hits: 1}, stats[TierStore])
+		assert.Equal(t, 0.5, stats[TierLocal].HitRatio())
+		assert.Len(t, tiered.GetRecentMarketData("SOL", 10), 1)
+	})
+
+	t.Run("redis hit promotes to local", func(t *testing.T) {
+		remote, store, tiered := newFixture()
+		remote.data["SOL"] = &MarketData{TokenAddress: "SOL", ClosePrice: 101, Timestamp: now}
+
+		data, err := tiered.GetLatestMarketData(ctx, "SOL", 0)
+		assert.NoError(t, err)
+		assert.Equal(t, 101.0, data.ClosePrice)
+		assert.Equal(t, 0, store.reads)
+
+		_, err = tiered.GetLatestMarketData(ctx, "SOL", 0)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, remote.reads)
+	})
+
+	t.Run("critical reads bypass stale tiers", func(t *testing.T) {
+		remote, store, tiered := newFixture()
+		old := &MarketData{TokenAddress: "SOL", ClosePrice: 90, Timestamp: now.Add(-time.Minute)}
+		assert.NoError(t, tiered.Put(ctx, old))
+
+		// Non-critical reads accept the cached point
+		data, err := tiered.GetLatestMarketData(ctx, "SOL", 0)
+		assert.NoError(t, err)
+		assert.Equal(t, 90.0, data.ClosePrice)
+
+		data, err = tiered.GetLatestMarketData(ctx, "SOL", 5*time.Second)
+		assert.NoError(t, err)
+		assert.Equal(t, 100.0, data.ClosePrice)
+		assert.Equal(t, 1, store.reads)
+		assert.Equal(t, int64(1), tiered.Stats()[TierLocal].Stale)
+		assert.Equal(t, int64(1), tiered.Stats()[TierRedis].Stale)
+		assert.Equal(t, 100.0, remote.data["SOL"].ClosePrice)
+
+		_, err = tiered.GetLatestMarketData(ctx, "SOL", time.Millisecond)
+		assert.ErrorIs(t, err, ErrStaleMarketData)
+	})
+
+	t.Run("remote errors fall through to the store", func(t *testing.T) {
+		remote, _, tiered := newFixture()
+		remote.err = errors.New("connection refused")
+
+		data, err := tiered.GetLatestMarketData(ctx, "SOL", 0)
+		assert.NoError(t, err)
+		assert.Equal(t, 100.0, data.ClosePrice)
+		assert.Equal(t, int64(1), tiered.Stats()[TierRedis].Errors)
+
+		_, err = tiered.GetLatestMarketData(ctx, "BONK", 0)
+		assert.Error(t, err)
+	})
+
+	t.Run("ring keeps the most recent points", func(t *testing.T) {
+		_, _, tiered := newFixture()
+		for i := 0; i < 5; i++ {
+			assert.NoError(t, tiered.Put(ctx, &MarketData{TokenAddress: "SOL", ClosePrice: float64(i), Timestamp: now.Add(time.Duration(i) * time.Second)}))
+		}
+
+		recent := tiered.GetRecentMarketData("SOL", 10)
+		assert.Len(t, recent, 3)
+		assert.Equal(t, []float64{2, 3, 4}, []float64{recent[0].ClosePrice, recent[1].ClosePrice, recent[2].ClosePrice})
+		assert.Len(t, tiered.GetRecentMarketData("SOL", 2), 2)
+	})
+}
@@ -0,0 +1,339 @@
+// Package carry trades the funding of perpetual futures. When perp longs pay
+// shorts, buying the asset on a spot DEX and shorting the same amount of the
+// perp on dYdX earns the funding without exposure to the price of the asset.
+package carry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/dex"
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/monitoring"
+)
+
+// IndicatorType tags the signals of the carry legs
+const IndicatorType = "funding_carry"
+
+// hoursPerYear annualizes funding rates
+const hoursPerYear = 365 * 24
+
+var (
+	// ErrInvalidPair is returned for a pair without a spot token or perp
+	// market
+	ErrInvalidPair = errors.New("invalid carry pair")
+	// ErrNoPrice is returned when a leg has no usable price
+	ErrNoPrice = errors.New("no price")
+	// ErrStaleFunding is returned for a funding rate older than MaxFundingAge
+	ErrStaleFunding = errors.New("stale funding rate")
+)
+
+// FundingRate is the current funding rate of a perp market, as reported by
+// the dYdX indexer
+type FundingRate struct {
+	Market string `json:"market"`
+	// Rate is paid by longs to shorts every funding interval, negative when
+	// shorts pay longs
+	Rate float64 `json:"rate"`
+	// Price is the oracle price of the market
+	Price float64   `json:"price"`
+	Time  time.Time `json:"time"`
+}
+
+// FundingSource returns the funding rates of perp markets, such as a dYdX
+// client
+type FundingSource interface {
+	GetFundingRate(ctx context.Context, market string) (*FundingRate, error)
+}
+
+// SpotSource returns spot DEX prices. dex.DexClient implements it.
+type SpotSource interface {
+	GetMarketData(ctx context.Context, tokenAddress string) (*dex.MarketData, error)
+}
+
+// RiskLimiter approves opening a position on one leg. *risk.RiskManager
+// implements it.
+type RiskLimiter interface {
+	CanOpenPosition(ctx context.Context, tokenAddress string, size float64, marketData *models.MarketData) error
+}
+
+// Pair is an asset traded on a spot DEX and as a dYdX perp
+type Pair struct {
+	Symbol     string `json:"symbol"`
+	SpotToken  string `json:"spot_token"`
+	PerpMarket string `json:"perp_market"`
+}
+
+// Config contains carry configuration
+type Config struct {
+	// FundingInterval is the period a funding rate applies to, one hour on
+	// dYdX
+	FundingInterval time.Duration `json:"funding_interval"`
+	// MinAnnualizedCarry is the annualized funding, as a fraction of the
+	// position, from which the legs are opened
+	MinAnnualizedCarry float64 `json:"min_annualized_carry"`
+	// MaxBasis bounds the relative gap between the perp and spot prices, so
+	// the legs are not opened into a dislocated market
+	MaxBasis float64 `json:"max_basis"`
+	// MaxFundingAge is the age from which a funding rate is stale
+	MaxFundingAge time.Duration `json:"max_funding_age"`
+	// Notional is the value of each leg at the spot price
+	Notional float64 `json:"notional"`
+}
+
+// DefaultConfig returns default carry configuration
+func DefaultConfig() Config {
+	return Config{
+		FundingInterval:    time.Hour,
+		MinAnnualizedCarry: 0.15,
+		MaxBasis:           0.01,
+		MaxFundingAge:      2 * time.Hour,
+		Notional:           1000,
+	}
+}
+
+// Opportunity is the carry of a pair and, when it is worth trading and both
+// legs are within their risk limits, the signals opening it
+type Opportunity struct {
+	Pair            Pair    `json:"pair"`
+	FundingRate     float64 `json:"funding_rate"`
+	AnnualizedCarry float64 `json:"annualized_carry"`
+	SpotPrice       float64 `json:"spot_price"`
+	PerpPrice       float64 `json:"perp_price"`
+	// Basis is the relative premium of the perp over spot
+	Basis float64 `json:"basis"`
+	// Signals are the spot buy and the perp sell, nil when the carry is not
+	// traded
+	Signals []models.TradeSignal `json:"signals,omitempty"`
+	// Reason tells why the carry is not traded
+	Reason    string    `json:"reason,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Scanner compares the funding of perp markets with spot prices and emits
+// delta-neutral signals for the pairs paying enough carry
+type Scanner struct {
+	pairs   []Pair
+	funding FundingSource
+	spot    SpotSource
+	// spotLimits and perpLimits approve the legs; nil limits approve any
+	spotLimits RiskLimiter
+	perpLimits RiskLimiter
+	config     Config
+	monitor    monitoring.IMonitor
+	now        func() time.Time
+	mu         sync.RWMutex
+}
+
+// NewScanner creates a scanner of pairs. The monitor may be nil.
+func NewScanner(pairs []Pair, funding FundingSource, spot SpotSource, config Config, monitor monitoring.IMonitor) (*Scanner, error) {
+	for _, pair := range pairs {
+		if pair.SpotToken == "" || pair.PerpMarket == "" {
+			return nil, fmt.Errorf("%w: %+v", ErrInvalidPair, pair)
+		}
+	}
+
+	defaults := DefaultConfig()
+	if config.FundingInterval <= 0 {
+		config.FundingInterval = defaults.FundingInterval
+	}
+	if config.MinAnnualizedCarry <= 0 {
+		config.MinAnnualizedCarry = defaults.MinAnnualizedCarry
+	}
+	if config.MaxBasis <= 0 {
+		config.MaxBasis = defaults.MaxBasis
+	}
+	if config.MaxFundingAge <= 0 {
+		config.MaxFundingAge = defaults.MaxFundingAge
+	}
+	if config.Notional <= 0 {
+		config.Notional = defaults.Notional
+	}
+
+	return &Scanner{
+		pairs:   pairs,
+		funding: funding,
+		spot:    spot,
+		config:  config,
+		monitor: monitor,
+		now:     time.Now,
+	}, nil
+}
+
+// SetRiskLimits sets the limits the spot and perp legs must both be within
+func (s *Scanner) SetRiskLimits(spot, perp RiskLimiter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.spotLimits = spot
+	s.perpLimits = perp
+}
+
+// Annualize converts a funding rate paid every interval into a yearly rate
+func Annualize(rate float64, interval time.Duration) float64 {
+	return rate * hoursPerYear * float64(time.Hour) / float64(interval)
+}
+
+// Scan evaluates every pair and returns the opportunities that could be
+// priced, along with the signals of those traded
+func (s *Scanner) Scan(ctx context.Context) ([]Opportunity, []models.TradeSignal) {
+	var opportunities []Opportunity
+	var signals []models.TradeSignal
+	for _, pair := range s.pairs {
+		opportunity, err := s.Evaluate(ctx, pair)
+		if err != nil {
+			s.recordFailure(ctx, pair, err)
+			continue
+		}
+		opportunities = append(opportunities, *opportunity)
+		signals = append(signals, opportunity.Signals...)
+	}
+	return opportunities, signals
+}
+
+// Evaluate prices the carry of a pair. The signals of the opportunity are
+// set when the annualized carry reaches MinAnnualizedCarry, the basis is
+// within MaxBasis and both legs are within their risk limits.
+func (s *Scanner) Evaluate(ctx context.Context, pair Pair) (*Opportunity, error) {
+	funding, err := s.funding.GetFundingRate(ctx, pair.PerpMarket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get funding rate of %s: %w", pair.PerpMarket, err)
+	}
+	now := s.now()
+	if !funding.Time.IsZero() && now.Sub(funding.Time) > s.config.MaxFundingAge {
+		return nil, fmt.Errorf("%w: %s as of %s", ErrStaleFunding, pair.PerpMarket, funding.Time.Format(time.RFC3339))
+	}
+	if !(funding.Price > 0) {
+		return nil, fmt.Errorf("%w: %s", ErrNoPrice, pair.PerpMarket)
+	}
+
+	spot, err := s.spot.GetMarketData(ctx, pair.SpotToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get spot price of %s: %w", pair.SpotToken, err)
+	}
+	if !(spot.Price > 0) {
+		return nil, fmt.Errorf("%w: %s", ErrNoPrice, pair.SpotToken)
+	}
+
+	opportunity := &Opportunity{
+		Pair:            pair,
+		FundingRate:     funding.Rate,
+		AnnualizedCarry: Annualize(funding.Rate, s.config.FundingInterval),
+		SpotPrice:       spot.Price,
+		PerpPrice:       funding.Price,
+		Basis:           (funding.Price - spot.Price) / spot.Price,
+		Timestamp:       now,
+	}
+	if s.monitor != nil {
+		s.monitor.RecordMetric(ctx, "funding_carry_annualized", opportunity.AnnualizedCarry, map[string]string{
+			"symbol": pair.Symbol,
+		})
+	}
+
+	switch {
+	case opportunity.AnnualizedCarry < s.config.MinAnnualizedCarry:
+		opportunity.Reason = fmt.Sprintf("annualized carry %.4f below %.4f", opportunity.AnnualizedCarry, s.config.MinAnnualizedCarry)
+		return opportunity, nil
+	case math.Abs(opportunity.Basis) > s.config.MaxBasis:
+		opportunity.Reason = fmt.Sprintf("basis %.4f exceeds %.4f", opportunity.Basis, s.config.MaxBasis)
+		return opportunity, nil
+	}
+
+	// Both legs hold the same amount of the asset, so their price exposure
+	// cancels out
+	size := s.config.Notional / spot.Price
+	if err := s.checkLimits(ctx, pair, size, spot, funding, now); err != nil {
+		opportunity.Reason = err.Error()
+		return opportunity, nil
+	}
+	opportunity.Signals = s.signals(opportunity, size)
+	return opportunity, nil
+}
+
+// checkLimits checks both legs against their risk limits
+func (s *Scanner) checkLimits(ctx context.Context, pair Pair, size float64, spot *dex.MarketData, funding *FundingRate, now time.Time) error {
+	s.mu.RLock()
+	spotLimits, perpLimits := s.spotLimits, s.perpLimits
+	s.mu.RUnlock()
+
+	if spotLimits != nil {
+		data := &models.MarketData{
+			Symbol:       pair.Symbol,
+			TokenAddress: pair.SpotToken,
+			ClosePrice:   spot.Price,
+			Volume24h:    spot.Volume24h,
+			Liquidity:    spot.Liquidity,
+			PriceImpact:  spot.PriceImpact,
+			Timestamp:    spot.Timestamp,
+		}
+		if err := spotLimits.CanOpenPosition(ctx, pair.SpotToken, size, data); err != nil {
+			return fmt.Errorf("spot leg rejected: %w", err)
+		}
+	}
+	if perpLimits != nil {
+		data := &models.MarketData{
+			Symbol:       pair.Symbol,
+			TokenAddress: pair.PerpMarket,
+			ClosePrice:   funding.Price,
+			Timestamp:    now,
+		}
+		if err := perpLimits.CanOpenPosition(ctx, pair.PerpMarket, size, data); err != nil {
+			return fmt.Errorf("perp leg rejected: %w", err)
+		}
+	}
+	return nil
+}
+
+// signals returns the spot buy and perp sell of an opportunity. Confidence
+// grows with the carry, reaching one at twice the threshold.
+func (s *Scanner) signals(opportunity *Opportunity, size float64) []models.TradeSignal {
+	ratio := opportunity.AnnualizedCarry / s.config.MinAnnualizedCarry
+	confidence := math.Min(ratio/2, 1)
+	strength := models.SignalStrengthWeak
+	switch {
+	case ratio >= 3:
+		strength = models.SignalStrengthStrong
+	case ratio >= 2:
+		strength = models.SignalStrengthMedium
+	}
+	description := fmt.Sprintf("%s funding carry %.2f%% annualized, basis %.4f",
+		opportunity.Pair.Symbol, opportunity.AnnualizedCarry*100, opportunity.Basis)
+
+	leg := func(symbol string, side models.SignalType, price float64) models.TradeSignal {
+		return models.TradeSignal{
+			Symbol:        symbol,
+			SignalType:    side,
+			Price:         price,
+			Size:          size,
+			Timestamp:     opportunity.Timestamp,
+			Strength:      strength,
+			Confidence:    confidence,
+			Description:   description,
+			IndicatorType: IndicatorType,
+		}
+	}
+	return []models.TradeSignal{
+		leg(opportunity.Pair.SpotToken, models.Buy, opportunity.SpotPrice),
+		leg(opportunity.Pair.PerpMarket, models.Sell, opportunity.PerpPrice),
+	}
+}
+
+func (s *Scanner) recordFailure(ctx context.Context, pair Pair, err error) {
+	if s.monitor == nil {
+		return
+	}
+	s.monitor.RecordEvent(ctx, monitoring.Event{
+		Type:     monitoring.MetricTrading,
+		Severity: monitoring.SeverityWarning,
+		Message:  "Failed to evaluate funding carry",
+		Details: map[string]interface{}{
+			"symbol":      pair.Symbol,
+			"spot_token":  pair.SpotToken,
+			"perp_market": pair.PerpMarket,
+			"error":       err.Error(),
+		},
+	})
+}
@@ -0,0 +1,153 @@
+package carry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/leonzhao/trading-system/backend/dex"
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/monitoring"
+)
+
+type staticFunding map[string]*FundingRate
+
+func (f staticFunding) GetFundingRate(ctx context.Context, market string) (*FundingRate, error) {
+	rate, ok := f[market]
+	if !ok {
+		return nil, errors.New("unknown market")
+	}
+	return rate, nil
+}
+
+type staticSpot map[string]float64
+
+func (s staticSpot) GetMarketData(ctx context.Context, tokenAddress string) (*dex.MarketData, error) {
+	return &dex.MarketData{TokenAddress: tokenAddress, Price: s[tokenAddress]}, nil
+}
+
+// sizeLimit rejects positions larger than its size
+type sizeLimit struct {
+	max     float64
+	checked []string
+}
+
+func (l *sizeLimit) CanOpenPosition(ctx context.Context, tokenAddress string, size float64, marketData *models.MarketData) error {
+	l.checked = append(l.checked, tokenAddress)
+	if size > l.max {
+		return errors.New("position too large")
+	}
+	return nil
+}
+
+type recordingMonitor struct {
+	monitoring.IMonitor
+	events []monitoring.Event
+}
+
+func (m *recordingMonitor) RecordEvent(ctx context.Context, event monitoring.Event) {
+	m.events = append(m.events, event)
+}
+
+func (m *recordingMonitor) RecordMetric(ctx context.Context, name string, value float64, tags map[string]string) {
+}
+
+var sol = Pair{Symbol: "SOL", SpotToken: "So11111111111111111111111111111111111111112", PerpMarket: "SOL-USD"}
+
+func newScanner(t *testing.T, rate float64, perpPrice float64, monitor monitoring.IMonitor) (*Scanner, time.Time) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	funding := staticFunding{"SOL-USD": {Market: "SOL-USD", Rate: rate, Price: perpPrice, Time: now.Add(-30 * time.Minute)}}
+	spot := staticSpot{sol.SpotToken: 100}
+	s, err := NewScanner([]Pair{sol}, funding, spot, DefaultConfig(), monitor)
+	require.NoError(t, err)
+	s.now = func() time.Time { return now }
+	return s, now
+}
+
+func TestAnnualize(t *testing.T) {
+	assert.InDelta(t, 0.876, Annualize(0.0001, time.Hour), 1e-9)
+	assert.InDelta(t, 0.876, Annualize(0.0008, 8*time.Hour), 1e-9)
+}
+
+func TestEvaluate(t *testing.T) {
+	ctx := context.Background()
+
+	// 0.005% an hour is 43.8% a year, about three times the threshold
+	s, now := newScanner(t, 0.00005, 100.2, nil)
+	opportunity, err := s.Evaluate(ctx, sol)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.438, opportunity.AnnualizedCarry, 1e-9)
+	assert.InDelta(t, 0.002, opportunity.Basis, 1e-9)
+	require.Len(t, opportunity.Signals, 2)
+
+	spot, perp := opportunity.Signals[0], opportunity.Signals[1]
+	assert.Equal(t, sol.SpotToken, spot.Symbol)
+	assert.Equal(t, models.Buy, spot.SignalType)
+	assert.Equal(t, 100.0, spot.Price)
+	assert.Equal(t, "SOL-USD", perp.Symbol)
+	assert.Equal(t, models.Sell, perp.SignalType)
+	assert.Equal(t, 100.2, perp.Price)
+	assert.InDelta(t, 10, spot.Size, 1e-9)
+	assert.Equal(t, spot.Size, perp.Size, "delta neutral")
+	assert.Equal(t, models.SignalStrengthMedium, spot.Strength)
+	assert.Equal(t, 1.0, spot.Confidence)
+	assert.Equal(t, IndicatorType, perp.IndicatorType)
+	assert.Equal(t, now, perp.Timestamp)
+
+	// Negative funding pays the perp longs, which this trade is not
+	s, _ = newScanner(t, -0.00005, 100, nil)
+	opportunity, err = s.Evaluate(ctx, sol)
+	require.NoError(t, err)
+	assert.Empty(t, opportunity.Signals)
+	assert.Contains(t, opportunity.Reason, "below")
+
+	s, _ = newScanner(t, 0.00005, 102, nil)
+	opportunity, err = s.Evaluate(ctx, sol)
+	require.NoError(t, err)
+	assert.Empty(t, opportunity.Signals)
+	assert.Contains(t, opportunity.Reason, "basis")
+}
+
+func TestRiskLimits(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newScanner(t, 0.00005, 100, nil)
+	spotLimit, perpLimit := &sizeLimit{max: 100}, &sizeLimit{max: 5}
+	s.SetRiskLimits(spotLimit, perpLimit)
+
+	opportunity, err := s.Evaluate(ctx, sol)
+	require.NoError(t, err)
+	assert.Empty(t, opportunity.Signals, "both legs or none")
+	assert.Contains(t, opportunity.Reason, "perp leg rejected")
+	assert.Equal(t, []string{sol.SpotToken}, spotLimit.checked)
+	assert.Equal(t, []string{"SOL-USD"}, perpLimit.checked)
+
+	perpLimit.max = 100
+	opportunity, err = s.Evaluate(ctx, sol)
+	require.NoError(t, err)
+	assert.Len(t, opportunity.Signals, 2)
+}
+
+func TestScan(t *testing.T) {
+	ctx := context.Background()
+	monitor := &recordingMonitor{}
+	s, now := newScanner(t, 0.00005, 100, monitor)
+	eth := Pair{Symbol: "ETH", SpotToken: "eth", PerpMarket: "ETH-USD"}
+	s.pairs = append(s.pairs, eth)
+
+	opportunities, signals := s.Scan(ctx)
+	require.Len(t, opportunities, 1)
+	assert.Len(t, signals, 2)
+	require.Len(t, monitor.events, 1, "unknown market")
+	assert.Equal(t, "ETH", monitor.events[0].Details.(map[string]interface{})["symbol"])
+
+	s.funding.(staticFunding)["SOL-USD"].Time = now.Add(-3 * time.Hour)
+	_, err := s.Evaluate(ctx, sol)
+	assert.ErrorIs(t, err, ErrStaleFunding)
+
+	_, err = NewScanner([]Pair{{Symbol: "BTC"}}, nil, nil, DefaultConfig(), nil)
+	assert.ErrorIs(t, err, ErrInvalidPair)
+}
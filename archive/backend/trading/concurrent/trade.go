@@ -93,36 +93,20 @@ func (p *TradeProcessor) ProcessTrade(ctx context.Context, trade *models.Trade)
 		return fmt.Errorf("failed to open position: %w", err)
 	}
 
-	// Save position
-	if err := p.repo.SavePosition(ctx, position); err != nil {
-		p.monitor.RecordEvent(ctx, monitoring.Event{
-			Type:      monitoring.MetricTrading,
-			Severity:  monitoring.SeverityError,
-			Message:   "Failed to save position",
-			Details:   map[string]interface{}{
-				"error": err.Error(),
-				"tokenAddress": trade.TokenAddress,
-			},
-			Timestamp: time.Now(),
-		})
-		return fmt.Errorf("failed to save position: %w", err)
-	}
-
-	// Update trade status
+	// Save the executed trade and its position together
 	trade.Status = models.TradeStatusCompleted
-	trade.UpdateTime = time.Now()
-	if err := p.repo.UpdateTrade(ctx, trade); err != nil {
+	if err := p.repo.SaveTradeAndUpdatePosition(ctx, trade, position); err != nil {
 		p.monitor.RecordEvent(ctx, monitoring.Event{
 			Type:      monitoring.MetricTrading,
 			Severity:  monitoring.SeverityError,
-			Message:   "Failed to update trade",
+			Message:   "Failed to save trade and position",
 			Details:   map[string]interface{}{
 				"error": err.Error(),
 				"tokenAddress": trade.TokenAddress,
 			},
 			Timestamp: time.Now(),
 		})
-		return fmt.Errorf("failed to update trade: %w", err)
+		return fmt.Errorf("failed to save trade and position: %w", err)
 	}
 
 	// Record successful processing
@@ -0,0 +1,141 @@
+package trading
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/monitoring"
+	"github.com/leonzhao/trading-system/backend/repository"
+)
+
+// ErrDuplicateSignal is returned when a signal has already been executed
+// within the deduplication window
+var ErrDuplicateSignal = errors.New("duplicate trade signal")
+
+// FingerprintStore persists signal fingerprints
+type FingerprintStore interface {
+	ReserveSignalFingerprint(ctx context.Context, fingerprint *models.SignalFingerprint) error
+	ReleaseSignalFingerprint(ctx context.Context, id string) error
+}
+
+// DedupConfig contains signal deduplication configuration
+type DedupConfig struct {
+	// Window is the bucket size used to group repeated signals
+	Window time.Duration
+	// TTL is how long a fingerprint is retained
+	TTL time.Duration
+}
+
+// DefaultDedupConfig returns default deduplication configuration
+func DefaultDedupConfig() DedupConfig {
+	return DedupConfig{
+		Window: 5 * time.Minute,
+		TTL:    24 * time.Hour,
+	}
+}
+
+// Deduplicator suppresses re-execution of the same trade signal
+type Deduplicator struct {
+	store      FingerprintStore
+	config     DedupConfig
+	monitor    monitoring.IMonitor
+	suppressed atomic.Int64
+}
+
+// NewDeduplicator creates a new signal deduplicator
+func NewDeduplicator(store FingerprintStore, config DedupConfig, monitor monitoring.IMonitor) *Deduplicator {
+	if config.Window <= 0 {
+		config.Window = DefaultDedupConfig().Window
+	}
+	if config.TTL < config.Window {
+		config.TTL = config.Window
+	}
+
+	return &Deduplicator{
+		store:   store,
+		config:  config,
+		monitor: monitor,
+	}
+}
+
+// Fingerprint builds the fingerprint for a signal produced by a strategy.
+// The window is that of the signal timestamp, or of now for signals without
+// one, so a signal redelivered later still falls in its original window.
+func (d *Deduplicator) Fingerprint(signal *models.TradeSignal, strategy string, now time.Time) *models.SignalFingerprint {
+	direction := mapSignalTypeToTradeSide(signal.SignalType)
+	at := signal.Timestamp
+	if at.IsZero() {
+		at = now
+	}
+	windowStart := at.Truncate(d.config.Window)
+
+	key := fmt.Sprintf("%s|%s|%s|%d", signal.Symbol, direction, strategy, windowStart.Unix())
+	sum := sha256.Sum256([]byte(key))
+
+	return &models.SignalFingerprint{
+		ID:           hex.EncodeToString(sum[:]),
+		TokenAddress: signal.Symbol,
+		Direction:    direction,
+		Strategy:     strategy,
+		WindowStart:  windowStart,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(d.config.TTL),
+	}
+}
+
+// Check reserves the fingerprint for a signal, returning ErrDuplicateSignal
+// if the same signal was already executed within the window. The caller
+// must Release the returned fingerprint if the signal is not executed.
+func (d *Deduplicator) Check(ctx context.Context, signal *models.TradeSignal, strategy string) (*models.SignalFingerprint, error) {
+	fingerprint := d.Fingerprint(signal, strategy, time.Now())
+
+	err := d.store.ReserveSignalFingerprint(ctx, fingerprint)
+	if err == nil {
+		return fingerprint, nil
+	}
+	if !errors.Is(err, repository.ErrDuplicateKey) {
+		return nil, fmt.Errorf("failed to reserve signal fingerprint: %w", err)
+	}
+
+	total := d.suppressed.Add(1)
+	if d.monitor != nil {
+		d.monitor.RecordMetric(ctx, "duplicate_signals_suppressed", float64(total), map[string]string{
+			"tokenAddress": signal.Symbol,
+			"strategy":     strategy,
+		})
+		d.monitor.RecordEvent(ctx, monitoring.Event{
+			Type:     monitoring.MetricTrading,
+			Severity: monitoring.SeverityWarning,
+			Message:  "Duplicate trade signal suppressed",
+			Details: map[string]interface{}{
+				"fingerprint":  fingerprint.ID,
+				"tokenAddress": signal.Symbol,
+				"direction":    fingerprint.Direction,
+				"strategy":     strategy,
+				"windowStart":  fingerprint.WindowStart,
+			},
+		})
+	}
+
+	return nil, ErrDuplicateSignal
+}
+
+// Release drops the reservation of a signal that was not executed, so it can
+// be retried within its window
+func (d *Deduplicator) Release(ctx context.Context, fingerprint *models.SignalFingerprint) error {
+	if err := d.store.ReleaseSignalFingerprint(ctx, fingerprint.ID); err != nil {
+		return fmt.Errorf("failed to release signal fingerprint: %w", err)
+	}
+	return nil
+}
+
+// Suppressed returns the number of duplicate signals suppressed
+func (d *Deduplicator) Suppressed() int64 {
+	return d.suppressed.Load()
+}
@@ -0,0 +1,112 @@
+package trading
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/repository"
+)
+
+type memoryFingerprintStore struct {
+	fingerprints map[string]*models.SignalFingerprint
+}
+
+func (s *memoryFingerprintStore) ReserveSignalFingerprint(ctx context.Context, fingerprint *models.SignalFingerprint) error {
+	if existing, ok := s.fingerprints[fingerprint.ID]; ok && existing.ExpiresAt.After(time.Now()) {
+		return repository.ErrDuplicateKey
+	}
+	s.fingerprints[fingerprint.ID] = fingerprint
+	return nil
+}
+
+func (s *memoryFingerprintStore) ReleaseSignalFingerprint(ctx context.Context, id string) error {
+	delete(s.fingerprints, id)
+	return nil
+}
+
+// check reserves the fingerprint of signal, returning only the error
+func check(dedup *Deduplicator, signal *models.TradeSignal, strategy string) error {
+	_, err := dedup.Check(context.Background(), signal, strategy)
+	return err
+}
+
+func TestDeduplicator(t *testing.T) {
+	ctx := context.Background()
+	signal := &models.TradeSignal{
+		Symbol:     "SOL/USDC",
+		SignalType: models.SignalTypeBuy,
+		Price:      95.0,
+		Size:       10.0,
+	}
+
+	t.Run("suppresses repeated signal", func(t *testing.T) {
+		store := &memoryFingerprintStore{fingerprints: make(map[string]*models.SignalFingerprint)}
+		dedup := NewDeduplicator(store, DefaultDedupConfig(), nil)
+
+		assert.NoError(t, check(dedup, signal, "momentum"))
+		assert.ErrorIs(t, check(dedup, signal, "momentum"), ErrDuplicateSignal)
+		assert.Equal(t, int64(1), dedup.Suppressed())
+	})
+
+	t.Run("survives restart through the store", func(t *testing.T) {
+		store := &memoryFingerprintStore{fingerprints: make(map[string]*models.SignalFingerprint)}
+
+		assert.NoError(t, check(NewDeduplicator(store, DefaultDedupConfig(), nil), signal, "momentum"))
+
+		restarted := NewDeduplicator(store, DefaultDedupConfig(), nil)
+		assert.ErrorIs(t, check(restarted, signal, "momentum"), ErrDuplicateSignal)
+	})
+
+	t.Run("different strategy or direction is not a duplicate", func(t *testing.T) {
+		store := &memoryFingerprintStore{fingerprints: make(map[string]*models.SignalFingerprint)}
+		dedup := NewDeduplicator(store, DefaultDedupConfig(), nil)
+
+		sell := *signal
+		sell.SignalType = models.SignalTypeSell
+
+		assert.NoError(t, check(dedup, signal, "momentum"))
+		assert.NoError(t, check(dedup, signal, "breakout"))
+		assert.NoError(t, check(dedup, &sell, "momentum"))
+	})
+
+	t.Run("fingerprint is stable within a window", func(t *testing.T) {
+		dedup := NewDeduplicator(nil, DedupConfig{Window: time.Minute, TTL: time.Hour}, nil)
+		base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+		first := dedup.Fingerprint(signal, "momentum", base.Add(10*time.Second))
+		second := dedup.Fingerprint(signal, "momentum", base.Add(50*time.Second))
+		next := dedup.Fingerprint(signal, "momentum", base.Add(70*time.Second))
+
+		assert.Equal(t, first.ID, second.ID)
+		assert.NotEqual(t, first.ID, next.ID)
+		assert.Equal(t, base.Add(10*time.Second).Add(time.Hour), first.ExpiresAt)
+	})
+	t.Run("window follows the signal timestamp", func(t *testing.T) {
+		dedup := NewDeduplicator(nil, DedupConfig{Window: time.Minute, TTL: time.Hour}, nil)
+		base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+		stamped := *signal
+		stamped.Timestamp = base.Add(50 * time.Second)
+
+		first := dedup.Fingerprint(&stamped, "momentum", base.Add(50*time.Second))
+		redelivered := dedup.Fingerprint(&stamped, "momentum", base.Add(5*time.Minute))
+
+		assert.Equal(t, first.ID, redelivered.ID, "a late redelivery falls in the original window")
+		assert.Equal(t, base, redelivered.WindowStart)
+	})
+
+	t.Run("released signal can be retried", func(t *testing.T) {
+		store := &memoryFingerprintStore{fingerprints: make(map[string]*models.SignalFingerprint)}
+		dedup := NewDeduplicator(store, DefaultDedupConfig(), nil)
+
+		fingerprint, err := dedup.Check(ctx, signal, "momentum")
+		assert.NoError(t, err)
+		assert.NoError(t, dedup.Release(ctx, fingerprint))
+		assert.NoError(t, check(dedup, signal, "momentum"))
+		assert.Equal(t, int64(0), dedup.Suppressed())
+	})
+}
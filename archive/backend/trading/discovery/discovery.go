@@ -0,0 +1,322 @@
+// Package discovery finds newly listed tokens by polling DEXes for new
+// pools and keeps a watchlist of the ones that pass its filters.
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/dex"
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/monitoring"
+	"github.com/leonzhao/trading-system/backend/trading/screening"
+)
+
+// PoolSource lists newly created pools. dex.RaydiumClient and
+// dex.JupiterClient implement it.
+type PoolSource interface {
+	GetNewPools(ctx context.Context, since time.Time) ([]dex.NewPool, error)
+}
+
+// Screener scores the safety of a token. screening.Screener implements it.
+type Screener interface {
+	Screen(ctx context.Context, mint string) (*screening.TokenRiskScore, error)
+}
+
+// WatchlistStore persists the watchlist. repository.Repository implements it.
+type WatchlistStore interface {
+	SaveWatchlistEntry(ctx context.Context, entry *models.WatchlistEntry) error
+	ListWatchlist(ctx context.Context, filter *models.WatchlistFilter) ([]*models.WatchlistEntry, error)
+}
+
+// SignalSink executes trade signals. trading.Executor implements it.
+type SignalSink interface {
+	ExecuteSignal(ctx context.Context, signal *models.TradeSignalMessage) error
+}
+
+// Config contains new listing discovery configuration
+type Config struct {
+	PollInterval time.Duration `json:"poll_interval"`
+	// Lookback is how far back the first poll looks for new pools
+	Lookback time.Duration `json:"lookback"`
+	// MinAge holds back pools until they are this old, so that launches
+	// that are pulled right away are never listed
+	MinAge time.Duration `json:"min_age"`
+	// MinLiquidityUSD is the minimum pool liquidity once MinAge has passed
+	MinLiquidityUSD float64 `json:"min_liquidity_usd"`
+	// MaxRiskScore rejects tokens with a higher screening score. Tokens the
+	// screener blocks are always rejected.
+	MaxRiskScore float64 `json:"max_risk_score"`
+	// SignalSize is the size of the buy signal sent for each new candidate
+	// when a signal sink is set. Zero disables signals.
+	SignalSize float64 `json:"signal_size"`
+}
+
+// DefaultConfig returns default new listing discovery configuration
+func DefaultConfig() Config {
+	return Config{
+		PollInterval:    30 * time.Second,
+		Lookback:        time.Hour,
+		MinAge:          5 * time.Minute,
+		MinLiquidityUSD: 20000,
+		MaxRiskScore:    25,
+	}
+}
+
+type pendingPool struct {
+	pool  dex.NewPool
+	venue string
+}
+
+// Service discovers new token listings. Pools are evaluated once they reach
+// the minimum age; every evaluated token is persisted, as watched or
+// rejected, and never evaluated again.
+type Service struct {
+	sources  map[string]PoolSource
+	screener Screener
+	store    WatchlistStore
+	sink     SignalSink
+	config   Config
+	monitor  monitoring.IMonitor
+	seen     map[string]bool
+	pending  map[string]pendingPool
+	lastPoll map[string]time.Time
+	mu       sync.Mutex
+}
+
+// NewService creates a discovery service polling the given sources, keyed by
+// venue name. The screener and monitor may be nil.
+func NewService(sources map[string]PoolSource, screener Screener, store WatchlistStore, config Config, monitor monitoring.IMonitor) *Service {
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultConfig().PollInterval
+	}
+	if config.Lookback <= 0 {
+		config.Lookback = DefaultConfig().Lookback
+	}
+
+	return &Service{
+		sources:  sources,
+		screener: screener,
+		store:    store,
+		config:   config,
+		monitor:  monitor,
+		seen:     make(map[string]bool),
+		pending:  make(map[string]pendingPool),
+		lastPoll: make(map[string]time.Time),
+	}
+}
+
+// SetSignalSink enables buy signals for new candidates
+func (s *Service) SetSignalSink(sink SignalSink) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sink = sink
+}
+
+// Load marks the persisted watchlist as already evaluated, so tokens are not
+// evaluated again after a restart
+func (s *Service) Load(ctx context.Context) error {
+	entries, err := s.store.ListWatchlist(ctx, &models.WatchlistFilter{})
+	if err != nil {
+		return fmt.Errorf("failed to load watchlist: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, entry := range entries {
+		s.seen[entry.TokenAddress] = true
+	}
+	return nil
+}
+
+// Watchlist returns the tokens currently watched
+func (s *Service) Watchlist(ctx context.Context) ([]*models.WatchlistEntry, error) {
+	return s.store.ListWatchlist(ctx, &models.WatchlistFilter{Status: models.WatchlistWatching})
+}
+
+// Run polls for new pools until the context is done
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.Poll(ctx); err != nil && s.monitor != nil {
+			s.monitor.RecordEvent(ctx, monitoring.Event{
+				Type:     monitoring.MetricSystem,
+				Severity: monitoring.SeverityWarning,
+				Message:  "Token discovery poll failed",
+				Details:  map[string]interface{}{"error": err.Error()},
+			})
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll fetches new pools from every source and evaluates the pools that
+// reached the minimum age. It returns the new watchlist candidates.
+func (s *Service) Poll(ctx context.Context) ([]*models.WatchlistEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	for venue, source := range s.sources {
+		start := time.Now()
+		since, ok := s.lastPoll[venue]
+		if !ok {
+			since = start.Add(-s.config.Lookback)
+		}
+
+		pools, err := source.GetNewPools(ctx, since)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", venue, err))
+			continue
+		}
+		s.lastPoll[venue] = start
+
+		for _, pool := range pools {
+			if pool.TokenAddress == "" || s.seen[pool.TokenAddress] {
+				continue
+			}
+			if _, ok := s.pending[pool.TokenAddress]; ok {
+				continue
+			}
+			s.pending[pool.TokenAddress] = pendingPool{pool: pool, venue: venue}
+			s.recordMetric(ctx, "discovery_pools_found", venue)
+		}
+	}
+
+	// Evaluate the oldest pools first
+	ready := make([]pendingPool, 0, len(s.pending))
+	for _, p := range s.pending {
+		if time.Since(p.pool.CreatedAt) >= s.config.MinAge {
+			ready = append(ready, p)
+		}
+	}
+	sort.Slice(ready, func(i, j int) bool {
+		return ready[i].pool.CreatedAt.Before(ready[j].pool.CreatedAt)
+	})
+
+	var candidates []*models.WatchlistEntry
+	for _, p := range ready {
+		entry, err := s.evaluate(ctx, p)
+		if err == nil {
+			err = s.store.SaveWatchlistEntry(ctx, entry)
+		}
+		if err != nil {
+			// Left pending and evaluated again on the next poll
+			errs = append(errs, fmt.Errorf("%s: %w", p.pool.TokenAddress, err))
+			continue
+		}
+		delete(s.pending, p.pool.TokenAddress)
+		s.seen[p.pool.TokenAddress] = true
+
+		if entry.Status != models.WatchlistWatching {
+			s.recordMetric(ctx, "discovery_tokens_rejected", p.venue)
+			continue
+		}
+		candidates = append(candidates, entry)
+		s.announce(ctx, entry)
+		if err := s.signal(ctx, entry); err != nil {
+			errs = append(errs, fmt.Errorf("%s: failed to execute signal: %w", entry.TokenAddress, err))
+		}
+	}
+
+	return candidates, errors.Join(errs...)
+}
+
+// evaluate applies the liquidity and screening filters to a pool. Errors are
+// transient and leave the pool pending.
+func (s *Service) evaluate(ctx context.Context, p pendingPool) (*models.WatchlistEntry, error) {
+	now := time.Now()
+	entry := &models.WatchlistEntry{
+		TokenAddress:  p.pool.TokenAddress,
+		PoolAddress:   p.pool.Address,
+		Venue:         p.venue,
+		Status:        models.WatchlistWatching,
+		Price:         p.pool.Price,
+		LiquidityUSD:  p.pool.LiquidityUSD,
+		PoolCreatedAt: p.pool.CreatedAt,
+		DiscoveredAt:  now,
+		UpdatedAt:     now,
+	}
+
+	if p.pool.LiquidityUSD < s.config.MinLiquidityUSD {
+		entry.Status = models.WatchlistRejected
+		entry.Reason = fmt.Sprintf("liquidity $%.0f below $%.0f", p.pool.LiquidityUSD, s.config.MinLiquidityUSD)
+		return entry, nil
+	}
+
+	if s.screener == nil {
+		return entry, nil
+	}
+	score, err := s.screener.Screen(ctx, p.pool.TokenAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to screen token: %w", err)
+	}
+	entry.RiskScore = score.Score
+	switch {
+	case score.Blocked:
+		entry.Status = models.WatchlistRejected
+		entry.Reason = score.Err().Error()
+	case score.Score > s.config.MaxRiskScore:
+		entry.Status = models.WatchlistRejected
+		entry.Reason = fmt.Sprintf("risk score %.0f above %.0f", score.Score, s.config.MaxRiskScore)
+	}
+	return entry, nil
+}
+
+// signal sends a buy signal for a new candidate
+func (s *Service) signal(ctx context.Context, entry *models.WatchlistEntry) error {
+	if s.sink == nil || s.config.SignalSize <= 0 {
+		return nil
+	}
+
+	signal := &models.TradeSignal{
+		Symbol:        entry.TokenAddress,
+		SignalType:    models.SignalTypeBuy,
+		Price:         entry.Price,
+		Size:          s.config.SignalSize,
+		Timestamp:     entry.DiscoveredAt,
+		Strength:      models.SignalStrengthWeak,
+		Description:   fmt.Sprintf("new %s listing with $%.0f liquidity", entry.Venue, entry.LiquidityUSD),
+		IndicatorType: "DISCOVERY",
+	}
+	return s.sink.ExecuteSignal(ctx, &models.TradeSignalMessage{
+		Signal: signal,
+		Metadata: map[string]interface{}{
+			"strategy":   "discovery",
+			"venue":      entry.Venue,
+			"pool":       entry.PoolAddress,
+			"risk_score": entry.RiskScore,
+		},
+		Timestamp: signal.Timestamp,
+	})
+}
+
+func (s *Service) announce(ctx context.Context, entry *models.WatchlistEntry) {
+	if s.monitor == nil {
+		return
+	}
+	s.monitor.RecordEvent(ctx, monitoring.Event{
+		Type:     monitoring.MetricTrading,
+		Severity: monitoring.SeverityInfo,
+		Message:  "New token candidate",
+		Details:  entry,
+	})
+}
+
+func (s *Service) recordMetric(ctx context.Context, name, venue string) {
+	if s.monitor == nil {
+		return
+	}
+	s.monitor.RecordMetric(ctx, name, 1, map[string]string{"venue": venue})
+}
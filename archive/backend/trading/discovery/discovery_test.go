@@ -0,0 +1,189 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/leonzhao/trading-system/backend/dex"
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/monitoring"
+	"github.com/leonzhao/trading-system/backend/trading/screening"
+)
+
+type fakeSource struct {
+	pools []dex.NewPool
+	since []time.Time
+	err   error
+}
+
+func (s *fakeSource) GetNewPools(ctx context.Context, since time.Time) ([]dex.NewPool, error) {
+	s.since = append(s.since, since)
+	return s.pools, s.err
+}
+
+type fakeScreener struct {
+	scores map[string]*screening.TokenRiskScore
+	err    error
+}
+
+func (s *fakeScreener) Screen(ctx context.Context, mint string) (*screening.TokenRiskScore, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if score, ok := s.scores[mint]; ok {
+		return score, nil
+	}
+	return &screening.TokenRiskScore{Mint: mint}, nil
+}
+
+type memoryWatchlist struct {
+	entries map[string]*models.WatchlistEntry
+}
+
+func (w *memoryWatchlist) SaveWatchlistEntry(ctx context.Context, entry *models.WatchlistEntry) error {
+	w.entries[entry.TokenAddress] = entry
+	return nil
+}
+
+func (w *memoryWatchlist) ListWatchlist(ctx context.Context, filter *models.WatchlistFilter) ([]*models.WatchlistEntry, error) {
+	var entries []*models.WatchlistEntry
+	for _, entry := range w.entries {
+		if filter.Status == "" || entry.Status == filter.Status {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+type recordingSink struct {
+	signals []*models.TradeSignalMessage
+}
+
+func (s *recordingSink) ExecuteSignal(ctx context.Context, signal *models.TradeSignalMessage) error {
+	s.signals = append(s.signals, signal)
+	return nil
+}
+
+type recordingMonitor struct {
+	monitoring.IMonitor
+	events  []monitoring.Event
+	metrics map[string]float64
+}
+
+func (m *recordingMonitor) RecordEvent(ctx context.Context, event monitoring.Event) {
+	m.events = append(m.events, event)
+}
+
+func (m *recordingMonitor) RecordMetric(ctx context.Context, name string, value float64, tags map[string]string) {
+	m.metrics[name] += value
+}
+
+func TestDiscovery(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	config := Config{MinAge: 5 * time.Minute, MinLiquidityUSD: 20000, MaxRiskScore: 25, SignalSize: 50}
+
+	newFixture := func() (*fakeSource, *fakeSource, *fakeScreener, *memoryWatchlist, *recordingMonitor, *Service) {
+		raydium := &fakeSource{pools: []dex.NewPool{
+			{Address: "pool1", TokenAddress: "GOOD", Price: 0.01, LiquidityUSD: 80000, CreatedAt: now.Add(-10 * time.Minute)},
+			{Address: "pool2", TokenAddress: "THIN", LiquidityUSD: 5000, CreatedAt: now.Add(-20 * time.Minute)},
+			{Address: "pool3", TokenAddress: "FRESH", LiquidityUSD: 90000, CreatedAt: now.Add(-5*time.Minute + 50*time.Millisecond)},
+		}}
+		jupiter := &fakeSource{pools: []dex.NewPool{
+			{Address: "market1", TokenAddress: "RUG", LiquidityUSD: 60000, CreatedAt: now.Add(-30 * time.Minute)},
+			{Address: "market2", TokenAddress: "GOOD", LiquidityUSD: 80000, CreatedAt: now.Add(-10 * time.Minute)},
+		}}
+		screener := &fakeScreener{scores: map[string]*screening.TokenRiskScore{
+			"RUG": {Mint: "RUG", Score: 30, Blocked: true},
+		}}
+		store := &memoryWatchlist{entries: make(map[string]*models.WatchlistEntry)}
+		monitor := &recordingMonitor{metrics: make(map[string]float64)}
+		service := NewService(map[string]PoolSource{"raydium": raydium, "jupiter": jupiter}, screener, store, config, monitor)
+		return raydium, jupiter, screener, store, monitor, service
+	}
+
+	t.Run("New pools are filtered into the watchlist", func(t *testing.T) {
+		raydium, _, _, store, monitor, service := newFixture()
+		sink := &recordingSink{}
+		service.SetSignalSink(sink)
+
+		candidates, err := service.Poll(ctx)
+		require.NoError(t, err)
+		require.Len(t, candidates, 1)
+		assert.Equal(t, "GOOD", candidates[0].TokenAddress)
+
+		assert.Equal(t, models.WatchlistRejected, store.entries["THIN"].Status)
+		assert.Contains(t, store.entries["THIN"].Reason, "liquidity")
+		assert.Equal(t, models.WatchlistRejected, store.entries["RUG"].Status)
+		assert.Equal(t, 30.0, store.entries["RUG"].RiskScore)
+		// Too young to evaluate yet
+		assert.NotContains(t, store.entries, "FRESH")
+
+		require.Len(t, sink.signals, 1)
+		assert.Equal(t, models.SignalTypeBuy, sink.signals[0].Signal.SignalType)
+		assert.Equal(t, 50.0, sink.signals[0].Signal.Size)
+		assert.Equal(t, "discovery", sink.signals[0].Metadata["strategy"])
+
+		require.Len(t, monitor.events, 1)
+		assert.Equal(t, "New token candidate", monitor.events[0].Message)
+		assert.Equal(t, 4.0, monitor.metrics["discovery_pools_found"])
+		assert.Equal(t, 2.0, monitor.metrics["discovery_tokens_rejected"])
+
+		// The next poll continues from the last one
+		time.Sleep(100 * time.Millisecond)
+		candidates, err = service.Poll(ctx)
+		require.NoError(t, err)
+		require.Len(t, candidates, 1)
+		assert.Equal(t, "FRESH", candidates[0].TokenAddress)
+		assert.True(t, raydium.since[1].After(raydium.since[0]))
+
+		watchlist, err := service.Watchlist(ctx)
+		require.NoError(t, err)
+		assert.Len(t, watchlist, 2)
+	})
+
+	t.Run("Evaluated tokens are not evaluated again after a restart", func(t *testing.T) {
+		_, _, _, store, _, service := newFixture()
+		_, err := service.Poll(ctx)
+		require.NoError(t, err)
+
+		raydium, jupiter, _, _, _, _ := newFixture()
+		sink := &recordingSink{}
+		restarted := NewService(map[string]PoolSource{"raydium": raydium, "jupiter": jupiter}, nil, store, config, nil)
+		restarted.SetSignalSink(sink)
+		require.NoError(t, restarted.Load(ctx))
+
+		candidates, err := restarted.Poll(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, candidates)
+		assert.Empty(t, sink.signals)
+	})
+
+	t.Run("Failures leave pools pending", func(t *testing.T) {
+		_, jupiter, screener, store, _, service := newFixture()
+		jupiter.err = errors.New("503")
+		screener.err = errors.New("rpc unavailable")
+
+		candidates, err := service.Poll(ctx)
+		assert.ErrorContains(t, err, "jupiter: 503")
+		assert.ErrorContains(t, err, "rpc unavailable")
+		assert.Empty(t, candidates)
+		// Only the liquidity filter could be applied
+		assert.Len(t, store.entries, 1)
+
+		jupiter.err = nil
+		screener.err = nil
+		candidates, err = service.Poll(ctx)
+		require.NoError(t, err)
+		require.NotEmpty(t, candidates)
+		assert.Equal(t, "GOOD", candidates[0].TokenAddress)
+		assert.Equal(t, models.WatchlistRejected, store.entries["RUG"].Status)
+		// The first poll of a failed source still looks back
+		assert.WithinDuration(t, now.Add(-time.Hour), jupiter.since[1], time.Minute)
+	})
+}
@@ -2,23 +2,50 @@ package trading
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/leonzhao/trading-system/backend/circuitbreaker"
 	"github.com/leonzhao/trading-system/backend/dex"
 	"github.com/leonzhao/trading-system/backend/models"
 	"github.com/leonzhao/trading-system/backend/monitoring"
 	"github.com/leonzhao/trading-system/backend/repository"
 	"github.com/leonzhao/trading-system/backend/trading/concurrent"
 	"github.com/leonzhao/trading-system/backend/trading/risk"
+	"github.com/leonzhao/trading-system/backend/trading/strategy"
+	"github.com/leonzhao/trading-system/backend/trading/synthetic"
 )
 
+// ErrEntriesPaused is returned for signals received while a dependency of
+// trade execution is unavailable
+var ErrEntriesPaused = errors.New("new entries paused")
+
+// ErrStrategyNotAllowed is returned for signals of a strategy not allowed to
+// trade their token
+var ErrStrategyNotAllowed = errors.New("strategy not allowed for token")
+
+// TokenStrategies restricts the strategies trading each token.
+// watchlist.WatchList implements it.
+type TokenStrategies interface {
+	AllowsStrategy(tokenAddress, strategy string) bool
+}
+
 // Executor handles trade execution
 type Executor struct {
 	repo      repository.Repository
 	dexClient *dex.DexClient
 	processor *concurrent.Processor
+	risk      *risk.RiskManager
 	monitor   *monitoring.Monitor
+	dedup     *Deduplicator
+	baskets   *synthetic.Engine
+	promotion *strategy.PromotionPipeline
+	// strategies restricts the strategies trading each token, if set
+	strategies TokenStrategies
+	// breakers guard the dependencies new entries need
+	breakers []*circuitbreaker.Breaker
 }
 
 // NewExecutor creates a new trade executor
@@ -29,18 +56,25 @@ func NewExecutor(repo repository.Repository, dexClient *dex.DexClient, riskManag
 		ProcessTimeout: 30 * time.Second,
 	}
 
-	// Create market data service
-	marketService := dex.NewMarketDataService(
-		dex.NewRaydiumClient("https://api.raydium.io"),
-		dex.NewJupiterClient("https://api.jup.ag"),
-	)
+	// Create market data service, priced across venues
+	raydium := dex.NewRaydiumClient("https://api.raydium.io")
+	jupiter := dex.NewJupiterClient("https://api.jup.ag")
+	marketService := dex.NewMarketDataService(raydium, jupiter)
+	marketService.SetPriceFeed(dex.NewPriceFeed(map[dex.Venue]dex.PriceSource{
+		dex.VenueJupiter: dex.JupiterPriceSource(jupiter),
+		dex.VenueRaydium: dex.RaydiumPriceSource(raydium),
+	}, dex.DefaultPriceFeedConfig(), monitor))
+	// Buys are checked against the impact they would have on the live book
+	riskManager.SetDepthSource(marketService)
 	processor := concurrent.NewProcessor(config, repo, dexClient, marketService, riskManager, monitor)
 
 	return &Executor{
 		repo:      repo,
 		dexClient: dexClient,
 		processor: processor,
+		risk:      riskManager,
 		monitor:   monitor,
+		dedup:     NewDeduplicator(repo, DefaultDedupConfig(), monitor),
 	}
 }
 
@@ -49,10 +83,93 @@ func (e *Executor) Start(ctx context.Context) {
 	e.processor.Start(ctx)
 }
 
+// SetBaskets enables trading synthetic baskets. Signals on a basket are
+// executed as one child trade per constituent.
+func (e *Executor) SetBaskets(baskets *synthetic.Engine) {
+	e.baskets = baskets
+}
+
+// SetPromotion enables strategy promotion. Signals of registered strategies
+// are scaled by their allocation and skipped while they are in shadow mode.
+func (e *Executor) SetPromotion(promotion *strategy.PromotionPipeline) {
+	e.promotion = promotion
+}
+
+// SetTokenStrategies skips signals of strategies a token does not allow
+func (e *Executor) SetTokenStrategies(strategies TokenStrategies) {
+	e.strategies = strategies
+}
+
+// SetBreakers pauses new entries while any of breakers is open. Position
+// updates and risk monitoring keep running.
+func (e *Executor) SetBreakers(breakers ...*circuitbreaker.Breaker) {
+	e.breakers = breakers
+}
+
 // ExecuteSignal executes a trade signal
 func (e *Executor) ExecuteSignal(ctx context.Context, signal *models.TradeSignalMessage) error {
 	start := time.Now()
 
+	if open := circuitbreaker.Open(e.breakers...); len(open) > 0 {
+		e.monitor.RecordEvent(ctx, monitoring.Event{
+			Type:     monitoring.MetricTrading,
+			Severity: monitoring.SeverityWarning,
+			Message:  "Signal skipped while dependencies are unavailable",
+			Details: map[string]interface{}{
+				"breakers":     open,
+				"tokenAddress": signal.Signal.Symbol,
+			},
+			Timestamp: time.Now(),
+		})
+		return fmt.Errorf("%w: %s unavailable", ErrEntriesPaused, strings.Join(open, ", "))
+	}
+
+	if e.baskets != nil && e.baskets.IsBasket(signal.Signal.Symbol) {
+		return e.executeBasket(ctx, signal)
+	}
+
+	if e.strategies != nil && !e.strategies.AllowsStrategy(signal.Signal.Symbol, signalStrategy(signal)) {
+		e.monitor.RecordEvent(ctx, monitoring.Event{
+			Type:     monitoring.MetricTrading,
+			Severity: monitoring.SeverityInfo,
+			Message:  "Signal skipped for a strategy not allowed on the token",
+			Details: map[string]interface{}{
+				"strategy":     signalStrategy(signal),
+				"tokenAddress": signal.Signal.Symbol,
+			},
+			Timestamp: time.Now(),
+		})
+		return fmt.Errorf("%w: %s on %s", ErrStrategyNotAllowed, signalStrategy(signal), signal.Signal.Symbol)
+	}
+
+	if e.promotion != nil {
+		allocation, ok := e.promotion.Allocation(signalStrategy(signal))
+		if ok && allocation <= 0 {
+			e.monitor.RecordEvent(ctx, monitoring.Event{
+				Type:      monitoring.MetricTrading,
+				Severity:  monitoring.SeverityInfo,
+				Message:   "Shadow signal not executed",
+				Details: map[string]interface{}{
+					"strategy":     signalStrategy(signal),
+					"tokenAddress": signal.Signal.Symbol,
+				},
+				Timestamp: time.Now(),
+			})
+			return nil
+		}
+		if ok && allocation != 1 {
+			scaled := *signal.Signal
+			scaled.Size *= allocation
+			signal = &models.TradeSignalMessage{Signal: &scaled, Metadata: signal.Metadata, Timestamp: signal.Timestamp}
+		}
+	}
+
+	// Skip signals that were already executed, including before a restart
+	fingerprint, err := e.dedup.Check(ctx, signal.Signal, signalStrategy(signal))
+	if err != nil {
+		return err
+	}
+
 	// Create trade from signal
 	trade := &models.Trade{
 		TokenAddress:  signal.Signal.Symbol, // Using Symbol as TokenAddress
@@ -60,11 +177,43 @@ func (e *Executor) ExecuteSignal(ctx context.Context, signal *models.TradeSignal
 		Side:         mapSignalTypeToTradeSide(signal.Signal.SignalType),
 		Amount:       signal.Signal.Size,
 		Price:        signal.Signal.Price,
+		Strategy:     signalStrategy(signal),
 		Status:       models.TradeStatusPending,
 		Timestamp:    time.Now(),
 		UpdateTime:   time.Now(),
 	}
 
+	// Downsize or reject the trade by its expected market impact
+	requested := trade.Amount
+	if err := e.risk.ValidateTradeSignal(ctx, trade, nil); err != nil {
+		e.monitor.RecordEvent(ctx, monitoring.Event{
+			Type:     monitoring.MetricTrading,
+			Severity: monitoring.SeverityWarning,
+			Message:  "Signal rejected by risk validation",
+			Details: map[string]interface{}{
+				"error":        err.Error(),
+				"strategy":     trade.Strategy,
+				"tokenAddress": signal.Signal.Symbol,
+			},
+			Timestamp: time.Now(),
+		})
+		return e.releaseSignal(ctx, fingerprint, fmt.Errorf("signal rejected: %w", err))
+	}
+	if trade.Amount != requested {
+		e.monitor.RecordEvent(ctx, monitoring.Event{
+			Type:     monitoring.MetricTrading,
+			Severity: monitoring.SeverityInfo,
+			Message:  "Signal downsized by risk validation",
+			Details: map[string]interface{}{
+				"requested":    requested,
+				"amount":       trade.Amount,
+				"strategy":     trade.Strategy,
+				"tokenAddress": signal.Signal.Symbol,
+			},
+			Timestamp: time.Now(),
+		})
+	}
+
 	// Process trade with high priority
 	if err := e.processor.ProcessTrade(ctx, trade, concurrent.PriorityHigh); err != nil {
 		e.monitor.RecordEvent(ctx, monitoring.Event{
@@ -77,7 +226,7 @@ func (e *Executor) ExecuteSignal(ctx context.Context, signal *models.TradeSignal
 			},
 			Timestamp: time.Now(),
 		})
-		return fmt.Errorf("failed to process trade: %w", err)
+		return e.releaseSignal(ctx, fingerprint, fmt.Errorf("failed to process trade: %w", err))
 	}
 
 	// Record successful execution
@@ -143,6 +292,51 @@ func (e *Executor) GetHealthStatus() string {
 	return e.processor.GetHealthStatus()
 }
 
+// executeBasket executes each constituent leg of a basket signal. Legs are
+// deduplicated individually, so a partly failed basket can be retried.
+func (e *Executor) executeBasket(ctx context.Context, signal *models.TradeSignalMessage) error {
+	children, err := e.baskets.ChildSignals(*signal.Signal)
+	if err != nil {
+		return fmt.Errorf("failed to split basket signal: %w", err)
+	}
+
+	var errs []error
+	for i := range children {
+		metadata := make(map[string]interface{}, len(signal.Metadata)+1)
+		for k, v := range signal.Metadata {
+			metadata[k] = v
+		}
+		metadata["basket"] = signal.Signal.Symbol
+
+		child := &models.TradeSignalMessage{
+			Signal:    &children[i],
+			Metadata:  metadata,
+			Timestamp: signal.Timestamp,
+		}
+		if err := e.ExecuteSignal(ctx, child); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", children[i].Symbol, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// releaseSignal releases the fingerprint of a signal that failed to execute,
+// so a redelivery is not suppressed as a duplicate, and returns the failure
+func (e *Executor) releaseSignal(ctx context.Context, fingerprint *models.SignalFingerprint, failure error) error {
+	if err := e.dedup.Release(ctx, fingerprint); err != nil {
+		return errors.Join(failure, err)
+	}
+	return failure
+}
+
+// signalStrategy returns the strategy that produced a signal
+func signalStrategy(signal *models.TradeSignalMessage) string {
+	if strategy, ok := signal.Metadata["strategy"].(string); ok && strategy != "" {
+		return strategy
+	}
+	return signal.Signal.IndicatorType
+}
+
 // mapSignalTypeToTradeSide maps signal type to trade side
 func mapSignalTypeToTradeSide(signalType models.TradeSignalType) models.TradeSide {
 	switch signalType {
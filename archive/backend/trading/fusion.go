@@ -0,0 +1,334 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/monitoring"
+)
+
+// ErrNoFusionInputs is returned when neither indicator signals nor an LLM
+// analysis are available to fuse
+var ErrNoFusionInputs = errors.New("no indicator signals or LLM analysis to fuse")
+
+// LLMRiskAnalysis mirrors the risk section of the Deepseek analysis
+type LLMRiskAnalysis struct {
+	ManipulationRisk string `json:"manipulation_risk"`
+	LiquidityRisk    string `json:"liquidity_risk"`
+	VolatilityRisk   string `json:"volatility_risk"`
+}
+
+// LLMRecommendation mirrors the recommendation section of the Deepseek analysis
+type LLMRecommendation struct {
+	Action      string    `json:"action"`
+	EntryPoints []float64 `json:"entry_points"`
+	ExitPoints  []float64 `json:"exit_points"`
+	StopLoss    float64   `json:"stop_loss"`
+}
+
+// LLMAnalysis is the Deepseek market analysis for a token
+type LLMAnalysis struct {
+	Sentiment      string            `json:"sentiment"`
+	Confidence     float64           `json:"confidence"`
+	KeyFactors     []string          `json:"key_factors"`
+	RiskAnalysis   LLMRiskAnalysis   `json:"risk_analysis"`
+	Recommendation LLMRecommendation `json:"recommendation"`
+}
+
+// FusionRiskState is the portfolio risk state at the time of fusion
+type FusionRiskState struct {
+	// Drawdown is the current drawdown from peak equity as a fraction
+	Drawdown float64
+	// ExposureUtilization is open exposure as a fraction of the exposure limit
+	ExposureUtilization float64
+	// Halted blocks any directional signal
+	Halted bool
+}
+
+// FusionInput contains everything fused into one signal for a symbol
+type FusionInput struct {
+	Symbol string
+	// Price is used when no indicator signal carries a price
+	Price      float64
+	Indicators []models.TradeSignal
+	LLM        *LLMAnalysis
+	Risk       FusionRiskState
+}
+
+// FusionConfig contains signal fusion configuration
+type FusionConfig struct {
+	// IndicatorWeight and LLMWeight weight the directional scores
+	IndicatorWeight float64
+	LLMWeight       float64
+	// RiskWeight scales how much the risk penalty dampens the fused score
+	RiskWeight float64
+	// MaxDrawdown is the drawdown at which the drawdown penalty is full
+	MaxDrawdown float64
+	// MinConfidence is the fused confidence below which a hold is emitted
+	MinConfidence float64
+}
+
+// DefaultFusionConfig returns default signal fusion configuration
+func DefaultFusionConfig() FusionConfig {
+	return FusionConfig{
+		IndicatorWeight: 0.6,
+		LLMWeight:       0.4,
+		RiskWeight:      0.5,
+		MaxDrawdown:     0.2,
+		MinConfidence:   0.3,
+	}
+}
+
+// SignalFusion combines technical indicator signals, LLM sentiment and risk
+// state into a single trade signal. Indicator and LLM inputs each produce a
+// score between -1 (sell) and 1 (buy); the weighted score is dampened by the
+// risk penalty and its magnitude becomes the signal confidence.
+type SignalFusion struct {
+	config  FusionConfig
+	monitor monitoring.IMonitor
+}
+
+// NewSignalFusion creates a new signal fusion engine
+func NewSignalFusion(config FusionConfig, monitor monitoring.IMonitor) *SignalFusion {
+	if config.IndicatorWeight < 0 {
+		config.IndicatorWeight = 0
+	}
+	if config.LLMWeight < 0 {
+		config.LLMWeight = 0
+	}
+	if config.IndicatorWeight+config.LLMWeight == 0 {
+		defaults := DefaultFusionConfig()
+		config.IndicatorWeight = defaults.IndicatorWeight
+		config.LLMWeight = defaults.LLMWeight
+	}
+	config.RiskWeight = math.Max(0, math.Min(config.RiskWeight, 1))
+	if config.MaxDrawdown <= 0 {
+		config.MaxDrawdown = DefaultFusionConfig().MaxDrawdown
+	}
+
+	return &SignalFusion{
+		config:  config,
+		monitor: monitor,
+	}
+}
+
+// Fuse merges the inputs into one trade signal message. A hold signal is
+// returned when the fused confidence is below the minimum or trading is
+// halted. Size is left for the executor's position sizing.
+func (f *SignalFusion) Fuse(ctx context.Context, input FusionInput) (*models.TradeSignalMessage, error) {
+	if len(input.Indicators) == 0 && input.LLM == nil {
+		return nil, ErrNoFusionInputs
+	}
+
+	var explanation []string
+	weights := 0.0
+	weighted := 0.0
+
+	indicatorScore, indicatorCount := f.indicatorScore(input.Indicators)
+	if indicatorCount > 0 {
+		weights += f.config.IndicatorWeight
+		weighted += f.config.IndicatorWeight * indicatorScore
+		explanation = append(explanation, fmt.Sprintf("%d indicator signals scored %.2f", indicatorCount, indicatorScore))
+	}
+
+	llmScore := 0.0
+	llmRisk := 0.0
+	if input.LLM != nil {
+		llmScore = f.llmScore(input.LLM)
+		llmRisk = llmRiskLevel(input.LLM.RiskAnalysis)
+		weights += f.config.LLMWeight
+		weighted += f.config.LLMWeight * llmScore
+		explanation = append(explanation, fmt.Sprintf("LLM sentiment %q scored %.2f", input.LLM.Sentiment, llmScore))
+	}
+
+	score := 0.0
+	if weights > 0 {
+		score = weighted / weights
+	}
+
+	penalty := f.riskPenalty(input.Risk, llmRisk)
+	fused := score * (1 - f.config.RiskWeight*penalty)
+	if penalty > 0 {
+		explanation = append(explanation, fmt.Sprintf("risk penalty %.2f reduced score from %.2f to %.2f", penalty, score, fused))
+	}
+
+	confidence := math.Abs(fused)
+	signalType := models.SignalTypeBuy
+	if fused < 0 {
+		signalType = models.SignalTypeSell
+	}
+	switch {
+	case input.Risk.Halted:
+		signalType = models.Hold
+		explanation = append(explanation, "trading halted")
+	case confidence < f.config.MinConfidence:
+		signalType = models.Hold
+		explanation = append(explanation, fmt.Sprintf("confidence %.2f below minimum %.2f", confidence, f.config.MinConfidence))
+	}
+
+	signal := &models.TradeSignal{
+		Symbol:        input.Symbol,
+		SignalType:    signalType,
+		Price:         fusionPrice(input),
+		Timestamp:     time.Now(),
+		Strength:      fusedStrength(confidence),
+		Confidence:    confidence,
+		Description:   strings.Join(explanation, "; "),
+		IndicatorType: "FUSION",
+	}
+
+	details := map[string]interface{}{
+		"symbol":          input.Symbol,
+		"signal_type":     signalType,
+		"confidence":      confidence,
+		"indicator_score": indicatorScore,
+		"indicator_count": indicatorCount,
+		"llm_score":       llmScore,
+		"risk_penalty":    penalty,
+		"fused_score":     fused,
+		"explanation":     explanation,
+	}
+	if f.monitor != nil {
+		f.monitor.RecordEvent(ctx, monitoring.Event{
+			Type:     monitoring.MetricTrading,
+			Severity: monitoring.SeverityInfo,
+			Message:  "Signal fusion decision",
+			Details:  details,
+		})
+	}
+
+	return &models.TradeSignalMessage{
+		Signal: signal,
+		Metadata: map[string]interface{}{
+			"strategy":        "fusion",
+			"indicator_score": indicatorScore,
+			"llm_score":       llmScore,
+			"risk_penalty":    penalty,
+			"explanation":     explanation,
+		},
+		Timestamp: signal.Timestamp,
+	}, nil
+}
+
+// indicatorScore averages the direction of each signal weighted by its
+// strength and confidence
+func (f *SignalFusion) indicatorScore(signals []models.TradeSignal) (float64, int) {
+	total := 0.0
+	count := 0
+	for _, signal := range signals {
+		direction := signalDirection(signal.SignalType)
+		if direction == 0 {
+			continue
+		}
+		confidence := signal.Confidence
+		if confidence <= 0 {
+			confidence = 1
+		}
+		total += direction * strengthWeight(signal.Strength) * math.Min(confidence, 1)
+		count++
+	}
+	if count == 0 {
+		return 0, 0
+	}
+	return total / float64(count), count
+}
+
+// llmScore converts the LLM sentiment, or its recommended action when the
+// sentiment is neutral, into a score scaled by the LLM's confidence
+func (f *SignalFusion) llmScore(analysis *LLMAnalysis) float64 {
+	direction := sentimentDirection(analysis.Sentiment)
+	if direction == 0 {
+		direction = sentimentDirection(analysis.Recommendation.Action)
+	}
+
+	confidence := analysis.Confidence
+	// Confidence is sometimes reported as a percentage
+	if confidence > 1 {
+		confidence /= 100
+	}
+	return direction * math.Max(0, math.Min(confidence, 1))
+}
+
+// riskPenalty returns the largest of the drawdown, exposure and LLM risk
+// penalties, between 0 and 1
+func (f *SignalFusion) riskPenalty(state FusionRiskState, llmRisk float64) float64 {
+	penalty := math.Max(state.Drawdown/f.config.MaxDrawdown, state.ExposureUtilization)
+	penalty = math.Max(penalty, llmRisk)
+	return math.Max(0, math.Min(penalty, 1))
+}
+
+func signalDirection(signalType models.TradeSignalType) float64 {
+	switch signalType {
+	case models.SignalTypeBuy, models.SignalTypeOversold:
+		return 1
+	case models.SignalTypeSell, models.SignalTypeOverbought:
+		return -1
+	default:
+		return 0
+	}
+}
+
+func sentimentDirection(sentiment string) float64 {
+	switch strings.ToLower(strings.TrimSpace(sentiment)) {
+	case "bullish", "positive", "buy", "strong_buy":
+		return 1
+	case "bearish", "negative", "sell", "strong_sell":
+		return -1
+	default:
+		return 0
+	}
+}
+
+func strengthWeight(strength models.SignalStrength) float64 {
+	switch strength {
+	case models.SignalStrengthStrong:
+		return 1
+	case models.SignalStrengthWeak:
+		return 0.4
+	default:
+		return 0.7
+	}
+}
+
+// llmRiskLevel maps the highest LLM risk rating to a penalty
+func llmRiskLevel(risk LLMRiskAnalysis) float64 {
+	level := 0.0
+	for _, rating := range []string{risk.ManipulationRisk, risk.LiquidityRisk, risk.VolatilityRisk} {
+		switch strings.ToLower(strings.TrimSpace(rating)) {
+		case "high":
+			level = math.Max(level, 1)
+		case "medium":
+			level = math.Max(level, 0.5)
+		}
+	}
+	return level
+}
+
+func fusedStrength(confidence float64) models.SignalStrength {
+	switch {
+	case confidence >= 0.7:
+		return models.SignalStrengthStrong
+	case confidence >= 0.4:
+		return models.SignalStrengthMedium
+	default:
+		return models.SignalStrengthWeak
+	}
+}
+
+// fusionPrice returns the price of the most recent indicator signal
+func fusionPrice(input FusionInput) float64 {
+	price := input.Price
+	var latest time.Time
+	for _, signal := range input.Indicators {
+		if signal.Price > 0 && !signal.Timestamp.Before(latest) {
+			price = signal.Price
+			latest = signal.Timestamp
+		}
+	}
+	return price
+}
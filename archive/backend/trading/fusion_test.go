@@ -0,0 +1,94 @@
+package trading
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/leonzhao/trading-system/backend/models"
+)
+
+func TestSignalFusion(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	bullish := []models.TradeSignal{
+		{Symbol: "SOL", SignalType: models.SignalTypeOversold, Price: 99, Strength: models.SignalStrengthStrong, Confidence: 1, Timestamp: now.Add(-time.Minute)},
+		{Symbol: "SOL", SignalType: models.SignalTypeBullish, Price: 100, Strength: models.SignalStrengthStrong, Confidence: 0.8, Timestamp: now},
+	}
+
+	t.Run("combines indicators and LLM sentiment", func(t *testing.T) {
+		fusion := NewSignalFusion(DefaultFusionConfig(), nil)
+		msg, err := fusion.Fuse(ctx, FusionInput{
+			Symbol:     "SOL",
+			Indicators: bullish,
+			LLM:        &LLMAnalysis{Sentiment: "Bullish", Confidence: 50},
+		})
+		assert.NoError(t, err)
+
+		// Indicators score 0.9, LLM 0.5: 0.6*0.9 + 0.4*0.5
+		assert.Equal(t, models.SignalTypeBuy, msg.Signal.SignalType)
+		assert.InDelta(t, 0.74, msg.Signal.Confidence, 1e-9)
+		assert.Equal(t, models.SignalStrengthStrong, msg.Signal.Strength)
+		assert.Equal(t, 100.0, msg.Signal.Price)
+		assert.Equal(t, "fusion", msg.Metadata["strategy"])
+		assert.InDelta(t, 0.9, msg.Metadata["indicator_score"], 1e-9)
+		assert.InDelta(t, 0.5, msg.Metadata["llm_score"], 1e-9)
+		assert.Len(t, msg.Metadata["explanation"], 2)
+	})
+
+	t.Run("conflicting inputs produce a hold", func(t *testing.T) {
+		fusion := NewSignalFusion(FusionConfig{IndicatorWeight: 1, LLMWeight: 1, MinConfidence: 0.3}, nil)
+		msg, err := fusion.Fuse(ctx, FusionInput{
+			Symbol:     "SOL",
+			Indicators: bullish,
+			LLM:        &LLMAnalysis{Sentiment: "bearish", Confidence: 0.8},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, models.Hold, msg.Signal.SignalType)
+		assert.InDelta(t, 0.05, msg.Signal.Confidence, 1e-9)
+	})
+
+	t.Run("risk state dampens confidence", func(t *testing.T) {
+		fusion := NewSignalFusion(DefaultFusionConfig(), nil)
+		msg, err := fusion.Fuse(ctx, FusionInput{
+			Symbol:     "SOL",
+			Indicators: bullish,
+			Risk:       FusionRiskState{Drawdown: 0.1, ExposureUtilization: 0.2},
+		})
+		assert.NoError(t, err)
+		// Drawdown penalty 0.1/0.2 = 0.5, halved by the risk weight
+		assert.Equal(t, models.SignalTypeBuy, msg.Signal.SignalType)
+		assert.InDelta(t, 0.9*0.75, msg.Signal.Confidence, 1e-9)
+		assert.InDelta(t, 0.5, msg.Metadata["risk_penalty"], 1e-9)
+
+		msg, err = fusion.Fuse(ctx, FusionInput{
+			Symbol: "SOL",
+			Price:  100,
+			LLM: &LLMAnalysis{
+				Sentiment:      "neutral",
+				Confidence:     0.9,
+				RiskAnalysis:   LLMRiskAnalysis{LiquidityRisk: "High"},
+				Recommendation: LLMRecommendation{Action: "sell"},
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, models.SignalTypeSell, msg.Signal.SignalType)
+		assert.InDelta(t, 0.45, msg.Signal.Confidence, 1e-9)
+		assert.Equal(t, 100.0, msg.Signal.Price)
+	})
+
+	t.Run("halted trading always holds", func(t *testing.T) {
+		fusion := NewSignalFusion(DefaultFusionConfig(), nil)
+		msg, err := fusion.Fuse(ctx, FusionInput{Symbol: "SOL", Indicators: bullish, Risk: FusionRiskState{Halted: true}})
+		assert.NoError(t, err)
+		assert.Equal(t, models.Hold, msg.Signal.SignalType)
+	})
+
+	t.Run("requires inputs", func(t *testing.T) {
+		fusion := NewSignalFusion(DefaultFusionConfig(), nil)
+		_, err := fusion.Fuse(ctx, FusionInput{Symbol: "SOL"})
+		assert.ErrorIs(t, err, ErrNoFusionInputs)
+	})
+}
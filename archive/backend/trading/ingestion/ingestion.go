@@ -0,0 +1,487 @@
+// Package ingestion polls DEXes for the market data of configured tokens on
+// a schedule and writes it to the repository and the market data cache, so
+// that consumers read shared data instead of each fetching their own.
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/dex"
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/monitoring"
+)
+
+// Source fetches market data from a DEX. dex.DexClient implements it.
+type Source interface {
+	GetMarketData(ctx context.Context, tokenAddress string) (*dex.MarketData, error)
+	GetOrderBook(ctx context.Context, tokenAddress string) (*dex.OrderBook, error)
+}
+
+// Store persists market data. repository.Repository implements it.
+type Store interface {
+	UpsertMarketData(ctx context.Context, data *models.MarketData) error
+}
+
+// Cache serves the latest market data to consumers.
+// cache.TieredMarketDataCache implements it.
+type Cache interface {
+	Put(ctx context.Context, data *models.MarketData) error
+}
+
+// ErrRejected is returned for polls whose market data the validator rejected
+var ErrRejected = errors.New("market data rejected")
+
+// ErrNotRunning is returned when restarting a service that is not running
+var ErrNotRunning = errors.New("ingestion service not running")
+
+// Validator checks market data before it is written, rejecting it with an
+// error. anomaly.Detector implements it.
+type Validator interface {
+	Check(ctx context.Context, data *models.MarketData) error
+}
+
+// Config contains market data ingestion configuration
+type Config struct {
+	// Tokens are the token addresses polled
+	Tokens       []string      `json:"tokens"`
+	PollInterval time.Duration `json:"poll_interval"`
+	// PollIntervals override the poll interval of tokens
+	PollIntervals map[string]time.Duration `json:"poll_intervals"`
+	// OrderBookEvery fetches the order book on every nth poll of a token,
+	// unless the source returns it with the market data. Zero disables
+	// order books.
+	OrderBookEvery int `json:"order_book_every"`
+	// Workers bounds the concurrent requests to the source
+	Workers int `json:"workers"`
+	// QueueSize bounds the polls waiting for a worker. Polls that do not fit
+	// are skipped until the next tick instead of piling up.
+	QueueSize    int           `json:"queue_size"`
+	FetchTimeout time.Duration `json:"fetch_timeout"`
+}
+
+// DefaultConfig returns default market data ingestion configuration
+func DefaultConfig() Config {
+	return Config{
+		PollInterval:   5 * time.Second,
+		OrderBookEvery: 6,
+		Workers:        4,
+		QueueSize:      64,
+		FetchTimeout:   10 * time.Second,
+	}
+}
+
+// TokenStats contains the ingestion state of a token
+type TokenStats struct {
+	LastSuccess time.Time `json:"last_success"`
+	LastError   string    `json:"last_error,omitempty"`
+	// Lag is the time from scheduling the last successful poll to writing
+	// its data, including the wait for a worker
+	Lag      time.Duration `json:"lag"`
+	Failures int64         `json:"failures"`
+	// Rejected counts the polls whose data the validator rejected
+	Rejected int64 `json:"rejected"`
+}
+
+// Stats contains ingestion statistics
+type Stats struct {
+	// Backlog is the number of polls waiting for a worker
+	Backlog  int   `json:"backlog"`
+	InFlight int   `json:"in_flight"`
+	Polls    int64 `json:"polls"`
+	Failures int64 `json:"failures"`
+	// Rejected counts the polls whose data the validator rejected. They
+	// are not counted as failures.
+	Rejected int64 `json:"rejected"`
+	// Skipped counts polls not scheduled because the previous poll of the
+	// token had not finished or the queue was full
+	Skipped int64                  `json:"skipped"`
+	Tokens  map[string]*TokenStats `json:"tokens"`
+}
+
+// job is a scheduled poll of a token
+type job struct {
+	tokenAddress string
+	orderBook    bool
+	scheduled    time.Time
+}
+
+// Service ingests market data. Every tick schedules one poll per token onto
+// a bounded queue drained by a fixed pool of workers. A token is never
+// queued twice, so a slow source delays its data rather than growing the
+// backlog.
+type Service struct {
+	source    Source
+	store     Store
+	cache     Cache
+	validator Validator
+	config    Config
+	monitor   monitoring.IMonitor
+	queue     chan job
+	// pending holds the tokens queued or in flight
+	pending map[string]bool
+	polls   map[string]int
+	// scheduled holds the time the last poll of each token was queued
+	scheduled map[string]time.Time
+	stats     Stats
+	// restart stops the workers of the running service
+	restart context.CancelFunc
+	mu      sync.Mutex
+}
+
+// NewService creates a market data ingestion service. The cache and monitor
+// may be nil.
+func NewService(source Source, store Store, cache Cache, config Config, monitor monitoring.IMonitor) *Service {
+	defaults := DefaultConfig()
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaults.PollInterval
+	}
+	if config.Workers <= 0 {
+		config.Workers = defaults.Workers
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaults.QueueSize
+	}
+	if config.FetchTimeout <= 0 {
+		config.FetchTimeout = defaults.FetchTimeout
+	}
+
+	return &Service{
+		source:    source,
+		store:     store,
+		cache:     cache,
+		config:    config,
+		monitor:   monitor,
+		queue:     make(chan job, config.QueueSize),
+		pending:   make(map[string]bool),
+		polls:     make(map[string]int),
+		scheduled: make(map[string]time.Time),
+		stats:     Stats{Tokens: make(map[string]*TokenStats)},
+	}
+}
+
+// SetValidator checks the market data of every poll with the validator,
+// keeping rejected data out of the store and the cache
+func (s *Service) SetValidator(validator Validator) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.validator = validator
+}
+
+// SetTokens replaces the polled tokens from the next tick on
+func (s *Service) SetTokens(tokens []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.Tokens = append([]string(nil), tokens...)
+}
+
+// SetPollIntervals replaces the poll interval overrides of tokens from the
+// next tick on
+func (s *Service) SetPollIntervals(intervals map[string]time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.PollIntervals = make(map[string]time.Duration, len(intervals))
+	for token, interval := range intervals {
+		s.config.PollIntervals[token] = interval
+	}
+}
+
+// Stats returns ingestion statistics
+func (s *Service) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.stats
+	stats.Backlog = len(s.queue)
+	stats.InFlight = max(len(s.pending)-stats.Backlog, 0)
+	stats.Tokens = make(map[string]*TokenStats, len(s.stats.Tokens))
+	for token, t := range s.stats.Tokens {
+		copied := *t
+		stats.Tokens[token] = &copied
+	}
+	return stats
+}
+
+// Run schedules polls until the context is done, then waits for the workers
+// to stop. Restart starts the workers again.
+func (s *Service) Run(ctx context.Context) {
+	defer func() {
+		s.mu.Lock()
+		s.restart = nil
+		s.mu.Unlock()
+	}()
+	for ctx.Err() == nil {
+		runCtx, cancel := context.WithCancel(ctx)
+		s.mu.Lock()
+		s.restart = cancel
+		s.mu.Unlock()
+		s.run(runCtx)
+		cancel()
+	}
+}
+
+// Restart abandons the polls in flight and starts new workers, unsticking a
+// feed whose requests hang. Queued polls are kept. It fails unless Run is
+// running.
+func (s *Service) Restart(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.restart == nil {
+		return ErrNotRunning
+	}
+	s.restart()
+	return nil
+}
+
+// LastSuccess returns the time of the last successful poll of any token,
+// zero before the first one
+func (s *Service) LastSuccess() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var last time.Time
+	for _, t := range s.stats.Tokens {
+		if t.LastSuccess.After(last) {
+			last = t.LastSuccess
+		}
+	}
+	return last
+}
+
+func (s *Service) run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < s.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.work(ctx)
+		}()
+	}
+	defer wg.Wait()
+
+	interval := s.tick()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.schedule(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Speed up or slow down with the shortest poll interval override
+		if next := s.tick(); next != interval {
+			interval = next
+			ticker.Reset(interval)
+		}
+	}
+}
+
+// tick returns the scheduling period, the shortest of the poll interval and
+// its overrides
+func (s *Service) tick() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tickLocked()
+}
+
+func (s *Service) tickLocked() time.Duration {
+	tick := s.config.PollInterval
+	for _, interval := range s.config.PollIntervals {
+		if interval > 0 && interval < tick {
+			tick = interval
+		}
+	}
+	return tick
+}
+
+// due reports whether a token is due for a poll. Tokens are polled on every
+// tick unless they have an interval override or the tick is faster than the
+// poll interval. The caller holds the lock.
+func (s *Service) due(token string, now time.Time, tick time.Duration) bool {
+	interval := s.config.PollIntervals[token]
+	if interval <= 0 {
+		if tick >= s.config.PollInterval {
+			return true
+		}
+		interval = s.config.PollInterval
+	}
+	last, ok := s.scheduled[token]
+	// Allow for ticks arriving a little early
+	return !ok || now.Sub(last) >= interval-tick/2
+}
+
+// schedule queues a poll of every due token without one pending and returns
+// the number queued
+func (s *Service) schedule(ctx context.Context, now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tick := s.tickLocked()
+	queued, skipped := 0, 0
+	for _, token := range s.config.Tokens {
+		if !s.due(token, now, tick) {
+			continue
+		}
+		if s.pending[token] {
+			skipped++
+			continue
+		}
+
+		s.polls[token]++
+		j := job{
+			tokenAddress: token,
+			orderBook:    s.config.OrderBookEvery > 0 && (s.polls[token]-1)%s.config.OrderBookEvery == 0,
+			scheduled:    now,
+		}
+		select {
+		case s.queue <- j:
+			s.pending[token] = true
+			s.scheduled[token] = now
+			queued++
+		default:
+			s.polls[token]--
+			skipped++
+		}
+	}
+	s.stats.Skipped += int64(skipped)
+
+	s.recordMetric(ctx, "ingestion_backlog", float64(len(s.queue)), nil)
+	if skipped > 0 {
+		s.recordMetric(ctx, "ingestion_polls_skipped", float64(skipped), nil)
+	}
+	return queued
+}
+
+func (s *Service) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case j := <-s.queue:
+			err := s.poll(ctx, j)
+			s.finish(ctx, j, err, time.Now())
+		}
+	}
+}
+
+// poll fetches the market data of a token and writes it to the store and
+// the cache
+func (s *Service) poll(ctx context.Context, j job) error {
+	ctx, cancel := context.WithTimeout(ctx, s.config.FetchTimeout)
+	defer cancel()
+
+	data, err := s.source.GetMarketData(ctx, j.tokenAddress)
+	if err != nil {
+		return fmt.Errorf("failed to fetch market data: %w", err)
+	}
+	book := data.OrderBook
+	if j.orderBook && book == nil {
+		if book, err = s.source.GetOrderBook(ctx, j.tokenAddress); err != nil {
+			return fmt.Errorf("failed to fetch order book: %w", err)
+		}
+	}
+
+	record := toMarketData(j.tokenAddress, data, book)
+	s.mu.Lock()
+	validator := s.validator
+	s.mu.Unlock()
+	if validator != nil {
+		if err := validator.Check(ctx, record); err != nil {
+			return fmt.Errorf("%w: %v", ErrRejected, err)
+		}
+	}
+	if err := s.store.UpsertMarketData(ctx, record); err != nil {
+		return fmt.Errorf("failed to save market data: %w", err)
+	}
+	if s.cache != nil {
+		if err := s.cache.Put(ctx, record); err != nil {
+			return fmt.Errorf("failed to cache market data: %w", err)
+		}
+	}
+	return nil
+}
+
+// finish records the outcome of a poll and releases its token for the next
+// tick
+func (s *Service) finish(ctx context.Context, j job, err error, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.pending, j.tokenAddress)
+	s.stats.Polls++
+
+	t, ok := s.stats.Tokens[j.tokenAddress]
+	if !ok {
+		t = &TokenStats{}
+		s.stats.Tokens[j.tokenAddress] = t
+	}
+	tags := map[string]string{"token": j.tokenAddress}
+
+	if errors.Is(err, ErrRejected) {
+		s.stats.Rejected++
+		t.Rejected++
+		t.LastError = err.Error()
+		s.recordMetric(ctx, "ingestion_polls_rejected", 1, tags)
+		return
+	}
+	if err != nil {
+		s.stats.Failures++
+		t.Failures++
+		t.LastError = err.Error()
+		s.recordMetric(ctx, "ingestion_poll_failures", 1, tags)
+		return
+	}
+	t.LastSuccess = now
+	t.LastError = ""
+	t.Lag = now.Sub(j.scheduled)
+	s.recordMetric(ctx, "ingestion_lag_seconds", t.Lag.Seconds(), tags)
+}
+
+func (s *Service) recordMetric(ctx context.Context, name string, value float64, tags map[string]string) {
+	if s.monitor == nil {
+		return
+	}
+	s.monitor.RecordMetric(ctx, name, value, tags)
+}
+
+// toMarketData converts DEX market data to its stored form. Order book
+// levels are stored as price and size pairs.
+func toMarketData(tokenAddress string, data *dex.MarketData, book *dex.OrderBook) *models.MarketData {
+	record := &models.MarketData{
+		TokenAddress: tokenAddress,
+		ClosePrice:   data.Price,
+		Volume24h:    data.Volume24h,
+		MarketCap:    data.MarketCap,
+		Liquidity:    data.Liquidity,
+		PriceImpact:  data.PriceImpact,
+		Timestamp:    data.Timestamp,
+	}
+	if record.Timestamp.IsZero() {
+		record.Timestamp = time.Now()
+	}
+	if book != nil {
+		record.OrderBook.Bids = levels(book.Bids)
+		record.OrderBook.Asks = levels(book.Asks)
+	}
+	return record
+}
+
+func levels(items []dex.OrderBookItem) [][]float64 {
+	levels := make([][]float64, len(items))
+	for i, item := range items {
+		size := item.Size
+		if size <= 0 {
+			// Some venues report the size as amount
+			size = item.Amount
+		}
+		levels[i] = []float64{item.Price, size}
+	}
+	return levels
+}
@@ -0,0 +1,262 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/leonzhao/trading-system/backend/dex"
+	"github.com/leonzhao/trading-system/backend/models"
+)
+
+type fakeSource struct {
+	prices map[string]float64
+	book   *dex.OrderBook
+	err    error
+	mu     sync.Mutex
+	books  int
+}
+
+func (s *fakeSource) GetMarketData(ctx context.Context, tokenAddress string) (*dex.MarketData, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &dex.MarketData{TokenAddress: tokenAddress, Price: s.prices[tokenAddress], Timestamp: time.Now()}, nil
+}
+
+func (s *fakeSource) GetOrderBook(ctx context.Context, tokenAddress string) (*dex.OrderBook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.books++
+	return s.book, nil
+}
+
+type memoryStore struct {
+	data map[string]*models.MarketData
+	mu   sync.Mutex
+}
+
+func (m *memoryStore) UpsertMarketData(ctx context.Context, data *models.MarketData) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[data.TokenAddress] = data
+	return nil
+}
+
+func (m *memoryStore) Put(ctx context.Context, data *models.MarketData) error {
+	return m.UpsertMarketData(ctx, data)
+}
+
+func (m *memoryStore) get(tokenAddress string) *models.MarketData {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.data[tokenAddress]
+}
+
+func TestServiceIngestsMarketData(t *testing.T) {
+	source := &fakeSource{
+		prices: map[string]float64{"token-a": 1.5, "token-b": 2.5},
+		book: &dex.OrderBook{
+			Bids: []dex.OrderBookItem{{Price: 1.4, Size: 10}},
+			Asks: []dex.OrderBookItem{{Price: 1.6, Amount: 20}},
+		},
+	}
+	store := &memoryStore{data: make(map[string]*models.MarketData)}
+	cache := &memoryStore{data: make(map[string]*models.MarketData)}
+
+	s := NewService(source, store, cache, Config{
+		Tokens:         []string{"token-a", "token-b"},
+		PollInterval:   time.Hour,
+		OrderBookEvery: 1,
+	}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	require.Eventually(t, func() bool {
+		return store.get("token-a") != nil && store.get("token-b") != nil && cache.get("token-b") != nil
+	}, time.Second, 5*time.Millisecond)
+	cancel()
+	<-done
+
+	data := store.get("token-a")
+	assert.Equal(t, 1.5, data.ClosePrice)
+	assert.Equal(t, [][]float64{{1.4, 10}}, data.OrderBook.Bids)
+	assert.Equal(t, [][]float64{{1.6, 20}}, data.OrderBook.Asks)
+
+	stats := s.Stats()
+	assert.Equal(t, int64(2), stats.Polls)
+	assert.Zero(t, stats.InFlight)
+	require.Contains(t, stats.Tokens, "token-a")
+	assert.False(t, stats.Tokens["token-a"].LastSuccess.IsZero())
+}
+
+func TestScheduleBackpressure(t *testing.T) {
+	s := NewService(&fakeSource{}, &memoryStore{}, nil, Config{
+		Tokens:    []string{"token-a", "token-b"},
+		QueueSize: 1,
+	}, nil)
+	ctx := context.Background()
+
+	assert.Equal(t, 1, s.schedule(ctx, time.Now()))
+	assert.Equal(t, 0, s.schedule(ctx, time.Now()), "pending token and full queue are skipped")
+
+	stats := s.Stats()
+	assert.Equal(t, 1, stats.Backlog)
+	assert.Equal(t, int64(3), stats.Skipped)
+
+	j := <-s.queue
+	s.finish(ctx, j, nil, time.Now())
+	assert.Equal(t, 1, s.schedule(ctx, time.Now()), "released token is queued again")
+}
+
+func TestScheduleOrderBookCadence(t *testing.T) {
+	s := NewService(&fakeSource{}, &memoryStore{}, nil, Config{
+		Tokens:         []string{"token-a"},
+		OrderBookEvery: 2,
+	}, nil)
+	ctx := context.Background()
+
+	var books []bool
+	for i := 0; i < 4; i++ {
+		s.schedule(ctx, time.Now())
+		j := <-s.queue
+		books = append(books, j.orderBook)
+		s.finish(ctx, j, nil, time.Now())
+	}
+	assert.Equal(t, []bool{true, false, true, false}, books)
+}
+
+func TestSchedulePollIntervals(t *testing.T) {
+	s := NewService(&fakeSource{}, &memoryStore{}, nil, Config{
+		Tokens:       []string{"fast", "default", "slow"},
+		PollInterval: 10 * time.Second,
+	}, nil)
+	s.SetPollIntervals(map[string]time.Duration{"fast": 5 * time.Second, "slow": 30 * time.Second})
+	assert.Equal(t, 5*time.Second, s.tick(), "ticks with the shortest interval")
+	ctx := context.Background()
+
+	start := time.Now()
+	var polled []string
+	for i := 0; i < 7; i++ {
+		s.schedule(ctx, start.Add(time.Duration(i)*5*time.Second))
+		for len(s.queue) > 0 {
+			j := <-s.queue
+			polled = append(polled, j.tokenAddress)
+			s.finish(ctx, j, nil, j.scheduled)
+		}
+	}
+	count := func(token string) (n int) {
+		for _, p := range polled {
+			if p == token {
+				n++
+			}
+		}
+		return n
+	}
+	assert.Equal(t, 7, count("fast"))
+	assert.Equal(t, 4, count("default"))
+	assert.Equal(t, 2, count("slow"))
+	assert.Zero(t, s.Stats().Skipped, "tokens not due are not skipped polls")
+
+	s.SetPollIntervals(nil)
+	assert.Equal(t, 10*time.Second, s.tick())
+}
+
+func TestPollFailure(t *testing.T) {
+	store := &memoryStore{data: make(map[string]*models.MarketData)}
+	s := NewService(&fakeSource{err: errors.New("rate limited")}, store, nil, Config{
+		Tokens: []string{"token-a"},
+	}, nil)
+	ctx := context.Background()
+
+	s.schedule(ctx, time.Now())
+	j := <-s.queue
+	err := s.poll(ctx, j)
+	require.Error(t, err)
+	s.finish(ctx, j, err, time.Now())
+
+	stats := s.Stats()
+	assert.Equal(t, int64(1), stats.Failures)
+	assert.Contains(t, stats.Tokens["token-a"].LastError, "rate limited")
+	assert.Nil(t, store.get("token-a"))
+}
+
+type rejectingValidator struct{}
+
+func (rejectingValidator) Check(ctx context.Context, data *models.MarketData) error {
+	return errors.New("price spike")
+}
+
+func TestPollRejected(t *testing.T) {
+	store := &memoryStore{data: make(map[string]*models.MarketData)}
+	s := NewService(&fakeSource{prices: map[string]float64{"token-a": 1.5}}, store, nil, Config{
+		Tokens: []string{"token-a"},
+	}, nil)
+	s.SetValidator(rejectingValidator{})
+	ctx := context.Background()
+
+	s.schedule(ctx, time.Now())
+	j := <-s.queue
+	err := s.poll(ctx, j)
+	require.ErrorIs(t, err, ErrRejected)
+	s.finish(ctx, j, err, time.Now())
+
+	stats := s.Stats()
+	assert.Equal(t, int64(1), stats.Rejected)
+	assert.Zero(t, stats.Failures)
+	assert.Contains(t, stats.Tokens["token-a"].LastError, "price spike")
+	assert.Nil(t, store.get("token-a"), "rejected data is not stored")
+}
+
+// hangingSource hangs on its first request until the request is cancelled
+type hangingSource struct {
+	fakeSource
+	hung atomic.Bool
+}
+
+func (s *hangingSource) GetMarketData(ctx context.Context, tokenAddress string) (*dex.MarketData, error) {
+	if !s.hung.Swap(true) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return s.fakeSource.GetMarketData(ctx, tokenAddress)
+}
+
+func TestRestart(t *testing.T) {
+	store := &memoryStore{data: make(map[string]*models.MarketData)}
+	s := NewService(&hangingSource{fakeSource: fakeSource{prices: map[string]float64{"token-a": 1.5}}}, store, nil, Config{
+		Tokens:       []string{"token-a"},
+		PollInterval: 10 * time.Millisecond,
+		Workers:      1,
+		FetchTimeout: time.Hour,
+	}, nil)
+	require.ErrorIs(t, s.Restart(context.Background()), ErrNotRunning)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	require.Eventually(t, func() bool { return s.Stats().InFlight == 1 }, time.Second, 5*time.Millisecond)
+	assert.True(t, s.LastSuccess().IsZero())
+
+	require.NoError(t, s.Restart(ctx))
+	require.Eventually(t, func() bool { return !s.LastSuccess().IsZero() }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, int64(1), s.Stats().Failures, "the hung poll is abandoned")
+	assert.NotNil(t, store.get("token-a"))
+
+	cancel()
+	<-done
+	assert.ErrorIs(t, s.Restart(context.Background()), ErrNotRunning)
+}
@@ -0,0 +1,801 @@
+// Package pipeline schedules the periodic analysis of watched tokens: fetch
+// market data, compute technical indicators, run the LLM analysis, fuse the
+// signals and persist the analysis result.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/circuitbreaker"
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/monitoring"
+	"github.com/leonzhao/trading-system/backend/trading"
+	"github.com/leonzhao/trading-system/backend/trading/analysis"
+	"github.com/leonzhao/trading-system/backend/trading/sentiment"
+)
+
+var (
+	// ErrNoMarketData is returned when a token has no stored market data
+	ErrNoMarketData = errors.New("no market data")
+	// ErrStaleData is returned when the latest market data of a token is
+	// older than the maximum data age
+	ErrStaleData = errors.New("market data is stale")
+	// ErrNoNewData is returned when a token has no market data newer than
+	// its last analysis
+	ErrNoNewData = errors.New("no new market data since last analysis")
+)
+
+// Stages of the pipeline
+const (
+	StageFetch      = "fetch"
+	StageIndicators = "indicators"
+	StageLLM        = "llm"
+	StageFusion     = "fusion"
+	StagePersist    = "persist"
+)
+
+// Outcome is the result of one pipeline run of a token
+type Outcome string
+
+const (
+	OutcomeAnalyzed Outcome = "analyzed"
+	OutcomeFailed   Outcome = "failed"
+	// OutcomeStale and OutcomeUnchanged skip tokens whose data is too old
+	// or was already analyzed
+	OutcomeStale     Outcome = "stale"
+	OutcomeUnchanged Outcome = "unchanged"
+	// OutcomeBusy skips tokens still running or without a free slot
+	OutcomeBusy Outcome = "busy"
+)
+
+// Store reads market data and persists analysis results.
+// repository.Repository implements it.
+type Store interface {
+	// GetHistoricalMarketData returns the latest market data, newest first
+	GetHistoricalMarketData(ctx context.Context, tokenAddress string, limit int) ([]*models.MarketData, error)
+	SaveAnalysisResult(ctx context.Context, result *models.AnalysisResult) error
+}
+
+// Watchlist lists discovered tokens. repository.Repository implements it.
+type Watchlist interface {
+	ListWatchlist(ctx context.Context, filter *models.WatchlistFilter) ([]*models.WatchlistEntry, error)
+}
+
+// Indicators are the technical indicators of a token
+type Indicators struct {
+	RSI      float64 `json:"rsi"`
+	MACD     float64 `json:"macd"`
+	MACDHist float64 `json:"macd_hist"`
+	// Volatility is the standard deviation of the candle returns
+	Volatility float64 `json:"volatility"`
+	// Momentum is the price change over the momentum period as a fraction
+	Momentum float64              `json:"momentum"`
+	Signals  []models.TradeSignal `json:"signals"`
+}
+
+// AnalystInput is the data an LLM analysis is based on
+type AnalystInput struct {
+	TokenAddress string
+	// Candles are the market data, oldest first
+	Candles    []models.MarketData
+	Indicators Indicators
+}
+
+// Analyst runs the LLM analysis of a token
+type Analyst interface {
+	Analyze(ctx context.Context, input AnalystInput) (*trading.LLMAnalysis, error)
+}
+
+type breakerAnalyst struct {
+	analyst Analyst
+	breaker *circuitbreaker.Breaker
+}
+
+// WithBreaker guards an analyst with a circuit breaker. While it is open the
+// LLM stage fails fast and signals fall back to the indicators.
+func WithBreaker(analyst Analyst, breaker *circuitbreaker.Breaker) Analyst {
+	return &breakerAnalyst{analyst: analyst, breaker: breaker}
+}
+
+func (a *breakerAnalyst) Analyze(ctx context.Context, input AnalystInput) (*trading.LLMAnalysis, error) {
+	var analysis *trading.LLMAnalysis
+	err := a.breaker.Execute(func() (err error) {
+		analysis, err = a.analyst.Analyze(ctx, input)
+		return err
+	})
+	return analysis, err
+}
+
+// RiskSource returns the portfolio risk state fused into signals
+type RiskSource func(ctx context.Context) (trading.FusionRiskState, error)
+
+// SentimentSource returns the rolling social sentiment of a token.
+// sentiment.Service implements it.
+type SentimentSource interface {
+	Sentiment(token string) (sentiment.Score, bool)
+}
+
+// StageTimeouts bound each stage of a run
+type StageTimeouts struct {
+	Fetch      time.Duration `json:"fetch"`
+	Indicators time.Duration `json:"indicators"`
+	LLM        time.Duration `json:"llm"`
+	Fusion     time.Duration `json:"fusion"`
+	Persist    time.Duration `json:"persist"`
+}
+
+// Config contains analysis pipeline configuration
+type Config struct {
+	// Tokens are analyzed in addition to the watching tokens of the
+	// watchlist
+	Tokens   []string      `json:"tokens"`
+	Interval time.Duration `json:"interval"`
+	// Candles is the number of market data points analyzed
+	Candles int `json:"candles"`
+	// MaxDataAge skips tokens whose latest market data is older
+	MaxDataAge time.Duration `json:"max_data_age"`
+	// Concurrency bounds the tokens analyzed at once. Tokens without a free
+	// slot are skipped until the next tick.
+	Concurrency int           `json:"concurrency"`
+	Timeouts    StageTimeouts `json:"timeouts"`
+}
+
+// DefaultConfig returns default analysis pipeline configuration
+func DefaultConfig() Config {
+	return Config{
+		Interval:    time.Minute,
+		Candles:     200,
+		MaxDataAge:  5 * time.Minute,
+		Concurrency: 4,
+		Timeouts: StageTimeouts{
+			Fetch:      10 * time.Second,
+			Indicators: 5 * time.Second,
+			LLM:        60 * time.Second,
+			Fusion:     5 * time.Second,
+			Persist:    10 * time.Second,
+		},
+	}
+}
+
+// TokenStats contains the pipeline state of a token
+type TokenStats struct {
+	LastRun      time.Time     `json:"last_run"`
+	LastAnalyzed time.Time     `json:"last_analyzed"`
+	LastOutcome  Outcome       `json:"last_outcome"`
+	LastError    string        `json:"last_error,omitempty"`
+	Duration     time.Duration `json:"duration"`
+	Failures     int64         `json:"failures"`
+}
+
+// Stats contains pipeline statistics
+type Stats struct {
+	Running  int                    `json:"running"`
+	Runs     int64                  `json:"runs"`
+	Analyzed int64                  `json:"analyzed"`
+	Skipped  int64                  `json:"skipped"`
+	Failures int64                  `json:"failures"`
+	Tokens   map[string]*TokenStats `json:"tokens"`
+}
+
+// momentumPeriod is the number of candles momentum is measured over
+const momentumPeriod = 10
+
+// Scheduler runs the analysis pipeline of every token on each tick. A token
+// is never analyzed twice at once, and tokens whose data is stale or
+// unchanged since their last analysis are skipped.
+type Scheduler struct {
+	store     Store
+	watchlist Watchlist
+	analyst   Analyst
+	fusion    *trading.SignalFusion
+	risk      RiskSource
+	sentiment SentimentSource
+	config    Config
+	monitor   monitoring.IMonitor
+	slots     chan struct{}
+	running   map[string]bool
+	// analyzed holds the timestamp of the latest data analyzed per token
+	analyzed map[string]time.Time
+	// cursor rotates the token scheduled first, so that tokens late in the
+	// list are not starved when slots run out
+	cursor int
+	stats  Stats
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+}
+
+// NewScheduler creates an analysis pipeline scheduler. The watchlist,
+// analyst and monitor may be nil; without an analyst signals are fused
+// from the indicators only.
+func NewScheduler(store Store, watchlist Watchlist, analyst Analyst, fusion *trading.SignalFusion, config Config, monitor monitoring.IMonitor) *Scheduler {
+	defaults := DefaultConfig()
+	if config.Interval <= 0 {
+		config.Interval = defaults.Interval
+	}
+	if config.Candles <= 0 {
+		config.Candles = defaults.Candles
+	}
+	if config.MaxDataAge <= 0 {
+		config.MaxDataAge = defaults.MaxDataAge
+	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = defaults.Concurrency
+	}
+	config.Timeouts = config.Timeouts.withDefaults(defaults.Timeouts)
+	if fusion == nil {
+		fusion = trading.NewSignalFusion(trading.DefaultFusionConfig(), monitor)
+	}
+
+	return &Scheduler{
+		store:     store,
+		watchlist: watchlist,
+		analyst:   analyst,
+		fusion:    fusion,
+		config:    config,
+		monitor:   monitor,
+		slots:     make(chan struct{}, config.Concurrency),
+		running:   make(map[string]bool),
+		analyzed:  make(map[string]time.Time),
+		stats:     Stats{Tokens: make(map[string]*TokenStats)},
+	}
+}
+
+func (t StageTimeouts) withDefaults(defaults StageTimeouts) StageTimeouts {
+	for _, d := range []struct {
+		value    *time.Duration
+		fallback time.Duration
+	}{
+		{&t.Fetch, defaults.Fetch},
+		{&t.Indicators, defaults.Indicators},
+		{&t.LLM, defaults.LLM},
+		{&t.Fusion, defaults.Fusion},
+		{&t.Persist, defaults.Persist},
+	} {
+		if *d.value <= 0 {
+			*d.value = d.fallback
+		}
+	}
+	return t
+}
+
+// SetTokens replaces the configured tokens from the next tick on
+func (s *Scheduler) SetTokens(tokens []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.Tokens = append([]string(nil), tokens...)
+}
+
+// SetRiskSource sets the source of the risk state fused into signals
+func (s *Scheduler) SetRiskSource(risk RiskSource) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.risk = risk
+}
+
+// SetSentimentSource sets the source of the social sentiment merged into
+// analysis results
+func (s *Scheduler) SetSentimentSource(source SentimentSource) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sentiment = source
+}
+
+// Stats returns pipeline statistics
+func (s *Scheduler) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.stats
+	stats.Running = len(s.running)
+	stats.Tokens = make(map[string]*TokenStats, len(s.stats.Tokens))
+	for token, t := range s.stats.Tokens {
+		copied := *t
+		stats.Tokens[token] = &copied
+	}
+	return stats
+}
+
+// Run starts a pipeline run for every token on each tick until the context
+// is done, then waits for the running ones to stop
+func (s *Scheduler) Run(ctx context.Context) {
+	defer s.wg.Wait()
+
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.schedule(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// schedule starts a run for every token not running, as long as slots are
+// free, and returns the number started
+func (s *Scheduler) schedule(ctx context.Context) int {
+	tokens := s.tokens(ctx)
+	if len(tokens) > 0 {
+		s.mu.Lock()
+		first := s.cursor % len(tokens)
+		s.mu.Unlock()
+		tokens = append(append(make([]string, 0, len(tokens)), tokens[first:]...), tokens[:first]...)
+	}
+
+	started := 0
+	for _, token := range tokens {
+		if !s.acquire(token) {
+			s.record(ctx, token, OutcomeBusy, nil, 0)
+			continue
+		}
+		started++
+
+		s.wg.Add(1)
+		go func(token string) {
+			defer s.wg.Done()
+			defer s.release(token)
+
+			start := time.Now()
+			_, err := s.analyze(ctx, token)
+			s.record(ctx, token, outcomeOf(err), err, time.Since(start))
+		}(token)
+	}
+	s.mu.Lock()
+	s.cursor += started
+	s.mu.Unlock()
+
+	s.recordMetric(ctx, "pipeline_running", float64(s.Stats().Running), nil)
+	return started
+}
+
+// RunOnce runs the pipeline of a token immediately, unless it is already
+// running, and returns the persisted analysis
+func (s *Scheduler) RunOnce(ctx context.Context, tokenAddress string) (*models.AnalysisResult, error) {
+	s.mu.Lock()
+	if s.running[tokenAddress] {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("analysis of %s already running", tokenAddress)
+	}
+	s.running[tokenAddress] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, tokenAddress)
+		s.mu.Unlock()
+	}()
+
+	start := time.Now()
+	result, err := s.analyze(ctx, tokenAddress)
+	s.record(ctx, tokenAddress, outcomeOf(err), err, time.Since(start))
+	return result, err
+}
+
+// tokens returns the configured tokens followed by the watching tokens of
+// the watchlist, without duplicates
+func (s *Scheduler) tokens(ctx context.Context) []string {
+	s.mu.Lock()
+	tokens := append([]string(nil), s.config.Tokens...)
+	s.mu.Unlock()
+
+	if s.watchlist != nil {
+		entries, err := s.watchlist.ListWatchlist(ctx, &models.WatchlistFilter{Status: models.WatchlistWatching})
+		if err != nil {
+			s.recordEvent(ctx, monitoring.SeverityWarning, "Failed to list watchlist for analysis", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+		for _, entry := range entries {
+			tokens = append(tokens, entry.TokenAddress)
+		}
+	}
+
+	seen := make(map[string]bool, len(tokens))
+	unique := tokens[:0]
+	for _, token := range tokens {
+		if token != "" && !seen[token] {
+			seen[token] = true
+			unique = append(unique, token)
+		}
+	}
+	return unique
+}
+
+// acquire marks a token running if it is not and a slot is free
+func (s *Scheduler) acquire(token string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running[token] {
+		return false
+	}
+	select {
+	case s.slots <- struct{}{}:
+		s.running[token] = true
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *Scheduler) release(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, token)
+	<-s.slots
+}
+
+// analyze runs every stage of the pipeline of a token
+func (s *Scheduler) analyze(ctx context.Context, token string) (*models.AnalysisResult, error) {
+	timings := make(map[string]float64)
+	timeouts := s.config.Timeouts
+
+	candles, err := runStage(ctx, s, token, StageFetch, timeouts.Fetch, timings, func(ctx context.Context) ([]models.MarketData, error) {
+		return s.fetch(ctx, token)
+	})
+	if err != nil {
+		return nil, err
+	}
+	latest := candles[len(candles)-1]
+	if err := s.checkFresh(token, latest.Timestamp); err != nil {
+		return nil, err
+	}
+
+	indicators, err := runStage(ctx, s, token, StageIndicators, timeouts.Indicators, timings, func(ctx context.Context) (Indicators, error) {
+		return computeIndicators(token, candles)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// A failed LLM analysis degrades the signal to the indicators rather
+	// than failing the run
+	var llm *trading.LLMAnalysis
+	var llmErr error
+	if s.analyst != nil {
+		llm, llmErr = runStage(ctx, s, token, StageLLM, timeouts.LLM, timings, func(ctx context.Context) (*trading.LLMAnalysis, error) {
+			return s.analyst.Analyze(ctx, AnalystInput{
+				TokenAddress: token,
+				Candles:      candles,
+				Indicators:   indicators,
+			})
+		})
+	}
+
+	message, err := runStage(ctx, s, token, StageFusion, timeouts.Fusion, timings, func(ctx context.Context) (*models.TradeSignalMessage, error) {
+		input := trading.FusionInput{
+			Symbol:     token,
+			Price:      latest.ClosePrice,
+			Indicators: indicators.Signals,
+			LLM:        llm,
+		}
+		s.mu.Lock()
+		risk := s.risk
+		s.mu.Unlock()
+		if risk != nil {
+			state, err := risk(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get risk state: %w", err)
+			}
+			input.Risk = state
+		}
+		return s.fusion.Fuse(ctx, input)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := newResult(token, latest, indicators, llm, message)
+	s.mu.Lock()
+	social := s.sentiment
+	s.mu.Unlock()
+	if social != nil {
+		if score, ok := social.Sentiment(token); ok {
+			mergeSentiment(result, score)
+		}
+	}
+	if llmErr != nil {
+		result.Metadata["llm_error"] = llmErr.Error()
+	}
+	stageSeconds := make(map[string]float64, len(timings))
+	for stage, seconds := range timings {
+		stageSeconds[stage] = seconds
+	}
+	result.Metadata["stage_seconds"] = stageSeconds
+
+	_, err = runStage(ctx, s, token, StagePersist, timeouts.Persist, timings, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, s.store.SaveAnalysisResult(ctx, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.analyzed[token] = latest.Timestamp
+	s.mu.Unlock()
+	return result, nil
+}
+
+// fetch returns the market data of a token, oldest first
+func (s *Scheduler) fetch(ctx context.Context, token string) ([]models.MarketData, error) {
+	data, err := s.store.GetHistoricalMarketData(ctx, token, s.config.Candles)
+	if err != nil {
+		return nil, err
+	}
+	candles := make([]models.MarketData, 0, len(data))
+	for i := len(data) - 1; i >= 0; i-- {
+		if data[i] != nil {
+			candles = append(candles, *data[i])
+		}
+	}
+	if len(candles) == 0 {
+		return nil, ErrNoMarketData
+	}
+	return candles, nil
+}
+
+// checkFresh rejects data older than the maximum age or already analyzed
+func (s *Scheduler) checkFresh(token string, latest time.Time) error {
+	if age := time.Since(latest); age > s.config.MaxDataAge {
+		return fmt.Errorf("%w: latest data is %s old", ErrStaleData, age.Round(time.Second))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if analyzed, ok := s.analyzed[token]; ok && !latest.After(analyzed) {
+		return ErrNoNewData
+	}
+	return nil
+}
+
+// runStage runs one stage within its timeout and records its duration.
+// Stages run in their own goroutine so that a stage ignoring its context
+// cannot hold up the run past the timeout; its late result is discarded.
+func runStage[T any](ctx context.Context, s *Scheduler, token, name string, timeout time.Duration, timings map[string]float64, fn func(ctx context.Context) (T, error)) (T, error) {
+	type result struct {
+		value T
+		err   error
+	}
+
+	stageCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan result, 1)
+	go func() {
+		value, err := fn(stageCtx)
+		done <- result{value, err}
+	}()
+
+	var r result
+	select {
+	case r = <-done:
+	case <-stageCtx.Done():
+		r.err = stageCtx.Err()
+	}
+	duration := time.Since(start)
+	timings[name] = duration.Seconds()
+
+	tags := map[string]string{"token": token, "stage": name}
+	s.recordMetric(ctx, "pipeline_stage_seconds", duration.Seconds(), tags)
+	if r.err != nil {
+		s.recordMetric(ctx, "pipeline_stage_failures", 1, tags)
+		var zero T
+		return zero, fmt.Errorf("%s stage: %w", name, r.err)
+	}
+	return r.value, nil
+}
+
+// record records the outcome of a run
+func (s *Scheduler) record(ctx context.Context, token string, outcome Outcome, err error, duration time.Duration) {
+	s.mu.Lock()
+	t, ok := s.stats.Tokens[token]
+	if !ok {
+		t = &TokenStats{}
+		s.stats.Tokens[token] = t
+	}
+	t.LastOutcome = outcome
+	t.LastError = ""
+	if err != nil {
+		t.LastError = err.Error()
+	}
+
+	switch outcome {
+	case OutcomeAnalyzed:
+		s.stats.Runs++
+		s.stats.Analyzed++
+		t.LastRun = time.Now()
+		t.LastAnalyzed = t.LastRun
+		t.Duration = duration
+	case OutcomeFailed:
+		s.stats.Runs++
+		s.stats.Failures++
+		t.LastRun = time.Now()
+		t.Duration = duration
+		t.Failures++
+	default:
+		s.stats.Skipped++
+	}
+	s.mu.Unlock()
+
+	tags := map[string]string{"token": token, "outcome": string(outcome)}
+	s.recordMetric(ctx, "pipeline_runs", 1, tags)
+	if outcome == OutcomeAnalyzed || outcome == OutcomeFailed {
+		s.recordMetric(ctx, "pipeline_run_seconds", duration.Seconds(), tags)
+	}
+	if outcome == OutcomeFailed {
+		s.recordEvent(ctx, monitoring.SeverityWarning, "Analysis pipeline run failed", map[string]interface{}{
+			"token": token,
+			"error": err.Error(),
+		})
+	}
+}
+
+func outcomeOf(err error) Outcome {
+	switch {
+	case err == nil:
+		return OutcomeAnalyzed
+	case errors.Is(err, ErrStaleData), errors.Is(err, ErrNoMarketData):
+		return OutcomeStale
+	case errors.Is(err, ErrNoNewData):
+		return OutcomeUnchanged
+	default:
+		return OutcomeFailed
+	}
+}
+
+func (s *Scheduler) recordMetric(ctx context.Context, name string, value float64, tags map[string]string) {
+	if s.monitor == nil {
+		return
+	}
+	s.monitor.RecordMetric(ctx, name, value, tags)
+}
+
+func (s *Scheduler) recordEvent(ctx context.Context, severity monitoring.EventSeverity, message string, details map[string]interface{}) {
+	if s.monitor == nil {
+		return
+	}
+	s.monitor.RecordEvent(ctx, monitoring.Event{
+		Type:     monitoring.MetricTrading,
+		Severity: severity,
+		Message:  message,
+		Details:  details,
+	})
+}
+
+// computeIndicators calculates the indicators of candles, oldest first.
+// Indicators without enough data are left zero.
+func computeIndicators(token string, candles []models.MarketData) (Indicators, error) {
+	signals, err := analysis.GenerateSignals(token, candles)
+	if err != nil {
+		return Indicators{}, err
+	}
+
+	prices := make([]float64, len(candles))
+	for i, candle := range candles {
+		prices[i] = candle.ClosePrice
+	}
+	indicators := Indicators{
+		Signals:    signals,
+		Volatility: volatility(prices),
+	}
+	if rsi, err := analysis.RSI(prices, 14); err == nil {
+		indicators.RSI = rsi
+	}
+	if macd, hist, err := analysis.MACD(prices, 12, 26, 9); err == nil {
+		indicators.MACD = macd
+		indicators.MACDHist = hist
+	}
+	if len(prices) > momentumPeriod && prices[len(prices)-1-momentumPeriod] > 0 {
+		indicators.Momentum = prices[len(prices)-1]/prices[len(prices)-1-momentumPeriod] - 1
+	}
+	return indicators, nil
+}
+
+// volatility is the standard deviation of the returns between prices
+func volatility(prices []float64) float64 {
+	var returns []float64
+	for i := 1; i < len(prices); i++ {
+		if prices[i-1] > 0 {
+			returns = append(returns, prices[i]/prices[i-1]-1)
+		}
+	}
+	if len(returns) < 2 {
+		return 0
+	}
+
+	mean := 0.0
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	variance := 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	return math.Sqrt(variance / float64(len(returns)-1))
+}
+
+// newResult builds the analysis result of a run
+func newResult(token string, latest models.MarketData, indicators Indicators, llm *trading.LLMAnalysis, message *models.TradeSignalMessage) *models.AnalysisResult {
+	result := models.NewAnalysisResult(token)
+	signal := message.Signal
+
+	result.Signal = signalName(signal.SignalType)
+	result.Confidence = signal.Confidence
+	result.TrendScore = floatOf(message.Metadata["indicator_score"])
+	result.RiskScore = floatOf(message.Metadata["risk_penalty"])
+	result.SentimentScore = floatOf(message.Metadata["llm_score"])
+	result.MarketTrend = marketTrend(result.TrendScore)
+	result.Volatility = indicators.Volatility
+	result.Momentum = indicators.Momentum
+	result.RSI = indicators.RSI
+	result.MACD = indicators.MACD
+	result.MACDHist = indicators.MACDHist
+	result.MACDSignal = indicators.MACD - indicators.MACDHist
+	result.CurrentPrice = latest.ClosePrice
+	result.Liquidity = latest.Liquidity
+
+	if llm != nil {
+		result.StopLoss = llm.Recommendation.StopLoss
+		if len(llm.Recommendation.ExitPoints) > 0 {
+			result.PriceTarget = llm.Recommendation.ExitPoints[0]
+		}
+		result.Metadata["llm_sentiment"] = llm.Sentiment
+		result.Metadata["llm_key_factors"] = llm.KeyFactors
+	}
+	result.Metadata["data_timestamp"] = latest.Timestamp
+	result.Metadata["explanation"] = signal.Description
+	result.Indicators["signals"] = len(indicators.Signals)
+	result.Indicators["rsi"] = indicators.RSI
+	result.Indicators["macd"] = indicators.MACD
+	result.Indicators["macd_hist"] = indicators.MACDHist
+	result.Indicators["volatility"] = indicators.Volatility
+	result.Indicators["momentum"] = indicators.Momentum
+	return result
+}
+
+// mergeSentiment replaces the LLM sentiment of a result with the social
+// sentiment of its token, keeping the former in the metadata
+func mergeSentiment(result *models.AnalysisResult, score sentiment.Score) {
+	result.Metadata["llm_score"] = result.SentimentScore
+	result.SentimentScore = score.Score
+	result.NewsImpact = score.Impact()
+	result.Metadata["social_mentions"] = score.Mentions
+	result.Metadata["social_bullish"] = score.Bullish
+	result.Metadata["social_bearish"] = score.Bearish
+}
+
+func signalName(signalType models.TradeSignalType) string {
+	switch signalType {
+	case models.SignalTypeBuy:
+		return "buy"
+	case models.SignalTypeSell:
+		return "sell"
+	default:
+		return "hold"
+	}
+}
+
+func marketTrend(score float64) string {
+	switch {
+	case score > 0.1:
+		return "bullish"
+	case score < -0.1:
+		return "bearish"
+	default:
+		return "neutral"
+	}
+}
+
+func floatOf(v interface{}) float64 {
+	f, _ := v.(float64)
+	return f
+}
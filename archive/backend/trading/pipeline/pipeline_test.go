@@ -0,0 +1,279 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/trading"
+	"github.com/leonzhao/trading-system/backend/trading/sentiment"
+)
+
+// memoryStore serves market data newest first and keeps saved analyses
+type memoryStore struct {
+	data    map[string][]*models.MarketData
+	results []*models.AnalysisResult
+	// block makes reads wait until it is closed
+	block chan struct{}
+	mu    sync.Mutex
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{data: make(map[string][]*models.MarketData)}
+}
+
+// add stores n rising candles of a token ending at end
+func (m *memoryStore) add(token string, n int, end time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data := make([]*models.MarketData, n)
+	for i := 0; i < n; i++ {
+		price := 100 + float64(n-i) + float64(i%3)
+		data[i] = &models.MarketData{
+			TokenAddress: token,
+			OpenPrice:    price - 0.5,
+			ClosePrice:   price,
+			HighPrice:    price + 1,
+			LowPrice:     price - 1,
+			Volume:       1000,
+			Liquidity:    50000,
+			Timestamp:    end.Add(-time.Duration(i) * time.Minute),
+		}
+	}
+	m.data[token] = data
+}
+
+func (m *memoryStore) GetHistoricalMarketData(ctx context.Context, tokenAddress string, limit int) ([]*models.MarketData, error) {
+	if m.block != nil {
+		select {
+		case <-m.block:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data := m.data[tokenAddress]
+	if len(data) > limit {
+		data = data[:limit]
+	}
+	return data, nil
+}
+
+func (m *memoryStore) SaveAnalysisResult(ctx context.Context, result *models.AnalysisResult) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.results = append(m.results, result)
+	return nil
+}
+
+func (m *memoryStore) saved() []*models.AnalysisResult {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*models.AnalysisResult(nil), m.results...)
+}
+
+type fakeWatchlist []string
+
+func (w fakeWatchlist) ListWatchlist(ctx context.Context, filter *models.WatchlistFilter) ([]*models.WatchlistEntry, error) {
+	entries := make([]*models.WatchlistEntry, len(w))
+	for i, token := range w {
+		entries[i] = &models.WatchlistEntry{TokenAddress: token, Status: models.WatchlistWatching}
+	}
+	return entries, nil
+}
+
+type analystFunc func(ctx context.Context, input AnalystInput) (*trading.LLMAnalysis, error)
+
+func (f analystFunc) Analyze(ctx context.Context, input AnalystInput) (*trading.LLMAnalysis, error) {
+	return f(ctx, input)
+}
+
+func bullishAnalyst(ctx context.Context, input AnalystInput) (*trading.LLMAnalysis, error) {
+	return &trading.LLMAnalysis{
+		Sentiment:  "bullish",
+		Confidence: 0.9,
+		KeyFactors: []string{"volume"},
+		Recommendation: trading.LLMRecommendation{
+			Action:     "buy",
+			ExitPoints: []float64{180},
+			StopLoss:   140,
+		},
+	}, nil
+}
+
+func TestRunOnceAnalyzesToken(t *testing.T) {
+	store := newMemoryStore()
+	store.add("token-a", 60, time.Now())
+
+	var input AnalystInput
+	s := NewScheduler(store, nil, analystFunc(func(ctx context.Context, in AnalystInput) (*trading.LLMAnalysis, error) {
+		input = in
+		return bullishAnalyst(ctx, in)
+	}), nil, DefaultConfig(), nil)
+
+	result, err := s.RunOnce(context.Background(), "token-a")
+	require.NoError(t, err)
+
+	assert.Equal(t, "token-a", input.TokenAddress)
+	require.Len(t, input.Candles, 60)
+	assert.True(t, input.Candles[0].Timestamp.Before(input.Candles[59].Timestamp), "candles are oldest first")
+	assert.NotEmpty(t, input.Indicators.Signals)
+
+	assert.Equal(t, "token-a", result.TokenAddress)
+	assert.Contains(t, []string{"buy", "sell", "hold"}, result.Signal)
+	assert.Greater(t, result.RSI, 0.0)
+	assert.Equal(t, input.Candles[59].ClosePrice, result.CurrentPrice)
+	assert.Equal(t, 140.0, result.StopLoss)
+	assert.Equal(t, 180.0, result.PriceTarget)
+	assert.Greater(t, result.SentimentScore, 0.0)
+	assert.Contains(t, result.Metadata, "stage_seconds")
+	assert.NotContains(t, result.Metadata, "llm_error")
+	assert.Len(t, store.saved(), 1)
+
+	stats := s.Stats()
+	assert.Equal(t, int64(1), stats.Analyzed)
+	assert.Equal(t, OutcomeAnalyzed, stats.Tokens["token-a"].LastOutcome)
+}
+
+func TestRunOnceSkipsStaleAndUnchangedData(t *testing.T) {
+	store := newMemoryStore()
+	store.add("fresh", 60, time.Now())
+	store.add("stale", 60, time.Now().Add(-time.Hour))
+
+	s := NewScheduler(store, nil, nil, nil, Config{MaxDataAge: 10 * time.Minute}, nil)
+
+	_, err := s.RunOnce(context.Background(), "stale")
+	assert.ErrorIs(t, err, ErrStaleData)
+	_, err = s.RunOnce(context.Background(), "missing")
+	assert.ErrorIs(t, err, ErrNoMarketData)
+
+	_, err = s.RunOnce(context.Background(), "fresh")
+	require.NoError(t, err)
+	_, err = s.RunOnce(context.Background(), "fresh")
+	assert.ErrorIs(t, err, ErrNoNewData)
+
+	// New data is analyzed again
+	store.add("fresh", 60, time.Now().Add(time.Second))
+	_, err = s.RunOnce(context.Background(), "fresh")
+	require.NoError(t, err)
+
+	stats := s.Stats()
+	assert.Equal(t, int64(2), stats.Analyzed)
+	assert.Equal(t, int64(3), stats.Skipped)
+	assert.Zero(t, stats.Failures)
+	assert.Equal(t, OutcomeStale, stats.Tokens["stale"].LastOutcome)
+	assert.Len(t, store.saved(), 2)
+}
+
+func TestLLMFailureDegradesToIndicators(t *testing.T) {
+	store := newMemoryStore()
+	store.add("token-a", 60, time.Now())
+
+	config := DefaultConfig()
+	config.Timeouts.LLM = 20 * time.Millisecond
+	s := NewScheduler(store, nil, analystFunc(func(ctx context.Context, in AnalystInput) (*trading.LLMAnalysis, error) {
+		// Ignores its context, so only the stage timeout stops the wait
+		time.Sleep(200 * time.Millisecond)
+		return bullishAnalyst(ctx, in)
+	}), nil, config, nil)
+
+	start := time.Now()
+	result, err := s.RunOnce(context.Background(), "token-a")
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 150*time.Millisecond)
+	assert.Contains(t, result.Metadata["llm_error"], "llm stage")
+	assert.Zero(t, result.SentimentScore)
+}
+
+type sentimentFunc func(token string) (sentiment.Score, bool)
+
+func (f sentimentFunc) Sentiment(token string) (sentiment.Score, bool) {
+	return f(token)
+}
+
+func TestSocialSentimentIsMerged(t *testing.T) {
+	store := newMemoryStore()
+	store.add("token-a", 60, time.Now())
+	store.add("token-b", 60, time.Now())
+
+	s := NewScheduler(store, nil, analystFunc(bullishAnalyst), nil, DefaultConfig(), nil)
+	s.SetSentimentSource(sentimentFunc(func(token string) (sentiment.Score, bool) {
+		if token != "token-a" {
+			return sentiment.Score{}, false
+		}
+		return sentiment.Score{Token: token, Score: -0.6, Mentions: 25, Bearish: 20, Bullish: 5}, true
+	}))
+
+	result, err := s.RunOnce(context.Background(), "token-a")
+	require.NoError(t, err)
+	assert.Equal(t, -0.6, result.SentimentScore)
+	assert.Equal(t, sentiment.ImpactHigh, result.NewsImpact)
+	assert.Equal(t, 25, result.Metadata["social_mentions"])
+	assert.Greater(t, result.Metadata["llm_score"], 0.0)
+
+	result, err = s.RunOnce(context.Background(), "token-b")
+	require.NoError(t, err)
+	assert.Greater(t, result.SentimentScore, 0.0, "tokens without social sentiment keep the LLM score")
+	assert.Empty(t, result.NewsImpact)
+}
+
+func TestStageFailureFailsRun(t *testing.T) {
+	store := newMemoryStore()
+	store.add("token-a", 60, time.Now())
+
+	s := NewScheduler(store, nil, nil, nil, DefaultConfig(), nil)
+	s.SetRiskSource(func(ctx context.Context) (trading.FusionRiskState, error) {
+		return trading.FusionRiskState{}, errors.New("risk unavailable")
+	})
+
+	_, err := s.RunOnce(context.Background(), "token-a")
+	assert.ErrorContains(t, err, "fusion stage")
+	assert.Empty(t, store.saved())
+
+	stats := s.Stats()
+	assert.Equal(t, int64(1), stats.Failures)
+	assert.Equal(t, OutcomeFailed, stats.Tokens["token-a"].LastOutcome)
+	assert.Contains(t, stats.Tokens["token-a"].LastError, "risk unavailable")
+}
+
+func TestScheduleBoundsConcurrency(t *testing.T) {
+	store := newMemoryStore()
+	for _, token := range []string{"token-a", "token-b", "token-c"} {
+		store.add(token, 60, time.Now())
+	}
+	store.block = make(chan struct{})
+
+	s := NewScheduler(store, fakeWatchlist{"token-b", "token-c"}, nil, nil, Config{
+		Tokens:      []string{"token-a", "token-b"},
+		Concurrency: 2,
+	}, nil)
+
+	assert.Equal(t, []string{"token-a", "token-b", "token-c"}, s.tokens(context.Background()))
+	assert.Equal(t, 2, s.schedule(context.Background()))
+	assert.Equal(t, 2, s.Stats().Running)
+	assert.Equal(t, OutcomeBusy, s.Stats().Tokens["token-c"].LastOutcome)
+
+	// Running tokens are not started again
+	assert.Zero(t, s.schedule(context.Background()))
+
+	close(store.block)
+	s.wg.Wait()
+	assert.Zero(t, s.Stats().Running)
+	assert.Len(t, store.saved(), 2)
+
+	// The next tick starts with the token that found no slot
+	assert.Equal(t, 2, s.schedule(context.Background()))
+	s.wg.Wait()
+	assert.Len(t, store.saved(), 3)
+	stats := s.Stats()
+	assert.Equal(t, OutcomeAnalyzed, stats.Tokens["token-c"].LastOutcome)
+	assert.Equal(t, OutcomeUnchanged, stats.Tokens["token-a"].LastOutcome)
+	assert.Equal(t, OutcomeBusy, stats.Tokens["token-b"].LastOutcome)
+}
@@ -0,0 +1,321 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/monitoring"
+)
+
+var (
+	// ErrNoPendingMismatch is returned when resolving a token without a flagged mismatch
+	ErrNoPendingMismatch = errors.New("no pending balance mismatch")
+	// ErrMismatchChanged is returned when the on-chain balance or recorded
+	// positions changed after the mismatch was flagged
+	ErrMismatchChanged = errors.New("balance mismatch changed since it was flagged")
+	// ErrNoPositionToCorrect is returned when correcting a token that has no
+	// recorded open position
+	ErrNoPositionToCorrect = errors.New("no open position to correct")
+)
+
+// TokenBalanceSource reads a wallet's on-chain token balances keyed by mint
+type TokenBalanceSource interface {
+	GetTokenBalances(ctx context.Context, walletAddress string) (map[string]float64, error)
+}
+
+// ReconcilerStore is the subset of the repository used by the reconciler
+type ReconcilerStore interface {
+	GetOpenPositions(ctx context.Context) ([]*models.Position, error)
+	UpdatePosition(ctx context.Context, position *models.Position) error
+	ClosePosition(ctx context.Context, id string, closePrice float64) error
+	SaveReconciliationRecord(ctx context.Context, record *models.ReconciliationRecord) error
+}
+
+// ReconcilerConfig contains position reconciliation configuration
+type ReconcilerConfig struct {
+	// WalletAddress is the wallet whose token balances back the positions
+	WalletAddress string
+	// Tolerance is the absolute size difference treated as rounding dust
+	Tolerance float64
+	// IgnoreTokens lists mints held without positions, such as the quote token
+	IgnoreTokens []string
+}
+
+// Reconciler compares recorded open positions to on-chain token balances.
+// Detected mismatches are flagged and held until an operator corrects or
+// dismisses them; every step is written to the reconciliation audit log.
+type Reconciler struct {
+	store    ReconcilerStore
+	balances TokenBalanceSource
+	config   ReconcilerConfig
+	monitor  monitoring.IMonitor
+	ignore   map[string]bool
+	pending  map[string]*models.BalanceMismatch
+	mu       sync.Mutex
+}
+
+// NewReconciler creates a new position reconciler
+func NewReconciler(store ReconcilerStore, balances TokenBalanceSource, config ReconcilerConfig, monitor monitoring.IMonitor) *Reconciler {
+	if config.Tolerance <= 0 {
+		config.Tolerance = 1e-9
+	}
+
+	ignore := make(map[string]bool, len(config.IgnoreTokens))
+	for _, token := range config.IgnoreTokens {
+		ignore[token] = true
+	}
+
+	return &Reconciler{
+		store:    store,
+		balances: balances,
+		config:   config,
+		monitor:  monitor,
+		ignore:   ignore,
+		pending:  make(map[string]*models.BalanceMismatch),
+	}
+}
+
+// Reconcile compares open positions to on-chain balances and returns the
+// current mismatches. New or changed mismatches are flagged in the audit log.
+func (r *Reconciler) Reconcile(ctx context.Context) ([]*models.BalanceMismatch, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, mismatches, err := r.detect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make(map[string]*models.BalanceMismatch, len(mismatches))
+	for token, mismatch := range mismatches {
+		if previous, ok := r.pending[token]; ok && sameMismatch(previous, mismatch) {
+			pending[token] = previous
+			continue
+		}
+
+		if err := r.audit(ctx, mismatch, models.ReconciliationFlagged, "", ""); err != nil {
+			return nil, err
+		}
+		if r.monitor != nil {
+			r.monitor.RecordEvent(ctx, monitoring.Event{
+				Type:     monitoring.MetricTrading,
+				Severity: monitoring.SeverityWarning,
+				Message:  "Position balance mismatch detected",
+				Details: map[string]interface{}{
+					"token_address":    mismatch.TokenAddress,
+					"recorded_size":    mismatch.RecordedSize,
+					"on_chain_balance": mismatch.OnChainBalance,
+					"difference":       mismatch.Difference,
+				},
+			})
+		}
+		pending[token] = mismatch
+	}
+	r.pending = pending
+
+	if r.monitor != nil {
+		r.monitor.RecordMetric(ctx, "position_balance_mismatches", float64(len(pending)), map[string]string{
+			"wallet": r.config.WalletAddress,
+		})
+	}
+
+	return sortedMismatches(pending), nil
+}
+
+// Pending returns the flagged mismatches awaiting resolution
+func (r *Reconciler) Pending() []*models.BalanceMismatch {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return sortedMismatches(r.pending)
+}
+
+// ApplyCorrection adjusts the recorded positions for a token to match its
+// on-chain balance. The mismatch is re-checked first so that a correction is
+// never applied to state the operator has not seen. The difference is applied
+// to the most recently opened positions first; positions reduced to zero are
+// closed at their current price.
+func (r *Reconciler) ApplyCorrection(ctx context.Context, tokenAddress, operator, note string) (*models.ReconciliationRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	flagged, ok := r.pending[tokenAddress]
+	if !ok {
+		return nil, ErrNoPendingMismatch
+	}
+
+	positions, mismatches, err := r.detect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	current := mismatches[tokenAddress]
+	if current == nil || !sameMismatch(flagged, current) {
+		return nil, ErrMismatchChanged
+	}
+
+	held := make([]*models.Position, 0, len(current.PositionIDs))
+	for _, position := range positions {
+		if position.TokenAddress == tokenAddress {
+			held = append(held, position)
+		}
+	}
+	if len(held) == 0 {
+		return nil, ErrNoPositionToCorrect
+	}
+
+	sort.Slice(held, func(i, j int) bool {
+		return held[i].OpenTime.After(held[j].OpenTime)
+	})
+
+	remaining := current.Difference
+	for _, position := range held {
+		if math.Abs(remaining) <= r.config.Tolerance {
+			break
+		}
+
+		if remaining > 0 || position.Size+remaining > r.config.Tolerance {
+			position.Size += remaining
+			position.UpdateValue(position.CurrentPrice)
+			if err := r.store.UpdatePosition(ctx, position); err != nil {
+				return nil, fmt.Errorf("failed to update position %s: %w", position.ID, err)
+			}
+			remaining = 0
+			break
+		}
+
+		remaining += position.Size
+		if err := r.store.ClosePosition(ctx, position.ID, position.CurrentPrice); err != nil {
+			return nil, fmt.Errorf("failed to close position %s: %w", position.ID, err)
+		}
+	}
+
+	delete(r.pending, tokenAddress)
+	return r.resolve(ctx, current, models.ReconciliationCorrected, operator, note)
+}
+
+// Dismiss resolves a flagged mismatch without changing any positions
+func (r *Reconciler) Dismiss(ctx context.Context, tokenAddress, operator, note string) (*models.ReconciliationRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	mismatch, ok := r.pending[tokenAddress]
+	if !ok {
+		return nil, ErrNoPendingMismatch
+	}
+
+	delete(r.pending, tokenAddress)
+	return r.resolve(ctx, mismatch, models.ReconciliationDismissed, operator, note)
+}
+
+func (r *Reconciler) detect(ctx context.Context) ([]*models.Position, map[string]*models.BalanceMismatch, error) {
+	positions, err := r.store.GetOpenPositions(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get open positions: %w", err)
+	}
+
+	balances, err := r.balances.GetTokenBalances(ctx, r.config.WalletAddress)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get token balances: %w", err)
+	}
+
+	return positions, compareBalances(positions, balances, r.ignore, r.config.Tolerance, time.Now()), nil
+}
+
+func (r *Reconciler) resolve(ctx context.Context, mismatch *models.BalanceMismatch, action models.ReconciliationAction, operator, note string) (*models.ReconciliationRecord, error) {
+	record := r.newRecord(mismatch, action, operator, note)
+	if err := r.store.SaveReconciliationRecord(ctx, record); err != nil {
+		return nil, fmt.Errorf("failed to save reconciliation record: %w", err)
+	}
+
+	if r.monitor != nil {
+		r.monitor.RecordEvent(ctx, monitoring.Event{
+			Type:     monitoring.MetricTrading,
+			Severity: monitoring.SeverityInfo,
+			Message:  "Position balance mismatch " + string(action),
+			Details: map[string]interface{}{
+				"token_address": mismatch.TokenAddress,
+				"difference":    mismatch.Difference,
+				"operator":      operator,
+			},
+		})
+	}
+
+	return record, nil
+}
+
+func (r *Reconciler) audit(ctx context.Context, mismatch *models.BalanceMismatch, action models.ReconciliationAction, operator, note string) error {
+	if err := r.store.SaveReconciliationRecord(ctx, r.newRecord(mismatch, action, operator, note)); err != nil {
+		return fmt.Errorf("failed to save reconciliation record: %w", err)
+	}
+	return nil
+}
+
+func (r *Reconciler) newRecord(mismatch *models.BalanceMismatch, action models.ReconciliationAction, operator, note string) *models.ReconciliationRecord {
+	return &models.ReconciliationRecord{
+		WalletAddress:  r.config.WalletAddress,
+		TokenAddress:   mismatch.TokenAddress,
+		PositionIDs:    mismatch.PositionIDs,
+		Action:         action,
+		RecordedSize:   mismatch.RecordedSize,
+		OnChainBalance: mismatch.OnChainBalance,
+		Difference:     mismatch.Difference,
+		Operator:       operator,
+		Note:           note,
+		CreatedAt:      time.Now(),
+	}
+}
+
+// compareBalances sums open position sizes per token and compares them to the
+// wallet balances. Tokens held on-chain without a recorded position are
+// reported with a zero recorded size unless ignored.
+func compareBalances(positions []*models.Position, balances map[string]float64, ignore map[string]bool, tolerance float64, now time.Time) map[string]*models.BalanceMismatch {
+	recorded := make(map[string]*models.BalanceMismatch)
+	for _, position := range positions {
+		mismatch, ok := recorded[position.TokenAddress]
+		if !ok {
+			mismatch = &models.BalanceMismatch{TokenAddress: position.TokenAddress}
+			recorded[position.TokenAddress] = mismatch
+		}
+		mismatch.PositionIDs = append(mismatch.PositionIDs, position.ID)
+		mismatch.RecordedSize += position.Size
+	}
+
+	for token := range balances {
+		if _, ok := recorded[token]; !ok && !ignore[token] {
+			recorded[token] = &models.BalanceMismatch{TokenAddress: token}
+		}
+	}
+
+	mismatches := make(map[string]*models.BalanceMismatch)
+	for token, mismatch := range recorded {
+		mismatch.OnChainBalance = balances[token]
+		mismatch.Difference = mismatch.OnChainBalance - mismatch.RecordedSize
+		mismatch.DetectedAt = now
+		if math.Abs(mismatch.Difference) > tolerance {
+			sort.Strings(mismatch.PositionIDs)
+			mismatches[token] = mismatch
+		}
+	}
+	return mismatches
+}
+
+func sameMismatch(a, b *models.BalanceMismatch) bool {
+	return a.RecordedSize == b.RecordedSize && a.OnChainBalance == b.OnChainBalance
+}
+
+func sortedMismatches(pending map[string]*models.BalanceMismatch) []*models.BalanceMismatch {
+	mismatches := make([]*models.BalanceMismatch, 0, len(pending))
+	for _, mismatch := range pending {
+		mismatches = append(mismatches, mismatch)
+	}
+	sort.Slice(mismatches, func(i, j int) bool {
+		return mismatches[i].TokenAddress < mismatches[j].TokenAddress
+	})
+	return mismatches
+}
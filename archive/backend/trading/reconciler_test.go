@@ -0,0 +1,147 @@
+package trading
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/leonzhao/trading-system/backend/models"
+)
+
+type memoryReconcilerStore struct {
+	positions map[string]*models.Position
+	records   []*models.ReconciliationRecord
+}
+
+func (s *memoryReconcilerStore) GetOpenPositions(ctx context.Context) ([]*models.Position, error) {
+	var open []*models.Position
+	for _, position := range s.positions {
+		if position.Status == "open" {
+			copied := *position
+			open = append(open, &copied)
+		}
+	}
+	return open, nil
+}
+
+func (s *memoryReconcilerStore) UpdatePosition(ctx context.Context, position *models.Position) error {
+	copied := *position
+	s.positions[position.ID] = &copied
+	return nil
+}
+
+func (s *memoryReconcilerStore) ClosePosition(ctx context.Context, id string, closePrice float64) error {
+	s.positions[id].Status = "closed"
+	s.positions[id].CurrentPrice = closePrice
+	return nil
+}
+
+func (s *memoryReconcilerStore) SaveReconciliationRecord(ctx context.Context, record *models.ReconciliationRecord) error {
+	s.records = append(s.records, record)
+	return nil
+}
+
+type staticBalances map[string]float64
+
+func (b staticBalances) GetTokenBalances(ctx context.Context, walletAddress string) (map[string]float64, error) {
+	return b, nil
+}
+
+func newReconcilerFixture(balances staticBalances) (*memoryReconcilerStore, *Reconciler) {
+	now := time.Now()
+	store := &memoryReconcilerStore{positions: map[string]*models.Position{
+		"p1": {ID: "p1", TokenAddress: "BONK", Side: "long", Size: 100, CurrentPrice: 1, Status: "open", OpenTime: now.Add(-2 * time.Hour)},
+		"p2": {ID: "p2", TokenAddress: "BONK", Side: "long", Size: 50, CurrentPrice: 1, Status: "open", OpenTime: now.Add(-time.Hour)},
+		"p3": {ID: "p3", TokenAddress: "WIF", Side: "long", Size: 10, CurrentPrice: 2, Status: "open", OpenTime: now},
+	}}
+
+	reconciler := NewReconciler(store, balances, ReconcilerConfig{
+		WalletAddress: "wallet",
+		Tolerance:     0.001,
+		IgnoreTokens:  []string{"USDC"},
+	}, nil)
+	return store, reconciler
+}
+
+func TestReconciler(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("flags mismatches once", func(t *testing.T) {
+		store, reconciler := newReconcilerFixture(staticBalances{"BONK": 120, "WIF": 10.0001, "USDC": 500, "JUP": 3})
+
+		mismatches, err := reconciler.Reconcile(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, mismatches, 2)
+		assert.Equal(t, "BONK", mismatches[0].TokenAddress)
+		assert.Equal(t, []string{"p1", "p2"}, mismatches[0].PositionIDs)
+		assert.Equal(t, -30.0, mismatches[0].Difference)
+		assert.Equal(t, "JUP", mismatches[1].TokenAddress)
+		assert.Equal(t, 0.0, mismatches[1].RecordedSize)
+		assert.Len(t, store.records, 2)
+
+		_, err = reconciler.Reconcile(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, store.records, 2, "unchanged mismatches are not re-flagged")
+	})
+
+	t.Run("correction reduces newest position first", func(t *testing.T) {
+		store, reconciler := newReconcilerFixture(staticBalances{"BONK": 70, "WIF": 10})
+		_, err := reconciler.Reconcile(ctx)
+		assert.NoError(t, err)
+
+		record, err := reconciler.ApplyCorrection(ctx, "BONK", "alice", "partial fill")
+		assert.NoError(t, err)
+		assert.Equal(t, models.ReconciliationCorrected, record.Action)
+		assert.Equal(t, "alice", record.Operator)
+		assert.Equal(t, "closed", store.positions["p2"].Status)
+		assert.Equal(t, 70.0, store.positions["p1"].Size)
+		assert.Empty(t, reconciler.Pending())
+
+		mismatches, err := reconciler.Reconcile(ctx)
+		assert.NoError(t, err)
+		assert.Empty(t, mismatches)
+	})
+
+	t.Run("correction increases newest position", func(t *testing.T) {
+		store, reconciler := newReconcilerFixture(staticBalances{"BONK": 175, "WIF": 10})
+		_, err := reconciler.Reconcile(ctx)
+		assert.NoError(t, err)
+
+		_, err = reconciler.ApplyCorrection(ctx, "BONK", "alice", "")
+		assert.NoError(t, err)
+		assert.Equal(t, 75.0, store.positions["p2"].Size)
+		assert.Equal(t, 75.0, store.positions["p2"].Value)
+		assert.Equal(t, 100.0, store.positions["p1"].Size)
+	})
+
+	t.Run("rejects stale correction", func(t *testing.T) {
+		balances := staticBalances{"BONK": 120, "WIF": 10}
+		store, reconciler := newReconcilerFixture(balances)
+		_, err := reconciler.Reconcile(ctx)
+		assert.NoError(t, err)
+
+		balances["BONK"] = 110
+		_, err = reconciler.ApplyCorrection(ctx, "BONK", "alice", "")
+		assert.ErrorIs(t, err, ErrMismatchChanged)
+		assert.Equal(t, 50.0, store.positions["p2"].Size)
+	})
+
+	t.Run("untracked token can only be dismissed", func(t *testing.T) {
+		store, reconciler := newReconcilerFixture(staticBalances{"BONK": 150, "WIF": 10, "JUP": 3})
+		_, err := reconciler.Reconcile(ctx)
+		assert.NoError(t, err)
+
+		_, err = reconciler.ApplyCorrection(ctx, "JUP", "alice", "")
+		assert.ErrorIs(t, err, ErrNoPositionToCorrect)
+
+		record, err := reconciler.Dismiss(ctx, "JUP", "alice", "airdrop")
+		assert.NoError(t, err)
+		assert.Equal(t, models.ReconciliationDismissed, record.Action)
+		assert.Len(t, store.records, 2)
+
+		_, err = reconciler.Dismiss(ctx, "JUP", "alice", "")
+		assert.ErrorIs(t, err, ErrNoPendingMismatch)
+	})
+}
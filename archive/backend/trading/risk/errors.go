@@ -15,6 +15,7 @@ const (
 	ErrMaxDailyLoss      = "max_daily_loss_reached"
 	ErrInsufficientLiquidity = "insufficient_liquidity"
 	ErrHighPriceImpact    = "high_price_impact"
+	ErrGapRisk            = "gap_risk"
 	ErrInvalidPosition    = "invalid_position"
 	ErrPositionNotFound   = "position_not_found"
 	ErrTradeNotAllowed    = "trade_not_allowed"
@@ -130,6 +131,19 @@ func NewHighPriceImpactError(impact, max float64) *RiskError {
 	)
 }
 
+// NewGapRiskError creates a new gap risk error
+func NewGapRiskError(token string, probability, max float64) *RiskError {
+	return NewRiskError(
+		ErrGapRisk,
+		fmt.Sprintf("stop-loss gap risk too high for %s: %.2f%% > %.2f%% of candles", token, probability*100, max*100),
+		map[string]interface{}{
+			"token":       token,
+			"probability": probability,
+			"max":         max,
+		},
+	)
+}
+
 // NewInvalidPositionError creates a new invalid position error
 func NewInvalidPositionError(reason string, details map[string]interface{}) *RiskError {
 	return NewRiskError(
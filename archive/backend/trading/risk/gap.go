@@ -0,0 +1,125 @@
+package risk
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/leonzhao/trading-system/backend/models"
+)
+
+const (
+	defaultGapInterval = "5m"
+	defaultGapLookback = 288
+	// minGapCandles is the history below which the gap probability of a
+	// token is not trusted, and its buys get the maximum haircut
+	minGapCandles = 30
+)
+
+// KlineSource returns historical candles of a token, oldest first.
+// analysis.KlineBuilder implements it.
+type KlineSource interface {
+	GetKlines(ctx context.Context, symbol, interval string, limit int) ([]models.Kline, error)
+}
+
+// GapRisk is the estimated risk of the price of a token gapping through a
+// stop-loss
+type GapRisk struct {
+	TokenAddress string `json:"token_address"`
+	// Candles is the number of candles the probability is estimated over,
+	// and Gaps the number of them dropping more than the gap threshold
+	// below the previous close
+	Candles int `json:"candles"`
+	Gaps    int `json:"gaps"`
+	// Probability is the share of candles that gapped
+	Probability float64 `json:"probability"`
+	// WorstGap is the largest drop below the previous close, in percent
+	WorstGap float64 `json:"worst_gap"`
+	// Haircut is the fraction buys of the token are downsized by
+	Haircut float64 `json:"haircut"`
+}
+
+// SetKlineSource enables the stop-loss gap risk check of buys against the
+// historical candles of source
+func (m *RiskManager) SetKlineSource(source KlineSource) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.klines = source
+}
+
+// EstimateGapRisk estimates, from its recent candles, the probability of the
+// price of a token dropping more than the gap threshold within a candle, past
+// where a long's stop-loss would fill. It fails without a kline source.
+func (m *RiskManager) EstimateGapRisk(ctx context.Context, tokenAddress string) (*GapRisk, error) {
+	m.mu.RLock()
+	source, config := m.klines, m.config
+	m.mu.RUnlock()
+	if source == nil {
+		return nil, fmt.Errorf("no kline source")
+	}
+
+	interval, lookback := config.GapInterval, config.GapLookback
+	if interval == "" {
+		interval = defaultGapInterval
+	}
+	if lookback <= 0 {
+		lookback = defaultGapLookback
+	}
+	// One more candle for the previous close of the first one
+	klines, err := source.GetKlines(ctx, tokenAddress, interval, lookback+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get klines of %s: %w", tokenAddress, err)
+	}
+
+	risk := &GapRisk{TokenAddress: tokenAddress}
+	for i := 1; i < len(klines); i++ {
+		prevClose := klines[i-1].Close
+		if prevClose <= 0 {
+			continue
+		}
+		risk.Candles++
+		drop := (prevClose - klines[i].Low) / prevClose * 100
+		risk.WorstGap = math.Max(risk.WorstGap, drop)
+		if drop > config.GapThreshold {
+			risk.Gaps++
+		}
+	}
+	if risk.Candles > 0 {
+		risk.Probability = float64(risk.Gaps) / float64(risk.Candles)
+	}
+
+	switch {
+	case risk.Candles < minGapCandles:
+		risk.Haircut = config.MaxGapHaircut
+	case config.MaxGapProbability > 0:
+		risk.Haircut = math.Min(config.MaxGapHaircut*risk.Probability/config.MaxGapProbability, config.MaxGapHaircut)
+	}
+	return risk, nil
+}
+
+// CheckGapRisk checks the stop-loss gap risk of a buy. Buys of tokens gapping
+// more often than MaxGapProbability are rejected with a gap risk error, and
+// the others are downsized by their haircut, updating trade.Amount. Sells are
+// never checked, and nothing is checked without a kline source, gap
+// threshold or maximum probability. It returns the estimated gap risk, or nil
+// when nothing was checked.
+func (m *RiskManager) CheckGapRisk(ctx context.Context, trade *models.Trade) (*GapRisk, error) {
+	m.mu.RLock()
+	source, config := m.klines, m.config
+	m.mu.RUnlock()
+	if trade.Side != models.TradeSideBuy || source == nil || config.GapThreshold <= 0 || config.MaxGapProbability <= 0 {
+		return nil, nil
+	}
+
+	risk, err := m.EstimateGapRisk(ctx, trade.TokenAddress)
+	if err != nil {
+		return nil, err
+	}
+	if risk.Candles >= minGapCandles && risk.Probability > config.MaxGapProbability {
+		return risk, NewGapRiskError(trade.TokenAddress, risk.Probability, config.MaxGapProbability)
+	}
+	if risk.Haircut > 0 {
+		trade.Amount *= 1 - risk.Haircut
+	}
+	return risk, nil
+}
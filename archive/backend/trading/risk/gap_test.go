@@ -0,0 +1,90 @@
+package risk
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/leonzhao/trading-system/backend/models"
+)
+
+type fakeKlines struct {
+	klines   map[string][]models.Kline
+	interval string
+	limit    int
+}
+
+func (k *fakeKlines) GetKlines(ctx context.Context, symbol, interval string, limit int) ([]models.Kline, error) {
+	k.interval, k.limit = interval, limit
+	return k.klines[symbol], nil
+}
+
+// candles returns n candles closing at 100, the ones at gaps dipping to 80
+// and the others to 99
+func candles(n int, gaps ...int) []models.Kline {
+	klines := make([]models.Kline, n)
+	for i := range klines {
+		klines[i] = models.Kline{Open: 100, High: 101, Low: 99, Close: 100}
+	}
+	for _, i := range gaps {
+		klines[i].Low = 80
+	}
+	return klines
+}
+
+func TestRiskManager_GapRisk(t *testing.T) {
+	ctx := context.Background()
+	source := &fakeKlines{klines: map[string][]models.Kline{
+		"calm":  candles(41, 10, 30),
+		"meme":  candles(41, 1, 5, 9, 13, 17, 21, 25, 29),
+		"fresh": candles(10),
+	}}
+	rm := NewRiskManager(RiskConfig{MaxPositions: 3, MaxPositionSize: 1000, InitialCapital: 100000, RiskPerTrade: 0.05,
+		GapThreshold: 10, MaxGapProbability: 0.1, MaxGapHaircut: 0.5})
+
+	// Without a kline source nothing is checked
+	buy := &models.Trade{TokenAddress: "calm", Side: models.TradeSideBuy, Amount: 100}
+	if risk, err := rm.CheckGapRisk(ctx, buy); risk != nil || err != nil {
+		t.Errorf("Expected no check without a kline source, got %+v, %v", risk, err)
+	}
+	rm.SetKlineSource(source)
+
+	// 2 of 40 candles gapped, half the maximum probability
+	risk, err := rm.CheckGapRisk(ctx, buy)
+	if err != nil {
+		t.Errorf("Expected no error below the maximum gap probability, got %v", err)
+	}
+	if risk == nil || risk.Candles != 40 || risk.Gaps != 2 || risk.Probability != 0.05 || risk.WorstGap != 20 {
+		t.Errorf("Expected 2 gaps of 20%% in 40 candles, got %+v", risk)
+	}
+	if math.Abs(buy.Amount-75) > 1e-9 {
+		t.Errorf("Expected a 25%% haircut to 75, got %f", buy.Amount)
+	}
+	if source.interval != "5m" || source.limit != 289 {
+		t.Errorf("Expected a day of 5m candles, got %d %s candles", source.limit, source.interval)
+	}
+
+	buy = &models.Trade{TokenAddress: "meme", Side: models.TradeSideBuy, Amount: 100}
+	_, err = rm.CheckGapRisk(ctx, buy)
+	if GetRiskErrorType(err) != ErrGapRisk {
+		t.Errorf("Expected gap risk error, got %v", err)
+	}
+	if buy.Amount != 100 {
+		t.Errorf("Expected a rejected buy to keep its amount, got %f", buy.Amount)
+	}
+
+	// Too short a history to rely on gets the maximum haircut
+	buy = &models.Trade{TokenAddress: "fresh", Side: models.TradeSideBuy, Amount: 100}
+	if _, err := rm.CheckGapRisk(ctx, buy); err != nil {
+		t.Errorf("Expected no error for a short history, got %v", err)
+	}
+	if buy.Amount != 50 {
+		t.Errorf("Expected the maximum haircut to 50, got %f", buy.Amount)
+	}
+
+	// Sells are never checked
+	sell := &models.Trade{TokenAddress: "meme", Side: models.TradeSideSell, Amount: 100}
+	if risk, err := rm.CheckGapRisk(ctx, sell); risk != nil || err != nil || sell.Amount != 100 {
+		t.Errorf("Expected sells not to be checked, got %+v, %v", risk, err)
+	}
+}
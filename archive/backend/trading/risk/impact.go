@@ -0,0 +1,129 @@
+package risk
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/leonzhao/trading-system/backend/dex"
+	"github.com/leonzhao/trading-system/backend/models"
+)
+
+// impactSearchSteps bounds the bisection of the largest size within the
+// maximum impact, which is then within size/2^40 of the exact one
+const impactSearchSteps = 40
+
+// DepthSource returns the live order book depth of a token.
+// dex.MarketDataService implements it.
+type DepthSource interface {
+	GetMarketDepth(ctx context.Context, tokenAddress string) (*dex.MarketDepth, error)
+}
+
+// ImpactEstimate is the expected impact of an order on the live order book
+type ImpactEstimate struct {
+	TokenAddress string  `json:"token_address"`
+	Side         string  `json:"side"`
+	Size         float64 `json:"size"`
+	// Impact is the distance of the average fill price from the mid price,
+	// in percent
+	Impact float64 `json:"impact"`
+	// Complete is false when the book is too thin to fill the size
+	Complete bool `json:"complete"`
+	// MaxSize is the largest size filled within the maximum impact, Size
+	// when the order fits
+	MaxSize float64 `json:"max_size"`
+}
+
+// SetDepthSource enables the pre-trade market impact check of buys against
+// the live order book depth of source
+func (m *RiskManager) SetDepthSource(source DepthSource) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.depth = source
+}
+
+// EstimateImpact estimates the impact of an order of size base units on the
+// live order book of a token, and the largest size within the maximum
+// impact. It fails without a depth source.
+func (m *RiskManager) EstimateImpact(ctx context.Context, tokenAddress, side string, size float64) (*ImpactEstimate, error) {
+	m.mu.RLock()
+	source, maxImpact := m.depth, m.config.MaxMarketImpact
+	m.mu.RUnlock()
+	if source == nil {
+		return nil, fmt.Errorf("no order book depth source")
+	}
+
+	depth, err := source.GetMarketDepth(ctx, tokenAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get market depth of %s: %w", tokenAddress, err)
+	}
+	book := &dex.OrderBook{Bids: depth.Bids, Asks: depth.Asks}
+	slippage, err := book.EstimateSlippage(side, size)
+	if err != nil {
+		return nil, NewRiskError(ErrInsufficientLiquidity, err.Error(), map[string]interface{}{
+			"token": tokenAddress,
+		})
+	}
+
+	estimate := &ImpactEstimate{
+		TokenAddress: tokenAddress,
+		Side:         side,
+		Size:         size,
+		Impact:       slippage.Slippage,
+		Complete:     slippage.Complete,
+		MaxSize:      size,
+	}
+	if !estimate.Complete || (maxImpact > 0 && estimate.Impact > maxImpact) {
+		estimate.MaxSize = maxSizeWithin(book, side, size, maxImpact)
+	}
+	return estimate, nil
+}
+
+// maxSizeWithin bisects the largest size up to size the book fills within
+// maxImpact, the impact growing with the size. Without a maximum impact it
+// is the size the book can fill.
+func maxSizeWithin(book *dex.OrderBook, side string, size, maxImpact float64) float64 {
+	var low float64
+	high := size
+	for i := 0; i < impactSearchSteps; i++ {
+		mid := (low + high) / 2
+		estimate, err := book.EstimateSlippage(side, mid)
+		if err == nil && estimate.Complete && (maxImpact <= 0 || estimate.Slippage <= maxImpact) {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return low
+}
+
+// CheckMarketImpact checks the expected impact of a buy on the live order
+// book against the maximum market impact. Buys over it are downsized to the
+// largest size within it when DownsizeOnImpact is set, updating
+// trade.Amount, or rejected with a high price impact error. Sells are never
+// checked, so positions can always be exited, and nothing is checked without
+// a depth source or maximum. It returns the estimate of the requested size,
+// or nil when nothing was checked.
+func (m *RiskManager) CheckMarketImpact(ctx context.Context, trade *models.Trade) (*ImpactEstimate, error) {
+	m.mu.RLock()
+	source, config := m.depth, m.config
+	m.mu.RUnlock()
+	if trade.Side != models.TradeSideBuy || source == nil || config.MaxMarketImpact <= 0 {
+		return nil, nil
+	}
+
+	estimate, err := m.EstimateImpact(ctx, trade.TokenAddress, dex.SideBuy, trade.Amount)
+	if err != nil {
+		return nil, err
+	}
+	if estimate.Complete && estimate.Impact <= config.MaxMarketImpact {
+		return estimate, nil
+	}
+	if !config.DownsizeOnImpact || estimate.MaxSize <= 0 || estimate.MaxSize < trade.Amount*config.MinDownsizeRatio {
+		if !estimate.Complete {
+			return estimate, NewInsufficientLiquidityError(trade.Amount, estimate.MaxSize, trade.TokenAddress)
+		}
+		return estimate, NewHighPriceImpactError(estimate.Impact, config.MaxMarketImpact)
+	}
+	trade.Amount = estimate.MaxSize
+	return estimate, nil
+}
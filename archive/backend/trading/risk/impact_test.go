@@ -0,0 +1,90 @@
+package risk
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/leonzhao/trading-system/backend/dex"
+	"github.com/leonzhao/trading-system/backend/models"
+)
+
+type fakeDepth struct {
+	depth *dex.MarketDepth
+	calls int
+}
+
+func (d *fakeDepth) GetMarketDepth(ctx context.Context, tokenAddress string) (*dex.MarketDepth, error) {
+	d.calls++
+	return d.depth, nil
+}
+
+func TestRiskManager_MarketImpact(t *testing.T) {
+	ctx := context.Background()
+	source := &fakeDepth{depth: &dex.MarketDepth{
+		TokenAddress: "token1",
+		Bids:         []dex.OrderBookItem{{Price: 99.9, Size: 100}},
+		Asks:         []dex.OrderBookItem{{Price: 100.1, Size: 50}, {Price: 101, Size: 50}, {Price: 105, Size: 50}},
+	}}
+	rm := NewRiskManager(RiskConfig{MaxPositions: 3, MaxPositionSize: 1000, InitialCapital: 100000, RiskPerTrade: 0.05, MaxMarketImpact: 1})
+
+	// Without a depth source nothing is checked
+	buy := &models.Trade{TokenAddress: "token1", Side: models.TradeSideBuy, Amount: 150}
+	if estimate, err := rm.CheckMarketImpact(ctx, buy); estimate != nil || err != nil {
+		t.Errorf("Expected no check without a depth source, got %+v, %v", estimate, err)
+	}
+	rm.SetDepthSource(source)
+
+	// Walks into the 101 level, within the maximum impact
+	buy.Amount = 100
+	estimate, err := rm.CheckMarketImpact(ctx, buy)
+	if err != nil {
+		t.Errorf("Expected no error within the maximum impact, got %v", err)
+	}
+	if estimate == nil || estimate.MaxSize != 100 || math.Abs(estimate.Impact-0.55) > 1e-9 {
+		t.Errorf("Expected a 0.55%% impact estimate, got %+v", estimate)
+	}
+
+	// Walks into the 105 level
+	buy.Amount = 150
+	estimate, err = rm.CheckMarketImpact(ctx, buy)
+	if GetRiskErrorType(err) != ErrHighPriceImpact {
+		t.Errorf("Expected high price impact error, got %v", err)
+	}
+	// 11.25 at 105 brings the average price to 101, 1% above the mid price
+	if estimate == nil || math.Abs(estimate.MaxSize-111.25) > 1e-6 {
+		t.Errorf("Expected a maximum size of 111.25, got %+v", estimate)
+	}
+	if buy.Amount != 150 {
+		t.Errorf("Expected a rejected buy to keep its amount, got %f", buy.Amount)
+	}
+
+	// Sells are never checked, so positions can always be exited
+	sell := &models.Trade{TokenAddress: "token1", Side: models.TradeSideSell, Amount: 500}
+	calls := source.calls
+	if err := rm.ValidateTradeSignal(ctx, sell, nil); err != nil {
+		t.Errorf("Expected no error for sell, got %v", err)
+	}
+	if source.calls != calls {
+		t.Error("Expected sells not to read the order book")
+	}
+
+	rm.config.DownsizeOnImpact = true
+	rm.config.MinDownsizeRatio = 0.5
+	if err := rm.ValidateTradeSignal(ctx, buy, nil); err != nil {
+		t.Errorf("Expected the buy to be downsized, got %v", err)
+	}
+	if math.Abs(buy.Amount-111.25) > 1e-6 {
+		t.Errorf("Expected the buy to be downsized to 111.25, got %f", buy.Amount)
+	}
+
+	// More than the book holds, which would be downsized below the minimum
+	buy.Amount = 500
+	if _, err := rm.CheckMarketImpact(ctx, buy); GetRiskErrorType(err) != ErrInsufficientLiquidity {
+		t.Errorf("Expected insufficient liquidity error, got %v", err)
+	}
+	rm.config.MinDownsizeRatio = 0.2
+	if _, err := rm.CheckMarketImpact(ctx, buy); err != nil || math.Abs(buy.Amount-111.25) > 1e-6 {
+		t.Errorf("Expected the buy to be downsized to 111.25, got %f, %v", buy.Amount, err)
+	}
+}
@@ -0,0 +1,60 @@
+package risk
+
+import (
+	"github.com/leonzhao/trading-system/backend/dex"
+)
+
+// Liquidity risk ratings, on the same scale as the LLM risk analysis
+const (
+	LiquidityRiskLow    = "Low"
+	LiquidityRiskMedium = "Medium"
+	LiquidityRiskHigh   = "High"
+)
+
+// imbalanceThreshold is the order book imbalance against an order's exit
+// side above which its liquidity risk is raised
+const imbalanceThreshold = 0.5
+
+// CheckLiquidity checks that the order book can fill an order of size base
+// units within the maximum slippage of the token, and returns the slippage
+// estimate
+func (m *RiskManager) CheckLiquidity(tokenAddress string, book *dex.OrderBook, side string, size float64) (*dex.SlippageEstimate, error) {
+	estimate, err := book.EstimateSlippage(side, size)
+	if err != nil {
+		return nil, NewRiskError(ErrInsufficientLiquidity, err.Error(), map[string]interface{}{
+			"token": tokenAddress,
+		})
+	}
+	if !estimate.Complete {
+		return estimate, NewInsufficientLiquidityError(size, estimate.Filled, tokenAddress)
+	}
+	if maxSlippage := m.maxSlippage(tokenAddress); maxSlippage > 0 && estimate.Slippage > maxSlippage {
+		return estimate, NewHighPriceImpactError(estimate.Slippage, maxSlippage)
+	}
+	return estimate, nil
+}
+
+// LiquidityRisk rates the liquidity risk of an order. Orders the book cannot
+// fill within the maximum slippage are high risk. Orders using more than
+// half of the slippage budget, or whose exit side of the book is much
+// thinner than their entry side, are medium risk.
+func (m *RiskManager) LiquidityRisk(book *dex.OrderBook, side string, size float64) string {
+	estimate, err := m.CheckLiquidity("", book, side, size)
+	if err != nil {
+		return LiquidityRiskHigh
+	}
+	if m.config.MaxSlippage > 0 && estimate.Slippage > m.config.MaxSlippage/2 {
+		return LiquidityRiskMedium
+	}
+
+	analytics, err := dex.AnalyzeDepth(book, dex.DefaultDepthConfig())
+	if err != nil {
+		return LiquidityRiskHigh
+	}
+	// A buy is exited against the bids and a sell against the asks
+	if (side == dex.SideBuy && analytics.Imbalance < -imbalanceThreshold) ||
+		(side == dex.SideSell && analytics.Imbalance > imbalanceThreshold) {
+		return LiquidityRiskMedium
+	}
+	return LiquidityRiskLow
+}
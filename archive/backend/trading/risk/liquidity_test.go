@@ -0,0 +1,87 @@
+package risk
+
+import (
+	"context"
+	"testing"
+
+	"github.com/leonzhao/trading-system/backend/dex"
+)
+
+func TestRiskManager_CheckLiquidity(t *testing.T) {
+	rm := NewRiskManager(RiskConfig{MaxSlippage: 1})
+
+	book := &dex.OrderBook{
+		Bids: []dex.OrderBookItem{{Price: 99.9, Size: 100}, {Price: 99, Size: 100}},
+		Asks: []dex.OrderBookItem{{Price: 100.1, Size: 50}, {Price: 101, Size: 50}, {Price: 105, Size: 50}},
+	}
+
+	// Filled at the best ask
+	estimate, err := rm.CheckLiquidity("token1", book, dex.SideBuy, 50)
+	if err != nil {
+		t.Errorf("Expected no error for shallow order, got %v", err)
+	}
+	if estimate == nil || !estimate.Complete {
+		t.Errorf("Expected complete fill estimate, got %+v", estimate)
+	}
+	if rating := rm.LiquidityRisk(book, dex.SideBuy, 50); rating != LiquidityRiskLow {
+		t.Errorf("Expected low liquidity risk, got %s", rating)
+	}
+
+	// Walks into the 101 level, using most of the slippage budget
+	if rating := rm.LiquidityRisk(book, dex.SideBuy, 100); rating != LiquidityRiskMedium {
+		t.Errorf("Expected medium liquidity risk, got %s", rating)
+	}
+
+	// Walks into the 105 level
+	_, err = rm.CheckLiquidity("token1", book, dex.SideBuy, 150)
+	if GetRiskErrorType(err) != ErrHighPriceImpact {
+		t.Errorf("Expected high price impact error, got %v", err)
+	}
+	if rating := rm.LiquidityRisk(book, dex.SideBuy, 150); rating != LiquidityRiskHigh {
+		t.Errorf("Expected high liquidity risk, got %s", rating)
+	}
+
+	// More than the book holds
+	_, err = rm.CheckLiquidity("token1", book, dex.SideSell, 500)
+	if GetRiskErrorType(err) != ErrInsufficientLiquidity {
+		t.Errorf("Expected insufficient liquidity error, got %v", err)
+	}
+}
+
+type tokenLimits map[string]TokenLimits
+
+func (l tokenLimits) TokenLimits(tokenAddress string) (TokenLimits, bool) {
+	limits, ok := l[tokenAddress]
+	return limits, ok
+}
+
+func TestRiskManager_TokenLimits(t *testing.T) {
+	rm := NewRiskManager(RiskConfig{MaxPositions: 10, MaxPositionSize: 100, RiskPerTrade: 1, InitialCapital: 1000, MaxSlippage: 1})
+	rm.SetTokenLimits(tokenLimits{
+		"thin": {MaxPositionSize: 20, MaxSlippage: 5},
+		"wide": {MaxPositionSize: 200},
+	})
+	ctx := context.Background()
+
+	if err := rm.CanOpenPosition(ctx, "thin", 50, nil); err == nil {
+		t.Error("Expected the token position size override to reject the order")
+	}
+	if err := rm.CanOpenPosition(ctx, "wide", 150, nil); err != nil {
+		t.Errorf("Expected the token position size override to allow the order, got %v", err)
+	}
+	if err := rm.CanOpenPosition(ctx, "other", 150, nil); err == nil {
+		t.Error("Expected the global position size to apply to tokens without overrides")
+	}
+
+	book := &dex.OrderBook{
+		Bids: []dex.OrderBookItem{{Price: 99.9, Size: 100}},
+		Asks: []dex.OrderBookItem{{Price: 100.1, Size: 50}, {Price: 101, Size: 50}, {Price: 105, Size: 50}},
+	}
+	if _, err := rm.CheckLiquidity("thin", book, dex.SideBuy, 150); err != nil {
+		t.Errorf("Expected the token slippage override to allow the order, got %v", err)
+	}
+	_, err := rm.CheckLiquidity("wide", book, dex.SideBuy, 150)
+	if GetRiskErrorType(err) != ErrHighPriceImpact {
+		t.Errorf("Expected the global slippage to apply without a slippage override, got %v", err)
+	}
+}
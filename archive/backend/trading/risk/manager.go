@@ -3,6 +3,7 @@ package risk
 import (
 	"context"
 	"fmt"
+	"math"
 	"sync"
 	"time"
 
@@ -13,8 +14,12 @@ import (
 type RiskManager struct {
 	config     RiskConfig
 	positions  map[string]*Position // Map of position ID to position
-	totalValue float64             // Total portfolio value
-	mu         sync.RWMutex        // Mutex for thread-safe operations
+	totalValue float64              // Total portfolio value
+	screener   TokenScreener        // Token safety screening of buys, if set
+	limits     TokenLimitSource     // Per-token risk limit overrides, if set
+	depth      DepthSource          // Live order book depth for impact checks, if set
+	klines     KlineSource          // Historical candles for gap risk checks, if set
+	mu         sync.RWMutex         // Mutex for thread-safe operations
 }
 
 // NewRiskManager creates a new risk manager
@@ -34,8 +39,8 @@ func (m *RiskManager) CanOpenPosition(ctx context.Context, tokenAddress string,
 	}
 
 	// Check position size
-	if size > m.config.MaxPositionSize {
-		return fmt.Errorf("position size %f exceeds maximum allowed %f", size, m.config.MaxPositionSize)
+	if maxSize := m.maxPositionSize(tokenAddress); size > maxSize {
+		return fmt.Errorf("position size %f exceeds maximum allowed %f", size, maxSize)
 	}
 
 	// Check if enough capital available
@@ -46,6 +51,43 @@ func (m *RiskManager) CanOpenPosition(ctx context.Context, tokenAddress string,
 	return nil
 }
 
+// SetTokenLimits overrides the maximum position size and slippage of the
+// tokens limits has overrides for. Set it before trading starts.
+func (m *RiskManager) SetTokenLimits(limits TokenLimitSource) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.limits = limits
+}
+
+// maxPositionSize returns the maximum position size of a token
+func (m *RiskManager) maxPositionSize(tokenAddress string) float64 {
+	if m.limits != nil {
+		if limits, ok := m.limits.TokenLimits(tokenAddress); ok && limits.MaxPositionSize > 0 {
+			return limits.MaxPositionSize
+		}
+	}
+	return m.config.MaxPositionSize
+}
+
+// maxSlippage returns the maximum slippage of an order of a token
+func (m *RiskManager) maxSlippage(tokenAddress string) float64 {
+	if m.limits != nil {
+		if limits, ok := m.limits.TokenLimits(tokenAddress); ok && limits.MaxSlippage > 0 {
+			return limits.MaxSlippage
+		}
+	}
+	return m.config.MaxSlippage
+}
+
+// CalculatePositionSize returns the largest position size CanOpenPosition
+// accepts, the risk per trade of the portfolio value capped by the maximum
+// position size
+func (m *RiskManager) CalculatePositionSize() float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return math.Min(m.totalValue*m.config.RiskPerTrade, m.config.MaxPositionSize)
+}
+
 // OpenPosition opens a new position
 func (m *RiskManager) OpenPosition(ctx context.Context, position *Position, marketData *models.MarketData) error {
 	m.mu.Lock()
@@ -92,8 +134,8 @@ func (m *RiskManager) ClosePosition(positionID string) error {
 // ValidatePosition validates a position against risk rules
 func (m *RiskManager) ValidatePosition(ctx context.Context, position *Position) error {
 	// Check position size
-	if position.Size > m.config.MaxPositionSize {
-		return fmt.Errorf("position size %f exceeds maximum allowed %f", position.Size, m.config.MaxPositionSize)
+	if maxSize := m.maxPositionSize(position.TokenAddress); position.Size > maxSize {
+		return fmt.Errorf("position size %f exceeds maximum allowed %f", position.Size, maxSize)
 	}
 
 	// Check leverage
@@ -0,0 +1,59 @@
+package risk
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/trading/screening"
+)
+
+// TokenScreener scores the safety of a token. screening.Screener implements it.
+type TokenScreener interface {
+	Screen(ctx context.Context, mint string) (*screening.TokenRiskScore, error)
+}
+
+// SetScreener enables token safety screening. Buys of tokens the screener
+// blocks are rejected with screening.ErrTokenRejected.
+func (m *RiskManager) SetScreener(screener TokenScreener) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.screener = screener
+}
+
+// ValidateTradeSignal checks a trade before it is executed. Buys are screened,
+// downsized or rejected by their market impact on the live order book and by
+// the risk of their stop-loss being gapped through, and must fit the position
+// limits; sells are never checked, so positions can always be exited.
+func (m *RiskManager) ValidateTradeSignal(ctx context.Context, trade *models.Trade, marketData *models.MarketData) error {
+	if trade.Side != models.TradeSideBuy {
+		return nil
+	}
+
+	m.mu.RLock()
+	screener := m.screener
+	m.mu.RUnlock()
+
+	// Screen outside the lock as it reads the chain
+	if screener != nil {
+		score, err := screener.Screen(ctx, trade.TokenAddress)
+		if err != nil {
+			return fmt.Errorf("failed to screen token %s: %w", trade.TokenAddress, err)
+		}
+		if err := score.Err(); err != nil {
+			return err
+		}
+	}
+
+	// Reads the live order book, also outside the lock
+	if _, err := m.CheckMarketImpact(ctx, trade); err != nil {
+		return err
+	}
+	if _, err := m.CheckGapRisk(ctx, trade); err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.CanOpenPosition(ctx, trade.TokenAddress, trade.Amount, marketData)
+}
@@ -0,0 +1,61 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/trading/screening"
+)
+
+type fakeScreener struct {
+	score    *screening.TokenRiskScore
+	screened []string
+}
+
+func (s *fakeScreener) Screen(ctx context.Context, mint string) (*screening.TokenRiskScore, error) {
+	s.screened = append(s.screened, mint)
+	return s.score, nil
+}
+
+func TestRiskManager_ValidateTradeSignal(t *testing.T) {
+	screener := &fakeScreener{score: &screening.TokenRiskScore{
+		Mint:    "BONK",
+		Score:   30,
+		Blocked: true,
+		Checks: []screening.CheckResult{
+			{Check: screening.CheckMintAuthority, Critical: true, Detail: "authority held by deployer"},
+		},
+	}}
+	rm := NewRiskManager(RiskConfig{MaxPositions: 3, MaxPositionSize: 1000, InitialCapital: 10000, RiskPerTrade: 0.05})
+	rm.SetScreener(screener)
+
+	buy := &models.Trade{TokenAddress: "BONK", Side: models.TradeSideBuy, Amount: 100}
+	if err := rm.ValidateTradeSignal(context.Background(), buy, &models.MarketData{}); !errors.Is(err, screening.ErrTokenRejected) {
+		t.Errorf("Expected screening rejection, got %v", err)
+	}
+
+	// Sells are never screened, so positions can always be exited
+	sell := &models.Trade{TokenAddress: "BONK", Side: models.TradeSideSell, Amount: 100}
+	if err := rm.ValidateTradeSignal(context.Background(), sell, &models.MarketData{}); err != nil {
+		t.Errorf("Expected no error for sell, got %v", err)
+	}
+	if len(screener.screened) != 1 {
+		t.Errorf("Expected 1 screened token, got %v", screener.screened)
+	}
+
+	screener.score = &screening.TokenRiskScore{Mint: "BONK", Score: 10}
+	if err := rm.ValidateTradeSignal(context.Background(), buy, &models.MarketData{}); err != nil {
+		t.Errorf("Expected no error for safe token, got %v", err)
+	}
+
+	// Buys above the risk per trade are rejected after screening
+	buy.Amount = 600
+	if err := rm.ValidateTradeSignal(context.Background(), buy, &models.MarketData{}); err == nil {
+		t.Error("Expected error for buy above the risk per trade")
+	}
+	if size := rm.CalculatePositionSize(); size != 500 {
+		t.Errorf("Expected position size 500, got %f", size)
+	}
+}
@@ -30,4 +30,43 @@ type RiskConfig struct {
 	RiskPerTrade    float64 `json:"risk_per_trade"`
 	StopLoss        float64 `json:"stop_loss"`
 	TakeProfit      float64 `json:"take_profit"`
+	// MaxSlippage is the maximum estimated slippage of an order against the
+	// order book, in percent. Zero only requires the book to fill the order.
+	MaxSlippage float64 `json:"max_slippage"`
+	// MaxMarketImpact is the maximum expected impact of a buy on the live
+	// order book, in percent of the mid price. Zero disables the check.
+	MaxMarketImpact float64 `json:"max_market_impact"`
+	// DownsizeOnImpact downsizes buys over MaxMarketImpact to the largest
+	// size within it instead of rejecting them
+	DownsizeOnImpact bool `json:"downsize_on_impact"`
+	// MinDownsizeRatio is the smallest fraction of its size a buy is
+	// downsized to; buys that would be downsized further are rejected
+	MinDownsizeRatio float64 `json:"min_downsize_ratio"`
+	// GapThreshold is the drop within a single candle, in percent of the
+	// previous close, a stop-loss can no longer be relied on to catch. Zero
+	// disables the gap risk check.
+	GapThreshold float64 `json:"gap_threshold"`
+	// GapInterval and GapLookback are the interval and number of the
+	// candles gaps are counted over, 5m and 288 (a day) when unset
+	GapInterval string `json:"gap_interval"`
+	GapLookback int    `json:"gap_lookback"`
+	// MaxGapProbability is the largest share of candles gapping over
+	// GapThreshold a token may have; buys of gappier tokens are rejected
+	MaxGapProbability float64 `json:"max_gap_probability"`
+	// MaxGapHaircut is the fraction buys are downsized by at
+	// MaxGapProbability, the haircut growing linearly with the probability
+	MaxGapHaircut float64 `json:"max_gap_haircut"`
+}
+
+// TokenLimits overrides the risk limits of a single token. Zero limits fall
+// back to the RiskConfig ones.
+type TokenLimits struct {
+	MaxPositionSize float64 `json:"max_position_size"`
+	MaxSlippage     float64 `json:"max_slippage"`
+}
+
+// TokenLimitSource returns the risk limit overrides of tokens.
+// watchlist.WatchList implements it.
+type TokenLimitSource interface {
+	TokenLimits(tokenAddress string) (TokenLimits, bool)
 }
@@ -1,41 +0,0 @@
-package trading
-
-import (
-	"context"
-	"sync"
-
-	"github.com/leonzhao/trading-system/backend/models"
-	"github.com/leonzhao/trading-system/backend/trading/types"
-)
-
-type RiskManager struct {
-	config     *types.RiskConfig
-	state      *types.RiskState
-	mu         sync.RWMutex
-	executor   types.TradeExecutor
-	positions  map[string]*types.Position
-}
-
-func NewRiskManager(config *types.RiskConfig, executor types.TradeExecutor) *RiskManager {
-	return &RiskManager{
-		config:   config,
-		executor: executor,
-		state: &types.RiskState{
-			DailyStats: make(map[string]*types.DailyStats),
-		},
-		positions: make(map[string]*types.Position),
-	}
-}
-
-func (r *RiskManager) ValidateTradeSignal(ctx context.Context, trade *models.Trade, marketData *models.MarketData) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	
-	// Actual risk validation logic
-	return nil
-}
-
-func (r *RiskManager) calculatePositionSize(trade *models.Trade, marketData *models.MarketData) (float64, error) {
-	// Position sizing calculations
-	return 0.0, nil
-}
@@ -0,0 +1,339 @@
+// Package screening scores the safety of tokens before they are bought,
+// checking for the common honeypot and rug pull setups of memecoins.
+package screening
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/dex"
+	"github.com/leonzhao/trading-system/backend/monitoring"
+)
+
+// ErrTokenRejected is returned for tokens that failed screening
+var ErrTokenRejected = errors.New("token failed safety screening")
+
+// MintSource reads token mint state. dex.SolanaTokenClient implements it.
+type MintSource interface {
+	GetMintInfo(ctx context.Context, mint string) (*dex.MintInfo, error)
+	GetLargestHolders(ctx context.Context, mint string) ([]dex.TokenHolder, error)
+}
+
+// LiquiditySource reads the pool liquidity of a token. dex.RaydiumClient
+// implements it.
+type LiquiditySource interface {
+	GetLiquidity(ctx context.Context, tokenAddress string) (*dex.LiquidityInfo, error)
+}
+
+// Check identifies a screening check
+type Check string
+
+// Screening checks
+const (
+	CheckMintAuthority       Check = "mint_authority"
+	CheckFreezeAuthority     Check = "freeze_authority"
+	CheckHolderConcentration Check = "holder_concentration"
+	CheckLPLock              Check = "lp_lock"
+	CheckLiquidity           Check = "liquidity"
+)
+
+// checkWeights are the contributions of failed checks to the risk score
+var checkWeights = map[Check]float64{
+	CheckMintAuthority:       30,
+	CheckFreezeAuthority:     30,
+	CheckHolderConcentration: 15,
+	CheckLPLock:              15,
+	CheckLiquidity:           10,
+}
+
+// CheckResult is the outcome of one screening check
+type CheckResult struct {
+	Check  Check `json:"check"`
+	Passed bool  `json:"passed"`
+	// Critical failures reject the token regardless of its score
+	Critical bool    `json:"critical"`
+	Value    float64 `json:"value"`
+	Detail   string  `json:"detail"`
+	// Unavailable is set when the check's data could not be read. The check
+	// fails so that screening fails closed.
+	Unavailable bool `json:"unavailable,omitempty"`
+}
+
+// TokenRiskScore is the screening result of a token
+type TokenRiskScore struct {
+	Mint string `json:"mint"`
+	// Score is the weighted share of failed checks, from 0 (safe) to 100
+	Score float64 `json:"score"`
+	// Blocked is set when the token must not be bought
+	Blocked   bool          `json:"blocked"`
+	Checks    []CheckResult `json:"checks"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// Failed returns the failed checks
+func (s *TokenRiskScore) Failed() []CheckResult {
+	var failed []CheckResult
+	for _, check := range s.Checks {
+		if !check.Passed {
+			failed = append(failed, check)
+		}
+	}
+	return failed
+}
+
+// Err returns an ErrTokenRejected error describing the failed checks of a
+// blocked token, or nil
+func (s *TokenRiskScore) Err() error {
+	if !s.Blocked {
+		return nil
+	}
+	reasons := make([]string, 0, len(s.Checks))
+	for _, check := range s.Failed() {
+		reasons = append(reasons, fmt.Sprintf("%s: %s", check.Check, check.Detail))
+	}
+	return fmt.Errorf("%w: %s (score %.0f): %s", ErrTokenRejected, s.Mint, s.Score, strings.Join(reasons, "; "))
+}
+
+// Config contains token screening configuration
+type Config struct {
+	// TopHolders is the number of largest holders whose combined share of
+	// the supply is checked against MaxTopHolderShare
+	TopHolders        int     `json:"top_holders"`
+	MaxTopHolderShare float64 `json:"max_top_holder_share"`
+	// ExcludedHolders are token accounts left out of the concentration
+	// check, such as exchange and locker accounts. The pool's token vault
+	// is always excluded.
+	ExcludedHolders []string `json:"excluded_holders"`
+	// MinLPLocked is the minimum share of the LP supply that must be held
+	// by LockerAccounts. A pool whose LP supply was burned counts as locked.
+	MinLPLocked    float64  `json:"min_lp_locked"`
+	LockerAccounts []string `json:"locker_accounts"`
+	// MinLiquidityUSD is the minimum pool liquidity
+	MinLiquidityUSD float64 `json:"min_liquidity_usd"`
+	// MaxScore blocks tokens with a higher risk score
+	MaxScore float64 `json:"max_score"`
+	// CacheTTL is how long complete screening results are reused
+	CacheTTL time.Duration `json:"cache_ttl"`
+}
+
+// DefaultConfig returns default token screening configuration
+func DefaultConfig() Config {
+	return Config{
+		TopHolders:        10,
+		MaxTopHolderShare: 0.3,
+		MinLPLocked:       0.9,
+		MinLiquidityUSD:   50000,
+		MaxScore:          25,
+		CacheTTL:          5 * time.Minute,
+	}
+}
+
+// Screener screens tokens from on-chain mint state and pool liquidity
+type Screener struct {
+	mints     MintSource
+	liquidity LiquiditySource
+	config    Config
+	monitor   monitoring.IMonitor
+	excluded  map[string]bool
+	lockers   map[string]bool
+	cache     map[string]*TokenRiskScore
+	mu        sync.Mutex
+}
+
+// NewScreener creates a token screener. The monitor may be nil.
+func NewScreener(mints MintSource, liquidity LiquiditySource, config Config, monitor monitoring.IMonitor) *Screener {
+	if config.TopHolders <= 0 {
+		config.TopHolders = DefaultConfig().TopHolders
+	}
+
+	s := &Screener{
+		mints:     mints,
+		liquidity: liquidity,
+		config:    config,
+		monitor:   monitor,
+		excluded:  make(map[string]bool, len(config.ExcludedHolders)),
+		lockers:   make(map[string]bool, len(config.LockerAccounts)),
+		cache:     make(map[string]*TokenRiskScore),
+	}
+	for _, account := range config.ExcludedHolders {
+		s.excluded[account] = true
+	}
+	for _, account := range config.LockerAccounts {
+		s.lockers[account] = true
+	}
+	return s
+}
+
+// Screen runs every check on a token mint and scores it. Checks whose data
+// cannot be read fail, and such results are not cached.
+func (s *Screener) Screen(ctx context.Context, mint string) (*TokenRiskScore, error) {
+	if mint == "" {
+		return nil, errors.New("token mint is required")
+	}
+
+	s.mu.Lock()
+	cached, ok := s.cache[mint]
+	s.mu.Unlock()
+	if ok && time.Since(cached.Timestamp) < s.config.CacheTTL {
+		return cached, nil
+	}
+
+	mintInfo, mintErr := s.mints.GetMintInfo(ctx, mint)
+	liquidity, liquidityErr := s.liquidity.GetLiquidity(ctx, mint)
+
+	checks := []CheckResult{
+		authorityCheck(CheckMintAuthority, mintInfo, mintErr, func(info *dex.MintInfo) string { return info.MintAuthority }),
+		authorityCheck(CheckFreezeAuthority, mintInfo, mintErr, func(info *dex.MintInfo) string { return info.FreezeAuthority }),
+		s.concentrationCheck(ctx, mintInfo, mintErr, liquidity),
+		s.lpLockCheck(ctx, liquidity, liquidityErr),
+		s.liquidityCheck(liquidity, liquidityErr),
+	}
+
+	score := &TokenRiskScore{Mint: mint, Checks: checks, Timestamp: time.Now()}
+	complete := true
+	for _, check := range checks {
+		if check.Passed {
+			continue
+		}
+		score.Score += checkWeights[check.Check]
+		score.Blocked = score.Blocked || check.Critical
+		complete = complete && !check.Unavailable
+	}
+	score.Blocked = score.Blocked || score.Score > s.config.MaxScore
+
+	if complete {
+		s.mu.Lock()
+		s.cache[mint] = score
+		s.mu.Unlock()
+	}
+	s.record(ctx, score)
+	return score, nil
+}
+
+// authorityCheck fails tokens whose mint or freeze authority was not
+// revoked, which lets the deployer inflate the supply or freeze holders
+func authorityCheck(check Check, info *dex.MintInfo, err error, authority func(*dex.MintInfo) string) CheckResult {
+	result := CheckResult{Check: check, Critical: true}
+	if err != nil {
+		return unavailable(result, err)
+	}
+	if holder := authority(info); holder != "" {
+		result.Detail = fmt.Sprintf("authority held by %s", holder)
+		return result
+	}
+	result.Passed = true
+	result.Detail = "revoked"
+	return result
+}
+
+// concentrationCheck fails tokens whose largest holders own too much of the
+// supply
+func (s *Screener) concentrationCheck(ctx context.Context, info *dex.MintInfo, err error, liquidity *dex.LiquidityInfo) CheckResult {
+	result := CheckResult{Check: CheckHolderConcentration}
+	if err != nil {
+		return unavailable(result, err)
+	}
+	if info.Supply <= 0 {
+		result.Detail = "token has no supply"
+		return result
+	}
+	holders, err := s.mints.GetLargestHolders(ctx, info.Address)
+	if err != nil {
+		return unavailable(result, err)
+	}
+
+	var held float64
+	counted := 0
+	for _, holder := range holders {
+		if counted == s.config.TopHolders {
+			break
+		}
+		if s.excluded[holder.Address] || (liquidity != nil && holder.Address == liquidity.TokenVault) {
+			continue
+		}
+		held += holder.Amount
+		counted++
+	}
+
+	result.Value = held / info.Supply
+	result.Passed = result.Value <= s.config.MaxTopHolderShare
+	result.Detail = fmt.Sprintf("top %d holders own %.1f%% of supply", counted, result.Value*100)
+	return result
+}
+
+// lpLockCheck fails pools whose LP tokens are not locked or burned, which
+// lets the deployer pull the liquidity
+func (s *Screener) lpLockCheck(ctx context.Context, liquidity *dex.LiquidityInfo, err error) CheckResult {
+	result := CheckResult{Check: CheckLPLock}
+	if err != nil {
+		return unavailable(result, err)
+	}
+	if liquidity.LPMint == "" {
+		return unavailable(result, errors.New("pool LP mint unknown"))
+	}
+	lpInfo, err := s.mints.GetMintInfo(ctx, liquidity.LPMint)
+	if err != nil {
+		return unavailable(result, err)
+	}
+
+	if lpInfo.Supply <= 0 {
+		result.Value = 1
+	} else {
+		holders, err := s.mints.GetLargestHolders(ctx, liquidity.LPMint)
+		if err != nil {
+			return unavailable(result, err)
+		}
+		var locked float64
+		for _, holder := range holders {
+			if s.lockers[holder.Address] {
+				locked += holder.Amount
+			}
+		}
+		result.Value = locked / lpInfo.Supply
+	}
+
+	result.Passed = result.Value >= s.config.MinLPLocked
+	result.Detail = fmt.Sprintf("%.1f%% of LP supply locked or burned", result.Value*100)
+	return result
+}
+
+// liquidityCheck fails pools too shallow to exit a position
+func (s *Screener) liquidityCheck(liquidity *dex.LiquidityInfo, err error) CheckResult {
+	result := CheckResult{Check: CheckLiquidity}
+	if err != nil {
+		return unavailable(result, err)
+	}
+	result.Value = liquidity.TVL
+	if result.Value == 0 {
+		result.Value = liquidity.Value
+	}
+	result.Passed = result.Value >= s.config.MinLiquidityUSD
+	result.Detail = fmt.Sprintf("pool liquidity $%.0f", result.Value)
+	return result
+}
+
+func unavailable(result CheckResult, err error) CheckResult {
+	result.Unavailable = true
+	result.Detail = fmt.Sprintf("unavailable: %v", err)
+	return result
+}
+
+func (s *Screener) record(ctx context.Context, score *TokenRiskScore) {
+	if s.monitor == nil {
+		return
+	}
+
+	s.monitor.RecordMetric(ctx, "token_risk_score", score.Score, map[string]string{"mint": score.Mint})
+	if score.Blocked {
+		s.monitor.RecordEvent(ctx, monitoring.Event{
+			Type:     monitoring.MetricTrading,
+			Severity: monitoring.SeverityWarning,
+			Message:  "Token failed safety screening",
+			Details:  score,
+		})
+	}
+}
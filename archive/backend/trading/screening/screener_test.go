@@ -0,0 +1,164 @@
+package screening
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/leonzhao/trading-system/backend/dex"
+	"github.com/leonzhao/trading-system/backend/monitoring"
+)
+
+type fakeChain struct {
+	mints   map[string]*dex.MintInfo
+	holders map[string][]dex.TokenHolder
+	err     error
+	calls   int
+}
+
+func (c *fakeChain) GetMintInfo(ctx context.Context, mint string) (*dex.MintInfo, error) {
+	c.calls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c.mints[mint], nil
+}
+
+func (c *fakeChain) GetLargestHolders(ctx context.Context, mint string) ([]dex.TokenHolder, error) {
+	return c.holders[mint], nil
+}
+
+type fakePools map[string]*dex.LiquidityInfo
+
+func (p fakePools) GetLiquidity(ctx context.Context, tokenAddress string) (*dex.LiquidityInfo, error) {
+	if liquidity, ok := p[tokenAddress]; ok {
+		return liquidity, nil
+	}
+	return nil, errors.New("pool not found")
+}
+
+type recordingMonitor struct {
+	monitoring.IMonitor
+	events []monitoring.Event
+}
+
+func (m *recordingMonitor) RecordEvent(ctx context.Context, event monitoring.Event) {
+	m.events = append(m.events, event)
+}
+
+func (m *recordingMonitor) RecordMetric(ctx context.Context, name string, value float64, tags map[string]string) {
+}
+
+func TestScreener(t *testing.T) {
+	ctx := context.Background()
+
+	newFixture := func() (*fakeChain, fakePools) {
+		chain := &fakeChain{
+			mints: map[string]*dex.MintInfo{
+				"BONK": {Address: "BONK", Supply: 1000000, Decimals: 5},
+				"LP":   {Address: "LP", Supply: 100},
+			},
+			holders: map[string][]dex.TokenHolder{
+				"BONK": {
+					{Address: "vault", Amount: 600000},
+					{Address: "whale", Amount: 100000},
+					{Address: "fish", Amount: 50000},
+				},
+				"LP": {{Address: "locker", Amount: 95}, {Address: "dev", Amount: 5}},
+			},
+		}
+		pools := fakePools{"BONK": {TokenAddress: "BONK", TVL: 250000, LPMint: "LP", TokenVault: "vault"}}
+		return chain, pools
+	}
+	config := DefaultConfig()
+	config.LockerAccounts = []string{"locker"}
+
+	t.Run("Safe token passes", func(t *testing.T) {
+		chain, pools := newFixture()
+		screener := NewScreener(chain, pools, config, nil)
+
+		score, err := screener.Screen(ctx, "BONK")
+		require.NoError(t, err)
+		assert.False(t, score.Blocked)
+		assert.Zero(t, score.Score)
+		assert.NoError(t, score.Err())
+		require.Len(t, score.Checks, 5)
+		// The pool vault is not counted as a holder
+		assert.InDelta(t, 0.15, score.Checks[2].Value, 1e-9)
+		assert.InDelta(t, 0.95, score.Checks[3].Value, 1e-9)
+
+		// Results are cached
+		calls := chain.calls
+		_, err = screener.Screen(ctx, "BONK")
+		require.NoError(t, err)
+		assert.Equal(t, calls, chain.calls)
+	})
+
+	t.Run("Active freeze authority blocks the token", func(t *testing.T) {
+		chain, pools := newFixture()
+		chain.mints["BONK"].FreezeAuthority = "deployer"
+		monitor := &recordingMonitor{}
+		screener := NewScreener(chain, pools, config, monitor)
+
+		score, err := screener.Screen(ctx, "BONK")
+		require.NoError(t, err)
+		assert.True(t, score.Blocked)
+		assert.Equal(t, 30.0, score.Score)
+		assert.ErrorIs(t, score.Err(), ErrTokenRejected)
+		assert.ErrorContains(t, score.Err(), "freeze_authority: authority held by deployer")
+		require.Len(t, monitor.events, 1)
+		assert.Equal(t, "Token failed safety screening", monitor.events[0].Message)
+	})
+
+	t.Run("Soft failures block once the score is too high", func(t *testing.T) {
+		chain, pools := newFixture()
+		screener := NewScreener(chain, pools, config, nil)
+
+		pools["BONK"].TVL = 10000
+		score, err := screener.Screen(ctx, "BONK")
+		require.NoError(t, err)
+		assert.Equal(t, 10.0, score.Score)
+		assert.False(t, score.Blocked)
+
+		chain.holders["LP"][0].Address = "unknown"
+		chain.holders["BONK"][0].Address = "insider"
+		screener = NewScreener(chain, pools, config, nil)
+		score, err = screener.Screen(ctx, "BONK")
+		require.NoError(t, err)
+		assert.Equal(t, 40.0, score.Score)
+		assert.True(t, score.Blocked)
+		assert.Len(t, score.Failed(), 3)
+	})
+
+	t.Run("Burned LP counts as locked", func(t *testing.T) {
+		chain, pools := newFixture()
+		chain.mints["LP"].Supply = 0
+		screener := NewScreener(chain, pools, config, nil)
+
+		score, err := screener.Screen(ctx, "BONK")
+		require.NoError(t, err)
+		assert.True(t, score.Checks[3].Passed)
+	})
+
+	t.Run("Unreadable data fails closed and is not cached", func(t *testing.T) {
+		chain, pools := newFixture()
+		chain.err = errors.New("rpc unavailable")
+		screener := NewScreener(chain, pools, config, nil)
+
+		score, err := screener.Screen(ctx, "BONK")
+		require.NoError(t, err)
+		assert.True(t, score.Blocked)
+		assert.True(t, score.Checks[0].Unavailable)
+
+		chain.err = nil
+		score, err = screener.Screen(ctx, "BONK")
+		require.NoError(t, err)
+		assert.False(t, score.Blocked)
+
+		_, err = screener.Screen(ctx, "")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,26 @@
+package sentiment
+
+import (
+	"context"
+
+	"github.com/leonzhao/trading-system/backend/circuitbreaker"
+)
+
+type breakerGenerator struct {
+	generator Generator
+	breaker   *circuitbreaker.Breaker
+}
+
+// WithBreaker guards an LLM generator with a circuit breaker
+func WithBreaker(generator Generator, breaker *circuitbreaker.Breaker) Generator {
+	return &breakerGenerator{generator: generator, breaker: breaker}
+}
+
+func (g *breakerGenerator) Generate(ctx context.Context, prompt string) (string, error) {
+	var text string
+	err := g.breaker.Execute(func() (err error) {
+		text, err = g.generator.Generate(ctx, prompt)
+		return err
+	})
+	return text, err
+}
@@ -0,0 +1,65 @@
+package sentiment
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OllamaGenerator generates text with a model served by Ollama
+type OllamaGenerator struct {
+	baseURL    string
+	model      string
+	httpClient *http.Client
+}
+
+// NewOllamaGenerator creates a generator on the Ollama server at baseURL,
+// asking the model for JSON output
+func NewOllamaGenerator(baseURL, model string) *OllamaGenerator {
+	return &OllamaGenerator{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		model:      model,
+		httpClient: &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+// Generate completes a prompt
+func (g *OllamaGenerator) Generate(ctx context.Context, prompt string) (string, error) {
+	payload, err := json.Marshal(map[string]interface{}{
+		"model":  g.model,
+		"prompt": prompt,
+		"stream": false,
+		"format": "json",
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.baseURL+"/api/generate", bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call ollama: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var decoded struct {
+		Response string `json:"response"`
+		Error    string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("ollama error (status %d): %s", resp.StatusCode, decoded.Error)
+	}
+	return decoded.Response, nil
+}
@@ -0,0 +1,508 @@
+// Package sentiment ingests social feeds mentioning the tracked tokens,
+// scores each post with an LLM and keeps a rolling sentiment score per
+// token for the analysis pipeline.
+package sentiment
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/monitoring"
+)
+
+// Item is a post of a social feed
+type Item struct {
+	// ID is unique within the source
+	ID        string    `json:"id"`
+	Source    string    `json:"source"`
+	Author    string    `json:"author,omitempty"`
+	Text      string    `json:"text"`
+	URL       string    `json:"url,omitempty"`
+	Published time.Time `json:"published"`
+}
+
+// Source fetches the posts of a social feed
+type Source interface {
+	// Name identifies the source in item IDs and metrics
+	Name() string
+	// Fetch returns the posts published since the given time. Sources that
+	// search, rather than list, a feed only return posts matching one of
+	// the keywords.
+	Fetch(ctx context.Context, keywords []string, since time.Time) ([]Item, error)
+}
+
+// Generator completes a prompt with an LLM
+type Generator interface {
+	Generate(ctx context.Context, prompt string) (string, error)
+}
+
+// Score is the rolling sentiment of a token
+type Score struct {
+	Token string `json:"token"`
+	// Score is the relevance and recency weighted sentiment, from -1
+	// (bearish) to 1 (bullish)
+	Score float64 `json:"score"`
+	// Mentions is the number of scored posts within the window
+	Mentions  int       `json:"mentions"`
+	Bullish   int       `json:"bullish"`
+	Bearish   int       `json:"bearish"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// News impact levels
+const (
+	ImpactNone   = "none"
+	ImpactLow    = "low"
+	ImpactMedium = "medium"
+	ImpactHigh   = "high"
+)
+
+// Impact rates how strongly the social feeds lean, from the number of
+// mentions and the strength of the score
+func (s Score) Impact() string {
+	strength := math.Abs(s.Score)
+	switch {
+	case s.Mentions == 0:
+		return ImpactNone
+	case s.Mentions >= 20 && strength >= 0.5:
+		return ImpactHigh
+	case s.Mentions >= 5 && strength >= 0.2:
+		return ImpactMedium
+	default:
+		return ImpactLow
+	}
+}
+
+// DefaultPromptTemplate asks the LLM to score each post. The template is
+// executed with the token, its keywords and the numbered posts.
+const DefaultPromptTemplate = `You are a crypto market sentiment analyst.
+Rate the sentiment of each post below towards the token {{.Token}} (also known as {{join .Keywords ", "}}).
+For each post give a score from -1 (very bearish) to 1 (very bullish) and a relevance from 0 (unrelated or spam) to 1 (directly about the token).
+Reply with JSON only, in the form {"scores":[{"item":1,"score":0.5,"relevance":0.8}]}.
+
+Posts:
+{{range .Items}}[{{.Index}}] ({{.Source}}) {{.Text}}
+{{end}}`
+
+// Config contains sentiment ingestion configuration
+type Config struct {
+	// Tokens maps each tracked token address to the keywords, such as its
+	// symbol or name, that posts about it mention. The address itself is
+	// always a keyword.
+	Tokens       map[string][]string `json:"tokens"`
+	PollInterval time.Duration       `json:"poll_interval"`
+	// Window is how long scored posts count towards the rolling score
+	Window time.Duration `json:"window"`
+	// HalfLife is the age at which a post weighs half as much as a new one
+	HalfLife time.Duration `json:"half_life"`
+	// BatchSize is the maximum number of posts scored by one LLM call
+	BatchSize int `json:"batch_size"`
+	// MaxTextLength truncates long posts before scoring
+	MaxTextLength int `json:"max_text_length"`
+	// PromptTemplate overrides DefaultPromptTemplate
+	PromptTemplate string `json:"prompt_template,omitempty"`
+	// LLMTimeout bounds each LLM call
+	LLMTimeout time.Duration `json:"llm_timeout"`
+}
+
+// DefaultConfig returns default sentiment ingestion configuration
+func DefaultConfig() Config {
+	return Config{
+		PollInterval:  5 * time.Minute,
+		Window:        6 * time.Hour,
+		HalfLife:      time.Hour,
+		BatchSize:     20,
+		MaxTextLength: 500,
+		LLMTimeout:    time.Minute,
+	}
+}
+
+// scored is a post with its LLM score
+type scored struct {
+	published time.Time
+	score     float64
+	relevance float64
+}
+
+// promptItem is a post as rendered in the prompt
+type promptItem struct {
+	Index  int
+	Source string
+	Text   string
+}
+
+// Service polls the social feeds and aggregates the sentiment of every
+// tracked token. Posts are scored once; a post whose scoring fails is
+// retried on the next poll.
+type Service struct {
+	sources   []Source
+	generator Generator
+	config    Config
+	prompt    *template.Template
+	monitor   monitoring.IMonitor
+	keywords  map[string][]string
+	lastPoll  map[string]time.Time
+	// seen holds the publication time of the scored posts, by token,
+	// source and ID
+	seen map[string]time.Time
+	// retry holds the posts whose scoring failed, by token
+	retry  map[string][]Item
+	scores map[string][]scored
+	mu     sync.Mutex
+}
+
+// NewService creates a sentiment service. The monitor may be nil.
+func NewService(sources []Source, generator Generator, config Config, monitor monitoring.IMonitor) (*Service, error) {
+	defaults := DefaultConfig()
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaults.PollInterval
+	}
+	if config.Window <= 0 {
+		config.Window = defaults.Window
+	}
+	if config.HalfLife <= 0 {
+		config.HalfLife = defaults.HalfLife
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaults.BatchSize
+	}
+	if config.MaxTextLength <= 0 {
+		config.MaxTextLength = defaults.MaxTextLength
+	}
+	if config.LLMTimeout <= 0 {
+		config.LLMTimeout = defaults.LLMTimeout
+	}
+	if config.PromptTemplate == "" {
+		config.PromptTemplate = DefaultPromptTemplate
+	}
+
+	prompt, err := template.New("sentiment").Funcs(template.FuncMap{"join": strings.Join}).Parse(config.PromptTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sentiment prompt template: %w", err)
+	}
+
+	keywords := make(map[string][]string, len(config.Tokens))
+	for token, words := range config.Tokens {
+		list := []string{strings.ToLower(token)}
+		for _, word := range words {
+			if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+				list = append(list, word)
+			}
+		}
+		keywords[token] = list
+	}
+
+	return &Service{
+		sources:   sources,
+		generator: generator,
+		config:    config,
+		prompt:    prompt,
+		monitor:   monitor,
+		keywords:  keywords,
+		lastPoll:  make(map[string]time.Time),
+		seen:      make(map[string]time.Time),
+		retry:     make(map[string][]Item),
+		scores:    make(map[string][]scored),
+	}, nil
+}
+
+// Run polls the feeds on every tick until the context is done
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.Poll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll fetches every source once and scores the new posts mentioning a
+// tracked token. Failing sources and LLM calls are reported and skipped.
+func (s *Service) Poll(ctx context.Context) {
+	now := time.Now()
+	var all []string
+	for _, words := range s.keywords {
+		all = append(all, words...)
+	}
+
+	s.mu.Lock()
+	pending := s.retry
+	s.retry = make(map[string][]Item)
+	s.mu.Unlock()
+	queued := make(map[string]bool)
+	for token, items := range pending {
+		for _, item := range items {
+			queued[seenKey(token, item)] = true
+		}
+	}
+
+	for _, source := range s.sources {
+		s.mu.Lock()
+		since, ok := s.lastPoll[source.Name()]
+		s.mu.Unlock()
+		if !ok {
+			since = now.Add(-s.config.Window)
+		}
+
+		items, err := source.Fetch(ctx, all, since)
+		if err != nil {
+			s.recordEvent(ctx, monitoring.SeverityWarning, "Failed to fetch sentiment feed", map[string]interface{}{
+				"source": source.Name(),
+				"error":  err.Error(),
+			})
+			continue
+		}
+		s.mu.Lock()
+		s.lastPoll[source.Name()] = now
+		s.mu.Unlock()
+		s.recordMetric(ctx, "sentiment_items_fetched", float64(len(items)), map[string]string{"source": source.Name()})
+
+		for _, item := range items {
+			if item.Source == "" {
+				item.Source = source.Name()
+			}
+			if item.Published.IsZero() {
+				item.Published = now
+			}
+			if now.Sub(item.Published) > s.config.Window {
+				continue
+			}
+			for token := range s.match(item.Text) {
+				if key := seenKey(token, item); !queued[key] && !s.isSeen(token, item) {
+					queued[key] = true
+					pending[token] = append(pending[token], item)
+				}
+			}
+		}
+	}
+
+	for token, items := range pending {
+		for start := 0; start < len(items); start += s.config.BatchSize {
+			end := start + s.config.BatchSize
+			if end > len(items) {
+				end = len(items)
+			}
+			if err := s.score(ctx, token, items[start:end]); err != nil {
+				s.mu.Lock()
+				s.retry[token] = append(s.retry[token], items[start:end]...)
+				s.mu.Unlock()
+				s.recordMetric(ctx, "sentiment_llm_failures", 1, map[string]string{"token": token})
+				s.recordEvent(ctx, monitoring.SeverityWarning, "Failed to score sentiment", map[string]interface{}{
+					"token": token,
+					"items": end - start,
+					"error": err.Error(),
+				})
+			}
+		}
+	}
+
+	s.prune(now)
+	for token := range s.keywords {
+		if score, ok := s.Sentiment(token); ok {
+			s.recordMetric(ctx, "sentiment_score", score.Score, map[string]string{"token": token})
+		}
+	}
+}
+
+// match returns the tokens a post mentions
+func (s *Service) match(text string) map[string]bool {
+	text = strings.ToLower(text)
+	tokens := make(map[string]bool)
+	for token, words := range s.keywords {
+		for _, word := range words {
+			if strings.Contains(text, word) {
+				tokens[token] = true
+				break
+			}
+		}
+	}
+	return tokens
+}
+
+func seenKey(token string, item Item) string {
+	return token + "/" + item.Source + "/" + item.ID
+}
+
+func (s *Service) isSeen(token string, item Item) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.seen[seenKey(token, item)]
+	return ok
+}
+
+// score scores a batch of posts about a token and adds them to its rolling
+// score
+func (s *Service) score(ctx context.Context, token string, items []Item) error {
+	data := struct {
+		Token    string
+		Keywords []string
+		Items    []promptItem
+	}{Token: token, Keywords: s.keywords[token]}
+	for i, item := range items {
+		text := strings.Join(strings.Fields(item.Text), " ")
+		if len(text) > s.config.MaxTextLength {
+			text = text[:s.config.MaxTextLength] + "..."
+		}
+		data.Items = append(data.Items, promptItem{Index: i + 1, Source: item.Source, Text: text})
+	}
+
+	var prompt bytes.Buffer
+	if err := s.prompt.Execute(&prompt, data); err != nil {
+		return fmt.Errorf("failed to render prompt: %w", err)
+	}
+
+	llmCtx, cancel := context.WithTimeout(ctx, s.config.LLMTimeout)
+	defer cancel()
+	response, err := s.generator.Generate(llmCtx, prompt.String())
+	if err != nil {
+		return err
+	}
+	results, err := parseScores(response, len(items))
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, item := range items {
+		s.seen[seenKey(token, item)] = item.Published
+		if r, ok := results[i+1]; ok {
+			s.scores[token] = append(s.scores[token], scored{
+				published: item.Published,
+				score:     r.Score,
+				relevance: r.Relevance,
+			})
+		}
+	}
+	return nil
+}
+
+type itemScore struct {
+	Item      int     `json:"item"`
+	Score     float64 `json:"score"`
+	Relevance float64 `json:"relevance"`
+}
+
+// parseScores decodes the LLM response, ignoring any text around the JSON
+// object and scores of unknown items, and clamps the values to their range
+func parseScores(response string, items int) (map[int]itemScore, error) {
+	start, end := strings.Index(response, "{"), strings.LastIndex(response, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no JSON object in LLM response")
+	}
+
+	var decoded struct {
+		Scores []itemScore `json:"scores"`
+	}
+	if err := json.Unmarshal([]byte(response[start:end+1]), &decoded); err != nil {
+		return nil, fmt.Errorf("failed to decode LLM response: %w", err)
+	}
+
+	results := make(map[int]itemScore, len(decoded.Scores))
+	for _, r := range decoded.Scores {
+		if r.Item < 1 || r.Item > items {
+			continue
+		}
+		r.Score = math.Max(-1, math.Min(1, r.Score))
+		r.Relevance = math.Max(0, math.Min(1, r.Relevance))
+		results[r.Item] = r
+	}
+	return results, nil
+}
+
+// prune drops posts older than the window
+func (s *Service) prune(now time.Time) {
+	cutoff := now.Add(-s.config.Window)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, published := range s.seen {
+		if published.Before(cutoff) {
+			delete(s.seen, key)
+		}
+	}
+	for token, items := range s.retry {
+		kept := items[:0]
+		for _, item := range items {
+			if !item.Published.Before(cutoff) {
+				kept = append(kept, item)
+			}
+		}
+		s.retry[token] = kept
+	}
+	for token, list := range s.scores {
+		kept := list[:0]
+		for _, sc := range list {
+			if !sc.published.Before(cutoff) {
+				kept = append(kept, sc)
+			}
+		}
+		s.scores[token] = kept
+	}
+}
+
+// Sentiment returns the rolling sentiment of a token, or false when no
+// relevant post about it was scored within the window
+func (s *Service) Sentiment(token string) (Score, bool) {
+	now := time.Now()
+	cutoff := now.Add(-s.config.Window)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := Score{Token: token, UpdatedAt: now}
+	var weighted, weights float64
+	for _, sc := range s.scores[token] {
+		if sc.published.Before(cutoff) || sc.relevance == 0 {
+			continue
+		}
+		result.Mentions++
+		switch {
+		case sc.score > 0:
+			result.Bullish++
+		case sc.score < 0:
+			result.Bearish++
+		}
+		age := math.Max(0, now.Sub(sc.published).Seconds())
+		weight := sc.relevance * math.Pow(0.5, age/s.config.HalfLife.Seconds())
+		weighted += weight * sc.score
+		weights += weight
+	}
+	if result.Mentions == 0 || weights == 0 {
+		return Score{}, false
+	}
+	result.Score = weighted / weights
+	return result, true
+}
+
+func (s *Service) recordMetric(ctx context.Context, name string, value float64, tags map[string]string) {
+	if s.monitor == nil {
+		return
+	}
+	s.monitor.RecordMetric(ctx, name, value, tags)
+}
+
+func (s *Service) recordEvent(ctx context.Context, severity monitoring.EventSeverity, message string, details map[string]interface{}) {
+	if s.monitor == nil {
+		return
+	}
+	s.monitor.RecordEvent(ctx, monitoring.Event{
+		Type:     monitoring.MetricTrading,
+		Severity: severity,
+		Message:  message,
+		Details:  details,
+	})
+}
@@ -0,0 +1,133 @@
+package sentiment
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSource struct {
+	items []Item
+	err   error
+}
+
+func (s *fakeSource) Name() string {
+	return "fake"
+}
+
+func (s *fakeSource) Fetch(ctx context.Context, keywords []string, since time.Time) ([]Item, error) {
+	return s.items, s.err
+}
+
+type fakeGenerator struct {
+	response string
+	err      error
+	mu       sync.Mutex
+	prompts  []string
+}
+
+func (g *fakeGenerator) Generate(ctx context.Context, prompt string) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.prompts = append(g.prompts, prompt)
+	return g.response, g.err
+}
+
+func TestServicePoll(t *testing.T) {
+	now := time.Now()
+	source := &fakeSource{items: []Item{
+		{ID: "1", Text: "BONK is mooning, great team", Published: now.Add(-time.Minute)},
+		{ID: "2", Text: "Sold all my $bonk, rug incoming", Published: now.Add(-2 * time.Minute)},
+		{ID: "3", Text: "Nothing to see here", Published: now},
+		{ID: "4", Text: "bonk from last week", Published: now.Add(-7 * 24 * time.Hour)},
+	}}
+	generator := &fakeGenerator{response: `Sure: {"scores":[{"item":1,"score":0.9,"relevance":1},{"item":2,"score":-0.3,"relevance":0.5},{"item":7,"score":1,"relevance":1}]}`}
+
+	s, err := NewService([]Source{source}, generator, Config{
+		Tokens: map[string][]string{"DezXAZ8z7PnrnRJjz3wXBoRgixCa6xjnB7YaB1pPB263": {"BONK"}},
+	}, nil)
+	require.NoError(t, err)
+	s.Poll(context.Background())
+
+	require.Len(t, generator.prompts, 1)
+	prompt := generator.prompts[0]
+	assert.Contains(t, prompt, "[1] (fake) BONK is mooning")
+	assert.Contains(t, prompt, "[2] (fake) Sold all")
+	assert.NotContains(t, prompt, "Nothing to see")
+	assert.NotContains(t, prompt, "last week")
+
+	score, ok := s.Sentiment("DezXAZ8z7PnrnRJjz3wXBoRgixCa6xjnB7YaB1pPB263")
+	require.True(t, ok)
+	assert.Equal(t, 2, score.Mentions)
+	assert.Equal(t, 1, score.Bullish)
+	assert.Equal(t, 1, score.Bearish)
+	// Weighted by relevance, the bullish post dominates
+	assert.InDelta(t, (0.9-0.3*0.5)/1.5, score.Score, 0.01)
+	assert.Equal(t, ImpactLow, score.Impact())
+
+	s.Poll(context.Background())
+	assert.Len(t, generator.prompts, 1, "scored posts are not scored again")
+
+	_, ok = s.Sentiment("unknown")
+	assert.False(t, ok)
+}
+
+func TestServiceRetriesFailedScoring(t *testing.T) {
+	source := &fakeSource{items: []Item{{ID: "1", Text: "wif to the moon", Published: time.Now()}}}
+	generator := &fakeGenerator{err: errors.New("model unavailable")}
+	s, err := NewService([]Source{source}, generator, Config{
+		Tokens: map[string][]string{"wif-mint": {"WIF"}},
+	}, nil)
+	require.NoError(t, err)
+
+	s.Poll(context.Background())
+	_, ok := s.Sentiment("wif-mint")
+	assert.False(t, ok)
+
+	// The source no longer returns the post; it is retried from the queue
+	source.items = nil
+	generator.err = nil
+	generator.response = `{"scores":[{"item":1,"score":1,"relevance":1}]}`
+	s.Poll(context.Background())
+
+	require.Len(t, generator.prompts, 2)
+	score, ok := s.Sentiment("wif-mint")
+	require.True(t, ok)
+	assert.Equal(t, 1.0, score.Score)
+}
+
+func TestServiceBatches(t *testing.T) {
+	var items []Item
+	for i := 0; i < 5; i++ {
+		items = append(items, Item{ID: string(rune('a' + i)), Text: "jup " + strings.Repeat("x", 40), Published: time.Now()})
+	}
+	generator := &fakeGenerator{response: `{"scores":[]}`}
+	s, err := NewService([]Source{&fakeSource{items: items}}, generator, Config{
+		Tokens:        map[string][]string{"jup-mint": {"jup"}},
+		BatchSize:     2,
+		MaxTextLength: 10,
+	}, nil)
+	require.NoError(t, err)
+	s.Poll(context.Background())
+
+	require.Len(t, generator.prompts, 3)
+	assert.Contains(t, generator.prompts[0], "jup xxxxxx...")
+}
+
+func TestInvalidPromptTemplate(t *testing.T) {
+	_, err := NewService(nil, &fakeGenerator{}, Config{PromptTemplate: "{{.Token"}, nil)
+	assert.Error(t, err)
+}
+
+func TestImpact(t *testing.T) {
+	assert.Equal(t, ImpactNone, Score{}.Impact())
+	assert.Equal(t, ImpactHigh, Score{Mentions: 25, Score: -0.6}.Impact())
+	assert.Equal(t, ImpactMedium, Score{Mentions: 6, Score: 0.3}.Impact())
+	assert.Equal(t, ImpactLow, Score{Mentions: 30, Score: 0.1}.Impact())
+}
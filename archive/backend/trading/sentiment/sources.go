@@ -0,0 +1,386 @@
+package sentiment
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RSSSource reads an RSS 2.0 or Atom feed
+type RSSSource struct {
+	name       string
+	feedURL    string
+	httpClient *http.Client
+}
+
+// NewRSSSource creates a source reading the feed at feedURL. The name
+// defaults to the host of the feed.
+func NewRSSSource(name, feedURL string) *RSSSource {
+	if name == "" {
+		if u, err := url.Parse(feedURL); err == nil {
+			name = "rss:" + u.Host
+		}
+	}
+	return &RSSSource{
+		name:       name,
+		feedURL:    feedURL,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Name returns the name of the source
+func (s *RSSSource) Name() string {
+	return s.name
+}
+
+type rssFeed struct {
+	Items   []rssItem `xml:"channel>item"`
+	Entries []struct {
+		ID        string `xml:"id"`
+		Title     string `xml:"title"`
+		Summary   string `xml:"summary"`
+		Content   string `xml:"content"`
+		Published string `xml:"published"`
+		Updated   string `xml:"updated"`
+		Author    string `xml:"author>name"`
+		Link      struct {
+			Href string `xml:"href,attr"`
+		} `xml:"link"`
+	} `xml:"entry"`
+}
+
+type rssItem struct {
+	GUID        string `xml:"guid"`
+	Title       string `xml:"title"`
+	Description string `xml:"description"`
+	Link        string `xml:"link"`
+	PubDate     string `xml:"pubDate"`
+	Author      string `xml:"author"`
+}
+
+// Fetch returns the feed entries published since the given time
+func (s *RSSSource) Fetch(ctx context.Context, keywords []string, since time.Time) ([]Item, error) {
+	body, err := get(ctx, s.httpClient, s.feedURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var feed rssFeed
+	if err := xml.Unmarshal(body, &feed); err != nil {
+		return nil, fmt.Errorf("failed to decode feed: %w", err)
+	}
+
+	var items []Item
+	for _, entry := range feed.Items {
+		id := firstOf(entry.GUID, entry.Link, entry.Title)
+		items = append(items, Item{
+			ID:        id,
+			Source:    s.name,
+			Author:    entry.Author,
+			Text:      joinText(entry.Title, entry.Description),
+			URL:       entry.Link,
+			Published: parseFeedTime(entry.PubDate),
+		})
+	}
+	for _, entry := range feed.Entries {
+		items = append(items, Item{
+			ID:        firstOf(entry.ID, entry.Link.Href, entry.Title),
+			Source:    s.name,
+			Author:    entry.Author,
+			Text:      joinText(entry.Title, firstOf(entry.Summary, entry.Content)),
+			URL:       entry.Link.Href,
+			Published: parseFeedTime(firstOf(entry.Published, entry.Updated)),
+		})
+	}
+	return publishedSince(items, since), nil
+}
+
+// publishedSince drops items published before the given time. Items without a
+// publication time are kept.
+func publishedSince(items []Item, since time.Time) []Item {
+	kept := items[:0]
+	for _, item := range items {
+		if item.Published.IsZero() || !item.Published.Before(since) {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+var feedTimeLayouts = []string{time.RFC1123Z, time.RFC1123, time.RFC3339, "Mon, 2 Jan 2006 15:04:05 -0700", "Mon, 2 Jan 2006 15:04:05 MST"}
+
+func parseFeedTime(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range feedTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+var htmlTags = regexp.MustCompile(`<[^>]*>`)
+
+// joinText joins a title and an HTML body into plain text
+func joinText(title, body string) string {
+	body = strings.TrimSpace(html.UnescapeString(htmlTags.ReplaceAllString(body, " ")))
+	title = strings.TrimSpace(title)
+	switch {
+	case title == "":
+		return body
+	case body == "":
+		return title
+	default:
+		return title + ". " + body
+	}
+}
+
+func firstOf(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// TwitterSource searches recent posts on X (Twitter) through the v2 API
+type TwitterSource struct {
+	baseURL     string
+	bearerToken string
+	httpClient  *http.Client
+}
+
+// twitterMaxQuery is the maximum length of a recent search query
+const twitterMaxQuery = 512
+
+// NewTwitterSource creates a source searching the posts mentioning the
+// keywords. baseURL defaults to https://api.twitter.com.
+func NewTwitterSource(baseURL, bearerToken string) *TwitterSource {
+	if baseURL == "" {
+		baseURL = "https://api.twitter.com"
+	}
+	return &TwitterSource{
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		bearerToken: bearerToken,
+		httpClient:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Name returns the name of the source
+func (s *TwitterSource) Name() string {
+	return "twitter"
+}
+
+// Fetch searches the posts mentioning a keyword since the given time,
+// excluding reposts. Keywords are split over several queries when they do
+// not fit in one.
+func (s *TwitterSource) Fetch(ctx context.Context, keywords []string, since time.Time) ([]Item, error) {
+	headers := map[string]string{"Authorization": "Bearer " + s.bearerToken}
+
+	var items []Item
+	for _, query := range twitterQueries(keywords) {
+		params := url.Values{}
+		params.Set("query", query)
+		params.Set("max_results", "100")
+		params.Set("tweet.fields", "created_at,author_id")
+		// The API rejects start times older than seven days
+		if limit := time.Now().Add(-7*24*time.Hour + time.Minute); since.Before(limit) {
+			since = limit
+		}
+		params.Set("start_time", since.UTC().Format(time.RFC3339))
+
+		body, err := get(ctx, s.httpClient, s.baseURL+"/2/tweets/search/recent?"+params.Encode(), headers)
+		if err != nil {
+			return nil, err
+		}
+		var resp struct {
+			Data []struct {
+				ID        string    `json:"id"`
+				Text      string    `json:"text"`
+				AuthorID  string    `json:"author_id"`
+				CreatedAt time.Time `json:"created_at"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, fmt.Errorf("failed to decode search response: %w", err)
+		}
+		for _, tweet := range resp.Data {
+			items = append(items, Item{
+				ID:        tweet.ID,
+				Source:    s.Name(),
+				Author:    tweet.AuthorID,
+				Text:      tweet.Text,
+				URL:       "https://x.com/i/web/status/" + tweet.ID,
+				Published: tweet.CreatedAt,
+			})
+		}
+	}
+	return items, nil
+}
+
+// twitterQueries ORs the keywords into as few queries as fit the query
+// length limit
+func twitterQueries(keywords []string) []string {
+	const suffix = ") -is:retweet"
+
+	var queries []string
+	var terms []string
+	length := 1 + len(suffix)
+	for _, keyword := range keywords {
+		term := keyword
+		if strings.ContainsAny(term, " \t") {
+			term = strconv.Quote(term)
+		}
+		if len(terms) > 0 && length+len(" OR ")+len(term) > twitterMaxQuery {
+			queries = append(queries, "("+strings.Join(terms, " OR ")+suffix)
+			terms, length = nil, 1+len(suffix)
+		}
+		if len(terms) > 0 {
+			length += len(" OR ")
+		}
+		terms = append(terms, term)
+		length += len(term)
+	}
+	if len(terms) > 0 {
+		queries = append(queries, "("+strings.Join(terms, " OR ")+suffix)
+	}
+	return queries
+}
+
+// TelegramSource reads the messages of the chats and channels a Telegram
+// bot is a member of, through the Bot API
+type TelegramSource struct {
+	baseURL    string
+	botToken   string
+	httpClient *http.Client
+	offset     int64
+	mu         sync.Mutex
+}
+
+// NewTelegramSource creates a source reading the updates of a bot. baseURL
+// defaults to https://api.telegram.org. The bot must not have a webhook.
+func NewTelegramSource(baseURL, botToken string) *TelegramSource {
+	if baseURL == "" {
+		baseURL = "https://api.telegram.org"
+	}
+	return &TelegramSource{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		botToken:   botToken,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Name returns the name of the source
+func (s *TelegramSource) Name() string {
+	return "telegram"
+}
+
+type telegramMessage struct {
+	MessageID int64  `json:"message_id"`
+	Date      int64  `json:"date"`
+	Text      string `json:"text"`
+	Caption   string `json:"caption"`
+	Chat      struct {
+		ID       int64  `json:"id"`
+		Title    string `json:"title"`
+		Username string `json:"username"`
+	} `json:"chat"`
+}
+
+// Fetch returns the messages received since the previous fetch. Telegram
+// keeps undelivered updates for 24 hours.
+func (s *TelegramSource) Fetch(ctx context.Context, keywords []string, since time.Time) ([]Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	params := url.Values{}
+	params.Set("offset", strconv.FormatInt(s.offset, 10))
+	params.Set("allowed_updates", `["message","channel_post"]`)
+	body, err := get(ctx, s.httpClient, s.baseURL+"/bot"+s.botToken+"/getUpdates?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+		Result      []struct {
+			UpdateID    int64            `json:"update_id"`
+			Message     *telegramMessage `json:"message"`
+			ChannelPost *telegramMessage `json:"channel_post"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode updates: %w", err)
+	}
+	if !resp.OK {
+		return nil, fmt.Errorf("telegram error: %s", resp.Description)
+	}
+
+	var items []Item
+	for _, update := range resp.Result {
+		if update.UpdateID >= s.offset {
+			s.offset = update.UpdateID + 1
+		}
+		msg := update.Message
+		if msg == nil {
+			msg = update.ChannelPost
+		}
+		if msg == nil {
+			continue
+		}
+		text := firstOf(msg.Text, msg.Caption)
+		if text == "" {
+			continue
+		}
+		item := Item{
+			ID:        fmt.Sprintf("%d:%d", msg.Chat.ID, msg.MessageID),
+			Source:    s.Name(),
+			Author:    firstOf(msg.Chat.Username, msg.Chat.Title),
+			Text:      text,
+			Published: time.Unix(msg.Date, 0),
+		}
+		if msg.Chat.Username != "" {
+			item.URL = fmt.Sprintf("https://t.me/%s/%d", msg.Chat.Username, msg.MessageID)
+		}
+		items = append(items, item)
+	}
+	return publishedSince(items, since), nil
+}
+
+// get fetches a URL, failing on non-2xx responses
+func get(ctx context.Context, client *http.Client, endpoint string, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch feed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
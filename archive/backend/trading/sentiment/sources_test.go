@@ -0,0 +1,102 @@
+package sentiment
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRSSSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rss":
+			fmt.Fprint(w, `<?xml version="1.0"?><rss version="2.0"><channel>
+				<item><guid>a1</guid><title>BONK rallies</title><description>&lt;p&gt;Up &amp;amp; away&lt;/p&gt;</description><pubDate>Mon, 06 May 2024 10:00:00 +0000</pubDate></item>
+				<item><guid>a0</guid><title>Old news</title><pubDate>Mon, 01 Jan 2024 10:00:00 +0000</pubDate></item>
+			</channel></rss>`)
+		case "/atom":
+			fmt.Fprint(w, `<?xml version="1.0"?><feed xmlns="http://www.w3.org/2005/Atom">
+				<entry><id>urn:1</id><title>WIF listed</title><summary>New pair</summary><updated>2024-05-06T10:00:00Z</updated><link href="https://example.com/1"/></entry>
+			</feed>`)
+		}
+	}))
+	defer server.Close()
+	since := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	items, err := NewRSSSource("news", server.URL+"/rss").Fetch(context.Background(), nil, since)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "a1", items[0].ID)
+	assert.Equal(t, "BONK rallies. Up & away", items[0].Text)
+	assert.Equal(t, "news", items[0].Source)
+
+	items, err = NewRSSSource("", server.URL+"/atom").Fetch(context.Background(), nil, since)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "urn:1", items[0].ID)
+	assert.Equal(t, "https://example.com/1", items[0].URL)
+	assert.True(t, strings.HasPrefix(items[0].Source, "rss:127.0.0.1"))
+}
+
+func TestTwitterSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2/tweets/search/recent", r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.Equal(t, `(bonk OR "dog coin") -is:retweet`, r.URL.Query().Get("query"))
+		fmt.Fprint(w, `{"data":[{"id":"42","text":"bonk!","author_id":"7","created_at":"2024-05-06T10:00:00.000Z"}]}`)
+	}))
+	defer server.Close()
+
+	items, err := NewTwitterSource(server.URL, "secret").Fetch(context.Background(), []string{"bonk", "dog coin"}, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "42", items[0].ID)
+	assert.Equal(t, "7", items[0].Author)
+	assert.Equal(t, 2024, items[0].Published.Year())
+}
+
+func TestTwitterQueries(t *testing.T) {
+	var keywords []string
+	for i := 0; i < 100; i++ {
+		keywords = append(keywords, fmt.Sprintf("keyword%02d", i))
+	}
+	queries := twitterQueries(keywords)
+	require.Greater(t, len(queries), 1)
+	terms := 0
+	for _, q := range queries {
+		assert.LessOrEqual(t, len(q), twitterMaxQuery)
+		terms += strings.Count(q, "keyword")
+	}
+	assert.Equal(t, 100, terms)
+}
+
+func TestTelegramSource(t *testing.T) {
+	var offsets []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/botTOKEN/getUpdates", r.URL.Path)
+		offsets = append(offsets, r.URL.Query().Get("offset"))
+		fmt.Fprintf(w, `{"ok":true,"result":[
+			{"update_id":10,"channel_post":{"message_id":5,"date":%d,"text":"bonk pump","chat":{"id":-100,"username":"alpha"}}},
+			{"update_id":11,"message":{"message_id":6,"date":%d,"chat":{"id":-100}}}
+		]}`, time.Now().Unix(), time.Now().Unix())
+	}))
+	defer server.Close()
+
+	source := NewTelegramSource(server.URL, "TOKEN")
+	items, err := source.Fetch(context.Background(), nil, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "-100:5", items[0].ID)
+	assert.Equal(t, "https://t.me/alpha/5", items[0].URL)
+
+	_, err = source.Fetch(context.Background(), nil, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0", "12"}, offsets)
+}
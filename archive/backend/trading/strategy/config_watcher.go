@@ -0,0 +1,129 @@
+package strategy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/monitoring"
+)
+
+// ConfigSource loads strategy records from the repository
+type ConfigSource interface {
+	GetStrategy(ctx context.Context, name string) (*models.StrategyRecord, error)
+}
+
+// Configurable is implemented by strategies that accept configuration
+// changes while running
+type Configurable interface {
+	ApplyConfig(config *models.StrategyConfig) error
+}
+
+// ConfigWatcher polls strategy records and hot-applies changed
+// configuration to running strategies
+type ConfigWatcher struct {
+	source     ConfigSource
+	interval   time.Duration
+	strategies map[string]Configurable
+	applied    map[string]string
+	monitor    monitoring.IMonitor
+	mu         sync.Mutex
+}
+
+// NewConfigWatcher creates a new strategy config watcher
+func NewConfigWatcher(source ConfigSource, interval time.Duration, monitor monitoring.IMonitor) *ConfigWatcher {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	return &ConfigWatcher{
+		source:     source,
+		interval:   interval,
+		strategies: make(map[string]Configurable),
+		applied:    make(map[string]string),
+		monitor:    monitor,
+	}
+}
+
+// Register adds a running strategy to be kept in sync with its stored config
+func (w *ConfigWatcher) Register(name string, strategy Configurable) {
+	w.mu.Lock()
+	w.strategies[name] = strategy
+	delete(w.applied, name)
+	w.mu.Unlock()
+}
+
+// Run polls for configuration changes until the context is cancelled
+func (w *ConfigWatcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.Poll(ctx); err != nil && w.monitor != nil {
+			w.monitor.RecordEvent(ctx, monitoring.Event{
+				Type:     monitoring.MetricSystem,
+				Severity: monitoring.SeverityWarning,
+				Message:  "Strategy config reload failed",
+				Details:  err.Error(),
+			})
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll loads the config of every registered strategy and applies it if it
+// changed since the last successful apply
+func (w *ConfigWatcher) Poll(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var errs []error
+	for name, strategy := range w.strategies {
+		record, err := w.source.GetStrategy(ctx, name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("load %s: %w", name, err))
+			continue
+		}
+
+		version := record.Status + "|" + record.Config
+		if w.applied[name] == version {
+			continue
+		}
+
+		config, err := models.StrategyConfigFromRecord(record)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		// A rejected config is retried on the next poll once it is fixed
+		if err := strategy.ApplyConfig(config); err != nil {
+			errs = append(errs, fmt.Errorf("apply %s: %w", name, err))
+			continue
+		}
+		w.applied[name] = version
+
+		if w.monitor != nil {
+			w.monitor.RecordEvent(ctx, monitoring.Event{
+				Type:     monitoring.MetricSystem,
+				Severity: monitoring.SeverityInfo,
+				Message:  "Strategy config applied",
+				Details: map[string]interface{}{
+					"strategy": name,
+					"enabled":  config.Enabled,
+					"symbols":  config.Symbols,
+				},
+			})
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -2,7 +2,10 @@ package strategy
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"math"
+	"sync"
 
 	"github.com/leonzhao/trading-system/backend/models"
 	"github.com/leonzhao/trading-system/backend/trading/analysis"
@@ -14,6 +17,28 @@ type MomentumStrategy struct {
 	MACDSlowPeriod int
 	SignalPeriod   int
 	RiskLevel      float64
+	// Equity is the capital the position sizer sizes signals against
+	Equity float64
+
+	// sizer sizes signals, an equal notional of RiskLevel*100 when nil
+	sizer PositionSizer
+	// regimeFilter drops the signals of market data in gated regimes
+	regimeFilter RegimeFilter
+	disabled     bool
+	symbols      []string
+	mu           sync.RWMutex
+}
+
+// momentumParameters contains the hot-reloadable momentum parameters
+type momentumParameters struct {
+	RSIPeriod      *int          `json:"rsi_period"`
+	MACDFastPeriod *int          `json:"macd_fast_period"`
+	MACDSlowPeriod *int          `json:"macd_slow_period"`
+	SignalPeriod   *int          `json:"signal_period"`
+	RiskLevel      *float64      `json:"risk_level"`
+	Equity         *float64      `json:"equity"`
+	Sizer          *SizerConfig  `json:"sizer"`
+	RegimeFilter   *RegimeFilter `json:"regime_filter"`
 }
 
 func NewMomentumStrategy(rsiPeriod, macdFast, macdSlow, signal int, risk float64) *MomentumStrategy {
@@ -26,18 +51,115 @@ func NewMomentumStrategy(rsiPeriod, macdFast, macdSlow, signal int, risk float64
 	}
 }
 
+// ApplyConfig hot-applies a stored configuration. Parameters omitted from
+// the config keep their current values.
+func (s *MomentumStrategy) ApplyConfig(config *models.StrategyConfig) error {
+	var params momentumParameters
+	if len(config.Parameters) > 0 {
+		if err := json.Unmarshal(config.Parameters, &params); err != nil {
+			return fmt.Errorf("invalid momentum parameters: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := MomentumStrategy{
+		RSIPeriod:      s.RSIPeriod,
+		MACDFastPeriod: s.MACDFastPeriod,
+		MACDSlowPeriod: s.MACDSlowPeriod,
+		SignalPeriod:   s.SignalPeriod,
+		RiskLevel:      s.RiskLevel,
+		Equity:         s.Equity,
+		sizer:          s.sizer,
+		regimeFilter:   s.regimeFilter,
+	}
+	if params.RSIPeriod != nil {
+		next.RSIPeriod = *params.RSIPeriod
+	}
+	if params.MACDFastPeriod != nil {
+		next.MACDFastPeriod = *params.MACDFastPeriod
+	}
+	if params.MACDSlowPeriod != nil {
+		next.MACDSlowPeriod = *params.MACDSlowPeriod
+	}
+	if params.SignalPeriod != nil {
+		next.SignalPeriod = *params.SignalPeriod
+	}
+	if params.RiskLevel != nil {
+		next.RiskLevel = *params.RiskLevel
+	}
+	if params.Equity != nil {
+		next.Equity = *params.Equity
+	}
+	if params.Sizer != nil {
+		sizer, err := NewPositionSizer(*params.Sizer)
+		if err != nil {
+			return err
+		}
+		next.sizer = sizer
+	}
+	if params.RegimeFilter != nil {
+		next.regimeFilter = *params.RegimeFilter
+	}
+
+	if next.RSIPeriod <= 0 || next.MACDFastPeriod <= 0 || next.SignalPeriod <= 0 {
+		return fmt.Errorf("momentum periods must be positive")
+	}
+	if next.MACDFastPeriod >= next.MACDSlowPeriod {
+		return fmt.Errorf("macd fast period %d must be below slow period %d", next.MACDFastPeriod, next.MACDSlowPeriod)
+	}
+	if next.RiskLevel <= 0 {
+		return fmt.Errorf("risk level must be positive")
+	}
+	if next.Equity < 0 {
+		return fmt.Errorf("equity must not be negative")
+	}
+
+	s.RSIPeriod = next.RSIPeriod
+	s.MACDFastPeriod = next.MACDFastPeriod
+	s.MACDSlowPeriod = next.MACDSlowPeriod
+	s.SignalPeriod = next.SignalPeriod
+	s.RiskLevel = next.RiskLevel
+	s.Equity = next.Equity
+	s.sizer = next.sizer
+	s.regimeFilter = next.regimeFilter
+	s.disabled = !config.Enabled
+	s.symbols = config.Symbols
+	return nil
+}
+
 func (s *MomentumStrategy) GenerateSignals(ctx context.Context, marketData []models.MarketData) []models.TradeSignal {
 	var signals []models.TradeSignal
 
+	s.mu.RLock()
+	disabled := s.disabled
+	symbols := &models.StrategyConfig{Symbols: s.symbols}
+	rsiPeriod, fast, slow, signalPeriod := s.RSIPeriod, s.MACDFastPeriod, s.MACDSlowPeriod, s.SignalPeriod
+	sizer, equity := s.sizer, s.Equity
+	if sizer == nil {
+		sizer = EqualNotionalSizer{Notional: s.RiskLevel * 100}
+	}
+	regimeFilter := s.regimeFilter
+	s.mu.RUnlock()
+
+	if disabled || !regimeFilter.AllowsMarket(marketData) {
+		return signals
+	}
+
 	prices := extractClosingPrices(marketData)
-	rsi := analysis.RSI(prices, s.RSIPeriod)
-	macdLine, signalLine, _ := analysis.MACD(prices, s.MACDFastPeriod, s.MACDSlowPeriod, s.SignalPeriod)
+	rsi := analysis.RSI(prices, rsiPeriod)
+	macdLine, signalLine, _ := analysis.MACD(prices, fast, slow, signalPeriod)
 
 	for i := 1; i < len(prices); i++ {
 		if i >= len(rsi) || i >= len(macdLine) {
 			continue
 		}
 
+		if !symbols.HasSymbol(marketData[i].Symbol) {
+			continue
+		}
+
 		var signalType models.SignalType
 		currentPrice := prices[i]
 
@@ -51,7 +173,12 @@ func (s *MomentumStrategy) GenerateSignals(ctx context.Context, marketData []mod
 			continue
 		}
 
-		positionSize := s.calculatePositionSize(currentPrice)
+		positionSize, sizing := sizeSignal(sizer, SizingInput{
+			Symbol:  marketData[i].Symbol,
+			Price:   currentPrice,
+			Equity:  equity,
+			Candles: marketData[:i+1],
+		})
 		if positionSize <= 0 {
 			continue
 		}
@@ -64,6 +191,7 @@ func (s *MomentumStrategy) GenerateSignals(ctx context.Context, marketData []mod
 			Timestamp:   marketData[i].Timestamp,
 			Confidence:  s.calculateConfidence(rsi[i-1], macdLine[i]-signalLine[i]),
 			Description: "Momentum based trading signal",
+			Sizing:      sizing,
 		})
 	}
 
@@ -78,11 +206,17 @@ func extractClosingPrices(marketData []models.MarketData) []float64 {
 	return prices
 }
 
-func (s *MomentumStrategy) calculatePositionSize(price float64) float64 {
-	if price == 0 {
-		return 0
+// sizeSignal sizes a signal, rounded to two decimals, and records the sizing.
+// Signals the sizer cannot size get a zero size.
+func sizeSignal(sizer PositionSizer, input SizingInput) (float64, *models.PositionSizing) {
+	if input.Price <= 0 {
+		return 0, nil
+	}
+	size, inputs, err := sizer.Size(input)
+	if err != nil {
+		return 0, nil
 	}
-	return math.Round(s.RiskLevel*100/price*100) / 100
+	return math.Round(size*100) / 100, &models.PositionSizing{Sizer: sizer.Name(), Inputs: inputs}
 }
 
 func (s *MomentumStrategy) calculateConfidence(rsi, macdDiff float64) float64 {
@@ -0,0 +1,407 @@
+package strategy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/monitoring"
+)
+
+// ErrUnknownStrategy is returned for strategies not registered for promotion
+var ErrUnknownStrategy = errors.New("strategy not registered for promotion")
+
+// PromotionStage is the stage of a strategy in the promotion pipeline
+type PromotionStage string
+
+const (
+	// StageShadow strategies only paper trade
+	StageShadow PromotionStage = "shadow"
+	// StagePilot strategies trade live with the pilot allocation
+	StagePilot PromotionStage = "pilot"
+	// StageLive strategies have been scaled up by live performance
+	StageLive PromotionStage = "live"
+)
+
+// PromotionCriteria are the trailing performance bounds a strategy must meet
+type PromotionCriteria struct {
+	// MinTrades is the number of trades required before evaluating
+	MinTrades int `json:"min_trades"`
+	// MinSharpe is the minimum annualized Sharpe ratio of trade returns
+	MinSharpe float64 `json:"min_sharpe"`
+	// MaxDrawdown is the maximum drawdown of the compounded returns, as a fraction
+	MaxDrawdown float64 `json:"max_drawdown"`
+}
+
+// PromotionConfig contains strategy promotion configuration
+type PromotionConfig struct {
+	// Graduation moves a shadow strategy to the pilot allocation
+	Graduation PromotionCriteria `json:"graduation"`
+	// Scaling multiplies the allocation of a live strategy each time it is
+	// met by the live trades since the last step
+	Scaling PromotionCriteria `json:"scaling"`
+	// Demotion returns a live strategy to shadow when its drawdown since
+	// going live exceeds MaxDrawdown, or when its trailing Sharpe falls
+	// below MinSharpe after MinTrades
+	Demotion PromotionCriteria `json:"demotion"`
+	// Window is the number of trailing trades evaluated
+	Window int `json:"window"`
+	// TradesPerYear annualizes the per-trade Sharpe ratio
+	TradesPerYear float64 `json:"trades_per_year"`
+	// PilotAllocation is the fraction of the strategy's signal size traded
+	// after graduation
+	PilotAllocation float64 `json:"pilot_allocation"`
+	// ScaleFactor multiplies the allocation at each scaling step
+	ScaleFactor float64 `json:"scale_factor"`
+	// MaxAllocation caps the allocation
+	MaxAllocation float64 `json:"max_allocation"`
+}
+
+// DefaultPromotionConfig returns default strategy promotion configuration
+func DefaultPromotionConfig() PromotionConfig {
+	return PromotionConfig{
+		Graduation:      PromotionCriteria{MinTrades: 50, MinSharpe: 1.5, MaxDrawdown: 0.15},
+		Scaling:         PromotionCriteria{MinTrades: 30, MinSharpe: 1.0, MaxDrawdown: 0.10},
+		Demotion:        PromotionCriteria{MinTrades: 20, MinSharpe: 0, MaxDrawdown: 0.20},
+		Window:          100,
+		TradesPerYear:   365,
+		PilotAllocation: 0.1,
+		ScaleFactor:     2,
+		MaxAllocation:   1,
+	}
+}
+
+// TradeResult is the outcome of one closed trade of a strategy
+type TradeResult struct {
+	// Return is the trade's return on the capital it used, as a fraction
+	Return float64
+	// Shadow is set for paper trades
+	Shadow    bool
+	Timestamp time.Time
+}
+
+// PromotionEvent records a stage or allocation change
+type PromotionEvent struct {
+	Strategy   string         `json:"strategy"`
+	From       PromotionStage `json:"from"`
+	To         PromotionStage `json:"to"`
+	Allocation float64        `json:"allocation"`
+	Reason     string         `json:"reason"`
+	Trades     int            `json:"trades"`
+	Sharpe     float64        `json:"sharpe"`
+	Drawdown   float64        `json:"drawdown"`
+	Timestamp  time.Time      `json:"timestamp"`
+}
+
+// PromotionStatus is the current promotion state of a strategy
+type PromotionStatus struct {
+	Strategy   string         `json:"strategy"`
+	Stage      PromotionStage `json:"stage"`
+	Allocation float64        `json:"allocation"`
+	// Trades counts the trades evaluated since entering the stage
+	Trades   int       `json:"trades"`
+	Sharpe   float64   `json:"sharpe"`
+	Drawdown float64   `json:"drawdown"`
+	Since    time.Time `json:"since"`
+}
+
+type promotionState struct {
+	stage      PromotionStage
+	allocation float64
+	since      time.Time
+	// returns holds the trailing trade returns of the current stage
+	returns []float64
+	trades  int
+	// stepTrades counts live trades since the last allocation change
+	stepTrades int
+	equity     float64
+	peak       float64
+	history    []PromotionEvent
+}
+
+// PromotionPipeline starts strategies in shadow mode, graduates them to a
+// small live allocation once their paper trades meet the graduation
+// criteria, and scales the allocation as live performance confirms. Live
+// strategies that breach the demotion bounds go back to shadow.
+type PromotionPipeline struct {
+	config     PromotionConfig
+	monitor    monitoring.IMonitor
+	strategies map[string]*promotionState
+	mu         sync.Mutex
+}
+
+// NewPromotionPipeline creates a promotion pipeline. The monitor may be nil.
+func NewPromotionPipeline(config PromotionConfig, monitor monitoring.IMonitor) *PromotionPipeline {
+	defaults := DefaultPromotionConfig()
+	if config.Window <= 0 {
+		config.Window = defaults.Window
+	}
+	if config.TradesPerYear <= 0 {
+		config.TradesPerYear = defaults.TradesPerYear
+	}
+	if config.PilotAllocation <= 0 {
+		config.PilotAllocation = defaults.PilotAllocation
+	}
+	if config.ScaleFactor <= 1 {
+		config.ScaleFactor = defaults.ScaleFactor
+	}
+	if config.MaxAllocation <= 0 {
+		config.MaxAllocation = defaults.MaxAllocation
+	}
+	if config.PilotAllocation > config.MaxAllocation {
+		config.PilotAllocation = config.MaxAllocation
+	}
+
+	return &PromotionPipeline{
+		config:     config,
+		monitor:    monitor,
+		strategies: make(map[string]*promotionState),
+	}
+}
+
+// Register adds a strategy to the pipeline in shadow mode. Registering a
+// strategy again keeps its current state.
+func (p *PromotionPipeline) Register(ctx context.Context, name string) {
+	p.mu.Lock()
+	if _, ok := p.strategies[name]; ok {
+		p.mu.Unlock()
+		return
+	}
+	state := &promotionState{}
+	p.strategies[name] = state
+	event := p.transition(name, state, StageShadow, 0, "registered")
+	p.mu.Unlock()
+
+	p.recordEvent(ctx, event)
+}
+
+// Allocation returns the fraction of its signal size a strategy may trade
+// live, zero while in shadow. The second value is false for strategies that
+// are not registered.
+func (p *PromotionPipeline) Allocation(name string) (float64, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state, ok := p.strategies[name]
+	if !ok {
+		return 0, false
+	}
+	return state.allocation, true
+}
+
+// Status returns the promotion state of a strategy
+func (p *PromotionPipeline) Status(name string) (PromotionStatus, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state, ok := p.strategies[name]
+	if !ok {
+		return PromotionStatus{}, fmt.Errorf("%w: %s", ErrUnknownStrategy, name)
+	}
+	return PromotionStatus{
+		Strategy:   name,
+		Stage:      state.stage,
+		Allocation: state.allocation,
+		Trades:     state.trades,
+		Sharpe:     sharpeRatio(state.returns, p.config.TradesPerYear),
+		Drawdown:   maxDrawdown(state.returns),
+		Since:      state.since,
+	}, nil
+}
+
+// History returns the promotion events of a strategy, oldest first
+func (p *PromotionPipeline) History(name string) ([]PromotionEvent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state, ok := p.strategies[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownStrategy, name)
+	}
+	return append([]PromotionEvent(nil), state.history...), nil
+}
+
+// RecordTrade adds a closed trade and applies any promotion, scaling or
+// demotion it triggers. Paper trades count only in shadow and live trades
+// only once the strategy trades live; other trades are ignored.
+func (p *PromotionPipeline) RecordTrade(ctx context.Context, name string, result TradeResult) error {
+	if math.IsNaN(result.Return) || math.IsInf(result.Return, 0) || result.Return <= -1 {
+		return fmt.Errorf("invalid trade return for %s: %v", name, result.Return)
+	}
+
+	p.mu.Lock()
+	state, ok := p.strategies[name]
+	if !ok {
+		p.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrUnknownStrategy, name)
+	}
+	if result.Shadow != (state.stage == StageShadow) {
+		p.mu.Unlock()
+		return nil
+	}
+
+	state.trades++
+	state.returns = append(state.returns, result.Return)
+	if len(state.returns) > p.config.Window {
+		state.returns = state.returns[len(state.returns)-p.config.Window:]
+	}
+	state.equity *= 1 + result.Return
+	state.peak = math.Max(state.peak, state.equity)
+
+	var event *PromotionEvent
+	if state.stage == StageShadow {
+		event = p.evaluateShadow(name, state)
+	} else {
+		event = p.evaluateLive(name, state)
+	}
+	p.mu.Unlock()
+
+	if event != nil {
+		p.recordEvent(ctx, *event)
+	}
+	return nil
+}
+
+// evaluateShadow graduates a shadow strategy that meets the graduation
+// criteria. Callers hold p.mu.
+func (p *PromotionPipeline) evaluateShadow(name string, state *promotionState) *PromotionEvent {
+	if !p.meets(state.returns, p.config.Graduation) {
+		return nil
+	}
+	event := p.transition(name, state, StagePilot, p.config.PilotAllocation, "graduation criteria met")
+	return &event
+}
+
+// evaluateLive demotes a live strategy that breached the demotion bounds, or
+// scales its allocation once the scaling criteria are met. Callers hold p.mu.
+func (p *PromotionPipeline) evaluateLive(name string, state *promotionState) *PromotionEvent {
+	demotion := p.config.Demotion
+	if drawdown := 1 - state.equity/state.peak; demotion.MaxDrawdown > 0 && drawdown > demotion.MaxDrawdown {
+		event := p.transition(name, state, StageShadow, 0, fmt.Sprintf("live drawdown %.1f%% above %.1f%%", drawdown*100, demotion.MaxDrawdown*100))
+		return &event
+	}
+	if len(state.returns) >= demotion.MinTrades && demotion.MinTrades > 0 {
+		if sharpe := sharpeRatio(state.returns, p.config.TradesPerYear); sharpe < demotion.MinSharpe {
+			event := p.transition(name, state, StageShadow, 0, fmt.Sprintf("trailing Sharpe %.2f below %.2f", sharpe, demotion.MinSharpe))
+			return &event
+		}
+	}
+
+	state.stepTrades++
+	if state.allocation >= p.config.MaxAllocation || state.stepTrades < p.config.Scaling.MinTrades {
+		return nil
+	}
+	if !p.meets(state.returns[max(0, len(state.returns)-state.stepTrades):], p.config.Scaling) {
+		return nil
+	}
+	allocation := math.Min(state.allocation*p.config.ScaleFactor, p.config.MaxAllocation)
+	event := p.transition(name, state, StageLive, allocation, "scaling criteria met")
+	return &event
+}
+
+// meets reports whether returns satisfy the criteria
+func (p *PromotionPipeline) meets(returns []float64, criteria PromotionCriteria) bool {
+	if len(returns) < max(criteria.MinTrades, 2) {
+		return false
+	}
+	if sharpeRatio(returns, p.config.TradesPerYear) < criteria.MinSharpe {
+		return false
+	}
+	return criteria.MaxDrawdown <= 0 || maxDrawdown(returns) <= criteria.MaxDrawdown
+}
+
+// transition moves a strategy to a stage and allocation and logs the change.
+// Entering a new stage restarts its evaluation. Callers hold p.mu.
+func (p *PromotionPipeline) transition(name string, state *promotionState, stage PromotionStage, allocation float64, reason string) PromotionEvent {
+	event := PromotionEvent{
+		Strategy:   name,
+		From:       state.stage,
+		To:         stage,
+		Allocation: allocation,
+		Reason:     reason,
+		Trades:     len(state.returns),
+		Sharpe:     sharpeRatio(state.returns, p.config.TradesPerYear),
+		Drawdown:   maxDrawdown(state.returns),
+		Timestamp:  time.Now(),
+	}
+
+	// Scaling keeps the live track record; every other change starts over
+	if !(state.stage == stage || (state.stage == StagePilot && stage == StageLive)) {
+		state.returns = nil
+		state.trades = 0
+		state.equity = 1
+		state.peak = 1
+		state.since = event.Timestamp
+	}
+	state.stage = stage
+	state.allocation = allocation
+	state.stepTrades = 0
+	state.history = append(state.history, event)
+	return event
+}
+
+func (p *PromotionPipeline) recordEvent(ctx context.Context, event PromotionEvent) {
+	if p.monitor == nil {
+		return
+	}
+
+	severity := monitoring.SeverityInfo
+	message := "Strategy promoted"
+	switch {
+	case event.From == "":
+		message = "Strategy registered for promotion"
+	case event.To == StageShadow:
+		severity = monitoring.SeverityWarning
+		message = "Strategy demoted to shadow"
+	case event.From == event.To:
+		message = "Strategy allocation scaled"
+	}
+	p.monitor.RecordEvent(ctx, monitoring.Event{
+		Type:     monitoring.MetricTrading,
+		Severity: severity,
+		Message:  message,
+		Details:  event,
+	})
+}
+
+// sharpeRatio returns the annualized Sharpe ratio of per-trade returns,
+// assuming a zero risk-free rate
+func sharpeRatio(returns []float64, periodsPerYear float64) float64 {
+	if len(returns) < 2 {
+		return 0
+	}
+
+	mean := 0.0
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+
+	variance := 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	stdDev := math.Sqrt(variance / float64(len(returns)-1))
+	if stdDev == 0 {
+		if mean > 0 {
+			return math.Inf(1)
+		}
+		return 0
+	}
+	return mean / stdDev * math.Sqrt(periodsPerYear)
+}
+
+// maxDrawdown returns the largest peak-to-trough decline of the compounded
+// returns, as a fraction
+func maxDrawdown(returns []float64) float64 {
+	equity, peak, drawdown := 1.0, 1.0, 0.0
+	for _, r := range returns {
+		equity *= 1 + r
+		peak = math.Max(peak, equity)
+		drawdown = math.Max(drawdown, 1-equity/peak)
+	}
+	return drawdown
+}
@@ -0,0 +1,144 @@
+package strategy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/leonzhao/trading-system/backend/monitoring"
+)
+
+type recordingMonitor struct {
+	monitoring.IMonitor
+	events []monitoring.Event
+}
+
+func (m *recordingMonitor) RecordEvent(ctx context.Context, event monitoring.Event) {
+	m.events = append(m.events, event)
+}
+
+func TestPromotionPipeline(t *testing.T) {
+	ctx := context.Background()
+	config := PromotionConfig{
+		Graduation:      PromotionCriteria{MinTrades: 10, MinSharpe: 1, MaxDrawdown: 0.1},
+		Scaling:         PromotionCriteria{MinTrades: 5, MinSharpe: 1, MaxDrawdown: 0.05},
+		Demotion:        PromotionCriteria{MinTrades: 5, MinSharpe: 0, MaxDrawdown: 0.05},
+		Window:          20,
+		PilotAllocation: 0.25,
+		ScaleFactor:     2,
+		MaxAllocation:   1,
+	}
+	winning := []float64{0.02, 0.01}
+
+	record := func(t *testing.T, p *PromotionPipeline, n int, shadow bool, returns ...float64) {
+		for i := 0; i < n; i++ {
+			require.NoError(t, p.RecordTrade(ctx, "momentum", TradeResult{Return: returns[i%len(returns)], Shadow: shadow}))
+		}
+	}
+
+	t.Run("Shadow strategies graduate and scale with live performance", func(t *testing.T) {
+		monitor := &recordingMonitor{}
+		p := NewPromotionPipeline(config, monitor)
+		p.Register(ctx, "momentum")
+
+		allocation, ok := p.Allocation("momentum")
+		assert.True(t, ok)
+		assert.Zero(t, allocation)
+
+		record(t, p, 9, true, winning...)
+		status, err := p.Status("momentum")
+		require.NoError(t, err)
+		assert.Equal(t, StageShadow, status.Stage)
+		assert.Equal(t, 9, status.Trades)
+
+		// Live results do not count while in shadow
+		record(t, p, 5, false, winning...)
+		record(t, p, 1, true, winning...)
+		status, err = p.Status("momentum")
+		require.NoError(t, err)
+		assert.Equal(t, StagePilot, status.Stage)
+		assert.Equal(t, 0.25, status.Allocation)
+		assert.Zero(t, status.Trades)
+
+		// Paper trades no longer count once live
+		record(t, p, 5, true, winning...)
+		record(t, p, 5, false, winning...)
+		allocation, _ = p.Allocation("momentum")
+		assert.Equal(t, 0.5, allocation)
+		record(t, p, 10, false, winning...)
+		allocation, _ = p.Allocation("momentum")
+		assert.Equal(t, 1.0, allocation)
+
+		status, err = p.Status("momentum")
+		require.NoError(t, err)
+		assert.Equal(t, StageLive, status.Stage)
+		assert.Equal(t, 15, status.Trades)
+
+		history, err := p.History("momentum")
+		require.NoError(t, err)
+		require.Len(t, history, 4)
+		assert.Equal(t, PromotionStage(""), history[0].From)
+		assert.Equal(t, StageShadow, history[1].From)
+		assert.Equal(t, StagePilot, history[1].To)
+		assert.Equal(t, 10, history[1].Trades)
+		assert.Equal(t, StagePilot, history[2].From)
+		assert.Equal(t, StageLive, history[3].To)
+		assert.Equal(t, []string{
+			"Strategy registered for promotion",
+			"Strategy promoted",
+			"Strategy promoted",
+			"Strategy allocation scaled",
+		}, messages(monitor.events))
+	})
+
+	t.Run("Drawdown demotes live strategies to shadow", func(t *testing.T) {
+		monitor := &recordingMonitor{}
+		p := NewPromotionPipeline(config, monitor)
+		p.Register(ctx, "momentum")
+		record(t, p, 10, true, winning...)
+		record(t, p, 3, false, winning...)
+
+		record(t, p, 2, false, -0.03)
+		status, err := p.Status("momentum")
+		require.NoError(t, err)
+		assert.Equal(t, StageShadow, status.Stage)
+		assert.Zero(t, status.Allocation)
+
+		last := monitor.events[len(monitor.events)-1]
+		assert.Equal(t, "Strategy demoted to shadow", last.Message)
+		assert.Equal(t, monitoring.SeverityWarning, last.Severity)
+		assert.Contains(t, last.Details.(PromotionEvent).Reason, "drawdown")
+	})
+
+	t.Run("Poor trailing Sharpe demotes live strategies", func(t *testing.T) {
+		p := NewPromotionPipeline(config, nil)
+		p.Register(ctx, "momentum")
+		record(t, p, 10, true, winning...)
+		record(t, p, 5, false, 0.01, -0.02)
+
+		history, err := p.History("momentum")
+		require.NoError(t, err)
+		assert.Equal(t, StageShadow, history[len(history)-1].To)
+		assert.Contains(t, history[len(history)-1].Reason, "Sharpe")
+	})
+
+	t.Run("Invalid input", func(t *testing.T) {
+		p := NewPromotionPipeline(config, nil)
+		assert.ErrorIs(t, p.RecordTrade(ctx, "unknown", TradeResult{Return: 0.01}), ErrUnknownStrategy)
+		_, ok := p.Allocation("unknown")
+		assert.False(t, ok)
+
+		p.Register(ctx, "momentum")
+		assert.Error(t, p.RecordTrade(ctx, "momentum", TradeResult{Return: -1}))
+	})
+}
+
+func messages(events []monitoring.Event) []string {
+	messages := make([]string, len(events))
+	for i, event := range events {
+		messages[i] = event.Message
+	}
+	return messages
+}
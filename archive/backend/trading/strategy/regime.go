@@ -0,0 +1,54 @@
+package strategy
+
+import (
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/trading/analysis"
+)
+
+// RegimeFilter gates the entries of a strategy by the market regime
+type RegimeFilter struct {
+	// Allowed lists the regimes entries are taken in, every regime when
+	// empty
+	Allowed []analysis.Regime `json:"allowed"`
+	// BlockStrongTrend blocks entries in strong trends whatever the regime
+	BlockStrongTrend bool `json:"block_strong_trend"`
+}
+
+// enabled reports whether the filter gates anything
+func (f RegimeFilter) enabled() bool {
+	return len(f.Allowed) > 0 || f.BlockStrongTrend
+}
+
+// Allows reports whether entries may be taken in a regime. Without a
+// classification, such as before enough data is available, entries are
+// allowed.
+func (f RegimeFilter) Allows(regime *analysis.RegimeClassification) bool {
+	if regime == nil {
+		return true
+	}
+	if f.BlockStrongTrend && regime.StrongTrend {
+		return false
+	}
+	if len(f.Allowed) == 0 {
+		return true
+	}
+	for _, allowed := range f.Allowed {
+		if regime.Regime == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsMarket classifies the regime of the market data and reports whether
+// entries may be taken in it
+func (f RegimeFilter) AllowsMarket(marketData []models.MarketData) bool {
+	if !f.enabled() {
+		return true
+	}
+	regime, err := analysis.ClassifyRegime(marketData, analysis.DefaultRegimeConfig())
+	if err != nil {
+		return true
+	}
+	return f.Allows(regime)
+}
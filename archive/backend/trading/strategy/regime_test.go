@@ -0,0 +1,38 @@
+package strategy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/leonzhao/trading-system/backend/trading/analysis"
+)
+
+func TestRegimeFilter(t *testing.T) {
+	trending := &analysis.RegimeClassification{Regime: analysis.RegimeTrending}
+	strongTrend := &analysis.RegimeClassification{Regime: analysis.RegimeTrending, StrongTrend: true}
+	ranging := &analysis.RegimeClassification{Regime: analysis.RegimeRanging}
+
+	assert.True(t, RegimeFilter{}.Allows(strongTrend))
+
+	rangeOnly := RegimeFilter{Allowed: []analysis.Regime{analysis.RegimeRanging}}
+	assert.True(t, rangeOnly.Allows(ranging))
+	assert.False(t, rangeOnly.Allows(trending))
+	// Without a classification entries are not gated
+	assert.True(t, rangeOnly.Allows(nil))
+
+	// Grids hold in strong trends only by default
+	grid := NewGridStrategy(110, 90, 5)
+	action, level := grid.EvaluateGridPositionInRegime(96, trending)
+	assert.Equal(t, "buy", action)
+	assert.Equal(t, 95.0, level)
+	action, level = grid.EvaluateGridPositionInRegime(96, strongTrend)
+	assert.Equal(t, "hold", action)
+	assert.Zero(t, level)
+
+	grid.SetRegimeFilter(rangeOnly)
+	action, _ = grid.EvaluateGridPositionInRegime(96, trending)
+	assert.Equal(t, "hold", action)
+	action, _ = grid.EvaluateGridPositionInRegime(96, ranging)
+	assert.Equal(t, "buy", action)
+}
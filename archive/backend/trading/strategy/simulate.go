@@ -0,0 +1,249 @@
+package strategy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/models"
+)
+
+// Simulation defaults
+const (
+	DefaultSimulationCandles = 200
+	MaxSimulationCandles     = 1000
+)
+
+var (
+	// ErrInvalidSimulation is returned for a simulation request with
+	// invalid parameters
+	ErrInvalidSimulation = errors.New("invalid simulation")
+	// ErrNoSimulator is returned when simulating a strategy that has no
+	// simulator
+	ErrNoSimulator = errors.New("no simulator for strategy")
+	// ErrNoSimulationData is returned when a token has no market data of the
+	// simulated timeframe
+	ErrNoSimulationData = errors.New("no market data to simulate")
+)
+
+// SignalGenerator is a configurable strategy generating signals from market
+// data, oldest first
+type SignalGenerator interface {
+	Configurable
+	GenerateSignals(ctx context.Context, marketData []models.MarketData) []models.TradeSignal
+}
+
+// simulators create the strategies that can be simulated, by name, with the
+// parameters a config does not override
+var simulators = map[string]func() SignalGenerator{
+	"momentum": func() SignalGenerator { return NewMomentumStrategy(14, 12, 26, 9, 1) },
+}
+
+// MarketDataSource returns stored market data. repository.Repository
+// implements it.
+type MarketDataSource interface {
+	// GetHistoricalMarketData returns the latest market data, newest first
+	GetHistoricalMarketData(ctx context.Context, tokenAddress string, limit int) ([]*models.MarketData, error)
+}
+
+// SimulationRequest describes a what-if run of a strategy
+type SimulationRequest struct {
+	Token string `json:"token"`
+	// Timeframe is the interval of the market data simulated, such as 1h.
+	// Empty simulates every stored data point.
+	Timeframe string `json:"timeframe"`
+	// Candles is the number of latest data points loaded,
+	// DefaultSimulationCandles by default
+	Candles int `json:"candles"`
+	// SlippageBps moves the hypothetical fills against the trade
+	SlippageBps float64 `json:"slippage_bps"`
+	// Strategy selects the strategy by name and overrides its parameters
+	Strategy models.StrategyConfig `json:"strategy"`
+}
+
+// SimulatedFill is the hypothetical execution of a signal, at the open of
+// the next candle
+type SimulatedFill struct {
+	Price     float64   `json:"price"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// SimulatedSignal is a signal the strategy would have produced
+type SimulatedSignal struct {
+	Side       string                 `json:"side"`
+	Price      float64                `json:"price"`
+	Size       float64                `json:"size"`
+	Confidence float64                `json:"confidence"`
+	Timestamp  time.Time              `json:"timestamp"`
+	Sizing     *models.PositionSizing `json:"sizing,omitempty"`
+	// Fill is nil for a signal of the last candle, which has no next open
+	Fill *SimulatedFill `json:"fill,omitempty"`
+}
+
+// SimulationResult contains the signals of a simulation and the position
+// their fills would have built
+type SimulationResult struct {
+	Token     string            `json:"token"`
+	Strategy  string            `json:"strategy"`
+	Timeframe string            `json:"timeframe,omitempty"`
+	Candles   int               `json:"candles"`
+	From      time.Time         `json:"from"`
+	To        time.Time         `json:"to"`
+	Signals   []SimulatedSignal `json:"signals"`
+	// NetSize is the position after the fills, negative when short
+	NetSize       float64 `json:"net_size"`
+	RealizedPnL   float64 `json:"realized_pnl"`
+	UnrealizedPnL float64 `json:"unrealized_pnl"`
+}
+
+// Simulate runs a fresh strategy over the latest market data of a token and
+// returns the signals it would have produced with their hypothetical fills.
+// Nothing is traded or persisted, and running strategies are not touched.
+func Simulate(ctx context.Context, source MarketDataSource, req SimulationRequest) (*SimulationResult, error) {
+	if req.Token == "" {
+		return nil, fmt.Errorf("%w: token is required", ErrInvalidSimulation)
+	}
+	if req.SlippageBps < 0 {
+		return nil, fmt.Errorf("%w: slippage must not be negative", ErrInvalidSimulation)
+	}
+	newStrategy, ok := simulators[req.Strategy.Name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrNoSimulator, req.Strategy.Name)
+	}
+	limit := req.Candles
+	if limit <= 0 {
+		limit = DefaultSimulationCandles
+	}
+	limit = min(limit, MaxSimulationCandles)
+
+	strategy := newStrategy()
+	// The enabled flag of a stored config does not apply to a sandbox run
+	config := req.Strategy
+	config.Enabled = true
+	if err := strategy.ApplyConfig(&config); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSimulation, err)
+	}
+
+	data, err := source.GetHistoricalMarketData(ctx, req.Token, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load market data: %w", err)
+	}
+	candles := make([]models.MarketData, 0, len(data))
+	for i := len(data) - 1; i >= 0; i-- {
+		if data[i] != nil && (req.Timeframe == "" || data[i].Interval == req.Timeframe) {
+			candles = append(candles, *data[i])
+		}
+	}
+	if len(candles) == 0 {
+		return nil, ErrNoSimulationData
+	}
+
+	result := &SimulationResult{
+		Token:     req.Token,
+		Strategy:  req.Strategy.Name,
+		Timeframe: req.Timeframe,
+		Candles:   len(candles),
+		From:      candles[0].Timestamp,
+		To:        candles[len(candles)-1].Timestamp,
+		Signals:   []SimulatedSignal{},
+	}
+	var book simulatedBook
+	for _, signal := range strategy.GenerateSignals(ctx, candles) {
+		simulated := SimulatedSignal{
+			Side:       signalSide(signal.SignalType),
+			Price:      signal.Price,
+			Size:       signal.Size,
+			Confidence: signal.Confidence,
+			Timestamp:  signal.Timestamp,
+			Sizing:     signal.Sizing,
+		}
+		if next := nextCandle(candles, signal.Timestamp); next != nil && simulated.Side != "" {
+			simulated.Fill = &SimulatedFill{
+				Price:     fillPrice(*next, simulated.Side, req.SlippageBps),
+				Timestamp: next.Timestamp,
+			}
+			book.fill(simulated.Side, simulated.Fill.Price, simulated.Size)
+		}
+		result.Signals = append(result.Signals, simulated)
+	}
+
+	last := candles[len(candles)-1].ClosePrice
+	result.NetSize = book.size
+	result.RealizedPnL = book.realized
+	result.UnrealizedPnL = book.size * (last - book.entry)
+	return result, nil
+}
+
+func signalSide(signal models.SignalType) string {
+	switch signal {
+	case models.Buy:
+		return "buy"
+	case models.Sell:
+		return "sell"
+	}
+	return ""
+}
+
+// nextCandle returns the first candle after a time, nil when there is none
+func nextCandle(candles []models.MarketData, at time.Time) *models.MarketData {
+	for i := range candles {
+		if candles[i].Timestamp.After(at) {
+			return &candles[i]
+		}
+	}
+	return nil
+}
+
+// fillPrice is the open of a candle, or its close for data without an open,
+// moved against the trade by the slippage
+func fillPrice(candle models.MarketData, side string, slippageBps float64) float64 {
+	price := candle.OpenPrice
+	if price <= 0 {
+		price = candle.ClosePrice
+	}
+	slippage := price * slippageBps / 10000
+	if side == "sell" {
+		return price - slippage
+	}
+	return price + slippage
+}
+
+// simulatedBook tracks the position of the simulated fills at their average
+// entry price
+type simulatedBook struct {
+	size     float64
+	entry    float64
+	realized float64
+}
+
+func (b *simulatedBook) fill(side string, price, size float64) {
+	if size <= 0 {
+		return
+	}
+	if side == "sell" {
+		size = -size
+	}
+	if b.size == 0 || math.Signbit(b.size) == math.Signbit(size) {
+		total := b.size + size
+		b.entry = (b.entry*b.size + price*size) / total
+		b.size = total
+		return
+	}
+
+	// Reduces the position, reversing it when the fill is larger
+	closed := math.Min(math.Abs(size), math.Abs(b.size))
+	if b.size > 0 {
+		b.realized += closed * (price - b.entry)
+	} else {
+		b.realized += closed * (b.entry - price)
+	}
+	b.size += size
+	switch {
+	case math.Abs(b.size) < 1e-12:
+		b.size, b.entry = 0, 0
+	case math.Signbit(b.size) == math.Signbit(size):
+		b.entry = price
+	}
+}
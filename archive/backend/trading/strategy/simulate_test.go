@@ -0,0 +1,144 @@
+package strategy
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type historySource struct {
+	data  []*models.MarketData
+	limit int
+}
+
+func (s *historySource) GetHistoricalMarketData(ctx context.Context, tokenAddress string, limit int) ([]*models.MarketData, error) {
+	s.limit = limit
+	return s.data, nil
+}
+
+// scriptedStrategy signals at fixed candles, sized by its size parameter
+type scriptedStrategy struct {
+	size    float64
+	signals map[int]models.SignalType
+}
+
+func (s *scriptedStrategy) ApplyConfig(config *models.StrategyConfig) error {
+	var params struct {
+		Size *float64 `json:"size"`
+	}
+	if err := json.Unmarshal(config.Parameters, &params); err != nil {
+		return err
+	}
+	if params.Size != nil {
+		s.size = *params.Size
+	}
+	return nil
+}
+
+func (s *scriptedStrategy) GenerateSignals(ctx context.Context, marketData []models.MarketData) []models.TradeSignal {
+	var signals []models.TradeSignal
+	for i, data := range marketData {
+		if signal, ok := s.signals[i]; ok {
+			signals = append(signals, models.TradeSignal{Symbol: data.Symbol, SignalType: signal, Price: data.ClosePrice, Size: s.size, Timestamp: data.Timestamp})
+		}
+	}
+	return signals
+}
+
+func TestSimulate(t *testing.T) {
+	simulators["scripted"] = func() SignalGenerator {
+		return &scriptedStrategy{size: 1, signals: map[int]models.SignalType{0: models.Buy, 2: models.Sell, 3: models.Sell}}
+	}
+	defer delete(simulators, "scripted")
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var history []*models.MarketData
+	for i, price := range []float64{100, 110, 120, 130} {
+		history = append([]*models.MarketData{{
+			TokenAddress: "token-a",
+			Interval:     "1h",
+			OpenPrice:    price - 5,
+			ClosePrice:   price,
+			Timestamp:    start.Add(time.Duration(i) * time.Hour),
+		}}, history...)
+	}
+	history = append(history, &models.MarketData{TokenAddress: "token-a", Interval: "1m", ClosePrice: 90, Timestamp: start.Add(-time.Minute)})
+	source := &historySource{data: history}
+
+	result, err := Simulate(context.Background(), source, SimulationRequest{
+		Token:     "token-a",
+		Timeframe: "1h",
+		Strategy:  models.StrategyConfig{Name: "scripted", Parameters: json.RawMessage(`{"size":2}`)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultSimulationCandles, source.limit)
+	assert.Equal(t, 4, result.Candles, "data of other timeframes is skipped")
+	assert.Equal(t, start, result.From)
+	require.Len(t, result.Signals, 3)
+
+	// Filled at the open of the next candle
+	buy := result.Signals[0]
+	assert.Equal(t, "buy", buy.Side)
+	assert.Equal(t, 100.0, buy.Price)
+	require.NotNil(t, buy.Fill)
+	assert.Equal(t, 105.0, buy.Fill.Price)
+	assert.Equal(t, start.Add(time.Hour), buy.Fill.Timestamp)
+	assert.Equal(t, 125.0, result.Signals[1].Fill.Price)
+	assert.Nil(t, result.Signals[2].Fill, "the last candle has no next open")
+
+	assert.Equal(t, 0.0, result.NetSize)
+	assert.InDelta(t, 40.0, result.RealizedPnL, 1e-9)
+	assert.Equal(t, 0.0, result.UnrealizedPnL)
+
+	// Slippage moves fills against the trade
+	result, err = Simulate(context.Background(), source, SimulationRequest{
+		Token:       "token-a",
+		Timeframe:   "1h",
+		Candles:     5000,
+		SlippageBps: 100,
+		Strategy:    models.StrategyConfig{Name: "scripted", Parameters: json.RawMessage(`{}`)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, MaxSimulationCandles, source.limit)
+	assert.InDelta(t, 106.05, result.Signals[0].Fill.Price, 1e-9)
+	assert.InDelta(t, 123.75, result.Signals[1].Fill.Price, 1e-9)
+}
+
+func TestSimulateErrors(t *testing.T) {
+	ctx := context.Background()
+	source := &historySource{data: []*models.MarketData{{Interval: "1m", ClosePrice: 1}}}
+
+	_, err := Simulate(ctx, source, SimulationRequest{Strategy: models.StrategyConfig{Name: "momentum"}})
+	assert.ErrorIs(t, err, ErrInvalidSimulation)
+	_, err = Simulate(ctx, source, SimulationRequest{Token: "token-a", Strategy: models.StrategyConfig{Name: "martingale"}})
+	assert.ErrorIs(t, err, ErrNoSimulator)
+	_, err = Simulate(ctx, source, SimulationRequest{
+		Token:    "token-a",
+		Strategy: models.StrategyConfig{Name: "momentum", Parameters: json.RawMessage(`{"macd_fast_period":30}`)},
+	})
+	assert.ErrorIs(t, err, ErrInvalidSimulation)
+	_, err = Simulate(ctx, source, SimulationRequest{Token: "token-a", Timeframe: "1h", Strategy: models.StrategyConfig{Name: "momentum"}})
+	assert.ErrorIs(t, err, ErrNoSimulationData)
+}
+
+func TestSimulatedBook(t *testing.T) {
+	var book simulatedBook
+	book.fill("buy", 100, 1)
+	book.fill("buy", 110, 1)
+	assert.Equal(t, 105.0, book.entry)
+
+	// Reverses to a short at the fill price
+	book.fill("sell", 120, 3)
+	assert.Equal(t, 30.0, book.realized)
+	assert.Equal(t, -1.0, book.size)
+	assert.Equal(t, 120.0, book.entry)
+
+	book.fill("buy", 100, 1)
+	assert.Equal(t, 50.0, book.realized)
+	assert.Equal(t, 0.0, book.size)
+}
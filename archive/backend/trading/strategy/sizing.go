@@ -0,0 +1,191 @@
+package strategy
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/trading/analysis"
+)
+
+// Position sizer types, as selected by the "sizer" strategy parameter
+const (
+	SizerFixedFraction    = "fixed_fraction"
+	SizerKelly            = "kelly"
+	SizerVolatilityTarget = "volatility_target"
+	SizerEqualNotional    = "equal_notional"
+)
+
+var (
+	// ErrNoEquity is returned by sizers risking a share of the equity when
+	// the equity is not known
+	ErrNoEquity = errors.New("equity required for position sizing")
+
+	// ErrNoEdge is returned by the Kelly sizer when the win rate and payoff
+	// ratio give no positive edge
+	ErrNoEdge = errors.New("no positive edge to size")
+)
+
+// SizingInput is what a PositionSizer sizes a signal from
+type SizingInput struct {
+	Symbol string
+	Price  float64
+	Equity float64
+	// Candles are the candles up to the signal, oldest first
+	Candles []models.MarketData
+}
+
+// PositionSizer chooses the size of a signal. It returns the size with the
+// inputs that determined it, which are recorded on the signal.
+type PositionSizer interface {
+	Name() string
+	Size(input SizingInput) (float64, map[string]float64, error)
+}
+
+// SizerConfig selects and parameterises a PositionSizer
+type SizerConfig struct {
+	Type string `json:"type"`
+	// Fraction is the share of the equity risked by the fixed fraction and
+	// volatility target sizers, and the share of the Kelly fraction taken
+	// by the Kelly sizer
+	Fraction    float64 `json:"fraction"`
+	WinRate     float64 `json:"win_rate"`
+	PayoffRatio float64 `json:"payoff_ratio"`
+	// Cap is the maximum share of the equity the Kelly sizer commits
+	Cap         float64 `json:"cap"`
+	ATRPeriod   int     `json:"atr_period"`
+	ATRMultiple float64 `json:"atr_multiple"`
+	Notional    float64 `json:"notional"`
+}
+
+// NewPositionSizer creates the sizer selected by a configuration
+func NewPositionSizer(config SizerConfig) (PositionSizer, error) {
+	switch config.Type {
+	case SizerFixedFraction:
+		if config.Fraction <= 0 || config.Fraction > 1 {
+			return nil, fmt.Errorf("fixed fraction must be between 0 and 1")
+		}
+		return FixedFractionSizer{Fraction: config.Fraction}, nil
+	case SizerKelly:
+		sizer := KellySizer{WinRate: config.WinRate, PayoffRatio: config.PayoffRatio, Fraction: config.Fraction, Cap: config.Cap}
+		if sizer.Fraction == 0 {
+			sizer.Fraction = 1
+		}
+		if sizer.WinRate <= 0 || sizer.WinRate >= 1 || sizer.PayoffRatio <= 0 {
+			return nil, fmt.Errorf("kelly sizing needs a win rate between 0 and 1 and a positive payoff ratio")
+		}
+		if sizer.Fraction < 0 || sizer.Fraction > 1 || sizer.Cap <= 0 || sizer.Cap > 1 {
+			return nil, fmt.Errorf("kelly fraction and cap must be between 0 and 1")
+		}
+		return sizer, nil
+	case SizerVolatilityTarget:
+		sizer := VolatilityTargetSizer{Fraction: config.Fraction, ATRPeriod: config.ATRPeriod, ATRMultiple: config.ATRMultiple}
+		if sizer.ATRPeriod == 0 {
+			sizer.ATRPeriod = 14
+		}
+		if sizer.ATRMultiple == 0 {
+			sizer.ATRMultiple = 2
+		}
+		if sizer.Fraction <= 0 || sizer.Fraction > 1 || sizer.ATRPeriod < 0 || sizer.ATRMultiple < 0 {
+			return nil, fmt.Errorf("volatility target needs a fraction between 0 and 1 and a positive ATR period and multiple")
+		}
+		return sizer, nil
+	case SizerEqualNotional:
+		if config.Notional <= 0 {
+			return nil, fmt.Errorf("equal notional must be positive")
+		}
+		return EqualNotionalSizer{Notional: config.Notional}, nil
+	default:
+		return nil, fmt.Errorf("unknown position sizer %q", config.Type)
+	}
+}
+
+// FixedFractionSizer commits a fixed share of the equity to each signal
+type FixedFractionSizer struct {
+	Fraction float64
+}
+
+func (s FixedFractionSizer) Name() string { return SizerFixedFraction }
+
+func (s FixedFractionSizer) Size(input SizingInput) (float64, map[string]float64, error) {
+	if input.Equity <= 0 {
+		return 0, nil, ErrNoEquity
+	}
+	inputs := map[string]float64{"equity": input.Equity, "fraction": s.Fraction, "price": input.Price}
+	return input.Equity * s.Fraction / input.Price, inputs, nil
+}
+
+// KellySizer commits the Kelly fraction of the equity, W - (1-W)/R for win
+// rate W and payoff ratio R, scaled by Fraction and capped at Cap
+type KellySizer struct {
+	WinRate     float64
+	PayoffRatio float64
+	Fraction    float64
+	Cap         float64
+}
+
+func (s KellySizer) Name() string { return SizerKelly }
+
+func (s KellySizer) Size(input SizingInput) (float64, map[string]float64, error) {
+	if input.Equity <= 0 {
+		return 0, nil, ErrNoEquity
+	}
+	kelly := s.WinRate - (1-s.WinRate)/s.PayoffRatio
+	if kelly <= 0 {
+		return 0, nil, ErrNoEdge
+	}
+	committed := math.Min(kelly*s.Fraction, s.Cap)
+	inputs := map[string]float64{
+		"equity":       input.Equity,
+		"price":        input.Price,
+		"win_rate":     s.WinRate,
+		"payoff_ratio": s.PayoffRatio,
+		"kelly":        kelly,
+		"fraction":     committed,
+	}
+	return input.Equity * committed / input.Price, inputs, nil
+}
+
+// VolatilityTargetSizer risks a share of the equity over a stop distance of
+// a multiple of the ATR, so positions shrink as volatility grows
+type VolatilityTargetSizer struct {
+	Fraction    float64
+	ATRPeriod   int
+	ATRMultiple float64
+}
+
+func (s VolatilityTargetSizer) Name() string { return SizerVolatilityTarget }
+
+func (s VolatilityTargetSizer) Size(input SizingInput) (float64, map[string]float64, error) {
+	if input.Equity <= 0 {
+		return 0, nil, ErrNoEquity
+	}
+	atr, err := analysis.ATR(input.Candles, s.ATRPeriod)
+	if err != nil {
+		return 0, nil, err
+	}
+	if atr <= 0 {
+		return 0, nil, fmt.Errorf("no volatility to target for %s", input.Symbol)
+	}
+	inputs := map[string]float64{
+		"equity":       input.Equity,
+		"fraction":     s.Fraction,
+		"price":        input.Price,
+		"atr":          atr,
+		"atr_multiple": s.ATRMultiple,
+	}
+	return input.Equity * s.Fraction / (atr * s.ATRMultiple), inputs, nil
+}
+
+// EqualNotionalSizer gives every signal the same notional value
+type EqualNotionalSizer struct {
+	Notional float64
+}
+
+func (s EqualNotionalSizer) Name() string { return SizerEqualNotional }
+
+func (s EqualNotionalSizer) Size(input SizingInput) (float64, map[string]float64, error) {
+	inputs := map[string]float64{"notional": s.Notional, "price": input.Price}
+	return s.Notional / input.Price, inputs, nil
+}
@@ -0,0 +1,84 @@
+package strategy
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/leonzhao/trading-system/backend/models"
+)
+
+func TestPositionSizers(t *testing.T) {
+	input := SizingInput{Symbol: "SOL/USDC", Price: 100, Equity: 10000}
+
+	size, inputs, err := FixedFractionSizer{Fraction: 0.1}.Size(input)
+	require.NoError(t, err)
+	assert.InDelta(t, 10, size, 1e-9)
+	assert.Equal(t, 0.1, inputs["fraction"])
+
+	// Kelly of a 60% win rate at 1:1 is 0.2, halved or capped
+	size, inputs, err = KellySizer{WinRate: 0.6, PayoffRatio: 1, Fraction: 0.5, Cap: 0.25}.Size(input)
+	require.NoError(t, err)
+	assert.InDelta(t, 10, size, 1e-9)
+	assert.InDelta(t, 0.2, inputs["kelly"], 1e-9)
+	size, _, err = KellySizer{WinRate: 0.6, PayoffRatio: 1, Fraction: 1, Cap: 0.05}.Size(input)
+	require.NoError(t, err)
+	assert.InDelta(t, 5, size, 1e-9)
+	_, _, err = KellySizer{WinRate: 0.4, PayoffRatio: 1, Fraction: 1, Cap: 1}.Size(input)
+	assert.ErrorIs(t, err, ErrNoEdge)
+
+	// Every candle has a true range of 4, so a 2 ATR stop is 8 away
+	for i := 0; i < 20; i++ {
+		input.Candles = append(input.Candles, models.MarketData{HighPrice: 102, LowPrice: 98, ClosePrice: 100})
+	}
+	size, inputs, err = VolatilityTargetSizer{Fraction: 0.01, ATRPeriod: 14, ATRMultiple: 2}.Size(input)
+	require.NoError(t, err)
+	assert.InDelta(t, 12.5, size, 1e-9)
+	assert.InDelta(t, 4, inputs["atr"], 1e-9)
+	_, _, err = VolatilityTargetSizer{Fraction: 0.01, ATRPeriod: 30, ATRMultiple: 2}.Size(input)
+	assert.Error(t, err)
+
+	size, _, err = EqualNotionalSizer{Notional: 500}.Size(input)
+	require.NoError(t, err)
+	assert.InDelta(t, 5, size, 1e-9)
+
+	_, _, err = FixedFractionSizer{Fraction: 0.1}.Size(SizingInput{Price: 100})
+	assert.ErrorIs(t, err, ErrNoEquity)
+
+	_, err = NewPositionSizer(SizerConfig{Type: "martingale"})
+	assert.Error(t, err)
+	sizer, err := NewPositionSizer(SizerConfig{Type: SizerVolatilityTarget, Fraction: 0.01})
+	require.NoError(t, err)
+	assert.Equal(t, VolatilityTargetSizer{Fraction: 0.01, ATRPeriod: 14, ATRMultiple: 2}, sizer)
+}
+
+func TestMomentumStrategy_Sizer(t *testing.T) {
+	momentum := NewMomentumStrategy(14, 12, 26, 9, 1000)
+	require.NoError(t, momentum.ApplyConfig(&models.StrategyConfig{
+		Enabled:    true,
+		Parameters: json.RawMessage(`{"equity": 20000, "sizer": {"type": "fixed_fraction", "fraction": 0.05}}`),
+	}))
+	assert.Equal(t, 20000.0, momentum.Equity)
+
+	data := []models.MarketData{
+		{Symbol: "BTC/USDT", ClosePrice: 45000, Timestamp: time.Now().Add(-24 * time.Hour)},
+		{Symbol: "BTC/USDT", ClosePrice: 46000, Timestamp: time.Now().Add(-23 * time.Hour)},
+		{Symbol: "BTC/USDT", ClosePrice: 47000, Timestamp: time.Now().Add(-22 * time.Hour)},
+	}
+	for _, signal := range momentum.GenerateSignals(context.Background(), data) {
+		require.NotNil(t, signal.Sizing)
+		assert.Equal(t, SizerFixedFraction, signal.Sizing.Sizer)
+		assert.Equal(t, 20000.0, signal.Sizing.Inputs["equity"])
+	}
+
+	err := momentum.ApplyConfig(&models.StrategyConfig{
+		Enabled:    true,
+		Parameters: json.RawMessage(`{"sizer": {"type": "kelly", "win_rate": 1.5, "payoff_ratio": 1, "cap": 0.1}}`),
+	})
+	assert.Error(t, err)
+	assert.Equal(t, SizerFixedFraction, momentum.sizer.Name(), "a rejected config keeps the current sizer")
+}
@@ -3,6 +3,8 @@ package strategy
 import (
 	"math"
 	"sort"
+
+	"github.com/leonzhao/trading-system/backend/trading/analysis"
 )
 
 // GridStrategy implements a grid trading strategy
@@ -12,19 +14,28 @@ type GridStrategy struct {
 	gridLevels int
 	gridSize   float64
 	positions  map[float64]float64 // price -> size
+	// regimeFilter gates new grid trades, blocking them in strong trends
+	// by default
+	regimeFilter RegimeFilter
 }
 
 // NewGridStrategy creates a new grid trading strategy
 func NewGridStrategy(upper, lower float64, levels int) *GridStrategy {
 	return &GridStrategy{
-		upperPrice: upper,
-		lowerPrice: lower,
-		gridLevels: levels,
-		gridSize:   (upper - lower) / float64(levels-1),
-		positions:  make(map[float64]float64),
+		upperPrice:   upper,
+		lowerPrice:   lower,
+		gridLevels:   levels,
+		gridSize:     (upper - lower) / float64(levels-1),
+		positions:    make(map[float64]float64),
+		regimeFilter: RegimeFilter{BlockStrongTrend: true},
 	}
 }
 
+// SetRegimeFilter replaces the regime filter of the grid
+func (g *GridStrategy) SetRegimeFilter(filter RegimeFilter) {
+	g.regimeFilter = filter
+}
+
 // CalculateGridLevels returns all price levels in the grid
 func (g *GridStrategy) CalculateGridLevels() []float64 {
 	levels := make([]float64, g.gridLevels)
@@ -58,6 +69,15 @@ func (g *GridStrategy) EvaluateGridPosition(currentPrice float64) (string, float
 	return "sell", upperLevel
 }
 
+// EvaluateGridPositionInRegime evaluates the grid like EvaluateGridPosition,
+// holding when the regime filter blocks the market regime
+func (g *GridStrategy) EvaluateGridPositionInRegime(currentPrice float64, regime *analysis.RegimeClassification) (string, float64) {
+	if !g.regimeFilter.Allows(regime) {
+		return "hold", 0
+	}
+	return g.EvaluateGridPosition(currentPrice)
+}
+
 // ArbitrageStrategy implements cross-exchange arbitrage
 type ArbitrageStrategy struct {
 	minProfitThreshold float64
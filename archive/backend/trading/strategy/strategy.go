@@ -2,7 +2,9 @@ package strategy
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/leonzhao/trading-system/backend/dex"
@@ -69,6 +71,9 @@ type BaseStrategy struct {
 	positions  map[string]*models.Position
 	signals    []*Signal
 	stats      map[string]interface{}
+	enabled    bool
+	symbols    []string
+	mu         sync.RWMutex
 }
 
 // NewBaseStrategy creates a new base strategy
@@ -77,28 +82,88 @@ func NewBaseStrategy() *BaseStrategy {
 		positions: make(map[string]*models.Position),
 		signals:   make([]*Signal, 0),
 		stats:    make(map[string]interface{}),
+		enabled:   true,
 	}
 }
 
 // Initialize initializes the base strategy
 func (s *BaseStrategy) Initialize(config StrategyConfig) error {
+	s.mu.Lock()
 	s.config = config
+	s.mu.Unlock()
+	return nil
+}
+
+// ApplyConfig hot-applies a stored configuration. Parameters are decoded
+// over the current StrategyConfig, so omitted fields keep their values.
+func (s *BaseStrategy) ApplyConfig(config *models.StrategyConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := s.config
+	if len(config.Parameters) > 0 {
+		if err := json.Unmarshal(config.Parameters, &next); err != nil {
+			return fmt.Errorf("invalid strategy parameters: %w", err)
+		}
+	}
+
+	s.config = next
+	s.enabled = config.Enabled
+	s.symbols = config.Symbols
 	return nil
 }
 
 // ValidateSignal performs basic signal validation
 func (s *BaseStrategy) ValidateSignal(ctx context.Context, signal *Signal) error {
-	if signal.Confidence < s.config.MinConfidence {
-		return fmt.Errorf("signal confidence %f below minimum %f", signal.Confidence, s.config.MinConfidence)
+	s.mu.RLock()
+	config := s.config
+	enabled := s.enabled
+	symbols := &models.StrategyConfig{Symbols: s.symbols}
+	s.mu.RUnlock()
+
+	if !enabled {
+		return fmt.Errorf("strategy disabled")
+	}
+
+	if !symbols.HasSymbol(signal.TokenAddress) {
+		return fmt.Errorf("token %s not enabled for strategy", signal.TokenAddress)
+	}
+
+	if signal.Confidence < config.MinConfidence {
+		return fmt.Errorf("signal confidence %f below minimum %f", signal.Confidence, config.MinConfidence)
 	}
 
-	if len(s.positions) >= s.config.MaxOpenPositions {
-		return fmt.Errorf("maximum open positions reached: %d", s.config.MaxOpenPositions)
+	if len(s.positions) >= config.MaxOpenPositions {
+		return fmt.Errorf("maximum open positions reached: %d", config.MaxOpenPositions)
 	}
 
 	return nil
 }
 
+// ValidateDepth checks that the order book can fill a buy or sell signal
+// within the strategy's maximum price impact
+func (s *BaseStrategy) ValidateDepth(signal *Signal, book *dex.OrderBook) error {
+	if signal.Action != dex.SideBuy && signal.Action != dex.SideSell {
+		return nil
+	}
+
+	s.mu.RLock()
+	maxImpact := s.config.MaxPriceImpact
+	s.mu.RUnlock()
+
+	estimate, err := book.EstimateSlippage(signal.Action, signal.Size)
+	if err != nil {
+		return fmt.Errorf("failed to estimate slippage: %w", err)
+	}
+	if !estimate.Complete {
+		return fmt.Errorf("order book depth %f below signal size %f", estimate.Filled, signal.Size)
+	}
+	if maxImpact > 0 && estimate.Slippage > maxImpact {
+		return fmt.Errorf("estimated slippage %.2f%% exceeds maximum price impact %.2f%%", estimate.Slippage, maxImpact)
+	}
+	return nil
+}
+
 // OnTradeExecuted updates strategy state after trade execution
 func (s *BaseStrategy) OnTradeExecuted(position *models.Position) error {
 	s.positions[position.TokenAddress] = position
@@ -2,9 +2,11 @@ package strategy
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
 	"time"
 
+	"github.com/leonzhao/trading-system/backend/dex"
 	"github.com/leonzhao/trading-system/backend/models"
 	"github.com/stretchr/testify/assert"
 )
@@ -32,3 +34,65 @@ func TestMomentumStrategy_GenerateSignals(t *testing.T) {
 		assert.NotEmpty(t, signal.Description)
 	}
 }
+
+type memoryConfigSource struct {
+	records map[string]*models.StrategyRecord
+}
+
+func (s *memoryConfigSource) GetStrategy(ctx context.Context, name string) (*models.StrategyRecord, error) {
+	return s.records[name], nil
+}
+
+func TestConfigWatcher(t *testing.T) {
+	ctx := context.Background()
+	momentum := NewMomentumStrategy(14, 12, 26, 9, 1000)
+
+	config := &models.StrategyConfig{
+		Name:       "momentum",
+		Enabled:    true,
+		Symbols:    []string{"SOL/USDC"},
+		Parameters: json.RawMessage(`{"rsi_period": 7, "risk_level": 500}`),
+	}
+	record := &models.StrategyRecord{}
+	assert.NoError(t, config.ApplyToRecord(record))
+
+	source := &memoryConfigSource{records: map[string]*models.StrategyRecord{"momentum": record}}
+	watcher := NewConfigWatcher(source, time.Minute, nil)
+	watcher.Register("momentum", momentum)
+
+	assert.NoError(t, watcher.Poll(ctx))
+	assert.Equal(t, 7, momentum.RSIPeriod)
+	assert.Equal(t, 500.0, momentum.RiskLevel)
+	assert.Equal(t, 12, momentum.MACDFastPeriod)
+
+	// Disabling the strategy stops signal generation without a restart
+	config.Enabled = false
+	assert.NoError(t, config.ApplyToRecord(record))
+	assert.NoError(t, watcher.Poll(ctx))
+	assert.Empty(t, momentum.GenerateSignals(ctx, []models.MarketData{
+		{Symbol: "SOL/USDC", ClosePrice: 100},
+		{Symbol: "SOL/USDC", ClosePrice: 101},
+	}))
+
+	// Invalid parameters are rejected and the previous config stays active
+	config.Enabled = true
+	config.Parameters = json.RawMessage(`{"macd_fast_period": 30}`)
+	assert.NoError(t, config.ApplyToRecord(record))
+	assert.Error(t, watcher.Poll(ctx))
+	assert.Equal(t, 12, momentum.MACDFastPeriod)
+}
+
+func TestBaseStrategy_ValidateDepth(t *testing.T) {
+	strategy := NewBaseStrategy()
+	assert.NoError(t, strategy.Initialize(StrategyConfig{MaxPriceImpact: 1}))
+
+	book := &dex.OrderBook{
+		Bids: []dex.OrderBookItem{{Price: 99.9, Size: 100}},
+		Asks: []dex.OrderBookItem{{Price: 100.1, Size: 50}, {Price: 103, Size: 50}},
+	}
+
+	assert.NoError(t, strategy.ValidateDepth(&Signal{Action: "buy", Size: 50}, book))
+	assert.ErrorContains(t, strategy.ValidateDepth(&Signal{Action: "buy", Size: 100}, book), "slippage")
+	assert.ErrorContains(t, strategy.ValidateDepth(&Signal{Action: "sell", Size: 200}, book), "depth")
+	assert.NoError(t, strategy.ValidateDepth(&Signal{Action: "hold", Size: 200}, book))
+}
@@ -0,0 +1,223 @@
+package synthetic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/models"
+)
+
+var (
+	// ErrInvalidBasket is returned when a basket definition is invalid
+	ErrInvalidBasket = errors.New("invalid basket")
+
+	// ErrUnknownBasket is returned when no basket has the given name
+	ErrUnknownBasket = errors.New("unknown basket")
+
+	// ErrBasketNotPriced is returned until every constituent has a price
+	ErrBasketNotPriced = errors.New("basket constituents not fully priced")
+)
+
+// Constituent is one token of a basket and its target weight
+type Constituent struct {
+	Symbol string  `json:"symbol"`
+	Weight float64 `json:"weight"`
+}
+
+// BasketConfig defines a synthetic instrument, such as a memecoin index, as
+// a weighted basket of tokens
+type BasketConfig struct {
+	Name         string        `json:"name"`
+	Constituents []Constituent `json:"constituents"`
+	// BaseValue is the basket price when it is first priced
+	BaseValue float64 `json:"base_value"`
+	// RebalanceThreshold is the drift of any constituent's weight from its
+	// target, as an absolute fraction, above which holdings are rebalanced
+	RebalanceThreshold float64 `json:"rebalance_threshold"`
+}
+
+// Default basket settings
+const (
+	DefaultBaseValue          = 100.0
+	DefaultRebalanceThreshold = 0.05
+)
+
+// LoadBaskets decodes and validates a JSON array of basket definitions.
+// Weights are normalized to sum to one.
+func LoadBaskets(data []byte) ([]BasketConfig, error) {
+	var configs []BasketConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("failed to decode baskets: %w", err)
+	}
+
+	seen := make(map[string]bool, len(configs))
+	for i := range configs {
+		if err := configs[i].normalize(); err != nil {
+			return nil, err
+		}
+		if seen[configs[i].Name] {
+			return nil, fmt.Errorf("%w: duplicate basket %s", ErrInvalidBasket, configs[i].Name)
+		}
+		seen[configs[i].Name] = true
+	}
+	return configs, nil
+}
+
+// normalize validates the basket, applies defaults and scales the weights to
+// sum to one
+func (c *BasketConfig) normalize() error {
+	if c.Name == "" {
+		return fmt.Errorf("%w: missing name", ErrInvalidBasket)
+	}
+	if len(c.Constituents) == 0 {
+		return fmt.Errorf("%w: %s has no constituents", ErrInvalidBasket, c.Name)
+	}
+
+	total := 0.0
+	seen := make(map[string]bool, len(c.Constituents))
+	for _, constituent := range c.Constituents {
+		if constituent.Symbol == "" || constituent.Symbol == c.Name {
+			return fmt.Errorf("%w: %s has an invalid constituent symbol %q", ErrInvalidBasket, c.Name, constituent.Symbol)
+		}
+		if seen[constituent.Symbol] {
+			return fmt.Errorf("%w: %s lists %s twice", ErrInvalidBasket, c.Name, constituent.Symbol)
+		}
+		seen[constituent.Symbol] = true
+		if !(constituent.Weight > 0) || math.IsInf(constituent.Weight, 0) {
+			return fmt.Errorf("%w: %s has weight %v for %s", ErrInvalidBasket, c.Name, constituent.Weight, constituent.Symbol)
+		}
+		total += constituent.Weight
+	}
+
+	constituents := make([]Constituent, len(c.Constituents))
+	for i, constituent := range c.Constituents {
+		constituents[i] = Constituent{Symbol: constituent.Symbol, Weight: constituent.Weight / total}
+	}
+	c.Constituents = constituents
+
+	if c.BaseValue < 0 || c.RebalanceThreshold < 0 {
+		return fmt.Errorf("%w: %s has a negative base value or rebalance threshold", ErrInvalidBasket, c.Name)
+	}
+	if c.BaseValue == 0 {
+		c.BaseValue = DefaultBaseValue
+	}
+	if c.RebalanceThreshold == 0 {
+		c.RebalanceThreshold = DefaultRebalanceThreshold
+	}
+	return nil
+}
+
+// basket prices a basket as a fixed quantity of each constituent per unit.
+// The quantities are set so each constituent holds its target weight at the
+// prices the basket was seeded or last rebalanced at.
+type basket struct {
+	config BasketConfig
+	units  map[string]float64
+}
+
+// seed sets the constituent quantities for a basket worth value at prices
+func (b *basket) seed(prices map[string]float64, value float64) {
+	b.units = make(map[string]float64, len(b.config.Constituents))
+	for _, constituent := range b.config.Constituents {
+		b.units[constituent.Symbol] = constituent.Weight * value / prices[constituent.Symbol]
+	}
+}
+
+// value returns the basket price, or false if it is not priced yet
+func (b *basket) value(prices map[string]float64) (float64, bool) {
+	if b.units == nil {
+		return 0, false
+	}
+	value := 0.0
+	for symbol, units := range b.units {
+		value += units * prices[symbol]
+	}
+	return value, true
+}
+
+// priced reports whether every constituent has a positive price
+func (b *basket) priced(prices map[string]float64) bool {
+	for _, constituent := range b.config.Constituents {
+		if !(prices[constituent.Symbol] > 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// composite builds basket candles from constituent candles, oldest first.
+// Constituents are joined on timestamp, carrying each one's latest candle
+// forward, and the series starts once all of them have traded. The basket is
+// seeded at BaseValue on the first candle and not rebalanced. High and low
+// are the sums of the constituent highs and lows, so they bound the true
+// basket range. Volume is the traded notional in basket units.
+func (b *basket) composite(history map[string][]models.MarketData) []models.MarketData {
+	var times []time.Time
+	seenTime := make(map[int64]bool)
+	for _, constituent := range b.config.Constituents {
+		for _, candle := range history[constituent.Symbol] {
+			if !seenTime[candle.Timestamp.UnixNano()] {
+				seenTime[candle.Timestamp.UnixNano()] = true
+				times = append(times, candle.Timestamp)
+			}
+		}
+	}
+	sort.Slice(times, func(i, j int) bool {
+		return times[i].Before(times[j])
+	})
+
+	series := make(map[string][]models.MarketData, len(b.config.Constituents))
+	for _, constituent := range b.config.Constituents {
+		candles := append([]models.MarketData(nil), history[constituent.Symbol]...)
+		sort.SliceStable(candles, func(i, j int) bool {
+			return candles[i].Timestamp.Before(candles[j].Timestamp)
+		})
+		series[constituent.Symbol] = candles
+	}
+
+	next := make(map[string]int, len(series))
+	latest := make(map[string]models.MarketData, len(series))
+	prices := make(map[string]float64, len(series))
+	index := &basket{config: b.config}
+	var candles []models.MarketData
+
+	for _, ts := range times {
+		for symbol, candles := range series {
+			for next[symbol] < len(candles) && !candles[next[symbol]].Timestamp.After(ts) {
+				latest[symbol] = candles[next[symbol]]
+				prices[symbol] = latest[symbol].ClosePrice
+				next[symbol]++
+			}
+		}
+		if index.units == nil {
+			if !index.priced(prices) {
+				continue
+			}
+			index.seed(prices, b.config.BaseValue)
+		}
+
+		candle := models.MarketData{
+			Symbol:       b.config.Name,
+			TokenAddress: b.config.Name,
+			Timestamp:    ts,
+		}
+		notional := 0.0
+		for symbol, units := range index.units {
+			c := latest[symbol]
+			candle.OpenPrice += units * c.OpenPrice
+			candle.HighPrice += units * c.HighPrice
+			candle.LowPrice += units * c.LowPrice
+			candle.ClosePrice += units * c.ClosePrice
+			notional += c.Volume * c.ClosePrice
+		}
+		if candle.ClosePrice > 0 {
+			candle.Volume = notional / candle.ClosePrice
+		}
+		candles = append(candles, candle)
+	}
+	return candles
+}
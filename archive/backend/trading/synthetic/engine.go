@@ -0,0 +1,255 @@
+package synthetic
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/monitoring"
+	"github.com/leonzhao/trading-system/backend/trading/analysis"
+)
+
+// Engine prices configured baskets from live constituent prices and
+// translates basket signals into constituent signals
+type Engine struct {
+	baskets  map[string]*basket
+	bySymbol map[string][]string
+	prices   map[string]float64
+	monitor  monitoring.IMonitor
+	mu       sync.RWMutex
+}
+
+// NewEngine creates an engine for the given baskets. The monitor may be nil.
+func NewEngine(configs []BasketConfig, monitor monitoring.IMonitor) (*Engine, error) {
+	e := &Engine{
+		baskets:  make(map[string]*basket, len(configs)),
+		bySymbol: make(map[string][]string),
+		prices:   make(map[string]float64),
+		monitor:  monitor,
+	}
+
+	for _, config := range configs {
+		if err := config.normalize(); err != nil {
+			return nil, err
+		}
+		if _, ok := e.baskets[config.Name]; ok {
+			return nil, fmt.Errorf("%w: duplicate basket %s", ErrInvalidBasket, config.Name)
+		}
+		e.baskets[config.Name] = &basket{config: config}
+		for _, constituent := range config.Constituents {
+			e.bySymbol[constituent.Symbol] = append(e.bySymbol[constituent.Symbol], config.Name)
+		}
+	}
+	return e, nil
+}
+
+// IsBasket reports whether symbol names a configured basket
+func (e *Engine) IsBasket(symbol string) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	_, ok := e.baskets[symbol]
+	return ok
+}
+
+// Update records a constituent price and returns the new price of every
+// priced basket containing it. A basket is seeded at its base value once all
+// of its constituents have a price.
+func (e *Engine) Update(data models.MarketData) []models.MarketData {
+	if !(data.ClosePrice > 0) {
+		return nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	names := e.bySymbol[data.Symbol]
+	if len(names) == 0 {
+		return nil
+	}
+	e.prices[data.Symbol] = data.ClosePrice
+
+	quotes := make([]models.MarketData, 0, len(names))
+	for _, name := range names {
+		b := e.baskets[name]
+		if b.units == nil {
+			if !b.priced(e.prices) {
+				continue
+			}
+			b.seed(e.prices, b.config.BaseValue)
+		}
+		value, _ := b.value(e.prices)
+		quotes = append(quotes, basketQuote(name, value, data.Timestamp))
+	}
+	return quotes
+}
+
+// Quote returns the current price of a basket
+func (e *Engine) Quote(name string) (models.MarketData, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	b, ok := e.baskets[name]
+	if !ok {
+		return models.MarketData{}, fmt.Errorf("%w: %s", ErrUnknownBasket, name)
+	}
+	value, ok := b.value(e.prices)
+	if !ok {
+		return models.MarketData{}, fmt.Errorf("%w: %s", ErrBasketNotPriced, name)
+	}
+	return basketQuote(name, value, time.Now()), nil
+}
+
+// Composite builds basket candles, oldest first, from constituent candles
+// keyed by symbol, so indicators can be calculated on the basket
+func (e *Engine) Composite(name string, history map[string][]models.MarketData) ([]models.MarketData, error) {
+	e.mu.RLock()
+	b, ok := e.baskets[name]
+	e.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownBasket, name)
+	}
+
+	candles := b.composite(history)
+	if len(candles) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrBasketNotPriced, name)
+	}
+	return candles, nil
+}
+
+// Signals generates indicator signals on a basket from constituent candles.
+// The signals carry the basket name as their symbol, so they can be passed to
+// ChildSignals or an executor configured with the engine.
+func (e *Engine) Signals(name string, history map[string][]models.MarketData) ([]models.TradeSignal, error) {
+	candles, err := e.Composite(name, history)
+	if err != nil {
+		return nil, err
+	}
+	return analysis.GenerateSignals(name, candles)
+}
+
+// ChildSignals translates a signal on a basket into one signal per
+// constituent. The signal size is in basket units; each child trades that
+// many units of its constituent's quantity per basket unit.
+func (e *Engine) ChildSignals(signal models.TradeSignal) ([]models.TradeSignal, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	b, ok := e.baskets[signal.Symbol]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownBasket, signal.Symbol)
+	}
+	if b.units == nil {
+		return nil, fmt.Errorf("%w: %s", ErrBasketNotPriced, signal.Symbol)
+	}
+
+	children := make([]models.TradeSignal, 0, len(b.config.Constituents))
+	for _, constituent := range b.config.Constituents {
+		child := signal
+		child.Symbol = constituent.Symbol
+		child.Price = e.prices[constituent.Symbol]
+		child.Size = signal.Size * b.units[constituent.Symbol]
+		child.Description = fmt.Sprintf("%s leg of %s: %s", constituent.Symbol, signal.Symbol, signal.Description)
+		children = append(children, child)
+	}
+	return children, nil
+}
+
+// Rebalance compares constituent holdings, keyed by symbol, against the
+// basket's target weights at current prices. If any weight drifted more than
+// the rebalance threshold it returns the buy and sell signals that restore
+// the targets, and re-seeds the basket at its current price so one unit again
+// holds the target weights.
+func (e *Engine) Rebalance(ctx context.Context, name string, holdings map[string]float64) ([]models.TradeSignal, error) {
+	e.mu.Lock()
+	b, ok := e.baskets[name]
+	if !ok {
+		e.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrUnknownBasket, name)
+	}
+	value, ok := b.value(e.prices)
+	if !ok {
+		e.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrBasketNotPriced, name)
+	}
+
+	total := 0.0
+	for _, constituent := range b.config.Constituents {
+		total += holdings[constituent.Symbol] * e.prices[constituent.Symbol]
+	}
+	if total <= 0 {
+		e.mu.Unlock()
+		return nil, nil
+	}
+
+	drift := 0.0
+	for _, constituent := range b.config.Constituents {
+		weight := holdings[constituent.Symbol] * e.prices[constituent.Symbol] / total
+		drift = math.Max(drift, math.Abs(weight-constituent.Weight))
+	}
+	if drift <= b.config.RebalanceThreshold {
+		e.mu.Unlock()
+		return nil, nil
+	}
+
+	now := time.Now()
+	var signals []models.TradeSignal
+	for _, constituent := range b.config.Constituents {
+		price := e.prices[constituent.Symbol]
+		delta := constituent.Weight*total/price - holdings[constituent.Symbol]
+		if delta == 0 {
+			continue
+		}
+		signal := models.TradeSignal{
+			Symbol:        constituent.Symbol,
+			SignalType:    models.SignalTypeBuy,
+			Price:         price,
+			Size:          delta,
+			Timestamp:     now,
+			Strength:      models.SignalStrengthMedium,
+			Confidence:    1,
+			Description:   fmt.Sprintf("Rebalance %s to %.1f%% weight", name, constituent.Weight*100),
+			IndicatorType: "rebalance",
+		}
+		if delta < 0 {
+			signal.SignalType = models.SignalTypeSell
+			signal.Size = -delta
+		}
+		signals = append(signals, signal)
+	}
+	sort.Slice(signals, func(i, j int) bool {
+		return signals[i].Symbol < signals[j].Symbol
+	})
+	b.seed(e.prices, value)
+	e.mu.Unlock()
+
+	if e.monitor != nil {
+		e.monitor.RecordEvent(ctx, monitoring.Event{
+			Type:     monitoring.MetricTrading,
+			Severity: monitoring.SeverityInfo,
+			Message:  "Basket rebalanced",
+			Details: map[string]interface{}{
+				"basket": name,
+				"drift":  drift,
+				"value":  total,
+				"orders": len(signals),
+			},
+		})
+	}
+	return signals, nil
+}
+
+func basketQuote(name string, value float64, ts time.Time) models.MarketData {
+	return models.MarketData{
+		Symbol:       name,
+		TokenAddress: name,
+		OpenPrice:    value,
+		HighPrice:    value,
+		LowPrice:     value,
+		ClosePrice:   value,
+		Timestamp:    ts,
+	}
+}
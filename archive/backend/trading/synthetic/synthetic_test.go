@@ -0,0 +1,172 @@
+package synthetic
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const memeIndex = `[{
+	"name": "MEME-INDEX",
+	"constituents": [
+		{"symbol": "BONK", "weight": 2},
+		{"symbol": "WIF", "weight": 1},
+		{"symbol": "POPCAT", "weight": 1}
+	],
+	"rebalance_threshold": 0.1
+}]`
+
+func quote(symbol string, price float64, ts time.Time) models.MarketData {
+	return models.MarketData{Symbol: symbol, ClosePrice: price, Timestamp: ts}
+}
+
+func TestLoadBaskets(t *testing.T) {
+	configs, err := LoadBaskets([]byte(memeIndex))
+	require.NoError(t, err)
+	require.Len(t, configs, 1)
+	assert.Equal(t, 0.5, configs[0].Constituents[0].Weight)
+	assert.Equal(t, 0.25, configs[0].Constituents[1].Weight)
+	assert.Equal(t, DefaultBaseValue, configs[0].BaseValue)
+	assert.Equal(t, 0.1, configs[0].RebalanceThreshold)
+
+	invalid := []string{
+		`[{"name": "EMPTY"}]`,
+		`[{"name": "NEG", "constituents": [{"symbol": "BONK", "weight": -1}]}]`,
+		`[{"name": "DUP", "constituents": [{"symbol": "BONK", "weight": 1}, {"symbol": "BONK", "weight": 1}]}]`,
+		`[{"name": "A", "constituents": [{"symbol": "BONK", "weight": 1}]}, {"name": "A", "constituents": [{"symbol": "WIF", "weight": 1}]}]`,
+	}
+	for _, data := range invalid {
+		_, err := LoadBaskets([]byte(data))
+		assert.ErrorIs(t, err, ErrInvalidBasket, data)
+	}
+}
+
+func TestEngine(t *testing.T) {
+	ctx := context.Background()
+	configs, err := LoadBaskets([]byte(memeIndex))
+	require.NoError(t, err)
+	engine, err := NewEngine(configs, nil)
+	require.NoError(t, err)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.True(t, engine.IsBasket("MEME-INDEX"))
+	assert.False(t, engine.IsBasket("BONK"))
+
+	t.Run("Basket is priced once all constituents are", func(t *testing.T) {
+		assert.Empty(t, engine.Update(quote("BONK", 0.00002, now)))
+		assert.Empty(t, engine.Update(quote("WIF", 2, now)))
+		_, err := engine.Quote("MEME-INDEX")
+		assert.ErrorIs(t, err, ErrBasketNotPriced)
+
+		quotes := engine.Update(quote("POPCAT", 0.5, now))
+		require.Len(t, quotes, 1)
+		assert.Equal(t, "MEME-INDEX", quotes[0].Symbol)
+		assert.InDelta(t, 100, quotes[0].ClosePrice, 1e-9)
+
+		// BONK doubles: half the basket doubles
+		quotes = engine.Update(quote("BONK", 0.00004, now.Add(time.Minute)))
+		require.Len(t, quotes, 1)
+		assert.InDelta(t, 150, quotes[0].ClosePrice, 1e-9)
+
+		_, err = engine.Quote("UNKNOWN")
+		assert.ErrorIs(t, err, ErrUnknownBasket)
+	})
+
+	t.Run("Basket orders become constituent orders", func(t *testing.T) {
+		children, err := engine.ChildSignals(models.TradeSignal{
+			Symbol:     "MEME-INDEX",
+			SignalType: models.SignalTypeBuy,
+			Size:       2,
+		})
+		require.NoError(t, err)
+		require.Len(t, children, 3)
+
+		value := 0.0
+		for _, child := range children {
+			assert.Equal(t, models.SignalTypeBuy, child.SignalType)
+			value += child.Size * child.Price
+		}
+		assert.InDelta(t, 300, value, 1e-6, "two basket units at 150")
+		assert.Equal(t, "BONK", children[0].Symbol)
+		assert.InDelta(t, 2*50/0.00002, children[0].Size, 1e-3)
+	})
+
+	t.Run("Drifted holdings are rebalanced to target weights", func(t *testing.T) {
+		// One seeded basket unit after BONK doubled: 66.7% BONK
+		holdings := map[string]float64{"BONK": 50 / 0.00002, "WIF": 12.5, "POPCAT": 50}
+		signals, err := engine.Rebalance(ctx, "MEME-INDEX", holdings)
+		require.NoError(t, err)
+		require.Len(t, signals, 3)
+
+		bySymbol := make(map[string]models.TradeSignal)
+		for _, signal := range signals {
+			bySymbol[signal.Symbol] = signal
+		}
+		assert.Equal(t, models.SignalTypeSell, bySymbol["BONK"].SignalType)
+		assert.InDelta(t, 25/0.00004, bySymbol["BONK"].Size, 1e-3)
+		assert.Equal(t, models.SignalTypeBuy, bySymbol["WIF"].SignalType)
+		assert.InDelta(t, 6.25, bySymbol["WIF"].Size, 1e-9)
+
+		// The basket keeps its price, and a unit holds the target weights again
+		quote, err := engine.Quote("MEME-INDEX")
+		require.NoError(t, err)
+		assert.InDelta(t, 150, quote.ClosePrice, 1e-9)
+		children, err := engine.ChildSignals(models.TradeSignal{Symbol: "MEME-INDEX", SignalType: models.SignalTypeSell, Size: 1})
+		require.NoError(t, err)
+		assert.InDelta(t, 75, children[0].Size*children[0].Price, 1e-9)
+
+		// Within the threshold nothing trades
+		balanced := map[string]float64{"BONK": 75 / 0.00004, "WIF": 18.75, "POPCAT": 75}
+		signals, err = engine.Rebalance(ctx, "MEME-INDEX", balanced)
+		require.NoError(t, err)
+		assert.Empty(t, signals)
+	})
+}
+
+func TestComposite(t *testing.T) {
+	configs, err := LoadBaskets([]byte(`[{"name": "PAIR", "constituents": [{"symbol": "A", "weight": 1}, {"symbol": "B", "weight": 1}]}]`))
+	require.NoError(t, err)
+	engine, err := NewEngine(configs, nil)
+	require.NoError(t, err)
+	base := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	candle := func(symbol string, i int, price, volume float64) models.MarketData {
+		return models.MarketData{
+			Symbol:     symbol,
+			OpenPrice:  price,
+			HighPrice:  price * 1.1,
+			LowPrice:   price * 0.9,
+			ClosePrice: price,
+			Volume:     volume,
+			Timestamp:  base.Add(time.Duration(i) * time.Hour),
+		}
+	}
+
+	// B starts an hour late and misses hour 3, which carries hour 2 forward
+	history := map[string][]models.MarketData{
+		"A": {candle("A", 0, 10, 1), candle("A", 1, 10, 1), candle("A", 2, 20, 1), candle("A", 3, 20, 1)},
+		"B": {candle("B", 1, 100, 1), candle("B", 2, 100, 1)},
+	}
+	candles, err := engine.Composite("PAIR", history)
+	require.NoError(t, err)
+	require.Len(t, candles, 3)
+	assert.Equal(t, base.Add(time.Hour), candles[0].Timestamp)
+	assert.InDelta(t, 100, candles[0].ClosePrice, 1e-9)
+	assert.InDelta(t, 110, candles[0].HighPrice, 1e-9)
+	assert.InDelta(t, 150, candles[1].ClosePrice, 1e-9)
+	assert.InDelta(t, 150, candles[2].ClosePrice, 1e-9)
+	assert.InDelta(t, 120.0/150, candles[2].Volume, 1e-9)
+
+	_, err = engine.Composite("PAIR", map[string][]models.MarketData{"A": history["A"]})
+	assert.ErrorIs(t, err, ErrBasketNotPriced)
+
+	signals, err := engine.Signals("PAIR", history)
+	require.NoError(t, err)
+	for _, signal := range signals {
+		assert.Equal(t, "PAIR", signal.Symbol)
+	}
+}
@@ -12,8 +12,10 @@ import (
 
 // TransactionManager handles DEX transactions
 type TransactionManager struct {
-	dexClient *dex.DexClient
-	monitor   *monitoring.Monitor
+	dexClient  *dex.DexClient
+	monitor    *monitoring.Monitor
+	tracker    *TxTracker
+	aggregator *dex.Aggregator
 }
 
 // NewTransactionManager creates a new transaction manager
@@ -76,19 +78,37 @@ func (m *TransactionManager) ExecuteSwap(ctx context.Context, swap *models.Trade
 	return nil
 }
 
+// SetAggregator routes swaps through the venue with the best quote
+func (m *TransactionManager) SetAggregator(aggregator *dex.Aggregator) {
+	m.aggregator = aggregator
+}
+
+// SetTxTracker sets the tracker that follows submitted transactions
+func (m *TransactionManager) SetTxTracker(tracker *TxTracker) {
+	m.tracker = tracker
+}
+
 // GetTransactionStatus gets the status of a transaction
 func (m *TransactionManager) GetTransactionStatus(ctx context.Context, txHash string) (string, error) {
-	// TODO: Implement transaction status check
-	return models.TxStatusPending, nil
+	if m.tracker == nil {
+		return models.TxStatusPending, nil
+	}
+	return m.tracker.Status(txHash)
 }
 
-// WaitForTransaction waits for a transaction to complete
+// WaitForTransaction waits for a transaction to be confirmed, returning an
+// error if it failed or was dropped
 func (m *TransactionManager) WaitForTransaction(ctx context.Context, txHash string) error {
-	// TODO: Implement transaction waiting
-	// This would involve:
-	// 1. Polling transaction status
-	// 2. Handling timeouts
-	// 3. Verifying transaction success
+	if m.tracker == nil {
+		return nil
+	}
+	status, err := m.tracker.Wait(ctx, txHash)
+	if err != nil {
+		return err
+	}
+	if status != models.TxStatusConfirmed {
+		return fmt.Errorf("transaction %s %s", txHash, status)
+	}
 	return nil
 }
 
@@ -98,13 +118,27 @@ func (m *TransactionManager) EstimateGas(ctx context.Context, swap *models.Trade
 	return 200000, nil
 }
 
-// GetOptimalRoute gets the optimal route for a swap
+// GetOptimalRoute gets the best quote for a swap across the aggregated DEX
+// venues, or from the DEX client if no aggregator is set
 func (m *TransactionManager) GetOptimalRoute(ctx context.Context, inputToken, outputToken string, amount float64) (*dex.QuoteResponse, error) {
-	// TODO: Implement route optimization
-	// This would involve:
-	// 1. Getting quotes from different DEXs
-	// 2. Comparing prices and fees
-	// 3. Considering price impact
-	// 4. Selecting best route
-	return m.dexClient.GetQuote(ctx, amount, inputToken, outputToken)
+	if m.aggregator == nil {
+		return m.dexClient.GetQuote(ctx, amount, inputToken, outputToken)
+	}
+
+	quotes, err := m.aggregator.BestQuote(ctx, &dex.QuoteRequest{
+		InputMint:  inputToken,
+		OutputMint: outputToken,
+		Amount:     amount,
+	})
+	if err != nil {
+		return nil, err
+	}
+	best := quotes.Best
+	return &dex.QuoteResponse{
+		InputAmount:  best.InputAmount,
+		OutputAmount: best.OutputAmount,
+		Price:        best.Price,
+		PriceImpact:  best.PriceImpact,
+		Fee:          best.Fee,
+	}, nil
 }
@@ -0,0 +1,375 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/dex"
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/monitoring"
+)
+
+// maxSignaturesPerRequest is the getSignatureStatuses batch limit
+const maxSignaturesPerRequest = 256
+
+// ErrUnknownTransaction is returned for signatures the tracker never tracked
+var ErrUnknownTransaction = errors.New("unknown transaction")
+
+// SignatureStatusSource reads transaction confirmation state from the chain
+type SignatureStatusSource interface {
+	GetSignatureStatuses(ctx context.Context, signatures []string) ([]*dex.SignatureStatus, error)
+	GetBlockHeight(ctx context.Context) (uint64, error)
+}
+
+// SwapResubmitter rebuilds a swap with a fresh blockhash and the given
+// priority fee, in micro-lamports per compute unit, and broadcasts it
+type SwapResubmitter interface {
+	ResubmitSwap(ctx context.Context, trade *models.Trade, priorityFee uint64) (signature string, lastValidBlockHeight uint64, err error)
+}
+
+// TradeUpdater persists trade status changes
+type TradeUpdater interface {
+	UpdateTrade(ctx context.Context, trade *models.Trade) error
+}
+
+// TxTrackerConfig contains transaction confirmation tracking configuration
+type TxTrackerConfig struct {
+	// PollInterval is how often pending signatures are checked
+	PollInterval time.Duration
+	// Commitment is the confirmation level at which a trade is completed
+	Commitment string
+	// MaxRetries is the number of resubmissions after blockhash expiry
+	// before a transaction is reported as dropped
+	MaxRetries int
+	// FeeMultiplier raises the priority fee on each resubmission
+	FeeMultiplier float64
+	// MaxPriorityFee caps the priority fee of resubmissions
+	MaxPriorityFee uint64
+}
+
+// DefaultTxTrackerConfig returns default transaction tracking configuration
+func DefaultTxTrackerConfig() TxTrackerConfig {
+	return TxTrackerConfig{
+		PollInterval:   2 * time.Second,
+		Commitment:     dex.CommitmentConfirmed,
+		MaxRetries:     3,
+		FeeMultiplier:  2,
+		MaxPriorityFee: 1_000_000,
+	}
+}
+
+// TrackedTx is a submitted swap transaction awaiting confirmation
+type TrackedTx struct {
+	Trade                *models.Trade
+	Signature            string
+	LastValidBlockHeight uint64
+	PriorityFee          uint64
+	Attempts             int
+	SubmittedAt          time.Time
+}
+
+// TxTracker follows submitted swap transactions until they are confirmed,
+// fail or are dropped, updating the trade status as they resolve. A
+// transaction whose blockhash expired without landing is resubmitted with a
+// higher priority fee; once the blockhash is expired the old transaction can
+// no longer land, so the swap cannot execute twice.
+type TxTracker struct {
+	source      SignatureStatusSource
+	resubmitter SwapResubmitter
+	store       TradeUpdater
+	config      TxTrackerConfig
+	monitor     monitoring.IMonitor
+	pending     map[string]*TrackedTx
+	resolved    map[string]string
+	replaced    map[string]string
+	waiters     map[string][]chan string
+	mu          sync.Mutex
+}
+
+// NewTxTracker creates a transaction tracker. The resubmitter may be nil, in
+// which case expired transactions are reported as dropped without retrying.
+func NewTxTracker(source SignatureStatusSource, resubmitter SwapResubmitter, store TradeUpdater, config TxTrackerConfig, monitor monitoring.IMonitor) *TxTracker {
+	defaults := DefaultTxTrackerConfig()
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaults.PollInterval
+	}
+	if config.Commitment == "" {
+		config.Commitment = defaults.Commitment
+	}
+	if config.MaxRetries < 0 {
+		config.MaxRetries = 0
+	}
+	if config.FeeMultiplier <= 1 {
+		config.FeeMultiplier = defaults.FeeMultiplier
+	}
+	if config.MaxPriorityFee == 0 {
+		config.MaxPriorityFee = defaults.MaxPriorityFee
+	}
+
+	return &TxTracker{
+		source:      source,
+		resubmitter: resubmitter,
+		store:       store,
+		config:      config,
+		monitor:     monitor,
+		pending:     make(map[string]*TrackedTx),
+		resolved:    make(map[string]string),
+		replaced:    make(map[string]string),
+		waiters:     make(map[string][]chan string),
+	}
+}
+
+// Track starts following a submitted transaction for a trade
+func (t *TxTracker) Track(trade *models.Trade, signature string, lastValidBlockHeight, priorityFee uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	trade.TxHash = signature
+	t.pending[signature] = &TrackedTx{
+		Trade:                trade,
+		Signature:            signature,
+		LastValidBlockHeight: lastValidBlockHeight,
+		PriorityFee:          priorityFee,
+		SubmittedAt:          time.Now(),
+	}
+}
+
+// Pending returns the transactions still awaiting confirmation
+func (t *TxTracker) Pending() []TrackedTx {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pending := make([]TrackedTx, 0, len(t.pending))
+	for _, tx := range t.pending {
+		pending = append(pending, *tx)
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].SubmittedAt.Before(pending[j].SubmittedAt)
+	})
+	return pending
+}
+
+// Status returns the transaction status of a signature as one of the
+// models.TxStatus values. Signatures replaced by a resubmission report the
+// status of the replacement.
+func (t *TxTracker) Status(signature string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	signature = t.current(signature)
+	if _, ok := t.pending[signature]; ok {
+		return models.TxStatusPending, nil
+	}
+	if status, ok := t.resolved[signature]; ok {
+		return status, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnknownTransaction, signature)
+}
+
+// Wait blocks until a tracked transaction resolves and returns its status
+func (t *TxTracker) Wait(ctx context.Context, signature string) (string, error) {
+	t.mu.Lock()
+	signature = t.current(signature)
+	if status, ok := t.resolved[signature]; ok {
+		t.mu.Unlock()
+		return status, nil
+	}
+	if _, ok := t.pending[signature]; !ok {
+		t.mu.Unlock()
+		return "", fmt.Errorf("%w: %s", ErrUnknownTransaction, signature)
+	}
+	ch := make(chan string, 1)
+	t.waiters[signature] = append(t.waiters[signature], ch)
+	t.mu.Unlock()
+
+	select {
+	case status := <-ch:
+		return status, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// current follows resubmissions to the latest signature. Callers hold t.mu.
+func (t *TxTracker) current(signature string) string {
+	for {
+		next, ok := t.replaced[signature]
+		if !ok {
+			return signature
+		}
+		signature = next
+	}
+}
+
+// Run polls pending transactions until the context is cancelled
+func (t *TxTracker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(t.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := t.Poll(ctx); err != nil && t.monitor != nil {
+			t.monitor.RecordEvent(ctx, monitoring.Event{
+				Type:     monitoring.MetricTrading,
+				Severity: monitoring.SeverityWarning,
+				Message:  "Transaction status poll failed",
+				Details:  err.Error(),
+			})
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll checks every pending transaction once. The block height is read
+// before the statuses, so a transaction reported unseen with an expired
+// blockhash can no longer land.
+func (t *TxTracker) Poll(ctx context.Context) error {
+	pending := t.Pending()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	height, err := t.source.GetBlockHeight(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get block height: %w", err)
+	}
+
+	var errs []error
+	for start := 0; start < len(pending); start += maxSignaturesPerRequest {
+		batch := pending[start:min(start+maxSignaturesPerRequest, len(pending))]
+		signatures := make([]string, len(batch))
+		for i, tx := range batch {
+			signatures[i] = tx.Signature
+		}
+
+		statuses, err := t.source.GetSignatureStatuses(ctx, signatures)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get signature statuses: %w", err))
+			continue
+		}
+		for i := range batch {
+			if err := t.check(ctx, &batch[i], statuses[i], height); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t *TxTracker) check(ctx context.Context, tx *TrackedTx, status *dex.SignatureStatus, height uint64) error {
+	switch {
+	case status != nil && status.Failed():
+		reason := fmt.Sprintf("transaction failed: %s", status.Err)
+		t.recordEvent(ctx, tx, monitoring.SeverityError, "Transaction failed", map[string]interface{}{"reason": reason})
+		return t.resolve(ctx, tx, models.TxStatusFailed, reason)
+	case status != nil && status.Reached(t.config.Commitment):
+		t.recordEvent(ctx, tx, monitoring.SeverityInfo, "Transaction confirmed", map[string]interface{}{
+			"latency": time.Since(tx.SubmittedAt).String(),
+		})
+		return t.resolve(ctx, tx, models.TxStatusConfirmed, "")
+	case status != nil || height <= tx.LastValidBlockHeight:
+		return nil
+	}
+
+	// The blockhash expired before the transaction was seen
+	if t.resubmitter == nil || tx.Attempts >= t.config.MaxRetries {
+		reason := fmt.Sprintf("transaction dropped: blockhash expired after %d attempts", tx.Attempts+1)
+		t.recordEvent(ctx, tx, monitoring.SeverityError, "Transaction dropped", map[string]interface{}{"reason": reason})
+		return t.resolve(ctx, tx, models.TxStatusFailed, reason)
+	}
+	return t.resubmit(ctx, tx)
+}
+
+func (t *TxTracker) resubmit(ctx context.Context, tx *TrackedTx) error {
+	fee := uint64(math.Ceil(float64(tx.PriorityFee) * t.config.FeeMultiplier))
+	if fee <= tx.PriorityFee {
+		fee = tx.PriorityFee + 1
+	}
+	if fee > t.config.MaxPriorityFee {
+		fee = t.config.MaxPriorityFee
+	}
+
+	signature, lastValid, err := t.resubmitter.ResubmitSwap(ctx, tx.Trade, fee)
+	if err != nil {
+		// The expired transaction stays pending, so the retry is repeated
+		// on the next poll
+		return fmt.Errorf("failed to resubmit %s: %w", tx.Signature, err)
+	}
+
+	t.mu.Lock()
+	delete(t.pending, tx.Signature)
+	t.replaced[tx.Signature] = signature
+	tx.Trade.TxHash = signature
+	tx.Trade.UpdateTime = time.Now()
+	t.pending[signature] = &TrackedTx{
+		Trade:                tx.Trade,
+		Signature:            signature,
+		LastValidBlockHeight: lastValid,
+		PriorityFee:          fee,
+		Attempts:             tx.Attempts + 1,
+		SubmittedAt:          tx.SubmittedAt,
+	}
+	t.waiters[signature] = append(t.waiters[signature], t.waiters[tx.Signature]...)
+	delete(t.waiters, tx.Signature)
+	t.mu.Unlock()
+
+	t.recordEvent(ctx, tx, monitoring.SeverityWarning, "Transaction expired, resubmitted", map[string]interface{}{
+		"resubmitted_as": signature,
+		"priority_fee":   fee,
+		"attempt":        tx.Attempts + 2,
+	})
+	return t.store.UpdateTrade(ctx, tx.Trade)
+}
+
+// resolve records the final status of a transaction and updates its trade
+func (t *TxTracker) resolve(ctx context.Context, tx *TrackedTx, status, reason string) error {
+	t.mu.Lock()
+	delete(t.pending, tx.Signature)
+	t.resolved[tx.Signature] = status
+	waiters := t.waiters[tx.Signature]
+	delete(t.waiters, tx.Signature)
+
+	trade := tx.Trade
+	if status == models.TxStatusConfirmed {
+		trade.Status = models.TradeStatusCompleted
+		trade.ErrorMessage = ""
+	} else {
+		trade.Status = models.TradeStatusFailed
+		trade.ErrorMessage = reason
+	}
+	trade.UpdateTime = time.Now()
+	t.mu.Unlock()
+
+	for _, ch := range waiters {
+		ch <- status
+	}
+
+	if err := t.store.UpdateTrade(ctx, trade); err != nil {
+		return fmt.Errorf("failed to update trade for %s: %w", tx.Signature, err)
+	}
+	return nil
+}
+
+func (t *TxTracker) recordEvent(ctx context.Context, tx *TrackedTx, severity monitoring.EventSeverity, message string, details map[string]interface{}) {
+	if t.monitor == nil {
+		return
+	}
+	details["signature"] = tx.Signature
+	details["tokenAddress"] = tx.Trade.TokenAddress
+	t.monitor.RecordEvent(ctx, monitoring.Event{
+		Type:      monitoring.MetricTrading,
+		Severity:  severity,
+		Message:   message,
+		Details:   details,
+		Timestamp: time.Now(),
+	})
+}
@@ -0,0 +1,180 @@
+package trading
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/leonzhao/trading-system/backend/dex"
+	"github.com/leonzhao/trading-system/backend/models"
+	"github.com/leonzhao/trading-system/backend/monitoring"
+)
+
+type fakeChain struct {
+	height   uint64
+	statuses map[string]*dex.SignatureStatus
+}
+
+func (c *fakeChain) GetSignatureStatuses(ctx context.Context, signatures []string) ([]*dex.SignatureStatus, error) {
+	statuses := make([]*dex.SignatureStatus, len(signatures))
+	for i, signature := range signatures {
+		statuses[i] = c.statuses[signature]
+	}
+	return statuses, nil
+}
+
+func (c *fakeChain) GetBlockHeight(ctx context.Context) (uint64, error) {
+	return c.height, nil
+}
+
+type fakeResubmitter struct {
+	fees []uint64
+	err  error
+}
+
+func (r *fakeResubmitter) ResubmitSwap(ctx context.Context, trade *models.Trade, priorityFee uint64) (string, uint64, error) {
+	if r.err != nil {
+		return "", 0, r.err
+	}
+	r.fees = append(r.fees, priorityFee)
+	return fmt.Sprintf("%s-retry%d", trade.ID, len(r.fees)), 200 + uint64(len(r.fees))*150, nil
+}
+
+type memoryTradeStore struct {
+	trades map[string]models.Trade
+}
+
+func (s *memoryTradeStore) UpdateTrade(ctx context.Context, trade *models.Trade) error {
+	s.trades[trade.ID] = *trade
+	return nil
+}
+
+type recordingMonitor struct {
+	monitoring.IMonitor
+	events []monitoring.Event
+}
+
+func (m *recordingMonitor) RecordEvent(ctx context.Context, event monitoring.Event) {
+	m.events = append(m.events, event)
+}
+
+func (m *recordingMonitor) messages() []string {
+	messages := make([]string, len(m.events))
+	for i, event := range m.events {
+		messages[i] = event.Message
+	}
+	return messages
+}
+
+func TestTxTracker(t *testing.T) {
+	ctx := context.Background()
+
+	newFixture := func(resubmitter SwapResubmitter) (*fakeChain, *memoryTradeStore, *recordingMonitor, *TxTracker) {
+		chain := &fakeChain{height: 100, statuses: make(map[string]*dex.SignatureStatus)}
+		store := &memoryTradeStore{trades: make(map[string]models.Trade)}
+		monitor := &recordingMonitor{}
+		tracker := NewTxTracker(chain, resubmitter, store, TxTrackerConfig{MaxRetries: 2, MaxPriorityFee: 25000}, monitor)
+		return chain, store, monitor, tracker
+	}
+
+	t.Run("Confirmed and failed transactions update their trades", func(t *testing.T) {
+		chain, store, monitor, tracker := newFixture(nil)
+		landed := &models.Trade{ID: "t1", TokenAddress: "BONK", Status: models.TradePending}
+		reverted := &models.Trade{ID: "t2", TokenAddress: "WIF", Status: models.TradePending}
+		tracker.Track(landed, "sig1", 250, 5000)
+		tracker.Track(reverted, "sig2", 250, 5000)
+
+		chain.statuses["sig1"] = &dex.SignatureStatus{ConfirmationStatus: dex.CommitmentProcessed}
+		require.NoError(t, tracker.Poll(ctx))
+		status, err := tracker.Status("sig1")
+		require.NoError(t, err)
+		assert.Equal(t, models.TxStatusPending, status)
+
+		chain.statuses["sig1"] = &dex.SignatureStatus{ConfirmationStatus: dex.CommitmentConfirmed, Err: json.RawMessage("null")}
+		chain.statuses["sig2"] = &dex.SignatureStatus{ConfirmationStatus: dex.CommitmentConfirmed, Err: json.RawMessage(`{"InstructionError":[2,{"Custom":6001}]}`)}
+		require.NoError(t, tracker.Poll(ctx))
+
+		assert.Equal(t, models.TradeStatus(models.TradeStatusCompleted), store.trades["t1"].Status)
+		assert.True(t, landed.IsComplete())
+		assert.Equal(t, models.TradeStatus(models.TradeStatusFailed), store.trades["t2"].Status)
+		assert.Contains(t, store.trades["t2"].ErrorMessage, "Custom")
+		assert.Empty(t, tracker.Pending())
+		assert.ElementsMatch(t, []string{"Transaction confirmed", "Transaction failed"}, monitor.messages())
+
+		status, err = tracker.Wait(ctx, "sig2")
+		require.NoError(t, err)
+		assert.Equal(t, models.TxStatusFailed, status)
+		_, err = tracker.Status("unknown")
+		assert.ErrorIs(t, err, ErrUnknownTransaction)
+	})
+
+	t.Run("Expired blockhash is retried with a higher priority fee", func(t *testing.T) {
+		resubmitter := &fakeResubmitter{}
+		chain, store, monitor, tracker := newFixture(resubmitter)
+		trade := &models.Trade{ID: "t3", TokenAddress: "BONK", Status: models.TradePending}
+		tracker.Track(trade, "sig3", 150, 10000)
+
+		waited := make(chan string, 1)
+		go func() {
+			status, _ := tracker.Wait(ctx, "sig3")
+			waited <- status
+		}()
+		time.Sleep(10 * time.Millisecond)
+
+		// Not seen, but the blockhash is still valid
+		require.NoError(t, tracker.Poll(ctx))
+		assert.Empty(t, resubmitter.fees)
+
+		chain.height = 151
+		require.NoError(t, tracker.Poll(ctx))
+		assert.Equal(t, []uint64{20000}, resubmitter.fees)
+		assert.Equal(t, "t3-retry1", trade.TxHash)
+		assert.Equal(t, "t3-retry1", store.trades["t3"].TxHash)
+
+		chain.statuses["t3-retry1"] = &dex.SignatureStatus{ConfirmationStatus: dex.CommitmentFinalized}
+		require.NoError(t, tracker.Poll(ctx))
+		assert.Equal(t, models.TxStatusConfirmed, <-waited)
+		status, err := tracker.Status("sig3")
+		require.NoError(t, err)
+		assert.Equal(t, models.TxStatusConfirmed, status)
+		assert.Equal(t, []string{"Transaction expired, resubmitted", "Transaction confirmed"}, monitor.messages())
+	})
+
+	t.Run("Transactions are dropped after the last retry", func(t *testing.T) {
+		resubmitter := &fakeResubmitter{}
+		chain, store, monitor, tracker := newFixture(resubmitter)
+		trade := &models.Trade{ID: "t4", TokenAddress: "WIF", Status: models.TradePending}
+		tracker.Track(trade, "sig4", 150, 10000)
+
+		for _, height := range []uint64{151, 351, 501} {
+			chain.height = height
+			require.NoError(t, tracker.Poll(ctx))
+		}
+		// The fee is capped at MaxPriorityFee
+		assert.Equal(t, []uint64{20000, 25000}, resubmitter.fees)
+		assert.Equal(t, models.TradeStatus(models.TradeStatusFailed), store.trades["t4"].Status)
+		assert.Contains(t, store.trades["t4"].ErrorMessage, "dropped")
+		assert.Equal(t, "Transaction dropped", monitor.events[len(monitor.events)-1].Message)
+		assert.Equal(t, monitoring.SeverityError, monitor.events[len(monitor.events)-1].Severity)
+	})
+
+	t.Run("Failed resubmission is retried on the next poll", func(t *testing.T) {
+		resubmitter := &fakeResubmitter{err: errors.New("rpc unavailable")}
+		chain, _, _, tracker := newFixture(resubmitter)
+		tracker.Track(&models.Trade{ID: "t5"}, "sig5", 150, 10000)
+
+		chain.height = 151
+		assert.ErrorContains(t, tracker.Poll(ctx), "rpc unavailable")
+		require.Len(t, tracker.Pending(), 1)
+
+		resubmitter.err = nil
+		require.NoError(t, tracker.Poll(ctx))
+		assert.Equal(t, "t5-retry1", tracker.Pending()[0].Signature)
+	})
+}
@@ -0,0 +1,313 @@
+// Package watchlist keeps the tokens the system trades, with per-token
+// overrides of the poll interval, the strategies allowed and the risk limits.
+// Ingestion, analysis, risk checks and signal execution all read it, and
+// tokens can be added and removed while running.
+package watchlist
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/leonzhao/trading-system/backend/monitoring"
+	"github.com/leonzhao/trading-system/backend/trading/risk"
+)
+
+var (
+	// ErrInvalidToken is returned when a token definition is invalid
+	ErrInvalidToken = errors.New("invalid watch list token")
+
+	// ErrUnknownToken is returned when a token is not on the watch list
+	ErrUnknownToken = errors.New("token not on the watch list")
+)
+
+// Token is a watched token and its overrides. Zero overrides fall back to
+// the global settings.
+type Token struct {
+	Address string `json:"address"`
+	Symbol  string `json:"symbol,omitempty"`
+	// PollInterval overrides the market data poll interval of the token
+	PollInterval time.Duration `json:"poll_interval,omitempty"`
+	// Strategies are the strategies allowed to trade the token. Empty
+	// allows every strategy.
+	Strategies []string `json:"strategies,omitempty"`
+	// MaxPositionSize overrides the maximum position size of the token
+	MaxPositionSize float64 `json:"max_position_size,omitempty"`
+	// MaxSlippage overrides the maximum estimated slippage of an order of
+	// the token, in percent
+	MaxSlippage float64 `json:"max_slippage,omitempty"`
+}
+
+func (t Token) validate() error {
+	if t.Address == "" {
+		return fmt.Errorf("%w: missing address", ErrInvalidToken)
+	}
+	if t.PollInterval < 0 {
+		return fmt.Errorf("%w: %s has a negative poll interval", ErrInvalidToken, t.Address)
+	}
+	if t.MaxPositionSize < 0 || t.MaxSlippage < 0 {
+		return fmt.Errorf("%w: %s has a negative risk limit", ErrInvalidToken, t.Address)
+	}
+	for _, strategy := range t.Strategies {
+		if strategy == "" {
+			return fmt.Errorf("%w: %s has an empty strategy name", ErrInvalidToken, t.Address)
+		}
+	}
+	return nil
+}
+
+// WatchList is the set of watched tokens, in the order they were added
+type WatchList struct {
+	// path is the file changes are saved to, if any
+	path        string
+	tokens      []Token
+	subscribers []func([]Token)
+	monitor     monitoring.IMonitor
+	mu          sync.RWMutex
+}
+
+// New creates a watch list of tokens kept in memory. The monitor may be nil.
+func New(tokens []Token, monitor monitoring.IMonitor) (*WatchList, error) {
+	w := &WatchList{monitor: monitor}
+	for _, token := range tokens {
+		if err := token.validate(); err != nil {
+			return nil, err
+		}
+		if w.index(token.Address) >= 0 {
+			return nil, fmt.Errorf("%w: duplicate token %s", ErrInvalidToken, token.Address)
+		}
+		w.tokens = append(w.tokens, token)
+	}
+	return w, nil
+}
+
+// Load creates a watch list from the JSON array of tokens in the file at
+// path, and saves every change back to it. A missing file is an empty watch
+// list.
+func Load(path string, monitor monitoring.IMonitor) (*WatchList, error) {
+	var tokens []Token
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read watch list: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &tokens); err != nil {
+			return nil, fmt.Errorf("failed to decode watch list: %w", err)
+		}
+	}
+
+	w, err := New(tokens, monitor)
+	if err != nil {
+		return nil, err
+	}
+	w.path = path
+	return w, nil
+}
+
+// Tokens returns the watched tokens
+func (w *WatchList) Tokens() []Token {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.snapshot()
+}
+
+// Get returns a watched token
+func (w *WatchList) Get(address string) (Token, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	i := w.index(address)
+	if i < 0 {
+		return Token{}, fmt.Errorf("%w: %s", ErrUnknownToken, address)
+	}
+	return copyToken(w.tokens[i]), nil
+}
+
+// Put adds a token, or replaces the overrides of a watched one
+func (w *WatchList) Put(ctx context.Context, token Token) error {
+	if err := token.validate(); err != nil {
+		return err
+	}
+	token = copyToken(token)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	next := w.snapshot()
+	message := "Watch list token added"
+	if i := w.index(token.Address); i >= 0 {
+		next[i] = token
+		message = "Watch list token updated"
+	} else {
+		next = append(next, token)
+	}
+	if err := w.commit(next); err != nil {
+		return err
+	}
+	w.recordEvent(ctx, message, token.Address)
+	return nil
+}
+
+// Remove stops watching a token
+func (w *WatchList) Remove(ctx context.Context, address string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	i := w.index(address)
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownToken, address)
+	}
+	next := w.snapshot()
+	next = append(next[:i], next[i+1:]...)
+	if err := w.commit(next); err != nil {
+		return err
+	}
+	w.recordEvent(ctx, "Watch list token removed", address)
+	return nil
+}
+
+// Subscribe calls fn with the watched tokens now and after every change.
+// Changes are delivered in order; fn must not change the watch list.
+func (w *WatchList) Subscribe(fn func([]Token)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subscribers = append(w.subscribers, fn)
+	fn(w.snapshot())
+}
+
+// TokenLimits returns the risk limit overrides of a token. It implements
+// risk.TokenLimitSource.
+func (w *WatchList) TokenLimits(tokenAddress string) (risk.TokenLimits, bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	i := w.index(tokenAddress)
+	if i < 0 {
+		return risk.TokenLimits{}, false
+	}
+	return risk.TokenLimits{
+		MaxPositionSize: w.tokens[i].MaxPositionSize,
+		MaxSlippage:     w.tokens[i].MaxSlippage,
+	}, true
+}
+
+// AllowsStrategy reports whether a strategy may trade a token. Tokens off
+// the watch list and tokens without a strategy set allow every strategy.
+func (w *WatchList) AllowsStrategy(tokenAddress, strategy string) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	i := w.index(tokenAddress)
+	if i < 0 || len(w.tokens[i].Strategies) == 0 {
+		return true
+	}
+	for _, allowed := range w.tokens[i].Strategies {
+		if allowed == strategy {
+			return true
+		}
+	}
+	return false
+}
+
+// Addresses returns the addresses of tokens
+func Addresses(tokens []Token) []string {
+	addresses := make([]string, len(tokens))
+	for i, token := range tokens {
+		addresses[i] = token.Address
+	}
+	return addresses
+}
+
+// PollIntervals returns the poll interval overrides of tokens
+func PollIntervals(tokens []Token) map[string]time.Duration {
+	intervals := make(map[string]time.Duration)
+	for _, token := range tokens {
+		if token.PollInterval > 0 {
+			intervals[token.Address] = token.PollInterval
+		}
+	}
+	return intervals
+}
+
+// commit saves next, then makes it the watched tokens and notifies the
+// subscribers. The caller holds the write lock.
+func (w *WatchList) commit(next []Token) error {
+	if w.path != "" {
+		if err := w.save(next); err != nil {
+			return err
+		}
+	}
+	w.tokens = next
+	for _, fn := range w.subscribers {
+		fn(w.snapshot())
+	}
+	return nil
+}
+
+func (w *WatchList) save(tokens []Token) error {
+	if tokens == nil {
+		tokens = []Token{}
+	}
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode watch list: %w", err)
+	}
+	// Write then rename, so an interruption never leaves a truncated file
+	tmp, err := os.CreateTemp(filepath.Dir(w.path), filepath.Base(w.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write watch list: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write watch list: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write watch list: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), w.path); err != nil {
+		return fmt.Errorf("failed to write watch list: %w", err)
+	}
+	return nil
+}
+
+// snapshot copies the watched tokens. The caller holds the lock.
+func (w *WatchList) snapshot() []Token {
+	tokens := make([]Token, len(w.tokens))
+	for i, token := range w.tokens {
+		tokens[i] = copyToken(token)
+	}
+	return tokens
+}
+
+// index returns the position of a token, or -1. The caller holds the lock.
+func (w *WatchList) index(address string) int {
+	for i, token := range w.tokens {
+		if token.Address == address {
+			return i
+		}
+	}
+	return -1
+}
+
+func (w *WatchList) recordEvent(ctx context.Context, message, address string) {
+	if w.monitor == nil {
+		return
+	}
+	w.monitor.RecordEvent(ctx, monitoring.Event{
+		Type:     monitoring.MetricTrading,
+		Severity: monitoring.SeverityInfo,
+		Message:  message,
+		Details: map[string]interface{}{
+			"tokenAddress": address,
+		},
+		Timestamp: time.Now(),
+	})
+}
+
+func copyToken(token Token) Token {
+	token.Strategies = append([]string(nil), token.Strategies...)
+	return token
+}
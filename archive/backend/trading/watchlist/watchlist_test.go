@@ -0,0 +1,91 @@
+package watchlist
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/leonzhao/trading-system/backend/trading/risk"
+)
+
+func TestWatchList(t *testing.T) {
+	ctx := context.Background()
+	w, err := New([]Token{
+		{Address: "bonk", Symbol: "BONK", PollInterval: 2 * time.Second, Strategies: []string{"momentum"}, MaxPositionSize: 50},
+		{Address: "wif"},
+	}, nil)
+	require.NoError(t, err)
+
+	var updates [][]string
+	w.Subscribe(func(tokens []Token) {
+		updates = append(updates, Addresses(tokens))
+	})
+	assert.Equal(t, [][]string{{"bonk", "wif"}}, updates, "subscribers start with the current tokens")
+
+	t.Run("Overrides", func(t *testing.T) {
+		assert.True(t, w.AllowsStrategy("bonk", "momentum"))
+		assert.False(t, w.AllowsStrategy("bonk", "grid"))
+		assert.True(t, w.AllowsStrategy("wif", "grid"), "no strategy set allows every strategy")
+		assert.True(t, w.AllowsStrategy("popcat", "grid"), "tokens off the list are not restricted")
+
+		limits, ok := w.TokenLimits("bonk")
+		require.True(t, ok)
+		assert.Equal(t, risk.TokenLimits{MaxPositionSize: 50}, limits)
+		_, ok = w.TokenLimits("popcat")
+		assert.False(t, ok)
+
+		assert.Equal(t, map[string]time.Duration{"bonk": 2 * time.Second}, PollIntervals(w.Tokens()))
+	})
+
+	t.Run("Changes", func(t *testing.T) {
+		require.NoError(t, w.Put(ctx, Token{Address: "popcat", MaxSlippage: 1.5}))
+		require.NoError(t, w.Put(ctx, Token{Address: "bonk", Strategies: []string{"grid"}}))
+		assert.True(t, w.AllowsStrategy("bonk", "grid"))
+		require.NoError(t, w.Remove(ctx, "wif"))
+		assert.ErrorIs(t, w.Remove(ctx, "wif"), ErrUnknownToken)
+		_, err := w.Get("wif")
+		assert.ErrorIs(t, err, ErrUnknownToken)
+
+		assert.Equal(t, [][]string{
+			{"bonk", "wif"},
+			{"bonk", "wif", "popcat"},
+			{"bonk", "wif", "popcat"},
+			{"bonk", "popcat"},
+		}, updates)
+	})
+
+	invalid := []Token{
+		{},
+		{Address: "bonk", PollInterval: -time.Second},
+		{Address: "bonk", MaxSlippage: -1},
+		{Address: "bonk", Strategies: []string{""}},
+	}
+	for _, token := range invalid {
+		assert.ErrorIs(t, w.Put(ctx, token), ErrInvalidToken)
+	}
+	_, err = New([]Token{{Address: "bonk"}, {Address: "bonk"}}, nil)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestLoad(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "watchlist.json")
+
+	w, err := Load(path, nil)
+	require.NoError(t, err)
+	assert.Empty(t, w.Tokens(), "a missing file is an empty watch list")
+
+	require.NoError(t, w.Put(ctx, Token{Address: "bonk", Strategies: []string{"momentum"}, MaxSlippage: 2}))
+	reloaded, err := Load(path, nil)
+	require.NoError(t, err)
+	assert.Equal(t, w.Tokens(), reloaded.Tokens())
+
+	require.NoError(t, os.WriteFile(path, []byte(`[{"address": ""}]`), 0o644))
+	_, err = Load(path, nil)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
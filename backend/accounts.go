@@ -0,0 +1,335 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "log/slog"
+    "time"
+
+    "github.com/go-redis/redis/v8"
+
+    "github.com/devinjacknz/godydxhyber/backend/config"
+    "github.com/devinjacknz/godydxhyber/backend/eventbus"
+    "github.com/devinjacknz/godydxhyber/backend/exchange"
+    "github.com/devinjacknz/godydxhyber/backend/exchange/dydx"
+    "github.com/devinjacknz/godydxhyber/backend/pkg/money"
+    "github.com/devinjacknz/godydxhyber/backend/pkg/websocket"
+    "github.com/devinjacknz/godydxhyber/backend/trading/account"
+    "github.com/devinjacknz/godydxhyber/backend/trading/fees"
+    "github.com/devinjacknz/godydxhyber/backend/trading/killswitch"
+    "github.com/devinjacknz/godydxhyber/backend/trading/order"
+    "github.com/devinjacknz/godydxhyber/backend/trading/position"
+    "github.com/devinjacknz/godydxhyber/backend/trading/risk"
+)
+
+// newAccount creates a trading account with its own exchange client, order
+// and position managers, risk manager and kill switch. Every change is
+// published to the push channel and the event bus, order changes and risk
+// checks are traced, and fills and closed positions count toward the daily
+// limits of the account's own risk manager. Orders on the symbols disabled by
+// gate, and orders failing the pre-trade risk checks, are rejected. Open orders,
+// positions and the daily risk state are saved with stores, when set, under
+// keys of the account.
+func newAccount(cfg config.AccountConfig, limits config.RiskConfig, hub *websocket.Hub, bus *eventbus.Bus, gate order.SymbolGate, stores *redis.Client, riskOpts ...risk.Option) (*account.Account, error) {
+    var client dydx.Client
+    if cfg.Exchange.Enabled {
+        var err error
+        // Read-only on v4, as no order broadcaster is configured
+        if client, err = exchange.NewDydxClient(cfg.Exchange, nil); err != nil {
+            return nil, fmt.Errorf("failed to create exchange client of account %s: %w", cfg.ID, err)
+        }
+    }
+
+    var riskStore risk.Store = risk.NewMemoryStore()
+    if stores != nil {
+        riskStore = risk.NewRedisStore(stores, risk.DefaultRedisKey+":"+cfg.ID)
+    }
+    riskOpts = append([]risk.Option{
+        risk.WithAccountID(cfg.ID),
+        risk.WithStore(riskStore),
+        risk.WithAlertListener(func(check *risk.RiskCheck) {
+            hub.PublishAccount(websocket.TopicRiskAlerts, cfg.ID, check)
+            if check.Status == risk.Violation {
+                publishEvent(bus, eventbus.RiskViolation, check.CorrelationID, riskViolationEvent(check))
+            }
+        }),
+    }, riskOpts...)
+    if client != nil {
+        riskOpts = append(riskOpts, risk.WithAccountSource(client))
+    }
+    riskManager := risk.Traced(risk.NewRiskManager(riskOpts...))
+    if err := applyRiskLimits(riskManager, limits); err != nil {
+        return nil, fmt.Errorf("failed to apply risk limits of account %s: %w", cfg.ID, err)
+    }
+
+    // Fills are charged to the position they trade, which the order
+    // listener finds once the position manager is created
+    feeModel := newFeeModel(cfg.Exchange)
+    var positionManager *position.Manager
+    orderOpts := []order.ManagerOption{
+        order.WithAccountID(cfg.ID),
+        order.WithSymbolGate(gate),
+        order.WithPreTradeCheck(func(ctx context.Context, params order.CreateOrderParams) error {
+            return validateOrder(ctx, riskManager, positionManager, client, params)
+        }),
+        order.WithListener(func(o *order.Order, fill money.Decimal) {
+            hub.PublishAccount(websocket.TopicOrders, cfg.ID, o)
+            if o.Status == order.Expired {
+                publishEvent(bus, eventbus.OrderExpired, o.CorrelationID, orderExpiredEvent(o))
+            }
+            if fill.Sign() > 0 {
+                hub.PublishAccount(websocket.TopicTrades, cfg.ID, order.NewTrade(o, fill))
+                fee := chargeFill(context.Background(), feeModel, positionManager, riskManager, o, fill.Float64())
+                publishEvent(bus, eventbus.OrderFilled, o.CorrelationID, orderFilledEvent(o, fill.Float64(), fee))
+                // Only the first fill of an order counts as a trade
+                if o.FilledSize.Equal(fill) {
+                    if err := riskManager.RecordTrade(context.Background(), risk.TradeRecord{Symbol: o.Symbol, Time: o.UpdatedAt}); err != nil {
+                        slog.Error("failed to record trade", "account_id", o.AccountID, "order_id", o.ID, "error", err)
+                    }
+                }
+            }
+        }),
+    }
+    positionOpts := []position.ManagerOption{
+        position.WithAccountID(cfg.ID),
+        position.WithMode(positionMode(cfg.Exchange)),
+        position.WithListener(func(p *position.Position) {
+            hub.PublishAccount(websocket.TopicPositions, cfg.ID, p)
+            if p.Status == position.Closed || p.Status == position.Liquidated {
+                publishEvent(bus, eventbus.PositionClosed, "", positionClosedEvent(p))
+                if err := riskManager.RecordRealizedPnL(context.Background(), p.RealizedPnL.Float64()); err != nil {
+                    slog.Error("failed to record realized PnL", "account_id", p.AccountID, "position_id", p.ID, "error", err)
+                }
+            }
+        }),
+    }
+    if client != nil {
+        orderOpts = append(orderOpts, order.WithExchange(client), order.WithPositions(client))
+        // v4 orders are placed by a chain broadcaster, which is not
+        // configured
+        if cfg.Exchange.Version != config.DydxV4 {
+            orderOpts = append(orderOpts,
+                order.WithVenue("dydx", client),
+                order.WithExecutionBudget(time.Duration(cfg.Exchange.OrderAckTimeout)),
+            )
+        }
+        positionOpts = append(positionOpts, position.WithExchange(client))
+    }
+    if stores != nil {
+        orderOpts = append(orderOpts, order.WithStore(order.NewRedisStore(stores, order.DefaultRedisKey+":"+cfg.ID)))
+        positionOpts = append(positionOpts, position.WithStore(position.NewRedisStore(stores, position.DefaultRedisKey+":"+cfg.ID)))
+    }
+    orderManager := order.Traced(order.NewOrderManager(orderOpts...))
+    positionManager = position.NewManager(positionOpts...)
+
+    return &account.Account{
+        ID:         cfg.ID,
+        Name:       cfg.Name,
+        Strategies: cfg.Strategies,
+        Exchange:   client,
+        Orders:     orderManager,
+        Positions:  positionManager,
+        Risk:       riskManager,
+        KillSwitch: killswitch.NewKillSwitch(orderManager, positionManager, killswitch.Config{}),
+    }, nil
+}
+
+// validateOrder runs the pre-trade risk checks on an order against the open
+// positions of the account: netting trades are measured against the net
+// position on the symbol, and otherwise against the gross one. Orders
+// without a price are valued at the mark price of a position on the symbol,
+// or at the top of the book on the side they take.
+func validateOrder(ctx context.Context, riskManager risk.RiskManager, positions *position.Manager, client dydx.Client, params order.CreateOrderParams) error {
+    open := position.Open
+    list, err := positions.ListPositions(ctx, position.PositionFilter{Status: &open})
+    if err != nil {
+        return fmt.Errorf("failed to list open positions: %w", err)
+    }
+    unrealized := money.Zero
+    var mark money.Decimal
+    for _, p := range list {
+        snapshot := p.Snapshot()
+        unrealized = unrealized.Add(snapshot.UnrealizedPnL)
+        if snapshot.Symbol == params.Symbol {
+            mark = snapshot.CurrentPrice
+        }
+    }
+
+    var price float64
+    switch {
+    case params.Price != nil:
+        price = params.Price.Float64()
+    case params.StopPrice != nil:
+        price = params.StopPrice.Float64()
+    case mark.Sign() > 0:
+        price = mark.Float64()
+    case client != nil:
+        if price, err = topOfBook(ctx, client, params); err != nil {
+            return err
+        }
+    default:
+        return fmt.Errorf("no price to value the %s order on %s", params.Type, params.Symbol)
+    }
+
+    side := risk.Long
+    if params.Side == order.Sell {
+        side = risk.Short
+    }
+    _, err = riskManager.ValidateTrade(ctx, risk.TradeParams{
+        Symbol:        params.Symbol,
+        Side:          side,
+        Size:          params.Size.Float64(),
+        Price:         price,
+        Netting:       positions.Mode() == position.NettingMode,
+        Position:      positions.Exposure(ctx, params.Symbol).RiskPosition().Float64(),
+        OpenPositions: len(list),
+        UnrealizedPnL: unrealized.Float64(),
+    })
+    return err
+}
+
+// topOfBook returns the best price an order on the book would take
+func topOfBook(ctx context.Context, client dydx.Client, params order.CreateOrderParams) (float64, error) {
+    book, err := client.GetOrderbook(ctx, params.Symbol)
+    if err != nil {
+        return 0, fmt.Errorf("failed to get order book of %s: %w", params.Symbol, err)
+    }
+    levels := book.Asks
+    if params.Side == order.Sell {
+        levels = book.Bids
+    }
+    if len(levels) == 0 {
+        return 0, fmt.Errorf("no %s liquidity on %s", params.Side, params.Symbol)
+    }
+    return levels[0].Price, nil
+}
+
+// newFeeModel charges dYdX fills by the fee tier of an exchange section
+func newFeeModel(exchange config.ExchangeConfig) fees.FeeModel {
+    schedule := fees.DefaultSchedules()[fees.VenueDydx]
+    if exchange.MakerFeeBps != 0 {
+        schedule.MakerBps = exchange.MakerFeeBps
+    }
+    if exchange.TakerFeeBps != 0 {
+        schedule.TakerBps = exchange.TakerFeeBps
+    }
+    return fees.NewVenueModel(map[string]fees.Schedule{fees.VenueDydx: schedule})
+}
+
+// chargeFill charges the fee of a fill to the open position of its symbol
+// and to the daily commissions, and returns it. Post-only orders fill as
+// makers and other orders are charged as takers, which never understates
+// the cost. Market orders have no price, so their fills are valued at the
+// mark price of the position.
+func chargeFill(ctx context.Context, model fees.FeeModel, positions *position.Manager, riskManager risk.RiskManager, o *order.Order, fill float64) float64 {
+    open := position.Open
+    list, err := positions.ListPositions(ctx, position.PositionFilter{Symbol: o.Symbol, Status: &open})
+    if err != nil {
+        slog.Error("failed to find position of fill", "account_id", o.AccountID, "order_id", o.ID, "error", err)
+    }
+    var p *position.Position
+    if len(list) > 0 {
+        p = list[0].Snapshot()
+    }
+
+    var price float64
+    switch {
+    case o.Price != nil:
+        price = o.Price.Float64()
+    case p != nil:
+        price = p.CurrentPrice.Float64()
+    }
+    liquidity := fees.Taker
+    if o.PostOnly {
+        liquidity = fees.Maker
+    }
+    fee := model.Fee(fees.Fill{Venue: fees.VenueDydx, Symbol: o.Symbol, Price: price, Size: fill, Liquidity: liquidity})
+    if fee == 0 {
+        return 0
+    }
+
+    if p != nil {
+        if err := positions.RecordFee(ctx, p.ID, money.FromFloat(fee)); err != nil {
+            slog.Error("failed to record fee", "account_id", o.AccountID, "position_id", p.ID, "error", err)
+        }
+    }
+    if err := riskManager.RecordCommission(ctx, fee); err != nil {
+        slog.Error("failed to record commission", "account_id", o.AccountID, "order_id", o.ID, "error", err)
+    }
+    return fee
+}
+
+// applyRiskLimits sets the limits of a risk section on a risk manager
+func applyRiskLimits(m risk.RiskManager, limits config.RiskConfig) error {
+    thresholds := limits.VolatilityThresholds
+    tradeCooldown := time.Duration(limits.TradeCooldown)
+    _, err := m.UpdateLimits(context.Background(), risk.LimitsUpdate{
+        PositionLimits:   limits.PositionLimits,
+        ExposureLimit:    &limits.ExposureLimit,
+        DrawdownLimit:    &limits.DrawdownLimit,
+        DailyLossLimit:   &limits.DailyLossLimit,
+        MaxDailyTrades:   &limits.MaxDailyTrades,
+        TradeCooldown:    &tradeCooldown,
+        MaxOpenPositions: &limits.MaxOpenPositions,
+        MaxLeverage:      &limits.MaxLeverage,
+        RiskPerTrade:     &limits.RiskPerTrade,
+        StopLoss:         &limits.StopLoss,
+        TakeProfit:       &limits.TakeProfit,
+        VolatilityThresholds: &risk.VolatilityThresholds{
+            LowThreshold:      thresholds[0],
+            MediumThreshold:   thresholds[1],
+            HighThreshold:     thresholds[2],
+            CriticalThreshold: thresholds[3],
+        },
+    })
+    return err
+}
+
+// positionMode returns the position mode of an exchange account
+func positionMode(exchange config.ExchangeConfig) position.Mode {
+    if exchange.PositionMode == config.PositionModeNetting {
+        return position.NettingMode
+    }
+    return position.HedgeMode
+}
+
+// newAccounts creates the default account, trading with the exchanges and
+// risk sections and the strategies no other account trades, and the
+// configured accounts
+func newAccounts(cfg *config.Config, hub *websocket.Hub, bus *eventbus.Bus, gate order.SymbolGate, stores *redis.Client, defaultRiskOpts ...risk.Option) (*account.Registry, error) {
+    traded := make(map[string]bool)
+    for _, ac := range cfg.Accounts {
+        for _, name := range ac.Strategies {
+            traded[name] = true
+        }
+    }
+    defaultConfig := config.AccountConfig{ID: account.Default, Exchange: cfg.Exchanges.Dydx}
+    for _, sc := range cfg.Strategies {
+        if !traded[sc.Name] {
+            defaultConfig.Strategies = append(defaultConfig.Strategies, sc.Name)
+        }
+    }
+
+    registry := account.NewRegistry()
+    defaultAccount, err := newAccount(defaultConfig, cfg.Risk, hub, bus, gate, stores, defaultRiskOpts...)
+    if err != nil {
+        return nil, err
+    }
+    if err := registry.Register(defaultAccount); err != nil {
+        return nil, err
+    }
+    for _, ac := range cfg.Accounts {
+        limits := cfg.Risk
+        if ac.Risk != nil {
+            limits = *ac.Risk
+        }
+        a, err := newAccount(ac, limits, hub, bus, gate, stores)
+        if err != nil {
+            return nil, err
+        }
+        if err := registry.Register(a); err != nil {
+            return nil, err
+        }
+    }
+    return registry, nil
+}
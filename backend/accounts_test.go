@@ -0,0 +1,53 @@
+package main
+
+import (
+    "context"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+
+    "github.com/devinjacknz/godydxhyber/backend/config"
+    "github.com/devinjacknz/godydxhyber/backend/pkg/money"
+    "github.com/devinjacknz/godydxhyber/backend/trading/order"
+    "github.com/devinjacknz/godydxhyber/backend/trading/position"
+    "github.com/devinjacknz/godydxhyber/backend/trading/risk"
+)
+
+func TestValidateOrder(t *testing.T) {
+    ctx := context.Background()
+    riskManager := risk.NewRiskManager()
+    _, err := riskManager.UpdateLimits(ctx, risk.LimitsUpdate{PositionLimits: map[string]float64{"BTC-USD": 150000}})
+    require.NoError(t, err)
+
+    price := money.FromFloat(50000)
+    sell := order.CreateOrderParams{Symbol: "BTC-USD", Type: order.Limit, Side: order.Sell, Price: &price, Size: money.NewFromInt(2)}
+    open := func(mode position.Mode) *position.Manager {
+        positions := position.NewManager(position.WithMode(mode))
+        _, err := positions.OpenPosition(ctx, position.OpenPositionParams{
+            Symbol: "BTC-USD", Side: position.Long, Size: money.NewFromInt(2), EntryPrice: price, Leverage: 1,
+        })
+        require.NoError(t, err)
+        return positions
+    }
+
+    t.Run("Opposite orders reduce the position when netting", func(t *testing.T) {
+        assert.NoError(t, validateOrder(ctx, riskManager, open(position.NettingMode), nil, sell))
+    })
+
+    t.Run("Opposite orders add to the gross position in hedge mode", func(t *testing.T) {
+        assert.ErrorIs(t, validateOrder(ctx, riskManager, open(position.HedgeMode), nil, sell), risk.ErrPositionLimitExceeded)
+    })
+
+    t.Run("Market orders are valued at the mark price of the position", func(t *testing.T) {
+        buy := order.CreateOrderParams{Symbol: "BTC-USD", Type: order.Market, Side: order.Buy, Size: money.NewFromInt(2)}
+        assert.ErrorIs(t, validateOrder(ctx, riskManager, open(position.NettingMode), nil, buy), risk.ErrPositionLimitExceeded)
+        assert.Error(t, validateOrder(ctx, riskManager, position.NewManager(), nil, buy), "no price without a position or exchange")
+    })
+}
+
+func TestPositionMode(t *testing.T) {
+    assert.Equal(t, position.HedgeMode, positionMode(config.ExchangeConfig{}))
+    assert.Equal(t, position.HedgeMode, positionMode(config.ExchangeConfig{PositionMode: config.PositionModeHedge}))
+    assert.Equal(t, position.NettingMode, positionMode(config.ExchangeConfig{PositionMode: config.PositionModeNetting}))
+}
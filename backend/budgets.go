@@ -0,0 +1,29 @@
+package main
+
+import (
+    "github.com/devinjacknz/godydxhyber/backend/config"
+    "github.com/devinjacknz/godydxhyber/backend/trading/budget"
+    "github.com/devinjacknz/godydxhyber/backend/trading/strategy"
+)
+
+// newBudgets creates the loss budgets of the configured strategies and
+// tokens. Strategies over budget are disabled in the registry; tokens over
+// budget are rejected by the order managers.
+func newBudgets(cfg *config.Config, strategies *strategy.Registry) *budget.Manager {
+    budgetConfig := budget.DefaultConfig()
+    budgetConfig.Strategies = make(map[string]budget.Limits)
+    for _, sc := range cfg.Strategies {
+        if sc.Budget != (config.LossBudgetConfig{}) {
+            budgetConfig.Strategies[sc.Name] = budgetLimits(sc.Budget)
+        }
+    }
+    budgetConfig.Tokens = make(map[string]budget.Limits, len(cfg.Budgets.Tokens))
+    for token, limits := range cfg.Budgets.Tokens {
+        budgetConfig.Tokens[token] = budgetLimits(limits)
+    }
+    return budget.New(strategies, budgetConfig)
+}
+
+func budgetLimits(limits config.LossBudgetConfig) budget.Limits {
+    return budget.Limits{MaxDailyLoss: limits.MaxDailyLoss, MaxWeeklyLoss: limits.MaxWeeklyLoss}
+}
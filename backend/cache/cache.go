@@ -0,0 +1,338 @@
+// Package cache is an in-memory cache bounded by entry count and memory,
+// evicting the least recently used entries, with de-duplicated loading of
+// missing entries.
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// entryOverhead approximates the memory taken by the bookkeeping of an
+// entry: its list element, map slot and expiration
+const entryOverhead = 96
+
+// Config contains cache configuration
+type Config struct {
+	// Name labels the metrics of the cache and should be unique per cache
+	Name string
+	// MaxEntries bounds the number of entries. Zero means no bound.
+	MaxEntries int
+	// MaxMemory bounds the estimated memory of the entries in bytes. Zero
+	// means no bound.
+	MaxMemory int64
+	// TTL is the expiration of entries stored by Set and GetOrLoad. Zero
+	// means entries do not expire.
+	TTL time.Duration
+}
+
+// Sizer estimates the memory held by an entry in bytes
+type Sizer[K comparable, V any] func(key K, value V) int64
+
+// Stats contains cache statistics
+type Stats struct {
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Evictions int64 `json:"evictions"`
+	Entries   int   `json:"entries"`
+	Memory    int64 `json:"memory"`
+}
+
+// HitRatio returns the fraction of reads answered by the cache
+func (s Stats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// Cache is a thread-safe LRU cache
+type Cache[K comparable, V any] struct {
+	config  Config
+	sizer   Sizer[K, V]
+	entries map[K]*list.Element
+	lru     *list.List
+	memory  int64
+	calls   map[K]*call[V]
+	stats   Stats
+	metrics metrics
+	mu      sync.Mutex
+}
+
+type entry[K comparable, V any] struct {
+	key     K
+	value   V
+	size    int64
+	expires time.Time
+}
+
+// call is a load in flight, shared by the callers of GetOrLoad missing the
+// same key
+type call[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// metrics are the cache metrics labeled with the cache name
+type metrics struct {
+	hits, misses, evictions prometheus.Counter
+	loaded, failed, shared  prometheus.Counter
+	entries, memory         prometheus.Gauge
+}
+
+// New creates a cache. sizer estimates the memory of entries for
+// Config.MaxMemory; when nil, entries are sized by DefaultSize.
+func New[K comparable, V any](config Config, sizer Sizer[K, V]) *Cache[K, V] {
+	if config.Name == "" {
+		config.Name = "default"
+	}
+	if sizer == nil {
+		sizer = DefaultSize[K, V]
+	}
+
+	return &Cache[K, V]{
+		config:  config,
+		sizer:   sizer,
+		entries: make(map[K]*list.Element),
+		lru:     list.New(),
+		calls:   make(map[K]*call[V]),
+		metrics: metrics{
+			hits:      cacheHits.WithLabelValues(config.Name),
+			misses:    cacheMisses.WithLabelValues(config.Name),
+			evictions: cacheEvictions.WithLabelValues(config.Name),
+			loaded:    cacheLoads.WithLabelValues(config.Name, "success"),
+			failed:    cacheLoads.WithLabelValues(config.Name, "error"),
+			shared:    cacheLoads.WithLabelValues(config.Name, "shared"),
+			entries:   cacheEntries.WithLabelValues(config.Name),
+			memory:    cacheMemory.WithLabelValues(config.Name),
+		},
+	}
+}
+
+// DefaultSize estimates the memory of an entry from the sizes of its key and
+// value types plus the contents of strings and byte slices. Memory referenced
+// through pointers, maps and other slices is not counted.
+func DefaultSize[K comparable, V any](key K, value V) int64 {
+	return entryOverhead +
+		int64(unsafe.Sizeof(key)) + contentSize(key) +
+		int64(unsafe.Sizeof(value)) + contentSize(value)
+}
+
+func contentSize(v interface{}) int64 {
+	switch v := v.(type) {
+	case string:
+		return int64(len(v))
+	case []byte:
+		return int64(len(v))
+	}
+	return 0
+}
+
+// Get returns the value of a key, marking it as recently used
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.get(key, time.Now())
+}
+
+// Set stores a value with the configured TTL
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, c.config.TTL)
+}
+
+// SetWithTTL stores a value that expires after ttl, or never when ttl is
+// zero. A value larger than Config.MaxMemory is not stored. A load of the key
+// in flight is not stored when it completes.
+func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.calls, key)
+	c.set(key, value, ttl)
+}
+
+// Delete removes a key. A load of the key in flight is not stored when it
+// completes.
+func (c *Cache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.calls, key)
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+		c.updateGauges()
+	}
+}
+
+// GetOrLoad returns the value of a key, calling load to fetch and store it
+// when missing. Concurrent callers missing the same key share one call of
+// load and its result. Errors are returned to every sharing caller and not
+// cached.
+func (c *Cache[K, V]) GetOrLoad(key K, load func() (V, error)) (V, error) {
+	c.mu.Lock()
+	if value, ok := c.get(key, time.Now()); ok {
+		c.mu.Unlock()
+		return value, nil
+	}
+	if pending, ok := c.calls[key]; ok {
+		c.mu.Unlock()
+		<-pending.done
+		c.metrics.shared.Inc()
+		return pending.value, pending.err
+	}
+	pending := &call[V]{done: make(chan struct{})}
+	c.calls[key] = pending
+	c.mu.Unlock()
+
+	c.load(key, pending, load)
+	return pending.value, pending.err
+}
+
+// load runs a loader for the callers sharing it. If the loader panics, the
+// sharing callers get ErrLoaderPanicked and the panic continues in the
+// caller that ran it.
+func (c *Cache[K, V]) load(key K, pending *call[V], load func() (V, error)) {
+	returned := false
+	defer func() {
+		if !returned {
+			pending.err = ErrLoaderPanicked
+		}
+		if pending.err != nil {
+			c.metrics.failed.Inc()
+		} else {
+			c.metrics.loaded.Inc()
+		}
+
+		c.mu.Lock()
+		// The call was forgotten if the key was set or deleted meanwhile
+		if c.calls[key] == pending {
+			delete(c.calls, key)
+			if pending.err == nil {
+				c.set(key, pending.value, c.config.TTL)
+			}
+		}
+		c.mu.Unlock()
+		close(pending.done)
+	}()
+
+	pending.value, pending.err = load()
+	returned = true
+}
+
+// RemoveExpired removes the expired entries and returns how many there were
+func (c *Cache[K, V]) RemoveExpired() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	removed := 0
+	for el := c.lru.Back(); el != nil; {
+		prev := el.Prev()
+		if c.expired(el.Value.(*entry[K, V]), now) {
+			c.remove(el)
+			removed++
+		}
+		el = prev
+	}
+	if removed > 0 {
+		c.updateGauges()
+	}
+	return removed
+}
+
+// Clear removes every entry
+func (c *Cache[K, V]) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[K]*list.Element)
+	c.lru.Init()
+	c.calls = make(map[K]*call[V])
+	c.memory = 0
+	c.updateGauges()
+}
+
+// Len returns the number of entries, including expired ones not yet removed
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Stats returns cache statistics
+func (c *Cache[K, V]) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Entries = len(c.entries)
+	stats.Memory = c.memory
+	return stats
+}
+
+func (c *Cache[K, V]) get(key K, now time.Time) (V, bool) {
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*entry[K, V])
+		if !c.expired(e, now) {
+			c.lru.MoveToFront(el)
+			c.stats.Hits++
+			c.metrics.hits.Inc()
+			return e.value, true
+		}
+		c.remove(el)
+		c.updateGauges()
+	}
+
+	c.stats.Misses++
+	c.metrics.misses.Inc()
+	var zero V
+	return zero, false
+}
+
+func (c *Cache[K, V]) set(key K, value V, ttl time.Duration) {
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+
+	e := &entry[K, V]{key: key, value: value, size: c.sizer(key, value)}
+	if ttl > 0 {
+		e.expires = time.Now().Add(ttl)
+	}
+	if c.config.MaxMemory > 0 && e.size > c.config.MaxMemory {
+		c.updateGauges()
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(e)
+	c.memory += e.size
+	for c.overBounds() {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+		c.metrics.evictions.Inc()
+	}
+	c.updateGauges()
+}
+
+func (c *Cache[K, V]) overBounds() bool {
+	return (c.config.MaxEntries > 0 && len(c.entries) > c.config.MaxEntries) ||
+		(c.config.MaxMemory > 0 && c.memory > c.config.MaxMemory)
+}
+
+func (c *Cache[K, V]) expired(e *entry[K, V], now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+func (c *Cache[K, V]) remove(el *list.Element) {
+	e := c.lru.Remove(el).(*entry[K, V])
+	delete(c.entries, e.key)
+	c.memory -= e.size
+}
+
+func (c *Cache[K, V]) updateGauges() {
+	c.metrics.entries.Set(float64(len(c.entries)))
+	c.metrics.memory.Set(float64(c.memory))
+}
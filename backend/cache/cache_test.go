@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheLRUEviction(t *testing.T) {
+	c := New[string, int](Config{Name: t.Name(), MaxEntries: 2}, nil)
+	c.Set("a", 1)
+	c.Set("b", 2)
+
+	_, ok := c.Get("a")
+	require.True(t, ok)
+	c.Set("c", 3)
+
+	_, ok = c.Get("b")
+	assert.False(t, ok, "least recently used entry evicted")
+	v, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+
+	stats := c.Stats()
+	assert.Equal(t, 2, stats.Entries)
+	assert.Equal(t, int64(1), stats.Evictions)
+	assert.Equal(t, int64(2), stats.Hits)
+	assert.Equal(t, int64(1), stats.Misses)
+}
+
+func TestCacheMaxMemory(t *testing.T) {
+	size := func(key string, value []byte) int64 { return int64(len(value)) }
+	c := New[string, []byte](Config{Name: t.Name(), MaxMemory: 10}, size)
+
+	c.Set("a", make([]byte, 4))
+	c.Set("b", make([]byte, 4))
+	c.Set("c", make([]byte, 4))
+	assert.Equal(t, 2, c.Len())
+	assert.Equal(t, int64(8), c.Stats().Memory)
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+
+	c.Set("b", make([]byte, 2))
+	assert.Equal(t, int64(6), c.Stats().Memory, "replacing an entry releases its memory")
+
+	c.Set("huge", make([]byte, 11))
+	_, ok = c.Get("huge")
+	assert.False(t, ok, "entries larger than the bound are not stored")
+	assert.Equal(t, 2, c.Len())
+}
+
+func TestCacheTTL(t *testing.T) {
+	c := New[string, int](Config{Name: t.Name(), TTL: time.Hour}, nil)
+	c.SetWithTTL("short", 1, time.Millisecond)
+	c.Set("long", 2)
+	time.Sleep(5 * time.Millisecond)
+
+	_, ok := c.Get("short")
+	assert.False(t, ok)
+	_, ok = c.Get("long")
+	assert.True(t, ok)
+
+	c.SetWithTTL("short", 1, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	assert.Equal(t, 1, c.RemoveExpired())
+	assert.Equal(t, 1, c.Len())
+}
+
+func TestCacheGetOrLoadSharesLoads(t *testing.T) {
+	c := New[string, int](Config{Name: t.Name()}, nil)
+
+	var loads atomic.Int32
+	release := make(chan struct{})
+	load := func() (int, error) {
+		loads.Add(1)
+		<-release
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]int, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v, err := c.GetOrLoad("key", load)
+			assert.NoError(t, err)
+			results[i] = v
+		}(i)
+	}
+	require.Eventually(t, func() bool { return loads.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), loads.Load())
+	for _, v := range results {
+		assert.Equal(t, 42, v)
+	}
+
+	v, err := c.GetOrLoad("key", func() (int, error) {
+		t.Fatal("loaded a cached key")
+		return 0, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 42, v)
+}
+
+func TestCacheGetOrLoadErrorNotCached(t *testing.T) {
+	c := New[string, int](Config{Name: t.Name()}, nil)
+	errLoad := errors.New("load failed")
+
+	_, err := c.GetOrLoad("key", func() (int, error) { return 0, errLoad })
+	assert.ErrorIs(t, err, errLoad)
+	assert.Equal(t, 0, c.Len())
+
+	v, err := c.GetOrLoad("key", func() (int, error) { return 7, nil })
+	require.NoError(t, err)
+	assert.Equal(t, 7, v)
+}
+
+func TestCacheDeleteDuringLoad(t *testing.T) {
+	c := New[string, int](Config{Name: t.Name()}, nil)
+
+	v, err := c.GetOrLoad("key", func() (int, error) {
+		c.Delete("key")
+		return 1, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, v)
+	_, ok := c.Get("key")
+	assert.False(t, ok, "a load invalidated while in flight is not stored")
+}
+
+func TestCacheGetOrLoadPanic(t *testing.T) {
+	c := New[string, int](Config{Name: t.Name()}, nil)
+
+	assert.Panics(t, func() {
+		c.GetOrLoad("key", func() (int, error) { panic("boom") })
+	})
+
+	v, err := c.GetOrLoad("key", func() (int, error) { return 3, nil })
+	require.NoError(t, err)
+	assert.Equal(t, 3, v, "a panicking load does not block later loads")
+}
@@ -0,0 +1,7 @@
+package cache
+
+import "errors"
+
+// ErrLoaderPanicked is returned to the callers sharing a load whose loader
+// panicked
+var ErrLoaderPanicked = errors.New("cache loader panicked")
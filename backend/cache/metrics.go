@@ -0,0 +1,52 @@
+package cache
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	cacheHits = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_hits_total",
+			Help: "Total number of cache hits",
+		},
+		[]string{"cache"},
+	)
+	cacheMisses = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_misses_total",
+			Help: "Total number of cache misses",
+		},
+		[]string{"cache"},
+	)
+	cacheEvictions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_evictions_total",
+			Help: "Total number of entries evicted to respect the cache bounds",
+		},
+		[]string{"cache"},
+	)
+	cacheLoads = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_loads_total",
+			Help: "Total number of GetOrLoad misses by result: success, error or shared",
+		},
+		[]string{"cache", "result"},
+	)
+	cacheEntries = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cache_entries",
+			Help: "Number of entries in the cache",
+		},
+		[]string{"cache"},
+	)
+	cacheMemory = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cache_memory_bytes",
+			Help: "Estimated memory held by the cache entries",
+		},
+		[]string{"cache"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(cacheHits, cacheMisses, cacheEvictions, cacheLoads, cacheEntries, cacheMemory)
+}
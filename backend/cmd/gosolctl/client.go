@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// client calls the REST API of a server
+type client struct {
+	server     string
+	token      string
+	account    string
+	httpClient *http.Client
+}
+
+func newClient(server, token, account string) *client {
+	return &client{
+		server:     strings.TrimSuffix(server, "/"),
+		token:      token,
+		account:    account,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// apiError is an error answered by the server
+type apiError struct {
+	Status  int
+	Message string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("server answered %d: %s", e.Status, e.Message)
+}
+
+// do calls an endpoint of the API, relative to the account when one is
+// selected, and decodes the answer into out
+func (c *client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	endpoint := c.server + "/api/v1"
+	if c.account != "" {
+		endpoint += "/accounts/" + url.PathEscape(c.account)
+	}
+	endpoint += path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var answer struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &answer) != nil || answer.Error == "" {
+			answer.Error = strings.TrimSpace(string(data))
+		}
+		return &apiError{Status: resp.StatusCode, Message: answer.Error}
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// pushMessage is a message of the push channel
+type pushMessage struct {
+	Type      string          `json:"type"`
+	Topic     string          `json:"topic"`
+	Seq       uint64          `json:"seq"`
+	Data      json.RawMessage `json:"data"`
+	Error     string          `json:"error"`
+	Timestamp time.Time       `json:"timestamp"`
+}
+
+// subscribe connects to the push channel and sends the messages of the
+// topics to handle until the context ends or the connection fails
+func (c *client) subscribe(ctx context.Context, topics []string, handle func(pushMessage) error) error {
+	endpoint := "ws" + strings.TrimPrefix(c.server, "http") + "/ws"
+	header := http.Header{}
+	if c.token != "" {
+		header.Set("Authorization", "Bearer "+c.token)
+	}
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, endpoint, header)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", endpoint, err)
+	}
+	defer conn.Close()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	for _, topic := range topics {
+		if err := conn.WriteJSON(map[string]string{"type": "subscribe", "topic": topic}); err != nil {
+			return err
+		}
+	}
+	for {
+		var msg pushMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if err := handle(msg); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,402 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/trading/killswitch"
+	"github.com/devinjacknz/godydxhyber/backend/trading/order"
+	"github.com/devinjacknz/godydxhyber/backend/trading/position"
+	"github.com/devinjacknz/godydxhyber/backend/trading/risk"
+)
+
+// cli runs the commands against a server
+type cli struct {
+	client *client
+	out    *printer
+	stderr io.Writer
+}
+
+func (c *cli) run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	command, args := args[0], args[1:]
+	sub := ""
+	if len(args) > 0 {
+		sub = args[0]
+	}
+
+	switch {
+	case command == "orders" && sub == "list":
+		return c.listOrders(ctx, args[1:])
+	case command == "orders" && sub == "cancel":
+		return c.cancelOrder(ctx, args[1:])
+	case command == "trades":
+		return c.listTrades(ctx, args)
+	case command == "positions" && sub == "list":
+		return c.listPositions(ctx, args[1:])
+	case command == "positions" && sub == "close":
+		return c.closePosition(ctx, args[1:])
+	case command == "risk" && sub == "limits":
+		return c.riskLimits(ctx)
+	case command == "risk" && sub == "set":
+		return c.setRiskLimits(ctx, args[1:])
+	case command == "killswitch" && (sub == "status" || sub == ""):
+		return c.killSwitch(ctx, http.MethodGet, "/killswitch", nil)
+	case command == "killswitch" && sub == "trigger":
+		return c.triggerKillSwitch(ctx, args[1:])
+	case command == "killswitch" && sub == "reset":
+		return c.resetKillSwitch(ctx, args[1:])
+	case command == "events":
+		return c.tailEvents(ctx, args)
+	default:
+		return fmt.Errorf("%w: %s", errUsage, strings.TrimSpace(command+" "+sub))
+	}
+}
+
+// newFlags returns the flag set of a command
+func (c *cli) newFlags(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	return flags
+}
+
+// parseID parses the flags of a command acting on one record, given by ID
+// before or after the flags
+func parseID(flags *flag.FlagSet, args []string) (string, error) {
+	var id string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		id, args = args[0], args[1:]
+	}
+	if err := flags.Parse(args); err != nil {
+		return "", err
+	}
+	if id == "" && flags.NArg() > 0 {
+		id = flags.Arg(0)
+	}
+	if id == "" {
+		return "", fmt.Errorf("%w: %s needs an ID", errUsage, flags.Name())
+	}
+	return id, nil
+}
+
+// queryFlags defines string flags sent as query parameters of the same name
+func queryFlags(flags *flag.FlagSet, names ...string) func() url.Values {
+	values := make(map[string]*string, len(names))
+	for _, name := range names {
+		values[name] = flags.String(name, "", name+" filter")
+	}
+	return func() url.Values {
+		query := url.Values{}
+		for name, v := range values {
+			if *v != "" {
+				query.Set(name, *v)
+			}
+		}
+		return query
+	}
+}
+
+func (c *cli) listOrders(ctx context.Context, args []string) error {
+	flags := c.newFlags("orders list")
+	query := queryFlags(flags, "symbol", "side", "status", "type")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var resp struct {
+		Orders []*order.Order `json:"orders"`
+	}
+	if err := c.client.do(ctx, http.MethodGet, "/orders", query(), nil, &resp); err != nil {
+		return err
+	}
+	sort.Slice(resp.Orders, func(i, j int) bool { return resp.Orders[i].CreatedAt.After(resp.Orders[j].CreatedAt) })
+	return c.printOrders(resp.Orders, resp.Orders)
+}
+
+func (c *cli) cancelOrder(ctx context.Context, args []string) error {
+	id, err := parseID(c.newFlags("orders cancel"), args)
+	if err != nil {
+		return err
+	}
+	var o order.Order
+	if err := c.client.do(ctx, http.MethodDelete, "/orders/"+url.PathEscape(id), nil, nil, &o); err != nil {
+		return err
+	}
+	return c.printOrders(&o, []*order.Order{&o})
+}
+
+func (c *cli) printOrders(v interface{}, orders []*order.Order) error {
+	rows := make([][]string, 0, len(orders))
+	for _, o := range orders {
+		rows = append(rows, []string{
+			o.ID, o.Symbol, o.Type.String(), o.Side.String(), formatPrice(o.Price),
+			o.Size.String(), o.FilledSize.String(), o.Status.String(), formatTime(o.CreatedAt),
+		})
+	}
+	return c.out.print(v, []string{"ID", "SYMBOL", "TYPE", "SIDE", "PRICE", "SIZE", "FILLED", "STATUS", "CREATED"}, rows)
+}
+
+func (c *cli) listTrades(ctx context.Context, args []string) error {
+	flags := c.newFlags("trades")
+	query := queryFlags(flags, "symbol", "side", "start", "end")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var resp struct {
+		Trades []order.Trade `json:"trades"`
+	}
+	if err := c.client.do(ctx, http.MethodGet, "/trades", query(), nil, &resp); err != nil {
+		return err
+	}
+	rows := make([][]string, 0, len(resp.Trades))
+	for _, t := range resp.Trades {
+		rows = append(rows, []string{
+			formatTime(t.Time), t.OrderID, t.Symbol, t.Side.String(),
+			formatPrice(t.Price), t.Size.String(), t.Status.String(),
+		})
+	}
+	return c.out.print(resp.Trades, []string{"TIME", "ORDER", "SYMBOL", "SIDE", "PRICE", "SIZE", "STATUS"}, rows)
+}
+
+func (c *cli) listPositions(ctx context.Context, args []string) error {
+	flags := c.newFlags("positions list")
+	query := queryFlags(flags, "symbol", "side", "status")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var resp struct {
+		Positions []*position.Position `json:"positions"`
+	}
+	if err := c.client.do(ctx, http.MethodGet, "/positions", query(), nil, &resp); err != nil {
+		return err
+	}
+	sort.Slice(resp.Positions, func(i, j int) bool { return resp.Positions[i].OpenTime.After(resp.Positions[j].OpenTime) })
+	return c.printPositions(resp.Positions, resp.Positions)
+}
+
+func (c *cli) closePosition(ctx context.Context, args []string) error {
+	flags := c.newFlags("positions close")
+	price := flags.Float64("price", 0, "close price, the last known price when zero")
+	id, err := parseID(flags, args)
+	if err != nil {
+		return err
+	}
+
+	body := map[string]interface{}{}
+	if *price > 0 {
+		body["price"] = *price
+	}
+	var p position.Position
+	if err := c.client.do(ctx, http.MethodPost, "/positions/"+url.PathEscape(id)+"/close", nil, body, &p); err != nil {
+		return err
+	}
+	return c.printPositions(&p, []*position.Position{&p})
+}
+
+func (c *cli) printPositions(v interface{}, positions []*position.Position) error {
+	rows := make([][]string, 0, len(positions))
+	for _, p := range positions {
+		rows = append(rows, []string{
+			p.ID, p.Symbol, p.Side.String(), p.Status.String(), p.Size.String(),
+			p.EntryPrice.String(), p.CurrentPrice.String(),
+			p.UnrealizedPnL.String(), p.RealizedPnL.String(), formatTime(p.OpenTime),
+		})
+	}
+	return c.out.print(v, []string{"ID", "SYMBOL", "SIDE", "STATUS", "SIZE", "ENTRY", "CURRENT", "UNREALIZED", "REALIZED", "OPENED"}, rows)
+}
+
+func (c *cli) riskLimits(ctx context.Context) error {
+	var limits risk.Limits
+	if err := c.client.do(ctx, http.MethodGet, "/risk/limits", nil, nil, &limits); err != nil {
+		return err
+	}
+	return c.printLimits(&limits)
+}
+
+// positionLimits collects -position SYMBOL=LIMIT flags
+type positionLimits map[string]float64
+
+func (p positionLimits) String() string {
+	return fmt.Sprint(map[string]float64(p))
+}
+
+func (p positionLimits) Set(v string) error {
+	symbol, limit, ok := strings.Cut(v, "=")
+	if !ok {
+		return fmt.Errorf("position limit must be SYMBOL=LIMIT")
+	}
+	n, err := strconv.ParseFloat(limit, 64)
+	if err != nil {
+		return fmt.Errorf("invalid position limit of %s: %w", symbol, err)
+	}
+	p[symbol] = n
+	return nil
+}
+
+func (c *cli) setRiskLimits(ctx context.Context, args []string) error {
+	flags := c.newFlags("risk set")
+	exposure := flags.Float64("exposure", 0, "exposure limit")
+	drawdown := flags.Float64("drawdown", 0, "drawdown limit, from 0 to 1")
+	dailyLoss := flags.Float64("daily-loss", 0, "daily loss limit, zero to disable")
+	maxDailyTrades := flags.Int("max-daily-trades", 0, "trades per day, zero for no limit")
+	cooldown := flags.Duration("cooldown", 0, "minimum time between two trades of a symbol")
+	maxOpenPositions := flags.Int("max-open-positions", 0, "open positions, zero for no limit")
+	maxLeverage := flags.Float64("max-leverage", 0, "leverage, zero for no limit")
+	riskPerTrade := flags.Float64("risk-per-trade", 0, "fraction of the equity risked by a trade")
+	stopLoss := flags.Float64("stop-loss", 0, "default stop loss distance, from 0 to 1")
+	takeProfit := flags.Float64("take-profit", 0, "default take profit distance, from 0 to 1")
+	positions := positionLimits{}
+	flags.Var(positions, "position", "position limit of a symbol as SYMBOL=LIMIT, repeatable")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	// Only the limits given on the command line change
+	var update risk.LimitsUpdate
+	set := 0
+	flags.Visit(func(f *flag.Flag) {
+		set++
+		switch f.Name {
+		case "exposure":
+			update.ExposureLimit = exposure
+		case "drawdown":
+			update.DrawdownLimit = drawdown
+		case "daily-loss":
+			update.DailyLossLimit = dailyLoss
+		case "max-daily-trades":
+			update.MaxDailyTrades = maxDailyTrades
+		case "cooldown":
+			update.TradeCooldown = cooldown
+		case "max-open-positions":
+			update.MaxOpenPositions = maxOpenPositions
+		case "max-leverage":
+			update.MaxLeverage = maxLeverage
+		case "risk-per-trade":
+			update.RiskPerTrade = riskPerTrade
+		case "stop-loss":
+			update.StopLoss = stopLoss
+		case "take-profit":
+			update.TakeProfit = takeProfit
+		case "position":
+			update.PositionLimits = positions
+		}
+	})
+	if set == 0 {
+		return fmt.Errorf("%w: risk set needs a limit", errUsage)
+	}
+
+	var limits risk.Limits
+	if err := c.client.do(ctx, http.MethodPut, "/risk/limits", nil, update, &limits); err != nil {
+		return err
+	}
+	return c.printLimits(&limits)
+}
+
+func (c *cli) printLimits(limits *risk.Limits) error {
+	pairs := [][2]string{
+		{"exposure_limit", formatFloat(limits.ExposureLimit)},
+		{"drawdown_limit", formatFloat(limits.DrawdownLimit)},
+		{"daily_loss_limit", formatFloat(limits.DailyLossLimit)},
+		{"max_daily_trades", strconv.Itoa(limits.MaxDailyTrades)},
+		{"trade_cooldown", limits.TradeCooldown.String()},
+		{"max_open_positions", strconv.Itoa(limits.MaxOpenPositions)},
+		{"max_leverage", formatFloat(limits.MaxLeverage)},
+		{"risk_per_trade", formatFloat(limits.RiskPerTrade)},
+		{"stop_loss", formatFloat(limits.StopLoss)},
+		{"take_profit", formatFloat(limits.TakeProfit)},
+	}
+	symbols := make([]string, 0, len(limits.PositionLimits))
+	for symbol := range limits.PositionLimits {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	for _, symbol := range symbols {
+		pairs = append(pairs, [2]string{"position_limit " + symbol, formatFloat(limits.PositionLimits[symbol])})
+	}
+	return c.out.fields(limits, pairs)
+}
+
+func (c *cli) triggerKillSwitch(ctx context.Context, args []string) error {
+	flags := c.newFlags("killswitch trigger")
+	reason := flags.String("reason", "", "reason of the halt, required")
+	flatten := flags.Bool("flatten", true, "close the open positions")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	return c.killSwitch(ctx, http.MethodPost, "/killswitch/trigger", map[string]interface{}{
+		"reason":  *reason,
+		"flatten": *flatten,
+	})
+}
+
+func (c *cli) resetKillSwitch(ctx context.Context, args []string) error {
+	flags := c.newFlags("killswitch reset")
+	reason := flags.String("reason", "", "reason of the reset, required")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	return c.killSwitch(ctx, http.MethodPost, "/killswitch/reset", map[string]string{"reason": *reason})
+}
+
+func (c *cli) killSwitch(ctx context.Context, method, path string, body interface{}) error {
+	var state killswitch.State
+	if err := c.client.do(ctx, method, path, nil, body, &state); err != nil {
+		return err
+	}
+	triggered := "-"
+	if state.TriggeredAt != nil {
+		triggered = formatTime(*state.TriggeredAt)
+	}
+	return c.out.fields(state, [][2]string{
+		{"active", strconv.FormatBool(state.Active)},
+		{"source", string(state.Source)},
+		{"reason", state.Reason},
+		{"triggered_at", triggered},
+		{"cancelled_orders", strconv.Itoa(state.CancelledOrders)},
+		{"closed_positions", strconv.Itoa(state.ClosedPositions)},
+	})
+}
+
+// tailEvents prints the messages of the push topics as they arrive, one
+// JSON object per line in JSON output
+func (c *cli) tailEvents(ctx context.Context, args []string) error {
+	flags := c.newFlags("events")
+	topics := flags.String("topics", "orders,trades,positions,risk_alerts", "comma separated topics, market:SYMBOL for market data")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	return c.client.subscribe(ctx, strings.Split(*topics, ","), func(msg pushMessage) error {
+		if c.out.json {
+			data, err := json.Marshal(msg)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(c.out.w, "%s\n", data)
+			return err
+		}
+		detail := string(msg.Data)
+		if msg.Error != "" {
+			detail = msg.Error
+		}
+		timestamp := msg.Timestamp
+		if timestamp.IsZero() {
+			timestamp = time.Now()
+		}
+		_, err := fmt.Fprintf(c.out.w, "%s  %-12s %-12s %s\n", formatTime(timestamp), msg.Topic, msg.Type, detail)
+		return err
+	})
+}
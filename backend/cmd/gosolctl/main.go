@@ -0,0 +1,93 @@
+// Command gosolctl manages a running trading engine through its REST API:
+// it lists and cancels orders, closes positions, sets risk limits, triggers
+// and clears the kill switch and tails the push topics.
+//
+//	gosolctl [-server URL] [-account ID] [-output table|json] <command> [args]
+//
+// The API token is read from GOSOL_API_TOKEN and the server from
+// GOSOL_SERVER, unless given as flags.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+const usage = `usage: gosolctl [flags] <command> [args]
+
+commands:
+  orders list [-symbol S] [-side buy|sell] [-status S] [-type T]
+  orders cancel <id>
+  trades [-symbol S] [-start T] [-end T]
+  positions list [-symbol S] [-side long|short] [-status open|closed|liquidated]
+  positions close <id> [-price P]
+  risk limits
+  risk set [-exposure N] [-drawdown N] [-daily-loss N] [-max-daily-trades N]
+           [-cooldown D] [-max-open-positions N] [-max-leverage N]
+           [-risk-per-trade N] [-stop-loss N] [-take-profit N] [-position SYMBOL=N]
+  killswitch status
+  killswitch trigger -reason R [-flatten=false]
+  killswitch reset -reason R
+  events [-topics orders,trades,positions,risk_alerts]
+
+flags:
+`
+
+// errUsage is returned for a missing or unknown command
+var errUsage = errors.New("invalid command")
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "gosolctl:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+// run parses the global flags and runs the command
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("gosolctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	server := flags.String("server", envOr("GOSOL_SERVER", "http://localhost:8080"), "URL of the server")
+	token := flags.String("token", os.Getenv("GOSOL_API_TOKEN"), "API token")
+	account := flags.String("account", "", "account to act on, the default account when empty")
+	output := flags.String("output", "table", "output format, table or json")
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *output != "table" && *output != "json" {
+		return fmt.Errorf("output must be table or json")
+	}
+
+	cli := &cli{
+		client: newClient(*server, *token, *account),
+		out:    newPrinter(stdout, *output == "json"),
+		stderr: stderr,
+	}
+	err := cli.run(ctx, flags.Args())
+	if errors.Is(err, errUsage) {
+		flags.Usage()
+	}
+	return err
+}
+
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+	"github.com/devinjacknz/godydxhyber/backend/pkg/websocket"
+	"github.com/devinjacknz/godydxhyber/backend/trading/killswitch"
+	"github.com/devinjacknz/godydxhyber/backend/trading/order"
+	"github.com/devinjacknz/godydxhyber/backend/trading/position"
+	"github.com/devinjacknz/godydxhyber/backend/trading/risk"
+)
+
+type testServer struct {
+	*httptest.Server
+	orders    order.OrderManager
+	positions *position.Manager
+	hub       *websocket.Hub
+}
+
+func newTestServer(t *testing.T) *testServer {
+	gin.SetMode(gin.TestMode)
+	orders := order.NewOrderManager()
+	positions := position.NewManager()
+	hub := websocket.NewHub(websocket.DefaultHubConfig())
+	t.Cleanup(hub.Close)
+
+	r := gin.New()
+	r.GET("/ws", hub.HandleWebSocket)
+	api := r.Group("/api/v1")
+	order.RegisterRoutes(api, orders)
+	positions.RegisterRoutes(api)
+	risk.RegisterRoutes(api, risk.NewRiskManager())
+	killswitch.NewKillSwitch(orders, positions, killswitch.Config{}).RegisterRoutes(api)
+
+	s := &testServer{Server: httptest.NewServer(r), orders: orders, positions: positions, hub: hub}
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *testServer) run(t *testing.T, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	err := run(context.Background(), append([]string{"-server", s.URL}, args...), &stdout, &stderr)
+	return stdout.String(), err
+}
+
+func TestOrders(t *testing.T) {
+	s := newTestServer(t)
+	ctx := context.Background()
+	price := money.FromFloat(50000.0)
+	created, err := s.orders.CreateOrder(ctx, order.CreateOrderParams{Symbol: "BTC-USD", Type: order.Limit, Side: order.Buy, Price: &price, Size: money.NewFromInt(1)})
+	require.NoError(t, err)
+
+	out, err := s.run(t, "orders", "list", "-symbol", "BTC-USD")
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, []string{"ID", "SYMBOL", "TYPE", "SIDE", "PRICE", "SIZE", "FILLED", "STATUS", "CREATED"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{created.ID, "BTC-USD", "limit", "buy", "50000", "1", "0"}, strings.Fields(lines[1])[:7])
+
+	out, err = s.run(t, "-output", "json", "orders", "cancel", created.ID)
+	require.NoError(t, err)
+	var cancelled order.Order
+	require.NoError(t, json.Unmarshal([]byte(out), &cancelled))
+	assert.Equal(t, order.Cancelled, cancelled.Status)
+
+	_, err = s.run(t, "orders", "cancel", "missing")
+	var apiErr *apiError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, 404, apiErr.Status)
+
+	_, err = s.run(t, "orders", "cancel")
+	assert.ErrorIs(t, err, errUsage)
+	_, err = s.run(t, "fills")
+	assert.ErrorIs(t, err, errUsage)
+	_, err = s.run(t, "-output", "yaml", "orders", "list")
+	assert.Error(t, err)
+}
+
+func TestPositions(t *testing.T) {
+	s := newTestServer(t)
+	p, err := s.positions.OpenPosition(context.Background(), position.OpenPositionParams{Symbol: "ETH-USD", Side: position.Long, Size: money.NewFromInt(2), EntryPrice: money.NewFromInt(3000), Leverage: 1})
+	require.NoError(t, err)
+
+	out, err := s.run(t, "positions", "close", p.ID, "-price", "3100")
+	require.NoError(t, err)
+	fields := strings.Fields(strings.Split(strings.TrimSpace(out), "\n")[1])
+	assert.Equal(t, []string{p.ID, "ETH-USD", "long", "closed", "2", "3000", "3100"}, fields[:7])
+
+	out, err = s.run(t, "positions", "list", "-status", "open")
+	require.NoError(t, err)
+	assert.Len(t, strings.Split(strings.TrimSpace(out), "\n"), 1)
+}
+
+func TestRiskAndKillSwitch(t *testing.T) {
+	s := newTestServer(t)
+
+	out, err := s.run(t, "-output", "json", "risk", "set", "-daily-loss", "500", "-cooldown", "1m", "-position", "BTC-USD=2")
+	require.NoError(t, err)
+	var limits risk.Limits
+	require.NoError(t, json.Unmarshal([]byte(out), &limits))
+	assert.Equal(t, 500.0, limits.DailyLossLimit)
+	assert.Equal(t, time.Minute, limits.TradeCooldown)
+	assert.Equal(t, 2.0, limits.PositionLimits["BTC-USD"])
+
+	out, err = s.run(t, "risk", "limits")
+	require.NoError(t, err)
+	assert.Contains(t, out, "position_limit BTC-USD  2")
+	_, err = s.run(t, "risk", "set")
+	assert.ErrorIs(t, err, errUsage)
+
+	out, err = s.run(t, "killswitch", "trigger", "-reason", "maintenance")
+	require.NoError(t, err)
+	assert.Contains(t, out, "active            true")
+	_, err = s.run(t, "killswitch", "trigger", "-reason", "again")
+	var apiErr *apiError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, 409, apiErr.Status)
+
+	out, err = s.run(t, "killswitch", "reset", "-reason", "done")
+	require.NoError(t, err)
+	assert.Contains(t, out, "active            false")
+}
+
+func TestEvents(t *testing.T) {
+	s := newTestServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	done := make(chan error, 1)
+	go func() {
+		done <- run(ctx, []string{"-server", s.URL, "-output", "json", "events", "-topics", "trades"}, &stdout, &stderr)
+	}()
+
+	// Publish until the subscription is in place, then stop tailing
+	require.Eventually(t, func() bool {
+		s.hub.Publish(websocket.TopicTrades, map[string]string{"order_id": "o1"})
+		return s.hub.ClientCount() == 1
+	}, time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	s.hub.Publish(websocket.TopicTrades, map[string]string{"order_id": "o2"})
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	var first, last pushMessage
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	require.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &last))
+	assert.Equal(t, "subscribed", first.Type)
+	assert.Equal(t, "update", last.Type)
+	assert.JSONEq(t, `{"order_id":"o2"}`, string(last.Data))
+}
@@ -0,0 +1,639 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// ErrInvalidConfig is returned when a loaded configuration fails validation
+var ErrInvalidConfig = errors.New("invalid configuration")
+
+// Config is the configuration of the trading engine
+type Config struct {
+	Server     ServerConfig     `yaml:"server" toml:"server"`
+	Exchanges  ExchangesConfig  `yaml:"exchanges" toml:"exchanges"`
+	LLM        LLMConfig        `yaml:"llm" toml:"llm"`
+	Risk       RiskConfig       `yaml:"risk" toml:"risk"`
+	Repository RepositoryConfig `yaml:"repository" toml:"repository"`
+	Bridge     BridgeConfig     `yaml:"bridge" toml:"bridge" env:"GOSOL_BRIDGE"`
+	Log        LogConfig        `yaml:"log" toml:"log" env:"GOSOL_LOG"`
+	Tracing    TracingConfig    `yaml:"tracing" toml:"tracing" env:"GOSOL_TRACING"`
+	Telegram   TelegramConfig   `yaml:"telegram" toml:"telegram" env:"GOSOL_TELEGRAM"`
+	Watchdog   WatchdogConfig   `yaml:"watchdog" toml:"watchdog" env:"GOSOL_WATCHDOG"`
+	Budgets    BudgetsConfig    `yaml:"budgets" toml:"budgets"`
+	Strategies []StrategyConfig `yaml:"strategies" toml:"strategies"`
+	// Accounts are the trading accounts beside the default account, which
+	// trades with the exchanges section and the risk section
+	Accounts []AccountConfig `yaml:"accounts" toml:"accounts"`
+}
+
+// ServerConfig contains HTTP server configuration
+type ServerConfig struct {
+	Addr string `yaml:"addr" toml:"addr" env:"GOSOL_SERVER_ADDR"`
+	// ShutdownTimeout bounds the graceful shutdown
+	ShutdownTimeout Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"GOSOL_SHUTDOWN_TIMEOUT"`
+	// HealthCheckInterval is the time between two runs of the dependency
+	// probes reported on /readyz
+	HealthCheckInterval Duration `yaml:"health_check_interval" toml:"health_check_interval" env:"GOSOL_HEALTH_CHECK_INTERVAL"`
+	// CancelOrdersOnShutdown cancels resting orders after in-flight
+	// submissions are drained, instead of leaving them on the exchange
+	CancelOrdersOnShutdown bool `yaml:"cancel_orders_on_shutdown" toml:"cancel_orders_on_shutdown" env:"GOSOL_CANCEL_ORDERS_ON_SHUTDOWN"`
+	// APITokens enables API token authentication, in the format accepted by
+	// middleware.TokenStore.LoadTokens
+	APITokens string `yaml:"api_tokens" toml:"api_tokens" env:"API_TOKENS"`
+	// WebSocketOrigins are the browser origins allowed to open the push
+	// channel, "*" for any; empty only allows the server's own origin
+	WebSocketOrigins []string `yaml:"websocket_origins" toml:"websocket_origins"`
+}
+
+// ExchangesConfig contains the exchange connections
+type ExchangesConfig struct {
+	Dydx        ExchangeConfig `yaml:"dydx" toml:"dydx" env:"GOSOL_DYDX"`
+	Hyperliquid ExchangeConfig `yaml:"hyperliquid" toml:"hyperliquid" env:"GOSOL_HYPERLIQUID"`
+}
+
+// dYdX protocol versions
+const (
+	DydxV3 = "v3"
+	DydxV4 = "v4"
+)
+
+// Position modes of an exchange account
+const (
+	// PositionModeHedge keeps long and short positions on a symbol apart
+	PositionModeHedge = "hedge"
+	// PositionModeNetting keeps one position per symbol, which opposite
+	// orders reduce
+	PositionModeNetting = "netting"
+)
+
+// ExchangeConfig contains the connection and credentials of an exchange
+type ExchangeConfig struct {
+	Enabled bool `yaml:"enabled" toml:"enabled" env:"ENABLED"`
+	// Version is the dYdX protocol version, v3 or v4. Empty means v3.
+	Version    string `yaml:"version" toml:"version" env:"VERSION"`
+	BaseURL    string `yaml:"base_url" toml:"base_url" env:"BASE_URL"`
+	WSURL      string `yaml:"ws_url" toml:"ws_url" env:"WS_URL"`
+	APIKey     string `yaml:"api_key" toml:"api_key" env:"API_KEY"`
+	APISecret  string `yaml:"api_secret" toml:"api_secret" env:"API_SECRET"`
+	Passphrase string `yaml:"passphrase" toml:"passphrase" env:"PASSPHRASE"`
+	// Address and SubaccountNumber select the dYdX v4 subaccount to trade
+	Address          string `yaml:"address" toml:"address" env:"ADDRESS"`
+	SubaccountNumber int    `yaml:"subaccount_number" toml:"subaccount_number" env:"SUBACCOUNT_NUMBER"`
+	// RateLimit is the request rate in requests per second. Zero keeps the
+	// client default.
+	RateLimit float64 `yaml:"rate_limit" toml:"rate_limit" env:"RATE_LIMIT"`
+	RateBurst int     `yaml:"rate_burst" toml:"rate_burst" env:"RATE_BURST"`
+	// OrderAckTimeout is the time the exchange has to acknowledge an order
+	// before it is cancelled and expired. Zero keeps the default of 10s.
+	OrderAckTimeout Duration `yaml:"order_ack_timeout" toml:"order_ack_timeout" env:"ORDER_ACK_TIMEOUT"`
+	// MakerFeeBps and TakerFeeBps are the fee tier of the account, in basis
+	// points of the notional. Zero keeps the base tier of the exchange, and
+	// a negative maker fee is a rebate.
+	MakerFeeBps float64 `yaml:"maker_fee_bps" toml:"maker_fee_bps" env:"MAKER_FEE_BPS"`
+	TakerFeeBps float64 `yaml:"taker_fee_bps" toml:"taker_fee_bps" env:"TAKER_FEE_BPS"`
+	// PositionMode is hedge or netting, as set on the exchange account.
+	// Empty means hedge.
+	PositionMode string `yaml:"position_mode" toml:"position_mode" env:"POSITION_MODE"`
+}
+
+// LLMConfig contains the LLM models
+type LLMConfig struct {
+	Primary  ModelConfig `yaml:"primary" toml:"primary" env:"GOSOL_LLM_PRIMARY"`
+	Fallback ModelConfig `yaml:"fallback" toml:"fallback" env:"GOSOL_LLM_FALLBACK"`
+}
+
+// LLM providers
+const (
+	ProviderOllama   = "ollama"
+	ProviderDeepSeek = "deepseek"
+)
+
+// ModelConfig contains the configuration of an LLM model. Models without a
+// name are not configured.
+type ModelConfig struct {
+	Provider  string `yaml:"provider" toml:"provider" env:"PROVIDER"`
+	Name      string `yaml:"name" toml:"name" env:"NAME"`
+	BaseURL   string `yaml:"base_url" toml:"base_url" env:"BASE_URL"`
+	APIKey    string `yaml:"api_key" toml:"api_key" env:"API_KEY"`
+	MaxTokens int    `yaml:"max_tokens" toml:"max_tokens" env:"MAX_TOKENS"`
+}
+
+// RiskConfig contains the risk limits
+type RiskConfig struct {
+	// PositionLimits are the maximum position values per symbol
+	PositionLimits map[string]float64 `yaml:"position_limits" toml:"position_limits"`
+	ExposureLimit  float64            `yaml:"exposure_limit" toml:"exposure_limit" env:"GOSOL_RISK_EXPOSURE_LIMIT"`
+	// DrawdownLimit is the maximum drawdown from peak equity, from 0 to 1
+	DrawdownLimit float64 `yaml:"drawdown_limit" toml:"drawdown_limit" env:"GOSOL_RISK_DRAWDOWN_LIMIT"`
+	// VolatilityThresholds are the low, medium, high and critical
+	// volatility levels, in increasing order
+	VolatilityThresholds []float64 `yaml:"volatility_thresholds" toml:"volatility_thresholds"`
+	// DailyLossLimit is the maximum loss of a UTC day, disabled when zero
+	DailyLossLimit float64 `yaml:"daily_loss_limit" toml:"daily_loss_limit" env:"GOSOL_RISK_DAILY_LOSS_LIMIT"`
+	// MaxDailyTrades is the maximum number of trades of a UTC day, disabled
+	// when zero
+	MaxDailyTrades int `yaml:"max_daily_trades" toml:"max_daily_trades" env:"GOSOL_RISK_MAX_DAILY_TRADES"`
+	// TradeCooldown is the minimum time between two trades of a symbol
+	TradeCooldown Duration `yaml:"trade_cooldown" toml:"trade_cooldown" env:"GOSOL_RISK_TRADE_COOLDOWN"`
+	// MaxOpenPositions and MaxLeverage bound the portfolio, disabled when
+	// zero
+	MaxOpenPositions int     `yaml:"max_open_positions" toml:"max_open_positions" env:"GOSOL_RISK_MAX_OPEN_POSITIONS"`
+	MaxLeverage      float64 `yaml:"max_leverage" toml:"max_leverage" env:"GOSOL_RISK_MAX_LEVERAGE"`
+	// RiskPerTrade is the fraction of the equity a trade risks down to its
+	// stop loss, used to size positions
+	RiskPerTrade float64 `yaml:"risk_per_trade" toml:"risk_per_trade" env:"GOSOL_RISK_PER_TRADE"`
+	// StopLoss and TakeProfit are the default distances of the stop levels
+	// from the entry price, from 0 to 1
+	StopLoss   float64 `yaml:"stop_loss" toml:"stop_loss" env:"GOSOL_RISK_STOP_LOSS"`
+	TakeProfit float64 `yaml:"take_profit" toml:"take_profit" env:"GOSOL_RISK_TAKE_PROFIT"`
+}
+
+// RepositoryConfig contains the storage connection URIs
+type RepositoryConfig struct {
+	PostgresURI string `yaml:"postgres_uri" toml:"postgres_uri" env:"GOSOL_POSTGRES_URI"`
+	// RedisURI is the Redis instance open orders and positions are saved to
+	// as they change, and recovered from at startup
+	RedisURI string `yaml:"redis_uri" toml:"redis_uri" env:"GOSOL_REDIS_URI"`
+	// SnapshotPath is the file the engine state is saved to at shutdown and
+	// restored from at startup. Without it the state is saved in Redis when
+	// RedisURI is set, and not saved otherwise.
+	SnapshotPath string `yaml:"snapshot_path" toml:"snapshot_path" env:"GOSOL_SNAPSHOT_PATH"`
+	// SnapshotMaxAge is the age beyond which a saved state is not restored.
+	// Zero restores it at any age.
+	SnapshotMaxAge Duration `yaml:"snapshot_max_age" toml:"snapshot_max_age" env:"GOSOL_SNAPSHOT_MAX_AGE"`
+}
+
+// Event bridge drivers
+const (
+	BridgeNATS  = "nats"
+	BridgeKafka = "kafka"
+)
+
+// BridgeConfig contains the bridge forwarding trading events to an external
+// broker. The bridge is disabled without a driver.
+type BridgeConfig struct {
+	Driver string `yaml:"driver" toml:"driver" env:"DRIVER"`
+	// URL is the NATS server URL or the Kafka REST proxy URL
+	URL string `yaml:"url" toml:"url" env:"URL"`
+	// TopicPrefix names the broker topics, prefix.order_filled and so on
+	TopicPrefix string `yaml:"topic_prefix" toml:"topic_prefix" env:"TOPIC_PREFIX"`
+	// Source identifies this process in published events, the host name by
+	// default
+	Source string `yaml:"source" toml:"source" env:"SOURCE"`
+	// SignalTopic is the broker topic of external trade signals. Signals are
+	// not consumed when empty.
+	SignalTopic string `yaml:"signal_topic" toml:"signal_topic" env:"SIGNAL_TOPIC"`
+	// FillTopic is the broker topic of fills of other venues, such as a
+	// Solana DEX executor. Fills are not consumed when empty.
+	FillTopic string `yaml:"fill_topic" toml:"fill_topic" env:"FILL_TOPIC"`
+	// ConsumerGroup is the Kafka consumer group of the signal topic
+	ConsumerGroup string `yaml:"consumer_group" toml:"consumer_group" env:"CONSUMER_GROUP"`
+}
+
+// LogConfig contains the level and format of the log
+type LogConfig struct {
+	// Level is debug, info, warn or error
+	Level string `yaml:"level" toml:"level" env:"LEVEL"`
+	// Format is text or json
+	Format string `yaml:"format" toml:"format" env:"FORMAT"`
+}
+
+// TracingConfig contains the OpenTelemetry trace export. Tracing is disabled
+// without an endpoint.
+type TracingConfig struct {
+	// Endpoint is the OTLP/HTTP collector URL, e.g. http://localhost:4318
+	Endpoint    string `yaml:"endpoint" toml:"endpoint" env:"ENDPOINT"`
+	ServiceName string `yaml:"service_name" toml:"service_name" env:"SERVICE_NAME"`
+	// SampleRatio is the fraction of traces sampled, from 0 to 1
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio" env:"SAMPLE_RATIO"`
+}
+
+// TelegramConfig contains the Telegram bot. The bot is disabled without a
+// token.
+type TelegramConfig struct {
+	Token string `yaml:"token" toml:"token" env:"TOKEN"`
+	// AllowedChats are the chat IDs notified and allowed to send commands
+	AllowedChats []int64 `yaml:"allowed_chats" toml:"allowed_chats"`
+	// SummaryTime is the UTC time of day, as "15:04", of the daily PnL
+	// summary
+	SummaryTime string `yaml:"summary_time" toml:"summary_time" env:"SUMMARY_TIME"`
+}
+
+// WatchdogConfig contains the dead man's switch clearing the book when the
+// strategy loop stops checking in. The watchdog is disabled without a
+// timeout.
+type WatchdogConfig struct {
+	// Timeout is the time without a check-in after which open orders are
+	// cancelled on the exchanges and trading is halted
+	Timeout Duration `yaml:"timeout" toml:"timeout" env:"TIMEOUT"`
+	// FlattenPositions also closes open positions with reduce-only market
+	// orders
+	FlattenPositions bool `yaml:"flatten_positions" toml:"flatten_positions" env:"FLATTEN_POSITIONS"`
+}
+
+// LossBudgetConfig contains the losses a strategy or token may make before
+// it is disabled until the end of the UTC day or ISO week. Zero disables a
+// limit.
+type LossBudgetConfig struct {
+	MaxDailyLoss  float64 `yaml:"max_daily_loss" toml:"max_daily_loss"`
+	MaxWeeklyLoss float64 `yaml:"max_weekly_loss" toml:"max_weekly_loss"`
+}
+
+// BudgetsConfig contains the loss budgets of the tokens. The budgets of the
+// strategies are set on each strategy.
+type BudgetsConfig struct {
+	// Tokens are the budgets by base asset, such as BTC for BTC-USD. A token
+	// over budget takes no new orders beside reduce-only ones.
+	Tokens map[string]LossBudgetConfig `yaml:"tokens" toml:"tokens"`
+}
+
+// AccountDefault is the ID of the default trading account
+const AccountDefault = "default"
+
+// AccountConfig contains a trading account with its own exchange credentials
+// and risk budget
+type AccountConfig struct {
+	ID   string `yaml:"id" toml:"id"`
+	Name string `yaml:"name" toml:"name"`
+	// Strategies are the strategies trading the account. A strategy trades
+	// at most one account.
+	Strategies []string       `yaml:"strategies" toml:"strategies"`
+	Exchange   ExchangeConfig `yaml:"exchange" toml:"exchange"`
+	// Risk are the risk limits of the account, those of the risk section
+	// when omitted. Each account keeps its own daily loss and trade counts.
+	Risk *RiskConfig `yaml:"risk" toml:"risk"`
+}
+
+// StrategyConfig contains the initial state of a strategy
+type StrategyConfig struct {
+	Name    string                 `yaml:"name" toml:"name"`
+	Enabled bool                   `yaml:"enabled" toml:"enabled"`
+	Params  map[string]interface{} `yaml:"params" toml:"params"`
+	// Schedule is the trading calendar of the strategy, which trades at all
+	// times when omitted
+	Schedule *ScheduleConfig `yaml:"schedule" toml:"schedule"`
+	// Budget are the losses after which the strategy is disabled until the
+	// end of the day or week
+	Budget LossBudgetConfig `yaml:"budget" toml:"budget"`
+}
+
+// ScheduleConfig contains the trading calendar of a strategy
+type ScheduleConfig struct {
+	// Timezone is the IANA time zone of the windows, UTC when empty
+	Timezone string `yaml:"timezone" toml:"timezone"`
+	// Windows are the recurring windows the strategy trades in
+	Windows []WindowConfig `yaml:"windows" toml:"windows"`
+	// Maintenance are one-off periods the strategy does not trade in
+	Maintenance []MaintenanceConfig `yaml:"maintenance" toml:"maintenance"`
+	// Outside is pause, protect or flatten: what happens to the strategy's
+	// positions outside its windows. Signals are paused in every case.
+	Outside string `yaml:"outside" toml:"outside"`
+	// Symbols are the markets whose positions Outside applies to, all the
+	// positions of the account trading the strategy when empty
+	Symbols []string `yaml:"symbols" toml:"symbols"`
+	// ProtectStop is the protective stop distance as a fraction of the price
+	ProtectStop float64 `yaml:"protect_stop" toml:"protect_stop"`
+}
+
+// WindowConfig is a recurring trading window, times of day as "15:04"
+type WindowConfig struct {
+	Days  []string `yaml:"days" toml:"days"`
+	Start string   `yaml:"start" toml:"start"`
+	End   string   `yaml:"end" toml:"end"`
+}
+
+// MaintenanceConfig is a one-off period without trading
+type MaintenanceConfig struct {
+	Start  time.Time `yaml:"start" toml:"start"`
+	End    time.Time `yaml:"end" toml:"end"`
+	Reason string    `yaml:"reason" toml:"reason"`
+}
+
+// Duration is a time.Duration read from strings such as "30s"
+type Duration time.Duration
+
+// UnmarshalText parses a duration string
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalText formats the duration as a string
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// Default returns the default configuration
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Addr:                ":8080",
+			ShutdownTimeout:     Duration(30 * time.Second),
+			HealthCheckInterval: Duration(15 * time.Second),
+		},
+		LLM: LLMConfig{
+			Primary: ModelConfig{
+				Provider: ProviderOllama,
+				BaseURL:  "http://localhost:11434",
+			},
+		},
+		Risk: RiskConfig{
+			PositionLimits:       map[string]float64{},
+			ExposureLimit:        1000000,
+			DrawdownLimit:        0.25,
+			VolatilityThresholds: []float64{0.15, 0.30, 0.50, 0.75},
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "text",
+		},
+		Tracing: TracingConfig{
+			ServiceName: "gosol",
+			SampleRatio: 1,
+		},
+		Repository: RepositoryConfig{
+			SnapshotMaxAge: Duration(time.Hour),
+		},
+		Telegram: TelegramConfig{
+			SummaryTime: "00:05",
+		},
+	}
+}
+
+// Validate checks the configuration and returns every problem found
+func (c *Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Server.Addr == "" {
+		add("server.addr is required")
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		add("server.shutdown_timeout must be positive")
+	}
+	if c.Server.HealthCheckInterval <= 0 {
+		add("server.health_check_interval must be positive")
+	}
+
+	validateExchange("exchanges.dydx", c.Exchanges.Dydx, add)
+	validateExchange("exchanges.hyperliquid", c.Exchanges.Hyperliquid, add)
+	validateDydx("exchanges.dydx", c.Exchanges.Dydx, add)
+
+	for _, m := range []struct {
+		name  string
+		model ModelConfig
+	}{{"primary", c.LLM.Primary}, {"fallback", c.LLM.Fallback}} {
+		name, model := m.name, m.model
+		if model.Name == "" {
+			continue
+		}
+		switch model.Provider {
+		case ProviderOllama:
+		case ProviderDeepSeek:
+			if model.APIKey == "" {
+				add("llm.%s.api_key is required for %s", name, model.Provider)
+			}
+		default:
+			add("llm.%s.provider must be %s or %s", name, ProviderOllama, ProviderDeepSeek)
+		}
+		if model.BaseURL != "" && !validURL(model.BaseURL) {
+			add("llm.%s.base_url is not a valid URL", name)
+		}
+		if model.MaxTokens < 0 {
+			add("llm.%s.max_tokens must not be negative", name)
+		}
+	}
+
+	validateRisk("risk", c.Risk, add)
+
+	if c.Repository.PostgresURI != "" && !validURL(c.Repository.PostgresURI) {
+		add("repository.postgres_uri is not a valid URI")
+	}
+	if c.Repository.RedisURI != "" && !validURL(c.Repository.RedisURI) {
+		add("repository.redis_uri is not a valid URI")
+	}
+	if c.Repository.SnapshotMaxAge < 0 {
+		add("repository.snapshot_max_age must not be negative")
+	}
+
+	switch c.Bridge.Driver {
+	case "":
+	case BridgeNATS, BridgeKafka:
+		if !validURL(c.Bridge.URL) {
+			add("bridge.url is required and must be a valid URL")
+		}
+	default:
+		add("bridge.driver must be %s or %s", BridgeNATS, BridgeKafka)
+	}
+
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		add("log.level must be debug, info, warn or error")
+	}
+	switch c.Log.Format {
+	case "text", "json":
+	default:
+		add("log.format must be text or json")
+	}
+
+	if c.Tracing.Endpoint != "" && !validURL(c.Tracing.Endpoint) {
+		add("tracing.endpoint is not a valid URL")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		add("tracing.sample_ratio must be between 0 and 1")
+	}
+	if c.Telegram.Token != "" {
+		if len(c.Telegram.AllowedChats) == 0 {
+			add("telegram.allowed_chats is required with a token")
+		}
+		if _, err := time.Parse("15:04", c.Telegram.SummaryTime); err != nil {
+			add("telegram.summary_time must be a time of day such as 00:05")
+		}
+	}
+	if c.Watchdog.Timeout < 0 {
+		add("watchdog.timeout must not be negative")
+	}
+	for token, budget := range c.Budgets.Tokens {
+		validateBudget("budgets.tokens."+token, budget, add)
+	}
+
+	seen := make(map[string]bool, len(c.Strategies))
+	for i, strategy := range c.Strategies {
+		switch {
+		case strategy.Name == "":
+			add("strategies[%d].name is required", i)
+		case seen[strategy.Name]:
+			add("strategies[%d]: duplicate strategy %s", i, strategy.Name)
+		}
+		seen[strategy.Name] = true
+		if schedule := strategy.Schedule; schedule != nil {
+			switch schedule.Outside {
+			case "", "pause", "protect", "flatten":
+			default:
+				add("strategies[%d].schedule.outside must be pause, protect or flatten", i)
+			}
+			if schedule.ProtectStop < 0 || schedule.ProtectStop >= 1 {
+				add("strategies[%d].schedule.protect_stop must be between 0 and 1", i)
+			}
+		}
+		validateBudget(fmt.Sprintf("strategies[%d].budget", i), strategy.Budget, add)
+	}
+
+	accounts := map[string]bool{AccountDefault: true}
+	traded := make(map[string]string, len(c.Strategies))
+	for i, account := range c.Accounts {
+		prefix := fmt.Sprintf("accounts[%d]", i)
+		switch {
+		case account.ID == "":
+			add("%s.id is required", prefix)
+		case accounts[account.ID]:
+			add("%s: duplicate account %s", prefix, account.ID)
+		}
+		accounts[account.ID] = true
+		validateExchange(prefix+".exchange", account.Exchange, add)
+		validateDydx(prefix+".exchange", account.Exchange, add)
+		if account.Risk != nil {
+			validateRisk(prefix+".risk", *account.Risk, add)
+		}
+		for _, strategy := range account.Strategies {
+			if !seen[strategy] {
+				add("%s: strategy %s is not configured", prefix, strategy)
+			}
+			if other, ok := traded[strategy]; ok {
+				add("%s: strategy %s is already traded by account %s", prefix, strategy, other)
+			}
+			traded[strategy] = account.ID
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", ErrInvalidConfig, errors.Join(errs...))
+}
+
+// validateBudget checks that the limits of a loss budget are not negative
+func validateBudget(prefix string, budget LossBudgetConfig, add func(format string, args ...interface{})) {
+	if budget.MaxDailyLoss < 0 || budget.MaxWeeklyLoss < 0 {
+		add("%s limits must not be negative", prefix)
+	}
+}
+
+// validateExchange checks the connection of an enabled exchange
+func validateExchange(prefix string, exchange ExchangeConfig, add func(format string, args ...interface{})) {
+	// Positions are netted by the mode without an exchange too
+	switch exchange.PositionMode {
+	case "", PositionModeHedge, PositionModeNetting:
+	default:
+		add("%s.position_mode must be %s or %s", prefix, PositionModeHedge, PositionModeNetting)
+	}
+	if !exchange.Enabled {
+		return
+	}
+	if exchange.BaseURL != "" && !validURL(exchange.BaseURL) {
+		add("%s.base_url is not a valid URL", prefix)
+	}
+	if exchange.WSURL != "" && !validURL(exchange.WSURL) {
+		add("%s.ws_url is not a valid URL", prefix)
+	}
+	if exchange.RateLimit < 0 || exchange.RateBurst < 0 {
+		add("%s rate limit must not be negative", prefix)
+	}
+	if exchange.OrderAckTimeout < 0 {
+		add("%s.order_ack_timeout must not be negative", prefix)
+	}
+	if exchange.TakerFeeBps < 0 {
+		add("%s.taker_fee_bps must not be negative", prefix)
+	}
+}
+
+// validateDydx checks the credentials of an enabled dYdX connection
+func validateDydx(prefix string, dydx ExchangeConfig, add func(format string, args ...interface{})) {
+	if !dydx.Enabled {
+		return
+	}
+	switch dydx.Version {
+	case "", DydxV3:
+		if dydx.APIKey == "" || dydx.APISecret == "" {
+			add("%s.api_key and api_secret are required", prefix)
+		}
+	case DydxV4:
+		if dydx.Address == "" {
+			add("%s.address is required for %s", prefix, DydxV4)
+		}
+		if dydx.SubaccountNumber < 0 {
+			add("%s.subaccount_number must not be negative", prefix)
+		}
+	default:
+		add("%s.version must be %s or %s", prefix, DydxV3, DydxV4)
+	}
+}
+
+// validateRisk checks the risk limits of a risk section
+func validateRisk(prefix string, risk RiskConfig, add func(format string, args ...interface{})) {
+	for symbol, limit := range risk.PositionLimits {
+		if limit <= 0 {
+			add("%s.position_limits.%s must be positive", prefix, symbol)
+		}
+	}
+	if risk.ExposureLimit <= 0 {
+		add("%s.exposure_limit must be positive", prefix)
+	}
+	if risk.DrawdownLimit <= 0 || risk.DrawdownLimit >= 1 {
+		add("%s.drawdown_limit must be between 0 and 1", prefix)
+	}
+	if !increasing(risk.VolatilityThresholds, 4) {
+		add("%s.volatility_thresholds must be 4 positive increasing values", prefix)
+	}
+	if risk.DailyLossLimit < 0 {
+		add("%s.daily_loss_limit must not be negative", prefix)
+	}
+	if risk.MaxDailyTrades < 0 {
+		add("%s.max_daily_trades must not be negative", prefix)
+	}
+	if risk.TradeCooldown < 0 {
+		add("%s.trade_cooldown must not be negative", prefix)
+	}
+	if risk.MaxOpenPositions < 0 {
+		add("%s.max_open_positions must not be negative", prefix)
+	}
+	if risk.MaxLeverage < 0 {
+		add("%s.max_leverage must not be negative", prefix)
+	}
+	for name, fraction := range map[string]float64{
+		"risk_per_trade": risk.RiskPerTrade,
+		"stop_loss":      risk.StopLoss,
+		"take_profit":    risk.TakeProfit,
+	} {
+		if fraction < 0 || fraction >= 1 {
+			add("%s.%s must be at least 0 and below 1", prefix, name)
+		}
+	}
+}
+
+func validURL(v string) bool {
+	u, err := url.Parse(v)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+func increasing(values []float64, n int) bool {
+	if len(values) != n {
+		return false
+	}
+	for i, v := range values {
+		if v <= 0 || (i > 0 && v <= values[i-1]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,307 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func env(vars map[string]string) LookupFunc {
+	return func(key string) (string, bool) {
+		v, ok := vars[key]
+		return v, ok
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("Defaults without a file", func(t *testing.T) {
+		cfg, err := load("", env(nil))
+		require.NoError(t, err)
+		assert.Equal(t, ":8080", cfg.Server.Addr)
+		assert.Equal(t, 30*time.Second, time.Duration(cfg.Server.ShutdownTimeout))
+		assert.Equal(t, 0.25, cfg.Risk.DrawdownLimit)
+	})
+
+	t.Run("YAML file", func(t *testing.T) {
+		path := writeFile(t, "gosol.yaml", `
+server:
+  addr: ":9090"
+  shutdown_timeout: 45s
+exchanges:
+  dydx:
+    enabled: true
+    base_url: https://api.dydx.exchange
+    api_key: key
+    api_secret: secret
+llm:
+  fallback:
+    provider: deepseek
+    name: deepseek-coder-33b-instruct
+    base_url: https://api.deepseek.com
+    api_key: sk-test
+risk:
+  exposure_limit: 250000
+  position_limits:
+    BTC-USD: 100000
+repository:
+  postgres_uri: postgres://localhost:5432/gosol
+budgets:
+  tokens:
+    SOL:
+      max_daily_loss: 500
+strategies:
+  - name: momentum
+    enabled: true
+    params:
+      period: 14
+    budget:
+      max_weekly_loss: 2000
+    schedule:
+      timezone: America/New_York
+      windows:
+        - days: [mon, tue, wed, thu, fri]
+          start: "08:00"
+          end: "22:00"
+      maintenance:
+        - start: 2024-06-01T00:00:00Z
+          end: 2024-06-01T02:00:00Z
+          reason: exchange upgrade
+      outside: protect
+`)
+		cfg, err := load(path, env(nil))
+		require.NoError(t, err)
+		assert.Equal(t, ":9090", cfg.Server.Addr)
+		assert.Equal(t, 45*time.Second, time.Duration(cfg.Server.ShutdownTimeout))
+		assert.Equal(t, "secret", cfg.Exchanges.Dydx.APISecret)
+		assert.Equal(t, "sk-test", cfg.LLM.Fallback.APIKey)
+		// Defaults are kept for keys the file does not set
+		assert.Equal(t, ProviderOllama, cfg.LLM.Primary.Provider)
+		assert.Equal(t, 0.25, cfg.Risk.DrawdownLimit)
+		assert.Equal(t, 100000.0, cfg.Risk.PositionLimits["BTC-USD"])
+		assert.Equal(t, time.Hour, time.Duration(cfg.Repository.SnapshotMaxAge))
+		require.Len(t, cfg.Strategies, 1)
+		assert.Equal(t, 14, cfg.Strategies[0].Params["period"])
+		assert.Equal(t, 2000.0, cfg.Strategies[0].Budget.MaxWeeklyLoss)
+		assert.Equal(t, 500.0, cfg.Budgets.Tokens["SOL"].MaxDailyLoss)
+		schedule := cfg.Strategies[0].Schedule
+		require.NotNil(t, schedule)
+		assert.Equal(t, "protect", schedule.Outside)
+		assert.Equal(t, "22:00", schedule.Windows[0].End)
+		assert.Equal(t, time.Date(2024, 6, 1, 2, 0, 0, 0, time.UTC), schedule.Maintenance[0].End)
+	})
+
+	t.Run("TOML file", func(t *testing.T) {
+		path := writeFile(t, "gosol.toml", `
+[server]
+addr = ":7070"
+shutdown_timeout = "1m"
+
+[risk]
+drawdown_limit = 0.1
+volatility_thresholds = [0.1, 0.2, 0.3, 0.4]
+
+[[strategies]]
+name = "mean_reversion"
+`)
+		cfg, err := load(path, env(nil))
+		require.NoError(t, err)
+		assert.Equal(t, ":7070", cfg.Server.Addr)
+		assert.Equal(t, time.Minute, time.Duration(cfg.Server.ShutdownTimeout))
+		assert.Equal(t, 0.1, cfg.Risk.DrawdownLimit)
+		assert.Equal(t, "mean_reversion", cfg.Strategies[0].Name)
+	})
+
+	t.Run("Environment overrides the file", func(t *testing.T) {
+		path := writeFile(t, "gosol.yml", "server:\n  addr: \":9090\"\n")
+		cfg, err := load(path, env(map[string]string{
+			"GOSOL_SERVER_ADDR":          ":6060",
+			"GOSOL_SHUTDOWN_TIMEOUT":     "5s",
+			"GOSOL_DYDX_ENABLED":         "true",
+			"GOSOL_DYDX_API_KEY":         "env-key",
+			"GOSOL_DYDX_API_SECRET":      "env-secret",
+			"GOSOL_DYDX_RATE_BURST":      "20",
+			"GOSOL_RISK_EXPOSURE_LIMIT":  "5000",
+			"GOSOL_LLM_FALLBACK_API_KEY": "sk-env",
+			"API_TOKENS":                 "ops:admin:secret",
+			"GOSOL_BRIDGE_DRIVER":        "nats",
+			"GOSOL_BRIDGE_URL":           "nats://localhost:4222",
+			"GOSOL_LOG_FORMAT":           "json",
+			"GOSOL_TRACING_ENDPOINT":     "http://localhost:4318",
+			"GOSOL_RISK_TRADE_COOLDOWN":  "2m",
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, ":6060", cfg.Server.Addr)
+		assert.Equal(t, 5*time.Second, time.Duration(cfg.Server.ShutdownTimeout))
+		assert.True(t, cfg.Exchanges.Dydx.Enabled)
+		assert.Equal(t, "env-key", cfg.Exchanges.Dydx.APIKey)
+		assert.Equal(t, 20, cfg.Exchanges.Dydx.RateBurst)
+		assert.Equal(t, 5000.0, cfg.Risk.ExposureLimit)
+		assert.Equal(t, "sk-env", cfg.LLM.Fallback.APIKey)
+		assert.Equal(t, "ops:admin:secret", cfg.Server.APITokens)
+		assert.Equal(t, BridgeNATS, cfg.Bridge.Driver)
+		assert.Equal(t, "nats://localhost:4222", cfg.Bridge.URL)
+		assert.Equal(t, "json", cfg.Log.Format)
+		assert.Equal(t, "info", cfg.Log.Level)
+		assert.Equal(t, "http://localhost:4318", cfg.Tracing.Endpoint)
+		assert.Equal(t, 1.0, cfg.Tracing.SampleRatio)
+		assert.Equal(t, 2*time.Minute, time.Duration(cfg.Risk.TradeCooldown))
+
+		_, err = load(path, env(map[string]string{"GOSOL_DYDX_ENABLED": "maybe"}))
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+	})
+
+	t.Run("Invalid configuration", func(t *testing.T) {
+		path := writeFile(t, "gosol.yaml", `
+exchanges:
+  dydx:
+    enabled: true
+    taker_fee_bps: -1
+    position_mode: one-way
+llm:
+  primary:
+    provider: openai
+    name: gpt
+risk:
+  drawdown_limit: 1.5
+  volatility_thresholds: [0.5, 0.1]
+  max_daily_trades: -1
+repository:
+  snapshot_max_age: -1h
+watchdog:
+  timeout: -1s
+budgets:
+  tokens:
+    SOL:
+      max_weekly_loss: -100
+bridge:
+  driver: rabbitmq
+log:
+  level: verbose
+tracing:
+  sample_ratio: 2
+telegram:
+  token: "123:abc"
+  summary_time: midnight
+strategies:
+  - name: momentum
+  - name: momentum
+  - name: grid
+    schedule:
+      outside: close
+    budget:
+      max_daily_loss: -1
+`)
+		_, err := load(path, env(nil))
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+		assert.ErrorContains(t, err, "exchanges.dydx.api_key and api_secret are required")
+		assert.ErrorContains(t, err, "exchanges.dydx.taker_fee_bps must not be negative")
+		assert.ErrorContains(t, err, "exchanges.dydx.position_mode must be hedge or netting")
+		assert.ErrorContains(t, err, "llm.primary.provider must be ollama or deepseek")
+		assert.ErrorContains(t, err, "risk.drawdown_limit must be between 0 and 1")
+		assert.ErrorContains(t, err, "risk.volatility_thresholds")
+		assert.ErrorContains(t, err, "risk.max_daily_trades must not be negative")
+		assert.ErrorContains(t, err, "repository.snapshot_max_age must not be negative")
+		assert.ErrorContains(t, err, "bridge.driver must be nats or kafka")
+		assert.ErrorContains(t, err, "log.level must be debug, info, warn or error")
+		assert.ErrorContains(t, err, "tracing.sample_ratio must be between 0 and 1")
+		assert.ErrorContains(t, err, "telegram.allowed_chats is required with a token")
+		assert.ErrorContains(t, err, "telegram.summary_time must be a time of day")
+		assert.ErrorContains(t, err, "watchdog.timeout must not be negative")
+		assert.ErrorContains(t, err, "budgets.tokens.SOL limits must not be negative")
+		assert.ErrorContains(t, err, "strategies[2].budget limits must not be negative")
+		assert.ErrorContains(t, err, "duplicate strategy momentum")
+		assert.ErrorContains(t, err, "strategies[2].schedule.outside must be pause, protect or flatten")
+	})
+
+	t.Run("dYdX v4 needs an address instead of API keys", func(t *testing.T) {
+		path := writeFile(t, "gosol.yaml", "exchanges:\n  dydx:\n    enabled: true\n    version: v4\n")
+		_, err := load(path, env(nil))
+		assert.ErrorContains(t, err, "exchanges.dydx.address is required for v4")
+		assert.NotContains(t, err.Error(), "api_key")
+
+		cfg, err := load(path, env(map[string]string{"GOSOL_DYDX_ADDRESS": "dydx1abc", "GOSOL_DYDX_SUBACCOUNT_NUMBER": "2"}))
+		require.NoError(t, err)
+		assert.Equal(t, 2, cfg.Exchanges.Dydx.SubaccountNumber)
+
+		_, err = load(writeFile(t, "gosol.yaml", "exchanges:\n  dydx:\n    enabled: true\n    version: v5\n"), env(nil))
+		assert.ErrorContains(t, err, "exchanges.dydx.version must be v3 or v4")
+	})
+
+	t.Run("Accounts", func(t *testing.T) {
+		path := writeFile(t, "gosol.yaml", `
+strategies:
+  - name: momentum
+  - name: breakout
+accounts:
+  - id: wallet-2
+    strategies: [momentum]
+    exchange:
+      enabled: true
+      version: v4
+      address: dydx1def
+      subaccount_number: 1
+    risk:
+      exposure_limit: 50000
+      drawdown_limit: 0.1
+      volatility_thresholds: [0.15, 0.30, 0.50, 0.75]
+      daily_loss_limit: 500
+`)
+		cfg, err := load(path, env(nil))
+		require.NoError(t, err)
+		require.Len(t, cfg.Accounts, 1)
+		assert.Equal(t, "dydx1def", cfg.Accounts[0].Exchange.Address)
+		require.NotNil(t, cfg.Accounts[0].Risk)
+		assert.Equal(t, 500.0, cfg.Accounts[0].Risk.DailyLossLimit)
+
+		_, err = load(writeFile(t, "gosol.yaml", `
+strategies:
+  - name: momentum
+accounts:
+  - id: default
+  - strategies: [grid]
+    exchange:
+      enabled: true
+  - id: wallet-3
+    strategies: [momentum]
+    risk:
+      drawdown_limit: 0.1
+  - id: wallet-4
+    strategies: [momentum]
+`), env(nil))
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+		assert.ErrorContains(t, err, "accounts[0]: duplicate account default")
+		assert.ErrorContains(t, err, "accounts[1].id is required")
+		assert.ErrorContains(t, err, "accounts[1].exchange.api_key and api_secret are required")
+		assert.ErrorContains(t, err, "accounts[1]: strategy grid is not configured")
+		assert.ErrorContains(t, err, "accounts[3]: strategy momentum is already traded by account wallet-3")
+		assert.ErrorContains(t, err, "accounts[2].risk.exposure_limit must be positive")
+	})
+
+	t.Run("Unknown keys and formats are rejected", func(t *testing.T) {
+		_, err := load(writeFile(t, "gosol.yaml", "server:\n  adr: \":9090\"\n"), env(nil))
+		assert.ErrorContains(t, err, "adr")
+		_, err = load(writeFile(t, "gosol.toml", "[sever]\n"), env(nil))
+		assert.Error(t, err)
+		_, err = load(writeFile(t, "gosol.json", "{}"), env(nil))
+		assert.ErrorContains(t, err, "unsupported config format")
+		_, err = load(filepath.Join(t.TempDir(), "missing.yaml"), env(nil))
+		assert.Error(t, err)
+	})
+}
+
+func TestExampleConfig(t *testing.T) {
+	// The fallback model needs a key, which is only ever set in the environment
+	cfg, err := load("gosol.example.yaml", env(map[string]string{"GOSOL_LLM_FALLBACK_API_KEY": "sk-test"}))
+	require.NoError(t, err)
+	assert.Equal(t, "momentum", cfg.Strategies[0].Name)
+}
@@ -39,9 +39,20 @@ CREATE TABLE trades_y2024m02 PARTITION OF trades
 CREATE INDEX trades_market_time_idx ON trades (market_id, executed_at);
 CREATE INDEX trades_time_idx ON trades (executed_at);
 
+-- Trading Accounts, each with its own credentials and risk budget
+CREATE TABLE accounts (
+    id VARCHAR(64) PRIMARY KEY,
+    name VARCHAR(100),
+    exchange VARCHAR(10), -- 'hyperliquid' or 'dydx'
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO accounts (id, name) VALUES ('default', 'Default');
+
 -- Order Management Tables
 CREATE TABLE orders (
     id BIGSERIAL PRIMARY KEY,
+    account_id VARCHAR(64) NOT NULL DEFAULT 'default',
     client_order_id VARCHAR(50) NOT NULL,
     market_id INT NOT NULL,
     type VARCHAR(20) NOT NULL,
@@ -54,10 +65,12 @@ CREATE TABLE orders (
     leverage INT NOT NULL,
     reduce_only BOOLEAN NOT NULL DEFAULT FALSE,
     post_only BOOLEAN NOT NULL DEFAULT FALSE,
+    time_in_force VARCHAR(3) NOT NULL DEFAULT 'GTC',
     status VARCHAR(20) NOT NULL,
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     expires_at TIMESTAMP,
+    CONSTRAINT orders_account_fk FOREIGN KEY (account_id) REFERENCES accounts(id),
     CONSTRAINT orders_market_fk FOREIGN KEY (market_id) REFERENCES markets(id),
     CONSTRAINT orders_client_id_idx UNIQUE (client_order_id)
 ) PARTITION BY RANGE (created_at);
@@ -71,10 +84,12 @@ CREATE TABLE orders_y2024m02 PARTITION OF orders
 -- Create indexes on orders partitions
 CREATE INDEX orders_market_status_idx ON orders (market_id, status, created_at);
 CREATE INDEX orders_status_time_idx ON orders (status, created_at);
+CREATE INDEX orders_account_status_idx ON orders (account_id, status, created_at);
 
 -- Position Management Tables
 CREATE TABLE positions (
     id BIGSERIAL PRIMARY KEY,
+    account_id VARCHAR(64) NOT NULL DEFAULT 'default',
     market_id INT NOT NULL,
     side VARCHAR(4) NOT NULL,
     entry_price DECIMAL(20,8) NOT NULL,
@@ -90,15 +105,18 @@ CREATE TABLE positions (
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     closed_at TIMESTAMP,
+    CONSTRAINT positions_account_fk FOREIGN KEY (account_id) REFERENCES accounts(id),
     CONSTRAINT positions_market_fk FOREIGN KEY (market_id) REFERENCES markets(id)
 );
 
 CREATE INDEX positions_market_status_idx ON positions (market_id, status);
 CREATE INDEX positions_status_time_idx ON positions (status, created_at);
+CREATE INDEX positions_account_status_idx ON positions (account_id, status);
 
 -- Risk Management Tables
 CREATE TABLE risk_checks (
     id BIGSERIAL PRIMARY KEY,
+    account_id VARCHAR(64) NOT NULL DEFAULT 'default',
     type VARCHAR(20) NOT NULL,
     level VARCHAR(10) NOT NULL,
     status VARCHAR(10) NOT NULL,
@@ -107,6 +125,7 @@ CREATE TABLE risk_checks (
     market_id INT,
     description TEXT,
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT risk_checks_account_fk FOREIGN KEY (account_id) REFERENCES accounts(id),
     CONSTRAINT risk_checks_market_fk FOREIGN KEY (market_id) REFERENCES markets(id)
 ) PARTITION BY RANGE (created_at);
 
@@ -119,6 +138,49 @@ CREATE TABLE risk_checks_y2024m02 PARTITION OF risk_checks
 -- Create indexes on risk_checks partitions
 CREATE INDEX risk_checks_type_time_idx ON risk_checks (type, created_at);
 CREATE INDEX risk_checks_market_time_idx ON risk_checks (market_id, created_at);
+CREATE INDEX risk_checks_account_time_idx ON risk_checks (account_id, created_at);
+
+-- Daily loss and trade counters of each account, kept across restarts
+CREATE TABLE risk_daily_state (
+    account_id VARCHAR(64) NOT NULL DEFAULT 'default',
+    day DATE NOT NULL,
+    realized_pnl DECIMAL(20,8) NOT NULL DEFAULT 0,
+    trades INT NOT NULL DEFAULT 0,
+    last_trade JSONB NOT NULL DEFAULT '{}', -- last trade time per symbol
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (account_id, day),
+    CONSTRAINT risk_daily_state_account_fk FOREIGN KEY (account_id) REFERENCES accounts(id)
+);
+
+-- Trade Journal Tables
+CREATE TABLE trade_journals (
+    position_id VARCHAR(64) PRIMARY KEY,
+    market_id INT,
+    side VARCHAR(5) NOT NULL,
+    size DECIMAL(20,8) NOT NULL,
+    entry_price DECIMAL(20,8) NOT NULL,
+    exit_price DECIMAL(20,8) NOT NULL,
+    realized_pnl DECIMAL(20,8) NOT NULL,
+    exit_reason VARCHAR(10) NOT NULL, -- 'liquidated', 'signal' or 'manual'
+    status VARCHAR(10) NOT NULL, -- 'pending', 'ready' or 'failed'
+    narrative TEXT,
+    model VARCHAR(100),
+    error TEXT,
+    details JSONB NOT NULL, -- fills, signals and risk checks the narrative is based on
+    opened_at TIMESTAMP NOT NULL,
+    closed_at TIMESTAMP NOT NULL,
+    generated_at TIMESTAMP,
+    CONSTRAINT trade_journals_market_fk FOREIGN KEY (market_id) REFERENCES markets(id)
+);
+
+-- Trades of a journaled position, by order ID
+CREATE TABLE trade_journal_trades (
+    trade_id VARCHAR(64) PRIMARY KEY,
+    position_id VARCHAR(64) NOT NULL,
+    CONSTRAINT trade_journal_trades_position_fk FOREIGN KEY (position_id) REFERENCES trade_journals(position_id)
+);
+
+CREATE INDEX trade_journals_market_time_idx ON trade_journals (market_id, closed_at);
 
 -- Funding Rate Tables
 CREATE TABLE funding_rates (
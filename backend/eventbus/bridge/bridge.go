@@ -0,0 +1,300 @@
+// Package bridge forwards event bus events to an external broker, NATS or
+// Kafka, and publishes the trade signals of external producers on the bus,
+// so several processes and external analytics can share the trading events.
+// The message schema is documented in README.md.
+package bridge
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/devinjacknz/godydxhyber/backend/eventbus"
+	"github.com/devinjacknz/godydxhyber/backend/logger"
+	"github.com/devinjacknz/godydxhyber/backend/pkg/tracing"
+)
+
+// SchemaVersion is the version of the message envelope
+const SchemaVersion = "gosol.event.v1"
+
+// Message directions, for metrics
+const (
+	directionOut = "out"
+	directionIn  = "in"
+)
+
+// Transport is a connection to a message broker
+type Transport interface {
+	// Publish sends a message to a topic
+	Publish(ctx context.Context, topic string, payload []byte) error
+	// Subscribe delivers the messages of a topic to handler, one at a time,
+	// until ctx is done. It returns once the subscription is active.
+	Subscribe(ctx context.Context, topic string, handler func(payload []byte)) error
+	// Close closes the connection
+	Close() error
+}
+
+// Envelope is the JSON form of every message exchanged with the broker
+type Envelope struct {
+	Schema string `json:"schema"`
+	ID     string `json:"id"`
+	// Type is the event bus topic name, such as order_filled
+	Type string `json:"type"`
+	// Source identifies the process that published the event
+	Source string          `json:"source"`
+	Time   time.Time       `json:"time"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// Config contains bridge configuration
+type Config struct {
+	// TopicPrefix is prepended to the event type to name the broker topic,
+	// so order fills go to gosol.order_filled
+	TopicPrefix string
+	// Source identifies this process in published events. Consumed events
+	// of the same source are ignored.
+	Source string
+	// SignalTopic is the broker topic whose trade signals are published on
+	// the bus. Signals are not consumed when empty.
+	SignalTopic string
+	// FillTopic is the broker topic whose fills of other venues, such as a
+	// Solana DEX executor, are published on the bus. Fills are not consumed
+	// when empty.
+	FillTopic string
+	// PublishTimeout bounds the publication of one event
+	PublishTimeout time.Duration
+}
+
+// DefaultConfig returns default bridge configuration
+func DefaultConfig() Config {
+	source, _ := os.Hostname()
+	return Config{
+		TopicPrefix:    "gosol",
+		Source:         source,
+		PublishTimeout: 5 * time.Second,
+	}
+}
+
+// consumedKey marks the context of signals and fills published by the
+// bridge, so they are not forwarded back to the broker
+type consumedKey struct{}
+
+// Bridge connects the event bus to a broker
+type Bridge struct {
+	bus         *eventbus.Bus
+	transport   Transport
+	config      Config
+	unsubscribe []func()
+	cancel      context.CancelFunc
+}
+
+// New creates a bridge between a bus and a broker transport
+func New(bus *eventbus.Bus, transport Transport, config Config) *Bridge {
+	defaults := DefaultConfig()
+	if config.TopicPrefix == "" {
+		config.TopicPrefix = defaults.TopicPrefix
+	}
+	if config.Source == "" {
+		config.Source = defaults.Source
+	}
+	if config.PublishTimeout <= 0 {
+		config.PublishTimeout = defaults.PublishTimeout
+	}
+	return &Bridge{
+		bus:       bus,
+		transport: transport,
+		config:    config,
+	}
+}
+
+// Topic returns the broker topic of an event type
+func (b *Bridge) Topic(eventType string) string {
+	return strings.TrimSuffix(b.config.TopicPrefix, ".") + "." + eventType
+}
+
+// Start forwards fills, expired orders, closed positions, signals, risk
+// violations and analyses to the broker, and consumes the signal and fill
+// topics if configured.
+// Events are forwarded asynchronously, so a slow broker does not hold up
+// trading.
+func (b *Bridge) Start(ctx context.Context) error {
+	ctx, b.cancel = context.WithCancel(ctx)
+
+	b.unsubscribe = []func(){
+		forward(b, eventbus.OrderFilled),
+		forward(b, eventbus.OrderExpired),
+		forward(b, eventbus.PositionClosed),
+		forward(b, eventbus.SignalGenerated),
+		forward(b, eventbus.RiskViolation),
+		forward(b, eventbus.AnalysisCompleted),
+	}
+
+	if b.config.SignalTopic != "" {
+		if err := b.transport.Subscribe(ctx, b.config.SignalTopic, b.consumeSignal); err != nil {
+			b.Close()
+			return fmt.Errorf("failed to subscribe to %s: %w", b.config.SignalTopic, err)
+		}
+	}
+	if b.config.FillTopic != "" {
+		if err := b.transport.Subscribe(ctx, b.config.FillTopic, b.consumeFill); err != nil {
+			b.Close()
+			return fmt.Errorf("failed to subscribe to %s: %w", b.config.FillTopic, err)
+		}
+	}
+	return nil
+}
+
+// Close stops forwarding events and closes the transport
+func (b *Bridge) Close() error {
+	for _, unsubscribe := range b.unsubscribe {
+		unsubscribe()
+	}
+	b.unsubscribe = nil
+	if b.cancel != nil {
+		b.cancel()
+	}
+	return b.transport.Close()
+}
+
+// forward subscribes a publisher of the events of a topic to the broker
+func forward[T any](b *Bridge, topic eventbus.Topic[T]) func() {
+	return eventbus.Subscribe(b.bus, topic, func(ctx context.Context, event T) error {
+		if ctx.Value(consumedKey{}) != nil {
+			return nil
+		}
+		ctx, cancel := context.WithTimeout(ctx, b.config.PublishTimeout)
+		defer cancel()
+
+		payload, err := b.encode(topic.Name(), event)
+		if err == nil {
+			err = b.transport.Publish(ctx, b.Topic(topic.Name()), payload)
+		}
+		recordEvent(topic.Name(), directionOut, err)
+		return err
+	}, eventbus.WithMode(eventbus.Async))
+}
+
+func (b *Bridge) encode(eventType string, event interface{}) ([]byte, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(Envelope{
+		Schema: SchemaVersion,
+		ID:     newID(),
+		Type:   eventType,
+		Source: b.config.Source,
+		Time:   time.Now().UTC(),
+		Data:   data,
+	})
+}
+
+// consumeSignal publishes an external signal on the bus
+func (b *Bridge) consumeSignal(payload []byte) {
+	signal, envelope, err := decodeSignal(payload)
+	if err == nil && envelope.Source == b.config.Source {
+		return
+	}
+	ctx := context.WithValue(context.Background(), consumedKey{}, envelope.ID)
+	if err == nil {
+		// Signals keep the correlation ID of their producer
+		if signal.CorrelationID == "" {
+			signal.CorrelationID = logger.NewCorrelationID()
+		}
+		ctx = logger.WithCorrelationID(ctx, signal.CorrelationID)
+		var span trace.Span
+		ctx, span = tracing.Start(ctx, "signal.consume",
+			attribute.String("strategy", signal.Strategy),
+			attribute.String("symbol", signal.Symbol),
+		)
+		err = eventbus.Publish(ctx, b.bus, eventbus.SignalGenerated, signal)
+		tracing.End(span, err)
+	}
+	recordEvent(eventbus.SignalGenerated.Name(), directionIn, err)
+	if err != nil {
+		slog.ErrorContext(ctx, "event bridge dropped signal", "topic", b.config.SignalTopic, "error", err)
+	}
+}
+
+// consumeFill publishes a fill of another venue on the bus
+func (b *Bridge) consumeFill(payload []byte) {
+	fill, envelope, err := decodeFill(payload)
+	if err == nil && envelope.Source == b.config.Source {
+		return
+	}
+	ctx := context.WithValue(context.Background(), consumedKey{}, envelope.ID)
+	if err == nil {
+		if fill.CorrelationID != "" {
+			ctx = logger.WithCorrelationID(ctx, fill.CorrelationID)
+		}
+		err = eventbus.Publish(ctx, b.bus, eventbus.OrderFilled, fill)
+	}
+	recordEvent(eventbus.OrderFilled.Name(), directionIn, err)
+	if err != nil {
+		slog.ErrorContext(ctx, "event bridge dropped fill", "topic", b.config.FillTopic, "error", err)
+	}
+}
+
+// decode decodes a message of the type of a topic
+func decode[T any](payload []byte, topic eventbus.Topic[T]) (T, Envelope, error) {
+	var envelope Envelope
+	var event T
+	if err := json.Unmarshal(payload, &envelope); err != nil {
+		return event, envelope, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	if envelope.Schema != SchemaVersion {
+		return event, envelope, fmt.Errorf("%w: unknown schema %q", ErrInvalidMessage, envelope.Schema)
+	}
+	if envelope.Type != topic.Name() {
+		return event, envelope, fmt.Errorf("%w: unexpected type %q", ErrInvalidMessage, envelope.Type)
+	}
+	if err := json.Unmarshal(envelope.Data, &event); err != nil {
+		return event, envelope, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	return event, envelope, nil
+}
+
+// decodeSignal decodes and validates a signal message
+func decodeSignal(payload []byte) (eventbus.SignalEvent, Envelope, error) {
+	signal, envelope, err := decode(payload, eventbus.SignalGenerated)
+	if err != nil {
+		return signal, envelope, err
+	}
+	if signal.Symbol == "" || (signal.Side != "buy" && signal.Side != "sell") || signal.Size <= 0 {
+		return signal, envelope, fmt.Errorf("%w: signal needs a symbol, a buy or sell side and a positive size", ErrInvalidMessage)
+	}
+	if signal.Timestamp.IsZero() {
+		signal.Timestamp = envelope.Time
+	}
+	return signal, envelope, nil
+}
+
+// decodeFill decodes and validates a fill message
+func decodeFill(payload []byte) (eventbus.OrderFilledEvent, Envelope, error) {
+	fill, envelope, err := decode(payload, eventbus.OrderFilled)
+	if err != nil {
+		return fill, envelope, err
+	}
+	if fill.Venue == "" || fill.Symbol == "" || (fill.Side != "buy" && fill.Side != "sell") || fill.Size <= 0 || fill.Price <= 0 {
+		return fill, envelope, fmt.Errorf("%w: fill needs a venue, a symbol, a buy or sell side and a positive size and price", ErrInvalidMessage)
+	}
+	if fill.Timestamp.IsZero() {
+		fill.Timestamp = envelope.Time
+	}
+	return fill, envelope, nil
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
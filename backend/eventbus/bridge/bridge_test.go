@@ -0,0 +1,238 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/devinjacknz/godydxhyber/backend/eventbus"
+	"github.com/devinjacknz/godydxhyber/backend/logger"
+)
+
+// memoryTransport is an in-memory broker
+type memoryTransport struct {
+	published chan published
+	handlers  map[string]func(payload []byte)
+	closed    bool
+	mu        sync.Mutex
+}
+
+type published struct {
+	topic   string
+	payload []byte
+}
+
+func newMemoryTransport() *memoryTransport {
+	return &memoryTransport{
+		published: make(chan published, 16),
+		handlers:  make(map[string]func(payload []byte)),
+	}
+}
+
+func (t *memoryTransport) Publish(ctx context.Context, topic string, payload []byte) error {
+	t.published <- published{topic: topic, payload: payload}
+	return nil
+}
+
+func (t *memoryTransport) Subscribe(ctx context.Context, topic string, handler func(payload []byte)) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.handlers[topic] = handler
+	return nil
+}
+
+func (t *memoryTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+	return nil
+}
+
+func (t *memoryTransport) deliver(topic string, payload []byte) {
+	t.mu.Lock()
+	handler := t.handlers[topic]
+	t.mu.Unlock()
+	handler(payload)
+}
+
+func (t *memoryTransport) next(tb testing.TB) published {
+	select {
+	case p := <-t.published:
+		return p
+	case <-time.After(time.Second):
+		tb.Fatal("nothing published")
+		return published{}
+	}
+}
+
+func signalMessage(t *testing.T, source string, signal eventbus.SignalEvent) []byte {
+	return message(t, source, eventbus.SignalGenerated.Name(), signal)
+}
+
+func fillMessage(t *testing.T, source string, fill eventbus.OrderFilledEvent) []byte {
+	return message(t, source, eventbus.OrderFilled.Name(), fill)
+}
+
+func message(t *testing.T, source, eventType string, event interface{}) []byte {
+	data, err := json.Marshal(event)
+	require.NoError(t, err)
+	payload, err := json.Marshal(Envelope{
+		Schema: SchemaVersion,
+		ID:     "external-1",
+		Type:   eventType,
+		Source: source,
+		Time:   time.Now().UTC(),
+		Data:   data,
+	})
+	require.NoError(t, err)
+	return payload
+}
+
+func TestBridge(t *testing.T) {
+	t.Run("Forwards bus events to the broker", func(t *testing.T) {
+		bus := eventbus.New(eventbus.DefaultConfig())
+		defer bus.Close()
+		transport := newMemoryTransport()
+		b := New(bus, transport, Config{TopicPrefix: "desk", Source: "node-1"})
+		require.NoError(t, b.Start(context.Background()))
+		defer b.Close()
+
+		fill := eventbus.OrderFilledEvent{OrderID: "o1", Symbol: "BTC-USD", Side: "buy", Price: 50000, Size: 0.5}
+		require.NoError(t, eventbus.Publish(context.Background(), bus, eventbus.OrderFilled, fill))
+
+		p := transport.next(t)
+		assert.Equal(t, "desk.order_filled", p.topic)
+
+		var envelope Envelope
+		require.NoError(t, json.Unmarshal(p.payload, &envelope))
+		assert.Equal(t, SchemaVersion, envelope.Schema)
+		assert.Equal(t, "order_filled", envelope.Type)
+		assert.Equal(t, "node-1", envelope.Source)
+		assert.NotEmpty(t, envelope.ID)
+
+		var got eventbus.OrderFilledEvent
+		require.NoError(t, json.Unmarshal(envelope.Data, &got))
+		assert.Equal(t, fill, got)
+	})
+
+	t.Run("Publishes external signals on the bus", func(t *testing.T) {
+		bus := eventbus.New(eventbus.DefaultConfig())
+		defer bus.Close()
+		transport := newMemoryTransport()
+		b := New(bus, transport, Config{Source: "node-1", SignalTopic: "external.signals"})
+		require.NoError(t, b.Start(context.Background()))
+		defer b.Close()
+
+		var signals []eventbus.SignalEvent
+		var correlationIDs []string
+		eventbus.Subscribe(bus, eventbus.SignalGenerated, func(ctx context.Context, e eventbus.SignalEvent) error {
+			signals = append(signals, e)
+			correlationIDs = append(correlationIDs, logger.CorrelationID(ctx))
+			return nil
+		})
+
+		transport.deliver("external.signals", signalMessage(t, "research", eventbus.SignalEvent{
+			Strategy: "ml", Symbol: "ETH-USD", Side: "sell", Size: 2,
+		}))
+		// Own signals, invalid signals and other messages are dropped
+		transport.deliver("external.signals", signalMessage(t, "node-1", eventbus.SignalEvent{
+			Symbol: "ETH-USD", Side: "sell", Size: 2,
+		}))
+		transport.deliver("external.signals", signalMessage(t, "research", eventbus.SignalEvent{
+			Symbol: "ETH-USD", Side: "short", Size: 2,
+		}))
+		transport.deliver("external.signals", []byte(`{"schema":"other"}`))
+
+		transport.deliver("external.signals", signalMessage(t, "research", eventbus.SignalEvent{
+			Strategy: "ml", Symbol: "ETH-USD", Side: "buy", Size: 1, CorrelationID: "upstream-1",
+		}))
+
+		require.Len(t, signals, 2)
+		assert.Equal(t, "ml", signals[0].Strategy)
+		assert.False(t, signals[0].Timestamp.IsZero())
+		// Signals get a correlation ID unless their producer set one
+		assert.NotEmpty(t, signals[0].CorrelationID)
+		assert.Equal(t, "upstream-1", signals[1].CorrelationID)
+		assert.Equal(t, []string{signals[0].CorrelationID, "upstream-1"}, correlationIDs)
+
+		// Consumed signals are not forwarded back to the broker
+		require.NoError(t, eventbus.Publish(context.Background(), bus, eventbus.SignalGenerated, eventbus.SignalEvent{
+			Symbol: "BTC-USD", Side: "buy", Size: 1,
+		}))
+		p := transport.next(t)
+		assert.Contains(t, string(p.payload), "BTC-USD")
+		select {
+		case p := <-transport.published:
+			t.Fatalf("unexpected message %s", p.payload)
+		case <-time.After(20 * time.Millisecond):
+		}
+	})
+
+	t.Run("Publishes fills of other venues on the bus", func(t *testing.T) {
+		bus := eventbus.New(eventbus.DefaultConfig())
+		defer bus.Close()
+		transport := newMemoryTransport()
+		b := New(bus, transport, Config{Source: "node-1", FillTopic: "dex.fills"})
+		require.NoError(t, b.Start(context.Background()))
+		defer b.Close()
+
+		var fills []eventbus.OrderFilledEvent
+		eventbus.Subscribe(bus, eventbus.OrderFilled, func(ctx context.Context, e eventbus.OrderFilledEvent) error {
+			fills = append(fills, e)
+			return nil
+		})
+
+		transport.deliver("dex.fills", fillMessage(t, "solana-executor", eventbus.OrderFilledEvent{
+			Venue: "solana", Symbol: "SOL/USDC", Side: "buy", Size: 10, Price: 150,
+		}))
+		// Fills need a venue and a price
+		transport.deliver("dex.fills", fillMessage(t, "solana-executor", eventbus.OrderFilledEvent{
+			Symbol: "SOL/USDC", Side: "buy", Size: 10, Price: 150,
+		}))
+		transport.deliver("dex.fills", fillMessage(t, "solana-executor", eventbus.OrderFilledEvent{
+			Venue: "solana", Symbol: "SOL/USDC", Side: "buy", Size: 10,
+		}))
+		transport.deliver("dex.fills", signalMessage(t, "solana-executor", eventbus.SignalEvent{
+			Symbol: "SOL/USDC", Side: "buy", Size: 10,
+		}))
+
+		require.Len(t, fills, 1)
+		assert.Equal(t, "solana", fills[0].Venue)
+		assert.False(t, fills[0].Timestamp.IsZero())
+
+		// Consumed fills are not forwarded back to the broker
+		select {
+		case p := <-transport.published:
+			t.Fatalf("unexpected message %s", p.payload)
+		case <-time.After(20 * time.Millisecond):
+		}
+	})
+
+	t.Run("Close stops forwarding", func(t *testing.T) {
+		bus := eventbus.New(eventbus.DefaultConfig())
+		defer bus.Close()
+		transport := newMemoryTransport()
+		b := New(bus, transport, DefaultConfig())
+		require.NoError(t, b.Start(context.Background()))
+
+		require.NoError(t, b.Close())
+		assert.True(t, transport.closed)
+		assert.Zero(t, bus.Subscribers(eventbus.OrderFilled.Name()))
+	})
+}
+
+func TestDecodeSignal(t *testing.T) {
+	_, _, err := decodeSignal([]byte("not json"))
+	assert.ErrorIs(t, err, ErrInvalidMessage)
+
+	payload := signalMessage(t, "research", eventbus.SignalEvent{Symbol: "BTC-USD", Side: "buy", Size: 1})
+	signal, envelope, err := decodeSignal(payload)
+	require.NoError(t, err)
+	assert.Equal(t, "research", envelope.Source)
+	assert.Equal(t, "BTC-USD", signal.Symbol)
+}
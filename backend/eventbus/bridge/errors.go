@@ -0,0 +1,15 @@
+package bridge
+
+import "errors"
+
+var (
+	// ErrInvalidMessage is returned for a consumed message that does not
+	// follow the event schema
+	ErrInvalidMessage = errors.New("invalid bridge message")
+
+	// ErrTransportClosed is returned when using a closed transport
+	ErrTransportClosed = errors.New("bridge transport closed")
+
+	// ErrUnsupportedURL is returned for a broker URL of an unknown scheme
+	ErrUnsupportedURL = errors.New("unsupported broker URL")
+)
@@ -0,0 +1,207 @@
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kafka REST proxy content types
+const (
+	kafkaJSONType = "application/vnd.kafka.json.v2+json"
+	kafkaType     = "application/vnd.kafka.v2+json"
+)
+
+// KafkaConfig contains Kafka transport configuration
+type KafkaConfig struct {
+	// URL is the base URL of the Kafka REST proxy
+	URL string
+	// ConsumerGroup is the consumer group of subscriptions. Processes
+	// sharing a group share the messages of a topic.
+	ConsumerGroup string
+	// PollInterval is how often subscriptions poll for new messages
+	PollInterval time.Duration
+	HTTPClient   *http.Client
+}
+
+// KafkaTransport publishes and subscribes through a Kafka REST proxy
+// (Confluent REST Proxy API v2), so the bridge needs no native Kafka client.
+type KafkaTransport struct {
+	config KafkaConfig
+	client *http.Client
+	wg     sync.WaitGroup
+	closed bool
+	mu     sync.Mutex
+}
+
+// NewKafkaTransport creates a Kafka REST proxy transport
+func NewKafkaTransport(config KafkaConfig) (*KafkaTransport, error) {
+	u, err := url.Parse(config.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedURL, config.URL)
+	}
+	config.URL = strings.TrimSuffix(config.URL, "/")
+	if config.ConsumerGroup == "" {
+		config.ConsumerGroup = "gosol"
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = time.Second
+	}
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &KafkaTransport{config: config, client: client}, nil
+}
+
+type kafkaRecord struct {
+	Value json.RawMessage `json:"value"`
+}
+
+// Publish produces a message to a topic
+func (t *KafkaTransport) Publish(ctx context.Context, topic string, payload []byte) error {
+	if t.isClosed() {
+		return ErrTransportClosed
+	}
+	body, err := json.Marshal(map[string][]kafkaRecord{
+		"records": {{Value: payload}},
+	})
+	if err != nil {
+		return err
+	}
+
+	var resp struct {
+		Offsets []struct {
+			ErrorCode *int   `json:"error_code"`
+			Error     string `json:"error"`
+		} `json:"offsets"`
+	}
+	if err := t.do(ctx, http.MethodPost, t.config.URL+"/topics/"+url.PathEscape(topic), kafkaJSONType, body, &resp); err != nil {
+		return err
+	}
+	for _, offset := range resp.Offsets {
+		if offset.ErrorCode != nil {
+			return fmt.Errorf("kafka produce to %s failed: %s", topic, offset.Error)
+		}
+	}
+	return nil
+}
+
+// Subscribe creates a consumer instance subscribed to a topic and polls it
+// until ctx is done, then deletes the instance
+func (t *KafkaTransport) Subscribe(ctx context.Context, topic string, handler func(payload []byte)) error {
+	if t.isClosed() {
+		return ErrTransportClosed
+	}
+
+	var consumer struct {
+		BaseURI string `json:"base_uri"`
+	}
+	body, _ := json.Marshal(map[string]string{
+		"format":            "json",
+		"auto.offset.reset": "latest",
+	})
+	if err := t.do(ctx, http.MethodPost, t.config.URL+"/consumers/"+url.PathEscape(t.config.ConsumerGroup), kafkaType, body, &consumer); err != nil {
+		return fmt.Errorf("failed to create kafka consumer: %w", err)
+	}
+
+	body, _ = json.Marshal(map[string][]string{"topics": {topic}})
+	if err := t.do(ctx, http.MethodPost, consumer.BaseURI+"/subscription", kafkaType, body, nil); err != nil {
+		t.deleteConsumer(consumer.BaseURI)
+		return fmt.Errorf("failed to subscribe kafka consumer: %w", err)
+	}
+
+	t.wg.Add(1)
+	go t.poll(ctx, consumer.BaseURI, handler)
+	return nil
+}
+
+// poll fetches the records of a consumer until ctx is done
+func (t *KafkaTransport) poll(ctx context.Context, consumer string, handler func(payload []byte)) {
+	defer t.wg.Done()
+	defer t.deleteConsumer(consumer)
+
+	ticker := time.NewTicker(t.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		var records []kafkaRecord
+		err := t.do(ctx, http.MethodGet, consumer+"/records", kafkaJSONType, nil, &records)
+		if err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "kafka poll failed", "error", err)
+		}
+		for _, record := range records {
+			handler(record.Value)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close rejects new requests and waits for the subscriptions, whose contexts
+// must be done, to delete their consumer instances
+func (t *KafkaTransport) Close() error {
+	t.mu.Lock()
+	t.closed = true
+	t.mu.Unlock()
+
+	t.wg.Wait()
+	return nil
+}
+
+func (t *KafkaTransport) isClosed() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.closed
+}
+
+func (t *KafkaTransport) deleteConsumer(consumer string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := t.do(ctx, http.MethodDelete, consumer, kafkaType, nil, nil); err != nil {
+		slog.ErrorContext(ctx, "failed to delete kafka consumer", "consumer", consumer, "error", err)
+	}
+}
+
+// do sends a REST proxy request and decodes the JSON response into out
+func (t *KafkaTransport) do(ctx context.Context, method, url, contentType string, body []byte, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", contentType)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("kafka REST proxy returned %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
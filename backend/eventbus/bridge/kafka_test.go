@@ -0,0 +1,97 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKafkaTransport(t *testing.T) {
+	var mu sync.Mutex
+	var produced []string
+	deleted := make(chan struct{})
+	polled := false
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/topics/gosol.order_filled":
+			assert.Equal(t, kafkaJSONType, r.Header.Get("Content-Type"))
+			body, _ := io.ReadAll(r.Body)
+			produced = append(produced, string(body))
+			w.Write([]byte(`{"offsets":[{"partition":0,"offset":1}]}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/topics/gosol.failing":
+			w.Write([]byte(`{"offsets":[{"error_code":50101,"error":"leader not available"}]}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/consumers/desk":
+			json.NewEncoder(w).Encode(map[string]string{
+				"instance_id": "c1",
+				"base_uri":    server.URL + "/consumers/desk/instances/c1",
+			})
+		case r.Method == http.MethodPost && r.URL.Path == "/consumers/desk/instances/c1/subscription":
+			body, _ := io.ReadAll(r.Body)
+			assert.JSONEq(t, `{"topics":["external.signals"]}`, string(body))
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodGet && r.URL.Path == "/consumers/desk/instances/c1/records":
+			if polled {
+				w.Write([]byte(`[]`))
+				return
+			}
+			polled = true
+			w.Write([]byte(`[{"topic":"external.signals","value":{"type":"signal_generated"},"partition":0,"offset":3}]`))
+		case r.Method == http.MethodDelete && r.URL.Path == "/consumers/desk/instances/c1":
+			w.WriteHeader(http.StatusNoContent)
+			close(deleted)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	transport, err := NewKafkaTransport(KafkaConfig{URL: server.URL, ConsumerGroup: "desk", PollInterval: 10 * time.Millisecond})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, transport.Publish(ctx, "gosol.order_filled", []byte(`{"id":"1"}`)))
+	assert.ErrorContains(t, transport.Publish(ctx, "gosol.failing", []byte(`{}`)), "leader not available")
+	assert.Error(t, transport.Publish(ctx, "gosol.missing", []byte(`{}`)))
+	mu.Lock()
+	assert.Equal(t, []string{`{"records":[{"value":{"id":"1"}}]}`}, produced)
+	mu.Unlock()
+
+	received := make(chan string, 1)
+	subCtx, cancel := context.WithCancel(ctx)
+	require.NoError(t, transport.Subscribe(subCtx, "external.signals", func(payload []byte) {
+		received <- string(payload)
+	}))
+	select {
+	case payload := <-received:
+		assert.JSONEq(t, `{"type":"signal_generated"}`, payload)
+	case <-time.After(time.Second):
+		t.Fatal("record not delivered")
+	}
+
+	cancel()
+	require.NoError(t, transport.Close())
+	select {
+	case <-deleted:
+	default:
+		t.Fatal("consumer instance not deleted")
+	}
+	assert.ErrorIs(t, transport.Publish(ctx, "gosol.order_filled", []byte(`{}`)), ErrTransportClosed)
+}
+
+func TestNewKafkaTransportRejectsUnknownScheme(t *testing.T) {
+	_, err := NewKafkaTransport(KafkaConfig{URL: "kafka://localhost:9092"})
+	assert.ErrorIs(t, err, ErrUnsupportedURL)
+}
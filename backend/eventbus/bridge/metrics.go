@@ -0,0 +1,25 @@
+package bridge
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	bridgeEvents = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "event_bridge_events_total",
+			Help: "Total number of events exchanged with the external broker",
+		},
+		[]string{"topic", "direction", "result"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(bridgeEvents)
+}
+
+func recordEvent(topic, direction string, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	bridgeEvents.WithLabelValues(topic, direction, result).Inc()
+}
@@ -0,0 +1,273 @@
+package bridge
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NATSTransport publishes and subscribes through a NATS server, using the
+// core NATS protocol. It does not reconnect: publishing fails once the
+// connection is lost, and the bridge must be recreated.
+type NATSTransport struct {
+	conn    net.Conn
+	w       *bufio.Writer
+	subs    map[int]func(payload []byte)
+	nextSID int
+	done    chan struct{}
+	err     error
+	wmu     sync.Mutex
+	mu      sync.Mutex
+}
+
+// DialNATS connects to a NATS server. The URL scheme is nats, or tls for
+// TLS connections; credentials may be given in the URL user info.
+func DialNATS(ctx context.Context, rawURL string) (*NATSTransport, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedURL, err)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "4222")
+	}
+
+	var conn net.Conn
+	var dialer net.Dialer
+	switch u.Scheme {
+	case "nats":
+		conn, err = dialer.DialContext(ctx, "tcp", host)
+	case "tls":
+		tlsDialer := tls.Dialer{NetDialer: &dialer, Config: &tls.Config{ServerName: u.Hostname()}}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", host)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedURL, rawURL)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	t := &NATSTransport{
+		conn: conn,
+		w:    bufio.NewWriter(conn),
+		subs: make(map[int]func(payload []byte)),
+		done: make(chan struct{}),
+	}
+	r := bufio.NewReader(conn)
+	if err := t.handshake(ctx, r, u.User); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("NATS handshake failed: %w", err)
+	}
+	go t.readLoop(r)
+	return t, nil
+}
+
+// handshake reads the server INFO, sends CONNECT and waits for the PONG of
+// a PING, which the server only sends once it accepted the connection
+func (t *NATSTransport) handshake(ctx context.Context, r *bufio.Reader, user *url.Userinfo) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		t.conn.SetDeadline(deadline)
+		defer t.conn.SetDeadline(time.Time{})
+	}
+
+	line, err := readLine(r)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return fmt.Errorf("unexpected greeting %q", line)
+	}
+
+	options := map[string]interface{}{
+		"verbose":  false,
+		"pedantic": false,
+		"lang":     "go",
+		"name":     "gosol-bridge",
+		"protocol": 1,
+	}
+	if user != nil {
+		if password, ok := user.Password(); ok {
+			options["user"] = user.Username()
+			options["pass"] = password
+		} else {
+			options["auth_token"] = user.Username()
+		}
+	}
+	connect, err := json.Marshal(options)
+	if err != nil {
+		return err
+	}
+	if err := t.write("CONNECT "+string(connect)+"\r\nPING\r\n", nil); err != nil {
+		return err
+	}
+
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case strings.HasPrefix(line, "-ERR"):
+			return errors.New(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+// Publish sends a message to a subject
+func (t *NATSTransport) Publish(ctx context.Context, topic string, payload []byte) error {
+	return t.write("PUB "+topic+" "+strconv.Itoa(len(payload))+"\r\n", payload)
+}
+
+// Subscribe delivers the messages of a subject to handler until ctx is done
+func (t *NATSTransport) Subscribe(ctx context.Context, topic string, handler func(payload []byte)) error {
+	t.mu.Lock()
+	t.nextSID++
+	sid := t.nextSID
+	t.subs[sid] = handler
+	t.mu.Unlock()
+
+	if err := t.write("SUB "+topic+" "+strconv.Itoa(sid)+"\r\n", nil); err != nil {
+		t.mu.Lock()
+		delete(t.subs, sid)
+		t.mu.Unlock()
+		return err
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-t.done:
+			return
+		}
+		t.mu.Lock()
+		delete(t.subs, sid)
+		t.mu.Unlock()
+		t.write("UNSUB "+strconv.Itoa(sid)+"\r\n", nil)
+	}()
+	return nil
+}
+
+// Close flushes pending messages and closes the connection
+func (t *NATSTransport) Close() error {
+	t.wmu.Lock()
+	flushErr := t.w.Flush()
+	t.wmu.Unlock()
+
+	err := t.conn.Close()
+	<-t.done
+	if flushErr != nil && !errors.Is(flushErr, net.ErrClosed) {
+		return flushErr
+	}
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+// write sends a protocol line followed by an optional payload
+func (t *NATSTransport) write(line string, payload []byte) error {
+	t.wmu.Lock()
+	defer t.wmu.Unlock()
+
+	if err := t.closedErr(); err != nil {
+		return err
+	}
+	t.w.WriteString(line)
+	if payload != nil {
+		t.w.Write(payload)
+		t.w.WriteString("\r\n")
+	}
+	return t.w.Flush()
+}
+
+func (t *NATSTransport) closedErr() error {
+	select {
+	case <-t.done:
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		return fmt.Errorf("%w: %v", ErrTransportClosed, t.err)
+	default:
+		return nil
+	}
+}
+
+// readLoop handles server messages until the connection is closed
+func (t *NATSTransport) readLoop(r *bufio.Reader) {
+	err := t.read(r)
+	t.mu.Lock()
+	t.err = err
+	t.mu.Unlock()
+	close(t.done)
+}
+
+func (t *NATSTransport) read(r *bufio.Reader) error {
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case strings.HasPrefix(line, "MSG "):
+			if err := t.deliver(r, strings.Fields(line)); err != nil {
+				return err
+			}
+		case line == "PING":
+			if err := t.write("PONG\r\n", nil); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return errors.New(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+// deliver reads the payload of a MSG line and passes it to its subscriber.
+// The line is MSG <subject> <sid> [reply-to] <size>.
+func (t *NATSTransport) deliver(r *bufio.Reader, fields []string) error {
+	if len(fields) != 4 && len(fields) != 5 {
+		return fmt.Errorf("malformed MSG: %q", strings.Join(fields, " "))
+	}
+	sid, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return fmt.Errorf("malformed MSG sid: %w", err)
+	}
+	size, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil || size < 0 {
+		return fmt.Errorf("malformed MSG size: %q", fields[len(fields)-1])
+	}
+
+	payload := make([]byte, size+2)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return err
+	}
+	payload = payload[:size]
+
+	t.mu.Lock()
+	handler := t.subs[sid]
+	t.mu.Unlock()
+	if handler != nil {
+		handler(payload)
+	}
+	return nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
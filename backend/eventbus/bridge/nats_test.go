@@ -0,0 +1,131 @@
+package bridge
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// natsServer is a single-connection NATS server routing PUB to SUB
+type natsServer struct {
+	listener net.Listener
+	connect  chan string
+	subs     map[string]string
+	mu       sync.Mutex
+}
+
+func newNATSServer(t *testing.T) *natsServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &natsServer{listener: listener, connect: make(chan string, 1), subs: make(map[string]string)}
+	t.Cleanup(func() { listener.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *natsServer) url() string {
+	return "nats://" + s.listener.Addr().String()
+}
+
+func (s *natsServer) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var wmu sync.Mutex
+	write := func(data string) {
+		wmu.Lock()
+		defer wmu.Unlock()
+		io.WriteString(conn, data)
+	}
+	write(`INFO {"server_id":"test","max_payload":1048576}` + "\r\n")
+
+	r := bufio.NewReader(conn)
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		switch fields[0] {
+		case "CONNECT":
+			s.connect <- strings.TrimPrefix(line, "CONNECT ")
+		case "PING":
+			write("PONG\r\n")
+		case "SUB":
+			s.mu.Lock()
+			s.subs[fields[1]] = fields[2]
+			s.mu.Unlock()
+		case "UNSUB":
+			s.mu.Lock()
+			for subject, sid := range s.subs {
+				if sid == fields[1] {
+					delete(s.subs, subject)
+				}
+			}
+			s.mu.Unlock()
+		case "PUB":
+			size, _ := strconv.Atoi(fields[2])
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return
+			}
+			s.mu.Lock()
+			sid, ok := s.subs[fields[1]]
+			s.mu.Unlock()
+			if ok {
+				write("MSG " + fields[1] + " " + sid + " " + fields[2] + "\r\n" + string(payload))
+			}
+		}
+	}
+}
+
+func TestNATSTransport(t *testing.T) {
+	server := newNATSServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	transport, err := DialNATS(ctx, strings.Replace(server.url(), "nats://", "nats://trader:secret@", 1))
+	require.NoError(t, err)
+	assert.Contains(t, <-server.connect, `"user":"trader"`)
+
+	received := make(chan string, 1)
+	subCtx, unsubscribe := context.WithCancel(ctx)
+	require.NoError(t, transport.Subscribe(subCtx, "gosol.order_filled", func(payload []byte) {
+		received <- string(payload)
+	}))
+	require.NoError(t, transport.Publish(ctx, "gosol.order_filled", []byte(`{"id":"1"}`)))
+
+	select {
+	case payload := <-received:
+		assert.Equal(t, `{"id":"1"}`, payload)
+	case <-time.After(time.Second):
+		t.Fatal("message not delivered")
+	}
+
+	unsubscribe()
+	assert.Eventually(t, func() bool {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		return len(server.subs) == 0
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, transport.Close())
+	assert.ErrorIs(t, transport.Publish(ctx, "gosol.order_filled", []byte("{}")), ErrTransportClosed)
+}
+
+func TestDialNATSRejectsUnknownScheme(t *testing.T) {
+	_, err := DialNATS(context.Background(), "http://localhost:4222")
+	assert.ErrorIs(t, err, ErrUnsupportedURL)
+}
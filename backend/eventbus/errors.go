@@ -0,0 +1,11 @@
+package eventbus
+
+import "errors"
+
+var (
+	// ErrClosed is returned when publishing on a closed bus
+	ErrClosed = errors.New("event bus closed")
+
+	// ErrHandlerPanicked is returned for a synchronous handler that panicked
+	ErrHandlerPanicked = errors.New("event handler panicked")
+)
@@ -0,0 +1,273 @@
+// Package eventbus is an in-process publish/subscribe bus with typed topics,
+// so trading components can react to each other's events without calling
+// each other directly.
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// Topic names a kind of event and the type of its payload
+type Topic[T any] struct {
+	name string
+}
+
+// NewTopic creates a topic. Topics are identified by name, so two topics with
+// the same name must have the same payload type.
+func NewTopic[T any](name string) Topic[T] {
+	return Topic[T]{name: name}
+}
+
+// Name returns the topic name
+func (t Topic[T]) Name() string {
+	return t.name
+}
+
+// Handler handles the events of a topic
+type Handler[T any] func(ctx context.Context, event T) error
+
+// Mode is how a subscriber receives events
+type Mode int
+
+const (
+	// Sync runs the handler in the publisher's goroutine before Publish
+	// returns, and returns its error to the publisher
+	Sync Mode = iota
+	// Async queues events to a goroutine of the subscriber, so a slow
+	// handler does not hold up the publisher. Handler errors go to
+	// Config.ErrorHandler.
+	Async
+)
+
+// Config contains event bus configuration
+type Config struct {
+	// AsyncBuffer is the number of events queued per async subscriber.
+	// Publishing to a full queue blocks until there is room or the context
+	// is done.
+	AsyncBuffer int
+	// ErrorHandler receives the errors of async handlers. They are logged
+	// when nil.
+	ErrorHandler func(topic string, err error)
+}
+
+// DefaultConfig returns default event bus configuration
+func DefaultConfig() Config {
+	return Config{
+		AsyncBuffer: 256,
+	}
+}
+
+// SubscribeOption configures a subscription
+type SubscribeOption func(*subscription)
+
+// WithMode sets the delivery mode of a subscription. Subscriptions are
+// synchronous by default.
+func WithMode(mode Mode) SubscribeOption {
+	return func(s *subscription) {
+		s.mode = mode
+	}
+}
+
+// queued is an event waiting for an async subscriber
+type queued struct {
+	ctx   context.Context
+	event interface{}
+}
+
+type subscription struct {
+	id     uint64
+	topic  string
+	mode   Mode
+	handle func(ctx context.Context, event interface{}) error
+	queue  chan queued
+	done   chan struct{}
+}
+
+// Bus delivers published events to the subscribers of their topic
+type Bus struct {
+	config Config
+	subs   map[string][]*subscription
+	nextID uint64
+	closed bool
+	wg     sync.WaitGroup
+	mu     sync.RWMutex
+}
+
+// New creates an event bus
+func New(config Config) *Bus {
+	if config.AsyncBuffer <= 0 {
+		config.AsyncBuffer = DefaultConfig().AsyncBuffer
+	}
+	return &Bus{
+		config: config,
+		subs:   make(map[string][]*subscription),
+	}
+}
+
+// Subscribe registers a handler for the events of a topic and returns a
+// function removing it. An async subscriber handles the events already
+// queued before it stops.
+func Subscribe[T any](b *Bus, topic Topic[T], handler Handler[T], opts ...SubscribeOption) (unsubscribe func()) {
+	s := &subscription{
+		topic: topic.name,
+		handle: func(ctx context.Context, event interface{}) error {
+			return handler(ctx, event.(T))
+		},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return func() {}
+	}
+
+	b.nextID++
+	s.id = b.nextID
+	if s.mode == Async {
+		s.queue = make(chan queued, b.config.AsyncBuffer)
+		s.done = make(chan struct{})
+		b.wg.Add(1)
+		go b.run(s)
+	}
+	b.subs[s.topic] = append(b.subs[s.topic], s)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { b.unsubscribe(s) })
+	}
+}
+
+// Publish delivers an event to the subscribers of a topic, in the order they
+// subscribed. Synchronous handlers run before Publish returns; their errors,
+// and a context done while waiting for a full async queue, are returned
+// joined.
+func Publish[T any](ctx context.Context, b *Bus, topic Topic[T], event T) error {
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		return ErrClosed
+	}
+	subs := append([]*subscription(nil), b.subs[topic.name]...)
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, s := range subs {
+		if s.mode == Async {
+			if err := b.enqueue(ctx, s, event); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", topic.name, err))
+			}
+			continue
+		}
+		if err := call(ctx, s, event); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", topic.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close stops accepting events and waits for async subscribers to handle
+// the events already queued
+func (b *Bus) Close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	for _, subs := range b.subs {
+		for _, s := range subs {
+			if s.mode == Async {
+				close(s.done)
+			}
+		}
+	}
+	b.subs = make(map[string][]*subscription)
+	b.mu.Unlock()
+
+	b.wg.Wait()
+}
+
+// Subscribers returns the number of subscribers of a topic
+func (b *Bus) Subscribers(topic string) int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subs[topic])
+}
+
+func (b *Bus) unsubscribe(s *subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	subs := b.subs[s.topic]
+	for i, sub := range subs {
+		if sub.id == s.id {
+			b.subs[s.topic] = append(subs[:i:i], subs[i+1:]...)
+			if s.mode == Async {
+				close(s.done)
+			}
+			return
+		}
+	}
+}
+
+// enqueue queues an event for an async subscriber. An event published while
+// the subscriber is stopping is dropped.
+func (b *Bus) enqueue(ctx context.Context, s *subscription, event interface{}) error {
+	select {
+	case <-s.done:
+		return nil
+	case s.queue <- queued{ctx: context.WithoutCancel(ctx), event: event}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run handles the events of an async subscriber until it is stopped, then
+// handles the events still queued
+func (b *Bus) run(s *subscription) {
+	defer b.wg.Done()
+	for {
+		select {
+		case q := <-s.queue:
+			b.handleAsync(s, q)
+		case <-s.done:
+			for {
+				select {
+				case q := <-s.queue:
+					b.handleAsync(s, q)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (b *Bus) handleAsync(s *subscription, q queued) {
+	if err := call(q.ctx, s, q.event); err != nil {
+		if b.config.ErrorHandler != nil {
+			b.config.ErrorHandler(s.topic, err)
+			return
+		}
+		slog.ErrorContext(q.ctx, "event bus handler failed", "topic", s.topic, "error", err)
+	}
+}
+
+// call runs a handler, converting a panic into an error so one faulty
+// subscriber cannot take down the publisher
+func call(ctx context.Context, s *subscription, event interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrHandlerPanicked, r)
+		}
+	}()
+	return s.handle(ctx, event)
+}
@@ -0,0 +1,181 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncDelivery(t *testing.T) {
+	t.Run("Delivers to subscribers in order", func(t *testing.T) {
+		b := New(DefaultConfig())
+		defer b.Close()
+
+		var calls []string
+		Subscribe(b, OrderFilled, func(ctx context.Context, e OrderFilledEvent) error {
+			calls = append(calls, "first:"+e.OrderID)
+			return nil
+		})
+		Subscribe(b, OrderFilled, func(ctx context.Context, e OrderFilledEvent) error {
+			calls = append(calls, "second:"+e.OrderID)
+			return nil
+		})
+		Subscribe(b, PositionClosed, func(ctx context.Context, e PositionClosedEvent) error {
+			calls = append(calls, "position")
+			return nil
+		})
+
+		require.NoError(t, Publish(context.Background(), b, OrderFilled, OrderFilledEvent{OrderID: "o1"}))
+		assert.Equal(t, []string{"first:o1", "second:o1"}, calls)
+	})
+
+	t.Run("Returns handler errors and panics", func(t *testing.T) {
+		b := New(DefaultConfig())
+		defer b.Close()
+
+		errFailed := errors.New("failed")
+		delivered := false
+		Subscribe(b, RiskViolation, func(ctx context.Context, e RiskViolationEvent) error {
+			return errFailed
+		})
+		Subscribe(b, RiskViolation, func(ctx context.Context, e RiskViolationEvent) error {
+			panic("boom")
+		})
+		Subscribe(b, RiskViolation, func(ctx context.Context, e RiskViolationEvent) error {
+			delivered = true
+			return nil
+		})
+
+		err := Publish(context.Background(), b, RiskViolation, RiskViolationEvent{})
+		assert.ErrorIs(t, err, errFailed)
+		assert.ErrorIs(t, err, ErrHandlerPanicked)
+		assert.True(t, delivered, "later subscribers still receive the event")
+	})
+
+	t.Run("Unsubscribe stops delivery", func(t *testing.T) {
+		b := New(DefaultConfig())
+		defer b.Close()
+
+		n := 0
+		unsubscribe := Subscribe(b, SignalGenerated, func(ctx context.Context, e SignalEvent) error {
+			n++
+			return nil
+		})
+		require.NoError(t, Publish(context.Background(), b, SignalGenerated, SignalEvent{}))
+		unsubscribe()
+		unsubscribe()
+		require.NoError(t, Publish(context.Background(), b, SignalGenerated, SignalEvent{}))
+
+		assert.Equal(t, 1, n)
+		assert.Zero(t, b.Subscribers(SignalGenerated.Name()))
+	})
+}
+
+func TestAsyncDelivery(t *testing.T) {
+	t.Run("Does not block the publisher", func(t *testing.T) {
+		b := New(DefaultConfig())
+		defer b.Close()
+
+		release := make(chan struct{})
+		received := make(chan MarketDataEvent, 2)
+		Subscribe(b, MarketDataUpdated, func(ctx context.Context, e MarketDataEvent) error {
+			<-release
+			received <- e
+			return nil
+		}, WithMode(Async))
+
+		require.NoError(t, Publish(context.Background(), b, MarketDataUpdated, MarketDataEvent{Symbol: "BTC", Price: 1}))
+		require.NoError(t, Publish(context.Background(), b, MarketDataUpdated, MarketDataEvent{Symbol: "BTC", Price: 2}))
+		close(release)
+
+		for _, price := range []float64{1, 2} {
+			select {
+			case e := <-received:
+				assert.Equal(t, price, e.Price)
+			case <-time.After(time.Second):
+				t.Fatal("event not delivered")
+			}
+		}
+	})
+
+	t.Run("Reports handler errors", func(t *testing.T) {
+		var mu sync.Mutex
+		var reported []error
+		done := make(chan struct{})
+		b := New(Config{ErrorHandler: func(topic string, err error) {
+			mu.Lock()
+			reported = append(reported, err)
+			mu.Unlock()
+			close(done)
+		}})
+		defer b.Close()
+
+		Subscribe(b, PositionClosed, func(ctx context.Context, e PositionClosedEvent) error {
+			panic("boom")
+		}, WithMode(Async))
+		require.NoError(t, Publish(context.Background(), b, PositionClosed, PositionClosedEvent{}))
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("error not reported")
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		require.Len(t, reported, 1)
+		assert.ErrorIs(t, reported[0], ErrHandlerPanicked)
+	})
+
+	t.Run("Full queue waits for the context", func(t *testing.T) {
+		b := New(Config{AsyncBuffer: 1})
+		release := make(chan struct{})
+		started := make(chan struct{}, 1)
+		Subscribe(b, OrderFilled, func(ctx context.Context, e OrderFilledEvent) error {
+			started <- struct{}{}
+			<-release
+			return nil
+		}, WithMode(Async))
+
+		require.NoError(t, Publish(context.Background(), b, OrderFilled, OrderFilledEvent{}))
+		<-started
+		require.NoError(t, Publish(context.Background(), b, OrderFilled, OrderFilledEvent{}))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		err := Publish(ctx, b, OrderFilled, OrderFilledEvent{})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		close(release)
+		b.Close()
+	})
+}
+
+func TestClose(t *testing.T) {
+	b := New(DefaultConfig())
+
+	var mu sync.Mutex
+	n := 0
+	Subscribe(b, OrderFilled, func(ctx context.Context, e OrderFilledEvent) error {
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		n++
+		mu.Unlock()
+		return nil
+	}, WithMode(Async))
+	for i := 0; i < 10; i++ {
+		require.NoError(t, Publish(context.Background(), b, OrderFilled, OrderFilledEvent{}))
+	}
+
+	b.Close()
+	mu.Lock()
+	assert.Equal(t, 10, n, "queued events are handled before Close returns")
+	mu.Unlock()
+
+	assert.ErrorIs(t, Publish(context.Background(), b, OrderFilled, OrderFilledEvent{}), ErrClosed)
+	b.Close()
+}
@@ -0,0 +1,120 @@
+package eventbus
+
+import "time"
+
+// Trading topics
+var (
+	MarketDataUpdated = NewTopic[MarketDataEvent]("market_data_updated")
+	SignalGenerated   = NewTopic[SignalEvent]("signal_generated")
+	OrderFilled       = NewTopic[OrderFilledEvent]("order_filled")
+	OrderExpired      = NewTopic[OrderExpiredEvent]("order_expired")
+	PositionClosed    = NewTopic[PositionClosedEvent]("position_closed")
+	RiskViolation     = NewTopic[RiskViolationEvent]("risk_violation")
+	AnalysisCompleted = NewTopic[AnalysisEvent]("analysis_completed")
+)
+
+// MarketDataEvent is a new price of a symbol
+type MarketDataEvent struct {
+	Symbol    string    `json:"symbol"`
+	Price     float64   `json:"price"`
+	Volume    float64   `json:"volume,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// SignalEvent is a trade signal generated by a strategy
+type SignalEvent struct {
+	Strategy string  `json:"strategy"`
+	Symbol   string  `json:"symbol"`
+	Side     string  `json:"side"`
+	Size     float64 `json:"size"`
+	// Price is the limit price, zero for market orders
+	Price  float64 `json:"price,omitempty"`
+	Reason string  `json:"reason,omitempty"`
+	// CorrelationID identifies the signal in the logs and events of the
+	// orders and risk checks it causes
+	CorrelationID string    `json:"correlation_id,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// OrderFilledEvent is a fill on an order
+type OrderFilledEvent struct {
+	OrderID       string  `json:"order_id"`
+	ClientOrderID string  `json:"client_order_id,omitempty"`
+	Symbol        string  `json:"symbol"`
+	Side          string  `json:"side"`
+	Price         float64 `json:"price,omitempty"`
+	// Size is the size of this fill
+	Size          float64 `json:"size"`
+	FilledSize    float64 `json:"filled_size"`
+	RemainingSize float64 `json:"remaining_size"`
+	// Fee is the trading fee of this fill, negative for a rebate
+	Fee float64 `json:"fee,omitempty"`
+	// Venue is the exchange or DEX of the fill, such as dydx or solana
+	Venue string `json:"venue,omitempty"`
+	// AccountID is the trading account of the order
+	AccountID     string    `json:"account_id,omitempty"`
+	CorrelationID string    `json:"correlation_id,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// OrderExpiredEvent is an order that expired before it was completely
+// filled
+type OrderExpiredEvent struct {
+	OrderID         string  `json:"order_id"`
+	ClientOrderID   string  `json:"client_order_id,omitempty"`
+	ExchangeOrderID string  `json:"exchange_order_id,omitempty"`
+	Symbol          string  `json:"symbol"`
+	Side            string  `json:"side"`
+	Size            float64 `json:"size"`
+	FilledSize      float64 `json:"filled_size"`
+	// ExpiresAt is the expiry of the order, nil when it expired because the
+	// venue did not acknowledge it in time
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	AccountID     string     `json:"account_id,omitempty"`
+	CorrelationID string     `json:"correlation_id,omitempty"`
+	Timestamp     time.Time  `json:"timestamp"`
+}
+
+// PositionClosedEvent is a position closed or liquidated
+type PositionClosedEvent struct {
+	PositionID  string  `json:"position_id"`
+	Symbol      string  `json:"symbol"`
+	Side        string  `json:"side"`
+	Size        float64 `json:"size"`
+	EntryPrice  float64 `json:"entry_price"`
+	ExitPrice   float64 `json:"exit_price"`
+	RealizedPnL float64 `json:"realized_pnl"`
+	Funding     float64 `json:"funding,omitempty"`
+	Fees        float64 `json:"fees,omitempty"`
+	// NetPnL is the realized profit or loss with funding and net of fees
+	NetPnL     float64   `json:"net_pnl"`
+	Liquidated bool      `json:"liquidated,omitempty"`
+	AccountID  string    `json:"account_id,omitempty"`
+	OpenedAt   time.Time `json:"opened_at"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// RiskViolationEvent is a risk check that failed
+type RiskViolationEvent struct {
+	CheckID       string    `json:"check_id"`
+	Type          string    `json:"type"`
+	Level         string    `json:"level"`
+	Symbol        string    `json:"symbol,omitempty"`
+	Value         float64   `json:"value"`
+	Threshold     float64   `json:"threshold"`
+	Description   string    `json:"description"`
+	AccountID     string    `json:"account_id,omitempty"`
+	CorrelationID string    `json:"correlation_id,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// AnalysisEvent is the result of an LLM market analysis
+type AnalysisEvent struct {
+	Model  string `json:"model"`
+	Symbol string `json:"symbol"`
+	// Action is the recommended action, such as buy, sell or hold
+	Action     string    `json:"action"`
+	Confidence float64   `json:"confidence"`
+	Summary    string    `json:"summary,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
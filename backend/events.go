@@ -0,0 +1,119 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "log/slog"
+    "time"
+
+    "github.com/devinjacknz/godydxhyber/backend/config"
+    "github.com/devinjacknz/godydxhyber/backend/eventbus"
+    "github.com/devinjacknz/godydxhyber/backend/eventbus/bridge"
+    "github.com/devinjacknz/godydxhyber/backend/logger"
+    "github.com/devinjacknz/godydxhyber/backend/trading/order"
+    "github.com/devinjacknz/godydxhyber/backend/trading/position"
+    "github.com/devinjacknz/godydxhyber/backend/trading/risk"
+    "github.com/devinjacknz/godydxhyber/backend/trading/routing"
+)
+
+// publishEvent publishes an event from a component listener, which has no
+// caller to return subscriber errors to. Subscribers receive a context
+// carrying the correlation ID of the event.
+func publishEvent[T any](bus *eventbus.Bus, topic eventbus.Topic[T], correlationID string, event T) {
+    ctx := logger.WithCorrelationID(context.Background(), correlationID)
+    if err := eventbus.Publish(ctx, bus, topic, event); err != nil {
+        slog.ErrorContext(ctx, "failed to publish event", "topic", topic.Name(), "error", err)
+    }
+}
+
+func orderFilledEvent(o *order.Order, fill, fee float64) eventbus.OrderFilledEvent {
+    e := eventbus.OrderFilledEvent{
+        OrderID:       o.ID,
+        ClientOrderID: o.ClientOrderID,
+        Venue:         string(routing.VenueDydx),
+        AccountID:     o.AccountID,
+        Symbol:        o.Symbol,
+        Side:          o.Side.String(),
+        Size:          fill,
+        FilledSize:    o.FilledSize.Float64(),
+        RemainingSize: o.RemainingSize.Float64(),
+        Fee:           fee,
+        CorrelationID: o.CorrelationID,
+        Timestamp:     o.UpdatedAt,
+    }
+    if o.Price != nil {
+        e.Price = o.Price.Float64()
+    }
+    return e
+}
+
+func orderExpiredEvent(o *order.Order) eventbus.OrderExpiredEvent {
+    return eventbus.OrderExpiredEvent{
+        OrderID:         o.ID,
+        ClientOrderID:   o.ClientOrderID,
+        ExchangeOrderID: o.ExchangeOrderID,
+        Symbol:          o.Symbol,
+        Side:            o.Side.String(),
+        Size:            o.Size.Float64(),
+        FilledSize:      o.FilledSize.Float64(),
+        AccountID:       o.AccountID,
+        ExpiresAt:       o.ExpiresAt,
+        CorrelationID:   o.CorrelationID,
+        Timestamp:       o.UpdatedAt,
+    }
+}
+
+func positionClosedEvent(p *position.Position) eventbus.PositionClosedEvent {
+    return eventbus.PositionClosedEvent{
+        PositionID:  p.ID,
+        Symbol:      p.Symbol,
+        Side:        p.Side.String(),
+        Size:        p.Size.Float64(),
+        EntryPrice:  p.EntryPrice.Float64(),
+        ExitPrice:   p.CurrentPrice.Float64(),
+        RealizedPnL: p.RealizedPnL.Float64(),
+        Funding:     p.FundingAccrual.Float64(),
+        Fees:        p.Fees.Float64(),
+        NetPnL:      p.NetPnL().Float64(),
+        Liquidated:  p.Status == position.Liquidated,
+        AccountID:   p.AccountID,
+        OpenedAt:    p.OpenTime,
+        Timestamp:   p.LastUpdateTime,
+    }
+}
+
+func riskViolationEvent(check *risk.RiskCheck) eventbus.RiskViolationEvent {
+    e := eventbus.RiskViolationEvent{
+        CheckID:       check.ID,
+        Type:          check.Type.String(),
+        Level:         check.Level.String(),
+        Symbol:        check.Symbol,
+        Value:         check.Value,
+        Threshold:     check.Threshold,
+        Description:   check.Description,
+        AccountID:     check.AccountID,
+        CorrelationID: check.CorrelationID,
+        Timestamp:     check.CreatedAt,
+    }
+    if e.Timestamp.IsZero() {
+        e.Timestamp = time.Now()
+    }
+    return e
+}
+
+// newBridgeTransport connects to the broker of the event bridge
+func newBridgeTransport(ctx context.Context, cfg config.BridgeConfig) (bridge.Transport, error) {
+    switch cfg.Driver {
+    case config.BridgeNATS:
+        ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+        defer cancel()
+        return bridge.DialNATS(ctx, cfg.URL)
+    case config.BridgeKafka:
+        return bridge.NewKafkaTransport(bridge.KafkaConfig{
+            URL:           cfg.URL,
+            ConsumerGroup: cfg.ConsumerGroup,
+        })
+    default:
+        return nil, fmt.Errorf("unknown event bridge driver %q", cfg.Driver)
+    }
+}
@@ -5,15 +5,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 	"time"
 )
 
 // GetAccount retrieves account information
 func (c *DefaultClient) GetAccount(ctx context.Context) (*Account, error) {
-	if err := c.limiter.Wait(ctx); err != nil {
-		return nil, err
-	}
-
 	url := fmt.Sprintf("%s/v3/accounts", c.baseURL)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -21,14 +19,9 @@ func (c *DefaultClient) GetAccount(ctx context.Context) (*Account, error) {
 	}
 
 	// Add authentication headers
-	timestamp := fmt.Sprintf("%d", time.Now().Unix())
-	signature := c.sign(timestamp)
-	req.Header.Set("DYDX-SIGNATURE", signature)
-	req.Header.Set("DYDX-API-KEY", c.apiKey)
-	req.Header.Set("DYDX-TIMESTAMP", timestamp)
-	req.Header.Set("DYDX-PASSPHRASE", c.passphrase)
-
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.doSigned(ctx, req, func(timestamp string) string {
+		return timestamp
+	})
 	if err != nil {
 		return nil, fmt.Errorf("do request error: %w", err)
 	}
@@ -61,10 +54,6 @@ func (c *DefaultClient) GetAccount(ctx context.Context) (*Account, error) {
 
 // GetBalance retrieves account balance
 func (c *DefaultClient) GetBalance(ctx context.Context) (*Balance, error) {
-	if err := c.limiter.Wait(ctx); err != nil {
-		return nil, err
-	}
-
 	url := fmt.Sprintf("%s/v3/accounts/balance", c.baseURL)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -72,14 +61,9 @@ func (c *DefaultClient) GetBalance(ctx context.Context) (*Balance, error) {
 	}
 
 	// Add authentication headers
-	timestamp := fmt.Sprintf("%d", time.Now().Unix())
-	signature := c.sign(timestamp)
-	req.Header.Set("DYDX-SIGNATURE", signature)
-	req.Header.Set("DYDX-API-KEY", c.apiKey)
-	req.Header.Set("DYDX-TIMESTAMP", timestamp)
-	req.Header.Set("DYDX-PASSPHRASE", c.passphrase)
-
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.doSigned(ctx, req, func(timestamp string) string {
+		return timestamp
+	})
 	if err != nil {
 		return nil, fmt.Errorf("do request error: %w", err)
 	}
@@ -110,6 +94,90 @@ func (c *DefaultClient) GetBalance(ctx context.Context) (*Balance, error) {
 	return &response.Balance, nil
 }
 
+// fundingPageSize is the maximum number of funding payments per request
+const fundingPageSize = 100
+
+// GetFundingPayments retrieves the account's funding payments effective at
+// or after since, newest first
+func (c *DefaultClient) GetFundingPayments(ctx context.Context, since time.Time) ([]FundingPayment, error) {
+	var payments []FundingPayment
+	// Pages are requested from the time of the last payment of the previous
+	// page inclusive, since payments of several markets share their time
+	seen := make(map[string]bool)
+	before := ""
+	for {
+		page, err := c.getFundingPage(ctx, before)
+		if err != nil {
+			return nil, err
+		}
+		added := 0
+		for _, payment := range page {
+			if payment.EffectiveAt.Before(since) {
+				return payments, nil
+			}
+			key := payment.Market + "/" + payment.EffectiveAt.String()
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			payments = append(payments, payment)
+			added++
+		}
+		if len(page) < fundingPageSize || added == 0 {
+			return payments, nil
+		}
+		before = page[len(page)-1].EffectiveAt.UTC().Format(time.RFC3339Nano)
+	}
+}
+
+// getFundingPage retrieves a page of funding payments effective before or
+// at the given RFC 3339 time, or the latest ones if it is empty
+func (c *DefaultClient) getFundingPage(ctx context.Context, beforeOrAt string) ([]FundingPayment, error) {
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(fundingPageSize))
+	if beforeOrAt != "" {
+		query.Set("effectiveBeforeOrAt", beforeOrAt)
+	}
+	endpoint := fmt.Sprintf("%s/v3/funding?%s", c.baseURL, query.Encode())
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request error: %w", err)
+	}
+
+	// Add authentication headers
+	resp, err := c.doSigned(ctx, req, func(timestamp string) string {
+		return timestamp
+	})
+	if err != nil {
+		return nil, fmt.Errorf("do request error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp struct {
+			Errors []struct {
+				Msg string `json:"msg"`
+			} `json:"errors"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil {
+			return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		}
+		if len(errResp.Errors) > 0 {
+			return nil, fmt.Errorf("get funding payments failed: %s", errResp.Errors[0].Msg)
+		}
+		return nil, fmt.Errorf("get funding payments failed with status code: %d", resp.StatusCode)
+	}
+
+	var response struct {
+		FundingPayments []FundingPayment `json:"fundingPayments"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("decode response error: %w", err)
+	}
+
+	return response.FundingPayments, nil
+}
+
 // Account status types
 const (
 	AccountStatusActive     = "ACTIVE"
@@ -3,13 +3,14 @@ package dydx
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/devinjacknz/godydxhyber/backend/pkg/httpx"
 	"github.com/gorilla/websocket"
-	"golang.org/x/time/rate"
 )
 
 // Client defines the interface for interacting with dYdX
@@ -32,6 +33,8 @@ type Client interface {
 	GetAccount(ctx context.Context) (*Account, error)
 	GetLeverage(ctx context.Context, symbol string) (int, error)
 	SetLeverage(ctx context.Context, symbol string, leverage int) error
+	GetFills(ctx context.Context, since time.Time) ([]Fill, error)
+	GetFundingPayments(ctx context.Context, since time.Time) ([]FundingPayment, error)
 
 	// WebSocket
 	SubscribeOrderbook(symbol string, ch chan<- Orderbook) error
@@ -50,9 +53,14 @@ type DefaultClient struct {
 	apiKey     string
 	apiSecret  string
 	passphrase string
-	httpClient *http.Client
+	httpClient *httpx.Client
 	wsConn     *websocket.Conn
-	limiter    *rate.Limiter
+	// connected is set while the WebSocket connection is authenticated and
+	// read
+	connected atomic.Bool
+	// rps and burst limit the requests to the REST API host
+	rps   float64
+	burst int
 
 	// Subscriptions
 	subscriptions map[string][]chan interface{}
@@ -61,29 +69,41 @@ type DefaultClient struct {
 	// Connection management
 	done      chan struct{}
 	reconnect chan struct{}
+
+	// clock corrects the timestamps of signed requests
+	clock clock
 }
 
 // ClientOption defines options for creating a new client
 type ClientOption func(*DefaultClient)
 
+// Default endpoints of the v3 API
+const (
+	DefaultBaseURL = "https://api.dydx.exchange"
+	DefaultWSURL   = "wss://api.dydx.exchange/v3/ws"
+)
+
 // NewClient creates a new dYdX client
 func NewClient(apiKey, apiSecret, passphrase string, opts ...ClientOption) Client {
 	client := &DefaultClient{
-		baseURL:       "https://api.dydx.exchange",
-		wsURL:         "wss://api.dydx.exchange/v3/ws",
+		baseURL:       DefaultBaseURL,
+		wsURL:         DefaultWSURL,
 		apiKey:        apiKey,
 		apiSecret:     apiSecret,
 		passphrase:    passphrase,
-		httpClient:    &http.Client{Timeout: 10 * time.Second},
-		limiter:       rate.NewLimiter(rate.Limit(5), 10), // 5 requests/second, burst of 10
+		rps:           5, // 5 requests/second, burst of 10
+		burst:         10,
 		subscriptions: make(map[string][]chan interface{}),
 		done:          make(chan struct{}),
 		reconnect:     make(chan struct{}, 1),
+		clock:         clock{interval: 5 * time.Minute, tolerance: time.Minute},
 	}
 
 	for _, opt := range opts {
 		opt(client)
 	}
+	client.httpClient = httpx.New(httpx.Config{Name: "dydx"})
+	client.httpClient.Limits().Set(httpx.Host(client.baseURL), client.rps, client.burst)
 
 	go client.maintainConnection()
 	return client
@@ -103,10 +123,13 @@ func WithWSURL(url string) ClientOption {
 	}
 }
 
-// WithRateLimit sets the rate limiter configuration
+// WithRateLimit overrides the limit of requests to the v3 REST API, 5 per
+// second with bursts of 10 by default. Signed requests count toward the same
+// limit as market data.
 func WithRateLimit(rps float64, burst int) ClientOption {
 	return func(c *DefaultClient) {
-		c.limiter = rate.NewLimiter(rate.Limit(rps), burst)
+		c.rps = rps
+		c.burst = burst
 	}
 }
 
@@ -123,7 +146,10 @@ func (c *DefaultClient) maintainConnection() {
 				c.reconnect <- struct{}{}
 			}
 		case <-c.reconnect:
-			c.wsConn.Close()
+			c.connected.Store(false)
+			if c.wsConn != nil {
+				c.wsConn.Close()
+			}
 			if err := c.connect(); err != nil {
 				time.Sleep(time.Second * 5)
 				c.reconnect <- struct{}{}
@@ -145,12 +171,13 @@ func (c *DefaultClient) connect() error {
 		return fmt.Errorf("websocket authentication error: %w", err)
 	}
 
+	c.connected.Store(true)
 	go c.readPump()
 	return nil
 }
 
 func (c *DefaultClient) authenticate() error {
-	timestamp := time.Now().Unix()
+	timestamp := c.now().Unix()
 	signature := c.sign(fmt.Sprintf("%d", timestamp))
 
 	msg := struct {
@@ -174,6 +201,7 @@ func (c *DefaultClient) readPump() {
 	for {
 		_, message, err := c.wsConn.ReadMessage()
 		if err != nil {
+			c.connected.Store(false)
 			c.reconnect <- struct{}{}
 			return
 		}
@@ -203,7 +231,15 @@ func (c *DefaultClient) readPump() {
 	}
 }
 
+// Connected reports whether the WebSocket feed is connected
+func (c *DefaultClient) Connected() bool {
+	return c.connected.Load()
+}
+
 func (c *DefaultClient) ping() error {
+	if !c.connected.Load() {
+		return errors.New("websocket not connected")
+	}
 	return c.wsConn.WriteMessage(websocket.PingMessage, nil)
 }
 
@@ -229,6 +265,10 @@ func (c *DefaultClient) subscribe(topic string, ch interface{}) error {
 // Close closes the client and all connections
 func (c *DefaultClient) Close() error {
 	close(c.done)
+	c.connected.Store(false)
+	if c.wsConn == nil {
+		return nil
+	}
 	return c.wsConn.Close()
 }
 
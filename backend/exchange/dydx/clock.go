@@ -0,0 +1,186 @@
+package dydx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrClockSkew is returned by signed requests when the local clock is off
+// from the exchange by more than the tolerance
+var ErrClockSkew = errors.New("clock skew exceeds tolerance")
+
+// ClockSkewError reports the offset of the exchange clock from the local
+// clock. It matches ErrClockSkew.
+type ClockSkewError struct {
+	// Offset is the server time minus the local time
+	Offset    time.Duration
+	Tolerance time.Duration
+}
+
+func (e *ClockSkewError) Error() string {
+	return fmt.Sprintf("local clock is %s off from the dYdX server, more than the %s tolerance; fix the system clock", e.Offset, e.Tolerance)
+}
+
+// Unwrap returns ErrClockSkew
+func (e *ClockSkewError) Unwrap() error {
+	return ErrClockSkew
+}
+
+// clock tracks the offset of the server clock and the nonces of signed
+// requests
+type clock struct {
+	mu sync.Mutex
+	// interval is the time between synchronizations; zero disables them
+	interval  time.Duration
+	tolerance time.Duration
+	offset    time.Duration
+	// synced is when the offset was last measured or attempted
+	synced    time.Time
+	measured  bool
+	lastNonce int64
+}
+
+// WithClockSync sets how often the server time is fetched and the largest
+// offset the client corrects; larger offsets fail signed requests with a
+// ClockSkewError. An interval of zero disables synchronization.
+func WithClockSync(interval, tolerance time.Duration) ClientOption {
+	return func(c *DefaultClient) {
+		c.clock.interval = interval
+		c.clock.tolerance = tolerance
+	}
+}
+
+// ClockOffset returns the last measured offset of the server clock from the
+// local clock
+func (c *DefaultClient) ClockOffset() time.Duration {
+	c.clock.mu.Lock()
+	defer c.clock.mu.Unlock()
+	return c.clock.offset
+}
+
+// SyncTime measures the offset of the server clock from the local clock.
+// The offset is applied to the timestamps of signed requests unless it
+// exceeds the tolerance, in which case a ClockSkewError is returned.
+func (c *DefaultClient) SyncTime(ctx context.Context) error {
+	url := fmt.Sprintf("%s/v3/time", c.baseURL)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("create request error: %w", err)
+	}
+
+	sent := time.Now()
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("do request error: %w", err)
+	}
+	defer resp.Body.Close()
+	received := time.Now()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("get time failed with status code: %d", resp.StatusCode)
+	}
+
+	var response struct {
+		ISO   time.Time `json:"iso"`
+		Epoch float64   `json:"epoch"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("decode response error: %w", err)
+	}
+	server := response.ISO
+	if server.IsZero() {
+		server = time.UnixMilli(int64(response.Epoch * 1000))
+	}
+
+	// The server read its clock about halfway through the round trip
+	offset := server.Sub(sent.Add(received.Sub(sent) / 2))
+
+	c.clock.mu.Lock()
+	defer c.clock.mu.Unlock()
+	c.clock.offset = offset
+	c.clock.synced = received
+	c.clock.measured = true
+	return c.clock.check()
+}
+
+// check returns a ClockSkewError if the offset exceeds the tolerance
+func (k *clock) check() error {
+	if k.tolerance > 0 && (k.offset > k.tolerance || k.offset < -k.tolerance) {
+		return &ClockSkewError{Offset: k.offset, Tolerance: k.tolerance}
+	}
+	return nil
+}
+
+// now returns the local time corrected by the last measured offset
+func (c *DefaultClient) now() time.Time {
+	c.clock.mu.Lock()
+	defer c.clock.mu.Unlock()
+	return time.Now().Add(c.clock.offset)
+}
+
+// timestamp returns the corrected time and a fresh nonce for a signed
+// request, synchronizing with the server first when the offset is stale. A
+// failed synchronization keeps the previous offset and is retried after the
+// interval.
+func (c *DefaultClient) timestamp(ctx context.Context) (time.Time, string, error) {
+	c.clock.mu.Lock()
+	stale := c.clock.interval > 0 && time.Since(c.clock.synced) >= c.clock.interval
+	if stale {
+		c.clock.synced = time.Now()
+	}
+	c.clock.mu.Unlock()
+
+	if stale {
+		if err := c.SyncTime(ctx); errors.Is(err, ErrClockSkew) {
+			return time.Time{}, "", err
+		}
+	}
+
+	c.clock.mu.Lock()
+	defer c.clock.mu.Unlock()
+	if err := c.clock.check(); err != nil {
+		return time.Time{}, "", err
+	}
+	now := time.Now().Add(c.clock.offset)
+
+	// Nonces strictly increase, so a signed request sent twice is rejected
+	// as a replay even within the same timestamp
+	nonce := now.UnixNano()
+	if nonce <= c.clock.lastNonce {
+		nonce = c.clock.lastNonce + 1
+	}
+	c.clock.lastNonce = nonce
+	return now, strconv.FormatInt(nonce, 10), nil
+}
+
+// doSigned sends a request with the authentication headers, signing each
+// attempt again so that retries are not rejected as replays. message builds
+// the signed payload from the timestamp.
+func (c *DefaultClient) doSigned(ctx context.Context, req *http.Request, message func(timestamp string) string) (*http.Response, error) {
+	return c.httpClient.DoSigned(req, func(req *http.Request) error {
+		return c.signRequest(ctx, req, message)
+	})
+}
+
+// signRequest sets the authentication headers of a request. message builds
+// the signed payload from the timestamp; the nonce is appended to it.
+func (c *DefaultClient) signRequest(ctx context.Context, req *http.Request, message func(timestamp string) string) error {
+	now, nonce, err := c.timestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	timestamp := fmt.Sprintf("%d", now.Unix())
+	req.Header.Set("DYDX-SIGNATURE", c.sign(message(timestamp)+nonce))
+	req.Header.Set("DYDX-API-KEY", c.apiKey)
+	req.Header.Set("DYDX-TIMESTAMP", timestamp)
+	req.Header.Set("DYDX-NONCE", nonce)
+	req.Header.Set("DYDX-PASSPHRASE", c.passphrase)
+	return nil
+}
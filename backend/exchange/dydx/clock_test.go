@@ -0,0 +1,95 @@
+package dydx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/devinjacknz/godydxhyber/backend/pkg/httpx"
+)
+
+func newClockClient(t *testing.T, skew time.Duration) (*DefaultClient, *int32) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		fmt.Fprintf(w, `{"iso":%q}`, time.Now().Add(skew).UTC().Format(time.RFC3339Nano))
+	}))
+	t.Cleanup(server.Close)
+
+	return &DefaultClient{
+		baseURL:    server.URL,
+		httpClient: httpx.New(httpx.Config{Limits: httpx.NewLimits()}),
+		clock:      clock{interval: time.Hour, tolerance: time.Minute},
+	}, &calls
+}
+
+func TestSignedRequestsUseServerTime(t *testing.T) {
+	client, calls := newClockClient(t, 20*time.Second)
+	ctx := context.Background()
+
+	req := httptest.NewRequest("GET", "/v3/accounts", nil)
+	require.NoError(t, client.signRequest(ctx, req, func(timestamp string) string { return timestamp }))
+	assert.InDelta(t, 20*time.Second, client.ClockOffset(), float64(time.Second))
+	assert.InDelta(t, time.Now().Add(20*time.Second).Unix(), mustInt(t, req.Header.Get("DYDX-TIMESTAMP")), 1)
+
+	// The offset is reused until the interval elapses and nonces increase
+	first := req.Header.Get("DYDX-NONCE")
+	require.NoError(t, client.signRequest(ctx, req, func(timestamp string) string { return timestamp }))
+	assert.Greater(t, mustInt(t, req.Header.Get("DYDX-NONCE")), mustInt(t, first))
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+}
+
+func TestRetriesAreSignedAgain(t *testing.T) {
+	var nonces []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v3/time" {
+			fmt.Fprintf(w, `{"iso":%q}`, time.Now().UTC().Format(time.RFC3339Nano))
+			return
+		}
+		nonces = append(nonces, r.Header.Get("DYDX-NONCE"))
+		if len(nonces) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+	client := &DefaultClient{
+		baseURL:    server.URL,
+		httpClient: httpx.New(httpx.Config{Limits: httpx.NewLimits(), Budget: httpx.NewBudget(0.2, 1)}),
+		clock:      clock{interval: time.Hour, tolerance: time.Minute},
+	}
+
+	req, err := http.NewRequest("GET", server.URL+"/v3/accounts", nil)
+	require.NoError(t, err)
+	resp, err := client.doSigned(context.Background(), req, func(timestamp string) string { return timestamp })
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Len(t, nonces, 2)
+	assert.Greater(t, mustInt(t, nonces[1]), mustInt(t, nonces[0]))
+}
+
+func TestClockSkewBeyondTolerance(t *testing.T) {
+	client, _ := newClockClient(t, -5*time.Minute)
+
+	err := client.signRequest(context.Background(), httptest.NewRequest("GET", "/", nil), func(timestamp string) string { return timestamp })
+	require.ErrorIs(t, err, ErrClockSkew)
+
+	var skew *ClockSkewError
+	require.ErrorAs(t, err, &skew)
+	assert.InDelta(t, -5*time.Minute, skew.Offset, float64(time.Second))
+	assert.Equal(t, time.Minute, skew.Tolerance)
+}
+
+func mustInt(t *testing.T, s string) int64 {
+	var n int64
+	_, err := fmt.Sscan(s, &n)
+	require.NoError(t, err)
+	return n
+}
@@ -10,10 +10,6 @@ import (
 
 // GetMarkets retrieves all available markets
 func (c *DefaultClient) GetMarkets(ctx context.Context) ([]Market, error) {
-	if err := c.limiter.Wait(ctx); err != nil {
-		return nil, err
-	}
-
 	url := fmt.Sprintf("%s/v3/markets", c.baseURL)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -47,10 +43,6 @@ func (c *DefaultClient) GetMarkets(ctx context.Context) ([]Market, error) {
 
 // GetOrderbook retrieves the current order book for a market
 func (c *DefaultClient) GetOrderbook(ctx context.Context, symbol string) (*Orderbook, error) {
-	if err := c.limiter.Wait(ctx); err != nil {
-		return nil, err
-	}
-
 	url := fmt.Sprintf("%s/v3/orderbook/%s", c.baseURL, symbol)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -118,10 +110,6 @@ func (c *DefaultClient) GetOrderbook(ctx context.Context, symbol string) (*Order
 
 // GetTrades retrieves recent trades for a market
 func (c *DefaultClient) GetTrades(ctx context.Context, symbol string, limit int) ([]Trade, error) {
-	if err := c.limiter.Wait(ctx); err != nil {
-		return nil, err
-	}
-
 	url := fmt.Sprintf("%s/v3/trades/%s?limit=%d", c.baseURL, symbol, limit)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -150,10 +138,6 @@ func (c *DefaultClient) GetTrades(ctx context.Context, symbol string, limit int)
 
 // GetFundingRate retrieves the current funding rate for a market
 func (c *DefaultClient) GetFundingRate(ctx context.Context, symbol string) (*FundingRate, error) {
-	if err := c.limiter.Wait(ctx); err != nil {
-		return nil, err
-	}
-
 	url := fmt.Sprintf("%s/v3/funding-rates/%s/current", c.baseURL, symbol)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -6,15 +6,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"time"
 )
 
 // GetPositions retrieves all positions
 func (c *DefaultClient) GetPositions(ctx context.Context) ([]Position, error) {
-	if err := c.limiter.Wait(ctx); err != nil {
-		return nil, err
-	}
-
 	url := fmt.Sprintf("%s/v3/positions", c.baseURL)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -22,14 +17,9 @@ func (c *DefaultClient) GetPositions(ctx context.Context) ([]Position, error) {
 	}
 
 	// Add authentication headers
-	timestamp := fmt.Sprintf("%d", time.Now().Unix())
-	signature := c.sign(timestamp)
-	req.Header.Set("DYDX-SIGNATURE", signature)
-	req.Header.Set("DYDX-API-KEY", c.apiKey)
-	req.Header.Set("DYDX-TIMESTAMP", timestamp)
-	req.Header.Set("DYDX-PASSPHRASE", c.passphrase)
-
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.doSigned(ctx, req, func(timestamp string) string {
+		return timestamp
+	})
 	if err != nil {
 		return nil, fmt.Errorf("do request error: %w", err)
 	}
@@ -62,10 +52,6 @@ func (c *DefaultClient) GetPositions(ctx context.Context) ([]Position, error) {
 
 // GetLeverage retrieves current leverage for a symbol
 func (c *DefaultClient) GetLeverage(ctx context.Context, symbol string) (int, error) {
-	if err := c.limiter.Wait(ctx); err != nil {
-		return 0, err
-	}
-
 	url := fmt.Sprintf("%s/v3/configs/leverage/%s", c.baseURL, symbol)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -73,14 +59,9 @@ func (c *DefaultClient) GetLeverage(ctx context.Context, symbol string) (int, er
 	}
 
 	// Add authentication headers
-	timestamp := fmt.Sprintf("%d", time.Now().Unix())
-	signature := c.sign(fmt.Sprintf("%s%s", symbol, timestamp))
-	req.Header.Set("DYDX-SIGNATURE", signature)
-	req.Header.Set("DYDX-API-KEY", c.apiKey)
-	req.Header.Set("DYDX-TIMESTAMP", timestamp)
-	req.Header.Set("DYDX-PASSPHRASE", c.passphrase)
-
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.doSigned(ctx, req, func(timestamp string) string {
+		return fmt.Sprintf("%s%s", symbol, timestamp)
+	})
 	if err != nil {
 		return 0, fmt.Errorf("do request error: %w", err)
 	}
@@ -113,10 +94,6 @@ func (c *DefaultClient) GetLeverage(ctx context.Context, symbol string) (int, er
 
 // SetLeverage sets leverage for a symbol
 func (c *DefaultClient) SetLeverage(ctx context.Context, symbol string, leverage int) error {
-	if err := c.limiter.Wait(ctx); err != nil {
-		return err
-	}
-
 	body, err := json.Marshal(struct {
 		Leverage int `json:"leverage"`
 	}{
@@ -132,16 +109,12 @@ func (c *DefaultClient) SetLeverage(ctx context.Context, symbol string, leverage
 		return fmt.Errorf("create request error: %w", err)
 	}
 
-	// Add authentication headers
-	timestamp := fmt.Sprintf("%d", time.Now().Unix())
-	signature := c.sign(fmt.Sprintf("%s%d%s", symbol, leverage, timestamp))
-	req.Header.Set("DYDX-SIGNATURE", signature)
-	req.Header.Set("DYDX-API-KEY", c.apiKey)
-	req.Header.Set("DYDX-TIMESTAMP", timestamp)
-	req.Header.Set("DYDX-PASSPHRASE", c.passphrase)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.httpClient.Do(req)
+	// Add authentication headers
+	resp, err := c.doSigned(ctx, req, func(timestamp string) string {
+		return fmt.Sprintf("%s%d%s", symbol, leverage, timestamp)
+	})
 	if err != nil {
 		return fmt.Errorf("do request error: %w", err)
 	}
@@ -9,24 +9,9 @@ import (
 	"time"
 )
 
-// CreateOrder creates a new order
+// CreateOrder creates a new order. When an order with the same client ID
+// already exists, the request is a retry and the existing order is returned.
 func (c *DefaultClient) CreateOrder(ctx context.Context, req CreateOrderRequest) (*Order, error) {
-	if err := c.limiter.Wait(ctx); err != nil {
-		return nil, err
-	}
-
-	// Generate signature
-	timestamp := fmt.Sprintf("%d", time.Now().Unix())
-	signature := c.sign(fmt.Sprintf("%s%s%s%s", req.Market, req.Side, timestamp, req.Type))
-
-	// Add authentication headers
-	headers := map[string]string{
-		"DYDX-SIGNATURE":  signature,
-		"DYDX-API-KEY":    c.apiKey,
-		"DYDX-TIMESTAMP":  timestamp,
-		"DYDX-PASSPHRASE": c.passphrase,
-	}
-
 	body, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("marshal request error: %w", err)
@@ -38,12 +23,12 @@ func (c *DefaultClient) CreateOrder(ctx context.Context, req CreateOrderRequest)
 		return nil, fmt.Errorf("create request error: %w", err)
 	}
 
-	for k, v := range headers {
-		httpReq.Header.Set(k, v)
-	}
 	httpReq.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.httpClient.Do(httpReq)
+	// Add authentication headers
+	resp, err := c.doSigned(ctx, httpReq, func(timestamp string) string {
+		return fmt.Sprintf("%s%s%s%s", req.Market, req.Side, timestamp, req.Type)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("do request error: %w", err)
 	}
@@ -53,6 +38,10 @@ func (c *DefaultClient) CreateOrder(ctx context.Context, req CreateOrderRequest)
 		return nil, fmt.Errorf("rate limit exceeded")
 	}
 
+	if resp.StatusCode == http.StatusConflict && req.ClientID != "" {
+		return c.getOrderByClientID(ctx, req.ClientID)
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		var errResp struct {
 			Errors []struct {
@@ -80,33 +69,16 @@ func (c *DefaultClient) CreateOrder(ctx context.Context, req CreateOrderRequest)
 
 // CancelOrder cancels an existing order
 func (c *DefaultClient) CancelOrder(ctx context.Context, orderID string) error {
-	if err := c.limiter.Wait(ctx); err != nil {
-		return err
-	}
-
-	// Generate signature
-	timestamp := fmt.Sprintf("%d", time.Now().Unix())
-	signature := c.sign(fmt.Sprintf("%s%s", orderID, timestamp))
-
-	// Add authentication headers
-	headers := map[string]string{
-		"DYDX-SIGNATURE":  signature,
-		"DYDX-API-KEY":    c.apiKey,
-		"DYDX-TIMESTAMP":  timestamp,
-		"DYDX-PASSPHRASE": c.passphrase,
-	}
-
 	url := fmt.Sprintf("%s/v3/orders/%s", c.baseURL, orderID)
 	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
 	if err != nil {
 		return fmt.Errorf("create request error: %w", err)
 	}
 
-	for k, v := range headers {
-		req.Header.Set(k, v)
-	}
-
-	resp, err := c.httpClient.Do(req)
+	// Add authentication headers
+	resp, err := c.doSigned(ctx, req, func(timestamp string) string {
+		return fmt.Sprintf("%s%s", orderID, timestamp)
+	})
 	if err != nil {
 		return fmt.Errorf("do request error: %w", err)
 	}
@@ -136,10 +108,6 @@ func (c *DefaultClient) CancelOrder(ctx context.Context, orderID string) error {
 
 // GetOrder retrieves an order by ID
 func (c *DefaultClient) GetOrder(ctx context.Context, orderID string) (*Order, error) {
-	if err := c.limiter.Wait(ctx); err != nil {
-		return nil, err
-	}
-
 	url := fmt.Sprintf("%s/v3/orders/%s", c.baseURL, orderID)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -147,14 +115,9 @@ func (c *DefaultClient) GetOrder(ctx context.Context, orderID string) (*Order, e
 	}
 
 	// Add authentication headers
-	timestamp := fmt.Sprintf("%d", time.Now().Unix())
-	signature := c.sign(fmt.Sprintf("%s%s", orderID, timestamp))
-	req.Header.Set("DYDX-SIGNATURE", signature)
-	req.Header.Set("DYDX-API-KEY", c.apiKey)
-	req.Header.Set("DYDX-TIMESTAMP", timestamp)
-	req.Header.Set("DYDX-PASSPHRASE", c.passphrase)
-
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.doSigned(ctx, req, func(timestamp string) string {
+		return fmt.Sprintf("%s%s", orderID, timestamp)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("do request error: %w", err)
 	}
@@ -189,12 +152,43 @@ func (c *DefaultClient) GetOrder(ctx context.Context, orderID string) (*Order, e
 	return &response.Order, nil
 }
 
-// GetOpenOrders retrieves all open orders
-func (c *DefaultClient) GetOpenOrders(ctx context.Context) ([]Order, error) {
-	if err := c.limiter.Wait(ctx); err != nil {
-		return nil, err
+// getOrderByClientID retrieves an order by the client ID it was created with
+func (c *DefaultClient) getOrderByClientID(ctx context.Context, clientID string) (*Order, error) {
+	url := fmt.Sprintf("%s/v3/orders/client/%s", c.baseURL, clientID)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request error: %w", err)
+	}
+
+	// Add authentication headers
+	resp, err := c.doSigned(ctx, req, func(timestamp string) string {
+		return fmt.Sprintf("%s%s", clientID, timestamp)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("do request error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("order not found for client ID: %s", clientID)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get order by client ID failed with status code: %d", resp.StatusCode)
+	}
+
+	var response struct {
+		Order Order `json:"order"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("decode response error: %w", err)
 	}
 
+	return &response.Order, nil
+}
+
+// GetOpenOrders retrieves all open orders
+func (c *DefaultClient) GetOpenOrders(ctx context.Context) ([]Order, error) {
 	url := fmt.Sprintf("%s/v3/orders?status=OPEN", c.baseURL)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -202,14 +196,9 @@ func (c *DefaultClient) GetOpenOrders(ctx context.Context) ([]Order, error) {
 	}
 
 	// Add authentication headers
-	timestamp := fmt.Sprintf("%d", time.Now().Unix())
-	signature := c.sign(timestamp)
-	req.Header.Set("DYDX-SIGNATURE", signature)
-	req.Header.Set("DYDX-API-KEY", c.apiKey)
-	req.Header.Set("DYDX-TIMESTAMP", timestamp)
-	req.Header.Set("DYDX-PASSPHRASE", c.passphrase)
-
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.doSigned(ctx, req, func(timestamp string) string {
+		return timestamp
+	})
 	if err != nil {
 		return nil, fmt.Errorf("do request error: %w", err)
 	}
@@ -239,3 +228,41 @@ func (c *DefaultClient) GetOpenOrders(ctx context.Context) ([]Order, error) {
 
 	return response.Orders, nil
 }
+
+// GetFills retrieves the account's most recent fills, up to 100, created at
+// or after since
+func (c *DefaultClient) GetFills(ctx context.Context, since time.Time) ([]Fill, error) {
+	url := fmt.Sprintf("%s/v3/fills?limit=100", c.baseURL)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request error: %w", err)
+	}
+
+	// Add authentication headers
+	resp, err := c.doSigned(ctx, req, func(timestamp string) string {
+		return timestamp
+	})
+	if err != nil {
+		return nil, fmt.Errorf("do request error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get fills failed with status code: %d", resp.StatusCode)
+	}
+
+	var response struct {
+		Fills []Fill `json:"fills"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("decode response error: %w", err)
+	}
+
+	fills := make([]Fill, 0, len(response.Fills))
+	for _, fill := range response.Fills {
+		if !fill.CreatedAt.Before(since) {
+			fills = append(fills, fill)
+		}
+	}
+	return fills, nil
+}
@@ -51,6 +51,17 @@ type FundingRate struct {
 	Time   time.Time `json:"time"`
 }
 
+// FundingPayment represents a funding payment made or received by a position
+type FundingPayment struct {
+	Market string `json:"market"`
+	// Payment is positive when received and negative when paid
+	Payment      float64   `json:"payment"`
+	Rate         float64   `json:"rate"`
+	PositionSize float64   `json:"positionSize"`
+	Price        float64   `json:"price"`
+	EffectiveAt  time.Time `json:"effectiveAt"`
+}
+
 // Order represents an order
 type Order struct {
 	ID            string    `json:"id"`
@@ -68,6 +79,20 @@ type Order struct {
 	ExpiresAt     time.Time `json:"expiresAt,omitempty"`
 	PostOnly      bool      `json:"postOnly"`
 	ReduceOnly    bool      `json:"reduceOnly"`
+	TimeInForce   string    `json:"timeInForce,omitempty"`
+}
+
+// Fill represents an execution of one of the account's orders
+type Fill struct {
+	ID        string    `json:"id"`
+	OrderID   string    `json:"orderId"`
+	Market    string    `json:"market"`
+	Side      string    `json:"side"`
+	Liquidity string    `json:"liquidity"`
+	Price     float64   `json:"price"`
+	Size      float64   `json:"size"`
+	Fee       float64   `json:"fee"`
+	CreatedAt time.Time `json:"createdAt"`
 }
 
 // CreateOrderRequest represents a request to create an order
@@ -82,6 +107,8 @@ type CreateOrderRequest struct {
 	ReduceOnly   bool    `json:"reduceOnly"`
 	ClientID     string  `json:"clientId"`
 	ExpiresAt    int64   `json:"expiresAt,omitempty"`
+	// TimeInForce is GTT, IOC or FOK, the exchange default when empty
+	TimeInForce string `json:"timeInForce,omitempty"`
 }
 
 // Position represents a position
@@ -136,6 +163,13 @@ const (
 	OrderTypeTakeProfit = "TAKE_PROFIT"
 )
 
+// Constants for time in force
+const (
+	TimeInForceGTT = "GTT"
+	TimeInForceIOC = "IOC"
+	TimeInForceFOK = "FOK"
+)
+
 // Constants for order sides
 const (
 	OrderSideBuy  = "BUY"
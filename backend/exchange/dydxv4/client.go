@@ -0,0 +1,640 @@
+// Package dydxv4 implements dydx.Client on the dYdX v4 protocol. Market and
+// account data are read from the indexer REST API; orders are placed and
+// canceled by transactions on the dYdX chain, which a Broadcaster signs and
+// sends to a validator over gRPC.
+package dydxv4
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/pkg/httpx"
+
+	"github.com/devinjacknz/godydxhyber/backend/exchange/dydx"
+)
+
+// Broadcaster signs order messages with the wallet of the subaccount and
+// broadcasts them to the chain, returning the transaction hash
+type Broadcaster interface {
+	PlaceOrder(ctx context.Context, order PlaceOrder) (string, error)
+	CancelOrder(ctx context.Context, order CancelOrder) (string, error)
+}
+
+// DefaultClient implements dydx.Client on the v4 indexer and chain
+type DefaultClient struct {
+	baseURL    string
+	address    string
+	subaccount uint32
+	httpClient *httpx.Client
+	// rps and burst limit the requests to the REST API host
+	rps         float64
+	burst       int
+	broadcaster Broadcaster
+	// slippage bounds the price of market orders without a price
+	slippage float64
+	// shortTermBlocks is the number of blocks short-term orders live for
+	shortTermBlocks uint32
+	// longTermTTL is the lifetime of limit orders without an expiry
+	longTermTTL time.Duration
+}
+
+// ClientOption defines options for creating a new client
+type ClientOption func(*DefaultClient)
+
+// DefaultBaseURL is the indexer of the dYdX v4 mainnet
+const DefaultBaseURL = "https://indexer.dydx.trade"
+
+// NewClient creates a dYdX v4 client trading the given subaccount of a
+// dydx1... address
+func NewClient(address string, subaccount uint32, opts ...ClientOption) dydx.Client {
+	client := &DefaultClient{
+		baseURL:         DefaultBaseURL,
+		address:         address,
+		subaccount:      subaccount,
+		rps:             5, // 5 requests/second, burst of 10
+		burst:           10,
+		slippage:        0.05,
+		shortTermBlocks: 20,
+		longTermTTL:     28 * 24 * time.Hour,
+	}
+
+	for _, opt := range opts {
+		opt(client)
+	}
+	client.httpClient = httpx.New(httpx.Config{Name: "dydxv4"})
+	client.httpClient.Limits().Set(httpx.Host(client.baseURL), client.rps, client.burst)
+
+	return client
+}
+
+// WithBaseURL sets the base URL of the indexer
+func WithBaseURL(url string) ClientOption {
+	return func(c *DefaultClient) {
+		c.baseURL = url
+	}
+}
+
+// WithRateLimit overrides the limit of requests to the indexer, 5 per second
+// with bursts of 10 by default. Orders go through the broadcaster and are not
+// limited.
+func WithRateLimit(rps float64, burst int) ClientOption {
+	return func(c *DefaultClient) {
+		c.rps = rps
+		c.burst = burst
+	}
+}
+
+// WithBroadcaster sets the broadcaster placing and canceling orders. Without
+// one the client is read-only.
+func WithBroadcaster(b Broadcaster) ClientOption {
+	return func(c *DefaultClient) {
+		c.broadcaster = b
+	}
+}
+
+// WithMarketSlippage sets the worst price of market orders without a price,
+// as a fraction of the oracle price
+func WithMarketSlippage(slippage float64) ClientOption {
+	return func(c *DefaultClient) {
+		c.slippage = slippage
+	}
+}
+
+// get sends a GET request to the indexer and decodes the response into out
+func (c *DefaultClient) get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return fmt.Errorf("create request error: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("do request error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp struct {
+			Errors []struct {
+				Msg string `json:"msg"`
+			} `json:"errors"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&errResp); err == nil && len(errResp.Errors) > 0 {
+			return fmt.Errorf("%s failed: %s", path, errResp.Errors[0].Msg)
+		}
+		return fmt.Errorf("%s failed with status code: %d", path, resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response error: %w", err)
+	}
+	return nil
+}
+
+// subaccountQuery returns the query selecting the subaccount of the client
+func (c *DefaultClient) subaccountQuery() url.Values {
+	return url.Values{
+		"address":          {c.address},
+		"subaccountNumber": {strconv.FormatUint(uint64(c.subaccount), 10)},
+	}
+}
+
+// GetMarkets retrieves all perpetual markets
+func (c *DefaultClient) GetMarkets(ctx context.Context) ([]dydx.Market, error) {
+	markets, err := c.markets(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]dydx.Market, 0, len(markets))
+	for _, m := range markets {
+		out = append(out, toMarket(m))
+	}
+	return out, nil
+}
+
+// markets retrieves the perpetual markets of the indexer, or only ticker
+// when it is set
+func (c *DefaultClient) markets(ctx context.Context, ticker string) (map[string]indexerMarket, error) {
+	query := url.Values{}
+	if ticker != "" {
+		query.Set("ticker", ticker)
+	}
+	var response struct {
+		Markets map[string]indexerMarket `json:"markets"`
+	}
+	if err := c.get(ctx, "/v4/perpetualMarkets", query, &response); err != nil {
+		return nil, err
+	}
+	return response.Markets, nil
+}
+
+// market retrieves a single perpetual market
+func (c *DefaultClient) market(ctx context.Context, ticker string) (indexerMarket, error) {
+	markets, err := c.markets(ctx, ticker)
+	if err != nil {
+		return indexerMarket{}, err
+	}
+	m, ok := markets[ticker]
+	if !ok {
+		return indexerMarket{}, fmt.Errorf("%w: %s", ErrUnknownMarket, ticker)
+	}
+	return m, nil
+}
+
+// GetOrderbook retrieves the orderbook for a market
+func (c *DefaultClient) GetOrderbook(ctx context.Context, symbol string) (*dydx.Orderbook, error) {
+	var response struct {
+		Bids []indexerLevel `json:"bids"`
+		Asks []indexerLevel `json:"asks"`
+	}
+	if err := c.get(ctx, "/v4/orderbooks/perpetualMarket/"+url.PathEscape(symbol), nil, &response); err != nil {
+		return nil, err
+	}
+
+	return &dydx.Orderbook{
+		Market: symbol,
+		Bids:   toLevels(response.Bids),
+		Asks:   toLevels(response.Asks),
+		Time:   time.Now(),
+	}, nil
+}
+
+// GetTrades retrieves recent trades for a market
+func (c *DefaultClient) GetTrades(ctx context.Context, symbol string, limit int) ([]dydx.Trade, error) {
+	var response struct {
+		Trades []indexerTrade `json:"trades"`
+	}
+	query := url.Values{"limit": {strconv.Itoa(limit)}}
+	if err := c.get(ctx, "/v4/trades/perpetualMarket/"+url.PathEscape(symbol), query, &response); err != nil {
+		return nil, err
+	}
+
+	trades := make([]dydx.Trade, len(response.Trades))
+	for i, t := range response.Trades {
+		trades[i] = toTrade(symbol, t)
+	}
+	return trades, nil
+}
+
+// GetFundingRate retrieves the latest funding rate for a market
+func (c *DefaultClient) GetFundingRate(ctx context.Context, symbol string) (*dydx.FundingRate, error) {
+	var response struct {
+		HistoricalFunding []indexerFunding `json:"historicalFunding"`
+	}
+	query := url.Values{"limit": {"1"}}
+	if err := c.get(ctx, "/v4/historicalFunding/"+url.PathEscape(symbol), query, &response); err != nil {
+		return nil, err
+	}
+	if len(response.HistoricalFunding) == 0 {
+		return nil, fmt.Errorf("no funding rate for %s", symbol)
+	}
+
+	f := response.HistoricalFunding[0]
+	return &dydx.FundingRate{
+		Market: symbol,
+		Rate:   parseFloat(f.Rate),
+		Price:  parseFloat(f.Price),
+		Time:   f.EffectiveAt,
+	}, nil
+}
+
+// CreateOrder translates an order request to chain units and broadcasts it.
+// The returned order is pending with the chain client ID until the indexer
+// has seen it.
+func (c *DefaultClient) CreateOrder(ctx context.Context, req dydx.CreateOrderRequest) (*dydx.Order, error) {
+	if c.broadcaster == nil {
+		return nil, ErrNoBroadcaster
+	}
+
+	m, err := c.market(ctx, req.Market)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := c.placeOrder(ctx, m, req)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.broadcaster.PlaceOrder(ctx, msg); err != nil {
+		return nil, fmt.Errorf("create order failed: %w", err)
+	}
+
+	order := &dydx.Order{
+		ClientID:      strconv.FormatUint(uint64(msg.OrderID.ClientID), 10),
+		Market:        req.Market,
+		Type:          req.Type,
+		Side:          req.Side,
+		Price:         req.Price,
+		TriggerPrice:  req.TriggerPrice,
+		Size:          req.Size,
+		RemainingSize: req.Size,
+		Status:        dydx.OrderStatusPending,
+		CreatedAt:     time.Now(),
+		PostOnly:      req.PostOnly,
+		ReduceOnly:    req.ReduceOnly,
+		TimeInForce:   req.TimeInForce,
+	}
+	if !msg.GoodTilBlockTime.IsZero() {
+		order.ExpiresAt = msg.GoodTilBlockTime
+	}
+	return order, nil
+}
+
+// placeOrder builds the chain message of an order request. Market orders are
+// short-term immediate-or-cancel orders, unless fill-or-kill; limit orders
+// are long-term, or short-term when IOC or FOK, and stop and take-profit
+// orders are conditional.
+func (c *DefaultClient) placeOrder(ctx context.Context, m indexerMarket, req dydx.CreateOrderRequest) (PlaceOrder, error) {
+	msg := PlaceOrder{
+		OrderID: OrderID{
+			Owner:            c.address,
+			SubaccountNumber: c.subaccount,
+			ClientID:         clientID(req.ClientID),
+			ClobPairID:       parseUint32(m.ClobPairID),
+		},
+		Side:        req.Side,
+		TimeInForce: TimeInForceUnspecified,
+		ReduceOnly:  req.ReduceOnly,
+	}
+	switch {
+	case req.PostOnly:
+		msg.TimeInForce = TimeInForcePostOnly
+	case req.TimeInForce == dydx.TimeInForceIOC:
+		msg.TimeInForce = TimeInForceIOC
+	case req.TimeInForce == dydx.TimeInForceFOK:
+		msg.TimeInForce = TimeInForceFillOrKill
+	}
+
+	var err error
+	if msg.Quantums, err = quantums(m, req.Size); err != nil {
+		return PlaceOrder{}, err
+	}
+
+	price := req.Price
+	switch req.Type {
+	case dydx.OrderTypeMarket, dydx.OrderTypeStopMarket:
+		if price == 0 {
+			// Market orders are limit orders at the worst acceptable price
+			reference := parseFloat(m.OraclePrice)
+			if req.TriggerPrice > 0 {
+				reference = req.TriggerPrice
+			}
+			if req.Side == dydx.OrderSideBuy {
+				price = reference * (1 + c.slippage)
+			} else {
+				price = reference * (1 - c.slippage)
+			}
+		}
+		if msg.TimeInForce != TimeInForceFillOrKill {
+			msg.TimeInForce = TimeInForceIOC
+		}
+	case dydx.OrderTypeLimit, dydx.OrderTypeStopLimit, dydx.OrderTypeTakeProfit:
+	default:
+		return PlaceOrder{}, fmt.Errorf("unsupported order type: %s", req.Type)
+	}
+	if msg.Subticks, err = subticks(m, price); err != nil {
+		return PlaceOrder{}, err
+	}
+
+	expiry := time.Now().Add(c.longTermTTL)
+	if req.ExpiresAt > 0 {
+		expiry = time.Unix(req.ExpiresAt, 0)
+	}
+
+	immediate := msg.TimeInForce == TimeInForceIOC || msg.TimeInForce == TimeInForceFillOrKill
+	switch {
+	case req.Type == dydx.OrderTypeMarket, req.Type == dydx.OrderTypeLimit && immediate:
+		msg.OrderID.OrderFlags = OrderFlagsShortTerm
+		height, err := c.height(ctx)
+		if err != nil {
+			return PlaceOrder{}, err
+		}
+		msg.GoodTilBlock = height + c.shortTermBlocks
+	case req.Type == dydx.OrderTypeLimit:
+		msg.OrderID.OrderFlags = OrderFlagsLongTerm
+		msg.GoodTilBlockTime = expiry
+	default:
+		if req.TriggerPrice <= 0 {
+			return PlaceOrder{}, fmt.Errorf("%s orders need a trigger price", req.Type)
+		}
+		msg.OrderID.OrderFlags = OrderFlagsConditional
+		msg.GoodTilBlockTime = expiry
+		msg.ConditionType = ConditionStopLoss
+		if req.Type == dydx.OrderTypeTakeProfit {
+			msg.ConditionType = ConditionTakeProfit
+		}
+		if msg.ConditionalOrderTriggerSubticks, err = subticks(m, req.TriggerPrice); err != nil {
+			return PlaceOrder{}, err
+		}
+	}
+	return msg, nil
+}
+
+// height retrieves the current block height of the chain
+func (c *DefaultClient) height(ctx context.Context) (uint32, error) {
+	var response struct {
+		Height string `json:"height"`
+	}
+	if err := c.get(ctx, "/v4/height", nil, &response); err != nil {
+		return 0, err
+	}
+	return parseUint32(response.Height), nil
+}
+
+// CancelOrder cancels an order by its indexer ID
+func (c *DefaultClient) CancelOrder(ctx context.Context, orderID string) error {
+	if c.broadcaster == nil {
+		return ErrNoBroadcaster
+	}
+
+	o, err := c.order(ctx, orderID)
+	if err != nil {
+		return err
+	}
+
+	msg := CancelOrder{
+		OrderID: OrderID{
+			Owner:            c.address,
+			SubaccountNumber: c.subaccount,
+			ClientID:         parseUint32(o.ClientID),
+			OrderFlags:       parseUint32(o.OrderFlags),
+			ClobPairID:       parseUint32(o.ClobPairID),
+		},
+	}
+	if msg.OrderID.OrderFlags == OrderFlagsShortTerm {
+		height, err := c.height(ctx)
+		if err != nil {
+			return err
+		}
+		msg.GoodTilBlock = height + c.shortTermBlocks
+	} else {
+		msg.GoodTilBlockTime = time.Now().Add(c.longTermTTL)
+	}
+
+	if _, err := c.broadcaster.CancelOrder(ctx, msg); err != nil {
+		return fmt.Errorf("cancel order failed: %w", err)
+	}
+	return nil
+}
+
+// GetOrder retrieves an order by its indexer ID
+func (c *DefaultClient) GetOrder(ctx context.Context, orderID string) (*dydx.Order, error) {
+	o, err := c.order(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	order := toOrder(o)
+	return &order, nil
+}
+
+func (c *DefaultClient) order(ctx context.Context, orderID string) (indexerOrder, error) {
+	var o indexerOrder
+	err := c.get(ctx, "/v4/orders/"+url.PathEscape(orderID), nil, &o)
+	return o, err
+}
+
+// GetOpenOrders retrieves the open orders of the subaccount
+func (c *DefaultClient) GetOpenOrders(ctx context.Context) ([]dydx.Order, error) {
+	query := c.subaccountQuery()
+	query.Set("status", "OPEN")
+	var response []indexerOrder
+	if err := c.get(ctx, "/v4/orders", query, &response); err != nil {
+		return nil, err
+	}
+
+	orders := make([]dydx.Order, len(response))
+	for i, o := range response {
+		orders[i] = toOrder(o)
+	}
+	return orders, nil
+}
+
+// GetPositions retrieves the open positions of the subaccount
+func (c *DefaultClient) GetPositions(ctx context.Context) ([]dydx.Position, error) {
+	query := c.subaccountQuery()
+	query.Set("status", "OPEN")
+	var response struct {
+		Positions []indexerPosition `json:"positions"`
+	}
+	if err := c.get(ctx, "/v4/perpetualPositions", query, &response); err != nil {
+		return nil, err
+	}
+
+	positions := make([]dydx.Position, len(response.Positions))
+	for i, p := range response.Positions {
+		positions[i] = toPosition(p)
+	}
+	return positions, nil
+}
+
+func (c *DefaultClient) subaccountInfo(ctx context.Context) (indexerSubaccount, error) {
+	var response struct {
+		Subaccount indexerSubaccount `json:"subaccount"`
+	}
+	path := fmt.Sprintf("/v4/addresses/%s/subaccountNumber/%d", url.PathEscape(c.address), c.subaccount)
+	err := c.get(ctx, path, nil, &response)
+	return response.Subaccount, err
+}
+
+// GetBalance retrieves the USDC balance of the subaccount
+func (c *DefaultClient) GetBalance(ctx context.Context) (*dydx.Balance, error) {
+	s, err := c.subaccountInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	balance := &dydx.Balance{
+		Currency:  "USDC",
+		Balance:   usdc(s),
+		Available: parseFloat(s.FreeCollateral),
+	}
+	for _, p := range s.OpenPerpetualPositions {
+		balance.UnrealizedPnl += parseFloat(p.UnrealizedPnl)
+		balance.RealizedPnl += parseFloat(p.RealizedPnl)
+	}
+	return balance, nil
+}
+
+// usdc returns the signed USDC asset position of a subaccount
+func usdc(s indexerSubaccount) float64 {
+	a, ok := s.AssetPositions["USDC"]
+	if !ok {
+		return 0
+	}
+	size := parseFloat(a.Size)
+	if a.Side == "SHORT" {
+		return -math.Abs(size)
+	}
+	return size
+}
+
+// GetAccount retrieves the subaccount
+func (c *DefaultClient) GetAccount(ctx context.Context) (*dydx.Account, error) {
+	s, err := c.subaccountInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dydx.Account{
+		ID:              fmt.Sprintf("%s/%d", s.Address, s.SubaccountNumber),
+		Equity:          parseFloat(s.Equity),
+		FreeCollateral:  parseFloat(s.FreeCollateral),
+		QuoteBalance:    usdc(s),
+		ActivePositions: len(s.OpenPerpetualPositions),
+	}, nil
+}
+
+// GetLeverage is not supported; v4 margins every position of a subaccount
+// by the fractions of its market
+func (c *DefaultClient) GetLeverage(ctx context.Context, symbol string) (int, error) {
+	return 0, ErrUnsupported
+}
+
+// SetLeverage is not supported; see GetLeverage
+func (c *DefaultClient) SetLeverage(ctx context.Context, symbol string, leverage int) error {
+	return ErrUnsupported
+}
+
+// GetFills retrieves the fills of the subaccount since a time, oldest first
+func (c *DefaultClient) GetFills(ctx context.Context, since time.Time) ([]dydx.Fill, error) {
+	var fills []dydx.Fill
+	seen := make(map[string]bool)
+	before := ""
+	for {
+		query := c.subaccountQuery()
+		query.Set("limit", "100")
+		if before != "" {
+			query.Set("createdBeforeOrAt", before)
+		}
+		var response struct {
+			Fills []indexerFill `json:"fills"`
+		}
+		if err := c.get(ctx, "/v4/fills", query, &response); err != nil {
+			return nil, err
+		}
+
+		added, done := 0, len(response.Fills) < 100
+		for _, f := range response.Fills {
+			if f.CreatedAt.Before(since) {
+				done = true
+				continue
+			}
+			if seen[f.ID] {
+				continue
+			}
+			seen[f.ID] = true
+			fills = append(fills, toFill(f))
+			added++
+			before = f.CreatedAt.Format(time.RFC3339Nano)
+		}
+		if done || added == 0 {
+			break
+		}
+	}
+
+	// The indexer returns the newest fills first
+	for i, j := 0, len(fills)-1; i < j; i, j = i+1, j-1 {
+		fills[i], fills[j] = fills[j], fills[i]
+	}
+	return fills, nil
+}
+
+// GetFundingPayments retrieves the funding payments of the subaccount since
+// a time
+func (c *DefaultClient) GetFundingPayments(ctx context.Context, since time.Time) ([]dydx.FundingPayment, error) {
+	query := c.subaccountQuery()
+	query.Set("limit", "1000")
+	var response struct {
+		FundingPayments []indexerFundingPayment `json:"fundingPayments"`
+	}
+	if err := c.get(ctx, "/v4/fundingPayments", query, &response); err != nil {
+		return nil, err
+	}
+
+	payments := make([]dydx.FundingPayment, 0, len(response.FundingPayments))
+	for _, p := range response.FundingPayments {
+		if p.CreatedAt.Before(since) {
+			continue
+		}
+		payments = append(payments, toFundingPayment(p))
+	}
+	return payments, nil
+}
+
+// SubscribeOrderbook is not supported yet; poll GetOrderbook instead
+func (c *DefaultClient) SubscribeOrderbook(symbol string, ch chan<- dydx.Orderbook) error {
+	return ErrUnsupported
+}
+
+// SubscribeTrades is not supported yet; poll GetTrades instead
+func (c *DefaultClient) SubscribeTrades(symbol string, ch chan<- dydx.Trade) error {
+	return ErrUnsupported
+}
+
+// SubscribePositions is not supported yet; poll GetPositions instead
+func (c *DefaultClient) SubscribePositions(ch chan<- dydx.Position) error {
+	return ErrUnsupported
+}
+
+// UnsubscribeAll is a no-op as the client holds no subscriptions
+func (c *DefaultClient) UnsubscribeAll() error {
+	return nil
+}
+
+// Close releases the idle connections of the client
+func (c *DefaultClient) Close() error {
+	c.httpClient.CloseIdleConnections()
+	return nil
+}
+
+var _ dydx.Client = (*DefaultClient)(nil)
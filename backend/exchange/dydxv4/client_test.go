@@ -0,0 +1,175 @@
+package dydxv4
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/devinjacknz/godydxhyber/backend/exchange/dydx"
+)
+
+const address = "dydx1trader"
+
+type fakeBroadcaster struct {
+	placed   []PlaceOrder
+	canceled []CancelOrder
+}
+
+func (b *fakeBroadcaster) PlaceOrder(ctx context.Context, order PlaceOrder) (string, error) {
+	b.placed = append(b.placed, order)
+	return "tx", nil
+}
+
+func (b *fakeBroadcaster) CancelOrder(ctx context.Context, order CancelOrder) (string, error) {
+	b.canceled = append(b.canceled, order)
+	return "tx", nil
+}
+
+func newIndexer(t *testing.T) *httptest.Server {
+	routes := map[string]string{
+		"/v4/perpetualMarkets": `{"markets":{"BTC-USD":{"ticker":"BTC-USD","clobPairId":"0","status":"ACTIVE",
+			"oraclePrice":"50000","tickSize":"1","stepSize":"0.0001","initialMarginFraction":"0.05",
+			"maintenanceMarginFraction":"0.03","atomicResolution":-10,"quantumConversionExponent":-9,
+			"subticksPerTick":100000,"stepBaseQuantums":1000000}}}`,
+		"/v4/height": `{"height":"1000","time":"2024-05-01T12:00:00.000Z"}`,
+		"/v4/orders/order-1": `{"id":"order-1","clientId":"42","clobPairId":"0","side":"BUY","size":"0.5",
+			"totalFilled":"0.2","price":"49000","type":"LIMIT","status":"BEST_EFFORT_CANCELED","timeInForce":"GTT",
+			"orderFlags":"64","ticker":"BTC-USD","goodTilBlockTime":"2024-06-01T00:00:00.000Z"}`,
+		"/v4/perpetualPositions": `{"positions":[{"market":"BTC-USD","status":"OPEN","side":"SHORT","size":"-0.5",
+			"entryPrice":"50000","realizedPnl":"1","unrealizedPnl":"-20","createdAt":"2024-05-01T12:00:00.000Z"}]}`,
+		"/v4/addresses/" + address + "/subaccountNumber/0": `{"subaccount":{"address":"` + address + `","subaccountNumber":0,
+			"equity":"10000","freeCollateral":"8000",
+			"openPerpetualPositions":{"BTC-USD":{"market":"BTC-USD","unrealizedPnl":"-20","realizedPnl":"1"}},
+			"assetPositions":{"USDC":{"symbol":"USDC","side":"LONG","size":"35000"}}}}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v4/perpetualPositions" {
+			assert.Equal(t, address, r.URL.Query().Get("address"))
+			assert.Equal(t, "OPEN", r.URL.Query().Get("status"))
+		}
+		body, ok := routes[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[{"msg":"not found"}]}`))
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestReadsAreTranslated(t *testing.T) {
+	server := newIndexer(t)
+	client := NewClient(address, 0, WithBaseURL(server.URL))
+	ctx := context.Background()
+
+	markets, err := client.GetMarkets(ctx)
+	require.NoError(t, err)
+	require.Len(t, markets, 1)
+	assert.Equal(t, "BTC", markets[0].BaseCurrency)
+	assert.Equal(t, 20, markets[0].MaxLeverage)
+	assert.Equal(t, 0.0001, markets[0].StepSize)
+
+	order, err := client.GetOrder(ctx, "order-1")
+	require.NoError(t, err)
+	assert.Equal(t, dydx.OrderStatusCanceled, order.Status)
+	assert.InDelta(t, 0.3, order.RemainingSize, 1e-9)
+	assert.Equal(t, "BTC-USD", order.Market)
+
+	positions, err := client.GetPositions(ctx)
+	require.NoError(t, err)
+	require.Len(t, positions, 1)
+	assert.Equal(t, dydx.PositionSideShort, positions[0].Side)
+	assert.Equal(t, -0.5, positions[0].Size)
+
+	account, err := client.GetAccount(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, address+"/0", account.ID)
+	assert.Equal(t, 10000.0, account.Equity)
+	assert.Equal(t, 35000.0, account.QuoteBalance)
+	assert.Equal(t, 1, account.ActivePositions)
+
+	_, err = client.GetOrder(ctx, "missing")
+	assert.ErrorContains(t, err, "not found")
+
+	_, err = client.GetLeverage(ctx, "BTC-USD")
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
+
+func TestCreateOrderIsTranslatedToChainUnits(t *testing.T) {
+	server := newIndexer(t)
+	broadcaster := &fakeBroadcaster{}
+	client := NewClient(address, 0, WithBaseURL(server.URL), WithBroadcaster(broadcaster))
+	ctx := context.Background()
+
+	order, err := client.CreateOrder(ctx, dydx.CreateOrderRequest{
+		Market:   "BTC-USD",
+		Side:     dydx.OrderSideBuy,
+		Type:     dydx.OrderTypeLimit,
+		Size:     0.25,
+		Price:    49000,
+		PostOnly: true,
+		ClientID: "7",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, dydx.OrderStatusPending, order.Status)
+	assert.Equal(t, "7", order.ClientID)
+
+	_, err = client.CreateOrder(ctx, dydx.CreateOrderRequest{
+		Market:   "BTC-USD",
+		Side:     dydx.OrderSideSell,
+		Type:     dydx.OrderTypeMarket,
+		Size:     0.1,
+		ClientID: "api-1",
+	})
+	require.NoError(t, err)
+
+	require.Len(t, broadcaster.placed, 2)
+	limit, market := broadcaster.placed[0], broadcaster.placed[1]
+
+	assert.Equal(t, OrderFlagsLongTerm, limit.OrderID.OrderFlags)
+	assert.Equal(t, uint32(7), limit.OrderID.ClientID)
+	assert.Equal(t, uint64(2_500_000_000), limit.Quantums)
+	assert.Equal(t, uint64(4_900_000_000), limit.Subticks)
+	assert.Equal(t, TimeInForcePostOnly, limit.TimeInForce)
+	assert.WithinDuration(t, time.Now().Add(28*24*time.Hour), limit.GoodTilBlockTime, time.Minute)
+
+	assert.Equal(t, OrderFlagsShortTerm, market.OrderID.OrderFlags)
+	assert.Equal(t, uint32(1020), market.GoodTilBlock)
+	assert.Equal(t, TimeInForceIOC, market.TimeInForce)
+	// 5% below the oracle price
+	assert.Equal(t, uint64(4_750_000_000), market.Subticks)
+	assert.Equal(t, clientID("api-1"), market.OrderID.ClientID)
+
+	_, err = client.CreateOrder(ctx, dydx.CreateOrderRequest{Market: "BTC-USD", Side: dydx.OrderSideBuy, Type: dydx.OrderTypeLimit, Size: 0.1, Price: 49000, TimeInForce: dydx.TimeInForceFOK})
+	require.NoError(t, err)
+	require.Len(t, broadcaster.placed, 3)
+	fok := broadcaster.placed[2]
+	assert.Equal(t, TimeInForceFillOrKill, fok.TimeInForce)
+	assert.Equal(t, OrderFlagsShortTerm, fok.OrderID.OrderFlags, "immediate limit orders are short-term")
+	assert.Equal(t, uint32(1020), fok.GoodTilBlock)
+
+	_, err = client.CreateOrder(ctx, dydx.CreateOrderRequest{Market: "BTC-USD", Side: dydx.OrderSideBuy, Type: dydx.OrderTypeLimit, Size: 0.00001, Price: 49000})
+	assert.ErrorContains(t, err, "below the step size")
+
+	_, err = client.CreateOrder(ctx, dydx.CreateOrderRequest{Market: "ETH-USD", Side: dydx.OrderSideBuy, Type: dydx.OrderTypeLimit, Size: 1, Price: 3000})
+	assert.ErrorIs(t, err, ErrUnknownMarket)
+
+	require.NoError(t, client.CancelOrder(ctx, "order-1"))
+	require.Len(t, broadcaster.canceled, 1)
+	assert.Equal(t, OrderID{Owner: address, ClientID: 42, OrderFlags: OrderFlagsLongTerm}, broadcaster.canceled[0].OrderID)
+}
+
+func TestReadOnlyClientRejectsOrders(t *testing.T) {
+	client := NewClient(address, 0, WithBaseURL(newIndexer(t).URL))
+
+	_, err := client.CreateOrder(context.Background(), dydx.CreateOrderRequest{Market: "BTC-USD"})
+	assert.ErrorIs(t, err, ErrNoBroadcaster)
+	assert.ErrorIs(t, client.CancelOrder(context.Background(), "order-1"), ErrNoBroadcaster)
+}
@@ -0,0 +1,16 @@
+package dydxv4
+
+import "errors"
+
+var (
+	// ErrUnsupported is returned by the dydx.Client methods without a v4
+	// equivalent
+	ErrUnsupported = errors.New("not supported by the dYdX v4 client")
+
+	// ErrNoBroadcaster is returned when placing or canceling orders without a
+	// chain broadcaster
+	ErrNoBroadcaster = errors.New("no chain broadcaster configured")
+
+	// ErrUnknownMarket is returned when a market is not listed by the indexer
+	ErrUnknownMarket = errors.New("unknown market")
+)
@@ -0,0 +1,218 @@
+package dydxv4
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/devinjacknz/godydxhyber/backend/exchange/dydx"
+)
+
+// quoteAtomicResolution is the atomic resolution of USDC, the quote asset of
+// every market
+const quoteAtomicResolution = -6
+
+// parseFloat parses a decimal string of the indexer, treating an empty
+// string as zero
+func parseFloat(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
+
+func parseUint32(s string) uint32 {
+	n, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0
+	}
+	return uint32(n)
+}
+
+// orderStatus maps an indexer order status to the shared status
+func orderStatus(status string) string {
+	switch status {
+	case "OPEN":
+		return dydx.OrderStatusOpen
+	case "FILLED":
+		return dydx.OrderStatusFilled
+	case "CANCELED", "BEST_EFFORT_CANCELED":
+		return dydx.OrderStatusCanceled
+	case "UNTRIGGERED":
+		return dydx.OrderStatusUntriggered
+	default:
+		// BEST_EFFORT_OPENED orders are seen by a validator but not yet
+		// in a block
+		return dydx.OrderStatusPending
+	}
+}
+
+// orderType maps an indexer order type to the shared type
+func orderType(t string) string {
+	switch t {
+	case "TAKE_PROFIT", "TAKE_PROFIT_MARKET":
+		return dydx.OrderTypeTakeProfit
+	case "STOP_LIMIT":
+		return dydx.OrderTypeStopLimit
+	case "STOP_MARKET":
+		return dydx.OrderTypeStopMarket
+	default:
+		return t
+	}
+}
+
+func toMarket(m indexerMarket) dydx.Market {
+	base, quote, _ := strings.Cut(m.Ticker, "-")
+	initial := parseFloat(m.InitialMarginFraction)
+	var maxLeverage int
+	if initial > 0 {
+		maxLeverage = int(math.Round(1 / initial))
+	}
+	return dydx.Market{
+		Symbol:            m.Ticker,
+		BaseCurrency:      base,
+		QuoteCurrency:     quote,
+		MinOrderSize:      parseFloat(m.StepSize),
+		TickSize:          parseFloat(m.TickSize),
+		StepSize:          parseFloat(m.StepSize),
+		MaxLeverage:       maxLeverage,
+		InitialMargin:     initial,
+		MaintenanceMargin: parseFloat(m.MaintenanceMarginFraction),
+		// Funding is paid every hour on v4
+		FundingInterval: 3600,
+		Status:          m.Status,
+	}
+}
+
+func toLevels(levels []indexerLevel) []dydx.OrderbookLevel {
+	out := make([]dydx.OrderbookLevel, len(levels))
+	for i, l := range levels {
+		out[i] = dydx.OrderbookLevel{Price: parseFloat(l.Price), Size: parseFloat(l.Size)}
+	}
+	return out
+}
+
+func toTrade(market string, t indexerTrade) dydx.Trade {
+	return dydx.Trade{
+		ID:          t.ID,
+		Market:      market,
+		Price:       parseFloat(t.Price),
+		Size:        parseFloat(t.Size),
+		Side:        t.Side,
+		Liquidation: t.Type == "LIQUIDATED",
+		Time:        t.CreatedAt,
+	}
+}
+
+func toOrder(o indexerOrder) dydx.Order {
+	size, filled := parseFloat(o.Size), parseFloat(o.TotalFilled)
+	return dydx.Order{
+		ID:            o.ID,
+		ClientID:      o.ClientID,
+		Market:        o.Ticker,
+		Type:          orderType(o.Type),
+		Side:          o.Side,
+		Price:         parseFloat(o.Price),
+		TriggerPrice:  parseFloat(o.TriggerPrice),
+		Size:          size,
+		FilledSize:    filled,
+		RemainingSize: size - filled,
+		Status:        orderStatus(o.Status),
+		// The indexer does not report the creation time of orders
+		CreatedAt:   o.UpdatedAt,
+		ExpiresAt:   o.GoodTilBlockTime,
+		PostOnly:    o.PostOnly || o.TimeInForce == "POST_ONLY",
+		ReduceOnly:  o.ReduceOnly,
+		TimeInForce: indexerTimeInForce(o.TimeInForce),
+	}
+}
+
+// indexerTimeInForce returns IOC and FOK as is, and nothing for GTT and
+// POST_ONLY orders, which rest on the book like the default
+func indexerTimeInForce(tif string) string {
+	switch tif {
+	case dydx.TimeInForceIOC, dydx.TimeInForceFOK:
+		return tif
+	}
+	return ""
+}
+
+func toPosition(p indexerPosition) dydx.Position {
+	return dydx.Position{
+		Market:        p.Market,
+		Side:          p.Side,
+		Size:          parseFloat(p.Size),
+		EntryPrice:    parseFloat(p.EntryPrice),
+		UnrealizedPnl: parseFloat(p.UnrealizedPnl),
+		RealizedPnl:   parseFloat(p.RealizedPnl),
+		CreatedAt:     p.CreatedAt,
+		UpdatedAt:     p.CreatedAt,
+	}
+}
+
+func toFill(f indexerFill) dydx.Fill {
+	return dydx.Fill{
+		ID:        f.ID,
+		OrderID:   f.OrderID,
+		Market:    f.Market,
+		Side:      f.Side,
+		Liquidity: f.Liquidity,
+		Price:     parseFloat(f.Price),
+		Size:      parseFloat(f.Size),
+		Fee:       parseFloat(f.Fee),
+		CreatedAt: f.CreatedAt,
+	}
+}
+
+func toFundingPayment(p indexerFundingPayment) dydx.FundingPayment {
+	return dydx.FundingPayment{
+		Market:       p.Ticker,
+		Payment:      parseFloat(p.Payment),
+		Rate:         parseFloat(p.Rate),
+		PositionSize: parseFloat(p.Size),
+		Price:        parseFloat(p.OraclePrice),
+		EffectiveAt:  p.CreatedAt,
+	}
+}
+
+// clientID converts the string client ID of an order request to the 32 bit
+// client ID of the chain. Numeric IDs are kept, others are hashed.
+func clientID(id string) uint32 {
+	if n, err := strconv.ParseUint(id, 10, 32); err == nil {
+		return uint32(n)
+	}
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return h.Sum32()
+}
+
+// quantums converts a size to base quantums, rounded down to the step size
+// of the market
+func quantums(m indexerMarket, size float64) (uint64, error) {
+	raw := size * math.Pow10(-m.AtomicResolution)
+	step := float64(max(m.StepBaseQuantums, 1))
+	q := uint64(math.Floor(raw/step+1e-9) * step)
+	if q == 0 {
+		return 0, fmt.Errorf("size %g is below the step size %s of %s", size, m.StepSize, m.Ticker)
+	}
+	return q, nil
+}
+
+// subticks converts a price to subticks, rounded to the tick size of the
+// market
+func subticks(m indexerMarket, price float64) (uint64, error) {
+	exponent := m.AtomicResolution - m.QuantumConversionExponent - quoteAtomicResolution
+	raw := price * math.Pow10(exponent)
+	tick := float64(max(m.SubticksPerTick, 1))
+	s := uint64(math.Round(raw/tick) * tick)
+	if s == 0 {
+		return 0, fmt.Errorf("price %g is below the tick size %s of %s", price, m.TickSize, m.Ticker)
+	}
+	return s, nil
+}
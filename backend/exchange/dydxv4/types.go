@@ -0,0 +1,174 @@
+package dydxv4
+
+import "time"
+
+// Order flags of the chain
+const (
+	// OrderFlagsShortTerm orders live in memory for a few blocks
+	OrderFlagsShortTerm uint32 = 0
+	// OrderFlagsConditional orders rest until their trigger price is hit
+	OrderFlagsConditional uint32 = 32
+	// OrderFlagsLongTerm orders are stored on chain until they expire
+	OrderFlagsLongTerm uint32 = 64
+)
+
+// Time in force of chain orders
+const (
+	TimeInForceUnspecified = "TIME_IN_FORCE_UNSPECIFIED"
+	TimeInForceIOC         = "TIME_IN_FORCE_IOC"
+	TimeInForcePostOnly    = "TIME_IN_FORCE_POST_ONLY"
+	TimeInForceFillOrKill  = "TIME_IN_FORCE_FILL_OR_KILL"
+)
+
+// Condition types of conditional orders
+const (
+	ConditionStopLoss   = "CONDITION_TYPE_STOP_LOSS"
+	ConditionTakeProfit = "CONDITION_TYPE_TAKE_PROFIT"
+)
+
+// OrderID identifies an order on chain
+type OrderID struct {
+	Owner            string
+	SubaccountNumber uint32
+	ClientID         uint32
+	OrderFlags       uint32
+	ClobPairID       uint32
+}
+
+// PlaceOrder is a MsgPlaceOrder in chain units
+type PlaceOrder struct {
+	OrderID OrderID
+	// Side is BUY or SELL
+	Side string
+	// Quantums is the size in base quantums
+	Quantums uint64
+	// Subticks is the price in subticks
+	Subticks uint64
+	// GoodTilBlock expires short-term orders
+	GoodTilBlock uint32
+	// GoodTilBlockTime expires long-term and conditional orders
+	GoodTilBlockTime time.Time
+	TimeInForce      string
+	ReduceOnly       bool
+	// ConditionType and ConditionalOrderTriggerSubticks are set on
+	// conditional orders
+	ConditionType                   string
+	ConditionalOrderTriggerSubticks uint64
+}
+
+// CancelOrder is a MsgCancelOrder
+type CancelOrder struct {
+	OrderID          OrderID
+	GoodTilBlock     uint32
+	GoodTilBlockTime time.Time
+}
+
+// indexerMarket is a perpetual market of the indexer
+type indexerMarket struct {
+	Ticker                    string `json:"ticker"`
+	ClobPairID                string `json:"clobPairId"`
+	Status                    string `json:"status"`
+	OraclePrice               string `json:"oraclePrice"`
+	TickSize                  string `json:"tickSize"`
+	StepSize                  string `json:"stepSize"`
+	InitialMarginFraction     string `json:"initialMarginFraction"`
+	MaintenanceMarginFraction string `json:"maintenanceMarginFraction"`
+	AtomicResolution          int    `json:"atomicResolution"`
+	QuantumConversionExponent int    `json:"quantumConversionExponent"`
+	SubticksPerTick           uint64 `json:"subticksPerTick"`
+	StepBaseQuantums          uint64 `json:"stepBaseQuantums"`
+}
+
+type indexerLevel struct {
+	Price string `json:"price"`
+	Size  string `json:"size"`
+}
+
+type indexerTrade struct {
+	ID        string    `json:"id"`
+	Side      string    `json:"side"`
+	Size      string    `json:"size"`
+	Price     string    `json:"price"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type indexerFunding struct {
+	Ticker      string    `json:"ticker"`
+	Rate        string    `json:"rate"`
+	Price       string    `json:"price"`
+	EffectiveAt time.Time `json:"effectiveAt"`
+}
+
+type indexerOrder struct {
+	ID               string    `json:"id"`
+	SubaccountID     string    `json:"subaccountId"`
+	ClientID         string    `json:"clientId"`
+	ClobPairID       string    `json:"clobPairId"`
+	Side             string    `json:"side"`
+	Size             string    `json:"size"`
+	TotalFilled      string    `json:"totalFilled"`
+	Price            string    `json:"price"`
+	Type             string    `json:"type"`
+	Status           string    `json:"status"`
+	TimeInForce      string    `json:"timeInForce"`
+	PostOnly         bool      `json:"postOnly"`
+	ReduceOnly       bool      `json:"reduceOnly"`
+	OrderFlags       string    `json:"orderFlags"`
+	GoodTilBlock     string    `json:"goodTilBlock"`
+	GoodTilBlockTime time.Time `json:"goodTilBlockTime"`
+	TriggerPrice     string    `json:"triggerPrice"`
+	Ticker           string    `json:"ticker"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
+
+type indexerPosition struct {
+	Market        string    `json:"market"`
+	Status        string    `json:"status"`
+	Side          string    `json:"side"`
+	Size          string    `json:"size"`
+	EntryPrice    string    `json:"entryPrice"`
+	RealizedPnl   string    `json:"realizedPnl"`
+	UnrealizedPnl string    `json:"unrealizedPnl"`
+	CreatedAt     time.Time `json:"createdAt"`
+	ClosedAt      time.Time `json:"closedAt"`
+}
+
+type indexerAssetPosition struct {
+	Symbol string `json:"symbol"`
+	Side   string `json:"side"`
+	Size   string `json:"size"`
+}
+
+type indexerSubaccount struct {
+	Address                string                          `json:"address"`
+	SubaccountNumber       int                             `json:"subaccountNumber"`
+	Equity                 string                          `json:"equity"`
+	FreeCollateral         string                          `json:"freeCollateral"`
+	OpenPerpetualPositions map[string]indexerPosition      `json:"openPerpetualPositions"`
+	AssetPositions         map[string]indexerAssetPosition `json:"assetPositions"`
+}
+
+type indexerFill struct {
+	ID        string    `json:"id"`
+	Side      string    `json:"side"`
+	Liquidity string    `json:"liquidity"`
+	Market    string    `json:"market"`
+	Price     string    `json:"price"`
+	Size      string    `json:"size"`
+	Fee       string    `json:"fee"`
+	OrderID   string    `json:"orderId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type indexerFundingPayment struct {
+	Ticker       string    `json:"ticker"`
+	Payment      string    `json:"payment"`
+	Rate         string    `json:"rate"`
+	Size         string    `json:"size"`
+	OraclePrice  string    `json:"oraclePrice"`
+	CreatedAt    time.Time `json:"createdAt"`
+	PerpetualID  string    `json:"perpetualId"`
+	Side         string    `json:"side"`
+	SubaccountID string    `json:"subaccountId"`
+}
@@ -0,0 +1,45 @@
+// Package exchange creates the exchange clients of the configuration
+package exchange
+
+import (
+	"fmt"
+
+	"github.com/devinjacknz/godydxhyber/backend/config"
+	"github.com/devinjacknz/godydxhyber/backend/exchange/dydx"
+	"github.com/devinjacknz/godydxhyber/backend/exchange/dydxv4"
+)
+
+// NewDydxClient creates the dYdX client of the configured protocol version.
+// Both versions share the dydx types, so callers do not depend on the
+// version. broadcaster places v4 orders and may be nil for a read-only
+// client; it is ignored on v3.
+func NewDydxClient(cfg config.ExchangeConfig, broadcaster dydxv4.Broadcaster) (dydx.Client, error) {
+	switch cfg.Version {
+	case "", config.DydxV3:
+		var opts []dydx.ClientOption
+		if cfg.BaseURL != "" {
+			opts = append(opts, dydx.WithBaseURL(cfg.BaseURL))
+		}
+		if cfg.WSURL != "" {
+			opts = append(opts, dydx.WithWSURL(cfg.WSURL))
+		}
+		if cfg.RateLimit > 0 {
+			opts = append(opts, dydx.WithRateLimit(cfg.RateLimit, max(cfg.RateBurst, 1)))
+		}
+		return dydx.NewClient(cfg.APIKey, cfg.APISecret, cfg.Passphrase, opts...), nil
+	case config.DydxV4:
+		if cfg.SubaccountNumber < 0 {
+			return nil, fmt.Errorf("invalid dYdX subaccount number: %d", cfg.SubaccountNumber)
+		}
+		opts := []dydxv4.ClientOption{dydxv4.WithBroadcaster(broadcaster)}
+		if cfg.BaseURL != "" {
+			opts = append(opts, dydxv4.WithBaseURL(cfg.BaseURL))
+		}
+		if cfg.RateLimit > 0 {
+			opts = append(opts, dydxv4.WithRateLimit(cfg.RateLimit, max(cfg.RateBurst, 1)))
+		}
+		return dydxv4.NewClient(cfg.Address, uint32(cfg.SubaccountNumber), opts...), nil
+	default:
+		return nil, fmt.Errorf("unknown dYdX version: %s", cfg.Version)
+	}
+}
@@ -37,10 +37,6 @@ type APIKey struct {
 
 // GetAccount retrieves account information
 func (c *DefaultClient) GetAccount(ctx context.Context) (*Account, error) {
-	if err := c.limiter.Wait(ctx); err != nil {
-		return nil, err
-	}
-
 	url := fmt.Sprintf("%s/api/v1/account", c.baseURL)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -71,10 +67,6 @@ func (c *DefaultClient) GetAccount(ctx context.Context) (*Account, error) {
 
 // GetAPIKeys retrieves all API keys
 func (c *DefaultClient) GetAPIKeys(ctx context.Context) ([]APIKey, error) {
-	if err := c.limiter.Wait(ctx); err != nil {
-		return nil, err
-	}
-
 	url := fmt.Sprintf("%s/api/v1/api-keys", c.baseURL)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -105,10 +97,6 @@ func (c *DefaultClient) GetAPIKeys(ctx context.Context) ([]APIKey, error) {
 
 // CreateAPIKey creates a new API key
 func (c *DefaultClient) CreateAPIKey(ctx context.Context, name string, permissions []string, ipWhitelist []string) (*APIKey, error) {
-	if err := c.limiter.Wait(ctx); err != nil {
-		return nil, err
-	}
-
 	body, err := json.Marshal(struct {
 		Name        string   `json:"name"`
 		Permissions []string `json:"permissions"`
@@ -153,10 +141,6 @@ func (c *DefaultClient) CreateAPIKey(ctx context.Context, name string, permissio
 
 // DeleteAPIKey deletes an API key
 func (c *DefaultClient) DeleteAPIKey(ctx context.Context, keyID string) error {
-	if err := c.limiter.Wait(ctx); err != nil {
-		return err
-	}
-
 	url := fmt.Sprintf("%s/api/v1/api-keys/%s", c.baseURL, keyID)
 	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
 	if err != nil {
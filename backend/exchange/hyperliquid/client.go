@@ -4,12 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"net/http"
 	"sync"
 	"time"
 
+	"github.com/devinjacknz/godydxhyber/backend/pkg/httpx"
 	"github.com/gorilla/websocket"
-	"golang.org/x/time/rate"
 )
 
 // Client defines the interface for interacting with Hyperliquid
@@ -43,9 +42,11 @@ type Client interface {
 type DefaultClient struct {
 	baseURL    string
 	wsURL      string
-	httpClient *http.Client
+	httpClient *httpx.Client
 	wsConn     *websocket.Conn
-	limiter    *rate.Limiter
+	// rps and burst limit the requests to the REST API host
+	rps   float64
+	burst int
 
 	// Subscriptions
 	subscriptions map[string][]chan interface{}
@@ -59,13 +60,19 @@ type DefaultClient struct {
 // ClientOption defines options for creating a new client
 type ClientOption func(*DefaultClient)
 
+// Default endpoints of the Hyperliquid API
+const (
+	DefaultBaseURL = "https://api.hyperliquid.xyz"
+	DefaultWSURL   = "wss://api.hyperliquid.xyz/ws"
+)
+
 // NewClient creates a new Hyperliquid client
 func NewClient(opts ...ClientOption) Client {
 	client := &DefaultClient{
-		baseURL:       "https://api.hyperliquid.xyz",
-		wsURL:         "wss://api.hyperliquid.xyz/ws",
-		httpClient:    &http.Client{Timeout: 10 * time.Second},
-		limiter:       rate.NewLimiter(rate.Limit(10), 20), // 10 requests/second, burst of 20
+		baseURL:       DefaultBaseURL,
+		wsURL:         DefaultWSURL,
+		rps:           10, // 10 requests/second, burst of 20
+		burst:         20,
 		subscriptions: make(map[string][]chan interface{}),
 		done:          make(chan struct{}),
 		reconnect:     make(chan struct{}, 1),
@@ -74,6 +81,8 @@ func NewClient(opts ...ClientOption) Client {
 	for _, opt := range opts {
 		opt(client)
 	}
+	client.httpClient = httpx.New(httpx.Config{Name: "hyperliquid"})
+	client.httpClient.Limits().Set(httpx.Host(client.baseURL), client.rps, client.burst)
 
 	go client.maintainConnection()
 	return client
@@ -93,10 +102,12 @@ func WithWSURL(url string) ClientOption {
 	}
 }
 
-// WithRateLimit sets the rate limiter configuration
+// WithRateLimit overrides the limit of requests to the info and exchange
+// endpoints, 10 per second with bursts of 20 by default
 func WithRateLimit(rps float64, burst int) ClientOption {
 	return func(c *DefaultClient) {
-		c.limiter = rate.NewLimiter(rate.Limit(rps), burst)
+		c.rps = rps
+		c.burst = burst
 	}
 }
 
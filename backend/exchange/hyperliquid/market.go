@@ -9,10 +9,6 @@ import (
 
 // GetMarkets returns all available markets
 func (c *DefaultClient) GetMarkets(ctx context.Context) ([]Market, error) {
-	if err := c.limiter.Wait(ctx); err != nil {
-		return nil, err
-	}
-
 	url := fmt.Sprintf("%s/api/v1/markets", c.baseURL)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -39,10 +35,6 @@ func (c *DefaultClient) GetMarkets(ctx context.Context) ([]Market, error) {
 
 // GetOrderbook returns the current order book for a market
 func (c *DefaultClient) GetOrderbook(ctx context.Context, symbol string) (*Orderbook, error) {
-	if err := c.limiter.Wait(ctx); err != nil {
-		return nil, err
-	}
-
 	url := fmt.Sprintf("%s/api/v1/orderbook/%s", c.baseURL, symbol)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -69,10 +61,6 @@ func (c *DefaultClient) GetOrderbook(ctx context.Context, symbol string) (*Order
 
 // GetTrades returns recent trades for a market
 func (c *DefaultClient) GetTrades(ctx context.Context, symbol string, limit int) ([]Trade, error) {
-	if err := c.limiter.Wait(ctx); err != nil {
-		return nil, err
-	}
-
 	url := fmt.Sprintf("%s/api/v1/trades/%s?limit=%d", c.baseURL, symbol, limit)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -99,10 +87,6 @@ func (c *DefaultClient) GetTrades(ctx context.Context, symbol string, limit int)
 
 // GetFundingRate returns the current funding rate for a market
 func (c *DefaultClient) GetFundingRate(ctx context.Context, symbol string) (*FundingRate, error) {
-	if err := c.limiter.Wait(ctx); err != nil {
-		return nil, err
-	}
-
 	url := fmt.Sprintf("%s/api/v1/funding/%s", c.baseURL, symbol)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
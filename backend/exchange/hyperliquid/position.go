@@ -10,10 +10,6 @@ import (
 
 // GetPositions retrieves all positions
 func (c *DefaultClient) GetPositions(ctx context.Context) ([]Position, error) {
-	if err := c.limiter.Wait(ctx); err != nil {
-		return nil, err
-	}
-
 	url := fmt.Sprintf("%s/api/v1/positions", c.baseURL)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -44,10 +40,6 @@ func (c *DefaultClient) GetPositions(ctx context.Context) ([]Position, error) {
 
 // GetBalance retrieves account balance
 func (c *DefaultClient) GetBalance(ctx context.Context) (*Balance, error) {
-	if err := c.limiter.Wait(ctx); err != nil {
-		return nil, err
-	}
-
 	url := fmt.Sprintf("%s/api/v1/balance", c.baseURL)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -78,10 +70,6 @@ func (c *DefaultClient) GetBalance(ctx context.Context) (*Balance, error) {
 
 // GetLeverage retrieves current leverage for a symbol
 func (c *DefaultClient) GetLeverage(ctx context.Context, symbol string) (int, error) {
-	if err := c.limiter.Wait(ctx); err != nil {
-		return 0, err
-	}
-
 	url := fmt.Sprintf("%s/api/v1/leverage/%s", c.baseURL, symbol)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -114,10 +102,6 @@ func (c *DefaultClient) GetLeverage(ctx context.Context, symbol string) (int, er
 
 // SetLeverage sets leverage for a symbol
 func (c *DefaultClient) SetLeverage(ctx context.Context, symbol string, leverage int) error {
-	if err := c.limiter.Wait(ctx); err != nil {
-		return err
-	}
-
 	body, err := json.Marshal(struct {
 		Leverage int `json:"leverage"`
 	}{
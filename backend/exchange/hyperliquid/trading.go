@@ -10,10 +10,6 @@ import (
 
 // CreateOrder creates a new order
 func (c *DefaultClient) CreateOrder(ctx context.Context, req CreateOrderRequest) (*Order, error) {
-	if err := c.limiter.Wait(ctx); err != nil {
-		return nil, err
-	}
-
 	body, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("marshal request error: %w", err)
@@ -53,10 +49,6 @@ func (c *DefaultClient) CreateOrder(ctx context.Context, req CreateOrderRequest)
 
 // CancelOrder cancels an existing order
 func (c *DefaultClient) CancelOrder(ctx context.Context, orderID string) error {
-	if err := c.limiter.Wait(ctx); err != nil {
-		return err
-	}
-
 	url := fmt.Sprintf("%s/api/v1/order/%s", c.baseURL, orderID)
 	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
 	if err != nil {
@@ -85,10 +77,6 @@ func (c *DefaultClient) CancelOrder(ctx context.Context, orderID string) error {
 
 // GetOrder retrieves an order by ID
 func (c *DefaultClient) GetOrder(ctx context.Context, orderID string) (*Order, error) {
-	if err := c.limiter.Wait(ctx); err != nil {
-		return nil, err
-	}
-
 	url := fmt.Sprintf("%s/api/v1/order/%s", c.baseURL, orderID)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -126,10 +114,6 @@ func (c *DefaultClient) GetOrder(ctx context.Context, orderID string) (*Order, e
 
 // GetOpenOrders retrieves all open orders
 func (c *DefaultClient) GetOpenOrders(ctx context.Context) ([]Order, error) {
-	if err := c.limiter.Wait(ctx); err != nil {
-		return nil, err
-	}
-
 	url := fmt.Sprintf("%s/api/v1/orders/open", c.baseURL)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -0,0 +1,113 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "flag"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "os"
+    "os/signal"
+    "strings"
+    "syscall"
+    "time"
+)
+
+// runExport downloads an export of a running server to a file, as the
+// records of the engine live in the memory of the server:
+//
+//    backend export -dataset trades -from 2024-01-01 [-to 2025-01-01]
+//        [-format csv|parquet] [-columns time,symbol,size] [-account main]
+//        [-server http://localhost:8080] [-out trades.csv]
+//
+// The API token is read from GOSOL_API_TOKEN when the server requires one.
+func runExport(args []string) error {
+    flags := flag.NewFlagSet("export", flag.ContinueOnError)
+    server := flags.String("server", "http://localhost:8080", "URL of the server")
+    accountID := flags.String("account", "", "account to export, the default account when empty")
+    dataset := flags.String("dataset", "trades", "trades, positions, daily_stats or risk_checks")
+    format := flags.String("format", "csv", "csv or parquet")
+    from := flags.String("from", "", "start of the range, as a date or RFC 3339 time")
+    to := flags.String("to", "", "end of the range, excluded, as a date or RFC 3339 time, defaults to now")
+    columns := flags.String("columns", "", "comma separated columns, all when empty")
+    out := flags.String("out", "", "output file, - for stdout, defaults to <dataset>.<format>")
+    if err := flags.Parse(args); err != nil {
+        return err
+    }
+
+    query := url.Values{}
+    query.Set("format", *format)
+    start, err := parseExportTime(*from)
+    if err != nil {
+        return fmt.Errorf("invalid -from: %w", err)
+    }
+    query.Set("from", start.Format(time.RFC3339Nano))
+    if *to != "" {
+        end, err := parseExportTime(*to)
+        if err != nil {
+            return fmt.Errorf("invalid -to: %w", err)
+        }
+        query.Set("to", end.Format(time.RFC3339Nano))
+    }
+    if *columns != "" {
+        query.Set("columns", *columns)
+    }
+    endpoint := strings.TrimSuffix(*server, "/") + "/api/v1"
+    if *accountID != "" {
+        endpoint += "/accounts/" + url.PathEscape(*accountID)
+    }
+    endpoint += "/export/" + url.PathEscape(*dataset) + "?" + query.Encode()
+
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer stop()
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+    if err != nil {
+        return err
+    }
+    if token := os.Getenv("GOSOL_API_TOKEN"); token != "" {
+        req.Header.Set("Authorization", "Bearer "+token)
+    }
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        return fmt.Errorf("failed to request export: %w", err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+        return fmt.Errorf("export failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+    }
+
+    var w io.Writer = os.Stdout
+    if *out != "-" {
+        path := *out
+        if path == "" {
+            path = *dataset + "." + *format
+        }
+        f, err := os.Create(path)
+        if err != nil {
+            return err
+        }
+        defer f.Close()
+        w = f
+    }
+    if _, err := io.Copy(w, resp.Body); err != nil {
+        return fmt.Errorf("failed to download export: %w", err)
+    }
+    if f, ok := w.(*os.File); ok && f != os.Stdout {
+        return f.Close()
+    }
+    return nil
+}
+
+// parseExportTime parses a date or an RFC 3339 time
+func parseExportTime(value string) (time.Time, error) {
+    if value == "" {
+        return time.Time{}, errors.New("missing time")
+    }
+    if t, err := time.Parse("2006-01-02", value); err == nil {
+        return t, nil
+    }
+    return time.Parse(time.RFC3339Nano, value)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "strings"
+    "time"
+
+    "github.com/devinjacknz/godydxhyber/backend/config"
+    "github.com/devinjacknz/godydxhyber/backend/exchange/dydx"
+    "github.com/devinjacknz/godydxhyber/backend/exchange/dydxv4"
+    "github.com/devinjacknz/godydxhyber/backend/exchange/hyperliquid"
+    "github.com/devinjacknz/godydxhyber/backend/pkg/health"
+    "github.com/devinjacknz/godydxhyber/backend/trading/account"
+)
+
+// newHealthChecker creates the checker of the dependencies of the server:
+// the dYdX time endpoint and WebSocket feed of every account trading on an
+// exchange, the Hyperliquid API and the LLM models. The LLM only writes the
+// trade journal, so it does not hold back readiness.
+func newHealthChecker(cfg *config.Config, accounts *account.Registry) *health.Checker {
+    checker := health.NewChecker(health.Config{Interval: time.Duration(cfg.Server.HealthCheckInterval)})
+
+    exchanges := map[string]config.ExchangeConfig{account.Default: cfg.Exchanges.Dydx}
+    for _, ac := range cfg.Accounts {
+        exchanges[ac.ID] = ac.Exchange
+    }
+    for _, a := range accounts.List() {
+        ec := exchanges[a.ID]
+        if !ec.Enabled {
+            continue
+        }
+        name := "dydx"
+        if a.ID != account.Default {
+            name += "." + a.ID
+        }
+        checker.Register(name, health.HTTPProbe(http.MethodGet, dydxTimeURL(ec), nil, nil))
+
+        // Only v3 clients keep a WebSocket feed
+        if feed, ok := a.Exchange.(interface{ Connected() bool }); ok {
+            checker.Register(name+"_feed", func(ctx context.Context) error {
+                if !feed.Connected() {
+                    return errors.New("WebSocket feed disconnected")
+                }
+                return nil
+            })
+        }
+    }
+
+    if hc := cfg.Exchanges.Hyperliquid; hc.Enabled {
+        checker.Register("hyperliquid", health.HTTPProbe(http.MethodPost, baseURL(hc.BaseURL, hyperliquid.DefaultBaseURL)+"/info", nil, []byte(`{"type":"meta"}`)))
+    }
+
+    for name, mc := range map[string]config.ModelConfig{"llm_primary": cfg.LLM.Primary, "llm_fallback": cfg.LLM.Fallback} {
+        if mc.Name == "" {
+            continue
+        }
+        checker.Register(name, llmProbe(mc), health.WithKind(health.Informational))
+    }
+    return checker
+}
+
+// dydxTimeURL returns the server time endpoint of a dYdX exchange
+func dydxTimeURL(ec config.ExchangeConfig) string {
+    if ec.Version == config.DydxV4 {
+        return baseURL(ec.BaseURL, dydxv4.DefaultBaseURL) + "/v4/time"
+    }
+    return baseURL(ec.BaseURL, dydx.DefaultBaseURL) + "/v3/time"
+}
+
+// llmProbe lists the models of an LLM provider, which needs neither a prompt
+// nor tokens
+func llmProbe(mc config.ModelConfig) health.Probe {
+    if mc.Provider == config.ProviderDeepSeek {
+        header := http.Header{"Authorization": {"Bearer " + mc.APIKey}}
+        return health.HTTPProbe(http.MethodGet, strings.TrimSuffix(mc.BaseURL, "/")+"/v1/models", header, nil)
+    }
+    return health.HTTPProbe(http.MethodGet, strings.TrimSuffix(mc.BaseURL, "/")+"/api/tags", nil, nil)
+}
+
+func baseURL(configured, fallback string) string {
+    if configured == "" {
+        return fallback
+    }
+    return strings.TrimSuffix(configured, "/")
+}
@@ -0,0 +1,182 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/trading/analysis/monitoring"
+)
+
+// ErrAlreadyShutdown is returned when Shutdown is called more than once
+var ErrAlreadyShutdown = errors.New("shutdown already started")
+
+// Phase orders the shutdown hooks. Phases run in increasing order and the
+// hooks of a phase run in registration order.
+type Phase int
+
+const (
+	// PhaseIntake stops accepting new requests and trade signals
+	PhaseIntake Phase = iota + 1
+	// PhaseStrategies cancels pending strategy ticks
+	PhaseStrategies
+	// PhaseOrders drains order submissions and optionally cancels open orders
+	PhaseOrders
+	// PhaseFlush flushes monitoring events and repository writes
+	PhaseFlush
+	// PhaseConnections closes client connections
+	PhaseConnections
+)
+
+func (p Phase) String() string {
+	switch p {
+	case PhaseIntake:
+		return "intake"
+	case PhaseStrategies:
+		return "strategies"
+	case PhaseOrders:
+		return "orders"
+	case PhaseFlush:
+		return "flush"
+	case PhaseConnections:
+		return "connections"
+	default:
+		return fmt.Sprintf("phase(%d)", int(p))
+	}
+}
+
+// HookFunc is one shutdown step. It should return once its work is done or
+// ctx is done, whichever comes first.
+type HookFunc func(ctx context.Context) error
+
+// HookResult is the outcome of one shutdown hook
+type HookResult struct {
+	Phase    Phase         `json:"phase"`
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Report summarizes a shutdown
+type Report struct {
+	Results  []HookResult  `json:"results"`
+	Duration time.Duration `json:"duration"`
+	// TimedOut is set when the deadline passed before every hook finished
+	TimedOut bool `json:"timed_out"`
+}
+
+// Failed returns the hooks that returned an error
+func (r *Report) Failed() []HookResult {
+	var failed []HookResult
+	for _, result := range r.Results {
+		if result.Error != "" {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+type hook struct {
+	phase Phase
+	name  string
+	fn    HookFunc
+}
+
+// Manager runs the shutdown hooks of every subsystem in phase order
+type Manager struct {
+	hooks    []hook
+	shutdown bool
+	mu       sync.Mutex
+}
+
+// NewManager creates a lifecycle manager without hooks
+func NewManager() *Manager {
+	return &Manager{}
+}
+
+// Register adds a shutdown hook to a phase
+func (m *Manager) Register(phase Phase, name string, fn HookFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, hook{phase: phase, name: name, fn: fn})
+}
+
+// WaitForSignal blocks until one of the signals is received or ctx is done,
+// then shuts down with the given timeout
+func (m *Manager) WaitForSignal(ctx context.Context, timeout time.Duration, signals ...os.Signal) (*Report, error) {
+	sigCtx, stop := signal.NotifyContext(ctx, signals...)
+	<-sigCtx.Done()
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+	return m.Shutdown(shutdownCtx)
+}
+
+// Shutdown runs every hook in phase order. A failing hook does not stop the
+// ones after it, and hooks still run once ctx is done so that connections are
+// closed even when draining took too long; they should then return promptly.
+// The returned error joins the errors of the failed hooks.
+func (m *Manager) Shutdown(ctx context.Context) (*Report, error) {
+	m.mu.Lock()
+	if m.shutdown {
+		m.mu.Unlock()
+		return nil, ErrAlreadyShutdown
+	}
+	m.shutdown = true
+	hooks := make([]hook, len(m.hooks))
+	copy(hooks, m.hooks)
+	m.mu.Unlock()
+
+	// Stable, so hooks of a phase keep their registration order
+	ordered := make([]hook, 0, len(hooks))
+	for phase := PhaseIntake; phase <= PhaseConnections; phase++ {
+		for _, h := range hooks {
+			if h.phase == phase {
+				ordered = append(ordered, h)
+			}
+		}
+	}
+
+	start := time.Now()
+	report := &Report{}
+	var errs []error
+	for _, h := range ordered {
+		hookStart := time.Now()
+		err := h.fn(ctx)
+		duration := time.Since(hookStart)
+		monitoring.RecordIndicatorCalculation("shutdown_"+h.name, duration)
+
+		result := HookResult{Phase: h.phase, Name: h.name, Duration: duration}
+		if err != nil {
+			result.Error = err.Error()
+			errs = append(errs, fmt.Errorf("%s/%s: %w", h.phase, h.name, err))
+			monitoring.RecordIndicatorError("shutdown_"+h.name, err.Error())
+		}
+		report.Results = append(report.Results, result)
+	}
+	report.Duration = time.Since(start)
+	report.TimedOut = ctx.Err() != nil
+
+	severity := monitoring.SeverityInfo
+	if len(errs) > 0 || report.TimedOut {
+		severity = monitoring.SeverityWarning
+	}
+	monitoring.RecordEvent(monitoring.Event{
+		Type:     "shutdown",
+		Severity: severity,
+		Message:  "Shutdown complete",
+		Details: map[string]interface{}{
+			"hooks":     len(report.Results),
+			"failed":    len(errs),
+			"timed_out": report.TimedOut,
+			"duration":  report.Duration.String(),
+		},
+	})
+
+	return report, errors.Join(errs...)
+}
@@ -0,0 +1,102 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdown(t *testing.T) {
+	t.Run("Runs phases in order", func(t *testing.T) {
+		m := NewManager()
+		var calls []string
+		record := func(name string) HookFunc {
+			return func(ctx context.Context) error {
+				calls = append(calls, name)
+				return nil
+			}
+		}
+		m.Register(PhaseConnections, "websocket", record("websocket"))
+		m.Register(PhaseIntake, "http", record("http"))
+		m.Register(PhaseOrders, "drain", record("drain"))
+		m.Register(PhaseOrders, "cancel", record("cancel"))
+		m.Register(PhaseStrategies, "strategies", record("strategies"))
+		m.Register(PhaseFlush, "events", record("events"))
+
+		report, err := m.Shutdown(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"http", "strategies", "drain", "cancel", "events", "websocket"}, calls)
+		assert.Len(t, report.Results, 6)
+		assert.Empty(t, report.Failed())
+		assert.False(t, report.TimedOut)
+	})
+
+	t.Run("Continues after failures", func(t *testing.T) {
+		m := NewManager()
+		failure := errors.New("drain failed")
+		closed := false
+		m.Register(PhaseOrders, "drain", func(ctx context.Context) error {
+			return failure
+		})
+		m.Register(PhaseConnections, "websocket", func(ctx context.Context) error {
+			closed = true
+			return nil
+		})
+
+		report, err := m.Shutdown(context.Background())
+		assert.ErrorIs(t, err, failure)
+		assert.True(t, closed)
+		require.Len(t, report.Failed(), 1)
+		assert.Equal(t, "drain", report.Failed()[0].Name)
+		assert.Equal(t, PhaseOrders, report.Failed()[0].Phase)
+	})
+
+	t.Run("Runs remaining hooks after the deadline", func(t *testing.T) {
+		m := NewManager()
+		closed := false
+		m.Register(PhaseOrders, "drain", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		m.Register(PhaseConnections, "websocket", func(ctx context.Context) error {
+			closed = true
+			return nil
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		report, err := m.Shutdown(ctx)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.True(t, closed)
+		assert.True(t, report.TimedOut)
+	})
+
+	t.Run("Runs once", func(t *testing.T) {
+		m := NewManager()
+		_, err := m.Shutdown(context.Background())
+		require.NoError(t, err)
+		_, err = m.Shutdown(context.Background())
+		assert.ErrorIs(t, err, ErrAlreadyShutdown)
+	})
+}
+
+func TestWaitForSignal(t *testing.T) {
+	m := NewManager()
+	called := false
+	m.Register(PhaseIntake, "http", func(ctx context.Context) error {
+		called = true
+		_, ok := ctx.Deadline()
+		assert.True(t, ok)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := m.WaitForSignal(ctx, time.Second)
+	require.NoError(t, err)
+	assert.True(t, called)
+}
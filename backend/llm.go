@@ -0,0 +1,31 @@
+package main
+
+import (
+    "github.com/devinjacknz/godydxhyber/backend/config"
+    "github.com/devinjacknz/godydxhyber/backend/llm"
+)
+
+// newLLMClient creates a client on the configured models. The primary model
+// doubles as the fallback when no fallback is configured.
+func newLLMClient(cfg config.LLMConfig) llm.Client {
+    primary := llmModel(cfg.Primary)
+    fallback := primary
+    if cfg.Fallback.Name != "" {
+        fallback = llmModel(cfg.Fallback)
+    }
+    return llm.NewClient(primary, fallback)
+}
+
+func llmModel(mc config.ModelConfig) *llm.Model {
+    model := &llm.Model{
+        Type:      llm.LocalOllama,
+        Name:      mc.Name,
+        BaseURL:   mc.BaseURL,
+        APIKey:    mc.APIKey,
+        MaxTokens: mc.MaxTokens,
+    }
+    if mc.Provider == config.ProviderDeepSeek {
+        model.Type = llm.DeepSeekAPI
+    }
+    return model
+}
@@ -7,13 +7,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/devinjacknz/godydxhyber/backend/pkg/monitoring"
+	"github.com/devinjacknz/godydxhyber/backend/monitoring"
+	"github.com/devinjacknz/godydxhyber/backend/pkg/httpx"
 
 	"golang.org/x/time/rate"
 )
@@ -50,6 +51,8 @@ type Model struct {
 	Template string         `json:"template,omitempty"` // Sets the prompt template
 	System   string         `json:"system,omitempty"`   // Sets the system message
 	Options  map[string]any `json:"options,omitempty"`  // Additional model parameters
+	// MaxTokens limits the length of the response. Zero leaves the model default.
+	MaxTokens int `json:"max_tokens,omitempty"`
 }
 
 // Response represents the LLM response
@@ -58,14 +61,22 @@ type Response struct {
 	Metadata   map[string]string `json:"metadata,omitempty"`
 	ModelUsed  string            `json:"model_used"`
 	TokenCount int               `json:"token_count"`
+	// Err is set on the last response of a stream that failed, if the
+	// stream was started WithStreamErrors
+	Err error `json:"-"`
 }
 
 // Client defines the interface for LLM interactions
 type Client interface {
 	// Generate generates text from a prompt
-	Generate(ctx context.Context, prompt string) (*Response, error)
+	Generate(ctx context.Context, prompt string, opts ...CallOption) (*Response, error)
 	// Stream generates text from a prompt with streaming response
-	Stream(ctx context.Context, prompt string) (<-chan *Response, error)
+	Stream(ctx context.Context, prompt string, opts ...CallOption) (<-chan *Response, error)
+	// StreamCollect streams a generation, passing the text of each chunk to
+	// onChunk, and returns the assembled response
+	StreamCollect(ctx context.Context, prompt string, onChunk func(string), opts ...CallOption) (*Response, error)
+	// Embed returns the embedding vector of each text
+	Embed(ctx context.Context, texts []string) ([][]float64, error)
 	// GetModel returns the current model configuration
 	GetModel() *Model
 	// SetModel sets the model configuration
@@ -76,12 +87,18 @@ type Client interface {
 type DefaultClient struct {
 	primaryModel  *Model
 	fallbackModel *Model
-	httpClient    *http.Client
-	ollamaClient  *http.Client
-	retryCount    int
-	retryDelay    time.Duration
+	// embeddingModel serves Embed, the primary model when nil
+	embeddingModel *Model
+	httpClient     *httpx.Client
+	ollamaClient   *httpx.Client
+	retryCount     int
+	retryDelay     time.Duration
+	// hedgeDelay starts the fallback model next to a slow primary model,
+	// disabled when zero
+	hedgeDelay    time.Duration
 	maxConcurrent int
 	rateLimiter   *rate.Limiter
+	profiles      map[TaskProfile]*profileRoute
 	mu            sync.RWMutex
 }
 
@@ -90,8 +107,8 @@ func NewClient(primaryModel, fallbackModel *Model) Client {
 	return &DefaultClient{
 		primaryModel:  primaryModel,
 		fallbackModel: fallbackModel,
-		httpClient:    &http.Client{Timeout: 30 * time.Second},
-		ollamaClient:  &http.Client{Timeout: 60 * time.Second},
+		httpClient:    httpx.New(httpx.Config{Name: "llm", Timeout: 30 * time.Second}),
+		ollamaClient:  httpx.New(httpx.Config{Name: "ollama", Timeout: 60 * time.Second}),
 		retryCount:    3,
 		retryDelay:    time.Second,
 		maxConcurrent: 5,
@@ -99,67 +116,169 @@ func NewClient(primaryModel, fallbackModel *Model) Client {
 	}
 }
 
-// Generate implements text generation
-func (c *DefaultClient) Generate(ctx context.Context, prompt string) (*Response, error) {
-	if err := c.rateLimiter.Wait(ctx); err != nil {
-		return nil, fmt.Errorf("rate limit error: %w", err)
+// Generate implements text generation. Pass WithProfile to route the call
+// to the models configured for a task profile. Transient failures of a
+// model are retried before falling back to the next one.
+func (c *DefaultClient) Generate(ctx context.Context, prompt string, opts ...CallOption) (*Response, error) {
+	r, err := c.resolveRoute(opts)
+	if err != nil {
+		return nil, err
 	}
+	if err := c.wait(ctx, r); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
 
 	start := time.Now()
-	// Try primary model first
-	resp, err := c.generateWithModel(ctx, c.primaryModel, prompt)
+	profile := string(r.profile)
+	resp, err := c.generateRoute(ctx, r, prompt)
 	if err != nil {
-		// Log primary model failure
-		log.Printf("Primary model failed: %v, falling back to secondary model", err)
-		monitoring.RecordLLMFallback()
-
-		// Try fallback model
-		resp, err = c.generateWithModel(ctx, c.fallbackModel, prompt)
-		if err != nil {
-			monitoring.RecordLLMRequest(c.fallbackModel.Name, "generate", time.Since(start), "error", 0)
-			return nil, fmt.Errorf("both models failed: %w", err)
-		}
+		monitoring.RecordLLMProfileRequest(profile, r.fallback.Name, "generate", time.Since(start), "error", 0)
+		return nil, err
 	}
 
-	monitoring.RecordLLMRequest(resp.ModelUsed, "generate", time.Since(start), "success", resp.TokenCount)
+	monitoring.RecordLLMProfileRequest(profile, resp.ModelUsed, "generate", time.Since(start), "success", resp.TokenCount)
 	return resp, nil
 }
 
-// Stream implements streaming text generation
-func (c *DefaultClient) Stream(ctx context.Context, prompt string) (<-chan *Response, error) {
-	if err := c.rateLimiter.Wait(ctx); err != nil {
-		return nil, fmt.Errorf("rate limit error: %w", err)
+// Stream implements streaming text generation. Pass WithProfile to route the
+// call to the models configured for a task profile. If both models fail the
+// channel is closed; pass WithStreamErrors to receive a last response with
+// Err set first.
+func (c *DefaultClient) Stream(ctx context.Context, prompt string, opts ...CallOption) (<-chan *Response, error) {
+	r, err := c.resolveRoute(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.wait(ctx, r); err != nil {
+		return nil, err
 	}
 
 	responseChan := make(chan *Response)
 	start := time.Now()
+	profile := string(r.profile)
 
 	go func() {
 		defer close(responseChan)
+		ctx, cancel := r.withTimeout(ctx)
+		defer cancel()
 		totalTokens := 0
 
 		// Try primary model first
-		err := c.streamWithModel(ctx, c.primaryModel, prompt, responseChan)
+		modelUsed := r.primary.Name
+		err := c.streamWithModel(ctx, r.primary, prompt, responseChan)
 		if err != nil {
 			// Log primary model failure
-			log.Printf("Primary model stream failed: %v, falling back to secondary model", err)
-			monitoring.RecordLLMFallback()
+			slog.WarnContext(ctx, "primary model stream failed, falling back to secondary model", "profile", profile, "error", err)
+			monitoring.RecordLLMProfileFallback(profile)
 
 			// Try fallback model
-			if err := c.streamWithModel(ctx, c.fallbackModel, prompt, responseChan); err != nil {
-				log.Printf("Both models failed for streaming: %v", err)
-				monitoring.RecordLLMRequest(c.fallbackModel.Name, "stream", time.Since(start), "error", 0)
+			modelUsed = r.fallback.Name
+			if err := c.streamWithModel(ctx, r.fallback, prompt, responseChan); err != nil {
+				slog.ErrorContext(ctx, "both models failed for streaming", "profile", profile, "error", err)
+				monitoring.RecordLLMProfileRequest(profile, modelUsed, "stream", time.Since(start), "error", 0)
+				if r.streamErrors {
+					responseChan <- &Response{ModelUsed: modelUsed, Err: fmt.Errorf("both models failed: %w", err)}
+				}
 				return
 			}
 		}
 
-		monitoring.RecordLLMRequest(c.primaryModel.Name, "stream", time.Since(start), "success", totalTokens)
+		monitoring.RecordLLMProfileRequest(profile, modelUsed, "stream", time.Since(start), "success", totalTokens)
 	}()
 
 	return responseChan, nil
 }
 
+// StreamCollect streams a generation, calling onChunk with the text of each
+// chunk as it arrives, and returns the full text with the total token count
+// and duration. onChunk may be nil. The fallback model is only tried if the
+// primary model fails before streaming any text, so the chunks passed to
+// onChunk always add up to the returned text.
+func (c *DefaultClient) StreamCollect(ctx context.Context, prompt string, onChunk func(string), opts ...CallOption) (*Response, error) {
+	r, err := c.resolveRoute(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.wait(ctx, r); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	start := time.Now()
+	profile := string(r.profile)
+	modelUsed := r.primary.Name
+	resp, streamed, err := c.collectWithModel(ctx, r.primary, prompt, onChunk)
+	if err != nil && !streamed {
+		slog.WarnContext(ctx, "primary model stream failed, falling back to secondary model", "profile", profile, "error", err)
+		monitoring.RecordLLMProfileFallback(profile)
+
+		modelUsed = r.fallback.Name
+		resp, _, err = c.collectWithModel(ctx, r.fallback, prompt, onChunk)
+		if err != nil {
+			err = fmt.Errorf("both models failed: %w", err)
+		}
+	}
+	if err != nil {
+		monitoring.RecordLLMProfileRequest(profile, modelUsed, "stream", time.Since(start), "error", 0)
+		return nil, err
+	}
+
+	duration := time.Since(start)
+	resp.Metadata = map[string]string{
+		"duration": fmt.Sprintf("%.2fs", duration.Seconds()),
+	}
+	monitoring.RecordLLMProfileRequest(profile, resp.ModelUsed, "stream", duration, "success", resp.TokenCount)
+	return resp, nil
+}
+
+// collectWithModel streams a generation of one model into a single response.
+// streamed reports whether any text reached onChunk before an error.
+func (c *DefaultClient) collectWithModel(ctx context.Context, model *Model, prompt string, onChunk func(string)) (resp *Response, streamed bool, err error) {
+	chunks := make(chan *Response)
+	done := make(chan error, 1)
+	go func() {
+		defer close(chunks)
+		done <- c.streamWithModel(ctx, model, prompt, chunks)
+	}()
+
+	var text strings.Builder
+	tokens := 0
+	for chunk := range chunks {
+		// Token counts are running totals, complete on the final chunk
+		if chunk.TokenCount > 0 {
+			tokens = chunk.TokenCount
+		}
+		if chunk.Text == "" {
+			continue
+		}
+		streamed = true
+		text.WriteString(chunk.Text)
+		if onChunk != nil {
+			onChunk(chunk.Text)
+		}
+	}
+	if err := <-done; err != nil {
+		return nil, streamed, fmt.Errorf("model %s stream failed: %w", model.Name, err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, streamed, fmt.Errorf("model %s stream interrupted: %w", model.Name, err)
+	}
+	return &Response{
+		Text:       text.String(),
+		ModelUsed:  model.Name,
+		TokenCount: tokens,
+	}, streamed, nil
+}
+
 func (c *DefaultClient) validateModel(model *Model) error {
+	if model == nil {
+		return fmt.Errorf("model cannot be nil")
+	}
 	if model.Name == "" {
 		return fmt.Errorf("model name cannot be empty")
 	}
@@ -205,6 +324,20 @@ func (c *DefaultClient) streamWithModel(ctx context.Context, model *Model, promp
 	}
 }
 
+// ollamaOptions returns the model options with MaxTokens applied as num_predict
+func ollamaOptions(model *Model) map[string]any {
+	if model.MaxTokens <= 0 {
+		return model.Options
+	}
+
+	options := make(map[string]any, len(model.Options)+1)
+	for k, v := range model.Options {
+		options[k] = v
+	}
+	options["num_predict"] = model.MaxTokens
+	return options
+}
+
 func (c *DefaultClient) generateOllama(ctx context.Context, model *Model, prompt string) (*Response, error) {
 	reqBody := map[string]interface{}{
 		"model":  model.Name,
@@ -224,8 +357,8 @@ func (c *DefaultClient) generateOllama(ctx context.Context, model *Model, prompt
 	if model.System != "" {
 		reqBody["system"] = model.System
 	}
-	if len(model.Options) > 0 {
-		reqBody["options"] = model.Options
+	if options := ollamaOptions(model); len(options) > 0 {
+		reqBody["options"] = options
 	}
 
 	reqData, err := json.Marshal(reqBody)
@@ -246,7 +379,7 @@ func (c *DefaultClient) generateOllama(ctx context.Context, model *Model, prompt
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, &statusError{code: resp.StatusCode}
 	}
 
 	var ollamaResp struct {
@@ -281,11 +414,33 @@ func (c *DefaultClient) generateOllama(ctx context.Context, model *Model, prompt
 	}, nil
 }
 
-func (c *DefaultClient) generateDeepSeek(ctx context.Context, model *Model, prompt string) (*Response, error) {
+// deepSeekRequest returns the chat completion request body for a prompt,
+// mapping the system message, format and sampling options of the model
+func deepSeekRequest(model *Model, prompt string) map[string]interface{} {
+	messages := []map[string]string{{"role": "user", "content": prompt}}
+	if model.System != "" {
+		messages = append([]map[string]string{{"role": "system", "content": model.System}}, messages...)
+	}
 	reqBody := map[string]interface{}{
 		"model":    model.Name,
-		"messages": []map[string]string{{"role": "user", "content": prompt}},
+		"messages": messages,
+	}
+	if model.MaxTokens > 0 {
+		reqBody["max_tokens"] = model.MaxTokens
 	}
+	if model.Format == "json" {
+		reqBody["response_format"] = map[string]string{"type": "json_object"}
+	}
+	for _, key := range []string{"temperature", "top_p", "stop"} {
+		if v, ok := model.Options[key]; ok {
+			reqBody[key] = v
+		}
+	}
+	return reqBody
+}
+
+func (c *DefaultClient) generateDeepSeek(ctx context.Context, model *Model, prompt string) (*Response, error) {
+	reqBody := deepSeekRequest(model, prompt)
 
 	reqData, err := json.Marshal(reqBody)
 	if err != nil {
@@ -306,7 +461,7 @@ func (c *DefaultClient) generateDeepSeek(ctx context.Context, model *Model, prom
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, &statusError{code: resp.StatusCode}
 	}
 
 	var deepseekResp struct {
@@ -355,8 +510,8 @@ func (c *DefaultClient) streamOllama(ctx context.Context, model *Model, prompt s
 	if model.System != "" {
 		reqBody["system"] = model.System
 	}
-	if len(model.Options) > 0 {
-		reqBody["options"] = model.Options
+	if options := ollamaOptions(model); len(options) > 0 {
+		reqBody["options"] = options
 	}
 
 	reqData, err := json.Marshal(reqBody)
@@ -377,7 +532,7 @@ func (c *DefaultClient) streamOllama(ctx context.Context, model *Model, prompt s
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return &statusError{code: resp.StatusCode}
 	}
 
 	reader := bufio.NewReader(resp.Body)
@@ -422,11 +577,10 @@ func (c *DefaultClient) streamOllama(ctx context.Context, model *Model, prompt s
 }
 
 func (c *DefaultClient) streamDeepSeek(ctx context.Context, model *Model, prompt string, responseChan chan<- *Response) error {
-	reqBody := map[string]interface{}{
-		"model":    model.Name,
-		"messages": []map[string]string{{"role": "user", "content": prompt}},
-		"stream":   true,
-	}
+	reqBody := deepSeekRequest(model, prompt)
+	reqBody["stream"] = true
+	// The last chunk then carries the token usage
+	reqBody["stream_options"] = map[string]bool{"include_usage": true}
 
 	reqData, err := json.Marshal(reqBody)
 	if err != nil {
@@ -447,7 +601,7 @@ func (c *DefaultClient) streamDeepSeek(ctx context.Context, model *Model, prompt
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return &statusError{code: resp.StatusCode}
 	}
 
 	reader := bufio.NewReader(resp.Body)
@@ -475,6 +629,9 @@ func (c *DefaultClient) streamDeepSeek(ctx context.Context, model *Model, prompt
 					Content string `json:"content"`
 				} `json:"delta"`
 			} `json:"choices"`
+			Usage *struct {
+				TotalTokens int `json:"total_tokens"`
+			} `json:"usage"`
 		}
 
 		if err := json.Unmarshal([]byte(line), &streamResp); err != nil {
@@ -487,6 +644,12 @@ func (c *DefaultClient) streamDeepSeek(ctx context.Context, model *Model, prompt
 				ModelUsed: model.Name,
 			}
 		}
+		if streamResp.Usage != nil && streamResp.Usage.TotalTokens > 0 {
+			responseChan <- &Response{
+				ModelUsed:  model.Name,
+				TokenCount: streamResp.Usage.TotalTokens,
+			}
+		}
 	}
 
 	return nil
@@ -11,6 +11,8 @@ import (
 
 	"golang.org/x/time/rate"
 
+	"github.com/devinjacknz/godydxhyber/backend/monitoring"
+	"github.com/devinjacknz/godydxhyber/backend/pkg/httpx"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -140,6 +142,8 @@ func TestGenerate_FallbackModel(t *testing.T) {
 		},
 	)
 
+	fastRetries(t, client)
+
 	ctx := context.Background()
 	resp, err := client.Generate(ctx, "test prompt")
 	require.NoError(t, err)
@@ -174,6 +178,8 @@ func TestGenerate_BothModelsFail(t *testing.T) {
 		},
 	)
 
+	fastRetries(t, client)
+
 	ctx := context.Background()
 	_, err := client.Generate(ctx, "test prompt")
 	require.Error(t, err)
@@ -312,6 +318,42 @@ func TestStream_BothModelsFail(t *testing.T) {
 	assert.Empty(t, collectedResponses)
 }
 
+func TestStream_StreamErrors(t *testing.T) {
+	failingServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failingServer.Close()
+
+	emptyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"response":"","done":true}`)
+	}))
+	defer emptyServer.Close()
+
+	model := func(url string) *Model {
+		return &Model{Type: LocalOllama, Name: ModelLlama3, BaseURL: url}
+	}
+	collect := func(client Client) []*Response {
+		stream, err := client.Stream(context.Background(), "test prompt", WithStreamErrors())
+		require.NoError(t, err)
+		var responses []*Response
+		for resp := range stream {
+			responses = append(responses, resp)
+		}
+		return responses
+	}
+
+	responses := collect(NewClient(model(failingServer.URL), model(failingServer.URL)))
+	require.Len(t, responses, 1, "everything is down")
+	require.Error(t, responses[0].Err)
+	assert.Contains(t, responses[0].Err.Error(), "both models failed")
+	assert.Empty(t, responses[0].Text)
+
+	responses = collect(NewClient(model(emptyServer.URL), model(failingServer.URL)))
+	require.Len(t, responses, 1, "the model said nothing")
+	assert.NoError(t, responses[0].Err)
+	assert.Empty(t, responses[0].Text)
+}
+
 func TestRateLimiting(t *testing.T) {
 	ollamaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		response := map[string]interface{}{
@@ -428,6 +470,8 @@ func TestDeepSeekErrorResponse(t *testing.T) {
 		},
 	)
 
+	fastRetries(t, client)
+
 	ctx := context.Background()
 	_, err := client.Generate(ctx, "test prompt")
 	require.Error(t, err)
@@ -459,6 +503,8 @@ func TestInvalidJSON(t *testing.T) {
 		},
 	)
 
+	fastRetries(t, client)
+
 	ctx := context.Background()
 	_, err := client.Generate(ctx, "test prompt")
 	require.Error(t, err)
@@ -858,8 +904,8 @@ func TestGenerateRequestErrors(t *testing.T) {
 				"invalid": make(chan int), // Will cause marshal error
 			},
 		},
-		httpClient:   &http.Client{},
-		ollamaClient: &http.Client{},
+		httpClient:   httpx.New(httpx.Config{}),
+		ollamaClient: httpx.New(httpx.Config{}),
 		rateLimiter:  rate.NewLimiter(rate.Inf, 1),
 	}
 
@@ -875,8 +921,8 @@ func TestGenerateRequestErrors(t *testing.T) {
 			Name:    ModelLlama3,
 			BaseURL: string([]byte{0x7f}), // Invalid URL
 		},
-		httpClient:   &http.Client{},
-		ollamaClient: &http.Client{},
+		httpClient:   httpx.New(httpx.Config{}),
+		ollamaClient: httpx.New(httpx.Config{}),
 		rateLimiter:  rate.NewLimiter(rate.Inf, 1),
 	}
 
@@ -916,3 +962,200 @@ func TestStreamInvalidJSON(t *testing.T) {
 
 	assert.Empty(t, collectedResponses)
 }
+
+func TestProfileRouting(t *testing.T) {
+	var quickBody, deepBody map[string]interface{}
+	quickServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		quickBody = nil
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&quickBody))
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"response": "ok",
+			"metrics":  map[string]interface{}{"tokens": 3},
+			"done":     true,
+		})
+	}))
+	defer quickServer.Close()
+
+	deepServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&deepBody))
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]interface{}{"content": "analysis"}}},
+			"usage":   map[string]interface{}{"total_tokens": 500},
+		})
+	}))
+	defer deepServer.Close()
+
+	client := NewClient(
+		&Model{Type: LocalOllama, Name: ModelLlama3, BaseURL: quickServer.URL},
+		&Model{},
+	).(*DefaultClient)
+
+	err := client.SetProfile(ProfileQuickCheck, ProfileConfig{
+		Model:             &Model{Type: LocalOllama, Name: ModelPhi4, BaseURL: quickServer.URL, MaxTokens: 64, Options: map[string]any{"temperature": 0.1}},
+		Timeout:           time.Second,
+		RequestsPerSecond: 100,
+	})
+	require.NoError(t, err)
+	err = client.SetProfile(ProfileDeepAnalysis, ProfileConfig{
+		Model: &Model{Type: DeepSeekAPI, Name: "deepseek-reasoner", BaseURL: deepServer.URL, APIKey: "test-key", MaxTokens: 4096},
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	t.Run("routes by profile", func(t *testing.T) {
+		before := monitoring.GetProfileMetrics(string(ProfileQuickCheck)).RequestCount.Load()
+
+		resp, err := client.Generate(ctx, "sanity check", WithProfile(ProfileQuickCheck))
+		require.NoError(t, err)
+		assert.Equal(t, ModelPhi4, resp.ModelUsed)
+		assert.Equal(t, ModelPhi4, quickBody["model"])
+		options := quickBody["options"].(map[string]interface{})
+		assert.Equal(t, float64(64), options["num_predict"])
+		assert.Equal(t, 0.1, options["temperature"])
+
+		resp, err = client.Generate(ctx, "deep analysis", WithProfile(ProfileDeepAnalysis))
+		require.NoError(t, err)
+		assert.Equal(t, "analysis", resp.Text)
+		assert.Equal(t, float64(4096), deepBody["max_tokens"])
+
+		quick := monitoring.GetProfileMetrics(string(ProfileQuickCheck))
+		assert.Equal(t, before+1, quick.RequestCount.Load())
+		assert.GreaterOrEqual(t, monitoring.GetProfileMetrics(string(ProfileDeepAnalysis)).TotalTokens.Load(), int64(500))
+	})
+
+	t.Run("default route uses the primary model", func(t *testing.T) {
+		resp, err := client.Generate(ctx, "prompt")
+		require.NoError(t, err)
+		assert.Equal(t, ModelLlama3, resp.ModelUsed)
+		assert.Nil(t, quickBody["options"])
+	})
+
+	t.Run("streams with profile", func(t *testing.T) {
+		ch, err := client.Stream(ctx, "sanity check", WithProfile(ProfileQuickCheck))
+		require.NoError(t, err)
+		for resp := range ch {
+			assert.Equal(t, ModelPhi4, resp.ModelUsed)
+		}
+	})
+
+	t.Run("unknown profile", func(t *testing.T) {
+		_, err := client.Generate(ctx, "prompt", WithProfile("missing"))
+		assert.ErrorIs(t, err, ErrUnknownProfile)
+
+		_, err = client.Stream(ctx, "prompt", WithProfile("missing"))
+		assert.ErrorIs(t, err, ErrUnknownProfile)
+	})
+
+	t.Run("invalid profile config", func(t *testing.T) {
+		assert.Error(t, client.SetProfile(ProfileDefault, ProfileConfig{Model: &Model{Type: LocalOllama, Name: "x", BaseURL: "http://x"}}))
+		assert.Error(t, client.SetProfile("empty", ProfileConfig{}))
+		assert.Error(t, client.SetProfile("bad", ProfileConfig{Model: &Model{Type: DeepSeekAPI, Name: "x", BaseURL: "http://x"}}))
+	})
+}
+
+func TestProfileTimeout(t *testing.T) {
+	slowServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer slowServer.Close()
+
+	client := NewClient(&Model{}, &Model{}).(*DefaultClient)
+	slow := &Model{Type: LocalOllama, Name: ModelLlama3, BaseURL: slowServer.URL}
+	require.NoError(t, client.SetProfile(ProfileQuickCheck, ProfileConfig{Model: slow, Fallback: slow, Timeout: 50 * time.Millisecond}))
+
+	start := time.Now()
+	_, err := client.Generate(context.Background(), "prompt", WithProfile(ProfileQuickCheck))
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.GreaterOrEqual(t, monitoring.GetProfileMetrics(string(ProfileQuickCheck)).FallbackCount.Load(), int64(1))
+}
+
+func TestStreamCollect(t *testing.T) {
+	ollamaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, resp := range []string{
+			`{"response":"Part 1","done":false,"metrics":{"tokens":10}}`,
+			`{"response":" Part 2","done":false,"metrics":{"tokens":20}}`,
+			`{"response":"","done":true,"metrics":{"tokens":30}}`,
+		} {
+			fmt.Fprintln(w, resp)
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer ollamaServer.Close()
+
+	deepseekServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&reqBody))
+		assert.Equal(t, map[string]interface{}{"include_usage": true}, reqBody["stream_options"])
+		for _, resp := range []string{
+			`data: {"choices":[{"delta":{"content":"Fallback"}}]}`,
+			`data: {"choices":[],"usage":{"total_tokens":12}}`,
+			`data: [DONE]`,
+		} {
+			fmt.Fprintln(w, resp)
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer deepseekServer.Close()
+
+	failingServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failingServer.Close()
+
+	// truncatedServer drops the connection after the first chunk
+	truncatedServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"response":"Partial","done":false}`)
+		w.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}))
+	defer truncatedServer.Close()
+
+	ollama := func(url string) *Model {
+		return &Model{Type: LocalOllama, Name: ModelLlama3, BaseURL: url}
+	}
+	deepseek := &Model{Type: DeepSeekAPI, Name: ModelDeepSeekR1, BaseURL: deepseekServer.URL, APIKey: "test-key"}
+	ctx := context.Background()
+
+	t.Run("Assembles the chunks", func(t *testing.T) {
+		var chunks []string
+		resp, err := NewClient(ollama(ollamaServer.URL), deepseek).StreamCollect(ctx, "test prompt", func(chunk string) {
+			chunks = append(chunks, chunk)
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Part 1", " Part 2"}, chunks)
+		assert.Equal(t, "Part 1 Part 2", resp.Text)
+		assert.Equal(t, ModelLlama3, resp.ModelUsed)
+		assert.Equal(t, 30, resp.TokenCount)
+		assert.Contains(t, resp.Metadata, "duration")
+	})
+
+	t.Run("Falls back before any text", func(t *testing.T) {
+		resp, err := NewClient(ollama(failingServer.URL), deepseek).StreamCollect(ctx, "test prompt", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "Fallback", resp.Text)
+		assert.Equal(t, ModelDeepSeekR1, resp.ModelUsed)
+		assert.Equal(t, 12, resp.TokenCount)
+	})
+
+	t.Run("Returns the error of a truncated stream", func(t *testing.T) {
+		var chunks []string
+		resp, err := NewClient(ollama(truncatedServer.URL), deepseek).StreamCollect(ctx, "test prompt", func(chunk string) {
+			chunks = append(chunks, chunk)
+		})
+		require.Error(t, err)
+		assert.Nil(t, resp)
+		assert.Equal(t, []string{"Partial"}, chunks, "no fallback once text was streamed")
+	})
+
+	t.Run("Both models fail", func(t *testing.T) {
+		_, err := NewClient(ollama(failingServer.URL), ollama(failingServer.URL)).StreamCollect(ctx, "test prompt", nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "both models failed")
+		assert.Contains(t, err.Error(), "unexpected status code: 500")
+	})
+}
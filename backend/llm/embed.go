@@ -0,0 +1,167 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/monitoring"
+)
+
+// Embedder turns texts into embedding vectors
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float64, error)
+}
+
+// SetEmbeddingModel sets the model used by Embed, such as an Ollama
+// "nomic-embed-text". Models of type DeepSeekAPI are called on the
+// OpenAI-compatible /v1/embeddings endpoint, so any compatible server can be
+// used. Without an embedding model Embed uses the primary model.
+func (c *DefaultClient) SetEmbeddingModel(model *Model) error {
+	if err := c.validateModel(model); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.embeddingModel = model
+	return nil
+}
+
+// Embed returns the embedding vector of each text, in order
+func (c *DefaultClient) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	c.mu.RLock()
+	model := c.embeddingModel
+	if model == nil {
+		model = c.primaryModel
+	}
+	c.mu.RUnlock()
+	if err := c.validateModel(model); err != nil {
+		return nil, err
+	}
+	if err := c.rateLimiter.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("rate limit error: %w", err)
+	}
+
+	start := time.Now()
+	var vectors [][]float64
+	var err error
+	switch model.Type {
+	case LocalOllama:
+		vectors, err = c.embedOllama(ctx, model, texts)
+	default:
+		vectors, err = c.embedOpenAI(ctx, model, texts)
+	}
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+	monitoring.RecordLLMProfileRequest(string(ProfileDefault), model.Name, "embed", time.Since(start), status, 0)
+	if err != nil {
+		return nil, fmt.Errorf("embedding failed: %w", err)
+	}
+	return vectors, nil
+}
+
+// embedOllama embeds the texts one by one, as /api/embeddings takes a
+// single prompt
+func (c *DefaultClient) embedOllama(ctx context.Context, model *Model, texts []string) ([][]float64, error) {
+	vectors := make([][]float64, 0, len(texts))
+	for _, text := range texts {
+		var resp struct {
+			Embedding []float64 `json:"embedding"`
+		}
+		body := map[string]interface{}{"model": model.Name, "prompt": text}
+		if err := c.postJSON(ctx, model, "/api/embeddings", body, &resp); err != nil {
+			return nil, err
+		}
+		if len(resp.Embedding) == 0 {
+			return nil, fmt.Errorf("no embedding returned")
+		}
+		vectors = append(vectors, resp.Embedding)
+	}
+	return vectors, nil
+}
+
+func (c *DefaultClient) embedOpenAI(ctx context.Context, model *Model, texts []string) ([][]float64, error) {
+	var resp struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+	body := map[string]interface{}{"model": model.Name, "input": texts}
+	if err := c.postJSON(ctx, model, "/v1/embeddings", body, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Data) != len(texts) {
+		return nil, fmt.Errorf("got %d embeddings for %d texts", len(resp.Data), len(texts))
+	}
+
+	sort.Slice(resp.Data, func(i, j int) bool { return resp.Data[i].Index < resp.Data[j].Index })
+	vectors := make([][]float64, len(resp.Data))
+	for i, d := range resp.Data {
+		vectors[i] = d.Embedding
+	}
+	return vectors, nil
+}
+
+// postJSON posts body to the model API and decodes the response into out
+func (c *DefaultClient) postJSON(ctx context.Context, model *Model, path string, body, out interface{}) error {
+	reqData, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshal request error: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", model.BaseURL+path, bytes.NewReader(reqData))
+	if err != nil {
+		return fmt.Errorf("create request error: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	httpClient := c.ollamaClient
+	if model.Type != LocalOllama {
+		req.Header.Set("Authorization", "Bearer "+model.APIKey)
+		httpClient = c.httpClient
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("do request error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &statusError{code: resp.StatusCode}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response error: %w", err)
+	}
+	return nil
+}
+
+// CosineSimilarity returns the cosine of the angle between two vectors, from
+// -1 to 1. Vectors of different lengths or without magnitude score 0.
+func CosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
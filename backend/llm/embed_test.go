@@ -0,0 +1,80 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbed(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Ollama", func(t *testing.T) {
+		var prompts []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/embeddings", r.URL.Path)
+			var req map[string]string
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, "nomic-embed-text", req["model"])
+			prompts = append(prompts, req["prompt"])
+			json.NewEncoder(w).Encode(map[string]interface{}{"embedding": []float64{float64(len(req["prompt"])), 1}})
+		}))
+		defer server.Close()
+
+		client := NewClient(&Model{Type: LocalOllama, Name: ModelLlama3, BaseURL: server.URL}, &Model{})
+		require.NoError(t, client.(*DefaultClient).SetEmbeddingModel(&Model{Type: LocalOllama, Name: "nomic-embed-text", BaseURL: server.URL}))
+
+		vectors, err := client.Embed(ctx, []string{"a", "bbb"})
+		require.NoError(t, err)
+		assert.Equal(t, [][]float64{{1, 1}, {3, 1}}, vectors)
+		assert.Equal(t, []string{"a", "bbb"}, prompts)
+	})
+
+	t.Run("OpenAICompatible", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/embeddings", r.URL.Path)
+			assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+			var req struct {
+				Input []string `json:"input"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, []string{"a", "b"}, req.Input)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": []map[string]interface{}{
+					{"index": 1, "embedding": []float64{0, 1}},
+					{"index": 0, "embedding": []float64{1, 0}},
+				},
+			})
+		}))
+		defer server.Close()
+
+		client := NewClient(&Model{Type: DeepSeekAPI, Name: "text-embedding-3-small", BaseURL: server.URL, APIKey: "test-key"}, &Model{})
+		vectors, err := client.Embed(ctx, []string{"a", "b"})
+		require.NoError(t, err)
+		assert.Equal(t, [][]float64{{1, 0}, {0, 1}}, vectors, "embeddings are returned in input order")
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		client := NewClient(&Model{Type: LocalOllama, Name: ModelLlama3, BaseURL: server.URL}, &Model{})
+		_, err := client.Embed(ctx, []string{"a"})
+		assert.ErrorContains(t, err, "unexpected status code: 404")
+	})
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	assert.InDelta(t, 1, CosineSimilarity([]float64{1, 2}, []float64{2, 4}), 1e-9)
+	assert.InDelta(t, 0, CosineSimilarity([]float64{1, 0}, []float64{0, 1}), 1e-9)
+	assert.InDelta(t, -1, CosineSimilarity([]float64{1, 0}, []float64{-1, 0}), 1e-9)
+	assert.Zero(t, CosineSimilarity([]float64{1}, []float64{1, 0}))
+	assert.Zero(t, CosineSimilarity([]float64{0, 0}, []float64{1, 0}))
+}
@@ -0,0 +1,529 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidVariant is returned when an evaluation variant is misconfigured
+var ErrInvalidVariant = errors.New("invalid evaluation variant")
+
+// Direction is the price move an analysis predicts
+type Direction string
+
+const (
+	DirectionUp   Direction = "up"
+	DirectionDown Direction = "down"
+	DirectionFlat Direction = "flat"
+	// DirectionUnknown means no prediction could be read from the output.
+	// Such outputs are not scored.
+	DirectionUnknown Direction = "unknown"
+)
+
+// Generator generates text from a prompt. Client implements it.
+type Generator interface {
+	Generate(ctx context.Context, prompt string, opts ...CallOption) (*Response, error)
+}
+
+// Variant is one arm of an A/B evaluation: the models of a task profile and
+// a prompt template
+type Variant struct {
+	Name string `json:"name"`
+	// Profile selects the models of the variant. Empty uses the client's
+	// primary and fallback models.
+	Profile TaskProfile `json:"profile,omitempty"`
+	// Template is a text/template rendering the prompt from an EvalContext
+	Template string `json:"template"`
+	// Options are added to the calls of the variant, such as
+	// WithTemperature(0) for deterministic predictions
+	Options []CallOption `json:"-"`
+}
+
+// EvalContext is the market context sent to both variants
+type EvalContext struct {
+	Symbol string `json:"symbol"`
+	// Price is the price at the time of the analysis, the base of the
+	// realized move
+	Price float64 `json:"price"`
+	// Data is the market data the prompt templates render, such as candles
+	// and indicators
+	Data any       `json:"data,omitempty"`
+	Time time.Time `json:"time"`
+	// Examples are the similar past situations recalled from the memory of
+	// the evaluator, for the templates to render as few-shot context
+	Examples string `json:"examples,omitempty"`
+}
+
+// EvalOutput is the analysis of one variant in a trial
+type EvalOutput struct {
+	ID         string        `json:"id"`
+	Variant    string        `json:"variant"`
+	Profile    TaskProfile   `json:"profile,omitempty"`
+	Model      string        `json:"model,omitempty"`
+	Text       string        `json:"text,omitempty"`
+	Direction  Direction     `json:"direction"`
+	Confidence float64       `json:"confidence,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Latency    time.Duration `json:"latency"`
+	// Hit and Score are set once the trial is scored. Score is the realized
+	// return in the predicted direction; flat predictions score the
+	// negative absolute return beyond the flat threshold.
+	Hit   bool    `json:"hit"`
+	Score float64 `json:"score"`
+}
+
+// EvalTrial is one market context analyzed by both variants
+type EvalTrial struct {
+	ID      string        `json:"id"`
+	Context EvalContext   `json:"context"`
+	Outputs []*EvalOutput `json:"outputs"`
+	// DueAt is the end of the horizon over which the trial is scored
+	DueAt  time.Time `json:"due_at"`
+	Scored bool      `json:"scored"`
+	// ExitPrice and Return are the price at scoring and the realized move
+	// from the context price
+	ExitPrice float64   `json:"exit_price,omitempty"`
+	Return    float64   `json:"return,omitempty"`
+	ScoredAt  time.Time `json:"scored_at,omitempty"`
+}
+
+func (t *EvalTrial) clone() *EvalTrial {
+	c := *t
+	c.Outputs = make([]*EvalOutput, len(t.Outputs))
+	for i, o := range t.Outputs {
+		copied := *o
+		c.Outputs[i] = &copied
+	}
+	return &c
+}
+
+// EvalStore persists evaluation trials
+type EvalStore interface {
+	SaveTrial(ctx context.Context, trial *EvalTrial) error
+	// ListTrials returns the trials in the order they were created
+	ListTrials(ctx context.Context) ([]*EvalTrial, error)
+}
+
+// MemoryEvalStore keeps evaluation trials in memory
+type MemoryEvalStore struct {
+	trials map[string]*EvalTrial
+	order  []string
+	mu     sync.RWMutex
+}
+
+// NewMemoryEvalStore creates an in-memory evaluation store
+func NewMemoryEvalStore() *MemoryEvalStore {
+	return &MemoryEvalStore{trials: make(map[string]*EvalTrial)}
+}
+
+// SaveTrial inserts or replaces a trial
+func (s *MemoryEvalStore) SaveTrial(ctx context.Context, trial *EvalTrial) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.trials[trial.ID]; !ok {
+		s.order = append(s.order, trial.ID)
+	}
+	s.trials[trial.ID] = trial.clone()
+	return nil
+}
+
+// ListTrials returns the trials in the order they were created
+func (s *MemoryEvalStore) ListTrials(ctx context.Context) ([]*EvalTrial, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	trials := make([]*EvalTrial, len(s.order))
+	for i, id := range s.order {
+		trials[i] = s.trials[id].clone()
+	}
+	return trials, nil
+}
+
+// PriceSource returns the current price of a symbol
+type PriceSource interface {
+	Price(ctx context.Context, symbol string) (float64, error)
+}
+
+// EvalConfig configures an evaluation
+type EvalConfig struct {
+	// Horizon is the time after a trial over which its predictions are
+	// scored
+	Horizon time.Duration
+	// FlatThreshold is the absolute return, as a fraction, within which a
+	// move counts as flat
+	FlatThreshold float64
+}
+
+// DefaultEvalConfig returns the default evaluation configuration
+func DefaultEvalConfig() EvalConfig {
+	return EvalConfig{
+		Horizon:       time.Hour,
+		FlatThreshold: 0.002,
+	}
+}
+
+type compiledVariant struct {
+	Variant
+	template *template.Template
+}
+
+// Evaluator sends the same market context to two variants, stores both
+// analyses and scores them against the realized price move once their
+// horizon has passed
+type Evaluator struct {
+	generator Generator
+	variants  [2]compiledVariant
+	store     EvalStore
+	config    EvalConfig
+	memory    *Memory
+	now       func() time.Time
+}
+
+// NewEvaluator creates an A/B evaluation of variants a and b
+func NewEvaluator(generator Generator, a, b Variant, store EvalStore, config EvalConfig) (*Evaluator, error) {
+	defaults := DefaultEvalConfig()
+	if config.Horizon <= 0 {
+		config.Horizon = defaults.Horizon
+	}
+	if config.FlatThreshold <= 0 {
+		config.FlatThreshold = defaults.FlatThreshold
+	}
+
+	e := &Evaluator{generator: generator, store: store, config: config, now: time.Now}
+	for i, v := range []Variant{a, b} {
+		if v.Name == "" {
+			return nil, fmt.Errorf("%w: missing name", ErrInvalidVariant)
+		}
+		tmpl, err := template.New(v.Name).Option("missingkey=error").Parse(v.Template)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidVariant, v.Name, err)
+		}
+		e.variants[i] = compiledVariant{Variant: v, template: tmpl}
+	}
+	if a.Name == b.Name {
+		return nil, fmt.Errorf("%w: both variants are named %s", ErrInvalidVariant, a.Name)
+	}
+	return e, nil
+}
+
+// Run analyzes the market context with both variants concurrently and stores
+// the trial. Failed generations are recorded on their output; an error is
+// returned only if the trial cannot be rendered or stored.
+func (e *Evaluator) Run(ctx context.Context, evalCtx EvalContext) (*EvalTrial, error) {
+	if evalCtx.Time.IsZero() {
+		evalCtx.Time = e.now()
+	}
+	if e.memory != nil && evalCtx.Examples == "" {
+		examples, err := e.memory.Examples(ctx, evalCtx.Symbol, situationText(evalCtx))
+		if err != nil {
+			slog.WarnContext(ctx, "failed to recall similar situations", "symbol", evalCtx.Symbol, "error", err)
+		}
+		evalCtx.Examples = examples
+	}
+	prompts := make([]string, len(e.variants))
+	for i, v := range e.variants {
+		var buf bytes.Buffer
+		if err := v.template.Execute(&buf, evalCtx); err != nil {
+			return nil, fmt.Errorf("failed to render prompt of variant %s: %w", v.Name, err)
+		}
+		prompts[i] = buf.String()
+	}
+
+	trial := &EvalTrial{
+		ID:      uuid.New().String(),
+		Context: evalCtx,
+		Outputs: make([]*EvalOutput, len(e.variants)),
+		DueAt:   evalCtx.Time.Add(e.config.Horizon),
+	}
+	var wg sync.WaitGroup
+	for i := range e.variants {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			trial.Outputs[i] = e.generate(ctx, e.variants[i].Variant, prompts[i])
+		}(i)
+	}
+	wg.Wait()
+
+	if err := e.store.SaveTrial(ctx, trial); err != nil {
+		return nil, fmt.Errorf("failed to save trial: %w", err)
+	}
+	return trial, nil
+}
+
+func (e *Evaluator) generate(ctx context.Context, v Variant, prompt string) *EvalOutput {
+	output := &EvalOutput{
+		ID:        uuid.New().String(),
+		Variant:   v.Name,
+		Profile:   v.Profile,
+		Direction: DirectionUnknown,
+	}
+
+	var opts []CallOption
+	if v.Profile != "" {
+		opts = append(opts, WithProfile(v.Profile))
+	}
+	opts = append(opts, v.Options...)
+	start := time.Now()
+	resp, err := e.generator.Generate(ctx, prompt, opts...)
+	output.Latency = time.Since(start)
+	if err != nil {
+		output.Error = err.Error()
+		return output
+	}
+	output.Model = resp.ModelUsed
+	output.Text = resp.Text
+	output.Direction, output.Confidence = ParsePrediction(resp.Text)
+	return output
+}
+
+// Score scores the trials whose horizon has passed against the current
+// price of their symbol, and returns the number scored. Trials whose price
+// cannot be read are left for the next call.
+func (e *Evaluator) Score(ctx context.Context, prices PriceSource) (int, error) {
+	trials, err := e.store.ListTrials(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list trials: %w", err)
+	}
+
+	now := e.now()
+	scored := 0
+	var errs []error
+	for _, trial := range trials {
+		if trial.Scored || now.Before(trial.DueAt) || trial.Context.Price <= 0 {
+			continue
+		}
+		price, err := prices.Price(ctx, trial.Context.Symbol)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get price of %s: %w", trial.Context.Symbol, err))
+			continue
+		}
+		e.score(trial, price, now)
+		if err := e.store.SaveTrial(ctx, trial); err != nil {
+			errs = append(errs, fmt.Errorf("failed to save trial %s: %w", trial.ID, err))
+			continue
+		}
+		scored++
+		if err := e.remember(ctx, trial); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remember trial %s: %w", trial.ID, err))
+		}
+	}
+	return scored, errors.Join(errs...)
+}
+
+// SetMemory makes the evaluator recall similar past situations into the
+// Examples of each trial, and remember the situation of each scored trial
+// with its outcome
+func (e *Evaluator) SetMemory(memory *Memory) {
+	e.memory = memory
+}
+
+func (e *Evaluator) remember(ctx context.Context, trial *EvalTrial) error {
+	if e.memory == nil {
+		return nil
+	}
+	_, err := e.memory.Remember(ctx, Situation{
+		ID:      trial.ID,
+		Symbol:  trial.Context.Symbol,
+		Text:    situationText(trial.Context),
+		Outcome: fmt.Sprintf("price moved %+.2f%% to %g within %s", trial.Return*100, trial.ExitPrice, e.config.Horizon),
+		Time:    trial.Context.Time,
+	})
+	return err
+}
+
+// situationText describes the market of an evaluation for embedding
+func situationText(evalCtx EvalContext) string {
+	data, ok := evalCtx.Data.(string)
+	if !ok && evalCtx.Data != nil {
+		encoded, err := json.Marshal(evalCtx.Data)
+		if err != nil {
+			data = fmt.Sprint(evalCtx.Data)
+		} else {
+			data = string(encoded)
+		}
+	}
+	return fmt.Sprintf("%s at %g: %s", evalCtx.Symbol, evalCtx.Price, data)
+}
+
+func (e *Evaluator) score(trial *EvalTrial, price float64, now time.Time) {
+	ret := price/trial.Context.Price - 1
+	trial.Scored = true
+	trial.ExitPrice = price
+	trial.Return = ret
+	trial.ScoredAt = now
+
+	for _, output := range trial.Outputs {
+		switch output.Direction {
+		case DirectionUp:
+			output.Score = ret
+			output.Hit = ret > e.config.FlatThreshold
+		case DirectionDown:
+			output.Score = -ret
+			output.Hit = ret < -e.config.FlatThreshold
+		case DirectionFlat:
+			output.Score = -math.Max(math.Abs(ret)-e.config.FlatThreshold, 0)
+			output.Hit = math.Abs(ret) <= e.config.FlatThreshold
+		}
+	}
+}
+
+// VariantReport summarizes the scored predictions of a variant
+type VariantReport struct {
+	Variant string `json:"variant"`
+	Trials  int    `json:"trials"`
+	// Failed counts the generations that failed and Unparsed the outputs
+	// without a readable prediction
+	Failed   int `json:"failed"`
+	Unparsed int `json:"unparsed"`
+	Scored   int `json:"scored"`
+	Hits     int `json:"hits"`
+	// HitRate is the share of scored predictions that were right
+	HitRate float64 `json:"hit_rate"`
+	// MeanScore is the mean return in the predicted direction
+	MeanScore float64 `json:"mean_score"`
+	// MeanLatency is the mean generation time
+	MeanLatency time.Duration `json:"mean_latency"`
+}
+
+// EvalReport compares the variants of an evaluation
+type EvalReport struct {
+	Variants []VariantReport `json:"variants"`
+	// Pending counts the trials whose horizon has not passed yet
+	Pending int `json:"pending"`
+	// Leader is the variant with the higher mean score, empty until both
+	// have scored predictions or while they are tied
+	Leader string `json:"leader,omitempty"`
+}
+
+// Report summarizes the stored trials per variant
+func (e *Evaluator) Report(ctx context.Context) (*EvalReport, error) {
+	trials, err := e.store.ListTrials(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trials: %w", err)
+	}
+
+	reports := make(map[string]*VariantReport, len(e.variants))
+	var names []string
+	for _, v := range e.variants {
+		reports[v.Name] = &VariantReport{Variant: v.Name}
+		names = append(names, v.Name)
+	}
+	latencies := make(map[string]time.Duration)
+
+	report := &EvalReport{}
+	for _, trial := range trials {
+		if !trial.Scored {
+			report.Pending++
+		}
+		for _, output := range trial.Outputs {
+			r, ok := reports[output.Variant]
+			if !ok {
+				// Trials of a former variant under another name
+				r = &VariantReport{Variant: output.Variant}
+				reports[output.Variant] = r
+				names = append(names, output.Variant)
+			}
+			r.Trials++
+			latencies[output.Variant] += output.Latency
+			switch {
+			case output.Error != "":
+				r.Failed++
+			case output.Direction == DirectionUnknown:
+				r.Unparsed++
+			case trial.Scored:
+				r.Scored++
+				r.MeanScore += output.Score
+				if output.Hit {
+					r.Hits++
+				}
+			}
+		}
+	}
+
+	for _, name := range names {
+		r := reports[name]
+		if r.Scored > 0 {
+			r.HitRate = float64(r.Hits) / float64(r.Scored)
+			r.MeanScore /= float64(r.Scored)
+		}
+		if r.Trials > 0 {
+			r.MeanLatency = latencies[name] / time.Duration(r.Trials)
+		}
+		report.Variants = append(report.Variants, *r)
+	}
+
+	a, b := reports[e.variants[0].Name], reports[e.variants[1].Name]
+	if a.Scored > 0 && b.Scored > 0 && a.MeanScore != b.MeanScore {
+		report.Leader = a.Variant
+		if b.MeanScore > a.MeanScore {
+			report.Leader = b.Variant
+		}
+	}
+	return report, nil
+}
+
+// jsonObject matches the outermost JSON object of a text
+var jsonObject = regexp.MustCompile(`(?s)\{.*\}`)
+
+// ParsePrediction reads the predicted direction and confidence from an
+// analysis. A JSON object with a "direction", "prediction", "signal" or
+// "trend" field is preferred; otherwise the direction words of the text are
+// counted. It returns DirectionUnknown if neither gives a direction.
+func ParsePrediction(text string) (Direction, float64) {
+	if raw := jsonObject.FindString(text); raw != "" {
+		var fields map[string]any
+		if err := json.Unmarshal([]byte(raw), &fields); err == nil {
+			for _, key := range []string{"direction", "prediction", "signal", "trend"} {
+				value, ok := fields[key].(string)
+				if !ok {
+					continue
+				}
+				if direction := directionOf(value); direction != DirectionUnknown {
+					confidence, _ := fields["confidence"].(float64)
+					return direction, confidence
+				}
+			}
+		}
+	}
+
+	counts := make(map[Direction]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z')
+	}) {
+		if direction := directionOf(word); direction != DirectionUnknown {
+			counts[direction]++
+		}
+	}
+	ranked := []Direction{DirectionUp, DirectionDown, DirectionFlat}
+	sort.SliceStable(ranked, func(i, j int) bool { return counts[ranked[i]] > counts[ranked[j]] })
+	if counts[ranked[0]] == 0 || counts[ranked[0]] == counts[ranked[1]] {
+		return DirectionUnknown, 0
+	}
+	return ranked[0], 0
+}
+
+func directionOf(word string) Direction {
+	switch strings.ToLower(strings.TrimSpace(word)) {
+	case "up", "bullish", "buy", "long":
+		return DirectionUp
+	case "down", "bearish", "sell", "short":
+		return DirectionDown
+	case "flat", "neutral", "hold", "sideways":
+		return DirectionFlat
+	default:
+		return DirectionUnknown
+	}
+}
@@ -0,0 +1,127 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// profileGenerator answers each task profile with a fixed text
+type profileGenerator map[TaskProfile]string
+
+func (g profileGenerator) Generate(ctx context.Context, prompt string, opts ...CallOption) (*Response, error) {
+	options := callOptions{profile: ProfileDefault}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	text, ok := g[options.profile]
+	if !ok {
+		return nil, errors.New("model unavailable")
+	}
+	return &Response{Text: text + "\n" + prompt, ModelUsed: string(options.profile)}, nil
+}
+
+type staticPrices map[string]float64
+
+func (p staticPrices) Price(ctx context.Context, symbol string) (float64, error) {
+	price, ok := p[symbol]
+	if !ok {
+		return 0, errors.New("no price")
+	}
+	return price, nil
+}
+
+func TestEvaluator(t *testing.T) {
+	ctx := context.Background()
+	generator := profileGenerator{
+		ProfileDefault:      `{"direction": "up", "confidence": 0.8}`,
+		ProfileDeepAnalysis: "The setup looks bearish.",
+	}
+	store := NewMemoryEvalStore()
+	e, err := NewEvaluator(generator,
+		Variant{Name: "baseline", Template: "Analyze {{.Symbol}} at {{.Price}}"},
+		Variant{Name: "deep", Profile: ProfileDeepAnalysis, Template: "Deep analysis of {{.Symbol}}: {{.Data}}"},
+		store, EvalConfig{Horizon: time.Hour, FlatThreshold: 0.01})
+	require.NoError(t, err)
+	now := time.Date(2024, 6, 3, 12, 0, 0, 0, time.UTC)
+	e.now = func() time.Time { return now }
+
+	trial, err := e.Run(ctx, EvalContext{Symbol: "BTC-USD", Price: 100, Data: "rsi=72"})
+	require.NoError(t, err)
+	require.Len(t, trial.Outputs, 2)
+	assert.Equal(t, now.Add(time.Hour), trial.DueAt)
+	baseline, deep := trial.Outputs[0], trial.Outputs[1]
+	assert.Equal(t, DirectionUp, baseline.Direction)
+	assert.Equal(t, 0.8, baseline.Confidence)
+	assert.Contains(t, baseline.Text, "Analyze BTC-USD at 100")
+	assert.Equal(t, DirectionDown, deep.Direction)
+	assert.Contains(t, deep.Text, "Deep analysis of BTC-USD: rsi=72")
+	assert.NotEqual(t, baseline.ID, deep.ID)
+
+	// The deep variant fails on the second trial
+	delete(generator, ProfileDeepAnalysis)
+	_, err = e.Run(ctx, EvalContext{Symbol: "ETH-USD", Price: 50})
+	require.NoError(t, err)
+
+	prices := staticPrices{"BTC-USD": 125}
+	scored, err := e.Score(ctx, prices)
+	require.NoError(t, err)
+	assert.Zero(t, scored, "trials are scored after their horizon")
+
+	now = now.Add(time.Hour)
+	scored, err = e.Score(ctx, prices)
+	assert.Error(t, err, "missing prices are reported")
+	assert.Equal(t, 1, scored)
+
+	trials, err := store.ListTrials(ctx)
+	require.NoError(t, err)
+	require.Len(t, trials, 2)
+	assert.True(t, trials[0].Scored)
+	assert.InDelta(t, 0.25, trials[0].Return, 1e-9)
+	assert.True(t, trials[0].Outputs[0].Hit)
+	assert.InDelta(t, 0.25, trials[0].Outputs[0].Score, 1e-9)
+	assert.False(t, trials[0].Outputs[1].Hit)
+	assert.InDelta(t, -0.25, trials[0].Outputs[1].Score, 1e-9)
+	assert.False(t, trials[1].Scored, "left for the next call")
+
+	report, err := e.Report(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Pending)
+	assert.Equal(t, "baseline", report.Leader)
+	require.Len(t, report.Variants, 2)
+	assert.Equal(t, VariantReport{Variant: "baseline", Trials: 2, Scored: 1, Hits: 1, HitRate: 1, MeanScore: 0.25}, withoutLatency(report.Variants[0]))
+	assert.Equal(t, VariantReport{Variant: "deep", Trials: 2, Failed: 1, Scored: 1, MeanScore: -0.25}, withoutLatency(report.Variants[1]))
+
+	_, err = NewEvaluator(generator, Variant{Name: "a"}, Variant{Name: "a"}, store, EvalConfig{})
+	assert.ErrorIs(t, err, ErrInvalidVariant)
+	_, err = NewEvaluator(generator, Variant{Name: "a", Template: "{{.Symbol"}, Variant{Name: "b"}, store, EvalConfig{})
+	assert.ErrorIs(t, err, ErrInvalidVariant)
+}
+
+func withoutLatency(r VariantReport) VariantReport {
+	r.MeanLatency = 0
+	return r
+}
+
+func TestParsePrediction(t *testing.T) {
+	tests := []struct {
+		text       string
+		direction  Direction
+		confidence float64
+	}{
+		{`Analysis: {"signal": "SELL", "confidence": 0.6}`, DirectionDown, 0.6},
+		{`{"trend": "sideways"}`, DirectionFlat, 0},
+		{`{"summary": "momentum is fading"} Overall bullish, buy the dip.`, DirectionUp, 0},
+		{"Bullish on the daily, bearish on the hourly.", DirectionUnknown, 0},
+		{"Not enough data.", DirectionUnknown, 0},
+	}
+	for _, tt := range tests {
+		direction, confidence := ParsePrediction(tt.text)
+		assert.Equal(t, tt.direction, direction, tt.text)
+		assert.Equal(t, tt.confidence, confidence, tt.text)
+	}
+}
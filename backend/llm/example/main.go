@@ -2,15 +2,44 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"time"
 
+	"github.com/devinjacknz/godydxhyber/backend/config"
 	"github.com/devinjacknz/godydxhyber/backend/llm"
 )
 
+// applyModelConfig overrides a model with the configured one, if any
+func applyModelConfig(model *llm.Model, mc config.ModelConfig) {
+	if mc.Name == "" {
+		return
+	}
+	model.Type = llm.LocalOllama
+	if mc.Provider == config.ProviderDeepSeek {
+		model.Type = llm.DeepSeekAPI
+	}
+	model.Name = mc.Name
+	if mc.BaseURL != "" {
+		model.BaseURL = mc.BaseURL
+	}
+	if mc.APIKey != "" {
+		model.APIKey = mc.APIKey
+	}
+	model.MaxTokens = mc.MaxTokens
+}
+
 func main() {
+	configPath := flag.String("config", "", "path to a YAML or TOML configuration file")
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("failed to load configuration: %v", err)
+	}
+
 	// Create primary model (Ollama)
 	primaryModel := &llm.Model{
 		Type:    llm.LocalOllama,
@@ -33,6 +62,9 @@ func main() {
 		APIKey:  os.Getenv("DEEPSEEK_API_KEY"),
 	}
 
+	applyModelConfig(primaryModel, cfg.LLM.Primary)
+	applyModelConfig(fallbackModel, cfg.LLM.Fallback)
+
 	// Create LLM client
 	client := llm.NewClient(primaryModel, fallbackModel)
 
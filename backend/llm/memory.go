@@ -0,0 +1,227 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Situation is a past market situation kept in the analysis memory: the
+// market data an analysis was made on and what happened afterwards
+type Situation struct {
+	ID     string `json:"id" bson:"_id"`
+	Symbol string `json:"symbol" bson:"symbol"`
+	// Text describes the market, as given to the analysis prompt
+	Text string `json:"text" bson:"text"`
+	// Outcome is what followed, such as "price rose 4% in the next hour"
+	Outcome   string    `json:"outcome,omitempty" bson:"outcome,omitempty"`
+	Time      time.Time `json:"time" bson:"time"`
+	Embedding []float64 `json:"embedding,omitempty" bson:"embedding"`
+}
+
+// Match is a stored situation similar to a query
+type Match struct {
+	Situation Situation `json:"situation"`
+	// Score is the cosine similarity to the query
+	Score float64 `json:"score"`
+}
+
+// VectorQuery selects the situations similar to an embedding
+type VectorQuery struct {
+	Embedding []float64
+	// Symbol limits the search to one symbol when set
+	Symbol string
+	Limit  int
+	// MinScore drops matches less similar than the score
+	MinScore float64
+}
+
+// VectorStore stores situations and searches them by similarity
+type VectorStore interface {
+	SaveSituation(ctx context.Context, s *Situation) error
+	SearchSimilar(ctx context.Context, query VectorQuery) ([]Match, error)
+}
+
+// MemoryVectorStore keeps situations in memory and searches them
+// exhaustively, which is fast enough for the few thousand situations an
+// analysis memory holds
+type MemoryVectorStore struct {
+	situations map[string]*Situation
+	mu         sync.RWMutex
+}
+
+// NewMemoryVectorStore creates an in-memory vector store
+func NewMemoryVectorStore() *MemoryVectorStore {
+	return &MemoryVectorStore{situations: make(map[string]*Situation)}
+}
+
+// SaveSituation inserts or replaces a situation
+func (s *MemoryVectorStore) SaveSituation(ctx context.Context, situation *Situation) error {
+	stored := *situation
+	stored.Embedding = append([]float64(nil), situation.Embedding...)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.situations[situation.ID] = &stored
+	return nil
+}
+
+// SearchSimilar returns the situations most similar to the query, most
+// similar first
+func (s *MemoryVectorStore) SearchSimilar(ctx context.Context, query VectorQuery) ([]Match, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matches := make([]Match, 0)
+	for _, situation := range s.situations {
+		if query.Symbol != "" && situation.Symbol != query.Symbol {
+			continue
+		}
+		if score := CosineSimilarity(query.Embedding, situation.Embedding); score >= query.MinScore {
+			matches = append(matches, Match{Situation: *situation, Score: score})
+		}
+	}
+	return RankMatches(matches, query.Limit), nil
+}
+
+// RankMatches sorts matches by score, most similar first and newest first
+// on ties, and keeps at most limit of them. A limit of zero keeps all.
+func RankMatches(matches []Match, limit int) []Match {
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Situation.Time.After(matches[j].Situation.Time)
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// MemoryConfig configures the analysis memory
+type MemoryConfig struct {
+	// Examples is the number of similar situations recalled
+	Examples int
+	// MinScore is the least cosine similarity of a recalled situation
+	MinScore float64
+	// SameSymbol only recalls situations of the analysed symbol
+	SameSymbol bool
+}
+
+// DefaultMemoryConfig returns the default analysis memory configuration
+func DefaultMemoryConfig() MemoryConfig {
+	return MemoryConfig{
+		Examples: 3,
+		MinScore: 0.75,
+	}
+}
+
+// Memory lets the analysis pipeline recall past market situations similar
+// to the current one and show them to the model as few-shot examples
+type Memory struct {
+	embedder Embedder
+	store    VectorStore
+	config   MemoryConfig
+	now      func() time.Time
+}
+
+// NewMemory creates an analysis memory. Zero config fields take their
+// default.
+func NewMemory(embedder Embedder, store VectorStore, config MemoryConfig) *Memory {
+	defaults := DefaultMemoryConfig()
+	if config.Examples <= 0 {
+		config.Examples = defaults.Examples
+	}
+	if config.MinScore == 0 {
+		config.MinScore = defaults.MinScore
+	}
+	return &Memory{embedder: embedder, store: store, config: config, now: time.Now}
+}
+
+// Remember embeds and stores a situation. Empty ID and time are filled in.
+func (m *Memory) Remember(ctx context.Context, situation Situation) (*Situation, error) {
+	if strings.TrimSpace(situation.Text) == "" {
+		return nil, errors.New("situation text cannot be empty")
+	}
+	if situation.ID == "" {
+		situation.ID = uuid.New().String()
+	}
+	if situation.Time.IsZero() {
+		situation.Time = m.now()
+	}
+
+	embedding, err := m.embed(ctx, situation.Text)
+	if err != nil {
+		return nil, err
+	}
+	situation.Embedding = embedding
+	if err := m.store.SaveSituation(ctx, &situation); err != nil {
+		return nil, fmt.Errorf("failed to save situation: %w", err)
+	}
+	return &situation, nil
+}
+
+// Recall returns the stored situations most similar to the described one
+func (m *Memory) Recall(ctx context.Context, symbol, text string) ([]Match, error) {
+	embedding, err := m.embed(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+
+	query := VectorQuery{Embedding: embedding, Limit: m.config.Examples, MinScore: m.config.MinScore}
+	if m.config.SameSymbol {
+		query.Symbol = symbol
+	}
+	matches, err := m.store.SearchSimilar(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search situations: %w", err)
+	}
+	return matches, nil
+}
+
+// Examples recalls the situations similar to the described one and formats
+// them as few-shot context for a prompt. It is empty when nothing similar
+// was stored.
+func (m *Memory) Examples(ctx context.Context, symbol, text string) (string, error) {
+	matches, err := m.Recall(ctx, symbol, text)
+	if err != nil {
+		return "", err
+	}
+	return FormatExamples(matches), nil
+}
+
+// FormatExamples formats recalled situations as few-shot context
+func FormatExamples(matches []Match) string {
+	if len(matches) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("Similar past market situations:\n")
+	for i, match := range matches {
+		s := match.Situation
+		fmt.Fprintf(&b, "\nExample %d (%s, %s, similarity %.2f):\n%s\n", i+1, s.Symbol, s.Time.UTC().Format(time.RFC3339), match.Score, strings.TrimSpace(s.Text))
+		if s.Outcome != "" {
+			fmt.Fprintf(&b, "Outcome: %s\n", s.Outcome)
+		}
+	}
+	return b.String()
+}
+
+func (m *Memory) embed(ctx context.Context, text string) ([]float64, error) {
+	vectors, err := m.embedder.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != 1 || len(vectors[0]) == 0 {
+		return nil, errors.New("no embedding returned")
+	}
+	return vectors[0], nil
+}
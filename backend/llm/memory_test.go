@@ -0,0 +1,87 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// keywordEmbedder embeds a text by counting market keywords
+type keywordEmbedder struct{}
+
+func (keywordEmbedder) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		for _, keyword := range []string{"breakout", "selloff", "volume"} {
+			vectors[i] = append(vectors[i], float64(strings.Count(text, keyword)))
+		}
+	}
+	return vectors, nil
+}
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	memory := NewMemory(keywordEmbedder{}, NewMemoryVectorStore(), MemoryConfig{Examples: 2, MinScore: 0.5})
+
+	now := time.Date(2024, 6, 3, 12, 0, 0, 0, time.UTC)
+	for _, s := range []Situation{
+		{Symbol: "BTC-USD", Text: "breakout on volume", Outcome: "rose 3%", Time: now},
+		{Symbol: "ETH-USD", Text: "breakout", Outcome: "rose 1%", Time: now.Add(time.Minute)},
+		{Symbol: "BTC-USD", Text: "selloff", Outcome: "fell 5%", Time: now},
+	} {
+		remembered, err := memory.Remember(ctx, s)
+		require.NoError(t, err)
+		assert.NotEmpty(t, remembered.ID)
+	}
+	_, err := memory.Remember(ctx, Situation{Symbol: "BTC-USD"})
+	assert.Error(t, err)
+
+	matches, err := memory.Recall(ctx, "SOL-USD", "breakout on volume")
+	require.NoError(t, err)
+	require.Len(t, matches, 2)
+	assert.Equal(t, "rose 3%", matches[0].Situation.Outcome)
+	assert.InDelta(t, 1, matches[0].Score, 1e-9)
+	assert.Equal(t, "rose 1%", matches[1].Situation.Outcome)
+
+	examples, err := memory.Examples(ctx, "SOL-USD", "selloff")
+	require.NoError(t, err)
+	assert.Equal(t, "Similar past market situations:\n\nExample 1 (BTC-USD, 2024-06-03T12:00:00Z, similarity 1.00):\nselloff\nOutcome: fell 5%\n", examples)
+
+	memory = NewMemory(keywordEmbedder{}, memory.store, MemoryConfig{MinScore: 0.5, SameSymbol: true})
+	matches, err = memory.Recall(ctx, "ETH-USD", "breakout on volume")
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Equal(t, "ETH-USD", matches[0].Situation.Symbol)
+}
+
+func TestEvaluator_Memory(t *testing.T) {
+	ctx := context.Background()
+	generator := profileGenerator{ProfileDefault: `{"direction": "up"}`}
+	e, err := NewEvaluator(generator,
+		Variant{Name: "plain", Template: "Analyze {{.Symbol}}: {{.Data}}"},
+		Variant{Name: "few-shot", Template: "{{.Examples}}Analyze {{.Symbol}}: {{.Data}}"},
+		NewMemoryEvalStore(), EvalConfig{Horizon: time.Hour})
+	require.NoError(t, err)
+	e.SetMemory(NewMemory(keywordEmbedder{}, NewMemoryVectorStore(), MemoryConfig{}))
+	now := time.Date(2024, 6, 3, 12, 0, 0, 0, time.UTC)
+	e.now = func() time.Time { return now }
+
+	trial, err := e.Run(ctx, EvalContext{Symbol: "BTC-USD", Price: 100, Data: "breakout"})
+	require.NoError(t, err)
+	assert.Empty(t, trial.Context.Examples, "nothing is remembered yet")
+
+	now = now.Add(time.Hour)
+	scored, err := e.Score(ctx, staticPrices{"BTC-USD": 104})
+	require.NoError(t, err)
+	assert.Equal(t, 1, scored)
+
+	trial, err = e.Run(ctx, EvalContext{Symbol: "BTC-USD", Price: 104, Data: "breakout"})
+	require.NoError(t, err)
+	assert.Contains(t, trial.Context.Examples, "BTC-USD at 100: breakout\nOutcome: price moved +4.00% to 104 within 1h0m0s")
+	assert.Contains(t, trial.Outputs[1].Text, "Similar past market situations")
+	assert.NotContains(t, trial.Outputs[0].Text, "Similar past market situations")
+}
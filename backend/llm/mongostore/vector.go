@@ -0,0 +1,83 @@
+// Package mongostore persists the analysis memory of the llm package in
+// MongoDB.
+package mongostore
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/devinjacknz/godydxhyber/backend/llm"
+)
+
+// DefaultScanLimit is the number of newest situations a search compares
+const DefaultScanLimit = 10000
+
+// VectorStore stores situations in a MongoDB collection. Searches compare
+// the newest situations in the process rather than relying on a server-side
+// vector index, so any MongoDB deployment works.
+type VectorStore struct {
+	collection *mongo.Collection
+	scanLimit  int64
+}
+
+var _ llm.VectorStore = (*VectorStore)(nil)
+
+// NewVectorStore creates a vector store on the collection and ensures its
+// indexes
+func NewVectorStore(ctx context.Context, collection *mongo.Collection) (*VectorStore, error) {
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "symbol", Value: 1}, {Key: "time", Value: -1}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s indexes: %w", collection.Name(), err)
+	}
+	return &VectorStore{collection: collection, scanLimit: DefaultScanLimit}, nil
+}
+
+// SetScanLimit sets the number of newest situations a search compares
+func (s *VectorStore) SetScanLimit(limit int64) {
+	if limit > 0 {
+		s.scanLimit = limit
+	}
+}
+
+// SaveSituation inserts or replaces a situation
+func (s *VectorStore) SaveSituation(ctx context.Context, situation *llm.Situation) error {
+	_, err := s.collection.ReplaceOne(ctx, bson.M{"_id": situation.ID}, situation, options.Replace().SetUpsert(true))
+	return err
+}
+
+// SearchSimilar returns the situations most similar to the query, most
+// similar first
+func (s *VectorStore) SearchSimilar(ctx context.Context, query llm.VectorQuery) ([]llm.Match, error) {
+	filter := bson.M{}
+	if query.Symbol != "" {
+		filter["symbol"] = query.Symbol
+	}
+	cursor, err := s.collection.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "time", Value: -1}}).
+		SetLimit(s.scanLimit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	matches := make([]llm.Match, 0)
+	for cursor.Next(ctx) {
+		var situation llm.Situation
+		if err := cursor.Decode(&situation); err != nil {
+			return nil, err
+		}
+		if score := llm.CosineSimilarity(query.Embedding, situation.Embedding); score >= query.MinScore {
+			matches = append(matches, llm.Match{Situation: situation, Score: score})
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	return llm.RankMatches(matches, query.Limit), nil
+}
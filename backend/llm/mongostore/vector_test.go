@@ -0,0 +1,58 @@
+package mongostore
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/devinjacknz/godydxhyber/backend/llm"
+)
+
+// TestVectorStore runs against the server in MONGODB_TEST_URI, in a new
+// database
+func TestVectorStore(t *testing.T) {
+	uri := os.Getenv("MONGODB_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGODB_TEST_URI not set")
+	}
+
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	require.NoError(t, err)
+	database := client.Database("gosol_test_" + strings.ToLower(primitive.NewObjectID().Hex()))
+	t.Cleanup(func() {
+		database.Drop(ctx)
+		client.Disconnect(ctx)
+	})
+
+	store, err := NewVectorStore(ctx, database.Collection("situations"))
+	require.NoError(t, err)
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	for _, s := range []llm.Situation{
+		{ID: "a", Symbol: "BTC-USD", Text: "breakout", Time: now, Embedding: []float64{1, 0}},
+		{ID: "b", Symbol: "ETH-USD", Text: "breakout", Time: now, Embedding: []float64{1, 0.1}},
+		{ID: "c", Symbol: "BTC-USD", Text: "selloff", Time: now, Embedding: []float64{0, 1}},
+	} {
+		require.NoError(t, store.SaveSituation(ctx, &s))
+	}
+
+	matches, err := store.SearchSimilar(ctx, llm.VectorQuery{Embedding: []float64{1, 0}, MinScore: 0.5})
+	require.NoError(t, err)
+	require.Len(t, matches, 2)
+	assert.Equal(t, "a", matches[0].Situation.ID)
+	assert.Equal(t, "b", matches[1].Situation.ID)
+
+	matches, err = store.SearchSimilar(ctx, llm.VectorQuery{Embedding: []float64{1, 0}, Symbol: "ETH-USD"})
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Equal(t, "breakout", matches[0].Situation.Text)
+}
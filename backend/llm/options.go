@@ -0,0 +1,84 @@
+package llm
+
+// modelOverrides holds the model settings replaced for a single call
+type modelOverrides struct {
+	temperature *float64
+	system      *string
+	maxTokens   int
+	format      *string
+	stop        []string
+}
+
+// WithTemperature sets the sampling temperature of the call, e.g. 0 for
+// deterministic analysis or a higher value for creative writing
+func WithTemperature(temperature float64) CallOption {
+	return func(o *callOptions) {
+		o.overrides.temperature = &temperature
+	}
+}
+
+// WithSystem replaces the system message of the models for the call
+func WithSystem(system string) CallOption {
+	return func(o *callOptions) {
+		o.overrides.system = &system
+	}
+}
+
+// WithMaxTokens limits the length of the response of the call
+func WithMaxTokens(maxTokens int) CallOption {
+	return func(o *callOptions) {
+		o.overrides.maxTokens = maxTokens
+	}
+}
+
+// WithFormat sets the response format of the call, e.g. "json". An empty
+// format returns free text.
+func WithFormat(format string) CallOption {
+	return func(o *callOptions) {
+		o.overrides.format = &format
+	}
+}
+
+// WithStopSequences stops the response of the call at any of the sequences
+func WithStopSequences(stop ...string) CallOption {
+	return func(o *callOptions) {
+		o.overrides.stop = append([]string(nil), stop...)
+	}
+}
+
+// empty reports whether no setting is overridden
+func (o modelOverrides) empty() bool {
+	return o.temperature == nil && o.system == nil && o.maxTokens <= 0 && o.format == nil && o.stop == nil
+}
+
+// apply returns a copy of model with the overrides applied, leaving the
+// shared model untouched
+func (o modelOverrides) apply(model *Model) *Model {
+	if model == nil || o.empty() {
+		return model
+	}
+
+	m := *model
+	if o.temperature != nil || o.stop != nil {
+		m.Options = make(map[string]any, len(model.Options)+2)
+		for k, v := range model.Options {
+			m.Options[k] = v
+		}
+		if o.temperature != nil {
+			m.Options["temperature"] = *o.temperature
+		}
+		if o.stop != nil {
+			m.Options["stop"] = o.stop
+		}
+	}
+	if o.system != nil {
+		m.System = *o.system
+	}
+	if o.maxTokens > 0 {
+		m.MaxTokens = o.maxTokens
+	}
+	if o.format != nil {
+		m.Format = *o.format
+	}
+	return &m
+}
@@ -0,0 +1,77 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallOverrides(t *testing.T) {
+	var ollamaReq, deepseekReq map[string]interface{}
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ollamaReq = nil
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&ollamaReq))
+		json.NewEncoder(w).Encode(map[string]interface{}{"response": "ok", "done": true})
+	}))
+	defer ollama.Close()
+	deepseek := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&deepseekReq))
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"content": "ok"}}},
+		})
+	}))
+	defer deepseek.Close()
+
+	primary := &Model{
+		Type:    LocalOllama,
+		Name:    ModelLlama3,
+		BaseURL: ollama.URL,
+		System:  "You are a trader.",
+		Options: map[string]any{"temperature": 0.7, "top_k": 40},
+	}
+	client := NewClient(primary, &Model{Type: DeepSeekAPI, Name: ModelDeepSeekR1, BaseURL: deepseek.URL, APIKey: "test-key"})
+	overrides := []CallOption{
+		WithTemperature(0),
+		WithSystem("Answer in JSON."),
+		WithMaxTokens(128),
+		WithFormat("json"),
+		WithStopSequences("\n\n"),
+	}
+
+	ctx := context.Background()
+	_, err := client.Generate(ctx, "test prompt", overrides...)
+	require.NoError(t, err)
+	assert.Equal(t, "Answer in JSON.", ollamaReq["system"])
+	assert.Equal(t, "json", ollamaReq["format"])
+	assert.Equal(t, map[string]interface{}{
+		"temperature": 0.0,
+		"top_k":       40.0,
+		"num_predict": 128.0,
+		"stop":        []interface{}{"\n\n"},
+	}, ollamaReq["options"])
+
+	// The shared model keeps its settings
+	assert.Equal(t, "You are a trader.", primary.System)
+	assert.Equal(t, map[string]any{"temperature": 0.7, "top_k": 40}, primary.Options)
+	_, err = client.Generate(ctx, "test prompt")
+	require.NoError(t, err)
+	assert.Equal(t, "You are a trader.", ollamaReq["system"])
+	assert.NotContains(t, ollamaReq, "format")
+
+	client = NewClient(client.(*DefaultClient).fallbackModel, primary)
+	_, err = client.Generate(ctx, "test prompt", overrides...)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"role": "system", "content": "Answer in JSON."},
+		map[string]interface{}{"role": "user", "content": "test prompt"},
+	}, deepseekReq["messages"])
+	assert.Equal(t, 0.0, deepseekReq["temperature"])
+	assert.Equal(t, 128.0, deepseekReq["max_tokens"])
+	assert.Equal(t, []interface{}{"\n\n"}, deepseekReq["stop"])
+	assert.Equal(t, map[string]interface{}{"type": "json_object"}, deepseekReq["response_format"])
+}
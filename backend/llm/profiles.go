@@ -0,0 +1,169 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// TaskProfile identifies the kind of task a request is for, such as a quick
+// sanity check or a deep analysis, and selects the models used for it
+type TaskProfile string
+
+const (
+	// ProfileDefault uses the client's primary and fallback models
+	ProfileDefault TaskProfile = "default"
+	// ProfileQuickCheck is for short, latency sensitive checks on a small model
+	ProfileQuickCheck TaskProfile = "quick_check"
+	// ProfileDeepAnalysis is for long-form analysis on a large model
+	ProfileDeepAnalysis TaskProfile = "deep_analysis"
+)
+
+// ErrUnknownProfile is returned when a call selects a profile that has not been configured
+var ErrUnknownProfile = errors.New("unknown task profile")
+
+// ProfileConfig maps a task profile to its models and limits
+type ProfileConfig struct {
+	Model *Model
+	// Fallback is tried when Model fails. If nil, the client's fallback model is used.
+	Fallback *Model
+	// Timeout bounds each call made with the profile. Zero means no extra timeout.
+	Timeout time.Duration
+	// RequestsPerSecond limits calls made with the profile, on top of the
+	// client's overall rate limit. Zero means no profile limit.
+	RequestsPerSecond float64
+}
+
+// CallOption configures a single Generate or Stream call
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	profile      TaskProfile
+	streamErrors bool
+	overrides    modelOverrides
+}
+
+// WithProfile routes the call to the models configured for the task profile
+func WithProfile(profile TaskProfile) CallOption {
+	return func(o *callOptions) {
+		o.profile = profile
+	}
+}
+
+// WithStreamErrors makes Stream deliver a last response with Err set when
+// the stream fails, instead of only closing the channel
+func WithStreamErrors() CallOption {
+	return func(o *callOptions) {
+		o.streamErrors = true
+	}
+}
+
+// route is the resolved routing for one call
+type route struct {
+	profile  TaskProfile
+	primary  *Model
+	fallback *Model
+	timeout  time.Duration
+	limiter  *rate.Limiter
+	// streamErrors delivers stream failures on the response channel
+	streamErrors bool
+}
+
+// profileRoute holds a configured profile and its rate limiter
+type profileRoute struct {
+	config  ProfileConfig
+	limiter *rate.Limiter
+}
+
+// SetProfile configures the models and limits used for a task profile
+func (c *DefaultClient) SetProfile(profile TaskProfile, config ProfileConfig) error {
+	if profile == "" || profile == ProfileDefault {
+		return fmt.Errorf("invalid profile name: %q", profile)
+	}
+	if config.Model == nil {
+		return fmt.Errorf("model cannot be nil")
+	}
+	if err := c.validateModel(config.Model); err != nil {
+		return err
+	}
+	if config.Fallback != nil {
+		if err := c.validateModel(config.Fallback); err != nil {
+			return fmt.Errorf("invalid fallback model: %w", err)
+		}
+	}
+
+	var limiter *rate.Limiter
+	if config.RequestsPerSecond > 0 {
+		limiter = rate.NewLimiter(rate.Limit(config.RequestsPerSecond), 1)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.profiles == nil {
+		c.profiles = make(map[TaskProfile]*profileRoute)
+	}
+	c.profiles[profile] = &profileRoute{config: config, limiter: limiter}
+	return nil
+}
+
+// resolveRoute returns the models and limits for a call
+func (c *DefaultClient) resolveRoute(opts []CallOption) (*route, error) {
+	options := callOptions{profile: ProfileDefault}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if options.profile == ProfileDefault {
+		return &route{
+			profile:      ProfileDefault,
+			primary:      options.overrides.apply(c.primaryModel),
+			fallback:     options.overrides.apply(c.fallbackModel),
+			streamErrors: options.streamErrors,
+		}, nil
+	}
+
+	configured, ok := c.profiles[options.profile]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProfile, options.profile)
+	}
+
+	fallback := configured.config.Fallback
+	if fallback == nil {
+		fallback = c.fallbackModel
+	}
+	return &route{
+		profile:      options.profile,
+		primary:      options.overrides.apply(configured.config.Model),
+		fallback:     options.overrides.apply(fallback),
+		timeout:      configured.config.Timeout,
+		limiter:      configured.limiter,
+		streamErrors: options.streamErrors,
+	}, nil
+}
+
+// wait applies the client and profile rate limits
+func (c *DefaultClient) wait(ctx context.Context, r *route) error {
+	if err := c.rateLimiter.Wait(ctx); err != nil {
+		return fmt.Errorf("rate limit error: %w", err)
+	}
+	if r.limiter != nil {
+		if err := r.limiter.Wait(ctx); err != nil {
+			return fmt.Errorf("profile %s rate limit error: %w", r.profile, err)
+		}
+	}
+	return nil
+}
+
+// withTimeout applies the profile timeout to ctx
+func (r *route) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, r.timeout)
+}
@@ -0,0 +1,189 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/monitoring"
+)
+
+// maxRetryDelay caps the exponential backoff between two attempts
+const maxRetryDelay = 30 * time.Second
+
+// statusError is returned for unexpected HTTP status codes of a model API
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.code)
+}
+
+// transient reports whether a failed attempt may succeed when retried:
+// server errors, rate limiting and attempts that timed out while the call
+// itself still had time
+func transient(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var status *statusError
+	if errors.As(err, &status) {
+		return status.code >= http.StatusInternalServerError || status.code == http.StatusTooManyRequests
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+// SetRetryPolicy sets the number of retries of a model call failing with a
+// transient error, and the base delay of their jittered exponential backoff.
+// Zero retries disables retrying.
+func (c *DefaultClient) SetRetryPolicy(retries int, delay time.Duration) error {
+	if retries < 0 || delay < 0 {
+		return fmt.Errorf("invalid retry policy: %d retries, %s delay", retries, delay)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.retryCount = retries
+	c.retryDelay = delay
+	return nil
+}
+
+// SetHedgeDelay enables hedged requests: a Generate call whose primary model
+// has not answered within delay also starts the fallback model, takes the
+// first answer and cancels the other call. Zero disables hedging, so the
+// fallback model only runs once the primary model failed.
+func (c *DefaultClient) SetHedgeDelay(delay time.Duration) error {
+	if delay < 0 {
+		return fmt.Errorf("invalid hedge delay: %s", delay)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hedgeDelay = delay
+	return nil
+}
+
+// generateRoute generates with the primary model of a route, turning to its
+// fallback model on failure or, when hedging, on a slow primary model
+func (c *DefaultClient) generateRoute(ctx context.Context, r *route, prompt string) (*Response, error) {
+	c.mu.RLock()
+	hedgeDelay := c.hedgeDelay
+	c.mu.RUnlock()
+	if hedgeDelay > 0 {
+		return c.generateHedged(ctx, r, prompt, hedgeDelay)
+	}
+
+	resp, err := c.generateWithRetry(ctx, r.primary, prompt)
+	if err == nil {
+		return resp, nil
+	}
+	slog.WarnContext(ctx, "primary model failed, falling back to secondary model", "profile", r.profile, "error", err)
+	monitoring.RecordLLMProfileFallback(string(r.profile))
+
+	resp, err = c.generateWithRetry(ctx, r.fallback, prompt)
+	if err != nil {
+		return nil, fmt.Errorf("both models failed: %w", err)
+	}
+	return resp, nil
+}
+
+// generateHedged races the fallback model against the primary model once
+// the primary model failed or took longer than delay. The first answer wins
+// and the other call is cancelled.
+func (c *DefaultClient) generateHedged(ctx context.Context, r *route, prompt string, delay time.Duration) (*Response, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		resp     *Response
+		err      error
+		fallback bool
+	}
+	results := make(chan result, 2)
+	start := func(model *Model, fallback bool) {
+		go func() {
+			resp, err := c.generateWithRetry(ctx, model, prompt)
+			results <- result{resp: resp, err: err, fallback: fallback}
+		}()
+	}
+
+	start(r.primary, false)
+	running, hedged := 1, false
+	hedge := time.NewTimer(delay)
+	defer hedge.Stop()
+
+	for {
+		select {
+		case <-hedge.C:
+			if !hedged {
+				slog.DebugContext(ctx, "primary model slow, hedging with secondary model", "profile", r.profile, "delay", delay)
+				monitoring.RecordLLMProfileFallback(string(r.profile))
+				hedged = true
+				running++
+				start(r.fallback, true)
+			}
+		case res := <-results:
+			running--
+			if res.err == nil {
+				return res.resp, nil
+			}
+			if !res.fallback {
+				slog.WarnContext(ctx, "primary model failed, falling back to secondary model", "profile", r.profile, "error", res.err)
+			}
+			if !hedged {
+				monitoring.RecordLLMProfileFallback(string(r.profile))
+				hedged = true
+				running++
+				start(r.fallback, true)
+				continue
+			}
+			if running == 0 {
+				return nil, fmt.Errorf("both models failed: %w", res.err)
+			}
+		}
+	}
+}
+
+// generateWithRetry calls a model, retrying transient failures with
+// jittered exponential backoff
+func (c *DefaultClient) generateWithRetry(ctx context.Context, model *Model, prompt string) (*Response, error) {
+	c.mu.RLock()
+	retries, delay := c.retryCount, c.retryDelay
+	c.mu.RUnlock()
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.generateWithModel(ctx, model, prompt)
+		if err == nil || attempt >= retries || !transient(ctx, err) {
+			return resp, err
+		}
+
+		wait := backoff(delay, attempt)
+		slog.WarnContext(ctx, "retrying model call", "model", model.Name, "attempt", attempt+1, "delay", wait, "error", err)
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w (retry interrupted: %v)", err, ctx.Err())
+		case <-time.After(wait):
+		}
+	}
+}
+
+// backoff returns a random delay up to the exponential backoff of an
+// attempt
+func backoff(base time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+	d := base << attempt
+	if d <= 0 || d > maxRetryDelay {
+		d = maxRetryDelay
+	}
+	return time.Duration(rand.Int63n(int64(d)) + 1)
+}
@@ -0,0 +1,144 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fastRetries keeps the retries of a client but shortens their backoff
+func fastRetries(t *testing.T, client Client) {
+	t.Helper()
+	require.NoError(t, client.(*DefaultClient).SetRetryPolicy(3, time.Millisecond))
+}
+
+// ollamaServer answers with status until it failed the given number of
+// times, then with text after delay
+func ollamaServer(t *testing.T, failures int32, status int, delay time.Duration, text string) (*httptest.Server, *atomic.Int32) {
+	calls := &atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"response": text, "done": true})
+	}))
+	t.Cleanup(server.Close)
+	return server, calls
+}
+
+func TestGenerate_Retry(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("TransientFailures", func(t *testing.T) {
+		primary, calls := ollamaServer(t, 2, http.StatusServiceUnavailable, 0, "Primary response")
+		fallback, fallbackCalls := ollamaServer(t, 0, 0, 0, "Fallback response")
+		client := NewClient(
+			&Model{Type: LocalOllama, Name: ModelLlama3, BaseURL: primary.URL},
+			&Model{Type: LocalOllama, Name: ModelLlama3, BaseURL: fallback.URL},
+		)
+		fastRetries(t, client)
+
+		resp, err := client.Generate(ctx, "test prompt")
+		require.NoError(t, err)
+		assert.Equal(t, "Primary response", resp.Text)
+		assert.Equal(t, int32(3), calls.Load())
+		assert.Zero(t, fallbackCalls.Load())
+	})
+
+	t.Run("PermanentFailure", func(t *testing.T) {
+		primary, calls := ollamaServer(t, 1, http.StatusBadRequest, 0, "Primary response")
+		fallback, _ := ollamaServer(t, 0, 0, 0, "Fallback response")
+		client := NewClient(
+			&Model{Type: LocalOllama, Name: ModelLlama3, BaseURL: primary.URL},
+			&Model{Type: LocalOllama, Name: ModelLlama3, BaseURL: fallback.URL},
+		)
+		fastRetries(t, client)
+
+		resp, err := client.Generate(ctx, "test prompt")
+		require.NoError(t, err)
+		assert.Equal(t, "Fallback response", resp.Text)
+		assert.Equal(t, int32(1), calls.Load(), "client errors are not retried")
+	})
+
+	t.Run("RetriesExhausted", func(t *testing.T) {
+		primary, calls := ollamaServer(t, 10, http.StatusInternalServerError, 0, "Primary response")
+		fallback, _ := ollamaServer(t, 0, 0, 0, "Fallback response")
+		client := NewClient(
+			&Model{Type: LocalOllama, Name: ModelLlama3, BaseURL: primary.URL},
+			&Model{Type: LocalOllama, Name: ModelLlama3, BaseURL: fallback.URL},
+		)
+		require.NoError(t, client.(*DefaultClient).SetRetryPolicy(1, time.Millisecond))
+
+		resp, err := client.Generate(ctx, "test prompt")
+		require.NoError(t, err)
+		assert.Equal(t, "Fallback response", resp.Text)
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	assert.Error(t, NewClient(&Model{}, &Model{}).(*DefaultClient).SetRetryPolicy(-1, 0))
+}
+
+func TestGenerate_Hedged(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("SlowPrimary", func(t *testing.T) {
+		primary, _ := ollamaServer(t, 0, 0, time.Second, "Primary response")
+		fallback, fallbackCalls := ollamaServer(t, 0, 0, 0, "Fallback response")
+		client := NewClient(
+			&Model{Type: LocalOllama, Name: ModelLlama3, BaseURL: primary.URL},
+			&Model{Type: LocalOllama, Name: ModelLlama3, BaseURL: fallback.URL},
+		)
+		require.NoError(t, client.(*DefaultClient).SetHedgeDelay(20*time.Millisecond))
+
+		start := time.Now()
+		resp, err := client.Generate(ctx, "test prompt")
+		require.NoError(t, err)
+		assert.Equal(t, "Fallback response", resp.Text)
+		assert.Equal(t, int32(1), fallbackCalls.Load())
+		assert.Less(t, time.Since(start), 500*time.Millisecond, "the slow primary call is cancelled")
+	})
+
+	t.Run("FastPrimary", func(t *testing.T) {
+		primary, _ := ollamaServer(t, 0, 0, 0, "Primary response")
+		fallback, fallbackCalls := ollamaServer(t, 0, 0, 0, "Fallback response")
+		client := NewClient(
+			&Model{Type: LocalOllama, Name: ModelLlama3, BaseURL: primary.URL},
+			&Model{Type: LocalOllama, Name: ModelLlama3, BaseURL: fallback.URL},
+		)
+		require.NoError(t, client.(*DefaultClient).SetHedgeDelay(time.Second))
+
+		resp, err := client.Generate(ctx, "test prompt")
+		require.NoError(t, err)
+		assert.Equal(t, "Primary response", resp.Text)
+		assert.Zero(t, fallbackCalls.Load())
+	})
+
+	t.Run("BothFail", func(t *testing.T) {
+		primary, _ := ollamaServer(t, 10, http.StatusBadRequest, 0, "")
+		fallback, _ := ollamaServer(t, 10, http.StatusBadRequest, 0, "")
+		client := NewClient(
+			&Model{Type: LocalOllama, Name: ModelLlama3, BaseURL: primary.URL},
+			&Model{Type: LocalOllama, Name: ModelLlama3, BaseURL: fallback.URL},
+		)
+		require.NoError(t, client.(*DefaultClient).SetHedgeDelay(time.Second))
+
+		start := time.Now()
+		_, err := client.Generate(ctx, "test prompt")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "both models failed")
+		assert.Less(t, time.Since(start), 500*time.Millisecond, "a failed primary call starts the fallback at once")
+	})
+}
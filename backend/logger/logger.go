@@ -1,182 +1,125 @@
+// Package logger configures the structured logging of the engine and carries
+// the correlation ID of a trade signal through contexts, so that the log
+// records of the strategy, risk, order and exchange steps of one signal can
+// be joined.
 package logger
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
-	"os"
-	"runtime"
+	"io"
+	"log/slog"
 	"strings"
-	"time"
-
-	"github.com/sirupsen/logrus"
 )
 
-type Logger struct {
-	*logrus.Logger
-	fields logrus.Fields
-}
-
-type LogLevel string
-
+// Log formats
 const (
-	DebugLevel LogLevel = "debug"
-	InfoLevel  LogLevel = "info"
-	WarnLevel  LogLevel = "warn"
-	ErrorLevel LogLevel = "error"
-	FatalLevel LogLevel = "fatal"
+	FormatText = "text"
+	FormatJSON = "json"
 )
 
-// Custom formatter for better debugging
-type CustomFormatter struct {
-	*logrus.JSONFormatter
-}
-
-func (f *CustomFormatter) Format(entry *logrus.Entry) ([]byte, error) {
-	// Add caller info
-	if _, file, line, ok := runtime.Caller(8); ok {
-		entry.Data["source"] = fmt.Sprintf("%s:%d", trimPath(file), line)
-	}
-
-	// Add timestamp in RFC3339Nano format
-	entry.Data["@timestamp"] = time.Now().Format(time.RFC3339Nano)
-
-	// Add goroutine ID
-	entry.Data["goroutine"] = getGoroutineID()
-
-	// Add memory stats
-	var memStats runtime.MemStats
-	runtime.ReadMemStats(&memStats)
-	entry.Data["memory"] = map[string]interface{}{
-		"alloc":      memStats.Alloc,
-		"total_alloc": memStats.TotalAlloc,
-		"sys":        memStats.Sys,
-		"num_gc":     memStats.NumGC,
-	}
-
-	return f.JSONFormatter.Format(entry)
-}
-
-func trimPath(path string) string {
-	parts := strings.Split(path, "/")
-	if len(parts) > 3 {
-		return strings.Join(parts[len(parts)-3:], "/")
+// CorrelationKey is the log attribute of the correlation ID
+const CorrelationKey = "correlation_id"
+
+// Config selects the level and format of the log
+type Config struct {
+	// Level is debug, info, warn or error. Empty means info.
+	Level string
+	// Format is text or json. Empty means text.
+	Format string
+}
+
+// ParseLevel parses a level name
+func ParseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
 	}
-	return path
+	return 0, fmt.Errorf("unknown log level %q", level)
 }
 
-func getGoroutineID() uint64 {
-	var buf [64]byte
-	n := runtime.Stack(buf[:], false)
-	idField := strings.Fields(strings.TrimPrefix(string(buf[:n]), "goroutine "))[0]
-	var id uint64
-	fmt.Sscanf(idField, "%d", &id)
-	return id
-}
-
-// Create a new logger instance
-func NewLogger() *Logger {
-	log := logrus.New()
-	log.SetFormatter(&CustomFormatter{
-		&logrus.JSONFormatter{
-			TimestampFormat: time.RFC3339Nano,
-		},
-	})
-	log.SetOutput(os.Stdout)
-
-	// Set log level from environment variable
-	level := os.Getenv("LOG_LEVEL")
-	if level == "" {
-		level = "info"
-	}
-	logLevel, err := logrus.ParseLevel(level)
+// New creates a logger writing to w. Records logged with a context carrying a
+// correlation ID have a correlation_id attribute.
+func New(w io.Writer, config Config) (*slog.Logger, error) {
+	level, err := ParseLevel(config.Level)
 	if err != nil {
-		logLevel = logrus.InfoLevel
+		return nil, err
 	}
-	log.SetLevel(logLevel)
 
-	return &Logger{
-		Logger: log,
-		fields: logrus.Fields{},
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch config.Format {
+	case "", FormatText:
+		handler = slog.NewTextHandler(w, options)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, options)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", config.Format)
 	}
+	return slog.New(contextHandler{handler}), nil
 }
 
-// WithFields adds fields to the logger
-func (l *Logger) WithFields(fields logrus.Fields) *Logger {
-	return &Logger{
-		Logger: l.Logger,
-		fields: fields,
-	}
+// contextHandler adds the correlation ID of the record context
+type contextHandler struct {
+	slog.Handler
 }
 
-// WithContext adds context fields to the logger
-func (l *Logger) WithContext(ctx context.Context) *Logger {
-	fields := make(logrus.Fields)
-	for k, v := range l.fields {
-		fields[k] = v
-	}
-
-	// Add trace ID if exists
-	if traceID := ctx.Value("trace_id"); traceID != nil {
-		fields["trace_id"] = traceID
-	}
-
-	// Add request ID if exists
-	if requestID := ctx.Value("request_id"); requestID != nil {
-		fields["request_id"] = requestID
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := CorrelationID(ctx); id != "" {
+		record.AddAttrs(slog.String(CorrelationKey, id))
 	}
-
-	return &Logger{
-		Logger: l.Logger,
-		fields: fields,
-	}
-}
-
-// Debug logs a debug message
-func (l *Logger) Debug(args ...interface{}) {
-	l.Logger.WithFields(l.fields).Debug(args...)
+	return h.Handler.Handle(ctx, record)
 }
 
-// Debugf logs a debug message with format
-func (l *Logger) Debugf(format string, args ...interface{}) {
-	l.Logger.WithFields(l.fields).Debugf(format, args...)
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
 }
 
-// Info logs an info message
-func (l *Logger) Info(args ...interface{}) {
-	l.Logger.WithFields(l.fields).Info(args...)
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
 }
 
-// Infof logs an info message with format
-func (l *Logger) Infof(format string, args ...interface{}) {
-	l.Logger.WithFields(l.fields).Infof(format, args...)
-}
-
-// Warn logs a warning message
-func (l *Logger) Warn(args ...interface{}) {
-	l.Logger.WithFields(l.fields).Warn(args...)
-}
-
-// Warnf logs a warning message with format
-func (l *Logger) Warnf(format string, args ...interface{}) {
-	l.Logger.WithFields(l.fields).Warnf(format, args...)
-}
+type correlationKey struct{}
 
-// Error logs an error message
-func (l *Logger) Error(args ...interface{}) {
-	l.Logger.WithFields(l.fields).Error(args...)
+// NewCorrelationID returns a random correlation ID
+func NewCorrelationID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
 }
 
-// Errorf logs an error message with format
-func (l *Logger) Errorf(format string, args ...interface{}) {
-	l.Logger.WithFields(l.fields).Errorf(format, args...)
+// WithCorrelationID returns a context carrying a correlation ID. An empty ID
+// leaves the context unchanged.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, correlationKey{}, id)
 }
 
-// Fatal logs a fatal message
-func (l *Logger) Fatal(args ...interface{}) {
-	l.Logger.WithFields(l.fields).Fatal(args...)
+// CorrelationID returns the correlation ID of a context, empty if it has none
+func CorrelationID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(correlationKey{}).(string)
+	return id
 }
 
-// Fatalf logs a fatal message with format
-func (l *Logger) Fatalf(format string, args ...interface{}) {
-	l.Logger.WithFields(l.fields).Fatalf(format, args...)
+// EnsureCorrelationID returns a context with a correlation ID, adding a new
+// one if ctx has none, and the ID
+func EnsureCorrelationID(ctx context.Context) (context.Context, string) {
+	if id := CorrelationID(ctx); id != "" {
+		return ctx, id
+	}
+	id := NewCorrelationID()
+	return WithCorrelationID(ctx, id), id
 }
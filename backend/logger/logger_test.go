@@ -0,0 +1,55 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	log, err := New(&buf, Config{Level: "warn", Format: FormatJSON})
+	require.NoError(t, err)
+
+	ctx := WithCorrelationID(context.Background(), "abc123")
+	log.InfoContext(ctx, "dropped")
+	log.With("component", "risk").WarnContext(ctx, "risk check failed", "symbol", "BTC-USD")
+	log.Warn("no correlation")
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(lines[0], &record))
+	assert.Equal(t, "risk check failed", record["msg"])
+	assert.Equal(t, "abc123", record[CorrelationKey])
+	assert.Equal(t, "risk", record["component"])
+
+	record = nil
+	require.NoError(t, json.Unmarshal(lines[1], &record))
+	assert.NotContains(t, record, CorrelationKey)
+
+	_, err = New(&buf, Config{Level: "verbose"})
+	assert.Error(t, err)
+	_, err = New(&buf, Config{Format: "xml"})
+	assert.Error(t, err)
+}
+
+func TestCorrelationID(t *testing.T) {
+	ctx := context.Background()
+	assert.Empty(t, CorrelationID(ctx))
+	assert.Equal(t, ctx, WithCorrelationID(ctx, ""))
+
+	ctx, id := EnsureCorrelationID(ctx)
+	assert.Len(t, id, 16)
+	assert.Equal(t, id, CorrelationID(ctx))
+
+	same, sameID := EnsureCorrelationID(ctx)
+	assert.Equal(t, ctx, same)
+	assert.Equal(t, id, sameID)
+	assert.NotEqual(t, id, NewCorrelationID())
+}
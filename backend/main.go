@@ -1,20 +1,81 @@
 package main
 
 import (
+    "context"
+    "errors"
+    "flag"
+    "log/slog"
+    "net/http"
+    "os"
+    "syscall"
+    "time"
+
     "github.com/gin-gonic/gin"
     "github.com/gin-contrib/cors"
+    "github.com/devinjacknz/godydxhyber/backend/config"
+    "github.com/devinjacknz/godydxhyber/backend/eventbus"
+    "github.com/devinjacknz/godydxhyber/backend/eventbus/bridge"
+    "github.com/devinjacknz/godydxhyber/backend/lifecycle"
+    "github.com/devinjacknz/godydxhyber/backend/logger"
+    "github.com/devinjacknz/godydxhyber/backend/middleware"
+    "github.com/devinjacknz/godydxhyber/backend/pkg/health"
     "github.com/devinjacknz/godydxhyber/backend/pkg/monitoring"
+    "github.com/devinjacknz/godydxhyber/backend/pkg/tracing"
     "github.com/devinjacknz/godydxhyber/backend/pkg/websocket"
+    "github.com/devinjacknz/godydxhyber/backend/telegram"
+    "github.com/devinjacknz/godydxhyber/backend/trading/account"
+    auditlog "github.com/devinjacknz/godydxhyber/backend/trading/analysis/monitoring"
+    "github.com/devinjacknz/godydxhyber/backend/trading/journal"
+    "github.com/devinjacknz/godydxhyber/backend/trading/killswitch"
+    "github.com/devinjacknz/godydxhyber/backend/trading/order"
+    "github.com/devinjacknz/godydxhyber/backend/trading/portfolio"
+    "github.com/devinjacknz/godydxhyber/backend/trading/position"
+    "github.com/devinjacknz/godydxhyber/backend/trading/risk"
+    "github.com/devinjacknz/godydxhyber/backend/trading/routing"
+    "github.com/devinjacknz/godydxhyber/backend/trading/sandbox"
+    "github.com/devinjacknz/godydxhyber/backend/trading/snapshot"
+    "github.com/devinjacknz/godydxhyber/backend/trading/strategy"
+    "github.com/devinjacknz/godydxhyber/backend/trading/watchdog"
 )
 
 func main() {
+    if len(os.Args) > 1 && os.Args[1] == "export" {
+        if err := runExport(os.Args[2:]); err != nil {
+            fatal("export failed", err)
+        }
+        return
+    }
+
+    configPath := flag.String("config", "", "path to a YAML or TOML configuration file")
+    flag.Parse()
+
+    cfg, err := config.Load(*configPath)
+    if err != nil {
+        fatal("failed to load configuration", err)
+    }
+    logging, err := logger.New(os.Stderr, logger.Config{Level: cfg.Log.Level, Format: cfg.Log.Format})
+    if err != nil {
+        fatal("failed to configure logging", err)
+    }
+    slog.SetDefault(logging)
+    stopTracing, err := tracing.Setup(context.Background(), tracing.Config{
+        Endpoint:    cfg.Tracing.Endpoint,
+        ServiceName: cfg.Tracing.ServiceName,
+        SampleRatio: cfg.Tracing.SampleRatio,
+    })
+    if err != nil {
+        fatal("failed to configure tracing", err)
+    }
+
     r := gin.Default()
+    r.Use(middleware.Correlation(), middleware.Tracing())
 
     // Configure CORS
     r.Use(cors.New(cors.Config{
         AllowOrigins:     []string{"*"},
         AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-        AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
+        AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", middleware.CorrelationHeader, "traceparent", "tracestate"},
+        ExposeHeaders:    []string{middleware.CorrelationHeader},
         AllowCredentials: true,
     }))
 
@@ -23,12 +84,349 @@ func main() {
         c.JSON(200, gin.H{"status": "ok"})
     })
 
-    // WebSocket endpoint
-    r.GET("/ws", websocket.HandleWebSocket)
+    // WebSocket push channel, served once authentication is configured
+    hubConfig := websocket.DefaultHubConfig()
+    hubConfig.AllowedOrigins = cfg.Server.WebSocketOrigins
+    hub := websocket.NewHub(hubConfig)
+
+    // Event bus decoupling trading components from their consumers
+    bus := eventbus.New(eventbus.DefaultConfig())
+    eventbus.Subscribe(bus, eventbus.RiskViolation, func(ctx context.Context, e eventbus.RiskViolationEvent) error {
+        auditlog.RecordEvent(auditlog.Event{
+            Type:          "risk_violation",
+            Severity:      auditlog.SeverityWarning,
+            Message:       e.Description,
+            CorrelationID: e.CorrelationID,
+            Details: map[string]interface{}{
+                "type":      e.Type,
+                "level":     e.Level,
+                "symbol":    e.Symbol,
+                "value":     e.Value,
+                "threshold": e.Threshold,
+            },
+        })
+        return nil
+    }, eventbus.WithMode(eventbus.Async))
+
+    // Optional bridge to an external broker
+    var eventBridge *bridge.Bridge
+    if cfg.Bridge.Driver != "" {
+        transport, err := newBridgeTransport(context.Background(), cfg.Bridge)
+        if err != nil {
+            fatal("failed to connect event bridge", err)
+        }
+        eventBridge = bridge.New(bus, transport, bridge.Config{
+            TopicPrefix: cfg.Bridge.TopicPrefix,
+            Source:      cfg.Bridge.Source,
+            SignalTopic: cfg.Bridge.SignalTopic,
+            FillTopic:   cfg.Bridge.FillTopic,
+        })
+        if err := eventBridge.Start(context.Background()); err != nil {
+            fatal("failed to start event bridge", err)
+        }
+    }
+
+    // Trading accounts, each with its own exchange credentials, orders,
+    // positions and risk budget. The API without an account acts on the
+    // default account.
+    var riskManager risk.RiskManager
+    // The portfolio aggregates the fills of every venue and account,
+    // published on the bus by the order managers and the bridge, and checks
+    // its exposure against the collateral of every venue on each change
+    tradePortfolio := portfolio.New(portfolio.WithListener(func(ctx context.Context, e *portfolio.Exposure) {
+        if e.Collateral <= 0 {
+            return
+        }
+        // Violations reach the alert listener
+        riskManager.CheckExposureLimit(ctx, risk.ExposureLimitParams{
+            TotalExposure:     e.GrossExposure,
+            CollateralBalance: e.Collateral,
+        })
+    }))
+    tradePortfolio.Subscribe(bus)
+    // Loss budgets disable the strategies and tokens over budget, alone,
+    // until the end of the day or week they were breached in
+    strategies := strategy.NewRegistry()
+    budgets := newBudgets(cfg, strategies)
+    budgets.Subscribe(bus)
+    // Open orders and positions are saved to Redis as they change, and
+    // recovered from it before any snapshot is restored. The daily risk
+    // counters are saved there too, so restarts do not reset them.
+    stores, err := newStoreClient(cfg.Repository)
+    if err != nil {
+        fatal("failed to create order and position stores", err)
+    }
+    accounts, err := newAccounts(cfg, hub, bus, budgets, stores, risk.WithExposureSource(tradePortfolio))
+    if err != nil {
+        fatal("failed to create trading accounts", err)
+    }
+    if stores != nil {
+        if err := recoverAccounts(context.Background(), accounts); err != nil {
+            fatal("failed to recover trading accounts", err)
+        }
+    }
+    defaultAccount, err := accounts.Get(account.Default)
+    if err != nil {
+        fatal("failed to create trading accounts", err)
+    }
+    riskManager = defaultAccount.Risk
+    orderManager := defaultAccount.Orders
+    positionManager := defaultAccount.Positions
+    killSwitch := defaultAccount.KillSwitch
+
+    // Post-trade journal, written by the LLM when one is configured
+    var tradeJournal *journal.Journal
+    if cfg.LLM.Primary.Name != "" {
+        tradeJournal = journal.New(newLLMClient(cfg.LLM), journal.NewMemoryStore(), riskManager, journal.DefaultConfig())
+        tradeJournal.Subscribe(bus)
+    }
+
+    // Telegram notifications and commands on the default account
+    botCtx, stopBot := context.WithCancel(context.Background())
+    if cfg.Telegram.Token != "" {
+        bot, err := telegram.New(
+            telegram.NewClient(telegram.DefaultBaseURL, cfg.Telegram.Token, telegram.DefaultConfig().PollTimeout),
+            positionManager, killSwitch, riskManager,
+            telegram.Config{AllowedChats: cfg.Telegram.AllowedChats, SummaryTime: cfg.Telegram.SummaryTime},
+        )
+        if err != nil {
+            fatal("failed to create telegram bot", err)
+        }
+        bot.Subscribe(bus)
+        go bot.Run(botCtx)
+    }
+
+    for _, sc := range cfg.Strategies {
+        if _, err := strategies.Register(context.Background(), sc.Name, sc.Params); err != nil {
+            fatal("failed to register strategy", err, "strategy", sc.Name)
+        }
+        if _, err := strategies.SetEnabled(context.Background(), sc.Name, sc.Enabled); err != nil {
+            fatal("failed to enable strategy", err, "strategy", sc.Name)
+        }
+        if sc.Schedule != nil {
+            if _, err := strategies.SetSchedule(context.Background(), sc.Name, newSchedule(*sc.Schedule)); err != nil {
+                fatal("failed to set strategy schedule", err, "strategy", sc.Name)
+            }
+        }
+    }
+    // Planned restarts resume the orders, positions, risk counters and
+    // strategies saved at the last shutdown, reconciled with the exchanges
+    snapshotRepository, closeSnapshots, err := newSnapshotRepository(cfg.Repository)
+    if err != nil {
+        fatal("failed to create snapshot repository", err)
+    }
+    var snapshots *snapshot.Manager
+    if snapshotRepository != nil {
+        snapshots = snapshot.NewManager(accounts, strategies, snapshotRepository, snapshot.Config{MaxAge: time.Duration(cfg.Repository.SnapshotMaxAge)})
+        if err := restoreSnapshot(context.Background(), snapshots); err != nil {
+            fatal("failed to restore engine snapshot", err)
+        }
+    }
+    // Strategies pause outside their sessions; the scheduler protects or
+    // flattens their positions as the sessions close
+    scheduler := strategy.NewScheduler(strategies, sessionGuard{accounts: accounts}, schedulerInterval(cfg.Watchdog))
+    // The dead man's switch clears the book on the exchanges when the
+    // scheduler stops checking in
+    watchdogCtx, stopWatchdog := context.WithCancel(context.Background())
+    var deadMan *watchdog.Watchdog
+    if cfg.Watchdog.Timeout > 0 {
+        deadMan = newWatchdog(cfg.Watchdog, accounts)
+        scheduler.SetHeartbeat(deadMan)
+        go deadMan.Run(watchdogCtx)
+    }
+    go scheduler.Run(strategies.Context())
+    go budgets.Run(strategies.Context())
+    costModel := routing.NewCostModel(routing.DefaultCostModelConfig())
+    router := routing.NewRouter(costModel, routing.VenueDydx, routing.VenueHyperliquid)
+    sandboxes := sandbox.NewManager(orderManager, positionManager, killswitch.Config{})
+
+    // Push clients receive the orders and positions of the accounts their
+    // token may see
+    hub.SetScopedSnapshot(websocket.TopicOrders, func(scope websocket.Scope) (interface{}, error) {
+        var snapshots []*order.Order
+        for _, a := range accounts.List() {
+            if !scope(a.ID) {
+                continue
+            }
+            orders, err := a.Orders.ListOrders(context.Background(), order.OrderFilter{})
+            if err != nil {
+                return nil, err
+            }
+            for _, o := range orders {
+                snapshots = append(snapshots, o.Snapshot())
+            }
+        }
+        return snapshots, nil
+    })
+    hub.SetScopedSnapshot(websocket.TopicPositions, func(scope websocket.Scope) (interface{}, error) {
+        open := position.Open
+        var snapshots []*position.Position
+        for _, a := range accounts.List() {
+            if !scope(a.ID) {
+                continue
+            }
+            positions, err := a.Positions.ListPositions(context.Background(), position.PositionFilter{Status: &open})
+            if err != nil {
+                return nil, err
+            }
+            for _, p := range positions {
+                snapshots = append(snapshots, p.Snapshot())
+            }
+        }
+        return snapshots, nil
+    })
+
+    // Liveness and readiness, probing the dependencies in the background
+    healthChecker := newHealthChecker(cfg, accounts)
+    health.RegisterRoutes(r, healthChecker)
+    healthCtx, stopHealth := context.WithCancel(context.Background())
+    go healthChecker.Run(healthCtx)
+
+    // Orders past their expiry are expired and cancelled on the venue
+    expiryCtx, stopExpiry := context.WithCancel(context.Background())
+    for _, a := range accounts.List() {
+        go order.RunExpirySweeper(expiryCtx, a.Orders, order.DefaultExpirySweep)
+    }
+
+    // API token authentication, enabled when tokens are configured
+    var tokens *middleware.TokenStore
+    if spec := cfg.Server.APITokens; spec != "" {
+        tokens = middleware.NewTokenStore()
+        if err := tokens.LoadTokens(spec); err != nil {
+            fatal("failed to load API tokens", err)
+        }
+    }
+    registerPushChannel(r, tokens, hub)
+
+    // Trading control API
+    err = registerAPI(r, tokens, apiServices{
+        accounts:   accounts,
+        portfolio:  tradePortfolio,
+        sandboxes:  sandboxes,
+        router:     router,
+        health:     healthChecker,
+        strategies: strategies,
+        budgets:    budgets,
+        snapshots:  snapshots,
+        watchdog:   deadMan,
+        journal:    tradeJournal,
+    })
+    if err != nil {
+        fatal("failed to register API routes", err)
+    }
 
     // Setup monitoring
     monitoring.Setup(r)
 
     // Start server
-    r.Run(":8080")
+    srv := &http.Server{Addr: cfg.Server.Addr, Handler: r}
+    go func() {
+        if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+            fatal("server error", err)
+        }
+    }()
+
+    // Shut down in order: stop taking requests and signals, cancel strategy
+    // ticks, drain order submissions, then disconnect push clients. Order
+    // and position stores write synchronously, so PhaseFlush is only needed
+    // by buffered repositories.
+    shutdown := lifecycle.NewManager()
+    shutdown.Register(lifecycle.PhaseIntake, "http", srv.Shutdown)
+    shutdown.Register(lifecycle.PhaseIntake, "health_checks", func(ctx context.Context) error {
+        stopHealth()
+        return nil
+    })
+    shutdown.Register(lifecycle.PhaseIntake, "telegram", func(ctx context.Context) error {
+        stopBot()
+        return nil
+    })
+    // Stopped before the strategies, whose heartbeats stop with them
+    shutdown.Register(lifecycle.PhaseIntake, "watchdog", func(ctx context.Context) error {
+        stopWatchdog()
+        return nil
+    })
+    shutdown.Register(lifecycle.PhaseStrategies, "strategies", func(ctx context.Context) error {
+        strategies.Stop()
+        return nil
+    })
+    shutdown.Register(lifecycle.PhaseStrategies, "order_expiry", func(ctx context.Context) error {
+        stopExpiry()
+        return nil
+    })
+    // Orders still unacknowledged at the deadline are marked for
+    // reconciliation on the next start
+    for _, a := range accounts.List() {
+        a := a
+        shutdown.Register(lifecycle.PhaseOrders, "order_submissions", func(ctx context.Context) error {
+            report, err := a.Orders.Shutdown(ctx)
+            if err != nil {
+                return err
+            }
+            if len(report.Unresolved) > 0 {
+                slog.Warn("order submissions unresolved at shutdown, marked for reconciliation", "account_id", a.ID, "orders", report.Unresolved)
+            }
+            return nil
+        })
+        if cfg.Server.CancelOrdersOnShutdown {
+            shutdown.Register(lifecycle.PhaseOrders, "cancel_orders", func(ctx context.Context) error {
+                flatten := false
+                state, err := a.KillSwitch.Trigger(ctx, killswitch.TriggerParams{
+                    Source:  killswitch.SourceShutdown,
+                    Reason:  "graceful shutdown",
+                    Flatten: &flatten,
+                })
+                if errors.Is(err, killswitch.ErrAlreadyActive) {
+                    return nil
+                }
+                slog.Info("cancelled open orders at shutdown", "account_id", a.ID, "orders", state.CancelledOrders)
+                return err
+            })
+        }
+    }
+    // The state is saved once strategies are stopped and submissions are
+    // drained, so the snapshot is consistent
+    if snapshots != nil {
+        shutdown.Register(lifecycle.PhaseFlush, "snapshot", func(ctx context.Context) error {
+            _, err := snapshots.Save(ctx)
+            return err
+        })
+        shutdown.Register(lifecycle.PhaseConnections, "snapshot_repository", func(ctx context.Context) error {
+            return closeSnapshots()
+        })
+    }
+    if stores != nil {
+        shutdown.Register(lifecycle.PhaseConnections, "order_stores", func(ctx context.Context) error {
+            return stores.Close()
+        })
+    }
+    shutdown.Register(lifecycle.PhaseConnections, "websocket", func(ctx context.Context) error {
+        hub.Close()
+        return nil
+    })
+    // Async subscribers, the event bridge included, handle queued events
+    // before the bridge disconnects
+    shutdown.Register(lifecycle.PhaseFlush, "event_bus", func(ctx context.Context) error {
+        bus.Close()
+        return nil
+    })
+    shutdown.Register(lifecycle.PhaseFlush, "tracing", stopTracing)
+    if eventBridge != nil {
+        shutdown.Register(lifecycle.PhaseConnections, "event_bridge", func(ctx context.Context) error {
+            return eventBridge.Close()
+        })
+    }
+
+    report, err := shutdown.WaitForSignal(context.Background(), time.Duration(cfg.Server.ShutdownTimeout), syscall.SIGINT, syscall.SIGTERM)
+    if err != nil {
+        slog.Error("shutdown error", "error", err)
+    }
+    if report != nil {
+        slog.Info("shutdown complete", "duration", report.Duration, "timed_out", report.TimedOut)
+    }
+}
+
+// fatal logs an error and exits
+func fatal(msg string, err error, args ...any) {
+    slog.Error(msg, append(args, "error", err)...)
+    os.Exit(1)
 }
@@ -0,0 +1,266 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Role determines what an API token is allowed to do
+type Role string
+
+const (
+	// RoleAdmin tokens have full access
+	RoleAdmin Role = "admin"
+	// RoleObserver tokens have read-only access to reporting endpoints
+	RoleObserver Role = "observer"
+)
+
+const tokenContextKey = "api_token"
+
+var (
+	// ErrInvalidTokenSpec is returned when a token specification cannot be parsed
+	ErrInvalidTokenSpec = errors.New("invalid token specification")
+)
+
+// APIToken describes an API token and its permissions
+type APIToken struct {
+	Name string
+	Role Role
+	// Portfolios restricts an observer to the listed sub-portfolios, the
+	// trading accounts of the same IDs; empty means all
+	Portfolios []string
+	// Sandbox routes trade-mutating calls to an isolated paper portfolio
+	Sandbox bool
+}
+
+// TokenStore holds API tokens keyed by their SHA-256 hash
+type TokenStore struct {
+	tokens map[string]*APIToken
+	mu     sync.RWMutex
+}
+
+// NewTokenStore creates a new token store
+func NewTokenStore() *TokenStore {
+	return &TokenStore{
+		tokens: make(map[string]*APIToken),
+	}
+}
+
+// Add registers a token secret with the given permissions
+func (s *TokenStore) Add(secret string, token APIToken) {
+	s.mu.Lock()
+	s.tokens[hashToken(secret)] = &token
+	s.mu.Unlock()
+}
+
+// Lookup returns the token registered for a secret
+func (s *TokenStore) Lookup(secret string) (*APIToken, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	token, ok := s.tokens[hashToken(secret)]
+	return token, ok
+}
+
+// Len returns the number of registered tokens
+func (s *TokenStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.tokens)
+}
+
+// LoadTokens parses a comma-separated list of
+// name:role:secret[:portfolio|portfolio[:sandbox]] entries into the store
+func (s *TokenStore) LoadTokens(spec string) error {
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.Split(entry, ":")
+		if len(parts) < 3 || len(parts) > 5 || parts[0] == "" || parts[2] == "" {
+			return fmt.Errorf("%w: %q", ErrInvalidTokenSpec, parts[0])
+		}
+
+		role := Role(parts[1])
+		if role != RoleAdmin && role != RoleObserver {
+			return fmt.Errorf("%w: unknown role %q", ErrInvalidTokenSpec, parts[1])
+		}
+
+		token := APIToken{Name: parts[0], Role: role}
+		if len(parts) >= 4 && parts[3] != "" {
+			token.Portfolios = strings.Split(parts[3], "|")
+		}
+		if len(parts) == 5 {
+			if parts[4] != "sandbox" {
+				return fmt.Errorf("%w: unknown flag %q", ErrInvalidTokenSpec, parts[4])
+			}
+			token.Sandbox = true
+		}
+		s.Add(parts[2], token)
+	}
+	return nil
+}
+
+// AuthConfig contains authentication configuration
+type AuthConfig struct {
+	// ObserverPaths are the path prefixes observer tokens may read. A
+	// segment starting with ':', such as /accounts/:account, matches any
+	// segment.
+	ObserverPaths []string
+	// QueryParam is the query parameter the token may be passed in instead
+	// of the Authorization header, for clients such as browser WebSockets
+	// that cannot set headers. Empty only accepts the header.
+	QueryParam string
+}
+
+// DefaultAuthConfig returns default authentication configuration, letting
+// observers read the performance reports, the equity curve and the
+// aggregate stats of the accounts, but no positions or orders
+func DefaultAuthConfig() AuthConfig {
+	return AuthConfig{
+		ObserverPaths: []string{
+			"/api/v1/reports",
+			"/api/v1/equity",
+			"/api/v1/stats",
+			"/api/v1/accounts/:account/reports",
+			"/api/v1/accounts/:account/equity",
+			"/api/v1/accounts/:account/stats",
+		},
+	}
+}
+
+// AuthMiddleware authenticates bearer tokens. Observer tokens are limited to
+// GET requests on the configured observer paths; everything else is denied.
+func AuthMiddleware(store *TokenStore, config AuthConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secret := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if secret == "" && config.QueryParam != "" {
+			secret = c.Query(config.QueryParam)
+		}
+		if secret == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing API token"})
+			return
+		}
+
+		token, ok := store.Lookup(secret)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid API token"})
+			return
+		}
+
+		if token.Role == RoleObserver && !observerAllowed(c.Request, config.ObserverPaths) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "observer tokens are read-only"})
+			return
+		}
+
+		c.Set(tokenContextKey, token)
+		c.Next()
+	}
+}
+
+// TokenFromContext returns the authenticated token for the request, if any
+func TokenFromContext(c *gin.Context) (*APIToken, bool) {
+	value, ok := c.Get(tokenContextKey)
+	if !ok {
+		return nil, false
+	}
+	token, ok := value.(*APIToken)
+	return token, ok
+}
+
+// IsSandbox reports whether the request was made with a sandbox token
+func IsSandbox(c *gin.Context) bool {
+	token, ok := TokenFromContext(c)
+	return ok && token.Sandbox
+}
+
+// DenySandboxWrites rejects mutating requests made with sandbox tokens, for
+// endpoints that have no paper equivalent and would change live settings
+func DenySandboxWrites() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if IsSandbox(c) && c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "sandbox tokens cannot change live settings"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// CanViewPortfolio reports whether the request may see the given sub-portfolio.
+// Requests without authentication configured are unrestricted.
+func CanViewPortfolio(c *gin.Context, portfolio string) bool {
+	token, ok := TokenFromContext(c)
+	return !ok || token.CanView(portfolio)
+}
+
+// CanViewAllPortfolios reports whether the request may see every
+// sub-portfolio, as needed for data aggregated across them
+func CanViewAllPortfolios(c *gin.Context) bool {
+	token, ok := TokenFromContext(c)
+	return !ok || token.Role == RoleAdmin || len(token.Portfolios) == 0
+}
+
+// RequirePortfolio answers requests that may not see the given
+// sub-portfolio with 404, for routes acting on a fixed sub-portfolio
+func RequirePortfolio(portfolio string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !CanViewPortfolio(c, portfolio) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "portfolio not found"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// CanView reports whether the token may see the given sub-portfolio
+func (t *APIToken) CanView(portfolio string) bool {
+	if t.Role == RoleAdmin || len(t.Portfolios) == 0 {
+		return true
+	}
+	for _, p := range t.Portfolios {
+		if p == portfolio {
+			return true
+		}
+	}
+	return false
+}
+
+func observerAllowed(r *http.Request, paths []string) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	for _, prefix := range paths {
+		if matchPrefix(segments, strings.Split(strings.Trim(prefix, "/"), "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPrefix reports whether the path segments start with the segments of
+// prefix, ':' segments of prefix matching any segment
+func matchPrefix(segments, prefix []string) bool {
+	if len(segments) < len(prefix) {
+		return false
+	}
+	for i, p := range prefix {
+		if p != segments[i] && !(strings.HasPrefix(p, ":") && segments[i] != "") {
+			return false
+		}
+	}
+	return true
+}
+
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := NewTokenStore()
+	assert.NoError(t, store.LoadTokens("ops:admin:admin-secret, investor:observer:observer-secret:fund-a|fund-b"))
+	assert.Equal(t, 2, store.Len())
+
+	r := gin.New()
+	api := r.Group("/api/v1", AuthMiddleware(store, DefaultAuthConfig()))
+	api.GET("/accounts/:portfolio/stats", func(c *gin.Context) {
+		if !CanViewPortfolio(c, c.Param("portfolio")) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "portfolio not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{})
+	})
+	api.GET("/accounts/:portfolio/positions", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{}) })
+	api.GET("/strategies", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{}) })
+	api.POST("/accounts/fund-a/stats", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{}) })
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		token    string
+		expected int
+	}{
+		{"Missing token", http.MethodGet, "/api/v1/accounts/fund-a/stats", "", http.StatusUnauthorized},
+		{"Unknown token", http.MethodGet, "/api/v1/accounts/fund-a/stats", "nope", http.StatusUnauthorized},
+		{"Observer reads stats", http.MethodGet, "/api/v1/accounts/fund-a/stats", "observer-secret", http.StatusOK},
+		{"Observer outside scope", http.MethodGet, "/api/v1/accounts/fund-c/stats", "observer-secret", http.StatusNotFound},
+		{"Observer reads positions", http.MethodGet, "/api/v1/accounts/fund-a/positions", "observer-secret", http.StatusForbidden},
+		{"Observer reads strategies", http.MethodGet, "/api/v1/strategies", "observer-secret", http.StatusForbidden},
+		{"Observer mutates", http.MethodPost, "/api/v1/accounts/fund-a/stats", "observer-secret", http.StatusForbidden},
+		{"Admin reads strategies", http.MethodGet, "/api/v1/strategies", "admin-secret", http.StatusOK},
+		{"Admin reads any portfolio", http.MethodGet, "/api/v1/accounts/fund-c/stats", "admin-secret", http.StatusOK},
+		{"Admin reads positions", http.MethodGet, "/api/v1/accounts/fund-c/positions", "admin-secret", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tt.expected, w.Code)
+		})
+	}
+
+	t.Run("Invalid token specs", func(t *testing.T) {
+		assert.ErrorIs(t, NewTokenStore().LoadTokens("missing-secret:admin"), ErrInvalidTokenSpec)
+		assert.ErrorIs(t, NewTokenStore().LoadTokens("x:superuser:secret"), ErrInvalidTokenSpec)
+		assert.ErrorIs(t, NewTokenStore().LoadTokens("x:admin:secret::paper"), ErrInvalidTokenSpec)
+	})
+
+	t.Run("Sandbox tokens", func(t *testing.T) {
+		store := NewTokenStore()
+		assert.NoError(t, store.LoadTokens("integrator:admin:sandbox-secret::sandbox"))
+
+		token, ok := store.Lookup("sandbox-secret")
+		assert.True(t, ok)
+		assert.True(t, token.Sandbox)
+		assert.Empty(t, token.Portfolios)
+
+		r := gin.New()
+		r.GET("/sandbox", AuthMiddleware(store, DefaultAuthConfig()), func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{"sandbox": IsSandbox(c)})
+		})
+		req := httptest.NewRequest(http.MethodGet, "/sandbox", nil)
+		req.Header.Set("Authorization", "Bearer sandbox-secret")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.JSONEq(t, `{"sandbox":true}`, w.Body.String())
+	})
+	t.Run("Query parameter tokens", func(t *testing.T) {
+		r := gin.New()
+		r.GET("/ws", AuthMiddleware(store, AuthConfig{ObserverPaths: []string{"/ws"}, QueryParam: "token"}), func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{})
+		})
+		r.GET("/header-only", AuthMiddleware(store, DefaultAuthConfig()), func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{})
+		})
+
+		for path, expected := range map[string]int{
+			"/ws?token=observer-secret":          http.StatusOK,
+			"/ws?token=nope":                     http.StatusUnauthorized,
+			"/ws":                                http.StatusUnauthorized,
+			"/header-only?token=observer-secret": http.StatusUnauthorized,
+		} {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			assert.Equal(t, expected, w.Code, path)
+		}
+	})
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/devinjacknz/godydxhyber/backend/logger"
+)
+
+// CorrelationHeader carries the correlation ID of a request and its response
+const CorrelationHeader = "X-Correlation-ID"
+
+// maxCorrelationIDLength bounds the correlation IDs accepted from clients
+const maxCorrelationIDLength = 64
+
+// Correlation adds a correlation ID to the request context, so the logs and
+// events of the orders and risk checks made by the request can be joined.
+// The ID of the request header is kept, otherwise a new one is generated,
+// and the ID is returned in the response header.
+func Correlation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(CorrelationHeader)
+		if id == "" || len(id) > maxCorrelationIDLength {
+			id = logger.NewCorrelationID()
+		}
+		c.Request = c.Request.WithContext(logger.WithCorrelationID(c.Request.Context(), id))
+		c.Header(CorrelationHeader, id)
+		c.Next()
+	}
+}
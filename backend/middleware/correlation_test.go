@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/devinjacknz/godydxhyber/backend/logger"
+)
+
+func TestCorrelation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(Correlation())
+	r.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, logger.CorrelationID(c.Request.Context()))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(CorrelationHeader, "client-id")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, "client-id", w.Body.String())
+	assert.Equal(t, "client-id", w.Header().Get(CorrelationHeader))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.NotEmpty(t, w.Body.String())
+	assert.Equal(t, w.Body.String(), w.Header().Get(CorrelationHeader))
+}
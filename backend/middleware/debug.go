@@ -1,7 +1,7 @@
 package middleware
 
 import (
-	"fmt"
+	"log/slog"
 	"net/http"
 	"runtime"
 	"time"
@@ -75,11 +75,11 @@ func DebugMiddleware() gin.HandlerFunc {
 		c.Set("request_stats", stats)
 		
 		// Log request stats
-		fmt.Printf("REQUEST STATS [%s] %s %s - %v\n",
-			stats.TraceID,
-			stats.Method,
-			stats.Path,
-			stats.Duration,
+		slog.DebugContext(c.Request.Context(), "request stats",
+			"trace_id", stats.TraceID,
+			"method", stats.Method,
+			"path", stats.Path,
+			"duration", stats.Duration,
 		)
 
 		// Store stats in memory for the /debug/stats endpoint
@@ -89,11 +89,11 @@ func DebugMiddleware() gin.HandlerFunc {
 		// Log slow requests (>500ms)
 		if stats.Duration > 500*time.Millisecond {
 			c.Set("slow_request", true)
-			fmt.Printf("SLOW REQUEST [%s] %s %s - %v\n",
-				stats.TraceID,
-				stats.Method,
-				stats.Path,
-				stats.Duration,
+			slog.WarnContext(c.Request.Context(), "slow request",
+				"trace_id", stats.TraceID,
+				"method", stats.Method,
+				"path", stats.Path,
+				"duration", stats.Duration,
 			)
 		}
 	}
@@ -153,11 +153,11 @@ func RecoverMiddleware() gin.HandlerFunc {
 				stackTrace := string(buf[:n])
 
 				// Log panic
-				fmt.Printf("PANIC [%s] %s\nError: %v\nStack Trace:\n%s\n",
-					c.GetString("trace_id"),
-					c.Request.URL.Path,
-					err,
-					stackTrace,
+				slog.ErrorContext(c.Request.Context(), "panic",
+					"trace_id", c.GetString("trace_id"),
+					"path", c.Request.URL.Path,
+					"error", err,
+					"stack_trace", stackTrace,
 				)
 
 				// Return error response
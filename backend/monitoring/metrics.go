@@ -1,6 +1,7 @@
 package monitoring
 
 import (
+	"sync"
 	"sync/atomic"
 	"time"
 )
@@ -16,6 +17,11 @@ type LLMMetrics struct {
 
 var metrics = &LLMMetrics{}
 
+var (
+	profileMetrics   = make(map[string]*LLMMetrics)
+	profileMetricsMu sync.Mutex
+)
+
 // RecordLLMRequest records metrics for an LLM API request
 func RecordLLMRequest(model, operation string, duration time.Duration, status string, tokens int) {
 	metrics.RequestCount.Add(1)
@@ -32,7 +38,41 @@ func RecordLLMFallback() {
 	metrics.FallbackCount.Add(1)
 }
 
+// RecordLLMProfileRequest records metrics for an LLM request routed through a
+// task profile, in both the overall and the per-profile metrics
+func RecordLLMProfileRequest(profile, model, operation string, duration time.Duration, status string, tokens int) {
+	RecordLLMRequest(model, operation, duration, status, tokens)
+
+	m := GetProfileMetrics(profile)
+	m.RequestCount.Add(1)
+	m.TotalLatencyMs.Add(duration.Milliseconds())
+	m.TotalTokens.Add(int64(tokens))
+
+	if status == "error" {
+		m.ErrorCount.Add(1)
+	}
+}
+
+// RecordLLMProfileFallback records a fallback attempt for a task profile
+func RecordLLMProfileFallback(profile string) {
+	RecordLLMFallback()
+	GetProfileMetrics(profile).FallbackCount.Add(1)
+}
+
 // GetMetrics returns the current metrics
 func GetMetrics() *LLMMetrics {
 	return metrics
 }
+
+// GetProfileMetrics returns the metrics for a task profile
+func GetProfileMetrics(profile string) *LLMMetrics {
+	profileMetricsMu.Lock()
+	defer profileMetricsMu.Unlock()
+
+	m, ok := profileMetrics[profile]
+	if !ok {
+		m = &LLMMetrics{}
+		profileMetrics[profile] = m
+	}
+	return m
+}
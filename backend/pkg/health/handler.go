@@ -0,0 +1,29 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes registers the health endpoints, answering 200 when the
+// report is ok and 503 otherwise:
+//
+//	GET /healthz  liveness
+//	GET /readyz   readiness
+func RegisterRoutes(r gin.IRouter, c *Checker) {
+	r.GET("/healthz", func(ctx *gin.Context) {
+		writeReport(ctx, c.Liveness())
+	})
+	r.GET("/readyz", func(ctx *gin.Context) {
+		writeReport(ctx, c.Readiness())
+	})
+}
+
+func writeReport(c *gin.Context, report Report) {
+	status := http.StatusOK
+	if !report.OK() {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
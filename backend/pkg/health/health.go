@@ -0,0 +1,306 @@
+// Package health runs the probes of the dependencies of the server in the
+// background and reports liveness on /healthz and readiness on /readyz.
+// Liveness fails only when the process itself is unhealthy, so an
+// orchestrator restarts it; readiness also fails while a dependency is down,
+// so traffic is held back until it recovers.
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Probe checks a dependency, returning an error when it is unavailable.
+// Probes must return when the context ends.
+type Probe func(ctx context.Context) error
+
+// Kind tells which reports a check counts toward
+type Kind int
+
+const (
+	// Readiness checks a dependency; a failure makes the server not ready
+	Readiness Kind = iota
+	// Liveness checks the process; a failure makes the server neither live
+	// nor ready
+	Liveness
+	// Informational checks are reported without failing either report
+	Informational
+)
+
+var kindNames = map[Kind]string{
+	Readiness:     "readiness",
+	Liveness:      "liveness",
+	Informational: "informational",
+}
+
+func (k Kind) String() string {
+	if name, ok := kindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("kind(%d)", int(k))
+}
+
+// MarshalText encodes the kind by name
+func (k Kind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// Check statuses
+const (
+	StatusUp      = "up"
+	StatusDown    = "down"
+	StatusPending = "pending"
+)
+
+// Report statuses
+const (
+	StatusOK      = "ok"
+	StatusFailing = "failing"
+)
+
+// Config contains the schedule of the probes
+type Config struct {
+	// Interval is the time between two runs of the probes
+	Interval time.Duration
+	// Timeout bounds a probe without its own timeout
+	Timeout time.Duration
+	// StaleAfter is how long results stay valid; liveness fails when the
+	// probes have not completed for longer, as the checker is stuck. Zero
+	// means three intervals.
+	StaleAfter time.Duration
+}
+
+// DefaultConfig returns the default checker configuration
+func DefaultConfig() Config {
+	return Config{
+		Interval: 15 * time.Second,
+		Timeout:  5 * time.Second,
+	}
+}
+
+// Option configures a check
+type Option func(*check)
+
+// WithKind sets the kind of a check, Readiness by default
+func WithKind(kind Kind) Option {
+	return func(c *check) {
+		c.kind = kind
+	}
+}
+
+// WithTimeout sets the timeout of a check, overriding the checker timeout
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *check) {
+		c.timeout = timeout
+	}
+}
+
+type check struct {
+	name    string
+	probe   Probe
+	kind    Kind
+	timeout time.Duration
+}
+
+// Result is the last outcome of a check
+type Result struct {
+	Name      string        `json:"name"`
+	Kind      Kind          `json:"kind"`
+	Status    string        `json:"status"`
+	Error     string        `json:"error,omitempty"`
+	Duration  time.Duration `json:"duration"`
+	CheckedAt time.Time     `json:"checked_at,omitempty"`
+}
+
+// Report aggregates the results of the checks of a report
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+	// LastRun is when the probes last completed, zero before the first run
+	LastRun time.Time `json:"last_run,omitempty"`
+}
+
+// OK reports whether every counted check is up
+func (r Report) OK() bool {
+	return r.Status == StatusOK
+}
+
+// Checker runs the registered probes periodically and keeps their results
+type Checker struct {
+	config  Config
+	checks  []*check
+	results map[string]Result
+	lastRun time.Time
+	now     func() time.Time
+	mu      sync.RWMutex
+}
+
+// NewChecker creates a checker without checks
+func NewChecker(config Config) *Checker {
+	defaults := DefaultConfig()
+	if config.Interval <= 0 {
+		config.Interval = defaults.Interval
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaults.Timeout
+	}
+	if config.StaleAfter <= 0 {
+		config.StaleAfter = 3 * config.Interval
+	}
+	return &Checker{
+		config:  config,
+		results: make(map[string]Result),
+		now:     time.Now,
+	}
+}
+
+// Register adds a check. Registering a name again replaces its check.
+func (c *Checker) Register(name string, probe Probe, opts ...Option) {
+	ch := &check{name: name, probe: probe, kind: Readiness, timeout: c.config.Timeout}
+	for _, opt := range opts {
+		opt(ch)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, existing := range c.checks {
+		if existing.name == name {
+			c.checks[i] = ch
+			delete(c.results, name)
+			return
+		}
+	}
+	c.checks = append(c.checks, ch)
+}
+
+// Run runs the probes at once and then every interval until the context
+// ends
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		c.CheckNow(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckNow runs every probe concurrently and stores their results
+func (c *Checker) CheckNow(ctx context.Context) {
+	c.mu.RLock()
+	checks := make([]*check, len(c.checks))
+	copy(checks, c.checks)
+	c.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, ch := range checks {
+		wg.Add(1)
+		go func(i int, ch *check) {
+			defer wg.Done()
+			results[i] = c.run(ctx, ch)
+		}(i, ch)
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		// Probes cut short by shutdown say nothing about the dependencies
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, ch := range checks {
+		recordResult(results[i])
+		c.results[ch.name] = results[i]
+	}
+	c.lastRun = c.now()
+}
+
+// run runs a probe within its timeout. A probe ignoring its context is
+// abandoned at the timeout rather than holding up the other checks.
+func (c *Checker) run(ctx context.Context, ch *check) Result {
+	ctx, cancel := context.WithTimeout(ctx, ch.timeout)
+	defer cancel()
+
+	start := c.now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("probe panicked: %v", r)
+			}
+		}()
+		done <- ch.probe(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("probe timed out after %s", ch.timeout)
+	}
+
+	result := Result{
+		Name:      ch.name,
+		Kind:      ch.kind,
+		Status:    StatusUp,
+		Duration:  c.now().Sub(start),
+		CheckedAt: start,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Liveness reports the liveness checks. It fails when one of them is down
+// or the probes have not completed for longer than StaleAfter; it passes
+// before the first run, while the server starts.
+func (c *Checker) Liveness() Report {
+	report := c.report(func(k Kind) bool { return k == Liveness }, false)
+	if !report.LastRun.IsZero() && c.now().Sub(report.LastRun) > c.config.StaleAfter {
+		report.Status = StatusFailing
+	}
+	return report
+}
+
+// Readiness reports every check. It fails while a liveness or readiness
+// check is down or has not run yet.
+func (c *Checker) Readiness() Report {
+	return c.report(func(Kind) bool { return true }, true)
+}
+
+// report collects the results of the included checks, failing when one
+// that is not informational is down, or pending when pendingFails is set
+func (c *Checker) report(include func(Kind) bool, pendingFails bool) Report {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	report := Report{Status: StatusOK, Checks: make([]Result, 0, len(c.checks)), LastRun: c.lastRun}
+	for _, ch := range c.checks {
+		if !include(ch.kind) {
+			continue
+		}
+		result, ok := c.results[ch.name]
+		if !ok {
+			result = Result{Name: ch.name, Kind: ch.kind, Status: StatusPending}
+		}
+		failed := result.Status == StatusDown || (result.Status == StatusPending && pendingFails)
+		if failed && ch.kind != Informational {
+			report.Status = StatusFailing
+		}
+		report.Checks = append(report.Checks, result)
+	}
+	sort.Slice(report.Checks, func(i, j int) bool {
+		return report.Checks[i].Name < report.Checks[j].Name
+	})
+	return report
+}
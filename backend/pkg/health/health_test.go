@@ -0,0 +1,179 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func up(ctx context.Context) error { return nil }
+
+func TestReports(t *testing.T) {
+	failing := errors.New("connection refused")
+	var dbErr error
+	c := NewChecker(Config{Interval: time.Minute})
+	c.Register("db", func(ctx context.Context) error { return dbErr })
+	c.Register("loop", up, WithKind(Liveness))
+	c.Register("llm", func(ctx context.Context) error { return failing }, WithKind(Informational))
+
+	// Not ready until the probes have run, but live while starting
+	assert.False(t, c.Readiness().OK())
+	assert.True(t, c.Liveness().OK())
+
+	c.CheckNow(context.Background())
+	ready := c.Readiness()
+	assert.True(t, ready.OK(), "informational checks do not fail readiness")
+	require.Len(t, ready.Checks, 3)
+	assert.Equal(t, "llm", ready.Checks[1].Name)
+	assert.Equal(t, StatusDown, ready.Checks[1].Status)
+	assert.Equal(t, "connection refused", ready.Checks[1].Error)
+
+	live := c.Liveness()
+	require.Len(t, live.Checks, 1)
+	assert.Equal(t, "loop", live.Checks[0].Name)
+
+	dbErr = failing
+	c.CheckNow(context.Background())
+	assert.False(t, c.Readiness().OK())
+	assert.True(t, c.Liveness().OK(), "dependencies do not fail liveness")
+
+	c.Register("loop", func(ctx context.Context) error { return failing }, WithKind(Liveness))
+	assert.Equal(t, StatusPending, c.Liveness().Checks[0].Status)
+	c.CheckNow(context.Background())
+	assert.False(t, c.Liveness().OK())
+}
+
+func TestProbeFailures(t *testing.T) {
+	c := NewChecker(Config{Timeout: 20 * time.Millisecond})
+	block := make(chan struct{})
+	defer close(block)
+	c.Register("stuck", func(ctx context.Context) error {
+		<-block
+		return nil
+	})
+	c.Register("panics", func(ctx context.Context) error {
+		panic("nil client")
+	})
+	c.Register("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithTimeout(10*time.Millisecond))
+
+	start := time.Now()
+	c.CheckNow(context.Background())
+	assert.Less(t, time.Since(start), time.Second)
+
+	results := c.Readiness().Checks
+	require.Len(t, results, 3)
+	assert.Equal(t, "probe panicked: nil client", results[0].Error)
+	assert.Contains(t, results[1].Error, "timed out after 10ms")
+	assert.Contains(t, results[2].Error, "timed out after 20ms")
+}
+
+func TestStaleResults(t *testing.T) {
+	c := NewChecker(Config{Interval: time.Second})
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	c.Register("db", up)
+
+	c.CheckNow(context.Background())
+	assert.True(t, c.Liveness().OK())
+
+	now = now.Add(4 * time.Second)
+	assert.False(t, c.Liveness().OK(), "a stuck checker is not live")
+
+	// Probes cut short by the end of the context keep the last results
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.CheckNow(ctx)
+	assert.False(t, c.Liveness().OK())
+}
+
+func TestRun(t *testing.T) {
+	c := NewChecker(Config{Interval: 10 * time.Millisecond})
+	runs := make(chan struct{}, 10)
+	c.Register("db", func(ctx context.Context) error {
+		select {
+		case runs <- struct{}{}:
+		default:
+		}
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Run(ctx)
+		close(done)
+	}()
+	for i := 0; i < 3; i++ {
+		<-runs
+	}
+	cancel()
+	<-done
+	assert.True(t, c.Readiness().OK())
+}
+
+func TestRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c := NewChecker(DefaultConfig())
+	c.Register("db", func(ctx context.Context) error { return errors.New("down") })
+	c.CheckNow(context.Background())
+	r := gin.New()
+	RegisterRoutes(r, c)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	var report struct {
+		Status string `json:"status"`
+		Checks []struct {
+			Name   string `json:"name"`
+			Kind   string `json:"kind"`
+			Status string `json:"status"`
+			Error  string `json:"error"`
+		} `json:"checks"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, StatusFailing, report.Status)
+	require.Len(t, report.Checks, 1)
+	assert.Equal(t, "readiness", report.Checks[0].Kind)
+	assert.Equal(t, "down", report.Checks[0].Error)
+}
+
+func TestHTTPProbe(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/time":
+			assert.Equal(t, "Bearer key", r.Header.Get("Authorization"))
+			w.Write([]byte(`{"iso":"2024-01-01T00:00:00Z"}`))
+		case "/info":
+			body, _ := io.ReadAll(r.Body)
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			assert.JSONEq(t, `{"type":"meta"}`, string(body))
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	assert.NoError(t, HTTPProbe(http.MethodGet, srv.URL+"/time", http.Header{"Authorization": {"Bearer key"}}, nil)(ctx))
+	assert.NoError(t, HTTPProbe(http.MethodPost, srv.URL+"/info", nil, []byte(`{"type":"meta"}`))(ctx))
+	err := HTTPProbe(http.MethodGet, srv.URL+"/missing", nil, nil)(ctx)
+	assert.EqualError(t, err, "GET "+srv.URL+"/missing answered 502")
+}
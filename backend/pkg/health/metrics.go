@@ -0,0 +1,34 @@
+package health
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	checkUp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "health_check_up",
+			Help: "Whether the last run of a health check succeeded",
+		},
+		[]string{"check", "kind"},
+	)
+	checkDuration = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "health_check_duration_seconds",
+			Help: "Duration of the last run of a health check",
+		},
+		[]string{"check", "kind"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(checkUp, checkDuration)
+}
+
+func recordResult(r Result) {
+	up := 0.0
+	if r.Status == StatusUp {
+		up = 1
+	}
+	kind := r.Kind.String()
+	checkUp.WithLabelValues(r.Name, kind).Set(up)
+	checkDuration.WithLabelValues(r.Name, kind).Set(r.Duration.Seconds())
+}
@@ -0,0 +1,45 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// probeClient sends the requests of HTTP probes. Probes bound requests with
+// their context and do not retry, so a failing dependency shows at once.
+var probeClient = &http.Client{}
+
+// HTTPProbe returns a probe sending a request to a URL, succeeding on a 2xx
+// response. body may be nil; a non-nil body is sent as JSON.
+func HTTPProbe(method, url string, header http.Header, body []byte) Probe {
+	return func(ctx context.Context) error {
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, url, reader)
+		if err != nil {
+			return err
+		}
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		resp, err := probeClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("%s %s answered %d", method, url, resp.StatusCode)
+		}
+		return nil
+	}
+}
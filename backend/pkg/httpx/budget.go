@@ -0,0 +1,63 @@
+package httpx
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Budget bounds the retries of the clients sharing it, so that an outage of
+// a dependency does not multiply the load on it. Every request deposits
+// ratio tokens and every second adds minPerSecond tokens; a retry spends one
+// token and is skipped when there is none.
+type Budget struct {
+	mu           sync.Mutex
+	ratio        float64
+	minPerSecond float64
+	capacity     float64
+	tokens       float64
+	last         time.Time
+}
+
+// DefaultBudget is the budget of clients without their own. Retries may add
+// a fifth to the request load, plus one retry per second.
+var DefaultBudget = NewBudget(0.2, 1)
+
+// NewBudget creates a retry budget. It holds at most ten seconds of
+// minPerSecond and at least ten retries.
+func NewBudget(ratio, minPerSecond float64) *Budget {
+	capacity := math.Max(10*minPerSecond, 10)
+	return &Budget{
+		ratio:        ratio,
+		minPerSecond: minPerSecond,
+		capacity:     capacity,
+		tokens:       capacity,
+		last:         time.Now(),
+	}
+}
+
+// deposit records a request
+func (b *Budget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	b.tokens = math.Min(b.tokens+b.ratio, b.capacity)
+}
+
+// withdraw spends a token for a retry, reporting whether one was left
+func (b *Budget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func (b *Budget) refill() {
+	now := time.Now()
+	b.tokens = math.Min(b.tokens+now.Sub(b.last).Seconds()*b.minPerSecond, b.capacity)
+	b.last = now
+}
@@ -0,0 +1,369 @@
+// Package httpx is the HTTP client shared by the exchange and LLM clients.
+// It limits the request rate per host, retries failed requests with jittered
+// backoff within a shared retry budget, bounds request and response sizes
+// and records latency and error metrics per endpoint.
+package httpx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+
+	"github.com/devinjacknz/godydxhyber/backend/pkg/tracing"
+)
+
+var (
+	// ErrRequestTooLarge is returned for request bodies over the limit
+	ErrRequestTooLarge = errors.New("request body too large")
+
+	// ErrResponseTooLarge is returned when reading a response body over the
+	// limit
+	ErrResponseTooLarge = errors.New("response body too large")
+)
+
+// Config contains the settings of a client. Zero fields take the defaults
+// of DefaultConfig.
+type Config struct {
+	// Name labels the metrics of the client
+	Name string
+	// Timeout bounds each attempt of a request
+	Timeout time.Duration
+	// MaxRetries is the number of retries of a failed request. Negative
+	// disables retries.
+	MaxRetries  int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// MaxRetryAfter is the longest Retry-After waited for; responses asking
+	// for longer are returned
+	MaxRetryAfter   time.Duration
+	MaxRequestBody  int64
+	MaxResponseBody int64
+	// Limits and Budget default to DefaultLimits and DefaultBudget
+	Limits *Limits
+	Budget *Budget
+}
+
+// DefaultConfig returns the default client configuration
+func DefaultConfig() Config {
+	return Config{
+		Name:            "default",
+		Timeout:         10 * time.Second,
+		MaxRetries:      2,
+		BaseBackoff:     100 * time.Millisecond,
+		MaxBackoff:      5 * time.Second,
+		MaxRetryAfter:   30 * time.Second,
+		MaxRequestBody:  1 << 20,
+		MaxResponseBody: 10 << 20,
+	}
+}
+
+// Client sends HTTP requests. Its Do method matches http.Client.
+type Client struct {
+	http   *http.Client
+	config Config
+	limits *Limits
+	budget *Budget
+}
+
+// New creates a client
+func New(config Config) *Client {
+	defaults := DefaultConfig()
+	if config.Name == "" {
+		config.Name = defaults.Name
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaults.Timeout
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = defaults.MaxRetries
+	}
+	if config.BaseBackoff <= 0 {
+		config.BaseBackoff = defaults.BaseBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = defaults.MaxBackoff
+	}
+	if config.MaxRetryAfter <= 0 {
+		config.MaxRetryAfter = defaults.MaxRetryAfter
+	}
+	if config.MaxRequestBody <= 0 {
+		config.MaxRequestBody = defaults.MaxRequestBody
+	}
+	if config.MaxResponseBody <= 0 {
+		config.MaxResponseBody = defaults.MaxResponseBody
+	}
+	if config.Limits == nil {
+		config.Limits = DefaultLimits
+	}
+	if config.Budget == nil {
+		config.Budget = DefaultBudget
+	}
+
+	return &Client{
+		http:   &http.Client{Timeout: config.Timeout},
+		config: config,
+		limits: config.Limits,
+		budget: config.Budget,
+	}
+}
+
+// Signer sets the authentication headers of a request
+type Signer func(req *http.Request) error
+
+// Limits returns the per-host limits of the client
+func (c *Client) Limits() *Limits {
+	return c.limits
+}
+
+// Do sends a request, waiting for the rate limit of its host. Network
+// errors and 502, 503 and 504 responses are retried for idempotent
+// requests, and 429 responses for any request, while the retry budget
+// allows. The body of the returned response fails with ErrResponseTooLarge
+// past the size limit. The request is traced in a client span whose trace
+// context is sent in the request headers. Retries resend the headers of the
+// first attempt, so requests signed with a timestamp or nonce are sent with
+// DoSigned.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	return c.send(req, nil)
+}
+
+// DoSigned sends a request like Do, signing every attempt with sign once the
+// rate limit allows it, so that retries carry a fresh timestamp and nonce
+// instead of being rejected as replays.
+func (c *Client) DoSigned(req *http.Request, sign Signer) (*http.Response, error) {
+	return c.send(req, sign)
+}
+
+// send traces and sends a request, signing every attempt when sign is set
+func (c *Client) send(req *http.Request, sign Signer) (*http.Response, error) {
+	if req.ContentLength > c.config.MaxRequestBody {
+		return nil, fmt.Errorf("%w: %d bytes", ErrRequestTooLarge, req.ContentLength)
+	}
+
+	endpoint := endpointOf(req)
+	ctx, span := tracing.StartClient(req.Context(), endpoint,
+		attribute.String("http.client", c.config.Name),
+		attribute.String("http.request.method", req.Method),
+		attribute.String("server.address", req.URL.Host),
+	)
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := c.do(req, endpoint, sign)
+	if resp != nil {
+		span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	}
+	tracing.End(span, err)
+	return resp, err
+}
+
+// do sends a request with retries
+func (c *Client) do(req *http.Request, endpoint string, sign Signer) (*http.Response, error) {
+	ctx := req.Context()
+	host := req.URL.Host
+	c.budget.deposit()
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.Body != nil && req.Body != http.NoBody {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("rewind request body: %w", err)
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+		if err := c.limits.Wait(ctx, host); err != nil {
+			return nil, err
+		}
+		if sign != nil {
+			if err := sign(req); err != nil {
+				return nil, fmt.Errorf("sign request: %w", err)
+			}
+		}
+
+		start := time.Now()
+		resp, err := c.http.Do(req)
+		code := "error"
+		if err == nil {
+			code = strconv.Itoa(resp.StatusCode)
+		}
+		requests.WithLabelValues(c.config.Name, host, endpoint, code).Inc()
+		requestDuration.WithLabelValues(c.config.Name, host, endpoint).Observe(time.Since(start).Seconds())
+		slog.DebugContext(ctx, "http request",
+			"client", c.config.Name,
+			"method", req.Method,
+			"host", host,
+			"endpoint", endpoint,
+			"status", code,
+			"attempt", attempt,
+			"duration", time.Since(start))
+
+		delay, reason := c.retryable(req, resp, err, attempt)
+		if reason != "" && !c.budget.withdraw() {
+			budgetExhausted.WithLabelValues(c.config.Name, host).Inc()
+			reason = ""
+		}
+		if reason == "" {
+			if err != nil {
+				return nil, err
+			}
+			return c.limitResponse(resp)
+		}
+
+		retries.WithLabelValues(c.config.Name, host, reason).Inc()
+		slog.WarnContext(ctx, "retrying http request",
+			"client", c.config.Name,
+			"host", host,
+			"endpoint", endpoint,
+			"reason", reason,
+			"delay", delay)
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// retryable returns the delay before retrying a request and the reason to
+// retry it, or an empty reason if it is not retried
+func (c *Client) retryable(req *http.Request, resp *http.Response, err error, attempt int) (time.Duration, string) {
+	if attempt >= c.config.MaxRetries || req.Context().Err() != nil {
+		return 0, ""
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return 0, ""
+	}
+
+	if err != nil {
+		if !idempotent(req) {
+			return 0, ""
+		}
+		return c.backoff(attempt), "error"
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		if !idempotent(req) {
+			return 0, ""
+		}
+	default:
+		return 0, ""
+	}
+
+	reason := strconv.Itoa(resp.StatusCode)
+	if after, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+		if after > c.config.MaxRetryAfter {
+			return 0, ""
+		}
+		return after, reason
+	}
+	return c.backoff(attempt), reason
+}
+
+// backoff returns a random delay up to the exponential backoff of an
+// attempt
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.config.BaseBackoff << attempt
+	if d <= 0 || d > c.config.MaxBackoff {
+		d = c.config.MaxBackoff
+	}
+	return time.Duration(rand.Int63n(int64(d)) + 1)
+}
+
+// retryAfter parses a Retry-After header in seconds or as an HTTP date
+func retryAfter(header string) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(header); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// idempotent reports whether a request may be sent twice
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+// limitResponse bounds the body of a response
+func (c *Client) limitResponse(resp *http.Response) (*http.Response, error) {
+	if resp.ContentLength > c.config.MaxResponseBody {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %d bytes", ErrResponseTooLarge, resp.ContentLength)
+	}
+	resp.Body = &limitedBody{ReadCloser: resp.Body, remaining: c.config.MaxResponseBody}
+	return resp, nil
+}
+
+// limitedBody fails reads past its size limit
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		// Allow a clean EOF exactly at the limit
+		var one [1]byte
+		if n, err := b.ReadCloser.Read(one[:]); n == 0 {
+			return 0, err
+		}
+		return 0, ErrResponseTooLarge
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+// CloseIdleConnections closes the idle connections of the client
+func (c *Client) CloseIdleConnections() {
+	c.http.CloseIdleConnections()
+}
+
+// endpointOf returns the method and path of a request with the segments
+// holding IDs replaced, to keep the metric labels few. Segments with digits
+// are taken as IDs, except versions like v3.
+func endpointOf(req *http.Request) string {
+	segments := strings.Split(req.URL.Path, "/")
+	for i, s := range segments {
+		if strings.IndexFunc(s, unicode.IsDigit) < 0 {
+			continue
+		}
+		if _, err := strconv.Atoi(strings.TrimPrefix(s, "v")); err == nil && strings.HasPrefix(s, "v") {
+			continue
+		}
+		segments[i] = ":id"
+	}
+	return req.Method + " " + strings.Join(segments, "/")
+}
@@ -0,0 +1,176 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(budget *Budget) *Client {
+	return New(Config{
+		Name:        "test",
+		BaseBackoff: time.Millisecond,
+		MaxBackoff:  5 * time.Millisecond,
+		Limits:      NewLimits(),
+		Budget:      budget,
+	})
+}
+
+func TestRetriesHonorRetryAfter(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, "order", string(body), "the body is sent again on retries")
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest("POST", server.URL+"/v3/orders", strings.NewReader("order"))
+	resp, err := newTestClient(NewBudget(0.2, 1)).Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestDoSignedSignsEveryAttempt(t *testing.T) {
+	var nonces []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nonces = append(nonces, r.Header.Get("Nonce"))
+		if len(nonces) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	var signed int
+	req, _ := http.NewRequest("POST", server.URL+"/v3/orders", strings.NewReader("order"))
+	resp, err := newTestClient(NewBudget(0.2, 1)).DoSigned(req, func(req *http.Request) error {
+		signed++
+		req.Header.Set("Nonce", strconv.Itoa(signed))
+		return nil
+	})
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, []string{"1", "2"}, nonces)
+	assert.Empty(t, req.Header.Get("Nonce"), "the caller's request is not modified")
+
+	_, err = newTestClient(NewBudget(0.2, 1)).DoSigned(req, func(req *http.Request) error {
+		return errors.New("clock not synced")
+	})
+	assert.ErrorContains(t, err, "clock not synced")
+}
+
+func TestNonIdempotentRequestsAreNotRetriedOnServerErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	client := newTestClient(NewBudget(0.2, 1))
+
+	req, _ := http.NewRequest("POST", server.URL, strings.NewReader("order"))
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	req, _ = http.NewRequest("GET", server.URL, nil)
+	resp, err = client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&calls), "a GET is retried twice")
+}
+
+func TestRetryBudgetIsShared(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	budget := NewBudget(0, 0)
+	a, b := newTestClient(budget), newTestClient(budget)
+	for i := 0; i < 6; i++ {
+		client := a
+		if i%2 == 1 {
+			client = b
+		}
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+	// Six requests and the ten retries of the budget
+	assert.Equal(t, int32(16), atomic.LoadInt32(&calls))
+}
+
+func TestSizeLimits(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 100)))
+	}))
+	defer server.Close()
+	client := New(Config{MaxRequestBody: 10, MaxResponseBody: 50, Limits: NewLimits()})
+
+	req, _ := http.NewRequest("POST", server.URL, strings.NewReader(strings.Repeat("x", 11)))
+	_, err := client.Do(req)
+	assert.ErrorIs(t, err, ErrRequestTooLarge)
+
+	req, _ = http.NewRequest("GET", server.URL, nil)
+	_, err = client.Do(req)
+	assert.ErrorIs(t, err, ErrResponseTooLarge, "Content-Length is over the limit")
+
+	client = New(Config{MaxResponseBody: 100, Limits: NewLimits()})
+	req, _ = http.NewRequest("GET", server.URL, nil)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err, "a body of exactly the limit is read")
+	assert.Len(t, body, 100)
+}
+
+func TestHostLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	limits := NewLimits()
+	limits.Set(strings.TrimPrefix(server.URL, "http://"), 1, 1)
+	client := New(Config{Limits: limits})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	req, _ = http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	_, err = client.Do(req)
+	assert.Error(t, err, "the second request waits past the deadline")
+}
+
+func TestEndpointOf(t *testing.T) {
+	req := httptest.NewRequest("GET", "/v4/addresses/dydx1abc/subaccountNumber/0", nil)
+	assert.Equal(t, "GET /v4/addresses/:id/subaccountNumber/:id", endpointOf(req))
+}
@@ -0,0 +1,67 @@
+package httpx
+
+import (
+	"context"
+	"net/url"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// Limits holds the token buckets limiting the request rate to each host.
+// Clients sharing Limits share the buckets, so two clients of the same API
+// together stay within its limit. Exchange clients set the limit of their API
+// host when created, and their WithRateLimit options override it; requests
+// wait for a token of their host, and hosts never set are not limited.
+type Limits struct {
+	mu    sync.RWMutex
+	hosts map[string]*rate.Limiter
+}
+
+// DefaultLimits are the limits of clients without their own
+var DefaultLimits = NewLimits()
+
+// NewLimits creates limits without any host limited
+func NewLimits() *Limits {
+	return &Limits{hosts: make(map[string]*rate.Limiter)}
+}
+
+// Set limits the requests to a host, e.g. "api.dydx.exchange", to rps
+// requests per second with bursts of burst requests. A host limited before
+// keeps its bucket with the new rate.
+func (l *Limits) Set(host string, rps float64, burst int) {
+	if burst < 1 {
+		burst = 1
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if limiter, ok := l.hosts[host]; ok {
+		limiter.SetLimit(rate.Limit(rps))
+		limiter.SetBurst(burst)
+		return
+	}
+	l.hosts[host] = rate.NewLimiter(rate.Limit(rps), burst)
+}
+
+// Wait blocks until a request to host is allowed. Hosts without a limit are
+// not waited for.
+func (l *Limits) Wait(ctx context.Context, host string) error {
+	l.mu.RLock()
+	limiter := l.hosts[host]
+	l.mu.RUnlock()
+
+	if limiter == nil {
+		return nil
+	}
+	return limiter.Wait(ctx)
+}
+
+// Host returns the host of a URL, as limited by Limits
+func Host(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
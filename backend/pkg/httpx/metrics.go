@@ -0,0 +1,39 @@
+package httpx
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	requests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "httpx_requests_total",
+			Help: "Total number of outgoing HTTP requests by status code, or error",
+		},
+		[]string{"client", "host", "endpoint", "code"},
+	)
+	requestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "httpx_request_duration_seconds",
+			Help:    "Latency of outgoing HTTP requests",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"client", "host", "endpoint"},
+	)
+	retries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "httpx_retries_total",
+			Help: "Total number of retried HTTP requests by reason",
+		},
+		[]string{"client", "host", "reason"},
+	)
+	budgetExhausted = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "httpx_retry_budget_exhausted_total",
+			Help: "Total number of retries skipped because the retry budget was spent",
+		},
+		[]string{"client", "host"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(requests, requestDuration, retries, budgetExhausted)
+}
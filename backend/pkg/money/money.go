@@ -0,0 +1,273 @@
+// Package money provides an exact decimal type for prices, sizes and profit
+// and loss.
+//
+// Orders and positions keep their prices, sizes and profit and loss as
+// Decimal, which encodes as a JSON number. Exchange adapters, analytics and
+// the other models still work in float64 and convert at their boundaries. A
+// float converts to the decimal with the fewest digits that rounds to it, so
+// a daily total kept as a float rounds once per update instead of
+// accumulating binary rounding errors.
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// MaxPlaces is the number of decimal places String keeps of decimals that do
+// not terminate, such as the average of three prices
+const MaxPlaces = 18
+
+// ErrInvalidDecimal is returned when parsing text that is not a decimal number
+var ErrInvalidDecimal = errors.New("invalid decimal")
+
+// ErrDivisionByZero is returned when dividing by a zero decimal
+var ErrDivisionByZero = errors.New("division by zero")
+
+var (
+	ten = big.NewInt(10)
+	two = big.NewInt(2)
+)
+
+// Decimal is an exact rational amount. The zero value is zero and Decimals
+// are immutable, so they can be copied freely.
+type Decimal struct {
+	// r is the amount, nil for zero
+	r *big.Rat
+}
+
+// Zero is the zero decimal
+var Zero Decimal
+
+// NewFromInt returns the decimal of an integer
+func NewFromInt(i int64) Decimal {
+	return fromRat(new(big.Rat).SetInt64(i))
+}
+
+// FromFloat returns the decimal with the fewest digits that rounds to f, so
+// that 0.1 is one tenth rather than the binary value nearest to it. NaN and
+// infinities convert to zero.
+func FromFloat(f float64) Decimal {
+	if f == 0 || math.IsNaN(f) || math.IsInf(f, 0) {
+		return Zero
+	}
+	r, _ := new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, 64))
+	return fromRat(r)
+}
+
+// Parse parses a decimal number such as "-12.345" or "1e-7"
+func Parse(s string) (Decimal, error) {
+	text := strings.TrimSpace(s)
+	if strings.ContainsAny(text, "/_") {
+		return Zero, fmt.Errorf("%w: %q", ErrInvalidDecimal, s)
+	}
+	r, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return Zero, fmt.Errorf("%w: %q", ErrInvalidDecimal, s)
+	}
+	return fromRat(r), nil
+}
+
+// MustParse is like Parse but panics on invalid text. It is meant for
+// constants.
+func MustParse(s string) Decimal {
+	d, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func fromRat(r *big.Rat) Decimal {
+	if r.Sign() == 0 {
+		return Zero
+	}
+	return Decimal{r: r}
+}
+
+func (d Decimal) rat() *big.Rat {
+	if d.r == nil {
+		return new(big.Rat)
+	}
+	return d.r
+}
+
+// Add returns d + x
+func (d Decimal) Add(x Decimal) Decimal {
+	return fromRat(new(big.Rat).Add(d.rat(), x.rat()))
+}
+
+// Sub returns d - x
+func (d Decimal) Sub(x Decimal) Decimal {
+	return fromRat(new(big.Rat).Sub(d.rat(), x.rat()))
+}
+
+// Neg returns -d
+func (d Decimal) Neg() Decimal {
+	return fromRat(new(big.Rat).Neg(d.rat()))
+}
+
+// Abs returns the absolute value of d
+func (d Decimal) Abs() Decimal {
+	return fromRat(new(big.Rat).Abs(d.rat()))
+}
+
+// Mul returns d * x
+func (d Decimal) Mul(x Decimal) Decimal {
+	return fromRat(new(big.Rat).Mul(d.rat(), x.rat()))
+}
+
+// Div returns d / x, or ErrDivisionByZero if x is zero
+func (d Decimal) Div(x Decimal) (Decimal, error) {
+	if x.IsZero() {
+		return Zero, ErrDivisionByZero
+	}
+	return fromRat(new(big.Rat).Quo(d.rat(), x.rat())), nil
+}
+
+// Round returns d rounded half to even to places decimal places, such as 2
+// for cents. Rounding half to even does not bias sums of rounded amounts.
+func (d Decimal) Round(places int) Decimal {
+	if places < 0 {
+		places = 0
+	}
+	scale := new(big.Int).Exp(ten, big.NewInt(int64(places)), nil)
+	r := d.rat()
+	units := divRound(new(big.Int).Mul(r.Num(), scale), r.Denom())
+	return fromRat(new(big.Rat).SetFrac(units, scale))
+}
+
+// divRound returns n / m rounded half to even, for a positive m
+func divRound(n, m *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(n, m, new(big.Int))
+	if r.Sign() == 0 {
+		return q
+	}
+	// Compare twice the remainder to the divisor
+	twice := new(big.Int).Mul(new(big.Int).Abs(r), two)
+	switch cmp := twice.Cmp(m); {
+	case cmp > 0, cmp == 0 && q.Bit(0) == 1:
+		// Away from zero, toward the exact quotient
+		if n.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q
+}
+
+// Cmp compares d and x, returning -1, 0 or +1
+func (d Decimal) Cmp(x Decimal) int {
+	return d.rat().Cmp(x.rat())
+}
+
+// Equal reports whether d and x are the same amount
+func (d Decimal) Equal(x Decimal) bool {
+	return d.Cmp(x) == 0
+}
+
+// Sign returns -1, 0 or +1 depending on the sign of d
+func (d Decimal) Sign() int {
+	return d.rat().Sign()
+}
+
+// IsZero reports whether d is zero
+func (d Decimal) IsZero() bool {
+	return d.Sign() == 0
+}
+
+// Float64 returns the float nearest to d
+func (d Decimal) Float64() float64 {
+	f, _ := d.rat().Float64()
+	return f
+}
+
+// String formats d as a decimal without trailing zeros, such as "-12.5".
+// Decimals that do not terminate are rounded to MaxPlaces places.
+func (d Decimal) String() string {
+	places, ok := terminatingPlaces(d.rat().Denom())
+	if !ok || places > MaxPlaces {
+		d, places = d.Round(MaxPlaces), MaxPlaces
+	}
+	text := d.rat().FloatString(places)
+	if strings.Contains(text, ".") {
+		text = strings.TrimRight(strings.TrimRight(text, "0"), ".")
+	}
+	return text
+}
+
+// terminatingPlaces returns the number of decimal places of a fraction with
+// the given denominator, and whether it terminates at all
+func terminatingPlaces(denom *big.Int) (int, bool) {
+	rest := new(big.Int).Set(denom)
+	count := func(factor int64) int {
+		f, n := big.NewInt(factor), 0
+		for {
+			q, r := new(big.Int).QuoRem(rest, f, new(big.Int))
+			if r.Sign() != 0 {
+				return n
+			}
+			rest, n = q, n+1
+		}
+	}
+	twos, fives := count(2), count(5)
+	if rest.Cmp(big.NewInt(1)) != 0 {
+		return 0, false
+	}
+	return max(twos, fives), true
+}
+
+// MarshalJSON encodes d as a JSON number
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalJSON decodes a JSON number or a string holding one. Like the
+// standard decoder for numbers, it leaves d unchanged on null.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	text := string(data)
+	if text == "null" {
+		return nil
+	}
+	if strings.HasPrefix(text, `"`) {
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+	}
+	parsed, err := Parse(text)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// Add returns the float sum of a and b computed as decimals
+func Add(a, b float64) float64 {
+	return FromFloat(a).Add(FromFloat(b)).Float64()
+}
+
+// Sub returns the float difference of a and b computed as decimals
+func Sub(a, b float64) float64 {
+	return FromFloat(a).Sub(FromFloat(b)).Float64()
+}
+
+// Mul returns the float product of a and b computed as decimals
+func Mul(a, b float64) float64 {
+	return FromFloat(a).Mul(FromFloat(b)).Float64()
+}
+
+// Sum returns the float sum of values computed as decimals
+func Sum(values ...float64) float64 {
+	var sum Decimal
+	for _, v := range values {
+		sum = sum.Add(FromFloat(v))
+	}
+	return sum.Float64()
+}
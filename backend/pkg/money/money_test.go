@@ -0,0 +1,233 @@
+package money
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// amount is a random decimal of up to 15 significant digits and up to 12
+// places, like a price, size or PnL
+type amount struct {
+	Decimal
+}
+
+func (amount) Generate(r *rand.Rand, size int) reflect.Value {
+	digits := r.Int63n(1_000_000_000_000_000) - 500_000_000_000_000
+	digits /= int64(math.Pow10(r.Intn(12)))
+	places := int64(r.Intn(13))
+	d := fromRat(new(big.Rat).SetFrac(big.NewInt(digits), new(big.Int).Exp(ten, big.NewInt(places), nil)))
+	return reflect.ValueOf(amount{d})
+}
+
+var quickConfig = &quick.Config{MaxCount: 2000}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"0", "0"},
+		{"-0.0", "0"},
+		{"12.50", "12.5"},
+		{"+3", "3"},
+		{"-0.00000001", "-0.00000001"},
+		{"0.0000087123", "0.0000087123"},
+		{"1e-7", "0.0000001"},
+		{"123456789012345678.123456789", "123456789012345678.123456789"},
+	}
+	for _, tt := range tests {
+		d, err := Parse(tt.text)
+		require.NoError(t, err, tt.text)
+		assert.Equal(t, tt.want, d.String(), tt.text)
+	}
+
+	for _, text := range []string{"", "-", "1.2.3", "abc", "1/3", "1_000", "NaN", "Inf"} {
+		_, err := Parse(text)
+		assert.ErrorIs(t, err, ErrInvalidDecimal, text)
+	}
+}
+
+func TestFloatConversion(t *testing.T) {
+	assert.Equal(t, "0.1", FromFloat(0.1).String())
+	assert.Equal(t, "50000.123456789", FromFloat(50000.123456789).String())
+	assert.Equal(t, "0.0000087123", FromFloat(0.0000087123).String())
+	assert.Equal(t, "-1234.5", FromFloat(-1234.5).String())
+	assert.True(t, FromFloat(math.NaN()).IsZero())
+	assert.True(t, FromFloat(math.Inf(-1)).IsZero())
+
+	properties := map[string]interface{}{
+		// Decimals of up to 15 significant digits survive being stored as
+		// floats, which is what keeps the models from drifting
+		"decimals survive a float round trip": func(a amount) bool {
+			return FromFloat(a.Float64()).Equal(a.Decimal)
+		},
+		"floats survive a decimal round trip": func(f float64) bool {
+			return FromFloat(f).Float64() == f
+		},
+		"conversion picks the nearest float": func(a amount) bool {
+			f := a.Float64()
+			distance := func(g float64) *big.Rat {
+				diff := new(big.Rat).Sub(new(big.Rat).SetFloat64(g), a.rat())
+				return diff.Abs(diff)
+			}
+			return distance(f).Cmp(distance(math.Nextafter(f, math.Inf(1)))) <= 0 &&
+				distance(f).Cmp(distance(math.Nextafter(f, math.Inf(-1)))) <= 0
+		},
+	}
+	for name, property := range properties {
+		assert.NoError(t, quick.Check(property, quickConfig), name)
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	a, b := MustParse("0.1"), MustParse("0.2")
+	assert.Equal(t, "0.3", a.Add(b).String())
+	assert.Equal(t, "-0.1", a.Sub(b).String())
+	assert.Equal(t, "0.02", a.Mul(b).String())
+	assert.Equal(t, "0.5", quo(a, b).String())
+	assert.Equal(t, "0.1", a.Neg().Abs().String())
+	assert.Equal(t, 1, b.Cmp(a))
+	assert.Equal(t, -1, a.Neg().Sign())
+	_, err := a.Div(Zero)
+	assert.ErrorIs(t, err, ErrDivisionByZero)
+
+	// Quotients are exact until rounded
+	third := quo(NewFromInt(1), NewFromInt(3))
+	assert.True(t, third.Mul(NewFromInt(3)).Equal(NewFromInt(1)))
+	assert.Equal(t, "0.333333333333333333", third.String())
+	assert.Equal(t, "-0.666666666666666667", quo(NewFromInt(-2), NewFromInt(3)).String())
+
+	assert.Equal(t, 0.3, Add(0.1, 0.2))
+	assert.Equal(t, 0.1, Sub(0.3, 0.2))
+	assert.Equal(t, 0.07, Mul(0.1, 0.7))
+	assert.Equal(t, 1.0, Sum(0.1, 0.2, 0.3, 0.4))
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		value  string
+		places int
+		want   string
+	}{
+		{"1.005", 2, "1"},
+		{"1.015", 2, "1.02"},
+		{"1.025", 2, "1.02"},
+		{"1.0250001", 2, "1.03"},
+		{"-1.035", 2, "-1.04"},
+		{"-1.045", 2, "-1.04"},
+		{"2.5", 0, "2"},
+		{"3.5", 0, "4"},
+		{"12.344", 2, "12.34"},
+		{"7", 3, "7"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, MustParse(tt.value).Round(tt.places).String(), "%s to %d places", tt.value, tt.places)
+	}
+
+	properties := map[string]interface{}{
+		"rounding is within half a unit": func(a amount, p uint8) bool {
+			places := int(p % 10)
+			diff := a.Round(places).Sub(a.Decimal).Abs()
+			half := quo(NewFromInt(1), NewFromInt(int64(2*math.Pow10(places))))
+			return diff.Cmp(half) <= 0
+		},
+		"rounding is symmetric": func(a amount, p uint8) bool {
+			places := int(p % 10)
+			return a.Neg().Round(places).Equal(a.Round(places).Neg())
+		},
+		"rounding twice changes nothing": func(a amount, p uint8) bool {
+			places := int(p % 10)
+			return a.Round(places).Round(places).Equal(a.Round(places))
+		},
+		"ties go to the even digit": func(n int32, p uint8) bool {
+			places := int(p % 6)
+			unit := quo(NewFromInt(1), NewFromInt(int64(math.Pow10(places))))
+			// n + 0.5 units, exactly halfway between n and n + 1 units
+			tie := NewFromInt(int64(n)).Mul(unit).Add(quo(unit, NewFromInt(2)))
+			want := int64(n)
+			if n%2 != 0 {
+				want++
+			}
+			return tie.Round(places).Equal(NewFromInt(want).Mul(unit))
+		},
+	}
+	for name, property := range properties {
+		assert.NoError(t, quick.Check(property, quickConfig), name)
+	}
+}
+
+func TestArithmeticProperties(t *testing.T) {
+	properties := map[string]interface{}{
+		"addition is associative": func(a, b, c amount) bool {
+			return a.Add(b.Decimal).Add(c.Decimal).Equal(a.Add(b.Add(c.Decimal)))
+		},
+		"subtraction undoes addition": func(a, b amount) bool {
+			return a.Add(b.Decimal).Sub(b.Decimal).Equal(a.Decimal)
+		},
+		"division undoes multiplication": func(a, b amount) bool {
+			return b.IsZero() || quo(a.Mul(b.Decimal), b.Decimal).Equal(a.Decimal)
+		},
+		"strings parse back": func(a amount) bool {
+			parsed, err := Parse(a.String())
+			return err == nil && parsed.Equal(a.Decimal)
+		},
+	}
+	for name, property := range properties {
+		assert.NoError(t, quick.Check(property, quickConfig), name)
+	}
+}
+
+func TestNoDrift(t *testing.T) {
+	// Ten thousand fills of 0.1 at 0.7 drift in floats but not in decimals
+	var floatSize, floatNotional float64
+	var size, notional float64
+	for i := 0; i < 10000; i++ {
+		floatSize += 0.1
+		floatNotional += 0.1 * 0.7
+		size = Add(size, 0.1)
+		notional = Add(notional, Mul(0.1, 0.7))
+	}
+	assert.NotEqual(t, 1000.0, floatSize)
+	assert.NotEqual(t, 700.0, floatNotional)
+	assert.Equal(t, 1000.0, size)
+	assert.Equal(t, 700.0, notional)
+}
+
+func TestJSON(t *testing.T) {
+	var v struct {
+		Price Decimal `json:"price"`
+		Size  Decimal `json:"size"`
+		Fee   Decimal `json:"fee"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"price": 50000.5, "size": "0.00000001", "fee": 1e-7}`), &v))
+	assert.Equal(t, "50000.5", v.Price.String())
+	assert.Equal(t, "0.00000001", v.Size.String())
+	assert.Equal(t, "0.0000001", v.Fee.String())
+
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"price": 50000.5, "size": 0.00000001, "fee": 0.0000001}`, string(data))
+
+	assert.ErrorIs(t, json.Unmarshal([]byte(`{"price": "cheap"}`), &v), ErrInvalidDecimal)
+
+	// Null leaves the amount unchanged, like it does a float
+	require.NoError(t, json.Unmarshal([]byte(`{"price": null}`), &v))
+	assert.Equal(t, "50000.5", v.Price.String())
+}
+
+// quo returns a / b for a b that is not zero
+func quo(a, b Decimal) Decimal {
+	q, err := a.Div(b)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
@@ -0,0 +1,111 @@
+// Package tracing exports OpenTelemetry spans of the order execution path,
+// from signal intake through risk checks and order placement to the exchange
+// HTTP calls and order persistence, to an OTLP endpoint.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/devinjacknz/godydxhyber/backend/logger"
+)
+
+// instrumentation names the tracer of the engine
+const instrumentation = "github.com/devinjacknz/godydxhyber/backend"
+
+// Config selects the OTLP endpoint spans are exported to
+type Config struct {
+	// Endpoint is the OTLP/HTTP collector URL, such as
+	// http://localhost:4318. Tracing is disabled when empty.
+	Endpoint string
+	// ServiceName is the service.name resource attribute
+	ServiceName string
+	// SampleRatio is the fraction of traces sampled, from 0 to 1. Child
+	// spans follow the decision of their parent.
+	SampleRatio float64
+}
+
+// DefaultConfig returns the default configuration, with tracing disabled
+func DefaultConfig() Config {
+	return Config{
+		ServiceName: "gosol",
+		SampleRatio: 1,
+	}
+}
+
+// Setup installs the global tracer provider and the W3C trace context
+// propagator. It returns a function flushing and stopping the exporter,
+// which does nothing when tracing is disabled.
+func Setup(ctx context.Context, config Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if config.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	defaults := DefaultConfig()
+	if config.ServiceName == "" {
+		config.ServiceName = defaults.ServiceName
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(config.Endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(config.ServiceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start starts a span named name. The span carries the correlation ID of ctx,
+// so traces can be found from the logs.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return start(ctx, name, trace.SpanKindInternal, attrs)
+}
+
+// StartClient starts a span of a call to a remote service
+func StartClient(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return start(ctx, name, trace.SpanKindClient, attrs)
+}
+
+// StartServer starts a span of a request served by the engine
+func StartServer(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return start(ctx, name, trace.SpanKindServer, attrs)
+}
+
+func start(ctx context.Context, name string, kind trace.SpanKind, attrs []attribute.KeyValue) (context.Context, trace.Span) {
+	if id := logger.CorrelationID(ctx); id != "" {
+		attrs = append(attrs, attribute.String(logger.CorrelationKey, id))
+	}
+	return otel.Tracer(instrumentation).Start(ctx, name, trace.WithAttributes(attrs...), trace.WithSpanKind(kind))
+}
+
+// End records err, if any, on the span and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
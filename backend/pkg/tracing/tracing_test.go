@@ -0,0 +1,59 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/devinjacknz/godydxhyber/backend/logger"
+	"github.com/devinjacknz/godydxhyber/backend/pkg/httpx"
+	"github.com/devinjacknz/godydxhyber/backend/pkg/tracing"
+)
+
+func TestSpans(t *testing.T) {
+	stop, err := tracing.Setup(context.Background(), tracing.DefaultConfig())
+	require.NoError(t, err)
+	defer stop(context.Background())
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(previous)
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer server.Close()
+
+	ctx := logger.WithCorrelationID(context.Background(), "abc123")
+	ctx, span := tracing.Start(ctx, "order.CreateOrder")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/v3/orders", nil)
+	require.NoError(t, err)
+	resp, err := httpx.New(httpx.Config{Name: "test", Limits: httpx.NewLimits()}).Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	tracing.End(span, errors.New("rejected"))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	client, parent := spans[0], spans[1]
+	assert.Equal(t, "GET /v3/orders", client.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), client.Parent().SpanID())
+	assert.Contains(t, traceparent, client.SpanContext().TraceID().String(), "the trace context is sent to the server")
+	assert.Contains(t, client.Attributes(), attribute.Int("http.response.status_code", http.StatusOK))
+
+	assert.Equal(t, codes.Error, parent.Status().Code)
+	assert.Contains(t, parent.Attributes(), attribute.String(logger.CorrelationKey, "abc123"))
+}
@@ -1,66 +1,609 @@
 package websocket
 
 import (
-    "encoding/json"
-    "github.com/gin-gonic/gin"
-    "github.com/gorilla/websocket"
-    "github.com/devinjacknz/godydxhyber/backend/pkg/monitoring"
-    "net/http"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/pkg/monitoring"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// Push topics
+const (
+	TopicPositions  = "positions"
+	TopicOrders     = "orders"
+	TopicTrades     = "trades"
+	TopicRiskAlerts = "risk_alerts"
+
+	marketTopicPrefix = "market:"
 )
 
-var upgrader = websocket.Upgrader{
-    CheckOrigin: func(r *http.Request) bool {
-        return true
-    },
-    ReadBufferSize:  1024,
-    WriteBufferSize: 1024,
-    Subprotocols:    []string{"13"},
-}
-
-func HandleWebSocket(c *gin.Context) {
-    conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
-    if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-        return
-    }
-    defer conn.Close()
-
-    monitoring.IncrementWSConnections()
-    defer monitoring.DecrementWSConnections()
-
-    for {
-        _, message, err := conn.ReadMessage()
-        if err != nil {
-            break
-        }
-
-        var msg struct {
-            Type    string      `json:"type"`
-            Channel string      `json:"channel,omitempty"`
-            Data    interface{} `json:"data,omitempty"`
-        }
-
-        if err := json.Unmarshal(message, &msg); err != nil {
-            continue
-        }
-
-        switch msg.Type {
-        case "subscribe":
-            monitoring.RecordMessage("subscribe", msg.Channel)
-            response := map[string]string{
-                "type":    "subscribed",
-                "channel": msg.Channel,
-            }
-            if data, err := json.Marshal(response); err == nil {
-                conn.WriteMessage(websocket.TextMessage, data)
-            }
-        case "ping":
-            response := map[string]string{"type": "pong"}
-            if data, err := json.Marshal(response); err == nil {
-                conn.WriteMessage(websocket.TextMessage, data)
-            }
-        default:
-            conn.WriteMessage(websocket.TextMessage, message)
-        }
-    }
+// Message types
+const (
+	TypeUpdate       = "update"
+	TypeSnapshot     = "snapshot"
+	TypeSubscribed   = "subscribed"
+	TypeUnsubscribed = "unsubscribed"
+	TypePong         = "pong"
+	TypeError        = "error"
+)
+
+// MarketTopic returns the market data topic of a token
+func MarketTopic(symbol string) string {
+	return marketTopicPrefix + symbol
+}
+
+// TopicConfig contains the buffering of the updates of a topic
+type TopicConfig struct {
+	// Buffer is the number of updates of the topic queued per client, at
+	// most the hub SendBuffer, which is also the default
+	Buffer int
+	// Conflate drops the oldest queued update of the topic when a client
+	// falls Buffer updates behind, for topics where only the latest state
+	// matters such as market data. Clients falling behind on other topics
+	// are disconnected. Clients detect dropped updates by the gap in Seq.
+	Conflate bool
+	// Prefix registers every topic starting with the name and followed by
+	// at least one character, such as the market topics of all tokens
+	Prefix bool
+}
+
+// Message is the envelope of every message sent to clients
+type Message struct {
+	Type  string `json:"type"`
+	Topic string `json:"topic,omitempty"`
+	// Seq increases with every published update, so clients can detect
+	// updates they missed
+	Seq       uint64      `json:"seq,omitempty"`
+	Data      interface{} `json:"data,omitempty"`
+	Error     string      `json:"error,omitempty"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// request is a message sent by a client
+type request struct {
+	Type  string `json:"type"`
+	Topic string `json:"topic"`
+	// Channel is the topic field of the original protocol
+	Channel string `json:"channel,omitempty"`
+}
+
+// SnapshotFunc returns the current state of a topic, sent to clients when
+// they subscribe
+type SnapshotFunc func() (interface{}, error)
+
+// Scope reports whether a client may receive the data of an account
+type Scope func(account string) bool
+
+// ScopedSnapshotFunc returns the current state of a topic limited to the
+// accounts in the scope of the subscribing client
+type ScopedSnapshotFunc func(scope Scope) (interface{}, error)
+
+// allAccounts is the scope of clients without restrictions
+func allAccounts(string) bool { return true }
+
+// TopicFilter reports whether a client may subscribe to a topic
+type TopicFilter func(topic string) bool
+
+// allTopics is the topic filter of clients without restrictions
+func allTopics(string) bool { return true }
+
+// HubConfig contains push channel configuration
+type HubConfig struct {
+	// SendBuffer is the number of messages queued per client. Clients that
+	// fall this far behind are disconnected.
+	SendBuffer int
+	// PingInterval is how often clients are pinged
+	PingInterval time.Duration
+	// PongWait is how long a client may go without answering a ping
+	PongWait time.Duration
+	// WriteWait is the write deadline of each message
+	WriteWait time.Duration
+	// MaxLag is how long a message may wait in the send buffer of a client
+	// before the client is considered stalled and disconnected
+	MaxLag time.Duration
+	// BatchInterval is how long the updates of a client are collected
+	// before they are written, so that bursts are written at once and
+	// conflated topics skip intermediate states. Zero writes as soon as the
+	// client is ready, batching whatever queued while it was busy.
+	BatchInterval time.Duration
+	// AllowedOrigins are the origins, such as "https://app.example.com",
+	// browsers may open connections from. "*" allows any origin; empty only
+	// allows the origin of the server itself.
+	AllowedOrigins []string
+}
+
+// DefaultHubConfig returns default push channel configuration
+func DefaultHubConfig() HubConfig {
+	return HubConfig{
+		SendBuffer:   256,
+		PingInterval: 30 * time.Second,
+		PongWait:     60 * time.Second,
+		WriteWait:    10 * time.Second,
+		MaxLag:       5 * time.Second,
+	}
+}
+
+// Hub pushes live updates to subscribed WebSocket clients. Every client has
+// its own send buffer and writer, so a stalled client never holds up the
+// others.
+type Hub struct {
+	config    HubConfig
+	upgrader  websocket.Upgrader
+	clients   map[*client]struct{}
+	topics    map[string]TopicConfig
+	prefixes  map[string]TopicConfig
+	snapshots map[string]ScopedSnapshotFunc
+	// scope returns the accounts a connecting client may see
+	scope func(c *gin.Context) Scope
+	// topicFilter returns the topics a connecting client may subscribe to
+	topicFilter func(c *gin.Context) TopicFilter
+	seq         atomic.Uint64
+	closed      bool
+	mu          sync.RWMutex
+}
+
+// NewHub creates a push channel hub
+func NewHub(config HubConfig) *Hub {
+	defaults := DefaultHubConfig()
+	if config.SendBuffer <= 0 {
+		config.SendBuffer = defaults.SendBuffer
+	}
+	if config.PingInterval <= 0 {
+		config.PingInterval = defaults.PingInterval
+	}
+	if config.PongWait <= config.PingInterval {
+		config.PongWait = 2 * config.PingInterval
+	}
+	if config.WriteWait <= 0 {
+		config.WriteWait = defaults.WriteWait
+	}
+	if config.MaxLag <= 0 {
+		config.MaxLag = defaults.MaxLag
+	}
+
+	h := &Hub{
+		config:    config,
+		clients:   make(map[*client]struct{}),
+		topics:    make(map[string]TopicConfig),
+		prefixes:  make(map[string]TopicConfig),
+		snapshots: make(map[string]ScopedSnapshotFunc),
+	}
+	h.upgrader = websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		Subprotocols:    []string{"13"},
+	}
+	if len(config.AllowedOrigins) > 0 {
+		h.upgrader.CheckOrigin = h.checkOrigin
+	}
+	for _, topic := range []string{TopicPositions, TopicOrders, TopicTrades, TopicRiskAlerts} {
+		h.RegisterTopic(topic, TopicConfig{})
+	}
+	h.RegisterTopic(marketTopicPrefix, TopicConfig{Buffer: 16, Conflate: true, Prefix: true})
+	return h
+}
+
+// RegisterTopic lets clients subscribe to a topic, replacing its buffering
+// when it is already registered
+func (h *Hub) RegisterTopic(name string, config TopicConfig) {
+	if config.Buffer <= 0 || config.Buffer > h.config.SendBuffer {
+		config.Buffer = h.config.SendBuffer
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if config.Prefix {
+		h.prefixes[name] = config
+		return
+	}
+	h.topics[name] = config
+}
+
+// topic returns the configuration of a registered topic
+func (h *Hub) topic(name string) (TopicConfig, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.topicLocked(name)
+}
+
+func (h *Hub) topicLocked(name string) (TopicConfig, bool) {
+	if config, ok := h.topics[name]; ok {
+		return config, true
+	}
+	for prefix, config := range h.prefixes {
+		if strings.HasPrefix(name, prefix) && len(name) > len(prefix) {
+			return config, true
+		}
+	}
+	return TopicConfig{}, false
+}
+
+// checkOrigin allows the configured origins. Requests without an Origin
+// header do not come from a browser and are allowed.
+func (h *Hub) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range h.config.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetSnapshot sets the function returning the current state of a topic
+func (h *Hub) SetSnapshot(topic string, fn SnapshotFunc) {
+	h.SetScopedSnapshot(topic, func(Scope) (interface{}, error) {
+		return fn()
+	})
+}
+
+// SetScopedSnapshot sets the function returning the current state of a
+// topic holding the data of several accounts
+func (h *Hub) SetScopedSnapshot(topic string, fn ScopedSnapshotFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.snapshots[topic] = fn
+}
+
+// SetScope sets the function returning the accounts a connecting client
+// may see, from its upgrade request. Clients see every account by default.
+func (h *Hub) SetScope(fn func(c *gin.Context) Scope) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.scope = fn
+}
+
+// SetTopicFilter sets the function returning the topics a connecting client
+// may subscribe to, from its upgrade request. Clients may subscribe to every
+// topic by default.
+func (h *Hub) SetTopicFilter(fn func(c *gin.Context) TopicFilter) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.topicFilter = fn
+}
+
+// Publish sends an update to the subscribers of a topic. It never blocks:
+// clients whose send buffer is full drop their oldest update of a conflated
+// topic, and are disconnected otherwise or when they stall, so they
+// reconnect and resynchronize instead of silently missing updates.
+func (h *Hub) Publish(topic string, data interface{}) {
+	h.publish(topic, "", data)
+}
+
+// PublishAccount sends an update holding the data of an account to the
+// subscribers of a topic whose scope includes the account
+func (h *Hub) PublishAccount(topic, account string, data interface{}) {
+	h.publish(topic, account, data)
+}
+
+// publish sends an update to the subscribers of a topic, only those allowed
+// to see the account when it is set
+func (h *Hub) publish(topic, account string, data interface{}) {
+	payload, err := json.Marshal(Message{
+		Type:      TypeUpdate,
+		Topic:     topic,
+		Seq:       h.seq.Add(1),
+		Data:      data,
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		monitoring.RecordMessage("marshal_error", topic)
+		return
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	config, ok := h.topicLocked(topic)
+	if !ok {
+		config = TopicConfig{Buffer: h.config.SendBuffer}
+	}
+	for c := range h.clients {
+		if !c.subscribed(topic) || (account != "" && !c.scope(account)) {
+			continue
+		}
+		switch c.enqueue(topic, config, payload) {
+		case enqueueSlow:
+			monitoring.RecordMessage("slow_client", topic)
+			c.close()
+			continue
+		case enqueueConflated:
+			monitoring.RecordMessage("conflated", topic)
+		}
+		monitoring.RecordMessage(TypeUpdate, topic)
+	}
+}
+
+// ClientCount returns the number of connected clients
+func (h *Hub) ClientCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients)
+}
+
+// Close disconnects all clients and rejects new connections
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for c := range h.clients {
+		c.close()
+	}
+}
+
+// HandleWebSocket upgrades a request to a push channel connection
+func (h *Hub) HandleWebSocket(c *gin.Context) {
+	scope, allowed := Scope(allAccounts), TopicFilter(allTopics)
+	h.mu.RLock()
+	if h.scope != nil {
+		scope = h.scope(c)
+	}
+	if h.topicFilter != nil {
+		allowed = h.topicFilter(c)
+	}
+	h.mu.RUnlock()
+
+	// The upgrader answers failed upgrades, such as from a foreign origin
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+
+	cl := &client{
+		hub:     h,
+		conn:    conn,
+		scope:   scope,
+		allowed: allowed,
+		pending: make(map[string]int),
+		ready:   make(chan struct{}, 1),
+		topics:  make(map[string]bool),
+		done:    make(chan struct{}),
+	}
+	if !h.register(cl) {
+		conn.Close()
+		return
+	}
+	defer h.unregister(cl)
+
+	monitoring.IncrementWSConnections()
+	defer monitoring.DecrementWSConnections()
+
+	go cl.writePump()
+	cl.readPump()
+}
+
+func (h *Hub) register(c *client) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return false
+	}
+	h.clients[c] = struct{}{}
+	return true
+}
+
+func (h *Hub) unregister(c *client) {
+	h.mu.Lock()
+	delete(h.clients, c)
+	h.mu.Unlock()
+	c.close()
+}
+
+func (h *Hub) snapshot(topic string) (ScopedSnapshotFunc, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	fn, ok := h.snapshots[topic]
+	return fn, ok
+}
+
+// queued is a message in the send buffer of a client
+type queued struct {
+	topic    string
+	payload  []byte
+	queuedAt time.Time
+}
+
+// Outcomes of queueing a message
+const (
+	enqueueOK = iota
+	enqueueConflated
+	enqueueSlow
+)
+
+// client is one push channel connection. Messages wait in its send buffer
+// until the write pump, woken through ready, takes them all at once; the
+// write pump stops when done is closed.
+type client struct {
+	hub  *Hub
+	conn *websocket.Conn
+	// scope limits the account data the client receives
+	scope Scope
+	// allowed limits the topics the client may subscribe to
+	allowed TopicFilter
+	topics  map[string]bool
+	// queue is the send buffer, oldest first, and pending counts its
+	// messages by topic
+	queue     []queued
+	pending   map[string]int
+	ready     chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	mu        sync.Mutex
+}
+
+func (c *client) subscribed(topic string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.topics[topic]
+}
+
+// enqueue queues a message of a topic without blocking. A full buffer
+// conflates the topic if it allows it; otherwise, or when the oldest queued
+// message is older than MaxLag, the client is slow.
+func (c *client) enqueue(topic string, config TopicConfig, payload []byte) int {
+	select {
+	case <-c.done:
+		return enqueueOK
+	default:
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.queue) > 0 && now.Sub(c.queue[0].queuedAt) > c.hub.config.MaxLag {
+		return enqueueSlow
+	}
+	result := enqueueOK
+	if c.pending[topic] >= config.Buffer || len(c.queue) >= c.hub.config.SendBuffer {
+		if !config.Conflate || c.pending[topic] == 0 {
+			return enqueueSlow
+		}
+		for i := range c.queue {
+			if c.queue[i].topic == topic {
+				c.queue = append(c.queue[:i], c.queue[i+1:]...)
+				c.pending[topic]--
+				break
+			}
+		}
+		result = enqueueConflated
+	}
+	c.queue = append(c.queue, queued{topic: topic, payload: payload, queuedAt: now})
+	c.pending[topic]++
+
+	select {
+	case c.ready <- struct{}{}:
+	default:
+	}
+	return result
+}
+
+// take removes every queued message
+func (c *client) take() []queued {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	batch := c.queue
+	c.queue = nil
+	clear(c.pending)
+	return batch
+}
+
+func (c *client) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+func (c *client) reply(msg Message) {
+	msg.Timestamp = time.Now()
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	if c.enqueue("", TopicConfig{Buffer: c.hub.config.SendBuffer}, payload) == enqueueSlow {
+		c.close()
+	}
+}
+
+func (c *client) readPump() {
+	c.conn.SetReadDeadline(time.Now().Add(c.hub.config.PongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(c.hub.config.PongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var req request
+		if err := json.Unmarshal(data, &req); err != nil {
+			c.reply(Message{Type: TypeError, Error: "invalid message"})
+			continue
+		}
+		if req.Topic == "" {
+			req.Topic = req.Channel
+		}
+		c.handle(req)
+	}
+}
+
+func (c *client) handle(req request) {
+	switch req.Type {
+	case "subscribe":
+		if _, ok := c.hub.topic(req.Topic); !ok {
+			c.reply(Message{Type: TypeError, Topic: req.Topic, Error: "unknown topic"})
+			return
+		}
+		if !c.allowed(req.Topic) {
+			c.reply(Message{Type: TypeError, Topic: req.Topic, Error: "topic not allowed"})
+			return
+		}
+		c.mu.Lock()
+		c.topics[req.Topic] = true
+		c.mu.Unlock()
+		monitoring.RecordMessage("subscribe", req.Topic)
+		c.reply(Message{Type: TypeSubscribed, Topic: req.Topic})
+
+		if fn, ok := c.hub.snapshot(req.Topic); ok {
+			data, err := fn(c.scope)
+			if err != nil {
+				c.reply(Message{Type: TypeError, Topic: req.Topic, Error: err.Error()})
+				return
+			}
+			c.reply(Message{Type: TypeSnapshot, Topic: req.Topic, Seq: c.hub.seq.Load(), Data: data})
+		}
+	case "unsubscribe":
+		c.mu.Lock()
+		delete(c.topics, req.Topic)
+		c.mu.Unlock()
+		c.reply(Message{Type: TypeUnsubscribed, Topic: req.Topic})
+	case "ping":
+		c.reply(Message{Type: TypePong})
+	default:
+		c.reply(Message{Type: TypeError, Error: "unknown message type: " + req.Type})
+	}
+}
+
+func (c *client) writePump() {
+	ticker := time.NewTicker(c.hub.config.PingInterval)
+	defer ticker.Stop()
+	defer c.close()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-c.ready:
+			if c.hub.config.BatchInterval > 0 {
+				select {
+				case <-c.done:
+					return
+				case <-time.After(c.hub.config.BatchInterval):
+				}
+			}
+			for _, msg := range c.take() {
+				c.conn.SetWriteDeadline(time.Now().Add(c.hub.config.WriteWait))
+				if err := c.conn.WriteMessage(websocket.TextMessage, msg.payload); err != nil {
+					return
+				}
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(c.hub.config.WriteWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
 }
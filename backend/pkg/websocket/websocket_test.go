@@ -0,0 +1,230 @@
+package websocket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHub(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newServer := func(config HubConfig) (*Hub, *httptest.Server) {
+		hub := NewHub(config)
+		r := gin.New()
+		r.GET("/ws", hub.HandleWebSocket)
+		srv := httptest.NewServer(r)
+		t.Cleanup(srv.Close)
+		return hub, srv
+	}
+
+	dial := func(srv *httptest.Server) *websocket.Conn {
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", nil)
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+
+	read := func(conn *websocket.Conn) Message {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var msg Message
+		require.NoError(t, conn.ReadJSON(&msg))
+		return msg
+	}
+
+	waitForClients := func(hub *Hub, n int) {
+		require.Eventually(t, func() bool { return hub.ClientCount() == n }, 2*time.Second, 10*time.Millisecond)
+	}
+
+	t.Run("Subscribers receive updates for their topics", func(t *testing.T) {
+		hub, srv := newServer(DefaultHubConfig())
+		hub.SetSnapshot(TopicPositions, func() (interface{}, error) {
+			return []string{"pos-1"}, nil
+		})
+		conn := dial(srv)
+
+		require.NoError(t, conn.WriteJSON(request{Type: "subscribe", Topic: TopicPositions}))
+		assert.Equal(t, TypeSubscribed, read(conn).Type)
+		snapshot := read(conn)
+		assert.Equal(t, TypeSnapshot, snapshot.Type)
+		assert.Equal(t, []interface{}{"pos-1"}, snapshot.Data)
+
+		require.NoError(t, conn.WriteJSON(request{Type: "subscribe", Channel: MarketTopic("SOL")}))
+		assert.Equal(t, TypeSubscribed, read(conn).Type)
+
+		hub.Publish(TopicOrders, "not subscribed")
+		hub.Publish(TopicPositions, map[string]float64{"pnl": 12.5})
+		hub.Publish(MarketTopic("SOL"), 101.0)
+
+		update := read(conn)
+		assert.Equal(t, TypeUpdate, update.Type)
+		assert.Equal(t, TopicPositions, update.Topic)
+		assert.Equal(t, map[string]interface{}{"pnl": 12.5}, update.Data)
+		market := read(conn)
+		assert.Equal(t, "market:SOL", market.Topic)
+		assert.Greater(t, market.Seq, update.Seq)
+
+		require.NoError(t, conn.WriteJSON(request{Type: "unsubscribe", Topic: TopicPositions}))
+		assert.Equal(t, TypeUnsubscribed, read(conn).Type)
+		require.NoError(t, conn.WriteJSON(request{Type: "ping"}))
+		assert.Equal(t, TypePong, read(conn).Type)
+	})
+
+	t.Run("Invalid requests get error messages", func(t *testing.T) {
+		_, srv := newServer(DefaultHubConfig())
+		conn := dial(srv)
+
+		require.NoError(t, conn.WriteJSON(request{Type: "subscribe", Topic: "market:"}))
+		assert.Equal(t, TypeError, read(conn).Type)
+		require.NoError(t, conn.WriteJSON(request{Type: "subscribe", Topic: "balances"}))
+		assert.Equal(t, "unknown topic", read(conn).Error)
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("{")))
+		assert.Equal(t, "invalid message", read(conn).Error)
+	})
+
+	t.Run("Slow clients are disconnected", func(t *testing.T) {
+		hub, srv := newServer(HubConfig{SendBuffer: 1})
+		conn := dial(srv)
+		require.NoError(t, conn.WriteJSON(request{Type: "subscribe", Topic: TopicTrades}))
+		assert.Equal(t, TypeSubscribed, read(conn).Type)
+
+		// The client never reads, so its buffer fills up
+		for i := 0; i < 1000 && hub.ClientCount() > 0; i++ {
+			hub.Publish(TopicTrades, strings.Repeat("x", 4096))
+		}
+		waitForClients(hub, 0)
+	})
+
+	t.Run("Conflated topics keep the latest updates", func(t *testing.T) {
+		hub, srv := newServer(HubConfig{BatchInterval: 200 * time.Millisecond})
+		conn := dial(srv)
+		require.NoError(t, conn.WriteJSON(request{Type: "subscribe", Topic: MarketTopic("SOL")}))
+		assert.Equal(t, TypeSubscribed, read(conn).Type)
+
+		// Published within one batch, so the client falls behind
+		for i := 0; i < 50; i++ {
+			hub.Publish(MarketTopic("SOL"), i)
+		}
+		first := read(conn)
+		assert.Equal(t, 34.0, first.Data, "the oldest updates are dropped")
+		var last Message
+		for i := 1; i < 16; i++ {
+			last = read(conn)
+		}
+		assert.Equal(t, 49.0, last.Data)
+		assert.Equal(t, first.Seq+15, last.Seq)
+		assert.Equal(t, 1, hub.ClientCount())
+	})
+
+	t.Run("Registered topics can be subscribed", func(t *testing.T) {
+		hub, srv := newServer(DefaultHubConfig())
+		hub.RegisterTopic("funding:", TopicConfig{Conflate: true, Prefix: true})
+		conn := dial(srv)
+
+		require.NoError(t, conn.WriteJSON(request{Type: "subscribe", Topic: "funding:SOL-USD"}))
+		assert.Equal(t, TypeSubscribed, read(conn).Type)
+		hub.Publish("funding:SOL-USD", 0.0001)
+		assert.Equal(t, 0.0001, read(conn).Data)
+	})
+
+	t.Run("Stalled clients are disconnected", func(t *testing.T) {
+		hub, srv := newServer(HubConfig{BatchInterval: 500 * time.Millisecond, MaxLag: 20 * time.Millisecond})
+		conn := dial(srv)
+		require.NoError(t, conn.WriteJSON(request{Type: "subscribe", Topic: TopicOrders}))
+		assert.Equal(t, TypeSubscribed, read(conn).Type)
+
+		// The first update waits for its batch longer than the client may lag
+		hub.Publish(TopicOrders, "first")
+		time.Sleep(50 * time.Millisecond)
+		hub.Publish(TopicOrders, "second")
+		waitForClients(hub, 0)
+	})
+
+	t.Run("Close disconnects clients", func(t *testing.T) {
+		hub, srv := newServer(DefaultHubConfig())
+		dial(srv)
+		waitForClients(hub, 1)
+
+		hub.Close()
+		waitForClients(hub, 0)
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", nil)
+		if err == nil {
+			conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			_, _, err = conn.ReadMessage()
+			conn.Close()
+		}
+		assert.Error(t, err)
+	})
+	t.Run("Origins outside the allowlist are rejected", func(t *testing.T) {
+		_, srv := newServer(HubConfig{AllowedOrigins: []string{"https://app.example.com"}})
+		url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+
+		conn, _, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {"https://app.example.com"}})
+		require.NoError(t, err)
+		conn.Close()
+
+		_, resp, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {"https://evil.example.com"}})
+		assert.Error(t, err)
+		require.NotNil(t, resp)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Account data reaches the clients of its scope", func(t *testing.T) {
+		hub, srv := newServer(DefaultHubConfig())
+		hub.SetScope(func(c *gin.Context) Scope {
+			allowed := c.Query("account")
+			return func(account string) bool { return allowed == "" || account == allowed }
+		})
+		hub.SetScopedSnapshot(TopicPositions, func(scope Scope) (interface{}, error) {
+			var accounts []string
+			for _, account := range []string{"a", "b"} {
+				if scope(account) {
+					accounts = append(accounts, account)
+				}
+			}
+			return accounts, nil
+		})
+
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws?account=b", nil)
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+
+		require.NoError(t, conn.WriteJSON(request{Type: "subscribe", Topic: TopicPositions}))
+		assert.Equal(t, TypeSubscribed, read(conn).Type)
+		assert.Equal(t, []interface{}{"b"}, read(conn).Data)
+
+		hub.PublishAccount(TopicPositions, "a", "position of a")
+		hub.PublishAccount(TopicPositions, "b", "position of b")
+		assert.Equal(t, "position of b", read(conn).Data)
+	})
+
+	t.Run("Clients only subscribe to the topics of their filter", func(t *testing.T) {
+		hub, srv := newServer(DefaultHubConfig())
+		hub.SetTopicFilter(func(c *gin.Context) TopicFilter {
+			return func(topic string) bool { return topic != TopicPositions }
+		})
+
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", nil)
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+
+		require.NoError(t, conn.WriteJSON(request{Type: "subscribe", Topic: TopicPositions}))
+		msg := read(conn)
+		assert.Equal(t, TypeError, msg.Type)
+		assert.Equal(t, "topic not allowed", msg.Error)
+
+		require.NoError(t, conn.WriteJSON(request{Type: "subscribe", Topic: TopicRiskAlerts}))
+		assert.Equal(t, TypeSubscribed, read(conn).Type)
+
+		hub.Publish(TopicPositions, "position")
+		hub.Publish(TopicRiskAlerts, "alert")
+		assert.Equal(t, "alert", read(conn).Data)
+	})
+}
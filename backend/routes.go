@@ -0,0 +1,129 @@
+package main
+
+import (
+    "github.com/gin-gonic/gin"
+    "github.com/devinjacknz/godydxhyber/backend/middleware"
+    "github.com/devinjacknz/godydxhyber/backend/pkg/health"
+    "github.com/devinjacknz/godydxhyber/backend/pkg/websocket"
+    "github.com/devinjacknz/godydxhyber/backend/trading/account"
+    "github.com/devinjacknz/godydxhyber/backend/trading/budget"
+    "github.com/devinjacknz/godydxhyber/backend/trading/dashboard"
+    "github.com/devinjacknz/godydxhyber/backend/trading/export"
+    "github.com/devinjacknz/godydxhyber/backend/trading/journal"
+    "github.com/devinjacknz/godydxhyber/backend/trading/killswitch"
+    "github.com/devinjacknz/godydxhyber/backend/trading/order"
+    "github.com/devinjacknz/godydxhyber/backend/trading/portfolio"
+    "github.com/devinjacknz/godydxhyber/backend/trading/position"
+    "github.com/devinjacknz/godydxhyber/backend/trading/report"
+    "github.com/devinjacknz/godydxhyber/backend/trading/risk"
+    "github.com/devinjacknz/godydxhyber/backend/trading/routing"
+    "github.com/devinjacknz/godydxhyber/backend/trading/sandbox"
+    "github.com/devinjacknz/godydxhyber/backend/trading/snapshot"
+    "github.com/devinjacknz/godydxhyber/backend/trading/strategy"
+    "github.com/devinjacknz/godydxhyber/backend/trading/watchdog"
+)
+
+// apiServices are the components served by the trading control API. The
+// snapshots, watchdog and journal are optional.
+type apiServices struct {
+    accounts   *account.Registry
+    portfolio  *portfolio.Portfolio
+    sandboxes  *sandbox.Manager
+    router     *routing.Router
+    health     *health.Checker
+    strategies *strategy.Registry
+    budgets    *budget.Manager
+    snapshots  *snapshot.Manager
+    watchdog   *watchdog.Watchdog
+    journal    *journal.Journal
+}
+
+// registerAPI registers the trading control API under /api/v1, behind API
+// token authentication when tokens is set. Routes without an account act
+// on the default account, so tokens limited to other accounts get 404.
+func registerAPI(r gin.IRouter, tokens *middleware.TokenStore, s apiServices) error {
+    defaultAccount, err := s.accounts.Get(account.Default)
+    if err != nil {
+        return err
+    }
+
+    api := r.Group("/api/v1")
+    if tokens != nil {
+        api.Use(middleware.AuthMiddleware(tokens, middleware.DefaultAuthConfig()))
+    }
+    defaultRoutes := api.Group("", middleware.RequirePortfolio(account.Default))
+
+    // Sandbox tokens act on a paper copy of the live portfolio
+    killswitch.RegisterRoutesWithResolver(defaultRoutes, s.sandboxes.KillSwitchResolver(defaultAccount.KillSwitch))
+    order.RegisterRoutesWithResolver(defaultRoutes, s.sandboxes.OrderResolver(defaultAccount.Orders))
+    position.RegisterRoutesWithResolver(defaultRoutes, s.sandboxes.PositionResolver(defaultAccount.Positions))
+    s.sandboxes.RegisterRoutes(api)
+    s.router.RegisterRoutes(api)
+
+    // Risk limits, the portfolio, the dashboard, reports, strategies and the
+    // trade journal have no sandbox copy
+    live := api.Group("", middleware.DenySandboxWrites())
+    liveDefault := live.Group("", middleware.RequirePortfolio(account.Default))
+    risk.RegisterRoutes(liveDefault, defaultAccount.Risk)
+    export.RegisterRoutes(liveDefault, defaultAccount.Exporter())
+    report.RegisterRoutes(liveDefault, defaultAccount.Reporter())
+    portfolio.RegisterRoutes(live, s.portfolio)
+    dashboard.RegisterRoutes(liveDefault, dashboard.NewService(s.health, defaultAccount.Positions, defaultAccount.Orders, defaultAccount.Risk, dashboard.DefaultConfig()))
+    s.strategies.RegisterRoutes(live)
+    budget.RegisterRoutes(live, s.budgets)
+    if s.snapshots != nil {
+        s.snapshots.RegisterRoutes(live)
+    }
+    if s.watchdog != nil {
+        watchdog.RegisterRoutes(live, s.watchdog)
+    }
+    if s.journal != nil {
+        journal.RegisterRoutes(live, s.journal)
+    }
+
+    // Every account under /accounts/:account, without a sandbox copy
+    s.accounts.RegisterRoutes(live)
+    accountRoutes := s.accounts.Group(live)
+    order.RegisterRoutesWithResolver(accountRoutes, s.accounts.OrderResolver())
+    position.RegisterRoutesWithResolver(accountRoutes, s.accounts.PositionResolver())
+    risk.RegisterRoutesWithResolver(accountRoutes, s.accounts.RiskResolver())
+    killswitch.RegisterRoutesWithResolver(accountRoutes, s.accounts.KillSwitchResolver())
+    export.RegisterRoutesWithResolver(accountRoutes, s.accounts.ExportResolver())
+    report.RegisterRoutesWithResolver(accountRoutes, s.accounts.ReportResolver())
+    return nil
+}
+
+// observerDeniedTopics are the push topics observer tokens may not subscribe to,
+// carrying live positions, orders and fills
+var observerDeniedTopics = map[string]bool{
+    websocket.TopicPositions: true,
+    websocket.TopicOrders:    true,
+    websocket.TopicTrades:    true,
+}
+
+// registerPushChannel serves the push channel on /ws, behind API token
+// authentication when tokens is set. Browser push clients cannot set
+// headers, so the token may also be passed in the token query parameter,
+// and clients only receive the accounts of their token. Observers cannot
+// subscribe to live positions, orders or fills.
+func registerPushChannel(r gin.IRouter, tokens *middleware.TokenStore, hub *websocket.Hub) {
+    if tokens == nil {
+        r.GET("/ws", hub.HandleWebSocket)
+        return
+    }
+    r.GET("/ws", middleware.AuthMiddleware(tokens, middleware.AuthConfig{ObserverPaths: []string{"/ws"}, QueryParam: "token"}), hub.HandleWebSocket)
+    hub.SetScope(func(c *gin.Context) websocket.Scope {
+        token, ok := middleware.TokenFromContext(c)
+        if !ok {
+            return func(string) bool { return true }
+        }
+        return token.CanView
+    })
+    hub.SetTopicFilter(func(c *gin.Context) websocket.TopicFilter {
+        token, ok := middleware.TokenFromContext(c)
+        if !ok || token.Role != middleware.RoleObserver {
+            return func(string) bool { return true }
+        }
+        return func(topic string) bool { return !observerDeniedTopics[topic] }
+    })
+}
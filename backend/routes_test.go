@@ -0,0 +1,139 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "github.com/gin-gonic/gin"
+    gorilla "github.com/gorilla/websocket"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+
+    "github.com/devinjacknz/godydxhyber/backend/config"
+    "github.com/devinjacknz/godydxhyber/backend/eventbus"
+    "github.com/devinjacknz/godydxhyber/backend/middleware"
+    "github.com/devinjacknz/godydxhyber/backend/pkg/websocket"
+    "github.com/devinjacknz/godydxhyber/backend/trading/killswitch"
+    "github.com/devinjacknz/godydxhyber/backend/trading/portfolio"
+    "github.com/devinjacknz/godydxhyber/backend/trading/routing"
+    "github.com/devinjacknz/godydxhyber/backend/trading/sandbox"
+    "github.com/devinjacknz/godydxhyber/backend/trading/strategy"
+)
+
+func TestRegisterAPI(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+
+    cfg := config.Default()
+    cfg.Accounts = []config.AccountConfig{{ID: "fund-a"}}
+    bus := eventbus.New(eventbus.DefaultConfig())
+    t.Cleanup(bus.Close)
+    strategies := strategy.NewRegistry()
+    budgets := newBudgets(&cfg, strategies)
+    accounts, err := newAccounts(&cfg, websocket.NewHub(websocket.DefaultHubConfig()), bus, budgets, nil)
+    require.NoError(t, err)
+    defaultAccount, err := accounts.Get("default")
+    require.NoError(t, err)
+
+    tokens := middleware.NewTokenStore()
+    require.NoError(t, tokens.LoadTokens("ops:admin:admin-secret,viewer:observer:viewer-secret,fund:observer:fund-secret:fund-a"))
+
+    r := gin.New()
+    require.NoError(t, registerAPI(r, tokens, apiServices{
+        accounts:   accounts,
+        portfolio:  portfolio.New(),
+        sandboxes:  sandbox.NewManager(defaultAccount.Orders, defaultAccount.Positions, killswitch.Config{}),
+        router:     routing.NewRouter(routing.NewCostModel(routing.DefaultCostModelConfig()), routing.VenueDydx),
+        health:     newHealthChecker(&cfg, accounts),
+        strategies: strategies,
+        budgets:    budgets,
+    }))
+
+    get := func(path, token string) *httptest.ResponseRecorder {
+        req := httptest.NewRequest(http.MethodGet, path, nil)
+        req.Header.Set("Authorization", "Bearer "+token)
+        w := httptest.NewRecorder()
+        r.ServeHTTP(w, req)
+        return w
+    }
+
+    t.Run("Observers read the reporting routes", func(t *testing.T) {
+        for _, path := range []string{
+            "/api/v1/reports/daily",
+            "/api/v1/equity",
+            "/api/v1/stats",
+            "/api/v1/accounts/fund-a/reports/daily",
+            "/api/v1/accounts/fund-a/equity",
+            "/api/v1/accounts/fund-a/stats",
+        } {
+            assert.Equal(t, http.StatusOK, get(path, "viewer-secret").Code, path)
+        }
+    })
+
+    t.Run("Observers cannot read live positions and orders", func(t *testing.T) {
+        for _, path := range []string{
+            "/api/v1/dashboard",
+            "/api/v1/portfolio",
+            "/api/v1/accounts",
+            "/api/v1/accounts/fund-a",
+            "/api/v1/accounts/fund-a/positions",
+            "/api/v1/accounts/fund-a/orders",
+            "/api/v1/orders",
+            "/api/v1/trades",
+            "/api/v1/positions",
+            "/api/v1/export/positions?from=2024-01-01T00:00:00Z",
+        } {
+            assert.Equal(t, http.StatusForbidden, get(path, "viewer-secret").Code, path)
+            assert.Equal(t, http.StatusOK, get(path, "admin-secret").Code, path)
+        }
+    })
+
+    t.Run("Observers cannot reach other routes or write", func(t *testing.T) {
+        assert.Equal(t, http.StatusForbidden, get("/api/v1/strategies", "viewer-secret").Code)
+        assert.Equal(t, http.StatusOK, get("/api/v1/strategies", "admin-secret").Code)
+
+        req := httptest.NewRequest(http.MethodPost, "/api/v1/orders", nil)
+        req.Header.Set("Authorization", "Bearer viewer-secret")
+        w := httptest.NewRecorder()
+        r.ServeHTTP(w, req)
+        assert.Equal(t, http.StatusForbidden, w.Code)
+    })
+
+    t.Run("Observers limited to an account only see that account", func(t *testing.T) {
+        assert.Equal(t, http.StatusOK, get("/api/v1/accounts/fund-a/stats", "fund-secret").Code)
+        assert.Equal(t, http.StatusNotFound, get("/api/v1/accounts/default/stats", "fund-secret").Code)
+        assert.Equal(t, http.StatusNotFound, get("/api/v1/stats", "fund-secret").Code, "the default account")
+    })
+}
+
+func TestRegisterPushChannel(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+
+    tokens := middleware.NewTokenStore()
+    require.NoError(t, tokens.LoadTokens("ops:admin:admin-secret,viewer:observer:viewer-secret"))
+    hub := websocket.NewHub(websocket.DefaultHubConfig())
+    t.Cleanup(hub.Close)
+
+    r := gin.New()
+    registerPushChannel(r, tokens, hub)
+    srv := httptest.NewServer(r)
+    t.Cleanup(srv.Close)
+
+    subscribe := func(token, topic string) websocket.Message {
+        conn, _, err := gorilla.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws?token="+token, nil)
+        require.NoError(t, err)
+        defer conn.Close()
+
+        require.NoError(t, conn.WriteJSON(map[string]string{"type": "subscribe", "topic": topic}))
+        var msg websocket.Message
+        require.NoError(t, conn.ReadJSON(&msg))
+        return msg
+    }
+
+    for _, topic := range []string{websocket.TopicPositions, websocket.TopicOrders, websocket.TopicTrades} {
+        assert.Equal(t, websocket.TypeError, subscribe("viewer-secret", topic).Type, topic)
+        assert.Equal(t, websocket.TypeSubscribed, subscribe("admin-secret", topic).Type, topic)
+    }
+    assert.Equal(t, websocket.TypeSubscribed, subscribe("viewer-secret", websocket.TopicRiskAlerts).Type)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+
+    "github.com/devinjacknz/godydxhyber/backend/config"
+    "github.com/devinjacknz/godydxhyber/backend/pkg/money"
+    "github.com/devinjacknz/godydxhyber/backend/trading/account"
+    "github.com/devinjacknz/godydxhyber/backend/trading/position"
+    "github.com/devinjacknz/godydxhyber/backend/trading/strategy"
+)
+
+// newSchedule converts the schedule section of a strategy
+func newSchedule(sc config.ScheduleConfig) *strategy.Schedule {
+    schedule := &strategy.Schedule{
+        Timezone:    sc.Timezone,
+        Outside:     strategy.OutsideAction(sc.Outside),
+        Symbols:     sc.Symbols,
+        ProtectStop: sc.ProtectStop,
+    }
+    for _, w := range sc.Windows {
+        schedule.Windows = append(schedule.Windows, strategy.Window{Days: w.Days, Start: w.Start, End: w.End})
+    }
+    for _, m := range sc.Maintenance {
+        schedule.Maintenance = append(schedule.Maintenance, strategy.MaintenanceWindow{Start: m.Start, End: m.End, Reason: m.Reason})
+    }
+    return schedule
+}
+
+// sessionGuard protects or flattens the positions of the account trading a
+// strategy when the strategy's session closes
+type sessionGuard struct {
+    accounts *account.Registry
+}
+
+// SessionClosed applies the outside action of a schedule to the open
+// positions of the strategy's account in the schedule's symbols
+func (g sessionGuard) SessionClosed(ctx context.Context, name string, schedule strategy.Schedule) error {
+    a := g.account(name)
+    if a == nil {
+        return nil
+    }
+
+    status := position.Open
+    positions, err := a.Positions.ListPositions(ctx, position.PositionFilter{Status: &status})
+    if err != nil {
+        return fmt.Errorf("list positions of account %s: %w", a.ID, err)
+    }
+
+    var errs []error
+    for _, pos := range positions {
+        if !tradesSymbol(schedule, pos.Symbol) {
+            continue
+        }
+        switch schedule.Outside {
+        case strategy.ActionFlatten:
+            err = a.Positions.ClosePosition(ctx, pos.ID, pos.CurrentPrice)
+        case strategy.ActionProtect:
+            err = protect(ctx, a.Positions, pos, schedule.ProtectStop)
+        }
+        if err != nil && !errors.Is(err, position.ErrPositionAlreadyClosed) {
+            errs = append(errs, fmt.Errorf("%s position %s: %w", schedule.Outside, pos.ID, err))
+        }
+    }
+    return errors.Join(errs...)
+}
+
+// account returns the account trading a strategy
+func (g sessionGuard) account(name string) *account.Account {
+    for _, a := range g.accounts.List() {
+        for _, s := range a.Strategies {
+            if s == name {
+                return a
+            }
+        }
+    }
+    return nil
+}
+
+func tradesSymbol(schedule strategy.Schedule, symbol string) bool {
+    if len(schedule.Symbols) == 0 {
+        return true
+    }
+    for _, s := range schedule.Symbols {
+        if s == symbol {
+            return true
+        }
+    }
+    return false
+}
+
+// protect places a stop at a distance from the current price, unless the
+// position already has a tighter one
+func protect(ctx context.Context, positions *position.Manager, pos *position.Position, distance float64) error {
+    stop := pos.CurrentPrice.Mul(money.FromFloat(1 - distance))
+    if pos.Side == position.Short {
+        stop = pos.CurrentPrice.Mul(money.FromFloat(1 + distance))
+    }
+    if pos.StopLoss != nil {
+        current := *pos.StopLoss
+        if (pos.Side == position.Long && current.Cmp(stop) >= 0) || (pos.Side == position.Short && current.Cmp(stop) <= 0) {
+            return nil
+        }
+    }
+    return positions.UpdatePosition(ctx, pos.ID, position.UpdatePositionParams{StopLoss: &stop})
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log/slog"
+    "time"
+
+    "github.com/go-redis/redis/v8"
+
+    "github.com/devinjacknz/godydxhyber/backend/config"
+    "github.com/devinjacknz/godydxhyber/backend/trading/snapshot"
+)
+
+// newSnapshotRepository returns the repository engine snapshots are saved
+// to, a file when a snapshot path is configured and Redis otherwise. It
+// returns nil when neither is configured.
+func newSnapshotRepository(cfg config.RepositoryConfig) (repository snapshot.Repository, closeRepository func() error, err error) {
+    switch {
+    case cfg.SnapshotPath != "":
+        return snapshot.NewFileRepository(cfg.SnapshotPath), func() error { return nil }, nil
+    case cfg.RedisURI != "":
+        opts, err := redis.ParseURL(cfg.RedisURI)
+        if err != nil {
+            return nil, nil, fmt.Errorf("invalid redis URI: %w", err)
+        }
+        client := redis.NewClient(opts)
+        return snapshot.NewRedisRepository(client, snapshot.DefaultRedisKey), client.Close, nil
+    default:
+        return nil, nil, nil
+    }
+}
+
+// restoreSnapshot resumes the engine from the snapshot saved at the last
+// shutdown. A missing or stale snapshot starts the engine from the
+// configuration alone.
+func restoreSnapshot(ctx context.Context, snapshots *snapshot.Manager) error {
+    report, err := snapshots.Restore(ctx)
+    switch {
+    case errors.Is(err, snapshot.ErrNoSnapshot):
+        slog.Info("no engine snapshot to restore")
+        return nil
+    case errors.Is(err, snapshot.ErrStaleSnapshot):
+        slog.Warn("engine snapshot not restored", "error", err)
+        return nil
+    case err != nil:
+        return err
+    }
+
+    for _, a := range report.Accounts {
+        slog.Info("restored account from snapshot",
+            "account_id", a.ID,
+            "orders", a.Orders.Loaded,
+            "orders_expired", len(a.Orders.Expired),
+            "positions", a.Positions.Loaded,
+            "positions_closed", len(a.Positions.Closed),
+            "daily_state", a.Daily,
+        )
+    }
+    if len(report.SkippedAccounts) > 0 {
+        slog.Warn("snapshot accounts no longer configured", "accounts", report.SkippedAccounts)
+    }
+    slog.Info("engine snapshot restored", "taken_at", report.TakenAt, "age", time.Since(report.TakenAt).Round(time.Second), "strategies", report.Strategies)
+    return nil
+}
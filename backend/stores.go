@@ -0,0 +1,52 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "log/slog"
+
+    "github.com/go-redis/redis/v8"
+
+    "github.com/devinjacknz/godydxhyber/backend/config"
+    "github.com/devinjacknz/godydxhyber/backend/trading/account"
+)
+
+// newStoreClient returns the Redis client the open orders, positions and
+// daily risk state of every account are saved with as they change, or nil
+// when no Redis URI is configured.
+func newStoreClient(cfg config.RepositoryConfig) (*redis.Client, error) {
+    if cfg.RedisURI == "" {
+        return nil, nil
+    }
+    opts, err := redis.ParseURL(cfg.RedisURI)
+    if err != nil {
+        return nil, fmt.Errorf("invalid redis URI: %w", err)
+    }
+    return redis.NewClient(opts), nil
+}
+
+// recoverAccounts reloads the open orders and positions saved by every
+// account and reconciles them with the exchanges, so a crash loses no more
+// than the changes in flight.
+func recoverAccounts(ctx context.Context, accounts *account.Registry) error {
+    for _, a := range accounts.List() {
+        orders, err := a.Orders.Recover(ctx)
+        if err != nil {
+            return fmt.Errorf("account %s: failed to recover orders: %w", a.ID, err)
+        }
+        positions, err := a.Positions.Recover(ctx)
+        if err != nil {
+            return fmt.Errorf("account %s: failed to recover positions: %w", a.ID, err)
+        }
+        slog.Info("recovered account",
+            "account_id", a.ID,
+            "orders", orders.Loaded,
+            "orders_adopted", len(orders.Adopted),
+            "orders_expired", len(orders.Expired),
+            "positions", positions.Loaded,
+            "positions_adopted", len(positions.Adopted),
+            "positions_closed", len(positions.Closed),
+        )
+    }
+    return nil
+}
@@ -0,0 +1,426 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/eventbus"
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+	"github.com/devinjacknz/godydxhyber/backend/trading/killswitch"
+	"github.com/devinjacknz/godydxhyber/backend/trading/position"
+	"github.com/devinjacknz/godydxhyber/backend/trading/risk"
+)
+
+// API sends and receives the bot's messages. *Client implements it.
+type API interface {
+	SendMessage(ctx context.Context, chatID int64, text string) error
+	GetUpdates(ctx context.Context, offset int64, timeout time.Duration) ([]Update, error)
+}
+
+// Positions lists and closes positions. *position.Manager implements it.
+type Positions interface {
+	ListPositions(ctx context.Context, filter position.PositionFilter) ([]*position.Position, error)
+	GetPosition(ctx context.Context, id string) (*position.Position, error)
+	ClosePosition(ctx context.Context, id string, closePrice money.Decimal) error
+}
+
+// Halter halts and resumes trading. *killswitch.KillSwitch implements it.
+type Halter interface {
+	Trigger(ctx context.Context, params killswitch.TriggerParams) (killswitch.State, error)
+	Reset(ctx context.Context, reason string) error
+}
+
+// DailyStates returns the daily trading activity. risk.RiskManager
+// implements it.
+type DailyStates interface {
+	GetDailyStates(ctx context.Context, from, to time.Time) ([]*risk.DailyState, error)
+}
+
+// Config contains bot configuration
+type Config struct {
+	// AllowedChats are the chats notified and allowed to send commands.
+	// Messages from other chats are ignored.
+	AllowedChats []int64
+	// PollTimeout is the long poll duration of each update request
+	PollTimeout time.Duration
+	// ConfirmTTL is how long a destructive command waits for /confirm
+	ConfirmTTL time.Duration
+	// SummaryTime is the UTC time of day, as "15:04", at which the PnL
+	// summary of the previous day is sent
+	SummaryTime string
+}
+
+// DefaultConfig returns default bot configuration
+func DefaultConfig() Config {
+	return Config{
+		PollTimeout: 30 * time.Second,
+		ConfirmTTL:  time.Minute,
+		SummaryTime: "00:05",
+	}
+}
+
+// pendingAction is a destructive command waiting for confirmation
+type pendingAction struct {
+	description string
+	run         func(ctx context.Context) (string, error)
+	expires     time.Time
+}
+
+// Bot notifies the allowed chats of trading events and runs their commands
+// on one account's positions and kill switch
+type Bot struct {
+	api       API
+	positions Positions
+	halter    Halter
+	daily     DailyStates
+	config    Config
+	summaryAt time.Duration
+	allowed   map[int64]bool
+	pending   map[int64]*pendingAction
+	now       func() time.Time
+	mu        sync.Mutex
+}
+
+// New creates a bot. It needs at least one allowed chat.
+func New(api API, positions Positions, halter Halter, daily DailyStates, config Config) (*Bot, error) {
+	if len(config.AllowedChats) == 0 {
+		return nil, ErrNoChats
+	}
+	defaults := DefaultConfig()
+	if config.PollTimeout <= 0 {
+		config.PollTimeout = defaults.PollTimeout
+	}
+	if config.ConfirmTTL <= 0 {
+		config.ConfirmTTL = defaults.ConfirmTTL
+	}
+	if config.SummaryTime == "" {
+		config.SummaryTime = defaults.SummaryTime
+	}
+	at, err := time.Parse("15:04", config.SummaryTime)
+	if err != nil {
+		return nil, fmt.Errorf("invalid summary time %q: %w", config.SummaryTime, err)
+	}
+
+	allowed := make(map[int64]bool, len(config.AllowedChats))
+	for _, id := range config.AllowedChats {
+		allowed[id] = true
+	}
+	return &Bot{
+		api:       api,
+		positions: positions,
+		halter:    halter,
+		daily:     daily,
+		config:    config,
+		summaryAt: time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute,
+		allowed:   allowed,
+		pending:   make(map[int64]*pendingAction),
+		now:       time.Now,
+	}, nil
+}
+
+// Run polls the commands and sends the daily summaries until the context is
+// cancelled
+func (b *Bot) Run(ctx context.Context) error {
+	go b.runSummaries(ctx)
+
+	var offset int64
+	for {
+		updates, err := b.api.GetUpdates(ctx, offset, b.config.PollTimeout)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			slog.WarnContext(ctx, "failed to poll telegram updates", "error", err)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(5 * time.Second):
+			}
+			continue
+		}
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			if u.Message != nil {
+				b.Handle(ctx, *u.Message)
+			}
+		}
+	}
+}
+
+// Subscribe notifies the allowed chats of fills and risk violations, off the
+// publisher's goroutine. It returns a function removing the subscriptions.
+func (b *Bot) Subscribe(bus *eventbus.Bus) func() {
+	unsubscribers := []func(){
+		eventbus.Subscribe(bus, eventbus.OrderFilled, func(ctx context.Context, e eventbus.OrderFilledEvent) error {
+			return b.Notify(ctx, fillText(e))
+		}, eventbus.WithMode(eventbus.Async)),
+		eventbus.Subscribe(bus, eventbus.RiskViolation, func(ctx context.Context, e eventbus.RiskViolationEvent) error {
+			return b.Notify(ctx, violationText(e))
+		}, eventbus.WithMode(eventbus.Async)),
+	}
+	return func() {
+		for _, unsubscribe := range unsubscribers {
+			unsubscribe()
+		}
+	}
+}
+
+// Notify sends a message to every allowed chat
+func (b *Bot) Notify(ctx context.Context, text string) error {
+	var errs []error
+	for _, id := range b.config.AllowedChats {
+		if err := b.api.SendMessage(ctx, id, text); err != nil {
+			errs = append(errs, fmt.Errorf("notify chat %d: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Handle runs the command of a message and replies with its outcome.
+// Messages from chats outside the allowlist are ignored.
+func (b *Bot) Handle(ctx context.Context, msg Message) {
+	if !b.allowed[msg.Chat.ID] {
+		slog.WarnContext(ctx, "ignored telegram message", "chat_id", msg.Chat.ID, "error", ErrChatNotAllowed)
+		return
+	}
+
+	reply := b.command(ctx, msg.Chat.ID, msg.Text)
+	if err := b.api.SendMessage(ctx, msg.Chat.ID, reply); err != nil {
+		slog.ErrorContext(ctx, "failed to reply to telegram command", "chat_id", msg.Chat.ID, "error", err)
+	}
+}
+
+// command runs a command and returns the reply
+func (b *Bot) command(ctx context.Context, chatID int64, text string) string {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return helpText
+	}
+	// Commands in groups are addressed as /command@bot
+	name, _, _ := strings.Cut(fields[0], "@")
+	args := fields[1:]
+
+	switch name {
+	case "/start", "/help":
+		return helpText
+	case "/positions":
+		return b.listPositions(ctx)
+	case "/close":
+		if len(args) != 1 {
+			return "Usage: /close <position id>"
+		}
+		return b.closePosition(ctx, chatID, args[0])
+	case "/halt":
+		reason := strings.Join(args, " ")
+		if reason == "" {
+			reason = "halted from telegram"
+		}
+		return b.confirm(chatID, "Halt trading: "+reason, func(ctx context.Context) (string, error) {
+			state, err := b.halter.Trigger(ctx, killswitch.TriggerParams{
+				Source: killswitch.SourceManual,
+				Reason: fmt.Sprintf("%s (telegram chat %d)", reason, chatID),
+			})
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Trading halted: %d orders cancelled, %d positions closed", state.CancelledOrders, state.ClosedPositions), nil
+		})
+	case "/resume":
+		return b.confirm(chatID, "Resume trading", func(ctx context.Context) (string, error) {
+			if err := b.halter.Reset(ctx, fmt.Sprintf("resumed from telegram chat %d", chatID)); err != nil {
+				return "", err
+			}
+			return "Trading resumed", nil
+		})
+	case "/confirm":
+		return b.runPending(ctx, chatID)
+	case "/cancel":
+		b.mu.Lock()
+		_, ok := b.pending[chatID]
+		delete(b.pending, chatID)
+		b.mu.Unlock()
+		if !ok {
+			return "Nothing to cancel"
+		}
+		return "Cancelled"
+	default:
+		return "Unknown command " + name + "\n\n" + helpText
+	}
+}
+
+const helpText = `Commands:
+/positions - list open positions
+/close <id> - close a position
+/halt [reason] - cancel orders and halt trading
+/resume - resume trading after a halt
+/confirm - run the command waiting for confirmation
+/cancel - drop the command waiting for confirmation`
+
+func (b *Bot) listPositions(ctx context.Context) string {
+	open := position.Open
+	positions, err := b.positions.ListPositions(ctx, position.PositionFilter{Status: &open})
+	if err != nil {
+		return "Failed to list positions: " + err.Error()
+	}
+	if len(positions) == 0 {
+		return "No open positions"
+	}
+
+	snapshots := make([]*position.Position, 0, len(positions))
+	for _, p := range positions {
+		snapshots = append(snapshots, p.Snapshot())
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].OpenTime.Before(snapshots[j].OpenTime) })
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d open positions:", len(snapshots))
+	for _, p := range snapshots {
+		fmt.Fprintf(&sb, "\n%s %s %s %s @ %s, mark %s, PnL %.2f", p.ID, p.Symbol, p.Side, p.Size, p.EntryPrice, p.CurrentPrice, p.UnrealizedPnL.Float64())
+	}
+	return sb.String()
+}
+
+func (b *Bot) closePosition(ctx context.Context, chatID int64, id string) string {
+	p, err := b.positions.GetPosition(ctx, id)
+	if err != nil {
+		return "Failed to find position " + id + ": " + err.Error()
+	}
+	snapshot := p.Snapshot()
+	if snapshot.Status != position.Open {
+		return "Position " + id + " is not open"
+	}
+
+	description := fmt.Sprintf("Close %s %s %s @ mark %s", snapshot.Symbol, snapshot.Side, snapshot.Size, snapshot.CurrentPrice)
+	return b.confirm(chatID, description, func(ctx context.Context) (string, error) {
+		// The mark may have moved while waiting for the confirmation
+		p, err := b.positions.GetPosition(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		price := p.Snapshot().CurrentPrice
+		if price.Sign() <= 0 {
+			return "", position.ErrInvalidPrice
+		}
+		if err := b.positions.ClosePosition(ctx, id, price); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Closed position %s at %s", id, price), nil
+	})
+}
+
+// confirm holds a destructive command until the chat confirms it. A new
+// command replaces the one pending.
+func (b *Bot) confirm(chatID int64, description string, run func(ctx context.Context) (string, error)) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending[chatID] = &pendingAction{
+		description: description,
+		run:         run,
+		expires:     b.now().Add(b.config.ConfirmTTL),
+	}
+	return fmt.Sprintf("%s?\nSend /confirm within %s to proceed or /cancel.", description, b.config.ConfirmTTL)
+}
+
+func (b *Bot) runPending(ctx context.Context, chatID int64) string {
+	b.mu.Lock()
+	action, ok := b.pending[chatID]
+	delete(b.pending, chatID)
+	b.mu.Unlock()
+
+	switch {
+	case !ok:
+		return "Nothing to confirm"
+	case b.now().After(action.expires):
+		return "Confirmation expired: " + action.description
+	}
+	reply, err := action.run(ctx)
+	if err != nil {
+		return "Failed: " + action.description + ": " + err.Error()
+	}
+	slog.InfoContext(ctx, "telegram command confirmed", "chat_id", chatID, "command", action.description)
+	return reply
+}
+
+// runSummaries sends the summary of the previous day at the summary time of
+// every day
+func (b *Bot) runSummaries(ctx context.Context) {
+	for {
+		now := b.now().UTC()
+		next := now.Truncate(24 * time.Hour).Add(b.summaryAt)
+		if !next.After(now) {
+			next = next.Add(24 * time.Hour)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(next.Sub(now)):
+		}
+		if err := b.SendDailySummary(ctx, next.Add(-24*time.Hour)); err != nil {
+			slog.ErrorContext(ctx, "failed to send daily summary", "error", err)
+		}
+	}
+}
+
+// SendDailySummary sends the realized PnL and trades of the UTC day of t,
+// with the open positions, to every allowed chat
+func (b *Bot) SendDailySummary(ctx context.Context, t time.Time) error {
+	day := t.UTC().Format("2006-01-02")
+	states, err := b.daily.GetDailyStates(ctx, t, t)
+	if err != nil {
+		return fmt.Errorf("get daily state of %s: %w", day, err)
+	}
+	realized, trades := 0.0, 0
+	for _, s := range states {
+		realized += s.RealizedPnL
+		trades += s.Trades
+	}
+
+	open := position.Open
+	positions, err := b.positions.ListPositions(ctx, position.PositionFilter{Status: &open})
+	if err != nil {
+		return fmt.Errorf("list positions: %w", err)
+	}
+	var unrealized money.Decimal
+	for _, p := range positions {
+		unrealized = unrealized.Add(p.Snapshot().UnrealizedPnL)
+	}
+
+	return b.Notify(ctx, fmt.Sprintf("Daily summary %s\nRealized PnL: %.2f\nTrades: %d\nOpen positions: %d, unrealized PnL %.2f",
+		day, realized, trades, len(positions), unrealized.Float64()))
+}
+
+func fillText(e eventbus.OrderFilledEvent) string {
+	text := fmt.Sprintf("Filled %s %g %s", strings.ToUpper(e.Side), e.Size, e.Symbol)
+	if e.Price > 0 {
+		text += fmt.Sprintf(" at %g", e.Price)
+	}
+	if e.RemainingSize > 0 {
+		text += fmt.Sprintf(", %g remaining", e.RemainingSize)
+	}
+	if e.AccountID != "" {
+		text += " (account " + e.AccountID + ")"
+	}
+	return text
+}
+
+func violationText(e eventbus.RiskViolationEvent) string {
+	text := "Risk violation: " + e.Type
+	if e.Symbol != "" {
+		text += " on " + e.Symbol
+	}
+	text += fmt.Sprintf(", %g over %g", e.Value, e.Threshold)
+	if e.Description != "" {
+		text += "\n" + e.Description
+	}
+	if e.AccountID != "" {
+		text += "\nAccount " + e.AccountID
+	}
+	return text
+}
@@ -0,0 +1,205 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/devinjacknz/godydxhyber/backend/eventbus"
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+	"github.com/devinjacknz/godydxhyber/backend/trading/killswitch"
+	"github.com/devinjacknz/godydxhyber/backend/trading/position"
+	"github.com/devinjacknz/godydxhyber/backend/trading/risk"
+)
+
+type sentMessage struct {
+	chatID int64
+	text   string
+}
+
+type fakeAPI struct {
+	sent chan sentMessage
+}
+
+func (a *fakeAPI) SendMessage(ctx context.Context, chatID int64, text string) error {
+	a.sent <- sentMessage{chatID: chatID, text: text}
+	return nil
+}
+
+func (a *fakeAPI) GetUpdates(ctx context.Context, offset int64, timeout time.Duration) ([]Update, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (a *fakeAPI) last(t *testing.T) sentMessage {
+	t.Helper()
+	select {
+	case m := <-a.sent:
+		return m
+	case <-time.After(time.Second):
+		t.Fatal("no message sent")
+		return sentMessage{}
+	}
+}
+
+type fakeHalter struct {
+	triggered []killswitch.TriggerParams
+	resets    int
+}
+
+func (h *fakeHalter) Trigger(ctx context.Context, params killswitch.TriggerParams) (killswitch.State, error) {
+	h.triggered = append(h.triggered, params)
+	return killswitch.State{Active: true, CancelledOrders: 2}, nil
+}
+
+func (h *fakeHalter) Reset(ctx context.Context, reason string) error {
+	h.resets++
+	return nil
+}
+
+type fakeDaily []*risk.DailyState
+
+func (d fakeDaily) GetDailyStates(ctx context.Context, from, to time.Time) ([]*risk.DailyState, error) {
+	return d, nil
+}
+
+const chat = int64(42)
+
+func newBot(t *testing.T) (*Bot, *fakeAPI, *position.Manager, *fakeHalter) {
+	api := &fakeAPI{sent: make(chan sentMessage, 10)}
+	positions := position.NewManager()
+	halter := &fakeHalter{}
+	daily := fakeDaily{{Day: "2024-06-03", RealizedPnL: 125.5, Trades: 4}}
+	bot, err := New(api, positions, halter, daily, Config{AllowedChats: []int64{chat}, ConfirmTTL: time.Minute})
+	require.NoError(t, err)
+	return bot, api, positions, halter
+}
+
+func TestCommands(t *testing.T) {
+	ctx := context.Background()
+	bot, api, positions, halter := newBot(t)
+	now := time.Date(2024, 6, 3, 12, 0, 0, 0, time.UTC)
+	bot.now = func() time.Time { return now }
+	send := func(text string) string {
+		bot.Handle(ctx, Message{Chat: Chat{ID: chat}, Text: text})
+		m := api.last(t)
+		assert.Equal(t, chat, m.chatID)
+		return m.text
+	}
+
+	assert.Equal(t, "No open positions", send("/positions"))
+	pos, err := positions.OpenPosition(ctx, position.OpenPositionParams{Symbol: "BTC-USD", Side: position.Long, Size: money.FromFloat(0.5), EntryPrice: money.NewFromInt(60000), Leverage: 1})
+	require.NoError(t, err)
+	assert.Contains(t, send("/positions@gosol_bot"), pos.ID+" BTC-USD long 0.5 @ 60000")
+
+	t.Run("Chats outside the allowlist are ignored", func(t *testing.T) {
+		bot.Handle(ctx, Message{Chat: Chat{ID: 7}, Text: "/halt"})
+		assert.Empty(t, api.sent)
+		assert.Equal(t, "Nothing to confirm", send("/confirm"))
+	})
+
+	t.Run("Close needs a confirmation", func(t *testing.T) {
+		assert.Contains(t, send("/close "+pos.ID), "Close BTC-USD long 0.5 @ mark 60000?")
+		price := money.FromFloat(61000.0)
+		require.NoError(t, positions.UpdatePosition(ctx, pos.ID, position.UpdatePositionParams{CurrentPrice: &price}))
+		assert.Equal(t, "Closed position "+pos.ID+" at 61000", send("/confirm"))
+		assert.Equal(t, position.Closed, pos.Snapshot().Status)
+		assert.Contains(t, send("/close "+pos.ID), "is not open")
+		assert.Contains(t, send("/close unknown"), "Failed to find position unknown")
+	})
+
+	t.Run("Halt and resume", func(t *testing.T) {
+		send("/halt exchange incident")
+		assert.Equal(t, "Cancelled", send("/cancel"))
+		assert.Equal(t, "Nothing to confirm", send("/confirm"))
+
+		send("/halt exchange incident")
+		assert.Equal(t, "Trading halted: 2 orders cancelled, 0 positions closed", send("/confirm"))
+		require.Len(t, halter.triggered, 1)
+		assert.Equal(t, killswitch.SourceManual, halter.triggered[0].Source)
+		assert.Equal(t, "exchange incident (telegram chat 42)", halter.triggered[0].Reason)
+
+		send("/resume")
+		now = now.Add(2 * time.Minute)
+		assert.Contains(t, send("/confirm"), "Confirmation expired: Resume trading")
+		assert.Zero(t, halter.resets)
+		send("/resume")
+		assert.Equal(t, "Trading resumed", send("/confirm"))
+		assert.Equal(t, 1, halter.resets)
+	})
+
+	assert.Contains(t, send("/flatten"), "Unknown command /flatten")
+}
+
+func TestNotifications(t *testing.T) {
+	ctx := context.Background()
+	bot, api, _, _ := newBot(t)
+	bus := eventbus.New(eventbus.DefaultConfig())
+	defer bus.Close()
+	unsubscribe := bot.Subscribe(bus)
+	defer unsubscribe()
+
+	require.NoError(t, eventbus.Publish(ctx, bus, eventbus.OrderFilled, eventbus.OrderFilledEvent{
+		Symbol: "ETH-USD", Side: "buy", Size: 2, Price: 3000, RemainingSize: 1, AccountID: "main",
+	}))
+	assert.Equal(t, "Filled BUY 2 ETH-USD at 3000, 1 remaining (account main)", api.last(t).text)
+
+	require.NoError(t, eventbus.Publish(ctx, bus, eventbus.RiskViolation, eventbus.RiskViolationEvent{
+		Type: "daily_loss", Value: 1200, Threshold: 1000, Description: "daily loss limit exceeded",
+	}))
+	assert.Equal(t, "Risk violation: daily_loss, 1200 over 1000\ndaily loss limit exceeded", api.last(t).text)
+
+	require.NoError(t, bot.SendDailySummary(ctx, time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)))
+	summary := api.last(t).text
+	assert.Contains(t, summary, "Daily summary 2024-06-03")
+	assert.Contains(t, summary, "Realized PnL: 125.50")
+	assert.Contains(t, summary, "Trades: 4")
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(&fakeAPI{}, nil, nil, nil, Config{})
+	assert.ErrorIs(t, err, ErrNoChats)
+	_, err = New(&fakeAPI{}, nil, nil, nil, Config{AllowedChats: []int64{chat}, SummaryTime: "noon"})
+	assert.Error(t, err)
+}
+
+func TestClient(t *testing.T) {
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		var params map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&params))
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/getUpdates"):
+			assert.Equal(t, 5.0, params["offset"])
+			w.Write([]byte(`{"ok":true,"result":[{"update_id":5,"message":{"message_id":1,"chat":{"id":42},"text":"/positions"}}]}`))
+		case params["chat_id"] == 42.0:
+			w.Write([]byte(`{"ok":true,"result":{}}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"ok":false,"description":"Bad Request: chat not found"}`))
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := NewClient(srv.URL, "123:abc", time.Second)
+	updates, err := c.GetUpdates(ctx, 5, time.Second)
+	require.NoError(t, err)
+	require.Len(t, updates, 1)
+	assert.Equal(t, "/positions", updates[0].Message.Text)
+	assert.Equal(t, int64(42), updates[0].Message.Chat.ID)
+
+	require.NoError(t, c.SendMessage(ctx, 42, "hello"))
+	err = c.SendMessage(ctx, 7, "hello")
+	assert.ErrorIs(t, err, ErrAPI)
+	assert.Contains(t, err.Error(), "chat not found")
+	assert.Equal(t, "/bot123:abc/sendMessage", requests[len(requests)-1])
+}
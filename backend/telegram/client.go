@@ -0,0 +1,123 @@
+// Package telegram is a Telegram bot to follow and control the engine from
+// a chat: it pushes fills, risk violations and daily PnL summaries, and
+// takes commands from an allowlist of chats.
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/pkg/httpx"
+)
+
+// DefaultBaseURL is the Telegram Bot API endpoint
+const DefaultBaseURL = "https://api.telegram.org"
+
+// Message is a message received by the bot
+type Message struct {
+	MessageID int64  `json:"message_id"`
+	Chat      Chat   `json:"chat"`
+	Text      string `json:"text"`
+}
+
+// Chat is the chat of a message
+type Chat struct {
+	ID int64 `json:"id"`
+}
+
+// Update is an incoming update of the bot
+type Update struct {
+	UpdateID int64    `json:"update_id"`
+	Message  *Message `json:"message,omitempty"`
+}
+
+// Client calls the Telegram Bot API
+type Client struct {
+	baseURL string
+	token   string
+	http    *httpx.Client
+}
+
+// NewClient creates a Bot API client. Long polls last up to pollTimeout, so
+// requests may take that long on top of the network round trip.
+func NewClient(baseURL, token string, pollTimeout time.Duration) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{
+		baseURL: baseURL,
+		token:   token,
+		http:    httpx.New(httpx.Config{Name: "telegram", Timeout: pollTimeout + 10*time.Second}),
+	}
+}
+
+// SendMessage sends a text message to a chat
+func (c *Client) SendMessage(ctx context.Context, chatID int64, text string) error {
+	return c.call(ctx, "sendMessage", map[string]interface{}{
+		"chat_id": chatID,
+		"text":    text,
+	}, nil)
+}
+
+// GetUpdates long polls the updates from offset, waiting up to timeout for
+// one to arrive
+func (c *Client) GetUpdates(ctx context.Context, offset int64, timeout time.Duration) ([]Update, error) {
+	var updates []Update
+	err := c.call(ctx, "getUpdates", map[string]interface{}{
+		"offset":          offset,
+		"timeout":         int(timeout.Seconds()),
+		"allowed_updates": []string{"message"},
+	}, &updates)
+	return updates, err
+}
+
+// apiResponse is the envelope of every Bot API response
+type apiResponse struct {
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	Description string          `json:"description"`
+}
+
+func (c *Client) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s request: %w", method, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/bot"+c.token+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", method, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		// The URL holds the token, so it is left out of the error
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("failed to call %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	var apiResp apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return fmt.Errorf("failed to decode %s response: unexpected status code: %d", method, resp.StatusCode)
+	}
+	if !apiResp.OK {
+		return fmt.Errorf("%w: %s: %s", ErrAPI, method, apiResp.Description)
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(apiResp.Result, result); err != nil {
+		return fmt.Errorf("failed to decode %s result: %w", method, err)
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package telegram
+
+import "errors"
+
+var (
+	// ErrAPI is returned when the Bot API rejects a request
+	ErrAPI = errors.New("telegram API error")
+
+	// ErrChatNotAllowed is returned for commands from a chat outside the
+	// allowlist
+	ErrChatNotAllowed = errors.New("chat not allowed")
+
+	// ErrNoChats is returned when the bot has no allowed chat
+	ErrNoChats = errors.New("no allowed chats")
+)
@@ -0,0 +1,181 @@
+// Package account holds the trading accounts of a deployment. Each account
+// trades with its own exchange credentials through its own order, position
+// and risk managers, so accounts never share orders, positions or risk
+// budgets.
+package account
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/devinjacknz/godydxhyber/backend/exchange/dydx"
+	"github.com/devinjacknz/godydxhyber/backend/trading/export"
+	"github.com/devinjacknz/godydxhyber/backend/trading/killswitch"
+	"github.com/devinjacknz/godydxhyber/backend/trading/order"
+	"github.com/devinjacknz/godydxhyber/backend/trading/position"
+	"github.com/devinjacknz/godydxhyber/backend/trading/report"
+	"github.com/devinjacknz/godydxhyber/backend/trading/risk"
+)
+
+// Default is the ID of the account trading with the exchanges and risk
+// sections of the configuration
+const Default = "default"
+
+// Account is a trading account and the managers of its orders, positions
+// and risk budget
+type Account struct {
+	ID   string
+	Name string
+	// Strategies are the strategies trading the account
+	Strategies []string
+	// Exchange is the client trading with the account's credentials, nil
+	// when the account has no exchange connection
+	Exchange   dydx.Client
+	Orders     order.OrderManager
+	Positions  *position.Manager
+	Risk       risk.RiskManager
+	KillSwitch *killswitch.KillSwitch
+}
+
+// Registry holds the trading accounts by ID
+type Registry struct {
+	accounts map[string]*Account
+	// ids keeps the registration order for listings
+	ids []string
+	mu  sync.RWMutex
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{accounts: make(map[string]*Account)}
+}
+
+// Register adds an account. Accounts need an ID and all of their managers.
+func (r *Registry) Register(a *Account) error {
+	if a.ID == "" || a.Orders == nil || a.Positions == nil || a.Risk == nil || a.KillSwitch == nil {
+		return ErrInvalidAccount
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.accounts[a.ID]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateAccount, a.ID)
+	}
+	r.accounts[a.ID] = a
+	r.ids = append(r.ids, a.ID)
+	return nil
+}
+
+// Get returns the account with the given ID
+func (r *Registry) Get(id string) (*Account, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	a, ok := r.accounts[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, id)
+	}
+	return a, nil
+}
+
+// List returns the accounts in registration order
+func (r *Registry) List() []*Account {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	accounts := make([]*Account, 0, len(r.ids))
+	for _, id := range r.ids {
+		accounts = append(accounts, r.accounts[id])
+	}
+	return accounts
+}
+
+// resolve returns the account named by the account path parameter
+func (r *Registry) resolve(c *gin.Context) (*Account, error) {
+	return r.Get(c.Param(pathParam))
+}
+
+// OrderResolver routes order calls to the orders of the account in the path
+func (r *Registry) OrderResolver() order.Resolver {
+	return func(c *gin.Context) (order.OrderManager, error) {
+		a, err := r.resolve(c)
+		if err != nil {
+			return nil, err
+		}
+		return a.Orders, nil
+	}
+}
+
+// PositionResolver routes position calls to the positions of the account in
+// the path
+func (r *Registry) PositionResolver() position.Resolver {
+	return func(c *gin.Context) (*position.Manager, error) {
+		a, err := r.resolve(c)
+		if err != nil {
+			return nil, err
+		}
+		return a.Positions, nil
+	}
+}
+
+// RiskResolver routes risk calls to the risk manager of the account in the
+// path
+func (r *Registry) RiskResolver() risk.Resolver {
+	return func(c *gin.Context) (risk.RiskManager, error) {
+		a, err := r.resolve(c)
+		if err != nil {
+			return nil, err
+		}
+		return a.Risk, nil
+	}
+}
+
+// KillSwitchResolver routes kill switch calls to the kill switch of the
+// account in the path
+func (r *Registry) KillSwitchResolver() killswitch.Resolver {
+	return func(c *gin.Context) (*killswitch.KillSwitch, error) {
+		a, err := r.resolve(c)
+		if err != nil {
+			return nil, err
+		}
+		return a.KillSwitch, nil
+	}
+}
+
+// ExportResolver routes exports to the records of the account in the path
+func (r *Registry) ExportResolver() export.Resolver {
+	return func(c *gin.Context) (*export.Exporter, error) {
+		a, err := r.resolve(c)
+		if err != nil {
+			return nil, err
+		}
+		return a.Exporter(), nil
+	}
+}
+
+// ReportResolver routes report calls to the performance of the account in
+// the path
+func (r *Registry) ReportResolver() report.Resolver {
+	return func(c *gin.Context) (*report.Reporter, error) {
+		a, err := r.resolve(c)
+		if err != nil {
+			return nil, err
+		}
+		return a.Reporter(), nil
+	}
+}
+
+// Exporter returns an exporter of the trades, positions, daily stats and
+// risk checks of the account
+func (a *Account) Exporter() *export.Exporter {
+	return export.NewExporter(export.Sources{
+		Orders:    a.Orders,
+		Positions: a.Positions,
+		Risk:      a.Risk,
+	}, export.DefaultConfig())
+}
+
+// Reporter returns a reporter of the performance of the account
+func (a *Account) Reporter() *report.Reporter {
+	return report.New(a.Risk)
+}
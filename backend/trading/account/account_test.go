@@ -0,0 +1,106 @@
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/devinjacknz/godydxhyber/backend/trading/killswitch"
+	"github.com/devinjacknz/godydxhyber/backend/trading/order"
+	"github.com/devinjacknz/godydxhyber/backend/trading/position"
+	"github.com/devinjacknz/godydxhyber/backend/trading/risk"
+)
+
+func newAccount(id string, strategies ...string) *Account {
+	orders := order.NewOrderManager(order.WithAccountID(id))
+	positions := position.NewManager(position.WithAccountID(id))
+	return &Account{
+		ID:         id,
+		Strategies: strategies,
+		Orders:     orders,
+		Positions:  positions,
+		Risk:       risk.NewRiskManager(risk.WithAccountID(id)),
+		KillSwitch: killswitch.NewKillSwitch(orders, positions, killswitch.Config{}),
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	require.NoError(t, registry.Register(newAccount(Default)))
+	require.NoError(t, registry.Register(newAccount("wallet-2", "momentum")))
+	assert.ErrorIs(t, registry.Register(newAccount("wallet-2")), ErrDuplicateAccount)
+	assert.ErrorIs(t, registry.Register(&Account{ID: "wallet-3"}), ErrInvalidAccount)
+
+	accounts := registry.List()
+	require.Len(t, accounts, 2)
+	assert.Equal(t, Default, accounts[0].ID)
+	assert.Equal(t, "wallet-2", accounts[1].ID)
+
+	_, err := registry.Get("wallet-3")
+	assert.ErrorIs(t, err, ErrAccountNotFound)
+}
+
+func TestAccountRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	registry := NewRegistry()
+	require.NoError(t, registry.Register(newAccount(Default)))
+	require.NoError(t, registry.Register(newAccount("wallet-2", "momentum")))
+
+	r := gin.New()
+	api := r.Group("/api/v1")
+	registry.RegisterRoutes(api)
+	accountRoutes := registry.Group(api)
+	order.RegisterRoutesWithResolver(accountRoutes, registry.OrderResolver())
+	risk.RegisterRoutesWithResolver(accountRoutes, registry.RiskResolver())
+	call := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	// Orders are placed and listed per account
+	w := call(http.MethodPost, "/api/v1/accounts/wallet-2/orders", `{"symbol": "BTC-USD", "type": "market", "side": "buy", "size": 1}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created order.Order
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "wallet-2", created.AccountID)
+
+	wallet, err := registry.Get("wallet-2")
+	require.NoError(t, err)
+	orders, err := wallet.Orders.ListOrders(ctx, order.OrderFilter{})
+	require.NoError(t, err)
+	assert.Len(t, orders, 1)
+	main, err := registry.Get(Default)
+	require.NoError(t, err)
+	orders, err = main.Orders.ListOrders(ctx, order.OrderFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, orders)
+
+	// Each account has its own risk limits
+	w = call(http.MethodPut, "/api/v1/accounts/wallet-2/risk/limits", `{"exposure_limit": 5000}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	limits, err := main.Risk.GetLimits(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1000000.0, limits.ExposureLimit)
+
+	assert.Equal(t, http.StatusNotFound, call(http.MethodGet, "/api/v1/accounts/wallet-3/orders", "").Code)
+	assert.Equal(t, http.StatusNotFound, call(http.MethodGet, "/api/v1/accounts/wallet-3", "").Code)
+
+	w = call(http.MethodGet, "/api/v1/accounts", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var listing struct {
+		Accounts []accountResponse `json:"accounts"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listing))
+	require.Len(t, listing.Accounts, 2)
+	assert.Equal(t, []string{"momentum"}, listing.Accounts[1].Strategies)
+	assert.False(t, listing.Accounts[1].Connected)
+}
@@ -0,0 +1,14 @@
+package account
+
+import "errors"
+
+var (
+	// ErrAccountNotFound is returned when no account has the given ID
+	ErrAccountNotFound = errors.New("account not found")
+
+	// ErrDuplicateAccount is returned when registering an account ID twice
+	ErrDuplicateAccount = errors.New("account already registered")
+
+	// ErrInvalidAccount is returned for accounts without an ID or managers
+	ErrInvalidAccount = errors.New("invalid account")
+)
@@ -0,0 +1,85 @@
+package account
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/devinjacknz/godydxhyber/backend/middleware"
+	"github.com/devinjacknz/godydxhyber/backend/trading/killswitch"
+)
+
+// pathParam is the path parameter naming the account of account routes
+const pathParam = "account"
+
+type accountResponse struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name,omitempty"`
+	Strategies []string `json:"strategies"`
+	// Connected reports whether the account trades on an exchange
+	Connected  bool             `json:"connected"`
+	KillSwitch killswitch.State `json:"kill_switch"`
+}
+
+// RegisterRoutes registers the account listing endpoints, listing the
+// accounts the API token may see. Routes of a group on /accounts/:account
+// act on the account in the path once Require has checked it exists.
+func (r *Registry) RegisterRoutes(g gin.IRouter) {
+	g.GET("/accounts", func(c *gin.Context) {
+		accounts := r.List()
+		resp := make([]accountResponse, 0, len(accounts))
+		for _, a := range accounts {
+			if !middleware.CanViewPortfolio(c, a.ID) {
+				continue
+			}
+			resp = append(resp, newAccountResponse(a))
+		}
+		c.JSON(http.StatusOK, gin.H{"accounts": resp})
+	})
+	g.GET("/accounts/:"+pathParam, r.Require(), func(c *gin.Context) {
+		a, err := r.resolve(c)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, newAccountResponse(a))
+	})
+}
+
+// Group returns the /accounts/:account group of g, answering requests for
+// unknown accounts with 404
+func (r *Registry) Group(g gin.IRouter) gin.IRouter {
+	return g.Group("/accounts/:"+pathParam, r.Require())
+}
+
+// Require answers requests for an account that is not registered, or that
+// the API token may not see, with 404
+func (r *Registry) Require() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_, err := r.resolve(c)
+		if err == nil && !middleware.CanViewPortfolio(c, c.Param(pathParam)) {
+			err = fmt.Errorf("%w: %s", ErrAccountNotFound, c.Param(pathParam))
+		}
+		if errors.Is(err, ErrAccountNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.Next()
+	}
+}
+
+func newAccountResponse(a *Account) accountResponse {
+	strategies := a.Strategies
+	if strategies == nil {
+		strategies = []string{}
+	}
+	return accountResponse{
+		ID:         a.ID,
+		Name:       a.Name,
+		Strategies: strategies,
+		Connected:  a.Exchange != nil,
+		KillSwitch: a.KillSwitch.Status(),
+	}
+}
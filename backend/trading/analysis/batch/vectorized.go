@@ -0,0 +1,193 @@
+package batch
+
+import (
+	"fmt"
+	"math"
+)
+
+// The functions in this file compute an indicator over a whole price series in
+// a single pass. They produce the same values as feeding the series through the
+// streaming indicators one price at a time with BatchAdapter, without the
+// per-bar allocations, for series known up front. Every result slice is aligned
+// with the input: result[i] is the indicator value at values[i].
+
+// MACDSeries holds the aligned MACD line, signal line and histogram
+type MACDSeries struct {
+	MACD      []float64
+	Signal    []float64
+	Histogram []float64
+}
+
+// BollingerSeries holds the aligned Bollinger Bands
+type BollingerSeries struct {
+	Upper  []float64
+	Middle []float64
+	Lower  []float64
+}
+
+// Closes extracts the closing prices from a batch of price data
+func Closes(prices []BatchPrice) []float64 {
+	closes := make([]float64, len(prices))
+	for i, price := range prices {
+		closes[i] = price.Close
+	}
+	return closes
+}
+
+// SMA computes the simple moving average. Values before the first full window
+// are NaN.
+func SMA(values []float64, period int) ([]float64, error) {
+	if period < 1 {
+		return nil, fmt.Errorf("period must be >= 1, got %d", period)
+	}
+
+	result := make([]float64, len(values))
+	sum := 0.0
+	for i, v := range values {
+		sum += v
+		if i >= period {
+			sum -= values[i-period]
+		}
+		if i < period-1 {
+			result[i] = math.NaN()
+			continue
+		}
+		result[i] = sum / float64(period)
+	}
+	return result, nil
+}
+
+// EMA computes the exponential moving average, seeded with the first value
+func EMA(values []float64, period int) ([]float64, error) {
+	if period < 1 {
+		return nil, fmt.Errorf("period must be >= 1, got %d", period)
+	}
+
+	result := make([]float64, len(values))
+	emaInto(result, values, 2.0/float64(period+1))
+	return result, nil
+}
+
+// RSI computes the relative strength index over a simple average of the last
+// period gains and losses. Values before the first full window are 50.
+func RSI(values []float64, period int) ([]float64, error) {
+	if period < 2 {
+		return nil, fmt.Errorf("period must be >= 2, got %d", period)
+	}
+
+	result := make([]float64, len(values))
+	gains := make([]float64, len(values))
+	losses := make([]float64, len(values))
+	gainSum, lossSum := 0.0, 0.0
+
+	for i := range values {
+		if i == 0 {
+			result[i] = 50
+			continue
+		}
+
+		change := values[i] - values[i-1]
+		gains[i] = math.Max(change, 0)
+		losses[i] = math.Max(-change, 0)
+		gainSum += gains[i]
+		lossSum += losses[i]
+		if i > period {
+			gainSum -= gains[i-period]
+			lossSum -= losses[i-period]
+		}
+
+		if i < period {
+			result[i] = 50
+			continue
+		}
+
+		avgGain := gainSum / float64(period)
+		avgLoss := lossSum / float64(period)
+		rs := avgGain / (avgLoss + 0.000001) // Avoid division by zero
+		result[i] = 100 - (100 / (1 + rs))
+	}
+	return result, nil
+}
+
+// MACD computes the MACD line, signal line and histogram
+func MACD(values []float64, fastPeriod, slowPeriod, signalPeriod int) (*MACDSeries, error) {
+	if fastPeriod >= slowPeriod {
+		return nil, fmt.Errorf("fast period must be less than slow period")
+	}
+	if fastPeriod < 1 || signalPeriod < 1 {
+		return nil, fmt.Errorf("periods must be >= 1, got %d and %d", fastPeriod, signalPeriod)
+	}
+
+	n := len(values)
+	series := &MACDSeries{
+		MACD:      make([]float64, n),
+		Signal:    make([]float64, n),
+		Histogram: make([]float64, n),
+	}
+
+	// Histogram is used as scratch space for the slow EMA
+	emaInto(series.MACD, values, 2.0/float64(fastPeriod+1))
+	emaInto(series.Histogram, values, 2.0/float64(slowPeriod+1))
+	for i := range series.MACD {
+		series.MACD[i] -= series.Histogram[i]
+	}
+
+	emaInto(series.Signal, series.MACD, 2.0/float64(signalPeriod+1))
+	for i := range series.Histogram {
+		series.Histogram[i] = series.MACD[i] - series.Signal[i]
+	}
+	return series, nil
+}
+
+// Bollinger computes Bollinger Bands using the population standard deviation
+// over each window. Values before the first full window are NaN.
+func Bollinger(values []float64, period int, stdDev float64) (*BollingerSeries, error) {
+	if period < 1 {
+		return nil, fmt.Errorf("period must be >= 1, got %d", period)
+	}
+
+	n := len(values)
+	series := &BollingerSeries{
+		Upper:  make([]float64, n),
+		Middle: make([]float64, n),
+		Lower:  make([]float64, n),
+	}
+
+	sum, sumSq := 0.0, 0.0
+	for i, v := range values {
+		sum += v
+		sumSq += v * v
+		if i >= period {
+			old := values[i-period]
+			sum -= old
+			sumSq -= old * old
+		}
+
+		if i < period-1 {
+			series.Upper[i] = math.NaN()
+			series.Middle[i] = math.NaN()
+			series.Lower[i] = math.NaN()
+			continue
+		}
+
+		mean := sum / float64(period)
+		variance := math.Max(sumSq/float64(period)-mean*mean, 0)
+		width := stdDev * math.Sqrt(variance)
+
+		series.Middle[i] = mean
+		series.Upper[i] = mean + width
+		series.Lower[i] = mean - width
+	}
+	return series, nil
+}
+
+// emaInto writes the EMA of values into dst, which must be at least as long
+func emaInto(dst, values []float64, multiplier float64) {
+	for i, v := range values {
+		if i == 0 {
+			dst[i] = v
+			continue
+		}
+		dst[i] = (v-dst[i-1])*multiplier + dst[i-1]
+	}
+}
@@ -0,0 +1,255 @@
+package batch
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/kwanRoshi/Gosol/backend/trading/analysis/streaming"
+)
+
+const benchmarkBars = 10000
+
+func generateBatchPrices(n int) []BatchPrice {
+	prices := make([]BatchPrice, n)
+	now := time.Now()
+
+	for i := 0; i < n; i++ {
+		closePrice := 100 + 10*math.Sin(float64(i)/15) + float64(i%7)
+		prices[i] = BatchPrice{
+			Timestamp: now.Add(time.Duration(i) * time.Minute),
+			Open:      closePrice - 0.5,
+			High:      closePrice + 1,
+			Low:       closePrice - 1,
+			Close:     closePrice,
+			Volume:    1000 + float64(i%100),
+		}
+	}
+	return prices
+}
+
+func assertMatchesStreaming(t *testing.T, indicator streaming.Indicator, prices []BatchPrice, got []float64) {
+	t.Helper()
+
+	want, err := NewBatchAdapter(indicator).ProcessBatch(context.Background(), prices)
+	if err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d values, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if math.Abs(got[i]-want[i].Value) > 1e-9 {
+			t.Fatalf("Value %d: expected %f, got %f", i, want[i].Value, got[i])
+		}
+	}
+}
+
+func TestVectorizedIndicators(t *testing.T) {
+	prices := generateBatchPrices(500)
+	closes := Closes(prices)
+
+	t.Run("EMA matches streaming", func(t *testing.T) {
+		ema, _ := streaming.NewEMA(10)
+		got, err := EMA(closes, 10)
+		if err != nil {
+			t.Fatalf("Failed to compute EMA: %v", err)
+		}
+		assertMatchesStreaming(t, ema, prices, got)
+	})
+
+	t.Run("RSI matches streaming", func(t *testing.T) {
+		rsi, _ := streaming.NewRSI(14)
+		got, err := RSI(closes, 14)
+		if err != nil {
+			t.Fatalf("Failed to compute RSI: %v", err)
+		}
+		assertMatchesStreaming(t, rsi, prices, got)
+	})
+
+	t.Run("MACD histogram matches streaming", func(t *testing.T) {
+		macd, _ := streaming.NewMACD(12, 26, 9)
+		got, err := MACD(closes, 12, 26, 9)
+		if err != nil {
+			t.Fatalf("Failed to compute MACD: %v", err)
+		}
+		assertMatchesStreaming(t, macd, prices, got.Histogram)
+
+		for i := range got.MACD {
+			if math.Abs(got.MACD[i]-got.Signal[i]-got.Histogram[i]) > 1e-12 {
+				t.Fatalf("Value %d: histogram does not equal MACD minus signal", i)
+			}
+		}
+	})
+
+	t.Run("SMA", func(t *testing.T) {
+		got, err := SMA([]float64{1, 2, 3, 4, 5}, 3)
+		if err != nil {
+			t.Fatalf("Failed to compute SMA: %v", err)
+		}
+		if !math.IsNaN(got[0]) || !math.IsNaN(got[1]) {
+			t.Errorf("Expected NaN before first full window, got %v", got[:2])
+		}
+		for i, want := range []float64{2, 3, 4} {
+			if got[i+2] != want {
+				t.Errorf("Value %d: expected %f, got %f", i+2, want, got[i+2])
+			}
+		}
+	})
+
+	t.Run("Bollinger", func(t *testing.T) {
+		got, err := Bollinger([]float64{2, 4, 4, 4, 5, 5, 7, 9}, 8, 2)
+		if err != nil {
+			t.Fatalf("Failed to compute Bollinger Bands: %v", err)
+		}
+		if !math.IsNaN(got.Middle[6]) {
+			t.Errorf("Expected NaN before first full window, got %f", got.Middle[6])
+		}
+		// Mean 5, population standard deviation 2
+		if got.Middle[7] != 5 || math.Abs(got.Upper[7]-9) > 1e-9 || math.Abs(got.Lower[7]-1) > 1e-9 {
+			t.Errorf("Expected bands 1/5/9, got %f/%f/%f", got.Lower[7], got.Middle[7], got.Upper[7])
+		}
+	})
+
+	t.Run("Invalid periods", func(t *testing.T) {
+		if _, err := SMA(closes, 0); err == nil {
+			t.Error("Expected error for SMA period 0")
+		}
+		if _, err := RSI(closes, 1); err == nil {
+			t.Error("Expected error for RSI period 1")
+		}
+		if _, err := MACD(closes, 26, 12, 9); err == nil {
+			t.Error("Expected error when fast period is not less than slow period")
+		}
+		if _, err := Bollinger(closes, 0, 2); err == nil {
+			t.Error("Expected error for Bollinger period 0")
+		}
+	})
+
+	t.Run("Empty input", func(t *testing.T) {
+		got, err := EMA(nil, 10)
+		if err != nil || len(got) != 0 {
+			t.Errorf("Expected empty result, got %v (err %v)", got, err)
+		}
+	})
+}
+
+func benchmarkAdapter(b *testing.B, indicator streaming.Indicator) {
+	ctx := context.Background()
+	adapter := NewBatchAdapter(indicator)
+	prices := generateBatchPrices(benchmarkBars)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := adapter.ProcessBatch(ctx, prices); err != nil {
+			b.Fatalf("Failed to process batch: %v", err)
+		}
+	}
+}
+
+// rollingWindow simulates per-bar recomputation of a windowed indicator
+func rollingWindow(values []float64, period int, fn func([]float64) float64) []float64 {
+	result := make([]float64, len(values))
+	for i := range values {
+		if i < period-1 {
+			result[i] = math.NaN()
+			continue
+		}
+		result[i] = fn(values[i-period+1 : i+1])
+	}
+	return result
+}
+
+func windowMean(window []float64) float64 {
+	sum := 0.0
+	for _, v := range window {
+		sum += v
+	}
+	return sum / float64(len(window))
+}
+
+func BenchmarkIncrementalEMA(b *testing.B) {
+	ema, _ := streaming.NewEMA(10)
+	benchmarkAdapter(b, ema)
+}
+
+func BenchmarkVectorizedEMA(b *testing.B) {
+	closes := Closes(generateBatchPrices(benchmarkBars))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		EMA(closes, 10)
+	}
+}
+
+func BenchmarkIncrementalRSI(b *testing.B) {
+	rsi, _ := streaming.NewRSI(14)
+	benchmarkAdapter(b, rsi)
+}
+
+func BenchmarkVectorizedRSI(b *testing.B) {
+	closes := Closes(generateBatchPrices(benchmarkBars))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		RSI(closes, 14)
+	}
+}
+
+func BenchmarkIncrementalMACD(b *testing.B) {
+	macd, _ := streaming.NewMACD(12, 26, 9)
+	benchmarkAdapter(b, macd)
+}
+
+func BenchmarkVectorizedMACD(b *testing.B) {
+	closes := Closes(generateBatchPrices(benchmarkBars))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		MACD(closes, 12, 26, 9)
+	}
+}
+
+func BenchmarkIncrementalSMA(b *testing.B) {
+	closes := Closes(generateBatchPrices(benchmarkBars))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rollingWindow(closes, 20, windowMean)
+	}
+}
+
+func BenchmarkVectorizedSMA(b *testing.B) {
+	closes := Closes(generateBatchPrices(benchmarkBars))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		SMA(closes, 20)
+	}
+}
+
+func BenchmarkIncrementalBollinger(b *testing.B) {
+	closes := Closes(generateBatchPrices(benchmarkBars))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rollingWindow(closes, 20, func(window []float64) float64 {
+			mean := windowMean(window)
+			variance := 0.0
+			for _, v := range window {
+				variance += (v - mean) * (v - mean)
+			}
+			return mean + 2*math.Sqrt(variance/float64(len(window)))
+		})
+	}
+}
+
+func BenchmarkVectorizedBollinger(b *testing.B) {
+	closes := Closes(generateBatchPrices(benchmarkBars))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Bollinger(closes, 20, 2)
+	}
+}
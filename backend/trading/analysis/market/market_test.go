@@ -71,6 +71,49 @@ func TestMarketAnalyzer(t *testing.T) {
 	})
 }
 
+func TestFindLevels(t *testing.T) {
+	// Three swings between 100 and 110, then a rally to 120 that pulls back
+	// to 115
+	var prices []float64
+	swing := func(from, to float64, steps int) {
+		for i := 0; i < steps; i++ {
+			prices = append(prices, from+(to-from)*float64(i)/float64(steps))
+		}
+	}
+	for i := 0; i < 3; i++ {
+		swing(100, 110, 10)
+		swing(110, 100, 10)
+	}
+	swing(100, 120, 20)
+	swing(120, 115, 10)
+	prices = append(prices, 115)
+
+	levels := FindLevels(prices, DefaultLevelConfig())
+	assert.Len(t, levels, 3)
+
+	// The 100 and 110 levels are touched three times, 100 most recently,
+	// and the broken 110 resistance is now support
+	assert.Equal(t, SupportLevel, levels[0].Type)
+	assert.Equal(t, SupportLevel, levels[1].Type)
+	assert.Equal(t, ResistanceLevel, levels[2].Type)
+	assert.InDelta(t, 100, levels[0].Price, 1e-9)
+	assert.InDelta(t, 110, levels[1].Price, 1e-9)
+	assert.InDelta(t, 120, levels[2].Price, 1e-9)
+	assert.Equal(t, 3, levels[1].Touches)
+	assert.Equal(t, 1, levels[2].Touches)
+	assert.Equal(t, 1.0, levels[0].Strength)
+	assert.Less(t, levels[2].Strength, levels[1].Strength)
+
+	support, ok := NearestLevel(levels, SupportLevel, 115)
+	assert.True(t, ok)
+	assert.InDelta(t, 110, support.Price, 1e-9)
+	resistance, ok := NearestLevel(levels, ResistanceLevel, 115)
+	assert.True(t, ok)
+	assert.InDelta(t, 120, resistance.Price, 1e-9)
+
+	assert.Empty(t, FindLevels(prices[:5], DefaultLevelConfig()))
+}
+
 func generateTestPrices() []float64 {
 	prices := make([]float64, 200)
 	basePrice := 100.0
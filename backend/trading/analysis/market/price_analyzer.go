@@ -18,8 +18,12 @@ type PriceAnalysis struct {
 	Volatility     float64
 	PriceRange     PriceRange
 	MovingAverages MovingAverages
-	Support        float64
-	Resistance     float64
+	// Support and Resistance are the nearest levels below and above the
+	// last price, taken from the order book when no level is found
+	Support    float64
+	Resistance float64
+	// Levels are the support and resistance levels, strongest first
+	Levels []Level
 }
 
 // PriceRange represents price range information
@@ -56,7 +60,8 @@ func (pa *PriceAnalyzer) Analyze(ctx context.Context, data MarketData) (*PriceAn
 	ma := calculateMovingAverages(data.Prices)
 
 	// Calculate support and resistance
-	support, resistance := calculateSupportResistance(data.Prices, data.OrderBook)
+	levels := FindLevels(data.Prices, DefaultLevelConfig())
+	support, resistance := calculateSupportResistance(data.Prices, levels, data.OrderBook)
 
 	return &PriceAnalysis{
 		Volatility:     volatility,
@@ -64,6 +69,7 @@ func (pa *PriceAnalyzer) Analyze(ctx context.Context, data MarketData) (*PriceAn
 		MovingAverages: ma,
 		Support:        support,
 		Resistance:     resistance,
+		Levels:         levels,
 	}, nil
 }
 
@@ -156,10 +162,19 @@ func calculateEMA(prices []float64, period int) float64 {
 	return ema
 }
 
-func calculateSupportResistance(prices []float64, orderBook OrderBook) (support, resistance float64) {
-	// Simple implementation using order book
-	if len(orderBook.Bids) > 0 && len(orderBook.Asks) > 0 {
-		// Find strongest bid level
+func calculateSupportResistance(prices []float64, levels []Level, orderBook OrderBook) (support, resistance float64) {
+	if len(prices) > 0 {
+		last := prices[len(prices)-1]
+		if level, ok := NearestLevel(levels, SupportLevel, last); ok {
+			support = level.Price
+		}
+		if level, ok := NearestLevel(levels, ResistanceLevel, last); ok {
+			resistance = level.Price
+		}
+	}
+
+	// Fall back to the largest order book levels
+	if support == 0 {
 		maxBidVolume := 0.0
 		for _, bid := range orderBook.Bids {
 			if bid.Amount > maxBidVolume {
@@ -167,8 +182,8 @@ func calculateSupportResistance(prices []float64, orderBook OrderBook) (support,
 				support = bid.Price
 			}
 		}
-
-		// Find strongest ask level
+	}
+	if resistance == 0 {
 		maxAskVolume := 0.0
 		for _, ask := range orderBook.Asks {
 			if ask.Amount > maxAskVolume {
@@ -0,0 +1,185 @@
+package market
+
+import (
+	"math"
+	"sort"
+)
+
+// LevelType is the side of a support or resistance level
+type LevelType int
+
+const (
+	SupportLevel LevelType = iota + 1
+	ResistanceLevel
+)
+
+// Level is a price level where price repeatedly turned
+type Level struct {
+	Type  LevelType
+	Price float64
+	// Touches is the number of pivots clustered into the level
+	Touches int
+	// Strength scores the level from 0 to 1 by its touches, recent touches
+	// weighing more. The strongest level scores 1.
+	Strength float64
+	// LastTouch is the index in the prices of the last pivot of the level
+	LastTouch int
+}
+
+// LevelConfig configures the detection of support and resistance levels
+type LevelConfig struct {
+	// PivotWindow is the number of prices on each side a pivot must be the
+	// highest or lowest of
+	PivotWindow int
+	// Tolerance is the distance, relative to the price, within which pivots
+	// are clustered into one level
+	Tolerance float64
+	// MaxLevels caps the number of levels returned, zero for all
+	MaxLevels int
+}
+
+// DefaultLevelConfig returns the level detection used by the price analyzer
+func DefaultLevelConfig() LevelConfig {
+	return LevelConfig{
+		PivotWindow: 5,
+		Tolerance:   0.005,
+		MaxLevels:   10,
+	}
+}
+
+type pivot struct {
+	index int
+	price float64
+}
+
+// FindLevels detects the support and resistance levels of a price series.
+// Pivot highs and lows are clustered by price, and each cluster is scored by
+// its touch count. Levels below the last price are supports and levels
+// above it resistances. They are returned strongest first.
+func FindLevels(prices []float64, config LevelConfig) []Level {
+	window := config.PivotWindow
+	if window <= 0 || len(prices) < 2*window+1 {
+		return nil
+	}
+
+	pivots := findPivots(prices, window)
+	if len(pivots) == 0 {
+		return nil
+	}
+
+	last := prices[len(prices)-1]
+	levels := clusterPivots(pivots, config.Tolerance, len(prices))
+	for i := range levels {
+		if levels[i].Price < last {
+			levels[i].Type = SupportLevel
+		} else {
+			levels[i].Type = ResistanceLevel
+		}
+	}
+
+	sort.SliceStable(levels, func(i, j int) bool {
+		if levels[i].Strength != levels[j].Strength {
+			return levels[i].Strength > levels[j].Strength
+		}
+		return levels[i].LastTouch > levels[j].LastTouch
+	})
+	if config.MaxLevels > 0 && len(levels) > config.MaxLevels {
+		levels = levels[:config.MaxLevels]
+	}
+	return levels
+}
+
+// NearestLevel returns the level of a type closest to price, the support
+// below it or the resistance above it. Stops placed beyond it sit outside
+// the nearest structure.
+func NearestLevel(levels []Level, levelType LevelType, price float64) (Level, bool) {
+	var nearest Level
+	found := false
+	for _, level := range levels {
+		if level.Type != levelType {
+			continue
+		}
+		if levelType == SupportLevel && level.Price > price {
+			continue
+		}
+		if levelType == ResistanceLevel && level.Price < price {
+			continue
+		}
+		if !found || math.Abs(level.Price-price) < math.Abs(nearest.Price-price) {
+			nearest = level
+			found = true
+		}
+	}
+	return nearest, found
+}
+
+// findPivots returns the prices that are the strict highest or lowest of the
+// window prices on each side
+func findPivots(prices []float64, window int) []pivot {
+	var pivots []pivot
+	for i := window; i < len(prices)-window; i++ {
+		high, low := true, true
+		for j := i - window; j <= i+window && (high || low); j++ {
+			if j == i {
+				continue
+			}
+			if prices[j] >= prices[i] {
+				high = false
+			}
+			if prices[j] <= prices[i] {
+				low = false
+			}
+		}
+		if high || low {
+			pivots = append(pivots, pivot{index: i, price: prices[i]})
+		}
+	}
+	return pivots
+}
+
+// clusterPivots groups pivots within tolerance of the running mean of their
+// cluster, sorted by price, into levels
+func clusterPivots(pivots []pivot, tolerance float64, length int) []Level {
+	sorted := append([]pivot(nil), pivots...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].price < sorted[j].price })
+
+	var levels []Level
+	var cluster []pivot
+	sum := 0.0
+	flush := func() {
+		if len(cluster) == 0 {
+			return
+		}
+		level := Level{Price: sum / float64(len(cluster)), Touches: len(cluster)}
+		for _, p := range cluster {
+			// Touches count from one half at the start of the series to
+			// one at its end
+			level.Strength += 0.5 + 0.5*float64(p.index)/float64(length-1)
+			if p.index > level.LastTouch {
+				level.LastTouch = p.index
+			}
+		}
+		levels = append(levels, level)
+		cluster, sum = nil, 0
+	}
+	for _, p := range sorted {
+		if len(cluster) > 0 {
+			mean := sum / float64(len(cluster))
+			if math.Abs(p.price-mean) > tolerance*mean {
+				flush()
+			}
+		}
+		cluster = append(cluster, p)
+		sum += p.price
+	}
+	flush()
+
+	strongest := 0.0
+	for _, level := range levels {
+		strongest = math.Max(strongest, level.Strength)
+	}
+	for i := range levels {
+		levels[i].Strength /= strongest
+	}
+	return levels
+}
@@ -0,0 +1,95 @@
+package monitoring
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// EventSeverity represents the severity level of an event
+type EventSeverity string
+
+const (
+	SeverityInfo     EventSeverity = "info"
+	SeverityWarning  EventSeverity = "warning"
+	SeverityError    EventSeverity = "error"
+	SeverityCritical EventSeverity = "critical"
+)
+
+// Event represents an audit event raised by a trading component
+type Event struct {
+	Type     string                 `json:"type"`
+	Severity EventSeverity          `json:"severity"`
+	Message  string                 `json:"message"`
+	Details  map[string]interface{} `json:"details,omitempty"`
+	// CorrelationID joins the event to the logs of the signal or request
+	// that caused it
+	CorrelationID string    `json:"correlation_id,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+const maxRecordedEvents = 1000
+
+var (
+	TradingEvents = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "trading_events_total",
+			Help: "Total number of trading audit events",
+		},
+		[]string{"type", "severity"},
+	)
+
+	eventsMu sync.RWMutex
+	events   = make([]Event, 0, maxRecordedEvents)
+)
+
+func init() {
+	prometheus.MustRegister(TradingEvents)
+}
+
+// RecordEvent records an audit event, keeping the most recent events in memory
+func RecordEvent(event Event) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	TradingEvents.WithLabelValues(event.Type, string(event.Severity)).Inc()
+
+	eventsMu.Lock()
+	if len(events) >= maxRecordedEvents {
+		events = append(events[:0], events[1:]...)
+	}
+	events = append(events, event)
+	eventsMu.Unlock()
+}
+
+// RecentEvents returns up to limit of the most recent events, oldest first.
+// A limit of zero or less returns all retained events.
+func RecentEvents(limit int) []Event {
+	eventsMu.RLock()
+	defer eventsMu.RUnlock()
+
+	start := 0
+	if limit > 0 && len(events) > limit {
+		start = len(events) - limit
+	}
+
+	result := make([]Event, len(events)-start)
+	copy(result, events[start:])
+	return result
+}
+
+// EventsByType returns all retained events of the given type
+func EventsByType(eventType string) []Event {
+	eventsMu.RLock()
+	defer eventsMu.RUnlock()
+
+	var result []Event
+	for _, event := range events {
+		if event.Type == eventType {
+			result = append(result, event)
+		}
+	}
+	return result
+}
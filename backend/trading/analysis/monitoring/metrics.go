@@ -66,6 +66,56 @@ var (
 		},
 		[]string{"indicator"},
 	)
+
+	OrderAckLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "order_ack_latency_seconds",
+			Help:    "Time for a venue to acknowledge or refuse an order, or the budget of expired orders",
+			Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10, 30},
+		},
+		[]string{"venue", "outcome"},
+	)
+
+	PositionMarginRatio = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "position_margin_ratio",
+			Help: "Equity over notional of the last evaluated open position of a symbol and side",
+		},
+		[]string{"symbol", "side"},
+	)
+
+	PositionLiquidationDistance = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "position_liquidation_distance",
+			Help: "Distance from the price to the liquidation price, as a fraction of the price, of the last evaluated open position of a symbol and side",
+		},
+		[]string{"symbol", "side"},
+	)
+
+	ExecutionDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "execution_duration_seconds",
+			Help:    "Duration of sliced order executions",
+			Buckets: []float64{1, 10, 60, 300, 900, 1800, 3600, 4 * 3600},
+		},
+		[]string{"algo", "symbol"},
+	)
+
+	ExecutionSlippage = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "execution_slippage_bps",
+			Help: "Slippage of the last sliced order execution of a symbol against its arrival price, in basis points",
+		},
+		[]string{"algo", "symbol"},
+	)
+
+	ExecutionErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "execution_errors_total",
+			Help: "Total number of sliced order execution errors by stage",
+		},
+		[]string{"algo", "symbol", "stage"},
+	)
 )
 
 func init() {
@@ -78,5 +128,11 @@ func init() {
 		StorageOperationErrors,
 		BatchProcessingDuration,
 		BatchSize,
+		OrderAckLatency,
+		PositionMarginRatio,
+		PositionLiquidationDistance,
+		ExecutionDuration,
+		ExecutionSlippage,
+		ExecutionErrors,
 	)
 }
@@ -79,3 +79,32 @@ func RecordBatchProcessing(indicatorName string, duration time.Duration, size in
 	BatchProcessingDuration.WithLabelValues(indicatorName).Observe(duration.Seconds())
 	BatchSize.WithLabelValues(indicatorName).Observe(float64(size))
 }
+
+// RecordOrderAck records the time a venue took to acknowledge, refuse or
+// leave an order unanswered
+func RecordOrderAck(venue, outcome string, duration time.Duration) {
+	OrderAckLatency.WithLabelValues(venue, outcome).Observe(duration.Seconds())
+}
+
+// RecordLiquidationRisk records the margin ratio of a position and its
+// distance to liquidation
+func RecordLiquidationRisk(symbol, side string, marginRatio, distance float64) {
+	PositionMarginRatio.WithLabelValues(symbol, side).Set(marginRatio)
+	PositionLiquidationDistance.WithLabelValues(symbol, side).Set(distance)
+}
+
+// RecordExecution records the duration of a sliced order execution
+func RecordExecution(algo, symbol string, duration time.Duration) {
+	ExecutionDuration.WithLabelValues(algo, symbol).Observe(duration.Seconds())
+}
+
+// RecordExecutionSlippage records the slippage of a sliced order execution
+func RecordExecutionSlippage(algo, symbol string, bps float64) {
+	ExecutionSlippage.WithLabelValues(algo, symbol).Set(bps)
+}
+
+// RecordExecutionError records an error of a sliced order execution at a
+// stage, such as placing or collecting fills
+func RecordExecutionError(algo, symbol, stage string) {
+	ExecutionErrors.WithLabelValues(algo, symbol, stage).Inc()
+}
@@ -3,6 +3,7 @@ package streaming
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"sync"
 )
 
@@ -59,8 +60,7 @@ func (p *IndicatorPipeline) ProcessPrice(ctx context.Context, price Price) error
 			}
 		}
 
-		// Log indicator value (in production, you might want to send this to a proper handler)
-		fmt.Printf("Indicator %s: %f\n", value.Name, value.Value)
+		slog.DebugContext(ctx, "indicator updated", "indicator", value.Name, "value", value.Value)
 	}
 
 	return nil
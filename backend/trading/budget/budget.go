@@ -0,0 +1,498 @@
+// Package budget enforces loss budgets on strategies and tokens. Each
+// strategy and each token may lose at most a configured amount per UTC day
+// and per ISO week. A breach disables only that strategy or token, not the
+// whole engine, until the day or week it was breached in is over.
+package budget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/eventbus"
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+	"github.com/devinjacknz/godydxhyber/backend/trading/analysis/monitoring"
+	"github.com/devinjacknz/godydxhyber/backend/trading/strategy"
+)
+
+// EventType is the monitoring event type of budget state changes
+const EventType = "budget"
+
+// Kind is what a budget applies to
+type Kind string
+
+const (
+	KindStrategy Kind = "strategy"
+	KindToken    Kind = "token"
+)
+
+// Limits are the losses allowed to a strategy or token, as positive amounts.
+// A zero limit is disabled.
+type Limits struct {
+	MaxDailyLoss  float64 `json:"max_daily_loss,omitempty"`
+	MaxWeeklyLoss float64 `json:"max_weekly_loss,omitempty"`
+}
+
+// Config contains the loss budgets
+type Config struct {
+	// Strategies are the budgets of the strategies, by name
+	Strategies map[string]Limits
+	// Tokens are the budgets of the tokens, by base asset such as BTC.
+	// Market symbols such as BTC-USD are reduced to their token.
+	Tokens map[string]Limits
+	// CheckInterval is the time between two checks for budgets to re-enable
+	CheckInterval time.Duration
+	// SignalHistory is the number of signals kept per symbol to attribute
+	// closed positions to strategies
+	SignalHistory int
+}
+
+// DefaultConfig returns the default budget configuration, without budgets
+func DefaultConfig() Config {
+	return Config{
+		CheckInterval: time.Minute,
+		SignalHistory: 100,
+	}
+}
+
+// Status is the state of the budget of a strategy or token
+type Status struct {
+	Kind   Kind   `json:"kind"`
+	Name   string `json:"name"`
+	Limits Limits `json:"limits"`
+	// DailyPnL and WeeklyPnL are the net PnL of the current UTC day and ISO
+	// week
+	DailyPnL  float64 `json:"daily_pnl"`
+	WeeklyPnL float64 `json:"weekly_pnl"`
+	Disabled  bool    `json:"disabled"`
+	Reason    string  `json:"reason,omitempty"`
+	// DisabledAt is when the budget was breached and ReenableAt when it
+	// is re-enabled, the end of the day or week it was breached in
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+	ReenableAt *time.Time `json:"reenable_at,omitempty"`
+}
+
+// Strategies enables and disables strategies. strategy.Registry implements
+// it.
+type Strategies interface {
+	Get(ctx context.Context, name string) (*strategy.Strategy, error)
+	SetEnabled(ctx context.Context, name string, enabled bool) (*strategy.Strategy, error)
+}
+
+type key struct {
+	kind Kind
+	name string
+}
+
+// budget is the running PnL and state of one budget
+type budget struct {
+	limits        Limits
+	day, week     string
+	daily, weekly float64
+	disabled      bool
+	reason        string
+	disabledAt    time.Time
+	reenableAt    time.Time
+	// reenable is set when the budget disabled an enabled strategy, which
+	// is then enabled again; strategies disabled by an operator stay so
+	reenable bool
+}
+
+// signal is the strategy that signalled on a symbol
+type signal struct {
+	strategy string
+	time     time.Time
+}
+
+// Manager tracks the PnL of every budget and disables and re-enables the
+// strategies and tokens whose budget is breached
+type Manager struct {
+	strategies Strategies
+	config     Config
+	budgets    map[key]*budget
+	signals    map[string][]signal
+	now        func() time.Time
+	mu         sync.Mutex
+}
+
+// New creates a budget manager disabling strategies through strategies.
+// Zero config fields take their default.
+func New(strategies Strategies, config Config) *Manager {
+	defaults := DefaultConfig()
+	if config.CheckInterval <= 0 {
+		config.CheckInterval = defaults.CheckInterval
+	}
+	if config.SignalHistory <= 0 {
+		config.SignalHistory = defaults.SignalHistory
+	}
+
+	m := &Manager{
+		strategies: strategies,
+		config:     config,
+		budgets:    make(map[key]*budget),
+		signals:    make(map[string][]signal),
+		now:        time.Now,
+	}
+	for name, limits := range config.Strategies {
+		m.budgets[key{KindStrategy, name}] = &budget{limits: limits}
+	}
+	for token, limits := range config.Tokens {
+		m.budgets[key{KindToken, Token(token)}] = &budget{limits: limits}
+	}
+	return m
+}
+
+// Token returns the token of a market symbol, its base asset in upper case:
+// BTC for BTC-USD, SOL for SOL/USDC
+func Token(symbol string) string {
+	if i := strings.IndexAny(symbol, "-/_"); i > 0 {
+		symbol = symbol[:i]
+	}
+	return strings.ToUpper(symbol)
+}
+
+// Subscribe attributes the net PnL of every closed position to its token and
+// to the strategy that last signalled on its symbol before it was opened. It
+// returns a function removing the subscriptions.
+func (m *Manager) Subscribe(bus *eventbus.Bus) func() {
+	unsubscribers := []func(){
+		eventbus.Subscribe(bus, eventbus.SignalGenerated, func(ctx context.Context, e eventbus.SignalEvent) error {
+			m.recordSignal(e)
+			return nil
+		}),
+		eventbus.Subscribe(bus, eventbus.PositionClosed, func(ctx context.Context, e eventbus.PositionClosedEvent) error {
+			return m.RecordPnL(ctx, m.strategyOf(e.Symbol, e.OpenedAt), e.Symbol, e.NetPnL)
+		}, eventbus.WithMode(eventbus.Async)),
+	}
+	return func() {
+		for _, unsubscribe := range unsubscribers {
+			unsubscribe()
+		}
+	}
+}
+
+func (m *Manager) recordSignal(e eventbus.SignalEvent) {
+	if e.Strategy == "" {
+		return
+	}
+	at := e.Timestamp
+	if at.IsZero() {
+		at = m.now()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	signals := append(m.signals[e.Symbol], signal{strategy: e.Strategy, time: at})
+	if len(signals) > m.config.SignalHistory {
+		signals = append(signals[:0:0], signals[len(signals)-m.config.SignalHistory:]...)
+	}
+	m.signals[e.Symbol] = signals
+}
+
+// strategyOf returns the strategy of the last signal on symbol at or before
+// openedAt, or the empty string for positions opened without a signal
+func (m *Manager) strategyOf(symbol string, openedAt time.Time) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	signals := m.signals[symbol]
+	for i := len(signals) - 1; i >= 0; i-- {
+		// Signals are published slightly after the orders they place
+		if !signals[i].time.After(openedAt.Add(time.Second)) {
+			return signals[i].strategy
+		}
+	}
+	return ""
+}
+
+// RecordPnL adds the net PnL of a closed trade to the budgets of a strategy,
+// which may be empty, and of the token of symbol, and disables those that
+// are breached
+func (m *Manager) RecordPnL(ctx context.Context, strategyName, symbol string, pnl float64) error {
+	now := m.now()
+	keys := []key{{KindToken, Token(symbol)}}
+	if strategyName != "" {
+		keys = append(keys, key{KindStrategy, strategyName})
+	}
+
+	var breached []key
+	m.mu.Lock()
+	for _, k := range keys {
+		b, ok := m.budgets[k]
+		if !ok {
+			continue
+		}
+		b.roll(now)
+		b.daily = money.Add(b.daily, pnl)
+		b.weekly = money.Add(b.weekly, pnl)
+
+		reason, until := b.breach(now)
+		// A weekly breach extends a disable for the day
+		if reason == "" || (b.disabled && !until.After(b.reenableAt)) {
+			continue
+		}
+		if !b.disabled {
+			b.disabledAt = now
+		}
+		b.disabled, b.reason, b.reenableAt = true, reason, until
+		breached = append(breached, k)
+	}
+	m.mu.Unlock()
+
+	var errs []error
+	for _, k := range breached {
+		if err := m.disable(ctx, k, now); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// disable disables the strategy of a breached budget, tokens being disabled
+// through SymbolDisabled, and records the breach
+func (m *Manager) disable(ctx context.Context, k key, now time.Time) error {
+	var err error
+	if k.kind == KindStrategy {
+		err = m.disableStrategy(ctx, k)
+	}
+
+	status := m.status(k)
+	slog.WarnContext(ctx, "loss budget breached, disabled", "kind", k.kind, "name", k.name, "reason", status.Reason, "reenable_at", status.ReenableAt)
+	details := statusDetails(status)
+	if err != nil {
+		details["error"] = err.Error()
+	}
+	monitoring.RecordEvent(monitoring.Event{
+		Type:      EventType,
+		Severity:  monitoring.SeverityWarning,
+		Message:   fmt.Sprintf("Loss budget of %s %s breached, disabled", k.kind, k.name),
+		Details:   details,
+		Timestamp: now,
+	})
+	m.recordDisabled()
+	return err
+}
+
+func (m *Manager) disableStrategy(ctx context.Context, k key) error {
+	s, err := m.strategies.Get(ctx, k.name)
+	if err != nil {
+		return fmt.Errorf("failed to disable strategy %s: %w", k.name, err)
+	}
+	if !s.Enabled {
+		return nil
+	}
+	if _, err := m.strategies.SetEnabled(ctx, k.name, false); err != nil {
+		return fmt.Errorf("failed to disable strategy %s: %w", k.name, err)
+	}
+	m.mu.Lock()
+	m.budgets[k].reenable = true
+	m.mu.Unlock()
+	return nil
+}
+
+// Run re-enables budgets on every check interval until ctx is done
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.config.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Check(ctx); err != nil {
+				slog.ErrorContext(ctx, "failed to re-enable loss budgets", "error", err)
+			}
+		}
+	}
+}
+
+// Check re-enables the budgets whose day or week is over, along with the
+// strategies they disabled
+func (m *Manager) Check(ctx context.Context) error {
+	now := m.now()
+	type reenabled struct {
+		key      key
+		strategy bool
+		status   Status
+	}
+	var due []reenabled
+
+	m.mu.Lock()
+	for k, b := range m.budgets {
+		b.roll(now)
+		if !b.disabled || now.Before(b.reenableAt) {
+			continue
+		}
+		status := b.status(k)
+		due = append(due, reenabled{key: k, strategy: b.reenable, status: status})
+		b.disabled, b.reason, b.reenable = false, "", false
+		b.disabledAt, b.reenableAt = time.Time{}, time.Time{}
+	}
+	m.mu.Unlock()
+
+	var errs []error
+	for _, r := range due {
+		var err error
+		if r.strategy {
+			if _, err = m.strategies.SetEnabled(ctx, r.key.name, true); err != nil {
+				err = fmt.Errorf("failed to re-enable strategy %s: %w", r.key.name, err)
+				errs = append(errs, err)
+			}
+		}
+
+		slog.InfoContext(ctx, "loss budget re-enabled", "kind", r.key.kind, "name", r.key.name)
+		details := statusDetails(r.status)
+		if err != nil {
+			details["error"] = err.Error()
+		}
+		monitoring.RecordEvent(monitoring.Event{
+			Type:      EventType,
+			Severity:  monitoring.SeverityInfo,
+			Message:   fmt.Sprintf("Loss budget of %s %s re-enabled", r.key.kind, r.key.name),
+			Details:   details,
+			Timestamp: now,
+		})
+	}
+	if len(due) > 0 {
+		m.recordDisabled()
+	}
+	return errors.Join(errs...)
+}
+
+// SymbolDisabled reports whether the token of symbol is disabled by its
+// budget and why. It implements order.SymbolGate.
+func (m *Manager) SymbolDisabled(symbol string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.budgets[key{KindToken, Token(symbol)}]
+	if !ok || !b.disabled || !m.now().Before(b.reenableAt) {
+		return "", false
+	}
+	return b.reason, true
+}
+
+// List returns the state of every budget, strategies first, sorted by name
+func (m *Manager) List() []Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	statuses := make([]Status, 0, len(m.budgets))
+	for k, b := range m.budgets {
+		b.roll(now)
+		statuses = append(statuses, b.status(k))
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Kind != statuses[j].Kind {
+			return statuses[i].Kind == KindStrategy
+		}
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+func (m *Manager) status(k key) Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.budgets[k].status(k)
+}
+
+// recordDisabled records the number of disabled budgets
+func (m *Manager) recordDisabled() {
+	m.mu.Lock()
+	disabled := 0
+	for _, b := range m.budgets {
+		if b.disabled {
+			disabled++
+		}
+	}
+	m.mu.Unlock()
+	monitoring.RecordIndicatorValue("budgets_disabled", float64(disabled))
+}
+
+// roll starts a new day or week of PnL once the current one is over
+func (b *budget) roll(now time.Time) {
+	if day := dayOf(now); b.day != day {
+		b.day, b.daily = day, 0
+	}
+	if week := weekOf(now); b.week != week {
+		b.week, b.weekly = week, 0
+	}
+}
+
+// breach returns why the budget is breached and until when it stays
+// disabled, or an empty reason
+func (b *budget) breach(now time.Time) (string, time.Time) {
+	if limit := b.limits.MaxWeeklyLoss; limit > 0 && -b.weekly >= limit {
+		return fmt.Sprintf("weekly loss %g reached the %g budget", -b.weekly, limit), nextWeek(now)
+	}
+	if limit := b.limits.MaxDailyLoss; limit > 0 && -b.daily >= limit {
+		return fmt.Sprintf("daily loss %g reached the %g budget", -b.daily, limit), nextDay(now)
+	}
+	return "", time.Time{}
+}
+
+func (b *budget) status(k key) Status {
+	s := Status{
+		Kind:      k.kind,
+		Name:      k.name,
+		Limits:    b.limits,
+		DailyPnL:  b.daily,
+		WeeklyPnL: b.weekly,
+		Disabled:  b.disabled,
+		Reason:    b.reason,
+	}
+	if b.disabled {
+		disabledAt, reenableAt := b.disabledAt, b.reenableAt
+		s.DisabledAt, s.ReenableAt = &disabledAt, &reenableAt
+	}
+	return s
+}
+
+func statusDetails(s Status) map[string]interface{} {
+	details := map[string]interface{}{
+		"kind":            string(s.Kind),
+		"name":            s.Name,
+		"daily_pnl":       s.DailyPnL,
+		"weekly_pnl":      s.WeeklyPnL,
+		"max_daily_loss":  s.Limits.MaxDailyLoss,
+		"max_weekly_loss": s.Limits.MaxWeeklyLoss,
+	}
+	if s.Reason != "" {
+		details["reason"] = s.Reason
+	}
+	if s.ReenableAt != nil {
+		details["reenable_at"] = s.ReenableAt.Format(time.RFC3339)
+	}
+	return details
+}
+
+func dayOf(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+func weekOf(t time.Time) string {
+	year, week := t.UTC().ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+// nextDay returns the next UTC midnight
+func nextDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+}
+
+// nextWeek returns the start of the next ISO week, Monday at UTC midnight
+func nextWeek(t time.Time) time.Time {
+	t = t.UTC()
+	days := (8 - int(t.Weekday())) % 7
+	if days == 0 {
+		days = 7
+	}
+	return time.Date(t.Year(), t.Month(), t.Day()+days, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,164 @@
+package budget
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/devinjacknz/godydxhyber/backend/eventbus"
+	"github.com/devinjacknz/godydxhyber/backend/trading/strategy"
+)
+
+func newRegistry(t *testing.T, enabled map[string]bool) *strategy.Registry {
+	registry := strategy.NewRegistry()
+	for name, on := range enabled {
+		_, err := registry.Register(context.Background(), name, nil)
+		require.NoError(t, err)
+		_, err = registry.SetEnabled(context.Background(), name, on)
+		require.NoError(t, err)
+	}
+	return registry
+}
+
+func TestBudgets(t *testing.T) {
+	ctx := context.Background()
+	// momentum is enabled, grid was disabled by an operator
+	strategies := newRegistry(t, map[string]bool{"momentum": true, "grid": false})
+	m := New(strategies, Config{
+		Strategies: map[string]Limits{
+			"momentum": {MaxDailyLoss: 100},
+			"grid":     {MaxDailyLoss: 50},
+		},
+		Tokens: map[string]Limits{"sol": {MaxDailyLoss: 200, MaxWeeklyLoss: 300}},
+	})
+	// A Wednesday
+	now := time.Date(2024, 3, 6, 10, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	require.NoError(t, m.RecordPnL(ctx, "momentum", "BTC-USD", -60))
+	assert.True(t, strategies.IsEnabled("momentum"))
+	require.NoError(t, m.RecordPnL(ctx, "momentum", "SOL-USD", -50))
+	assert.False(t, strategies.IsEnabled("momentum"), "the strategy is disabled once its daily budget is spent")
+
+	_, disabled := m.SymbolDisabled("SOL-USD")
+	assert.False(t, disabled, "a strategy breach does not disable its tokens")
+	require.NoError(t, m.RecordPnL(ctx, "", "SOL/USDC", -160))
+	reason, disabled := m.SymbolDisabled("SOL-USD")
+	assert.True(t, disabled)
+	assert.Equal(t, "daily loss 210 reached the 200 budget", reason)
+	_, disabled = m.SymbolDisabled("BTC-USD")
+	assert.False(t, disabled, "tokens without a budget are never disabled")
+
+	require.NoError(t, m.RecordPnL(ctx, "grid", "ETH-USD", -75))
+
+	statuses := m.List()
+	require.Len(t, statuses, 3)
+	assert.Equal(t, []string{"grid", "momentum", "SOL"}, []string{statuses[0].Name, statuses[1].Name, statuses[2].Name})
+	momentum := statuses[1]
+	assert.True(t, momentum.Disabled)
+	assert.Equal(t, -110.0, momentum.DailyPnL)
+	assert.Equal(t, now, *momentum.DisabledAt)
+	assert.Equal(t, time.Date(2024, 3, 7, 0, 0, 0, 0, time.UTC), *momentum.ReenableAt)
+
+	// Nothing is re-enabled before the end of the day
+	now = now.Add(13 * time.Hour)
+	require.NoError(t, m.Check(ctx))
+	assert.False(t, strategies.IsEnabled("momentum"))
+
+	now = time.Date(2024, 3, 7, 0, 0, 30, 0, time.UTC)
+	require.NoError(t, m.Check(ctx))
+	assert.True(t, strategies.IsEnabled("momentum"))
+	assert.False(t, strategies.IsEnabled("grid"), "strategies disabled by an operator stay disabled")
+	_, disabled = m.SymbolDisabled("SOL-USD")
+	assert.False(t, disabled)
+	for _, s := range m.List() {
+		assert.False(t, s.Disabled, s.Name)
+		assert.Zero(t, s.DailyPnL, s.Name)
+	}
+
+	// The weekly budget carries over the days of the week
+	require.NoError(t, m.RecordPnL(ctx, "", "SOL-USD", -100))
+	reason, disabled = m.SymbolDisabled("SOL-USD")
+	assert.True(t, disabled)
+	assert.Equal(t, "weekly loss 310 reached the 300 budget", reason)
+	sol := m.List()[2]
+	assert.Equal(t, -310.0, sol.WeeklyPnL)
+	assert.Equal(t, time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC), *sol.ReenableAt)
+
+	now = time.Date(2024, 3, 10, 23, 59, 0, 0, time.UTC)
+	require.NoError(t, m.Check(ctx))
+	_, disabled = m.SymbolDisabled("SOL-USD")
+	assert.True(t, disabled)
+	now = time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)
+	_, disabled = m.SymbolDisabled("SOL-USD")
+	assert.False(t, disabled, "a token is re-enabled on time even between checks")
+}
+
+func TestWeeklyBreachExtendsDailyDisable(t *testing.T) {
+	ctx := context.Background()
+	strategies := newRegistry(t, map[string]bool{"momentum": true})
+	m := New(strategies, Config{Strategies: map[string]Limits{"momentum": {MaxDailyLoss: 100, MaxWeeklyLoss: 150}}})
+	now := time.Date(2024, 3, 8, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	require.NoError(t, m.RecordPnL(ctx, "momentum", "BTC-USD", -120))
+	assert.Equal(t, time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC), *m.List()[0].ReenableAt)
+	require.NoError(t, m.RecordPnL(ctx, "momentum", "BTC-USD", -40))
+	status := m.List()[0]
+	assert.Equal(t, time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC), *status.ReenableAt)
+	assert.Equal(t, now, *status.DisabledAt)
+
+	now = time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, m.Check(ctx))
+	assert.True(t, strategies.IsEnabled("momentum"))
+}
+
+func TestSubscribe(t *testing.T) {
+	ctx := context.Background()
+	strategies := newRegistry(t, map[string]bool{"momentum": true, "grid": true})
+	m := New(strategies, Config{
+		Strategies: map[string]Limits{"momentum": {MaxDailyLoss: 100}, "grid": {MaxDailyLoss: 100}},
+	})
+	bus := eventbus.New(eventbus.DefaultConfig())
+	defer bus.Close()
+	m.Subscribe(bus)
+
+	opened := time.Now().Add(-time.Hour)
+	require.NoError(t, eventbus.Publish(ctx, bus, eventbus.SignalGenerated, eventbus.SignalEvent{Strategy: "momentum", Symbol: "BTC-USD", Timestamp: opened.Add(-time.Minute)}))
+	// Signals after the open are not the rationale of the position
+	require.NoError(t, eventbus.Publish(ctx, bus, eventbus.SignalGenerated, eventbus.SignalEvent{Strategy: "grid", Symbol: "BTC-USD", Timestamp: opened.Add(time.Minute)}))
+	require.NoError(t, eventbus.Publish(ctx, bus, eventbus.PositionClosed, eventbus.PositionClosedEvent{Symbol: "BTC-USD", NetPnL: -150, OpenedAt: opened}))
+
+	assert.Eventually(t, func() bool { return !strategies.IsEnabled("momentum") }, time.Second, time.Millisecond)
+	assert.True(t, strategies.IsEnabled("grid"))
+}
+
+func TestToken(t *testing.T) {
+	for symbol, token := range map[string]string{
+		"BTC-USD":  "BTC",
+		"SOL/USDC": "SOL",
+		"eth_usdt": "ETH",
+		"JUP":      "JUP",
+	} {
+		assert.Equal(t, token, Token(symbol), symbol)
+	}
+}
+
+func TestBudgetRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := New(newRegistry(t, nil), Config{Tokens: map[string]Limits{"BTC": {MaxDailyLoss: 500}}})
+
+	r := gin.New()
+	RegisterRoutes(r.Group("/api/v1"), m)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/budgets", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"kind":"token","name":"BTC"`)
+	assert.Contains(t, w.Body.String(), `"max_daily_loss":500`)
+}
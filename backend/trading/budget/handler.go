@@ -0,0 +1,14 @@
+package budget
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes registers the budget HTTP endpoints
+func RegisterRoutes(r gin.IRouter, m *Manager) {
+	r.GET("/budgets", func(c *gin.Context) {
+		c.JSON(http.StatusOK, m.List())
+	})
+}
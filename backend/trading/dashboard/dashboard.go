@@ -0,0 +1,253 @@
+// Package dashboard assembles the state a web dashboard renders, health,
+// positions, orders, risk and recent events, into one payload.
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/pkg/health"
+	"github.com/devinjacknz/godydxhyber/backend/trading/analysis/monitoring"
+	"github.com/devinjacknz/godydxhyber/backend/trading/order"
+	"github.com/devinjacknz/godydxhyber/backend/trading/position"
+	"github.com/devinjacknz/godydxhyber/backend/trading/risk"
+)
+
+// Section names of a dashboard
+const (
+	SectionHealth    = "health"
+	SectionPositions = "positions"
+	SectionDaily     = "daily"
+	SectionOrders    = "orders"
+	SectionRisk      = "risk"
+	SectionEvents    = "events"
+)
+
+// HealthReporter reports the readiness of the dependencies. *health.Checker
+// implements it.
+type HealthReporter interface {
+	Readiness() health.Report
+}
+
+// Config contains dashboard configuration
+type Config struct {
+	// Budget bounds the assembly of a dashboard. Sections not ready by then
+	// are left out and listed as incomplete.
+	Budget time.Duration
+	// Events is the number of recent events of a dashboard
+	Events int
+}
+
+// DefaultConfig returns default dashboard configuration
+func DefaultConfig() Config {
+	return Config{
+		Budget: 250 * time.Millisecond,
+		Events: 20,
+	}
+}
+
+// PnL sums the profit and loss of the open positions
+type PnL struct {
+	Unrealized float64 `json:"unrealized"`
+	// Realized is the realized profit or loss of the current UTC day
+	Realized float64 `json:"realized"`
+	Funding  float64 `json:"funding"`
+	Fees     float64 `json:"fees"`
+}
+
+// Dashboard is the payload of a dashboard
+type Dashboard struct {
+	Health    *health.Report       `json:"health,omitempty"`
+	Positions []*position.Position `json:"positions"`
+	PnL       PnL                  `json:"pnl"`
+	// Daily is the trading activity of the current UTC day
+	Daily  *risk.DailyState   `json:"daily,omitempty"`
+	Orders []*order.Order     `json:"orders"`
+	Risk   *risk.RiskMetrics  `json:"risk,omitempty"`
+	Events []monitoring.Event `json:"events"`
+	// Incomplete are the sections that failed or missed the budget, with
+	// their error
+	Incomplete  map[string]string `json:"incomplete,omitempty"`
+	GeneratedAt time.Time         `json:"generated_at"`
+	Duration    time.Duration     `json:"duration"`
+}
+
+// Service assembles dashboards
+type Service struct {
+	health    HealthReporter
+	positions *position.Manager
+	orders    order.OrderManager
+	risk      risk.RiskManager
+	events    func(limit int) []monitoring.Event
+	config    Config
+}
+
+// NewService creates a dashboard service over an account's managers. The
+// health reporter may be nil.
+func NewService(health HealthReporter, positions *position.Manager, orders order.OrderManager, riskManager risk.RiskManager, config Config) *Service {
+	defaults := DefaultConfig()
+	if config.Budget <= 0 {
+		config.Budget = defaults.Budget
+	}
+	if config.Events <= 0 {
+		config.Events = defaults.Events
+	}
+
+	return &Service{
+		health:    health,
+		positions: positions,
+		orders:    orders,
+		risk:      riskManager,
+		events:    monitoring.RecentEvents,
+		config:    config,
+	}
+}
+
+// section is the outcome of one section, applied to the dashboard by the
+// assembling goroutine only
+type section struct {
+	name  string
+	apply func(d *Dashboard)
+	err   error
+}
+
+// Get assembles a dashboard with up to events recent events, the
+// configured number when zero. Sections are fetched concurrently; the
+// dashboard is returned when all are done or the budget is spent, whichever
+// comes first.
+func (s *Service) Get(ctx context.Context, events int) *Dashboard {
+	start := time.Now()
+	if events <= 0 {
+		events = s.config.Events
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.config.Budget)
+	defer cancel()
+
+	fetchers := map[string]func(ctx context.Context) (func(d *Dashboard), error){
+		SectionPositions: s.fetchPositions,
+		SectionDaily:     s.fetchDaily,
+		SectionOrders:    s.fetchOrders,
+		SectionRisk:      s.fetchRisk,
+		SectionEvents: func(ctx context.Context) (func(d *Dashboard), error) {
+			recent := s.events(events)
+			return func(d *Dashboard) { d.Events = recent }, nil
+		},
+	}
+	if s.health != nil {
+		fetchers[SectionHealth] = func(ctx context.Context) (func(d *Dashboard), error) {
+			report := s.health.Readiness()
+			return func(d *Dashboard) { d.Health = &report }, nil
+		}
+	}
+
+	// Buffered so that sections finishing after the budget do not block
+	results := make(chan section, len(fetchers))
+	for name, fetch := range fetchers {
+		go func(name string, fetch func(ctx context.Context) (func(d *Dashboard), error)) {
+			apply, err := fetch(ctx)
+			results <- section{name: name, apply: apply, err: err}
+		}(name, fetch)
+	}
+
+	d := &Dashboard{
+		Positions:  []*position.Position{},
+		Orders:     []*order.Order{},
+		Events:     []monitoring.Event{},
+		Incomplete: make(map[string]string),
+	}
+	pending := make(map[string]bool, len(fetchers))
+	for name := range fetchers {
+		pending[name] = true
+	}
+	for len(pending) > 0 {
+		select {
+		case r := <-results:
+			delete(pending, r.name)
+			if r.err != nil {
+				d.Incomplete[r.name] = r.err.Error()
+				continue
+			}
+			r.apply(d)
+		case <-ctx.Done():
+			for name := range pending {
+				d.Incomplete[name] = fmt.Sprintf("%s: %v", ErrBudgetExceeded, ctx.Err())
+			}
+			pending = nil
+		}
+	}
+
+	if d.Daily != nil {
+		d.PnL.Realized = d.Daily.RealizedPnL
+	}
+	if len(d.Incomplete) == 0 {
+		d.Incomplete = nil
+	}
+	d.GeneratedAt = time.Now()
+	d.Duration = d.GeneratedAt.Sub(start)
+	return d
+}
+
+func (s *Service) fetchPositions(ctx context.Context) (func(d *Dashboard), error) {
+	open := position.Open
+	positions, err := s.positions.ListPositions(ctx, position.PositionFilter{Status: &open})
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]*position.Position, 0, len(positions))
+	var pnl PnL
+	for _, p := range positions {
+		snapshot := p.Snapshot()
+		snapshots = append(snapshots, snapshot)
+		pnl.Unrealized += snapshot.UnrealizedPnL.Float64()
+		pnl.Funding += snapshot.FundingAccrual.Float64()
+		pnl.Fees += snapshot.Fees.Float64()
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].OpenTime.Before(snapshots[j].OpenTime) })
+	return func(d *Dashboard) {
+		d.Positions = snapshots
+		d.PnL.Unrealized, d.PnL.Funding, d.PnL.Fees = pnl.Unrealized, pnl.Funding, pnl.Fees
+	}, nil
+}
+
+func (s *Service) fetchDaily(ctx context.Context) (func(d *Dashboard), error) {
+	now := time.Now()
+	states, err := s.risk.GetDailyStates(ctx, now, now)
+	if err != nil {
+		return nil, err
+	}
+	daily := &risk.DailyState{Day: now.UTC().Format("2006-01-02")}
+	if len(states) > 0 {
+		daily = states[len(states)-1]
+	}
+	return func(d *Dashboard) { d.Daily = daily }, nil
+}
+
+// fetchOrders lists the orders still working on the exchange
+func (s *Service) fetchOrders(ctx context.Context) (func(d *Dashboard), error) {
+	orders, err := s.orders.ListOrders(ctx, order.OrderFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	active := make([]*order.Order, 0, len(orders))
+	for _, o := range orders {
+		snapshot := o.Snapshot()
+		switch snapshot.Status {
+		case order.Created, order.Pending, order.PartiallyFilled:
+			active = append(active, snapshot)
+		}
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].CreatedAt.Before(active[j].CreatedAt) })
+	return func(d *Dashboard) { d.Orders = active }, nil
+}
+
+func (s *Service) fetchRisk(ctx context.Context) (func(d *Dashboard), error) {
+	metrics, err := s.risk.GetRiskMetrics(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return func(d *Dashboard) { d.Risk = metrics }, nil
+}
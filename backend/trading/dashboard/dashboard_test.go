@@ -0,0 +1,120 @@
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/devinjacknz/godydxhyber/backend/pkg/health"
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+	"github.com/devinjacknz/godydxhyber/backend/trading/analysis/monitoring"
+	"github.com/devinjacknz/godydxhyber/backend/trading/order"
+	"github.com/devinjacknz/godydxhyber/backend/trading/position"
+	"github.com/devinjacknz/godydxhyber/backend/trading/risk"
+)
+
+type staticHealth health.Report
+
+func (h staticHealth) Readiness() health.Report {
+	return health.Report(h)
+}
+
+// slowRisk holds its risk metrics until the request is done
+type slowRisk struct {
+	risk.RiskManager
+}
+
+func (r slowRisk) GetRiskMetrics(ctx context.Context) (*risk.RiskMetrics, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestDashboard(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	positions := position.NewManager()
+	pos, err := positions.OpenPosition(ctx, position.OpenPositionParams{Symbol: "BTC-USD", Side: position.Long, Size: money.NewFromInt(2), EntryPrice: money.NewFromInt(100), Leverage: 1})
+	require.NoError(t, err)
+	mark := money.FromFloat(110.0)
+	require.NoError(t, positions.UpdatePosition(ctx, pos.ID, position.UpdatePositionParams{CurrentPrice: &mark}))
+	closed, err := positions.OpenPosition(ctx, position.OpenPositionParams{Symbol: "ETH-USD", Side: position.Short, Size: money.NewFromInt(1), EntryPrice: money.NewFromInt(50), Leverage: 1})
+	require.NoError(t, err)
+	require.NoError(t, positions.ClosePosition(ctx, closed.ID, money.NewFromInt(45)))
+
+	orders := order.NewOrderManager()
+	price := money.FromFloat(95.0)
+	resting, err := orders.CreateOrder(ctx, order.CreateOrderParams{Symbol: "BTC-USD", Type: order.Limit, Side: order.Buy, Price: &price, Size: money.NewFromInt(1)})
+	require.NoError(t, err)
+	cancelled, err := orders.CreateOrder(ctx, order.CreateOrderParams{Symbol: "BTC-USD", Type: order.Limit, Side: order.Buy, Price: &price, Size: money.NewFromInt(1)})
+	require.NoError(t, err)
+	require.NoError(t, orders.CancelOrder(ctx, cancelled.ID))
+
+	riskManager := risk.NewRiskManager()
+	require.NoError(t, riskManager.RecordRealizedPnL(ctx, 5))
+
+	s := NewService(staticHealth{Status: health.StatusOK}, positions, orders, riskManager, Config{})
+	s.events = func(limit int) []monitoring.Event {
+		events := make([]monitoring.Event, limit)
+		for i := range events {
+			events[i] = monitoring.Event{Type: "test", Message: "event"}
+		}
+		return events
+	}
+
+	r := gin.New()
+	RegisterRoutes(r.Group("/api/v1"), s)
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	t.Run("All sections", func(t *testing.T) {
+		w := get("/api/v1/dashboard?events=3")
+		require.Equal(t, http.StatusOK, w.Code)
+		var d struct {
+			Health     *health.Report       `json:"health"`
+			Positions  []*position.Position `json:"positions"`
+			PnL        PnL                  `json:"pnl"`
+			Daily      *risk.DailyState     `json:"daily"`
+			Orders     []json.RawMessage    `json:"orders"`
+			Risk       *risk.RiskMetrics    `json:"risk"`
+			Events     []monitoring.Event   `json:"events"`
+			Incomplete map[string]string    `json:"incomplete"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &d))
+		assert.Equal(t, health.StatusOK, d.Health.Status)
+		require.Len(t, d.Positions, 1, "open positions only")
+		assert.Equal(t, pos.ID, d.Positions[0].ID)
+		assert.Equal(t, 20.0, d.PnL.Unrealized)
+		assert.Equal(t, 5.0, d.PnL.Realized)
+		assert.Equal(t, 5.0, d.Daily.RealizedPnL)
+		assert.Len(t, d.Orders, 1, "active orders only")
+		assert.Contains(t, string(d.Orders[0]), resting.ID)
+		assert.NotNil(t, d.Risk)
+		assert.Len(t, d.Events, 3)
+		assert.Empty(t, d.Incomplete)
+	})
+
+	t.Run("Sections over budget are left out", func(t *testing.T) {
+		slow := NewService(nil, positions, orders, slowRisk{riskManager}, Config{Budget: 20 * time.Millisecond})
+		start := time.Now()
+		d := slow.Get(ctx, 0)
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Nil(t, d.Risk)
+		assert.Contains(t, d.Incomplete[SectionRisk], ErrBudgetExceeded.Error())
+		assert.Len(t, d.Positions, 1)
+		assert.Nil(t, d.Health)
+		assert.NotContains(t, d.Incomplete, SectionHealth)
+	})
+
+	assert.Equal(t, http.StatusBadRequest, get("/api/v1/dashboard?events=none").Code)
+}
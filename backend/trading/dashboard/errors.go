@@ -0,0 +1,7 @@
+package dashboard
+
+import "errors"
+
+// ErrBudgetExceeded is reported for the sections not ready within the
+// latency budget
+var ErrBudgetExceeded = errors.New("latency budget exceeded")
@@ -0,0 +1,29 @@
+package dashboard
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes registers the dashboard HTTP endpoint
+func RegisterRoutes(r gin.IRouter, s *Service) {
+	r.GET("/dashboard", s.handleGet)
+}
+
+// handleGet returns the dashboard, with the number of recent events given
+// by the events query parameter
+func (s *Service) handleGet(c *gin.Context) {
+	events := 0
+	if v := c.Query("events"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "events must be a positive integer"})
+			return
+		}
+		events = n
+	}
+
+	c.JSON(http.StatusOK, s.Get(c.Request.Context(), events))
+}
@@ -0,0 +1,17 @@
+package execution
+
+import "errors"
+
+var (
+	// ErrInvalidParentOrder is returned when the order to be sliced is invalid
+	ErrInvalidParentOrder = errors.New("invalid parent order")
+
+	// ErrInvalidSlices is returned when the number of child orders is invalid
+	ErrInvalidSlices = errors.New("invalid number of slices")
+
+	// ErrInvalidWindow is returned when the execution window is invalid
+	ErrInvalidWindow = errors.New("invalid execution window")
+
+	// ErrInvalidParticipationRate is returned when the participation rate is outside (0, 1]
+	ErrInvalidParticipationRate = errors.New("invalid participation rate")
+)
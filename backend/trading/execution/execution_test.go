@@ -0,0 +1,160 @@
+package execution
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+	"github.com/devinjacknz/godydxhyber/backend/trading/order"
+	"github.com/stretchr/testify/assert"
+)
+
+// fillingOrderManager fills every order immediately after it is created
+type fillingOrderManager struct {
+	order.OrderManager
+	fillRatio float64
+}
+
+func (m *fillingOrderManager) CreateOrder(ctx context.Context, params order.CreateOrderParams) (*order.Order, error) {
+	created, err := m.OrderManager.CreateOrder(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.UpdateOrderStatus(ctx, created.ID, order.Pending); err != nil {
+		return nil, err
+	}
+	if err := m.UpdateFilledSize(ctx, created.ID, params.Size.Mul(money.FromFloat(m.fillRatio))); err != nil {
+		return nil, err
+	}
+	// Order IDs are time based; keep consecutive children distinct
+	time.Sleep(2 * time.Millisecond)
+	return created, nil
+}
+
+// rampingMarket returns a price that rises by step on every call
+type rampingMarket struct {
+	price     float64
+	step      float64
+	volume24h float64
+	mu        sync.Mutex
+}
+
+func (m *rampingMarket) GetMarketSnapshot(ctx context.Context, symbol string) (*MarketSnapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snapshot := &MarketSnapshot{Price: m.price, Volume24h: m.volume24h}
+	m.price += m.step
+	return snapshot, nil
+}
+
+func TestTWAPExecutor(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Invalid configuration", func(t *testing.T) {
+		_, err := NewTWAPExecutor(order.NewOrderManager(), &rampingMarket{}, TWAPConfig{Slices: 0, Window: time.Second})
+		assert.ErrorIs(t, err, ErrInvalidSlices)
+
+		_, err = NewTWAPExecutor(order.NewOrderManager(), &rampingMarket{}, TWAPConfig{Slices: 2})
+		assert.ErrorIs(t, err, ErrInvalidWindow)
+
+		_, err = NewTWAPExecutor(order.NewOrderManager(), &rampingMarket{}, TWAPConfig{Slices: 2, Window: time.Second, ParticipationRate: 1.5})
+		assert.ErrorIs(t, err, ErrInvalidParticipationRate)
+	})
+
+	t.Run("Splits parent order evenly and reports slippage", func(t *testing.T) {
+		manager := &fillingOrderManager{OrderManager: order.NewOrderManager(), fillRatio: 1}
+		market := &rampingMarket{price: 100, step: 1}
+		executor, err := NewTWAPExecutor(manager, market, TWAPConfig{Slices: 4, Window: 40 * time.Millisecond, Jitter: 0.5})
+		assert.NoError(t, err)
+
+		report, err := executor.Execute(ctx, ParentOrder{Symbol: "BTC-USD", Side: order.Buy, Size: 8, ClientOrderID: "twap"})
+		assert.NoError(t, err)
+
+		assert.Len(t, report.Children, 4)
+		for _, child := range report.Children {
+			assert.InDelta(t, 2.0, child.Size, 1e-9)
+			assert.False(t, child.Capped)
+		}
+		assert.InDelta(t, 8.0, report.FilledSize, 1e-9)
+		assert.InDelta(t, 0.0, report.Unexecuted, 1e-9)
+		assert.Equal(t, 100.0, report.ArrivalPrice)
+		// Fills at 100, 101, 102 and 103
+		assert.InDelta(t, 101.5, report.AverageFillPrice, 1e-9)
+		assert.InDelta(t, 150.0, report.SlippageBps, 1e-6)
+		assert.GreaterOrEqual(t, report.CompletedAt.Sub(report.StartedAt), 40*time.Millisecond)
+
+		orders, err := manager.ListOrders(ctx, order.OrderFilter{Symbol: "BTC-USD"})
+		assert.NoError(t, err)
+		assert.Len(t, orders, 4)
+	})
+
+	t.Run("Sell slippage is adverse when price falls", func(t *testing.T) {
+		manager := &fillingOrderManager{OrderManager: order.NewOrderManager(), fillRatio: 1}
+		market := &rampingMarket{price: 100, step: -1}
+		executor, err := NewTWAPExecutor(manager, market, TWAPConfig{Slices: 2, Window: 20 * time.Millisecond})
+		assert.NoError(t, err)
+
+		report, err := executor.Execute(ctx, ParentOrder{Symbol: "ETH-USD", Side: order.Sell, Size: 2, ArrivalPrice: 100})
+		assert.NoError(t, err)
+		assert.InDelta(t, 99.5, report.AverageFillPrice, 1e-9)
+		assert.InDelta(t, 50.0, report.SlippageBps, 1e-6)
+	})
+
+	t.Run("Participation cap carries size forward", func(t *testing.T) {
+		manager := &fillingOrderManager{OrderManager: order.NewOrderManager(), fillRatio: 0.5}
+		// Each 10ms slice expects volume24h * 10ms / 24h of traded volume
+		interval := 10 * time.Millisecond
+		volume24h := 24 * time.Hour.Hours() / interval.Hours() * 10
+		market := &rampingMarket{price: 50, volume24h: volume24h}
+		executor, err := NewTWAPExecutor(manager, market, TWAPConfig{Slices: 3, Window: 3 * interval, ParticipationRate: 0.1})
+		assert.NoError(t, err)
+
+		report, err := executor.Execute(ctx, ParentOrder{Symbol: "SOL-USD", Side: order.Buy, Size: 6})
+		assert.NoError(t, err)
+
+		// The cap is 1 per slice, so only 3 of 6 can be placed
+		assert.Len(t, report.Children, 3)
+		for _, child := range report.Children {
+			assert.InDelta(t, 1.0, child.Size, 1e-6)
+			assert.True(t, child.Capped)
+		}
+		assert.InDelta(t, 3.0, report.PlacedSize, 1e-6)
+		assert.InDelta(t, 3.0, report.Unexecuted, 1e-6)
+		assert.InDelta(t, 1.5, report.FilledSize, 1e-6)
+		assert.InDelta(t, 0.0, report.SlippageBps, 1e-9)
+	})
+
+	t.Run("Stops when trading is halted", func(t *testing.T) {
+		manager := &fillingOrderManager{OrderManager: order.NewOrderManager(), fillRatio: 1}
+		executor, err := NewTWAPExecutor(manager, &rampingMarket{price: 10}, TWAPConfig{Slices: 2, Window: 20 * time.Millisecond})
+		assert.NoError(t, err)
+
+		manager.Halt("maintenance")
+		report, err := executor.Execute(ctx, ParentOrder{Symbol: "BTC-USD", Side: order.Buy, Size: 1})
+		assert.ErrorIs(t, err, order.ErrTradingHalted)
+		assert.Empty(t, report.Children)
+		assert.Equal(t, 1.0, report.Unexecuted)
+	})
+
+	t.Run("Jitter keeps children within half the fraction of their slot", func(t *testing.T) {
+		executor, err := NewTWAPExecutor(order.NewOrderManager(), &rampingMarket{}, TWAPConfig{Slices: 50, Window: time.Hour, Jitter: 0.2})
+		assert.NoError(t, err)
+
+		start := time.Now()
+		interval := time.Minute
+		for i, at := range executor.schedule(start, interval) {
+			slot := start.Add(time.Duration(i) * interval)
+			assert.LessOrEqual(t, at.Sub(slot).Abs(), interval/10)
+		}
+	})
+
+	t.Run("Invalid parent order", func(t *testing.T) {
+		executor, err := NewTWAPExecutor(order.NewOrderManager(), &rampingMarket{}, DefaultTWAPConfig())
+		assert.NoError(t, err)
+
+		_, err = executor.Execute(ctx, ParentOrder{Symbol: "BTC-USD", Side: order.Buy})
+		assert.ErrorIs(t, err, ErrInvalidParentOrder)
+	})
+}
@@ -0,0 +1,311 @@
+package execution
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+	"github.com/devinjacknz/godydxhyber/backend/trading/analysis/monitoring"
+	"github.com/devinjacknz/godydxhyber/backend/trading/order"
+)
+
+// algoTWAP labels the execution metrics of the TWAP executor
+const algoTWAP = "twap"
+
+// MarketSnapshot contains the market data used to schedule child orders
+type MarketSnapshot struct {
+	Price     float64
+	Volume24h float64
+}
+
+// MarketDataSource provides current price and 24h volume for a symbol
+type MarketDataSource interface {
+	GetMarketSnapshot(ctx context.Context, symbol string) (*MarketSnapshot, error)
+}
+
+// ParentOrder is a large order, typically derived from a trade signal, that
+// is executed as a series of smaller child orders
+type ParentOrder struct {
+	Symbol string
+	Side   order.OrderSide
+	Size   float64
+	// ArrivalPrice is the price when the decision to trade was made. If zero
+	// the market price at the start of execution is used.
+	ArrivalPrice float64
+	// ClientOrderID prefixes the client order IDs of the child orders
+	ClientOrderID string
+}
+
+// TWAPConfig contains DCA/TWAP execution configuration
+type TWAPConfig struct {
+	// Slices is the number of child orders
+	Slices int
+	// Window is the time over which the child orders are spread
+	Window time.Duration
+	// Jitter randomizes each child's placement time within a span of this
+	// fraction of the slice interval centred on its slot, so 0.2 moves a
+	// child up to a tenth of the interval earlier or later, making the
+	// schedule harder to detect
+	Jitter float64
+	// ParticipationRate caps each child at this fraction of the volume
+	// expected to trade during one slice interval, derived from Volume24h.
+	// Zero disables the cap.
+	ParticipationRate float64
+}
+
+// DefaultTWAPConfig returns default TWAP configuration
+func DefaultTWAPConfig() TWAPConfig {
+	return TWAPConfig{
+		Slices:            10,
+		Window:            time.Hour,
+		Jitter:            0.2,
+		ParticipationRate: 0.05,
+	}
+}
+
+// ChildOrder describes one placed child order
+type ChildOrder struct {
+	OrderID    string    `json:"order_id"`
+	Size       float64   `json:"size"`
+	Price      float64   `json:"price"`
+	FilledSize float64   `json:"filled_size"`
+	Capped     bool      `json:"capped"`
+	PlacedAt   time.Time `json:"placed_at"`
+}
+
+// ExecutionReport summarizes a TWAP execution
+type ExecutionReport struct {
+	Symbol        string          `json:"symbol"`
+	Side          order.OrderSide `json:"side"`
+	RequestedSize float64         `json:"requested_size"`
+	PlacedSize    float64         `json:"placed_size"`
+	FilledSize    float64         `json:"filled_size"`
+	// Unexecuted is the size left over because of the participation cap or
+	// an early stop
+	Unexecuted       float64 `json:"unexecuted"`
+	ArrivalPrice     float64 `json:"arrival_price"`
+	AverageFillPrice float64 `json:"average_fill_price"`
+	// SlippageBps is the average fill price relative to the arrival price.
+	// Positive values are adverse for the order side.
+	SlippageBps float64      `json:"slippage_bps"`
+	Children    []ChildOrder `json:"children"`
+	StartedAt   time.Time    `json:"started_at"`
+	CompletedAt time.Time    `json:"completed_at"`
+}
+
+// TWAPExecutor splits a parent order into child orders placed evenly over a
+// time window through the order manager
+type TWAPExecutor struct {
+	orders order.OrderManager
+	market MarketDataSource
+	config TWAPConfig
+	rand   *rand.Rand
+	mu     sync.Mutex
+}
+
+// NewTWAPExecutor creates a new TWAP executor
+func NewTWAPExecutor(orders order.OrderManager, market MarketDataSource, config TWAPConfig) (*TWAPExecutor, error) {
+	if config.Slices < 1 {
+		return nil, ErrInvalidSlices
+	}
+	if config.Window <= 0 {
+		return nil, ErrInvalidWindow
+	}
+	if config.ParticipationRate < 0 || config.ParticipationRate > 1 {
+		return nil, ErrInvalidParticipationRate
+	}
+	if config.Jitter < 0 {
+		config.Jitter = 0
+	}
+	if config.Jitter > 1 {
+		config.Jitter = 1
+	}
+
+	return &TWAPExecutor{
+		orders: orders,
+		market: market,
+		config: config,
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}, nil
+}
+
+// Execute places the child orders and blocks until the window has elapsed.
+// Size a child cannot place because of the participation cap is carried
+// forward to the next child. If execution stops early, the report so far is
+// returned together with the error.
+func (e *TWAPExecutor) Execute(ctx context.Context, parent ParentOrder) (*ExecutionReport, error) {
+	start := time.Now()
+	defer func() {
+		monitoring.RecordExecution(algoTWAP, parent.Symbol, time.Since(start))
+	}()
+
+	if parent.Symbol == "" || parent.Size <= 0 || (parent.Side != order.Buy && parent.Side != order.Sell) {
+		return nil, ErrInvalidParentOrder
+	}
+
+	report := &ExecutionReport{
+		Symbol:        parent.Symbol,
+		Side:          parent.Side,
+		RequestedSize: parent.Size,
+		ArrivalPrice:  parent.ArrivalPrice,
+		StartedAt:     start,
+	}
+
+	interval := e.config.Window / time.Duration(e.config.Slices)
+	schedule := e.schedule(start, interval)
+	remaining := parent.Size
+
+	var err error
+	for i, at := range schedule {
+		if remaining <= 0 {
+			break
+		}
+		if err = sleepUntil(ctx, at); err != nil {
+			break
+		}
+
+		var snapshot *MarketSnapshot
+		snapshot, err = e.market.GetMarketSnapshot(ctx, parent.Symbol)
+		if err != nil {
+			err = fmt.Errorf("failed to get market data for %s: %w", parent.Symbol, err)
+			break
+		}
+		if report.ArrivalPrice <= 0 {
+			report.ArrivalPrice = snapshot.Price
+		}
+
+		// Spread what is left evenly over the remaining slices
+		size := remaining / float64(len(schedule)-i)
+		child := ChildOrder{Price: snapshot.Price}
+		if limit := e.participationLimit(snapshot, interval); limit > 0 && size > limit {
+			size = limit
+			child.Capped = true
+		}
+		if size <= 0 {
+			continue
+		}
+
+		var placed *order.Order
+		placed, err = e.orders.CreateOrder(ctx, order.CreateOrderParams{
+			Symbol:        parent.Symbol,
+			Type:          order.Market,
+			Side:          parent.Side,
+			Size:          money.FromFloat(size),
+			ClientOrderID: childClientOrderID(parent.ClientOrderID, i),
+		})
+		if err != nil {
+			err = fmt.Errorf("failed to place child order %d: %w", i, err)
+			break
+		}
+
+		child.OrderID = placed.ID
+		child.Size = size
+		child.PlacedAt = time.Now()
+		report.Children = append(report.Children, child)
+		report.PlacedSize += size
+		remaining -= size
+	}
+
+	if err == nil {
+		// Let the last child run to the end of the window before collecting fills
+		err = sleepUntil(ctx, start.Add(e.config.Window))
+	}
+
+	e.collectFills(ctx, report)
+	report.Unexecuted = parent.Size - report.PlacedSize
+	report.CompletedAt = time.Now()
+
+	if report.FilledSize > 0 {
+		monitoring.RecordExecutionSlippage(algoTWAP, parent.Symbol, report.SlippageBps)
+	}
+	if err != nil {
+		monitoring.RecordExecutionError(algoTWAP, parent.Symbol, "execute")
+		return report, err
+	}
+	return report, nil
+}
+
+// schedule returns the placement time of each child order
+func (e *TWAPExecutor) schedule(start time.Time, interval time.Duration) []time.Time {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	times := make([]time.Time, e.config.Slices)
+	for i := range times {
+		offset := float64(i)
+		if i > 0 {
+			offset += e.config.Jitter * (e.rand.Float64() - 0.5)
+		}
+		times[i] = start.Add(time.Duration(offset * float64(interval)))
+	}
+	return times
+}
+
+// participationLimit returns the largest child size allowed by the
+// participation rate, or zero if there is no limit
+func (e *TWAPExecutor) participationLimit(snapshot *MarketSnapshot, interval time.Duration) float64 {
+	if e.config.ParticipationRate <= 0 || snapshot.Volume24h <= 0 {
+		return 0
+	}
+	expected := snapshot.Volume24h * interval.Hours() / 24
+	return expected * e.config.ParticipationRate
+}
+
+// collectFills reads the fill state of each child and computes the average
+// fill price and slippage. Market children are assumed to fill at the price
+// observed when they were placed.
+func (e *TWAPExecutor) collectFills(ctx context.Context, report *ExecutionReport) {
+	notional := 0.0
+	for i := range report.Children {
+		child := &report.Children[i]
+		placed, err := e.orders.GetOrder(ctx, child.OrderID)
+		if err != nil {
+			monitoring.RecordExecutionError(algoTWAP, report.Symbol, "collect_fills")
+			continue
+		}
+
+		child.FilledSize = placed.FilledSize.Float64()
+		report.FilledSize += child.FilledSize
+		notional += child.FilledSize * child.Price
+	}
+
+	if report.FilledSize <= 0 {
+		return
+	}
+
+	report.AverageFillPrice = notional / report.FilledSize
+	if report.ArrivalPrice > 0 {
+		slippage := (report.AverageFillPrice - report.ArrivalPrice) / report.ArrivalPrice * 10000
+		if report.Side == order.Sell {
+			slippage = -slippage
+		}
+		report.SlippageBps = slippage
+	}
+}
+
+func childClientOrderID(parentID string, index int) string {
+	if parentID == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s-%d", parentID, index)
+}
+
+func sleepUntil(ctx context.Context, at time.Time) error {
+	wait := time.Until(at)
+	if wait <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
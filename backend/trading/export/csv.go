@@ -0,0 +1,106 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// rowWriter writes the rows of an export in chunks
+type rowWriter interface {
+	Write(rows []Row) error
+	// Close completes the file, without closing the underlying writer
+	Close() error
+}
+
+// flusher is implemented by HTTP response writers
+type flusher interface {
+	Flush()
+}
+
+// csvWriter writes a header line and one line per row. Missing values are
+// empty fields.
+type csvWriter struct {
+	w       io.Writer
+	csv     *csv.Writer
+	columns []Column
+	header  bool
+}
+
+func newCSVWriter(w io.Writer, columns []Column) *csvWriter {
+	return &csvWriter{w: w, csv: csv.NewWriter(w), columns: columns}
+}
+
+// Write writes the header, before the first chunk, and the rows, then
+// flushes them
+func (w *csvWriter) Write(rows []Row) error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	record := make([]string, len(w.columns))
+	for _, row := range rows {
+		for i, v := range row {
+			record[i] = formatValue(v)
+		}
+		if err := w.csv.Write(record); err != nil {
+			return fmt.Errorf("failed to write csv: %w", err)
+		}
+	}
+	return w.flush()
+}
+
+// Close writes the header of an export without rows
+func (w *csvWriter) Close() error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	return w.flush()
+}
+
+func (w *csvWriter) writeHeader() error {
+	if w.header {
+		return nil
+	}
+	w.header = true
+	names := make([]string, len(w.columns))
+	for i, c := range w.columns {
+		names[i] = c.Name
+	}
+	if err := w.csv.Write(names); err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+	return nil
+}
+
+func (w *csvWriter) flush() error {
+	w.csv.Flush()
+	if err := w.csv.Error(); err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+	if f, ok := w.w.(flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.UTC().Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,417 @@
+// Package export writes the trades, positions, daily stats and risk checks
+// of a time range to CSV or Parquet, for tax reporting and analysis outside
+// the engine. Rows are written in chunks, flushed as they are written, so
+// large ranges stream to the client.
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+	"github.com/devinjacknz/godydxhyber/backend/trading/order"
+	"github.com/devinjacknz/godydxhyber/backend/trading/position"
+	"github.com/devinjacknz/godydxhyber/backend/trading/risk"
+)
+
+var (
+	// ErrUnknownDataset is returned for a dataset other than trades,
+	// positions, daily_stats or risk_checks
+	ErrUnknownDataset = errors.New("unknown dataset")
+	// ErrUnknownFormat is returned for a format other than csv or parquet
+	ErrUnknownFormat = errors.New("unknown format")
+	// ErrUnknownColumn is returned for a selected column the dataset does
+	// not have
+	ErrUnknownColumn = errors.New("unknown column")
+	// ErrInvalidRange is returned when the range does not end after it starts
+	ErrInvalidRange = errors.New("invalid time range")
+)
+
+// Dataset names the records exported
+type Dataset string
+
+const (
+	// Trades are the fills of orders, by fill time
+	Trades Dataset = "trades"
+	// Positions are the positions open at some time of the range
+	Positions Dataset = "positions"
+	// DailyStats are the daily realized profit or loss and trade counts
+	DailyStats Dataset = "daily_stats"
+	// RiskChecks are the risk checks run in the range
+	RiskChecks Dataset = "risk_checks"
+)
+
+// Format is the file format of an export
+type Format string
+
+const (
+	CSV     Format = "csv"
+	Parquet Format = "parquet"
+)
+
+// ContentType returns the media type of the format
+func (f Format) ContentType() string {
+	if f == Parquet {
+		return "application/vnd.apache.parquet"
+	}
+	return "text/csv"
+}
+
+// ColumnType is the type of the values of a column
+type ColumnType int
+
+const (
+	String ColumnType = iota + 1
+	Float
+	Int
+	// Time values are written as RFC 3339 in CSV and as millisecond
+	// timestamps in Parquet
+	Time
+)
+
+// Column is a column of a dataset
+type Column struct {
+	Name string
+	Type ColumnType
+}
+
+// Row holds the values of a row by column: a string, float64, int64 or
+// time.Time matching the column type, or nil for a missing value
+type Row []interface{}
+
+// OrderSource lists orders. order.OrderManager implements it.
+type OrderSource interface {
+	ListOrders(ctx context.Context, filter order.OrderFilter) ([]*order.Order, error)
+}
+
+// PositionSource lists positions. *position.Manager implements it.
+type PositionSource interface {
+	ListPositions(ctx context.Context, filter position.PositionFilter) ([]*position.Position, error)
+}
+
+// RiskSource returns the daily states and risk checks of a risk manager.
+// risk.RiskManager implements it.
+type RiskSource interface {
+	GetDailyStates(ctx context.Context, from, to time.Time) ([]*risk.DailyState, error)
+	GetRiskHistory(ctx context.Context, filter risk.RiskHistoryFilter) ([]*risk.RiskCheck, error)
+}
+
+// Sources are the managers the datasets are read from
+type Sources struct {
+	Orders    OrderSource
+	Positions PositionSource
+	Risk      RiskSource
+}
+
+// Config configures an exporter
+type Config struct {
+	// ChunkRows is the number of rows written at once, and the size of the
+	// Parquet row groups
+	ChunkRows int
+}
+
+// DefaultConfig returns the default exporter configuration
+func DefaultConfig() Config {
+	return Config{ChunkRows: 10000}
+}
+
+// Request selects the records of an export
+type Request struct {
+	Dataset Dataset
+	Format  Format
+	// From and To bound the range, To excluded. To defaults to now.
+	From time.Time
+	To   time.Time
+	// Columns are the columns written, in order, all when empty
+	Columns []string
+}
+
+// Exporter writes the datasets of one set of managers
+type Exporter struct {
+	sources Sources
+	config  Config
+	now     func() time.Time
+}
+
+// NewExporter creates an exporter reading from the given managers
+func NewExporter(sources Sources, config Config) *Exporter {
+	if config.ChunkRows <= 0 {
+		config.ChunkRows = DefaultConfig().ChunkRows
+	}
+	return &Exporter{sources: sources, config: config, now: time.Now}
+}
+
+// table is the columns of a dataset and the reader of its rows, with every
+// column, ordered by time
+type table struct {
+	columns []Column
+	rows    func(e *Exporter, ctx context.Context, from, to time.Time) ([]Row, error)
+}
+
+var tables = map[Dataset]table{
+	Trades: {
+		columns: []Column{
+			{"time", Time}, {"account_id", String}, {"order_id", String}, {"client_order_id", String},
+			{"symbol", String}, {"side", String}, {"price", Float}, {"size", Float}, {"status", String},
+		},
+		rows: (*Exporter).tradeRows,
+	},
+	Positions: {
+		columns: []Column{
+			{"id", String}, {"account_id", String}, {"symbol", String}, {"side", String}, {"status", String},
+			{"entry_price", Float}, {"current_price", Float}, {"size", Float}, {"leverage", Float}, {"margin", Float},
+			{"unrealized_pnl", Float}, {"realized_pnl", Float}, {"funding", Float}, {"fees", Float},
+			{"stop_loss", Float}, {"take_profit", Float}, {"open_time", Time}, {"last_update_time", Time},
+		},
+		rows: (*Exporter).positionRows,
+	},
+	DailyStats: {
+		columns: []Column{
+			{"day", String}, {"realized_pnl", Float}, {"commissions", Float}, {"net_pnl", Float},
+			{"trades", Int}, {"updated_at", Time},
+		},
+		rows: (*Exporter).dailyRows,
+	},
+	RiskChecks: {
+		columns: []Column{
+			{"time", Time}, {"account_id", String}, {"id", String}, {"type", String}, {"level", String},
+			{"status", String}, {"symbol", String}, {"value", Float}, {"threshold", Float},
+			{"description", String}, {"correlation_id", String},
+		},
+		rows: (*Exporter).riskCheckRows,
+	},
+}
+
+// Columns returns the columns of a dataset
+func Columns(dataset Dataset) ([]Column, error) {
+	t, ok := tables[dataset]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownDataset, dataset)
+	}
+	return append([]Column(nil), t.columns...), nil
+}
+
+// Export writes the records selected by the request to w and returns the
+// number of rows written. Invalid requests and failing sources return an
+// error before anything is written.
+func (e *Exporter) Export(ctx context.Context, w io.Writer, req Request) (int, error) {
+	t, columns, indexes, err := e.prepare(req)
+	if err != nil {
+		return 0, err
+	}
+	to := req.To
+	if to.IsZero() {
+		to = e.now()
+	}
+	if !req.From.Before(to) {
+		return 0, ErrInvalidRange
+	}
+
+	rows, err := t.rows(e, ctx, req.From, to)
+	if err != nil {
+		return 0, err
+	}
+
+	var out rowWriter
+	if req.Format == Parquet {
+		out = newParquetWriter(w, columns)
+	} else {
+		out = newCSVWriter(w, columns)
+	}
+	chunk := make([]Row, 0, e.config.ChunkRows)
+	for start := 0; start < len(rows); start += e.config.ChunkRows {
+		if err := ctx.Err(); err != nil {
+			return start, err
+		}
+		end := start + e.config.ChunkRows
+		if end > len(rows) {
+			end = len(rows)
+		}
+		chunk = chunk[:0]
+		for _, row := range rows[start:end] {
+			selected := make(Row, len(indexes))
+			for i, index := range indexes {
+				selected[i] = row[index]
+			}
+			chunk = append(chunk, selected)
+		}
+		if err := out.Write(chunk); err != nil {
+			return start, err
+		}
+	}
+	return len(rows), out.Close()
+}
+
+// Validate checks the dataset, format and columns of a request
+func (e *Exporter) Validate(req Request) error {
+	_, _, _, err := e.prepare(req)
+	return err
+}
+
+// prepare returns the table of a request and its selected columns with
+// their indexes in the rows of the table
+func (e *Exporter) prepare(req Request) (table, []Column, []int, error) {
+	t, ok := tables[req.Dataset]
+	if !ok {
+		return table{}, nil, nil, fmt.Errorf("%w: %s", ErrUnknownDataset, req.Dataset)
+	}
+	if req.Format != CSV && req.Format != Parquet {
+		return table{}, nil, nil, fmt.Errorf("%w: %s", ErrUnknownFormat, req.Format)
+	}
+	if len(req.Columns) == 0 {
+		indexes := make([]int, len(t.columns))
+		for i := range indexes {
+			indexes[i] = i
+		}
+		return t, t.columns, indexes, nil
+	}
+
+	columns := make([]Column, 0, len(req.Columns))
+	indexes := make([]int, 0, len(req.Columns))
+	for _, name := range req.Columns {
+		index := -1
+		for i, c := range t.columns {
+			if c.Name == strings.TrimSpace(name) {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return table{}, nil, nil, fmt.Errorf("%w: %s", ErrUnknownColumn, name)
+		}
+		columns = append(columns, t.columns[index])
+		indexes = append(indexes, index)
+	}
+	return t, columns, indexes, nil
+}
+
+// inRange reports whether t is within [from, to)
+func inRange(t, from, to time.Time) bool {
+	return !t.Before(from) && t.Before(to)
+}
+
+// optional returns the value of an optional price
+func optional(v *money.Decimal) interface{} {
+	if v == nil {
+		return nil
+	}
+	return v.Float64()
+}
+
+func (e *Exporter) tradeRows(ctx context.Context, from, to time.Time) ([]Row, error) {
+	if e.sources.Orders == nil {
+		return nil, nil
+	}
+	orders, err := e.sources.Orders.ListOrders(ctx, order.OrderFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list orders: %w", err)
+	}
+
+	var trades []order.Trade
+	for _, o := range orders {
+		snapshot := o.Snapshot()
+		if snapshot.FilledSize.Sign() <= 0 {
+			continue
+		}
+		trade := order.NewTrade(snapshot, snapshot.FilledSize)
+		if inRange(trade.Time, from, to) {
+			trades = append(trades, trade)
+		}
+	}
+	sort.Slice(trades, func(i, j int) bool { return trades[i].Time.Before(trades[j].Time) })
+
+	rows := make([]Row, 0, len(trades))
+	for _, t := range trades {
+		rows = append(rows, Row{
+			t.Time, t.AccountID, t.OrderID, t.ClientOrderID,
+			t.Symbol, t.Side.String(), optional(t.Price), t.Size.Float64(), t.Status.String(),
+		})
+	}
+	return rows, nil
+}
+
+func (e *Exporter) positionRows(ctx context.Context, from, to time.Time) ([]Row, error) {
+	if e.sources.Positions == nil {
+		return nil, nil
+	}
+	positions, err := e.sources.Positions.ListPositions(ctx, position.PositionFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list positions: %w", err)
+	}
+
+	// Positions open at some time of the range: opened before its end, and
+	// still open or closed after its start
+	var selected []*position.Position
+	for _, p := range positions {
+		snapshot := p.Snapshot()
+		if !snapshot.OpenTime.Before(to) {
+			continue
+		}
+		if snapshot.Status != position.Open && snapshot.LastUpdateTime.Before(from) {
+			continue
+		}
+		selected = append(selected, snapshot)
+	}
+	sort.Slice(selected, func(i, j int) bool { return selected[i].OpenTime.Before(selected[j].OpenTime) })
+
+	rows := make([]Row, 0, len(selected))
+	for _, p := range selected {
+		rows = append(rows, Row{
+			p.ID, p.AccountID, p.Symbol, p.Side.String(), p.Status.String(),
+			p.EntryPrice.Float64(), p.CurrentPrice.Float64(), p.Size.Float64(), p.Leverage, p.Margin.Float64(),
+			p.UnrealizedPnL.Float64(), p.RealizedPnL.Float64(), p.FundingAccrual.Float64(), p.Fees.Float64(),
+			optional(p.StopLoss), optional(p.TakeProfit), p.OpenTime, p.LastUpdateTime,
+		})
+	}
+	return rows, nil
+}
+
+func (e *Exporter) dailyRows(ctx context.Context, from, to time.Time) ([]Row, error) {
+	if e.sources.Risk == nil {
+		return nil, nil
+	}
+	// The day of to is only included when the range reaches into it
+	states, err := e.sources.Risk.GetDailyStates(ctx, from, to.Add(-time.Nanosecond))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load daily stats: %w", err)
+	}
+
+	rows := make([]Row, 0, len(states))
+	for _, s := range states {
+		rows = append(rows, Row{s.Day, s.RealizedPnL, s.Commissions, s.NetPnL(), int64(s.Trades), s.UpdatedAt})
+	}
+	return rows, nil
+}
+
+func (e *Exporter) riskCheckRows(ctx context.Context, from, to time.Time) ([]Row, error) {
+	if e.sources.Risk == nil {
+		return nil, nil
+	}
+	checks, err := e.sources.Risk.GetRiskHistory(ctx, risk.RiskHistoryFilter{StartTime: &from, EndTime: &to})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load risk checks: %w", err)
+	}
+
+	var selected []*risk.RiskCheck
+	for _, c := range checks {
+		if inRange(c.CreatedAt, from, to) {
+			selected = append(selected, c)
+		}
+	}
+	sort.SliceStable(selected, func(i, j int) bool { return selected[i].CreatedAt.Before(selected[j].CreatedAt) })
+
+	rows := make([]Row, 0, len(selected))
+	for _, c := range selected {
+		rows = append(rows, Row{
+			c.CreatedAt, c.AccountID, c.ID, c.Type.String(), c.Level.String(),
+			c.Status.String(), c.Symbol, c.Value, c.Threshold,
+			c.Description, c.CorrelationID,
+		})
+	}
+	return rows, nil
+}
@@ -0,0 +1,287 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/csv"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+	"github.com/devinjacknz/godydxhyber/backend/trading/order"
+	"github.com/devinjacknz/godydxhyber/backend/trading/position"
+	"github.com/devinjacknz/godydxhyber/backend/trading/risk"
+)
+
+var start = time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+type fakeOrders struct {
+	orders []*order.Order
+	err    error
+}
+
+func (s *fakeOrders) ListOrders(ctx context.Context, filter order.OrderFilter) ([]*order.Order, error) {
+	return s.orders, s.err
+}
+
+type fakePositions struct {
+	positions []*position.Position
+}
+
+func (s *fakePositions) ListPositions(ctx context.Context, filter position.PositionFilter) ([]*position.Position, error) {
+	return s.positions, nil
+}
+
+type fakeRisk struct {
+	states []*risk.DailyState
+	checks []*risk.RiskCheck
+}
+
+func (s *fakeRisk) GetDailyStates(ctx context.Context, from, to time.Time) ([]*risk.DailyState, error) {
+	return s.states, nil
+}
+
+func (s *fakeRisk) GetRiskHistory(ctx context.Context, filter risk.RiskHistoryFilter) ([]*risk.RiskCheck, error) {
+	return s.checks, nil
+}
+
+func newTestExporter(chunk int) (*Exporter, *fakeOrders) {
+	price := money.FromFloat(100.0)
+	stop := money.FromFloat(95.0)
+	orders := &fakeOrders{orders: []*order.Order{
+		{ID: "o2", Symbol: "ETH-USD", Side: order.Sell, Size: money.NewFromInt(2), FilledSize: money.NewFromInt(2), Status: order.Filled, UpdatedAt: start.Add(2 * time.Hour)},
+		{ID: "o1", Symbol: "BTC-USD", Side: order.Buy, Price: &price, Size: money.NewFromInt(1), FilledSize: money.FromFloat(0.5), Status: order.PartiallyFilled, UpdatedAt: start.Add(time.Hour), AccountID: "main"},
+		{ID: "o3", Symbol: "BTC-USD", Side: order.Buy, Size: money.NewFromInt(1), Status: order.Pending, UpdatedAt: start.Add(time.Hour)},
+		{ID: "o4", Symbol: "BTC-USD", Side: order.Buy, Size: money.NewFromInt(1), FilledSize: money.NewFromInt(1), Status: order.Filled, UpdatedAt: start.Add(48 * time.Hour)},
+	}}
+	positions := &fakePositions{positions: []*position.Position{
+		{ID: "p1", Symbol: "BTC-USD", Side: position.Long, Status: position.Open, EntryPrice: money.NewFromInt(100), Size: money.NewFromInt(1), StopLoss: &stop, OpenTime: start.Add(-time.Hour)},
+		{ID: "p2", Symbol: "ETH-USD", Side: position.Short, Status: position.Closed, OpenTime: start.Add(-48 * time.Hour), LastUpdateTime: start.Add(-24 * time.Hour)},
+	}}
+	riskSource := &fakeRisk{
+		states: []*risk.DailyState{{Day: "2024-05-01", RealizedPnL: -12.5, Commissions: 1.5, Trades: 3, UpdatedAt: start.Add(time.Hour)}},
+		checks: []*risk.RiskCheck{
+			{ID: "c2", Type: risk.DailyLossRisk, Level: risk.High, Status: risk.Violation, Value: 2, Threshold: 1, CreatedAt: start.Add(2 * time.Hour)},
+			{ID: "c1", Type: risk.PositionRisk, Level: risk.Low, Status: risk.Pass, Symbol: "BTC-USD", CreatedAt: start.Add(time.Hour)},
+		},
+	}
+	e := NewExporter(Sources{Orders: orders, Positions: positions, Risk: riskSource}, Config{ChunkRows: chunk})
+	return e, orders
+}
+
+func exportCSV(t *testing.T, e *Exporter, req Request) [][]string {
+	var buf bytes.Buffer
+	req.Format = CSV
+	_, err := e.Export(context.Background(), &buf, req)
+	require.NoError(t, err)
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	return records
+}
+
+func TestExportCSV(t *testing.T) {
+	e, _ := newTestExporter(1)
+	day := Request{From: start, To: start.Add(24 * time.Hour)}
+
+	day.Dataset = Trades
+	records := exportCSV(t, e, day)
+	require.Len(t, records, 3)
+	assert.Equal(t, []string{"time", "account_id", "order_id", "client_order_id", "symbol", "side", "price", "size", "status"}, records[0])
+	assert.Equal(t, []string{"2024-05-01T01:00:00Z", "main", "o1", "", "BTC-USD", "buy", "100", "0.5", "partially_filled"}, records[1])
+	assert.Equal(t, "o2", records[2][2])
+	assert.Equal(t, "", records[2][6])
+
+	day.Dataset = Positions
+	day.Columns = []string{"id", "status", "stop_loss", "take_profit"}
+	records = exportCSV(t, e, day)
+	assert.Equal(t, [][]string{{"id", "status", "stop_loss", "take_profit"}, {"p1", "open", "95", ""}}, records)
+
+	day.Dataset = DailyStats
+	day.Columns = nil
+	records = exportCSV(t, e, day)
+	assert.Equal(t, []string{"2024-05-01", "-12.5", "1.5", "-14", "3", "2024-05-01T01:00:00Z"}, records[1])
+
+	day.Dataset = RiskChecks
+	day.Columns = []string{"id", "type", "status"}
+	records = exportCSV(t, e, day)
+	assert.Equal(t, [][]string{{"id", "type", "status"}, {"c1", "position", "pass"}, {"c2", "daily_loss", "violation"}}, records)
+
+	// A range without records still has the header
+	records = exportCSV(t, e, Request{Dataset: Trades, From: start.Add(-time.Hour), To: start})
+	assert.Len(t, records, 1)
+}
+
+func TestExportErrors(t *testing.T) {
+	e, orders := newTestExporter(0)
+	var buf bytes.Buffer
+	ctx := context.Background()
+
+	_, err := e.Export(ctx, &buf, Request{Dataset: "fills", Format: CSV, From: start})
+	assert.ErrorIs(t, err, ErrUnknownDataset)
+	_, err = e.Export(ctx, &buf, Request{Dataset: Trades, Format: "xlsx", From: start})
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+	_, err = e.Export(ctx, &buf, Request{Dataset: Trades, Format: CSV, From: start, Columns: []string{"pnl"}})
+	assert.ErrorIs(t, err, ErrUnknownColumn)
+	_, err = e.Export(ctx, &buf, Request{Dataset: Trades, Format: CSV, From: start, To: start})
+	assert.ErrorIs(t, err, ErrInvalidRange)
+
+	orders.err = errors.New("store down")
+	_, err = e.Export(ctx, &buf, Request{Dataset: Trades, Format: CSV, From: start})
+	assert.Error(t, err)
+	assert.Zero(t, buf.Len())
+}
+
+func TestExportParquet(t *testing.T) {
+	e, _ := newTestExporter(1)
+	var buf bytes.Buffer
+	n, err := e.Export(context.Background(), &buf, Request{
+		Dataset: Trades, Format: Parquet, From: start, To: start.Add(24 * time.Hour),
+		Columns: []string{"time", "order_id", "price", "size"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	file := buf.Bytes()
+	require.Equal(t, "PAR1", string(file[:4]))
+	require.Equal(t, "PAR1", string(file[len(file)-4:]))
+	length := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+	r := &thriftReader{buf: file[len(file)-8-length : len(file)-8]}
+	meta := r.readStruct()
+
+	assert.Equal(t, int64(2), meta[3])
+	schema := meta[2].([]interface{})
+	require.Len(t, schema, 5)
+	assert.Equal(t, "order_id", string(schema[2].(map[int16]interface{})[4].([]byte)))
+
+	// One row group per chunk
+	groups := meta[4].([]interface{})
+	require.Len(t, groups, 2)
+	var orderIDs []string
+	var prices []interface{}
+	var times []int64
+	for _, g := range groups {
+		columns := g.(map[int16]interface{})[1].([]interface{})
+		require.Len(t, columns, 4)
+		for i, c := range columns {
+			chunk := c.(map[int16]interface{})[3].(map[int16]interface{})
+			page := &thriftReader{buf: file[chunk[9].(int64):]}
+			header := page.readStruct()
+			data := page.buf[:header[3].(int64)]
+			levels := int(binary.LittleEndian.Uint32(data))
+			defined := data[4+levels-1]&1 == 1
+			values := data[4+levels:]
+			switch i {
+			case 0:
+				times = append(times, int64(binary.LittleEndian.Uint64(values)))
+			case 1:
+				orderIDs = append(orderIDs, string(values[4:4+binary.LittleEndian.Uint32(values)]))
+			case 2:
+				if defined {
+					prices = append(prices, math.Float64frombits(binary.LittleEndian.Uint64(values)))
+				} else {
+					prices = append(prices, nil)
+				}
+			}
+		}
+	}
+	assert.Equal(t, []string{"o1", "o2"}, orderIDs)
+	assert.Equal(t, []interface{}{100.0, nil}, prices)
+	assert.Equal(t, []int64{start.Add(time.Hour).UnixMilli(), start.Add(2 * time.Hour).UnixMilli()}, times)
+}
+
+func TestHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	e, _ := newTestExporter(0)
+	r := gin.New()
+	RegisterRoutes(r, e)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/export/risk_checks?from=2024-05-01T00:00:00Z&to=2024-05-02T00:00:00Z&columns=id", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+	assert.Equal(t, "attachment; filename=risk_checks.csv", w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "id\nc1\nc2\n", w.Body.String())
+
+	for _, target := range []string{
+		"/export/risk_checks",
+		"/export/fills?from=2024-05-01T00:00:00Z",
+		"/export/trades?from=2024-05-01T00:00:00Z&format=xlsx",
+		"/export/trades?from=2024-05-01T00:00:00Z&to=2024-04-01T00:00:00Z",
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, target)
+		assert.Contains(t, w.Header().Get("Content-Type"), "application/json", target)
+	}
+}
+
+// thriftReader decodes Thrift compact protocol structs into maps of field
+// IDs to int64, []byte, []interface{} or nested maps
+type thriftReader struct {
+	buf []byte
+}
+
+func (r *thriftReader) varint() uint64 {
+	v, n := binary.Uvarint(r.buf)
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *thriftReader) zigzag() int64 {
+	v := r.varint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) value(typ byte) interface{} {
+	switch typ {
+	case thriftI32, thriftI64:
+		return r.zigzag()
+	case thriftBinary:
+		n := r.varint()
+		v := r.buf[:n]
+		r.buf = r.buf[n:]
+		return v
+	case thriftList:
+		header := r.buf[0]
+		r.buf = r.buf[1:]
+		size := int(header >> 4)
+		if size == 15 {
+			size = int(r.varint())
+		}
+		list := make([]interface{}, size)
+		for i := range list {
+			list[i] = r.value(header & 0x0f)
+		}
+		return list
+	case thriftStruct:
+		return r.readStruct()
+	}
+	panic("unexpected thrift type")
+}
+
+func (r *thriftReader) readStruct() map[int16]interface{} {
+	fields := make(map[int16]interface{})
+	var id int16
+	for {
+		header := r.buf[0]
+		r.buf = r.buf[1:]
+		if header == 0 {
+			return fields
+		}
+		if delta := int16(header >> 4); delta > 0 {
+			id += delta
+		} else {
+			id = int16(r.zigzag())
+		}
+		fields[id] = r.value(header & 0x0f)
+	}
+}
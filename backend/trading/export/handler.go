@@ -0,0 +1,85 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Resolver selects the exporter a request reads from, such as the exporter
+// of the account in the path
+type Resolver func(c *gin.Context) (*Exporter, error)
+
+// RegisterRoutes registers the export HTTP endpoint
+func RegisterRoutes(r gin.IRouter, e *Exporter) {
+	RegisterRoutesWithResolver(r, func(c *gin.Context) (*Exporter, error) {
+		return e, nil
+	})
+}
+
+// RegisterRoutesWithResolver registers the export HTTP endpoint, reading
+// from the exporter returned by resolve for each request:
+//
+//	GET /export/:dataset?format=csv|parquet&from=&to=&columns=a,b
+//
+// from is required and to defaults to now, both RFC 3339 timestamps.
+func RegisterRoutesWithResolver(r gin.IRouter, resolve Resolver) {
+	r.GET("/export/:dataset", func(c *gin.Context) {
+		e, err := resolve(c)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		handleExport(e, c)
+	})
+}
+
+func handleExport(e *Exporter, c *gin.Context) {
+	req := Request{
+		Dataset: Dataset(c.Param("dataset")),
+		Format:  Format(c.DefaultQuery("format", string(CSV))),
+	}
+	if v := c.Query("columns"); v != "" {
+		req.Columns = strings.Split(v, ",")
+	}
+	var err error
+	if req.From, err = time.Parse(time.RFC3339Nano, c.Query("from")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be an RFC 3339 timestamp"})
+		return
+	}
+	if v := c.Query("to"); v != "" {
+		if req.To, err = time.Parse(time.RFC3339Nano, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be an RFC 3339 timestamp"})
+			return
+		}
+	}
+	if err := e.Validate(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", req.Format.ContentType())
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.%s", req.Dataset, req.Format))
+	_, err = e.Export(c.Request.Context(), c.Writer, req)
+	if err == nil {
+		return
+	}
+	if c.Writer.Written() {
+		// The rows already sent cannot be taken back; the truncated body
+		// is the error
+		c.Error(err)
+		return
+	}
+	c.Writer.Header().Del("Content-Type")
+	c.Writer.Header().Del("Content-Disposition")
+	switch {
+	case errors.Is(err, ErrInvalidRange):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,347 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Parquet physical types, converted types, encodings and field repetitions
+// of the parquet.thrift file format definition
+const (
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	convertedUTF8            = 0
+	convertedTimestampMillis = 9
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	repetitionOptional = 1
+
+	pageTypeData = 0
+)
+
+var parquetMagic = []byte("PAR1")
+
+// parquetColumnChunk is the metadata of a written column chunk
+type parquetColumnChunk struct {
+	offset int64
+	size   int64
+	values int64
+}
+
+// parquetRowGroup is the metadata of a written row group
+type parquetRowGroup struct {
+	columns []parquetColumnChunk
+	size    int64
+	rows    int64
+}
+
+// parquetWriter writes a Parquet file of optional, flat columns. Every
+// chunk is a row group holding one uncompressed, plain encoded data page per
+// column, so only one chunk is held in memory.
+type parquetWriter struct {
+	w         io.Writer
+	columns   []Column
+	offset    int64
+	rowGroups []parquetRowGroup
+	err       error
+}
+
+func newParquetWriter(w io.Writer, columns []Column) *parquetWriter {
+	return &parquetWriter{w: w, columns: columns}
+}
+
+// Write writes the rows as a row group
+func (w *parquetWriter) Write(rows []Row) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	if w.offset == 0 {
+		w.write(parquetMagic)
+	}
+
+	group := parquetRowGroup{rows: int64(len(rows))}
+	for i, column := range w.columns {
+		page := encodePage(column, rows, i)
+		chunk := parquetColumnChunk{offset: w.offset, values: int64(len(rows))}
+		w.write(page)
+		chunk.size = w.offset - chunk.offset
+		group.columns = append(group.columns, chunk)
+		group.size += chunk.size
+	}
+	if w.err != nil {
+		return fmt.Errorf("failed to write parquet: %w", w.err)
+	}
+	w.rowGroups = append(w.rowGroups, group)
+	if f, ok := w.w.(flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// Close writes the file metadata
+func (w *parquetWriter) Close() error {
+	if w.offset == 0 {
+		w.write(parquetMagic)
+	}
+	footer := w.footer()
+	length := make([]byte, 4)
+	binary.LittleEndian.PutUint32(length, uint32(len(footer)))
+	w.write(footer)
+	w.write(length)
+	w.write(parquetMagic)
+	if w.err != nil {
+		return fmt.Errorf("failed to write parquet: %w", w.err)
+	}
+	return nil
+}
+
+func (w *parquetWriter) write(p []byte) {
+	if w.err != nil {
+		return
+	}
+	n, err := w.w.Write(p)
+	w.offset += int64(n)
+	w.err = err
+}
+
+// footer encodes the FileMetaData of the file
+func (w *parquetWriter) footer() []byte {
+	var rows int64
+	for _, g := range w.rowGroups {
+		rows += g.rows
+	}
+
+	t := &thriftWriter{}
+	t.i32(1, 1) // version
+	t.listBegin(2, thriftStruct, len(w.columns)+1)
+	t.elemBegin()
+	t.binary(4, []byte("schema"))
+	t.i32(5, int32(len(w.columns)))
+	t.structEnd()
+	for _, c := range w.columns {
+		physical, converted := parquetType(c.Type)
+		t.elemBegin()
+		t.i32(1, physical)
+		t.i32(3, repetitionOptional)
+		t.binary(4, []byte(c.Name))
+		if converted >= 0 {
+			t.i32(6, converted)
+		}
+		t.structEnd()
+	}
+	t.i64(3, rows)
+	t.listBegin(4, thriftStruct, len(w.rowGroups))
+	for _, g := range w.rowGroups {
+		t.elemBegin()
+		t.listBegin(1, thriftStruct, len(g.columns))
+		for i, chunk := range g.columns {
+			physical, _ := parquetType(w.columns[i].Type)
+			t.elemBegin()
+			t.i64(2, chunk.offset)
+			t.structBegin(3)
+			t.i32(1, physical)
+			t.listBegin(2, thriftI32, 2)
+			t.varint(zigzag(encodingPlain))
+			t.varint(zigzag(encodingRLE))
+			t.listBegin(3, thriftBinary, 1)
+			t.rawBinary([]byte(w.columns[i].Name))
+			t.i32(4, 0) // uncompressed
+			t.i64(5, chunk.values)
+			t.i64(6, chunk.size)
+			t.i64(7, chunk.size)
+			t.i64(9, chunk.offset)
+			t.structEnd()
+			t.structEnd()
+		}
+		t.i64(2, g.size)
+		t.i64(3, g.rows)
+		t.structEnd()
+	}
+	t.binary(6, []byte("gosol export"))
+	t.stop()
+	return t.buf.Bytes()
+}
+
+// parquetType returns the physical and converted type of a column type,
+// with a converted type of -1 for none
+func parquetType(columnType ColumnType) (int32, int32) {
+	switch columnType {
+	case Float:
+		return parquetDouble, -1
+	case Int:
+		return parquetInt64, -1
+	case Time:
+		return parquetInt64, convertedTimestampMillis
+	default:
+		return parquetByteArray, convertedUTF8
+	}
+}
+
+// encodePage encodes the values of column i of the rows as a data page with
+// its header. Definition levels mark the missing values, which have no
+// value in the page.
+func encodePage(column Column, rows []Row, i int) []byte {
+	defined := make([]bool, len(rows))
+	var values bytes.Buffer
+	var scratch [8]byte
+	for r, row := range rows {
+		v := row[i]
+		if t, ok := v.(time.Time); ok && t.IsZero() {
+			v = nil
+		}
+		if v == nil {
+			continue
+		}
+		defined[r] = true
+		switch column.Type {
+		case Float:
+			binary.LittleEndian.PutUint64(scratch[:], math.Float64bits(v.(float64)))
+			values.Write(scratch[:])
+		case Int:
+			binary.LittleEndian.PutUint64(scratch[:], uint64(v.(int64)))
+			values.Write(scratch[:])
+		case Time:
+			binary.LittleEndian.PutUint64(scratch[:], uint64(v.(time.Time).UnixMilli()))
+			values.Write(scratch[:])
+		default:
+			s := formatValue(v)
+			binary.LittleEndian.PutUint32(scratch[:4], uint32(len(s)))
+			values.Write(scratch[:4])
+			values.WriteString(s)
+		}
+	}
+
+	levels := encodeLevels(defined)
+	var data bytes.Buffer
+	binary.LittleEndian.PutUint32(scratch[:4], uint32(len(levels)))
+	data.Write(scratch[:4])
+	data.Write(levels)
+	data.Write(values.Bytes())
+
+	t := &thriftWriter{}
+	t.i32(1, pageTypeData)
+	t.i32(2, int32(data.Len()))
+	t.i32(3, int32(data.Len()))
+	t.structBegin(5)
+	t.i32(1, int32(len(rows)))
+	t.i32(2, encodingPlain)
+	t.i32(3, encodingRLE)
+	t.i32(4, encodingRLE)
+	t.structEnd()
+	t.stop()
+	return append(t.buf.Bytes(), data.Bytes()...)
+}
+
+// encodeLevels encodes definition levels of bit width one as a single
+// bit-packed run of the RLE/bit-packing hybrid encoding
+func encodeLevels(defined []bool) []byte {
+	groups := (len(defined) + 7) / 8
+	t := &thriftWriter{}
+	t.varint(uint64(groups)<<1 | 1)
+	packed := make([]byte, groups)
+	for i, d := range defined {
+		if d {
+			packed[i/8] |= 1 << (i % 8)
+		}
+	}
+	return append(t.buf.Bytes(), packed...)
+}
+
+// Thrift compact protocol types
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes structs with the Thrift compact protocol
+type thriftWriter struct {
+	buf bytes.Buffer
+	// last holds the last field ID of each open struct
+	last []int16
+	id   int16
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	if delta := id - t.id; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.varint(zigzag(int64(id)))
+	}
+	t.id = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.varint(zigzag(int64(v)))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(zigzag(v))
+}
+
+func (t *thriftWriter) binary(id int16, v []byte) {
+	t.field(id, thriftBinary)
+	t.rawBinary(v)
+}
+
+func (t *thriftWriter) rawBinary(v []byte) {
+	t.varint(uint64(len(v)))
+	t.buf.Write(v)
+}
+
+// structBegin opens a struct field
+func (t *thriftWriter) structBegin(id int16) {
+	t.field(id, thriftStruct)
+	t.elemBegin()
+}
+
+// elemBegin opens a struct list element
+func (t *thriftWriter) elemBegin() {
+	t.last = append(t.last, t.id)
+	t.id = 0
+}
+
+// structEnd closes the innermost struct
+func (t *thriftWriter) structEnd() {
+	t.stop()
+	t.id = t.last[len(t.last)-1]
+	t.last = t.last[:len(t.last)-1]
+}
+
+func (t *thriftWriter) stop() {
+	t.buf.WriteByte(0)
+}
+
+// listBegin opens a list field of size elements, written next
+func (t *thriftWriter) listBegin(id int16, elemType byte, size int) {
+	t.field(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elemType)
+		return
+	}
+	t.buf.WriteByte(0xf0 | elemType)
+	t.varint(uint64(size))
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	t.buf.Write(b[:n])
+}
+
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
@@ -0,0 +1,128 @@
+// Package fees models the trading fees charged by the venues, so that
+// positions, daily stats and journal entries report profit and loss net of
+// costs.
+package fees
+
+import (
+	"math"
+	"sync"
+)
+
+// Liquidity is whether a fill took liquidity from the book or added to it
+type Liquidity int
+
+const (
+	// Taker fills take liquidity, like market orders and crossing limit
+	// orders
+	Taker Liquidity = iota
+	// Maker fills add liquidity, like resting post-only orders
+	Maker
+)
+
+var liquidityNames = map[Liquidity]string{
+	Taker: "taker",
+	Maker: "maker",
+}
+
+// String returns the name of the liquidity
+func (l Liquidity) String() string {
+	if name, ok := liquidityNames[l]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// Venues with a default fee schedule
+const (
+	VenueDydx        = "dydx"
+	VenueHyperliquid = "hyperliquid"
+	VenueSolana      = "solana"
+)
+
+// Schedule is the fee schedule of a venue
+type Schedule struct {
+	// MakerBps and TakerBps are charged on the notional of maker and taker
+	// fills, in basis points. A negative MakerBps is a rebate.
+	MakerBps float64 `json:"maker_bps"`
+	TakerBps float64 `json:"taker_bps"`
+	// Fixed is charged on every fill, in quote currency
+	Fixed float64 `json:"fixed,omitempty"`
+	// PriorityFee is paid on every fill to get the transaction of a Solana
+	// swap included, in quote currency
+	PriorityFee float64 `json:"priority_fee,omitempty"`
+}
+
+// Fee returns the fee of a fill of size at price
+func (s Schedule) Fee(price, size float64, liquidity Liquidity) float64 {
+	bps := s.TakerBps
+	if liquidity == Maker {
+		bps = s.MakerBps
+	}
+	return math.Abs(price*size)*bps/10000 + s.Fixed + s.PriorityFee
+}
+
+// DefaultSchedules returns the base fee tier of each venue. Solana swaps pay
+// the pool fee in the quoted price, so only the priority fee is charged.
+func DefaultSchedules() map[string]Schedule {
+	return map[string]Schedule{
+		VenueDydx:        {MakerBps: 1, TakerBps: 5},
+		VenueHyperliquid: {MakerBps: 1.5, TakerBps: 4.5},
+		VenueSolana:      {PriorityFee: 0.01},
+	}
+}
+
+// Fill is a fill to charge
+type Fill struct {
+	Venue     string
+	Symbol    string
+	Price     float64
+	Size      float64
+	Liquidity Liquidity
+}
+
+// FeeModel computes the fee of fills
+type FeeModel interface {
+	Fee(fill Fill) float64
+}
+
+// VenueModel charges fills by the fee schedule of their venue
+type VenueModel struct {
+	schedules map[string]Schedule
+	mu        sync.RWMutex
+}
+
+// NewVenueModel creates a fee model with the given schedules. Venues
+// missing from schedules are charged by their default schedule, and fills of
+// unknown venues are free.
+func NewVenueModel(schedules map[string]Schedule) *VenueModel {
+	m := &VenueModel{schedules: DefaultSchedules()}
+	for venue, schedule := range schedules {
+		m.schedules[venue] = schedule
+	}
+	return m
+}
+
+// SetSchedule replaces the fee schedule of a venue, such as after reaching
+// a new volume tier
+func (m *VenueModel) SetSchedule(venue string, schedule Schedule) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.schedules[venue] = schedule
+}
+
+// Schedule returns the fee schedule of a venue
+func (m *VenueModel) Schedule(venue string) (Schedule, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	schedule, ok := m.schedules[venue]
+	return schedule, ok
+}
+
+// Fee returns the fee of a fill
+func (m *VenueModel) Fee(fill Fill) float64 {
+	schedule, ok := m.Schedule(fill.Venue)
+	if !ok || fill.Size == 0 {
+		return 0
+	}
+	return schedule.Fee(fill.Price, fill.Size, fill.Liquidity)
+}
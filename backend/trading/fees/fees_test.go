@@ -0,0 +1,45 @@
+package fees
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVenueModel(t *testing.T) {
+	model := NewVenueModel(map[string]Schedule{
+		VenueHyperliquid: {MakerBps: -0.2, TakerBps: 3.5},
+	})
+
+	tests := []struct {
+		name string
+		fill Fill
+		want float64
+	}{
+		{"dYdX taker", Fill{Venue: VenueDydx, Price: 50000, Size: 0.5, Liquidity: Taker}, 12.5},
+		{"dYdX maker", Fill{Venue: VenueDydx, Price: 50000, Size: 0.5, Liquidity: Maker}, 2.5},
+		{"Short fills pay on the notional", Fill{Venue: VenueDydx, Price: 50000, Size: -0.5}, 12.5},
+		{"Configured taker tier", Fill{Venue: VenueHyperliquid, Price: 2000, Size: 10}, 7},
+		{"Maker rebate", Fill{Venue: VenueHyperliquid, Price: 2000, Size: 10, Liquidity: Maker}, -0.4},
+		{"Solana priority fee", Fill{Venue: VenueSolana, Price: 150, Size: 10}, 0.01},
+		{"Unknown venue", Fill{Venue: "binance", Price: 100, Size: 1}, 0},
+		{"Empty fill", Fill{Venue: VenueSolana}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.want, model.Fee(tt.fill), 1e-9)
+		})
+	}
+
+	model.SetSchedule(VenueDydx, Schedule{TakerBps: 2.5, Fixed: 0.1})
+	assert.InDelta(t, 6.35, model.Fee(Fill{Venue: VenueDydx, Price: 50000, Size: 0.5}), 1e-9)
+
+	// Default schedules are not shared between models
+	assert.Equal(t, 5.0, NewVenueModel(nil).Fee(Fill{Venue: VenueDydx, Price: 10000, Size: 1}))
+}
+
+func TestLiquidityString(t *testing.T) {
+	assert.Equal(t, "taker", Taker.String())
+	assert.Equal(t, "maker", Maker.String())
+	assert.Equal(t, "unknown", Liquidity(7).String())
+}
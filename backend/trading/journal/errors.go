@@ -0,0 +1,8 @@
+package journal
+
+import "errors"
+
+var (
+	// ErrEntryNotFound is returned when a trade has no journal entry
+	ErrEntryNotFound = errors.New("journal entry not found")
+)
@@ -0,0 +1,25 @@
+package journal
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes registers the trade journal HTTP endpoint. The id is the
+// ID of a filled order, as listed by GET /trades, or of a position.
+func RegisterRoutes(r gin.IRouter, j *Journal) {
+	r.GET("/trades/:id/journal", func(c *gin.Context) {
+		entry, err := j.Get(c.Request.Context(), c.Param("id"))
+		if errors.Is(err, ErrEntryNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, entry)
+	})
+}
@@ -0,0 +1,456 @@
+// Package journal writes a natural-language post-mortem of every closed
+// position: why it was opened, why it was closed and what the strategies and
+// risk checks said while it was open.
+package journal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/eventbus"
+	"github.com/devinjacknz/godydxhyber/backend/llm"
+	"github.com/devinjacknz/godydxhyber/backend/trading/risk"
+)
+
+// Status is the state of a journal entry
+type Status string
+
+const (
+	// StatusPending means the narrative is being generated
+	StatusPending Status = "pending"
+	// StatusReady means the narrative has been generated
+	StatusReady Status = "ready"
+	// StatusFailed means generation failed; the facts of the entry are kept
+	StatusFailed Status = "failed"
+)
+
+// ExitReason is why a position was closed
+type ExitReason string
+
+const (
+	ExitLiquidated ExitReason = "liquidated"
+	// ExitSignal means a strategy signalled the close
+	ExitSignal ExitReason = "signal"
+	// ExitManual means no signal preceded the close, as for API orders
+	ExitManual ExitReason = "manual"
+)
+
+// Fill is an order fill of a journaled position
+type Fill struct {
+	OrderID string    `json:"order_id"`
+	Side    string    `json:"side"`
+	Price   float64   `json:"price,omitempty"`
+	Size    float64   `json:"size"`
+	Fee     float64   `json:"fee,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// SignalNote is a strategy signal on the symbol of a journaled position
+type SignalNote struct {
+	Strategy string    `json:"strategy"`
+	Side     string    `json:"side"`
+	Size     float64   `json:"size"`
+	Price    float64   `json:"price,omitempty"`
+	Reason   string    `json:"reason,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// RiskNote is a risk check made on the symbol while a position was open
+type RiskNote struct {
+	Type        string    `json:"type"`
+	Level       string    `json:"level"`
+	Status      string    `json:"status"`
+	Value       float64   `json:"value"`
+	Threshold   float64   `json:"threshold"`
+	Description string    `json:"description,omitempty"`
+	Time        time.Time `json:"time"`
+}
+
+// Entry is the journal entry of a closed position
+type Entry struct {
+	PositionID  string    `json:"position_id"`
+	Symbol      string    `json:"symbol"`
+	Side        string    `json:"side"`
+	Size        float64   `json:"size"`
+	EntryPrice  float64   `json:"entry_price"`
+	ExitPrice   float64   `json:"exit_price"`
+	RealizedPnL float64   `json:"realized_pnl"`
+	Fees        float64   `json:"fees"`
+	NetPnL      float64   `json:"net_pnl"`
+	OpenedAt    time.Time `json:"opened_at"`
+	ClosedAt    time.Time `json:"closed_at"`
+	// TradeIDs are the IDs of the orders filled on the position
+	TradeIDs     []string     `json:"trade_ids"`
+	Fills        []Fill       `json:"fills"`
+	EntrySignals []SignalNote `json:"entry_signals"`
+	ExitSignals  []SignalNote `json:"exit_signals"`
+	RiskChecks   []RiskNote   `json:"risk_checks"`
+	ExitReason   ExitReason   `json:"exit_reason"`
+
+	Status      Status    `json:"status"`
+	Narrative   string    `json:"narrative,omitempty"`
+	Model       string    `json:"model,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	GeneratedAt time.Time `json:"generated_at,omitempty"`
+}
+
+func (e *Entry) clone() *Entry {
+	c := *e
+	c.TradeIDs = append([]string(nil), e.TradeIDs...)
+	c.Fills = append([]Fill(nil), e.Fills...)
+	c.EntrySignals = append([]SignalNote(nil), e.EntrySignals...)
+	c.ExitSignals = append([]SignalNote(nil), e.ExitSignals...)
+	c.RiskChecks = append([]RiskNote(nil), e.RiskChecks...)
+	return &c
+}
+
+// Generator generates text from a prompt. llm.Client implements it.
+type Generator interface {
+	Generate(ctx context.Context, prompt string, opts ...llm.CallOption) (*llm.Response, error)
+}
+
+// RiskHistory returns past risk checks. risk.RiskManager implements it.
+type RiskHistory interface {
+	GetRiskHistory(ctx context.Context, filter risk.RiskHistoryFilter) ([]*risk.RiskCheck, error)
+}
+
+// Config configures a journal
+type Config struct {
+	// Profile is the LLM task profile used for the narratives
+	Profile llm.TaskProfile
+	// Temperature is the sampling temperature of the narratives, which
+	// read better a little less deterministic than trading analysis
+	Temperature float64
+	// SignalLookback is how long before the open signals count as the
+	// rationale of a position
+	SignalLookback time.Duration
+	// History is the number of fills and signals kept per symbol
+	History int
+	// Timeout bounds the generation of one narrative
+	Timeout time.Duration
+	// FillTolerance widens the open and close times when matching fills and
+	// signals, since events are published slightly after the fact
+	FillTolerance time.Duration
+}
+
+// DefaultConfig returns the default journal configuration
+func DefaultConfig() Config {
+	return Config{
+		Profile:        llm.ProfileDefault,
+		Temperature:    0.8,
+		SignalLookback: time.Hour,
+		History:        500,
+		Timeout:        2 * time.Minute,
+		FillTolerance:  time.Second,
+	}
+}
+
+// Journal records a post-mortem of every closed position
+type Journal struct {
+	generator Generator
+	store     Store
+	risk      RiskHistory
+	config    Config
+
+	fills   map[string][]Fill
+	signals map[string][]SignalNote
+	mu      sync.Mutex
+}
+
+// New creates a journal. riskHistory may be nil, in which case entries list
+// no risk checks. Zero config fields take their default.
+func New(generator Generator, store Store, riskHistory RiskHistory, config Config) *Journal {
+	defaults := DefaultConfig()
+	if config.Profile == "" {
+		config.Profile = defaults.Profile
+	}
+	if config.Temperature <= 0 {
+		config.Temperature = defaults.Temperature
+	}
+	if config.SignalLookback <= 0 {
+		config.SignalLookback = defaults.SignalLookback
+	}
+	if config.History <= 0 {
+		config.History = defaults.History
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaults.Timeout
+	}
+	if config.FillTolerance <= 0 {
+		config.FillTolerance = defaults.FillTolerance
+	}
+	return &Journal{
+		generator: generator,
+		store:     store,
+		risk:      riskHistory,
+		config:    config,
+		fills:     make(map[string][]Fill),
+		signals:   make(map[string][]SignalNote),
+	}
+}
+
+// Subscribe records the fills and signals published on the bus and writes
+// an entry for every closed position, off the publisher's goroutine. It
+// returns a function removing the subscriptions.
+func (j *Journal) Subscribe(bus *eventbus.Bus) func() {
+	unsubscribers := []func(){
+		eventbus.Subscribe(bus, eventbus.OrderFilled, func(ctx context.Context, e eventbus.OrderFilledEvent) error {
+			j.recordFill(e)
+			return nil
+		}),
+		eventbus.Subscribe(bus, eventbus.SignalGenerated, func(ctx context.Context, e eventbus.SignalEvent) error {
+			j.recordSignal(e)
+			return nil
+		}),
+		eventbus.Subscribe(bus, eventbus.PositionClosed, func(ctx context.Context, e eventbus.PositionClosedEvent) error {
+			_, err := j.Record(ctx, e)
+			return err
+		}, eventbus.WithMode(eventbus.Async)),
+	}
+	return func() {
+		for _, unsubscribe := range unsubscribers {
+			unsubscribe()
+		}
+	}
+}
+
+func (j *Journal) recordFill(e eventbus.OrderFilledEvent) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.fills[e.Symbol] = appendBounded(j.fills[e.Symbol], Fill{
+		OrderID: e.OrderID,
+		Side:    e.Side,
+		Price:   e.Price,
+		Size:    e.Size,
+		Fee:     e.Fee,
+		Time:    e.Timestamp,
+	}, j.config.History)
+}
+
+func (j *Journal) recordSignal(e eventbus.SignalEvent) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.signals[e.Symbol] = appendBounded(j.signals[e.Symbol], SignalNote{
+		Strategy: e.Strategy,
+		Side:     e.Side,
+		Size:     e.Size,
+		Price:    e.Price,
+		Reason:   e.Reason,
+		Time:     e.Timestamp,
+	}, j.config.History)
+}
+
+// appendBounded appends v, dropping the oldest values beyond limit
+func appendBounded[T any](values []T, v T, limit int) []T {
+	values = append(values, v)
+	if len(values) > limit {
+		values = append(values[:0:0], values[len(values)-limit:]...)
+	}
+	return values
+}
+
+// Record writes the entry of a closed position. The entry is saved as
+// pending first so the facts are kept even if generation fails; the
+// returned error is then the generation error and the entry is saved as
+// failed.
+func (j *Journal) Record(ctx context.Context, e eventbus.PositionClosedEvent) (*Entry, error) {
+	entry, err := j.collect(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+	if err := j.store.SaveEntry(ctx, entry); err != nil {
+		return nil, fmt.Errorf("failed to save journal entry: %w", err)
+	}
+
+	genCtx, cancel := context.WithTimeout(ctx, j.config.Timeout)
+	resp, err := j.generator.Generate(genCtx, buildPrompt(entry),
+		llm.WithProfile(j.config.Profile), llm.WithTemperature(j.config.Temperature))
+	cancel()
+
+	entry.GeneratedAt = time.Now()
+	if err != nil {
+		entry.Status = StatusFailed
+		entry.Error = err.Error()
+	} else {
+		entry.Status = StatusReady
+		entry.Narrative = strings.TrimSpace(resp.Text)
+		entry.Model = resp.ModelUsed
+	}
+	if saveErr := j.store.SaveEntry(ctx, entry); saveErr != nil {
+		return nil, fmt.Errorf("failed to save journal entry: %w", saveErr)
+	}
+	if err != nil {
+		return entry, fmt.Errorf("failed to generate journal of position %s: %w", e.PositionID, err)
+	}
+	return entry, nil
+}
+
+// collect gathers the fills, signals and risk checks of a closed position
+func (j *Journal) collect(ctx context.Context, e eventbus.PositionClosedEvent) (*Entry, error) {
+	entry := &Entry{
+		PositionID:   e.PositionID,
+		Symbol:       e.Symbol,
+		Side:         e.Side,
+		Size:         e.Size,
+		EntryPrice:   e.EntryPrice,
+		ExitPrice:    e.ExitPrice,
+		RealizedPnL:  e.RealizedPnL,
+		Fees:         e.Fees,
+		NetPnL:       e.NetPnL,
+		OpenedAt:     e.OpenedAt,
+		ClosedAt:     e.Timestamp,
+		TradeIDs:     []string{},
+		Fills:        []Fill{},
+		EntrySignals: []SignalNote{},
+		ExitSignals:  []SignalNote{},
+		RiskChecks:   []RiskNote{},
+		Status:       StatusPending,
+	}
+
+	tolerance := j.config.FillTolerance
+	opened := e.OpenedAt.Add(-tolerance)
+	closed := e.Timestamp.Add(tolerance)
+
+	j.mu.Lock()
+	seen := make(map[string]bool)
+	for _, f := range j.fills[e.Symbol] {
+		if f.Time.Before(opened) || f.Time.After(closed) {
+			continue
+		}
+		entry.Fills = append(entry.Fills, f)
+		if !seen[f.OrderID] {
+			seen[f.OrderID] = true
+			entry.TradeIDs = append(entry.TradeIDs, f.OrderID)
+		}
+	}
+	for _, s := range j.signals[e.Symbol] {
+		switch {
+		case s.Time.Before(e.OpenedAt.Add(-j.config.SignalLookback)) || s.Time.After(closed):
+		case !s.Time.After(e.OpenedAt.Add(tolerance)):
+			entry.EntrySignals = append(entry.EntrySignals, s)
+		default:
+			entry.ExitSignals = append(entry.ExitSignals, s)
+		}
+	}
+	j.mu.Unlock()
+
+	switch {
+	case e.Liquidated:
+		entry.ExitReason = ExitLiquidated
+	case len(entry.ExitSignals) > 0:
+		entry.ExitReason = ExitSignal
+	default:
+		entry.ExitReason = ExitManual
+	}
+
+	if j.risk != nil {
+		start, end := e.OpenedAt, e.Timestamp
+		checks, err := j.risk.GetRiskHistory(ctx, risk.RiskHistoryFilter{
+			Symbol:    e.Symbol,
+			StartTime: &start,
+			EndTime:   &end,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get risk history: %w", err)
+		}
+		for _, check := range checks {
+			entry.RiskChecks = append(entry.RiskChecks, RiskNote{
+				Type:        check.Type.String(),
+				Level:       check.Level.String(),
+				Status:      riskStatusNames[check.Status],
+				Value:       check.Value,
+				Threshold:   check.Threshold,
+				Description: check.Description,
+				Time:        check.CreatedAt,
+			})
+		}
+	}
+
+	return entry, nil
+}
+
+var riskStatusNames = map[risk.RiskStatus]string{
+	risk.Pass:      "pass",
+	risk.Warning:   "warning",
+	risk.Violation: "violation",
+}
+
+// Get returns the entry of a trade, identified by the ID of a filled order,
+// or of a position
+func (j *Journal) Get(ctx context.Context, id string) (*Entry, error) {
+	entry, err := j.store.GetEntryByTrade(ctx, id)
+	if errors.Is(err, ErrEntryNotFound) {
+		return j.store.GetEntry(ctx, id)
+	}
+	return entry, err
+}
+
+// buildPrompt asks for a post-mortem of the entry
+func buildPrompt(e *Entry) string {
+	var b strings.Builder
+	b.WriteString("You are a trading coach writing a journal entry for a closed position.\n")
+	b.WriteString("Explain, using only the facts below:\n")
+	b.WriteString("1. Entry rationale: why the position was opened.\n")
+	b.WriteString("2. Exit reason: why it was closed and whether the exit was well timed.\n")
+	b.WriteString("3. Indicators and risk: what the signals and risk checks said while it was open.\n")
+	b.WriteString("4. Lessons: what to repeat or avoid next time.\n")
+	b.WriteString("Answer in plain prose under those four headings, without inventing data.\n\n")
+
+	fmt.Fprintf(&b, "Position %s: %s %s, size %g\n", e.PositionID, e.Side, e.Symbol, e.Size)
+	fmt.Fprintf(&b, "Opened %s at %g, closed %s at %g (held %s)\n",
+		e.OpenedAt.UTC().Format(time.RFC3339), e.EntryPrice,
+		e.ClosedAt.UTC().Format(time.RFC3339), e.ExitPrice,
+		e.ClosedAt.Sub(e.OpenedAt).Round(time.Second))
+	fmt.Fprintf(&b, "Realized PnL: %g, fees %g, net PnL %g\n", e.RealizedPnL, e.Fees, e.NetPnL)
+	fmt.Fprintf(&b, "Exit reason: %s\n", e.ExitReason)
+
+	b.WriteString("\nFills:\n")
+	if len(e.Fills) == 0 {
+		b.WriteString("- none recorded\n")
+	}
+	for _, f := range e.Fills {
+		fmt.Fprintf(&b, "- %s %s %g at %s\n", f.Time.UTC().Format(time.RFC3339), f.Side, f.Size, priceText(f.Price))
+	}
+
+	writeSignals(&b, "Signals before entry", e.EntrySignals)
+	writeSignals(&b, "Signals while open", e.ExitSignals)
+
+	b.WriteString("\nRisk checks while open:\n")
+	if len(e.RiskChecks) == 0 {
+		b.WriteString("- none recorded\n")
+	}
+	for _, r := range e.RiskChecks {
+		fmt.Fprintf(&b, "- %s %s check: %s, level %s, value %g vs threshold %g",
+			r.Time.UTC().Format(time.RFC3339), r.Type, r.Status, r.Level, r.Value, r.Threshold)
+		if r.Description != "" {
+			fmt.Fprintf(&b, " (%s)", r.Description)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func writeSignals(b *strings.Builder, title string, signals []SignalNote) {
+	fmt.Fprintf(b, "\n%s:\n", title)
+	if len(signals) == 0 {
+		b.WriteString("- none recorded\n")
+	}
+	for _, s := range signals {
+		fmt.Fprintf(b, "- %s %s: %s %g at %s", s.Time.UTC().Format(time.RFC3339), s.Strategy, s.Side, s.Size, priceText(s.Price))
+		if s.Reason != "" {
+			fmt.Fprintf(b, ", reason: %s", s.Reason)
+		}
+		b.WriteString("\n")
+	}
+}
+
+func priceText(price float64) string {
+	if price == 0 {
+		return "market"
+	}
+	return fmt.Sprintf("%g", price)
+}
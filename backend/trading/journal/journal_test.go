@@ -0,0 +1,166 @@
+package journal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/devinjacknz/godydxhyber/backend/eventbus"
+	"github.com/devinjacknz/godydxhyber/backend/llm"
+	"github.com/devinjacknz/godydxhyber/backend/trading/risk"
+)
+
+type fakeGenerator struct {
+	err     error
+	mu      sync.Mutex
+	prompts []string
+}
+
+func (g *fakeGenerator) Generate(ctx context.Context, prompt string, opts ...llm.CallOption) (*llm.Response, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.prompts = append(g.prompts, prompt)
+	if g.err != nil {
+		return nil, g.err
+	}
+	return &llm.Response{Text: "  Entered on a breakout.  ", ModelUsed: "test-model"}, nil
+}
+
+type fakeRiskHistory struct {
+	checks []*risk.RiskCheck
+}
+
+func (h *fakeRiskHistory) GetRiskHistory(ctx context.Context, filter risk.RiskHistoryFilter) ([]*risk.RiskCheck, error) {
+	return h.checks, nil
+}
+
+func TestRecord(t *testing.T) {
+	ctx := context.Background()
+	opened := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	closed := opened.Add(30 * time.Minute)
+
+	generator := &fakeGenerator{}
+	history := &fakeRiskHistory{checks: []*risk.RiskCheck{{
+		Type: risk.PositionRisk, Level: risk.High, Status: risk.Warning,
+		Value: 9, Threshold: 10, Symbol: "BTC-USD", CreatedAt: opened.Add(time.Minute),
+	}}}
+	store := NewMemoryStore()
+	j := New(generator, store, history, Config{})
+
+	j.recordSignal(eventbus.SignalEvent{Strategy: "breakout", Symbol: "BTC-USD", Side: "buy", Size: 1, Reason: "range break", Timestamp: opened.Add(-time.Minute)})
+	j.recordSignal(eventbus.SignalEvent{Strategy: "breakout", Symbol: "BTC-USD", Side: "buy", Size: 1, Timestamp: opened.Add(-2 * time.Hour)})
+	j.recordSignal(eventbus.SignalEvent{Strategy: "breakout", Symbol: "ETH-USD", Side: "buy", Size: 1, Timestamp: opened})
+	j.recordSignal(eventbus.SignalEvent{Strategy: "meanrev", Symbol: "BTC-USD", Side: "sell", Size: 1, Reason: "overbought", Timestamp: closed.Add(-time.Second)})
+	j.recordFill(eventbus.OrderFilledEvent{OrderID: "o1", Symbol: "BTC-USD", Side: "buy", Price: 50000, Size: 1, Fee: 25, Timestamp: opened})
+	j.recordFill(eventbus.OrderFilledEvent{OrderID: "o2", Symbol: "BTC-USD", Side: "sell", Size: 1, Timestamp: closed})
+	j.recordFill(eventbus.OrderFilledEvent{OrderID: "o0", Symbol: "BTC-USD", Side: "buy", Size: 1, Timestamp: opened.Add(-time.Hour)})
+
+	entry, err := j.Record(ctx, eventbus.PositionClosedEvent{
+		PositionID: "p1", Symbol: "BTC-USD", Side: "long", Size: 1,
+		EntryPrice: 50000, ExitPrice: 51000, RealizedPnL: 1000, Fees: 50, NetPnL: 950,
+		OpenedAt: opened, Timestamp: closed,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, StatusReady, entry.Status)
+	assert.Equal(t, "Entered on a breakout.", entry.Narrative)
+	assert.Equal(t, "test-model", entry.Model)
+	assert.Equal(t, []string{"o1", "o2"}, entry.TradeIDs)
+	assert.Equal(t, 25.0, entry.Fills[0].Fee)
+	assert.Equal(t, 950.0, entry.NetPnL)
+	require.Len(t, entry.EntrySignals, 1)
+	assert.Equal(t, "range break", entry.EntrySignals[0].Reason)
+	require.Len(t, entry.ExitSignals, 1)
+	assert.Equal(t, ExitSignal, entry.ExitReason)
+	require.Len(t, entry.RiskChecks, 1)
+	assert.Equal(t, "warning", entry.RiskChecks[0].Status)
+
+	require.Len(t, generator.prompts, 1)
+	prompt := generator.prompts[0]
+	assert.Contains(t, prompt, "Entry rationale")
+	assert.Contains(t, prompt, "range break")
+	assert.Contains(t, prompt, "overbought")
+	assert.Contains(t, prompt, "value 9 vs threshold 10")
+	assert.Contains(t, prompt, "Realized PnL: 1000, fees 50, net PnL 950")
+
+	for _, id := range []string{"o1", "o2", "p1"} {
+		got, err := j.Get(ctx, id)
+		require.NoError(t, err, id)
+		assert.Equal(t, "p1", got.PositionID)
+	}
+	_, err = j.Get(ctx, "o0")
+	assert.ErrorIs(t, err, ErrEntryNotFound)
+}
+
+func TestRecordGenerationFailure(t *testing.T) {
+	ctx := context.Background()
+	j := New(&fakeGenerator{err: errors.New("model unavailable")}, NewMemoryStore(), nil, Config{})
+
+	entry, err := j.Record(ctx, eventbus.PositionClosedEvent{
+		PositionID: "p1", Symbol: "BTC-USD", Side: "short", Liquidated: true,
+		OpenedAt: time.Now().Add(-time.Minute), Timestamp: time.Now(),
+	})
+	require.Error(t, err)
+	require.NotNil(t, entry)
+
+	saved, err := j.Get(ctx, "p1")
+	require.NoError(t, err)
+	assert.Equal(t, StatusFailed, saved.Status)
+	assert.Equal(t, ExitLiquidated, saved.ExitReason)
+	assert.Contains(t, saved.Error, "model unavailable")
+}
+
+func TestSubscribe(t *testing.T) {
+	ctx := context.Background()
+	bus := eventbus.New(eventbus.DefaultConfig())
+	j := New(&fakeGenerator{}, NewMemoryStore(), nil, Config{})
+	unsubscribe := j.Subscribe(bus)
+	defer unsubscribe()
+
+	now := time.Now()
+	require.NoError(t, eventbus.Publish(ctx, bus, eventbus.OrderFilled, eventbus.OrderFilledEvent{OrderID: "o1", Symbol: "BTC-USD", Side: "buy", Size: 1, Timestamp: now}))
+	require.NoError(t, eventbus.Publish(ctx, bus, eventbus.PositionClosed, eventbus.PositionClosedEvent{PositionID: "p1", Symbol: "BTC-USD", OpenedAt: now, Timestamp: now}))
+	bus.Close()
+
+	entry, err := j.Get(ctx, "o1")
+	require.NoError(t, err)
+	assert.Equal(t, StatusReady, entry.Status)
+	assert.Equal(t, ExitManual, entry.ExitReason)
+}
+
+func TestJournalHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	j := New(&fakeGenerator{}, NewMemoryStore(), nil, Config{})
+	now := time.Now()
+	j.recordFill(eventbus.OrderFilledEvent{OrderID: "o1", Symbol: "BTC-USD", Side: "buy", Size: 1, Timestamp: now})
+	_, err := j.Record(ctx, eventbus.PositionClosedEvent{PositionID: "p1", Symbol: "BTC-USD", OpenedAt: now, Timestamp: now})
+	require.NoError(t, err)
+
+	r := gin.New()
+	RegisterRoutes(r.Group("/api/v1"), j)
+	call := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, strings.NewReader("")))
+		return w
+	}
+
+	w := call("/api/v1/trades/o1/journal")
+	require.Equal(t, http.StatusOK, w.Code)
+	var entry Entry
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &entry))
+	assert.Equal(t, "p1", entry.PositionID)
+	assert.Equal(t, "Entered on a breakout.", entry.Narrative)
+
+	assert.Equal(t, http.StatusNotFound, call("/api/v1/trades/missing/journal").Code)
+}
@@ -0,0 +1,64 @@
+package journal
+
+import (
+	"context"
+	"sync"
+)
+
+// Store persists journal entries next to the trades they explain
+type Store interface {
+	SaveEntry(ctx context.Context, entry *Entry) error
+	// GetEntry returns the entry of a position
+	GetEntry(ctx context.Context, positionID string) (*Entry, error)
+	// GetEntryByTrade returns the entry listing a trade, identified by the
+	// ID of the filled order
+	GetEntryByTrade(ctx context.Context, tradeID string) (*Entry, error)
+}
+
+// MemoryStore keeps journal entries in memory
+type MemoryStore struct {
+	entries map[string]*Entry
+	trades  map[string]string
+	mu      sync.RWMutex
+}
+
+// NewMemoryStore creates an in-memory journal store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: make(map[string]*Entry),
+		trades:  make(map[string]string),
+	}
+}
+
+// SaveEntry inserts or replaces the entry of a position
+func (s *MemoryStore) SaveEntry(ctx context.Context, entry *Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[entry.PositionID] = entry.clone()
+	for _, tradeID := range entry.TradeIDs {
+		s.trades[tradeID] = entry.PositionID
+	}
+	return nil
+}
+
+// GetEntry returns the entry of a position
+func (s *MemoryStore) GetEntry(ctx context.Context, positionID string) (*Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entry, ok := s.entries[positionID]
+	if !ok {
+		return nil, ErrEntryNotFound
+	}
+	return entry.clone(), nil
+}
+
+// GetEntryByTrade returns the entry listing a trade
+func (s *MemoryStore) GetEntryByTrade(ctx context.Context, tradeID string) (*Entry, error) {
+	s.mu.RLock()
+	positionID, ok := s.trades[tradeID]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrEntryNotFound
+	}
+	return s.GetEntry(ctx, positionID)
+}
@@ -0,0 +1,14 @@
+package killswitch
+
+import "errors"
+
+var (
+	// ErrAlreadyActive is returned when the kill switch is triggered while already active
+	ErrAlreadyActive = errors.New("kill switch already active")
+
+	// ErrNotActive is returned when resetting a kill switch that is not active
+	ErrNotActive = errors.New("kill switch not active")
+
+	// ErrReasonRequired is returned when a trigger or reset has no reason
+	ErrReasonRequired = errors.New("reason is required")
+)
@@ -0,0 +1,96 @@
+package killswitch
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type triggerRequest struct {
+	Reason  string `json:"reason"`
+	Flatten *bool  `json:"flatten,omitempty"`
+}
+
+type resetRequest struct {
+	Reason string `json:"reason"`
+}
+
+// Resolver selects the kill switch a request acts on, such as a sandbox
+// kill switch for sandbox API tokens
+type Resolver func(c *gin.Context) (*KillSwitch, error)
+
+// RegisterRoutes registers the kill switch HTTP endpoints
+func (k *KillSwitch) RegisterRoutes(r gin.IRouter) {
+	RegisterRoutesWithResolver(r, func(c *gin.Context) (*KillSwitch, error) {
+		return k, nil
+	})
+}
+
+// RegisterRoutesWithResolver registers the kill switch HTTP endpoints, acting
+// on the kill switch returned by resolve for each request
+func RegisterRoutesWithResolver(r gin.IRouter, resolve Resolver) {
+	r.GET("/killswitch", resolved(resolve, (*KillSwitch).handleStatus))
+	r.POST("/killswitch/trigger", resolved(resolve, (*KillSwitch).handleTrigger))
+	r.POST("/killswitch/reset", resolved(resolve, (*KillSwitch).handleReset))
+}
+
+func resolved(resolve Resolver, handler func(*KillSwitch, *gin.Context)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		k, err := resolve(c)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		handler(k, c)
+	}
+}
+
+func (k *KillSwitch) handleStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, k.Status())
+}
+
+func (k *KillSwitch) handleTrigger(c *gin.Context) {
+	var req triggerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	state, err := k.Trigger(c.Request.Context(), TriggerParams{
+		Source:  SourceManual,
+		Reason:  req.Reason,
+		Flatten: req.Flatten,
+	})
+	switch {
+	case errors.Is(err, ErrReasonRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrAlreadyActive):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "state": state})
+	case err != nil:
+		// The halt is in effect even if some orders or positions could not be cleared
+		c.JSON(http.StatusMultiStatus, gin.H{"error": err.Error(), "state": state})
+	default:
+		c.JSON(http.StatusOK, state)
+	}
+}
+
+func (k *KillSwitch) handleReset(c *gin.Context) {
+	var req resetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := k.Reset(c.Request.Context(), req.Reason)
+	switch {
+	case errors.Is(err, ErrReasonRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrNotActive):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, k.Status())
+	}
+}
@@ -0,0 +1,270 @@
+package killswitch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/trading/analysis/monitoring"
+	"github.com/devinjacknz/godydxhyber/backend/trading/order"
+	"github.com/devinjacknz/godydxhyber/backend/trading/position"
+	"github.com/devinjacknz/godydxhyber/backend/trading/risk"
+)
+
+// EventType is the monitoring event type used for kill switch audit events
+const EventType = "kill_switch"
+
+// TriggerSource identifies what triggered the kill switch
+type TriggerSource string
+
+const (
+	SourceManual TriggerSource = "manual"
+	SourceRisk   TriggerSource = "risk"
+	// SourceShutdown cancels open orders on a graceful shutdown
+	SourceShutdown TriggerSource = "shutdown"
+	// SourceWatchdog halts trading when the strategy loop stops checking in
+	SourceWatchdog TriggerSource = "watchdog"
+)
+
+// Config contains kill switch configuration
+type Config struct {
+	// FlattenPositions closes all open positions when the switch is triggered
+	FlattenPositions bool
+	// Paper marks a kill switch over a sandbox portfolio. Its audit events are
+	// tagged as paper and it does not drive the live kill_switch_active indicator.
+	Paper bool
+}
+
+// TriggerParams contains parameters for triggering the kill switch
+type TriggerParams struct {
+	Source TriggerSource
+	Reason string
+	// Flatten overrides Config.FlattenPositions when set
+	Flatten *bool
+}
+
+// State represents the current kill switch state
+type State struct {
+	Active          bool          `json:"active"`
+	Source          TriggerSource `json:"source,omitempty"`
+	Reason          string        `json:"reason,omitempty"`
+	TriggeredAt     *time.Time    `json:"triggered_at,omitempty"`
+	CancelledOrders int           `json:"cancelled_orders"`
+	ClosedPositions int           `json:"closed_positions"`
+}
+
+// KillSwitch halts all trading activity
+type KillSwitch struct {
+	orders    order.OrderManager
+	positions *position.Manager
+	config    Config
+	state     State
+	mu        sync.Mutex
+}
+
+// NewKillSwitch creates a new kill switch over the given order and position managers.
+// The position manager may be nil when positions should never be flattened.
+func NewKillSwitch(orders order.OrderManager, positions *position.Manager, config Config) *KillSwitch {
+	return &KillSwitch{
+		orders:    orders,
+		positions: positions,
+		config:    config,
+	}
+}
+
+// Trigger halts new order creation, cancels resting orders and optionally
+// flattens open positions. The halt is applied before any cancellation so no
+// new order can slip in while the book is being cleared.
+func (k *KillSwitch) Trigger(ctx context.Context, params TriggerParams) (State, error) {
+	if params.Reason == "" {
+		return State{}, ErrReasonRequired
+	}
+	if params.Source == "" {
+		params.Source = SourceManual
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.state.Active {
+		return k.state, ErrAlreadyActive
+	}
+
+	k.orders.Halt(params.Reason)
+
+	now := time.Now()
+	k.state = State{
+		Active:      true,
+		Source:      params.Source,
+		Reason:      params.Reason,
+		TriggeredAt: &now,
+	}
+
+	cancelled, cancelErr := k.cancelRestingOrders(ctx)
+	k.state.CancelledOrders = cancelled
+
+	flatten := k.config.FlattenPositions
+	if params.Flatten != nil {
+		flatten = *params.Flatten
+	}
+
+	var flattenErr error
+	if flatten && k.positions != nil {
+		k.state.ClosedPositions, flattenErr = k.flattenPositions(ctx)
+	}
+
+	err := errors.Join(cancelErr, flattenErr)
+
+	details := map[string]interface{}{
+		"source":           string(params.Source),
+		"reason":           params.Reason,
+		"cancelled_orders": k.state.CancelledOrders,
+		"closed_positions": k.state.ClosedPositions,
+		"flatten":          flatten,
+		"paper":            k.config.Paper,
+	}
+	if err != nil {
+		details["error"] = err.Error()
+	}
+	monitoring.RecordEvent(monitoring.Event{
+		Type:      EventType,
+		Severity:  monitoring.SeverityCritical,
+		Message:   "Kill switch triggered",
+		Details:   details,
+		Timestamp: now,
+	})
+	if !k.config.Paper {
+		monitoring.RecordIndicatorValue("kill_switch_active", 1)
+	}
+
+	return k.state, err
+}
+
+// Reset re-enables trading after the kill switch has been triggered
+func (k *KillSwitch) Reset(ctx context.Context, reason string) error {
+	if reason == "" {
+		return ErrReasonRequired
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if !k.state.Active {
+		return ErrNotActive
+	}
+
+	previous := k.state
+	k.orders.Resume()
+	k.state = State{}
+
+	monitoring.RecordEvent(monitoring.Event{
+		Type:     EventType,
+		Severity: monitoring.SeverityWarning,
+		Message:  "Kill switch reset",
+		Details: map[string]interface{}{
+			"reason":         reason,
+			"trigger_source": string(previous.Source),
+			"trigger_reason": previous.Reason,
+			"halted_for":     time.Since(*previous.TriggeredAt).String(),
+			"paper":          k.config.Paper,
+		},
+	})
+	if !k.config.Paper {
+		monitoring.RecordIndicatorValue("kill_switch_active", 0)
+	}
+
+	return nil
+}
+
+// Status returns the current kill switch state
+func (k *KillSwitch) Status() State {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.state
+}
+
+// HandleRiskCheck triggers the kill switch automatically on drawdown
+// violations and critical volatility. It reports whether the switch fired.
+func (k *KillSwitch) HandleRiskCheck(ctx context.Context, check *risk.RiskCheck) (bool, error) {
+	if check == nil || check.Status != risk.Violation {
+		return false, nil
+	}
+
+	var reason string
+	switch {
+	case check.Type == risk.DrawdownRisk:
+		reason = fmt.Sprintf("drawdown %.4f exceeded limit %.4f", check.Value, check.Threshold)
+	case check.Type == risk.VolatilityRisk && check.Level == risk.Critical:
+		reason = fmt.Sprintf("critical volatility %.4f on %s", check.Value, check.Symbol)
+	default:
+		return false, nil
+	}
+
+	_, err := k.Trigger(ctx, TriggerParams{
+		Source: SourceRisk,
+		Reason: reason,
+	})
+	if errors.Is(err, ErrAlreadyActive) {
+		return false, nil
+	}
+	return true, err
+}
+
+// Halted reports whether trading is halted and why
+func (k *KillSwitch) Halted() (bool, string) {
+	return k.orders.IsHalted()
+}
+
+func (k *KillSwitch) cancelRestingOrders(ctx context.Context) (int, error) {
+	orders, err := k.orders.ListOrders(ctx, order.OrderFilter{})
+	if err != nil {
+		return 0, fmt.Errorf("list orders: %w", err)
+	}
+
+	var errs []error
+	cancelled := 0
+	for _, o := range orders {
+		if !isResting(o.Status) {
+			continue
+		}
+		if err := k.orders.CancelOrder(ctx, o.ID); err != nil {
+			if errors.Is(err, order.ErrOrderNotCancellable) {
+				continue
+			}
+			errs = append(errs, fmt.Errorf("cancel order %s: %w", o.ID, err))
+			continue
+		}
+		cancelled++
+	}
+
+	return cancelled, errors.Join(errs...)
+}
+
+func (k *KillSwitch) flattenPositions(ctx context.Context) (int, error) {
+	status := position.Open
+	positions, err := k.positions.ListPositions(ctx, position.PositionFilter{Status: &status})
+	if err != nil {
+		return 0, fmt.Errorf("list positions: %w", err)
+	}
+
+	var errs []error
+	closed := 0
+	for _, pos := range positions {
+		if err := k.positions.ClosePosition(ctx, pos.ID, pos.CurrentPrice); err != nil {
+			if errors.Is(err, position.ErrPositionAlreadyClosed) {
+				continue
+			}
+			errs = append(errs, fmt.Errorf("close position %s: %w", pos.ID, err))
+			continue
+		}
+		closed++
+	}
+
+	return closed, errors.Join(errs...)
+}
+
+func isResting(status order.OrderStatus) bool {
+	return status == order.Created || status == order.Pending || status == order.PartiallyFilled
+}
@@ -0,0 +1,233 @@
+package killswitch
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/devinjacknz/godydxhyber/backend/exchange/dydx"
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+	"github.com/devinjacknz/godydxhyber/backend/trading/order"
+	"github.com/devinjacknz/godydxhyber/backend/trading/position"
+	"github.com/devinjacknz/godydxhyber/backend/trading/risk"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeVenue acknowledges every order and cancels them unless cancelErr is set
+type fakeVenue struct {
+	cancelErr error
+	cancelled []string
+}
+
+func (v *fakeVenue) CreateOrder(ctx context.Context, req dydx.CreateOrderRequest) (*dydx.Order, error) {
+	return &dydx.Order{ID: "ex-" + req.ClientID, ClientID: req.ClientID}, nil
+}
+
+func (v *fakeVenue) CancelOrder(ctx context.Context, orderID string) error {
+	if v.cancelErr != nil {
+		return v.cancelErr
+	}
+	v.cancelled = append(v.cancelled, orderID)
+	return nil
+}
+
+func (v *fakeVenue) GetOpenOrders(ctx context.Context) ([]dydx.Order, error) {
+	return nil, nil
+}
+
+func TestKillSwitch(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Trigger halts trading and cancels resting orders", func(t *testing.T) {
+		orders := order.NewOrderManager()
+		ks := NewKillSwitch(orders, nil, Config{})
+
+		price := money.FromFloat(100.0)
+		resting, err := orders.CreateOrder(ctx, order.CreateOrderParams{
+			Symbol: "SOL/USD",
+			Type:   order.Limit,
+			Side:   order.Buy,
+			Price:  &price,
+			Size:   money.FromFloat(1.0),
+		})
+		assert.NoError(t, err)
+
+		state, err := ks.Trigger(ctx, TriggerParams{Reason: "manual test"})
+		assert.NoError(t, err)
+		assert.True(t, state.Active)
+		assert.Equal(t, SourceManual, state.Source)
+		assert.Equal(t, 1, state.CancelledOrders)
+
+		cancelled, err := orders.GetOrder(ctx, resting.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, order.Cancelled, cancelled.Status)
+
+		_, err = orders.CreateOrder(ctx, order.CreateOrderParams{
+			Symbol: "SOL/USD",
+			Type:   order.Market,
+			Side:   order.Buy,
+			Size:   money.FromFloat(1.0),
+		})
+		assert.Equal(t, order.ErrTradingHalted, err)
+
+		halted, reason := ks.Halted()
+		assert.True(t, halted)
+		assert.Equal(t, "manual test", reason)
+	})
+
+	t.Run("Trigger cancels submitted orders on the venue", func(t *testing.T) {
+		venue := &fakeVenue{cancelErr: errors.New("venue unavailable")}
+		orders := order.NewOrderManager(order.WithVenue("dydx", venue))
+		ks := NewKillSwitch(orders, nil, Config{})
+
+		price := money.FromFloat(100.0)
+		resting, err := orders.CreateOrder(ctx, order.CreateOrderParams{
+			Symbol:        "SOL/USD",
+			Type:          order.Limit,
+			Side:          order.Buy,
+			Price:         &price,
+			Size:          money.FromFloat(1.0),
+			ClientOrderID: "c1",
+		})
+		assert.NoError(t, err)
+		_, err = orders.Submit(ctx, resting.ID)
+		assert.NoError(t, err)
+
+		// Orders the venue does not cancel are still live and not counted
+		state, err := ks.Trigger(ctx, TriggerParams{Reason: "venue down"})
+		assert.ErrorIs(t, err, order.ErrVenueCancelFailed)
+		assert.Equal(t, 0, state.CancelledOrders)
+		assert.Equal(t, order.Pending, resting.Snapshot().Status)
+
+		venue.cancelErr = nil
+		assert.NoError(t, ks.Reset(ctx, "venue back"))
+		state, err = ks.Trigger(ctx, TriggerParams{Reason: "venue back"})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, state.CancelledOrders)
+		assert.Equal(t, []string{"ex-c1"}, venue.cancelled)
+		assert.Equal(t, order.Cancelled, resting.Snapshot().Status)
+	})
+
+	t.Run("Trigger twice returns already active", func(t *testing.T) {
+		ks := NewKillSwitch(order.NewOrderManager(), nil, Config{})
+
+		_, err := ks.Trigger(ctx, TriggerParams{Reason: "first"})
+		assert.NoError(t, err)
+
+		state, err := ks.Trigger(ctx, TriggerParams{Reason: "second"})
+		assert.Equal(t, ErrAlreadyActive, err)
+		assert.Equal(t, "first", state.Reason)
+	})
+
+	t.Run("Reason is required", func(t *testing.T) {
+		ks := NewKillSwitch(order.NewOrderManager(), nil, Config{})
+
+		_, err := ks.Trigger(ctx, TriggerParams{})
+		assert.Equal(t, ErrReasonRequired, err)
+		assert.False(t, ks.Status().Active)
+	})
+
+	t.Run("Reset resumes trading", func(t *testing.T) {
+		orders := order.NewOrderManager()
+		ks := NewKillSwitch(orders, nil, Config{})
+
+		assert.Equal(t, ErrNotActive, ks.Reset(ctx, "nothing to reset"))
+
+		_, err := ks.Trigger(ctx, TriggerParams{Reason: "halt"})
+		assert.NoError(t, err)
+		assert.NoError(t, ks.Reset(ctx, "all clear"))
+		assert.False(t, ks.Status().Active)
+
+		_, err = orders.CreateOrder(ctx, order.CreateOrderParams{
+			Symbol: "SOL/USD",
+			Type:   order.Market,
+			Side:   order.Buy,
+			Size:   money.FromFloat(1.0),
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Flatten closes open positions", func(t *testing.T) {
+		positions := position.NewManager()
+		ks := NewKillSwitch(order.NewOrderManager(), positions, Config{FlattenPositions: true})
+
+		pos, err := positions.OpenPosition(ctx, position.OpenPositionParams{
+			Symbol:     "BTC/USD",
+			Side:       position.Long,
+			Size:       money.FromFloat(1.0),
+			EntryPrice: money.FromFloat(50000.0),
+			Leverage:   2.0,
+		})
+		assert.NoError(t, err)
+
+		state, err := ks.Trigger(ctx, TriggerParams{Reason: "flatten"})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, state.ClosedPositions)
+
+		closed, err := positions.GetPosition(ctx, pos.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, position.Closed, closed.Status)
+	})
+
+	t.Run("Flatten override skips positions", func(t *testing.T) {
+		positions := position.NewManager()
+		ks := NewKillSwitch(order.NewOrderManager(), positions, Config{FlattenPositions: true})
+
+		_, err := positions.OpenPosition(ctx, position.OpenPositionParams{
+			Symbol:     "ETH/USD",
+			Side:       position.Short,
+			Size:       money.FromFloat(1.0),
+			EntryPrice: money.FromFloat(3000.0),
+			Leverage:   2.0,
+		})
+		assert.NoError(t, err)
+
+		flatten := false
+		state, err := ks.Trigger(ctx, TriggerParams{Reason: "halt only", Flatten: &flatten})
+		assert.NoError(t, err)
+		assert.Equal(t, 0, state.ClosedPositions)
+	})
+
+	t.Run("Risk violations trigger automatically", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			check    *risk.RiskCheck
+			expected bool
+		}{
+			{
+				name:     "Drawdown violation",
+				check:    &risk.RiskCheck{Type: risk.DrawdownRisk, Status: risk.Violation, Level: risk.Critical},
+				expected: true,
+			},
+			{
+				name:     "Critical volatility",
+				check:    &risk.RiskCheck{Type: risk.VolatilityRisk, Status: risk.Violation, Level: risk.Critical},
+				expected: true,
+			},
+			{
+				name:     "Drawdown warning",
+				check:    &risk.RiskCheck{Type: risk.DrawdownRisk, Status: risk.Warning, Level: risk.High},
+				expected: false,
+			},
+			{
+				name:     "Exposure violation",
+				check:    &risk.RiskCheck{Type: risk.ExposureRisk, Status: risk.Violation, Level: risk.Critical},
+				expected: false,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				ks := NewKillSwitch(order.NewOrderManager(), nil, Config{})
+
+				fired, err := ks.HandleRiskCheck(ctx, tt.check)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, fired)
+				assert.Equal(t, tt.expected, ks.Status().Active)
+				if tt.expected {
+					assert.Equal(t, SourceRisk, ks.Status().Source)
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package order
+
+import (
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+	"github.com/devinjacknz/godydxhyber/backend/trading/analysis/monitoring"
+)
+
+// DefaultDedupWindow is how long a client order ID stays reserved by the
+// order created with it
+const DefaultDedupWindow = 24 * time.Hour
+
+// WithDedupWindow sets how long a client order ID stays reserved. A
+// non-positive window disables deduplication.
+func WithDedupWindow(window time.Duration) ManagerOption {
+	return func(m *DefaultOrderManager) {
+		m.dedupWindow = window
+	}
+}
+
+// replay returns the order already created with the client order ID of
+// params, or nil when the ID is unused or its window has passed. Replays with
+// different parameters are rejected with ErrDuplicateClientOrderID. A retry
+// racing the save of the first request returns its order before it is saved.
+// Callers hold m.mu.
+func (m *DefaultOrderManager) replay(params CreateOrderParams) (*Order, error) {
+	if params.ClientOrderID == "" || m.dedupWindow <= 0 {
+		return nil, nil
+	}
+
+	existing, ok := m.clientIDs[params.ClientOrderID]
+	if !ok {
+		return nil, nil
+	}
+	if time.Since(existing.CreatedAt) > m.dedupWindow {
+		delete(m.clientIDs, params.ClientOrderID)
+		return nil, nil
+	}
+
+	if !sameOrder(existing, params) {
+		monitoring.RecordIndicatorError("create_order", "duplicate client order ID")
+		return nil, ErrDuplicateClientOrderID
+	}
+	monitoring.RecordIndicatorValue("replayed_orders", 1)
+	return existing, nil
+}
+
+// index reserves the client order ID of an order. Callers hold m.mu.
+func (m *DefaultOrderManager) index(order *Order) {
+	if order.ClientOrderID != "" {
+		m.clientIDs[order.ClientOrderID] = order
+	}
+}
+
+// sameOrder reports whether params describe the order. Only the fields set
+// by the caller are compared, so a retried request matches its order
+// whatever happened to the order since. The terms of an order never change,
+// so they are read without its lock, which may be held while it is saved.
+func sameOrder(order *Order, params CreateOrderParams) bool {
+	return order.Symbol == params.Symbol &&
+		order.Type == params.Type &&
+		order.Side == params.Side &&
+		order.Size.Equal(params.Size) &&
+		order.PostOnly == params.PostOnly &&
+		order.ReduceOnly == params.ReduceOnly &&
+		order.TimeInForce == params.TimeInForce &&
+		samePrice(order.Price, params.Price) &&
+		samePrice(order.StopPrice, params.StopPrice)
+}
+
+func samePrice(a, b *money.Decimal) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -41,4 +41,34 @@ var (
 
 	// ErrMarketClosed is returned when the market is closed
 	ErrMarketClosed = errors.New("market closed")
+
+	// ErrTradingHalted is returned when order creation is blocked by a trading halt
+	ErrTradingHalted = errors.New("trading halted")
+
+	// ErrSymbolDisabled is returned when orders on the symbol are disabled
+	ErrSymbolDisabled = errors.New("trading disabled on symbol")
+
+	// ErrRiskRejected is returned when an order fails the pre-trade check
+	ErrRiskRejected = errors.New("order rejected by risk check")
+
+	// ErrShuttingDown is returned when orders are created or submitted after shutdown began
+	ErrShuttingDown = errors.New("order manager shutting down")
+
+	// ErrNoVenue is returned when submitting orders without a venue configured
+	ErrNoVenue = errors.New("no venue configured")
+
+	// ErrVenueCancelFailed is returned when the venue does not confirm the cancellation of an order
+	ErrVenueCancelFailed = errors.New("venue did not cancel order")
+
+	// ErrSubmissionInProgress is returned when submitting an order already being submitted
+	ErrSubmissionInProgress = errors.New("order submission in progress")
+
+	// ErrInvalidOrderFlags is returned when the execution flags of an order do not fit together
+	ErrInvalidOrderFlags = errors.New("invalid order flags")
+
+	// ErrReduceOnlyIncreases is returned when a reduce-only order would open or increase a position
+	ErrReduceOnlyIncreases = errors.New("reduce-only order would increase position")
+
+	// ErrExecutionTimeout is returned when the venue does not acknowledge an order within the execution budget
+	ErrExecutionTimeout = errors.New("order not acknowledged within the execution budget")
 )
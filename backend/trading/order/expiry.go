@@ -0,0 +1,117 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+	"github.com/devinjacknz/godydxhyber/backend/trading/analysis/monitoring"
+)
+
+// DefaultExpirySweep is how often RunExpirySweeper looks for expired orders
+const DefaultExpirySweep = time.Second
+
+// ExpireOrders moves the created and pending orders whose ExpiresAt has
+// passed to Expired and returns their IDs. Orders acknowledged by the venue
+// are cancelled there first; an order whose cancellation fails stays open and
+// is expired by a later call. Orders being submitted are left to the
+// submission.
+func (m *DefaultOrderManager) ExpireOrders(ctx context.Context) ([]string, error) {
+	now := time.Now()
+	m.mu.RLock()
+	due := make([]*Order, 0)
+	for id, order := range m.orders {
+		if m.inflight[id] > 0 {
+			continue
+		}
+		order.mu.RLock()
+		if isExpirable(order, now) {
+			due = append(due, order)
+		}
+		order.mu.RUnlock()
+	}
+	m.mu.RUnlock()
+
+	expired := make([]string, 0, len(due))
+	var errs []error
+	for _, order := range due {
+		ok, err := m.expire(ctx, order, now)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if ok {
+			expired = append(expired, order.ID)
+		}
+	}
+	if len(expired) > 0 {
+		monitoring.RecordIndicatorValue("expired_orders", float64(len(expired)))
+	}
+	return expired, errors.Join(errs...)
+}
+
+// expire cancels an expired order on the venue, if it was submitted there,
+// and marks it Expired. It reports false if the order was filled or
+// cancelled meanwhile.
+func (m *DefaultOrderManager) expire(ctx context.Context, order *Order, now time.Time) (bool, error) {
+	order.mu.RLock()
+	exchangeID := order.ExchangeOrderID
+	order.mu.RUnlock()
+
+	if exchangeID != "" && m.venue != nil {
+		cancelCtx, cancel := context.WithTimeout(ctx, m.executionBudget)
+		err := m.venue.CancelOrder(cancelCtx, exchangeID)
+		cancel()
+		if err != nil {
+			return false, fmt.Errorf("failed to cancel expired order %s on %s: %w", order.ID, m.venueName, err)
+		}
+	}
+
+	order.mu.Lock()
+	defer order.mu.Unlock()
+
+	if !isExpirable(order, now) {
+		return false, nil
+	}
+	err := m.update(ctx, order, money.Zero, func(next *Order) {
+		next.Status = Expired
+		next.UpdatedAt = now
+	})
+	if err != nil {
+		return false, err
+	}
+	slog.InfoContext(ctx, "order expired", "order_id", order.ID, "exchange_order_id", exchangeID, "expires_at", *order.ExpiresAt)
+	return true, nil
+}
+
+// isExpirable reports whether an order is open and past its expiry. Callers
+// hold the order's lock.
+func isExpirable(order *Order, now time.Time) bool {
+	if order.ExpiresAt == nil || order.ExpiresAt.After(now) {
+		return false
+	}
+	return order.Status == Created || order.Status == Pending
+}
+
+// RunExpirySweeper expires the orders of m every interval until ctx is done.
+// A zero interval sweeps every DefaultExpirySweep.
+func RunExpirySweeper(ctx context.Context, m OrderManager, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultExpirySweep
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := m.ExpireOrders(ctx); err != nil && ctx.Err() == nil {
+				slog.ErrorContext(ctx, "failed to expire orders", "error", err)
+			}
+		}
+	}
+}
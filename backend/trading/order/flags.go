@@ -0,0 +1,124 @@
+package order
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/devinjacknz/godydxhyber/backend/exchange/dydx"
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+)
+
+// TimeInForce is how long an order stays open on the book
+type TimeInForce int
+
+const (
+	// GTC orders rest until they fill, are cancelled or expire
+	GTC TimeInForce = iota
+	// IOC orders fill what they can at once and cancel the rest
+	IOC
+	// FOK orders fill completely at once or not at all
+	FOK
+)
+
+var timeInForces = map[string]TimeInForce{
+	"GTC": GTC,
+	"IOC": IOC,
+	"FOK": FOK,
+}
+
+// String returns the name of the time in force
+func (t TimeInForce) String() string {
+	for name, tif := range timeInForces {
+		if tif == t {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// ParseTimeInForce parses GTC, IOC or FOK, in any case. An empty name is
+// GTC.
+func ParseTimeInForce(name string) (TimeInForce, error) {
+	if name == "" {
+		return GTC, nil
+	}
+	tif, ok := timeInForces[strings.ToUpper(name)]
+	if !ok {
+		return GTC, fmt.Errorf("%w: time in force must be GTC, IOC or FOK", ErrInvalidOrderFlags)
+	}
+	return tif, nil
+}
+
+// PositionSource lists the open positions on the exchange, against which
+// reduce-only orders are checked
+type PositionSource interface {
+	GetPositions(ctx context.Context) ([]dydx.Position, error)
+}
+
+// WithPositions rejects reduce-only orders that would open or increase a
+// position on the exchange. Without it reduce-only orders are left to the
+// exchange to enforce.
+func WithPositions(source PositionSource) ManagerOption {
+	return func(m *DefaultOrderManager) {
+		m.positions = source
+	}
+}
+
+// validateFlags checks that the execution flags of params fit together. A
+// post-only order must rest on the book, so it has to be a limit order that
+// is good till cancelled.
+func validateFlags(params CreateOrderParams) error {
+	if _, ok := timeInForces[params.TimeInForce.String()]; !ok {
+		return fmt.Errorf("%w: unknown time in force %d", ErrInvalidOrderFlags, params.TimeInForce)
+	}
+	if params.PostOnly && params.Type != Limit {
+		return fmt.Errorf("%w: post-only orders must be limit orders", ErrInvalidOrderFlags)
+	}
+	if params.PostOnly && params.TimeInForce != GTC {
+		return fmt.Errorf("%w: post-only orders cannot be %s", ErrInvalidOrderFlags, params.TimeInForce)
+	}
+	return nil
+}
+
+// checkReduceOnly rejects a reduce-only order that is not opposite to the
+// position on its symbol or larger than it
+func (m *DefaultOrderManager) checkReduceOnly(ctx context.Context, params CreateOrderParams) error {
+	if !params.ReduceOnly || m.positions == nil {
+		return nil
+	}
+
+	positions, err := m.positions.GetPositions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check reduce-only order: %w", err)
+	}
+	var size float64
+	for _, p := range positions {
+		if p.Market != params.Symbol {
+			continue
+		}
+		size = math.Abs(p.Size)
+		if strings.EqualFold(p.Side, dydx.PositionSideShort) {
+			size = -size
+		}
+		break
+	}
+
+	switch {
+	case size == 0:
+		return fmt.Errorf("%w: no open position on %s", ErrReduceOnlyIncreases, params.Symbol)
+	case size > 0 && params.Side == Buy, size < 0 && params.Side == Sell:
+		return fmt.Errorf("%w: %s order on a %s position", ErrReduceOnlyIncreases, params.Side, positionSide(size))
+	case params.Size.Cmp(money.FromFloat(math.Abs(size))) > 0:
+		return fmt.Errorf("%w: size %s exceeds the %g position", ErrReduceOnlyIncreases, params.Size, math.Abs(size))
+	}
+	return nil
+}
+
+func positionSide(size float64) string {
+	if size < 0 {
+		return "short"
+	}
+	return "long"
+}
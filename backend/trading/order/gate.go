@@ -0,0 +1,57 @@
+package order
+
+import (
+	"context"
+	"fmt"
+)
+
+// SymbolGate disables trading on some symbols, such as the tokens whose loss
+// budget is spent
+type SymbolGate interface {
+	// SymbolDisabled reports whether new orders on symbol are disabled and
+	// why
+	SymbolDisabled(symbol string) (reason string, disabled bool)
+}
+
+// WithSymbolGate rejects orders on the symbols disabled by gate. Reduce-only
+// orders are still accepted so that positions can be closed.
+func WithSymbolGate(gate SymbolGate) ManagerOption {
+	return func(m *DefaultOrderManager) {
+		m.gate = gate
+	}
+}
+
+// checkGate rejects an order on a symbol disabled by the gate
+func (m *DefaultOrderManager) checkGate(params CreateOrderParams) error {
+	if m.gate == nil || params.ReduceOnly {
+		return nil
+	}
+	if reason, disabled := m.gate.SymbolDisabled(params.Symbol); disabled {
+		return fmt.Errorf("%w: %s: %s", ErrSymbolDisabled, params.Symbol, reason)
+	}
+	return nil
+}
+
+// PreTradeCheck vets an order before it is created, such as against the
+// risk limits of the account, returning an error for orders that must not
+// be placed
+type PreTradeCheck func(ctx context.Context, params CreateOrderParams) error
+
+// WithPreTradeCheck rejects orders failing check with ErrRiskRejected.
+// Reduce-only orders are not checked so that positions can be closed.
+func WithPreTradeCheck(check PreTradeCheck) ManagerOption {
+	return func(m *DefaultOrderManager) {
+		m.preTrade = check
+	}
+}
+
+// checkPreTrade rejects an order failing the pre-trade check
+func (m *DefaultOrderManager) checkPreTrade(ctx context.Context, params CreateOrderParams) error {
+	if m.preTrade == nil || params.ReduceOnly {
+		return nil
+	}
+	if err := m.preTrade(ctx, params); err != nil {
+		return fmt.Errorf("%w: %w", ErrRiskRejected, err)
+	}
+	return nil
+}
@@ -0,0 +1,311 @@
+package order
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+)
+
+var orderTypes = map[string]OrderType{
+	"market":      Market,
+	"limit":       Limit,
+	"stop_loss":   StopLoss,
+	"take_profit": TakeProfit,
+}
+
+var orderSides = map[string]OrderSide{
+	"buy":  Buy,
+	"sell": Sell,
+}
+
+var orderStatuses = map[string]OrderStatus{
+	"created":          Created,
+	"pending":          Pending,
+	"partially_filled": PartiallyFilled,
+	"filled":           Filled,
+	"cancelled":        Cancelled,
+	"rejected":         Rejected,
+	"expired":          Expired,
+}
+
+type createOrderRequest struct {
+	Symbol        string         `json:"symbol"`
+	Type          string         `json:"type"`
+	Side          string         `json:"side"`
+	Price         *money.Decimal `json:"price,omitempty"`
+	StopPrice     *money.Decimal `json:"stop_price,omitempty"`
+	Size          money.Decimal  `json:"size"`
+	ClientOrderID string         `json:"client_order_id,omitempty"`
+	ExpiresAt     *time.Time     `json:"expires_at,omitempty"`
+	PostOnly      bool           `json:"post_only,omitempty"`
+	ReduceOnly    bool           `json:"reduce_only,omitempty"`
+	// TimeInForce is GTC, IOC or FOK, GTC when empty
+	TimeInForce string `json:"time_in_force,omitempty"`
+}
+
+// Trade is a fill of an order, as reported by the trades history endpoint
+// and the trades push topic
+type Trade struct {
+	AccountID     string         `json:"account_id,omitempty"`
+	OrderID       string         `json:"order_id"`
+	ClientOrderID string         `json:"client_order_id,omitempty"`
+	Symbol        string         `json:"symbol"`
+	Side          OrderSide      `json:"side"`
+	Price         *money.Decimal `json:"price"`
+	Size          money.Decimal  `json:"size"`
+	Status        OrderStatus    `json:"status"`
+	Time          time.Time      `json:"time"`
+}
+
+// NewTrade returns the trade of size filled on an order snapshot
+func NewTrade(o *Order, size money.Decimal) Trade {
+	return Trade{
+		AccountID:     o.AccountID,
+		OrderID:       o.ID,
+		ClientOrderID: o.ClientOrderID,
+		Symbol:        o.Symbol,
+		Side:          o.Side,
+		Price:         o.Price,
+		Size:          size,
+		Status:        o.Status,
+		Time:          o.UpdatedAt,
+	}
+}
+
+// Resolver selects the order manager a request acts on, such as a sandbox
+// order manager for sandbox API tokens
+type Resolver func(c *gin.Context) (OrderManager, error)
+
+// RegisterRoutes registers the order and trade history HTTP endpoints
+func RegisterRoutes(r gin.IRouter, m OrderManager) {
+	RegisterRoutesWithResolver(r, func(c *gin.Context) (OrderManager, error) {
+		return m, nil
+	})
+}
+
+// RegisterRoutesWithResolver registers the order and trade history HTTP
+// endpoints, acting on the order manager returned by resolve for each request
+func RegisterRoutesWithResolver(r gin.IRouter, resolve Resolver) {
+	r.POST("/orders", resolved(resolve, handleCreate))
+	r.GET("/orders", resolved(resolve, handleList))
+	r.GET("/orders/:id", resolved(resolve, handleGet))
+	r.DELETE("/orders/:id", resolved(resolve, handleCancel))
+	r.GET("/trades", resolved(resolve, handleTrades))
+}
+
+func resolved(resolve Resolver, handler func(OrderManager, *gin.Context)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		m, err := resolve(c)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		handler(m, c)
+	}
+}
+
+func handleCreate(m OrderManager, c *gin.Context) {
+	var req createOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	orderType, ok := orderTypes[req.Type]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be market, limit, stop_loss or take_profit"})
+		return
+	}
+	side, ok := orderSides[req.Side]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "side must be buy or sell"})
+		return
+	}
+	tif, err := ParseTimeInForce(req.TimeInForce)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	created, err := m.CreateOrder(ctx, CreateOrderParams{
+		Symbol:        req.Symbol,
+		Type:          orderType,
+		Side:          side,
+		Price:         req.Price,
+		StopPrice:     req.StopPrice,
+		Size:          req.Size,
+		ClientOrderID: req.ClientOrderID,
+		ExpiresAt:     req.ExpiresAt,
+		PostOnly:      req.PostOnly,
+		ReduceOnly:    req.ReduceOnly,
+		TimeInForce:   tif,
+	})
+	switch {
+	case errors.Is(err, ErrInvalidSymbol), errors.Is(err, ErrInvalidSize),
+		errors.Is(err, ErrInvalidPrice), errors.Is(err, ErrInvalidStopPrice),
+		errors.Is(err, ErrInvalidOrderFlags):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrReduceOnlyIncreases), errors.Is(err, ErrRiskRejected):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrDuplicateClientOrderID):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrTradingHalted), errors.Is(err, ErrSymbolDisabled), errors.Is(err, ErrShuttingDown):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		submitOrder(m, c, created)
+	}
+}
+
+// submitOrder places a created order on the venue of the manager, if it has
+// one. A replayed order that was already submitted is answered as is.
+func submitOrder(m OrderManager, c *gin.Context, created *Order) {
+	if created.Snapshot().Status != Created {
+		c.JSON(http.StatusCreated, created.Snapshot())
+		return
+	}
+	_, err := m.Submit(c.Request.Context(), created.ID)
+	switch {
+	case err == nil, errors.Is(err, ErrNoVenue), errors.Is(err, ErrSubmissionInProgress):
+		c.JSON(http.StatusCreated, created.Snapshot())
+	case errors.Is(err, ErrShuttingDown):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error(), "order": created.Snapshot()})
+	case errors.Is(err, ErrExecutionTimeout):
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": err.Error(), "order": created.Snapshot()})
+	default:
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "order": created.Snapshot()})
+	}
+}
+
+func handleList(m OrderManager, c *gin.Context) {
+	filter, err := parseFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if v := c.Query("type"); v != "" {
+		orderType, ok := orderTypes[v]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "type must be market, limit, stop_loss or take_profit"})
+			return
+		}
+		filter.Type = &orderType
+	}
+	if v := c.Query("status"); v != "" {
+		status, ok := orderStatuses[v]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown order status: " + v})
+			return
+		}
+		filter.Status = &status
+	}
+
+	orders, err := m.ListOrders(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp := make([]*Order, 0, len(orders))
+	for _, o := range orders {
+		resp = append(resp, o.Snapshot())
+	}
+	c.JSON(http.StatusOK, gin.H{"orders": resp})
+}
+
+func handleGet(m OrderManager, c *gin.Context) {
+	o, err := m.GetOrder(c.Request.Context(), c.Param("id"))
+	switch {
+	case errors.Is(err, ErrOrderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, o.Snapshot())
+	}
+}
+
+func handleCancel(m OrderManager, c *gin.Context) {
+	ctx := c.Request.Context()
+	err := m.CancelOrder(ctx, c.Param("id"))
+	switch {
+	case errors.Is(err, ErrOrderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrOrderNotCancellable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrVenueCancelFailed):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		handleGet(m, c)
+	}
+}
+
+// handleTrades returns the orders with fills, most recent first
+func handleTrades(m OrderManager, c *gin.Context) {
+	filter, err := parseFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	orders, err := m.ListOrders(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	trades := make([]Trade, 0, len(orders))
+	for _, o := range orders {
+		snapshot := o.Snapshot()
+		if snapshot.FilledSize.Sign() <= 0 {
+			continue
+		}
+		trades = append(trades, NewTrade(snapshot, snapshot.FilledSize))
+	}
+	sort.Slice(trades, func(i, j int) bool { return trades[i].Time.After(trades[j].Time) })
+
+	c.JSON(http.StatusOK, gin.H{"trades": trades})
+}
+
+// parseFilter reads the symbol, side, start and end query parameters shared
+// by the order and trade listings
+func parseFilter(c *gin.Context) (OrderFilter, error) {
+	filter := OrderFilter{Symbol: c.Query("symbol")}
+	if v := c.Query("side"); v != "" {
+		side, ok := orderSides[v]
+		if !ok {
+			return filter, errors.New("side must be buy or sell")
+		}
+		filter.Side = &side
+	}
+	var err error
+	if filter.StartTime, err = parseTime(c, "start"); err != nil {
+		return filter, err
+	}
+	if filter.EndTime, err = parseTime(c, "end"); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+// parseTime reads an optional RFC 3339 query parameter
+func parseTime(c *gin.Context, name string) (*time.Time, error) {
+	v := c.Query(name)
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return nil, errors.New(name + " must be an RFC 3339 timestamp")
+	}
+	return &t, nil
+}
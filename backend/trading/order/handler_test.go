@@ -0,0 +1,145 @@
+package order
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+)
+
+func TestOrderHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	manager := NewOrderManager()
+
+	r := gin.New()
+	RegisterRoutes(r.Group("/api/v1"), manager)
+
+	call := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	var created Order
+	t.Run("Create order", func(t *testing.T) {
+		w := call(http.MethodPost, "/api/v1/orders", `{"symbol":"BTC/USD","type":"limit","side":"buy","price":50000,"size":1,"client_order_id":"api-1"}`)
+		require.Equal(t, http.StatusCreated, w.Code)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+		assert.Equal(t, "BTC/USD", created.Symbol)
+		assert.Equal(t, Limit, created.Type)
+		assert.Equal(t, Buy, created.Side)
+		assert.Equal(t, Created, created.Status)
+	})
+
+	t.Run("Replayed create returns the same order", func(t *testing.T) {
+		w := call(http.MethodPost, "/api/v1/orders", `{"symbol":"BTC/USD","type":"limit","side":"buy","price":50000,"size":1,"client_order_id":"api-1"}`)
+		require.Equal(t, http.StatusCreated, w.Code)
+		var replayed Order
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &replayed))
+		assert.Equal(t, created.ID, replayed.ID)
+
+		w = call(http.MethodPost, "/api/v1/orders", `{"symbol":"BTC/USD","type":"limit","side":"buy","price":50000,"size":3,"client_order_id":"api-1"}`)
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("Invalid orders are rejected", func(t *testing.T) {
+		for _, body := range []string{
+			`{"symbol":"BTC/USD","type":"limit","side":"hold","price":50000,"size":1}`,
+			`{"symbol":"BTC/USD","type":"iceberg","side":"buy","size":1}`,
+			`{"symbol":"BTC/USD","type":"limit","side":"buy","size":1}`,
+			`{"symbol":"","type":"market","side":"buy","size":1}`,
+			`{"symbol":"BTC/USD","type":"market","side":"buy","size":-1}`,
+			`{"symbol":`,
+		} {
+			w := call(http.MethodPost, "/api/v1/orders", body)
+			assert.Equal(t, http.StatusBadRequest, w.Code, body)
+			assert.Contains(t, w.Body.String(), `"error"`)
+		}
+	})
+
+	t.Run("List and get orders", func(t *testing.T) {
+		w := call(http.MethodGet, "/api/v1/orders?symbol=BTC/USD&side=buy&type=limit&status=created", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Orders []*Order `json:"orders"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.Orders, 1)
+		assert.Equal(t, created.ID, resp.Orders[0].ID)
+
+		assert.Equal(t, http.StatusOK, call(http.MethodGet, "/api/v1/orders/"+created.ID, "").Code)
+		assert.Equal(t, http.StatusNotFound, call(http.MethodGet, "/api/v1/orders/missing", "").Code)
+		assert.Equal(t, http.StatusBadRequest, call(http.MethodGet, "/api/v1/orders?status=open", "").Code)
+		assert.Equal(t, http.StatusBadRequest, call(http.MethodGet, "/api/v1/orders?start=yesterday", "").Code)
+	})
+
+	t.Run("Trade history lists filled orders", func(t *testing.T) {
+		w := call(http.MethodPost, "/api/v1/orders", `{"symbol":"ETH/USD","type":"market","side":"sell","size":2}`)
+		require.Equal(t, http.StatusCreated, w.Code)
+		var filled Order
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &filled))
+		require.NoError(t, manager.UpdateOrderStatus(ctx, filled.ID, Pending))
+		require.NoError(t, manager.UpdateFilledSize(ctx, filled.ID, money.NewFromInt(2)))
+
+		w = call(http.MethodGet, "/api/v1/trades", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Trades []Trade `json:"trades"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.Trades, 1)
+		assert.Equal(t, filled.ID, resp.Trades[0].OrderID)
+		assert.Equal(t, 2.0, resp.Trades[0].Size.Float64())
+		assert.Equal(t, Sell, resp.Trades[0].Side)
+	})
+
+	t.Run("Cancel order", func(t *testing.T) {
+		w := call(http.MethodDelete, "/api/v1/orders/"+created.ID, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var cancelled Order
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &cancelled))
+		assert.Equal(t, Cancelled, cancelled.Status)
+
+		assert.Equal(t, http.StatusConflict, call(http.MethodDelete, "/api/v1/orders/"+created.ID, "").Code)
+		assert.Equal(t, http.StatusNotFound, call(http.MethodDelete, "/api/v1/orders/missing", "").Code)
+	})
+
+	t.Run("Halted trading", func(t *testing.T) {
+		manager.Halt("maintenance")
+		defer manager.Resume()
+		w := call(http.MethodPost, "/api/v1/orders", `{"symbol":"BTC/USD","type":"market","side":"buy","size":1}`)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	})
+}
+
+func TestCreateOrderSubmission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	venue := &fakeVenue{delay: 100 * time.Millisecond, cancelled: make(chan string, 2)}
+	manager := NewOrderManager(WithVenue("dydx", venue), WithExecutionBudget(10*time.Millisecond))
+	r := gin.New()
+	RegisterRoutes(r, manager)
+
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"symbol":"BTC/USD","type":"market","side":"buy","size":1}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusGatewayTimeout, w.Code)
+	var resp struct {
+		Error string `json:"error"`
+		Order Order  `json:"order"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, ErrExecutionTimeout.Error(), resp.Error)
+	assert.Equal(t, Expired, resp.Order.Status)
+}
@@ -2,10 +2,14 @@ package order
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
 	"sync"
 	"time"
 
-"github.com/devinjacknz/godydxhyber/backend/trading/analysis/monitoring"
+	"github.com/devinjacknz/godydxhyber/backend/logger"
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+	"github.com/devinjacknz/godydxhyber/backend/trading/analysis/monitoring"
 )
 
 // OrderType represents the type of order
@@ -18,6 +22,16 @@ const (
 	TakeProfit
 )
 
+// String returns the name of the order type
+func (t OrderType) String() string {
+	for name, orderType := range orderTypes {
+		if orderType == t {
+			return name
+		}
+	}
+	return "unknown"
+}
+
 // OrderStatus represents the status of an order
 type OrderStatus int
 
@@ -31,6 +45,16 @@ const (
 	Expired
 )
 
+// String returns the name of the status
+func (s OrderStatus) String() string {
+	for name, status := range orderStatuses {
+		if status == s {
+			return name
+		}
+	}
+	return "unknown"
+}
+
 // OrderSide represents the side of an order (buy/sell)
 type OrderSide int
 
@@ -39,23 +63,48 @@ const (
 	Sell
 )
 
+// String returns the name of the side
+func (s OrderSide) String() string {
+	for name, side := range orderSides {
+		if side == s {
+			return name
+		}
+	}
+	return "unknown"
+}
+
 // Order represents a trading order
 type Order struct {
 	ID            string
 	Symbol        string
 	Type          OrderType
 	Side          OrderSide
-	Price         *float64 // nil for market orders
-	StopPrice     *float64 // for stop loss/take profit orders
-	Size          float64
-	FilledSize    float64
-	RemainingSize float64
+	Price         *money.Decimal // nil for market orders
+	StopPrice     *money.Decimal // for stop loss/take profit orders
+	Size          money.Decimal
+	FilledSize    money.Decimal
+	RemainingSize money.Decimal
 	Status        OrderStatus
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
 	ExpiresAt     *time.Time
 	ClientOrderID string
-	mu            sync.RWMutex
+	// PostOnly orders are cancelled rather than take liquidity
+	PostOnly bool
+	// ReduceOnly orders may only reduce the position on the symbol
+	ReduceOnly  bool
+	TimeInForce TimeInForce
+	// ExchangeOrderID is the ID the venue acknowledged the order with
+	ExchangeOrderID string
+	// AccountID is the trading account the order was placed for
+	AccountID string
+	// CorrelationID is the correlation ID of the context that created the
+	// order, carried by the events of the order
+	CorrelationID string
+	// NeedsReconciliation is set when the order was still being submitted
+	// to the exchange at shutdown, so it may or may not have been placed
+	NeedsReconciliation bool
+	mu                  sync.RWMutex
 }
 
 // OrderManager interface defines the contract for order management
@@ -65,7 +114,26 @@ type OrderManager interface {
 	GetOrder(ctx context.Context, orderID string) (*Order, error)
 	ListOrders(ctx context.Context, filter OrderFilter) ([]*Order, error)
 	UpdateOrderStatus(ctx context.Context, orderID string, status OrderStatus) error
-	UpdateFilledSize(ctx context.Context, orderID string, filledSize float64) error
+	UpdateFilledSize(ctx context.Context, orderID string, filledSize money.Decimal) error
+
+	// Trading halt
+	Halt(reason string)
+	Resume()
+	IsHalted() (bool, string)
+
+	// Persistence
+	Recover(ctx context.Context) (*RecoveryReport, error)
+	Restore(ctx context.Context, orders []*Order) (*RecoveryReport, error)
+
+	// Exchange submission
+	Submit(ctx context.Context, orderID string) (*Order, error)
+
+	// Expiry
+	ExpireOrders(ctx context.Context) ([]string, error)
+
+	// Shutdown barrier
+	BeginSubmission(orderID string) (func(), error)
+	Shutdown(ctx context.Context) (*ShutdownReport, error)
 }
 
 // CreateOrderParams contains parameters for creating an order
@@ -73,11 +141,14 @@ type CreateOrderParams struct {
 	Symbol        string
 	Type          OrderType
 	Side          OrderSide
-	Price         *float64
-	StopPrice     *float64
-	Size          float64
+	Price         *money.Decimal
+	StopPrice     *money.Decimal
+	Size          money.Decimal
 	ClientOrderID string
 	ExpiresAt     *time.Time
+	PostOnly      bool
+	ReduceOnly    bool
+	TimeInForce   TimeInForce
 }
 
 // OrderFilter contains filters for listing orders
@@ -92,18 +163,48 @@ type OrderFilter struct {
 
 // DefaultOrderManager implements OrderManager interface
 type DefaultOrderManager struct {
-	orders map[string]*Order
-	mu     sync.RWMutex
+	orders      map[string]*Order
+	accountID   string
+	store       Store
+	exchange    OpenOrderSource
+	positions   PositionSource
+	gate        SymbolGate
+	preTrade    PreTradeCheck
+	listener    Listener
+	clientIDs   map[string]*Order
+	dedupWindow time.Duration
+	halted      bool
+	haltReason  string
+	inflight    map[string]int
+	closing     bool
+	drained     chan struct{}
+	mu          sync.RWMutex
+
+	// venue receives submitted orders, which it has executionBudget to
+	// acknowledge
+	venue           Venue
+	venueName       string
+	executionBudget time.Duration
 }
 
 // NewOrderManager creates a new order manager instance
-func NewOrderManager() OrderManager {
-	return &DefaultOrderManager{
-		orders: make(map[string]*Order),
+func NewOrderManager(opts ...ManagerOption) OrderManager {
+	m := &DefaultOrderManager{
+		orders:      make(map[string]*Order),
+		clientIDs:   make(map[string]*Order),
+		dedupWindow: DefaultDedupWindow,
+		inflight:    make(map[string]int),
+
+		executionBudget: DefaultExecutionBudget,
+	}
+	for _, opt := range opts {
+		opt(m)
 	}
+	return m
 }
 
-// CreateOrder creates a new order
+// CreateOrder creates a new order. Retrying a request with the same client
+// order ID within the dedup window returns the order already created.
 func (m *DefaultOrderManager) CreateOrder(ctx context.Context, params CreateOrderParams) (*Order, error) {
 	start := time.Now()
 	defer func() {
@@ -115,9 +216,30 @@ func (m *DefaultOrderManager) CreateOrder(ctx context.Context, params CreateOrde
 		monitoring.RecordIndicatorError("create_order", err.Error())
 		return nil, err
 	}
+	if err := m.checkReduceOnly(ctx, params); err != nil {
+		monitoring.RecordIndicatorError("create_order", "reduce-only rejected")
+		return nil, err
+	}
+	if err := m.checkGate(params); err != nil {
+		monitoring.RecordIndicatorError("create_order", "symbol disabled")
+		return nil, err
+	}
+	// A retried request returns its order even when the limits have since
+	// been reached, as by its own fills
+	m.mu.Lock()
+	existing, err := m.replay(params)
+	m.mu.Unlock()
+	if existing != nil || err != nil {
+		return existing, err
+	}
+	if err := m.checkPreTrade(ctx, params); err != nil {
+		monitoring.RecordIndicatorError("create_order", "risk rejected")
+		return nil, err
+	}
 
 	order := &Order{
 		ID:            generateOrderID(),
+		AccountID:     m.accountID,
 		Symbol:        params.Symbol,
 		Type:          params.Type,
 		Side:          params.Side,
@@ -130,17 +252,62 @@ func (m *DefaultOrderManager) CreateOrder(ctx context.Context, params CreateOrde
 		UpdatedAt:     time.Now(),
 		ExpiresAt:     params.ExpiresAt,
 		ClientOrderID: params.ClientOrderID,
+		PostOnly:      params.PostOnly,
+		ReduceOnly:    params.ReduceOnly,
+		TimeInForce:   params.TimeInForce,
+		CorrelationID: logger.CorrelationID(ctx),
+	}
+
+	m.mu.Lock()
+	if existing, err := m.replay(params); existing != nil || err != nil {
+		m.mu.Unlock()
+		return existing, err
+	}
+	if m.closing {
+		m.mu.Unlock()
+		monitoring.RecordIndicatorError("create_order", "shutting down")
+		return nil, ErrShuttingDown
 	}
+	if m.halted {
+		m.mu.Unlock()
+		monitoring.RecordIndicatorError("create_order", "trading halted")
+		return nil, ErrTradingHalted
+	}
+	// Reserve the client order ID so a retry cannot create the order twice
+	// while it is saved without m.mu
+	m.index(order)
+	m.mu.Unlock()
+
+	order.mu.Lock()
+	err = m.persist(ctx, order)
+	order.mu.Unlock()
 
 	m.mu.Lock()
+	if err != nil {
+		if m.clientIDs[order.ClientOrderID] == order {
+			delete(m.clientIDs, order.ClientOrderID)
+		}
+		m.mu.Unlock()
+		return nil, err
+	}
 	m.orders[order.ID] = order
+	active := len(m.orders)
 	m.mu.Unlock()
 
-	monitoring.RecordIndicatorValue("active_orders", float64(len(m.orders)))
+	slog.InfoContext(ctx, "order created",
+		"order_id", order.ID,
+		"client_order_id", order.ClientOrderID,
+		"symbol", order.Symbol,
+		"side", order.Side.String(),
+		"size", order.Size)
+	monitoring.RecordIndicatorValue("active_orders", float64(active))
 	return order, nil
 }
 
-// CancelOrder cancels an existing order
+// CancelOrder cancels an existing order. An order the venue acknowledged is
+// cancelled there first and only marked Cancelled once the venue confirms;
+// an order still being submitted is cancelled on the venue when its
+// acknowledgement arrives.
 func (m *DefaultOrderManager) CancelOrder(ctx context.Context, orderID string) error {
 	start := time.Now()
 	defer func() {
@@ -148,23 +315,40 @@ func (m *DefaultOrderManager) CancelOrder(ctx context.Context, orderID string) e
 		monitoring.RecordIndicatorCalculation("cancel_order", duration)
 	}()
 
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
+	m.mu.RLock()
 	order, exists := m.orders[orderID]
+	m.mu.RUnlock()
+
 	if !exists {
 		return ErrOrderNotFound
 	}
 
+	current := order.Snapshot()
+	if !isOrderCancellable(current.Status) {
+		return ErrOrderNotCancellable
+	}
+	if m.venue != nil && current.ExchangeOrderID != "" {
+		if err := m.venue.CancelOrder(ctx, current.ExchangeOrderID); err != nil {
+			monitoring.RecordIndicatorError("cancel_order", "venue cancel failed")
+			return fmt.Errorf("%w: %s: %w", ErrVenueCancelFailed, m.venueName, err)
+		}
+	}
+
 	order.mu.Lock()
 	defer order.mu.Unlock()
 
+	// Filled while the venue cancelled it
 	if !isOrderCancellable(order.Status) {
 		return ErrOrderNotCancellable
 	}
 
-	order.Status = Cancelled
-	order.UpdatedAt = time.Now()
+	err := m.update(ctx, order, money.Zero, func(next *Order) {
+		next.Status = Cancelled
+		next.UpdatedAt = time.Now()
+	})
+	if err != nil {
+		return err
+	}
 
 	monitoring.RecordIndicatorValue("cancelled_orders", 1)
 	return nil
@@ -221,15 +405,20 @@ func (m *DefaultOrderManager) UpdateOrderStatus(ctx context.Context, orderID str
 		return ErrInvalidStatusTransition
 	}
 
-	order.Status = status
-	order.UpdatedAt = time.Now()
+	err := m.update(ctx, order, money.Zero, func(next *Order) {
+		next.Status = status
+		next.UpdatedAt = time.Now()
+	})
+	if err != nil {
+		return err
+	}
 
 	monitoring.RecordIndicatorValue("order_status_updates", 1)
 	return nil
 }
 
 // UpdateFilledSize updates the filled size of an order
-func (m *DefaultOrderManager) UpdateFilledSize(ctx context.Context, orderID string, filledSize float64) error {
+func (m *DefaultOrderManager) UpdateFilledSize(ctx context.Context, orderID string, filledSize money.Decimal) error {
 	start := time.Now()
 	defer func() {
 		duration := time.Since(start)
@@ -251,29 +440,127 @@ func (m *DefaultOrderManager) UpdateFilledSize(ctx context.Context, orderID stri
 		return ErrOrderNotFillable
 	}
 
-	if filledSize > order.RemainingSize {
+	if filledSize.Cmp(order.RemainingSize) > 0 {
 		return ErrInvalidFilledSize
 	}
 
-	order.FilledSize += filledSize
-	order.RemainingSize -= filledSize
-	order.UpdatedAt = time.Now()
+	err := m.update(ctx, order, filledSize, func(next *Order) {
+		next.FilledSize = next.FilledSize.Add(filledSize)
+		next.RemainingSize = next.RemainingSize.Sub(filledSize)
+		next.UpdatedAt = time.Now()
 
-	if order.RemainingSize == 0 {
-		order.Status = Filled
-	} else {
-		order.Status = PartiallyFilled
+		if next.RemainingSize.IsZero() {
+			next.Status = Filled
+		} else {
+			next.Status = PartiallyFilled
+		}
+	})
+	if err != nil {
+		return err
 	}
 
-	monitoring.RecordIndicatorValue("filled_size", filledSize)
+	monitoring.RecordIndicatorValue("filled_size", filledSize.Float64())
 	return nil
 }
 
+// Halt blocks creation of new orders until Resume is called
+func (m *DefaultOrderManager) Halt(reason string) {
+	m.mu.Lock()
+	m.halted = true
+	m.haltReason = reason
+	m.mu.Unlock()
+
+	monitoring.RecordIndicatorValue("order_creation_halted", 1)
+}
+
+// Resume re-enables order creation after a halt
+func (m *DefaultOrderManager) Resume() {
+	m.mu.Lock()
+	m.halted = false
+	m.haltReason = ""
+	m.mu.Unlock()
+
+	monitoring.RecordIndicatorValue("order_creation_halted", 0)
+}
+
+// IsHalted reports whether order creation is halted and why
+func (m *DefaultOrderManager) IsHalted() (bool, string) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.halted, m.haltReason
+}
+
+// Snapshot returns a copy of the order that does not share state with it
+func (o *Order) Snapshot() *Order {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	return o.snapshot()
+}
+
+// snapshot copies the order. Callers hold o.mu.
+func (o *Order) snapshot() *Order {
+	c := &Order{}
+	c.set(o)
+	return c
+}
+
+// set copies the fields of from into the order. Callers hold the locks of
+// both orders, or own them.
+func (o *Order) set(from *Order) {
+	o.ID = from.ID
+	o.AccountID = from.AccountID
+	o.Symbol = from.Symbol
+	o.Type = from.Type
+	o.Side = from.Side
+	o.Price = copyDecimal(from.Price)
+	o.StopPrice = copyDecimal(from.StopPrice)
+	o.Size = from.Size
+	o.CreatedAt = from.CreatedAt
+	o.ExpiresAt = copyTime(from.ExpiresAt)
+	o.ClientOrderID = from.ClientOrderID
+	o.PostOnly = from.PostOnly
+	o.ReduceOnly = from.ReduceOnly
+	o.TimeInForce = from.TimeInForce
+	o.CorrelationID = from.CorrelationID
+	o.apply(from)
+}
+
+// apply copies the fields that change after an order is created from from
+// into the order. The terms of the order are left alone, so fields such as
+// the ID and symbol may be read without the order's lock. Callers hold the
+// order's lock.
+func (o *Order) apply(from *Order) {
+	o.FilledSize = from.FilledSize
+	o.RemainingSize = from.RemainingSize
+	o.Status = from.Status
+	o.UpdatedAt = from.UpdatedAt
+
+	o.ExchangeOrderID = from.ExchangeOrderID
+	o.NeedsReconciliation = from.NeedsReconciliation
+}
+
+func copyDecimal(v *money.Decimal) *money.Decimal {
+	if v == nil {
+		return nil
+	}
+	c := *v
+	return &c
+}
+
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
+
 func validateCreateParams(params CreateOrderParams) error {
 	if params.Symbol == "" {
 		return ErrInvalidSymbol
 	}
-	if params.Size <= 0 {
+	if params.Size.Sign() <= 0 {
 		return ErrInvalidSize
 	}
 	if params.Type == Limit && params.Price == nil {
@@ -282,7 +569,7 @@ func validateCreateParams(params CreateOrderParams) error {
 	if (params.Type == StopLoss || params.Type == TakeProfit) && params.StopPrice == nil {
 		return ErrInvalidStopPrice
 	}
-	return nil
+	return validateFlags(params)
 }
 
 func isOrderCancellable(status OrderStatus) bool {
@@ -291,7 +578,7 @@ func isOrderCancellable(status OrderStatus) bool {
 
 func isValidStatusTransition(from, to OrderStatus) bool {
 	validTransitions := map[OrderStatus][]OrderStatus{
-		Created:         {Pending, Rejected, Cancelled},
+		Created:         {Pending, Rejected, Cancelled, Expired},
 		Pending:         {PartiallyFilled, Filled, Cancelled, Rejected, Expired},
 		PartiallyFilled: {Filled, Cancelled, Expired},
 	}
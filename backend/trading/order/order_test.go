@@ -2,10 +2,15 @@ package order
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/devinjacknz/godydxhyber/backend/exchange/dydx"
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestOrderManager(t *testing.T) {
@@ -13,13 +18,13 @@ func TestOrderManager(t *testing.T) {
 	ctx := context.Background()
 
 	t.Run("Create order", func(t *testing.T) {
-		price := 50000.0
+		price := money.FromFloat(50000.0)
 		params := CreateOrderParams{
 			Symbol:        "BTC/USD",
 			Type:          Limit,
 			Side:          Buy,
 			Price:         &price,
-			Size:          1.0,
+			Size:          money.FromFloat(1.0),
 			ClientOrderID: "test-order-1",
 		}
 
@@ -37,9 +42,9 @@ func TestOrderManager(t *testing.T) {
 
 	t.Run("Create order with invalid parameters", func(t *testing.T) {
 		invalidParams := []CreateOrderParams{
-			{Symbol: "", Type: Market, Side: Buy, Size: 1.0},
-			{Symbol: "BTC/USD", Type: Market, Side: Buy, Size: 0},
-			{Symbol: "BTC/USD", Type: Limit, Side: Buy, Size: 1.0}, // Missing price for limit order
+			{Symbol: "", Type: Market, Side: Buy, Size: money.FromFloat(1.0)},
+			{Symbol: "BTC/USD", Type: Market, Side: Buy, Size: money.NewFromInt(0)},
+			{Symbol: "BTC/USD", Type: Limit, Side: Buy, Size: money.FromFloat(1.0)}, // Missing price for limit order
 		}
 
 		for _, params := range invalidParams {
@@ -51,13 +56,13 @@ func TestOrderManager(t *testing.T) {
 
 	t.Run("Update order status", func(t *testing.T) {
 		// Create a new order
-		price := 3000.0
+		price := money.FromFloat(3000.0)
 		params := CreateOrderParams{
 			Symbol:        "ETH/USD",
 			Type:          Limit,
 			Side:          Sell,
 			Price:         &price,
-			Size:          2.0,
+			Size:          money.FromFloat(2.0),
 			ClientOrderID: "test-order-2",
 		}
 
@@ -83,13 +88,13 @@ func TestOrderManager(t *testing.T) {
 
 	t.Run("Cancel order", func(t *testing.T) {
 		// Create a new order
-		price := 100.0
+		price := money.FromFloat(100.0)
 		params := CreateOrderParams{
 			Symbol:        "SOL/USD",
 			Type:          Limit,
 			Side:          Buy,
 			Price:         &price,
-			Size:          10.0,
+			Size:          money.FromFloat(10.0),
 			ClientOrderID: "test-order-3",
 		}
 
@@ -113,13 +118,13 @@ func TestOrderManager(t *testing.T) {
 
 	t.Run("Update filled size", func(t *testing.T) {
 		// Create a new order
-		price := 200.0
+		price := money.FromFloat(200.0)
 		params := CreateOrderParams{
 			Symbol:        "DOT/USD",
 			Type:          Limit,
 			Side:          Buy,
 			Price:         &price,
-			Size:          5.0,
+			Size:          money.FromFloat(5.0),
 			ClientOrderID: "test-order-4",
 		}
 
@@ -131,27 +136,27 @@ func TestOrderManager(t *testing.T) {
 		assert.NoError(t, err)
 
 		// Test partial fill
-		err = manager.UpdateFilledSize(ctx, order.ID, 2.0)
+		err = manager.UpdateFilledSize(ctx, order.ID, money.FromFloat(2.0))
 		assert.NoError(t, err)
 
 		partialOrder, err := manager.GetOrder(ctx, order.ID)
 		assert.NoError(t, err)
 		assert.Equal(t, PartiallyFilled, partialOrder.Status)
-		assert.Equal(t, 2.0, partialOrder.FilledSize)
-		assert.Equal(t, 3.0, partialOrder.RemainingSize)
+		assert.Equal(t, 2.0, partialOrder.FilledSize.Float64())
+		assert.Equal(t, 3.0, partialOrder.RemainingSize.Float64())
 
 		// Test complete fill
-		err = manager.UpdateFilledSize(ctx, order.ID, 3.0)
+		err = manager.UpdateFilledSize(ctx, order.ID, money.FromFloat(3.0))
 		assert.NoError(t, err)
 
 		filledOrder, err := manager.GetOrder(ctx, order.ID)
 		assert.NoError(t, err)
 		assert.Equal(t, Filled, filledOrder.Status)
-		assert.Equal(t, 5.0, filledOrder.FilledSize)
-		assert.Equal(t, 0.0, filledOrder.RemainingSize)
+		assert.Equal(t, 5.0, filledOrder.FilledSize.Float64())
+		assert.Equal(t, 0.0, filledOrder.RemainingSize.Float64())
 
 		// Test overfill
-		err = manager.UpdateFilledSize(ctx, order.ID, 1.0)
+		err = manager.UpdateFilledSize(ctx, order.ID, money.FromFloat(1.0))
 		assert.Error(t, err)
 		assert.Equal(t, ErrOrderNotFillable, err)
 	})
@@ -161,10 +166,10 @@ func TestOrderManager(t *testing.T) {
 		symbols := []string{"BTC/USD", "ETH/USD", "SOL/USD"}
 		sides := []OrderSide{Buy, Sell, Buy}
 		types := []OrderType{Market, Limit, StopLoss}
-		prices := []float64{60000.0, 4000.0, 200.0}
+		prices := []money.Decimal{money.FromFloat(60000.0), money.FromFloat(4000.0), money.FromFloat(200.0)}
 
 		for i := range symbols {
-			var price *float64
+			var price *money.Decimal
 			if types[i] != Market {
 				price = &prices[i]
 			}
@@ -174,7 +179,7 @@ func TestOrderManager(t *testing.T) {
 				Type:          types[i],
 				Side:          sides[i],
 				Price:         price,
-				Size:          1.0,
+				Size:          money.FromFloat(1.0),
 				ClientOrderID: "test-order-list-" + symbols[i],
 			}
 
@@ -241,7 +246,7 @@ func TestConcurrency(t *testing.T) {
 
 	t.Run("Concurrent order creation", func(t *testing.T) {
 		done := make(chan bool)
-		price := 50000.0
+		price := money.FromFloat(50000.0)
 
 		for i := 0; i < numOperations; i++ {
 			go func(idx int) {
@@ -250,7 +255,7 @@ func TestConcurrency(t *testing.T) {
 					Type:          Limit,
 					Side:          Buy,
 					Price:         &price,
-					Size:          1.0,
+					Size:          money.FromFloat(1.0),
 					ClientOrderID: "test-concurrent-" + string(rune(idx)),
 				}
 
@@ -270,13 +275,13 @@ func TestConcurrency(t *testing.T) {
 	})
 
 	t.Run("Concurrent order updates", func(t *testing.T) {
-		price := 3000.0
+		price := money.FromFloat(3000.0)
 		params := CreateOrderParams{
 			Symbol:        "ETH/USD",
 			Type:          Limit,
 			Side:          Buy,
 			Price:         &price,
-			Size:          1.0,
+			Size:          money.FromFloat(1.0),
 			ClientOrderID: "test-concurrent-updates",
 		}
 
@@ -289,7 +294,7 @@ func TestConcurrency(t *testing.T) {
 		done := make(chan bool)
 		for i := 0; i < numOperations; i++ {
 			go func() {
-				err := manager.UpdateFilledSize(ctx, order.ID, 0.01)
+				err := manager.UpdateFilledSize(ctx, order.ID, money.FromFloat(0.01))
 				if err != nil && err != ErrOrderNotFillable {
 					assert.NoError(t, err)
 				}
@@ -303,6 +308,750 @@ func TestConcurrency(t *testing.T) {
 
 		updatedOrder, err := manager.GetOrder(ctx, order.ID)
 		assert.NoError(t, err)
-		assert.True(t, updatedOrder.FilledSize > 0)
+		assert.True(t, updatedOrder.FilledSize.Sign() > 0)
 	})
 }
+
+type memoryOrderStore struct {
+	orders map[string]*Order
+	// err fails every save when set
+	err error
+}
+
+func (s *memoryOrderStore) SaveOrder(ctx context.Context, order *Order) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.orders[order.ID] = copyOrder(order)
+	return nil
+}
+
+func (s *memoryOrderStore) LoadOpenOrders(ctx context.Context) ([]*Order, error) {
+	var orders []*Order
+	for _, order := range s.orders {
+		if isOrderCancellable(order.Status) {
+			orders = append(orders, copyOrder(order))
+		}
+	}
+	return orders, nil
+}
+
+func copyOrder(order *Order) *Order {
+	return &Order{
+		ID:            order.ID,
+		Symbol:        order.Symbol,
+		Type:          order.Type,
+		Side:          order.Side,
+		Price:         order.Price,
+		StopPrice:     order.StopPrice,
+		Size:          order.Size,
+		FilledSize:    order.FilledSize,
+		RemainingSize: order.RemainingSize,
+		Status:        order.Status,
+		CreatedAt:     order.CreatedAt,
+		UpdatedAt:     order.UpdatedAt,
+		ExpiresAt:     order.ExpiresAt,
+		ClientOrderID: order.ClientOrderID,
+
+		ExchangeOrderID:     order.ExchangeOrderID,
+		NeedsReconciliation: order.NeedsReconciliation,
+	}
+}
+
+// slowOrderStore holds the saves of the orders of symbol until release is
+// closed, signalling saving as each one starts
+type slowOrderStore struct {
+	memoryOrderStore
+	mu      sync.Mutex
+	symbol  string
+	saving  chan struct{}
+	release chan struct{}
+}
+
+func (s *slowOrderStore) SaveOrder(ctx context.Context, order *Order) error {
+	if order.Symbol == s.symbol {
+		s.saving <- struct{}{}
+		<-s.release
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.memoryOrderStore.SaveOrder(ctx, order)
+}
+
+type fakeOpenOrderSource struct {
+	orders []dydx.Order
+}
+
+func (s *fakeOpenOrderSource) GetOpenOrders(ctx context.Context) ([]dydx.Order, error) {
+	return s.orders, nil
+}
+
+func TestOrderRecovery(t *testing.T) {
+	ctx := context.Background()
+	price := money.FromFloat(100.0)
+
+	t.Run("Orders are persisted and reloaded", func(t *testing.T) {
+		store := &memoryOrderStore{orders: make(map[string]*Order)}
+		manager := NewOrderManager(WithStore(store))
+
+		order, err := manager.CreateOrder(ctx, CreateOrderParams{
+			Symbol: "BTC-USD", Type: Limit, Side: Buy, Price: &price, Size: money.FromFloat(1.0),
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, manager.UpdateOrderStatus(ctx, order.ID, Pending))
+		assert.Equal(t, Pending, store.orders[order.ID].Status)
+
+		restarted := NewOrderManager(WithStore(store))
+		report, err := restarted.Recover(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, report.Loaded)
+
+		recovered, err := restarted.GetOrder(ctx, order.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, Pending, recovered.Status)
+	})
+
+	t.Run("Recover reconciles against exchange", func(t *testing.T) {
+		store := &memoryOrderStore{orders: make(map[string]*Order)}
+		manager := NewOrderManager(WithStore(store))
+
+		live, err := manager.CreateOrder(ctx, CreateOrderParams{
+			Symbol: "BTC-USD", Type: Limit, Side: Buy, Price: &price, Size: money.FromFloat(2.0), ClientOrderID: "live",
+		})
+		assert.NoError(t, err)
+		gone, err := manager.CreateOrder(ctx, CreateOrderParams{
+			Symbol: "BTC-USD", Type: Limit, Side: Sell, Price: &price, Size: money.FromFloat(1.0), ClientOrderID: "gone",
+		})
+		assert.NoError(t, err)
+
+		exchange := &fakeOpenOrderSource{orders: []dydx.Order{
+			{ID: "ex-1", ClientID: "live", Market: "BTC-USD", Type: "LIMIT", Side: "BUY", Price: 100.0, Size: 2.0, FilledSize: 0.5, RemainingSize: 1.5},
+			{ID: "ex-2", Market: "ETH-USD", Type: "LIMIT", Side: "SELL", Price: 3000.0, Size: 1.0, RemainingSize: 1.0},
+		}}
+
+		restarted := NewOrderManager(WithStore(store), WithExchange(exchange))
+		report, err := restarted.Recover(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, report.Loaded)
+		assert.Equal(t, []string{"ex-2"}, report.Adopted)
+		assert.Equal(t, []string{gone.ID}, report.Expired)
+
+		synced, err := restarted.GetOrder(ctx, live.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, PartiallyFilled, synced.Status)
+		assert.Equal(t, 1.5, synced.RemainingSize.Float64())
+
+		adopted, err := restarted.GetOrder(ctx, "ex-2")
+		assert.NoError(t, err)
+		assert.Equal(t, Sell, adopted.Side)
+		assert.Equal(t, Pending, adopted.Status)
+
+		assert.Equal(t, Expired, store.orders[gone.ID].Status)
+	})
+
+	t.Run("Failed saves leave the order unchanged", func(t *testing.T) {
+		store := &memoryOrderStore{orders: make(map[string]*Order)}
+		manager := NewOrderManager(WithStore(store))
+
+		order, err := manager.CreateOrder(ctx, CreateOrderParams{
+			Symbol: "BTC-USD", Type: Limit, Side: Buy, Price: &price, Size: money.FromFloat(2.0),
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, manager.UpdateOrderStatus(ctx, order.ID, Pending))
+
+		store.err = errors.New("store unavailable")
+		assert.ErrorIs(t, manager.UpdateFilledSize(ctx, order.ID, money.FromFloat(1.0)), store.err)
+		assert.ErrorIs(t, manager.CancelOrder(ctx, order.ID), store.err)
+
+		unchanged, err := manager.GetOrder(ctx, order.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, Pending, unchanged.Status)
+		assert.Equal(t, 0.0, unchanged.FilledSize.Float64())
+		assert.Equal(t, 2.0, unchanged.RemainingSize.Float64())
+
+		params := CreateOrderParams{
+			Symbol: "BTC-USD", Type: Limit, Side: Buy, Price: &price, Size: money.FromFloat(1.0), ClientOrderID: "retried",
+		}
+		_, err = manager.CreateOrder(ctx, params)
+		assert.ErrorIs(t, err, store.err)
+
+		store.err = nil
+		retried, err := manager.CreateOrder(ctx, params)
+		require.NoError(t, err, "a failed create releases its client order ID")
+		assert.Equal(t, Created, store.orders[retried.ID].Status)
+	})
+
+	t.Run("Orders are saved without blocking other orders", func(t *testing.T) {
+		store := &slowOrderStore{
+			memoryOrderStore: memoryOrderStore{orders: make(map[string]*Order)},
+			symbol:           "ETH-USD",
+			saving:           make(chan struct{}),
+			release:          make(chan struct{}),
+		}
+		manager := NewOrderManager(WithStore(store))
+
+		created := make(chan error, 1)
+		go func() {
+			_, err := manager.CreateOrder(ctx, CreateOrderParams{
+				Symbol: "ETH-USD", Type: Limit, Side: Buy, Price: &price, Size: money.FromFloat(1.0), ClientOrderID: "slow",
+			})
+			created <- err
+		}()
+		<-store.saving
+
+		other, err := manager.CreateOrder(ctx, CreateOrderParams{
+			Symbol: "BTC-USD", Type: Limit, Side: Buy, Price: &price, Size: money.FromFloat(1.0),
+		})
+		require.NoError(t, err)
+		require.NoError(t, manager.CancelOrder(ctx, other.ID))
+		orders, err := manager.ListOrders(ctx, OrderFilter{})
+		require.NoError(t, err)
+		assert.Len(t, orders, 1, "the slow order is listed once saved")
+
+		close(store.release)
+		require.NoError(t, <-created)
+		orders, err = manager.ListOrders(ctx, OrderFilter{})
+		require.NoError(t, err)
+		assert.Len(t, orders, 2)
+	})
+}
+
+func TestShutdownBarrier(t *testing.T) {
+	ctx := context.Background()
+	price := money.FromFloat(100.0)
+
+	t.Run("Shutdown waits for in-flight submissions", func(t *testing.T) {
+		manager := NewOrderManager()
+		order, err := manager.CreateOrder(ctx, CreateOrderParams{
+			Symbol: "BTC-USD", Type: Limit, Side: Buy, Price: &price, Size: money.FromFloat(1.0),
+		})
+		assert.NoError(t, err)
+
+		done, err := manager.BeginSubmission(order.ID)
+		assert.NoError(t, err)
+		_, err = manager.BeginSubmission("missing")
+		assert.Equal(t, ErrOrderNotFound, err)
+
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			assert.NoError(t, manager.UpdateOrderStatus(ctx, order.ID, Pending))
+			done()
+		}()
+
+		report, err := manager.Shutdown(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, report.Resolved)
+		assert.Empty(t, report.Unresolved)
+
+		_, err = manager.CreateOrder(ctx, CreateOrderParams{
+			Symbol: "BTC-USD", Type: Limit, Side: Buy, Price: &price, Size: money.FromFloat(1.0),
+		})
+		assert.Equal(t, ErrShuttingDown, err)
+		_, err = manager.BeginSubmission(order.ID)
+		assert.Equal(t, ErrShuttingDown, err)
+		_, err = manager.Shutdown(ctx)
+		assert.Equal(t, ErrShuttingDown, err)
+	})
+
+	t.Run("Unresolved submissions are marked for reconciliation", func(t *testing.T) {
+		store := &memoryOrderStore{orders: make(map[string]*Order)}
+		manager := NewOrderManager(WithStore(store))
+
+		acked, err := manager.CreateOrder(ctx, CreateOrderParams{
+			Symbol: "BTC-USD", Type: Limit, Side: Buy, Price: &price, Size: money.FromFloat(1.0), ClientOrderID: "acked",
+		})
+		assert.NoError(t, err)
+		placed, err := manager.CreateOrder(ctx, CreateOrderParams{
+			Symbol: "BTC-USD", Type: Limit, Side: Buy, Price: &price, Size: money.FromFloat(2.0), ClientOrderID: "placed",
+		})
+		assert.NoError(t, err)
+		lost, err := manager.CreateOrder(ctx, CreateOrderParams{
+			Symbol: "BTC-USD", Type: Limit, Side: Sell, Price: &price, Size: money.FromFloat(1.0), ClientOrderID: "lost",
+		})
+		assert.NoError(t, err)
+
+		done, err := manager.BeginSubmission(acked.ID)
+		assert.NoError(t, err)
+		done()
+		done() // Calling it again is a no-op
+		_, err = manager.BeginSubmission(placed.ID)
+		assert.NoError(t, err)
+		_, err = manager.BeginSubmission(lost.ID)
+		assert.NoError(t, err)
+
+		shutdownCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		report, err := manager.Shutdown(shutdownCtx)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, report.Resolved)
+		assert.ElementsMatch(t, []string{placed.ID, lost.ID}, report.Unresolved)
+		assert.True(t, store.orders[placed.ID].NeedsReconciliation)
+		assert.True(t, store.orders[lost.ID].NeedsReconciliation)
+		assert.False(t, store.orders[acked.ID].NeedsReconciliation)
+
+		// Only the order that reached the exchange is still open after restart
+		exchange := &fakeOpenOrderSource{orders: []dydx.Order{
+			{ID: "ex-1", ClientID: "acked", Market: "BTC-USD", Type: "LIMIT", Side: "BUY", Price: 100.0, Size: 1.0, RemainingSize: 1.0},
+			{ID: "ex-2", ClientID: "placed", Market: "BTC-USD", Type: "LIMIT", Side: "BUY", Price: 100.0, Size: 2.0, RemainingSize: 2.0},
+		}}
+		restarted := NewOrderManager(WithStore(store), WithExchange(exchange))
+		recovery, err := restarted.Recover(ctx)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{placed.ID, lost.ID}, recovery.Unreconciled)
+		assert.Equal(t, []string{lost.ID}, recovery.Expired)
+
+		recovered, err := restarted.GetOrder(ctx, placed.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, Pending, recovered.Status)
+		assert.False(t, recovered.NeedsReconciliation)
+		assert.Equal(t, Expired, store.orders[lost.ID].Status)
+		assert.False(t, store.orders[lost.ID].NeedsReconciliation)
+	})
+}
+
+func TestOrderListener(t *testing.T) {
+	ctx := context.Background()
+	var updates []*Order
+	var fills []float64
+	manager := NewOrderManager(WithListener(func(o *Order, fill money.Decimal) {
+		updates = append(updates, o)
+		fills = append(fills, fill.Float64())
+	}))
+
+	order, err := manager.CreateOrder(ctx, CreateOrderParams{Symbol: "BTC/USD", Type: Market, Side: Buy, Size: money.NewFromInt(2)})
+	assert.NoError(t, err)
+	assert.NoError(t, manager.UpdateOrderStatus(ctx, order.ID, Pending))
+	assert.NoError(t, manager.UpdateFilledSize(ctx, order.ID, money.FromFloat(0.5)))
+
+	assert.Len(t, updates, 3)
+	assert.Equal(t, []float64{0, 0, 0.5}, fills)
+	assert.Equal(t, PartiallyFilled, updates[2].Status)
+	// Listeners get copies
+	assert.NotSame(t, order, updates[2])
+	assert.Equal(t, Created, updates[0].Status)
+}
+
+func TestClientOrderIDDeduplication(t *testing.T) {
+	ctx := context.Background()
+	price := money.FromFloat(50000.0)
+	params := CreateOrderParams{Symbol: "BTC/USD", Type: Limit, Side: Buy, Price: &price, Size: money.NewFromInt(1), ClientOrderID: "retry-1"}
+
+	t.Run("Replay returns the existing order", func(t *testing.T) {
+		manager := NewOrderManager()
+		first, err := manager.CreateOrder(ctx, params)
+		assert.NoError(t, err)
+
+		samePrice := price
+		retry := params
+		retry.Price = &samePrice
+		second, err := manager.CreateOrder(ctx, retry)
+		assert.NoError(t, err)
+		assert.Same(t, first, second)
+
+		orders, err := manager.ListOrders(ctx, OrderFilter{})
+		assert.NoError(t, err)
+		assert.Len(t, orders, 1)
+	})
+
+	t.Run("Replay after halt returns the existing order", func(t *testing.T) {
+		manager := NewOrderManager()
+		first, err := manager.CreateOrder(ctx, params)
+		assert.NoError(t, err)
+
+		manager.Halt("maintenance")
+		second, err := manager.CreateOrder(ctx, params)
+		assert.NoError(t, err)
+		assert.Equal(t, first.ID, second.ID)
+	})
+
+	t.Run("Reuse with different parameters is rejected", func(t *testing.T) {
+		manager := NewOrderManager()
+		_, err := manager.CreateOrder(ctx, params)
+		assert.NoError(t, err)
+
+		changed := params
+		changed.Size = money.NewFromInt(2)
+		_, err = manager.CreateOrder(ctx, changed)
+		assert.ErrorIs(t, err, ErrDuplicateClientOrderID)
+	})
+
+	t.Run("IDs are released after the window", func(t *testing.T) {
+		manager := NewOrderManager(WithDedupWindow(time.Millisecond))
+		first, err := manager.CreateOrder(ctx, params)
+		assert.NoError(t, err)
+
+		time.Sleep(5 * time.Millisecond)
+		second, err := manager.CreateOrder(ctx, params)
+		assert.NoError(t, err)
+		assert.NotEqual(t, first.ID, second.ID)
+	})
+
+	t.Run("Recovered orders reserve their IDs", func(t *testing.T) {
+		store := &memoryOrderStore{orders: make(map[string]*Order)}
+		first, err := NewOrderManager(WithStore(store)).CreateOrder(ctx, params)
+		assert.NoError(t, err)
+
+		manager := NewOrderManager(WithStore(store))
+		_, err = manager.Recover(ctx)
+		assert.NoError(t, err)
+		second, err := manager.CreateOrder(ctx, params)
+		assert.NoError(t, err)
+		assert.Equal(t, first.ID, second.ID)
+	})
+}
+
+func TestExchangeRequest(t *testing.T) {
+	price, stop := money.FromFloat(50000.0), money.FromFloat(49000.0)
+	order := &Order{ID: "order-1", Symbol: "BTC-USD", Type: StopLoss, Side: Sell, Price: &price, StopPrice: &stop, Size: money.NewFromInt(2)}
+
+	req := order.ExchangeRequest()
+	assert.Equal(t, dydx.OrderTypeStopLimit, req.Type)
+	assert.Equal(t, dydx.OrderSideSell, req.Side)
+	assert.Equal(t, 49000.0, req.TriggerPrice)
+	assert.Equal(t, "order-1", req.ClientID)
+
+	order.ClientOrderID = "client-1"
+	assert.Equal(t, "client-1", order.ExchangeRequest().ClientID)
+	assert.Empty(t, req.TimeInForce, "GTC orders use the exchange default")
+
+	order.ReduceOnly, order.TimeInForce = true, FOK
+	req = order.ExchangeRequest()
+	assert.True(t, req.ReduceOnly)
+	assert.False(t, req.PostOnly)
+	assert.Equal(t, dydx.TimeInForceFOK, req.TimeInForce)
+}
+
+// staticPositions reports fixed exchange positions
+type staticPositions []dydx.Position
+
+func (p staticPositions) GetPositions(ctx context.Context) ([]dydx.Position, error) {
+	return p, nil
+}
+
+// disabledSymbols disables trading on its symbols for the given reasons
+type disabledSymbols map[string]string
+
+func (d disabledSymbols) SymbolDisabled(symbol string) (string, bool) {
+	reason, ok := d[symbol]
+	return reason, ok
+}
+
+func TestOrderFlags(t *testing.T) {
+	ctx := context.Background()
+	price := money.FromFloat(100.0)
+
+	t.Run("Flags must fit together", func(t *testing.T) {
+		manager := NewOrderManager()
+		invalid := []CreateOrderParams{
+			{Symbol: "BTC-USD", Type: Market, Side: Buy, Size: money.NewFromInt(1), PostOnly: true},
+			{Symbol: "BTC-USD", Type: Limit, Side: Buy, Price: &price, Size: money.NewFromInt(1), PostOnly: true, TimeInForce: IOC},
+			{Symbol: "BTC-USD", Type: Limit, Side: Buy, Price: &price, Size: money.NewFromInt(1), TimeInForce: TimeInForce(7)},
+		}
+		for _, params := range invalid {
+			_, err := manager.CreateOrder(ctx, params)
+			assert.ErrorIs(t, err, ErrInvalidOrderFlags)
+		}
+
+		order, err := manager.CreateOrder(ctx, CreateOrderParams{Symbol: "BTC-USD", Type: Limit, Side: Buy, Price: &price, Size: money.NewFromInt(1), PostOnly: true})
+		assert.NoError(t, err)
+		snapshot := order.Snapshot()
+		assert.True(t, snapshot.PostOnly)
+		assert.Equal(t, GTC, snapshot.TimeInForce)
+	})
+
+	t.Run("Reduce-only orders cannot increase positions", func(t *testing.T) {
+		manager := NewOrderManager(WithPositions(staticPositions{
+			{Market: "BTC-USD", Side: dydx.PositionSideLong, Size: 2},
+			{Market: "ETH-USD", Side: dydx.PositionSideShort, Size: -3},
+		}))
+
+		rejected := []CreateOrderParams{
+			{Symbol: "BTC-USD", Type: Market, Side: Buy, Size: money.NewFromInt(1), ReduceOnly: true},
+			{Symbol: "BTC-USD", Type: Market, Side: Sell, Size: money.FromFloat(2.5), ReduceOnly: true},
+			{Symbol: "ETH-USD", Type: Market, Side: Sell, Size: money.NewFromInt(1), ReduceOnly: true},
+			{Symbol: "SOL-USD", Type: Market, Side: Sell, Size: money.NewFromInt(1), ReduceOnly: true},
+		}
+		for _, params := range rejected {
+			_, err := manager.CreateOrder(ctx, params)
+			assert.ErrorIs(t, err, ErrReduceOnlyIncreases, "%s %s %g", params.Side, params.Symbol, params.Size)
+		}
+
+		_, err := manager.CreateOrder(ctx, CreateOrderParams{Symbol: "BTC-USD", Type: Market, Side: Sell, Size: money.NewFromInt(2), ReduceOnly: true})
+		assert.NoError(t, err)
+		_, err = manager.CreateOrder(ctx, CreateOrderParams{Symbol: "ETH-USD", Type: Limit, Side: Buy, Price: &price, Size: money.NewFromInt(3), ReduceOnly: true, TimeInForce: IOC})
+		assert.NoError(t, err)
+		_, err = manager.CreateOrder(ctx, CreateOrderParams{Symbol: "SOL-USD", Type: Market, Side: Buy, Size: money.NewFromInt(1)})
+		assert.NoError(t, err, "orders that are not reduce-only are not checked")
+	})
+
+	t.Run("Disabled symbols accept reduce-only orders only", func(t *testing.T) {
+		manager := NewOrderManager(WithSymbolGate(disabledSymbols{"SOL-USD": "loss budget spent"}))
+
+		_, err := manager.CreateOrder(ctx, CreateOrderParams{Symbol: "SOL-USD", Type: Market, Side: Buy, Size: money.NewFromInt(1)})
+		assert.ErrorIs(t, err, ErrSymbolDisabled)
+		assert.ErrorContains(t, err, "loss budget spent")
+		_, err = manager.CreateOrder(ctx, CreateOrderParams{Symbol: "SOL-USD", Type: Market, Side: Sell, Size: money.NewFromInt(1), ReduceOnly: true})
+		assert.NoError(t, err)
+		_, err = manager.CreateOrder(ctx, CreateOrderParams{Symbol: "BTC-USD", Type: Market, Side: Buy, Size: money.NewFromInt(1)})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Pre-trade checks reject orders but not reduce-only ones or replays", func(t *testing.T) {
+		limitReached := false
+		manager := NewOrderManager(WithPreTradeCheck(func(ctx context.Context, params CreateOrderParams) error {
+			if limitReached {
+				return errors.New("daily loss limit exceeded")
+			}
+			return nil
+		}))
+
+		params := CreateOrderParams{Symbol: "BTC-USD", Type: Market, Side: Buy, Size: money.NewFromInt(1), ClientOrderID: "c1"}
+		created, err := manager.CreateOrder(ctx, params)
+		require.NoError(t, err)
+
+		limitReached = true
+		replayed, err := manager.CreateOrder(ctx, params)
+		assert.NoError(t, err)
+		assert.Same(t, created, replayed)
+
+		_, err = manager.CreateOrder(ctx, CreateOrderParams{Symbol: "BTC-USD", Type: Market, Side: Buy, Size: money.NewFromInt(1)})
+		assert.ErrorIs(t, err, ErrRiskRejected)
+		assert.ErrorContains(t, err, "daily loss limit exceeded")
+		_, err = manager.CreateOrder(ctx, CreateOrderParams{Symbol: "BTC-USD", Type: Market, Side: Sell, Size: money.NewFromInt(1), ReduceOnly: true})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Replays compare flags", func(t *testing.T) {
+		manager := NewOrderManager()
+		params := CreateOrderParams{Symbol: "BTC-USD", Type: Limit, Side: Buy, Price: &price, Size: money.NewFromInt(1), ClientOrderID: "c1", PostOnly: true}
+		_, err := manager.CreateOrder(ctx, params)
+		assert.NoError(t, err)
+
+		params.PostOnly = false
+		_, err = manager.CreateOrder(ctx, params)
+		assert.ErrorIs(t, err, ErrDuplicateClientOrderID)
+	})
+
+	tif, err := ParseTimeInForce("ioc")
+	assert.NoError(t, err)
+	assert.Equal(t, IOC, tif)
+	_, err = ParseTimeInForce("GTD")
+	assert.ErrorIs(t, err, ErrInvalidOrderFlags)
+}
+
+// fakeVenue acknowledges orders after a delay, or refuses them
+type fakeVenue struct {
+	delay     time.Duration
+	err       error
+	open      []dydx.Order
+	cancelled chan string
+	cancelErr error
+	mu        sync.Mutex
+}
+
+func (v *fakeVenue) CreateOrder(ctx context.Context, req dydx.CreateOrderRequest) (*dydx.Order, error) {
+	// The venue ignores the context, like a hung HTTP call
+	time.Sleep(v.delay)
+	if v.err != nil {
+		return nil, v.err
+	}
+	return &dydx.Order{ID: "ex-" + req.ClientID, ClientID: req.ClientID}, nil
+}
+
+func (v *fakeVenue) CancelOrder(ctx context.Context, orderID string) error {
+	if v.cancelErr != nil {
+		return v.cancelErr
+	}
+	v.cancelled <- orderID
+	return nil
+}
+
+func (v *fakeVenue) GetOpenOrders(ctx context.Context) ([]dydx.Order, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.open, nil
+}
+
+func TestSubmit(t *testing.T) {
+	ctx := context.Background()
+	price := money.FromFloat(100.0)
+	params := CreateOrderParams{Symbol: "BTC-USD", Type: Limit, Side: Buy, Price: &price, Size: money.NewFromInt(1), ClientOrderID: "c1"}
+
+	t.Run("Acknowledged orders are pending", func(t *testing.T) {
+		store := &memoryOrderStore{orders: make(map[string]*Order)}
+		manager := NewOrderManager(WithStore(store), WithVenue("dydx", &fakeVenue{}))
+		order, err := manager.CreateOrder(ctx, params)
+		assert.NoError(t, err)
+
+		submitted, err := manager.Submit(ctx, order.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, Pending, submitted.Status)
+		assert.Equal(t, "ex-c1", store.orders[order.ID].ExchangeOrderID)
+
+		_, err = manager.Submit(ctx, order.ID)
+		assert.ErrorIs(t, err, ErrInvalidStatusTransition)
+	})
+
+	t.Run("Refused orders are rejected", func(t *testing.T) {
+		refused := errors.New("insufficient margin")
+		manager := NewOrderManager(WithVenue("dydx", &fakeVenue{err: refused}))
+		order, err := manager.CreateOrder(ctx, params)
+		assert.NoError(t, err)
+
+		_, err = manager.Submit(ctx, order.ID)
+		assert.ErrorIs(t, err, refused)
+		assert.Equal(t, Rejected, order.Snapshot().Status)
+	})
+
+	t.Run("Unacknowledged orders are cancelled and expired", func(t *testing.T) {
+		venue := &fakeVenue{
+			delay:     200 * time.Millisecond,
+			open:      []dydx.Order{{ID: "resting", ClientID: "c1"}},
+			cancelled: make(chan string, 2),
+		}
+		manager := NewOrderManager(WithVenue("dydx", venue), WithExecutionBudget(20*time.Millisecond))
+		order, err := manager.CreateOrder(ctx, params)
+		assert.NoError(t, err)
+
+		start := time.Now()
+		_, err = manager.Submit(ctx, order.ID)
+		assert.ErrorIs(t, err, ErrExecutionTimeout)
+		assert.Less(t, time.Since(start), 150*time.Millisecond)
+		assert.Equal(t, Expired, order.Snapshot().Status)
+		assert.Equal(t, "resting", <-venue.cancelled)
+
+		// The late acknowledgement is cancelled too
+		select {
+		case id := <-venue.cancelled:
+			assert.Equal(t, "ex-c1", id)
+		case <-time.After(time.Second):
+			t.Fatal("late acknowledgement not cancelled")
+		}
+
+		// The submission no longer holds up shutdown
+		report, err := manager.Shutdown(ctx)
+		assert.NoError(t, err)
+		assert.Empty(t, report.Unresolved)
+	})
+
+	t.Run("Acknowledged orders are cancelled on the venue", func(t *testing.T) {
+		venue := &fakeVenue{cancelled: make(chan string, 1)}
+		manager := NewOrderManager(WithVenue("dydx", venue))
+		order, err := manager.CreateOrder(ctx, params)
+		require.NoError(t, err)
+		_, err = manager.Submit(ctx, order.ID)
+		require.NoError(t, err)
+
+		venue.cancelErr = errors.New("venue unavailable")
+		err = manager.CancelOrder(ctx, order.ID)
+		assert.ErrorIs(t, err, ErrVenueCancelFailed)
+		assert.Equal(t, Pending, order.Snapshot().Status, "still live on the venue")
+
+		venue.cancelErr = nil
+		require.NoError(t, manager.CancelOrder(ctx, order.ID))
+		assert.Equal(t, "ex-c1", <-venue.cancelled)
+		assert.Equal(t, Cancelled, order.Snapshot().Status)
+	})
+
+	t.Run("Submitting needs a venue", func(t *testing.T) {
+		manager := NewOrderManager()
+		order, err := manager.CreateOrder(ctx, params)
+		assert.NoError(t, err)
+
+		_, err = manager.Submit(ctx, order.ID)
+		assert.ErrorIs(t, err, ErrNoVenue)
+		assert.Equal(t, Created, order.Snapshot().Status)
+	})
+}
+
+func TestExpireOrders(t *testing.T) {
+	ctx := context.Background()
+	price := money.FromFloat(100.0)
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	var expiredUpdates []string
+	venue := &fakeVenue{cancelled: make(chan string, 4)}
+	manager := NewOrderManager(WithVenue("dydx", venue), WithListener(func(o *Order, fill money.Decimal) {
+		if o.Status == Expired {
+			expiredUpdates = append(expiredUpdates, o.ID)
+		}
+	}))
+	create := func(clientID string, expiresAt *time.Time) *Order {
+		order, err := manager.CreateOrder(ctx, CreateOrderParams{
+			Symbol: "BTC-USD", Type: Limit, Side: Buy, Price: &price, Size: money.NewFromInt(1),
+			ClientOrderID: clientID, ExpiresAt: expiresAt,
+		})
+		assert.NoError(t, err)
+		return order
+	}
+
+	created := create("created", &past)
+	submitted := create("submitted", &past)
+	_, err := manager.Submit(ctx, submitted.ID)
+	assert.NoError(t, err)
+	live := create("live", &future)
+	forever := create("forever", nil)
+	cancelled := create("cancelled", &past)
+	assert.NoError(t, manager.CancelOrder(ctx, cancelled.ID))
+
+	expired, err := manager.ExpireOrders(ctx)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{created.ID, submitted.ID}, expired)
+	assert.ElementsMatch(t, expired, expiredUpdates)
+	assert.Equal(t, Expired, created.Snapshot().Status)
+	assert.Equal(t, Expired, submitted.Snapshot().Status)
+	assert.Equal(t, Created, live.Snapshot().Status)
+	assert.Equal(t, Created, forever.Snapshot().Status)
+	assert.Equal(t, Cancelled, cancelled.Snapshot().Status)
+
+	// Only the order acknowledged by the venue is cancelled there
+	assert.Equal(t, "ex-submitted", <-venue.cancelled)
+	assert.Empty(t, venue.cancelled)
+
+	expired, err = manager.ExpireOrders(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, expired)
+
+	t.Run("Orders stay open when the venue cancellation fails", func(t *testing.T) {
+		venue := &failingCancelVenue{}
+		manager := NewOrderManager(WithVenue("dydx", venue))
+		order, err := manager.CreateOrder(ctx, CreateOrderParams{
+			Symbol: "BTC-USD", Type: Limit, Side: Buy, Price: &price, Size: money.NewFromInt(1), ExpiresAt: &past,
+		})
+		assert.NoError(t, err)
+		_, err = manager.Submit(ctx, order.ID)
+		assert.NoError(t, err)
+
+		expired, err := manager.ExpireOrders(ctx)
+		assert.Error(t, err)
+		assert.Empty(t, expired)
+		assert.Equal(t, Pending, order.Snapshot().Status)
+	})
+
+	t.Run("The sweeper expires orders until stopped", func(t *testing.T) {
+		manager := NewOrderManager()
+		soon := time.Now().Add(20 * time.Millisecond)
+		order, err := manager.CreateOrder(ctx, CreateOrderParams{Symbol: "BTC-USD", Type: Market, Side: Sell, Size: money.NewFromInt(1), ExpiresAt: &soon})
+		assert.NoError(t, err)
+
+		sweepCtx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			RunExpirySweeper(sweepCtx, manager, 5*time.Millisecond)
+			close(done)
+		}()
+		assert.Eventually(t, func() bool { return order.Snapshot().Status == Expired }, time.Second, 5*time.Millisecond)
+		cancel()
+		<-done
+	})
+}
+
+// failingCancelVenue acknowledges orders but cannot cancel them
+type failingCancelVenue struct {
+	fakeVenue
+}
+
+func (v *failingCancelVenue) CancelOrder(ctx context.Context, orderID string) error {
+	return errors.New("venue unavailable")
+}
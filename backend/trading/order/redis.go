@@ -0,0 +1,67 @@
+package order
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// DefaultRedisKey is the hash RedisStore saves open orders under
+const DefaultRedisKey = "gosol:orders"
+
+// RedisStore saves open orders in a Redis hash keyed by order ID. Orders are
+// removed once they are filled, cancelled, rejected or expired, so the hash
+// only holds the orders Recover needs.
+type RedisStore struct {
+	client *redis.Client
+	key    string
+}
+
+// NewRedisStore creates a store saving open orders under key, or
+// DefaultRedisKey when key is empty. Accounts sharing a Redis instance need
+// keys of their own.
+func NewRedisStore(client *redis.Client, key string) *RedisStore {
+	if key == "" {
+		key = DefaultRedisKey
+	}
+	return &RedisStore{client: client, key: key}
+}
+
+// SaveOrder saves an open order and removes a closed one
+func (s *RedisStore) SaveOrder(ctx context.Context, order *Order) error {
+	if !isOrderCancellable(order.Status) {
+		if err := s.client.HDel(ctx, s.key, order.ID).Err(); err != nil {
+			return fmt.Errorf("failed to remove order from redis: %w", err)
+		}
+		return nil
+	}
+
+	data, err := json.Marshal(order)
+	if err != nil {
+		return fmt.Errorf("failed to encode order: %w", err)
+	}
+	if err := s.client.HSet(ctx, s.key, order.ID, data).Err(); err != nil {
+		return fmt.Errorf("failed to save order to redis: %w", err)
+	}
+	return nil
+}
+
+// LoadOpenOrders returns the saved open orders
+func (s *RedisStore) LoadOpenOrders(ctx context.Context) ([]*Order, error) {
+	values, err := s.client.HGetAll(ctx, s.key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load orders from redis: %w", err)
+	}
+
+	orders := make([]*Order, 0, len(values))
+	for id, data := range values {
+		var order Order
+		if err := json.Unmarshal([]byte(data), &order); err != nil {
+			return nil, fmt.Errorf("failed to decode order %s: %w", id, err)
+		}
+		orders = append(orders, &order)
+	}
+	return orders, nil
+}
@@ -0,0 +1,140 @@
+package order
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+	"github.com/devinjacknz/godydxhyber/backend/trading/analysis/monitoring"
+)
+
+// ShutdownReport summarizes the result of Shutdown
+type ShutdownReport struct {
+	// Resolved is the number of submissions that finished before the deadline
+	Resolved int
+	// Unresolved lists orders still being submitted at the deadline. They
+	// are marked NeedsReconciliation and persisted so Recover checks them
+	// against the exchange on restart.
+	Unresolved []string
+}
+
+// BeginSubmission registers an order as being submitted to the exchange.
+// The returned function must be called once the exchange has acknowledged or
+// rejected the order, after its status has been updated. Submissions cannot
+// begin once Shutdown has been called.
+func (m *DefaultOrderManager) BeginSubmission(orderID string) (func(), error) {
+	return m.beginSubmission(orderID, false)
+}
+
+// beginSubmission registers a submission, failing with ErrSubmissionInProgress
+// when exclusive and the order is already being submitted
+func (m *DefaultOrderManager) beginSubmission(orderID string, exclusive bool) (func(), error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closing {
+		return nil, ErrShuttingDown
+	}
+	if _, ok := m.orders[orderID]; !ok {
+		return nil, ErrOrderNotFound
+	}
+	if exclusive && m.inflight[orderID] > 0 {
+		return nil, ErrSubmissionInProgress
+	}
+	m.inflight[orderID]++
+	monitoring.RecordIndicatorValue("inflight_submissions", float64(m.inflightCount()))
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { m.endSubmission(orderID) })
+	}, nil
+}
+
+// Shutdown stops order creation and new submissions, then waits until the
+// in-flight submissions resolve or ctx is done. Orders still in flight at the
+// deadline are marked for reconciliation and persisted, so the restart
+// recovery path knows about every order that may have been placed.
+func (m *DefaultOrderManager) Shutdown(ctx context.Context) (*ShutdownReport, error) {
+	m.mu.Lock()
+	if m.closing {
+		m.mu.Unlock()
+		return nil, ErrShuttingDown
+	}
+	m.closing = true
+	total := m.inflightCount()
+	m.drained = make(chan struct{})
+	if total == 0 {
+		close(m.drained)
+	}
+	drained := m.drained
+	m.mu.Unlock()
+
+	report := &ShutdownReport{}
+	select {
+	case <-drained:
+		report.Resolved = total
+		return report, nil
+	case <-ctx.Done():
+	}
+
+	m.mu.RLock()
+	unresolved := make([]*Order, 0, len(m.inflight))
+	for id := range m.inflight {
+		unresolved = append(unresolved, m.orders[id])
+	}
+	remaining := m.inflightCount()
+	m.mu.RUnlock()
+
+	sort.Slice(unresolved, func(i, j int) bool {
+		return unresolved[i].ID < unresolved[j].ID
+	})
+	report.Resolved = total - remaining
+
+	// The deadline has passed, so the markers are saved without it
+	saveCtx := context.WithoutCancel(ctx)
+	for _, order := range unresolved {
+		order.mu.Lock()
+		err := m.update(saveCtx, order, money.Zero, func(next *Order) {
+			next.NeedsReconciliation = true
+			next.UpdatedAt = time.Now()
+		})
+		order.mu.Unlock()
+		if err != nil {
+			return report, err
+		}
+		report.Unresolved = append(report.Unresolved, order.ID)
+	}
+
+	monitoring.RecordIndicatorValue("unresolved_submissions", float64(len(report.Unresolved)))
+	return report, nil
+}
+
+func (m *DefaultOrderManager) endSubmission(orderID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.inflight[orderID]--
+	if m.inflight[orderID] <= 0 {
+		delete(m.inflight, orderID)
+	}
+	monitoring.RecordIndicatorValue("inflight_submissions", float64(m.inflightCount()))
+
+	if m.closing && len(m.inflight) == 0 {
+		select {
+		case <-m.drained:
+		default:
+			close(m.drained)
+		}
+	}
+}
+
+// inflightCount returns the number of in-flight submissions. Callers hold m.mu.
+func (m *DefaultOrderManager) inflightCount() int {
+	count := 0
+	for _, n := range m.inflight {
+		count += n
+	}
+	return count
+}
@@ -0,0 +1,363 @@
+package order
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/devinjacknz/godydxhyber/backend/exchange/dydx"
+	"github.com/devinjacknz/godydxhyber/backend/logger"
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+	"github.com/devinjacknz/godydxhyber/backend/pkg/tracing"
+	"github.com/devinjacknz/godydxhyber/backend/trading/analysis/monitoring"
+)
+
+// Store persists orders so they survive restarts
+type Store interface {
+	SaveOrder(ctx context.Context, order *Order) error
+	LoadOpenOrders(ctx context.Context) ([]*Order, error)
+}
+
+// OpenOrderSource lists the orders currently resting on the exchange
+type OpenOrderSource interface {
+	GetOpenOrders(ctx context.Context) ([]dydx.Order, error)
+}
+
+// Listener receives a copy of an order after each persisted change. fill is
+// the size filled by the change, zero for changes that are not fills. It is
+// called with the order locked, so it must not block or call back into the
+// manager.
+type Listener func(order *Order, fill money.Decimal)
+
+// ManagerOption configures a DefaultOrderManager
+type ManagerOption func(*DefaultOrderManager)
+
+// WithStore persists every order change through the given store
+func WithStore(store Store) ManagerOption {
+	return func(m *DefaultOrderManager) {
+		m.store = store
+	}
+}
+
+// WithAccountID sets the trading account of the orders of the manager
+func WithAccountID(accountID string) ManagerOption {
+	return func(m *DefaultOrderManager) {
+		m.accountID = accountID
+	}
+}
+
+// WithExchange reconciles recovered orders against the exchange
+func WithExchange(exchange OpenOrderSource) ManagerOption {
+	return func(m *DefaultOrderManager) {
+		m.exchange = exchange
+	}
+}
+
+// WithListener notifies the listener of every order change
+func WithListener(listener Listener) ManagerOption {
+	return func(m *DefaultOrderManager) {
+		m.listener = listener
+	}
+}
+
+// RecoveryReport summarizes the result of Recover
+type RecoveryReport struct {
+	// Loaded is the number of open orders loaded from the store
+	Loaded int
+	// Adopted lists exchange orders that were not known locally
+	Adopted []string
+	// Expired lists stored orders that are no longer open on the exchange
+	Expired []string
+	// Unreconciled lists loaded orders that were still being submitted at
+	// the last shutdown. When an exchange is configured they are resolved
+	// like any other stored order and the flag is cleared.
+	Unreconciled []string
+}
+
+// Recover reloads open orders from the store and reconciles them against the
+// exchange. Stored orders missing on the exchange are expired, and exchange
+// orders missing locally are adopted.
+func (m *DefaultOrderManager) Recover(ctx context.Context) (*RecoveryReport, error) {
+	start := time.Now()
+	defer func() {
+		monitoring.RecordIndicatorCalculation("recover_orders", time.Since(start))
+	}()
+
+	if m.store == nil {
+		return &RecoveryReport{}, nil
+	}
+
+	stored, err := m.store.LoadOpenOrders(ctx)
+	if err != nil {
+		monitoring.RecordIndicatorError("recover_orders", err.Error())
+		return nil, fmt.Errorf("failed to load open orders: %w", err)
+	}
+	return m.restore(ctx, stored)
+}
+
+// Restore loads open orders saved elsewhere, such as in an engine snapshot,
+// and reconciles them against the exchange like Recover
+func (m *DefaultOrderManager) Restore(ctx context.Context, orders []*Order) (*RecoveryReport, error) {
+	start := time.Now()
+	defer func() {
+		monitoring.RecordIndicatorCalculation("restore_orders", time.Since(start))
+	}()
+
+	restored := make([]*Order, 0, len(orders))
+	for _, order := range orders {
+		restored = append(restored, order.Snapshot())
+	}
+	return m.restore(ctx, restored)
+}
+
+// restore adds loaded orders to the manager and reconciles them
+func (m *DefaultOrderManager) restore(ctx context.Context, stored []*Order) (*RecoveryReport, error) {
+	report := &RecoveryReport{Loaded: len(stored)}
+
+	m.mu.Lock()
+	for _, order := range stored {
+		m.orders[order.ID] = order
+		m.index(order)
+		if order.NeedsReconciliation {
+			report.Unreconciled = append(report.Unreconciled, order.ID)
+		}
+	}
+	m.mu.Unlock()
+
+	if m.exchange == nil {
+		monitoring.RecordIndicatorValue("active_orders", float64(len(stored)))
+		return report, nil
+	}
+
+	remote, err := m.exchange.GetOpenOrders(ctx)
+	if err != nil {
+		monitoring.RecordIndicatorError("recover_orders", err.Error())
+		return nil, fmt.Errorf("failed to get exchange orders: %w", err)
+	}
+
+	matched := make(map[string]bool, len(stored))
+	for i := range remote {
+		ex := &remote[i]
+		local := findStoredOrder(stored, ex)
+		if local == nil {
+			local = orderFromExchange(ex)
+			local.AccountID = m.accountID
+			m.mu.Lock()
+			m.orders[local.ID] = local
+			m.index(local)
+			m.mu.Unlock()
+			report.Adopted = append(report.Adopted, local.ID)
+		} else {
+			syncFromExchange(local, ex)
+		}
+		matched[local.ID] = true
+
+		local.mu.Lock()
+		err := m.persist(ctx, local)
+		local.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, order := range stored {
+		if matched[order.ID] {
+			continue
+		}
+
+		// Unacknowledged orders missing on the exchange were never placed
+		order.mu.Lock()
+		order.Status = Expired
+		order.NeedsReconciliation = false
+		order.UpdatedAt = time.Now()
+		err := m.persist(ctx, order)
+		order.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		report.Expired = append(report.Expired, order.ID)
+	}
+
+	monitoring.RecordIndicatorValue("recovered_orders", float64(len(matched)))
+	return report, nil
+}
+
+// update applies a change to a copy of an order and saves the copy before
+// applying it to the order, so the order is left unchanged when it cannot be
+// saved. fill is the size filled by the change. Callers hold the order's
+// lock.
+func (m *DefaultOrderManager) update(ctx context.Context, order *Order, fill money.Decimal, change func(next *Order)) error {
+	next := order.snapshot()
+	change(next)
+	if err := m.persistFill(ctx, next, fill); err != nil {
+		return err
+	}
+	order.apply(next)
+	return nil
+}
+
+// persist saves an order if a store is configured. Callers hold the order lock.
+func (m *DefaultOrderManager) persist(ctx context.Context, order *Order) error {
+	return m.persistFill(ctx, order, money.Zero)
+}
+
+// persistFill saves an order change and notifies the listener. Callers hold
+// the order's lock. Changes made without a correlation ID in ctx are saved
+// with the correlation ID of the order.
+func (m *DefaultOrderManager) persistFill(ctx context.Context, order *Order, fill money.Decimal) error {
+	if logger.CorrelationID(ctx) == "" {
+		ctx = logger.WithCorrelationID(ctx, order.CorrelationID)
+	}
+	if m.store != nil {
+		ctx, span := tracing.Start(ctx, "order.SaveOrder", attribute.String("order.id", order.ID))
+		err := m.store.SaveOrder(ctx, order)
+		tracing.End(span, err)
+		if err != nil {
+			monitoring.RecordIndicatorError("persist_order", err.Error())
+			slog.ErrorContext(ctx, "failed to persist order", "order_id", order.ID, "error", err)
+			return fmt.Errorf("failed to persist order %s: %w", order.ID, err)
+		}
+	}
+	if m.listener != nil {
+		m.listener(order.snapshot(), fill)
+	}
+	return nil
+}
+
+func findStoredOrder(stored []*Order, ex *dydx.Order) *Order {
+	for _, order := range stored {
+		if order.ID == ex.ID || order.ExchangeOrderID == ex.ID || (ex.ClientID != "" && order.ClientOrderID == ex.ClientID) {
+			return order
+		}
+	}
+	return nil
+}
+
+func syncFromExchange(order *Order, ex *dydx.Order) {
+	order.mu.Lock()
+	defer order.mu.Unlock()
+
+	order.FilledSize = money.FromFloat(ex.FilledSize)
+	order.RemainingSize = money.FromFloat(ex.RemainingSize)
+	order.Status = exchangeOrderStatus(ex)
+	order.NeedsReconciliation = false
+	order.UpdatedAt = time.Now()
+}
+
+func orderFromExchange(ex *dydx.Order) *Order {
+	order := &Order{
+		ID:            ex.ID,
+		Symbol:        ex.Market,
+		Type:          Limit,
+		Side:          Buy,
+		Size:          money.FromFloat(ex.Size),
+		FilledSize:    money.FromFloat(ex.FilledSize),
+		RemainingSize: money.FromFloat(ex.RemainingSize),
+		Status:        exchangeOrderStatus(ex),
+		CreatedAt:     ex.CreatedAt,
+		UpdatedAt:     time.Now(),
+		ClientOrderID: ex.ClientID,
+		PostOnly:      ex.PostOnly,
+		ReduceOnly:    ex.ReduceOnly,
+	}
+
+	if strings.EqualFold(ex.Side, dydx.OrderSideSell) {
+		order.Side = Sell
+	}
+
+	switch strings.ToUpper(ex.Type) {
+	case dydx.OrderTypeMarket:
+		order.Type = Market
+	case dydx.OrderTypeStopMarket, dydx.OrderTypeStopLimit:
+		order.Type = StopLoss
+	case dydx.OrderTypeTakeProfit:
+		order.Type = TakeProfit
+	}
+
+	if ex.Price > 0 {
+		price := money.FromFloat(ex.Price)
+		order.Price = &price
+	}
+	if ex.TriggerPrice > 0 {
+		trigger := money.FromFloat(ex.TriggerPrice)
+		order.StopPrice = &trigger
+	}
+	if !ex.ExpiresAt.IsZero() {
+		expires := ex.ExpiresAt
+		order.ExpiresAt = &expires
+	}
+	switch strings.ToUpper(ex.TimeInForce) {
+	case dydx.TimeInForceIOC:
+		order.TimeInForce = IOC
+	case dydx.TimeInForceFOK:
+		order.TimeInForce = FOK
+	}
+
+	return order
+}
+
+func exchangeOrderStatus(ex *dydx.Order) OrderStatus {
+	if ex.FilledSize > 0 {
+		return PartiallyFilled
+	}
+	return Pending
+}
+
+// ExchangeRequest returns the dYdX request placing the order. The client
+// order ID, or the order ID when there is none, is sent as the dYdX client
+// ID so a retried submission cannot place the order twice. GTC orders leave
+// the time in force to the exchange default.
+func (o *Order) ExchangeRequest() dydx.CreateOrderRequest {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	req := dydx.CreateOrderRequest{
+		Market:     o.Symbol,
+		Side:       dydx.OrderSideBuy,
+		Size:       o.Size.Float64(),
+		PostOnly:   o.PostOnly,
+		ReduceOnly: o.ReduceOnly,
+		ClientID:   o.ClientOrderID,
+	}
+	if req.ClientID == "" {
+		req.ClientID = o.ID
+	}
+	if o.Side == Sell {
+		req.Side = dydx.OrderSideSell
+	}
+
+	switch o.Type {
+	case Market:
+		req.Type = dydx.OrderTypeMarket
+	case Limit:
+		req.Type = dydx.OrderTypeLimit
+	case StopLoss:
+		req.Type = dydx.OrderTypeStopMarket
+		if o.Price != nil {
+			req.Type = dydx.OrderTypeStopLimit
+		}
+	case TakeProfit:
+		req.Type = dydx.OrderTypeTakeProfit
+	}
+
+	if o.Price != nil {
+		req.Price = o.Price.Float64()
+	}
+	if o.StopPrice != nil {
+		req.TriggerPrice = o.StopPrice.Float64()
+	}
+	if o.ExpiresAt != nil {
+		req.ExpiresAt = o.ExpiresAt.Unix()
+	}
+	switch o.TimeInForce {
+	case IOC:
+		req.TimeInForce = dydx.TimeInForceIOC
+	case FOK:
+		req.TimeInForce = dydx.TimeInForceFOK
+	}
+	return req
+}
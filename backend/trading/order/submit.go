@@ -0,0 +1,188 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/exchange/dydx"
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+	"github.com/devinjacknz/godydxhyber/backend/trading/analysis/monitoring"
+)
+
+// DefaultExecutionBudget is the time the exchange has to acknowledge an
+// order before it is cancelled and expired
+const DefaultExecutionBudget = 10 * time.Second
+
+// Acknowledgement outcomes, the outcome label of the latency histogram
+const (
+	outcomeAcknowledged = "acknowledged"
+	outcomeRejected     = "rejected"
+	outcomeExpired      = "expired"
+)
+
+// Venue places and cancels orders on an exchange
+type Venue interface {
+	OpenOrderSource
+	CreateOrder(ctx context.Context, req dydx.CreateOrderRequest) (*dydx.Order, error)
+	CancelOrder(ctx context.Context, orderID string) error
+}
+
+// WithVenue submits orders to an exchange, named in the latency metrics
+func WithVenue(name string, venue Venue) ManagerOption {
+	return func(m *DefaultOrderManager) {
+		m.venueName = name
+		m.venue = venue
+	}
+}
+
+// WithExecutionBudget sets the time the exchange has to acknowledge an order,
+// DefaultExecutionBudget by default
+func WithExecutionBudget(budget time.Duration) ManagerOption {
+	return func(m *DefaultOrderManager) {
+		if budget > 0 {
+			m.executionBudget = budget
+		}
+	}
+}
+
+// placement is the answer of the exchange to an order submission
+type placement struct {
+	ack *dydx.Order
+	err error
+}
+
+// Submit places a created order on the venue, one submission at a time. An
+// acknowledged order becomes Pending and a refused one Rejected. When the
+// venue does not answer within the execution budget, the order is cancelled
+// on the venue if it got there, expired locally and ErrExecutionTimeout is
+// returned; an acknowledgement arriving later is cancelled as well.
+func (m *DefaultOrderManager) Submit(ctx context.Context, orderID string) (*Order, error) {
+	if m.venue == nil {
+		return nil, ErrNoVenue
+	}
+	order, err := m.GetOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Snapshot().Status != Created {
+		return nil, ErrInvalidStatusTransition
+	}
+	done, err := m.beginSubmission(orderID, true)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	req := order.ExchangeRequest()
+	submitCtx, cancel := context.WithTimeout(ctx, m.executionBudget)
+	defer cancel()
+
+	// The call runs apart so a venue ignoring its context cannot hold the
+	// order past the budget
+	start := time.Now()
+	answer := make(chan placement, 1)
+	go func() {
+		ack, err := m.venue.CreateOrder(submitCtx, req)
+		answer <- placement{ack: ack, err: err}
+	}()
+
+	select {
+	case p := <-answer:
+		latency := time.Since(start)
+		if p.err != nil {
+			monitoring.RecordOrderAck(m.venueName, outcomeRejected, latency)
+			if err := m.resolveSubmission(ctx, order, Rejected, ""); err != nil {
+				return order, err
+			}
+			return order, fmt.Errorf("%s refused order %s: %w", m.venueName, orderID, p.err)
+		}
+		monitoring.RecordOrderAck(m.venueName, outcomeAcknowledged, latency)
+		exchangeID := ""
+		if p.ack != nil {
+			exchangeID = p.ack.ID
+		}
+		err := m.resolveSubmission(ctx, order, Pending, exchangeID)
+		if errors.Is(err, ErrInvalidStatusTransition) && exchangeID != "" {
+			// Cancelled locally while it was submitted
+			m.cancelAcknowledged(context.WithoutCancel(ctx), orderID, exchangeID)
+		}
+		return order, err
+	case <-submitCtx.Done():
+	}
+
+	monitoring.RecordOrderAck(m.venueName, outcomeExpired, time.Since(start))
+	slog.WarnContext(ctx, "order not acknowledged within the execution budget, cancelling",
+		"order_id", orderID, "venue", m.venueName, "budget", m.executionBudget)
+
+	// The caller may be gone as well, so the cancellation gets its own budget
+	cleanupCtx := context.WithoutCancel(ctx)
+	m.cancelOnVenue(cleanupCtx, req.ClientID)
+	go m.cancelLate(cleanupCtx, orderID, answer)
+	if err := m.resolveSubmission(cleanupCtx, order, Expired, ""); err != nil {
+		return order, err
+	}
+	return order, ErrExecutionTimeout
+}
+
+// resolveSubmission moves a submitted order to its status
+func (m *DefaultOrderManager) resolveSubmission(ctx context.Context, order *Order, status OrderStatus, exchangeID string) error {
+	order.mu.Lock()
+	defer order.mu.Unlock()
+
+	if !isValidStatusTransition(order.Status, status) {
+		// Cancelled meanwhile, e.g. by the kill switch
+		return ErrInvalidStatusTransition
+	}
+	return m.update(ctx, order, money.Zero, func(next *Order) {
+		next.Status = status
+		if exchangeID != "" {
+			next.ExchangeOrderID = exchangeID
+		}
+		next.UpdatedAt = time.Now()
+	})
+}
+
+// cancelOnVenue cancels the open order with a client ID on the venue, if the
+// submission reached it
+func (m *DefaultOrderManager) cancelOnVenue(ctx context.Context, clientID string) {
+	ctx, cancel := context.WithTimeout(ctx, m.executionBudget)
+	defer cancel()
+
+	open, err := m.venue.GetOpenOrders(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to look up expired order on venue", "client_order_id", clientID, "venue", m.venueName, "error", err)
+		return
+	}
+	for _, ex := range open {
+		if ex.ClientID != clientID {
+			continue
+		}
+		if err := m.venue.CancelOrder(ctx, ex.ID); err != nil {
+			slog.ErrorContext(ctx, "failed to cancel expired order on venue", "client_order_id", clientID, "venue", m.venueName, "error", err)
+		}
+		return
+	}
+}
+
+// cancelLate cancels an order the venue acknowledges after it expired
+func (m *DefaultOrderManager) cancelLate(ctx context.Context, orderID string, answer <-chan placement) {
+	p := <-answer
+	if p.err != nil || p.ack == nil {
+		return
+	}
+	slog.WarnContext(ctx, "expired order acknowledged late", "order_id", orderID, "exchange_order_id", p.ack.ID, "venue", m.venueName)
+	m.cancelAcknowledged(ctx, orderID, p.ack.ID)
+}
+
+// cancelAcknowledged cancels an acknowledged order on the venue that is no
+// longer open locally
+func (m *DefaultOrderManager) cancelAcknowledged(ctx context.Context, orderID, exchangeID string) {
+	ctx, cancel := context.WithTimeout(ctx, m.executionBudget)
+	defer cancel()
+	if err := m.venue.CancelOrder(ctx, exchangeID); err != nil {
+		slog.ErrorContext(ctx, "failed to cancel order on venue", "order_id", orderID, "exchange_order_id", exchangeID, "venue", m.venueName, "error", err)
+	}
+}
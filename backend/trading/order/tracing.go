@@ -0,0 +1,63 @@
+package order
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+	"github.com/devinjacknz/godydxhyber/backend/pkg/tracing"
+)
+
+// tracedOrderManager traces the order changes of an OrderManager
+type tracedOrderManager struct {
+	OrderManager
+}
+
+// Traced returns an OrderManager tracing order placement, cancellation and
+// updates in spans
+func Traced(m OrderManager) OrderManager {
+	return tracedOrderManager{m}
+}
+
+func (m tracedOrderManager) CreateOrder(ctx context.Context, params CreateOrderParams) (*Order, error) {
+	ctx, span := tracing.Start(ctx, "order.CreateOrder",
+		attribute.String("symbol", params.Symbol),
+		attribute.String("side", params.Side.String()),
+		attribute.Float64("size", params.Size.Float64()),
+	)
+	order, err := m.OrderManager.CreateOrder(ctx, params)
+	if order != nil {
+		span.SetAttributes(attribute.String("order.id", order.ID))
+	}
+	tracing.End(span, err)
+	return order, err
+}
+
+func (m tracedOrderManager) CancelOrder(ctx context.Context, orderID string) error {
+	ctx, span := tracing.Start(ctx, "order.CancelOrder", attribute.String("order.id", orderID))
+	err := m.OrderManager.CancelOrder(ctx, orderID)
+	tracing.End(span, err)
+	return err
+}
+
+func (m tracedOrderManager) UpdateOrderStatus(ctx context.Context, orderID string, status OrderStatus) error {
+	ctx, span := tracing.Start(ctx, "order.UpdateOrderStatus", attribute.String("order.id", orderID))
+	err := m.OrderManager.UpdateOrderStatus(ctx, orderID, status)
+	tracing.End(span, err)
+	return err
+}
+
+func (m tracedOrderManager) UpdateFilledSize(ctx context.Context, orderID string, filledSize money.Decimal) error {
+	ctx, span := tracing.Start(ctx, "order.UpdateFilledSize", attribute.String("order.id", orderID))
+	err := m.OrderManager.UpdateFilledSize(ctx, orderID, filledSize)
+	tracing.End(span, err)
+	return err
+}
+
+func (m tracedOrderManager) Submit(ctx context.Context, orderID string) (*Order, error) {
+	ctx, span := tracing.Start(ctx, "order.Submit", attribute.String("order.id", orderID))
+	order, err := m.OrderManager.Submit(ctx, orderID)
+	tracing.End(span, err)
+	return order, err
+}
@@ -0,0 +1,169 @@
+package orderbook
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/exchange/dydx"
+)
+
+const codecVersion = 1
+
+// encodeSnapshots packs snapshots of one market into a flate-compressed
+// columnar layout: timestamps as millisecond deltas, level counts, then the
+// prices, sizes and order counts of every level. Prices and sizes are XORed
+// with the previous value's bits so that slowly changing books compress well.
+func encodeSnapshots(snapshots []dydx.Orderbook) ([]byte, error) {
+	var raw bytes.Buffer
+	buf := make([]byte, binary.MaxVarintLen64)
+	putUvarint := func(v uint64) {
+		raw.Write(buf[:binary.PutUvarint(buf, v)])
+	}
+	putVarint := func(v int64) {
+		raw.Write(buf[:binary.PutVarint(buf, v)])
+	}
+
+	raw.WriteByte(codecVersion)
+	putUvarint(uint64(len(snapshots)))
+
+	var previous int64
+	for _, snapshot := range snapshots {
+		ms := snapshot.Time.UnixMilli()
+		putVarint(ms - previous)
+		previous = ms
+	}
+	for _, snapshot := range snapshots {
+		putUvarint(uint64(len(snapshot.Bids)))
+		putUvarint(uint64(len(snapshot.Asks)))
+	}
+
+	var prevPrice, prevSize uint64
+	forEachLevel(snapshots, func(level dydx.OrderbookLevel) {
+		bits := math.Float64bits(level.Price)
+		putUvarint(bits ^ prevPrice)
+		prevPrice = bits
+	})
+	forEachLevel(snapshots, func(level dydx.OrderbookLevel) {
+		bits := math.Float64bits(level.Size)
+		putUvarint(bits ^ prevSize)
+		prevSize = bits
+	})
+	forEachLevel(snapshots, func(level dydx.OrderbookLevel) {
+		putUvarint(uint64(level.NumOrders))
+	})
+
+	var compressed bytes.Buffer
+	w, err := flate.NewWriter(&compressed, flate.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(raw.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return compressed.Bytes(), nil
+}
+
+// decodeSnapshots reverses encodeSnapshots
+func decodeSnapshots(market string, data []byte) ([]dydx.Orderbook, error) {
+	raw, err := io.ReadAll(flate.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptBlock, err)
+	}
+
+	r := bytes.NewReader(raw)
+	version, err := r.ReadByte()
+	if err != nil || version != codecVersion {
+		return nil, fmt.Errorf("%w: unsupported version", ErrCorruptBlock)
+	}
+
+	var decodeErr error
+	uvarint := func() uint64 {
+		v, err := binary.ReadUvarint(r)
+		if err != nil && decodeErr == nil {
+			decodeErr = err
+		}
+		return v
+	}
+	varint := func() int64 {
+		v, err := binary.ReadVarint(r)
+		if err != nil && decodeErr == nil {
+			decodeErr = err
+		}
+		return v
+	}
+
+	count := uvarint()
+	// Each snapshot takes at least one byte, which bounds the allocation
+	if decodeErr != nil || count > uint64(len(raw)) {
+		return nil, fmt.Errorf("%w: invalid snapshot count", ErrCorruptBlock)
+	}
+
+	snapshots := make([]dydx.Orderbook, count)
+	var ms int64
+	for i := range snapshots {
+		ms += varint()
+		snapshots[i].Market = market
+		snapshots[i].Time = time.UnixMilli(ms).UTC()
+	}
+	levels := uint64(0)
+	for i := range snapshots {
+		bids, asks := uvarint(), uvarint()
+		if decodeErr != nil || bids+asks > uint64(len(raw)) {
+			return nil, fmt.Errorf("%w: invalid level count", ErrCorruptBlock)
+		}
+		snapshots[i].Bids = make([]dydx.OrderbookLevel, bids)
+		snapshots[i].Asks = make([]dydx.OrderbookLevel, asks)
+		levels += bids + asks
+	}
+	if levels > uint64(len(raw)) {
+		return nil, fmt.Errorf("%w: invalid level count", ErrCorruptBlock)
+	}
+
+	var price, size uint64
+	forEachLevelPtr(snapshots, func(level *dydx.OrderbookLevel) {
+		price ^= uvarint()
+		level.Price = math.Float64frombits(price)
+	})
+	forEachLevelPtr(snapshots, func(level *dydx.OrderbookLevel) {
+		size ^= uvarint()
+		level.Size = math.Float64frombits(size)
+	})
+	forEachLevelPtr(snapshots, func(level *dydx.OrderbookLevel) {
+		level.NumOrders = int(uvarint())
+	})
+
+	if decodeErr != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptBlock, decodeErr)
+	}
+	return snapshots, nil
+}
+
+func forEachLevel(snapshots []dydx.Orderbook, fn func(dydx.OrderbookLevel)) {
+	for _, snapshot := range snapshots {
+		for _, level := range snapshot.Bids {
+			fn(level)
+		}
+		for _, level := range snapshot.Asks {
+			fn(level)
+		}
+	}
+}
+
+func forEachLevelPtr(snapshots []dydx.Orderbook, fn func(*dydx.OrderbookLevel)) {
+	for i := range snapshots {
+		for j := range snapshots[i].Bids {
+			fn(&snapshots[i].Bids[j])
+		}
+		for j := range snapshots[i].Asks {
+			fn(&snapshots[i].Asks[j])
+		}
+	}
+}
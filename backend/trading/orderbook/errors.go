@@ -0,0 +1,20 @@
+package orderbook
+
+import "errors"
+
+var (
+	// ErrNoSnapshot is returned when no snapshot exists at or before the requested time
+	ErrNoSnapshot = errors.New("no order book snapshot")
+
+	// ErrCorruptBlock is returned when a snapshot block cannot be decoded
+	ErrCorruptBlock = errors.New("corrupt snapshot block")
+
+	// ErrInvalidTiers is returned when retention tiers are not in increasing age order
+	ErrInvalidTiers = errors.New("invalid retention tiers")
+
+	// ErrSequenceGap is returned when a delta does not follow the last update applied to a book
+	ErrSequenceGap = errors.New("order book sequence gap")
+
+	// ErrNotSynced is returned when a delta is applied to a book without a snapshot
+	ErrNotSynced = errors.New("order book not synced")
+)
@@ -0,0 +1,33 @@
+package orderbook
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes registers the order book history HTTP endpoints
+func (r *Recorder) RegisterRoutes(g gin.IRouter) {
+	g.GET("/orderbook/:market/history", r.handleBookAt)
+}
+
+// handleBookAt returns the book as of the RFC 3339 "at" query parameter
+func (r *Recorder) handleBookAt(c *gin.Context) {
+	at, err := time.Parse(time.RFC3339Nano, c.Query("at"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at must be an RFC 3339 timestamp"})
+		return
+	}
+
+	book, err := r.BookAt(c.Request.Context(), c.Param("market"), at)
+	switch {
+	case errors.Is(err, ErrNoSnapshot):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, book)
+	}
+}
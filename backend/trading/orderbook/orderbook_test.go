@@ -0,0 +1,159 @@
+package orderbook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/exchange/dydx"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSource struct {
+	mid  float64
+	fail map[string]bool
+}
+
+func (s *fakeSource) GetOrderbook(ctx context.Context, symbol string) (*dydx.Orderbook, error) {
+	if s.fail[symbol] {
+		return nil, errors.New("unavailable")
+	}
+	book := &dydx.Orderbook{Market: symbol}
+	for i := 0; i < 5; i++ {
+		book.Bids = append(book.Bids, dydx.OrderbookLevel{Price: s.mid - 0.01*float64(i+1), Size: 1.5 + float64(i), NumOrders: i + 1})
+		book.Asks = append(book.Asks, dydx.OrderbookLevel{Price: s.mid + 0.01*float64(i+1), Size: 2.5 + float64(i), NumOrders: i + 2})
+	}
+	return book, nil
+}
+
+func TestCodec(t *testing.T) {
+	source := &fakeSource{mid: 100}
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	var snapshots []dydx.Orderbook
+	for i := 0; i < 100; i++ {
+		source.mid += 0.01
+		book, _ := source.GetOrderbook(context.Background(), "ETH-USD")
+		book.Time = base.Add(time.Duration(i) * 5 * time.Second)
+		snapshots = append(snapshots, *book)
+	}
+
+	data, err := encodeSnapshots(snapshots)
+	require.NoError(t, err)
+	raw, _ := json.Marshal(snapshots)
+	assert.Less(t, len(data)*4, len(raw), "encoded block should be well under a quarter of the JSON size")
+
+	decoded, err := decodeSnapshots("ETH-USD", data)
+	require.NoError(t, err)
+	assert.Equal(t, snapshots, decoded)
+
+	_, err = decodeSnapshots("ETH-USD", data[:len(data)/2])
+	assert.ErrorIs(t, err, ErrCorruptBlock)
+}
+
+func TestRecorder(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	source := &fakeSource{mid: 100, fail: map[string]bool{"BTC-USD": true}}
+	store := NewMemoryBlockStore()
+
+	_, err := NewRecorder(source, store, Config{Tiers: []RetentionTier{{MaxAge: time.Hour}, {MaxAge: time.Minute}}})
+	assert.ErrorIs(t, err, ErrInvalidTiers)
+
+	recorder, err := NewRecorder(source, store, Config{
+		Watchlist: []string{"ETH-USD", "BTC-USD"},
+		Depth:     3,
+		BlockSize: 10,
+		Tiers: []RetentionTier{
+			{MaxAge: time.Hour, Interval: 0},
+			{MaxAge: 24 * time.Hour, Interval: time.Minute},
+		},
+		MaxLookback: 10 * time.Minute,
+	})
+	require.NoError(t, err)
+
+	// 25 captures 10s apart: two full blocks and five pending snapshots
+	for i := 0; i < 25; i++ {
+		source.mid = 100 + float64(i)
+		err := recorder.Capture(ctx, base.Add(time.Duration(i)*10*time.Second))
+		assert.ErrorContains(t, err, "capture BTC-USD")
+	}
+	blocks, err := store.ListBlocks(ctx, "ETH-USD", time.Time{}, base.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, blocks, 2)
+	assert.Equal(t, 10, blocks[0].Count)
+
+	t.Run("Book as of a time", func(t *testing.T) {
+		// From a stored block
+		book, err := recorder.BookAt(ctx, "ETH-USD", base.Add(95*time.Second))
+		require.NoError(t, err)
+		assert.Equal(t, base.Add(90*time.Second), book.Time)
+		assert.Len(t, book.Bids, 3)
+		assert.InDelta(t, 108.99, book.Bids[0].Price, 1e-9)
+
+		// From the pending snapshots
+		book, err = recorder.BookAt(ctx, "ETH-USD", base.Add(241*time.Second))
+		require.NoError(t, err)
+		assert.Equal(t, base.Add(240*time.Second), book.Time)
+
+		// Beyond the lookback of the last snapshot
+		_, err = recorder.BookAt(ctx, "ETH-USD", base.Add(time.Hour))
+		assert.ErrorIs(t, err, ErrNoSnapshot)
+
+		_, err = recorder.BookAt(ctx, "ETH-USD", base.Add(-time.Second))
+		assert.ErrorIs(t, err, ErrNoSnapshot)
+	})
+
+	t.Run("Retention tiers downsample and expire blocks", func(t *testing.T) {
+		require.NoError(t, recorder.Flush(ctx))
+
+		// Blocks are 2h old: downsampled to one snapshot a minute
+		require.NoError(t, recorder.Compact(ctx, base.Add(2*time.Hour)))
+		blocks, err := store.ListBlocks(ctx, "ETH-USD", time.Time{}, base.Add(time.Hour))
+		require.NoError(t, err)
+		require.Len(t, blocks, 3)
+		assert.Equal(t, 2, blocks[0].Count)
+		assert.Equal(t, time.Minute, blocks[0].Resolution)
+
+		book, err := recorder.BookAt(ctx, "ETH-USD", base.Add(95*time.Second))
+		require.NoError(t, err)
+		assert.Equal(t, base.Add(60*time.Second), book.Time)
+
+		require.NoError(t, recorder.Compact(ctx, base.Add(48*time.Hour)))
+		blocks, err = store.ListBlocks(ctx, "ETH-USD", time.Time{}, base.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Empty(t, blocks)
+	})
+
+	t.Run("History endpoint", func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+		require.NoError(t, recorder.Record(ctx, "ETH-USD", dydx.Orderbook{
+			Bids: []dydx.OrderbookLevel{{Price: 99, Size: 1}},
+			Asks: []dydx.OrderbookLevel{{Price: 101, Size: 1}},
+		}, base))
+
+		r := gin.New()
+		recorder.RegisterRoutes(r)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orderbook/ETH-USD/history?at=2024-05-01T12:00:30Z", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		var book dydx.Orderbook
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &book))
+		assert.Equal(t, 99.0, book.Bids[0].Price)
+
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orderbook/ETH-USD/history?at=yesterday", nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orderbook/BTC-USD/history?at=2024-05-01T12:00:30Z", nil))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -0,0 +1,410 @@
+package orderbook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/exchange/dydx"
+	"github.com/devinjacknz/godydxhyber/backend/trading/analysis/monitoring"
+)
+
+// BookSource provides the current order book for a market
+type BookSource interface {
+	GetOrderbook(ctx context.Context, symbol string) (*dydx.Orderbook, error)
+}
+
+// Block is a compressed run of snapshots for one market
+type Block struct {
+	Market string
+	Start  time.Time
+	End    time.Time
+	Count  int
+	// Resolution is the minimum spacing between snapshots after downsampling.
+	// Zero means every captured snapshot is kept.
+	Resolution time.Duration
+	Data       []byte
+}
+
+// BlockStore persists snapshot blocks. SaveBlock replaces any block of the
+// same market and start time. ListBlocks returns the blocks overlapping
+// [from, to] ordered by start time.
+type BlockStore interface {
+	SaveBlock(ctx context.Context, block *Block) error
+	ListBlocks(ctx context.Context, market string, from, to time.Time) ([]*Block, error)
+	DeleteBlock(ctx context.Context, market string, start time.Time) error
+}
+
+// RetentionTier keeps snapshots younger than MaxAge at one per Interval
+type RetentionTier struct {
+	MaxAge   time.Duration
+	Interval time.Duration
+}
+
+// Config contains snapshot recorder configuration
+type Config struct {
+	// Watchlist is the list of markets captured
+	Watchlist []string
+	// Interval is the time between captures
+	Interval time.Duration
+	// Depth is the number of levels kept per side
+	Depth int
+	// BlockSize is the number of snapshots per block
+	BlockSize int
+	// Tiers are the retention tiers in increasing MaxAge order. Snapshots
+	// older than the last tier are deleted.
+	Tiers []RetentionTier
+	// MaxLookback bounds how far before the requested time BookAt searches
+	MaxLookback time.Duration
+}
+
+// DefaultConfig returns default snapshot recorder configuration
+func DefaultConfig() Config {
+	return Config{
+		Interval:  5 * time.Second,
+		Depth:     50,
+		BlockSize: 120,
+		Tiers: []RetentionTier{
+			{MaxAge: 24 * time.Hour, Interval: 0},
+			{MaxAge: 7 * 24 * time.Hour, Interval: time.Minute},
+			{MaxAge: 30 * 24 * time.Hour, Interval: 15 * time.Minute},
+		},
+		MaxLookback: time.Hour,
+	}
+}
+
+// Recorder captures periodic order book snapshots for the watchlist and
+// serves the book as of a past time for backtests and forensics
+type Recorder struct {
+	source  BookSource
+	store   BlockStore
+	config  Config
+	pending map[string][]dydx.Orderbook
+	mu      sync.Mutex
+}
+
+// NewRecorder creates a new order book snapshot recorder
+func NewRecorder(source BookSource, store BlockStore, config Config) (*Recorder, error) {
+	defaults := DefaultConfig()
+	if config.Interval <= 0 {
+		config.Interval = defaults.Interval
+	}
+	if config.Depth <= 0 {
+		config.Depth = defaults.Depth
+	}
+	if config.BlockSize <= 0 {
+		config.BlockSize = defaults.BlockSize
+	}
+	if config.Tiers == nil {
+		config.Tiers = defaults.Tiers
+	}
+	if config.MaxLookback <= 0 {
+		config.MaxLookback = defaults.MaxLookback
+	}
+	for i, tier := range config.Tiers {
+		if tier.MaxAge <= 0 || tier.Interval < 0 || (i > 0 && tier.MaxAge <= config.Tiers[i-1].MaxAge) {
+			return nil, ErrInvalidTiers
+		}
+	}
+
+	return &Recorder{
+		source:  source,
+		store:   store,
+		config:  config,
+		pending: make(map[string][]dydx.Orderbook),
+	}, nil
+}
+
+// Run captures snapshots every interval and compacts stored blocks hourly
+// until the context is cancelled, then flushes pending snapshots
+func (r *Recorder) Run(ctx context.Context) error {
+	capture := time.NewTicker(r.config.Interval)
+	defer capture.Stop()
+	compact := time.NewTicker(time.Hour)
+	defer compact.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Flush with a fresh context so pending snapshots are not lost on shutdown
+			flushCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := r.Flush(flushCtx); err != nil {
+				return err
+			}
+			return ctx.Err()
+		case now := <-capture.C:
+			if err := r.Capture(ctx, now); err != nil {
+				monitoring.RecordStorageError("orderbook_capture", err.Error())
+			}
+		case now := <-compact.C:
+			if err := r.Compact(ctx, now); err != nil {
+				monitoring.RecordStorageError("orderbook_compact", err.Error())
+			}
+		}
+	}
+}
+
+// Capture records the current book of every watchlist market. A market that
+// fails does not prevent the others from being captured.
+func (r *Recorder) Capture(ctx context.Context, now time.Time) error {
+	var errs []error
+	for _, market := range r.config.Watchlist {
+		book, err := r.source.GetOrderbook(ctx, market)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("capture %s: %w", market, err))
+			continue
+		}
+		if err := r.Record(ctx, market, *book, now); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Record adds a snapshot taken at the given time, writing a block once
+// BlockSize snapshots are pending for the market
+func (r *Recorder) Record(ctx context.Context, market string, book dydx.Orderbook, at time.Time) error {
+	snapshot := dydx.Orderbook{
+		Market: market,
+		Bids:   truncateLevels(book.Bids, r.config.Depth),
+		Asks:   truncateLevels(book.Asks, r.config.Depth),
+		Time:   at.UTC().Truncate(time.Millisecond),
+	}
+
+	r.mu.Lock()
+	r.pending[market] = append(r.pending[market], snapshot)
+	var full []dydx.Orderbook
+	if len(r.pending[market]) >= r.config.BlockSize {
+		full = r.pending[market]
+		delete(r.pending, market)
+	}
+	r.mu.Unlock()
+
+	if full == nil {
+		return nil
+	}
+	return r.writeBlock(ctx, market, full, 0)
+}
+
+// Flush writes all pending snapshots as blocks
+func (r *Recorder) Flush(ctx context.Context) error {
+	r.mu.Lock()
+	pending := r.pending
+	r.pending = make(map[string][]dydx.Orderbook)
+	r.mu.Unlock()
+
+	var errs []error
+	for market, snapshots := range pending {
+		if err := r.writeBlock(ctx, market, snapshots, 0); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// BookAt returns the latest snapshot of the market taken at or before the
+// given time, within MaxLookback
+func (r *Recorder) BookAt(ctx context.Context, market string, at time.Time) (*dydx.Orderbook, error) {
+	var found *dydx.Orderbook
+
+	r.mu.Lock()
+	for i := len(r.pending[market]) - 1; i >= 0; i-- {
+		snapshot := r.pending[market][i]
+		if !snapshot.Time.After(at) {
+			found = &snapshot
+			break
+		}
+	}
+	r.mu.Unlock()
+	if found != nil && !found.Time.Before(at.Add(-r.config.MaxLookback)) {
+		return found, nil
+	}
+
+	blocks, err := r.store.ListBlocks(ctx, market, at.Add(-r.config.MaxLookback), at)
+	if err != nil {
+		return nil, fmt.Errorf("list blocks: %w", err)
+	}
+	for i := len(blocks) - 1; i >= 0; i-- {
+		snapshots, err := decodeSnapshots(market, blocks[i].Data)
+		if err != nil {
+			return nil, fmt.Errorf("block %s %s: %w", market, blocks[i].Start.Format(time.RFC3339), err)
+		}
+		for j := len(snapshots) - 1; j >= 0; j-- {
+			if snapshots[j].Time.After(at) {
+				continue
+			}
+			if snapshots[j].Time.Before(at.Add(-r.config.MaxLookback)) {
+				break
+			}
+			return &snapshots[j], nil
+		}
+	}
+	return nil, fmt.Errorf("%w for %s at %s", ErrNoSnapshot, market, at.Format(time.RFC3339))
+}
+
+// Compact applies the retention tiers to stored blocks: blocks are
+// downsampled to the interval of the tier their age falls in, and blocks
+// older than the last tier are deleted
+func (r *Recorder) Compact(ctx context.Context, now time.Time) error {
+	if len(r.config.Tiers) == 0 {
+		return nil
+	}
+
+	var errs []error
+	for _, market := range r.config.Watchlist {
+		blocks, err := r.store.ListBlocks(ctx, market, time.Time{}, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("list blocks for %s: %w", market, err))
+			continue
+		}
+		for _, block := range blocks {
+			if err := r.compactBlock(ctx, block, now); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Recorder) compactBlock(ctx context.Context, block *Block, now time.Time) error {
+	age := now.Sub(block.End)
+	tier := sort.Search(len(r.config.Tiers), func(i int) bool {
+		return age < r.config.Tiers[i].MaxAge
+	})
+	if tier == len(r.config.Tiers) {
+		if err := r.store.DeleteBlock(ctx, block.Market, block.Start); err != nil {
+			return fmt.Errorf("delete block %s %s: %w", block.Market, block.Start.Format(time.RFC3339), err)
+		}
+		return nil
+	}
+
+	interval := r.config.Tiers[tier].Interval
+	if interval <= block.Resolution {
+		return nil
+	}
+
+	snapshots, err := decodeSnapshots(block.Market, block.Data)
+	if err != nil {
+		return fmt.Errorf("block %s %s: %w", block.Market, block.Start.Format(time.RFC3339), err)
+	}
+	return r.writeBlock(ctx, block.Market, downsample(snapshots, interval), interval)
+}
+
+func (r *Recorder) writeBlock(ctx context.Context, market string, snapshots []dydx.Orderbook, resolution time.Duration) error {
+	if len(snapshots) == 0 {
+		return nil
+	}
+	start := time.Now()
+
+	data, err := encodeSnapshots(snapshots)
+	if err != nil {
+		monitoring.RecordStorageError("orderbook_block", err.Error())
+		return fmt.Errorf("encode block for %s: %w", market, err)
+	}
+	block := &Block{
+		Market:     market,
+		Start:      snapshots[0].Time,
+		End:        snapshots[len(snapshots)-1].Time,
+		Count:      len(snapshots),
+		Resolution: resolution,
+		Data:       data,
+	}
+	if err := r.store.SaveBlock(ctx, block); err != nil {
+		monitoring.RecordStorageError("orderbook_block", err.Error())
+		return fmt.Errorf("save block for %s: %w", market, err)
+	}
+
+	monitoring.RecordStorageOperation("orderbook_block", time.Since(start))
+	return nil
+}
+
+// downsample keeps the first snapshot of every interval. The first snapshot
+// is always kept so the block's start time is unchanged.
+func downsample(snapshots []dydx.Orderbook, interval time.Duration) []dydx.Orderbook {
+	kept := snapshots[:1]
+	next := snapshots[0].Time.Truncate(interval).Add(interval)
+	for _, snapshot := range snapshots[1:] {
+		if snapshot.Time.Before(next) {
+			continue
+		}
+		kept = append(kept, snapshot)
+		next = snapshot.Time.Truncate(interval).Add(interval)
+	}
+	return kept
+}
+
+func truncateLevels(levels []dydx.OrderbookLevel, depth int) []dydx.OrderbookLevel {
+	if len(levels) > depth {
+		levels = levels[:depth]
+	}
+	copied := make([]dydx.OrderbookLevel, len(levels))
+	copy(copied, levels)
+	return copied
+}
+
+// MemoryBlockStore keeps snapshot blocks in memory
+type MemoryBlockStore struct {
+	blocks map[string][]*Block
+	mu     sync.RWMutex
+}
+
+// NewMemoryBlockStore creates an in-memory block store
+func NewMemoryBlockStore() *MemoryBlockStore {
+	return &MemoryBlockStore{
+		blocks: make(map[string][]*Block),
+	}
+}
+
+// SaveBlock stores a block, replacing any block with the same start time
+func (s *MemoryBlockStore) SaveBlock(ctx context.Context, block *Block) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	blocks := s.blocks[block.Market]
+	i := sort.Search(len(blocks), func(i int) bool {
+		return !blocks[i].Start.Before(block.Start)
+	})
+	if i < len(blocks) && blocks[i].Start.Equal(block.Start) {
+		blocks[i] = block
+		return nil
+	}
+	blocks = append(blocks, nil)
+	copy(blocks[i+1:], blocks[i:])
+	blocks[i] = block
+	s.blocks[block.Market] = blocks
+	return nil
+}
+
+// ListBlocks returns the blocks overlapping [from, to]
+func (s *MemoryBlockStore) ListBlocks(ctx context.Context, market string, from, to time.Time) ([]*Block, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var blocks []*Block
+	for _, block := range s.blocks[market] {
+		if block.End.Before(from) || block.Start.After(to) {
+			continue
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+// DeleteBlock removes a block
+func (s *MemoryBlockStore) DeleteBlock(ctx context.Context, market string, start time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	blocks := s.blocks[market]
+	for i, block := range blocks {
+		if block.Start.Equal(start) {
+			s.blocks[market] = append(blocks[:i], blocks[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,385 @@
+package orderbook
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/exchange/dydx"
+	"github.com/devinjacknz/godydxhyber/backend/pkg/websocket"
+)
+
+// Update types of the order book stream
+const (
+	UpdateSnapshot = "snapshot"
+	UpdateDelta    = "delta"
+)
+
+// TopicPrefix is the prefix of the push channel topics of order books
+const TopicPrefix = "orderbook:"
+
+// Topic returns the push channel topic of the order book of a market
+func Topic(market string) string {
+	return TopicPrefix + market
+}
+
+// Update is a message of an order book stream. A snapshot carries the whole
+// book; a delta carries the levels changed since the previous update, a
+// level of size zero being removed. Seq increases by one with every update
+// of a market, so a receiver missing an update sees a gap and resyncs from a
+// new snapshot.
+type Update struct {
+	Type   string                `json:"type"`
+	Market string                `json:"market"`
+	Seq    uint64                `json:"seq"`
+	Bids   []dydx.OrderbookLevel `json:"bids"`
+	Asks   []dydx.OrderbookLevel `json:"asks"`
+	Time   time.Time             `json:"time"`
+}
+
+// Book is an order book maintained from a stream of updates
+type Book struct {
+	market string
+	seq    uint64
+	synced bool
+	bids   map[float64]dydx.OrderbookLevel
+	asks   map[float64]dydx.OrderbookLevel
+	time   time.Time
+}
+
+// NewBook creates an empty book, synced by its first snapshot
+func NewBook(market string) *Book {
+	return &Book{
+		market: market,
+		bids:   make(map[float64]dydx.OrderbookLevel),
+		asks:   make(map[float64]dydx.OrderbookLevel),
+	}
+}
+
+// Apply applies an update. A delta not directly following the last update
+// returns ErrSequenceGap and unsyncs the book until the next snapshot.
+func (b *Book) Apply(u Update) error {
+	if u.Type == UpdateSnapshot {
+		clear(b.bids)
+		clear(b.asks)
+		b.seq = u.Seq
+		b.synced = true
+		b.apply(u)
+		return nil
+	}
+
+	if !b.synced {
+		return ErrNotSynced
+	}
+	if u.Seq != b.seq+1 {
+		b.synced = false
+		return fmt.Errorf("%w: %s expected %d, got %d", ErrSequenceGap, b.market, b.seq+1, u.Seq)
+	}
+	b.seq = u.Seq
+	b.apply(u)
+	return nil
+}
+
+func (b *Book) apply(u Update) {
+	for _, level := range u.Bids {
+		setLevel(b.bids, level)
+	}
+	for _, level := range u.Asks {
+		setLevel(b.asks, level)
+	}
+	b.time = u.Time
+}
+
+func setLevel(levels map[float64]dydx.OrderbookLevel, level dydx.OrderbookLevel) {
+	if level.Size <= 0 {
+		delete(levels, level.Price)
+		return
+	}
+	levels[level.Price] = level
+}
+
+// Seq returns the sequence number of the last update applied
+func (b *Book) Seq() uint64 {
+	return b.seq
+}
+
+// Synced reports whether the book has a snapshot and no gap since
+func (b *Book) Synced() bool {
+	return b.synced
+}
+
+// Orderbook returns the best levels of each side, best first. A depth of
+// zero returns every level.
+func (b *Book) Orderbook(depth int) dydx.Orderbook {
+	return dydx.Orderbook{
+		Market: b.market,
+		Bids:   sortedLevels(b.bids, depth, true),
+		Asks:   sortedLevels(b.asks, depth, false),
+		Time:   b.time,
+	}
+}
+
+func sortedLevels(levels map[float64]dydx.OrderbookLevel, depth int, descending bool) []dydx.OrderbookLevel {
+	sorted := make([]dydx.OrderbookLevel, 0, len(levels))
+	for _, level := range levels {
+		sorted = append(sorted, level)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if descending {
+			return sorted[i].Price > sorted[j].Price
+		}
+		return sorted[i].Price < sorted[j].Price
+	})
+	if depth > 0 && len(sorted) > depth {
+		sorted = sorted[:depth]
+	}
+	return sorted
+}
+
+// SnapshotSource returns a snapshot of a book along with the sequence
+// number of the last update it includes, to resync a follower
+type SnapshotSource interface {
+	OrderbookSnapshot(ctx context.Context, market string) (Update, error)
+}
+
+// FollowerConfig contains feed follower configuration
+type FollowerConfig struct {
+	// MaxPending bounds the deltas buffered while resyncing
+	MaxPending int
+	// ResyncBackoff is the minimum time between two snapshot requests
+	ResyncBackoff time.Duration
+}
+
+// DefaultFollowerConfig returns default feed follower configuration
+func DefaultFollowerConfig() FollowerConfig {
+	return FollowerConfig{
+		MaxPending:    1000,
+		ResyncBackoff: time.Second,
+	}
+}
+
+// Follower maintains the book of a market from an exchange feed, such as
+// the dYdX order book channel. On a gap it requests a snapshot and replays
+// the deltas received meanwhile that the snapshot does not include.
+type Follower struct {
+	book       *Book
+	source     SnapshotSource
+	config     FollowerConfig
+	pending    []Update
+	lastResync time.Time
+	now        func() time.Time
+	mu         sync.Mutex
+}
+
+// NewFollower creates a follower of the book of a market
+func NewFollower(market string, source SnapshotSource, config FollowerConfig) *Follower {
+	defaults := DefaultFollowerConfig()
+	if config.MaxPending <= 0 {
+		config.MaxPending = defaults.MaxPending
+	}
+	if config.ResyncBackoff <= 0 {
+		config.ResyncBackoff = defaults.ResyncBackoff
+	}
+
+	return &Follower{
+		book:   NewBook(market),
+		source: source,
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// Handle applies an update of the feed and reports whether the book is
+// synced afterwards. While it is not, deltas are buffered and a snapshot is
+// requested at most every ResyncBackoff; the error is that of the request.
+func (f *Follower) Handle(ctx context.Context, u Update) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if u.Type == UpdateSnapshot {
+		f.book.Apply(u)
+		f.replay()
+		return f.book.Synced(), nil
+	}
+	if f.book.Synced() {
+		if err := f.book.Apply(u); err == nil {
+			return true, nil
+		}
+	}
+
+	f.pending = append(f.pending, u)
+	if len(f.pending) > f.config.MaxPending {
+		f.pending = f.pending[len(f.pending)-f.config.MaxPending:]
+	}
+	if f.now().Sub(f.lastResync) < f.config.ResyncBackoff {
+		return false, nil
+	}
+	f.lastResync = f.now()
+	snapshot, err := f.source.OrderbookSnapshot(ctx, f.book.market)
+	if err != nil {
+		return false, fmt.Errorf("failed to resync %s: %w", f.book.market, err)
+	}
+	f.book.Apply(snapshot)
+	f.replay()
+	return f.book.Synced(), nil
+}
+
+// replay applies the buffered deltas newer than the snapshot just applied.
+// A gap between them leaves the book unsynced with the deltas still
+// buffered, for a later snapshot.
+func (f *Follower) replay() {
+	pending := f.pending
+	f.pending = nil
+	for i, u := range pending {
+		if u.Seq <= f.book.Seq() {
+			continue
+		}
+		if err := f.book.Apply(u); err != nil {
+			f.pending = pending[i:]
+			return
+		}
+	}
+}
+
+// Orderbook returns the best levels of the followed book and whether it is
+// synced
+func (f *Follower) Orderbook(depth int) (dydx.Orderbook, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.book.Orderbook(depth), f.book.Synced()
+}
+
+// Publisher pushes updates to subscribed clients. *websocket.Hub
+// implements it.
+type Publisher interface {
+	RegisterTopic(name string, config websocket.TopicConfig)
+	SetSnapshot(topic string, fn websocket.SnapshotFunc)
+	Publish(topic string, data interface{})
+}
+
+// StreamConfig contains order book fan-out configuration
+type StreamConfig struct {
+	// Depth is the number of levels per side streamed to clients
+	Depth int
+}
+
+// DefaultStreamConfig returns default order book fan-out configuration
+func DefaultStreamConfig() StreamConfig {
+	return StreamConfig{
+		Depth: 25,
+	}
+}
+
+// streamedBook is the last book streamed for a market
+type streamedBook struct {
+	seq  uint64
+	bids []dydx.OrderbookLevel
+	asks []dydx.OrderbookLevel
+	time time.Time
+}
+
+// Stream fans books out to push channel clients as a snapshot when they
+// subscribe followed by deltas. Clients seeing a gap in Seq subscribe again
+// for a new snapshot.
+type Stream struct {
+	hub    Publisher
+	config StreamConfig
+	books  map[string]*streamedBook
+	mu     sync.Mutex
+}
+
+// NewStream creates an order book stream and registers its topics on hub.
+// Deltas cannot be conflated, so clients falling behind are disconnected.
+func NewStream(hub Publisher, config StreamConfig) *Stream {
+	if config.Depth <= 0 {
+		config.Depth = DefaultStreamConfig().Depth
+	}
+	hub.RegisterTopic(TopicPrefix, websocket.TopicConfig{Prefix: true})
+
+	return &Stream{
+		hub:    hub,
+		config: config,
+		books:  make(map[string]*streamedBook),
+	}
+}
+
+// Publish streams a new book of a market, levels best first. The first book
+// of a market is published as a snapshot, later ones as the delta of the
+// streamed depth, if anything changed.
+func (s *Stream) Publish(book dydx.Orderbook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bids := truncateLevels(book.Bids, s.config.Depth)
+	asks := truncateLevels(book.Asks, s.config.Depth)
+	streamed, ok := s.books[book.Market]
+	if !ok {
+		streamed = &streamedBook{seq: 1, bids: bids, asks: asks, time: book.Time}
+		s.books[book.Market] = streamed
+		market := book.Market
+		s.hub.SetSnapshot(Topic(market), func() (interface{}, error) {
+			return s.snapshot(market), nil
+		})
+		s.hub.Publish(Topic(market), s.snapshotLocked(market))
+		return
+	}
+
+	delta := Update{
+		Type:   UpdateDelta,
+		Market: book.Market,
+		Bids:   diffLevels(streamed.bids, bids),
+		Asks:   diffLevels(streamed.asks, asks),
+		Time:   book.Time,
+	}
+	if len(delta.Bids) == 0 && len(delta.Asks) == 0 {
+		return
+	}
+	streamed.seq++
+	streamed.bids, streamed.asks, streamed.time = bids, asks, book.Time
+	delta.Seq = streamed.seq
+	s.hub.Publish(Topic(book.Market), delta)
+}
+
+// snapshot returns the streamed book of a market
+func (s *Stream) snapshot(market string) Update {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshotLocked(market)
+}
+
+func (s *Stream) snapshotLocked(market string) Update {
+	streamed := s.books[market]
+	return Update{
+		Type:   UpdateSnapshot,
+		Market: market,
+		Seq:    streamed.seq,
+		Bids:   streamed.bids,
+		Asks:   streamed.asks,
+		Time:   streamed.time,
+	}
+}
+
+// diffLevels returns the levels of next that are new or changed from prev,
+// and the levels of prev that next no longer has with a size of zero
+func diffLevels(prev, next []dydx.OrderbookLevel) []dydx.OrderbookLevel {
+	old := make(map[float64]dydx.OrderbookLevel, len(prev))
+	for _, level := range prev {
+		old[level.Price] = level
+	}
+
+	var changed []dydx.OrderbookLevel
+	for _, level := range next {
+		if previous, ok := old[level.Price]; !ok || previous != level {
+			changed = append(changed, level)
+		}
+		delete(old, level.Price)
+	}
+	for _, level := range prev {
+		if _, ok := old[level.Price]; ok {
+			changed = append(changed, dydx.OrderbookLevel{Price: level.Price})
+		}
+	}
+	return changed
+}
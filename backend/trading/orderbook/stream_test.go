@@ -0,0 +1,176 @@
+package orderbook
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/exchange/dydx"
+	"github.com/devinjacknz/godydxhyber/backend/pkg/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func level(price, size float64) dydx.OrderbookLevel {
+	return dydx.OrderbookLevel{Price: price, Size: size, NumOrders: 1}
+}
+
+func TestBook(t *testing.T) {
+	book := NewBook("ETH-USD")
+	assert.ErrorIs(t, book.Apply(Update{Type: UpdateDelta, Seq: 1}), ErrNotSynced)
+
+	require.NoError(t, book.Apply(Update{
+		Type: UpdateSnapshot,
+		Seq:  10,
+		Bids: []dydx.OrderbookLevel{level(99, 1), level(98, 2), level(97, 3)},
+		Asks: []dydx.OrderbookLevel{level(101, 1), level(102, 2)},
+	}))
+	require.NoError(t, book.Apply(Update{
+		Type: UpdateDelta,
+		Seq:  11,
+		Bids: []dydx.OrderbookLevel{level(99.5, 4), {Price: 98}},
+		Asks: []dydx.OrderbookLevel{level(101, 5)},
+	}))
+
+	ob := book.Orderbook(2)
+	assert.Equal(t, []dydx.OrderbookLevel{level(99.5, 4), level(99, 1)}, ob.Bids)
+	assert.Equal(t, []dydx.OrderbookLevel{level(101, 5), level(102, 2)}, ob.Asks)
+	assert.Len(t, book.Orderbook(0).Bids, 3)
+
+	err := book.Apply(Update{Type: UpdateDelta, Seq: 13})
+	assert.ErrorIs(t, err, ErrSequenceGap)
+	assert.False(t, book.Synced())
+	assert.ErrorIs(t, book.Apply(Update{Type: UpdateDelta, Seq: 12}), ErrNotSynced)
+}
+
+type snapshotSource struct {
+	snapshot Update
+	err      error
+	calls    int
+}
+
+func (s *snapshotSource) OrderbookSnapshot(ctx context.Context, market string) (Update, error) {
+	s.calls++
+	return s.snapshot, s.err
+}
+
+func TestFollower(t *testing.T) {
+	ctx := context.Background()
+	source := &snapshotSource{err: errors.New("unavailable")}
+	f := NewFollower("ETH-USD", source, FollowerConfig{ResyncBackoff: time.Second})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	f.now = func() time.Time { return now }
+
+	synced, err := f.Handle(ctx, Update{Type: UpdateSnapshot, Seq: 1, Bids: []dydx.OrderbookLevel{level(99, 1)}})
+	require.NoError(t, err)
+	assert.True(t, synced)
+	synced, err = f.Handle(ctx, Update{Type: UpdateDelta, Seq: 2, Bids: []dydx.OrderbookLevel{level(99, 2)}})
+	require.NoError(t, err)
+	assert.True(t, synced)
+
+	// Delta 3 is lost; the resync fails and is not retried within the backoff
+	synced, err = f.Handle(ctx, Update{Type: UpdateDelta, Seq: 4, Bids: []dydx.OrderbookLevel{level(98, 1)}})
+	assert.False(t, synced)
+	assert.Error(t, err)
+	synced, err = f.Handle(ctx, Update{Type: UpdateDelta, Seq: 5, Asks: []dydx.OrderbookLevel{level(101, 1)}})
+	require.NoError(t, err)
+	assert.False(t, synced)
+	assert.Equal(t, 1, source.calls)
+
+	// The snapshot includes delta 4 but not 5 and 6, which are replayed
+	source.err = nil
+	source.snapshot = Update{
+		Type: UpdateSnapshot,
+		Seq:  4,
+		Bids: []dydx.OrderbookLevel{level(99, 2), level(98, 1)},
+		Asks: []dydx.OrderbookLevel{level(100.5, 1)},
+	}
+	now = now.Add(2 * time.Second)
+	synced, err = f.Handle(ctx, Update{Type: UpdateDelta, Seq: 6, Asks: []dydx.OrderbookLevel{{Price: 100.5}}})
+	require.NoError(t, err)
+	assert.True(t, synced)
+	assert.Equal(t, 2, source.calls)
+
+	ob, synced := f.Orderbook(10)
+	assert.True(t, synced)
+	assert.Equal(t, []dydx.OrderbookLevel{level(99, 2), level(98, 1)}, ob.Bids)
+	assert.Equal(t, []dydx.OrderbookLevel{level(101, 1)}, ob.Asks)
+}
+
+type publishedUpdate struct {
+	topic string
+	data  interface{}
+}
+
+type fakePublisher struct {
+	topics    map[string]websocket.TopicConfig
+	snapshots map[string]websocket.SnapshotFunc
+	published []publishedUpdate
+}
+
+func (p *fakePublisher) RegisterTopic(name string, config websocket.TopicConfig) {
+	p.topics[name] = config
+}
+
+func (p *fakePublisher) SetSnapshot(topic string, fn websocket.SnapshotFunc) {
+	p.snapshots[topic] = fn
+}
+
+func (p *fakePublisher) Publish(topic string, data interface{}) {
+	p.published = append(p.published, publishedUpdate{topic: topic, data: data})
+}
+
+func TestStream(t *testing.T) {
+	hub := &fakePublisher{
+		topics:    make(map[string]websocket.TopicConfig),
+		snapshots: make(map[string]websocket.SnapshotFunc),
+	}
+	s := NewStream(hub, StreamConfig{Depth: 2})
+	assert.True(t, hub.topics[TopicPrefix].Prefix)
+	assert.False(t, hub.topics[TopicPrefix].Conflate, "deltas are not conflated")
+
+	s.Publish(dydx.Orderbook{
+		Market: "ETH-USD",
+		Bids:   []dydx.OrderbookLevel{level(99, 1), level(98, 2), level(97, 3)},
+		Asks:   []dydx.OrderbookLevel{level(101, 1)},
+	})
+	require.Len(t, hub.published, 1)
+	assert.Equal(t, Topic("ETH-USD"), hub.published[0].topic)
+	first := hub.published[0].data.(Update)
+	assert.Equal(t, UpdateSnapshot, first.Type)
+	assert.Len(t, first.Bids, 2, "truncated to the depth")
+
+	// 97 enters the streamed depth as 99 leaves
+	s.Publish(dydx.Orderbook{
+		Market: "ETH-USD",
+		Bids:   []dydx.OrderbookLevel{level(98, 2), level(97, 3)},
+		Asks:   []dydx.OrderbookLevel{level(101, 1)},
+	})
+	s.Publish(dydx.Orderbook{
+		Market: "ETH-USD",
+		Bids:   []dydx.OrderbookLevel{level(98, 2), level(97, 3), level(96, 1)},
+		Asks:   []dydx.OrderbookLevel{level(101, 1)},
+	})
+	require.Len(t, hub.published, 2, "changes beyond the depth are not streamed")
+	delta := hub.published[1].data.(Update)
+	assert.Equal(t, UpdateDelta, delta.Type)
+	assert.Equal(t, uint64(2), delta.Seq)
+	assert.ElementsMatch(t, []dydx.OrderbookLevel{level(97, 3), {Price: 99}}, delta.Bids)
+	assert.Empty(t, delta.Asks)
+
+	// A client subscribing now gets the book the deltas apply to
+	data, err := hub.snapshots[Topic("ETH-USD")]()
+	require.NoError(t, err)
+	book := NewBook("ETH-USD")
+	require.NoError(t, book.Apply(data.(Update)))
+	assert.Equal(t, uint64(2), book.Seq())
+
+	s.Publish(dydx.Orderbook{
+		Market: "ETH-USD",
+		Bids:   []dydx.OrderbookLevel{level(98, 2), level(97, 3)},
+		Asks:   []dydx.OrderbookLevel{level(100.5, 2), level(101, 1)},
+	})
+	require.NoError(t, book.Apply(hub.published[2].data.(Update)))
+	assert.Equal(t, []dydx.OrderbookLevel{level(100.5, 2), level(101, 1)}, book.Orderbook(2).Asks)
+}
@@ -0,0 +1,18 @@
+package portfolio
+
+import "errors"
+
+var (
+	// ErrInvalidFill is returned for fills without a venue, a symbol, a buy
+	// or sell side or a positive size
+	ErrInvalidFill = errors.New("invalid fill")
+
+	// ErrInvalidVenue is returned when the venue is empty
+	ErrInvalidVenue = errors.New("invalid venue")
+
+	// ErrInvalidPrice is returned when a mark price is not positive
+	ErrInvalidPrice = errors.New("invalid price")
+
+	// ErrInvalidCollateral is returned when a collateral balance is negative
+	ErrInvalidCollateral = errors.New("invalid collateral")
+)
@@ -0,0 +1,45 @@
+package portfolio
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/devinjacknz/godydxhyber/backend/middleware"
+)
+
+type collateralRequest struct {
+	Balance *float64 `json:"balance" binding:"required"`
+}
+
+// RegisterRoutes registers the portfolio HTTP endpoints: the exposure across
+// venues and the collateral of each venue. The exposure aggregates every
+// account, so tokens limited to some accounts cannot read it.
+func RegisterRoutes(r gin.IRouter, p *Portfolio) {
+	r.GET("/portfolio", func(c *gin.Context) {
+		if !middleware.CanViewAllPortfolios(c) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "the portfolio aggregates accounts outside the token's scope"})
+			return
+		}
+		c.JSON(http.StatusOK, p.Exposure(c.Request.Context()))
+	})
+
+	r.PUT("/portfolio/collateral/:venue", func(c *gin.Context) {
+		var req collateralRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		err := p.SetCollateral(c.Request.Context(), c.Param("venue"), *req.Balance)
+		if errors.Is(err, ErrInvalidVenue) || errors.Is(err, ErrInvalidCollateral) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, p.Exposure(c.Request.Context()))
+	})
+}
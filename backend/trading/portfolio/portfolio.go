@@ -0,0 +1,269 @@
+// Package portfolio aggregates the fills of every venue, the dYdX orders of
+// the engine and the fills of other venues such as a Solana DEX executor,
+// into the net exposure of each token, the collateral and the leverage of
+// the whole portfolio.
+package portfolio
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/eventbus"
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+)
+
+// Position is the net position of a symbol on one venue
+type Position struct {
+	Venue  string `json:"venue"`
+	Symbol string `json:"symbol"`
+	Token  string `json:"token"`
+	// Size is the net size, positive long and negative short
+	Size float64 `json:"size"`
+	// MarkPrice is the last fill or market price of the symbol on any venue
+	MarkPrice float64   `json:"mark_price"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Notional returns the signed value of the position at its mark price
+func (p Position) Notional() float64 {
+	return p.Size * p.MarkPrice
+}
+
+// Exposure is the exposure of the portfolio across venues
+type Exposure struct {
+	// Tokens is the net notional of each token, netted across venues
+	Tokens    map[string]float64 `json:"tokens"`
+	Positions []Position         `json:"positions"`
+	// NetExposure is the sum of the token notionals, longs offsetting shorts
+	NetExposure float64 `json:"net_exposure"`
+	// GrossExposure is the sum of the absolute token notionals
+	GrossExposure float64 `json:"gross_exposure"`
+	// Collateral is the collateral of all venues
+	Collateral float64 `json:"collateral"`
+	// Leverage is the gross exposure over the collateral, zero without
+	// collateral
+	Leverage  float64   `json:"leverage"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Listener is notified of the exposure after every change. It must not
+// block or call back into the portfolio.
+type Listener func(ctx context.Context, exposure *Exposure)
+
+// Option configures a Portfolio
+type Option func(*Portfolio)
+
+// WithListener notifies the listener of the exposure after every fill,
+// price and collateral change
+func WithListener(listener Listener) Option {
+	return func(p *Portfolio) {
+		p.listener = listener
+	}
+}
+
+// Portfolio tracks the positions and collateral of every venue
+type Portfolio struct {
+	// positions are keyed by venue and symbol
+	positions map[positionKey]*Position
+	// marks are the last prices keyed by symbol
+	marks      map[string]float64
+	collateral map[string]float64
+	listener   Listener
+	updatedAt  time.Time
+	mu         sync.RWMutex
+}
+
+type positionKey struct {
+	venue  string
+	symbol string
+}
+
+// New creates an empty portfolio
+func New(opts ...Option) *Portfolio {
+	p := &Portfolio{
+		positions:  make(map[positionKey]*Position),
+		marks:      make(map[string]float64),
+		collateral: make(map[string]float64),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Token returns the traded token of a symbol, the base asset of pairs such
+// as BTC-USD or SOL/USDC and the symbol itself otherwise, such as a mint
+// address
+func Token(symbol string) string {
+	if i := strings.IndexAny(symbol, "-/"); i > 0 {
+		return strings.ToUpper(symbol[:i])
+	}
+	return symbol
+}
+
+// ApplyFill adds a fill to the position of its venue. Fills without a price
+// are valued at the mark price of the symbol.
+func (p *Portfolio) ApplyFill(ctx context.Context, fill eventbus.OrderFilledEvent) error {
+	if fill.Venue == "" || fill.Symbol == "" || fill.Size <= 0 || fill.Price < 0 {
+		return ErrInvalidFill
+	}
+	size := fill.Size
+	switch fill.Side {
+	case "buy":
+	case "sell":
+		size = -size
+	default:
+		return ErrInvalidFill
+	}
+	at := fill.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	p.mu.Lock()
+	key := positionKey{venue: fill.Venue, symbol: fill.Symbol}
+	position, ok := p.positions[key]
+	if !ok {
+		position = &Position{Venue: fill.Venue, Symbol: fill.Symbol, Token: Token(fill.Symbol)}
+		p.positions[key] = position
+	}
+	position.Size = money.Add(position.Size, size)
+	position.UpdatedAt = at
+	if position.Size == 0 {
+		delete(p.positions, key)
+	}
+	if fill.Price > 0 {
+		p.marks[fill.Symbol] = fill.Price
+	}
+	p.updatedAt = at
+	exposure := p.exposure()
+	p.mu.Unlock()
+
+	p.notify(ctx, exposure)
+	return nil
+}
+
+// UpdateMarkPrice revalues the positions of a symbol on every venue
+func (p *Portfolio) UpdateMarkPrice(ctx context.Context, symbol string, price float64) error {
+	if price <= 0 {
+		return ErrInvalidPrice
+	}
+
+	p.mu.Lock()
+	p.marks[symbol] = price
+	if !p.holds(symbol) {
+		p.mu.Unlock()
+		return nil
+	}
+	p.updatedAt = time.Now()
+	exposure := p.exposure()
+	p.mu.Unlock()
+
+	p.notify(ctx, exposure)
+	return nil
+}
+
+// SetCollateral sets the collateral balance of a venue
+func (p *Portfolio) SetCollateral(ctx context.Context, venue string, balance float64) error {
+	if venue == "" {
+		return ErrInvalidVenue
+	}
+	if balance < 0 {
+		return ErrInvalidCollateral
+	}
+
+	p.mu.Lock()
+	p.collateral[venue] = balance
+	p.updatedAt = time.Now()
+	exposure := p.exposure()
+	p.mu.Unlock()
+
+	p.notify(ctx, exposure)
+	return nil
+}
+
+// Exposure returns the current exposure of the portfolio
+func (p *Portfolio) Exposure(ctx context.Context) *Exposure {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.exposure()
+}
+
+// PortfolioExposure returns the gross exposure and the collateral of the
+// portfolio, for the exposure limit of the risk manager
+func (p *Portfolio) PortfolioExposure(ctx context.Context) (float64, float64, error) {
+	exposure := p.Exposure(ctx)
+	return exposure.GrossExposure, exposure.Collateral, nil
+}
+
+// Subscribe applies the fills and market prices published on the bus. It
+// returns a function removing the subscriptions.
+func (p *Portfolio) Subscribe(bus *eventbus.Bus) func() {
+	unsubscribers := []func(){
+		eventbus.Subscribe(bus, eventbus.OrderFilled, func(ctx context.Context, e eventbus.OrderFilledEvent) error {
+			return p.ApplyFill(ctx, e)
+		}),
+		eventbus.Subscribe(bus, eventbus.MarketDataUpdated, func(ctx context.Context, e eventbus.MarketDataEvent) error {
+			return p.UpdateMarkPrice(ctx, e.Symbol, e.Price)
+		}),
+	}
+	return func() {
+		for _, unsubscribe := range unsubscribers {
+			unsubscribe()
+		}
+	}
+}
+
+// holds reports whether any venue has a position in a symbol. Callers hold
+// p.mu.
+func (p *Portfolio) holds(symbol string) bool {
+	for key := range p.positions {
+		if key.symbol == symbol {
+			return true
+		}
+	}
+	return false
+}
+
+// exposure computes the exposure from the positions. Callers hold p.mu.
+func (p *Portfolio) exposure() *Exposure {
+	exposure := &Exposure{
+		Tokens:    make(map[string]float64),
+		Positions: make([]Position, 0, len(p.positions)),
+		UpdatedAt: p.updatedAt,
+	}
+	for _, position := range p.positions {
+		valued := *position
+		valued.MarkPrice = p.marks[position.Symbol]
+		exposure.Positions = append(exposure.Positions, valued)
+		exposure.Tokens[position.Token] += valued.Notional()
+	}
+	sort.Slice(exposure.Positions, func(i, j int) bool {
+		a, b := exposure.Positions[i], exposure.Positions[j]
+		if a.Venue != b.Venue {
+			return a.Venue < b.Venue
+		}
+		return a.Symbol < b.Symbol
+	})
+	for _, notional := range exposure.Tokens {
+		exposure.NetExposure += notional
+		exposure.GrossExposure += math.Abs(notional)
+	}
+	for _, balance := range p.collateral {
+		exposure.Collateral += balance
+	}
+	if exposure.Collateral > 0 {
+		exposure.Leverage = exposure.GrossExposure / exposure.Collateral
+	}
+	return exposure
+}
+
+func (p *Portfolio) notify(ctx context.Context, exposure *Exposure) {
+	if p.listener != nil {
+		p.listener(ctx, exposure)
+	}
+}
@@ -0,0 +1,106 @@
+package portfolio
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/devinjacknz/godydxhyber/backend/eventbus"
+)
+
+func TestExposure(t *testing.T) {
+	ctx := context.Background()
+	var notified []*Exposure
+	p := New(WithListener(func(ctx context.Context, e *Exposure) {
+		notified = append(notified, e)
+	}))
+
+	// A dYdX short hedges part of a Solana DEX long of the same token
+	require.NoError(t, p.ApplyFill(ctx, eventbus.OrderFilledEvent{Venue: "solana", Symbol: "SOL/USDC", Side: "buy", Size: 10, Price: 100}))
+	require.NoError(t, p.ApplyFill(ctx, eventbus.OrderFilledEvent{Venue: "dydx", Symbol: "SOL-USD", Side: "sell", Size: 4, Price: 100}))
+	require.NoError(t, p.ApplyFill(ctx, eventbus.OrderFilledEvent{Venue: "dydx", Symbol: "BTC-USD", Side: "sell", Size: 0.1, Price: 50000}))
+	require.NoError(t, p.SetCollateral(ctx, "dydx", 2000))
+	require.NoError(t, p.SetCollateral(ctx, "solana", 3000))
+
+	exposure := p.Exposure(ctx)
+	require.Len(t, exposure.Positions, 3)
+	assert.Equal(t, "dydx", exposure.Positions[0].Venue)
+	assert.Equal(t, "BTC-USD", exposure.Positions[0].Symbol)
+	assert.InDelta(t, 600, exposure.Tokens["SOL"], 1e-9)
+	assert.InDelta(t, -5000, exposure.Tokens["BTC"], 1e-9)
+	assert.InDelta(t, -4400, exposure.NetExposure, 1e-9)
+	assert.InDelta(t, 5600, exposure.GrossExposure, 1e-9)
+	assert.Equal(t, 5000.0, exposure.Collateral)
+	assert.InDelta(t, 1.12, exposure.Leverage, 1e-9)
+	require.Len(t, notified, 5)
+
+	// Prices revalue the symbol on every venue holding it
+	require.NoError(t, p.UpdateMarkPrice(ctx, "SOL/USDC", 110))
+	assert.InDelta(t, 700, p.Exposure(ctx).Tokens["SOL"], 1e-9)
+	require.NoError(t, p.UpdateMarkPrice(ctx, "ETH-USD", 3000))
+	assert.Len(t, notified, 6, "prices of symbols not held change nothing")
+
+	// Closing a position removes it and fills without a price use the mark
+	require.NoError(t, p.ApplyFill(ctx, eventbus.OrderFilledEvent{Venue: "dydx", Symbol: "BTC-USD", Side: "buy", Size: 0.1}))
+	require.NoError(t, p.ApplyFill(ctx, eventbus.OrderFilledEvent{Venue: "dydx", Symbol: "BTC-USD", Side: "buy", Size: 0.2}))
+	exposure = p.Exposure(ctx)
+	assert.InDelta(t, 10000, exposure.Tokens["BTC"], 1e-9)
+	gross, collateral, err := p.PortfolioExposure(ctx)
+	require.NoError(t, err)
+	assert.InDelta(t, 10700, gross, 1e-9)
+	assert.Equal(t, 5000.0, collateral)
+
+	assert.ErrorIs(t, p.ApplyFill(ctx, eventbus.OrderFilledEvent{Symbol: "BTC-USD", Side: "buy", Size: 1}), ErrInvalidFill)
+	assert.ErrorIs(t, p.ApplyFill(ctx, eventbus.OrderFilledEvent{Venue: "dydx", Symbol: "BTC-USD", Side: "hold", Size: 1}), ErrInvalidFill)
+	assert.ErrorIs(t, p.UpdateMarkPrice(ctx, "BTC-USD", 0), ErrInvalidPrice)
+	assert.ErrorIs(t, p.SetCollateral(ctx, "", 1), ErrInvalidVenue)
+	assert.ErrorIs(t, p.SetCollateral(ctx, "dydx", -1), ErrInvalidCollateral)
+}
+
+func TestSubscribe(t *testing.T) {
+	ctx := context.Background()
+	bus := eventbus.New(eventbus.DefaultConfig())
+	p := New()
+	unsubscribe := p.Subscribe(bus)
+	defer unsubscribe()
+
+	now := time.Now()
+	require.NoError(t, eventbus.Publish(ctx, bus, eventbus.OrderFilled, eventbus.OrderFilledEvent{Venue: "dydx", Symbol: "ETH-USD", Side: "buy", Size: 2, Price: 3000, Timestamp: now}))
+	require.NoError(t, eventbus.Publish(ctx, bus, eventbus.MarketDataUpdated, eventbus.MarketDataEvent{Symbol: "ETH-USD", Price: 3100, Timestamp: now}))
+	bus.Close()
+
+	exposure := p.Exposure(ctx)
+	assert.InDelta(t, 6200, exposure.Tokens["ETH"], 1e-9)
+	assert.Equal(t, now, exposure.Positions[0].UpdatedAt)
+}
+
+func TestPortfolioHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	p := New()
+	r := gin.New()
+	RegisterRoutes(r.Group("/api/v1"), p)
+	call := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	w := call(http.MethodPut, "/api/v1/portfolio/collateral/dydx", `{"balance": 2500}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodPut, "/api/v1/portfolio/collateral/dydx", `{"balance": -1}`).Code)
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodPut, "/api/v1/portfolio/collateral/dydx", `{}`).Code)
+
+	w = call(http.MethodGet, "/api/v1/portfolio", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var exposure Exposure
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &exposure))
+	assert.Equal(t, 2500.0, exposure.Collateral)
+}
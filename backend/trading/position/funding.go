@@ -0,0 +1,134 @@
+package position
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/exchange/dydx"
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+	"github.com/devinjacknz/godydxhyber/backend/trading/analysis/monitoring"
+)
+
+// FundingRateClient retrieves funding rates from the exchange
+type FundingRateClient interface {
+	GetFundingRate(ctx context.Context, symbol string) (*dydx.FundingRate, error)
+}
+
+// FundingAccruer periodically applies exchange funding rates to open positions
+type FundingAccruer struct {
+	manager  *Manager
+	client   FundingRateClient
+	interval time.Duration
+	applied  map[string]time.Time
+	mu       sync.Mutex
+}
+
+// NewFundingAccruer creates a new funding accruer
+func NewFundingAccruer(manager *Manager, client FundingRateClient, interval time.Duration) *FundingAccruer {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	return &FundingAccruer{
+		manager:  manager,
+		client:   client,
+		interval: interval,
+		applied:  make(map[string]time.Time),
+	}
+}
+
+// Run accrues funding on every interval until the context is cancelled
+func (f *FundingAccruer) Run(ctx context.Context) error {
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			f.Accrue(ctx)
+		}
+	}
+}
+
+// Accrue fetches the current funding rate for every symbol with open
+// positions and applies it. A funding period is only applied once, so
+// polling more often than the exchange funding interval is safe. The rate's
+// time is the start of its funding period.
+func (f *FundingAccruer) Accrue(ctx context.Context) {
+	start := time.Now()
+	defer func() {
+		monitoring.RecordIndicatorCalculation("funding_accrual", time.Since(start))
+	}()
+
+	status := Open
+	positions, err := f.manager.ListPositions(ctx, PositionFilter{Status: &status})
+	if err != nil {
+		monitoring.RecordIndicatorError("funding_accrual", err.Error())
+		return
+	}
+
+	symbols := make(map[string]bool)
+	for _, pos := range positions {
+		symbols[pos.Symbol] = true
+	}
+
+	for symbol := range symbols {
+		rate, err := f.client.GetFundingRate(ctx, symbol)
+		if err != nil {
+			monitoring.RecordIndicatorError("funding_accrual", err.Error())
+			continue
+		}
+
+		f.mu.Lock()
+		last, seen := f.applied[symbol]
+		if seen && !rate.Time.After(last) {
+			f.mu.Unlock()
+			continue
+		}
+		f.applied[symbol] = rate.Time
+		f.mu.Unlock()
+
+		f.manager.ApplyFunding(ctx, symbol, rate.Rate, money.FromFloat(rate.Price), rate.Time)
+		monitoring.RecordIndicatorValue("funding_rate_"+symbol, rate.Rate)
+	}
+}
+
+// ApplyFunding applies the funding payment of the period starting at
+// periodStart to the open positions on a symbol opened by then; positions
+// opened during the period pay from the next one. A positive rate means
+// longs pay shorts. If markPrice is zero the position's current price is
+// used.
+func (m *Manager) ApplyFunding(ctx context.Context, symbol string, rate float64, markPrice money.Decimal, periodStart time.Time) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, position := range m.positions {
+		if position.Symbol != symbol {
+			continue
+		}
+
+		position.mu.Lock()
+		if position.Status == Open && !position.OpenTime.After(periodStart) {
+			price := markPrice
+			if price.Sign() <= 0 {
+				price = position.CurrentPrice
+			}
+
+			payment := position.Size.Mul(price).Mul(money.FromFloat(rate))
+			if position.Side == Long {
+				payment = payment.Neg()
+			}
+			err := m.update(ctx, position, func(next *Position) {
+				next.FundingAccrual = next.FundingAccrual.Add(payment)
+				next.LastUpdateTime = time.Now()
+			})
+			if err != nil {
+				monitoring.RecordIndicatorError("funding_accrual", err.Error())
+			}
+		}
+		position.mu.Unlock()
+	}
+}
@@ -0,0 +1,135 @@
+package position
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+)
+
+var positionSides = map[string]Side{
+	"long":  Long,
+	"short": Short,
+}
+
+var positionStatuses = map[string]PositionStatus{
+	"open":       Open,
+	"closed":     Closed,
+	"liquidated": Liquidated,
+}
+
+type closeRequest struct {
+	// Price is the close price. The last known price is used when omitted.
+	Price *money.Decimal `json:"price,omitempty"`
+}
+
+// Resolver selects the position manager a request acts on, such as a sandbox
+// position manager for sandbox API tokens
+type Resolver func(c *gin.Context) (*Manager, error)
+
+// RegisterRoutes registers the position HTTP endpoints
+func (m *Manager) RegisterRoutes(r gin.IRouter) {
+	RegisterRoutesWithResolver(r, func(c *gin.Context) (*Manager, error) {
+		return m, nil
+	})
+}
+
+// RegisterRoutesWithResolver registers the position HTTP endpoints, acting on
+// the position manager returned by resolve for each request
+func RegisterRoutesWithResolver(r gin.IRouter, resolve Resolver) {
+	r.GET("/positions", resolved(resolve, (*Manager).handleList))
+	r.GET("/positions/:id", resolved(resolve, (*Manager).handleGet))
+	r.POST("/positions/:id/close", resolved(resolve, (*Manager).handleClose))
+}
+
+func resolved(resolve Resolver, handler func(*Manager, *gin.Context)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		m, err := resolve(c)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		handler(m, c)
+	}
+}
+
+func (m *Manager) handleList(c *gin.Context) {
+	filter := PositionFilter{Symbol: c.Query("symbol")}
+	if v := c.Query("side"); v != "" {
+		side, ok := positionSides[v]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "side must be long or short"})
+			return
+		}
+		filter.Side = &side
+	}
+	if v := c.Query("status"); v != "" {
+		status, ok := positionStatuses[v]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status must be open, closed or liquidated"})
+			return
+		}
+		filter.Status = &status
+	}
+
+	positions, err := m.ListPositions(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp := make([]*Position, 0, len(positions))
+	for _, p := range positions {
+		resp = append(resp, p.Snapshot())
+	}
+	c.JSON(http.StatusOK, gin.H{"positions": resp})
+}
+
+func (m *Manager) handleGet(c *gin.Context) {
+	p, err := m.GetPosition(c.Request.Context(), c.Param("id"))
+	switch {
+	case errors.Is(err, ErrPositionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, p.Snapshot())
+	}
+}
+
+func (m *Manager) handleClose(c *gin.Context) {
+	var req closeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	p, err := m.GetPosition(ctx, c.Param("id"))
+	if err == nil {
+		price := p.Snapshot().CurrentPrice
+		if req.Price != nil {
+			price = *req.Price
+		}
+		if price.Sign() <= 0 {
+			err = ErrInvalidPrice
+		} else {
+			err = m.ClosePosition(ctx, p.ID, price)
+		}
+	}
+
+	switch {
+	case errors.Is(err, ErrPositionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidPrice):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrPositionAlreadyClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, p.Snapshot())
+	}
+}
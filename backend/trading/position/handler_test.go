@@ -0,0 +1,76 @@
+package position
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+)
+
+func TestPositionHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	manager := NewManager()
+
+	long, err := manager.OpenPosition(ctx, OpenPositionParams{Symbol: "BTC/USD", Side: Long, Size: money.NewFromInt(1), EntryPrice: money.NewFromInt(50000), Leverage: 1})
+	require.NoError(t, err)
+	short, err := manager.OpenPosition(ctx, OpenPositionParams{Symbol: "ETH/USD", Side: Short, Size: money.NewFromInt(2), EntryPrice: money.NewFromInt(3000), Leverage: 1})
+	require.NoError(t, err)
+
+	r := gin.New()
+	manager.RegisterRoutes(r.Group("/api/v1"))
+
+	call := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("List positions", func(t *testing.T) {
+		w := call(http.MethodGet, "/api/v1/positions?side=short&status=open", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Positions []*Position `json:"positions"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.Positions, 1)
+		assert.Equal(t, short.ID, resp.Positions[0].ID)
+
+		assert.Equal(t, http.StatusBadRequest, call(http.MethodGet, "/api/v1/positions?side=flat", "").Code)
+		assert.Equal(t, http.StatusNotFound, call(http.MethodGet, "/api/v1/positions/missing", "").Code)
+	})
+
+	t.Run("Close position", func(t *testing.T) {
+		w := call(http.MethodPost, "/api/v1/positions/"+long.ID+"/close", `{"price":51000}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		var closed Position
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &closed))
+		assert.Equal(t, Closed, closed.Status)
+		assert.Equal(t, 1000.0, closed.RealizedPnL.Float64())
+
+		assert.Equal(t, http.StatusConflict, call(http.MethodPost, "/api/v1/positions/"+long.ID+"/close", `{"price":51000}`).Code)
+		assert.Equal(t, http.StatusNotFound, call(http.MethodPost, "/api/v1/positions/missing/close", `{}`).Code)
+		assert.Equal(t, http.StatusBadRequest, call(http.MethodPost, "/api/v1/positions/"+short.ID+"/close", `{"price":-1}`).Code)
+	})
+
+	t.Run("Close at the last known price", func(t *testing.T) {
+		price := money.FromFloat(2900.0)
+		require.NoError(t, manager.UpdatePosition(ctx, short.ID, UpdatePositionParams{CurrentPrice: &price}))
+
+		w := call(http.MethodPost, "/api/v1/positions/"+short.ID+"/close", `{}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		var closed Position
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &closed))
+		assert.Equal(t, 200.0, closed.RealizedPnL.Float64())
+	})
+}
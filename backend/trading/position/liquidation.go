@@ -0,0 +1,393 @@
+package position
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+	"github.com/devinjacknz/godydxhyber/backend/trading/analysis/monitoring"
+)
+
+// LiquidationEventType is the audit event type of liquidation warnings and
+// deleverages
+const LiquidationEventType = "liquidation_risk"
+
+// volatilityDecay is the weight of the previous variance in the volatility
+// estimate, as in RiskMetrics
+const volatilityDecay = 0.94
+
+// LiquidationConfig contains liquidation monitor configuration
+type LiquidationConfig struct {
+	// MaintenanceMarginRate derives the liquidation price of positions the
+	// exchange reported none for
+	MaintenanceMarginRate float64
+	// WarningBuffer is the distance to the liquidation price, as a fraction
+	// of the price, within which a position raises a warning and may be
+	// deleveraged
+	WarningBuffer float64
+	// AutoDeleverage closes part of a position within the warning buffer
+	// once its breach probability reaches DeleverageProbability, instead of
+	// only emitting deleverage requests
+	AutoDeleverage        bool
+	DeleverageProbability float64
+	// DeleverageFraction is the fraction of the size closed per deleverage
+	DeleverageFraction float64
+	// DeleverageCooldown is the minimum time between two deleverages of a
+	// position, so the price can react to the first
+	DeleverageCooldown time.Duration
+	// Horizon is the time the breach probability is estimated over
+	Horizon time.Duration
+	// MinSamples is the number of price changes of a symbol required
+	// before its volatility, and so the breach probability, is trusted
+	MinSamples int
+	// RequestBuffer is the capacity of the deleverage request channel
+	RequestBuffer int
+}
+
+// DefaultLiquidationConfig returns default liquidation monitor configuration
+func DefaultLiquidationConfig() LiquidationConfig {
+	return LiquidationConfig{
+		MaintenanceMarginRate: 0.03,
+		WarningBuffer:         0.05,
+		DeleverageProbability: 0.5,
+		DeleverageFraction:    0.25,
+		DeleverageCooldown:    time.Minute,
+		Horizon:               15 * time.Minute,
+		MinSamples:            20,
+		RequestBuffer:         100,
+	}
+}
+
+// LiquidationRisk is how close an open position is to liquidation
+type LiquidationRisk struct {
+	PositionID       string
+	Symbol           string
+	Side             Side
+	Price            float64
+	LiquidationPrice float64
+	// Distance is how far the price is from the liquidation price, as a
+	// fraction of the price; zero or less once it is breached
+	Distance float64
+	// MarginRatio is the margin plus unrealized PnL over the notional
+	MarginRatio float64
+	// BreachProbability estimates the chance of the price reaching the
+	// liquidation price within the horizon. It is negative while the
+	// volatility of the symbol is unknown.
+	BreachProbability float64
+	Timestamp         time.Time
+}
+
+// DeleverageRequest is emitted when an open position is likely to be
+// liquidated
+type DeleverageRequest struct {
+	LiquidationRisk
+	// Size is the part of the position to close
+	Size float64
+	// Deleveraged reports whether the monitor already reduced the position
+	Deleveraged bool
+}
+
+// volatility is the exponentially weighted variance of the log returns of a
+// symbol, per second
+type volatility struct {
+	price    float64
+	at       time.Time
+	variance float64
+	samples  int
+}
+
+// liquidationState is the warning and deleverage state of a position
+type liquidationState struct {
+	symbol        string
+	warned        bool
+	deleveragedAt time.Time
+}
+
+// LiquidationMonitor watches the distance of open positions to their
+// liquidation price against a price feed. It warns when a position gets
+// within the warning buffer and deleverages positions likely to reach it.
+// Liquidation itself is left to the PriceWatcher.
+type LiquidationMonitor struct {
+	manager    *Manager
+	config     LiquidationConfig
+	requests   chan DeleverageRequest
+	volatility map[string]*volatility
+	states     map[string]*liquidationState
+	mu         sync.Mutex
+}
+
+// NewLiquidationMonitor creates a new liquidation monitor. Unset settings
+// take their default, except AutoDeleverage.
+func NewLiquidationMonitor(manager *Manager, config LiquidationConfig) *LiquidationMonitor {
+	defaults := DefaultLiquidationConfig()
+	if config.WarningBuffer <= 0 {
+		config.WarningBuffer = defaults.WarningBuffer
+	}
+	if config.DeleverageProbability <= 0 {
+		config.DeleverageProbability = defaults.DeleverageProbability
+	}
+	if config.DeleverageFraction <= 0 || config.DeleverageFraction > 1 {
+		config.DeleverageFraction = defaults.DeleverageFraction
+	}
+	if config.Horizon <= 0 {
+		config.Horizon = defaults.Horizon
+	}
+	if config.MinSamples <= 0 {
+		config.MinSamples = defaults.MinSamples
+	}
+	if config.RequestBuffer <= 0 {
+		config.RequestBuffer = defaults.RequestBuffer
+	}
+
+	return &LiquidationMonitor{
+		manager:    manager,
+		config:     config,
+		requests:   make(chan DeleverageRequest, config.RequestBuffer),
+		volatility: make(map[string]*volatility),
+		states:     make(map[string]*liquidationState),
+	}
+}
+
+// Requests returns the channel on which deleverage requests are emitted
+func (l *LiquidationMonitor) Requests() <-chan DeleverageRequest {
+	return l.requests
+}
+
+// Run consumes price updates until the context is cancelled or the feed is closed
+func (l *LiquidationMonitor) Run(ctx context.Context, prices <-chan PriceUpdate) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case update, ok := <-prices:
+			if !ok {
+				return nil
+			}
+			l.Evaluate(ctx, update)
+		}
+	}
+}
+
+// Evaluate applies a price update to the open positions on the symbol and
+// returns their liquidation risk
+func (l *LiquidationMonitor) Evaluate(ctx context.Context, update PriceUpdate) []LiquidationRisk {
+	start := time.Now()
+	defer func() {
+		monitoring.RecordIndicatorCalculation("liquidation_monitor", time.Since(start))
+	}()
+
+	if update.Price <= 0 {
+		monitoring.RecordIndicatorError("liquidation_monitor", ErrInvalidPrice.Error())
+		return nil
+	}
+	if update.Timestamp.IsZero() {
+		update.Timestamp = time.Now()
+	}
+	sigma, known := l.observe(update)
+
+	status := Open
+	positions, err := l.manager.ListPositions(ctx, PositionFilter{Symbol: update.Symbol, Status: &status})
+	if err != nil {
+		monitoring.RecordIndicatorError("liquidation_monitor", err.Error())
+		return nil
+	}
+
+	risks := make([]LiquidationRisk, 0, len(positions))
+	seen := make(map[string]bool, len(positions))
+	for _, pos := range positions {
+		risk, size, ok := l.assess(pos, update)
+		if !ok {
+			continue
+		}
+		seen[pos.ID] = true
+		risk.BreachProbability = breachProbability(risk, sigma, known, l.config.Horizon)
+		monitoring.RecordLiquidationRisk(risk.Symbol, risk.Side.String(), risk.MarginRatio, risk.Distance)
+		risks = append(risks, risk)
+
+		if reduce, ok := l.update(risk, size); ok {
+			l.deleverage(ctx, DeleverageRequest{LiquidationRisk: risk, Size: reduce})
+		}
+	}
+	l.forget(update.Symbol, seen)
+
+	return risks
+}
+
+// observe adds a price to the volatility of its symbol and returns the
+// volatility per square root second, and whether enough prices were seen
+func (l *LiquidationMonitor) observe(update PriceUpdate) (float64, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	v, ok := l.volatility[update.Symbol]
+	if !ok {
+		l.volatility[update.Symbol] = &volatility{price: update.Price, at: update.Timestamp}
+		return 0, false
+	}
+	if elapsed := update.Timestamp.Sub(v.at).Seconds(); elapsed > 0 {
+		r := math.Log(update.Price / v.price)
+		rate := r * r / elapsed
+		if v.samples == 0 {
+			v.variance = rate
+		} else {
+			v.variance = volatilityDecay*v.variance + (1-volatilityDecay)*rate
+		}
+		v.samples++
+		v.price = update.Price
+		v.at = update.Timestamp
+	}
+	return math.Sqrt(v.variance), v.samples >= l.config.MinSamples
+}
+
+// assess measures the liquidation risk of a position at the update price
+// and returns its size. Positions without a liquidation price are skipped.
+func (l *LiquidationMonitor) assess(pos *Position, update PriceUpdate) (LiquidationRisk, float64, bool) {
+	// The risk is an estimate, computed in floats
+	pos.mu.RLock()
+	side := pos.Side
+	entry := pos.EntryPrice.Float64()
+	size := pos.Size.Float64()
+	margin := pos.Margin.Float64()
+	liquidation := pos.LiquidationPrice.Float64()
+	if liquidation <= 0 {
+		liquidation = liquidationPrice(side, entry, pos.Leverage, l.config.MaintenanceMarginRate)
+	}
+	pos.mu.RUnlock()
+
+	if liquidation <= 0 || size <= 0 {
+		return LiquidationRisk{}, 0, false
+	}
+
+	price := update.Price
+	pnl := (price - entry) * size
+	distance := (price - liquidation) / price
+	if side == Short {
+		pnl = -pnl
+		distance = -distance
+	}
+
+	return LiquidationRisk{
+		PositionID:       pos.ID,
+		Symbol:           pos.Symbol,
+		Side:             side,
+		Price:            price,
+		LiquidationPrice: liquidation,
+		Distance:         distance,
+		MarginRatio:      (margin + pnl) / (size * price),
+		Timestamp:        update.Timestamp,
+	}, size, true
+}
+
+// update raises the warning of a position entering the warning buffer and
+// returns the size to deleverage, if any
+func (l *LiquidationMonitor) update(risk LiquidationRisk, size float64) (float64, bool) {
+	l.mu.Lock()
+	state, ok := l.states[risk.PositionID]
+	if !ok {
+		state = &liquidationState{symbol: risk.Symbol}
+		l.states[risk.PositionID] = state
+	}
+
+	if risk.Distance > l.config.WarningBuffer {
+		state.warned = false
+		l.mu.Unlock()
+		return 0, false
+	}
+	warn := !state.warned
+	state.warned = true
+
+	// A breached position is liquidated rather than deleveraged
+	deleverage := risk.Distance > 0 &&
+		risk.BreachProbability >= l.config.DeleverageProbability &&
+		(state.deleveragedAt.IsZero() || risk.Timestamp.Sub(state.deleveragedAt) >= l.config.DeleverageCooldown)
+	if deleverage {
+		state.deleveragedAt = risk.Timestamp
+	}
+	l.mu.Unlock()
+
+	if warn {
+		monitoring.RecordEvent(monitoring.Event{
+			Type:     LiquidationEventType,
+			Severity: monitoring.SeverityWarning,
+			Message:  "Position approaching liquidation",
+			Details:  riskDetails(risk),
+		})
+	}
+	return size * l.config.DeleverageFraction, deleverage
+}
+
+// deleverage reduces a position when auto-deleverage is enabled and emits
+// the request
+func (l *LiquidationMonitor) deleverage(ctx context.Context, req DeleverageRequest) {
+	severity := monitoring.SeverityWarning
+	message := "Position deleverage recommended"
+	if l.config.AutoDeleverage {
+		if err := l.manager.ReducePosition(ctx, req.PositionID, money.FromFloat(req.Size), money.FromFloat(req.Price)); err != nil {
+			monitoring.RecordIndicatorError("liquidation_monitor", err.Error())
+		} else {
+			req.Deleveraged = true
+			severity = monitoring.SeverityCritical
+			message = "Position deleveraged"
+		}
+	}
+
+	details := riskDetails(req.LiquidationRisk)
+	details["size"] = req.Size
+	details["deleveraged"] = req.Deleveraged
+	monitoring.RecordEvent(monitoring.Event{
+		Type:     LiquidationEventType,
+		Severity: severity,
+		Message:  message,
+		Details:  details,
+	})
+
+	select {
+	case l.requests <- req:
+	default:
+		monitoring.RecordIndicatorError("liquidation_monitor", "deleverage request channel full")
+	}
+}
+
+// forget drops the state of the positions of a symbol that are no longer open
+func (l *LiquidationMonitor) forget(symbol string, open map[string]bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for id, state := range l.states {
+		if state.symbol == symbol && !open[id] {
+			delete(l.states, id)
+		}
+	}
+}
+
+// breachProbability is the probability of a driftless random walk with the
+// volatility sigma per square root second reaching the liquidation price
+// within the horizon, by the reflection principle
+func breachProbability(risk LiquidationRisk, sigma float64, known bool, horizon time.Duration) float64 {
+	if risk.Distance <= 0 {
+		return 1
+	}
+	if !known {
+		return -1
+	}
+	spread := sigma * math.Sqrt(horizon.Seconds())
+	if spread == 0 {
+		return 0
+	}
+	d := math.Abs(math.Log(risk.LiquidationPrice / risk.Price))
+	return math.Erfc(d / (spread * math.Sqrt2))
+}
+
+func riskDetails(risk LiquidationRisk) map[string]interface{} {
+	return map[string]interface{}{
+		"position_id":        risk.PositionID,
+		"symbol":             risk.Symbol,
+		"side":               risk.Side.String(),
+		"price":              risk.Price,
+		"liquidation_price":  risk.LiquidationPrice,
+		"distance":           risk.Distance,
+		"margin_ratio":       risk.MarginRatio,
+		"breach_probability": risk.BreachProbability,
+	}
+}
@@ -0,0 +1,214 @@
+package position
+
+import (
+	"context"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+	"github.com/devinjacknz/godydxhyber/backend/trading/analysis/monitoring"
+)
+
+// Mode determines how opposite-side positions on the same symbol interact
+type Mode int
+
+const (
+	// HedgeMode keeps long and short positions on the same symbol independent
+	HedgeMode Mode = iota + 1
+	// NettingMode keeps a single position per symbol; opposite orders reduce it
+	NettingMode
+)
+
+// String returns the name of the mode
+func (m Mode) String() string {
+	if m == NettingMode {
+		return "netting"
+	}
+	return "hedge"
+}
+
+// WithMode sets the position mode
+func WithMode(mode Mode) ManagerOption {
+	return func(m *Manager) {
+		m.mode = mode
+	}
+}
+
+// Mode returns the position mode of the manager
+func (m *Manager) Mode() Mode {
+	return m.mode
+}
+
+// Exposure summarizes open position sizes on a symbol
+type Exposure struct {
+	Symbol string
+	Mode   Mode
+	Long   money.Decimal
+	Short  money.Decimal
+	// Net is Long minus Short
+	Net money.Decimal
+	// Gross is Long plus Short
+	Gross money.Decimal
+}
+
+// RiskPosition returns the position size that limit checks should use: the
+// signed net size in netting mode and the gross size in hedge mode
+func (e Exposure) RiskPosition() money.Decimal {
+	if e.Mode == NettingMode {
+		return e.Net
+	}
+	return e.Gross
+}
+
+// Exposure returns the open long, short, net and gross size on a symbol
+func (m *Manager) Exposure(ctx context.Context, symbol string) Exposure {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	exposure := Exposure{Symbol: symbol, Mode: m.mode}
+	for _, position := range m.positions {
+		if position.Symbol != symbol {
+			continue
+		}
+
+		position.mu.RLock()
+		if position.Status == Open {
+			if position.Side == Long {
+				exposure.Long = exposure.Long.Add(position.Size)
+			} else {
+				exposure.Short = exposure.Short.Add(position.Size)
+			}
+		}
+		position.mu.RUnlock()
+	}
+
+	exposure.Net = exposure.Long.Sub(exposure.Short)
+	exposure.Gross = exposure.Long.Add(exposure.Short)
+	return exposure
+}
+
+// openNetted applies an open request in netting mode. It nets the request
+// against the open position on the symbol and opens a position with whatever
+// is left, all under m.mu, so concurrent requests on a symbol without a
+// position cannot each open one.
+func (m *Manager) openNetted(ctx context.Context, params OpenPositionParams) (*Position, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	netted, remaining, err := m.netPosition(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if remaining.IsZero() {
+		return netted, nil
+	}
+
+	// The order flipped the position or there was none; open the remainder
+	// on the side of the order. The fee was charged to the netted position.
+	params.Size = remaining
+	if netted != nil {
+		params.Fee = money.Zero
+	}
+	position, err := m.newPosition(params)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.persist(ctx, position); err != nil {
+		return nil, err
+	}
+	m.positions[position.ID] = position
+
+	monitoring.RecordIndicatorValue("active_positions", float64(len(m.positions)))
+	return position, nil
+}
+
+// netPosition applies an open request to the existing position on the symbol.
+// Same-side requests increase the position; opposite-side requests reduce it.
+// It returns the affected position and any size left over after the existing
+// position was fully closed. Callers hold m.mu.
+func (m *Manager) netPosition(ctx context.Context, params OpenPositionParams) (*Position, money.Decimal, error) {
+	var existing *Position
+	for _, position := range m.positions {
+		position.mu.RLock()
+		open := position.Status == Open && position.Symbol == params.Symbol
+		position.mu.RUnlock()
+		if open {
+			existing = position
+			break
+		}
+	}
+	if existing == nil {
+		return nil, params.Size, nil
+	}
+
+	existing.mu.Lock()
+	defer existing.mu.Unlock()
+
+	// Same-side requests add margin, opposite-side ones release the margin
+	// of the size they reduce
+	var margin, reduce, remaining money.Decimal
+	var err error
+	if existing.Side == params.Side {
+		margin, err = calculateMargin(params.Size, params.EntryPrice, params.Leverage)
+		margin = existing.Margin.Add(margin)
+	} else {
+		reduce = params.Size
+		if reduce.Cmp(existing.Size) > 0 {
+			reduce = existing.Size
+		}
+		remaining = params.Size.Sub(reduce)
+		margin, err = reduceMargin(existing.Margin, existing.Size, reduce)
+	}
+	if err != nil {
+		return nil, money.Zero, err
+	}
+
+	err = m.update(ctx, existing, func(next *Position) {
+		next.Margin = margin
+		if next.Side == params.Side {
+			next.EntryPrice, next.Size = averageEntry(next.EntryPrice, next.Size, params.EntryPrice, params.Size)
+			if params.StopLoss != nil {
+				next.StopLoss = params.StopLoss
+			}
+			if params.TakeProfit != nil {
+				next.TakeProfit = params.TakeProfit
+			}
+		} else {
+			next.RealizedPnL = next.RealizedPnL.Add(tradePnL(next.Side, next.EntryPrice, params.EntryPrice, reduce))
+			next.Size = next.Size.Sub(reduce)
+
+			if next.Size.IsZero() {
+				next.Status = Closed
+				next.UnrealizedPnL = money.Zero
+			}
+		}
+
+		next.Fees = next.Fees.Add(params.Fee)
+		next.CurrentPrice = params.EntryPrice
+		if next.Status == Open {
+			next.UnrealizedPnL = calculateUnrealizedPnL(next)
+		}
+		next.LastUpdateTime = time.Now()
+	})
+	if err != nil {
+		return nil, money.Zero, err
+	}
+	if existing.Status == Closed {
+		monitoring.RecordIndicatorValue("realized_pnl", existing.RealizedPnL.Float64())
+	}
+
+	monitoring.RecordIndicatorValue("netted_positions", 1)
+	return existing, remaining, nil
+}
+
+// averageEntry returns the size-weighted entry price and the size of a
+// position of size at entry increased by add at price. The sizes are
+// positive, so their total is not zero.
+func averageEntry(entry, size, price, add money.Decimal) (money.Decimal, money.Decimal) {
+	total := size.Add(add)
+	notional := entry.Mul(size).Add(price.Mul(add))
+	average, err := notional.Div(total)
+	if err != nil {
+		return entry, total
+	}
+	return average, total
+}
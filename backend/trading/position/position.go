@@ -2,10 +2,13 @@ package position
 
 import (
 	"context"
+	"fmt"
+	"math"
 	"sync"
 	"time"
 
-"github.com/devinjacknz/godydxhyber/backend/trading/analysis/monitoring"
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+	"github.com/devinjacknz/godydxhyber/backend/trading/analysis/monitoring"
 )
 
 // Position represents a trading position
@@ -13,19 +16,28 @@ type Position struct {
 	ID             string
 	Symbol         string
 	Side           Side
-	EntryPrice     float64
-	CurrentPrice   float64
-	Size           float64
+	EntryPrice     money.Decimal
+	CurrentPrice   money.Decimal
+	Size           money.Decimal
 	OpenTime       time.Time
 	LastUpdateTime time.Time
-	StopLoss       *float64
-	TakeProfit     *float64
+	StopLoss       *money.Decimal
+	TakeProfit     *money.Decimal
 	Status         PositionStatus
-	UnrealizedPnL  float64
-	RealizedPnL    float64
-	Leverage       float64
-	Margin         float64
-	mu             sync.RWMutex
+	UnrealizedPnL  money.Decimal
+	RealizedPnL    money.Decimal
+	// FundingAccrual is the cumulative funding received (positive) or paid (negative)
+	FundingAccrual money.Decimal
+	// Fees is the cumulative trading fees paid on the position
+	Fees     money.Decimal
+	Leverage float64
+	Margin   money.Decimal
+	// LiquidationPrice is the liquidation price reported by the exchange,
+	// zero when it is derived from the leverage
+	LiquidationPrice money.Decimal
+	// AccountID is the trading account holding the position
+	AccountID string
+	mu        sync.RWMutex
 }
 
 // Side represents the position side (long or short)
@@ -36,6 +48,16 @@ const (
 	Short
 )
 
+// String returns the name of the side
+func (s Side) String() string {
+	for name, side := range positionSides {
+		if side == s {
+			return name
+		}
+	}
+	return "unknown"
+}
+
 // PositionStatus represents the status of a position
 type PositionStatus int
 
@@ -45,17 +67,37 @@ const (
 	Liquidated
 )
 
+// String returns the name of the status
+func (s PositionStatus) String() string {
+	for name, status := range positionStatuses {
+		if status == s {
+			return name
+		}
+	}
+	return "unknown"
+}
+
 // Manager manages trading positions
 type Manager struct {
 	positions map[string]*Position
+	accountID string
+	mode      Mode
+	store     Store
+	exchange  ExchangePositionSource
+	listener  Listener
 	mu        sync.RWMutex
 }
 
 // NewManager creates a new position manager
-func NewManager() *Manager {
-	return &Manager{
+func NewManager(opts ...ManagerOption) *Manager {
+	m := &Manager{
 		positions: make(map[string]*Position),
+		mode:      HedgeMode,
+	}
+	for _, opt := range opts {
+		opt(m)
 	}
+	return m
 }
 
 // OpenPosition opens a new position
@@ -71,8 +113,36 @@ func (m *Manager) OpenPosition(ctx context.Context, params OpenPositionParams) (
 		return nil, err
 	}
 
-	position := &Position{
+	if m.mode == NettingMode {
+		return m.openNetted(ctx, params)
+	}
+
+	position, err := m.newPosition(params)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.persist(ctx, position); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.positions[position.ID] = position
+	active := len(m.positions)
+	m.mu.Unlock()
+
+	monitoring.RecordIndicatorValue("active_positions", float64(active))
+	return position, nil
+}
+
+// newPosition builds an open position from params
+func (m *Manager) newPosition(params OpenPositionParams) (*Position, error) {
+	margin, err := calculateMargin(params.Size, params.EntryPrice, params.Leverage)
+	if err != nil {
+		return nil, err
+	}
+	return &Position{
 		ID:             generatePositionID(),
+		AccountID:      m.accountID,
 		Symbol:         params.Symbol,
 		Side:           params.Side,
 		EntryPrice:     params.EntryPrice,
@@ -83,20 +153,23 @@ func (m *Manager) OpenPosition(ctx context.Context, params OpenPositionParams) (
 		StopLoss:       params.StopLoss,
 		TakeProfit:     params.TakeProfit,
 		Status:         Open,
+		Fees:           params.Fee,
 		Leverage:       params.Leverage,
-		Margin:         calculateMargin(params.Size, params.EntryPrice, params.Leverage),
-	}
+		Margin:         margin,
+	}, nil
+}
 
-	m.mu.Lock()
-	m.positions[position.ID] = position
-	m.mu.Unlock()
+// ClosePosition closes an existing position
+func (m *Manager) ClosePosition(ctx context.Context, id string, closePrice money.Decimal) error {
+	return m.closePosition(ctx, id, closePrice, Closed)
+}
 
-	monitoring.RecordIndicatorValue("active_positions", float64(len(m.positions)))
-	return position, nil
+// LiquidatePosition marks an existing position as liquidated at the given price
+func (m *Manager) LiquidatePosition(ctx context.Context, id string, liquidationPrice money.Decimal) error {
+	return m.closePosition(ctx, id, liquidationPrice, Liquidated)
 }
 
-// ClosePosition closes an existing position
-func (m *Manager) ClosePosition(ctx context.Context, id string, closePrice float64) error {
+func (m *Manager) closePosition(ctx context.Context, id string, closePrice money.Decimal, status PositionStatus) error {
 	start := time.Now()
 	defer func() {
 		duration := time.Since(start)
@@ -118,17 +191,91 @@ func (m *Manager) ClosePosition(ctx context.Context, id string, closePrice float
 		return ErrPositionAlreadyClosed
 	}
 
-	position.Status = Closed
-	position.CurrentPrice = closePrice
-	position.LastUpdateTime = time.Now()
-	position.RealizedPnL = calculateRealizedPnL(position, closePrice)
+	err := m.update(ctx, position, func(next *Position) {
+		next.Status = status
+		next.CurrentPrice = closePrice
+		next.LastUpdateTime = time.Now()
+		// Adds to the PnL realized by earlier reductions
+		next.RealizedPnL = next.RealizedPnL.Add(calculateRealizedPnL(next, closePrice))
+	})
+	if err != nil {
+		return err
+	}
 
 	monitoring.RecordIndicatorValue("active_positions", float64(len(m.positions)-1))
-	monitoring.RecordIndicatorValue("realized_pnl", position.RealizedPnL)
+	monitoring.RecordIndicatorValue("realized_pnl", position.RealizedPnL.Float64())
 
 	return nil
 }
 
+// ReducePosition closes part of an open position at the given price,
+// realizing its PnL and releasing its margin. Reducing by the whole size
+// closes the position.
+func (m *Manager) ReducePosition(ctx context.Context, id string, size, price money.Decimal) error {
+	if size.Sign() <= 0 {
+		return ErrInvalidSize
+	}
+	if price.Sign() <= 0 {
+		return ErrInvalidPrice
+	}
+
+	m.mu.RLock()
+	position, exists := m.positions[id]
+	m.mu.RUnlock()
+	if !exists {
+		return ErrPositionNotFound
+	}
+
+	position.mu.Lock()
+	if position.Status != Open {
+		position.mu.Unlock()
+		return ErrPositionAlreadyClosed
+	}
+	if size.Cmp(position.Size) >= 0 {
+		position.mu.Unlock()
+		return m.ClosePosition(ctx, id, price)
+	}
+	defer position.mu.Unlock()
+
+	margin, err := reduceMargin(position.Margin, position.Size, size)
+	if err != nil {
+		return err
+	}
+	return m.update(ctx, position, func(next *Position) {
+		next.RealizedPnL = next.RealizedPnL.Add(tradePnL(next.Side, next.EntryPrice, price, size))
+		next.Margin, next.Size = margin, next.Size.Sub(size)
+		next.CurrentPrice = price
+		next.UnrealizedPnL = calculateUnrealizedPnL(next)
+		next.LastUpdateTime = time.Now()
+	})
+}
+
+// UpdateLiquidationPrice sets the liquidation price the exchange reports for
+// a position. Zero reverts to the price derived from the leverage.
+func (m *Manager) UpdateLiquidationPrice(ctx context.Context, id string, price money.Decimal) error {
+	if price.Sign() < 0 {
+		return ErrInvalidPrice
+	}
+
+	m.mu.RLock()
+	position, exists := m.positions[id]
+	m.mu.RUnlock()
+	if !exists {
+		return ErrPositionNotFound
+	}
+
+	position.mu.Lock()
+	defer position.mu.Unlock()
+
+	if position.Status != Open {
+		return ErrPositionAlreadyClosed
+	}
+	return m.update(ctx, position, func(next *Position) {
+		next.LiquidationPrice = price
+		next.LastUpdateTime = time.Now()
+	})
+}
+
 // UpdatePosition updates position details
 func (m *Manager) UpdatePosition(ctx context.Context, id string, params UpdatePositionParams) error {
 	start := time.Now()
@@ -152,22 +299,27 @@ func (m *Manager) UpdatePosition(ctx context.Context, id string, params UpdatePo
 		return ErrPositionAlreadyClosed
 	}
 
-	if params.CurrentPrice != nil {
-		position.CurrentPrice = *params.CurrentPrice
-		position.UnrealizedPnL = calculateUnrealizedPnL(position)
-	}
+	err := m.update(ctx, position, func(next *Position) {
+		if params.CurrentPrice != nil {
+			next.CurrentPrice = *params.CurrentPrice
+			next.UnrealizedPnL = calculateUnrealizedPnL(next)
+		}
 
-	if params.StopLoss != nil {
-		position.StopLoss = params.StopLoss
-	}
+		if params.StopLoss != nil {
+			next.StopLoss = params.StopLoss
+		}
 
-	if params.TakeProfit != nil {
-		position.TakeProfit = params.TakeProfit
-	}
+		if params.TakeProfit != nil {
+			next.TakeProfit = params.TakeProfit
+		}
 
-	position.LastUpdateTime = time.Now()
+		next.LastUpdateTime = time.Now()
+	})
+	if err != nil {
+		return err
+	}
 
-	monitoring.RecordIndicatorValue("unrealized_pnl", position.UnrealizedPnL)
+	monitoring.RecordIndicatorValue("unrealized_pnl", position.UnrealizedPnL.Float64())
 	return nil
 }
 
@@ -199,22 +351,124 @@ func (m *Manager) ListPositions(ctx context.Context, filter PositionFilter) ([]*
 	return positions, nil
 }
 
+// RecordFee adds a trading fee to a position
+func (m *Manager) RecordFee(ctx context.Context, id string, fee money.Decimal) error {
+	m.mu.RLock()
+	position, exists := m.positions[id]
+	m.mu.RUnlock()
+
+	if !exists {
+		return ErrPositionNotFound
+	}
+
+	position.mu.Lock()
+	defer position.mu.Unlock()
+
+	return m.update(ctx, position, func(next *Position) {
+		next.Fees = next.Fees.Add(fee)
+		next.LastUpdateTime = time.Now()
+	})
+}
+
+// NetPnL returns realized and unrealized PnL adjusted for funding and fees
+func (p *Position) NetPnL() money.Decimal {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	pnl := p.RealizedPnL
+	if p.Status == Open {
+		pnl = p.UnrealizedPnL
+	}
+	return pnl.Add(p.FundingAccrual).Sub(p.Fees)
+}
+
+// ROI returns the net return on margin as a percentage, zero without margin
+func (p *Position) ROI() float64 {
+	p.mu.RLock()
+	margin := p.Margin
+	p.mu.RUnlock()
+
+	roi, err := p.NetPnL().Div(margin)
+	if err != nil {
+		return 0
+	}
+	return roi.Mul(money.NewFromInt(100)).Float64()
+}
+
+// Snapshot returns a copy of the position that does not share state with it
+func (p *Position) Snapshot() *Position {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.snapshot()
+}
+
+// snapshot copies the position. Callers hold p.mu.
+func (p *Position) snapshot() *Position {
+	c := &Position{}
+	c.set(p)
+	return c
+}
+
+// set copies the fields of from into the position. Callers hold the locks
+// of both positions, or own them.
+func (p *Position) set(from *Position) {
+	p.ID = from.ID
+	p.AccountID = from.AccountID
+	p.Symbol = from.Symbol
+	p.Side = from.Side
+	p.OpenTime = from.OpenTime
+	p.Leverage = from.Leverage
+	p.apply(from)
+}
+
+// apply copies the fields that change after a position is opened from from
+// into the position. The ID, account, symbol, side, open time and leverage
+// are left alone, so they may be read without the position's lock. Callers
+// hold the position's lock.
+func (p *Position) apply(from *Position) {
+	p.EntryPrice = from.EntryPrice
+	p.CurrentPrice = from.CurrentPrice
+	p.Size = from.Size
+	p.LastUpdateTime = from.LastUpdateTime
+	p.StopLoss = copyDecimal(from.StopLoss)
+	p.TakeProfit = copyDecimal(from.TakeProfit)
+	p.Status = from.Status
+	p.UnrealizedPnL = from.UnrealizedPnL
+	p.RealizedPnL = from.RealizedPnL
+	p.FundingAccrual = from.FundingAccrual
+	p.Fees = from.Fees
+	p.Margin = from.Margin
+
+	p.LiquidationPrice = from.LiquidationPrice
+}
+
+func copyDecimal(v *money.Decimal) *money.Decimal {
+	if v == nil {
+		return nil
+	}
+	c := *v
+	return &c
+}
+
 // OpenPositionParams contains parameters for opening a position
 type OpenPositionParams struct {
 	Symbol     string
 	Side       Side
-	Size       float64
-	EntryPrice float64
-	StopLoss   *float64
-	TakeProfit *float64
+	Size       money.Decimal
+	EntryPrice money.Decimal
+	StopLoss   *money.Decimal
+	TakeProfit *money.Decimal
 	Leverage   float64
+	// Fee is the trading fee paid to open the position
+	Fee money.Decimal
 }
 
 // UpdatePositionParams contains parameters for updating a position
 type UpdatePositionParams struct {
-	CurrentPrice *float64
-	StopLoss     *float64
-	TakeProfit   *float64
+	CurrentPrice *money.Decimal
+	StopLoss     *money.Decimal
+	TakeProfit   *money.Decimal
 }
 
 // PositionFilter contains filters for listing positions
@@ -222,26 +476,51 @@ type PositionFilter struct {
 	Symbol  string
 	Side    *Side
 	Status  *PositionStatus
-	MinSize *float64
-	MaxSize *float64
+	MinSize *money.Decimal
+	MaxSize *money.Decimal
+}
+
+func calculateUnrealizedPnL(position *Position) money.Decimal {
+	return tradePnL(position.Side, position.EntryPrice, position.CurrentPrice, position.Size)
+}
+
+func calculateRealizedPnL(position *Position, closePrice money.Decimal) money.Decimal {
+	return tradePnL(position.Side, position.EntryPrice, closePrice, position.Size)
+}
+
+// tradePnL returns the profit or loss of size traded on a side from entry to
+// exit
+func tradePnL(side Side, entry, exit, size money.Decimal) money.Decimal {
+	pnl := exit.Sub(entry).Mul(size)
+	if side == Short {
+		return pnl.Neg()
+	}
+	return pnl
 }
 
-func calculateUnrealizedPnL(position *Position) float64 {
-	if position.Side == Long {
-		return (position.CurrentPrice - position.EntryPrice) * position.Size
+// calculateMargin returns the margin of size at price. A leverage that is
+// not a positive number is invalid.
+func calculateMargin(size, price money.Decimal, leverage float64) (money.Decimal, error) {
+	if !validLeverage(leverage) {
+		return money.Zero, ErrInvalidLeverage
 	}
-	return (position.EntryPrice - position.CurrentPrice) * position.Size
+	return size.Mul(price).Div(money.FromFloat(leverage))
 }
 
-func calculateRealizedPnL(position *Position, closePrice float64) float64 {
-	if position.Side == Long {
-		return (closePrice - position.EntryPrice) * position.Size
+// reduceMargin returns the margin left on a position of size after reducing
+// it by reduce. The margin of a position without size cannot be reduced.
+func reduceMargin(margin, size, reduce money.Decimal) (money.Decimal, error) {
+	left, err := margin.Mul(size.Sub(reduce)).Div(size)
+	if err != nil {
+		return money.Zero, fmt.Errorf("%w: cannot reduce a position without size", ErrInvalidSize)
 	}
-	return (position.EntryPrice - closePrice) * position.Size
+	return left, nil
 }
 
-func calculateMargin(size, price, leverage float64) float64 {
-	return (size * price) / leverage
+// validLeverage reports whether leverage is a finite positive number. NaN is
+// not.
+func validLeverage(leverage float64) bool {
+	return leverage > 0 && !math.IsInf(leverage, 0)
 }
 
 func matchesFilter(position *Position, filter PositionFilter) bool {
@@ -254,10 +533,10 @@ func matchesFilter(position *Position, filter PositionFilter) bool {
 	if filter.Status != nil && position.Status != *filter.Status {
 		return false
 	}
-	if filter.MinSize != nil && position.Size < *filter.MinSize {
+	if filter.MinSize != nil && position.Size.Cmp(*filter.MinSize) < 0 {
 		return false
 	}
-	if filter.MaxSize != nil && position.Size > *filter.MaxSize {
+	if filter.MaxSize != nil && position.Size.Cmp(*filter.MaxSize) > 0 {
 		return false
 	}
 	return true
@@ -267,19 +546,19 @@ func validateOpenParams(params OpenPositionParams) error {
 	if params.Symbol == "" {
 		return ErrInvalidSymbol
 	}
-	if params.Size <= 0 {
+	if params.Size.Sign() <= 0 {
 		return ErrInvalidSize
 	}
-	if params.EntryPrice <= 0 {
+	if params.EntryPrice.Sign() <= 0 {
 		return ErrInvalidPrice
 	}
-	if params.Leverage <= 0 {
+	if !validLeverage(params.Leverage) {
 		return ErrInvalidLeverage
 	}
-	if params.StopLoss != nil && *params.StopLoss <= 0 {
+	if params.StopLoss != nil && params.StopLoss.Sign() <= 0 {
 		return ErrInvalidStopLoss
 	}
-	if params.TakeProfit != nil && *params.TakeProfit <= 0 {
+	if params.TakeProfit != nil && params.TakeProfit.Sign() <= 0 {
 		return ErrInvalidTakeProfit
 	}
 	return nil
@@ -2,8 +2,15 @@ package position
 
 import (
 	"context"
+	"errors"
+	"math"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/devinjacknz/godydxhyber/backend/exchange/dydx"
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+	"github.com/devinjacknz/godydxhyber/backend/trading/analysis/monitoring"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -15,8 +22,8 @@ func TestPositionManager(t *testing.T) {
 		params := OpenPositionParams{
 			Symbol:     "BTC/USD",
 			Side:       Long,
-			Size:       1.0,
-			EntryPrice: 50000.0,
+			Size:       money.FromFloat(1.0),
+			EntryPrice: money.FromFloat(50000.0),
 			Leverage:   10.0,
 		}
 
@@ -32,10 +39,10 @@ func TestPositionManager(t *testing.T) {
 
 	t.Run("Open position with invalid parameters", func(t *testing.T) {
 		invalidParams := []OpenPositionParams{
-			{Symbol: "", Side: Long, Size: 1.0, EntryPrice: 50000.0, Leverage: 10.0},
-			{Symbol: "BTC/USD", Side: Long, Size: 0, EntryPrice: 50000.0, Leverage: 10.0},
-			{Symbol: "BTC/USD", Side: Long, Size: 1.0, EntryPrice: 0, Leverage: 10.0},
-			{Symbol: "BTC/USD", Side: Long, Size: 1.0, EntryPrice: 50000.0, Leverage: 0},
+			{Symbol: "", Side: Long, Size: money.FromFloat(1.0), EntryPrice: money.FromFloat(50000.0), Leverage: 10.0},
+			{Symbol: "BTC/USD", Side: Long, Size: money.NewFromInt(0), EntryPrice: money.FromFloat(50000.0), Leverage: 10.0},
+			{Symbol: "BTC/USD", Side: Long, Size: money.FromFloat(1.0), EntryPrice: money.NewFromInt(0), Leverage: 10.0},
+			{Symbol: "BTC/USD", Side: Long, Size: money.FromFloat(1.0), EntryPrice: money.FromFloat(50000.0), Leverage: 0},
 		}
 
 		for _, params := range invalidParams {
@@ -50,8 +57,8 @@ func TestPositionManager(t *testing.T) {
 		openParams := OpenPositionParams{
 			Symbol:     "ETH/USD",
 			Side:       Long,
-			Size:       2.0,
-			EntryPrice: 3000.0,
+			Size:       money.FromFloat(2.0),
+			EntryPrice: money.FromFloat(3000.0),
 			Leverage:   5.0,
 		}
 
@@ -59,7 +66,7 @@ func TestPositionManager(t *testing.T) {
 		assert.NoError(t, err)
 
 		// Update position
-		newPrice := 3500.0
+		newPrice := money.FromFloat(3500.0)
 		updateParams := UpdatePositionParams{
 			CurrentPrice: &newPrice,
 		}
@@ -71,7 +78,7 @@ func TestPositionManager(t *testing.T) {
 		updatedPosition, err := manager.GetPosition(ctx, position.ID)
 		assert.NoError(t, err)
 		assert.Equal(t, newPrice, updatedPosition.CurrentPrice)
-		assert.True(t, updatedPosition.UnrealizedPnL > 0) // Price increased for long position
+		assert.True(t, updatedPosition.UnrealizedPnL.Sign() > 0) // Price increased for long position
 	})
 
 	t.Run("Close position", func(t *testing.T) {
@@ -79,8 +86,8 @@ func TestPositionManager(t *testing.T) {
 		openParams := OpenPositionParams{
 			Symbol:     "SOL/USD",
 			Side:       Short,
-			Size:       10.0,
-			EntryPrice: 100.0,
+			Size:       money.FromFloat(10.0),
+			EntryPrice: money.FromFloat(100.0),
 			Leverage:   2.0,
 		}
 
@@ -89,28 +96,28 @@ func TestPositionManager(t *testing.T) {
 
 		// Close position
 		closePrice := 90.0
-		err = manager.ClosePosition(ctx, position.ID, closePrice)
+		err = manager.ClosePosition(ctx, position.ID, money.FromFloat(closePrice))
 		assert.NoError(t, err)
 
 		// Verify closure
 		closedPosition, err := manager.GetPosition(ctx, position.ID)
 		assert.NoError(t, err)
 		assert.Equal(t, Closed, closedPosition.Status)
-		assert.True(t, closedPosition.RealizedPnL > 0) // Price decreased for short position
+		assert.True(t, closedPosition.RealizedPnL.Sign() > 0) // Price decreased for short position
 	})
 
 	t.Run("List positions", func(t *testing.T) {
 		// Open multiple positions
 		symbols := []string{"BTC/USD", "ETH/USD", "SOL/USD"}
 		sides := []Side{Long, Short, Long}
-		sizes := []float64{1.0, 2.0, 3.0}
+		sizes := []money.Decimal{money.FromFloat(1.0), money.FromFloat(2.0), money.FromFloat(3.0)}
 
 		for i := range symbols {
 			params := OpenPositionParams{
 				Symbol:     symbols[i],
 				Side:       sides[i],
-				Size:       sizes[i],
-				EntryPrice: 1000.0,
+				Size:       money.FromFloat(sizes[i].Float64()),
+				EntryPrice: money.FromFloat(1000.0),
 				Leverage:   10.0,
 			}
 			_, err := manager.OpenPosition(ctx, params)
@@ -165,37 +172,37 @@ func TestPnLCalculations(t *testing.T) {
 	t.Run("Long position PnL", func(t *testing.T) {
 		position := &Position{
 			Side:       Long,
-			EntryPrice: 100.0,
-			Size:       1.0,
+			EntryPrice: money.FromFloat(100.0),
+			Size:       money.FromFloat(1.0),
 		}
 
 		// Profit scenario
-		position.CurrentPrice = 120.0
+		position.CurrentPrice = money.FromFloat(120.0)
 		profit := calculateUnrealizedPnL(position)
-		assert.Equal(t, 20.0, profit)
+		assert.Equal(t, 20.0, profit.Float64())
 
 		// Loss scenario
-		position.CurrentPrice = 80.0
+		position.CurrentPrice = money.FromFloat(80.0)
 		loss := calculateUnrealizedPnL(position)
-		assert.Equal(t, -20.0, loss)
+		assert.Equal(t, -20.0, loss.Float64())
 	})
 
 	t.Run("Short position PnL", func(t *testing.T) {
 		position := &Position{
 			Side:       Short,
-			EntryPrice: 100.0,
-			Size:       1.0,
+			EntryPrice: money.FromFloat(100.0),
+			Size:       money.FromFloat(1.0),
 		}
 
 		// Profit scenario
-		position.CurrentPrice = 80.0
+		position.CurrentPrice = money.FromFloat(80.0)
 		profit := calculateUnrealizedPnL(position)
-		assert.Equal(t, 20.0, profit)
+		assert.Equal(t, 20.0, profit.Float64())
 
 		// Loss scenario
-		position.CurrentPrice = 120.0
+		position.CurrentPrice = money.FromFloat(120.0)
 		loss := calculateUnrealizedPnL(position)
-		assert.Equal(t, -20.0, loss)
+		assert.Equal(t, -20.0, loss.Float64())
 	})
 }
 
@@ -232,10 +239,29 @@ func TestMarginCalculations(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			margin := calculateMargin(tt.size, tt.price, tt.leverage)
-			assert.Equal(t, tt.expected, margin)
+			margin, err := calculateMargin(money.FromFloat(tt.size), money.FromFloat(tt.price), tt.leverage)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, margin.Float64())
 		})
 	}
+
+	t.Run("Invalid leverage", func(t *testing.T) {
+		for _, leverage := range []float64{0, -5, math.NaN(), math.Inf(1)} {
+			_, err := calculateMargin(money.NewFromInt(1), money.NewFromInt(100), leverage)
+			assert.ErrorIs(t, err, ErrInvalidLeverage, "leverage %v", leverage)
+		}
+
+		manager := NewManager()
+		_, err := manager.OpenPosition(context.Background(), OpenPositionParams{
+			Symbol: "BTC-USD", Side: Long, Size: money.NewFromInt(1), EntryPrice: money.NewFromInt(50000), Leverage: math.NaN(),
+		})
+		assert.ErrorIs(t, err, ErrInvalidLeverage)
+	})
+
+	t.Run("Reducing a position without size", func(t *testing.T) {
+		_, err := reduceMargin(money.NewFromInt(10), money.Zero, money.NewFromInt(1))
+		assert.ErrorIs(t, err, ErrInvalidSize)
+	})
 }
 
 func TestConcurrency(t *testing.T) {
@@ -251,8 +277,8 @@ func TestConcurrency(t *testing.T) {
 				params := OpenPositionParams{
 					Symbol:     "BTC/USD",
 					Side:       Long,
-					Size:       1.0,
-					EntryPrice: 50000.0,
+					Size:       money.FromFloat(1.0),
+					EntryPrice: money.FromFloat(50000.0),
 					Leverage:   10.0,
 				}
 				_, err := manager.OpenPosition(ctx, params)
@@ -275,8 +301,8 @@ func TestConcurrency(t *testing.T) {
 		position, err := manager.OpenPosition(ctx, OpenPositionParams{
 			Symbol:     "ETH/USD",
 			Side:       Long,
-			Size:       1.0,
-			EntryPrice: 3000.0,
+			Size:       money.FromFloat(1.0),
+			EntryPrice: money.FromFloat(3000.0),
 			Leverage:   10.0,
 		})
 		assert.NoError(t, err)
@@ -284,7 +310,7 @@ func TestConcurrency(t *testing.T) {
 		done := make(chan bool)
 		for i := 0; i < numOperations; i++ {
 			go func(idx int) {
-				price := float64(3000 + idx)
+				price := money.NewFromInt(int64(3000 + idx))
 				err := manager.UpdatePosition(ctx, position.ID, UpdatePositionParams{
 					CurrentPrice: &price,
 				})
@@ -298,3 +324,553 @@ func TestConcurrency(t *testing.T) {
 		}
 	})
 }
+
+func TestPriceWatcher(t *testing.T) {
+	ctx := context.Background()
+
+	open := func(t *testing.T, manager *Manager, side Side, stopLoss, takeProfit *money.Decimal) *Position {
+		pos, err := manager.OpenPosition(ctx, OpenPositionParams{
+			Symbol:     "BTC/USD",
+			Side:       side,
+			Size:       money.FromFloat(1.0),
+			EntryPrice: money.FromFloat(50000.0),
+			StopLoss:   stopLoss,
+			TakeProfit: takeProfit,
+			Leverage:   2.0,
+		})
+		assert.NoError(t, err)
+		return pos
+	}
+	tick := func(price float64) PriceUpdate {
+		return PriceUpdate{Symbol: "BTC/USD", Price: price}
+	}
+
+	t.Run("Stop loss requires confirmation", func(t *testing.T) {
+		manager := NewManager()
+		stopLoss := money.FromFloat(48000.0)
+		pos := open(t, manager, Long, &stopLoss, nil)
+		watcher := NewPriceWatcher(manager, DefaultWatcherConfig())
+
+		assert.Empty(t, watcher.Evaluate(ctx, tick(47900.0)))
+		assert.Equal(t, Open, pos.Status)
+
+		requests := watcher.Evaluate(ctx, tick(47900.0))
+		assert.Len(t, requests, 1)
+		assert.Equal(t, TriggerStopLoss, requests[0].Reason)
+		assert.True(t, requests[0].Closed)
+		assert.Equal(t, Closed, pos.Status)
+		assert.Equal(t, -2100.0, pos.RealizedPnL.Float64())
+
+		req := <-watcher.Requests()
+		assert.Equal(t, pos.ID, req.PositionID)
+	})
+
+	t.Run("Wick inside buffer does not trigger", func(t *testing.T) {
+		manager := NewManager()
+		stopLoss := money.FromFloat(48000.0)
+		pos := open(t, manager, Long, &stopLoss, nil)
+		watcher := NewPriceWatcher(manager, DefaultWatcherConfig())
+
+		assert.Empty(t, watcher.Evaluate(ctx, tick(47990.0)))
+		assert.Empty(t, watcher.Evaluate(ctx, tick(47990.0)))
+
+		// A single breaching tick followed by a recovery resets the count
+		assert.Empty(t, watcher.Evaluate(ctx, tick(47000.0)))
+		assert.Empty(t, watcher.Evaluate(ctx, tick(49000.0)))
+		assert.Empty(t, watcher.Evaluate(ctx, tick(47000.0)))
+		assert.Equal(t, Open, pos.Status)
+	})
+
+	t.Run("Take profit on short position", func(t *testing.T) {
+		manager := NewManager()
+		takeProfit := money.FromFloat(45000.0)
+		pos := open(t, manager, Short, nil, &takeProfit)
+		watcher := NewPriceWatcher(manager, WatcherConfig{AutoClose: true, ConfirmTicks: 1})
+
+		requests := watcher.Evaluate(ctx, tick(44900.0))
+		assert.Len(t, requests, 1)
+		assert.Equal(t, TriggerTakeProfit, requests[0].Reason)
+		assert.Equal(t, Closed, pos.Status)
+	})
+
+	t.Run("Liquidation fires immediately", func(t *testing.T) {
+		manager := NewManager()
+		pos := open(t, manager, Long, nil, nil)
+		watcher := NewPriceWatcher(manager, DefaultWatcherConfig())
+
+		requests := watcher.Evaluate(ctx, tick(26000.0))
+		assert.Len(t, requests, 1)
+		assert.Equal(t, TriggerLiquidation, requests[0].Reason)
+		assert.Equal(t, Liquidated, pos.Status)
+	})
+
+	t.Run("Emit only leaves position open", func(t *testing.T) {
+		manager := NewManager()
+		stopLoss := money.FromFloat(48000.0)
+		pos := open(t, manager, Long, &stopLoss, nil)
+		watcher := NewPriceWatcher(manager, WatcherConfig{ConfirmTicks: 1})
+
+		requests := watcher.Evaluate(ctx, tick(47000.0))
+		assert.Len(t, requests, 1)
+		assert.False(t, requests[0].Closed)
+		assert.Equal(t, Open, pos.Status)
+		assert.Equal(t, 47000.0, pos.CurrentPrice.Float64())
+	})
+
+	t.Run("Run consumes feed until closed", func(t *testing.T) {
+		manager := NewManager()
+		takeProfit := money.FromFloat(52000.0)
+		pos := open(t, manager, Long, nil, &takeProfit)
+		watcher := NewPriceWatcher(manager, WatcherConfig{AutoClose: true, ConfirmTicks: 1})
+
+		prices := make(chan PriceUpdate, 2)
+		prices <- tick(51000.0)
+		prices <- tick(52500.0)
+		close(prices)
+
+		assert.NoError(t, watcher.Run(ctx, prices))
+		assert.Equal(t, Closed, pos.Status)
+	})
+}
+
+func TestLiquidationMonitor(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now()
+
+	open := func(t *testing.T, manager *Manager) *Position {
+		pos, err := manager.OpenPosition(ctx, OpenPositionParams{
+			Symbol:     "BTC/USD",
+			Side:       Long,
+			Size:       money.FromFloat(1.0),
+			EntryPrice: money.FromFloat(50000.0),
+			Leverage:   2.0,
+		})
+		assert.NoError(t, err)
+		return pos
+	}
+	tick := func(price float64, second int) PriceUpdate {
+		return PriceUpdate{Symbol: "BTC/USD", Price: price, Timestamp: start.Add(time.Duration(second) * time.Second)}
+	}
+	warnings := func() int {
+		n := 0
+		for _, e := range monitoring.EventsByType(LiquidationEventType) {
+			if e.Message == "Position approaching liquidation" {
+				n++
+			}
+		}
+		return n
+	}
+
+	t.Run("Warns once within the buffer", func(t *testing.T) {
+		manager := NewManager()
+		pos := open(t, manager)
+		monitor := NewLiquidationMonitor(manager, DefaultLiquidationConfig())
+		before := warnings()
+
+		risks := monitor.Evaluate(ctx, tick(40000.0, 0))
+		assert.Len(t, risks, 1)
+		assert.Equal(t, pos.ID, risks[0].PositionID)
+		assert.Equal(t, 26500.0, risks[0].LiquidationPrice)
+		assert.InDelta(t, 0.3375, risks[0].Distance, 1e-9)
+		assert.InDelta(t, 0.375, risks[0].MarginRatio, 1e-9)
+		assert.Equal(t, -1.0, risks[0].BreachProbability, "volatility is unknown")
+		assert.Equal(t, before, warnings())
+
+		monitor.Evaluate(ctx, tick(27500.0, 1))
+		monitor.Evaluate(ctx, tick(27400.0, 2))
+		assert.Equal(t, before+1, warnings())
+
+		// Recovering re-arms the warning
+		monitor.Evaluate(ctx, tick(35000.0, 3))
+		monitor.Evaluate(ctx, tick(27500.0, 4))
+		assert.Equal(t, before+2, warnings())
+		assert.Empty(t, monitor.Requests())
+		assert.Equal(t, 1.0, pos.Size.Float64())
+	})
+
+	t.Run("Exchange liquidation price", func(t *testing.T) {
+		manager := NewManager()
+		pos := open(t, manager)
+		assert.NoError(t, manager.UpdateLiquidationPrice(ctx, pos.ID, money.FromFloat(30000.0)))
+		monitor := NewLiquidationMonitor(manager, DefaultLiquidationConfig())
+
+		risks := monitor.Evaluate(ctx, tick(40000.0, 0))
+		assert.Equal(t, 30000.0, risks[0].LiquidationPrice)
+		assert.Equal(t, 30000.0, pos.Snapshot().LiquidationPrice.Float64())
+	})
+
+	t.Run("Deleverages likely breaches", func(t *testing.T) {
+		manager := NewManager()
+		pos := open(t, manager)
+		monitor := NewLiquidationMonitor(manager, LiquidationConfig{
+			MaintenanceMarginRate: 0.03,
+			AutoDeleverage:        true,
+			MinSamples:            2,
+			DeleverageCooldown:    time.Minute,
+		})
+
+		// Far from liquidation a volatile market does not deleverage
+		monitor.Evaluate(ctx, tick(36000.0, 0))
+		monitor.Evaluate(ctx, tick(34000.0, 1))
+		monitor.Evaluate(ctx, tick(36000.0, 2))
+		assert.Empty(t, monitor.Requests())
+
+		risks := monitor.Evaluate(ctx, tick(27500.0, 3))
+		assert.Greater(t, risks[0].BreachProbability, 0.5)
+		req := <-monitor.Requests()
+		assert.Equal(t, pos.ID, req.PositionID)
+		assert.Equal(t, 0.25, req.Size)
+		assert.True(t, req.Deleveraged)
+		assert.Equal(t, 0.75, pos.Size.Float64())
+		assert.Equal(t, Open, pos.Status)
+		assert.Equal(t, -22500.0*0.25, pos.RealizedPnL.Float64())
+
+		// Within the cooldown
+		monitor.Evaluate(ctx, tick(27400.0, 4))
+		assert.Empty(t, monitor.Requests())
+		monitor.Evaluate(ctx, tick(27400.0, 64))
+		req = <-monitor.Requests()
+		assert.Equal(t, 0.1875, req.Size)
+	})
+
+	t.Run("Emit only leaves position open", func(t *testing.T) {
+		manager := NewManager()
+		pos := open(t, manager)
+		monitor := NewLiquidationMonitor(manager, LiquidationConfig{MaintenanceMarginRate: 0.03, MinSamples: 1})
+
+		monitor.Evaluate(ctx, tick(30000.0, 0))
+		monitor.Evaluate(ctx, tick(27500.0, 1))
+		req := <-monitor.Requests()
+		assert.False(t, req.Deleveraged)
+		assert.Equal(t, 1.0, pos.Size.Float64())
+
+		// Closed positions are forgotten
+		assert.NoError(t, manager.ClosePosition(ctx, pos.ID, money.FromFloat(27500.0)))
+		assert.Empty(t, monitor.Evaluate(ctx, tick(27500.0, 2)))
+		assert.Empty(t, monitor.states)
+	})
+}
+
+func TestReducePosition(t *testing.T) {
+	ctx := context.Background()
+	manager := NewManager()
+	pos, err := manager.OpenPosition(ctx, OpenPositionParams{Symbol: "ETH/USD", Side: Short, Size: money.NewFromInt(4), EntryPrice: money.NewFromInt(3000), Leverage: 3})
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, manager.ReducePosition(ctx, pos.ID, money.NewFromInt(0), money.NewFromInt(2900)), ErrInvalidSize)
+	assert.ErrorIs(t, manager.ReducePosition(ctx, "missing", money.NewFromInt(1), money.NewFromInt(2900)), ErrPositionNotFound)
+
+	assert.NoError(t, manager.ReducePosition(ctx, pos.ID, money.NewFromInt(1), money.NewFromInt(2900)))
+	assert.Equal(t, 3.0, pos.Size.Float64())
+	assert.Equal(t, 100.0, pos.RealizedPnL.Float64())
+	assert.Equal(t, 3000.0, pos.Margin.Float64())
+	assert.Equal(t, 300.0, pos.UnrealizedPnL.Float64())
+
+	assert.NoError(t, manager.ReducePosition(ctx, pos.ID, money.NewFromInt(5), money.NewFromInt(2800)))
+	assert.Equal(t, Closed, pos.Status)
+	assert.Equal(t, 700.0, pos.RealizedPnL.Float64())
+	assert.ErrorIs(t, manager.ReducePosition(ctx, pos.ID, money.NewFromInt(1), money.NewFromInt(2800)), ErrPositionAlreadyClosed)
+
+	// Thousands of partial closes leave no residue in size or PnL
+	pos, err = manager.OpenPosition(ctx, OpenPositionParams{Symbol: "DOGE/USD", Side: Long, Size: money.NewFromInt(100), EntryPrice: money.FromFloat(0.7), Leverage: 1})
+	assert.NoError(t, err)
+	for i := 0; i < 999; i++ {
+		assert.NoError(t, manager.ReducePosition(ctx, pos.ID, money.FromFloat(0.1), money.FromFloat(0.8)))
+	}
+	assert.Equal(t, 0.1, pos.Size.Float64())
+	assert.Equal(t, 9.99, pos.RealizedPnL.Float64())
+	assert.Equal(t, 0.07, pos.Margin.Float64())
+	assert.NoError(t, manager.ReducePosition(ctx, pos.ID, money.FromFloat(0.1), money.FromFloat(0.8)))
+	assert.Equal(t, Closed, pos.Status)
+	assert.Equal(t, 10.0, pos.RealizedPnL.Float64())
+}
+
+type fakeFundingClient struct {
+	rates map[string]*dydx.FundingRate
+}
+
+func (c *fakeFundingClient) GetFundingRate(ctx context.Context, symbol string) (*dydx.FundingRate, error) {
+	return c.rates[symbol], nil
+}
+
+func TestFundingAccrual(t *testing.T) {
+	ctx := context.Background()
+	// Periods start after the positions of the tests are opened
+	fundingTime := time.Now().Add(time.Minute)
+
+	t.Run("Accrue applies funding once per period", func(t *testing.T) {
+		manager := NewManager()
+		long, err := manager.OpenPosition(ctx, OpenPositionParams{
+			Symbol: "BTC-USD", Side: Long, Size: money.FromFloat(2.0), EntryPrice: money.FromFloat(50000.0), Leverage: 5.0, Fee: money.FromFloat(10.0),
+		})
+		assert.NoError(t, err)
+		short, err := manager.OpenPosition(ctx, OpenPositionParams{
+			Symbol: "BTC-USD", Side: Short, Size: money.FromFloat(1.0), EntryPrice: money.FromFloat(50000.0), Leverage: 5.0,
+		})
+		assert.NoError(t, err)
+
+		client := &fakeFundingClient{rates: map[string]*dydx.FundingRate{
+			"BTC-USD": {Market: "BTC-USD", Rate: 0.0001, Price: 50000.0, Time: fundingTime},
+		}}
+		accruer := NewFundingAccruer(manager, client, time.Hour)
+
+		accruer.Accrue(ctx)
+		accruer.Accrue(ctx)
+
+		assert.InDelta(t, -10.0, long.FundingAccrual.Float64(), 1e-9)
+		assert.InDelta(t, 5.0, short.FundingAccrual.Float64(), 1e-9)
+
+		client.rates["BTC-USD"] = &dydx.FundingRate{Market: "BTC-USD", Rate: 0.0001, Price: 50000.0, Time: fundingTime.Add(time.Hour)}
+		accruer.Accrue(ctx)
+		assert.InDelta(t, -20.0, long.FundingAccrual.Float64(), 1e-9)
+	})
+
+	t.Run("Positions opened during a period pay from the next one", func(t *testing.T) {
+		manager := NewManager()
+		pos, err := manager.OpenPosition(ctx, OpenPositionParams{
+			Symbol: "SOL-USD", Side: Long, Size: money.FromFloat(10.0), EntryPrice: money.FromFloat(100.0), Leverage: 2.0,
+		})
+		assert.NoError(t, err)
+
+		manager.ApplyFunding(ctx, "SOL-USD", 0.001, money.Zero, pos.OpenTime.Add(-time.Minute))
+		assert.True(t, pos.FundingAccrual.IsZero(), "the period started before the position was opened")
+
+		manager.ApplyFunding(ctx, "SOL-USD", 0.001, money.Zero, pos.OpenTime.Add(time.Hour))
+		assert.InDelta(t, -1.0, pos.FundingAccrual.Float64(), 1e-9)
+	})
+
+	t.Run("NetPnL and ROI include funding and fees", func(t *testing.T) {
+		manager := NewManager()
+		pos, err := manager.OpenPosition(ctx, OpenPositionParams{
+			Symbol: "ETH-USD", Side: Long, Size: money.FromFloat(1.0), EntryPrice: money.FromFloat(3000.0), Leverage: 3.0, Fee: money.FromFloat(3.0),
+		})
+		assert.NoError(t, err)
+
+		price := money.FromFloat(3100.0)
+		assert.NoError(t, manager.UpdatePosition(ctx, pos.ID, UpdatePositionParams{CurrentPrice: &price}))
+		manager.ApplyFunding(ctx, "ETH-USD", -0.001, money.FromFloat(3100.0), time.Now())
+		assert.NoError(t, manager.RecordFee(ctx, pos.ID, money.FromFloat(2.0)))
+
+		assert.InDelta(t, 100.0+3.1-5.0, pos.NetPnL().Float64(), 1e-9)
+		assert.InDelta(t, 98.1/1000.0*100, pos.ROI(), 1e-9)
+
+		assert.NoError(t, manager.ClosePosition(ctx, pos.ID, money.FromFloat(3200.0)))
+		assert.InDelta(t, 200.0+3.1-5.0, pos.NetPnL().Float64(), 1e-9)
+	})
+}
+
+type memoryPositionStore struct {
+	positions map[string]*Position
+	// err fails every save when set
+	err error
+}
+
+func (s *memoryPositionStore) SavePosition(ctx context.Context, position *Position) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.positions[position.ID] = copyPosition(position)
+	return nil
+}
+
+func (s *memoryPositionStore) LoadOpenPositions(ctx context.Context) ([]*Position, error) {
+	var positions []*Position
+	for _, position := range s.positions {
+		if position.Status == Open {
+			positions = append(positions, copyPosition(position))
+		}
+	}
+	return positions, nil
+}
+
+func copyPosition(position *Position) *Position {
+	return &Position{
+		ID:             position.ID,
+		Symbol:         position.Symbol,
+		Side:           position.Side,
+		EntryPrice:     position.EntryPrice,
+		CurrentPrice:   position.CurrentPrice,
+		Size:           position.Size,
+		OpenTime:       position.OpenTime,
+		LastUpdateTime: position.LastUpdateTime,
+		StopLoss:       position.StopLoss,
+		TakeProfit:     position.TakeProfit,
+		Status:         position.Status,
+		UnrealizedPnL:  position.UnrealizedPnL,
+		RealizedPnL:    position.RealizedPnL,
+		FundingAccrual: position.FundingAccrual,
+		Fees:           position.Fees,
+		Leverage:       position.Leverage,
+		Margin:         position.Margin,
+	}
+}
+
+type fakePositionSource struct {
+	positions []dydx.Position
+}
+
+func (s *fakePositionSource) GetPositions(ctx context.Context) ([]dydx.Position, error) {
+	return s.positions, nil
+}
+
+func TestPositionRecovery(t *testing.T) {
+	ctx := context.Background()
+
+	store := &memoryPositionStore{positions: make(map[string]*Position)}
+	manager := NewManager(WithStore(store))
+
+	btc, err := manager.OpenPosition(ctx, OpenPositionParams{
+		Symbol: "BTC-USD", Side: Long, Size: money.FromFloat(1.0), EntryPrice: money.FromFloat(50000.0), Leverage: 5.0,
+	})
+	assert.NoError(t, err)
+	eth, err := manager.OpenPosition(ctx, OpenPositionParams{
+		Symbol: "ETH-USD", Side: Short, Size: money.FromFloat(2.0), EntryPrice: money.FromFloat(3000.0), Leverage: 5.0,
+	})
+	assert.NoError(t, err)
+
+	exchange := &fakePositionSource{positions: []dydx.Position{
+		{Market: "BTC-USD", Side: "LONG", Size: 1.5, EntryPrice: 50500.0, MarkPrice: 51000.0, Leverage: 5},
+		{Market: "SOL-USD", Side: "SHORT", Size: 10.0, EntryPrice: 100.0, MarkPrice: 98.0, Leverage: 2},
+	}}
+
+	restarted := NewManager(WithStore(store), WithExchange(exchange))
+	report, err := restarted.Recover(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, report.Loaded)
+	assert.Equal(t, []string{btc.ID}, report.Resized)
+	assert.Equal(t, []string{eth.ID}, report.Closed)
+	assert.Len(t, report.Adopted, 1)
+
+	resized, err := restarted.GetPosition(ctx, btc.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.5, resized.Size.Float64())
+	assert.Equal(t, 51000.0, resized.CurrentPrice.Float64())
+
+	adopted, err := restarted.GetPosition(ctx, report.Adopted[0])
+	assert.NoError(t, err)
+	assert.Equal(t, Short, adopted.Side)
+	assert.Equal(t, "SOL-USD", adopted.Symbol)
+
+	assert.Equal(t, Closed, store.positions[eth.ID].Status)
+
+	t.Run("Failed saves leave the position unchanged", func(t *testing.T) {
+		store.err = errors.New("store unavailable")
+		t.Cleanup(func() { store.err = nil })
+
+		assert.ErrorIs(t, restarted.ClosePosition(ctx, btc.ID, money.FromFloat(51000.0)), store.err)
+		assert.ErrorIs(t, restarted.ReducePosition(ctx, btc.ID, money.FromFloat(0.5), money.FromFloat(51000.0)), store.err)
+
+		unchanged, err := restarted.GetPosition(ctx, btc.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, Open, unchanged.Status)
+		assert.Equal(t, 1.5, unchanged.Size.Float64())
+		assert.Equal(t, 0.0, unchanged.RealizedPnL.Float64())
+	})
+}
+
+func TestNettingMode(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Hedge mode keeps both sides", func(t *testing.T) {
+		manager := NewManager()
+		_, err := manager.OpenPosition(ctx, OpenPositionParams{Symbol: "BTC-USD", Side: Long, Size: money.FromFloat(2.0), EntryPrice: money.FromFloat(50000.0), Leverage: 2.0})
+		assert.NoError(t, err)
+		_, err = manager.OpenPosition(ctx, OpenPositionParams{Symbol: "BTC-USD", Side: Short, Size: money.FromFloat(1.0), EntryPrice: money.FromFloat(51000.0), Leverage: 2.0})
+		assert.NoError(t, err)
+
+		exposure := manager.Exposure(ctx, "BTC-USD")
+		assert.Equal(t, HedgeMode, exposure.Mode)
+		assert.Equal(t, 1.0, exposure.Net.Float64())
+		assert.Equal(t, 3.0, exposure.Gross.Float64())
+		assert.Equal(t, 3.0, exposure.RiskPosition().Float64())
+	})
+
+	t.Run("Opposite order reduces position", func(t *testing.T) {
+		manager := NewManager(WithMode(NettingMode))
+		long, err := manager.OpenPosition(ctx, OpenPositionParams{Symbol: "BTC-USD", Side: Long, Size: money.FromFloat(2.0), EntryPrice: money.FromFloat(50000.0), Leverage: 2.0})
+		assert.NoError(t, err)
+
+		reduced, err := manager.OpenPosition(ctx, OpenPositionParams{Symbol: "BTC-USD", Side: Short, Size: money.FromFloat(0.5), EntryPrice: money.FromFloat(52000.0), Leverage: 2.0})
+		assert.NoError(t, err)
+		assert.Equal(t, long.ID, reduced.ID)
+		assert.Equal(t, 1.5, long.Size.Float64())
+		assert.Equal(t, 1000.0, long.RealizedPnL.Float64())
+		assert.Equal(t, Open, long.Status)
+
+		exposure := manager.Exposure(ctx, "BTC-USD")
+		assert.Equal(t, 1.5, exposure.Net.Float64())
+		assert.Equal(t, 1.5, exposure.RiskPosition().Float64())
+	})
+
+	t.Run("Same side order increases position", func(t *testing.T) {
+		manager := NewManager(WithMode(NettingMode))
+		long, err := manager.OpenPosition(ctx, OpenPositionParams{Symbol: "ETH-USD", Side: Long, Size: money.FromFloat(1.0), EntryPrice: money.FromFloat(3000.0), Leverage: 2.0})
+		assert.NoError(t, err)
+
+		added, err := manager.OpenPosition(ctx, OpenPositionParams{Symbol: "ETH-USD", Side: Long, Size: money.FromFloat(1.0), EntryPrice: money.FromFloat(3200.0), Leverage: 2.0})
+		assert.NoError(t, err)
+		assert.Equal(t, long.ID, added.ID)
+		assert.Equal(t, 2.0, long.Size.Float64())
+		assert.Equal(t, 3100.0, long.EntryPrice.Float64())
+		assert.Equal(t, 3100.0, long.Margin.Float64())
+	})
+
+	t.Run("Larger opposite order flips position", func(t *testing.T) {
+		manager := NewManager(WithMode(NettingMode))
+		long, err := manager.OpenPosition(ctx, OpenPositionParams{Symbol: "SOL-USD", Side: Long, Size: money.FromFloat(10.0), EntryPrice: money.FromFloat(100.0), Leverage: 2.0})
+		assert.NoError(t, err)
+
+		short, err := manager.OpenPosition(ctx, OpenPositionParams{Symbol: "SOL-USD", Side: Short, Size: money.FromFloat(15.0), EntryPrice: money.FromFloat(90.0), Leverage: 2.0})
+		assert.NoError(t, err)
+		assert.NotEqual(t, long.ID, short.ID)
+		assert.Equal(t, Closed, long.Status)
+		assert.Equal(t, -100.0, long.RealizedPnL.Float64())
+		assert.Equal(t, Short, short.Side)
+		assert.Equal(t, 5.0, short.Size.Float64())
+
+		exposure := manager.Exposure(ctx, "SOL-USD")
+		assert.Equal(t, -5.0, exposure.Net.Float64())
+		assert.Equal(t, 5.0, exposure.Gross.Float64())
+	})
+
+	t.Run("Concurrent opens on a new symbol share one position", func(t *testing.T) {
+		manager := NewManager(WithMode(NettingMode))
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := manager.OpenPosition(ctx, OpenPositionParams{Symbol: "AVAX-USD", Side: Long, Size: money.FromFloat(1.0), EntryPrice: money.FromFloat(30.0), Leverage: 2.0, Fee: money.FromFloat(0.1)})
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		positions, err := manager.ListPositions(ctx, PositionFilter{Symbol: "AVAX-USD"})
+		assert.NoError(t, err)
+		if assert.Len(t, positions, 1) {
+			assert.Equal(t, 20.0, positions[0].Size.Float64())
+			assert.Equal(t, 2.0, positions[0].Fees.Float64(), "the first open keeps its fee")
+		}
+	})
+}
+
+func TestPositionListener(t *testing.T) {
+	ctx := context.Background()
+	var updates []*Position
+	manager := NewManager(WithListener(func(p *Position) {
+		updates = append(updates, p)
+	}))
+
+	position, err := manager.OpenPosition(ctx, OpenPositionParams{Symbol: "BTC-USD", Side: Long, Size: money.FromFloat(1.0), EntryPrice: money.FromFloat(50000.0), Leverage: 1.0})
+	assert.NoError(t, err)
+	price := money.FromFloat(51000.0)
+	assert.NoError(t, manager.UpdatePosition(ctx, position.ID, UpdatePositionParams{CurrentPrice: &price}))
+	assert.NoError(t, manager.ClosePosition(ctx, position.ID, money.FromFloat(52000.0)))
+
+	assert.Len(t, updates, 3)
+	assert.Equal(t, 1000.0, updates[1].UnrealizedPnL.Float64())
+	assert.Equal(t, Closed, updates[2].Status)
+	assert.Equal(t, 2000.0, updates[2].RealizedPnL.Float64())
+	assert.Equal(t, Open, updates[0].Status)
+}
@@ -0,0 +1,67 @@
+package position
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// DefaultRedisKey is the hash RedisStore saves open positions under
+const DefaultRedisKey = "gosol:positions"
+
+// RedisStore saves open positions in a Redis hash keyed by position ID.
+// Positions are removed once closed or liquidated, so the hash only holds
+// the positions Recover needs.
+type RedisStore struct {
+	client *redis.Client
+	key    string
+}
+
+// NewRedisStore creates a store saving open positions under key, or
+// DefaultRedisKey when key is empty. Accounts sharing a Redis instance need
+// keys of their own.
+func NewRedisStore(client *redis.Client, key string) *RedisStore {
+	if key == "" {
+		key = DefaultRedisKey
+	}
+	return &RedisStore{client: client, key: key}
+}
+
+// SavePosition saves an open position and removes a closed one
+func (s *RedisStore) SavePosition(ctx context.Context, position *Position) error {
+	if position.Status != Open {
+		if err := s.client.HDel(ctx, s.key, position.ID).Err(); err != nil {
+			return fmt.Errorf("failed to remove position from redis: %w", err)
+		}
+		return nil
+	}
+
+	data, err := json.Marshal(position)
+	if err != nil {
+		return fmt.Errorf("failed to encode position: %w", err)
+	}
+	if err := s.client.HSet(ctx, s.key, position.ID, data).Err(); err != nil {
+		return fmt.Errorf("failed to save position to redis: %w", err)
+	}
+	return nil
+}
+
+// LoadOpenPositions returns the saved open positions
+func (s *RedisStore) LoadOpenPositions(ctx context.Context) ([]*Position, error) {
+	values, err := s.client.HGetAll(ctx, s.key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load positions from redis: %w", err)
+	}
+
+	positions := make([]*Position, 0, len(values))
+	for id, data := range values {
+		var position Position
+		if err := json.Unmarshal([]byte(data), &position); err != nil {
+			return nil, fmt.Errorf("failed to decode position %s: %w", id, err)
+		}
+		positions = append(positions, &position)
+	}
+	return positions, nil
+}
@@ -0,0 +1,273 @@
+package position
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/exchange/dydx"
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+	"github.com/devinjacknz/godydxhyber/backend/trading/analysis/monitoring"
+)
+
+// Store persists positions so they survive restarts
+type Store interface {
+	SavePosition(ctx context.Context, position *Position) error
+	LoadOpenPositions(ctx context.Context) ([]*Position, error)
+}
+
+// ExchangePositionSource lists the positions currently open on the exchange
+type ExchangePositionSource interface {
+	GetPositions(ctx context.Context) ([]dydx.Position, error)
+}
+
+// Listener receives a copy of a position after each persisted change. It is
+// called with the position locked, so it must not block or call back into
+// the manager.
+type Listener func(position *Position)
+
+// ManagerOption configures a Manager
+type ManagerOption func(*Manager)
+
+// WithStore persists every position change through the given store
+func WithStore(store Store) ManagerOption {
+	return func(m *Manager) {
+		m.store = store
+	}
+}
+
+// WithAccountID sets the trading account of the positions of the manager
+func WithAccountID(accountID string) ManagerOption {
+	return func(m *Manager) {
+		m.accountID = accountID
+	}
+}
+
+// WithExchange reconciles recovered positions against the exchange
+func WithExchange(exchange ExchangePositionSource) ManagerOption {
+	return func(m *Manager) {
+		m.exchange = exchange
+	}
+}
+
+// WithListener notifies the listener of every position change
+func WithListener(listener Listener) ManagerOption {
+	return func(m *Manager) {
+		m.listener = listener
+	}
+}
+
+// RecoveryReport summarizes the result of Recover
+type RecoveryReport struct {
+	// Loaded is the number of open positions loaded from the store
+	Loaded int
+	// Adopted lists exchange positions that were not known locally
+	Adopted []string
+	// Resized lists stored positions whose size was corrected from the exchange
+	Resized []string
+	// Closed lists stored positions that are no longer open on the exchange
+	Closed []string
+}
+
+// Recover reloads open positions from the store and reconciles them against
+// the exchange. The exchange is treated as the source of truth for size and
+// entry price.
+func (m *Manager) Recover(ctx context.Context) (*RecoveryReport, error) {
+	start := time.Now()
+	defer func() {
+		monitoring.RecordIndicatorCalculation("recover_positions", time.Since(start))
+	}()
+
+	if m.store == nil {
+		return &RecoveryReport{}, nil
+	}
+
+	stored, err := m.store.LoadOpenPositions(ctx)
+	if err != nil {
+		monitoring.RecordIndicatorError("recover_positions", err.Error())
+		return nil, fmt.Errorf("failed to load open positions: %w", err)
+	}
+	return m.restore(ctx, stored)
+}
+
+// Restore loads open positions saved elsewhere, such as in an engine
+// snapshot, and reconciles them against the exchange like Recover
+func (m *Manager) Restore(ctx context.Context, positions []*Position) (*RecoveryReport, error) {
+	start := time.Now()
+	defer func() {
+		monitoring.RecordIndicatorCalculation("restore_positions", time.Since(start))
+	}()
+
+	restored := make([]*Position, 0, len(positions))
+	for _, position := range positions {
+		restored = append(restored, position.Snapshot())
+	}
+	return m.restore(ctx, restored)
+}
+
+// restore adds loaded positions to the manager and reconciles them
+func (m *Manager) restore(ctx context.Context, stored []*Position) (*RecoveryReport, error) {
+	report := &RecoveryReport{Loaded: len(stored)}
+
+	m.mu.Lock()
+	for _, position := range stored {
+		m.positions[position.ID] = position
+	}
+	m.mu.Unlock()
+
+	if m.exchange == nil {
+		monitoring.RecordIndicatorValue("active_positions", float64(len(stored)))
+		return report, nil
+	}
+
+	remote, err := m.exchange.GetPositions(ctx)
+	if err != nil {
+		monitoring.RecordIndicatorError("recover_positions", err.Error())
+		return nil, fmt.Errorf("failed to get exchange positions: %w", err)
+	}
+
+	matched := make(map[string]bool, len(stored))
+	for i := range remote {
+		ex := &remote[i]
+		if ex.Size == 0 {
+			continue
+		}
+
+		local := findStoredPosition(stored, ex, matched)
+		if local == nil {
+			local = positionFromExchange(ex)
+			local.AccountID = m.accountID
+			m.mu.Lock()
+			m.positions[local.ID] = local
+			m.mu.Unlock()
+			report.Adopted = append(report.Adopted, local.ID)
+		} else if syncFromExchange(local, ex) {
+			report.Resized = append(report.Resized, local.ID)
+		}
+		matched[local.ID] = true
+
+		local.mu.Lock()
+		err := m.persist(ctx, local)
+		local.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, position := range stored {
+		if matched[position.ID] {
+			continue
+		}
+
+		position.mu.Lock()
+		position.Status = Closed
+		position.LastUpdateTime = time.Now()
+		position.RealizedPnL = calculateRealizedPnL(position, position.CurrentPrice)
+		err := m.persist(ctx, position)
+		position.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		report.Closed = append(report.Closed, position.ID)
+	}
+
+	monitoring.RecordIndicatorValue("active_positions", float64(len(matched)))
+	return report, nil
+}
+
+// update applies a change to a copy of a position and saves the copy before
+// applying it to the position, so the position is left unchanged when it
+// cannot be saved. Callers hold the position's lock.
+func (m *Manager) update(ctx context.Context, position *Position, change func(next *Position)) error {
+	next := position.snapshot()
+	change(next)
+	if err := m.persist(ctx, next); err != nil {
+		return err
+	}
+	position.apply(next)
+	return nil
+}
+
+// persist saves a position if a store is configured. Callers hold the position lock.
+func (m *Manager) persist(ctx context.Context, position *Position) error {
+	if m.store != nil {
+		if err := m.store.SavePosition(ctx, position); err != nil {
+			monitoring.RecordIndicatorError("persist_position", err.Error())
+			return fmt.Errorf("failed to persist position %s: %w", position.ID, err)
+		}
+	}
+	if m.listener != nil {
+		m.listener(position.snapshot())
+	}
+	return nil
+}
+
+func findStoredPosition(stored []*Position, ex *dydx.Position, matched map[string]bool) *Position {
+	side := exchangeSide(ex.Side)
+	for _, position := range stored {
+		if !matched[position.ID] && position.Symbol == ex.Market && position.Side == side {
+			return position
+		}
+	}
+	return nil
+}
+
+// syncFromExchange updates a stored position from exchange state and reports
+// whether its size changed
+func syncFromExchange(position *Position, ex *dydx.Position) bool {
+	position.mu.Lock()
+	defer position.mu.Unlock()
+
+	size := money.FromFloat(ex.Size)
+	resized := !position.Size.Equal(size)
+	position.Size = size
+	if ex.EntryPrice > 0 {
+		position.EntryPrice = money.FromFloat(ex.EntryPrice)
+	}
+	if ex.MarkPrice > 0 {
+		position.CurrentPrice = money.FromFloat(ex.MarkPrice)
+	}
+	position.LiquidationPrice = money.FromFloat(ex.LiquidationPrice)
+	position.UnrealizedPnL = calculateUnrealizedPnL(position)
+	position.LastUpdateTime = time.Now()
+	return resized
+}
+
+func positionFromExchange(ex *dydx.Position) *Position {
+	leverage := float64(ex.Leverage)
+	if leverage <= 0 {
+		leverage = 1
+	}
+	size, entry := money.FromFloat(ex.Size), money.FromFloat(ex.EntryPrice)
+	// The leverage is positive, so the margin cannot fail
+	margin, _ := calculateMargin(size, entry, leverage)
+
+	position := &Position{
+		ID:             generatePositionID(),
+		Symbol:         ex.Market,
+		Side:           exchangeSide(ex.Side),
+		EntryPrice:     entry,
+		CurrentPrice:   money.FromFloat(ex.MarkPrice),
+		Size:           size,
+		OpenTime:       ex.CreatedAt,
+		LastUpdateTime: time.Now(),
+		Status:         Open,
+		UnrealizedPnL:  money.FromFloat(ex.UnrealizedPnl),
+		Leverage:       leverage,
+		Margin:         margin,
+
+		LiquidationPrice: money.FromFloat(ex.LiquidationPrice),
+	}
+	if position.CurrentPrice.Sign() <= 0 {
+		position.CurrentPrice = entry
+	}
+	return position
+}
+
+func exchangeSide(side string) Side {
+	if strings.EqualFold(side, dydx.PositionSideShort) {
+		return Short
+	}
+	return Long
+}
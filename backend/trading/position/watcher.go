@@ -0,0 +1,259 @@
+package position
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+	"github.com/devinjacknz/godydxhyber/backend/trading/analysis/monitoring"
+)
+
+// TriggerReason describes why a position was closed by the watcher
+type TriggerReason string
+
+const (
+	TriggerStopLoss    TriggerReason = "stop_loss"
+	TriggerTakeProfit  TriggerReason = "take_profit"
+	TriggerLiquidation TriggerReason = "liquidation"
+)
+
+// PriceUpdate represents a price tick from the price feed
+type PriceUpdate struct {
+	Symbol    string
+	Price     float64
+	Timestamp time.Time
+}
+
+// CloseRequest is emitted when a position breaches one of its trigger levels
+type CloseRequest struct {
+	PositionID   string
+	Symbol       string
+	Side         Side
+	Reason       TriggerReason
+	TriggerLevel float64
+	Price        float64
+	Timestamp    time.Time
+	// Closed reports whether the watcher already closed the position
+	Closed bool
+}
+
+// WatcherConfig contains price watcher configuration
+type WatcherConfig struct {
+	// AutoClose closes positions directly instead of only emitting close requests
+	AutoClose bool
+	// ConfirmTicks is the number of consecutive breaching ticks required
+	// before a stop-loss or take-profit fires
+	ConfirmTicks int
+	// BufferBps is how far past a stop-loss or take-profit level, in basis
+	// points, the price must trade before the tick counts as a breach
+	BufferBps float64
+	// MaintenanceMarginRate is used to derive the liquidation price
+	MaintenanceMarginRate float64
+	// RequestBuffer is the capacity of the close request channel
+	RequestBuffer int
+}
+
+// DefaultWatcherConfig returns default price watcher configuration
+func DefaultWatcherConfig() WatcherConfig {
+	return WatcherConfig{
+		AutoClose:             true,
+		ConfirmTicks:          2,
+		BufferBps:             5.0,
+		MaintenanceMarginRate: 0.03,
+		RequestBuffer:         100,
+	}
+}
+
+// PriceWatcher monitors open positions against a price feed and closes
+// positions whose stop-loss, take-profit or liquidation levels are breached
+type PriceWatcher struct {
+	manager  *Manager
+	config   WatcherConfig
+	requests chan CloseRequest
+	breaches map[string]int
+	mu       sync.Mutex
+}
+
+// NewPriceWatcher creates a new price watcher
+func NewPriceWatcher(manager *Manager, config WatcherConfig) *PriceWatcher {
+	if config.ConfirmTicks <= 0 {
+		config.ConfirmTicks = 1
+	}
+	if config.RequestBuffer <= 0 {
+		config.RequestBuffer = DefaultWatcherConfig().RequestBuffer
+	}
+
+	return &PriceWatcher{
+		manager:  manager,
+		config:   config,
+		requests: make(chan CloseRequest, config.RequestBuffer),
+		breaches: make(map[string]int),
+	}
+}
+
+// Requests returns the channel on which close requests are emitted
+func (w *PriceWatcher) Requests() <-chan CloseRequest {
+	return w.requests
+}
+
+// Run consumes price updates until the context is cancelled or the feed is closed
+func (w *PriceWatcher) Run(ctx context.Context, prices <-chan PriceUpdate) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case update, ok := <-prices:
+			if !ok {
+				return nil
+			}
+			w.Evaluate(ctx, update)
+		}
+	}
+}
+
+// Evaluate applies a price update to all open positions on the symbol and
+// returns the close requests it produced
+func (w *PriceWatcher) Evaluate(ctx context.Context, update PriceUpdate) []CloseRequest {
+	start := time.Now()
+	defer func() {
+		monitoring.RecordIndicatorCalculation("price_watcher", time.Since(start))
+	}()
+
+	if update.Price <= 0 {
+		monitoring.RecordIndicatorError("price_watcher", ErrInvalidPrice.Error())
+		return nil
+	}
+	if update.Timestamp.IsZero() {
+		update.Timestamp = time.Now()
+	}
+
+	status := Open
+	positions, err := w.manager.ListPositions(ctx, PositionFilter{Symbol: update.Symbol, Status: &status})
+	if err != nil {
+		monitoring.RecordIndicatorError("price_watcher", err.Error())
+		return nil
+	}
+
+	var requests []CloseRequest
+	for _, pos := range positions {
+		price := money.FromFloat(update.Price)
+		if err := w.manager.UpdatePosition(ctx, pos.ID, UpdatePositionParams{CurrentPrice: &price}); err != nil {
+			continue
+		}
+
+		req, ok := w.check(pos, update)
+		if !ok {
+			continue
+		}
+
+		if w.config.AutoClose {
+			var err error
+			if req.Reason == TriggerLiquidation {
+				err = w.manager.LiquidatePosition(ctx, req.PositionID, money.FromFloat(req.Price))
+			} else {
+				err = w.manager.ClosePosition(ctx, req.PositionID, money.FromFloat(req.Price))
+			}
+			if err != nil {
+				monitoring.RecordIndicatorError("price_watcher", err.Error())
+				continue
+			}
+			req.Closed = true
+		}
+
+		w.emit(req)
+		requests = append(requests, req)
+	}
+
+	return requests
+}
+
+// check evaluates a single position and applies hysteresis to stop-loss and
+// take-profit triggers. Liquidation fires immediately.
+func (w *PriceWatcher) check(pos *Position, update PriceUpdate) (CloseRequest, bool) {
+	pos.mu.RLock()
+	side := pos.Side
+	entry := pos.EntryPrice.Float64()
+	leverage := pos.Leverage
+	stopLoss := pos.StopLoss
+	takeProfit := pos.TakeProfit
+	pos.mu.RUnlock()
+
+	req := CloseRequest{
+		PositionID: pos.ID,
+		Symbol:     pos.Symbol,
+		Side:       side,
+		Price:      update.Price,
+		Timestamp:  update.Timestamp,
+	}
+
+	liquidation := liquidationPrice(side, entry, leverage, w.config.MaintenanceMarginRate)
+	if liquidation > 0 && breached(side, update.Price, liquidation, true, 0) {
+		w.resetBreach(pos.ID)
+		req.Reason = TriggerLiquidation
+		req.TriggerLevel = liquidation
+		return req, true
+	}
+
+	switch {
+	case stopLoss != nil && breached(side, update.Price, stopLoss.Float64(), true, w.config.BufferBps):
+		req.Reason = TriggerStopLoss
+		req.TriggerLevel = stopLoss.Float64()
+	case takeProfit != nil && breached(side, update.Price, takeProfit.Float64(), false, w.config.BufferBps):
+		req.Reason = TriggerTakeProfit
+		req.TriggerLevel = takeProfit.Float64()
+	default:
+		w.resetBreach(pos.ID)
+		return req, false
+	}
+
+	w.mu.Lock()
+	w.breaches[pos.ID]++
+	confirmed := w.breaches[pos.ID] >= w.config.ConfirmTicks
+	if confirmed {
+		delete(w.breaches, pos.ID)
+	}
+	w.mu.Unlock()
+
+	return req, confirmed
+}
+
+func (w *PriceWatcher) resetBreach(id string) {
+	w.mu.Lock()
+	delete(w.breaches, id)
+	w.mu.Unlock()
+}
+
+func (w *PriceWatcher) emit(req CloseRequest) {
+	select {
+	case w.requests <- req:
+		monitoring.RecordIndicatorValue("position_triggers_"+string(req.Reason), 1)
+	default:
+		monitoring.RecordIndicatorError("price_watcher", "close request channel full")
+	}
+}
+
+// breached reports whether price has moved past level by at least bufferBps.
+// Adverse levels (stop-loss, liquidation) are breached by moves against the
+// position, favorable levels (take-profit) by moves in its favor.
+func breached(side Side, price, level float64, adverse bool, bufferBps float64) bool {
+	buffer := level * bufferBps / 10000
+	below := side == Long
+	if !adverse {
+		below = !below
+	}
+	if below {
+		return price <= level-buffer
+	}
+	return price >= level+buffer
+}
+
+func liquidationPrice(side Side, entry, leverage, maintenanceMarginRate float64) float64 {
+	if leverage <= 0 || entry <= 0 {
+		return 0
+	}
+	if side == Long {
+		return entry * (1 - 1/leverage + maintenanceMarginRate)
+	}
+	return entry * (1 + 1/leverage - maintenanceMarginRate)
+}
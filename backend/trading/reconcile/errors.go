@@ -0,0 +1,12 @@
+package reconcile
+
+import "errors"
+
+var (
+	// ErrInProgress is returned when a reconciliation is started while
+	// another one is running
+	ErrInProgress = errors.New("reconciliation already in progress")
+
+	// ErrNoReport is returned when no reconciliation has completed yet
+	ErrNoReport = errors.New("no reconciliation has run yet")
+)
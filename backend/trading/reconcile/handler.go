@@ -0,0 +1,37 @@
+package reconcile
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes registers the trade reconciliation HTTP endpoints
+func (r *Reconciler) RegisterRoutes(g gin.IRouter) {
+	g.GET("/reconciliation", r.handleLast)
+	g.POST("/reconciliation/run", r.handleRun)
+}
+
+func (r *Reconciler) handleLast(c *gin.Context) {
+	report, err := r.Last()
+	respond(c, report, err)
+}
+
+func (r *Reconciler) handleRun(c *gin.Context) {
+	report, err := r.Reconcile(c.Request.Context())
+	respond(c, report, err)
+}
+
+func respond(c *gin.Context, report *Report, err error) {
+	switch {
+	case errors.Is(err, ErrNoReport):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInProgress):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, report)
+	}
+}
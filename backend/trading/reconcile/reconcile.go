@@ -0,0 +1,474 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/exchange/dydx"
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+	"github.com/devinjacknz/godydxhyber/backend/trading/analysis/monitoring"
+	"github.com/devinjacknz/godydxhyber/backend/trading/order"
+)
+
+// EventType is the monitoring event type of reconciliation alerts
+const EventType = "trade_reconciliation"
+
+// Kind identifies a type of discrepancy
+type Kind string
+
+const (
+	// KindMissingFill is an exchange fill not applied to the local order
+	KindMissingFill Kind = "missing_fill"
+	// KindExcessFill is a local fill the exchange does not report
+	KindExcessFill Kind = "excess_fill"
+	// KindUnknownFill is an exchange fill for an order not known locally
+	KindUnknownFill Kind = "unknown_fill"
+	// KindOrphanedOrder is a local open order no longer open on the exchange
+	KindOrphanedOrder Kind = "orphaned_order"
+	// KindUnknownOrder is an exchange open order not known locally
+	KindUnknownOrder Kind = "unknown_order"
+	// KindChainMismatch is an on-chain settlement that disagrees with the
+	// local fills
+	KindChainMismatch Kind = "chain_mismatch"
+)
+
+// ExchangeSource reads the account's orders and fills from the exchange.
+// It is implemented by the dYdX client.
+type ExchangeSource interface {
+	GetOpenOrders(ctx context.Context) ([]dydx.Order, error)
+	GetOrder(ctx context.Context, orderID string) (*dydx.Order, error)
+	GetFills(ctx context.Context, since time.Time) ([]dydx.Fill, error)
+}
+
+// Transaction is an on-chain settlement of an order
+type Transaction struct {
+	Hash          string
+	ClientOrderID string
+	Size          float64
+	Failed        bool
+	Time          time.Time
+}
+
+// TransactionSource reads the on-chain transaction history of the account
+type TransactionSource interface {
+	GetTransactions(ctx context.Context, since time.Time) ([]Transaction, error)
+}
+
+// Discrepancy is a difference between the exchange, the chain and the local
+// order records
+type Discrepancy struct {
+	Kind            Kind    `json:"kind"`
+	OrderID         string  `json:"order_id,omitempty"`
+	ClientOrderID   string  `json:"client_order_id,omitempty"`
+	ExchangeOrderID string  `json:"exchange_order_id,omitempty"`
+	Symbol          string  `json:"symbol,omitempty"`
+	LocalSize       float64 `json:"local_size"`
+	RemoteSize      float64 `json:"remote_size"`
+	// Corrected is set when the local record was fixed automatically
+	Corrected bool   `json:"corrected"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Report is the result of one reconciliation
+type Report struct {
+	StartedAt     time.Time      `json:"started_at"`
+	Duration      time.Duration  `json:"duration"`
+	Orders        int            `json:"orders"`
+	Fills         int            `json:"fills"`
+	Discrepancies []*Discrepancy `json:"discrepancies"`
+}
+
+// Corrected returns the number of discrepancies fixed automatically
+func (r *Report) Corrected() int {
+	n := 0
+	for _, d := range r.Discrepancies {
+		if d.Corrected {
+			n++
+		}
+	}
+	return n
+}
+
+// Config contains reconciliation configuration
+type Config struct {
+	// Interval is the time between runs of Run
+	Interval time.Duration
+	// Lookback bounds the orders and fills compared on each run
+	Lookback time.Duration
+	// GracePeriod skips orders changed more recently, whose submission or
+	// fills may still be in flight
+	GracePeriod time.Duration
+	// Tolerance is the size difference treated as rounding
+	Tolerance float64
+	// AutoCorrect applies missing fills and expires orphaned orders. Other
+	// discrepancies are only alerted on.
+	AutoCorrect bool
+}
+
+// DefaultConfig returns default reconciliation configuration
+func DefaultConfig() Config {
+	return Config{
+		Interval:    time.Minute,
+		Lookback:    24 * time.Hour,
+		GracePeriod: 30 * time.Second,
+		Tolerance:   1e-9,
+		AutoCorrect: true,
+	}
+}
+
+// Option configures a Reconciler
+type Option func(*Reconciler)
+
+// WithTransactions also compares the local fills to on-chain settlements
+func WithTransactions(source TransactionSource) Option {
+	return func(r *Reconciler) {
+		r.chain = source
+	}
+}
+
+// Reconciler periodically compares the local orders to the exchange and the
+// chain, fixes the discrepancies that are safe to fix and raises monitoring
+// alerts for the others
+type Reconciler struct {
+	orders   order.OrderManager
+	exchange ExchangeSource
+	chain    TransactionSource
+	config   Config
+	// clientIDs caches the client ID of exchange orders, which never changes
+	clientIDs map[string]string
+	last      *Report
+	running   sync.Mutex
+	mu        sync.RWMutex
+}
+
+// NewReconciler creates a reconciler of the local orders against the exchange
+func NewReconciler(orders order.OrderManager, exchange ExchangeSource, config Config, opts ...Option) *Reconciler {
+	defaults := DefaultConfig()
+	if config.Interval <= 0 {
+		config.Interval = defaults.Interval
+	}
+	if config.Lookback <= 0 {
+		config.Lookback = defaults.Lookback
+	}
+	if config.Tolerance <= 0 {
+		config.Tolerance = defaults.Tolerance
+	}
+
+	r := &Reconciler{
+		orders:    orders,
+		exchange:  exchange,
+		config:    config,
+		clientIDs: make(map[string]string),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Run reconciles every interval until ctx is done. Failed runs are recorded
+// as monitoring errors and retried on the next tick.
+func (r *Reconciler) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := r.Reconcile(ctx); err != nil && ctx.Err() == nil {
+				monitoring.RecordIndicatorError("reconcile_trades", err.Error())
+			}
+		}
+	}
+}
+
+// Last returns the report of the last completed reconciliation
+func (r *Reconciler) Last() (*Report, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.last == nil {
+		return nil, ErrNoReport
+	}
+	return r.last, nil
+}
+
+// Reconcile compares the fills of the local orders created within the
+// lookback to the exchange and to the chain when configured, and checks that
+// every local open order is still open on the exchange
+func (r *Reconciler) Reconcile(ctx context.Context) (*Report, error) {
+	if !r.running.TryLock() {
+		return nil, ErrInProgress
+	}
+	defer r.running.Unlock()
+
+	start := time.Now()
+	since := start.Add(-r.config.Lookback)
+	settled := start.Add(-r.config.GracePeriod)
+
+	local, err := r.orders.ListOrders(ctx, order.OrderFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list local orders: %w", err)
+	}
+	open, err := r.exchange.GetOpenOrders(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exchange open orders: %w", err)
+	}
+	fills, err := r.exchange.GetFills(ctx, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exchange fills: %w", err)
+	}
+
+	for i := range open {
+		if open[i].ClientID != "" {
+			r.clientIDs[open[i].ID] = open[i].ClientID
+		}
+	}
+
+	// Local orders by the client ID they are submitted with
+	byClientID := make(map[string]*order.Order, len(local))
+	for _, o := range local {
+		snapshot := o.Snapshot()
+		byClientID[clientID(snapshot)] = snapshot
+	}
+
+	report := &Report{StartedAt: start, Fills: len(fills)}
+	remoteFilled := make(map[string]float64)
+	for _, fill := range fills {
+		id, err := r.clientID(ctx, fill.OrderID)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := byClientID[id]; !ok {
+			report.add(&Discrepancy{
+				Kind:            KindUnknownFill,
+				ClientOrderID:   id,
+				ExchangeOrderID: fill.OrderID,
+				Symbol:          fill.Market,
+				RemoteSize:      fill.Size,
+			})
+			continue
+		}
+		remoteFilled[id] += fill.Size
+	}
+
+	openClientIDs := make(map[string]bool, len(open))
+	for i := range open {
+		ex := &open[i]
+		id := ex.ClientID
+		if id == "" {
+			id = ex.ID
+		}
+		openClientIDs[id] = true
+		if _, ok := byClientID[id]; !ok {
+			report.add(&Discrepancy{
+				Kind:            KindUnknownOrder,
+				ClientOrderID:   id,
+				ExchangeOrderID: ex.ID,
+				Symbol:          ex.Market,
+				RemoteSize:      ex.Size,
+			})
+		}
+	}
+
+	ids := make([]string, 0, len(byClientID))
+	for id := range byClientID {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		o := byClientID[id]
+		if o.UpdatedAt.After(settled) || o.Status == order.Created || o.NeedsReconciliation {
+			// Not yet submitted, or still settling
+			continue
+		}
+
+		// Older orders may have fills before the fetched ones
+		recent := !o.CreatedAt.Before(since)
+		if recent {
+			report.Orders++
+		}
+
+		remote := remoteFilled[id]
+		switch diff := remote - o.FilledSize.Float64(); {
+		case recent && diff > r.config.Tolerance:
+			report.add(r.applyFill(ctx, o, diff, remote))
+		case recent && diff < -r.config.Tolerance:
+			report.add(&Discrepancy{
+				Kind:          KindExcessFill,
+				OrderID:       o.ID,
+				ClientOrderID: id,
+				Symbol:        o.Symbol,
+				LocalSize:     o.FilledSize.Float64(),
+				RemoteSize:    remote,
+			})
+		case isOpen(o.Status) && !openClientIDs[id]:
+			report.add(r.expire(ctx, o))
+		}
+	}
+
+	if r.chain != nil {
+		txs, err := r.chain.GetTransactions(ctx, since)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get on-chain transactions: %w", err)
+		}
+		r.compareChain(report, txs, byClientID, settled)
+	}
+
+	report.Duration = time.Since(start)
+	r.record(report)
+	return report, nil
+}
+
+// applyFill applies the exchange fills missing locally. Fills are only
+// applied to orders the exchange has accepted, so they never move an order
+// backwards.
+func (r *Reconciler) applyFill(ctx context.Context, o *order.Order, missing, remote float64) *Discrepancy {
+	d := &Discrepancy{
+		Kind:          KindMissingFill,
+		OrderID:       o.ID,
+		ClientOrderID: clientID(o),
+		Symbol:        o.Symbol,
+		LocalSize:     o.FilledSize.Float64(),
+		RemoteSize:    remote,
+	}
+	if !r.config.AutoCorrect || !isOpen(o.Status) {
+		return d
+	}
+	fill := money.FromFloat(missing)
+	if fill.Cmp(o.RemainingSize) > 0 {
+		fill = o.RemainingSize
+	}
+	if err := r.orders.UpdateFilledSize(ctx, o.ID, fill); err != nil {
+		d.Error = err.Error()
+		return d
+	}
+	d.Corrected = true
+	return d
+}
+
+// expire marks a local open order missing on the exchange as expired
+func (r *Reconciler) expire(ctx context.Context, o *order.Order) *Discrepancy {
+	d := &Discrepancy{
+		Kind:          KindOrphanedOrder,
+		OrderID:       o.ID,
+		ClientOrderID: clientID(o),
+		Symbol:        o.Symbol,
+		LocalSize:     o.RemainingSize.Float64(),
+	}
+	if !r.config.AutoCorrect {
+		return d
+	}
+	if err := r.orders.UpdateOrderStatus(ctx, o.ID, order.Expired); err != nil {
+		d.Error = err.Error()
+		return d
+	}
+	d.Corrected = true
+	return d
+}
+
+// compareChain checks that the settled on-chain size of each order matches
+// its local fills. Chain discrepancies are never corrected automatically.
+func (r *Reconciler) compareChain(report *Report, txs []Transaction, byClientID map[string]*order.Order, settled time.Time) {
+	chainFilled := make(map[string]float64)
+	for _, tx := range txs {
+		if tx.Failed || tx.Time.After(settled) {
+			continue
+		}
+		chainFilled[tx.ClientOrderID] += tx.Size
+	}
+
+	ids := make([]string, 0, len(chainFilled))
+	for id := range chainFilled {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		d := &Discrepancy{Kind: KindChainMismatch, ClientOrderID: id, RemoteSize: chainFilled[id]}
+		if o, ok := byClientID[id]; ok {
+			if o.UpdatedAt.After(settled) || math.Abs(chainFilled[id]-o.FilledSize.Float64()) <= r.config.Tolerance {
+				continue
+			}
+			d.OrderID, d.Symbol, d.LocalSize = o.ID, o.Symbol, o.FilledSize.Float64()
+		}
+		report.add(d)
+	}
+}
+
+// clientID returns the client ID of an exchange order, fetching the order
+// the first time it is seen
+func (r *Reconciler) clientID(ctx context.Context, exchangeOrderID string) (string, error) {
+	if id, ok := r.clientIDs[exchangeOrderID]; ok {
+		return id, nil
+	}
+	ex, err := r.exchange.GetOrder(ctx, exchangeOrderID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get exchange order %s: %w", exchangeOrderID, err)
+	}
+	id := ex.ClientID
+	if id == "" {
+		id = ex.ID
+	}
+	r.clientIDs[exchangeOrderID] = id
+	return id, nil
+}
+
+// record publishes the report and alerts on the discrepancies that were not
+// corrected
+func (r *Reconciler) record(report *Report) {
+	r.mu.Lock()
+	r.last = report
+	r.mu.Unlock()
+
+	monitoring.RecordIndicatorCalculation("reconcile_trades", report.Duration)
+	monitoring.RecordIndicatorValue("reconciliation_discrepancies", float64(len(report.Discrepancies)))
+
+	for _, d := range report.Discrepancies {
+		severity := monitoring.SeverityWarning
+		message := "Trade discrepancy requires review"
+		if d.Corrected {
+			severity = monitoring.SeverityInfo
+			message = "Trade discrepancy corrected"
+		}
+		details := map[string]interface{}{
+			"kind":              string(d.Kind),
+			"order_id":          d.OrderID,
+			"client_order_id":   d.ClientOrderID,
+			"exchange_order_id": d.ExchangeOrderID,
+			"symbol":            d.Symbol,
+			"local_size":        d.LocalSize,
+			"remote_size":       d.RemoteSize,
+		}
+		if d.Error != "" {
+			details["error"] = d.Error
+		}
+		monitoring.RecordEvent(monitoring.Event{
+			Type:     EventType,
+			Severity: severity,
+			Message:  message,
+			Details:  details,
+		})
+	}
+}
+
+func (r *Report) add(d *Discrepancy) {
+	r.Discrepancies = append(r.Discrepancies, d)
+}
+
+// clientID returns the ID an order is submitted to the exchange with, as in
+// order.Order.ExchangeRequest
+func clientID(o *order.Order) string {
+	if o.ClientOrderID != "" {
+		return o.ClientOrderID
+	}
+	return o.ID
+}
+
+func isOpen(status order.OrderStatus) bool {
+	return status == order.Pending || status == order.PartiallyFilled
+}
@@ -0,0 +1,194 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/exchange/dydx"
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+	"github.com/devinjacknz/godydxhyber/backend/trading/order"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeExchange struct {
+	open   []dydx.Order
+	orders map[string]*dydx.Order
+	fills  []dydx.Fill
+}
+
+func (e *fakeExchange) GetOpenOrders(ctx context.Context) ([]dydx.Order, error) {
+	return e.open, nil
+}
+
+func (e *fakeExchange) GetOrder(ctx context.Context, orderID string) (*dydx.Order, error) {
+	o, ok := e.orders[orderID]
+	if !ok {
+		return nil, errors.New("order not found")
+	}
+	return o, nil
+}
+
+func (e *fakeExchange) GetFills(ctx context.Context, since time.Time) ([]dydx.Fill, error) {
+	return e.fills, nil
+}
+
+type fakeChain []Transaction
+
+func (c fakeChain) GetTransactions(ctx context.Context, since time.Time) ([]Transaction, error) {
+	return c, nil
+}
+
+func testConfig() Config {
+	config := DefaultConfig()
+	config.GracePeriod = 0
+	return config
+}
+
+func submit(t *testing.T, manager order.OrderManager, clientID string, size float64) *order.Order {
+	t.Helper()
+	o, err := manager.CreateOrder(context.Background(), order.CreateOrderParams{
+		Symbol: "BTC-USD", Type: order.Market, Side: order.Buy, Size: money.FromFloat(size), ClientOrderID: clientID,
+	})
+	require.NoError(t, err)
+	require.NoError(t, manager.UpdateOrderStatus(context.Background(), o.ID, order.Pending))
+	return o
+}
+
+func kinds(report *Report) map[Kind]*Discrepancy {
+	byKind := make(map[Kind]*Discrepancy)
+	for _, d := range report.Discrepancies {
+		byKind[d.Kind] = d
+	}
+	return byKind
+}
+
+func TestReconcile(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Missing fills are applied", func(t *testing.T) {
+		manager := order.NewOrderManager()
+		o := submit(t, manager, "c1", 2)
+		exchange := &fakeExchange{
+			open:  []dydx.Order{{ID: "x1", ClientID: "c1", Market: "BTC-USD", Size: 2}},
+			fills: []dydx.Fill{{ID: "f1", OrderID: "x1", Market: "BTC-USD", Size: 0.5}, {ID: "f2", OrderID: "x1", Market: "BTC-USD", Size: 0.25}},
+		}
+
+		report, err := NewReconciler(manager, exchange, testConfig()).Reconcile(ctx)
+		require.NoError(t, err)
+		require.Len(t, report.Discrepancies, 1)
+		d := report.Discrepancies[0]
+		assert.Equal(t, KindMissingFill, d.Kind)
+		assert.True(t, d.Corrected)
+		assert.Equal(t, 1, report.Corrected())
+
+		updated, err := manager.GetOrder(ctx, o.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 0.75, updated.Snapshot().FilledSize.Float64())
+		assert.Equal(t, order.PartiallyFilled, updated.Snapshot().Status)
+	})
+
+	t.Run("Orphaned orders are expired", func(t *testing.T) {
+		manager := order.NewOrderManager()
+		o := submit(t, manager, "c1", 1)
+
+		report, err := NewReconciler(manager, &fakeExchange{}, testConfig()).Reconcile(ctx)
+		require.NoError(t, err)
+		d := kinds(report)[KindOrphanedOrder]
+		require.NotNil(t, d)
+		assert.True(t, d.Corrected)
+
+		updated, err := manager.GetOrder(ctx, o.ID)
+		require.NoError(t, err)
+		assert.Equal(t, order.Expired, updated.Snapshot().Status)
+	})
+
+	t.Run("Unsafe discrepancies are only flagged", func(t *testing.T) {
+		manager := order.NewOrderManager()
+		o := submit(t, manager, "c1", 1)
+		require.NoError(t, manager.UpdateFilledSize(ctx, o.ID, money.NewFromInt(1)))
+		exchange := &fakeExchange{
+			open:   []dydx.Order{{ID: "x9", ClientID: "stranger", Market: "ETH-USD", Size: 3}},
+			orders: map[string]*dydx.Order{"x2": {ID: "x2", ClientID: "other"}},
+			fills:  []dydx.Fill{{ID: "f1", OrderID: "x2", Market: "ETH-USD", Size: 1}},
+		}
+
+		report, err := NewReconciler(manager, exchange, testConfig()).Reconcile(ctx)
+		require.NoError(t, err)
+		byKind := kinds(report)
+		require.Len(t, byKind, 3)
+		assert.Equal(t, "other", byKind[KindUnknownFill].ClientOrderID)
+		assert.Equal(t, "stranger", byKind[KindUnknownOrder].ClientOrderID)
+		assert.Equal(t, 1.0, byKind[KindExcessFill].LocalSize)
+		assert.Zero(t, report.Corrected())
+	})
+
+	t.Run("Recent changes are left to settle", func(t *testing.T) {
+		manager := order.NewOrderManager()
+		submit(t, manager, "c1", 1)
+
+		report, err := NewReconciler(manager, &fakeExchange{}, DefaultConfig()).Reconcile(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, report.Discrepancies)
+	})
+
+	t.Run("Auto-correct can be disabled", func(t *testing.T) {
+		manager := order.NewOrderManager()
+		o := submit(t, manager, "c1", 1)
+		config := testConfig()
+		config.AutoCorrect = false
+
+		report, err := NewReconciler(manager, &fakeExchange{}, config).Reconcile(ctx)
+		require.NoError(t, err)
+		require.Len(t, report.Discrepancies, 1)
+		assert.False(t, report.Discrepancies[0].Corrected)
+
+		updated, err := manager.GetOrder(ctx, o.ID)
+		require.NoError(t, err)
+		assert.Equal(t, order.Pending, updated.Snapshot().Status)
+	})
+
+	t.Run("On-chain settlements are compared", func(t *testing.T) {
+		manager := order.NewOrderManager()
+		o := submit(t, manager, "c1", 1)
+		require.NoError(t, manager.UpdateFilledSize(ctx, o.ID, money.NewFromInt(1)))
+		exchange := &fakeExchange{
+			orders: map[string]*dydx.Order{"x1": {ID: "x1", ClientID: "c1"}},
+			fills:  []dydx.Fill{{ID: "f1", OrderID: "x1", Size: 1}},
+		}
+		chain := fakeChain{
+			{Hash: "tx1", ClientOrderID: "c1", Size: 0.5},
+			{Hash: "tx2", ClientOrderID: "c1", Size: 0.5, Failed: true},
+			{Hash: "tx3", ClientOrderID: "ghost", Size: 2},
+		}
+
+		report, err := NewReconciler(manager, exchange, testConfig(), WithTransactions(chain)).Reconcile(ctx)
+		require.NoError(t, err)
+		require.Len(t, report.Discrepancies, 2)
+		assert.Equal(t, "c1", report.Discrepancies[0].ClientOrderID)
+		assert.Equal(t, 0.5, report.Discrepancies[0].RemoteSize)
+		assert.Equal(t, "ghost", report.Discrepancies[1].ClientOrderID)
+	})
+}
+
+func TestReconcileHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	reconciler := NewReconciler(order.NewOrderManager(), &fakeExchange{}, testConfig())
+	r := gin.New()
+	reconciler.RegisterRoutes(r.Group("/api/v1"))
+
+	call := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	assert.Equal(t, http.StatusNotFound, call(http.MethodGet, "/api/v1/reconciliation").Code)
+	assert.Equal(t, http.StatusOK, call(http.MethodPost, "/api/v1/reconciliation/run").Code)
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, "/api/v1/reconciliation").Code)
+}
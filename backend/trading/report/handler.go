@@ -0,0 +1,81 @@
+package report
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultRange is the range reports cover when the request sets no start
+const DefaultRange = 30 * 24 * time.Hour
+
+// Resolver selects the reporter a request reads from, such as the reporter
+// of the account in the path
+type Resolver func(c *gin.Context) (*Reporter, error)
+
+// RegisterRoutes registers the report HTTP endpoints
+func RegisterRoutes(r gin.IRouter, rep *Reporter) {
+	RegisterRoutesWithResolver(r, func(c *gin.Context) (*Reporter, error) {
+		return rep, nil
+	})
+}
+
+// RegisterRoutesWithResolver registers the report HTTP endpoints, reading
+// from the reporter returned by resolve for each request:
+//
+//	GET /reports/daily?from=&to=
+//	GET /equity?from=&to=
+//	GET /stats?from=&to=
+//
+// to defaults to now and from to DefaultRange before to, both RFC 3339
+// timestamps.
+func RegisterRoutesWithResolver(r gin.IRouter, resolve Resolver) {
+	r.GET("/reports/daily", resolved(resolve, func(rep *Reporter, c *gin.Context, from, to time.Time) (interface{}, error) {
+		days, err := rep.Daily(c.Request.Context(), from, to)
+		return gin.H{"days": days}, err
+	}))
+	r.GET("/equity", resolved(resolve, func(rep *Reporter, c *gin.Context, from, to time.Time) (interface{}, error) {
+		curve, err := rep.Equity(c.Request.Context(), from, to)
+		return gin.H{"equity": curve}, err
+	}))
+	r.GET("/stats", resolved(resolve, func(rep *Reporter, c *gin.Context, from, to time.Time) (interface{}, error) {
+		return rep.Stats(c.Request.Context(), from, to)
+	}))
+}
+
+func resolved(resolve Resolver, handler func(rep *Reporter, c *gin.Context, from, to time.Time) (interface{}, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rep, err := resolve(c)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		to := time.Now()
+		if v := c.Query("to"); v != "" {
+			if to, err = time.Parse(time.RFC3339Nano, v); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "to must be an RFC 3339 timestamp"})
+				return
+			}
+		}
+		from := to.Add(-DefaultRange)
+		if v := c.Query("from"); v != "" {
+			if from, err = time.Parse(time.RFC3339Nano, v); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "from must be an RFC 3339 timestamp"})
+				return
+			}
+		}
+
+		resp, err := handler(rep, c, from, to)
+		switch {
+		case errors.Is(err, ErrInvalidRange):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusOK, resp)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Package report computes the performance of an account from its daily risk
+// state: the daily profit and loss, the equity curve and aggregate stats.
+// Reports never include open positions or orders, so observer tokens may
+// read them.
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+	"github.com/devinjacknz/godydxhyber/backend/trading/risk"
+)
+
+// ErrInvalidRange is returned when the range does not end after it starts
+var ErrInvalidRange = errors.New("invalid time range")
+
+// DailySource reads the daily risk state of an account. risk.RiskManager
+// implements it.
+type DailySource interface {
+	GetDailyStates(ctx context.Context, from, to time.Time) ([]*risk.DailyState, error)
+}
+
+// Day is the performance of a UTC day with trading
+type Day struct {
+	Day         string  `json:"day"`
+	RealizedPnL float64 `json:"realized_pnl"`
+	Commissions float64 `json:"commissions"`
+	NetPnL      float64 `json:"net_pnl"`
+	Trades      int     `json:"trades"`
+}
+
+// EquityPoint is the cumulative net profit or loss at the end of a day
+type EquityPoint struct {
+	Day    string  `json:"day"`
+	Equity float64 `json:"equity"`
+	// Drawdown is the fall of the equity from its highest point so far
+	Drawdown float64 `json:"drawdown"`
+}
+
+// Stats aggregate the performance of a range
+type Stats struct {
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	Days        int       `json:"days"`
+	WinningDays int       `json:"winning_days"`
+	LosingDays  int       `json:"losing_days"`
+	Trades      int       `json:"trades"`
+	RealizedPnL float64   `json:"realized_pnl"`
+	Commissions float64   `json:"commissions"`
+	NetPnL      float64   `json:"net_pnl"`
+	BestDay     float64   `json:"best_day"`
+	WorstDay    float64   `json:"worst_day"`
+	MaxDrawdown float64   `json:"max_drawdown"`
+}
+
+// Reporter computes the reports of one account
+type Reporter struct {
+	source DailySource
+}
+
+// New creates a reporter reading the daily state of source
+func New(source DailySource) *Reporter {
+	return &Reporter{source: source}
+}
+
+// Daily returns the days with trading from the day of from to the day before
+// to, oldest first
+func (r *Reporter) Daily(ctx context.Context, from, to time.Time) ([]Day, error) {
+	if !to.After(from) {
+		return nil, ErrInvalidRange
+	}
+	// The day of to is only included when the range reaches into it
+	states, err := r.source.GetDailyStates(ctx, from, to.Add(-time.Nanosecond))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load daily states: %w", err)
+	}
+
+	days := make([]Day, 0, len(states))
+	for _, s := range states {
+		days = append(days, Day{
+			Day:         s.Day,
+			RealizedPnL: s.RealizedPnL,
+			Commissions: s.Commissions,
+			NetPnL:      s.NetPnL(),
+			Trades:      s.Trades,
+		})
+	}
+	return days, nil
+}
+
+// Equity returns the equity curve of the range, starting from zero at from
+func (r *Reporter) Equity(ctx context.Context, from, to time.Time) ([]EquityPoint, error) {
+	days, err := r.Daily(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return equityCurve(days), nil
+}
+
+// Stats returns the aggregate performance of the range
+func (r *Reporter) Stats(ctx context.Context, from, to time.Time) (*Stats, error) {
+	days, err := r.Daily(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &Stats{From: from, To: to, Days: len(days)}
+	for i, d := range days {
+		switch {
+		case d.NetPnL > 0:
+			stats.WinningDays++
+		case d.NetPnL < 0:
+			stats.LosingDays++
+		}
+		if i == 0 || d.NetPnL > stats.BestDay {
+			stats.BestDay = d.NetPnL
+		}
+		if i == 0 || d.NetPnL < stats.WorstDay {
+			stats.WorstDay = d.NetPnL
+		}
+		stats.Trades += d.Trades
+		stats.RealizedPnL = money.Add(stats.RealizedPnL, d.RealizedPnL)
+		stats.Commissions = money.Add(stats.Commissions, d.Commissions)
+	}
+	stats.NetPnL = money.Sub(stats.RealizedPnL, stats.Commissions)
+	for _, p := range equityCurve(days) {
+		stats.MaxDrawdown = math.Max(stats.MaxDrawdown, p.Drawdown)
+	}
+	return stats, nil
+}
+
+// equityCurve accumulates the net profit or loss of the days
+func equityCurve(days []Day) []EquityPoint {
+	curve := make([]EquityPoint, 0, len(days))
+	var equity, peak float64
+	for _, d := range days {
+		equity = money.Add(equity, d.NetPnL)
+		peak = math.Max(peak, equity)
+		curve = append(curve, EquityPoint{Day: d.Day, Equity: equity, Drawdown: money.Sub(peak, equity)})
+	}
+	return curve
+}
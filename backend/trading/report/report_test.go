@@ -0,0 +1,106 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/devinjacknz/godydxhyber/backend/trading/risk"
+)
+
+type fakeDaily struct {
+	states   []*risk.DailyState
+	from, to time.Time
+}
+
+func (s *fakeDaily) GetDailyStates(ctx context.Context, from, to time.Time) ([]*risk.DailyState, error) {
+	s.from, s.to = from, to
+	return s.states, nil
+}
+
+func TestReporter(t *testing.T) {
+	ctx := context.Background()
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 4)
+	source := &fakeDaily{states: []*risk.DailyState{
+		{Day: "2024-05-01", RealizedPnL: 100, Commissions: 10, Trades: 4},
+		{Day: "2024-05-02", RealizedPnL: -150, Commissions: 5, Trades: 3, LastTrade: map[string]time.Time{"BTC-USD": from}},
+		{Day: "2024-05-04", RealizedPnL: 200, Commissions: 10, Trades: 2},
+	}}
+	rep := New(source)
+
+	t.Run("Daily", func(t *testing.T) {
+		days, err := rep.Daily(ctx, from, to)
+		require.NoError(t, err)
+		require.Len(t, days, 3)
+		assert.Equal(t, Day{Day: "2024-05-02", RealizedPnL: -150, Commissions: 5, NetPnL: -155, Trades: 3}, days[1])
+		assert.Equal(t, from, source.from)
+		assert.Equal(t, "2024-05-04", source.to.Format("2006-01-02"), "the day of to is excluded")
+	})
+
+	t.Run("Equity", func(t *testing.T) {
+		curve, err := rep.Equity(ctx, from, to)
+		require.NoError(t, err)
+		assert.Equal(t, []EquityPoint{
+			{Day: "2024-05-01", Equity: 90},
+			{Day: "2024-05-02", Equity: -65, Drawdown: 155},
+			{Day: "2024-05-04", Equity: 125},
+		}, curve)
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		stats, err := rep.Stats(ctx, from, to)
+		require.NoError(t, err)
+		assert.Equal(t, 3, stats.Days)
+		assert.Equal(t, 2, stats.WinningDays)
+		assert.Equal(t, 1, stats.LosingDays)
+		assert.Equal(t, 9, stats.Trades)
+		assert.Equal(t, 125.0, stats.NetPnL)
+		assert.Equal(t, 190.0, stats.BestDay)
+		assert.Equal(t, -155.0, stats.WorstDay)
+		assert.Equal(t, 155.0, stats.MaxDrawdown)
+	})
+
+	t.Run("Invalid range", func(t *testing.T) {
+		_, err := rep.Stats(ctx, to, from)
+		assert.ErrorIs(t, err, ErrInvalidRange)
+	})
+}
+
+func TestHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	RegisterRoutes(r, New(&fakeDaily{states: []*risk.DailyState{
+		{Day: "2024-05-01", RealizedPnL: 100, Commissions: 10, Trades: 4, LastTrade: map[string]time.Time{"BTC-USD": time.Now()}},
+	}}))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := get("/reports/daily?from=2024-05-01T00:00:00Z&to=2024-05-02T00:00:00Z")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "BTC-USD", "reports do not reveal the traded symbols")
+
+	var resp struct {
+		Equity []EquityPoint `json:"equity"`
+	}
+	w = get("/equity")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []EquityPoint{{Day: "2024-05-01", Equity: 90}}, resp.Equity)
+
+	assert.Equal(t, http.StatusOK, get("/stats").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/stats?from=yesterday").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/stats?from=2024-05-02T00:00:00Z&to=2024-05-01T00:00:00Z").Code)
+}
@@ -0,0 +1,135 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/exchange/dydx"
+)
+
+// ErrNoAccountSource is returned by account checks when the manager has no
+// account source
+var ErrNoAccountSource = errors.New("no exchange account source")
+
+// AccountSource reads the state of the exchange account. dydx.Client
+// implements it.
+type AccountSource interface {
+	GetAccount(ctx context.Context) (*dydx.Account, error)
+	GetPositions(ctx context.Context) ([]dydx.Position, error)
+}
+
+// WithAccountSource computes exposure and risk metrics from the exchange
+// account instead of the caller's bookkeeping
+func WithAccountSource(source AccountSource) Option {
+	return func(m *DefaultRiskManager) {
+		m.accountSource = source
+	}
+}
+
+// ExposureSource reports the exposure and collateral of the whole portfolio
+// across venues. portfolio.Portfolio implements it.
+type ExposureSource interface {
+	PortfolioExposure(ctx context.Context) (exposure, collateral float64, err error)
+}
+
+// WithExposureSource measures the exposure of trades against the portfolio
+// of every venue instead of the exchange account alone
+func WithExposureSource(source ExposureSource) Option {
+	return func(m *DefaultRiskManager) {
+		m.exposureSource = source
+	}
+}
+
+// AccountState is the exposure of the exchange account
+type AccountState struct {
+	Equity         float64
+	FreeCollateral float64
+	// TotalExposure is the notional value of all positions at mark price
+	TotalExposure float64
+	// Exposures is the notional value of the position of each market
+	Exposures       map[string]float64
+	LargestPosition float64
+	UpdatedAt       time.Time
+}
+
+// accountState reads the account and its positions
+func (m *DefaultRiskManager) accountState(ctx context.Context) (*AccountState, error) {
+	if m.accountSource == nil {
+		return nil, ErrNoAccountSource
+	}
+
+	account, err := m.accountSource.GetAccount(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account: %w", err)
+	}
+	positions, err := m.accountSource.GetPositions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get positions: %w", err)
+	}
+
+	state := &AccountState{
+		Equity:         account.Equity,
+		FreeCollateral: account.FreeCollateral,
+		Exposures:      make(map[string]float64, len(positions)),
+		UpdatedAt:      time.Now(),
+	}
+	for _, p := range positions {
+		price := p.MarkPrice
+		if price == 0 {
+			price = p.EntryPrice
+		}
+		notional := math.Abs(p.Size) * price
+		state.Exposures[p.Market] += notional
+		state.TotalExposure += notional
+	}
+	for _, notional := range state.Exposures {
+		state.LargestPosition = math.Max(state.LargestPosition, notional)
+	}
+
+	m.mu.Lock()
+	m.peakEquity = math.Max(m.peakEquity, account.Equity)
+	m.mu.Unlock()
+	return state, nil
+}
+
+// GetAccountState returns the exposure of the exchange account
+func (m *DefaultRiskManager) GetAccountState(ctx context.Context) (*AccountState, error) {
+	return m.accountState(ctx)
+}
+
+// CheckAccountExposure checks if adding additionalAmount of notional to the
+// exchange account would exceed the exposure limit, measured against the
+// account equity
+func (m *DefaultRiskManager) CheckAccountExposure(ctx context.Context, additionalAmount float64) (*RiskCheck, error) {
+	state, err := m.accountState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return m.CheckExposureLimit(ctx, ExposureLimitParams{
+		TotalExposure:     state.TotalExposure,
+		AdditionalAmount:  additionalAmount,
+		CollateralBalance: state.Equity,
+	})
+}
+
+// checkPortfolioExposure checks if adding additionalAmount of notional to
+// the portfolio would exceed the exposure limit, measured against the
+// collateral of every venue. Without collateral there is nothing to measure
+// against and it returns ErrLimitNotSet.
+func (m *DefaultRiskManager) checkPortfolioExposure(ctx context.Context, additionalAmount float64) (*RiskCheck, error) {
+	exposure, collateral, err := m.exposureSource.PortfolioExposure(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get portfolio exposure: %w", err)
+	}
+	if collateral <= 0 {
+		return nil, ErrLimitNotSet
+	}
+	return m.CheckExposureLimit(ctx, ExposureLimitParams{
+		TotalExposure:     exposure,
+		AdditionalAmount:  additionalAmount,
+		CollateralBalance: collateral,
+	})
+}
@@ -0,0 +1,377 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/logger"
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+	"github.com/devinjacknz/godydxhyber/backend/trading/analysis/monitoring"
+)
+
+// ErrDailyStateNotFound is returned by a Store without state for a day
+var ErrDailyStateNotFound = errors.New("daily state not found")
+
+// dayFormat names the UTC day of a DailyState
+const dayFormat = "2006-01-02"
+
+// DailyState is the trading activity of one UTC day, the basis of the daily
+// loss limit and the trade throttles
+type DailyState struct {
+	Day         string  `json:"day"`
+	RealizedPnL float64 `json:"realized_pnl"`
+	// Commissions are the trading fees paid on the fills of the day
+	Commissions float64 `json:"commissions"`
+	Trades      int     `json:"trades"`
+	// LastTrade is the time of the last trade of each symbol
+	LastTrade map[string]time.Time `json:"last_trade"`
+	UpdatedAt time.Time            `json:"updated_at"`
+}
+
+// NetPnL returns the realized profit or loss of the day net of commissions
+func (s *DailyState) NetPnL() float64 {
+	return money.Sub(s.RealizedPnL, s.Commissions)
+}
+
+func (s *DailyState) clone() *DailyState {
+	c := *s
+	c.LastTrade = make(map[string]time.Time, len(s.LastTrade))
+	for symbol, t := range s.LastTrade {
+		c.LastTrade[symbol] = t
+	}
+	return &c
+}
+
+// Store persists the daily state so that restarts do not reset the daily
+// loss and trade counters
+type Store interface {
+	SaveDailyState(ctx context.Context, state *DailyState) error
+	// LoadDailyState returns the state of a day, formatted as 2006-01-02,
+	// or ErrDailyStateNotFound
+	LoadDailyState(ctx context.Context, day string) (*DailyState, error)
+}
+
+// WithStore persists the daily state through the given store
+func WithStore(store Store) Option {
+	return func(m *DefaultRiskManager) {
+		m.store = store
+	}
+}
+
+// MemoryStore keeps the daily state in memory
+type MemoryStore struct {
+	states map[string]*DailyState
+	mu     sync.RWMutex
+}
+
+// NewMemoryStore creates an in-memory daily state store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{states: make(map[string]*DailyState)}
+}
+
+// SaveDailyState inserts or replaces the state of a day
+func (s *MemoryStore) SaveDailyState(ctx context.Context, state *DailyState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[state.Day] = state.clone()
+	return nil
+}
+
+// LoadDailyState returns the state of a day
+func (s *MemoryStore) LoadDailyState(ctx context.Context, day string) (*DailyState, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	state, ok := s.states[day]
+	if !ok {
+		return nil, ErrDailyStateNotFound
+	}
+	return state.clone(), nil
+}
+
+// TradeRecord is a trade counted by the trade throttles
+type TradeRecord struct {
+	Symbol string
+	// Time is the time of the trade, now when zero
+	Time time.Time
+}
+
+// DailyLossParams contains parameters for the daily loss check
+type DailyLossParams struct {
+	// UnrealizedPnL is the unrealized profit or loss of the open positions,
+	// counted toward the daily loss when negative
+	UnrealizedPnL float64
+}
+
+// TradeFrequencyParams contains parameters for the trade frequency check
+type TradeFrequencyParams struct {
+	Symbol string
+}
+
+// RecordTrade counts a trade in the state of its day
+func (m *DefaultRiskManager) RecordTrade(ctx context.Context, trade TradeRecord) error {
+	if trade.Symbol == "" {
+		return ErrInvalidSymbol
+	}
+	if trade.Time.IsZero() {
+		trade.Time = m.now()
+	}
+	return m.updateDaily(ctx, trade.Time, func(state *DailyState) {
+		state.Trades++
+		if trade.Time.After(state.LastTrade[trade.Symbol]) {
+			state.LastTrade[trade.Symbol] = trade.Time
+		}
+	})
+}
+
+// RecordRealizedPnL adds realized profit or loss, negative for a loss, to the
+// state of the current day
+func (m *DefaultRiskManager) RecordRealizedPnL(ctx context.Context, pnl float64) error {
+	return m.updateDaily(ctx, m.now(), func(state *DailyState) {
+		state.RealizedPnL = money.Add(state.RealizedPnL, pnl)
+	})
+}
+
+// RecordCommission adds a trading fee, negative for a rebate, to the state of
+// the current day
+func (m *DefaultRiskManager) RecordCommission(ctx context.Context, fee float64) error {
+	return m.updateDaily(ctx, m.now(), func(state *DailyState) {
+		state.Commissions = money.Add(state.Commissions, fee)
+	})
+}
+
+// RestoreDailyState replaces the state of a day, such as with the state saved
+// in an engine snapshot before a restart. The state of a day before the
+// current one is saved without replacing it.
+func (m *DefaultRiskManager) RestoreDailyState(ctx context.Context, state *DailyState) error {
+	if _, err := time.Parse(dayFormat, state.Day); err != nil {
+		return fmt.Errorf("%w: %q", ErrInvalidDay, state.Day)
+	}
+	restored := state.clone()
+	if restored.LastTrade == nil {
+		restored.LastTrade = make(map[string]time.Time)
+	}
+
+	m.dailyMu.Lock()
+	defer m.dailyMu.Unlock()
+
+	if m.store != nil {
+		if err := m.store.SaveDailyState(ctx, restored); err != nil {
+			return fmt.Errorf("failed to save daily state: %w", err)
+		}
+	}
+	if m.daily == nil || restored.Day >= m.daily.Day {
+		m.daily = restored
+	}
+	return nil
+}
+
+// updateDaily applies a change to a copy of the state of the day of t and
+// saves it
+func (m *DefaultRiskManager) updateDaily(ctx context.Context, t time.Time, change func(state *DailyState)) error {
+	m.dailyMu.Lock()
+	defer m.dailyMu.Unlock()
+
+	state, err := m.dailyState(ctx, t)
+	if err != nil {
+		return err
+	}
+	next := state.clone()
+	change(next)
+	next.UpdatedAt = m.now()
+
+	if m.store != nil {
+		if err := m.store.SaveDailyState(ctx, next); err != nil {
+			return fmt.Errorf("failed to save daily state: %w", err)
+		}
+	}
+	// Changes of a past day are saved but do not replace the current state
+	if state == m.daily {
+		m.daily = next
+	}
+	monitoring.RecordIndicatorValue("daily_trades", float64(next.Trades))
+	monitoring.RecordIndicatorValue("daily_realized_pnl", next.RealizedPnL)
+	monitoring.RecordIndicatorValue("daily_commissions", next.Commissions)
+	return nil
+}
+
+// CheckDailyLoss checks if the realized loss of the day net of commissions,
+// with the unrealized loss of open positions, reaches the daily loss limit
+func (m *DefaultRiskManager) CheckDailyLoss(ctx context.Context, params DailyLossParams) (*RiskCheck, error) {
+	m.mu.RLock()
+	limit := m.dailyLossLimit
+	m.mu.RUnlock()
+	if limit <= 0 {
+		return nil, ErrLimitNotSet
+	}
+
+	m.dailyMu.Lock()
+	state, err := m.dailyState(ctx, m.now())
+	m.dailyMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	loss := -state.NetPnL()
+	if params.UnrealizedPnL < 0 {
+		loss -= params.UnrealizedPnL
+	}
+
+	check := &RiskCheck{
+		ID:            generateCheckID(),
+		CorrelationID: logger.CorrelationID(ctx),
+		AccountID:     m.accountID,
+		Type:          DailyLossRisk,
+		Value:         loss,
+		Threshold:     limit,
+		CreatedAt:     time.Now(),
+		Description:   "Daily loss check",
+	}
+
+	switch {
+	case loss >= limit:
+		check.Status = Violation
+		check.Level = Critical
+		monitoring.RecordIndicatorError("daily_loss", "Daily loss limit reached")
+		m.alert(ctx, check)
+		return check, ErrDailyLossLimitExceeded
+	case loss >= limit*0.8:
+		check.Status = Warning
+		check.Level = High
+	default:
+		check.Status = Pass
+		check.Level = Low
+	}
+
+	m.record(ctx, check)
+	return check, nil
+}
+
+// CheckTradeFrequency checks if another trade of a symbol stays within the
+// daily trade count and the cooldown since the last trade of the symbol
+func (m *DefaultRiskManager) CheckTradeFrequency(ctx context.Context, params TradeFrequencyParams) (*RiskCheck, error) {
+	if params.Symbol == "" {
+		return nil, ErrInvalidSymbol
+	}
+
+	m.mu.RLock()
+	maxTrades, cooldown := m.maxDailyTrades, m.tradeCooldown
+	m.mu.RUnlock()
+	if maxTrades <= 0 && cooldown <= 0 {
+		return nil, ErrLimitNotSet
+	}
+
+	now := m.now()
+	m.dailyMu.Lock()
+	state, err := m.dailyState(ctx, now)
+	m.dailyMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	check := &RiskCheck{
+		ID:            generateCheckID(),
+		CorrelationID: logger.CorrelationID(ctx),
+		AccountID:     m.accountID,
+		Type:          TradeFrequencyRisk,
+		Value:         float64(state.Trades),
+		Threshold:     float64(maxTrades),
+		Symbol:        params.Symbol,
+		CreatedAt:     time.Now(),
+		Description:   "Trade frequency check",
+	}
+
+	if last, ok := state.LastTrade[params.Symbol]; ok && cooldown > 0 {
+		if wait := cooldown - now.Sub(last); wait > 0 {
+			check.Status = Violation
+			check.Level = Medium
+			check.Description = fmt.Sprintf("Trade cooldown, %s remaining", wait.Round(time.Second))
+			m.alert(ctx, check)
+			return check, ErrTradeCooldown
+		}
+	}
+
+	switch {
+	case maxTrades > 0 && state.Trades >= maxTrades:
+		check.Status = Violation
+		check.Level = High
+		monitoring.RecordIndicatorError("trade_frequency", "Daily trade limit reached")
+		m.alert(ctx, check)
+		return check, ErrTradeLimitExceeded
+	case maxTrades > 0 && float64(state.Trades) >= float64(maxTrades)*0.8:
+		check.Status = Warning
+		check.Level = Medium
+	default:
+		check.Status = Pass
+		check.Level = Low
+	}
+
+	m.record(ctx, check)
+	return check, nil
+}
+
+// dailyState returns the state of the day of t, loading it from the store
+// when the day changes. Callers hold m.dailyMu.
+func (m *DefaultRiskManager) dailyState(ctx context.Context, t time.Time) (*DailyState, error) {
+	day := t.UTC().Format(dayFormat)
+	if m.daily != nil && m.daily.Day == day {
+		return m.daily, nil
+	}
+
+	state := &DailyState{Day: day, LastTrade: make(map[string]time.Time)}
+	if m.store != nil {
+		stored, err := m.store.LoadDailyState(ctx, day)
+		switch {
+		case err == nil:
+			state = stored
+			if state.LastTrade == nil {
+				state.LastTrade = make(map[string]time.Time)
+			}
+		case !errors.Is(err, ErrDailyStateNotFound):
+			return nil, fmt.Errorf("failed to load daily state: %w", err)
+		}
+	}
+	// Cooldowns run on across midnight
+	if m.daily != nil && day > m.daily.Day && len(state.LastTrade) == 0 {
+		for symbol, last := range m.daily.LastTrade {
+			state.LastTrade[symbol] = last
+		}
+	}
+
+	// Only the current day replaces the cached state
+	if m.daily == nil || day > m.daily.Day {
+		m.daily = state
+	}
+	return state, nil
+}
+
+// GetDailyStates returns the states of the UTC days from the day of from to
+// the day of to, oldest first. Days without trading are skipped.
+func (m *DefaultRiskManager) GetDailyStates(ctx context.Context, from, to time.Time) ([]*DailyState, error) {
+	m.dailyMu.Lock()
+	defer m.dailyMu.Unlock()
+
+	var states []*DailyState
+	last := to.UTC().Format(dayFormat)
+	for t := from.UTC(); t.Format(dayFormat) <= last; t = t.AddDate(0, 0, 1) {
+		day := t.Format(dayFormat)
+		if m.daily != nil && m.daily.Day == day {
+			states = append(states, m.daily.clone())
+			continue
+		}
+		if m.store == nil {
+			continue
+		}
+		state, err := m.store.LoadDailyState(ctx, day)
+		switch {
+		case errors.Is(err, ErrDailyStateNotFound):
+		case err != nil:
+			return nil, fmt.Errorf("failed to load daily state: %w", err)
+		default:
+			states = append(states, state)
+		}
+	}
+	return states, nil
+}
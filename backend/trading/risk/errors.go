@@ -32,4 +32,34 @@ var (
 
 	// ErrInvalidSymbol is returned when the symbol is invalid
 	ErrInvalidSymbol = errors.New("invalid symbol")
+
+	// ErrDailyLossLimitExceeded is returned when the loss of the day reaches
+	// the daily loss limit
+	ErrDailyLossLimitExceeded = errors.New("daily loss limit exceeded")
+
+	// ErrTradeLimitExceeded is returned when the trades of the day reach the
+	// daily trade count
+	ErrTradeLimitExceeded = errors.New("daily trade limit exceeded")
+
+	// ErrTradeCooldown is returned when a symbol traded within the cooldown
+	ErrTradeCooldown = errors.New("trade cooldown active")
+
+	// ErrInvalidTrade is returned when the side, size or price of a trade is
+	// invalid
+	ErrInvalidTrade = errors.New("invalid trade")
+
+	// ErrSymbolRejected is wrapped by screeners rejecting a symbol
+	ErrSymbolRejected = errors.New("symbol rejected by screening")
+
+	// ErrMaxPositionsReached is returned when a trade would open more
+	// positions than allowed
+	ErrMaxPositionsReached = errors.New("maximum open positions reached")
+
+	// ErrLeverageExceeded is returned when the leverage of a position exceeds
+	// the maximum leverage
+	ErrLeverageExceeded = errors.New("leverage limit exceeded")
+
+	// ErrInvalidDay is returned when a daily state does not name a day as
+	// 2006-01-02
+	ErrInvalidDay = errors.New("invalid day")
 )
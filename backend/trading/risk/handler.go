@@ -0,0 +1,140 @@
+package risk
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Resolver selects the risk manager a request acts on, such as the risk
+// manager of a trading account
+type Resolver func(c *gin.Context) (RiskManager, error)
+
+// RegisterRoutes registers the risk limit HTTP endpoints
+func RegisterRoutes(r gin.IRouter, m RiskManager) {
+	RegisterRoutesWithResolver(r, func(c *gin.Context) (RiskManager, error) {
+		return m, nil
+	})
+}
+
+// RegisterRoutesWithResolver registers the risk limit HTTP endpoints, acting
+// on the risk manager returned by resolve for each request
+func RegisterRoutesWithResolver(r gin.IRouter, resolve Resolver) {
+	r.GET("/risk/limits", resolved(resolve, handleGetLimits))
+	r.PUT("/risk/limits", resolved(resolve, handleUpdateLimits))
+	r.POST("/risk/validate", resolved(resolve, handleValidateTrade))
+}
+
+func resolved(resolve Resolver, handler func(RiskManager) gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		m, err := resolve(c)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		handler(m)(c)
+	}
+}
+
+var sides = map[string]Side{
+	"long":  Long,
+	"short": Short,
+}
+
+type validateTradeRequest struct {
+	Symbol        string  `json:"symbol"`
+	Side          string  `json:"side"`
+	Size          float64 `json:"size"`
+	Price         float64 `json:"price"`
+	Leverage      float64 `json:"leverage,omitempty"`
+	Netting       bool    `json:"netting,omitempty"`
+	Position      float64 `json:"position,omitempty"`
+	OpenPositions int     `json:"open_positions,omitempty"`
+	UnrealizedPnL float64 `json:"unrealized_pnl,omitempty"`
+}
+
+func handleGetLimits(m RiskManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limits, err := m.GetLimits(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, limits)
+	}
+}
+
+func handleUpdateLimits(m RiskManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var update LimitsUpdate
+		if err := c.ShouldBindJSON(&update); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		limits, err := m.UpdateLimits(c.Request.Context(), update)
+		switch {
+		case errors.Is(err, ErrInvalidLimit), errors.Is(err, ErrInvalidThresholds), errors.Is(err, ErrInvalidSymbol):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusOK, limits)
+		}
+	}
+}
+
+// handleValidateTrade runs the pre-trade checks on a trade without placing
+// it. A trade failing a check is answered with allowed set to false and the
+// reason.
+func handleValidateTrade(m RiskManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req validateTradeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		side, ok := sides[req.Side]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "side must be long or short"})
+			return
+		}
+
+		checks, err := m.ValidateTrade(c.Request.Context(), TradeParams{
+			Symbol:        req.Symbol,
+			Side:          side,
+			Size:          req.Size,
+			Price:         req.Price,
+			Leverage:      req.Leverage,
+			Netting:       req.Netting,
+			Position:      req.Position,
+			OpenPositions: req.OpenPositions,
+			UnrealizedPnL: req.UnrealizedPnL,
+		})
+		switch {
+		case errors.Is(err, ErrInvalidTrade), errors.Is(err, ErrInvalidSymbol):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case isRejection(err):
+			c.JSON(http.StatusOK, gin.H{"allowed": false, "reason": err.Error(), "checks": checks})
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusOK, gin.H{"allowed": true, "checks": checks})
+		}
+	}
+}
+
+// isRejection reports whether err rejects a trade rather than failing to
+// check it
+func isRejection(err error) bool {
+	for _, target := range []error{
+		ErrSymbolRejected, ErrMaxPositionsReached, ErrLeverageExceeded, ErrPositionLimitExceeded,
+		ErrExposureLimitExceeded, ErrDailyLossLimitExceeded, ErrTradeLimitExceeded, ErrTradeCooldown,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
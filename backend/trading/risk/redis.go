@@ -0,0 +1,58 @@
+package risk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// DefaultRedisKey is the hash RedisStore saves the daily state under
+const DefaultRedisKey = "gosol:risk_daily"
+
+// RedisStore saves the daily state in a Redis hash keyed by day, so the
+// daily loss and trade counters survive restarts
+type RedisStore struct {
+	client *redis.Client
+	key    string
+}
+
+// NewRedisStore creates a store saving the daily state under key, or
+// DefaultRedisKey when key is empty. Accounts sharing a Redis instance need
+// keys of their own.
+func NewRedisStore(client *redis.Client, key string) *RedisStore {
+	if key == "" {
+		key = DefaultRedisKey
+	}
+	return &RedisStore{client: client, key: key}
+}
+
+// SaveDailyState inserts or replaces the state of a day
+func (s *RedisStore) SaveDailyState(ctx context.Context, state *DailyState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode daily state: %w", err)
+	}
+	if err := s.client.HSet(ctx, s.key, state.Day, data).Err(); err != nil {
+		return fmt.Errorf("failed to save daily state to redis: %w", err)
+	}
+	return nil
+}
+
+// LoadDailyState returns the state of a day
+func (s *RedisStore) LoadDailyState(ctx context.Context, day string) (*DailyState, error) {
+	data, err := s.client.HGet(ctx, s.key, day).Bytes()
+	if err == redis.Nil {
+		return nil, ErrDailyStateNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load daily state from redis: %w", err)
+	}
+
+	var state DailyState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to decode daily state of %s: %w", day, err)
+	}
+	return &state, nil
+}
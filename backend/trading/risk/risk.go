@@ -2,10 +2,13 @@ package risk
 
 import (
 	"context"
+	"log/slog"
+	"math"
 	"sync"
 	"time"
 
-"github.com/devinjacknz/godydxhyber/backend/trading/analysis/monitoring"
+	"github.com/devinjacknz/godydxhyber/backend/logger"
+	"github.com/devinjacknz/godydxhyber/backend/trading/analysis/monitoring"
 )
 
 // RiskLevel represents the risk level
@@ -27,8 +30,45 @@ const (
 	DrawdownRisk
 	VolatilityRisk
 	LiquidityRisk
+	DailyLossRisk
+	TradeFrequencyRisk
+	PortfolioRisk
 )
 
+var riskLevelNames = map[RiskLevel]string{
+	Low:      "low",
+	Medium:   "medium",
+	High:     "high",
+	Critical: "critical",
+}
+
+var riskTypeNames = map[RiskType]string{
+	PositionRisk:       "position",
+	ExposureRisk:       "exposure",
+	DrawdownRisk:       "drawdown",
+	VolatilityRisk:     "volatility",
+	LiquidityRisk:      "liquidity",
+	DailyLossRisk:      "daily_loss",
+	TradeFrequencyRisk: "trade_frequency",
+	PortfolioRisk:      "portfolio",
+}
+
+// String returns the name of the risk level
+func (l RiskLevel) String() string {
+	if name, ok := riskLevelNames[l]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// String returns the name of the risk type
+func (t RiskType) String() string {
+	if name, ok := riskTypeNames[t]; ok {
+		return name
+	}
+	return "unknown"
+}
+
 // RiskStatus represents the status of a risk check
 type RiskStatus int
 
@@ -38,6 +78,20 @@ const (
 	Violation
 )
 
+var riskStatusNames = map[RiskStatus]string{
+	Pass:      "pass",
+	Warning:   "warning",
+	Violation: "violation",
+}
+
+// String returns the name of the risk status
+func (s RiskStatus) String() string {
+	if name, ok := riskStatusNames[s]; ok {
+		return name
+	}
+	return "unknown"
+}
+
 // RiskCheck represents a risk check result
 type RiskCheck struct {
 	ID          string
@@ -49,6 +103,10 @@ type RiskCheck struct {
 	Symbol      string
 	CreatedAt   time.Time
 	Description string
+	// CorrelationID is the correlation ID of the context of the check
+	CorrelationID string
+	// AccountID is the trading account whose limits were checked
+	AccountID string
 }
 
 // RiskManager interface defines the contract for risk management
@@ -69,6 +127,29 @@ type RiskManager interface {
 	CheckVolatility(ctx context.Context, params VolatilityParams) (*RiskCheck, error)
 	UpdateVolatilityThresholds(ctx context.Context, params VolatilityThresholds) error
 
+	// Limits
+	GetLimits(ctx context.Context) (*Limits, error)
+	UpdateLimits(ctx context.Context, update LimitsUpdate) (*Limits, error)
+
+	// Exchange account
+	GetAccountState(ctx context.Context) (*AccountState, error)
+	CheckAccountExposure(ctx context.Context, additionalAmount float64) (*RiskCheck, error)
+
+	// Daily loss limit and trade throttles
+	RecordTrade(ctx context.Context, trade TradeRecord) error
+	RecordRealizedPnL(ctx context.Context, pnl float64) error
+	RecordCommission(ctx context.Context, fee float64) error
+	CheckDailyLoss(ctx context.Context, params DailyLossParams) (*RiskCheck, error)
+	CheckTradeFrequency(ctx context.Context, params TradeFrequencyParams) (*RiskCheck, error)
+	GetDailyStates(ctx context.Context, from, to time.Time) ([]*DailyState, error)
+	RestoreDailyState(ctx context.Context, state *DailyState) error
+
+	// Pre-trade validation, position sizing and stop levels
+	ValidateTrade(ctx context.Context, params TradeParams) ([]*RiskCheck, error)
+	CheckPortfolio(ctx context.Context, params PortfolioParams) (*RiskCheck, error)
+	CalculatePositionSize(ctx context.Context, params SizingParams) (float64, error)
+	CalculateStopLevels(ctx context.Context, params StopLevelParams) (*StopLevels, error)
+
 	// Risk metrics
 	GetRiskMetrics(ctx context.Context) (*RiskMetrics, error)
 	GetRiskHistory(ctx context.Context, filter RiskHistoryFilter) ([]*RiskCheck, error)
@@ -80,6 +161,9 @@ type PositionLimitParams struct {
 	Size          float64
 	CurrentPrice  float64
 	TotalPosition float64
+	// Netting checks the resulting net position instead of the gross one.
+	// Size and TotalPosition are then signed: positive long, negative short.
+	Netting bool
 }
 
 // ExposureLimitParams contains parameters for exposure limit check
@@ -106,10 +190,51 @@ type VolatilityParams struct {
 
 // VolatilityThresholds contains thresholds for volatility levels
 type VolatilityThresholds struct {
-	LowThreshold      float64
-	MediumThreshold   float64
-	HighThreshold     float64
-	CriticalThreshold float64
+	LowThreshold      float64 `json:"low_threshold"`
+	MediumThreshold   float64 `json:"medium_threshold"`
+	HighThreshold     float64 `json:"high_threshold"`
+	CriticalThreshold float64 `json:"critical_threshold"`
+}
+
+// Limits contains the configured risk limits. A zero daily loss limit,
+// daily trade count, trade cooldown, open position count or leverage is not
+// enforced, and zero sizing and stop distances are not applied.
+type Limits struct {
+	PositionLimits       map[string]float64   `json:"position_limits"`
+	ExposureLimit        float64              `json:"exposure_limit"`
+	DrawdownLimit        float64              `json:"drawdown_limit"`
+	VolatilityThresholds VolatilityThresholds `json:"volatility_thresholds"`
+	DailyLossLimit       float64              `json:"daily_loss_limit"`
+	MaxDailyTrades       int                  `json:"max_daily_trades"`
+	// TradeCooldown is the minimum time between two trades of a symbol, in
+	// nanoseconds in JSON
+	TradeCooldown    time.Duration `json:"trade_cooldown"`
+	MaxOpenPositions int           `json:"max_open_positions"`
+	MaxLeverage      float64       `json:"max_leverage"`
+	// RiskPerTrade is the fraction of the equity risked by a trade between
+	// its entry and its stop loss, from 0 to 1
+	RiskPerTrade float64 `json:"risk_per_trade"`
+	// StopLoss and TakeProfit are the default distances of the stop levels
+	// from the entry price, from 0 to 1
+	StopLoss   float64 `json:"stop_loss"`
+	TakeProfit float64 `json:"take_profit"`
+}
+
+// LimitsUpdate contains the risk limits to change. Nil fields are left as is
+// and position limits are set per symbol.
+type LimitsUpdate struct {
+	PositionLimits       map[string]float64    `json:"position_limits,omitempty"`
+	ExposureLimit        *float64              `json:"exposure_limit,omitempty"`
+	DrawdownLimit        *float64              `json:"drawdown_limit,omitempty"`
+	VolatilityThresholds *VolatilityThresholds `json:"volatility_thresholds,omitempty"`
+	DailyLossLimit       *float64              `json:"daily_loss_limit,omitempty"`
+	MaxDailyTrades       *int                  `json:"max_daily_trades,omitempty"`
+	TradeCooldown        *time.Duration        `json:"trade_cooldown,omitempty"`
+	MaxOpenPositions     *int                  `json:"max_open_positions,omitempty"`
+	MaxLeverage          *float64              `json:"max_leverage,omitempty"`
+	RiskPerTrade         *float64              `json:"risk_per_trade,omitempty"`
+	StopLoss             *float64              `json:"stop_loss,omitempty"`
+	TakeProfit           *float64              `json:"take_profit,omitempty"`
 }
 
 // RiskMetrics contains current risk metrics
@@ -134,17 +259,59 @@ type RiskHistoryFilter struct {
 
 // DefaultRiskManager implements RiskManager interface
 type DefaultRiskManager struct {
+	accountID            string
 	positionLimits       map[string]float64
 	exposureLimit        float64
 	drawdownLimit        float64
 	volatilityThresholds VolatilityThresholds
 	riskChecks           []*RiskCheck
-	mu                   sync.RWMutex
+	alertListener        AlertListener
+	accountSource        AccountSource
+	exposureSource       ExposureSource
+	dailyLossLimit       float64
+	maxDailyTrades       int
+	tradeCooldown        time.Duration
+	maxOpenPositions     int
+	maxLeverage          float64
+	riskPerTrade         float64
+	stopLoss             float64
+	takeProfit           float64
+	screener             Screener
+	// peakEquity is the highest account equity seen
+	peakEquity float64
+	mu         sync.RWMutex
+
+	// store persists daily, the state of the current day
+	store   Store
+	daily   *DailyState
+	dailyMu sync.Mutex
+	now     func() time.Time
+}
+
+// AlertListener is notified of every risk check that did not pass. It must
+// not block or call back into the manager.
+type AlertListener func(check *RiskCheck)
+
+// Option configures a DefaultRiskManager
+type Option func(*DefaultRiskManager)
+
+// WithAlertListener notifies the listener of every warning and violation
+func WithAlertListener(listener AlertListener) Option {
+	return func(m *DefaultRiskManager) {
+		m.alertListener = listener
+	}
+}
+
+// WithAccountID sets the trading account whose limits the manager checks
+func WithAccountID(accountID string) Option {
+	return func(m *DefaultRiskManager) {
+		m.accountID = accountID
+	}
 }
 
 // NewRiskManager creates a new risk manager instance
-func NewRiskManager() RiskManager {
-	return &DefaultRiskManager{
+func NewRiskManager(opts ...Option) RiskManager {
+	m := &DefaultRiskManager{
 		positionLimits: make(map[string]float64),
 		exposureLimit:  1000000.0, // Default 1M
 		drawdownLimit:  0.25,      // Default 25%
@@ -155,7 +322,12 @@ func NewRiskManager() RiskManager {
 			CriticalThreshold: 0.75,
 		},
 		riskChecks: make([]*RiskCheck, 0),
+		now:        time.Now,
 	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
 }
 
 // CheckPositionLimit checks if a position exceeds the limit
@@ -174,17 +346,21 @@ func (m *DefaultRiskManager) CheckPositionLimit(ctx context.Context, params Posi
 		return nil, ErrLimitNotSet
 	}
 
-	totalExposure := params.TotalPosition * params.CurrentPrice
-	newExposure := params.Size * params.CurrentPrice
+	resulting := params.TotalPosition + params.Size
+	if params.Netting {
+		resulting = math.Abs(resulting)
+	}
 
 	check := &RiskCheck{
-		ID:          generateCheckID(),
-		Type:        PositionRisk,
-		Value:       totalExposure + newExposure,
-		Threshold:   limit,
-		Symbol:      params.Symbol,
-		CreatedAt:   time.Now(),
-		Description: "Position limit check",
+		ID:            generateCheckID(),
+		CorrelationID: logger.CorrelationID(ctx),
+		AccountID:     m.accountID,
+		Type:          PositionRisk,
+		Value:         resulting * params.CurrentPrice,
+		Threshold:     limit,
+		Symbol:        params.Symbol,
+		CreatedAt:     time.Now(),
+		Description:   "Position limit check",
 	}
 
 	if check.Value >= limit*0.9 && check.Value < limit {
@@ -194,15 +370,14 @@ func (m *DefaultRiskManager) CheckPositionLimit(ctx context.Context, params Posi
 		check.Status = Violation
 		check.Level = Critical
 		monitoring.RecordIndicatorError("position_limit", "Position limit exceeded")
+		m.alert(ctx, check)
 		return check, ErrPositionLimitExceeded
 	} else {
 		check.Status = Pass
 		check.Level = Low
 	}
 
-	m.mu.Lock()
-	m.riskChecks = append(m.riskChecks, check)
-	m.mu.Unlock()
+	m.record(ctx, check)
 
 	monitoring.RecordIndicatorValue("position_utilization", check.Value/check.Threshold)
 	return check, nil
@@ -238,12 +413,14 @@ func (m *DefaultRiskManager) CheckExposureLimit(ctx context.Context, params Expo
 	maxExposure := params.CollateralBalance * limit
 
 	check := &RiskCheck{
-		ID:          generateCheckID(),
-		Type:        ExposureRisk,
-		Value:       totalExposure,
-		Threshold:   maxExposure,
-		CreatedAt:   time.Now(),
-		Description: "Exposure limit check",
+		ID:            generateCheckID(),
+		CorrelationID: logger.CorrelationID(ctx),
+		AccountID:     m.accountID,
+		Type:          ExposureRisk,
+		Value:         totalExposure,
+		Threshold:     maxExposure,
+		CreatedAt:     time.Now(),
+		Description:   "Exposure limit check",
 	}
 
 	if totalExposure >= maxExposure*0.9 && totalExposure < maxExposure {
@@ -253,15 +430,14 @@ func (m *DefaultRiskManager) CheckExposureLimit(ctx context.Context, params Expo
 		check.Status = Violation
 		check.Level = Critical
 		monitoring.RecordIndicatorError("exposure_limit", "Exposure limit exceeded")
+		m.alert(ctx, check)
 		return check, ErrExposureLimitExceeded
 	} else {
 		check.Status = Pass
 		check.Level = Low
 	}
 
-	m.mu.Lock()
-	m.riskChecks = append(m.riskChecks, check)
-	m.mu.Unlock()
+	m.record(ctx, check)
 
 	monitoring.RecordIndicatorValue("exposure_utilization", check.Value/check.Threshold)
 	return check, nil
@@ -296,12 +472,14 @@ func (m *DefaultRiskManager) CheckDrawdown(ctx context.Context, params DrawdownP
 	drawdown := (params.PeakEquity - params.CurrentEquity) / params.PeakEquity
 
 	check := &RiskCheck{
-		ID:          generateCheckID(),
-		Type:        DrawdownRisk,
-		Value:       drawdown,
-		Threshold:   limit,
-		CreatedAt:   time.Now(),
-		Description: "Drawdown check",
+		ID:            generateCheckID(),
+		CorrelationID: logger.CorrelationID(ctx),
+		AccountID:     m.accountID,
+		Type:          DrawdownRisk,
+		Value:         drawdown,
+		Threshold:     limit,
+		CreatedAt:     time.Now(),
+		Description:   "Drawdown check",
 	}
 
 	if drawdown >= limit*0.8 && drawdown < limit {
@@ -311,15 +489,14 @@ func (m *DefaultRiskManager) CheckDrawdown(ctx context.Context, params DrawdownP
 		check.Status = Violation
 		check.Level = Critical
 		monitoring.RecordIndicatorError("drawdown", "Maximum drawdown exceeded")
+		m.alert(ctx, check)
 		return check, ErrDrawdownLimitExceeded
 	} else {
 		check.Status = Pass
 		check.Level = Low
 	}
 
-	m.mu.Lock()
-	m.riskChecks = append(m.riskChecks, check)
-	m.mu.Unlock()
+	m.record(ctx, check)
 
 	monitoring.RecordIndicatorValue("drawdown", drawdown)
 	return check, nil
@@ -352,12 +529,14 @@ func (m *DefaultRiskManager) CheckVolatility(ctx context.Context, params Volatil
 	m.mu.RUnlock()
 
 	check := &RiskCheck{
-		ID:          generateCheckID(),
-		Type:        VolatilityRisk,
-		Value:       params.CurrentVolatility,
-		Symbol:      params.Symbol,
-		CreatedAt:   time.Now(),
-		Description: "Volatility check",
+		ID:            generateCheckID(),
+		CorrelationID: logger.CorrelationID(ctx),
+		AccountID:     m.accountID,
+		Type:          VolatilityRisk,
+		Value:         params.CurrentVolatility,
+		Symbol:        params.Symbol,
+		CreatedAt:     time.Now(),
+		Description:   "Volatility check",
 	}
 
 	switch {
@@ -366,6 +545,7 @@ func (m *DefaultRiskManager) CheckVolatility(ctx context.Context, params Volatil
 		check.Level = Critical
 		check.Threshold = thresholds.CriticalThreshold
 		monitoring.RecordIndicatorError("volatility", "Critical volatility level")
+		m.alert(ctx, check)
 		return check, ErrVolatilityTooHigh
 	case params.CurrentVolatility >= thresholds.HighThreshold:
 		check.Status = Warning
@@ -381,9 +561,7 @@ func (m *DefaultRiskManager) CheckVolatility(ctx context.Context, params Volatil
 		check.Threshold = thresholds.LowThreshold
 	}
 
-	m.mu.Lock()
-	m.riskChecks = append(m.riskChecks, check)
-	m.mu.Unlock()
+	m.record(ctx, check)
 
 	monitoring.RecordIndicatorValue("volatility_"+params.Symbol, params.CurrentVolatility)
 	return check, nil
@@ -402,16 +580,191 @@ func (m *DefaultRiskManager) UpdateVolatilityThresholds(ctx context.Context, thr
 	return nil
 }
 
-// GetRiskMetrics returns current risk metrics
-func (m *DefaultRiskManager) GetRiskMetrics(ctx context.Context) (*RiskMetrics, error) {
+// record adds a check to the history and alerts on warnings. Violations are
+// returned as errors instead of being recorded and alert on their own.
+func (m *DefaultRiskManager) record(ctx context.Context, check *RiskCheck) {
+	m.mu.Lock()
+	m.riskChecks = append(m.riskChecks, check)
+	m.mu.Unlock()
+
+	if check.Status != Pass {
+		m.alert(ctx, check)
+	}
+}
+
+// alert logs a check that did not pass and notifies the alert listener
+func (m *DefaultRiskManager) alert(ctx context.Context, check *RiskCheck) {
+	level := slog.LevelWarn
+	if check.Status == Violation {
+		level = slog.LevelError
+	}
+	slog.Log(ctx, level, "risk check failed",
+		"check_id", check.ID,
+		"type", check.Type.String(),
+		"symbol", check.Symbol,
+		"value", check.Value,
+		"threshold", check.Threshold)
+
+	if m.alertListener != nil {
+		c := *check
+		m.alertListener(&c)
+	}
+}
+
+// GetLimits returns the configured risk limits
+func (m *DefaultRiskManager) GetLimits(ctx context.Context) (*Limits, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	// Implementation would calculate metrics from current state
-	// This is a placeholder implementation
-	return &RiskMetrics{
-		UpdatedAt: time.Now(),
-	}, nil
+	return m.limits(), nil
+}
+
+// UpdateLimits validates every change before applying any, so a rejected
+// update leaves all limits unchanged
+func (m *DefaultRiskManager) UpdateLimits(ctx context.Context, update LimitsUpdate) (*Limits, error) {
+	for symbol, limit := range update.PositionLimits {
+		if symbol == "" {
+			return nil, ErrInvalidSymbol
+		}
+		if limit <= 0 {
+			return nil, ErrInvalidLimit
+		}
+	}
+	if update.ExposureLimit != nil && *update.ExposureLimit <= 0 {
+		return nil, ErrInvalidLimit
+	}
+	if update.DrawdownLimit != nil && (*update.DrawdownLimit <= 0 || *update.DrawdownLimit >= 1) {
+		return nil, ErrInvalidLimit
+	}
+	if update.VolatilityThresholds != nil && !isValidThresholds(*update.VolatilityThresholds) {
+		return nil, ErrInvalidThresholds
+	}
+	if update.DailyLossLimit != nil && *update.DailyLossLimit < 0 {
+		return nil, ErrInvalidLimit
+	}
+	if update.MaxDailyTrades != nil && *update.MaxDailyTrades < 0 {
+		return nil, ErrInvalidLimit
+	}
+	if update.TradeCooldown != nil && *update.TradeCooldown < 0 {
+		return nil, ErrInvalidLimit
+	}
+	if update.MaxOpenPositions != nil && *update.MaxOpenPositions < 0 {
+		return nil, ErrInvalidLimit
+	}
+	if update.MaxLeverage != nil && *update.MaxLeverage < 0 {
+		return nil, ErrInvalidLimit
+	}
+	for _, fraction := range []*float64{update.RiskPerTrade, update.StopLoss, update.TakeProfit} {
+		if fraction != nil && (*fraction < 0 || *fraction >= 1) {
+			return nil, ErrInvalidLimit
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for symbol, limit := range update.PositionLimits {
+		m.positionLimits[symbol] = limit
+		monitoring.RecordIndicatorValue("position_limit_"+symbol, limit)
+	}
+	if update.ExposureLimit != nil {
+		m.exposureLimit = *update.ExposureLimit
+		monitoring.RecordIndicatorValue("exposure_limit", m.exposureLimit)
+	}
+	if update.DrawdownLimit != nil {
+		m.drawdownLimit = *update.DrawdownLimit
+		monitoring.RecordIndicatorValue("drawdown_limit", m.drawdownLimit)
+	}
+	if update.VolatilityThresholds != nil {
+		m.volatilityThresholds = *update.VolatilityThresholds
+	}
+	if update.DailyLossLimit != nil {
+		m.dailyLossLimit = *update.DailyLossLimit
+		monitoring.RecordIndicatorValue("daily_loss_limit", m.dailyLossLimit)
+	}
+	if update.MaxDailyTrades != nil {
+		m.maxDailyTrades = *update.MaxDailyTrades
+	}
+	if update.TradeCooldown != nil {
+		m.tradeCooldown = *update.TradeCooldown
+	}
+	if update.MaxOpenPositions != nil {
+		m.maxOpenPositions = *update.MaxOpenPositions
+	}
+	if update.MaxLeverage != nil {
+		m.maxLeverage = *update.MaxLeverage
+	}
+	if update.RiskPerTrade != nil {
+		m.riskPerTrade = *update.RiskPerTrade
+	}
+	if update.StopLoss != nil {
+		m.stopLoss = *update.StopLoss
+	}
+	if update.TakeProfit != nil {
+		m.takeProfit = *update.TakeProfit
+	}
+
+	return m.limits(), nil
+}
+
+// limits returns a copy of the configured limits. Callers hold m.mu.
+func (m *DefaultRiskManager) limits() *Limits {
+	positionLimits := make(map[string]float64, len(m.positionLimits))
+	for symbol, limit := range m.positionLimits {
+		positionLimits[symbol] = limit
+	}
+	return &Limits{
+		PositionLimits:       positionLimits,
+		ExposureLimit:        m.exposureLimit,
+		DrawdownLimit:        m.drawdownLimit,
+		VolatilityThresholds: m.volatilityThresholds,
+		DailyLossLimit:       m.dailyLossLimit,
+		MaxDailyTrades:       m.maxDailyTrades,
+		TradeCooldown:        m.tradeCooldown,
+		MaxOpenPositions:     m.maxOpenPositions,
+		MaxLeverage:          m.maxLeverage,
+		RiskPerTrade:         m.riskPerTrade,
+		StopLoss:             m.stopLoss,
+		TakeProfit:           m.takeProfit,
+	}
+}
+
+// GetRiskMetrics returns current risk metrics. Without an account source
+// only the update time is set.
+func (m *DefaultRiskManager) GetRiskMetrics(ctx context.Context) (*RiskMetrics, error) {
+	if m.accountSource == nil {
+		return &RiskMetrics{
+			UpdatedAt: time.Now(),
+		}, nil
+	}
+
+	state, err := m.accountState(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	peak, limit := m.peakEquity, m.exposureLimit
+	m.mu.RUnlock()
+
+	metrics := &RiskMetrics{
+		TotalExposure:   state.TotalExposure,
+		LargestPosition: state.LargestPosition,
+		RiskLevel:       Low,
+		UpdatedAt:       state.UpdatedAt,
+	}
+	if peak > 0 {
+		metrics.CurrentDrawdown = (peak - state.Equity) / peak
+	}
+	// Levels follow the warning and violation bands of the exposure check
+	switch maxExposure := state.Equity * limit; {
+	case state.TotalExposure == 0:
+	case state.TotalExposure >= maxExposure:
+		metrics.RiskLevel = Critical
+	case state.TotalExposure >= maxExposure*0.9:
+		metrics.RiskLevel = High
+	}
+	return metrics, nil
 }
 
 // GetRiskHistory returns risk check history based on filter
@@ -2,10 +2,14 @@ package risk
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/devinjacknz/godydxhyber/backend/exchange/dydx"
 )
 
 func TestRiskManager(t *testing.T) {
@@ -45,6 +49,25 @@ func TestRiskManager(t *testing.T) {
 		assert.Equal(t, Violation, check.Status)
 		assert.Equal(t, Critical, check.Level)
 
+		// Test netting: an opposite order reduces the net position
+		params = PositionLimitParams{
+			Symbol:        "BTC/USD",
+			Size:          -10.0,
+			CurrentPrice:  50000.0,
+			TotalPosition: 25.0,
+			Netting:       true,
+		}
+		check, err = manager.CheckPositionLimit(ctx, params)
+		assert.NoError(t, err)
+		assert.Equal(t, Pass, check.Status)
+		assert.Equal(t, 750000.0, check.Value)
+
+		// Test netting: a flip is measured by the size of the new position
+		params.Size = -50.0
+		check, err = manager.CheckPositionLimit(ctx, params)
+		assert.Equal(t, ErrPositionLimitExceeded, err)
+		assert.Equal(t, 1250000.0, check.Value)
+
 		// Test invalid limit
 		err = manager.UpdatePositionLimit(ctx, "ETH/USD", -1.0)
 		assert.Error(t, err)
@@ -304,3 +327,337 @@ func TestConcurrency(t *testing.T) {
 		}
 	})
 }
+
+func TestRiskLimits(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Update applies all changes", func(t *testing.T) {
+		manager := NewRiskManager()
+		exposure := 500000.0
+		limits, err := manager.UpdateLimits(ctx, LimitsUpdate{
+			PositionLimits: map[string]float64{"BTC/USD": 100000},
+			ExposureLimit:  &exposure,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 100000.0, limits.PositionLimits["BTC/USD"])
+		assert.Equal(t, exposure, limits.ExposureLimit)
+		assert.Equal(t, 0.25, limits.DrawdownLimit)
+
+		got, err := manager.GetLimits(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, limits, got)
+	})
+
+	t.Run("Invalid update changes nothing", func(t *testing.T) {
+		manager := NewRiskManager()
+		exposure := 500000.0
+		drawdown := 1.5
+		_, err := manager.UpdateLimits(ctx, LimitsUpdate{ExposureLimit: &exposure, DrawdownLimit: &drawdown})
+		assert.Equal(t, ErrInvalidLimit, err)
+
+		_, err = manager.UpdateLimits(ctx, LimitsUpdate{VolatilityThresholds: &VolatilityThresholds{LowThreshold: 0.5, MediumThreshold: 0.1}})
+		assert.Equal(t, ErrInvalidThresholds, err)
+
+		limits, err := manager.GetLimits(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 1000000.0, limits.ExposureLimit)
+	})
+}
+
+func TestRiskAlerts(t *testing.T) {
+	ctx := context.Background()
+	var alerts []*RiskCheck
+	manager := NewRiskManager(WithAlertListener(func(check *RiskCheck) {
+		alerts = append(alerts, check)
+	}))
+
+	_, err := manager.CheckDrawdown(ctx, DrawdownParams{CurrentEquity: 95000, PeakEquity: 100000, TimeWindow: time.Hour})
+	assert.NoError(t, err)
+	assert.Empty(t, alerts)
+
+	_, err = manager.CheckDrawdown(ctx, DrawdownParams{CurrentEquity: 70000, PeakEquity: 100000, TimeWindow: time.Hour})
+	assert.Error(t, err)
+	assert.Len(t, alerts, 1)
+	assert.Equal(t, DrawdownRisk, alerts[0].Type)
+	assert.Equal(t, Violation, alerts[0].Status)
+}
+
+type fakeAccountSource struct {
+	account   dydx.Account
+	positions []dydx.Position
+}
+
+func (s *fakeAccountSource) GetAccount(ctx context.Context) (*dydx.Account, error) {
+	account := s.account
+	return &account, nil
+}
+
+func (s *fakeAccountSource) GetPositions(ctx context.Context) ([]dydx.Position, error) {
+	return s.positions, nil
+}
+
+func TestAccountExposure(t *testing.T) {
+	ctx := context.Background()
+	source := &fakeAccountSource{
+		account: dydx.Account{Equity: 10000, FreeCollateral: 6000},
+		positions: []dydx.Position{
+			{Market: "BTC-USD", Side: dydx.PositionSideLong, Size: 0.2, EntryPrice: 48000, MarkPrice: 50000},
+			{Market: "ETH-USD", Side: dydx.PositionSideShort, Size: -2, EntryPrice: 3000},
+		},
+	}
+	manager := NewRiskManager(WithAccountSource(source))
+	require.NoError(t, manager.UpdateExposureLimit(ctx, 2))
+
+	state, err := manager.GetAccountState(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 16000.0, state.TotalExposure)
+	assert.Equal(t, 10000.0, state.LargestPosition)
+	assert.Equal(t, 6000.0, state.Exposures["ETH-USD"], "positions without a mark price use the entry price")
+
+	check, err := manager.CheckAccountExposure(ctx, 1000)
+	require.NoError(t, err)
+	assert.Equal(t, Pass, check.Status)
+	assert.Equal(t, 20000.0, check.Threshold)
+
+	check, err = manager.CheckAccountExposure(ctx, 2500)
+	require.NoError(t, err)
+	assert.Equal(t, Warning, check.Status)
+
+	_, err = manager.CheckAccountExposure(ctx, 5000)
+	assert.ErrorIs(t, err, ErrExposureLimitExceeded)
+
+	metrics, err := manager.GetRiskMetrics(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 16000.0, metrics.TotalExposure)
+	assert.Equal(t, Low, metrics.RiskLevel)
+	assert.Zero(t, metrics.CurrentDrawdown)
+
+	source.account.Equity = 8000
+	metrics, err = manager.GetRiskMetrics(ctx)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.2, metrics.CurrentDrawdown, 1e-9)
+	assert.Equal(t, Critical, metrics.RiskLevel)
+
+	_, err = NewRiskManager().CheckAccountExposure(ctx, 1000)
+	assert.ErrorIs(t, err, ErrNoAccountSource)
+}
+
+func TestDailyLimits(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	now := time.Date(2024, 3, 1, 23, 50, 0, 0, time.UTC)
+	newManager := func() RiskManager {
+		manager := NewRiskManager(WithStore(store))
+		manager.(*DefaultRiskManager).now = func() time.Time { return now }
+		lossLimit, maxTrades, cooldown := 1000.0, 2, 5*time.Minute
+		_, err := manager.UpdateLimits(ctx, LimitsUpdate{
+			DailyLossLimit: &lossLimit,
+			MaxDailyTrades: &maxTrades,
+			TradeCooldown:  &cooldown,
+		})
+		require.NoError(t, err)
+		return manager
+	}
+	manager := newManager()
+
+	check, err := manager.CheckTradeFrequency(ctx, TradeFrequencyParams{Symbol: "BTC-USD"})
+	require.NoError(t, err)
+	assert.Equal(t, Pass, check.Status)
+
+	require.NoError(t, manager.RecordTrade(ctx, TradeRecord{Symbol: "BTC-USD"}))
+	_, err = manager.CheckTradeFrequency(ctx, TradeFrequencyParams{Symbol: "BTC-USD"})
+	assert.ErrorIs(t, err, ErrTradeCooldown)
+	check, err = manager.CheckTradeFrequency(ctx, TradeFrequencyParams{Symbol: "ETH-USD"})
+	require.NoError(t, err)
+	assert.Equal(t, Pass, check.Status)
+
+	require.NoError(t, manager.RecordTrade(ctx, TradeRecord{Symbol: "ETH-USD"}))
+	require.NoError(t, manager.RecordRealizedPnL(ctx, -900))
+	require.NoError(t, manager.RecordCommission(ctx, 60))
+	check, err = manager.CheckDailyLoss(ctx, DailyLossParams{})
+	require.NoError(t, err)
+	assert.Equal(t, Warning, check.Status)
+	// The commissions count toward the loss
+	_, err = manager.CheckDailyLoss(ctx, DailyLossParams{UnrealizedPnL: -50})
+	assert.ErrorIs(t, err, ErrDailyLossLimitExceeded)
+
+	// A restart keeps the counters of the day
+	now = now.Add(6 * time.Minute)
+	manager = newManager()
+	_, err = manager.CheckTradeFrequency(ctx, TradeFrequencyParams{Symbol: "SOL-USD"})
+	assert.ErrorIs(t, err, ErrTradeLimitExceeded)
+	check, err = manager.CheckDailyLoss(ctx, DailyLossParams{})
+	require.NoError(t, err)
+	assert.Equal(t, 960.0, check.Value)
+
+	// The counters reset on the next day, while cooldowns run on
+	now = time.Date(2024, 3, 2, 0, 0, 30, 0, time.UTC)
+	require.NoError(t, manager.RecordTrade(ctx, TradeRecord{Symbol: "BTC-USD"}))
+	now = now.Add(time.Minute)
+	_, err = manager.CheckTradeFrequency(ctx, TradeFrequencyParams{Symbol: "BTC-USD"})
+	assert.ErrorIs(t, err, ErrTradeCooldown)
+	check, err = manager.CheckTradeFrequency(ctx, TradeFrequencyParams{Symbol: "ETH-USD"})
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, check.Value)
+	check, err = manager.CheckDailyLoss(ctx, DailyLossParams{})
+	require.NoError(t, err)
+	assert.Equal(t, Pass, check.Status)
+
+	// Both days are listed, the past one from the store
+	states, err := manager.GetDailyStates(ctx, time.Date(2024, 2, 28, 0, 0, 0, 0, time.UTC), now)
+	require.NoError(t, err)
+	require.Len(t, states, 2)
+	assert.Equal(t, "2024-03-01", states[0].Day)
+	assert.Equal(t, 2, states[0].Trades)
+	assert.Equal(t, -900.0, states[0].RealizedPnL)
+	assert.Equal(t, 60.0, states[0].Commissions)
+	assert.Equal(t, -960.0, states[0].NetPnL())
+	assert.Equal(t, "2024-03-02", states[1].Day)
+	assert.Equal(t, 1, states[1].Trades)
+
+	_, err = NewRiskManager().CheckDailyLoss(ctx, DailyLossParams{})
+	assert.ErrorIs(t, err, ErrLimitNotSet)
+}
+
+type fakeScreener struct {
+	rejected map[string]bool
+}
+
+func (s fakeScreener) Screen(ctx context.Context, symbol string) error {
+	if s.rejected[symbol] {
+		return fmt.Errorf("%s: %w", symbol, ErrSymbolRejected)
+	}
+	return nil
+}
+
+func TestValidateTrade(t *testing.T) {
+	ctx := context.Background()
+	manager := NewRiskManager(WithScreener(fakeScreener{rejected: map[string]bool{"SCAM-USD": true}}))
+	maxPositions, maxLeverage := 2, 5.0
+	_, err := manager.UpdateLimits(ctx, LimitsUpdate{
+		PositionLimits:   map[string]float64{"BTC-USD": 100000},
+		MaxOpenPositions: &maxPositions,
+		MaxLeverage:      &maxLeverage,
+	})
+	require.NoError(t, err)
+
+	checks, err := manager.ValidateTrade(ctx, TradeParams{Symbol: "BTC-USD", Side: Long, Size: 1, Price: 50000, Leverage: 2})
+	require.NoError(t, err)
+	require.Len(t, checks, 2, "checks without limits are skipped")
+	assert.Equal(t, PortfolioRisk, checks[0].Type)
+	assert.Equal(t, PositionRisk, checks[1].Type)
+
+	_, err = manager.ValidateTrade(ctx, TradeParams{Symbol: "SCAM-USD", Side: Long, Size: 1, Price: 1})
+	assert.ErrorIs(t, err, ErrSymbolRejected)
+	_, err = manager.ValidateTrade(ctx, TradeParams{Symbol: "ETH-USD", Side: Long, Size: 1, Price: 3000, OpenPositions: 2})
+	assert.ErrorIs(t, err, ErrMaxPositionsReached)
+	_, err = manager.ValidateTrade(ctx, TradeParams{Symbol: "ETH-USD", Side: Short, Size: 1, Price: 3000, Leverage: 10})
+	assert.ErrorIs(t, err, ErrLeverageExceeded)
+	checks, err = manager.ValidateTrade(ctx, TradeParams{Symbol: "BTC-USD", Side: Long, Size: 3, Price: 50000, Netting: true, Position: 1, OpenPositions: 1})
+	assert.ErrorIs(t, err, ErrPositionLimitExceeded)
+	assert.Len(t, checks, 2)
+
+	// Netting trades reducing a position always pass, even when screened out
+	checks, err = manager.ValidateTrade(ctx, TradeParams{Symbol: "SCAM-USD", Side: Short, Size: 1, Price: 1, Netting: true, Position: 5, OpenPositions: 9})
+	require.NoError(t, err)
+	assert.Empty(t, checks)
+
+	// Without netting an opposite trade opens a position of its own, which
+	// adds to the gross position
+	_, err = manager.ValidateTrade(ctx, TradeParams{Symbol: "SCAM-USD", Side: Short, Size: 1, Price: 1, Position: 5})
+	assert.ErrorIs(t, err, ErrSymbolRejected)
+	_, err = manager.ValidateTrade(ctx, TradeParams{Symbol: "BTC-USD", Side: Short, Size: 1, Price: 50000, Position: 1, OpenPositions: 2})
+	assert.ErrorIs(t, err, ErrMaxPositionsReached)
+	checks, err = manager.ValidateTrade(ctx, TradeParams{Symbol: "BTC-USD", Side: Short, Size: 1, Price: 50000, Position: 1, OpenPositions: 1})
+	assert.ErrorIs(t, err, ErrPositionLimitExceeded)
+	require.Len(t, checks, 2)
+	assert.Equal(t, 100000.0, checks[1].Value)
+
+	_, err = manager.ValidateTrade(ctx, TradeParams{Symbol: "BTC-USD", Side: Long, Price: 50000})
+	assert.ErrorIs(t, err, ErrInvalidTrade)
+}
+
+type fakeExposureSource struct {
+	exposure, collateral float64
+}
+
+func (s *fakeExposureSource) PortfolioExposure(ctx context.Context) (float64, float64, error) {
+	return s.exposure, s.collateral, nil
+}
+
+func TestValidateTradeExposureSource(t *testing.T) {
+	ctx := context.Background()
+	source := &fakeExposureSource{exposure: 15000}
+	manager := NewRiskManager(
+		WithExposureSource(source),
+		WithAccountSource(&fakeAccountSource{account: dydx.Account{Equity: 1000000}}),
+	)
+	limit := 2.0
+	_, err := manager.UpdateLimits(ctx, LimitsUpdate{ExposureLimit: &limit})
+	require.NoError(t, err)
+
+	// Without portfolio collateral the exposure check is skipped
+	checks, err := manager.ValidateTrade(ctx, TradeParams{Symbol: "SOL-USD", Side: Long, Size: 100, Price: 100})
+	require.NoError(t, err)
+	assert.Empty(t, checks)
+
+	// The portfolio is measured instead of the account
+	source.collateral = 10000
+	checks, err = manager.ValidateTrade(ctx, TradeParams{Symbol: "SOL-USD", Side: Long, Size: 10, Price: 100})
+	require.NoError(t, err)
+	require.Len(t, checks, 1)
+	assert.Equal(t, ExposureRisk, checks[0].Type)
+	assert.Equal(t, 16000.0, checks[0].Value)
+	assert.Equal(t, 20000.0, checks[0].Threshold)
+
+	_, err = manager.ValidateTrade(ctx, TradeParams{Symbol: "SOL-USD", Side: Long, Size: 100, Price: 100})
+	assert.ErrorIs(t, err, ErrExposureLimitExceeded)
+}
+
+func TestPositionSizing(t *testing.T) {
+	ctx := context.Background()
+	manager := NewRiskManager(WithAccountSource(&fakeAccountSource{account: dydx.Account{Equity: 10000}}))
+
+	_, err := manager.CalculatePositionSize(ctx, SizingParams{Side: Long, EntryPrice: 100})
+	assert.ErrorIs(t, err, ErrLimitNotSet)
+
+	riskPerTrade, stopLoss, takeProfit, maxLeverage := 0.01, 0.02, 0.04, 3.0
+	_, err = manager.UpdateLimits(ctx, LimitsUpdate{
+		RiskPerTrade: &riskPerTrade,
+		StopLoss:     &stopLoss,
+		TakeProfit:   &takeProfit,
+		MaxLeverage:  &maxLeverage,
+	})
+	require.NoError(t, err)
+
+	levels, err := manager.CalculateStopLevels(ctx, StopLevelParams{Side: Long, EntryPrice: 100})
+	require.NoError(t, err)
+	assert.InDelta(t, 98, levels.StopLoss, 1e-9)
+	assert.InDelta(t, 104, levels.TakeProfit, 1e-9)
+	levels, err = manager.CalculateStopLevels(ctx, StopLevelParams{Side: Short, EntryPrice: 100})
+	require.NoError(t, err)
+	assert.InDelta(t, 102, levels.StopLoss, 1e-9)
+	assert.InDelta(t, 96, levels.TakeProfit, 1e-9)
+
+	// 1% of 10000 equity over a 2 unit stop distance, from the account equity
+	size, err := manager.CalculatePositionSize(ctx, SizingParams{Symbol: "SOL-USD", Side: Long, EntryPrice: 100})
+	require.NoError(t, err)
+	assert.InDelta(t, 50, size, 1e-9)
+
+	size, err = manager.CalculatePositionSize(ctx, SizingParams{Symbol: "SOL-USD", Side: Short, EntryPrice: 100, StopLoss: 101, Equity: 20000})
+	require.NoError(t, err)
+	assert.InDelta(t, 200, size, 1e-9)
+
+	// Capped by the maximum leverage, then by the position limit
+	size, err = manager.CalculatePositionSize(ctx, SizingParams{Symbol: "SOL-USD", Side: Long, EntryPrice: 100, StopLoss: 99.9})
+	require.NoError(t, err)
+	assert.InDelta(t, 300, size, 1e-9)
+	require.NoError(t, manager.UpdatePositionLimit(ctx, "SOL-USD", 5000))
+	size, err = manager.CalculatePositionSize(ctx, SizingParams{Symbol: "SOL-USD", Side: Long, EntryPrice: 100, StopLoss: 99.9})
+	require.NoError(t, err)
+	assert.InDelta(t, 50, size, 1e-9)
+
+	tooHigh := 1.0
+	_, err = manager.UpdateLimits(ctx, LimitsUpdate{StopLoss: &tooHigh})
+	assert.ErrorIs(t, err, ErrInvalidLimit)
+}
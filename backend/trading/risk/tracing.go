@@ -0,0 +1,101 @@
+package risk
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/devinjacknz/godydxhyber/backend/pkg/tracing"
+)
+
+// tracedRiskManager traces the risk checks of a RiskManager
+type tracedRiskManager struct {
+	RiskManager
+}
+
+// Traced returns a RiskManager tracing every risk check in a span
+func Traced(m RiskManager) RiskManager {
+	return tracedRiskManager{m}
+}
+
+func (m tracedRiskManager) CheckPositionLimit(ctx context.Context, params PositionLimitParams) (*RiskCheck, error) {
+	ctx, span := tracing.Start(ctx, "risk.CheckPositionLimit", attribute.String("symbol", params.Symbol))
+	check, err := m.RiskManager.CheckPositionLimit(ctx, params)
+	endCheck(span, check, err)
+	return check, err
+}
+
+func (m tracedRiskManager) CheckExposureLimit(ctx context.Context, params ExposureLimitParams) (*RiskCheck, error) {
+	ctx, span := tracing.Start(ctx, "risk.CheckExposureLimit")
+	check, err := m.RiskManager.CheckExposureLimit(ctx, params)
+	endCheck(span, check, err)
+	return check, err
+}
+
+func (m tracedRiskManager) CheckDrawdown(ctx context.Context, params DrawdownParams) (*RiskCheck, error) {
+	ctx, span := tracing.Start(ctx, "risk.CheckDrawdown")
+	check, err := m.RiskManager.CheckDrawdown(ctx, params)
+	endCheck(span, check, err)
+	return check, err
+}
+
+func (m tracedRiskManager) CheckVolatility(ctx context.Context, params VolatilityParams) (*RiskCheck, error) {
+	ctx, span := tracing.Start(ctx, "risk.CheckVolatility", attribute.String("symbol", params.Symbol))
+	check, err := m.RiskManager.CheckVolatility(ctx, params)
+	endCheck(span, check, err)
+	return check, err
+}
+
+func (m tracedRiskManager) CheckAccountExposure(ctx context.Context, additionalAmount float64) (*RiskCheck, error) {
+	ctx, span := tracing.Start(ctx, "risk.CheckAccountExposure")
+	check, err := m.RiskManager.CheckAccountExposure(ctx, additionalAmount)
+	endCheck(span, check, err)
+	return check, err
+}
+
+func (m tracedRiskManager) CheckDailyLoss(ctx context.Context, params DailyLossParams) (*RiskCheck, error) {
+	ctx, span := tracing.Start(ctx, "risk.CheckDailyLoss")
+	check, err := m.RiskManager.CheckDailyLoss(ctx, params)
+	endCheck(span, check, err)
+	return check, err
+}
+
+func (m tracedRiskManager) CheckTradeFrequency(ctx context.Context, params TradeFrequencyParams) (*RiskCheck, error) {
+	ctx, span := tracing.Start(ctx, "risk.CheckTradeFrequency", attribute.String("symbol", params.Symbol))
+	check, err := m.RiskManager.CheckTradeFrequency(ctx, params)
+	endCheck(span, check, err)
+	return check, err
+}
+
+func (m tracedRiskManager) ValidateTrade(ctx context.Context, params TradeParams) ([]*RiskCheck, error) {
+	ctx, span := tracing.Start(ctx, "risk.ValidateTrade",
+		attribute.String("symbol", params.Symbol),
+		attribute.String("side", params.Side.String()),
+		attribute.Float64("size", params.Size),
+	)
+	checks, err := m.RiskManager.ValidateTrade(ctx, params)
+	span.SetAttributes(attribute.Int("risk.checks", len(checks)))
+	tracing.End(span, err)
+	return checks, err
+}
+
+func (m tracedRiskManager) CheckPortfolio(ctx context.Context, params PortfolioParams) (*RiskCheck, error) {
+	ctx, span := tracing.Start(ctx, "risk.CheckPortfolio")
+	check, err := m.RiskManager.CheckPortfolio(ctx, params)
+	endCheck(span, check, err)
+	return check, err
+}
+
+// endCheck records the result of a check on its span and ends it
+func endCheck(span trace.Span, check *RiskCheck, err error) {
+	if check != nil {
+		span.SetAttributes(
+			attribute.String("risk.check_id", check.ID),
+			attribute.String("risk.level", check.Level.String()),
+			attribute.Float64("risk.value", check.Value),
+			attribute.Float64("risk.threshold", check.Threshold),
+		)
+	}
+	tracing.End(span, err)
+}
@@ -0,0 +1,331 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/logger"
+	"github.com/devinjacknz/godydxhyber/backend/trading/analysis/monitoring"
+)
+
+// Side is the direction of a position
+type Side int
+
+const (
+	Long Side = iota + 1
+	Short
+)
+
+// String returns the name of the side
+func (s Side) String() string {
+	switch s {
+	case Long:
+		return "long"
+	case Short:
+		return "short"
+	default:
+		return "unknown"
+	}
+}
+
+// Screener vets a symbol before positions in it are opened or increased. It
+// returns an error wrapping ErrSymbolRejected for symbols that must not be
+// traded.
+type Screener interface {
+	Screen(ctx context.Context, symbol string) error
+}
+
+// WithScreener screens the symbol of every trade opening or increasing a
+// position
+func WithScreener(screener Screener) Option {
+	return func(m *DefaultRiskManager) {
+		m.screener = screener
+	}
+}
+
+// TradeParams contains the parameters of a trade validated before it is
+// placed
+type TradeParams struct {
+	Symbol string
+	Side   Side
+	Size   float64
+	Price  float64
+	// Leverage is the leverage of the resulting position, 1 when zero
+	Leverage float64
+	// Netting is set when the account keeps a single position per symbol,
+	// which opposite trades reduce. Otherwise every trade opens a position
+	// of its own.
+	Netting bool
+	// Position is the size of the current position in the symbol: signed,
+	// positive long and negative short, when netting, and the gross size of
+	// the open positions otherwise
+	Position float64
+	// OpenPositions is the number of open positions of the account
+	OpenPositions int
+	// UnrealizedPnL is the unrealized profit or loss of the open positions
+	UnrealizedPnL float64
+}
+
+// reduces reports whether the trade only reduces the current position,
+// which only netting trades can
+func (p TradeParams) reduces() bool {
+	if !p.Netting {
+		return false
+	}
+	switch p.Side {
+	case Long:
+		return p.Position < 0 && p.Size <= -p.Position
+	case Short:
+		return p.Position > 0 && p.Size <= p.Position
+	default:
+		return false
+	}
+}
+
+// limitSize returns the size the trade adds to the position: signed,
+// negative for shorts, when netting
+func (p TradeParams) limitSize() float64 {
+	if p.Netting && p.Side == Short {
+		return -p.Size
+	}
+	return p.Size
+}
+
+// PortfolioParams contains parameters for the portfolio check
+type PortfolioParams struct {
+	// OpenPositions is the number of open positions, including the one the
+	// trade opens
+	OpenPositions int
+	Leverage      float64
+}
+
+// SizingParams contains parameters for position sizing
+type SizingParams struct {
+	Symbol     string
+	Side       Side
+	EntryPrice float64
+	// StopLoss is the stop loss price, the default stop loss when zero
+	StopLoss float64
+	// Equity is the account equity, read from the account source when zero
+	Equity float64
+}
+
+// StopLevelParams contains parameters for the stop level calculation
+type StopLevelParams struct {
+	Side       Side
+	EntryPrice float64
+}
+
+// StopLevels are the stop loss and take profit prices of a position. A
+// level is zero when its distance is not configured.
+type StopLevels struct {
+	StopLoss   float64 `json:"stop_loss"`
+	TakeProfit float64 `json:"take_profit"`
+}
+
+// ValidateTrade runs the pre-trade checks on a trade and returns the checks
+// run, stopping at the first violation. Netting trades that only reduce a
+// position pass, so positions can always be exited. Checks without a
+// configured limit are skipped.
+func (m *DefaultRiskManager) ValidateTrade(ctx context.Context, params TradeParams) ([]*RiskCheck, error) {
+	if params.Symbol == "" {
+		return nil, ErrInvalidSymbol
+	}
+	if params.Size <= 0 || params.Price <= 0 || (params.Side != Long && params.Side != Short) {
+		return nil, ErrInvalidTrade
+	}
+	if params.reduces() {
+		return nil, nil
+	}
+
+	if m.screener != nil {
+		if err := m.screener.Screen(ctx, params.Symbol); err != nil {
+			return nil, fmt.Errorf("failed to screen %s: %w", params.Symbol, err)
+		}
+	}
+
+	openPositions := params.OpenPositions
+	if !params.Netting || params.Position == 0 {
+		openPositions++
+	}
+	checks := []func() (*RiskCheck, error){
+		func() (*RiskCheck, error) {
+			return m.CheckPortfolio(ctx, PortfolioParams{OpenPositions: openPositions, Leverage: params.Leverage})
+		},
+		func() (*RiskCheck, error) {
+			return m.CheckPositionLimit(ctx, PositionLimitParams{
+				Symbol:        params.Symbol,
+				Size:          params.limitSize(),
+				CurrentPrice:  params.Price,
+				TotalPosition: params.Position,
+				Netting:       params.Netting,
+			})
+		},
+		func() (*RiskCheck, error) {
+			// The portfolio covers every venue, the account only dYdX
+			if m.exposureSource != nil {
+				return m.checkPortfolioExposure(ctx, params.Size*params.Price)
+			}
+			if m.accountSource == nil {
+				return nil, ErrLimitNotSet
+			}
+			return m.CheckAccountExposure(ctx, params.Size*params.Price)
+		},
+		func() (*RiskCheck, error) {
+			return m.CheckDailyLoss(ctx, DailyLossParams{UnrealizedPnL: params.UnrealizedPnL})
+		},
+		func() (*RiskCheck, error) {
+			return m.CheckTradeFrequency(ctx, TradeFrequencyParams{Symbol: params.Symbol})
+		},
+	}
+
+	results := make([]*RiskCheck, 0, len(checks))
+	for _, run := range checks {
+		check, err := run()
+		if errors.Is(err, ErrLimitNotSet) {
+			continue
+		}
+		if check != nil {
+			results = append(results, check)
+		}
+		if err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// CheckPortfolio checks the number of open positions and the leverage of a
+// position against their maxima
+func (m *DefaultRiskManager) CheckPortfolio(ctx context.Context, params PortfolioParams) (*RiskCheck, error) {
+	m.mu.RLock()
+	maxPositions, maxLeverage := m.maxOpenPositions, m.maxLeverage
+	m.mu.RUnlock()
+	if maxPositions <= 0 && maxLeverage <= 0 {
+		return nil, ErrLimitNotSet
+	}
+
+	check := &RiskCheck{
+		ID:            generateCheckID(),
+		CorrelationID: logger.CorrelationID(ctx),
+		AccountID:     m.accountID,
+		Type:          PortfolioRisk,
+		Value:         float64(params.OpenPositions),
+		Threshold:     float64(maxPositions),
+		CreatedAt:     time.Now(),
+		Description:   "Portfolio check",
+	}
+
+	if maxLeverage > 0 && params.Leverage > maxLeverage {
+		check.Status = Violation
+		check.Level = High
+		check.Value = params.Leverage
+		check.Threshold = maxLeverage
+		check.Description = "Leverage check"
+		m.alert(ctx, check)
+		return check, ErrLeverageExceeded
+	}
+
+	switch {
+	case maxPositions > 0 && params.OpenPositions > maxPositions:
+		check.Status = Violation
+		check.Level = High
+		monitoring.RecordIndicatorError("portfolio", "Maximum open positions reached")
+		m.alert(ctx, check)
+		return check, ErrMaxPositionsReached
+	case maxPositions > 0 && params.OpenPositions == maxPositions:
+		check.Status = Warning
+		check.Level = Medium
+	default:
+		check.Status = Pass
+		check.Level = Low
+	}
+
+	m.record(ctx, check)
+	return check, nil
+}
+
+// CalculatePositionSize returns the size risking the configured fraction of
+// the equity between the entry and the stop loss, capped by the position
+// limit of the symbol and the maximum leverage
+func (m *DefaultRiskManager) CalculatePositionSize(ctx context.Context, params SizingParams) (float64, error) {
+	if params.EntryPrice <= 0 {
+		return 0, ErrInvalidTrade
+	}
+
+	m.mu.RLock()
+	riskPerTrade, maxLeverage := m.riskPerTrade, m.maxLeverage
+	positionLimit := m.positionLimits[params.Symbol]
+	m.mu.RUnlock()
+	if riskPerTrade <= 0 {
+		return 0, ErrLimitNotSet
+	}
+
+	equity := params.Equity
+	if equity <= 0 {
+		state, err := m.accountState(ctx)
+		if err != nil {
+			return 0, err
+		}
+		equity = state.Equity
+	}
+	if equity <= 0 {
+		return 0, ErrInsufficientData
+	}
+
+	stop := params.StopLoss
+	if stop == 0 {
+		levels, err := m.CalculateStopLevels(ctx, StopLevelParams{Side: params.Side, EntryPrice: params.EntryPrice})
+		if err != nil {
+			return 0, err
+		}
+		stop = levels.StopLoss
+	}
+	if stop <= 0 {
+		return 0, ErrLimitNotSet
+	}
+	distance := math.Abs(params.EntryPrice - stop)
+	if distance == 0 {
+		return 0, ErrInvalidTrade
+	}
+
+	size := equity * riskPerTrade / distance
+	if positionLimit > 0 {
+		size = math.Min(size, positionLimit/params.EntryPrice)
+	}
+	if maxLeverage > 0 {
+		size = math.Min(size, equity*maxLeverage/params.EntryPrice)
+	}
+	return size, nil
+}
+
+// CalculateStopLevels returns the stop loss and take profit prices at the
+// configured distances from the entry price
+func (m *DefaultRiskManager) CalculateStopLevels(ctx context.Context, params StopLevelParams) (*StopLevels, error) {
+	if params.EntryPrice <= 0 || (params.Side != Long && params.Side != Short) {
+		return nil, ErrInvalidTrade
+	}
+
+	m.mu.RLock()
+	stopLoss, takeProfit := m.stopLoss, m.takeProfit
+	m.mu.RUnlock()
+	if stopLoss <= 0 && takeProfit <= 0 {
+		return nil, ErrLimitNotSet
+	}
+
+	direction := 1.0
+	if params.Side == Short {
+		direction = -1
+	}
+	levels := &StopLevels{}
+	if stopLoss > 0 {
+		levels.StopLoss = params.EntryPrice * (1 - direction*stopLoss)
+	}
+	if takeProfit > 0 {
+		levels.TakeProfit = params.EntryPrice * (1 + direction*takeProfit)
+	}
+	return levels, nil
+}
@@ -0,0 +1,175 @@
+package routing
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/trading/analysis/monitoring"
+	"github.com/devinjacknz/godydxhyber/backend/trading/order"
+)
+
+// Venue identifies an execution venue
+type Venue string
+
+const (
+	VenueDydx        Venue = "dydx"
+	VenueHyperliquid Venue = "hyperliquid"
+)
+
+// Execution represents a historical execution attempt on a venue
+type Execution struct {
+	Venue         Venue
+	Symbol        string
+	Side          order.OrderSide
+	Notional      float64
+	ExpectedPrice float64
+	FillPrice     float64
+	Fee           float64
+	Failed        bool
+	Timestamp     time.Time
+}
+
+// CostEstimate contains the estimated cost of executing on a venue.
+// All cost figures are expressed in basis points of notional.
+type CostEstimate struct {
+	Venue              Venue   `json:"venue"`
+	FeeBps             float64 `json:"fee_bps"`
+	SlippageBps        float64 `json:"slippage_bps"`
+	FailureProbability float64 `json:"failure_probability"`
+	FailurePenaltyBps  float64 `json:"failure_penalty_bps"`
+	ExpectedCostBps    float64 `json:"expected_cost_bps"`
+	SampleSize         int     `json:"sample_size"`
+}
+
+// CostModelConfig contains cost model configuration
+type CostModelConfig struct {
+	// WindowSize is the number of recent executions kept per venue
+	WindowSize int
+	// DefaultFeeBps is the fee assumed for venues without execution history
+	DefaultFeeBps map[Venue]float64
+	// FailurePenaltyBps is the cost charged for a failed execution attempt
+	FailurePenaltyBps float64
+}
+
+// DefaultCostModelConfig returns default cost model configuration
+func DefaultCostModelConfig() CostModelConfig {
+	return CostModelConfig{
+		WindowSize: 500,
+		DefaultFeeBps: map[Venue]float64{
+			VenueDydx:        5.0,
+			VenueHyperliquid: 3.5,
+		},
+		FailurePenaltyBps: 25.0,
+	}
+}
+
+// CostModel maintains per-venue execution cost estimates
+type CostModel struct {
+	config     CostModelConfig
+	executions map[Venue][]Execution
+	mu         sync.RWMutex
+}
+
+// NewCostModel creates a new cost model
+func NewCostModel(config CostModelConfig) *CostModel {
+	if config.WindowSize <= 0 {
+		config.WindowSize = DefaultCostModelConfig().WindowSize
+	}
+	if config.DefaultFeeBps == nil {
+		config.DefaultFeeBps = make(map[Venue]float64)
+	}
+
+	return &CostModel{
+		config:     config,
+		executions: make(map[Venue][]Execution),
+	}
+}
+
+// RecordExecution adds an execution to the venue history
+func (m *CostModel) RecordExecution(exec Execution) error {
+	if exec.Venue == "" {
+		return ErrUnknownVenue
+	}
+	if !exec.Failed && (exec.Notional <= 0 || exec.ExpectedPrice <= 0 || exec.FillPrice <= 0) {
+		return ErrInvalidExecution
+	}
+	if exec.Timestamp.IsZero() {
+		exec.Timestamp = time.Now()
+	}
+
+	m.mu.Lock()
+	history := append(m.executions[exec.Venue], exec)
+	if len(history) > m.config.WindowSize {
+		history = history[len(history)-m.config.WindowSize:]
+	}
+	m.executions[exec.Venue] = history
+	m.mu.Unlock()
+
+	estimate := m.Estimate(exec.Venue)
+	monitoring.RecordIndicatorValue("venue_cost_bps_"+string(exec.Venue), estimate.ExpectedCostBps)
+	monitoring.RecordIndicatorValue("venue_failure_rate_"+string(exec.Venue), estimate.FailureProbability)
+	return nil
+}
+
+// Estimate returns the current cost estimate for a venue
+func (m *CostModel) Estimate(venue Venue) CostEstimate {
+	m.mu.RLock()
+	history := m.executions[venue]
+	defaultFee := m.config.DefaultFeeBps[venue]
+	penalty := m.config.FailurePenaltyBps
+	m.mu.RUnlock()
+
+	estimate := CostEstimate{
+		Venue:             venue,
+		FeeBps:            defaultFee,
+		FailurePenaltyBps: penalty,
+		SampleSize:        len(history),
+	}
+
+	var (
+		feeSum      float64
+		slippageSum float64
+		filled      int
+		failed      int
+	)
+	for _, exec := range history {
+		if exec.Failed {
+			failed++
+			continue
+		}
+		filled++
+		feeSum += exec.Fee / exec.Notional * 10000
+		slippageSum += slippageBps(exec.Side, exec.ExpectedPrice, exec.FillPrice)
+	}
+
+	if filled > 0 {
+		estimate.FeeBps = feeSum / float64(filled)
+		estimate.SlippageBps = slippageSum / float64(filled)
+	}
+	if len(history) > 0 {
+		estimate.FailureProbability = float64(failed) / float64(len(history))
+	}
+
+	estimate.ExpectedCostBps = (1-estimate.FailureProbability)*(estimate.FeeBps+estimate.SlippageBps) +
+		estimate.FailureProbability*estimate.FailurePenaltyBps
+	return estimate
+}
+
+// slippageBps returns the adverse price movement between expected and fill
+// price in basis points. Favorable fills produce negative slippage.
+func slippageBps(side order.OrderSide, expected, fill float64) float64 {
+	if side == order.Sell {
+		return (expected - fill) / expected * 10000
+	}
+	return (fill - expected) / expected * 10000
+}
+
+func sortEstimates(estimates []CostEstimate) {
+	sort.Slice(estimates, func(i, j int) bool {
+		if estimates[i].ExpectedCostBps == estimates[j].ExpectedCostBps {
+			return estimates[i].Venue < estimates[j].Venue
+		}
+		return estimates[i].ExpectedCostBps < estimates[j].ExpectedCostBps
+	})
+}
@@ -0,0 +1,20 @@
+package routing
+
+import "errors"
+
+var (
+	// ErrUnknownVenue is returned when a venue is not known to the router
+	ErrUnknownVenue = errors.New("unknown venue")
+
+	// ErrNoVenues is returned when no venue is available for routing
+	ErrNoVenues = errors.New("no venues available")
+
+	// ErrInvalidExecution is returned when an execution record is invalid
+	ErrInvalidExecution = errors.New("invalid execution")
+
+	// ErrInvalidSize is returned when the preview size is invalid
+	ErrInvalidSize = errors.New("invalid size")
+
+	// ErrInvalidPrice is returned when the reference price is invalid
+	ErrInvalidPrice = errors.New("invalid price")
+)
@@ -0,0 +1,75 @@
+package routing
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/devinjacknz/godydxhyber/backend/trading/order"
+	"github.com/gin-gonic/gin"
+)
+
+type previewRequest struct {
+	Symbol string   `json:"symbol"`
+	Side   string   `json:"side"`
+	Size   float64  `json:"size"`
+	Price  float64  `json:"price"`
+	Venues []string `json:"venues,omitempty"`
+}
+
+// RegisterRoutes registers the routing cost model HTTP endpoints
+func (r *Router) RegisterRoutes(g gin.IRouter) {
+	g.GET("/routing/venues", r.handleVenues)
+	g.POST("/routing/preview", r.handlePreview)
+}
+
+func (r *Router) handleVenues(c *gin.Context) {
+	estimates := make([]CostEstimate, 0, len(r.venues))
+	for _, venue := range r.venues {
+		estimates = append(estimates, r.model.Estimate(venue))
+	}
+	sortEstimates(estimates)
+
+	c.JSON(http.StatusOK, gin.H{"venues": estimates})
+}
+
+func (r *Router) handlePreview(c *gin.Context) {
+	var req previewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var side order.OrderSide
+	switch req.Side {
+	case "buy":
+		side = order.Buy
+	case "sell":
+		side = order.Sell
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "side must be buy or sell"})
+		return
+	}
+
+	venues := make([]Venue, 0, len(req.Venues))
+	for _, v := range req.Venues {
+		venues = append(venues, Venue(v))
+	}
+
+	preview, err := r.Preview(c.Request.Context(), PreviewParams{
+		Symbol: req.Symbol,
+		Side:   side,
+		Size:   req.Size,
+		Price:  req.Price,
+		Venues: venues,
+	})
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrInvalidSize) || errors.Is(err, ErrInvalidPrice) || errors.Is(err, ErrUnknownVenue) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
@@ -0,0 +1,150 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/devinjacknz/godydxhyber/backend/trading/order"
+)
+
+// PreviewParams contains parameters for a trade preview
+type PreviewParams struct {
+	Symbol string
+	Side   order.OrderSide
+	Size   float64
+	// Price is the reference price used to compute notional and fill estimates
+	Price float64
+	// Venues restricts the preview to the given venues; empty means all
+	Venues []Venue
+}
+
+// VenuePreview contains the estimated outcome of executing on one venue
+type VenuePreview struct {
+	CostEstimate
+	EstimatedFillPrice float64 `json:"estimated_fill_price"`
+	EstimatedFee       float64 `json:"estimated_fee"`
+	ExpectedCost       float64 `json:"expected_cost"`
+}
+
+// TradePreview contains per-venue estimates and the recommended venue
+type TradePreview struct {
+	Symbol      string          `json:"symbol"`
+	Side        order.OrderSide `json:"side"`
+	Size        float64         `json:"size"`
+	Notional    float64         `json:"notional"`
+	Venues      []VenuePreview  `json:"venues"`
+	Recommended Venue           `json:"recommended"`
+	Explanation string          `json:"explanation"`
+}
+
+// Router selects execution venues using the cost model
+type Router struct {
+	model  *CostModel
+	venues []Venue
+}
+
+// NewRouter creates a new router over the given venues
+func NewRouter(model *CostModel, venues ...Venue) *Router {
+	return &Router{
+		model:  model,
+		venues: venues,
+	}
+}
+
+// Preview estimates fees, slippage and failure cost for the trade on each venue
+func (r *Router) Preview(ctx context.Context, params PreviewParams) (*TradePreview, error) {
+	if params.Size <= 0 {
+		return nil, ErrInvalidSize
+	}
+	if params.Price <= 0 {
+		return nil, ErrInvalidPrice
+	}
+
+	venues, err := r.candidateVenues(params.Venues)
+	if err != nil {
+		return nil, err
+	}
+
+	notional := params.Size * params.Price
+	estimates := make([]CostEstimate, 0, len(venues))
+	for _, venue := range venues {
+		estimates = append(estimates, r.model.Estimate(venue))
+	}
+	sortEstimates(estimates)
+
+	preview := &TradePreview{
+		Symbol:   params.Symbol,
+		Side:     params.Side,
+		Size:     params.Size,
+		Notional: notional,
+		Venues:   make([]VenuePreview, 0, len(estimates)),
+	}
+
+	for _, estimate := range estimates {
+		slippage := params.Price * estimate.SlippageBps / 10000
+		fillPrice := params.Price + slippage
+		if params.Side == order.Sell {
+			fillPrice = params.Price - slippage
+		}
+
+		preview.Venues = append(preview.Venues, VenuePreview{
+			CostEstimate:       estimate,
+			EstimatedFillPrice: fillPrice,
+			EstimatedFee:       notional * estimate.FeeBps / 10000,
+			ExpectedCost:       notional * estimate.ExpectedCostBps / 10000,
+		})
+	}
+
+	preview.Recommended = preview.Venues[0].Venue
+	preview.Explanation = explain(preview.Venues)
+	return preview, nil
+}
+
+// SelectVenue returns the venue with the lowest expected cost for the trade
+func (r *Router) SelectVenue(ctx context.Context, params PreviewParams) (Venue, *TradePreview, error) {
+	preview, err := r.Preview(ctx, params)
+	if err != nil {
+		return "", nil, err
+	}
+	return preview.Recommended, preview, nil
+}
+
+// Venues returns the venues known to the router
+func (r *Router) Venues() []Venue {
+	venues := make([]Venue, len(r.venues))
+	copy(venues, r.venues)
+	return venues
+}
+
+func (r *Router) candidateVenues(requested []Venue) ([]Venue, error) {
+	if len(requested) == 0 {
+		if len(r.venues) == 0 {
+			return nil, ErrNoVenues
+		}
+		return r.venues, nil
+	}
+
+	known := make(map[Venue]bool, len(r.venues))
+	for _, venue := range r.venues {
+		known[venue] = true
+	}
+	for _, venue := range requested {
+		if !known[venue] {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownVenue, venue)
+		}
+	}
+	return requested, nil
+}
+
+func explain(previews []VenuePreview) string {
+	best := previews[0]
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s selected: expected cost %.2f bps (fee %.2f + slippage %.2f, failure probability %.1f%%)",
+		best.Venue, best.ExpectedCostBps, best.FeeBps, best.SlippageBps, best.FailureProbability*100)
+
+	for _, alt := range previews[1:] {
+		fmt.Fprintf(&b, "; %s at %.2f bps", alt.Venue, alt.ExpectedCostBps)
+	}
+	return b.String()
+}
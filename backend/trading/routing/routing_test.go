@@ -0,0 +1,131 @@
+package routing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/devinjacknz/godydxhyber/backend/trading/order"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCostModel(t *testing.T) {
+	t.Run("Defaults without history", func(t *testing.T) {
+		model := NewCostModel(DefaultCostModelConfig())
+
+		estimate := model.Estimate(VenueDydx)
+		assert.Equal(t, 5.0, estimate.FeeBps)
+		assert.Equal(t, 0.0, estimate.SlippageBps)
+		assert.Equal(t, 0.0, estimate.FailureProbability)
+		assert.Equal(t, 5.0, estimate.ExpectedCostBps)
+		assert.Equal(t, 0, estimate.SampleSize)
+	})
+
+	t.Run("Estimates from execution history", func(t *testing.T) {
+		model := NewCostModel(CostModelConfig{WindowSize: 10, FailurePenaltyBps: 20})
+
+		// Buy filled 10 bps above expected with a 5 bps fee
+		assert.NoError(t, model.RecordExecution(Execution{
+			Venue: VenueDydx, Side: order.Buy, Notional: 10000, ExpectedPrice: 100, FillPrice: 100.1, Fee: 5,
+		}))
+		// Sell filled 10 bps below expected with a 5 bps fee
+		assert.NoError(t, model.RecordExecution(Execution{
+			Venue: VenueDydx, Side: order.Sell, Notional: 10000, ExpectedPrice: 100, FillPrice: 99.9, Fee: 5,
+		}))
+		assert.NoError(t, model.RecordExecution(Execution{Venue: VenueDydx, Failed: true}))
+		assert.NoError(t, model.RecordExecution(Execution{Venue: VenueDydx, Failed: true}))
+
+		estimate := model.Estimate(VenueDydx)
+		assert.InDelta(t, 5.0, estimate.FeeBps, 1e-9)
+		assert.InDelta(t, 10.0, estimate.SlippageBps, 1e-6)
+		assert.InDelta(t, 0.5, estimate.FailureProbability, 1e-9)
+		assert.InDelta(t, 0.5*15+0.5*20, estimate.ExpectedCostBps, 1e-6)
+		assert.Equal(t, 4, estimate.SampleSize)
+	})
+
+	t.Run("Window drops old executions", func(t *testing.T) {
+		model := NewCostModel(CostModelConfig{WindowSize: 2})
+
+		assert.NoError(t, model.RecordExecution(Execution{Venue: VenueDydx, Failed: true}))
+		for i := 0; i < 2; i++ {
+			assert.NoError(t, model.RecordExecution(Execution{
+				Venue: VenueDydx, Side: order.Buy, Notional: 1000, ExpectedPrice: 10, FillPrice: 10,
+			}))
+		}
+
+		estimate := model.Estimate(VenueDydx)
+		assert.Equal(t, 2, estimate.SampleSize)
+		assert.Equal(t, 0.0, estimate.FailureProbability)
+	})
+
+	t.Run("Invalid executions are rejected", func(t *testing.T) {
+		model := NewCostModel(DefaultCostModelConfig())
+
+		assert.Equal(t, ErrUnknownVenue, model.RecordExecution(Execution{Notional: 1, ExpectedPrice: 1, FillPrice: 1}))
+		assert.Equal(t, ErrInvalidExecution, model.RecordExecution(Execution{Venue: VenueDydx, ExpectedPrice: 1, FillPrice: 1}))
+	})
+}
+
+func TestRouter(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Selects cheapest venue with explanation", func(t *testing.T) {
+		model := NewCostModel(DefaultCostModelConfig())
+		router := NewRouter(model, VenueDydx, VenueHyperliquid)
+
+		venue, preview, err := router.SelectVenue(ctx, PreviewParams{
+			Symbol: "BTC-USD",
+			Side:   order.Buy,
+			Size:   2,
+			Price:  50000,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, VenueHyperliquid, venue)
+		assert.Equal(t, 100000.0, preview.Notional)
+		assert.Len(t, preview.Venues, 2)
+		assert.InDelta(t, 35.0, preview.Venues[0].EstimatedFee, 1e-9)
+		assert.Contains(t, preview.Explanation, "hyperliquid selected")
+		assert.Contains(t, preview.Explanation, "dydx at 5.00 bps")
+	})
+
+	t.Run("Execution history changes the recommendation", func(t *testing.T) {
+		model := NewCostModel(DefaultCostModelConfig())
+		router := NewRouter(model, VenueDydx, VenueHyperliquid)
+
+		for i := 0; i < 4; i++ {
+			assert.NoError(t, model.RecordExecution(Execution{Venue: VenueHyperliquid, Failed: true}))
+		}
+
+		preview, err := router.Preview(ctx, PreviewParams{Symbol: "ETH-USD", Side: order.Sell, Size: 1, Price: 3000})
+		assert.NoError(t, err)
+		assert.Equal(t, VenueDydx, preview.Recommended)
+	})
+
+	t.Run("Sell fill estimate includes slippage", func(t *testing.T) {
+		model := NewCostModel(CostModelConfig{})
+		router := NewRouter(model, VenueDydx)
+
+		assert.NoError(t, model.RecordExecution(Execution{
+			Venue: VenueDydx, Side: order.Sell, Notional: 1000, ExpectedPrice: 100, FillPrice: 99,
+		}))
+
+		preview, err := router.Preview(ctx, PreviewParams{Symbol: "SOL-USD", Side: order.Sell, Size: 1, Price: 200})
+		assert.NoError(t, err)
+		assert.InDelta(t, 198.0, preview.Venues[0].EstimatedFillPrice, 1e-9)
+	})
+
+	t.Run("Invalid parameters", func(t *testing.T) {
+		router := NewRouter(NewCostModel(DefaultCostModelConfig()), VenueDydx)
+
+		_, err := router.Preview(ctx, PreviewParams{Size: 0, Price: 1})
+		assert.Equal(t, ErrInvalidSize, err)
+
+		_, err = router.Preview(ctx, PreviewParams{Size: 1, Price: 0})
+		assert.Equal(t, ErrInvalidPrice, err)
+
+		_, err = router.Preview(ctx, PreviewParams{Size: 1, Price: 1, Venues: []Venue{"unknown"}})
+		assert.ErrorIs(t, err, ErrUnknownVenue)
+
+		_, err = NewRouter(NewCostModel(DefaultCostModelConfig())).Preview(ctx, PreviewParams{Size: 1, Price: 1})
+		assert.Equal(t, ErrNoVenues, err)
+	})
+}
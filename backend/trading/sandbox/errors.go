@@ -0,0 +1,8 @@
+package sandbox
+
+import "errors"
+
+var (
+	// ErrNotSandbox is returned when a sandbox-only call is made without a sandbox token
+	ErrNotSandbox = errors.New("request is not authenticated with a sandbox token")
+)
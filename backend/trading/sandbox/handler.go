@@ -0,0 +1,88 @@
+package sandbox
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/middleware"
+	"github.com/devinjacknz/godydxhyber/backend/trading/killswitch"
+	"github.com/devinjacknz/godydxhyber/backend/trading/order"
+	"github.com/devinjacknz/godydxhyber/backend/trading/position"
+	"github.com/gin-gonic/gin"
+)
+
+type sessionResponse struct {
+	Name       string               `json:"name"`
+	SeededAt   time.Time            `json:"seeded_at"`
+	Orders     []*order.Order       `json:"orders"`
+	Positions  []*position.Position `json:"positions"`
+	KillSwitch killswitch.State     `json:"kill_switch"`
+}
+
+// RegisterRoutes registers the sandbox session endpoints. They are only
+// available to sandbox tokens.
+func (m *Manager) RegisterRoutes(r gin.IRouter) {
+	r.GET("/sandbox", m.handleSession)
+	r.POST("/sandbox/reset", m.handleReset)
+}
+
+func (m *Manager) handleSession(c *gin.Context) {
+	token, ok := middleware.TokenFromContext(c)
+	if !ok || !token.Sandbox {
+		c.JSON(http.StatusForbidden, gin.H{"error": ErrNotSandbox.Error()})
+		return
+	}
+
+	session, err := m.Session(c.Request.Context(), token.Name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	m.respond(c, session)
+}
+
+func (m *Manager) handleReset(c *gin.Context) {
+	token, ok := middleware.TokenFromContext(c)
+	if !ok || !token.Sandbox {
+		c.JSON(http.StatusForbidden, gin.H{"error": ErrNotSandbox.Error()})
+		return
+	}
+
+	session, err := m.Reset(c.Request.Context(), token.Name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	m.respond(c, session)
+}
+
+func (m *Manager) respond(c *gin.Context, session *Session) {
+	ctx := c.Request.Context()
+	resp := sessionResponse{
+		Name:       session.Name,
+		SeededAt:   session.SeededAt,
+		Orders:     []*order.Order{},
+		Positions:  []*position.Position{},
+		KillSwitch: session.KillSwitch.Status(),
+	}
+
+	orders, err := session.Orders.ListOrders(ctx, order.OrderFilter{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for _, o := range orders {
+		resp.Orders = append(resp.Orders, o.Snapshot())
+	}
+
+	positions, err := session.Positions.ListPositions(ctx, position.PositionFilter{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for _, p := range positions {
+		resp.Positions = append(resp.Positions, p.Snapshot())
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
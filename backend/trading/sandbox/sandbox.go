@@ -0,0 +1,265 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/middleware"
+	"github.com/devinjacknz/godydxhyber/backend/trading/killswitch"
+	"github.com/devinjacknz/godydxhyber/backend/trading/order"
+	"github.com/devinjacknz/godydxhyber/backend/trading/position"
+	"github.com/gin-gonic/gin"
+)
+
+// Session is the isolated paper portfolio of one sandbox API token. Its
+// managers start from a copy of the live open orders and positions and never
+// share state with them.
+type Session struct {
+	Name       string
+	Orders     order.OrderManager
+	Positions  *position.Manager
+	KillSwitch *killswitch.KillSwitch
+	SeededAt   time.Time
+}
+
+// Manager creates and holds the sandbox sessions
+type Manager struct {
+	liveOrders    order.OrderManager
+	livePositions *position.Manager
+	config        killswitch.Config
+	sessions      map[string]*Session
+	mu            sync.Mutex
+}
+
+// NewManager creates a sandbox manager that seeds sessions from the live
+// order and position managers. The position manager may be nil. config is
+// used for the kill switch of each session.
+func NewManager(orders order.OrderManager, positions *position.Manager, config killswitch.Config) *Manager {
+	config.Paper = true
+	return &Manager{
+		liveOrders:    orders,
+		livePositions: positions,
+		config:        config,
+		sessions:      make(map[string]*Session),
+	}
+}
+
+// Session returns the session for a sandbox token, seeding it from the live
+// state on first use
+func (m *Manager) Session(ctx context.Context, name string) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if session, ok := m.sessions[name]; ok {
+		return session, nil
+	}
+	return m.seed(ctx, name)
+}
+
+// Reset discards a session and seeds a new one from the current live state
+func (m *Manager) Reset(ctx context.Context, name string) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.sessions, name)
+	return m.seed(ctx, name)
+}
+
+// Resolve returns the session for a request made with a sandbox token, or
+// nil for requests that act on the live portfolio
+func (m *Manager) Resolve(c *gin.Context) (*Session, error) {
+	token, ok := middleware.TokenFromContext(c)
+	if !ok || !token.Sandbox {
+		return nil, nil
+	}
+	return m.Session(c.Request.Context(), token.Name)
+}
+
+// KillSwitchResolver routes kill switch calls made with a sandbox token to
+// the session's kill switch and all other calls to live
+func (m *Manager) KillSwitchResolver(live *killswitch.KillSwitch) killswitch.Resolver {
+	return func(c *gin.Context) (*killswitch.KillSwitch, error) {
+		session, err := m.Resolve(c)
+		if err != nil {
+			return nil, err
+		}
+		if session == nil {
+			return live, nil
+		}
+		return session.KillSwitch, nil
+	}
+}
+
+// OrderResolver routes order calls made with a sandbox token to the
+// session's paper orders and all other calls to live
+func (m *Manager) OrderResolver(live order.OrderManager) order.Resolver {
+	return func(c *gin.Context) (order.OrderManager, error) {
+		session, err := m.Resolve(c)
+		if err != nil {
+			return nil, err
+		}
+		if session == nil {
+			return live, nil
+		}
+		return session.Orders, nil
+	}
+}
+
+// PositionResolver routes position calls made with a sandbox token to the
+// session's paper positions and all other calls to live
+func (m *Manager) PositionResolver(live *position.Manager) position.Resolver {
+	return func(c *gin.Context) (*position.Manager, error) {
+		session, err := m.Resolve(c)
+		if err != nil {
+			return nil, err
+		}
+		if session == nil {
+			return live, nil
+		}
+		return session.Positions, nil
+	}
+}
+
+// seed builds a session from copies of the live open orders and positions.
+// Callers hold m.mu.
+func (m *Manager) seed(ctx context.Context, name string) (*Session, error) {
+	store := newPaperStore()
+
+	live, err := m.liveOrders.ListOrders(ctx, order.OrderFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list live orders: %w", err)
+	}
+	for _, o := range live {
+		snapshot := o.Snapshot()
+		if isOpenOrder(snapshot.Status) {
+			store.orders[snapshot.ID] = snapshot
+		}
+	}
+
+	if m.livePositions != nil {
+		status := position.Open
+		positions, err := m.livePositions.ListPositions(ctx, position.PositionFilter{Status: &status})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list live positions: %w", err)
+		}
+		for _, p := range positions {
+			snapshot := p.Snapshot()
+			store.positions[snapshot.ID] = snapshot
+		}
+	}
+
+	orders := &paperOrders{OrderManager: order.NewOrderManager(order.WithStore(store))}
+	if _, err := orders.Recover(ctx); err != nil {
+		return nil, fmt.Errorf("failed to seed sandbox orders: %w", err)
+	}
+	positions := position.NewManager(position.WithStore(store))
+	if _, err := positions.Recover(ctx); err != nil {
+		return nil, fmt.Errorf("failed to seed sandbox positions: %w", err)
+	}
+
+	session := &Session{
+		Name:       name,
+		Orders:     orders,
+		Positions:  positions,
+		KillSwitch: killswitch.NewKillSwitch(orders, positions, m.config),
+		SeededAt:   time.Now(),
+	}
+	m.sessions[name] = session
+	return session, nil
+}
+
+// paperOrders keeps the halt state of a paper portfolio out of the live
+// order_creation_halted indicator
+type paperOrders struct {
+	order.OrderManager
+	halted     bool
+	haltReason string
+	mu         sync.RWMutex
+}
+
+func (o *paperOrders) CreateOrder(ctx context.Context, params order.CreateOrderParams) (*order.Order, error) {
+	if halted, _ := o.IsHalted(); halted {
+		return nil, order.ErrTradingHalted
+	}
+	return o.OrderManager.CreateOrder(ctx, params)
+}
+
+func (o *paperOrders) Halt(reason string) {
+	o.mu.Lock()
+	o.halted = true
+	o.haltReason = reason
+	o.mu.Unlock()
+}
+
+func (o *paperOrders) Resume() {
+	o.mu.Lock()
+	o.halted = false
+	o.haltReason = ""
+	o.mu.Unlock()
+}
+
+func (o *paperOrders) IsHalted() (bool, string) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.halted, o.haltReason
+}
+
+// paperStore holds a session's orders and positions in memory
+type paperStore struct {
+	orders    map[string]*order.Order
+	positions map[string]*position.Position
+	mu        sync.Mutex
+}
+
+func newPaperStore() *paperStore {
+	return &paperStore{
+		orders:    make(map[string]*order.Order),
+		positions: make(map[string]*position.Position),
+	}
+}
+
+func (s *paperStore) SaveOrder(ctx context.Context, o *order.Order) error {
+	s.mu.Lock()
+	s.orders[o.ID] = o
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *paperStore) LoadOpenOrders(ctx context.Context) ([]*order.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	orders := make([]*order.Order, 0, len(s.orders))
+	for _, o := range s.orders {
+		if isOpenOrder(o.Status) {
+			orders = append(orders, o)
+		}
+	}
+	return orders, nil
+}
+
+func (s *paperStore) SavePosition(ctx context.Context, p *position.Position) error {
+	s.mu.Lock()
+	s.positions[p.ID] = p
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *paperStore) LoadOpenPositions(ctx context.Context) ([]*position.Position, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	positions := make([]*position.Position, 0, len(s.positions))
+	for _, p := range s.positions {
+		if p.Status == position.Open {
+			positions = append(positions, p)
+		}
+	}
+	return positions, nil
+}
+
+func isOpenOrder(status order.OrderStatus) bool {
+	return status == order.Created || status == order.Pending || status == order.PartiallyFilled
+}
@@ -0,0 +1,125 @@
+package sandbox
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/devinjacknz/godydxhyber/backend/middleware"
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+	"github.com/devinjacknz/godydxhyber/backend/trading/killswitch"
+	"github.com/devinjacknz/godydxhyber/backend/trading/order"
+	"github.com/devinjacknz/godydxhyber/backend/trading/position"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSandbox(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	liveOrders := order.NewOrderManager()
+	livePositions := position.NewManager()
+	liveKillSwitch := killswitch.NewKillSwitch(liveOrders, livePositions, killswitch.Config{})
+
+	price := money.FromFloat(100.0)
+	resting, err := liveOrders.CreateOrder(ctx, order.CreateOrderParams{
+		Symbol: "SOL/USD",
+		Type:   order.Limit,
+		Side:   order.Buy,
+		Price:  &price,
+		Size:   money.NewFromInt(2),
+	})
+	require.NoError(t, err)
+	open, err := livePositions.OpenPosition(ctx, position.OpenPositionParams{
+		Symbol:     "SOL/USD",
+		Side:       position.Long,
+		Size:       money.NewFromInt(1),
+		EntryPrice: money.NewFromInt(100),
+		Leverage:   1,
+	})
+	require.NoError(t, err)
+
+	tokens := middleware.NewTokenStore()
+	require.NoError(t, tokens.LoadTokens("ops:admin:live-secret,integrator:admin:sandbox-secret::sandbox"))
+
+	sandboxes := NewManager(liveOrders, livePositions, killswitch.Config{})
+	r := gin.New()
+	api := r.Group("/api/v1", middleware.AuthMiddleware(tokens, middleware.DefaultAuthConfig()))
+	killswitch.RegisterRoutesWithResolver(api, sandboxes.KillSwitchResolver(liveKillSwitch))
+	sandboxes.RegisterRoutes(api)
+
+	call := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Sandbox session is seeded from live state", func(t *testing.T) {
+		w := call(http.MethodGet, "/api/v1/sandbox", "sandbox-secret", "")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp sessionResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "integrator", resp.Name)
+		require.Len(t, resp.Orders, 1)
+		assert.Equal(t, resting.ID, resp.Orders[0].ID)
+		require.Len(t, resp.Positions, 1)
+		assert.Equal(t, open.ID, resp.Positions[0].ID)
+	})
+
+	t.Run("Sandbox kill switch never touches live orders", func(t *testing.T) {
+		w := call(http.MethodPost, "/api/v1/killswitch/trigger", "sandbox-secret", `{"reason":"integration test","flatten":true}`)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var state killswitch.State
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &state))
+		assert.True(t, state.Active)
+		assert.Equal(t, 1, state.CancelledOrders)
+		assert.Equal(t, 1, state.ClosedPositions)
+
+		session, err := sandboxes.Session(ctx, "integrator")
+		require.NoError(t, err)
+		paper, err := session.Orders.GetOrder(ctx, resting.ID)
+		require.NoError(t, err)
+		assert.Equal(t, order.Cancelled, paper.Snapshot().Status)
+		_, err = session.Orders.CreateOrder(ctx, order.CreateOrderParams{Symbol: "SOL/USD", Type: order.Market, Side: order.Buy, Size: money.NewFromInt(1)})
+		assert.Equal(t, order.ErrTradingHalted, err)
+
+		live, err := liveOrders.GetOrder(ctx, resting.ID)
+		require.NoError(t, err)
+		assert.Equal(t, order.Created, live.Snapshot().Status)
+		livePosition, err := livePositions.GetPosition(ctx, open.ID)
+		require.NoError(t, err)
+		assert.Equal(t, position.Open, livePosition.Snapshot().Status)
+		halted, _ := liveOrders.IsHalted()
+		assert.False(t, halted)
+		assert.False(t, liveKillSwitch.Status().Active)
+
+		w = call(http.MethodGet, "/api/v1/killswitch", "live-secret", "")
+		assert.JSONEq(t, `{"active":false,"cancelled_orders":0,"closed_positions":0}`, w.Body.String())
+	})
+
+	t.Run("Reset reseeds from live state", func(t *testing.T) {
+		w := call(http.MethodPost, "/api/v1/sandbox/reset", "sandbox-secret", "")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp sessionResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.Orders, 1)
+		assert.Equal(t, order.Created, resp.Orders[0].Status)
+		assert.False(t, resp.KillSwitch.Active)
+	})
+
+	t.Run("Sandbox endpoints require a sandbox token", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, call(http.MethodGet, "/api/v1/sandbox", "live-secret", "").Code)
+		assert.Equal(t, http.StatusForbidden, call(http.MethodPost, "/api/v1/sandbox/reset", "live-secret", "").Code)
+	})
+}
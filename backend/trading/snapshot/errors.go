@@ -0,0 +1,16 @@
+package snapshot
+
+import "errors"
+
+var (
+	// ErrNoSnapshot is returned by a Repository without a saved snapshot
+	ErrNoSnapshot = errors.New("no snapshot saved")
+
+	// ErrStaleSnapshot is returned when the saved snapshot is older than the
+	// configured MaxAge
+	ErrStaleSnapshot = errors.New("snapshot too old to restore")
+
+	// ErrUnsupportedVersion is returned for snapshots written in another
+	// format version
+	ErrUnsupportedVersion = errors.New("unsupported snapshot version")
+)
@@ -0,0 +1,38 @@
+package snapshot
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes registers the snapshot HTTP endpoints
+func (m *Manager) RegisterRoutes(r gin.IRouter) {
+	r.GET("/snapshot", m.handleGet)
+	r.POST("/snapshot", m.handleSave)
+}
+
+// handleGet returns the saved snapshot
+func (m *Manager) handleGet(c *gin.Context) {
+	snapshot, err := m.repository.LoadSnapshot(c.Request.Context())
+	switch {
+	case errors.Is(err, ErrNoSnapshot):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, snapshot)
+	}
+}
+
+// handleSave takes and saves a snapshot on demand, such as before a
+// deployment
+func (m *Manager) handleSave(c *gin.Context) {
+	snapshot, err := m.Save(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, snapshot)
+}
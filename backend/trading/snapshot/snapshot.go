@@ -0,0 +1,237 @@
+// Package snapshot saves the state of the trading engine, so that a planned
+// restart resumes with the open orders, positions, risk counters and
+// strategies it stopped with rather than flattening the book first.
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/trading/account"
+	"github.com/devinjacknz/godydxhyber/backend/trading/analysis/monitoring"
+	"github.com/devinjacknz/godydxhyber/backend/trading/order"
+	"github.com/devinjacknz/godydxhyber/backend/trading/position"
+	"github.com/devinjacknz/godydxhyber/backend/trading/risk"
+	"github.com/devinjacknz/godydxhyber/backend/trading/strategy"
+)
+
+// Version is the format version of the snapshots written by this package
+const Version = 1
+
+// Snapshot is the state of the engine at one point in time
+type Snapshot struct {
+	Version    int                  `json:"version"`
+	TakenAt    time.Time            `json:"taken_at"`
+	Accounts   []*AccountState      `json:"accounts"`
+	Strategies []*strategy.Strategy `json:"strategies"`
+}
+
+// AccountState is the state of one trading account
+type AccountState struct {
+	ID        string               `json:"id"`
+	Orders    []*order.Order       `json:"orders"`
+	Positions []*position.Position `json:"positions"`
+	// Daily holds the risk counters of the day the snapshot was taken, nil
+	// when the account had not traded yet
+	Daily *risk.DailyState `json:"daily,omitempty"`
+}
+
+// Repository persists the latest snapshot
+type Repository interface {
+	// SaveSnapshot replaces the saved snapshot
+	SaveSnapshot(ctx context.Context, snapshot *Snapshot) error
+	// LoadSnapshot returns the saved snapshot or ErrNoSnapshot
+	LoadSnapshot(ctx context.Context) (*Snapshot, error)
+}
+
+// Config contains snapshot configuration
+type Config struct {
+	// MaxAge is the age beyond which a saved snapshot is not restored, so
+	// that a process started long after the last one does not resume stale
+	// strategies. Zero restores snapshots of any age.
+	MaxAge time.Duration
+}
+
+// DefaultConfig returns the default snapshot configuration
+func DefaultConfig() Config {
+	return Config{MaxAge: time.Hour}
+}
+
+// RestoreReport summarizes the result of Restore
+type RestoreReport struct {
+	TakenAt  time.Time        `json:"taken_at"`
+	Accounts []*AccountReport `json:"accounts"`
+	// Strategies lists the restored strategies
+	Strategies []string `json:"strategies"`
+	// SkippedAccounts lists saved accounts that are no longer configured
+	SkippedAccounts []string `json:"skipped_accounts,omitempty"`
+}
+
+// AccountReport is the result of restoring one account
+type AccountReport struct {
+	ID        string                   `json:"id"`
+	Orders    *order.RecoveryReport    `json:"orders"`
+	Positions *position.RecoveryReport `json:"positions"`
+	// Daily reports whether the risk counters of the day were restored
+	Daily bool `json:"daily"`
+}
+
+// Manager takes snapshots of the accounts and strategies of the engine and
+// restores them
+type Manager struct {
+	accounts   *account.Registry
+	strategies *strategy.Registry
+	repository Repository
+	config     Config
+	now        func() time.Time
+}
+
+// NewManager creates a snapshot manager saving to repository
+func NewManager(accounts *account.Registry, strategies *strategy.Registry, repository Repository, config Config) *Manager {
+	return &Manager{
+		accounts:   accounts,
+		strategies: strategies,
+		repository: repository,
+		config:     config,
+		now:        time.Now,
+	}
+}
+
+// Take captures the state of the engine. Only open orders and positions are
+// kept. Orders and positions changing meanwhile may be captured before or
+// after the change, so snapshots taken at shutdown, once strategies are
+// stopped and submissions drained, are the consistent ones.
+func (m *Manager) Take(ctx context.Context) (*Snapshot, error) {
+	now := m.now()
+	snapshot := &Snapshot{
+		Version:    Version,
+		TakenAt:    now,
+		Strategies: m.strategies.List(ctx),
+	}
+
+	for _, a := range m.accounts.List() {
+		state := &AccountState{ID: a.ID}
+
+		orders, err := a.Orders.ListOrders(ctx, order.OrderFilter{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list orders of account %s: %w", a.ID, err)
+		}
+		for _, o := range orders {
+			if o := o.Snapshot(); isOpenOrder(o.Status) {
+				state.Orders = append(state.Orders, o)
+			}
+		}
+
+		status := position.Open
+		positions, err := a.Positions.ListPositions(ctx, position.PositionFilter{Status: &status})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list positions of account %s: %w", a.ID, err)
+		}
+		for _, p := range positions {
+			state.Positions = append(state.Positions, p.Snapshot())
+		}
+
+		daily, err := a.Risk.GetDailyStates(ctx, now, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get daily state of account %s: %w", a.ID, err)
+		}
+		if len(daily) > 0 {
+			state.Daily = daily[len(daily)-1]
+		}
+
+		snapshot.Accounts = append(snapshot.Accounts, state)
+	}
+	return snapshot, nil
+}
+
+// Save takes a snapshot and saves it to the repository
+func (m *Manager) Save(ctx context.Context) (*Snapshot, error) {
+	start := time.Now()
+	defer func() {
+		monitoring.RecordIndicatorCalculation("save_snapshot", time.Since(start))
+	}()
+
+	snapshot, err := m.Take(ctx)
+	if err == nil {
+		err = m.repository.SaveSnapshot(ctx, snapshot)
+	}
+	if err != nil {
+		monitoring.RecordIndicatorError("save_snapshot", err.Error())
+		return nil, fmt.Errorf("failed to save snapshot: %w", err)
+	}
+	slog.InfoContext(ctx, "engine snapshot saved", "accounts", len(snapshot.Accounts), "strategies", len(snapshot.Strategies))
+	return snapshot, nil
+}
+
+// Restore loads the saved snapshot into the accounts and strategies. Orders
+// and positions are reconciled against the exchange like on recovery, so
+// that changes made while the engine was down are picked up. It returns
+// ErrNoSnapshot when nothing was saved and ErrStaleSnapshot when the saved
+// snapshot is older than the configured MaxAge.
+func (m *Manager) Restore(ctx context.Context) (*RestoreReport, error) {
+	start := time.Now()
+	defer func() {
+		monitoring.RecordIndicatorCalculation("restore_snapshot", time.Since(start))
+	}()
+
+	snapshot, err := m.repository.LoadSnapshot(ctx)
+	if err != nil {
+		if !errors.Is(err, ErrNoSnapshot) {
+			monitoring.RecordIndicatorError("restore_snapshot", err.Error())
+		}
+		return nil, err
+	}
+	if snapshot.Version != Version {
+		return nil, fmt.Errorf("%w: version %d", ErrUnsupportedVersion, snapshot.Version)
+	}
+	if age := m.now().Sub(snapshot.TakenAt); m.config.MaxAge > 0 && age > m.config.MaxAge {
+		return nil, fmt.Errorf("%w: taken %s ago", ErrStaleSnapshot, age.Round(time.Second))
+	}
+
+	report := &RestoreReport{TakenAt: snapshot.TakenAt}
+	for _, state := range snapshot.Accounts {
+		a, err := m.accounts.Get(state.ID)
+		if err != nil {
+			report.SkippedAccounts = append(report.SkippedAccounts, state.ID)
+			continue
+		}
+		restored, err := restoreAccount(ctx, a, state)
+		if err != nil {
+			monitoring.RecordIndicatorError("restore_snapshot", err.Error())
+			return nil, err
+		}
+		report.Accounts = append(report.Accounts, restored)
+	}
+
+	if report.Strategies, err = m.strategies.Restore(ctx, snapshot.Strategies); err != nil {
+		monitoring.RecordIndicatorError("restore_snapshot", err.Error())
+		return nil, fmt.Errorf("failed to restore strategies: %w", err)
+	}
+	return report, nil
+}
+
+func restoreAccount(ctx context.Context, a *account.Account, state *AccountState) (*AccountReport, error) {
+	report := &AccountReport{ID: a.ID}
+
+	var err error
+	if report.Orders, err = a.Orders.Restore(ctx, state.Orders); err != nil {
+		return nil, fmt.Errorf("failed to restore orders of account %s: %w", a.ID, err)
+	}
+	if report.Positions, err = a.Positions.Restore(ctx, state.Positions); err != nil {
+		return nil, fmt.Errorf("failed to restore positions of account %s: %w", a.ID, err)
+	}
+	if state.Daily != nil {
+		if err := a.Risk.RestoreDailyState(ctx, state.Daily); err != nil {
+			return nil, fmt.Errorf("failed to restore daily state of account %s: %w", a.ID, err)
+		}
+		report.Daily = true
+	}
+	return report, nil
+}
+
+func isOpenOrder(status order.OrderStatus) bool {
+	return status == order.Created || status == order.Pending || status == order.PartiallyFilled
+}
@@ -0,0 +1,183 @@
+package snapshot
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/devinjacknz/godydxhyber/backend/pkg/money"
+	"github.com/devinjacknz/godydxhyber/backend/trading/account"
+	"github.com/devinjacknz/godydxhyber/backend/trading/killswitch"
+	"github.com/devinjacknz/godydxhyber/backend/trading/order"
+	"github.com/devinjacknz/godydxhyber/backend/trading/position"
+	"github.com/devinjacknz/godydxhyber/backend/trading/risk"
+	"github.com/devinjacknz/godydxhyber/backend/trading/strategy"
+)
+
+// engine is the state a snapshot is taken from and restored into
+type engine struct {
+	accounts   *account.Registry
+	strategies *strategy.Registry
+}
+
+func newEngine(t *testing.T, ids ...string) *engine {
+	e := &engine{accounts: account.NewRegistry(), strategies: strategy.NewRegistry()}
+	for _, id := range ids {
+		orders := order.NewOrderManager(order.WithAccountID(id))
+		positions := position.NewManager(position.WithAccountID(id))
+		require.NoError(t, e.accounts.Register(&account.Account{
+			ID:         id,
+			Orders:     orders,
+			Positions:  positions,
+			Risk:       risk.NewRiskManager(risk.WithAccountID(id), risk.WithStore(risk.NewMemoryStore())),
+			KillSwitch: killswitch.NewKillSwitch(orders, positions, killswitch.Config{}),
+		}))
+	}
+	_, err := e.strategies.Register(context.Background(), "momentum", map[string]interface{}{"period": 14.0})
+	require.NoError(t, err)
+	return e
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	ctx := context.Background()
+	repository := NewFileRepository(filepath.Join(t.TempDir(), "state", "snapshot.json"))
+
+	before := newEngine(t, account.Default, "wallet-2")
+	a, err := before.accounts.Get(account.Default)
+	require.NoError(t, err)
+
+	price := money.FromFloat(50000.0)
+	resting, err := a.Orders.CreateOrder(ctx, order.CreateOrderParams{Symbol: "BTC-USD", Type: order.Limit, Side: order.Buy, Price: &price, Size: money.FromFloat(0.5), PostOnly: true})
+	require.NoError(t, err)
+	cancelled, err := a.Orders.CreateOrder(ctx, order.CreateOrderParams{Symbol: "ETH-USD", Type: order.Market, Side: order.Sell, Size: money.NewFromInt(1)})
+	require.NoError(t, err)
+	require.NoError(t, a.Orders.CancelOrder(ctx, cancelled.ID))
+
+	open, err := a.Positions.OpenPosition(ctx, position.OpenPositionParams{Symbol: "BTC-USD", Side: position.Long, Size: money.FromFloat(0.2), EntryPrice: money.NewFromInt(49000), Leverage: 5})
+	require.NoError(t, err)
+	closed, err := a.Positions.OpenPosition(ctx, position.OpenPositionParams{Symbol: "SOL-USD", Side: position.Short, Size: money.NewFromInt(10), EntryPrice: money.NewFromInt(150), Leverage: 2})
+	require.NoError(t, err)
+	require.NoError(t, a.Positions.ClosePosition(ctx, closed.ID, money.NewFromInt(140)))
+
+	require.NoError(t, a.Risk.RecordTrade(ctx, risk.TradeRecord{Symbol: "ETH-USD"}))
+	require.NoError(t, a.Risk.RecordRealizedPnL(ctx, -120.5))
+	require.NoError(t, a.Risk.RecordCommission(ctx, 3.25))
+
+	_, err = before.strategies.SetEnabled(ctx, "momentum", true)
+	require.NoError(t, err)
+	_, err = before.strategies.UpdateConfig(ctx, "momentum", map[string]interface{}{"threshold": 0.7})
+	require.NoError(t, err)
+	_, err = before.strategies.SetState(ctx, "momentum", map[string]interface{}{"last_signal": "long"})
+	require.NoError(t, err)
+	_, err = before.strategies.SetSchedule(ctx, "momentum", &strategy.Schedule{Windows: []strategy.Window{{Start: "00:00", End: "00:00"}}})
+	require.NoError(t, err)
+
+	saved, err := NewManager(before.accounts, before.strategies, repository, DefaultConfig()).Save(ctx)
+	require.NoError(t, err)
+	require.Len(t, saved.Accounts, 2)
+	assert.Len(t, saved.Accounts[0].Orders, 1, "only open orders are saved")
+	assert.Len(t, saved.Accounts[0].Positions, 1, "only open positions are saved")
+	assert.Nil(t, saved.Accounts[1].Daily)
+
+	// The restarted engine is configured without wallet-2 and with the
+	// strategy disabled
+	after := newEngine(t, account.Default)
+	report, err := NewManager(after.accounts, after.strategies, repository, DefaultConfig()).Restore(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"wallet-2"}, report.SkippedAccounts)
+	assert.Equal(t, []string{"momentum"}, report.Strategies)
+	require.Len(t, report.Accounts, 1)
+	assert.Equal(t, 1, report.Accounts[0].Orders.Loaded)
+	assert.Equal(t, 1, report.Accounts[0].Positions.Loaded)
+	assert.True(t, report.Accounts[0].Daily)
+
+	b, err := after.accounts.Get(account.Default)
+	require.NoError(t, err)
+
+	o, err := b.Orders.GetOrder(ctx, resting.ID)
+	require.NoError(t, err)
+	assert.Equal(t, order.Created, o.Status)
+	assert.Equal(t, 50000.0, o.Price.Float64())
+	assert.True(t, o.PostOnly)
+	_, err = b.Orders.GetOrder(ctx, cancelled.ID)
+	assert.ErrorIs(t, err, order.ErrOrderNotFound)
+
+	p, err := b.Positions.GetPosition(ctx, open.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0.2, p.Size.Float64())
+	assert.Equal(t, 49000.0, p.EntryPrice.Float64())
+	assert.Equal(t, position.Open, p.Status)
+
+	states, err := b.Risk.GetDailyStates(ctx, time.Now(), time.Now())
+	require.NoError(t, err)
+	require.Len(t, states, 1)
+	assert.Equal(t, 1, states[0].Trades)
+	assert.Equal(t, -120.5, states[0].RealizedPnL)
+	assert.Equal(t, 3.25, states[0].Commissions)
+	assert.Contains(t, states[0].LastTrade, "ETH-USD")
+
+	s, err := after.strategies.Get(ctx, "momentum")
+	require.NoError(t, err)
+	assert.True(t, s.Enabled)
+	assert.Equal(t, map[string]interface{}{"period": 14.0, "threshold": 0.7}, s.Config)
+	assert.Equal(t, map[string]interface{}{"last_signal": "long"}, s.State)
+	require.NotNil(t, s.Schedule)
+	assert.True(t, s.InSession)
+	assert.True(t, after.strategies.IsEnabled("momentum"))
+}
+
+func TestRestoreErrors(t *testing.T) {
+	ctx := context.Background()
+	e := newEngine(t, account.Default)
+	repository := NewMemoryRepository()
+	m := NewManager(e.accounts, e.strategies, repository, DefaultConfig())
+
+	_, err := m.Restore(ctx)
+	assert.ErrorIs(t, err, ErrNoSnapshot)
+	_, err = NewFileRepository(filepath.Join(t.TempDir(), "missing.json")).LoadSnapshot(ctx)
+	assert.ErrorIs(t, err, ErrNoSnapshot)
+
+	_, err = m.Save(ctx)
+	require.NoError(t, err)
+	m.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	_, err = m.Restore(ctx)
+	assert.ErrorIs(t, err, ErrStaleSnapshot)
+
+	// Without a maximum age any snapshot is restored
+	m.config.MaxAge = 0
+	_, err = m.Restore(ctx)
+	assert.NoError(t, err)
+
+	require.NoError(t, repository.SaveSnapshot(ctx, &Snapshot{Version: Version + 1, TakenAt: time.Now()}))
+	_, err = m.Restore(ctx)
+	assert.ErrorIs(t, err, ErrUnsupportedVersion)
+}
+
+func TestSnapshotRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	e := newEngine(t, account.Default)
+	m := NewManager(e.accounts, e.strategies, NewMemoryRepository(), DefaultConfig())
+
+	r := gin.New()
+	m.RegisterRoutes(r.Group("/api/v1"))
+	call := func(method string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, "/api/v1/snapshot", nil))
+		return w
+	}
+
+	assert.Equal(t, http.StatusNotFound, call(http.MethodGet).Code)
+	w := call(http.MethodPost)
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"name":"momentum"`)
+	w = call(http.MethodGet)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"id":"default"`)
+}
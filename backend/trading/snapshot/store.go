@@ -0,0 +1,153 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// DefaultRedisKey is the key RedisRepository saves the snapshot under
+const DefaultRedisKey = "gosol:snapshot"
+
+// MemoryRepository keeps the snapshot in memory
+type MemoryRepository struct {
+	data []byte
+	mu   sync.RWMutex
+}
+
+// NewMemoryRepository creates an in-memory snapshot repository
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{}
+}
+
+// SaveSnapshot replaces the saved snapshot
+func (r *MemoryRepository) SaveSnapshot(ctx context.Context, snapshot *Snapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	r.mu.Lock()
+	r.data = data
+	r.mu.Unlock()
+	return nil
+}
+
+// LoadSnapshot returns a copy of the saved snapshot
+func (r *MemoryRepository) LoadSnapshot(ctx context.Context) (*Snapshot, error) {
+	r.mu.RLock()
+	data := r.data
+	r.mu.RUnlock()
+	if data == nil {
+		return nil, ErrNoSnapshot
+	}
+	return decode(data)
+}
+
+// FileRepository saves the snapshot as a JSON file
+type FileRepository struct {
+	path string
+}
+
+// NewFileRepository creates a repository saving the snapshot to path
+func NewFileRepository(path string) *FileRepository {
+	return &FileRepository{path: path}
+}
+
+// SaveSnapshot writes the snapshot to a temporary file and renames it over
+// the saved one, so a crash while saving keeps the previous snapshot
+func (r *FileRepository) SaveSnapshot(ctx context.Context, snapshot *Snapshot) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	dir := filepath.Dir(r.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(r.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), r.path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write snapshot file: %w", err)
+	}
+	return nil
+}
+
+// LoadSnapshot reads the snapshot file
+func (r *FileRepository) LoadSnapshot(ctx context.Context) (*Snapshot, error) {
+	data, err := os.ReadFile(r.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoSnapshot
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot file: %w", err)
+	}
+	return decode(data)
+}
+
+// RedisRepository saves the snapshot as JSON under one Redis key
+type RedisRepository struct {
+	client *redis.Client
+	key    string
+}
+
+// NewRedisRepository creates a repository saving the snapshot under key, or
+// DefaultRedisKey when key is empty
+func NewRedisRepository(client *redis.Client, key string) *RedisRepository {
+	if key == "" {
+		key = DefaultRedisKey
+	}
+	return &RedisRepository{client: client, key: key}
+}
+
+// SaveSnapshot replaces the saved snapshot
+func (r *RedisRepository) SaveSnapshot(ctx context.Context, snapshot *Snapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	if err := r.client.Set(ctx, r.key, data, 0).Err(); err != nil {
+		return fmt.Errorf("failed to save snapshot to redis: %w", err)
+	}
+	return nil
+}
+
+// LoadSnapshot reads the saved snapshot
+func (r *RedisRepository) LoadSnapshot(ctx context.Context) (*Snapshot, error) {
+	data, err := r.client.Get(ctx, r.key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNoSnapshot
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load snapshot from redis: %w", err)
+	}
+	return decode(data)
+}
+
+func decode(data []byte) (*Snapshot, error) {
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+	return &snapshot, nil
+}
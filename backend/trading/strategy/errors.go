@@ -0,0 +1,17 @@
+package strategy
+
+import "errors"
+
+var (
+	// ErrStrategyNotFound is returned when a strategy is not registered
+	ErrStrategyNotFound = errors.New("strategy not found")
+
+	// ErrStrategyExists is returned when a strategy is registered twice
+	ErrStrategyExists = errors.New("strategy already registered")
+
+	// ErrInvalidName is returned when the strategy name is empty
+	ErrInvalidName = errors.New("invalid strategy name")
+
+	// ErrInvalidSchedule is returned when a trading schedule is malformed
+	ErrInvalidSchedule = errors.New("invalid trading schedule")
+)
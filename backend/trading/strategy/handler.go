@@ -0,0 +1,82 @@
+package strategy
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes registers the strategy HTTP endpoints
+func (r *Registry) RegisterRoutes(g gin.IRouter) {
+	g.GET("/strategies", r.handleList)
+	g.GET("/strategies/:name", r.handleGet)
+	g.POST("/strategies/:name/enable", r.handleEnable)
+	g.POST("/strategies/:name/disable", r.handleDisable)
+	g.PUT("/strategies/:name/config", r.handleConfig)
+	g.PUT("/strategies/:name/schedule", r.handleSchedule)
+	g.DELETE("/strategies/:name/schedule", r.handleClearSchedule)
+}
+
+func (r *Registry) handleList(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"strategies": r.List(c.Request.Context())})
+}
+
+func (r *Registry) handleGet(c *gin.Context) {
+	s, err := r.Get(c.Request.Context(), c.Param("name"))
+	respond(c, s, err)
+}
+
+func (r *Registry) handleEnable(c *gin.Context) {
+	s, err := r.SetEnabled(c.Request.Context(), c.Param("name"), true)
+	respond(c, s, err)
+}
+
+func (r *Registry) handleDisable(c *gin.Context) {
+	s, err := r.SetEnabled(c.Request.Context(), c.Param("name"), false)
+	respond(c, s, err)
+}
+
+func (r *Registry) handleConfig(c *gin.Context) {
+	var config map[string]interface{}
+	if err := c.ShouldBindJSON(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(config) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "config must not be empty"})
+		return
+	}
+
+	s, err := r.UpdateConfig(c.Request.Context(), c.Param("name"), config)
+	respond(c, s, err)
+}
+
+func (r *Registry) handleSchedule(c *gin.Context) {
+	var schedule Schedule
+	if err := c.ShouldBindJSON(&schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	s, err := r.SetSchedule(c.Request.Context(), c.Param("name"), &schedule)
+	respond(c, s, err)
+}
+
+func (r *Registry) handleClearSchedule(c *gin.Context) {
+	s, err := r.SetSchedule(c.Request.Context(), c.Param("name"), nil)
+	respond(c, s, err)
+}
+
+func respond(c *gin.Context, s *Strategy, err error) {
+	switch {
+	case errors.Is(err, ErrStrategyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidSchedule):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, s)
+	}
+}
@@ -0,0 +1,323 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/trading/analysis/monitoring"
+)
+
+// SessionEventType is the monitoring event type used for session audit events
+const SessionEventType = "strategy_session"
+
+// OutsideAction is what happens to the positions of a strategy when its
+// session closes. Signal generation is paused in every case.
+type OutsideAction string
+
+const (
+	// ActionPause only pauses signal generation
+	ActionPause OutsideAction = "pause"
+	// ActionProtect places a stop on the open positions
+	ActionProtect OutsideAction = "protect"
+	// ActionFlatten closes the open positions
+	ActionFlatten OutsideAction = "flatten"
+)
+
+// Window is a recurring window in which a strategy trades, such as weekdays
+// from 08:00 to 22:00. A window ending before it starts runs overnight into
+// the next day.
+type Window struct {
+	// Days are the days the window starts on, such as "mon", every day when
+	// empty
+	Days []string `json:"days,omitempty" yaml:"days" toml:"days"`
+	// Start and End are times of day as "15:04" in the schedule's time zone.
+	// Equal times span the whole day.
+	Start string `json:"start" yaml:"start" toml:"start"`
+	End   string `json:"end" yaml:"end" toml:"end"`
+}
+
+// MaintenanceWindow is a one-off period in which a strategy does not trade,
+// such as an exchange upgrade
+type MaintenanceWindow struct {
+	Start  time.Time `json:"start" yaml:"start" toml:"start"`
+	End    time.Time `json:"end" yaml:"end" toml:"end"`
+	Reason string    `json:"reason,omitempty" yaml:"reason" toml:"reason"`
+}
+
+// Schedule is the trading calendar of a strategy
+type Schedule struct {
+	// Timezone is the IANA time zone of the windows, UTC when empty
+	Timezone string `json:"timezone,omitempty" yaml:"timezone" toml:"timezone"`
+	// Windows are the windows the strategy trades in, at all times when
+	// empty
+	Windows     []Window            `json:"windows,omitempty" yaml:"windows" toml:"windows"`
+	Maintenance []MaintenanceWindow `json:"maintenance,omitempty" yaml:"maintenance" toml:"maintenance"`
+	// Outside is the action taken when the session closes, ActionPause when
+	// empty
+	Outside OutsideAction `json:"outside,omitempty" yaml:"outside" toml:"outside"`
+	// Symbols are the markets the strategy trades, whose positions Outside
+	// applies to. Positions are held per account, so when empty it applies
+	// to every position of the account trading the strategy.
+	Symbols []string `json:"symbols,omitempty" yaml:"symbols" toml:"symbols"`
+	// ProtectStop is the distance of the protective stop from the current
+	// price, as a fraction of it
+	ProtectStop float64 `json:"protect_stop,omitempty" yaml:"protect_stop" toml:"protect_stop"`
+
+	location *time.Location
+	windows  []window
+}
+
+// DefaultProtectStop is the protective stop distance of schedules without one
+const DefaultProtectStop = 0.02
+
+// window is a parsed Window, times in minutes from midnight
+type window struct {
+	days       map[time.Weekday]bool
+	start, end int
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// compile validates a schedule and returns a copy ready to be evaluated
+func (s Schedule) compile() (*Schedule, error) {
+	location, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+	s.location = location
+
+	switch s.Outside {
+	case "":
+		s.Outside = ActionPause
+	case ActionPause, ActionProtect, ActionFlatten:
+	default:
+		return nil, fmt.Errorf("%w: unknown outside action %q", ErrInvalidSchedule, s.Outside)
+	}
+	if s.ProtectStop < 0 || s.ProtectStop >= 1 {
+		return nil, fmt.Errorf("%w: protect_stop must be between 0 and 1", ErrInvalidSchedule)
+	}
+	if s.ProtectStop == 0 {
+		s.ProtectStop = DefaultProtectStop
+	}
+
+	s.windows = make([]window, 0, len(s.Windows))
+	for i, w := range s.Windows {
+		parsed := window{days: make(map[time.Weekday]bool, len(w.Days))}
+		for _, day := range w.Days {
+			weekday, ok := weekdays[strings.ToLower(day)]
+			if !ok {
+				return nil, fmt.Errorf("%w: windows[%d]: unknown day %q", ErrInvalidSchedule, i, day)
+			}
+			parsed.days[weekday] = true
+		}
+		if parsed.start, err = minuteOfDay(w.Start); err == nil {
+			parsed.end, err = minuteOfDay(w.End)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: windows[%d]: %v", ErrInvalidSchedule, i, err)
+		}
+		s.windows = append(s.windows, parsed)
+	}
+	for i, m := range s.Maintenance {
+		if !m.End.After(m.Start) {
+			return nil, fmt.Errorf("%w: maintenance[%d] must end after it starts", ErrInvalidSchedule, i)
+		}
+	}
+
+	s.Windows = append([]Window(nil), s.Windows...)
+	s.Maintenance = append([]MaintenanceWindow(nil), s.Maintenance...)
+	s.Symbols = append([]string(nil), s.Symbols...)
+	return &s, nil
+}
+
+func minuteOfDay(v string) (int, error) {
+	t, err := time.Parse("15:04", v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q", v)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// active reports whether the strategy may trade at t and, when it may not,
+// why. Maintenance windows take precedence over trading windows.
+func (s *Schedule) active(t time.Time) (bool, string) {
+	for _, m := range s.Maintenance {
+		if !t.Before(m.Start) && t.Before(m.End) {
+			if m.Reason != "" {
+				return false, "maintenance: " + m.Reason
+			}
+			return false, "maintenance"
+		}
+	}
+	if len(s.windows) == 0 {
+		return true, ""
+	}
+
+	local := t.In(s.location)
+	for _, w := range s.windows {
+		if w.contains(local) {
+			return true, ""
+		}
+	}
+	return false, "outside trading windows"
+}
+
+func (w window) contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	startsOn := func(day time.Weekday) bool {
+		return len(w.days) == 0 || w.days[day]
+	}
+
+	if w.start < w.end {
+		return startsOn(t.Weekday()) && minute >= w.start && minute < w.end
+	}
+	// Overnight windows are open from the start to midnight on their days
+	// and from midnight to the end on the following days
+	if startsOn(t.Weekday()) && minute >= w.start {
+		return true
+	}
+	return startsOn((t.Weekday()+6)%7) && minute < w.end
+}
+
+// SessionHandler acts on the positions of a strategy when its session
+// closes, as set by the Outside action of its schedule
+type SessionHandler interface {
+	SessionClosed(ctx context.Context, name string, schedule Schedule) error
+}
+
+// Scheduler watches the sessions of the enabled strategies and calls the
+// handler when one closes. Signal generation needs no scheduler: IsEnabled
+// is false outside the session.
+type Scheduler struct {
+	registry  *Registry
+	handler   SessionHandler
+	interval  time.Duration
+	heartbeat Heartbeat
+	// open is the last session state of each enabled strategy with a
+	// schedule
+	open map[string]bool
+	mu   sync.Mutex
+}
+
+// Heartbeat is told after every check of the scheduler, so that a watchdog
+// can tell a stuck strategy loop from an idle one
+type Heartbeat interface {
+	Beat()
+}
+
+// NewScheduler creates a scheduler checking the sessions on every interval.
+// The handler may be nil when positions are left as they are.
+func NewScheduler(registry *Registry, handler SessionHandler, interval time.Duration) *Scheduler {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	return &Scheduler{
+		registry: registry,
+		handler:  handler,
+		interval: interval,
+		open:     make(map[string]bool),
+	}
+}
+
+// SetHeartbeat sets the heartbeat told after every check
+func (s *Scheduler) SetHeartbeat(heartbeat Heartbeat) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.heartbeat = heartbeat
+}
+
+// Run checks the sessions on every interval until the context is cancelled
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.Check(ctx)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			s.Check(ctx)
+		}
+	}
+}
+
+// Check compares the session of every enabled strategy with its last state
+// and calls the handler for those that closed since. A strategy found
+// outside its session on the first check is handled too, so positions left
+// over a restart are protected.
+func (s *Scheduler) Check(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[string]bool)
+	for _, st := range s.registry.List(ctx) {
+		if !st.Enabled || st.Schedule == nil {
+			continue
+		}
+		seen[st.Name] = true
+		was, known := s.open[st.Name]
+		s.open[st.Name] = st.InSession
+		if known && was == st.InSession {
+			continue
+		}
+		if st.InSession {
+			if known {
+				s.record(st, monitoring.SeverityInfo, "Strategy session opened", nil)
+			}
+			continue
+		}
+
+		var err error
+		if s.handler != nil && st.Schedule.Outside != ActionPause {
+			err = s.handler.SessionClosed(ctx, st.Name, *st.Schedule)
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to close strategy session", "strategy", st.Name, "action", st.Schedule.Outside, "error", err)
+			s.record(st, monitoring.SeverityError, "Strategy session closed", err)
+			continue
+		}
+		s.record(st, monitoring.SeverityWarning, "Strategy session closed", nil)
+	}
+	for name := range s.open {
+		if !seen[name] {
+			delete(s.open, name)
+		}
+	}
+	if s.heartbeat != nil {
+		s.heartbeat.Beat()
+	}
+}
+
+func (s *Scheduler) record(st *Strategy, severity monitoring.EventSeverity, message string, err error) {
+	details := map[string]interface{}{
+		"strategy": st.Name,
+		"action":   string(st.Schedule.Outside),
+	}
+	if st.SessionReason != "" {
+		details["reason"] = st.SessionReason
+	}
+	if err != nil {
+		details["error"] = err.Error()
+	}
+	monitoring.RecordEvent(monitoring.Event{
+		Type:     SessionEventType,
+		Severity: severity,
+		Message:  message,
+		Details:  details,
+	})
+}
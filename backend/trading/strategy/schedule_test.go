@@ -0,0 +1,154 @@
+package strategy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedule(t *testing.T) {
+	schedule, err := Schedule{
+		Timezone: "America/New_York",
+		Windows: []Window{
+			{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "08:00", End: "22:00"},
+			{Days: []string{"Sun"}, Start: "20:00", End: "02:00"},
+		},
+		Maintenance: []MaintenanceWindow{{
+			Start:  time.Date(2024, 6, 4, 14, 0, 0, 0, time.UTC),
+			End:    time.Date(2024, 6, 4, 16, 0, 0, 0, time.UTC),
+			Reason: "exchange upgrade",
+		}},
+	}.compile()
+	require.NoError(t, err)
+	assert.Equal(t, ActionPause, schedule.Outside)
+	assert.Equal(t, DefaultProtectStop, schedule.ProtectStop)
+
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	at := func(day, hour, minute int) time.Time {
+		// June 2024 starts on a Saturday
+		return time.Date(2024, 6, day, hour, minute, 0, 0, newYork)
+	}
+
+	tests := []struct {
+		name   string
+		t      time.Time
+		active bool
+		reason string
+	}{
+		{"weekday session", at(3, 9, 30), true, ""},
+		{"before the session", at(3, 7, 59), false, "outside trading windows"},
+		{"session end is exclusive", at(3, 22, 0), false, "outside trading windows"},
+		{"weekend", at(1, 12, 0), false, "outside trading windows"},
+		{"overnight window on its day", at(2, 21, 0), true, ""},
+		{"overnight window past midnight", at(3, 1, 59), true, ""},
+		{"overnight window over", at(3, 2, 0), false, "outside trading windows"},
+		{"maintenance", at(4, 10, 30), false, "maintenance: exchange upgrade"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			active, reason := schedule.active(tt.t)
+			assert.Equal(t, tt.active, active)
+			assert.Equal(t, tt.reason, reason)
+		})
+	}
+
+	invalid := []Schedule{
+		{Timezone: "Mars/Olympus"},
+		{Outside: "close"},
+		{ProtectStop: 1},
+		{Windows: []Window{{Days: []string{"someday"}, Start: "08:00", End: "09:00"}}},
+		{Windows: []Window{{Start: "8am", End: "09:00"}}},
+		{Maintenance: []MaintenanceWindow{{Start: at(3, 10, 0), End: at(3, 9, 0)}}},
+	}
+	for _, s := range invalid {
+		_, err := s.compile()
+		assert.ErrorIs(t, err, ErrInvalidSchedule)
+	}
+}
+
+type sessionHandler struct {
+	closed []string
+	err    error
+}
+
+func (h *sessionHandler) SessionClosed(ctx context.Context, name string, schedule Schedule) error {
+	h.closed = append(h.closed, name+":"+string(schedule.Outside))
+	return h.err
+}
+
+func TestScheduler(t *testing.T) {
+	ctx := context.Background()
+	registry := NewRegistry()
+	now := time.Date(2024, 6, 3, 12, 0, 0, 0, time.UTC)
+	registry.now = func() time.Time { return now }
+
+	for _, name := range []string{"momentum", "grid", "always"} {
+		_, err := registry.Register(ctx, name, nil)
+		require.NoError(t, err)
+		_, err = registry.SetEnabled(ctx, name, true)
+		require.NoError(t, err)
+	}
+	weekdays := []Window{{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "00:00", End: "00:00"}}
+	_, err := registry.SetSchedule(ctx, "momentum", &Schedule{Windows: weekdays, Outside: ActionFlatten})
+	require.NoError(t, err)
+	s, err := registry.SetSchedule(ctx, "grid", &Schedule{Windows: weekdays})
+	require.NoError(t, err)
+	assert.True(t, s.InSession)
+	_, err = registry.SetSchedule(ctx, "grid", &Schedule{Outside: "close"})
+	assert.ErrorIs(t, err, ErrInvalidSchedule)
+
+	handler := &sessionHandler{}
+	scheduler := NewScheduler(registry, handler, time.Minute)
+	heartbeat := &countingHeartbeat{}
+	scheduler.SetHeartbeat(heartbeat)
+	scheduler.Check(ctx)
+	assert.Empty(t, handler.closed)
+	assert.Equal(t, 1, heartbeat.beats)
+	assert.True(t, registry.IsEnabled("momentum"))
+
+	// Saturday
+	now = now.Add(5 * 24 * time.Hour)
+	assert.False(t, registry.IsEnabled("momentum"))
+	assert.False(t, registry.IsEnabled("grid"))
+	assert.True(t, registry.IsEnabled("always"))
+	s, err = registry.Get(ctx, "grid")
+	require.NoError(t, err)
+	assert.False(t, s.InSession)
+	assert.Equal(t, "outside trading windows", s.SessionReason)
+
+	scheduler.Check(ctx)
+	assert.Equal(t, []string{"momentum:flatten"}, handler.closed, "paused strategies need no handling")
+	scheduler.Check(ctx)
+	assert.Len(t, handler.closed, 1, "handled once per session")
+
+	// Monday, and the session closes again the following weekend
+	now = now.Add(2 * 24 * time.Hour)
+	scheduler.Check(ctx)
+	now = now.Add(5 * 24 * time.Hour)
+	handler.err = errors.New("exchange unavailable")
+	scheduler.Check(ctx)
+	assert.Len(t, handler.closed, 2)
+	assert.Equal(t, 5, heartbeat.beats, "failed session handling still checks in")
+
+	// A strategy outside its session at start is handled
+	restarted := &sessionHandler{}
+	NewScheduler(registry, restarted, time.Minute).Check(ctx)
+	assert.Equal(t, []string{"momentum:flatten"}, restarted.closed)
+
+	_, err = registry.SetSchedule(ctx, "momentum", nil)
+	require.NoError(t, err)
+	assert.True(t, registry.IsEnabled("momentum"))
+}
+
+type countingHeartbeat struct {
+	beats int
+}
+
+func (h *countingHeartbeat) Beat() {
+	h.beats++
+}
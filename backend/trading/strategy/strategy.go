@@ -0,0 +1,245 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Strategy is the runtime state of a trading strategy
+type Strategy struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	// Config holds the strategy parameters, read by the strategy on each
+	// evaluation
+	Config map[string]interface{} `json:"config"`
+	// State holds the values a strategy keeps between evaluations, such as
+	// its last signal, so that they survive restarts through engine
+	// snapshots
+	State map[string]interface{} `json:"state,omitempty"`
+	// Schedule is the trading calendar of the strategy, nil when it trades
+	// at all times
+	Schedule *Schedule `json:"schedule,omitempty"`
+	// InSession reports whether the schedule allows trading now, and
+	// SessionReason why it does not
+	InSession     bool      `json:"in_session"`
+	SessionReason string    `json:"session_reason,omitempty"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Registry holds the strategies known to the engine and whether each one
+// may trade
+type Registry struct {
+	strategies map[string]*Strategy
+	stopped    bool
+	ctx        context.Context
+	cancel     context.CancelFunc
+	now        func() time.Time
+	mu         sync.RWMutex
+}
+
+// NewRegistry creates an empty strategy registry
+func NewRegistry() *Registry {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Registry{
+		strategies: make(map[string]*Strategy),
+		ctx:        ctx,
+		cancel:     cancel,
+		now:        time.Now,
+	}
+}
+
+// Context returns a context that is cancelled when the registry is stopped.
+// Strategy ticks derive their context from it so pending ticks are cancelled
+// on shutdown.
+func (r *Registry) Context() context.Context {
+	return r.ctx
+}
+
+// Stop stops all strategies: no strategy is enabled afterwards and pending
+// ticks are cancelled. The enabled flags are kept as they were.
+func (r *Registry) Stop() {
+	r.mu.Lock()
+	r.stopped = true
+	r.mu.Unlock()
+	r.cancel()
+}
+
+// Register adds a strategy with its initial parameters. Strategies start
+// disabled.
+func (r *Registry) Register(ctx context.Context, name string, config map[string]interface{}) (*Strategy, error) {
+	if name == "" {
+		return nil, ErrInvalidName
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.strategies[name]; ok {
+		return nil, ErrStrategyExists
+	}
+	s := &Strategy{
+		Name:      name,
+		Config:    copyConfig(config),
+		UpdatedAt: time.Now(),
+	}
+	r.strategies[name] = s
+	return r.snapshot(s), nil
+}
+
+// Get returns a strategy by name
+func (r *Registry) Get(ctx context.Context, name string) (*Strategy, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.strategies[name]
+	if !ok {
+		return nil, ErrStrategyNotFound
+	}
+	return r.snapshot(s), nil
+}
+
+// List returns all strategies sorted by name
+func (r *Registry) List(ctx context.Context) []*Strategy {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	strategies := make([]*Strategy, 0, len(r.strategies))
+	for _, s := range r.strategies {
+		strategies = append(strategies, r.snapshot(s))
+	}
+	sort.Slice(strategies, func(i, j int) bool { return strategies[i].Name < strategies[j].Name })
+	return strategies
+}
+
+// IsEnabled reports whether a strategy may trade. Unknown strategies may not,
+// nor strategies outside their trading session, and no strategy may once the
+// registry is stopped.
+func (r *Registry) IsEnabled(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.strategies[name]
+	if !ok || !s.Enabled || r.stopped {
+		return false
+	}
+	if s.Schedule == nil {
+		return true
+	}
+	active, _ := s.Schedule.active(r.now())
+	return active
+}
+
+// SetEnabled enables or disables a strategy
+func (r *Registry) SetEnabled(ctx context.Context, name string, enabled bool) (*Strategy, error) {
+	return r.update(name, func(s *Strategy) {
+		s.Enabled = enabled
+	})
+}
+
+// UpdateConfig merges parameters into a strategy's configuration
+func (r *Registry) UpdateConfig(ctx context.Context, name string, config map[string]interface{}) (*Strategy, error) {
+	return r.update(name, func(s *Strategy) {
+		for k, v := range config {
+			s.Config[k] = v
+		}
+	})
+}
+
+// SetSchedule sets the trading calendar of a strategy. A nil schedule lets
+// the strategy trade at all times.
+func (r *Registry) SetSchedule(ctx context.Context, name string, schedule *Schedule) (*Strategy, error) {
+	var compiled *Schedule
+	if schedule != nil {
+		var err error
+		if compiled, err = schedule.compile(); err != nil {
+			return nil, err
+		}
+	}
+	return r.update(name, func(s *Strategy) {
+		s.Schedule = compiled
+	})
+}
+
+// SetState replaces the internal state of a strategy
+func (r *Registry) SetState(ctx context.Context, name string, state map[string]interface{}) (*Strategy, error) {
+	return r.update(name, func(s *Strategy) {
+		s.State = copyConfig(state)
+	})
+}
+
+// Restore applies strategies saved in an engine snapshot to the registered
+// strategies of the same name: whether they are enabled, their parameters,
+// schedule and internal state. It returns the names of the restored
+// strategies; saved strategies that are no longer registered are skipped.
+func (r *Registry) Restore(ctx context.Context, saved []*Strategy) ([]string, error) {
+	compiled := make([]*Schedule, len(saved))
+	for i, s := range saved {
+		if s.Schedule == nil {
+			continue
+		}
+		var err error
+		if compiled[i], err = s.Schedule.compile(); err != nil {
+			return nil, fmt.Errorf("strategy %s: %w", s.Name, err)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	restored := make([]string, 0, len(saved))
+	for i, s := range saved {
+		current, ok := r.strategies[s.Name]
+		if !ok {
+			continue
+		}
+		current.Enabled = s.Enabled
+		current.Config = copyConfig(s.Config)
+		current.State = nil
+		if s.State != nil {
+			current.State = copyConfig(s.State)
+		}
+		current.Schedule = compiled[i]
+		current.UpdatedAt = time.Now()
+		restored = append(restored, s.Name)
+	}
+	return restored, nil
+}
+
+func (r *Registry) update(name string, apply func(s *Strategy)) (*Strategy, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.strategies[name]
+	if !ok {
+		return nil, ErrStrategyNotFound
+	}
+	apply(s)
+	s.UpdatedAt = time.Now()
+	return r.snapshot(s), nil
+}
+
+// snapshot copies a strategy with its current session state. Schedules are
+// replaced rather than modified, so the copy shares it.
+func (r *Registry) snapshot(s *Strategy) *Strategy {
+	c := *s
+	c.Config = copyConfig(s.Config)
+	if s.State != nil {
+		c.State = copyConfig(s.State)
+	}
+	c.InSession, c.SessionReason = true, ""
+	if s.Schedule != nil {
+		c.InSession, c.SessionReason = s.Schedule.active(r.now())
+	}
+	return &c
+}
+
+func copyConfig(config map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(config))
+	for k, v := range config {
+		c[k] = v
+	}
+	return c
+}
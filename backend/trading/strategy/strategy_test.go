@@ -0,0 +1,83 @@
+package strategy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	registry := NewRegistry()
+
+	_, err := registry.Register(ctx, "momentum", map[string]interface{}{"period": 14.0})
+	require.NoError(t, err)
+	_, err = registry.Register(ctx, "momentum", nil)
+	assert.Equal(t, ErrStrategyExists, err)
+	_, err = registry.Register(ctx, "", nil)
+	assert.Equal(t, ErrInvalidName, err)
+	assert.False(t, registry.IsEnabled("momentum"))
+
+	r := gin.New()
+	registry.RegisterRoutes(r.Group("/api/v1"))
+
+	call := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Enable and disable", func(t *testing.T) {
+		w := call(http.MethodPost, "/api/v1/strategies/momentum/enable", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, registry.IsEnabled("momentum"))
+
+		w = call(http.MethodPost, "/api/v1/strategies/momentum/disable", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.False(t, registry.IsEnabled("momentum"))
+
+		assert.Equal(t, http.StatusNotFound, call(http.MethodPost, "/api/v1/strategies/missing/enable", "").Code)
+	})
+
+	t.Run("Update config", func(t *testing.T) {
+		w := call(http.MethodPut, "/api/v1/strategies/momentum/config", `{"threshold":0.7}`)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var s Strategy
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &s))
+		assert.Equal(t, map[string]interface{}{"period": 14.0, "threshold": 0.7}, s.Config)
+
+		assert.Equal(t, http.StatusBadRequest, call(http.MethodPut, "/api/v1/strategies/momentum/config", `{}`).Code)
+		assert.Equal(t, http.StatusBadRequest, call(http.MethodPut, "/api/v1/strategies/momentum/config", `[1]`).Code)
+	})
+
+	t.Run("List strategies", func(t *testing.T) {
+		w := call(http.MethodGet, "/api/v1/strategies", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"name":"momentum"`)
+	})
+
+	t.Run("Stop", func(t *testing.T) {
+		_, err := registry.SetEnabled(context.Background(), "momentum", true)
+		require.NoError(t, err)
+		require.True(t, registry.IsEnabled("momentum"))
+
+		registry.Stop()
+		assert.False(t, registry.IsEnabled("momentum"))
+		assert.Error(t, registry.Context().Err())
+
+		s, err := registry.Get(context.Background(), "momentum")
+		require.NoError(t, err)
+		assert.True(t, s.Enabled)
+	})
+}
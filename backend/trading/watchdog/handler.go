@@ -0,0 +1,14 @@
+package watchdog
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes registers the watchdog HTTP endpoints
+func RegisterRoutes(r gin.IRouter, w *Watchdog) {
+	r.GET("/watchdog", func(c *gin.Context) {
+		c.JSON(http.StatusOK, w.Status())
+	})
+}
@@ -0,0 +1,305 @@
+// Package watchdog is a dead man's switch for the trading engine. The
+// strategy loop checks in on every pass; when the check-ins stop, because the
+// loop hangs, deadlocks or loses its connections, the watchdog clears the
+// book through the exchange clients directly, without going through the
+// order and position managers that may be stuck.
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/devinjacknz/godydxhyber/backend/exchange/dydx"
+	"github.com/devinjacknz/godydxhyber/backend/trading/analysis/monitoring"
+)
+
+// EventType is the monitoring event type used for watchdog audit events
+const EventType = "watchdog"
+
+// Exchange is the part of an exchange client the watchdog clears the book
+// with
+type Exchange interface {
+	GetOpenOrders(ctx context.Context) ([]dydx.Order, error)
+	CancelOrder(ctx context.Context, orderID string) error
+	GetPositions(ctx context.Context) ([]dydx.Position, error)
+	CreateOrder(ctx context.Context, req dydx.CreateOrderRequest) (*dydx.Order, error)
+}
+
+// Target is the exchange account of a trading account
+type Target struct {
+	AccountID string
+	Exchange  Exchange
+}
+
+// Config contains watchdog configuration
+type Config struct {
+	// Timeout is the time without a heartbeat after which the watchdog
+	// trips
+	Timeout time.Duration
+	// CheckInterval is the time between two checks of the last heartbeat,
+	// a quarter of Timeout when zero
+	CheckInterval time.Duration
+	// FlattenPositions closes open positions with reduce-only market orders
+	// when the watchdog trips
+	FlattenPositions bool
+	// Slippage bounds the price of the market orders flattening positions,
+	// as a fraction of the mark price
+	Slippage float64
+	// CallTimeout bounds each exchange call, so that an unresponsive
+	// exchange does not stall clearing the other accounts
+	CallTimeout time.Duration
+}
+
+// DefaultConfig returns the default watchdog configuration
+func DefaultConfig() Config {
+	return Config{
+		Timeout:     90 * time.Second,
+		Slippage:    0.05,
+		CallTimeout: 10 * time.Second,
+	}
+}
+
+// State is the state of the watchdog
+type State struct {
+	// Tripped is set from the moment heartbeats are missed until the next
+	// heartbeat
+	Tripped       bool       `json:"tripped"`
+	LastHeartbeat time.Time  `json:"last_heartbeat"`
+	TrippedAt     *time.Time `json:"tripped_at,omitempty"`
+	// CancelledOrders and ClosedPositions count the orders and positions
+	// cleared by the last trip
+	CancelledOrders int    `json:"cancelled_orders"`
+	ClosedPositions int    `json:"closed_positions"`
+	Error           string `json:"error,omitempty"`
+}
+
+// Listener is told when the watchdog trips, in a goroutine of its own so
+// that a stuck listener cannot hold up the watchdog
+type Listener func(state State)
+
+// Watchdog trips when heartbeats stop for longer than the timeout
+type Watchdog struct {
+	targets  []Target
+	config   Config
+	listener Listener
+	state    State
+	now      func() time.Time
+	mu       sync.Mutex
+}
+
+// New creates a watchdog clearing the book of the targets. Heartbeats are
+// expected from the moment it is created.
+func New(targets []Target, config Config, listener Listener) *Watchdog {
+	defaults := DefaultConfig()
+	if config.Timeout <= 0 {
+		config.Timeout = defaults.Timeout
+	}
+	if config.CheckInterval <= 0 {
+		config.CheckInterval = config.Timeout / 4
+	}
+	if config.Slippage <= 0 {
+		config.Slippage = defaults.Slippage
+	}
+	if config.CallTimeout <= 0 {
+		config.CallTimeout = defaults.CallTimeout
+	}
+
+	w := &Watchdog{
+		targets:  targets,
+		config:   config,
+		listener: listener,
+		now:      time.Now,
+	}
+	w.state.LastHeartbeat = w.now()
+	return w
+}
+
+// Beat records a heartbeat. A heartbeat after a trip re-arms the watchdog;
+// trading stays halted by whatever the listener halted.
+func (w *Watchdog) Beat() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.state.LastHeartbeat = w.now()
+	if !w.state.Tripped {
+		return
+	}
+	w.state.Tripped = false
+	monitoring.RecordIndicatorValue("watchdog_tripped", 0)
+	monitoring.RecordEvent(monitoring.Event{
+		Type:     EventType,
+		Severity: monitoring.SeverityWarning,
+		Message:  "Watchdog re-armed",
+		Details: map[string]interface{}{
+			"tripped_for": w.state.LastHeartbeat.Sub(*w.state.TrippedAt).String(),
+		},
+	})
+}
+
+// Status returns the current watchdog state
+func (w *Watchdog) Status() State {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.state
+}
+
+// Run checks the heartbeats on every check interval until ctx is done
+func (w *Watchdog) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.config.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.Check(ctx)
+		}
+	}
+}
+
+// Check trips the watchdog if the last heartbeat is older than the timeout.
+// It reports whether the watchdog tripped.
+func (w *Watchdog) Check(ctx context.Context) bool {
+	w.mu.Lock()
+	now := w.now()
+	silence := now.Sub(w.state.LastHeartbeat)
+	if w.state.Tripped || silence <= w.config.Timeout {
+		w.mu.Unlock()
+		return false
+	}
+	w.state = State{Tripped: true, LastHeartbeat: w.state.LastHeartbeat, TrippedAt: &now}
+	w.mu.Unlock()
+
+	slog.ErrorContext(ctx, "heartbeats stopped, clearing the book", "silence", silence.Round(time.Second), "flatten", w.config.FlattenPositions)
+	cancelled, closed, err := w.clear(ctx)
+
+	w.mu.Lock()
+	w.state.CancelledOrders, w.state.ClosedPositions = cancelled, closed
+	if err != nil {
+		w.state.Error = err.Error()
+	}
+	state := w.state
+	w.mu.Unlock()
+
+	details := map[string]interface{}{
+		"silence":          silence.String(),
+		"cancelled_orders": cancelled,
+		"closed_positions": closed,
+		"flatten":          w.config.FlattenPositions,
+	}
+	if err != nil {
+		details["error"] = err.Error()
+	}
+	monitoring.RecordEvent(monitoring.Event{
+		Type:      EventType,
+		Severity:  monitoring.SeverityCritical,
+		Message:   "Watchdog tripped",
+		Details:   details,
+		Timestamp: now,
+	})
+	monitoring.RecordIndicatorValue("watchdog_tripped", 1)
+
+	if w.listener != nil {
+		go w.listener(state)
+	}
+	return true
+}
+
+// clear cancels the open orders of every target and flattens its positions
+// if configured
+func (w *Watchdog) clear(ctx context.Context) (cancelled, closed int, err error) {
+	var errs []error
+	for _, target := range w.targets {
+		n, err := w.cancelOrders(ctx, target)
+		cancelled += n
+		if err != nil {
+			errs = append(errs, fmt.Errorf("account %s: %w", target.AccountID, err))
+		}
+		if !w.config.FlattenPositions {
+			continue
+		}
+		n, err = w.flattenPositions(ctx, target)
+		closed += n
+		if err != nil {
+			errs = append(errs, fmt.Errorf("account %s: %w", target.AccountID, err))
+		}
+	}
+	return cancelled, closed, errors.Join(errs...)
+}
+
+func (w *Watchdog) cancelOrders(ctx context.Context, target Target) (int, error) {
+	callCtx, cancel := context.WithTimeout(ctx, w.config.CallTimeout)
+	orders, err := target.Exchange.GetOpenOrders(callCtx)
+	cancel()
+	if err != nil {
+		return 0, fmt.Errorf("list open orders: %w", err)
+	}
+
+	var errs []error
+	cancelled := 0
+	for _, o := range orders {
+		callCtx, cancel := context.WithTimeout(ctx, w.config.CallTimeout)
+		err := target.Exchange.CancelOrder(callCtx, o.ID)
+		cancel()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cancel order %s: %w", o.ID, err))
+			continue
+		}
+		cancelled++
+	}
+	return cancelled, errors.Join(errs...)
+}
+
+func (w *Watchdog) flattenPositions(ctx context.Context, target Target) (int, error) {
+	callCtx, cancel := context.WithTimeout(ctx, w.config.CallTimeout)
+	positions, err := target.Exchange.GetPositions(callCtx)
+	cancel()
+	if err != nil {
+		return 0, fmt.Errorf("list positions: %w", err)
+	}
+
+	var errs []error
+	closed := 0
+	for i := range positions {
+		if positions[i].Size == 0 {
+			continue
+		}
+		callCtx, cancel := context.WithTimeout(ctx, w.config.CallTimeout)
+		_, err := target.Exchange.CreateOrder(callCtx, w.closingOrder(&positions[i]))
+		cancel()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("close position on %s: %w", positions[i].Market, err))
+			continue
+		}
+		closed++
+	}
+	return closed, errors.Join(errs...)
+}
+
+// closingOrder is a reduce-only market order closing a position, priced at
+// the worst price accepted within the slippage of the mark price
+func (w *Watchdog) closingOrder(p *dydx.Position) dydx.CreateOrderRequest {
+	req := dydx.CreateOrderRequest{
+		Market:      p.Market,
+		Side:        dydx.OrderSideSell,
+		Type:        dydx.OrderTypeMarket,
+		Size:        math.Abs(p.Size),
+		Price:       p.MarkPrice * (1 - w.config.Slippage),
+		ReduceOnly:  true,
+		TimeInForce: dydx.TimeInForceIOC,
+		ClientID:    "watchdog-" + p.Market + "-" + strconv.FormatInt(w.now().UnixNano(), 36),
+	}
+	if strings.EqualFold(p.Side, dydx.PositionSideShort) {
+		req.Side = dydx.OrderSideBuy
+		req.Price = p.MarkPrice * (1 + w.config.Slippage)
+	}
+	return req
+}
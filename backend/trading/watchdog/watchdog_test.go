@@ -0,0 +1,135 @@
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/devinjacknz/godydxhyber/backend/exchange/dydx"
+)
+
+type fakeExchange struct {
+	orders    []dydx.Order
+	positions []dydx.Position
+	failOn    string
+	cancelled []string
+	created   []dydx.CreateOrderRequest
+	mu        sync.Mutex
+}
+
+func (e *fakeExchange) GetOpenOrders(ctx context.Context) ([]dydx.Order, error) {
+	return e.orders, nil
+}
+
+func (e *fakeExchange) CancelOrder(ctx context.Context, orderID string) error {
+	if orderID == e.failOn {
+		return errors.New("exchange unavailable")
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.cancelled = append(e.cancelled, orderID)
+	return nil
+}
+
+func (e *fakeExchange) GetPositions(ctx context.Context) ([]dydx.Position, error) {
+	return e.positions, nil
+}
+
+func (e *fakeExchange) CreateOrder(ctx context.Context, req dydx.CreateOrderRequest) (*dydx.Order, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.created = append(e.created, req)
+	return &dydx.Order{ID: req.ClientID, Market: req.Market}, nil
+}
+
+func TestWatchdog(t *testing.T) {
+	ctx := context.Background()
+	exchange := &fakeExchange{
+		orders: []dydx.Order{{ID: "o1"}, {ID: "o2"}, {ID: "o3"}},
+		positions: []dydx.Position{
+			{Market: "BTC-USD", Side: dydx.PositionSideLong, Size: 0.5, MarkPrice: 50000},
+			{Market: "ETH-USD", Side: dydx.PositionSideShort, Size: 2, MarkPrice: 2000},
+			{Market: "SOL-USD", Side: dydx.PositionSideLong},
+		},
+		failOn: "o2",
+	}
+	idle := &fakeExchange{orders: []dydx.Order{{ID: "o4"}}}
+
+	tripped := make(chan State, 1)
+	w := New([]Target{{AccountID: "default", Exchange: exchange}, {AccountID: "wallet-2", Exchange: idle}},
+		Config{Timeout: time.Minute, FlattenPositions: true, Slippage: 0.01},
+		func(state State) { tripped <- state })
+	now := time.Now()
+	w.now = func() time.Time { return now }
+	w.Beat()
+
+	now = now.Add(time.Minute)
+	assert.False(t, w.Check(ctx), "a heartbeat within the timeout keeps it armed")
+
+	now = now.Add(time.Second)
+	require.True(t, w.Check(ctx))
+	assert.False(t, w.Check(ctx), "the watchdog trips once")
+
+	state := w.Status()
+	assert.True(t, state.Tripped)
+	assert.Equal(t, 3, state.CancelledOrders)
+	assert.Equal(t, 2, state.ClosedPositions)
+	assert.Contains(t, state.Error, "account default: cancel order o2: exchange unavailable")
+	assert.Equal(t, []string{"o1", "o3"}, exchange.cancelled)
+	assert.Equal(t, []string{"o4"}, idle.cancelled)
+	assert.Empty(t, idle.created)
+
+	require.Len(t, exchange.created, 2)
+	sell, buy := exchange.created[0], exchange.created[1]
+	assert.Equal(t, dydx.OrderSideSell, sell.Side)
+	assert.Equal(t, 0.5, sell.Size)
+	assert.InDelta(t, 49500, sell.Price, 1e-9)
+	assert.Equal(t, dydx.OrderSideBuy, buy.Side)
+	assert.InDelta(t, 2020, buy.Price, 1e-9)
+	for _, req := range exchange.created {
+		assert.Equal(t, dydx.OrderTypeMarket, req.Type)
+		assert.Equal(t, dydx.TimeInForceIOC, req.TimeInForce)
+		assert.True(t, req.ReduceOnly)
+	}
+
+	select {
+	case notified := <-tripped:
+		assert.Equal(t, state, notified)
+	case <-time.After(time.Second):
+		t.Fatal("listener not notified")
+	}
+
+	// Heartbeats resuming re-arm the watchdog
+	w.Beat()
+	assert.False(t, w.Status().Tripped)
+	now = now.Add(2 * time.Minute)
+	assert.True(t, w.Check(ctx))
+}
+
+func TestWatchdogWithoutFlatten(t *testing.T) {
+	exchange := &fakeExchange{
+		orders:    []dydx.Order{{ID: "o1"}},
+		positions: []dydx.Position{{Market: "BTC-USD", Side: dydx.PositionSideLong, Size: 1, MarkPrice: 50000}},
+	}
+	w := New([]Target{{AccountID: "default", Exchange: exchange}}, Config{Timeout: time.Millisecond}, nil)
+	assert.Equal(t, time.Millisecond/4, w.config.CheckInterval)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		w.Run(ctx)
+		close(done)
+	}()
+	assert.Eventually(t, func() bool { return w.Status().Tripped }, time.Second, time.Millisecond)
+	cancel()
+	<-done
+
+	assert.Equal(t, []string{"o1"}, exchange.cancelled)
+	assert.Empty(t, exchange.created)
+	assert.Zero(t, w.Status().ClosedPositions)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log/slog"
+    "time"
+
+    "github.com/devinjacknz/godydxhyber/backend/config"
+    "github.com/devinjacknz/godydxhyber/backend/trading/account"
+    "github.com/devinjacknz/godydxhyber/backend/trading/killswitch"
+    "github.com/devinjacknz/godydxhyber/backend/trading/watchdog"
+)
+
+// newWatchdog creates the dead man's switch over the exchange clients of the
+// accounts. When it trips, the kill switch of every account is triggered
+// without flattening, the watchdog having flattened on the exchanges already
+// if configured, so trading stays halted until an operator resets it.
+func newWatchdog(cfg config.WatchdogConfig, accounts *account.Registry) *watchdog.Watchdog {
+    var targets []watchdog.Target
+    for _, a := range accounts.List() {
+        if a.Exchange != nil {
+            targets = append(targets, watchdog.Target{AccountID: a.ID, Exchange: a.Exchange})
+        }
+    }
+
+    watchdogConfig := watchdog.DefaultConfig()
+    watchdogConfig.Timeout = time.Duration(cfg.Timeout)
+    watchdogConfig.FlattenPositions = cfg.FlattenPositions
+    return watchdog.New(targets, watchdogConfig, func(state watchdog.State) {
+        silence := state.TrippedAt.Sub(state.LastHeartbeat).Round(time.Second)
+        flatten := false
+        // Each account halts on its own, so one stuck order manager does
+        // not keep the others trading
+        for _, a := range accounts.List() {
+            a := a
+            go func() {
+                _, err := a.KillSwitch.Trigger(context.Background(), killswitch.TriggerParams{
+                    Source:  killswitch.SourceWatchdog,
+                    Reason:  fmt.Sprintf("no strategy loop heartbeat for %s", silence),
+                    Flatten: &flatten,
+                })
+                if err != nil && !errors.Is(err, killswitch.ErrAlreadyActive) {
+                    slog.Error("failed to halt trading after watchdog trip", "account_id", a.ID, "error", err)
+                }
+            }()
+        }
+    })
+}
+
+// schedulerInterval is the interval of the strategy scheduler, short enough
+// for three check-ins within the watchdog timeout. Zero keeps the default.
+func schedulerInterval(cfg config.WatchdogConfig) time.Duration {
+    interval := time.Duration(cfg.Timeout) / 3
+    if interval <= 0 || interval > 30*time.Second {
+        return 0
+    }
+    return interval
+}
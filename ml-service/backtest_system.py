@@ -1,7 +1,7 @@
 import logging
 import pandas as pd
 import numpy as np
-from typing import Dict, List, Optional, Union
+from typing import Callable, Dict, List, Optional, Tuple, Union
 from datetime import datetime, timedelta
 from dataclasses import dataclass
 from agent_system import AgentSystem, AgentConfig, TradeSignal
@@ -73,6 +73,35 @@ class BacktestResult:
     positions_history: List[Position]
     risk_metrics: Dict[str, float]
 
+@dataclass
+class BacktestProgress:
+    """回测进度"""
+    percent_complete: float
+    current_time: datetime
+    steps: int
+    total_trades: int
+    current_equity: float
+    max_drawdown: float
+    equity_points: List[Tuple[datetime, float]]  # 自上次进度以来新增的权益点
+    finished: bool = False
+
+    def to_dict(self) -> Dict:
+        return {
+            "percent_complete": self.percent_complete,
+            "current_time": self.current_time.isoformat(),
+            "steps": self.steps,
+            "total_trades": self.total_trades,
+            "current_equity": self.current_equity,
+            "max_drawdown": self.max_drawdown,
+            "equity_points": [
+                {"timestamp": ts.isoformat(), "equity": equity}
+                for ts, equity in self.equity_points
+            ],
+            "finished": self.finished
+        }
+
+ProgressCallback = Callable[[BacktestProgress], None]
+
 class BacktestSystem:
     """回测系统"""
     
@@ -99,6 +128,10 @@ class BacktestSystem:
         
         self.funding_rates: Dict[str, float] = {}  # 各交易对的资金费率
         self.next_funding_time = config.start_date + timedelta(hours=config.funding_rate_interval)
+        
+        # 进度上报
+        self._steps = 0
+        self._reported_points = 0
     
     def _load_historical_data(self) -> Dict[str, Dict[str, pd.DataFrame]]:
         """加载历史数据"""
@@ -145,8 +178,13 @@ class BacktestSystem:
         
         return data
     
-    def run(self) -> BacktestResult:
-        """运行回测"""
+    def run(self, progress_callback: Optional[ProgressCallback] = None,
+            progress_interval: int = 1440) -> BacktestResult:
+        """运行回测
+        
+        progress_callback 每 progress_interval 个时间步（默认一个模拟日）被调用一次，
+        回测结束时再调用一次，用于实时展示进度和权益曲线。
+        """
         logger.info("Starting backtest...")
         
         # 初始化Agent
@@ -155,6 +193,10 @@ class BacktestSystem:
         # 回测主循环
         while self.current_time <= self.config.end_date:
             try:
+                self._steps += 1
+                if progress_callback and self._steps % max(1, progress_interval) == 0:
+                    self._report_progress(progress_callback)
+                
                 # 更新市场数据
                 current_data = self._get_current_data()
                 if not current_data:
@@ -199,9 +241,40 @@ class BacktestSystem:
         # 平掉所有持仓
         self._close_all_positions()
         
+        if progress_callback:
+            self._report_progress(progress_callback, finished=True)
+        
         # 计算回测结果
         return self._calculate_results()
     
+    def _report_progress(self, callback: ProgressCallback, finished: bool = False):
+        """上报回测进度"""
+        total = (self.config.end_date - self.config.start_date).total_seconds()
+        elapsed = (min(self.current_time, self.config.end_date) - self.config.start_date).total_seconds()
+        percent = 100.0 if finished or total <= 0 else min(100.0, max(0.0, elapsed / total * 100))
+        
+        points = list(zip(
+            self.equity_timestamps[self._reported_points:],
+            self.equity_curve[self._reported_points:]
+        ))
+        self._reported_points = len(self.equity_curve)
+        
+        progress = BacktestProgress(
+            percent_complete=percent,
+            current_time=self.current_time,
+            steps=self._steps,
+            total_trades=len(self.trades_history),
+            current_equity=self.equity_curve[-1],
+            max_drawdown=self.max_drawdown,
+            equity_points=points,
+            finished=finished
+        )
+        
+        try:
+            callback(progress)
+        except Exception as e:
+            logger.error(f"Error in progress callback: {str(e)}")
+    
     def _initialize_agents(self):
         """初始化交易代理"""
         # 这里添加需要测试的交易代理
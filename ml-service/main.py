@@ -1,4 +1,4 @@
-from fastapi import FastAPI, HTTPException, APIRouter
+from fastapi import FastAPI, HTTPException, APIRouter, WebSocket, WebSocketDisconnect
 from fastapi.middleware.cors import CORSMiddleware
 from fastapi.responses import JSONResponse
 from pydantic import BaseModel
@@ -737,6 +737,80 @@ async def predict(request: PredictionRequest):
         if len(analyzer.inference_times) > 1000:
             analyzer.inference_times.pop(0)
 
+class BacktestRequest(BaseModel):
+    start_date: datetime
+    end_date: datetime
+    initial_capital: float
+    symbols: List[str]
+    timeframes: List[str] = ["1h"]
+    progress_interval: int = 1440
+
+@router.websocket("/backtest/stream")
+async def stream_backtest(websocket: WebSocket):
+    """Run a backtest and stream progress and the equity curve as it builds.
+
+    The client sends a BacktestRequest as the first message and receives
+    {"type": "progress", ...} messages followed by a single {"type": "result", ...}.
+    """
+    from backtest_system import BacktestSystem, BacktestConfig
+
+    await websocket.accept()
+    try:
+        request = BacktestRequest(**await websocket.receive_json())
+    except WebSocketDisconnect:
+        return
+    except Exception as e:
+        await websocket.send_json({"type": "error", "message": f"Invalid backtest request: {str(e)}"})
+        await websocket.close()
+        return
+
+    loop = asyncio.get_running_loop()
+    queue: asyncio.Queue = asyncio.Queue()
+
+    def on_progress(progress):
+        loop.call_soon_threadsafe(queue.put_nowait, {"type": "progress", **progress.to_dict()})
+
+    def run_backtest():
+        config = BacktestConfig(
+            start_date=request.start_date,
+            end_date=request.end_date,
+            initial_capital=request.initial_capital,
+            symbols=request.symbols,
+            timeframes=request.timeframes
+        )
+        return BacktestSystem(config).run(
+            progress_callback=on_progress,
+            progress_interval=request.progress_interval
+        )
+
+    task = loop.run_in_executor(None, run_backtest)
+    try:
+        while not task.done() or not queue.empty():
+            try:
+                message = await asyncio.wait_for(queue.get(), timeout=1.0)
+            except asyncio.TimeoutError:
+                continue
+            await websocket.send_json(message)
+
+        result = task.result()
+        await websocket.send_json({
+            "type": "result",
+            "total_returns": result.total_returns,
+            "annual_returns": result.annual_returns,
+            "sharpe_ratio": result.sharpe_ratio,
+            "max_drawdown": result.max_drawdown,
+            "win_rate": result.win_rate,
+            "profit_factor": result.profit_factor,
+            "total_trades": result.total_trades
+        })
+        await websocket.close()
+    except WebSocketDisconnect:
+        logger.info("Backtest stream client disconnected")
+    except Exception as e:
+        logger.error(f"Backtest stream failed: {str(e)}")
+        await websocket.send_json({"type": "error", "message": str(e)})
+        await websocket.close()
+
 # Include router in app
 # Router already has /api/v1 prefix, so we include it at root
 app.include_router(router)
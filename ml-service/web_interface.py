@@ -8,7 +8,7 @@ from typing import Dict, List
 from pathlib import Path
 import json
 import sqlite3
-from backtest_system import BacktestSystem, BacktestConfig, BacktestResult
+from backtest_system import BacktestSystem, BacktestConfig, BacktestResult, BacktestProgress
 from reporting_system import ReportingSystem
 from market_data_service import MarketDataService
 from agent_system import AgentSystem
@@ -162,9 +162,42 @@ def show_backtest_page():
             timeframes=timeframes
         )
         
-        # 运行回测
+        # 运行回测并实时显示进度
+        progress_bar = st.progress(0)
+        status_text = st.empty()
+        metrics_placeholder = st.empty()
+        chart_placeholder = st.empty()
+        timestamps: List[datetime] = []
+        equity: List[float] = []
+        
+        def on_progress(progress: BacktestProgress):
+            for ts, value in progress.equity_points:
+                timestamps.append(ts)
+                equity.append(value)
+            
+            progress_bar.progress(int(progress.percent_complete))
+            status_text.text(
+                f"Simulated up to {progress.current_time:%Y-%m-%d %H:%M} "
+                f"({progress.percent_complete:.1f}%)"
+            )
+            
+            with metrics_placeholder.container():
+                col1, col2, col3 = st.columns(3)
+                col1.metric("Current Equity", f"${progress.current_equity:,.2f}")
+                col2.metric("Trades", progress.total_trades)
+                col3.metric("Max Drawdown", f"{progress.max_drawdown:.2%}")
+            
+            fig = go.Figure(go.Scatter(x=timestamps, y=equity, name="Equity"))
+            fig.update_layout(title="Equity Curve (in progress)", height=300)
+            chart_placeholder.plotly_chart(fig, use_container_width=True)
+        
         backtest = BacktestSystem(config)
-        result = backtest.run()
+        result = backtest.run(progress_callback=on_progress)
+        
+        progress_bar.empty()
+        status_text.empty()
+        metrics_placeholder.empty()
+        chart_placeholder.empty()
         
         # 显示回测结果
         show_backtest_result(result)